@@ -0,0 +1,67 @@
+import { configSchema, SECRET_KEYS, type Config } from './schema.js';
+
+export type { Config } from './schema.js';
+
+// Startup configuration. loadConfig validates every setting at once and
+// reports everything that is wrong in one go, so a bad deployment fails
+// before it serves a request instead of with a cryptic error the first time
+// a setting is used.
+
+type Source = Record<string, string | undefined>;
+
+// Older deployments set these under a different name
+const LEGACY_NAMES: Record<string, string> = {
+  UPLOADS_DIR: 'UPLOAD_DIR',
+};
+
+export class ConfigError extends Error {
+  constructor(readonly problems: string[]) {
+    super(`Invalid configuration:\n${problems.map((p) => `  - ${p}`).join('\n')}`);
+    this.name = 'ConfigError';
+  }
+}
+
+// The schema's keys with blanks dropped, so they get their defaults
+function settingsFrom(source: Source): Record<string, string> {
+  const settings: Record<string, string> = {};
+  for (const key of Object.keys(configSchema.innerType().shape)) {
+    const value = source[key] ?? (LEGACY_NAMES[key] ? source[LEGACY_NAMES[key]] : undefined);
+    if (value !== undefined && value.trim() !== '') settings[key] = value;
+  }
+  return settings;
+}
+
+// ── LoadConfig ──────────────────────────────────────────────────────────────
+
+export function loadConfig(source: Source): Readonly<Config> {
+  const result = configSchema.safeParse(settingsFrom(source));
+  if (!result.success) {
+    throw new ConfigError(result.error.issues.map((issue) => {
+      const key = issue.path.join('.');
+      const value = source[key];
+      const shown = value === undefined || SECRET_KEYS.has(key) ? '' : ` (got "${value}")`;
+      return `${key}: ${issue.message}${shown}`;
+    }));
+  }
+  return Object.freeze(result.data);
+}
+
+// ── ConfigSummary ───────────────────────────────────────────────────────────
+// One line per setting for the startup log. Secrets only show whether they
+// are set, and the password in a URL is masked.
+
+function redact(key: string, value: unknown): string {
+  if (SECRET_KEYS.has(key)) return value ? '********' : '(not set)';
+  if (typeof value === 'string' && /^[a-z][a-z0-9+.-]*:\/\/[^/]*@/i.test(value)) {
+    return value.replace(/(:\/\/[^:/@]*:)[^@]*@/, '$1********@');
+  }
+  return value === '' ? '(not set)' : String(value);
+}
+
+export function configSummary(config: Readonly<Config>, source: Source): string[] {
+  const given = settingsFrom(source);
+  return Object.entries(config).map(([key, value]) => {
+    const origin = key in given || value === '' ? '' : ' (default)';
+    return `${key}=${redact(key, value)}${origin}`;
+  });
+}
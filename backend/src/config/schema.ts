@@ -0,0 +1,192 @@
+import { z } from 'zod';
+
+// Every environment setting the server reads, with its type, default and
+// limits. A blank value counts as unset and gets the default, so
+// `SMTP_HOST=` in .env.example means "off", not "an empty host name".
+
+const DEV_JWT_SECRET = 'dev-only-secret-change-in-production-min-32-chars';
+
+function text(fallback = '') {
+  return z.string().trim().default(fallback);
+}
+
+function int(fallback: number, min = 1, max = Number.MAX_SAFE_INTEGER) {
+  return z.coerce.number().int().min(min).max(max).default(fallback);
+}
+
+// true/false, also 1/0 and yes/no in any case
+function flag(fallback: boolean) {
+  return z
+    .string()
+    .trim()
+    .toLowerCase()
+    .pipe(z.enum(['true', 'false', '1', '0', 'yes', 'no'], {
+      errorMap: () => ({ message: 'Expected true or false' }),
+    }))
+    .transform((v) => v === 'true' || v === '1' || v === 'yes')
+    .default(fallback ? 'true' : 'false');
+}
+
+// HH:MM, 24-hour, in the restaurant timezone
+function clockTime(fallback: string) {
+  return z.string().trim().regex(/^([01]\d|2[0-3]):[0-5]\d$/, 'Expected a time as HH:MM').default(fallback);
+}
+
+function url(fallback: string) {
+  return z.string().trim().url().default(fallback);
+}
+
+// Off while blank
+function optionalUrl() {
+  return z.union([z.literal(''), z.string().trim().url()]).default('');
+}
+
+export const configSchema = z
+  .object({
+    NODE_ENV: z.enum(['development', 'production', 'test']).default('development'),
+    PORT: int(8080, 1, 65535),
+
+    DB_HOST: text('postgres'),
+    DB_PORT: int(5432, 1, 65535),
+    DB_USER: text('postgres'),
+    DB_PASSWORD: text('postgres123'),
+    DB_NAME: text('pos_system'),
+    DB_SSLMODE: z.enum(['disable', 'allow', 'prefer', 'require', 'verify-ca', 'verify-full']).default('disable'),
+    MIGRATIONS_DIR: text(),
+    AUTO_MIGRATE: flag(false),
+
+    JWT_SECRET: z.string().min(32, 'Must be at least 32 characters').default(DEV_JWT_SECRET),
+    CORS_ALLOWED_ORIGINS: text('http://localhost:8000,http://localhost:3001,http://localhost:5173'),
+    CSRF_ENABLED: flag(true),
+    PUBLIC_APP_URL: url('http://localhost:8000'),
+    PASSWORD_RESET_TTL_MINUTES: int(60, 5, 1440),
+    // 0 turns the lockout off
+    LOGIN_MAX_FAILED_ATTEMPTS: int(5, 0),
+    LOGIN_LOCKOUT_MINUTES: int(15),
+    PASSWORD_MIN_LENGTH: int(8, 6, 72),
+    PASSWORD_REQUIRE_UPPER: flag(true),
+    PASSWORD_REQUIRE_LOWER: flag(true),
+    PASSWORD_REQUIRE_NUMBER: flag(true),
+    PASSWORD_REQUIRE_SPECIAL: flag(true),
+    PASSWORD_DENY_COMMON: flag(true),
+    PASSWORD_BREACH_CHECK: flag(false),
+    // Signs table QR codes; empty uses JWT_SECRET. Changing it invalidates every printed code
+    QR_SIGNING_SECRET: text(),
+    // Encrypts customer order and survey links; empty uses JWT_SECRET
+    LINK_TOKEN_SECRET: text(),
+    ORDER_LINK_TTL_HOURS: int(48, 1, 720),
+    SURVEY_LINK_TTL_DAYS: int(14, 1, 90),
+
+    UPLOADS_DIR: text('./uploads'),
+    MAX_BODY_KB: int(1024),
+    MAX_UPLOAD_BODY_MB: int(6),
+    // 0 turns the quota off
+    UPLOAD_DAILY_QUOTA_COUNT: int(200, 0),
+    UPLOAD_DAILY_QUOTA_MB: int(200, 0),
+    HEALTH_MIN_FREE_DISK_MB: int(500),
+
+    SHUTDOWN_TIMEOUT_MS: int(15000),
+    RUNTIME_CONFIG_REFRESH_MS: int(30000, 1000),
+    SCHEDULER_ENABLED: flag(true),
+    JOB_WORKER_ENABLED: flag(true),
+    JOB_POLL_INTERVAL_MS: int(2000, 100),
+    JOB_CONCURRENCY: int(2, 1, 50),
+    DAILY_SPECIALS_RESET_TIME: clockTime('06:00'),
+    EIGHTY_SIX_RESET_TIME: clockTime('06:00'),
+    LOGBOOK_DIGEST_TIME: clockTime('07:00'),
+    SALES_SUMMARY_TIME: clockTime('06:30'),
+    LOW_STOCK_DIGEST_TIME: clockTime('08:00'),
+
+    PAYMENT_GATEWAY_SERVER_KEY: text(),
+    PAYMENT_GATEWAY_PRODUCTION: flag(false),
+
+    METRICS_TOKEN: text(),
+    SENTRY_DSN: optionalUrl(),
+    SENTRY_RELEASE: text(),
+    BUILD_VERSION: text(),
+    BUILD_COMMIT: text(),
+
+    SMTP_HOST: text(),
+    SMTP_PORT: int(587, 1, 65535),
+    SMTP_SECURE: flag(false),
+    SMTP_USER: text(),
+    SMTP_PASSWORD: text(),
+    SMTP_FROM: text(),
+    SMTP_TIMEOUT_MS: int(15000),
+
+    // Analytics export to object storage (services/elt-export.ts), off by default
+    ELT_EXPORT_TARGET: z.enum(['off', 'file', 's3']).default('off'),
+    ELT_EXPORT_DIR: text('./exports/analytics'),
+    ELT_EXPORT_PREFIX: text('pos'),
+    ELT_EXPORT_INTERVAL_MINUTES: int(15, 1, 1440),
+    ELT_EXPORT_BATCH_ROWS: int(50000, 100, 1_000_000),
+    // Changes younger than this wait for the next run, so a transaction still open isn't skipped
+    ELT_EXPORT_SETTLE_SECONDS: int(300, 0, 3600),
+    ELT_S3_ENDPOINT: optionalUrl(),
+    ELT_S3_REGION: text('us-east-1'),
+    ELT_S3_BUCKET: text(),
+    ELT_S3_ACCESS_KEY_ID: text(),
+    ELT_S3_SECRET_ACCESS_KEY: text(),
+
+    REPORT_RATE_LIMIT: int(20),
+    REPORT_MAX_CONCURRENT: int(2),
+    REPORT_QUEUE_TIMEOUT_MS: int(5000),
+    REPORT_CACHE_TTL_MS: int(30000),
+    REDIS_URL: optionalUrl(),
+    RESPONSE_CACHE_TTL_MS: int(60000),
+
+    GOFOOD_API_URL: url('https://api.gobiz.co.id'),
+    GOFOOD_API_TOKEN: text(),
+    GOFOOD_OUTLET_ID: text(),
+    GRABFOOD_API_URL: url('https://partner-api.grab.com/grabfood'),
+    GRABFOOD_API_TOKEN: text(),
+    GRABFOOD_MERCHANT_ID: text(),
+
+    MESSAGING_CHANNEL: z.enum(['whatsapp', 'sms']).default('whatsapp'),
+    MESSAGING_API_URL: optionalUrl(),
+    MESSAGING_API_TOKEN: text(),
+    MESSAGING_SENDER: text(),
+    MESSAGING_TIMEOUT_MS: int(10000),
+
+    WHATSAPP_APP_SECRET: text(),
+    WHATSAPP_VERIFY_TOKEN: text(),
+    GOFOOD_WEBHOOK_SECRET: text(),
+    GRABFOOD_WEBHOOK_SECRET: text(),
+    ACCOUNTING_WEBHOOK_SECRET: text(),
+  })
+  .superRefine((config, ctx) => {
+    if (config.NODE_ENV === 'production' && config.JWT_SECRET === DEV_JWT_SECRET) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ['JWT_SECRET'], message: 'Must be set in production' });
+    }
+    if (config.MAX_UPLOAD_BODY_MB * 1024 < config.MAX_BODY_KB) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ['MAX_UPLOAD_BODY_MB'], message: 'Must not be smaller than MAX_BODY_KB' });
+    }
+    if (config.ELT_EXPORT_TARGET === 's3') {
+      for (const key of ['ELT_S3_ENDPOINT', 'ELT_S3_BUCKET', 'ELT_S3_ACCESS_KEY_ID', 'ELT_S3_SECRET_ACCESS_KEY'] as const) {
+        if (!config[key]) ctx.addIssue({ code: z.ZodIssueCode.custom, path: [key], message: 'Must be set when ELT_EXPORT_TARGET is s3' });
+      }
+    }
+  });
+
+export type Config = z.infer<typeof configSchema>;
+
+// Never printed; the summary only says whether they are set
+export const SECRET_KEYS: ReadonlySet<string> = new Set([
+  'DB_PASSWORD',
+  'JWT_SECRET',
+  'QR_SIGNING_SECRET',
+  'LINK_TOKEN_SECRET',
+  'PAYMENT_GATEWAY_SERVER_KEY',
+  'METRICS_TOKEN',
+  'SENTRY_DSN',
+  'SMTP_PASSWORD',
+  'ELT_S3_SECRET_ACCESS_KEY',
+  'GOFOOD_API_TOKEN',
+  'GRABFOOD_API_TOKEN',
+  'MESSAGING_API_TOKEN',
+  'WHATSAPP_APP_SECRET',
+  'WHATSAPP_VERIFY_TOKEN',
+  'GOFOOD_WEBHOOK_SECRET',
+  'GRABFOOD_WEBHOOK_SECRET',
+  'ACCOUNTING_WEBHOOK_SECRET',
+]);
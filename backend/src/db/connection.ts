@@ -3,6 +3,7 @@ import { Pool } from 'pg';
 import * as schema from './schema.js';
 import * as relations from './relations.js';
 import { env } from '../env.js';
+import { onShutdown } from '../lib/lifecycle.js';
 
 const pool = new Pool({
   host: env.DB_HOST,
@@ -23,6 +24,9 @@ export const db = drizzle(pool, {
 
 export { pool };
 
+// Registered first so it runs last, after everything that still needs the DB.
+onShutdown('database pool', () => pool.end());
+
 export async function testConnection(): Promise<void> {
   const client = await pool.connect();
   try {
@@ -0,0 +1,247 @@
+import fs from 'node:fs';
+import path from 'node:path';
+import crypto from 'node:crypto';
+import type { PoolClient } from 'pg';
+import { pool } from './connection.js';
+import { env } from '../env.js';
+
+// SQL migration runner. Migrations are the files in database/migrations,
+// applied in filename order and tracked in schema_migrations — the same table
+// the db-migrate compose service and scripts/apply-migrations.sh use, so all
+// three stay interchangeable.
+//
+// Down scripts live in a down/ subdirectory under the same filename, so the
+// psql-based runners (which glob *.sql non-recursively) never execute them.
+// `-- migrate:no-transaction` opts a file out of the per-file transaction
+// (needed for CREATE INDEX CONCURRENTLY).
+
+const NO_TX_MARKER = /^--\s*migrate:no-transaction\s*$/m;
+
+// Arbitrary constant shared by all instances so only one runs migrations
+const MIGRATION_LOCK_ID = 74_209_331;
+
+export interface Migration {
+  filename: string;
+  up: string;
+  down: string | null;
+  checksum: string;
+  transactional: boolean;
+}
+
+export interface MigrationStatus {
+  filename: string;
+  applied: boolean;
+  applied_at: string | null;
+  modified: boolean;
+}
+
+export function resolveMigrationsDir(): string {
+  if (env.MIGRATIONS_DIR) return path.resolve(env.MIGRATIONS_DIR);
+
+  // Packaged next to the app in containers, or the repo copy in development
+  const candidates = [path.resolve('migrations'), path.resolve('..', 'database', 'migrations')];
+  return candidates.find((dir) => fs.existsSync(dir)) ?? candidates[candidates.length - 1];
+}
+
+export function loadMigrations(dir = resolveMigrationsDir()): Migration[] {
+  if (!fs.existsSync(dir)) {
+    throw new Error(`Migrations directory not found: ${dir}`);
+  }
+
+  return fs.readdirSync(dir)
+    .filter((f) => f.endsWith('.sql'))
+    .sort()
+    .map((filename) => {
+      const content = fs.readFileSync(path.join(dir, filename), 'utf8');
+      const downPath = path.join(dir, 'down', filename);
+      return {
+        filename,
+        up: content,
+        down: fs.existsSync(downPath) ? fs.readFileSync(downPath, 'utf8') : null,
+        checksum: crypto.createHash('sha256').update(content).digest('hex'),
+        transactional: !NO_TX_MARKER.test(content),
+      };
+    });
+}
+
+async function ensureMigrationsTable(client: PoolClient): Promise<void> {
+  await client.query(`
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      filename TEXT PRIMARY KEY,
+      applied_at TIMESTAMPTZ DEFAULT NOW()
+    )
+  `);
+  await client.query('ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT');
+}
+
+async function appliedMigrations(client: PoolClient): Promise<Map<string, { applied_at: string; checksum: string | null }>> {
+  const res = await client.query('SELECT filename, applied_at, checksum FROM schema_migrations');
+  return new Map(res.rows.map((r) => [r.filename, { applied_at: r.applied_at, checksum: r.checksum }]));
+}
+
+async function withMigrationLock<T>(fn: (client: PoolClient) => Promise<T>): Promise<T> {
+  const client = await pool.connect();
+  try {
+    await client.query('SELECT pg_advisory_lock($1)', [MIGRATION_LOCK_ID]);
+    try {
+      await ensureMigrationsTable(client);
+      return await fn(client);
+    } finally {
+      await client.query('SELECT pg_advisory_unlock($1)', [MIGRATION_LOCK_ID]);
+    }
+  } finally {
+    client.release();
+  }
+}
+
+// ── Status ──────────────────────────────────────────────────────────────────
+
+export async function getMigrationStatus(): Promise<MigrationStatus[]> {
+  const migrations = loadMigrations();
+  const client = await pool.connect();
+  try {
+    await ensureMigrationsTable(client);
+    const applied = await appliedMigrations(client);
+
+    return migrations.map((m) => {
+      const row = applied.get(m.filename);
+      return {
+        filename: m.filename,
+        applied: row !== undefined,
+        applied_at: row?.applied_at ?? null,
+        // Rows written by the shell runner have no checksum to compare
+        modified: row?.checksum ? row.checksum !== m.checksum : false,
+      };
+    });
+  } finally {
+    client.release();
+  }
+}
+
+/**
+ * Schema version summary for health reporting. Never throws: a missing
+ * migrations directory or table is reported rather than failing the check.
+ */
+export async function getSchemaVersion(): Promise<{ version: string | null; applied: number; pending: number; error?: string }> {
+  try {
+    const res = await pool.query(
+      `SELECT filename FROM schema_migrations ORDER BY filename DESC`,
+    );
+    const applied = new Set(res.rows.map((r) => r.filename as string));
+    let pending = 0;
+    try {
+      pending = loadMigrations().filter((m) => !applied.has(m.filename)).length;
+    } catch {
+      // Directory not shipped with this deployment
+    }
+    return { version: res.rows[0]?.filename ?? null, applied: applied.size, pending };
+  } catch (err) {
+    return { version: null, applied: 0, pending: 0, error: (err as Error).message };
+  }
+}
+
+// ── Up ──────────────────────────────────────────────────────────────────────
+
+export async function migrateUp(log: (msg: string) => void = console.log): Promise<string[]> {
+  const migrations = loadMigrations();
+
+  return withMigrationLock(async (client) => {
+    const applied = await appliedMigrations(client);
+    const ran: string[] = [];
+
+    for (const m of migrations) {
+      if (applied.has(m.filename)) continue;
+
+      log(`Applying migration: ${m.filename}`);
+      try {
+        if (m.transactional) await client.query('BEGIN');
+        await client.query(m.up);
+        await client.query(
+          'INSERT INTO schema_migrations (filename, checksum) VALUES ($1, $2)',
+          [m.filename, m.checksum],
+        );
+        if (m.transactional) await client.query('COMMIT');
+      } catch (err) {
+        if (m.transactional) await client.query('ROLLBACK');
+        throw new Error(`Migration ${m.filename} failed: ${(err as Error).message}`);
+      }
+      ran.push(m.filename);
+    }
+
+    return ran;
+  });
+}
+
+// ── Down ────────────────────────────────────────────────────────────────────
+
+export async function migrateDown(steps = 1, log: (msg: string) => void = console.log): Promise<string[]> {
+  const byName = new Map(loadMigrations().map((m) => [m.filename, m]));
+
+  return withMigrationLock(async (client) => {
+    const res = await client.query(
+      'SELECT filename FROM schema_migrations ORDER BY filename DESC LIMIT $1',
+      [steps],
+    );
+    const reverted: string[] = [];
+
+    for (const { filename } of res.rows) {
+      const m = byName.get(filename);
+      if (!m?.down) {
+        throw new Error(`Migration ${filename} has no down script; cannot revert`);
+      }
+
+      log(`Reverting migration: ${filename}`);
+      try {
+        if (m.transactional) await client.query('BEGIN');
+        await client.query(m.down);
+        await client.query('DELETE FROM schema_migrations WHERE filename = $1', [filename]);
+        if (m.transactional) await client.query('COMMIT');
+      } catch (err) {
+        if (m.transactional) await client.query('ROLLBACK');
+        throw new Error(`Reverting ${filename} failed: ${(err as Error).message}`);
+      }
+      reverted.push(filename);
+    }
+
+    return reverted;
+  });
+}
+
+// ── CLI ─────────────────────────────────────────────────────────────────────
+// node dist/index.js migrate [up|down [steps]|status]
+
+export async function runMigrateCommand(args: string[]): Promise<number> {
+  const [action = 'up', arg] = args;
+
+  try {
+    switch (action) {
+      case 'up': {
+        const ran = await migrateUp();
+        console.log(ran.length === 0 ? 'No pending migrations' : `Applied ${ran.length} migration(s)`);
+        return 0;
+      }
+      case 'down': {
+        const steps = Math.max(1, Number(arg) || 1);
+        const reverted = await migrateDown(steps);
+        console.log(`Reverted ${reverted.length} migration(s)`);
+        return 0;
+      }
+      case 'status': {
+        const status = await getMigrationStatus();
+        for (const s of status) {
+          const state = s.applied ? (s.modified ? 'modified' : 'applied ') : 'pending ';
+          console.log(`${state}  ${s.filename}${s.applied_at ? `  (${new Date(s.applied_at).toISOString()})` : ''}`);
+        }
+        const pending = status.filter((s) => !s.applied).length;
+        console.log(`\n${status.length - pending} applied, ${pending} pending`);
+        return 0;
+      }
+      default:
+        console.error(`Unknown migrate action "${action}". Usage: migrate [up|down [steps]|status]`);
+        return 2;
+    }
+  } catch (err) {
+    console.error((err as Error).message);
+    return 1;
+  }
+}
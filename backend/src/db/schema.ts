@@ -5,6 +5,8 @@ import {
   text,
   boolean,
   integer,
+  bigint,
+  smallint,
   decimal,
   timestamp,
   time,
@@ -12,9 +14,18 @@ import {
   jsonb,
   index,
   uniqueIndex,
+  primaryKey,
+  customType,
+  type AnyPgColumn,
 } from 'drizzle-orm/pg-core';
 import { sql } from 'drizzle-orm';
 
+const tsvector = customType<{ data: string }>({
+  dataType() {
+    return 'tsvector';
+  },
+});
+
 // ---------------------------------------------------------------------------
 // users
 // ---------------------------------------------------------------------------
@@ -22,18 +33,33 @@ export const users = pgTable(
   'users',
   {
     id: uuid('id').defaultRandom().primaryKey(),
-    username: varchar('username', { length: 50 }).unique().notNull(),
-    email: varchar('email', { length: 100 }).unique().notNull(),
+    username: varchar('username', { length: 50 }).notNull(),
+    email: varchar('email', { length: 100 }).notNull(),
     passwordHash: varchar('password_hash', { length: 255 }).notNull(),
     firstName: varchar('first_name', { length: 50 }).notNull(),
     lastName: varchar('last_name', { length: 50 }).notNull(),
     role: varchar('role', { length: 20 }).notNull(),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'set null' }),
     isActive: boolean('is_active').default(true),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    deletedAt: timestamp('deleted_at', { withTimezone: true, mode: 'string' }),
+    deletedBy: uuid('deleted_by'),
+    failedLoginAttempts: integer('failed_login_attempts').notNull().default(0),
+    lockedUntil: timestamp('locked_until', { withTimezone: true, mode: 'string' }),
+    tokenVersion: integer('token_version').notNull().default(0),
+    attendancePinHash: varchar('attendance_pin_hash', { length: 255 }),
+    attendancePinFailedAttempts: integer('attendance_pin_failed_attempts').notNull().default(0),
+    attendancePinLockedUntil: timestamp('attendance_pin_locked_until', { withTimezone: true, mode: 'string' }),
   },
   (table) => ({
-    // No additional indexes beyond the unique constraints on username/email
+    // Unique among live users, so a deleted user's username and email can be reused
+    usernameLiveIdx: uniqueIndex('idx_users_username_live')
+      .on(table.username)
+      .where(sql`deleted_at IS NULL`),
+    emailLiveIdx: uniqueIndex('idx_users_email_live')
+      .on(table.email)
+      .where(sql`deleted_at IS NULL`),
   }),
 );
 
@@ -48,9 +74,16 @@ export const categories = pgTable(
     description: text('description'),
     color: varchar('color', { length: 7 }),
     sortOrder: integer('sort_order').default(0),
+    station: varchar('station', { length: 20 }).notNull().default('kitchen'),
+    autoRelease: boolean('auto_release'),
+    kdsColor: varchar('kds_color', { length: 7 }),
+    kdsPriority: integer('kds_priority'),
+    kdsGroup: varchar('kds_group', { length: 50 }),
     isActive: boolean('is_active').default(true),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    deletedAt: timestamp('deleted_at', { withTimezone: true, mode: 'string' }),
+    deletedBy: uuid('deleted_by'),
   },
 );
 
@@ -72,13 +105,31 @@ export const products = pgTable(
     isDeleted: boolean('is_deleted').default(false),
     preparationTime: integer('preparation_time').default(0),
     sortOrder: integer('sort_order').default(0),
+    taxClassId: uuid('tax_class_id').references(() => taxClasses.id, { onDelete: 'set null' }),
+    costOverride: decimal('cost_override', { precision: 10, scale: 2 }),
+    saleUnit: varchar('sale_unit', { length: 10 }).notNull().default('each'),
+    // See services/stock-availability.ts
+    availabilityOverride: boolean('availability_override').notNull().default(false),
+    stockUnavailableAt: timestamp('stock_unavailable_at', { withTimezone: true, mode: 'string' }),
+    // See services/eighty-six.ts
+    eightySixedAt: timestamp('eighty_sixed_at', { withTimezone: true, mode: 'string' }),
+    // See services/dietary.ts
+    allergens: text('allergens').array().notNull().default(sql`'{}'`),
+    dietaryTags: text('dietary_tags').array().notNull().default(sql`'{}'`),
+    spicyLevel: smallint('spicy_level'),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    deletedAt: timestamp('deleted_at', { withTimezone: true, mode: 'string' }),
+    deletedBy: uuid('deleted_by'),
+    // Maintained by Postgres; see services/product-search.ts
+    searchVector: tsvector('search_vector').generatedAlwaysAs(sql`setweight(to_tsvector('simple', COALESCE(name, '')), 'A') || setweight(to_tsvector('simple', COALESCE(sku, '')), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B')`),
   },
   (table) => ({
     categoryIdIdx: index('idx_products_category_id').on(table.categoryId),
+    searchVectorIdx: index('idx_products_search_vector').using('gin', table.searchVector),
     isAvailableIdx: index('idx_products_is_available').on(table.isAvailable),
     isDeletedIdx: index('idx_products_is_deleted').on(table.isDeleted),
+    deletedAtIdx: index('idx_products_deleted_at').on(table.deletedAt),
   }),
 );
 
@@ -89,16 +140,72 @@ export const diningTables = pgTable(
   'dining_tables',
   {
     id: uuid('id').defaultRandom().primaryKey(),
-    tableNumber: varchar('table_number', { length: 20 }).unique().notNull(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
+    tableNumber: varchar('table_number', { length: 20 }).notNull(),
     seatingCapacity: integer('seating_capacity').default(4),
     location: varchar('location', { length: 50 }),
     isOccupied: boolean('is_occupied').default(false),
-    qrCode: varchar('qr_code', { length: 50 }).unique(),
+    // Signed token; see services/table-qr.ts
+    qrCode: varchar('qr_code', { length: 100 }).unique(),
+    qrVersion: integer('qr_version').notNull().default(0),
+    qrRotatedAt: timestamp('qr_rotated_at', { withTimezone: true, mode: 'string' }),
+    sectionId: uuid('section_id').references(() => serverSections.id, { onDelete: 'set null' }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    deletedAt: timestamp('deleted_at', { withTimezone: true, mode: 'string' }),
+    deletedBy: uuid('deleted_by'),
   },
   (table) => ({
     qrCodeIdx: index('idx_dining_tables_qr_code').on(table.qrCode),
+    branchNumberIdx: uniqueIndex('idx_dining_tables_branch_number')
+      .on(table.branchId, table.tableNumber)
+      .where(sql`deleted_at IS NULL`),
+    sectionIdx: index('idx_dining_tables_section').on(table.sectionId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// server_sections
+// ---------------------------------------------------------------------------
+export const serverSections = pgTable(
+  'server_sections',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
+    name: varchar('name', { length: 50 }).notNull(),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    branchNameIdx: uniqueIndex('idx_server_sections_branch_name').on(table.branchId, table.name),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// section_assignments
+// ---------------------------------------------------------------------------
+export const sectionAssignments = pgTable(
+  'section_assignments',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    sectionId: uuid('section_id')
+      .notNull()
+      .references(() => serverSections.id, { onDelete: 'cascade' }),
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    startsAt: timestamp('starts_at', { withTimezone: true, mode: 'string' }).notNull(),
+    endsAt: timestamp('ends_at', { withTimezone: true, mode: 'string' }).notNull(),
+    assignedBy: uuid('assigned_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    userIdx: index('idx_section_assignments_user').on(table.userId, table.startsAt, table.endsAt),
+    sectionIdx: index('idx_section_assignments_section').on(table.sectionId, table.startsAt, table.endsAt),
   }),
 );
 
@@ -112,23 +219,60 @@ export const orders = pgTable(
     orderNumber: varchar('order_number', { length: 20 }).unique().notNull(),
     tableId: uuid('table_id').references(() => diningTables.id, { onDelete: 'set null' }),
     userId: uuid('user_id').references(() => users.id, { onDelete: 'set null' }),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
     customerName: varchar('customer_name', { length: 100 }),
     orderType: varchar('order_type', { length: 20 }).notNull(),
     status: varchar('status', { length: 20 }).notNull().default('pending'),
     subtotal: decimal('subtotal', { precision: 10, scale: 2 }).notNull().default('0'),
     taxAmount: decimal('tax_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    serviceChargeAmount: decimal('service_charge_amount', { precision: 10, scale: 2 }).notNull().default('0'),
     discountAmount: decimal('discount_amount', { precision: 10, scale: 2 }).notNull().default('0'),
     totalAmount: decimal('total_amount', { precision: 10, scale: 2 }).notNull().default('0'),
     notes: text('notes'),
+    scheduledAt: timestamp('scheduled_at', { withTimezone: true, mode: 'string' }),
+    deliveryAddress: text('delivery_address'),
+    deliveryPhone: varchar('delivery_phone', { length: 20 }),
+    deliveryNotes: text('delivery_notes'),
+    deliveryFee: decimal('delivery_fee', { precision: 10, scale: 2 }).notNull().default('0'),
+    depositAmount: decimal('deposit_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    surchargeAmount: decimal('surcharge_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    deliveryStatus: varchar('delivery_status', { length: 20 }),
+    courierId: uuid('courier_id').references(() => users.id, { onDelete: 'set null' }),
+    courierAssignedAt: timestamp('courier_assigned_at', { withTimezone: true, mode: 'string' }),
+    pickedUpAt: timestamp('picked_up_at', { withTimezone: true, mode: 'string' }),
+    deliveredAt: timestamp('delivered_at', { withTimezone: true, mode: 'string' }),
+    displayCurrency: varchar('display_currency', { length: 3 }),
+    exchangeRate: decimal('exchange_rate', { precision: 18, scale: 6 }),
+    receiptLanguage: varchar('receipt_language', { length: 10 }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     servedAt: timestamp('served_at', { withTimezone: true, mode: 'string' }),
     completedAt: timestamp('completed_at', { withTimezone: true, mode: 'string' }),
+    parkedAt: timestamp('parked_at', { withTimezone: true, mode: 'string' }),
+    parkedBy: uuid('parked_by').references(() => users.id, { onDelete: 'set null' }),
+    parkReason: text('park_reason'),
+    parkExpiresAt: timestamp('park_expires_at', { withTimezone: true, mode: 'string' }),
+    tabId: uuid('tab_id').references(() => tabs.id, { onDelete: 'set null' }),
+    serverId: uuid('server_id').references(() => users.id, { onDelete: 'set null' }),
+    covers: integer('covers'),
   },
   (table) => ({
     statusIdx: index('idx_orders_status').on(table.status),
-    createdAtIdx: index('idx_orders_created_at').on(table.createdAt),
+    createdIdIdx: index('idx_orders_created_id').on(table.createdAt, table.id),
+    updatedIdIdx: index('idx_orders_updated_id').on(table.updatedAt, table.id),
     tableIdIdx: index('idx_orders_table_id').on(table.tableId),
+    branchCreatedIdIdx: index('idx_orders_branch_created_id').on(table.branchId, table.createdAt, table.id),
+    statusCreatedIdIdx: index('idx_orders_status_created_id').on(table.status, table.createdAt, table.id),
+    scheduledAtIdx: index('idx_orders_scheduled_at').on(table.scheduledAt).where(sql`status = 'scheduled'`),
+    parkExpiresAtIdx: index('idx_orders_park_expires_at').on(table.parkExpiresAt).where(sql`status = 'parked'`),
+    tabIdx: index('idx_orders_tab').on(table.tabId).where(sql`tab_id IS NOT NULL`),
+    servedAtIdx: index('idx_orders_served_at').on(table.servedAt).where(sql`status = 'served'`),
+    serverCreatedIdx: index('idx_orders_server_created').on(table.serverId, table.createdAt).where(sql`server_id IS NOT NULL`),
+    courierActiveIdx: index('idx_orders_courier_active')
+      .on(table.courierId)
+      .where(sql`delivery_status IN ('assigned', 'picked_up')`),
   }),
 );
 
@@ -141,20 +285,92 @@ export const orderItems = pgTable(
     id: uuid('id').defaultRandom().primaryKey(),
     orderId: uuid('order_id').references(() => orders.id, { onDelete: 'cascade' }),
     productId: uuid('product_id').references(() => products.id, { onDelete: 'cascade' }),
-    quantity: integer('quantity').notNull().default(1),
+    quantity: decimal('quantity', { precision: 10, scale: 3 }).notNull().default('1'),
+    weightGrams: decimal('weight_grams', { precision: 10, scale: 1 }),
+    scaleDevice: varchar('scale_device', { length: 100 }),
     unitPrice: decimal('unit_price', { precision: 10, scale: 2 }).notNull(),
     totalPrice: decimal('total_price', { precision: 10, scale: 2 }).notNull(),
+    taxAmount: decimal('tax_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    serviceChargeAmount: decimal('service_charge_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    taxExempt: boolean('tax_exempt').notNull().default(false),
+    serviceExempt: boolean('service_exempt').notNull().default(false),
+    taxClassId: uuid('tax_class_id').references(() => taxClasses.id, { onDelete: 'set null' }),
+    taxLabel: varchar('tax_label', { length: 100 }),
+    taxRate: decimal('tax_rate', { precision: 5, scale: 2 }),
     specialInstructions: text('special_instructions'),
     status: varchar('status', { length: 20 }).notNull().default('pending'),
+    isRemake: boolean('is_remake').notNull().default(false),
+    priceScheduleId: uuid('price_schedule_id').references(() => priceSchedules.id, { onDelete: 'set null' }),
+    releasedAt: timestamp('released_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    course: smallint('course').notNull().default(1),
+    notePhrases: text('note_phrases').array().notNull().default(sql`'{}'`),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
   (table) => ({
     orderIdIdx: index('idx_order_items_order_id').on(table.orderId),
+    updatedIdIdx: index('idx_order_items_updated_id').on(table.updatedAt, table.id),
     productIdIdx: index('idx_order_items_product_id').on(table.productId),
+    notePhrasesIdx: index('idx_order_items_note_phrases').using('gin', table.notePhrases),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// kitchen_note_phrases
+// ---------------------------------------------------------------------------
+export const kitchenNotePhrases = pgTable(
+  'kitchen_note_phrases',
+  {
+    code: varchar('code', { length: 40 }).primaryKey(),
+    label: varchar('label', { length: 60 }).notNull(),
+    categoryId: uuid('category_id').references(() => categories.id, { onDelete: 'cascade' }),
+    customerVisible: boolean('customer_visible').notNull().default(true),
+    kitchenFlag: varchar('kitchen_flag', { length: 20 }),
+    isActive: boolean('is_active').notNull().default(true),
+    sortOrder: integer('sort_order').notNull().default(0),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    categoryIdx: index('idx_kitchen_note_phrases_category').on(table.categoryId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_courses
+// ---------------------------------------------------------------------------
+export const orderCourses = pgTable(
+  'order_courses',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    course: smallint('course').notNull(),
+    firedAt: timestamp('fired_at', { withTimezone: true, mode: 'string' }),
+    firedBy: uuid('fired_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderCourseIdx: uniqueIndex('idx_order_courses_order_course').on(table.orderId, table.course),
   }),
 );
 
+// ---------------------------------------------------------------------------
+// payment_methods
+// ---------------------------------------------------------------------------
+export const paymentMethods = pgTable('payment_methods', {
+  code: varchar('code', { length: 20 }).primaryKey(),
+  displayName: varchar('display_name', { length: 50 }).notNull(),
+  surchargePercent: decimal('surcharge_percent', { precision: 5, scale: 2 }).notNull().default('0'),
+  isActive: boolean('is_active').notNull().default(true),
+  customerSelectable: boolean('customer_selectable').notNull().default(false),
+  sortOrder: integer('sort_order').notNull().default(0),
+  isSystem: boolean('is_system').notNull().default(false),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
 // ---------------------------------------------------------------------------
 // payments
 // ---------------------------------------------------------------------------
@@ -163,16 +379,32 @@ export const payments = pgTable(
   {
     id: uuid('id').defaultRandom().primaryKey(),
     orderId: uuid('order_id').references(() => orders.id, { onDelete: 'cascade' }),
-    paymentMethod: varchar('payment_method', { length: 20 }).notNull(),
+    paymentMethod: varchar('payment_method', { length: 20 })
+      .notNull()
+      .references(() => paymentMethods.code, { onUpdate: 'cascade' }),
     amount: decimal('amount', { precision: 10, scale: 2 }).notNull(),
+    roundingAdjustment: decimal('rounding_adjustment', { precision: 10, scale: 2 }).notNull().default('0'),
+    surchargeAmount: decimal('surcharge_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    tipAmount: decimal('tip_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    tipRecipientId: uuid('tip_recipient_id').references(() => users.id, { onDelete: 'set null' }),
     referenceNumber: varchar('reference_number', { length: 100 }),
     status: varchar('status', { length: 20 }).notNull().default('pending'),
     processedBy: uuid('processed_by').references(() => users.id, { onDelete: 'set null' }),
     processedAt: timestamp('processed_at', { withTimezone: true, mode: 'string' }),
+    refundOf: uuid('refund_of').references((): AnyPgColumn => payments.id, { onDelete: 'restrict' }),
+    refundReason: varchar('refund_reason', { length: 30 }),
+    refundNotes: text('refund_notes'),
+    approvedBy: uuid('approved_by').references(() => users.id, { onDelete: 'set null' }),
+    batchId: uuid('batch_id').references((): AnyPgColumn => paymentBatches.id, { onDelete: 'set null' }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
   (table) => ({
     orderIdIdx: index('idx_payments_order_id').on(table.orderId),
+    updatedIdIdx: index('idx_payments_updated_id').on(table.updatedAt, table.id),
+    refundOfIdx: index('idx_payments_refund_of').on(table.refundOf),
+    batchIdx: index('idx_payments_batch').on(table.batchId),
+    tipsIdx: index('idx_payments_tips').on(table.processedAt, table.tipRecipientId).where(sql`tip_amount > 0`),
   }),
 );
 
@@ -184,6 +416,9 @@ export const inventory = pgTable(
   {
     id: uuid('id').defaultRandom().primaryKey(),
     productId: uuid('product_id').references(() => products.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
     currentStock: integer('current_stock').notNull().default(0),
     minimumStock: integer('minimum_stock').default(0),
     maximumStock: integer('maximum_stock').default(0),
@@ -194,6 +429,7 @@ export const inventory = pgTable(
   },
   (table) => ({
     productIdIdx: index('idx_inventory_product_id').on(table.productId),
+    branchProductIdx: index('idx_inventory_branch_product').on(table.branchId, table.productId),
   }),
 );
 
@@ -362,6 +598,7 @@ export const ingredientHistory = pgTable(
     notes: text('notes'),
     adjustedBy: uuid('adjusted_by').references(() => users.id, { onDelete: 'set null' }),
     orderId: uuid('order_id').references(() => orders.id, { onDelete: 'set null' }),
+    wasteLogId: uuid('waste_log_id').references(() => wasteLogs.id, { onDelete: 'set null' }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
   (table) => ({
@@ -381,6 +618,9 @@ export const inventoryHistory = pgTable(
     productId: uuid('product_id')
       .notNull()
       .references(() => products.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
     operation: varchar('operation', { length: 20 }).notNull(),
     quantity: integer('quantity').notNull(),
     previousStock: integer('previous_stock').notNull(),
@@ -388,10 +628,13 @@ export const inventoryHistory = pgTable(
     reason: varchar('reason', { length: 50 }).notNull(),
     notes: text('notes'),
     adjustedBy: uuid('adjusted_by').references(() => users.id, { onDelete: 'set null' }),
+    orderId: uuid('order_id').references(() => orders.id, { onDelete: 'set null' }),
+    wasteLogId: uuid('waste_log_id').references(() => wasteLogs.id, { onDelete: 'set null' }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
   (table) => ({
     productIdIdx: index('idx_inventory_history_product_id').on(table.productId),
+    orderIdIdx: index('idx_inventory_history_order_id').on(table.orderId),
     createdAtIdx: index('idx_inventory_history_created_at').on(table.createdAt),
     adjustedByIdx: index('idx_inventory_history_adjusted_by').on(table.adjustedBy),
     operationIdx: index('idx_inventory_history_operation').on(table.operation),
@@ -416,6 +659,11 @@ export const reservations = pgTable(
     notes: text('notes'),
     confirmedBy: uuid('confirmed_by').references(() => users.id),
     confirmedAt: timestamp('confirmed_at', { withTimezone: true, mode: 'string' }),
+    tableId: uuid('table_id').references(() => diningTables.id, { onDelete: 'set null' }),
+    responseToken: varchar('response_token', { length: 64 }),
+    reminderSentAt: timestamp('reminder_sent_at', { withTimezone: true, mode: 'string' }),
+    reminderError: text('reminder_error'),
+    customerRespondedAt: timestamp('customer_responded_at', { withTimezone: true, mode: 'string' }),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
@@ -424,6 +672,13 @@ export const reservations = pgTable(
     statusIdx: index('idx_reservations_status').on(table.status),
     emailIdx: index('idx_reservations_email').on(table.email),
     createdAtIdx: index('idx_reservations_created_at').on(table.createdAt),
+    responseTokenIdx: uniqueIndex('idx_reservations_response_token')
+      .on(table.responseToken)
+      .where(sql`response_token IS NOT NULL`),
+    openIdx: index('idx_reservations_open')
+      .on(table.reservationDate, table.reservationTime)
+      .where(sql`status IN ('pending', 'confirmed')`),
+    phoneIdx: index('idx_reservations_phone').on(table.phone),
   }),
 );
 
@@ -436,6 +691,8 @@ export const notifications = pgTable(
     id: uuid('id').defaultRandom().primaryKey(),
     userId: uuid('user_id').references(() => users.id, { onDelete: 'cascade' }),
     type: varchar('type', { length: 50 }).notNull(),
+    event: varchar('event', { length: 50 }),
+    severity: varchar('severity', { length: 10 }).notNull().default('info'),
     title: varchar('title', { length: 200 }).notNull(),
     message: text('message').notNull(),
     isRead: boolean('is_read').default(false),
@@ -446,6 +703,27 @@ export const notifications = pgTable(
     userIdIdx: index('idx_notifications_user_id').on(table.userId),
     isReadIdx: index('idx_notifications_is_read').on(table.isRead),
     createdAtIdx: index('idx_notifications_created_at').on(table.createdAt),
+    userUnreadSeverityIdx: index('idx_notifications_user_unread_severity')
+      .on(table.userId, table.severity)
+      .where(sql`is_read = false`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// notification_severity_rules
+// ---------------------------------------------------------------------------
+export const notificationSeverityRules = pgTable(
+  'notification_severity_rules',
+  {
+    event: varchar('event', { length: 50 }).notNull(),
+    // '' applies to every role
+    role: varchar('role', { length: 50 }).notNull().default(''),
+    severity: varchar('severity', { length: 10 }).notNull(),
+    updatedBy: uuid('updated_by').references(() => users.id, { onDelete: 'set null' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    pk: primaryKey({ columns: [table.event, table.role] }),
   }),
 );
 
@@ -466,6 +744,8 @@ export const notificationPreferences = pgTable(
     quietHoursStart: time('quiet_hours_start'),
     quietHoursEnd: time('quiet_hours_end'),
     notificationEmail: varchar('notification_email', { length: 100 }),
+    // Fields the user set themselves; applying role defaults skips them
+    overriddenFields: text('overridden_fields').array().notNull().default(sql`'{}'`),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
@@ -474,6 +754,23 @@ export const notificationPreferences = pgTable(
   }),
 );
 
+// ---------------------------------------------------------------------------
+// notification_role_defaults
+// ---------------------------------------------------------------------------
+export const notificationRoleDefaults = pgTable('notification_role_defaults', {
+  role: varchar('role', { length: 20 })
+    .primaryKey()
+    .references(() => roles.name, { onDelete: 'cascade', onUpdate: 'cascade' }),
+  emailEnabled: boolean('email_enabled').notNull().default(true),
+  typesEnabled: jsonb('types_enabled')
+    .notNull()
+    .default('{"order_update": true, "low_stock": true, "payment": true, "system_alert": true, "daily_report": true}'),
+  quietHoursStart: time('quiet_hours_start'),
+  quietHoursEnd: time('quiet_hours_end'),
+  updatedBy: uuid('updated_by').references(() => users.id, { onDelete: 'set null' }),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
 // ---------------------------------------------------------------------------
 // order_notifications
 // ---------------------------------------------------------------------------
@@ -517,14 +814,22 @@ export const satisfactionSurveys = pgTable(
     wouldRecommend: boolean('would_recommend'),
     customerName: varchar('customer_name', { length: 100 }),
     customerEmail: varchar('customer_email', { length: 255 }),
+    status: varchar('status', { length: 20 }).notNull().default('new'),
+    staffNote: text('staff_note'),
+    acknowledgedAt: timestamp('acknowledged_at', { withTimezone: true, mode: 'string' }),
+    acknowledgedBy: uuid('acknowledged_by').references(() => users.id, { onDelete: 'set null' }),
+    repliedAt: timestamp('replied_at', { withTimezone: true, mode: 'string' }),
+    repliedBy: uuid('replied_by').references(() => users.id, { onDelete: 'set null' }),
     submittedAt: timestamp('submitted_at', { withTimezone: true, mode: 'string' }).defaultNow(),
     createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
   },
   (table) => ({
     orderIdIdx: index('idx_satisfaction_surveys_order_id').on(table.orderId),
     overallRatingIdx: index('idx_satisfaction_surveys_overall_rating').on(table.overallRating),
     submittedAtIdx: index('idx_satisfaction_surveys_submitted_at').on(table.submittedAt),
     wouldRecommendIdx: index('idx_satisfaction_surveys_would_recommend').on(table.wouldRecommend),
+    statusIdx: index('idx_satisfaction_surveys_status').on(table.status, table.submittedAt),
   }),
 );
 
@@ -549,3 +854,1547 @@ export const systemSettings = pgTable(
     categoryIdx: index('idx_system_settings_category').on(table.category),
   }),
 );
+
+// ---------------------------------------------------------------------------
+// corporate_accounts
+// ---------------------------------------------------------------------------
+export const corporateAccounts = pgTable('corporate_accounts', {
+  id: uuid('id').defaultRandom().primaryKey(),
+  companyName: varchar('company_name', { length: 150 }).notNull(),
+  contactName: varchar('contact_name', { length: 100 }),
+  contactEmail: varchar('contact_email', { length: 255 }),
+  contactPhone: varchar('contact_phone', { length: 20 }),
+  billingAddress: text('billing_address'),
+  balance: decimal('balance', { precision: 12, scale: 2 }).notNull().default('0'),
+  isActive: boolean('is_active').notNull().default(true),
+  notes: text('notes'),
+  onAccountEnabled: boolean('on_account_enabled').notNull().default(false),
+  creditLimit: decimal('credit_limit', { precision: 12, scale: 2 }).notNull().default('0'),
+  paymentTermsDays: integer('payment_terms_days').notNull().default(30),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// corporate_employees
+// ---------------------------------------------------------------------------
+export const corporateEmployees = pgTable(
+  'corporate_employees',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    accountId: uuid('account_id')
+      .notNull()
+      .references(() => corporateAccounts.id, { onDelete: 'cascade' }),
+    employeeCode: varchar('employee_code', { length: 30 }).unique().notNull(),
+    employeeName: varchar('employee_name', { length: 100 }).notNull(),
+    dailyLimit: decimal('daily_limit', { precision: 12, scale: 2 }),
+    isActive: boolean('is_active').notNull().default(true),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    accountIdx: index('idx_corporate_employees_account').on(table.accountId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// corporate_wallet_transactions
+// ---------------------------------------------------------------------------
+export const corporateWalletTransactions = pgTable(
+  'corporate_wallet_transactions',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    accountId: uuid('account_id')
+      .notNull()
+      .references(() => corporateAccounts.id, { onDelete: 'cascade' }),
+    employeeId: uuid('employee_id').references(() => corporateEmployees.id, { onDelete: 'set null' }),
+    transactionType: varchar('transaction_type', { length: 20 }).notNull(),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    balanceAfter: decimal('balance_after', { precision: 12, scale: 2 }),
+    status: varchar('status', { length: 20 }).notNull().default('completed'),
+    orderId: uuid('order_id').references(() => orders.id, { onDelete: 'set null' }),
+    paymentId: uuid('payment_id').references(() => payments.id, { onDelete: 'set null' }),
+    gatewayReference: varchar('gateway_reference', { length: 100 }).unique(),
+    notes: text('notes'),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    completedAt: timestamp('completed_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    accountCreatedIdx: index('idx_corporate_wallet_tx_account_created').on(table.accountId, table.createdAt),
+    employeeIdx: index('idx_corporate_wallet_tx_employee').on(table.employeeId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// corporate_invoices
+// ---------------------------------------------------------------------------
+export const corporateInvoices = pgTable(
+  'corporate_invoices',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    accountId: uuid('account_id')
+      .notNull()
+      .references(() => corporateAccounts.id, { onDelete: 'restrict' }),
+    invoiceNumber: varchar('invoice_number', { length: 30 }).unique().notNull(),
+    periodStart: date('period_start').notNull(),
+    periodEnd: date('period_end').notNull(),
+    totalAmount: decimal('total_amount', { precision: 12, scale: 2 }).notNull().default('0'),
+    amountPaid: decimal('amount_paid', { precision: 12, scale: 2 }).notNull().default('0'),
+    status: varchar('status', { length: 20 }).notNull().default('issued'),
+    issuedAt: timestamp('issued_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    dueDate: date('due_date').notNull(),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    accountIdx: index('idx_corporate_invoices_account').on(table.accountId, table.periodStart),
+    statusIdx: index('idx_corporate_invoices_status').on(table.status),
+    periodUnique: uniqueIndex('uq_corporate_invoice_period').on(table.accountId, table.periodStart),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// corporate_account_charges
+// ---------------------------------------------------------------------------
+export const corporateAccountCharges = pgTable(
+  'corporate_account_charges',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    accountId: uuid('account_id')
+      .notNull()
+      .references(() => corporateAccounts.id, { onDelete: 'restrict' }),
+    orderId: uuid('order_id').references(() => orders.id, { onDelete: 'set null' }),
+    paymentId: uuid('payment_id').references(() => payments.id, { onDelete: 'set null' }),
+    invoiceId: uuid('invoice_id').references(() => corporateInvoices.id, { onDelete: 'set null' }),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    chargedBy: uuid('charged_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    accountIdx: index('idx_corporate_charges_account').on(table.accountId, table.createdAt),
+    invoiceIdx: index('idx_corporate_charges_invoice').on(table.invoiceId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// corporate_invoice_payments
+// ---------------------------------------------------------------------------
+export const corporateInvoicePayments = pgTable(
+  'corporate_invoice_payments',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    invoiceId: uuid('invoice_id')
+      .notNull()
+      .references(() => corporateInvoices.id, { onDelete: 'cascade' }),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    paymentMethod: varchar('payment_method', { length: 30 }).notNull(),
+    referenceNumber: varchar('reference_number', { length: 100 }),
+    notes: text('notes'),
+    receivedBy: uuid('received_by').references(() => users.id, { onDelete: 'set null' }),
+    receivedAt: timestamp('received_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    invoiceIdx: index('idx_corporate_invoice_payments_invoice').on(table.invoiceId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// pricing_rules
+// ---------------------------------------------------------------------------
+export const pricingRules = pgTable(
+  'pricing_rules',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    name: varchar('name', { length: 100 }).notNull(),
+    description: text('description'),
+    ruleType: varchar('rule_type', { length: 20 }).notNull(),
+    discountType: varchar('discount_type', { length: 20 }).notNull(),
+    discountValue: decimal('discount_value', { precision: 10, scale: 2 }).notNull(),
+    priority: integer('priority').notNull().default(0),
+    stackable: boolean('stackable').notNull().default(false),
+    productIds: uuid('product_ids').array().notNull().default(sql`'{}'`),
+    categoryIds: uuid('category_ids').array().notNull().default(sql`'{}'`),
+    bundleProductIds: uuid('bundle_product_ids').array().notNull().default(sql`'{}'`),
+    minQuantity: integer('min_quantity').notNull().default(1),
+    daysOfWeek: integer('days_of_week').array().notNull().default(sql`'{}'`),
+    startTime: time('start_time'),
+    endTime: time('end_time'),
+    validFrom: timestamp('valid_from', { withTimezone: true, mode: 'string' }),
+    validUntil: timestamp('valid_until', { withTimezone: true, mode: 'string' }),
+    isActive: boolean('is_active').notNull().default(true),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    activeIdx: index('idx_pricing_rules_active').on(table.isActive, table.priority),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_pricing_adjustments
+// ---------------------------------------------------------------------------
+export const orderPricingAdjustments = pgTable(
+  'order_pricing_adjustments',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    ruleId: uuid('rule_id').references(() => pricingRules.id, { onDelete: 'set null' }),
+    ruleName: varchar('rule_name', { length: 100 }).notNull(),
+    ruleType: varchar('rule_type', { length: 20 }).notNull(),
+    amount: decimal('amount', { precision: 10, scale: 2 }).notNull(),
+    details: jsonb('details').notNull().default({}),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_order_pricing_adjustments_order').on(table.orderId),
+    ruleIdx: index('idx_order_pricing_adjustments_rule').on(table.ruleId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// scheduled_job_runs
+// ---------------------------------------------------------------------------
+export const scheduledJobRuns = pgTable(
+  'scheduled_job_runs',
+  {
+    jobName: varchar('job_name', { length: 100 }).notNull(),
+    runDate: date('run_date').notNull(),
+    startedAt: timestamp('started_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    finishedAt: timestamp('finished_at', { withTimezone: true, mode: 'string' }),
+    status: varchar('status', { length: 20 }).notNull().default('running'),
+    error: text('error'),
+  },
+  (table) => ({
+    pk: primaryKey({ columns: [table.jobName, table.runDate] }),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// daily_specials
+// ---------------------------------------------------------------------------
+export const dailySpecials = pgTable(
+  'daily_specials',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    productId: uuid('product_id')
+      .notNull()
+      .unique()
+      .references(() => products.id, { onDelete: 'cascade' }),
+    dailyQuantity: integer('daily_quantity').notNull(),
+    remainingQuantity: integer('remaining_quantity').notNull(),
+    specialDate: date('special_date').notNull(),
+    soldOutAt: timestamp('sold_out_at', { withTimezone: true, mode: 'string' }),
+    isActive: boolean('is_active').notNull().default(true),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    activeIdx: index('idx_daily_specials_active').on(table.isActive),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// daily_special_sales
+// ---------------------------------------------------------------------------
+export const dailySpecialSales = pgTable(
+  'daily_special_sales',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    specialId: uuid('special_id')
+      .notNull()
+      .references(() => dailySpecials.id, { onDelete: 'cascade' }),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    specialDate: date('special_date').notNull(),
+    quantity: integer('quantity').notNull(),
+    releasedAt: timestamp('released_at', { withTimezone: true, mode: 'string' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_daily_special_sales_order').on(table.orderId),
+    specialIdx: index('idx_daily_special_sales_special').on(table.specialId, table.specialDate),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_item_changes
+// ---------------------------------------------------------------------------
+export const orderItemChanges = pgTable(
+  'order_item_changes',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    orderItemId: uuid('order_item_id'),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'set null' }),
+    productName: varchar('product_name', { length: 100 }).notNull(),
+    action: varchar('action', { length: 20 }).notNull(),
+    previousQuantity: decimal('previous_quantity', { precision: 10, scale: 3 }).notNull().default('0'),
+    newQuantity: decimal('new_quantity', { precision: 10, scale: 3 }).notNull().default('0'),
+    unitPrice: decimal('unit_price', { precision: 10, scale: 2 }).notNull(),
+    reason: text('reason'),
+    changedBy: uuid('changed_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_order_item_changes_order').on(table.orderId, table.createdAt),
+    itemIdx: index('idx_order_item_changes_item').on(table.orderItemId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_item_status_history
+// ---------------------------------------------------------------------------
+export const orderItemStatusHistory = pgTable(
+  'order_item_status_history',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    orderItemId: uuid('order_item_id').notNull(),
+    previousStatus: varchar('previous_status', { length: 20 }),
+    newStatus: varchar('new_status', { length: 20 }).notNull(),
+    station: varchar('station', { length: 20 }).notNull(),
+    changedBy: uuid('changed_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    itemIdx: index('idx_order_item_status_history_item').on(table.orderItemId, table.createdAt),
+    orderIdx: index('idx_order_item_status_history_order').on(table.orderId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// logbook_entries
+// ---------------------------------------------------------------------------
+export const logbookEntries = pgTable(
+  'logbook_entries',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    entryDate: date('entry_date').notNull().default(sql`CURRENT_DATE`),
+    shift: varchar('shift', { length: 20 }),
+    category: varchar('category', { length: 20 }).notNull(),
+    title: varchar('title', { length: 200 }).notNull(),
+    body: text('body').notNull(),
+    tags: text('tags').array().notNull().default(sql`'{}'`),
+    priority: varchar('priority', { length: 10 }).notNull().default('normal'),
+    status: varchar('status', { length: 20 }).notNull().default('open'),
+    resolvedAt: timestamp('resolved_at', { withTimezone: true, mode: 'string' }),
+    resolvedBy: uuid('resolved_by').references(() => users.id, { onDelete: 'set null' }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    dateIdx: index('idx_logbook_entries_date').on(table.entryDate, table.createdAt),
+    categoryIdx: index('idx_logbook_entries_category').on(table.category, table.status),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// sales_targets
+// ---------------------------------------------------------------------------
+export const salesTargets = pgTable(
+  'sales_targets',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    periodType: varchar('period_type', { length: 10 }).notNull(),
+    targetAmount: decimal('target_amount', { precision: 12, scale: 2 }).notNull(),
+    effectiveFrom: date('effective_from').notNull().default(sql`CURRENT_DATE`),
+    effectiveUntil: date('effective_until'),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    currentIdx: uniqueIndex('idx_sales_targets_current')
+      .on(table.userId, table.periodType)
+      .where(sql`effective_until IS NULL`),
+    userIdx: index('idx_sales_targets_user').on(table.userId, table.periodType, table.effectiveFrom),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// commission_rules
+// ---------------------------------------------------------------------------
+export const commissionRules = pgTable(
+  'commission_rules',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    name: varchar('name', { length: 100 }).notNull(),
+    ruleType: varchar('rule_type', { length: 30 }).notNull(),
+    categoryId: uuid('category_id').references(() => categories.id, { onDelete: 'cascade' }),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'cascade' }),
+    percentage: decimal('percentage', { precision: 5, scale: 2 }),
+    bonusAmount: decimal('bonus_amount', { precision: 10, scale: 2 }),
+    role: varchar('role', { length: 20 }),
+    effectiveFrom: date('effective_from').notNull().default(sql`CURRENT_DATE`),
+    effectiveUntil: date('effective_until'),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    categoryIdx: index('idx_commission_rules_category').on(table.categoryId).where(sql`category_id IS NOT NULL`),
+    productIdx: index('idx_commission_rules_product').on(table.productId).where(sql`product_id IS NOT NULL`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// customer_flags
+// ---------------------------------------------------------------------------
+export const customerFlags = pgTable(
+  'customer_flags',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    phone: varchar('phone', { length: 20 }).notNull(),
+    customerName: varchar('customer_name', { length: 100 }),
+    reasonType: varchar('reason_type', { length: 20 }).notNull(),
+    reason: text('reason').notNull(),
+    isActive: boolean('is_active').notNull().default(true),
+    flaggedBy: uuid('flagged_by').references(() => users.id, { onDelete: 'set null' }),
+    clearedBy: uuid('cleared_by').references(() => users.id, { onDelete: 'set null' }),
+    clearedAt: timestamp('cleared_at', { withTimezone: true, mode: 'string' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    phoneIdx: index('idx_customer_flags_phone').on(table.phone).where(sql`is_active = true`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// customer_flag_events
+// ---------------------------------------------------------------------------
+export const customerFlagEvents = pgTable(
+  'customer_flag_events',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    flagId: uuid('flag_id')
+      .notNull()
+      .references(() => customerFlags.id, { onDelete: 'cascade' }),
+    action: varchar('action', { length: 20 }).notNull(),
+    reasonType: varchar('reason_type', { length: 20 }),
+    reason: text('reason'),
+    changedBy: uuid('changed_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    flagIdx: index('idx_customer_flag_events_flag').on(table.flagId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// branches
+// ---------------------------------------------------------------------------
+export const branches = pgTable(
+  'branches',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    code: varchar('code', { length: 20 }).unique().notNull(),
+    name: varchar('name', { length: 100 }).notNull(),
+    address: varchar('address', { length: 500 }),
+    phone: varchar('phone', { length: 50 }),
+    latitude: decimal('latitude', { precision: 10, scale: 8 }),
+    longitude: decimal('longitude', { precision: 11, scale: 8 }),
+    isDefault: boolean('is_default').notNull().default(false),
+    isActive: boolean('is_active').notNull().default(true),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    defaultIdx: uniqueIndex('idx_branches_default').on(table.isDefault).where(sql`is_default = true`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// branch_settings
+// ---------------------------------------------------------------------------
+export const branchSettings = pgTable(
+  'branch_settings',
+  {
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id, { onDelete: 'cascade' }),
+    settingKey: varchar('setting_key', { length: 100 })
+      .notNull()
+      .references(() => systemSettings.settingKey, { onDelete: 'cascade' }),
+    settingValue: text('setting_value').notNull(),
+    updatedBy: uuid('updated_by').references(() => users.id, { onDelete: 'set null' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    pk: primaryKey({ columns: [table.branchId, table.settingKey] }),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// payment_links
+// ---------------------------------------------------------------------------
+export const paymentLinks = pgTable(
+  'payment_links',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    status: varchar('status', { length: 20 }).notNull().default('pending'),
+    gatewayReference: varchar('gateway_reference', { length: 100 }).notNull().unique(),
+    paymentUrl: text('payment_url').notNull(),
+    expiresAt: timestamp('expires_at', { withTimezone: true, mode: 'string' }).notNull(),
+    sentTo: varchar('sent_to', { length: 20 }),
+    paymentId: uuid('payment_id').references(() => payments.id, { onDelete: 'set null' }),
+    gatewayTransactionId: varchar('gateway_transaction_id', { length: 100 }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    paidAt: timestamp('paid_at', { withTimezone: true, mode: 'string' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_payment_links_order').on(table.orderId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// tabs
+// ---------------------------------------------------------------------------
+export const tabs = pgTable(
+  'tabs',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
+    customerName: varchar('customer_name', { length: 100 }).notNull(),
+    tableId: uuid('table_id').references(() => diningTables.id, { onDelete: 'set null' }),
+    status: varchar('status', { length: 20 }).notNull().default('pending'),
+    authorizedAmount: decimal('authorized_amount', { precision: 12, scale: 2 }).notNull(),
+    capturedAmount: decimal('captured_amount', { precision: 12, scale: 2 }),
+    gatewayReference: varchar('gateway_reference', { length: 100 }).notNull().unique(),
+    gatewayTransactionId: varchar('gateway_transaction_id', { length: 100 }),
+    paymentUrl: text('payment_url').notNull(),
+    authExpiresAt: timestamp('auth_expires_at', { withTimezone: true, mode: 'string' }),
+    lastError: text('last_error'),
+    openedBy: uuid('opened_by').references(() => users.id, { onDelete: 'set null' }),
+    closedBy: uuid('closed_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    authorizedAt: timestamp('authorized_at', { withTimezone: true, mode: 'string' }),
+    closedAt: timestamp('closed_at', { withTimezone: true, mode: 'string' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    branchStatusIdx: index('idx_tabs_branch_status').on(table.branchId, table.status),
+    authExpiresAtIdx: index('idx_tabs_auth_expires_at').on(table.authExpiresAt).where(sql`status = 'open'`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// roles
+// ---------------------------------------------------------------------------
+export const roles = pgTable('roles', {
+  name: varchar('name', { length: 20 }).primaryKey(),
+  displayName: varchar('display_name', { length: 50 }).notNull(),
+  description: text('description'),
+  isSystem: boolean('is_system').notNull().default(false),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// role_permissions
+// ---------------------------------------------------------------------------
+export const rolePermissions = pgTable(
+  'role_permissions',
+  {
+    role: varchar('role', { length: 20 })
+      .notNull()
+      .references(() => roles.name, { onUpdate: 'cascade', onDelete: 'cascade' }),
+    permission: varchar('permission', { length: 50 }).notNull(),
+    grantedBy: uuid('granted_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    pk: primaryKey({ columns: [table.role, table.permission] }),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// jobs
+// ---------------------------------------------------------------------------
+export const jobs = pgTable(
+  'jobs',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    type: varchar('type', { length: 50 }).notNull(),
+    payload: jsonb('payload').notNull().default({}),
+    status: varchar('status', { length: 20 }).notNull().default('pending'),
+    attempts: integer('attempts').notNull().default(0),
+    maxAttempts: integer('max_attempts').notNull().default(5),
+    runAt: timestamp('run_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    lockedAt: timestamp('locked_at', { withTimezone: true, mode: 'string' }),
+    lockedBy: varchar('locked_by', { length: 100 }),
+    lastError: text('last_error'),
+    errors: jsonb('errors').notNull().default([]),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    completedAt: timestamp('completed_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    statusIdx: index('idx_jobs_status').on(table.status, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// dead_letters
+// ---------------------------------------------------------------------------
+export const deadLetters = pgTable(
+  'dead_letters',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    jobId: uuid('job_id').unique().references(() => jobs.id, { onDelete: 'set null' }),
+    jobType: varchar('job_type', { length: 50 }).notNull(),
+    payload: jsonb('payload').notNull().default({}),
+    maxAttempts: integer('max_attempts').notNull(),
+    attempts: integer('attempts').notNull(),
+    lastError: text('last_error'),
+    errors: jsonb('errors').notNull().default([]),
+    status: varchar('status', { length: 20 }).notNull().default('open'),
+    failureCount: integer('failure_count').notNull().default(1),
+    firstFailedAt: timestamp('first_failed_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    lastFailedAt: timestamp('last_failed_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    replayCount: integer('replay_count').notNull().default(0),
+    replayedAt: timestamp('replayed_at', { withTimezone: true, mode: 'string' }),
+    replayedBy: uuid('replayed_by').references(() => users.id, { onDelete: 'set null' }),
+    discardedAt: timestamp('discarded_at', { withTimezone: true, mode: 'string' }),
+    discardedBy: uuid('discarded_by').references(() => users.id, { onDelete: 'set null' }),
+    note: text('note'),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    statusIdx: index('idx_dead_letters_status').on(table.status, table.lastFailedAt),
+    typeIdx: index('idx_dead_letters_type').on(table.jobType, table.lastFailedAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// email_outbox
+// ---------------------------------------------------------------------------
+export const emailOutbox = pgTable(
+  'email_outbox',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    template: varchar('template', { length: 50 }).notNull(),
+    recipients: text('recipients').array().notNull(),
+    subject: varchar('subject', { length: 255 }).notNull(),
+    body: text('body').notNull(),
+    status: varchar('status', { length: 20 }).notNull().default('queued'),
+    attempts: integer('attempts').notNull().default(0),
+    lastError: text('last_error'),
+    relatedType: varchar('related_type', { length: 50 }),
+    relatedId: uuid('related_id'),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    sentAt: timestamp('sent_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    statusIdx: index('idx_email_outbox_status').on(table.status, table.createdAt),
+    relatedIdx: index('idx_email_outbox_related').on(table.relatedType, table.relatedId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// tax_exemptions
+// ---------------------------------------------------------------------------
+export const taxExemptions = pgTable(
+  'tax_exemptions',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    name: varchar('name', { length: 100 }).notNull(),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'cascade' }),
+    categoryId: uuid('category_id').references(() => categories.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'cascade' }),
+    orderType: varchar('order_type', { length: 20 }),
+    exemptTax: boolean('exempt_tax').notNull().default(false),
+    exemptService: boolean('exempt_service').notNull().default(false),
+    isActive: boolean('is_active').notNull().default(true),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    productIdx: index('idx_tax_exemptions_product').on(table.productId).where(sql`is_active = true`),
+    categoryIdx: index('idx_tax_exemptions_category').on(table.categoryId).where(sql`is_active = true`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// device_print_preferences
+// ---------------------------------------------------------------------------
+export const devicePrintPreferences = pgTable(
+  'device_print_preferences',
+  {
+    deviceId: varchar('device_id', { length: 64 }).primaryKey(),
+    name: varchar('name', { length: 100 }),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'set null' }),
+    receiptPrinter: varchar('receipt_printer', { length: 100 }),
+    autoPrintReceipt: boolean('auto_print_receipt'),
+    receiptCopies: integer('receipt_copies'),
+    kitchenPrinter: varchar('kitchen_printer', { length: 100 }),
+    autoPrintKitchen: boolean('auto_print_kitchen'),
+    kitchenCopies: integer('kitchen_copies'),
+    attendanceClock: boolean('attendance_clock').notNull().default(false),
+    lastSeenAt: timestamp('last_seen_at', { withTimezone: true, mode: 'string' }),
+    lastUserId: uuid('last_user_id').references(() => users.id, { onDelete: 'set null' }),
+    updatedBy: uuid('updated_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    branchIdx: index('idx_device_print_preferences_branch').on(table.branchId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// delivery_platform_items
+// ---------------------------------------------------------------------------
+export const deliveryPlatformItems = pgTable(
+  'delivery_platform_items',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    platform: varchar('platform', { length: 20 }).notNull(),
+    productId: uuid('product_id')
+      .notNull()
+      .references(() => products.id, { onDelete: 'cascade' }),
+    platformItemId: varchar('platform_item_id', { length: 100 }).notNull(),
+    syncedPrice: decimal('synced_price', { precision: 10, scale: 2 }),
+    syncedAvailable: boolean('synced_available'),
+    syncStatus: varchar('sync_status', { length: 20 }).notNull().default('pending'),
+    lastError: text('last_error'),
+    lastSyncedAt: timestamp('last_synced_at', { withTimezone: true, mode: 'string' }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    productIdx: uniqueIndex('idx_delivery_platform_items_product').on(table.productId, table.platform),
+    platformItemIdx: uniqueIndex('idx_delivery_platform_items_platform_item').on(table.platform, table.platformItemId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// webhook_endpoints
+// ---------------------------------------------------------------------------
+export const webhookEndpoints = pgTable('webhook_endpoints', {
+  id: uuid('id').defaultRandom().primaryKey(),
+  name: varchar('name', { length: 100 }).notNull(),
+  url: text('url').notNull(),
+  secret: varchar('secret', { length: 100 }).notNull(),
+  events: text('events').array().notNull(),
+  isActive: boolean('is_active').notNull().default(true),
+  createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// webhook_deliveries
+// ---------------------------------------------------------------------------
+export const webhookDeliveries = pgTable(
+  'webhook_deliveries',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    endpointId: uuid('endpoint_id')
+      .notNull()
+      .references(() => webhookEndpoints.id, { onDelete: 'cascade' }),
+    event: varchar('event', { length: 50 }).notNull(),
+    eventId: uuid('event_id').notNull(),
+    payload: jsonb('payload').notNull(),
+    status: varchar('status', { length: 20 }).notNull().default('queued'),
+    attempts: integer('attempts').notNull().default(0),
+    responseStatus: integer('response_status'),
+    responseBody: text('response_body'),
+    durationMs: integer('duration_ms'),
+    lastError: text('last_error'),
+    deliveredAt: timestamp('delivered_at', { withTimezone: true, mode: 'string' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    endpointIdx: index('idx_webhook_deliveries_endpoint').on(table.endpointId, table.createdAt),
+    statusIdx: index('idx_webhook_deliveries_status').on(table.status, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// inbound_webhook_events
+// ---------------------------------------------------------------------------
+export const inboundWebhookEvents = pgTable(
+  'inbound_webhook_events',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    provider: varchar('provider', { length: 30 }).notNull(),
+    externalId: varchar('external_id', { length: 255 }).notNull(),
+    eventType: varchar('event_type', { length: 100 }).notNull(),
+    payload: jsonb('payload').notNull(),
+    status: varchar('status', { length: 20 }).notNull().default('queued'),
+    attempts: integer('attempts').notNull().default(0),
+    duplicateCount: integer('duplicate_count').notNull().default(0),
+    lastError: text('last_error'),
+    receivedAt: timestamp('received_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    processedAt: timestamp('processed_at', { withTimezone: true, mode: 'string' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    providerEventIdx: uniqueIndex('inbound_webhook_events_provider_external_id_key').on(table.provider, table.externalId),
+    statusIdx: index('idx_inbound_webhook_events_status').on(table.status, table.receivedAt),
+    providerIdx: index('idx_inbound_webhook_events_provider').on(table.provider, table.receivedAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// settings_change_sets
+// ---------------------------------------------------------------------------
+export const settingsChangeSets = pgTable(
+  'settings_change_sets',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    changedBy: uuid('changed_by').references(() => users.id, { onDelete: 'set null' }),
+    rollbackOf: uuid('rollback_of').references((): AnyPgColumn => settingsChangeSets.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    createdIdx: index('idx_settings_change_sets_created').on(table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// settings_changes
+// ---------------------------------------------------------------------------
+export const settingsChanges = pgTable(
+  'settings_changes',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    changeSetId: uuid('change_set_id')
+      .notNull()
+      .references(() => settingsChangeSets.id, { onDelete: 'cascade' }),
+    settingKey: varchar('setting_key', { length: 100 }).notNull(),
+    oldValue: text('old_value'),
+    newValue: text('new_value'),
+    isSecret: boolean('is_secret').notNull().default(false),
+  },
+  (table) => ({
+    setIdx: index('idx_settings_changes_set').on(table.changeSetId),
+    keyIdx: index('idx_settings_changes_key').on(table.settingKey),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// upload_usage
+// ---------------------------------------------------------------------------
+export const uploadUsage = pgTable(
+  'upload_usage',
+  {
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    usageDate: date('usage_date').notNull(),
+    uploads: integer('uploads').notNull().default(0),
+    bytes: bigint('bytes', { mode: 'number' }).notNull().default(0),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    pk: primaryKey({ columns: [table.userId, table.usageDate] }),
+    dateIdx: index('idx_upload_usage_date').on(table.usageDate),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// export_log
+// ---------------------------------------------------------------------------
+export const exportLog = pgTable(
+  'export_log',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    exportType: varchar('export_type', { length: 50 }).notNull(),
+    format: varchar('format', { length: 20 }).notNull(),
+    userId: uuid('user_id').references(() => users.id, { onDelete: 'set null' }),
+    username: varchar('username', { length: 100 }).notNull(),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'set null' }),
+    params: jsonb('params').notNull().default({}),
+    rowCount: integer('row_count').notNull().default(0),
+    ipAddress: varchar('ip_address', { length: 64 }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    createdIdx: index('idx_export_log_created').on(table.createdAt),
+    userIdx: index('idx_export_log_user').on(table.userId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// elt_watermarks / elt_export_runs (analytics export pipeline)
+// ---------------------------------------------------------------------------
+export const eltWatermarks = pgTable('elt_watermarks', {
+  stream: varchar('stream', { length: 40 }).primaryKey(),
+  watermarkAt: timestamp('watermark_at', { withTimezone: true, mode: 'string' }),
+  watermarkId: uuid('watermark_id'),
+  rowsExported: bigint('rows_exported', { mode: 'number' }).notNull().default(0),
+  lastRunAt: timestamp('last_run_at', { withTimezone: true, mode: 'string' }),
+  lastStatus: varchar('last_status', { length: 20 }),
+  lastError: text('last_error'),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+export const eltExportRuns = pgTable(
+  'elt_export_runs',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    stream: varchar('stream', { length: 40 })
+      .notNull()
+      .references(() => eltWatermarks.stream, { onDelete: 'cascade' }),
+    status: varchar('status', { length: 20 }).notNull(),
+    fromAt: timestamp('from_at', { withTimezone: true, mode: 'string' }),
+    fromId: uuid('from_id'),
+    toAt: timestamp('to_at', { withTimezone: true, mode: 'string' }),
+    toId: uuid('to_id'),
+    rowCount: integer('row_count').notNull().default(0),
+    files: jsonb('files').notNull().default([]),
+    error: text('error'),
+    startedAt: timestamp('started_at', { withTimezone: true, mode: 'string' }).notNull(),
+    finishedAt: timestamp('finished_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    streamStartedIdx: index('idx_elt_export_runs_stream_started').on(table.stream, table.startedAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// staff_shifts
+// ---------------------------------------------------------------------------
+export const staffShifts = pgTable(
+  'staff_shifts',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
+    startsAt: timestamp('starts_at', { withTimezone: true, mode: 'string' }).notNull(),
+    endsAt: timestamp('ends_at', { withTimezone: true, mode: 'string' }).notNull(),
+    breakMinutes: integer('break_minutes').notNull().default(0),
+    note: varchar('note', { length: 200 }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    userIdx: index('idx_staff_shifts_user').on(table.userId, table.startsAt, table.endsAt),
+    branchIdx: index('idx_staff_shifts_branch').on(table.branchId, table.startsAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// attendance
+// ---------------------------------------------------------------------------
+export const attendance = pgTable(
+  'attendance',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id').references(() => branches.id),
+    shiftId: uuid('shift_id').references(() => staffShifts.id, { onDelete: 'set null' }),
+    clockInAt: timestamp('clock_in_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    clockOutAt: timestamp('clock_out_at', { withTimezone: true, mode: 'string' }),
+    clockInDeviceId: varchar('clock_in_device_id', { length: 64 }),
+    clockOutDeviceId: varchar('clock_out_device_id', { length: 64 }),
+    note: varchar('note', { length: 500 }),
+    adjustedBy: uuid('adjusted_by').references(() => users.id, { onDelete: 'set null' }),
+    adjustedAt: timestamp('adjusted_at', { withTimezone: true, mode: 'string' }),
+    adjustReason: varchar('adjust_reason', { length: 500 }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    openIdx: uniqueIndex('idx_attendance_open').on(table.userId).where(sql`clock_out_at IS NULL`),
+    userClockInIdx: index('idx_attendance_user_clock_in').on(table.userId, table.clockInAt),
+    branchClockInIdx: index('idx_attendance_branch_clock_in').on(table.branchId, table.clockInAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// password_reset_tokens
+// ---------------------------------------------------------------------------
+export const passwordResetTokens = pgTable(
+  'password_reset_tokens',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    userId: uuid('user_id')
+      .notNull()
+      .references(() => users.id, { onDelete: 'cascade' }),
+    tokenHash: varchar('token_hash', { length: 64 }).unique().notNull(),
+    expiresAt: timestamp('expires_at', { withTimezone: true, mode: 'string' }).notNull(),
+    usedAt: timestamp('used_at', { withTimezone: true, mode: 'string' }),
+    requestedIp: varchar('requested_ip', { length: 64 }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    userIdx: index('idx_password_reset_tokens_user').on(table.userId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// payment_batches
+// ---------------------------------------------------------------------------
+export const paymentBatches = pgTable(
+  'payment_batches',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'set null' }),
+    paymentMethod: varchar('payment_method', { length: 30 })
+      .notNull()
+      .references(() => paymentMethods.code, { onUpdate: 'cascade' }),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    roundingAdjustment: decimal('rounding_adjustment', { precision: 12, scale: 2 }).notNull().default('0'),
+    referenceNumber: varchar('reference_number', { length: 100 }),
+    orderCount: integer('order_count').notNull(),
+    processedBy: uuid('processed_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    createdIdx: index('idx_payment_batches_created').on(table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// status_incidents
+// ---------------------------------------------------------------------------
+export const statusIncidents = pgTable(
+  'status_incidents',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    title: varchar('title', { length: 150 }).notNull(),
+    message: text('message'),
+    severity: varchar('severity', { length: 20 }).notNull(),
+    component: varchar('component', { length: 30 }).notNull(),
+    startedAt: timestamp('started_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    resolvedAt: timestamp('resolved_at', { withTimezone: true, mode: 'string' }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    resolvedBy: uuid('resolved_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    openIdx: index('idx_status_incidents_open').on(table.startedAt).where(sql`resolved_at IS NULL`),
+    resolvedIdx: index('idx_status_incidents_resolved').on(table.resolvedAt).where(sql`resolved_at IS NOT NULL`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// container_types
+// ---------------------------------------------------------------------------
+export const containerTypes = pgTable('container_types', {
+  id: uuid('id').defaultRandom().primaryKey(),
+  name: varchar('name', { length: 100 }).unique().notNull(),
+  depositAmount: decimal('deposit_amount', { precision: 10, scale: 2 }).notNull(),
+  isActive: boolean('is_active').notNull().default(true),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// order_container_deposits
+// ---------------------------------------------------------------------------
+export const orderContainerDeposits = pgTable(
+  'order_container_deposits',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    containerTypeId: uuid('container_type_id')
+      .notNull()
+      .references(() => containerTypes.id, { onDelete: 'restrict' }),
+    quantity: integer('quantity').notNull(),
+    unitDeposit: decimal('unit_deposit', { precision: 10, scale: 2 }).notNull(),
+    returnedQuantity: integer('returned_quantity').notNull().default(0),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderTypeIdx: uniqueIndex('idx_order_container_deposits_order_type').on(table.orderId, table.containerTypeId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// container_returns
+// ---------------------------------------------------------------------------
+export const containerReturns = pgTable(
+  'container_returns',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderDepositId: uuid('order_deposit_id')
+      .notNull()
+      .references(() => orderContainerDeposits.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id),
+    quantity: integer('quantity').notNull(),
+    refundAmount: decimal('refund_amount', { precision: 10, scale: 2 }).notNull(),
+    refundMethod: varchar('refund_method', { length: 20 }).notNull(),
+    notes: text('notes'),
+    returnedBy: uuid('returned_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    createdIdx: index('idx_container_returns_created').on(table.createdAt),
+    depositIdx: index('idx_container_returns_deposit').on(table.orderDepositId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_source_details
+// ---------------------------------------------------------------------------
+export const orderSourceDetails = pgTable(
+  'order_source_details',
+  {
+    orderId: uuid('order_id')
+      .primaryKey()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    ipAddress: varchar('ip_address', { length: 64 }),
+    userAgent: varchar('user_agent', { length: 500 }),
+    deviceFingerprint: varchar('device_fingerprint', { length: 128 }),
+    latitude: decimal('latitude', { precision: 7, scale: 3 }),
+    longitude: decimal('longitude', { precision: 8, scale: 3 }),
+    locationAccuracyM: integer('location_accuracy_m'),
+    distanceM: integer('distance_m'),
+    riskFlags: text('risk_flags').array().notNull().default(sql`'{}'`),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    fingerprintIdx: index('idx_order_source_details_fingerprint')
+      .on(table.deviceFingerprint, table.createdAt)
+      .where(sql`device_fingerprint IS NOT NULL`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// tax_classes
+// ---------------------------------------------------------------------------
+export const taxClasses = pgTable('tax_classes', {
+  id: uuid('id').defaultRandom().primaryKey(),
+  name: varchar('name', { length: 100 }).notNull().unique(),
+  rate: decimal('rate', { precision: 5, scale: 2 }).notNull(),
+  description: text('description'),
+  isActive: boolean('is_active').notNull().default(true),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// gateway_refunds
+// ---------------------------------------------------------------------------
+export const gatewayRefunds = pgTable(
+  'gateway_refunds',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    refundPaymentId: uuid('refund_payment_id')
+      .notNull()
+      .unique()
+      .references(() => payments.id, { onDelete: 'cascade' }),
+    paymentLinkId: uuid('payment_link_id')
+      .notNull()
+      .references(() => paymentLinks.id, { onDelete: 'cascade' }),
+    gatewayOrderId: varchar('gateway_order_id', { length: 100 }).notNull(),
+    refundKey: varchar('refund_key', { length: 100 }).notNull().unique(),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    reason: varchar('reason', { length: 255 }).notNull(),
+    status: varchar('status', { length: 20 }).notNull().default('queued'),
+    attempts: integer('attempts').notNull().default(0),
+    gatewayStatusCode: varchar('gateway_status_code', { length: 10 }),
+    lastError: text('last_error'),
+    submittedAt: timestamp('submitted_at', { withTimezone: true, mode: 'string' }),
+    confirmedAt: timestamp('confirmed_at', { withTimezone: true, mode: 'string' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_gateway_refunds_order').on(table.gatewayOrderId),
+    openIdx: index('idx_gateway_refunds_open').on(table.createdAt).where(sql`status IN ('queued', 'submitted')`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_sla_breaches
+// ---------------------------------------------------------------------------
+export const orderSlaBreaches = pgTable(
+  'order_sla_breaches',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    stage: varchar('stage', { length: 20 }).notNull(),
+    daypart: varchar('daypart', { length: 50 }),
+    targetMinutes: integer('target_minutes').notNull(),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderStageIdx: uniqueIndex('order_sla_breaches_order_id_stage_key').on(table.orderId, table.stage),
+    createdIdx: index('idx_order_sla_breaches_created').on(table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// currencies
+// ---------------------------------------------------------------------------
+export const currencies = pgTable('currencies', {
+  id: uuid('id').defaultRandom().primaryKey(),
+  code: varchar('code', { length: 3 }).notNull().unique(),
+  name: varchar('name', { length: 100 }).notNull(),
+  symbol: varchar('symbol', { length: 10 }).notNull(),
+  rateToIdr: decimal('rate_to_idr', { precision: 18, scale: 6 }).notNull(),
+  decimalPlaces: integer('decimal_places').notNull().default(2),
+  isActive: boolean('is_active').notNull().default(false),
+  rateUpdatedAt: timestamp('rate_updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// cogs_adjustments
+// ---------------------------------------------------------------------------
+export const cogsAdjustments = pgTable(
+  'cogs_adjustments',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id, { onDelete: 'cascade' }),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'set null' }),
+    adjustmentDate: date('adjustment_date', { mode: 'string' }).notNull(),
+    amount: decimal('amount', { precision: 12, scale: 2 }).notNull(),
+    reason: varchar('reason', { length: 255 }).notNull(),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    dateIdx: index('idx_cogs_adjustments_date').on(table.adjustmentDate),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// stock_takes
+// ---------------------------------------------------------------------------
+export const stockTakes = pgTable(
+  'stock_takes',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id, { onDelete: 'cascade' }),
+    scope: varchar('scope', { length: 20 }).notNull().default('all'),
+    status: varchar('status', { length: 20 }).notNull().default('open'),
+    notes: text('notes'),
+    startedBy: uuid('started_by').references(() => users.id, { onDelete: 'set null' }),
+    startedAt: timestamp('started_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    postedBy: uuid('posted_by').references(() => users.id, { onDelete: 'set null' }),
+    postedAt: timestamp('posted_at', { withTimezone: true, mode: 'string' }),
+    cancelledAt: timestamp('cancelled_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    openBranchIdx: uniqueIndex('stock_takes_open_branch_key').on(table.branchId).where(sql`status = 'open'`),
+    openIngredientsIdx: uniqueIndex('stock_takes_open_ingredients_key')
+      .on(sql`(true)`)
+      .where(sql`status = 'open' AND scope <> 'products'`),
+    startedAtIdx: index('idx_stock_takes_started_at').on(table.startedAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// stock_take_lines
+// ---------------------------------------------------------------------------
+export const stockTakeLines = pgTable(
+  'stock_take_lines',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    stockTakeId: uuid('stock_take_id')
+      .notNull()
+      .references(() => stockTakes.id, { onDelete: 'cascade' }),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'cascade' }),
+    ingredientId: uuid('ingredient_id').references(() => ingredients.id, { onDelete: 'cascade' }),
+    expectedQuantity: decimal('expected_quantity', { precision: 10, scale: 2 }).notNull(),
+    countedQuantity: decimal('counted_quantity', { precision: 10, scale: 2 }),
+    unitCost: decimal('unit_cost', { precision: 10, scale: 2 }),
+    postedAdjustment: decimal('posted_adjustment', { precision: 10, scale: 2 }),
+    countedBy: uuid('counted_by').references(() => users.id, { onDelete: 'set null' }),
+    countedAt: timestamp('counted_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    productIdx: uniqueIndex('stock_take_lines_stock_take_id_product_id_key').on(table.stockTakeId, table.productId),
+    ingredientIdx: uniqueIndex('stock_take_lines_stock_take_id_ingredient_id_key').on(table.stockTakeId, table.ingredientId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// ingredient_batches
+// ---------------------------------------------------------------------------
+export const ingredientBatches = pgTable(
+  'ingredient_batches',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    ingredientId: uuid('ingredient_id')
+      .notNull()
+      .references(() => ingredients.id, { onDelete: 'cascade' }),
+    batchCode: varchar('batch_code', { length: 50 }),
+    quantityReceived: decimal('quantity_received', { precision: 10, scale: 2 }).notNull(),
+    quantityRemaining: decimal('quantity_remaining', { precision: 10, scale: 2 }).notNull(),
+    expiryDate: date('expiry_date'),
+    receivedAt: timestamp('received_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    receivedBy: uuid('received_by').references(() => users.id, { onDelete: 'set null' }),
+    expiryNotifiedAt: timestamp('expiry_notified_at', { withTimezone: true, mode: 'string' }),
+  },
+  (table) => ({
+    openIdx: index('idx_ingredient_batches_open')
+      .on(table.ingredientId, table.expiryDate)
+      .where(sql`quantity_remaining > 0`),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// ingredient_batch_usage
+// ---------------------------------------------------------------------------
+export const ingredientBatchUsage = pgTable(
+  'ingredient_batch_usage',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    batchId: uuid('batch_id')
+      .notNull()
+      .references(() => ingredientBatches.id, { onDelete: 'cascade' }),
+    orderId: uuid('order_id').references(() => orders.id, { onDelete: 'set null' }),
+    quantity: decimal('quantity', { precision: 10, scale: 2 }).notNull(),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_ingredient_batch_usage_order').on(table.orderId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// customer_receipt_preferences
+// ---------------------------------------------------------------------------
+export const customerReceiptPreferences = pgTable('customer_receipt_preferences', {
+  phone: varchar('phone', { length: 20 }).primaryKey(),
+  receiptLanguage: varchar('receipt_language', { length: 10 }).notNull(),
+  updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+});
+
+// ---------------------------------------------------------------------------
+// waste_logs
+// ---------------------------------------------------------------------------
+export const wasteLogs = pgTable(
+  'waste_logs',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    branchId: uuid('branch_id')
+      .notNull()
+      .references(() => branches.id, { onDelete: 'cascade' }),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'set null' }),
+    ingredientId: uuid('ingredient_id').references(() => ingredients.id, { onDelete: 'set null' }),
+    quantity: decimal('quantity', { precision: 10, scale: 3 }).notNull(),
+    reasonType: varchar('reason_type', { length: 20 }).notNull(),
+    reason: text('reason'),
+    photoUrl: varchar('photo_url', { length: 500 }),
+    unitCost: decimal('unit_cost', { precision: 10, scale: 2 }),
+    totalCost: decimal('total_cost', { precision: 12, scale: 2 }),
+    loggedBy: uuid('logged_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    branchCreatedIdx: index('idx_waste_logs_branch_created').on(table.branchId, table.createdAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// surcharges
+// ---------------------------------------------------------------------------
+export const surcharges = pgTable(
+  'surcharges',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    name: varchar('name', { length: 100 }).notNull(),
+    description: text('description'),
+    surchargeType: varchar('surcharge_type', { length: 20 }).notNull(),
+    value: decimal('value', { precision: 10, scale: 2 }).notNull(),
+    startsAt: timestamp('starts_at', { withTimezone: true, mode: 'string' }).notNull(),
+    endsAt: timestamp('ends_at', { withTimezone: true, mode: 'string' }).notNull(),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'cascade' }),
+    orderTypes: text('order_types').array().notNull().default(sql`'{}'`),
+    isActive: boolean('is_active').notNull().default(true),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    windowIdx: index('idx_surcharges_window').on(table.startsAt, table.endsAt),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_surcharges
+// ---------------------------------------------------------------------------
+export const orderSurcharges = pgTable(
+  'order_surcharges',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    surchargeId: uuid('surcharge_id').references(() => surcharges.id, { onDelete: 'set null' }),
+    name: varchar('name', { length: 100 }).notNull(),
+    amount: decimal('amount', { precision: 10, scale: 2 }).notNull(),
+    taxAmount: decimal('tax_amount', { precision: 10, scale: 2 }).notNull().default('0'),
+    taxRate: decimal('tax_rate', { precision: 5, scale: 2 }).notNull().default('0'),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    orderIdx: index('idx_order_surcharges_order').on(table.orderId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// eighty_six_events
+// ---------------------------------------------------------------------------
+export const eightySixEvents = pgTable(
+  'eighty_six_events',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    productId: uuid('product_id')
+      .notNull()
+      .references(() => products.id, { onDelete: 'cascade' }),
+    businessDate: date('business_date').notNull(),
+    reason: text('reason'),
+    eightySixedBy: uuid('eighty_sixed_by').references(() => users.id, { onDelete: 'set null' }),
+    eightySixedAt: timestamp('eighty_sixed_at', { withTimezone: true, mode: 'string' }).notNull().defaultNow(),
+    restoredAt: timestamp('restored_at', { withTimezone: true, mode: 'string' }),
+    restoredBy: uuid('restored_by').references(() => users.id, { onDelete: 'set null' }),
+  },
+  (table) => ({
+    dateIdx: index('idx_eighty_six_events_date').on(table.businessDate),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// order_item_remakes
+// ---------------------------------------------------------------------------
+export const orderItemRemakes = pgTable(
+  'order_item_remakes',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    orderId: uuid('order_id')
+      .notNull()
+      .references(() => orders.id, { onDelete: 'cascade' }),
+    branchId: uuid('branch_id').references(() => branches.id, { onDelete: 'set null' }),
+    orderItemId: uuid('order_item_id').references(() => orderItems.id, { onDelete: 'set null' }),
+    remakeItemId: uuid('remake_item_id').references(() => orderItems.id, { onDelete: 'set null' }),
+    productId: uuid('product_id').references(() => products.id, { onDelete: 'set null' }),
+    productName: varchar('product_name', { length: 255 }).notNull(),
+    quantity: decimal('quantity', { precision: 10, scale: 3 }).notNull(),
+    reasonType: varchar('reason_type', { length: 20 }).notNull(),
+    reason: text('reason'),
+    station: varchar('station', { length: 20 }).notNull().default('kitchen'),
+    unitCost: decimal('unit_cost', { precision: 10, scale: 2 }),
+    totalCost: decimal('total_cost', { precision: 12, scale: 2 }),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    createdIdx: index('idx_order_item_remakes_created').on(table.createdAt),
+    orderIdx: index('idx_order_item_remakes_order').on(table.orderId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// product_bundle_components
+// ---------------------------------------------------------------------------
+export const productBundleComponents = pgTable(
+  'product_bundle_components',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    bundleProductId: uuid('bundle_product_id')
+      .notNull()
+      .references(() => products.id, { onDelete: 'cascade' }),
+    componentProductId: uuid('component_product_id')
+      .notNull()
+      .references(() => products.id, { onDelete: 'cascade' }),
+    quantity: integer('quantity').notNull().default(1),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    bundleComponentUniqueIdx: uniqueIndex('product_bundle_components_bundle_product_id_component_product_id_key').on(
+      table.bundleProductId,
+      table.componentProductId,
+    ),
+    componentIdx: index('idx_product_bundle_components_component').on(table.componentProductId),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// price_schedules
+// ---------------------------------------------------------------------------
+export const priceSchedules = pgTable(
+  'price_schedules',
+  {
+    id: uuid('id').defaultRandom().primaryKey(),
+    name: varchar('name', { length: 100 }).notNull(),
+    description: text('description'),
+    productIds: uuid('product_ids').array().notNull().default(sql`'{}'`),
+    categoryIds: uuid('category_ids').array().notNull().default(sql`'{}'`),
+    priceType: varchar('price_type', { length: 20 }).notNull(),
+    value: decimal('value', { precision: 10, scale: 2 }).notNull(),
+    daysOfWeek: integer('days_of_week').array().notNull().default(sql`'{}'`),
+    startTime: time('start_time').notNull(),
+    endTime: time('end_time').notNull(),
+    isActive: boolean('is_active').notNull().default(true),
+    createdBy: uuid('created_by').references(() => users.id, { onDelete: 'set null' }),
+    createdAt: timestamp('created_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+  (table) => ({
+    activeIdx: index('idx_price_schedules_active').on(table.isActive),
+  }),
+);
+
+// ---------------------------------------------------------------------------
+// kitchen_station_display
+// ---------------------------------------------------------------------------
+export const kitchenStationDisplay = pgTable(
+  'kitchen_station_display',
+  {
+    station: varchar('station', { length: 20 }).primaryKey(),
+    color: varchar('color', { length: 7 }),
+    sortPriority: integer('sort_priority').notNull().default(0),
+    groupItemsBy: varchar('group_items_by', { length: 20 }).notNull().default('category'),
+    updatedBy: uuid('updated_by').references(() => users.id, { onDelete: 'set null' }),
+    updatedAt: timestamp('updated_at', { withTimezone: true, mode: 'string' }).defaultNow(),
+  },
+);
@@ -0,0 +1,81 @@
+import type { PoolClient } from 'pg';
+import { pool } from './connection.js';
+import { dbTransactionRetriesTotal } from '../lib/metrics.js';
+
+// Transactions. withTransaction runs `fn` on one pooled client between BEGIN
+// and COMMIT and rolls back when it throws or returns a failure (any
+// { ok: false }, the shape services already return), so a validation
+// error halfway through leaves nothing behind. Serialization
+// failures and deadlocks are retried from the start with a fresh
+// transaction, so `fn` must only touch the database: send notifications,
+// call the gateway and the like after it returns.
+
+export type IsolationLevel = 'read committed' | 'repeatable read' | 'serializable';
+
+export interface TransactionOptions {
+  isolation?: IsolationLevel;
+  /** Attempts in total, including the first */
+  attempts?: number;
+}
+
+// SQLSTATEs that mean "nothing wrong with the statements, run them again"
+const RETRYABLE: Record<string, string> = {
+  '40001': 'serialization',
+  '40P01': 'deadlock',
+};
+
+const DEFAULT_ATTEMPTS = 3;
+
+export interface TxFailure {
+  ok: false;
+  failure: { message: string; code: string; status: 400 | 403 | 404 | 409 | 502 | 503 };
+}
+
+/** Return from a transaction callback to roll back and report the error. */
+export function txFailure(message: string, code: string, status: TxFailure['failure']['status']): TxFailure {
+  return { ok: false, failure: { message, code, status } };
+}
+
+function isFailure(value: unknown): boolean {
+  return typeof value === 'object' && value !== null && (value as { ok?: unknown }).ok === false;
+}
+
+function retryReason(err: unknown): string | undefined {
+  const code = (err as { code?: unknown } | null)?.code;
+  return typeof code === 'string' ? RETRYABLE[code] : undefined;
+}
+
+// ── WithTransaction ─────────────────────────────────────────────────────────
+
+export async function withTransaction<T>(
+  fn: (client: PoolClient) => Promise<T>,
+  options: TransactionOptions = {},
+): Promise<T> {
+  const attempts = Math.max(1, options.attempts ?? DEFAULT_ATTEMPTS);
+  const begin = options.isolation ? `BEGIN ISOLATION LEVEL ${options.isolation.toUpperCase()}` : 'BEGIN';
+
+  for (let attempt = 1; ; attempt++) {
+    const client = await pool.connect();
+    let broken: Error | undefined;
+    try {
+      await client.query(begin);
+      const result = await fn(client);
+      await client.query(isFailure(result) ? 'ROLLBACK' : 'COMMIT');
+      return result;
+    } catch (err) {
+      try {
+        await client.query('ROLLBACK');
+      } catch (rollbackErr) {
+        // The connection is unusable; don't hand it back to the pool
+        broken = rollbackErr as Error;
+      }
+      const reason = retryReason(err);
+      if (!reason || attempt >= attempts) throw err;
+      dbTransactionRetriesTotal.inc({ reason });
+    } finally {
+      client.release(broken);
+    }
+    // Back off a little, with jitter, so the transactions that collided don't collide again
+    await new Promise((resolve) => setTimeout(resolve, 10 * 2 ** attempt + Math.random() * 25));
+  }
+}
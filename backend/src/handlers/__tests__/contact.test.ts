@@ -0,0 +1,205 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { Hono } from 'hono';
+import { db, pool } from '../../db/connection.js';
+import { emailConfigured } from '../../services/email.js';
+import {
+  getNewContactsCount, getContactSubmissions, getContactSubmission, replyToContact, updateContactStatus,
+  deleteContactSubmission,
+} from '../contact.js';
+
+vi.mock('../../db/connection.js', () => ({
+  db: { execute: vi.fn() },
+  pool: { query: vi.fn(), connect: vi.fn() },
+}));
+
+vi.mock('../../services/email.js', () => ({
+  emailConfigured: vi.fn(),
+  loadRestaurantName: vi.fn(),
+  queueEmail: vi.fn(),
+}));
+
+const CONTACT_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+
+const submission = {
+  id: CONTACT_ID,
+  name: 'Budi',
+  email: 'budi@example.com',
+  phone: null,
+  subject: 'Reservation',
+  message: 'Table for four?',
+  status: 'new',
+  created_at: '2026-10-14T08:00:00Z',
+  updated_at: '2026-10-14T08:00:00Z',
+};
+
+const app = new Hono();
+app.get('/contacts/count', getNewContactsCount);
+app.get('/contacts', getContactSubmissions);
+app.get('/contacts/:id', getContactSubmission);
+app.post('/contacts/:id/reply', replyToContact);
+app.put('/contacts/:id/status', updateContactStatus);
+app.delete('/contacts/:id', deleteContactSubmission);
+
+const query = pool.query as unknown as ReturnType<typeof vi.fn>;
+const execute = db.execute as unknown as ReturnType<typeof vi.fn>;
+const client = { query: vi.fn(), release: vi.fn() };
+
+function send(method: string, path: string, body: unknown) {
+  return app.request(path, {
+    method,
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  });
+}
+
+beforeEach(() => {
+  vi.clearAllMocks();
+  vi.mocked(pool.connect).mockResolvedValue(client as never);
+  client.query.mockResolvedValue({ rows: [], rowCount: 0 });
+});
+
+describe('GET /contacts/count', () => {
+  it('wraps the count in the success envelope', async () => {
+    execute.mockResolvedValueOnce({ rows: [{ count: '3' }] });
+
+    const res = await app.request('/contacts/count');
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'New contacts count retrieved successfully',
+      data: { new_contacts: 3 },
+    });
+  });
+
+  it('reports a database failure in the error envelope', async () => {
+    execute.mockRejectedValueOnce(new Error('connection refused'));
+
+    const res = await app.request('/contacts/count');
+    expect(res.status).toBe(500);
+    expect(await res.json()).toEqual({
+      success: false,
+      message: 'Failed to fetch new contacts count',
+      error: 'connection refused',
+    });
+  });
+});
+
+describe('GET /contacts', () => {
+  it('returns the submissions as data', async () => {
+    query.mockResolvedValueOnce({ rows: [submission] });
+
+    const res = await app.request('/contacts?status=new');
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Contact submissions retrieved successfully',
+      data: [submission],
+    });
+    expect(query.mock.calls[0][1]).toEqual(['new']);
+  });
+
+  it('answers an unknown status filter with a field error', async () => {
+    const res = await app.request('/contacts?status=archived');
+    expect(res.status).toBe(400);
+    const body = await res.json();
+    expect(body).toMatchObject({ success: false, error: 'invalid_status' });
+    expect(body.errors).toEqual([{ field: 'status', code: 'invalid_status', message: body.message }]);
+    expect(query).not.toHaveBeenCalled();
+  });
+
+  it('answers a date that is not a date with invalid_contact_dates', async () => {
+    const res = await app.request('/contacts?start_date=yesterday');
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'invalid_contact_dates' });
+  });
+});
+
+describe('GET /contacts/:id', () => {
+  it('includes the replies sent', async () => {
+    query
+      .mockResolvedValueOnce({ rows: [submission] })
+      .mockResolvedValueOnce({ rows: [{ id: 'reply', subject: 'Re: Reservation' }] });
+
+    const res = await app.request(`/contacts/${CONTACT_ID}`);
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Contact submission retrieved successfully',
+      data: { ...submission, replies: [{ id: 'reply', subject: 'Re: Reservation' }] },
+    });
+  });
+
+  it('answers an unknown or malformed ID with 404 contact_not_found', async () => {
+    query.mockResolvedValueOnce({ rows: [] });
+
+    for (const id of [CONTACT_ID, 'not-a-uuid']) {
+      const res = await app.request(`/contacts/${id}`);
+      expect(res.status).toBe(404);
+      expect(await res.json()).toEqual({ success: false, message: 'Contact submission not found', error: 'contact_not_found' });
+    }
+  });
+});
+
+describe('POST /contacts/:id/reply', () => {
+  it('requires a message', async () => {
+    const res = await send('POST', `/contacts/${CONTACT_ID}/reply`, { message: '  ' });
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'message_required' });
+  });
+
+  it('refuses to reply while email is not configured', async () => {
+    vi.mocked(emailConfigured).mockResolvedValueOnce(false);
+
+    const res = await send('POST', `/contacts/${CONTACT_ID}/reply`, { message: 'See you at seven' });
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'email_not_configured' });
+  });
+});
+
+describe('PUT /contacts/:id/status', () => {
+  it('returns the updated submission', async () => {
+    query.mockResolvedValueOnce({ rows: [{ ...submission, status: 'resolved' }] });
+
+    const res = await send('PUT', `/contacts/${CONTACT_ID}/status`, { status: 'resolved' });
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Contact status updated successfully',
+      data: { ...submission, status: 'resolved' },
+    });
+  });
+
+  it('validates the status', async () => {
+    const missing = await send('PUT', `/contacts/${CONTACT_ID}/status`, {});
+    expect(missing.status).toBe(400);
+    expect(await missing.json()).toMatchObject({ success: false, error: 'missing_status' });
+
+    const invalid = await send('PUT', `/contacts/${CONTACT_ID}/status`, { status: 'closed' });
+    expect(invalid.status).toBe(400);
+    expect(await invalid.json()).toMatchObject({ success: false, error: 'invalid_status' });
+  });
+
+  it('answers a body that is not JSON with invalid_json', async () => {
+    const res = await app.request(`/contacts/${CONTACT_ID}/status`, { method: 'PUT', body: '{' });
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'invalid_json' });
+  });
+});
+
+describe('DELETE /contacts/:id', () => {
+  it('answers with a message and no data', async () => {
+    query.mockResolvedValueOnce({ rowCount: 1 });
+
+    const res = await app.request(`/contacts/${CONTACT_ID}`, { method: 'DELETE' });
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ success: true, message: 'Contact submission deleted successfully' });
+  });
+
+  it('answers an unknown ID with 404', async () => {
+    query.mockResolvedValueOnce({ rowCount: 0 });
+
+    const res = await app.request(`/contacts/${CONTACT_ID}`, { method: 'DELETE' });
+    expect(res.status).toBe(404);
+    expect(await res.json()).toMatchObject({ success: false, error: 'contact_not_found' });
+  });
+});
@@ -0,0 +1,247 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { Hono } from 'hono';
+import { pool } from '../../db/connection.js';
+import { createIngredientBatch } from '../../services/ingredient-batches.js';
+import { refreshStockAvailability } from '../../services/stock-availability.js';
+import {
+  getIngredients, getIngredient, createIngredient, updateIngredient, deleteIngredient, restockIngredient,
+  getLowStockIngredients,
+} from '../ingredients.js';
+
+vi.mock('../../db/connection.js', () => ({
+  pool: { query: vi.fn(), connect: vi.fn() },
+}));
+
+vi.mock('../../services/ingredient-batches.js', () => ({
+  createIngredientBatch: vi.fn(),
+  listExpiringBatches: vi.fn(),
+  loadExpiryWarningDays: vi.fn(),
+}));
+
+vi.mock('../../services/stock-availability.js', () => ({
+  refreshStockAvailability: vi.fn(),
+}));
+
+const INGREDIENT_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+
+// As Postgres returns it: NUMERIC columns come back as strings
+const row = {
+  id: INGREDIENT_ID,
+  name: 'Beef tenderloin',
+  description: null,
+  unit: 'kg',
+  current_stock: '4.500',
+  minimum_stock: '5.000',
+  maximum_stock: '20.000',
+  unit_cost: '350000.00',
+  supplier: null,
+  last_restocked: '2026-10-13T07:00:00Z',
+  is_active: true,
+  status: 'low',
+  total_value: '1575000.00',
+  created_at: '2026-10-01T07:00:00Z',
+  updated_at: '2026-10-13T07:00:00Z',
+};
+
+const ingredient = {
+  id: INGREDIENT_ID,
+  name: 'Beef tenderloin',
+  description: '',
+  unit: 'kg',
+  current_stock: 4.5,
+  minimum_stock: 5,
+  maximum_stock: 20,
+  unit_cost: 350000,
+  supplier: '',
+  last_restocked: '2026-10-13T07:00:00Z',
+  is_active: true,
+  status: 'low',
+  total_value: 1575000,
+  created_at: '2026-10-01T07:00:00Z',
+  updated_at: '2026-10-13T07:00:00Z',
+};
+
+const app = new Hono<{ Variables: { user_id: string } }>();
+app.use('*', async (c, next) => {
+  c.set('user_id', 'user');
+  await next();
+});
+app.get('/ingredients', getIngredients);
+app.get('/ingredients/low-stock', getLowStockIngredients);
+app.post('/ingredients/restock', restockIngredient);
+app.get('/ingredients/:id', getIngredient);
+app.post('/ingredients', createIngredient);
+app.put('/ingredients/:id', updateIngredient);
+app.delete('/ingredients/:id', deleteIngredient);
+
+const query = pool.query as unknown as ReturnType<typeof vi.fn>;
+const client = { query: vi.fn(), release: vi.fn() };
+
+function send(method: string, path: string, body: unknown, headers: Record<string, string> = {}) {
+  return app.request(path, {
+    method,
+    headers: { 'Content-Type': 'application/json', ...headers },
+    body: JSON.stringify(body),
+  });
+}
+
+beforeEach(() => {
+  vi.clearAllMocks();
+  vi.mocked(pool.connect).mockResolvedValue(client as never);
+  client.query.mockResolvedValue({ rows: [], rowCount: 0 });
+});
+
+describe('GET /ingredients', () => {
+  it('returns formatted ingredients in the success envelope', async () => {
+    query.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request('/ingredients');
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Ingredients retrieved successfully',
+      data: [ingredient],
+    });
+  });
+
+  it('passes the search as a parameter', async () => {
+    query.mockResolvedValueOnce({ rows: [] });
+
+    await app.request('/ingredients?search=beef');
+    expect(query.mock.calls[0][1]).toEqual(['%beef%']);
+  });
+
+  it('lists low stock ingredients the same way', async () => {
+    query.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request('/ingredients/low-stock');
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Low stock ingredients retrieved successfully',
+      data: [ingredient],
+    });
+  });
+});
+
+describe('GET /ingredients/:id', () => {
+  it('returns the ingredient', async () => {
+    query.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request(`/ingredients/${INGREDIENT_ID}`);
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ success: true, message: 'Ingredient retrieved successfully', data: ingredient });
+  });
+
+  it('answers an unknown or malformed ID with 404 ingredient_not_found', async () => {
+    query.mockResolvedValueOnce({ rows: [] });
+
+    for (const id of [INGREDIENT_ID, 'not-a-uuid']) {
+      const res = await app.request(`/ingredients/${id}`);
+      expect(res.status).toBe(404);
+      expect(await res.json()).toEqual({ success: false, message: 'Ingredient not found', error: 'ingredient_not_found' });
+    }
+  });
+});
+
+describe('POST /ingredients', () => {
+  it('creates an ingredient with 201', async () => {
+    query.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await send('POST', '/ingredients', { name: 'Beef tenderloin', unit: 'kg', current_stock: 4.5 });
+    expect(res.status).toBe(201);
+    expect(await res.json()).toEqual({ success: true, message: 'Ingredient created successfully', data: ingredient });
+  });
+
+  it('rejects invalid input with a field error', async () => {
+    const missing = await send('POST', '/ingredients', { unit: 'kg' });
+    expect(missing.status).toBe(400);
+    expect((await missing.json()).errors).toEqual([
+      { field: 'name', code: 'missing_name', message: 'name is required' },
+    ]);
+
+    const negative = await send('POST', '/ingredients', { name: 'Beef tenderloin', unit: 'kg', current_stock: -1 });
+    expect(negative.status).toBe(400);
+    expect((await negative.json()).errors[0]).toMatchObject({ field: 'current_stock', code: 'invalid_current_stock' });
+
+    expect(query).not.toHaveBeenCalled();
+  });
+
+  it('localizes validation messages for Indonesian clients', async () => {
+    const res = await send('POST', '/ingredients', { unit: 'kg' }, { 'Accept-Language': 'id-ID,id;q=0.9' });
+    const body = await res.json();
+    expect(body.message).toBe('Nama wajib diisi');
+    expect(body.errors[0].message).toBe('Nama wajib diisi');
+  });
+
+  it('answers a body that is not JSON with invalid_json', async () => {
+    const res = await app.request('/ingredients', { method: 'POST', body: '{' });
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'invalid_json' });
+  });
+});
+
+describe('PUT /ingredients/:id', () => {
+  it('returns the updated ingredient', async () => {
+    query.mockResolvedValueOnce({ rows: [{ ...row, supplier: 'PT Daging Segar' }] });
+
+    const res = await send('PUT', `/ingredients/${INGREDIENT_ID}`, { supplier: 'PT Daging Segar' });
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Ingredient updated successfully',
+      data: { ...ingredient, supplier: 'PT Daging Segar' },
+    });
+  });
+
+  it('answers an unknown ID with 404', async () => {
+    query.mockResolvedValueOnce({ rows: [] });
+
+    const res = await send('PUT', `/ingredients/${INGREDIENT_ID}`, { name: 'Wagyu' });
+    expect(res.status).toBe(404);
+    expect(await res.json()).toMatchObject({ success: false, error: 'ingredient_not_found' });
+  });
+});
+
+describe('DELETE /ingredients/:id', () => {
+  it('answers with a message and no data', async () => {
+    query.mockResolvedValueOnce({ rowCount: 1 });
+
+    const res = await app.request(`/ingredients/${INGREDIENT_ID}`, { method: 'DELETE' });
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ success: true, message: 'Ingredient deleted successfully' });
+  });
+});
+
+describe('POST /ingredients/restock', () => {
+  it('returns the stock before and after with the batch', async () => {
+    client.query.mockImplementation(async (text: string) =>
+      text.startsWith('SELECT current_stock FROM ingredients') ? { rows: [{ current_stock: '4.5' }] } : { rows: [] });
+    vi.mocked(createIngredientBatch).mockResolvedValueOnce({ id: 'batch' } as never);
+
+    const res = await send('POST', '/ingredients/restock', { ingredient_id: INGREDIENT_ID, quantity: 10 });
+    expect(res.status).toBe(200);
+    const body = await res.json();
+    expect(body).toMatchObject({
+      success: true,
+      message: 'Ingredient restocked successfully',
+      data: { batch: { id: 'batch' } },
+    });
+    expect(client.query).toHaveBeenCalledWith('COMMIT');
+    expect(refreshStockAvailability).toHaveBeenCalledWith({ ingredientIds: [INGREDIENT_ID] });
+  });
+
+  it('answers an unknown ingredient with 404 and rolls back', async () => {
+    const res = await send('POST', '/ingredients/restock', { ingredient_id: INGREDIENT_ID, quantity: 10 });
+    expect(res.status).toBe(404);
+    expect(await res.json()).toMatchObject({ success: false, error: 'ingredient_not_found' });
+    expect(client.query).toHaveBeenCalledWith('ROLLBACK');
+    expect(client.query).not.toHaveBeenCalledWith('COMMIT');
+  });
+
+  it('validates the quantity', async () => {
+    const res = await send('POST', '/ingredients/restock', { ingredient_id: INGREDIENT_ID, quantity: 0 });
+    expect(res.status).toBe(400);
+    expect((await res.json()).errors[0]).toMatchObject({ field: 'quantity', code: 'invalid_quantity' });
+    expect(pool.connect).not.toHaveBeenCalled();
+  });
+});
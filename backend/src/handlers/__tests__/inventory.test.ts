@@ -0,0 +1,243 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { Hono } from 'hono';
+import { db, pool } from '../../db/connection.js';
+import { refreshStockAvailability } from '../../services/stock-availability.js';
+import { getInventory, getProductInventory, adjustStock, getLowStock, getInventoryLedger } from '../inventory.js';
+
+vi.mock('../../db/connection.js', () => ({
+  db: { execute: vi.fn() },
+  pool: { query: vi.fn(), connect: vi.fn() },
+}));
+
+vi.mock('../../services/stock-availability.js', () => ({
+  refreshStockAvailability: vi.fn(),
+}));
+
+const BRANCH_ID = '7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d';
+const OTHER_BRANCH_ID = '1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f';
+const PRODUCT_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+
+const row = {
+  product_id: PRODUCT_ID,
+  product_name: 'Ribeye 250g',
+  category_name: 'Steaks',
+  current_stock: 3,
+  min_stock: 10,
+  max_stock: 100,
+  last_restocked: '2026-10-13T07:00:00Z',
+  price: '285000.00',
+  status: 'low',
+};
+
+const item = { ...row, unit: 'pcs', price: 285000 };
+
+// Branch staff: inventory is read from and written to their own branch
+const app = new Hono<{ Variables: { branch_id: string | null; user_id: string } }>();
+app.use('*', async (c, next) => {
+  c.set('branch_id', BRANCH_ID);
+  c.set('user_id', 'user');
+  await next();
+});
+app.get('/inventory', getInventory);
+app.get('/inventory/low-stock', getLowStock);
+app.post('/inventory/adjust', adjustStock);
+app.get('/inventory/:product_id/ledger', getInventoryLedger);
+app.get('/inventory/:product_id', getProductInventory);
+
+const query = pool.query as unknown as ReturnType<typeof vi.fn>;
+const execute = db.execute as unknown as ReturnType<typeof vi.fn>;
+const client = { query: vi.fn(), release: vi.fn() };
+
+function adjust(body: unknown) {
+  return app.request('/inventory/adjust', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  });
+}
+
+// The stock row adjustStock locks, answered by SQL text so the order of the
+// other statements doesn't matter
+function stockOnHand(currentStock: number | null) {
+  client.query.mockImplementation(async (text: string) =>
+    text.startsWith('SELECT id, current_stock FROM inventory') && currentStock !== null
+      ? { rows: [{ id: 'inventory', current_stock: currentStock }] }
+      : { rows: [] });
+}
+
+beforeEach(() => {
+  vi.clearAllMocks();
+  vi.mocked(pool.connect).mockResolvedValue(client as never);
+  client.query.mockResolvedValue({ rows: [], rowCount: 0 });
+});
+
+describe('GET /inventory', () => {
+  it('returns formatted inventory in the success envelope', async () => {
+    execute.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request('/inventory');
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ success: true, message: 'Inventory retrieved successfully', data: [item] });
+  });
+
+  it("refuses another branch's inventory with 403 branch_forbidden", async () => {
+    const res = await app.request(`/inventory?branch_id=${OTHER_BRANCH_ID}`);
+    expect(res.status).toBe(403);
+    expect(await res.json()).toEqual({
+      success: false,
+      message: 'You can only access your own branch',
+      error: 'branch_forbidden',
+    });
+    expect(execute).not.toHaveBeenCalled();
+  });
+
+  it('lists low stock the same way', async () => {
+    execute.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request('/inventory/low-stock');
+    expect(await res.json()).toEqual({ success: true, message: 'Low stock items retrieved successfully', data: [item] });
+  });
+});
+
+describe('GET /inventory/:product_id', () => {
+  it('returns the product', async () => {
+    execute.mockResolvedValueOnce({ rows: [row] });
+
+    const res = await app.request(`/inventory/${PRODUCT_ID}`);
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({ success: true, message: 'Product inventory retrieved successfully', data: item });
+  });
+
+  it('answers an unknown or malformed product with 404 product_not_found', async () => {
+    execute.mockResolvedValueOnce({ rows: [] });
+
+    for (const id of [PRODUCT_ID, 'not-a-uuid']) {
+      const res = await app.request(`/inventory/${id}`);
+      expect(res.status).toBe(404);
+      expect(await res.json()).toEqual({ success: false, message: 'Product not found', error: 'product_not_found' });
+    }
+  });
+});
+
+describe('POST /inventory/adjust', () => {
+  it('returns the stock before and after', async () => {
+    stockOnHand(3);
+
+    const res = await adjust({ product_id: PRODUCT_ID, operation: 'add', quantity: 12, reason: 'purchase' });
+    expect(res.status).toBe(200);
+    expect(await res.json()).toEqual({
+      success: true,
+      message: 'Stock adjusted successfully',
+      data: { product_id: PRODUCT_ID, branch_id: BRANCH_ID, previous_stock: 3, new_stock: 15 },
+    });
+    expect(client.query).toHaveBeenCalledWith('COMMIT');
+    expect(refreshStockAvailability).toHaveBeenCalledWith({ productIds: [PRODUCT_ID] });
+  });
+
+  it('refuses to take stock below zero', async () => {
+    stockOnHand(3);
+
+    const res = await adjust({ product_id: PRODUCT_ID, operation: 'remove', quantity: 5, reason: 'damage' });
+    expect(res.status).toBe(400);
+    expect(await res.json()).toMatchObject({ success: false, error: 'insufficient_stock' });
+    expect(client.query).toHaveBeenCalledWith('ROLLBACK');
+    expect(client.query).not.toHaveBeenCalledWith('COMMIT');
+    expect(refreshStockAvailability).not.toHaveBeenCalled();
+  });
+
+  it('validates the adjustment before touching stock', async () => {
+    const cases: [unknown, string, string][] = [
+      [{ operation: 'add', quantity: 1, reason: 'purchase' }, 'product_id', 'missing_product_id'],
+      [{ product_id: PRODUCT_ID, operation: 'add', quantity: 0, reason: 'purchase' }, 'quantity', 'invalid_quantity'],
+      [{ product_id: PRODUCT_ID, operation: 'set', quantity: 1, reason: 'purchase' }, 'operation', 'invalid_operation'],
+      [{ product_id: PRODUCT_ID, operation: 'add', quantity: 1, reason: 'gift' }, 'reason', 'invalid_reason'],
+    ];
+    for (const [body, field, code] of cases) {
+      const res = await adjust(body);
+      expect(res.status).toBe(400);
+      const json = await res.json();
+      expect(json).toMatchObject({ success: false, error: code });
+      expect(json.errors[0]).toMatchObject({ field, code });
+    }
+    expect(pool.connect).not.toHaveBeenCalled();
+  });
+
+  it("refuses a write to another branch with 403", async () => {
+    const res = await adjust({
+      product_id: PRODUCT_ID, operation: 'add', quantity: 1, reason: 'purchase', branch_id: OTHER_BRANCH_ID,
+    });
+    expect(res.status).toBe(403);
+    expect(await res.json()).toMatchObject({ success: false, error: 'branch_forbidden' });
+  });
+});
+
+describe('GET /inventory/:product_id/ledger', () => {
+  const movement = {
+    id: 'movement',
+    movement_type: 'restock',
+    operation: 'add',
+    quantity: 12,
+    change: 12,
+    running_balance: 15,
+    recorded_stock: 15,
+    reason: 'purchase',
+    notes: '',
+    order_id: null,
+    adjusted_by: 'manager',
+    created_at: '2026-10-14T02:00:00Z',
+  };
+
+  beforeEach(() => {
+    query.mockImplementation(async (text: string) => {
+      if (text.includes('FROM products p')) return { rows: [{ name: 'Ribeye 250g', current_stock: 15 }] };
+      if (text.includes('AS opening_in_range')) {
+        return {
+          rows: [{
+            opening_in_range: 3, before_range: null, first_previous: 3, closing_in_range: 15, net_by_type: { restock: 12 },
+          }],
+        };
+      }
+      if (text.includes('SELECT COUNT(*) FROM ledger')) return { rows: [{ count: '1' }] };
+      return { rows: [movement] };
+    });
+  });
+
+  it('returns the balances, movements and page meta in data', async () => {
+    const res = await app.request(`/inventory/${PRODUCT_ID}/ledger?from=2026-10-14&to=2026-10-14`);
+    expect(res.status).toBe(200);
+    const body = await res.json();
+    expect(body).toMatchObject({
+      success: true,
+      message: 'Inventory ledger retrieved successfully',
+      data: {
+        product_id: PRODUCT_ID,
+        product_name: 'Ribeye 250g',
+        branch_id: BRANCH_ID,
+        from: '2026-10-14',
+        to: '2026-10-14',
+        type: null,
+        current_stock: 15,
+        opening_balance: 3,
+        closing_balance: 15,
+        net_by_type: { restock: 12 },
+      },
+    });
+    const { movement_type, ...rest } = movement;
+    expect(body.data.movements).toEqual([{ ...rest, type: movement_type }]);
+    expect(body.data.meta).toMatchObject({
+      current_page: 1, per_page: 20, total: 1, total_pages: 1, has_next: false, has_prev: false,
+    });
+  });
+
+  it('validates the date range and type', async () => {
+    const range = await app.request(`/inventory/${PRODUCT_ID}/ledger?from=2026-10-15&to=2026-10-14`);
+    expect(range.status).toBe(400);
+    expect(await range.json()).toMatchObject({ success: false, error: 'invalid_date_range' });
+
+    const type = await app.request(`/inventory/${PRODUCT_ID}/ledger?type=gift`);
+    expect(type.status).toBe(400);
+    expect(await type.json()).toMatchObject({ success: false, error: 'invalid_ledger_type' });
+
+    expect(query).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,105 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { Hono } from 'hono';
+import { pool } from '../../db/connection.js';
+import { refundPayment } from '../payments.js';
+
+vi.mock('../../db/connection.js', () => ({
+  db: { execute: vi.fn() },
+  pool: { query: vi.fn(), connect: vi.fn() },
+}));
+
+vi.mock('../../services/stock-availability.js', () => ({
+  refreshStockAvailability: vi.fn(),
+}));
+
+const BRANCH_ID = '7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d';
+const ORDER_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+const PAYMENT_ID = '1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f';
+
+const app = new Hono<{ Variables: { branch_id: string | null; user_id: string } }>();
+app.use('*', async (c, next) => {
+  c.set('branch_id', BRANCH_ID);
+  c.set('user_id', 'manager');
+  await next();
+});
+app.post('/orders/:id/payments/:payment_id/refund', refundPayment);
+
+const client = { query: vi.fn(), release: vi.fn() };
+
+function refund(body: unknown) {
+  return app.request(`/orders/${ORDER_ID}/payments/${PAYMENT_ID}/refund`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  });
+}
+
+// Statements answered by SQL text, so their order doesn't matter
+function companyPayment(method: string, refunded = 0) {
+  client.query.mockImplementation(async (text: string) => {
+    if (text.includes('FROM payments p') && text.includes('FOR UPDATE OF p')) {
+      return {
+        rows: [{
+          id: PAYMENT_ID, payment_method: method, amount: '100000.00', surcharge_amount: '2000.00',
+          tip_amount: '10000.00', status: 'completed', refund_of: null, branch_id: BRANCH_ID,
+        }],
+      };
+    }
+    if (text.includes('AS refunded')) return { rows: [{ refunded: String(refunded) }] };
+    if (text.includes('INSERT INTO payments')) {
+      return { rows: [{ id: 'refund', status: 'completed', created_at: '2026-10-14T12:00:00Z' }] };
+    }
+    if (text.includes('FROM corporate_wallet_transactions t')) {
+      return { rows: [{ account_id: 'account', employee_id: 'employee', order_id: ORDER_ID, balance: '388000.00' }] };
+    }
+    if (text.includes('FROM corporate_account_charges')) {
+      return { rows: [{ account_id: 'account', order_id: ORDER_ID }] };
+    }
+    return { rows: [], rowCount: 0 };
+  });
+}
+
+function statement(prefix: string) {
+  return client.query.mock.calls.find(([text]) => (text as string).trimStart().startsWith(prefix));
+}
+
+beforeEach(() => {
+  vi.clearAllMocks();
+  vi.mocked(pool.connect).mockResolvedValue(client as never);
+});
+
+describe('POST /orders/:id/payments/:payment_id/refund', () => {
+  it('gives a full corporate wallet refund back with the surcharge and tip billed', async () => {
+    companyPayment('corporate_wallet');
+
+    const res = await refund({ reason: 'wrong_amount' });
+    expect(res.status).toBe(201);
+    expect(await res.json()).toMatchObject({
+      success: true,
+      data: { amount: -100000, payment_method: 'corporate_wallet', remaining_refundable: 0 },
+    });
+
+    // The order is refunded its amount; the wallet everything it paid
+    expect(statement('INSERT INTO payments')?.[1]).toContain(-100000);
+    expect(statement('UPDATE corporate_accounts')?.[1]).toEqual([500000, 'account']);
+    expect(statement('INSERT INTO corporate_wallet_transactions')?.[1]).toContain(112000);
+    expect(client.query).toHaveBeenCalledWith('COMMIT');
+  });
+
+  it('credits the rest of the billed figure after a partial refund', async () => {
+    companyPayment('corporate_wallet', 25000);
+
+    const res = await refund({ reason: 'wrong_amount' });
+    expect(res.status).toBe(201);
+    expect((await res.json()).data.amount).toBe(-75000);
+    expect(statement('INSERT INTO corporate_wallet_transactions')?.[1]).toContain(84000);
+  });
+
+  it('credits an on-account charge the same way', async () => {
+    companyPayment('on_account');
+
+    const res = await refund({ reason: 'wrong_amount' });
+    expect(res.status).toBe(201);
+    expect(statement('INSERT INTO corporate_account_charges')?.[1]).toContain(-112000);
+  });
+});
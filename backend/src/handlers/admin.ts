@@ -2,14 +2,40 @@ import type { Context } from 'hono';
 import bcrypt from 'bcryptjs';
 import { pool } from '../db/connection.js';
 import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
-import { parsePagination, buildMeta } from '../lib/pagination.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { includeDeleted } from '../lib/soft-delete.js';
+import { invalidateCache } from '../lib/cache.js';
+import { checkPassword, weakPasswordResponse } from '../lib/password.js';
+import { findActiveBranch, resolveBranchScope, resolveWriteBranch } from '../services/branches.js';
+import { roleExists } from '../services/permissions.js';
+import { issueTableCode } from '../services/table-qr.js';
+import { KITCHEN_STATIONS, isKitchenStation } from '../services/kitchen-routing.js';
+import { isDisplayColor } from '../services/kitchen-display.js';
 
 // ── Admin Categories ─────────────────────────────────────────────────────────
 
+function validateKitchenDisplay(body: {
+  kds_color?: string | null;
+  kds_priority?: number | null;
+  kds_group?: string | null;
+}): { message: string; code: string } | null {
+  if (body.kds_color !== undefined && body.kds_color !== null && !isDisplayColor(body.kds_color)) {
+    return { message: 'kds_color must be a hex color like #DC2626', code: 'invalid_color' };
+  }
+  if (body.kds_priority !== undefined && body.kds_priority !== null && !Number.isInteger(body.kds_priority)) {
+    return { message: 'kds_priority must be a whole number', code: 'invalid_sort_priority' };
+  }
+  if (body.kds_group !== undefined && body.kds_group !== null && body.kds_group.trim().length > 50) {
+    return { message: 'kds_group must be at most 50 characters', code: 'invalid_group' };
+  }
+  return null;
+}
+
 export async function getAdminCategories(c: Context) {
-  const { page, perPage, offset } = parsePagination({
+  const pagination = parsePagination({
     page: c.req.query('page'),
     per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
   });
   const activeOnly = c.req.query('active_only') === 'true';
   const search = c.req.query('search');
@@ -19,6 +45,9 @@ export async function getAdminCategories(c: Context) {
     const params: unknown[] = [];
     let paramIdx = 1;
 
+    if (!includeDeleted(c)) {
+      conditions.push(`deleted_at IS NULL`);
+    }
     if (activeOnly) {
       conditions.push(`is_active = true`);
     }
@@ -30,30 +59,46 @@ export async function getAdminCategories(c: Context) {
 
     const whereClause = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
 
-    // Count
-    const countRes = await pool.query(
-      `SELECT COUNT(*) FROM categories ${whereClause}`,
-      params,
-    );
-    const total = Number(countRes.rows[0].count);
-
-    // Fetch
-    const dataRes = await pool.query(
-      `SELECT id, name, description, color, sort_order, is_active, created_at, updated_at
-       FROM categories ${whereClause}
-       ORDER BY sort_order ASC, name ASC
-       LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
-      [...params, perPage, offset],
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM categories ${whereClause}`, params);
+        return Number(countRes.rows[0].count);
+      },
+      async (limit) => {
+        const dataRes = await pool.query(
+          `SELECT id, name, description, color, sort_order, station, auto_release, kds_color, kds_priority, kds_group,
+                  is_active, created_at, updated_at, deleted_at
+           FROM categories ${whereClause}
+           ORDER BY sort_order ASC, name ASC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return dataRes.rows;
+      },
     );
 
-    return paginatedResponse(c, 'Categories retrieved successfully', dataRes.rows, buildMeta(page, perPage, total));
+    return paginatedResponse(c, 'Categories retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'sort_order:asc,name:asc',
+      filters: { active_only: activeOnly || undefined, search, include_deleted: includeDeleted(c) || undefined },
+    }));
   } catch (err) {
     return errorResponse(c, 'Failed to fetch categories', (err as Error).message);
   }
 }
 
 export async function createCategory(c: Context) {
-  let body: { name?: string; description?: string; color?: string; sort_order?: number };
+  let body: {
+    name?: string;
+    description?: string;
+    color?: string;
+    sort_order?: number;
+    station?: string;
+    auto_release?: boolean | null;
+    kds_color?: string | null;
+    kds_priority?: number | null;
+    kds_group?: string | null;
+  };
   try {
     body = await c.req.json();
   } catch {
@@ -63,14 +108,25 @@ export async function createCategory(c: Context) {
   if (!body.name) {
     return errorResponse(c, 'Category name is required', 'missing_name', 400);
   }
+  if (body.station !== undefined && !isKitchenStation(body.station)) {
+    return errorResponse(c, `Station must be one of: ${KITCHEN_STATIONS.join(', ')}`, 'invalid_station', 400);
+  }
+  const kdsInvalid = validateKitchenDisplay(body);
+  if (kdsInvalid) {
+    return errorResponse(c, kdsInvalid.message, kdsInvalid.code, 400);
+  }
 
   try {
     const res = await pool.query(
-      `INSERT INTO categories (name, description, color, sort_order)
-       VALUES ($1, $2, $3, $4) RETURNING id`,
-      [body.name, body.description || null, body.color || null, body.sort_order ?? 0],
+      `INSERT INTO categories (name, description, color, sort_order, station, auto_release, kds_color, kds_priority, kds_group)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+      [
+        body.name, body.description || null, body.color || null, body.sort_order ?? 0, body.station ?? 'kitchen', body.auto_release ?? null,
+        body.kds_color ?? null, body.kds_priority ?? null, body.kds_group?.trim() || null,
+      ],
     );
 
+    invalidateCache('menu');
     return successResponse(c, 'Category created successfully', { id: res.rows[0].id }, 201);
   } catch (err) {
     return errorResponse(c, 'Failed to create category', (err as Error).message);
@@ -80,13 +136,34 @@ export async function createCategory(c: Context) {
 export async function updateCategory(c: Context) {
   const categoryId = c.req.param('id');
 
-  let body: { name?: string; description?: string; color?: string; sort_order?: number; is_active?: boolean };
+  let body: {
+    name?: string;
+    description?: string;
+    color?: string;
+    sort_order?: number;
+    is_active?: boolean;
+    station?: string;
+    /** null follows the station's default */
+    auto_release?: boolean | null;
+    /** Kitchen display overrides; null falls back to the station's */
+    kds_color?: string | null;
+    kds_priority?: number | null;
+    kds_group?: string | null;
+  };
   try {
     body = await c.req.json();
   } catch {
     return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
+  if (body.station !== undefined && !isKitchenStation(body.station)) {
+    return errorResponse(c, `Station must be one of: ${KITCHEN_STATIONS.join(', ')}`, 'invalid_station', 400);
+  }
+  const kdsInvalid = validateKitchenDisplay(body);
+  if (kdsInvalid) {
+    return errorResponse(c, kdsInvalid.message, kdsInvalid.code, 400);
+  }
+
   try {
     const setClauses: string[] = [];
     const params: unknown[] = [];
@@ -112,6 +189,31 @@ export async function updateCategory(c: Context) {
       params.push(body.sort_order);
       paramIdx++;
     }
+    if (body.station !== undefined) {
+      setClauses.push(`station = $${paramIdx}`);
+      params.push(body.station);
+      paramIdx++;
+    }
+    if (body.auto_release !== undefined) {
+      setClauses.push(`auto_release = $${paramIdx}`);
+      params.push(body.auto_release);
+      paramIdx++;
+    }
+    if (body.kds_color !== undefined) {
+      setClauses.push(`kds_color = $${paramIdx}`);
+      params.push(body.kds_color);
+      paramIdx++;
+    }
+    if (body.kds_priority !== undefined) {
+      setClauses.push(`kds_priority = $${paramIdx}`);
+      params.push(body.kds_priority);
+      paramIdx++;
+    }
+    if (body.kds_group !== undefined) {
+      setClauses.push(`kds_group = $${paramIdx}`);
+      params.push(body.kds_group?.trim() || null);
+      paramIdx++;
+    }
     if (body.is_active !== undefined) {
       setClauses.push(`is_active = $${paramIdx}`);
       params.push(body.is_active);
@@ -126,7 +228,7 @@ export async function updateCategory(c: Context) {
     params.push(categoryId);
 
     const res = await pool.query(
-      `UPDATE categories SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`,
+      `UPDATE categories SET ${setClauses.join(', ')} WHERE id = $${paramIdx} AND deleted_at IS NULL`,
       params,
     );
 
@@ -134,6 +236,7 @@ export async function updateCategory(c: Context) {
       return errorResponse(c, 'Category not found', 'not_found', 404);
     }
 
+    invalidateCache('menu');
     return successResponse(c, 'Category updated successfully');
   } catch (err) {
     return errorResponse(c, 'Failed to update category', (err as Error).message);
@@ -142,49 +245,79 @@ export async function updateCategory(c: Context) {
 
 export async function deleteCategory(c: Context) {
   const categoryId = c.req.param('id');
+  const userId = c.get('user_id');
 
   try {
-    // Check if category has products
-    const productCount = await pool.query(
-      `SELECT COUNT(*) FROM products WHERE category_id = $1`,
-      [categoryId],
+    // Soft delete: products keep their category reference and order history
+    // stays intact, so there is no need to block on associated products.
+    const res = await pool.query(
+      `UPDATE categories SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NULL`,
+      [categoryId, userId],
     );
 
-    if (Number(productCount.rows[0].count) > 0) {
-      return errorResponse(c, 'Cannot delete category with associated products', 'category_has_products', 400);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Category not found', 'not_found', 404);
     }
 
+    invalidateCache('menu');
+    return successResponse(c, 'Category deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete category', (err as Error).message);
+  }
+}
+
+export async function restoreCategory(c: Context) {
+  const categoryId = c.req.param('id');
+
+  try {
     const res = await pool.query(
-      `DELETE FROM categories WHERE id = $1`,
+      `UPDATE categories SET deleted_at = NULL, deleted_by = NULL, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NOT NULL`,
       [categoryId],
     );
 
     if (res.rowCount === 0) {
-      return errorResponse(c, 'Category not found', 'not_found', 404);
+      return errorResponse(c, 'Deleted category not found', 'not_found', 404);
     }
 
-    return successResponse(c, 'Category deleted successfully');
+    invalidateCache('menu');
+    return successResponse(c, 'Category restored successfully');
   } catch (err) {
-    return errorResponse(c, 'Failed to delete category', (err as Error).message);
+    return errorResponse(c, 'Failed to restore category', (err as Error).message);
   }
 }
 
 // ── Admin Tables ─────────────────────────────────────────────────────────────
 
 export async function getAdminTables(c: Context) {
-  const { page, perPage, offset } = parsePagination({
+  const pagination = parsePagination({
     page: c.req.query('page'),
     per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
   });
   const location = c.req.query('location');
   const status = c.req.query('status');
   const search = c.req.query('search');
 
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
   try {
     const conditions: string[] = [];
     const params: unknown[] = [];
     let paramIdx = 1;
 
+    if (!includeDeleted(c)) {
+      conditions.push(`t.deleted_at IS NULL`);
+    }
+    if (scope.branchId) {
+      conditions.push(`t.branch_id = $${paramIdx}`);
+      params.push(scope.branchId);
+      paramIdx++;
+    }
     if (location) {
       conditions.push(`t.location ILIKE $${paramIdx}`);
       params.push(`%${location}%`);
@@ -203,28 +336,31 @@ export async function getAdminTables(c: Context) {
 
     const whereClause = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
 
-    // Count
-    const countRes = await pool.query(
-      `SELECT COUNT(*) FROM dining_tables t ${whereClause}`,
-      params,
-    );
-    const total = Number(countRes.rows[0].count);
-
     // Fetch with LEFT JOIN to active orders
-    const dataRes = await pool.query(
-      `SELECT t.id, t.table_number, t.seating_capacity, t.location, t.is_occupied,
-              t.qr_code, t.created_at, t.updated_at,
-              o.id as order_id, o.order_number, o.customer_name, o.status as order_status,
-              o.created_at as order_created_at, o.total_amount
-       FROM dining_tables t
-       LEFT JOIN orders o ON t.id = o.table_id AND o.status NOT IN ('completed', 'cancelled')
-       ${whereClause}
-       ORDER BY t.table_number ASC
-       LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
-      [...params, perPage, offset],
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM dining_tables t ${whereClause}`, params);
+        return Number(countRes.rows[0].count);
+      },
+      async (limit) => {
+        const dataRes = await pool.query(
+          `SELECT t.id, t.table_number, t.seating_capacity, t.location, t.is_occupied,
+                  t.qr_code, t.branch_id, t.created_at, t.updated_at, t.deleted_at,
+                  o.id as order_id, o.order_number, o.customer_name, o.status as order_status,
+                  o.created_at as order_created_at, o.total_amount
+           FROM dining_tables t
+           LEFT JOIN orders o ON t.id = o.table_id AND o.status NOT IN ('completed', 'cancelled')
+           ${whereClause}
+           ORDER BY t.table_number ASC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return dataRes.rows;
+      },
     );
 
-    const data = dataRes.rows.map((row: Record<string, unknown>) => {
+    const data = result.rows.map((row: Record<string, unknown>) => {
       const table: Record<string, unknown> = {
         id: row.id,
         table_number: row.table_number,
@@ -232,8 +368,10 @@ export async function getAdminTables(c: Context) {
         location: row.location,
         is_occupied: row.is_occupied,
         qr_code: row.qr_code,
+        branch_id: row.branch_id,
         created_at: row.created_at,
         updated_at: row.updated_at,
+        deleted_at: row.deleted_at,
         current_order: null,
       };
 
@@ -251,14 +389,23 @@ export async function getAdminTables(c: Context) {
       return table;
     });
 
-    return paginatedResponse(c, 'Tables retrieved successfully', data, buildMeta(page, perPage, total));
+    return paginatedResponse(c, 'Tables retrieved successfully', data, pageMeta(pagination, result, {
+      sort: 'table_number:asc',
+      filters: {
+        branch_id: scope.branchId,
+        location,
+        status: status === 'occupied' || status === 'available' ? status : undefined,
+        search,
+        include_deleted: includeDeleted(c) || undefined,
+      },
+    }));
   } catch (err) {
     return errorResponse(c, 'Failed to fetch tables', (err as Error).message);
   }
 }
 
 export async function createTable(c: Context) {
-  let body: { table_number?: string; seating_capacity?: number; location?: string };
+  let body: { table_number?: string; seating_capacity?: number; location?: string; branch_id?: string };
   try {
     body = await c.req.json();
   } catch {
@@ -270,13 +417,20 @@ export async function createTable(c: Context) {
   }
 
   try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
     const res = await pool.query(
-      `INSERT INTO dining_tables (table_number, seating_capacity, location)
-       VALUES ($1, $2, $3) RETURNING id`,
-      [body.table_number, body.seating_capacity ?? 4, body.location || null],
+      `INSERT INTO dining_tables (table_number, seating_capacity, location, branch_id)
+       VALUES ($1, $2, $3, $4) RETURNING id`,
+      [body.table_number, body.seating_capacity ?? 4, body.location || null, branch.branchId],
     );
+    // Ready to print straight away
+    const code = await issueTableCode(pool, res.rows[0].id, false);
 
-    return successResponse(c, 'Table created successfully', { id: res.rows[0].id }, 201);
+    return successResponse(c, 'Table created successfully', { id: res.rows[0].id, qr_code: code?.qr_code ?? null }, 201);
   } catch (err) {
     return errorResponse(c, 'Failed to create table', (err as Error).message);
   }
@@ -326,7 +480,7 @@ export async function updateTable(c: Context) {
     params.push(tableId);
 
     const res = await pool.query(
-      `UPDATE dining_tables SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`,
+      `UPDATE dining_tables SET ${setClauses.join(', ')} WHERE id = $${paramIdx} AND deleted_at IS NULL`,
       params,
     );
 
@@ -342,6 +496,7 @@ export async function updateTable(c: Context) {
 
 export async function deleteTable(c: Context) {
   const tableId = c.req.param('id');
+  const userId = c.get('user_id');
 
   try {
     // Check for active orders
@@ -355,8 +510,10 @@ export async function deleteTable(c: Context) {
     }
 
     const res = await pool.query(
-      `DELETE FROM dining_tables WHERE id = $1`,
-      [tableId],
+      `UPDATE dining_tables
+       SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2, is_occupied = false, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NULL`,
+      [tableId, userId],
     );
 
     if (res.rowCount === 0) {
@@ -369,22 +526,60 @@ export async function deleteTable(c: Context) {
   }
 }
 
+export async function restoreTable(c: Context) {
+  const tableId = c.req.param('id');
+
+  try {
+    const res = await pool.query(
+      `UPDATE dining_tables SET deleted_at = NULL, deleted_by = NULL, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NOT NULL`,
+      [tableId],
+    );
+
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Deleted table not found', 'not_found', 404);
+    }
+
+    return successResponse(c, 'Table restored successfully');
+  } catch (err) {
+    // Another live table in the branch took the number since the delete
+    if ((err as { code?: string }).code === '23505') {
+      return errorResponse(c, 'Another table already uses this table number', 'duplicate_table_number', 409);
+    }
+    return errorResponse(c, 'Failed to restore table', (err as Error).message);
+  }
+}
+
 // ── Admin Users ──────────────────────────────────────────────────────────────
 
 export async function getAdminUsers(c: Context) {
-  const { page, perPage, offset } = parsePagination({
+  const pagination = parsePagination({
     page: c.req.query('page'),
     per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
   });
   const role = c.req.query('role');
   const active = c.req.query('active');
   const search = c.req.query('search');
 
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
   try {
     const conditions: string[] = [];
     const params: unknown[] = [];
     let paramIdx = 1;
 
+    if (!includeDeleted(c)) {
+      conditions.push(`deleted_at IS NULL`);
+    }
+    if (scope.branchId) {
+      conditions.push(`branch_id = $${paramIdx}`);
+      params.push(scope.branchId);
+      paramIdx++;
+    }
     if (role) {
       conditions.push(`role = $${paramIdx}`);
       params.push(role);
@@ -403,23 +598,35 @@ export async function getAdminUsers(c: Context) {
 
     const whereClause = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
 
-    // Count
-    const countRes = await pool.query(
-      `SELECT COUNT(*) FROM users ${whereClause}`,
-      params,
-    );
-    const total = Number(countRes.rows[0].count);
-
     // Fetch (exclude password_hash)
-    const dataRes = await pool.query(
-      `SELECT id, username, email, first_name, last_name, role, is_active, created_at
-       FROM users ${whereClause}
-       ORDER BY created_at DESC
-       LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
-      [...params, perPage, offset],
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM users ${whereClause}`, params);
+        return Number(countRes.rows[0].count);
+      },
+      async (limit) => {
+        const dataRes = await pool.query(
+          `SELECT id, username, email, first_name, last_name, role, is_active, branch_id, created_at, deleted_at
+           FROM users ${whereClause}
+           ORDER BY created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return dataRes.rows;
+      },
     );
 
-    return paginatedResponse(c, 'Users retrieved successfully', dataRes.rows, buildMeta(page, perPage, total));
+    return paginatedResponse(c, 'Users retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: {
+        branch_id: scope.branchId,
+        role,
+        active: active === 'true' || active === 'false' ? active === 'true' : undefined,
+        search,
+        include_deleted: includeDeleted(c) || undefined,
+      },
+    }));
   } catch (err) {
     return errorResponse(c, 'Failed to fetch users', (err as Error).message);
   }
@@ -433,6 +640,7 @@ export async function createUser(c: Context) {
     first_name?: string;
     last_name?: string;
     role?: string;
+    branch_id?: string | null;
   };
   try {
     body = await c.req.json();
@@ -445,12 +653,33 @@ export async function createUser(c: Context) {
   }
 
   try {
+    const policyCheck = await checkPassword(body.password, body);
+    if (policyCheck) {
+      return weakPasswordResponse(c, policyCheck, 'password');
+    }
+
+    if (!(await roleExists(pool, body.role))) {
+      return errorResponse(c, 'Role not found', 'invalid_role', 400);
+    }
+
+    // Branch managers hire for their own branch; head office may create
+    // head office staff (no branch) or staff for any branch
+    const ownBranch = c.get('branch_id');
+    let branchId: string | null = null;
+    if (ownBranch || body.branch_id) {
+      const branch = await resolveWriteBranch(pool, ownBranch, body.branch_id);
+      if (!branch.ok) {
+        return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+      }
+      branchId = branch.branchId;
+    }
+
     const passwordHash = await bcrypt.hash(body.password, 12);
 
     const res = await pool.query(
-      `INSERT INTO users (username, email, password_hash, first_name, last_name, role)
-       VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
-      [body.username, body.email, passwordHash, body.first_name, body.last_name, body.role],
+      `INSERT INTO users (username, email, password_hash, first_name, last_name, role, branch_id)
+       VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+      [body.username, body.email, passwordHash, body.first_name, body.last_name, body.role, branchId],
     );
 
     return successResponse(c, 'User created successfully', { id: res.rows[0].id }, 201);
@@ -470,6 +699,7 @@ export async function updateUser(c: Context) {
     last_name?: string;
     role?: string;
     is_active?: boolean;
+    branch_id?: string | null;
   };
   try {
     body = await c.req.json();
@@ -477,6 +707,12 @@ export async function updateUser(c: Context) {
     return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
+  // Only head office moves staff between branches
+  const ownBranch = c.get('branch_id');
+  if (ownBranch && body.branch_id !== undefined && body.branch_id !== ownBranch) {
+    return errorResponse(c, 'Only head office can change a user\'s branch', 'branch_forbidden', 403);
+  }
+
   try {
     const setClauses: string[] = [];
     const params: unknown[] = [];
@@ -493,6 +729,14 @@ export async function updateUser(c: Context) {
       paramIdx++;
     }
     if (body.password !== undefined) {
+      const current = await pool.query('SELECT username, email FROM users WHERE id = $1', [userId]);
+      const policyCheck = await checkPassword(body.password, {
+        username: body.username ?? current.rows[0]?.username,
+        email: body.email ?? current.rows[0]?.email,
+      });
+      if (policyCheck) {
+        return weakPasswordResponse(c, policyCheck, 'password');
+      }
       const passwordHash = await bcrypt.hash(body.password, 12);
       setClauses.push(`password_hash = $${paramIdx}`);
       params.push(passwordHash);
@@ -509,6 +753,9 @@ export async function updateUser(c: Context) {
       paramIdx++;
     }
     if (body.role !== undefined) {
+      if (!(await roleExists(pool, body.role))) {
+        return errorResponse(c, 'Role not found', 'invalid_role', 400);
+      }
       setClauses.push(`role = $${paramIdx}`);
       params.push(body.role);
       paramIdx++;
@@ -518,16 +765,35 @@ export async function updateUser(c: Context) {
       params.push(body.is_active);
       paramIdx++;
     }
+    if (body.branch_id !== undefined) {
+      if (body.branch_id !== null && !(await findActiveBranch(pool, body.branch_id))) {
+        return errorResponse(c, 'Branch not found', 'branch_not_found', 400);
+      }
+      setClauses.push(`branch_id = $${paramIdx}`);
+      params.push(body.branch_id);
+      paramIdx++;
+    }
 
     if (setClauses.length === 0) {
       return errorResponse(c, 'No fields to update', 'no_fields', 400);
     }
 
+    // The user's sessions carry the old password's trust, role and branch
+    if (body.password !== undefined || body.role !== undefined || body.branch_id !== undefined) {
+      setClauses.push('token_version = token_version + 1');
+    }
     setClauses.push('updated_at = CURRENT_TIMESTAMP');
     params.push(userId);
 
+    // Branch managers can only edit their own branch's staff
+    let branchGuard = '';
+    if (ownBranch) {
+      params.push(ownBranch);
+      branchGuard = ` AND branch_id = $${paramIdx + 1}`;
+    }
+
     const res = await pool.query(
-      `UPDATE users SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`,
+      `UPDATE users SET ${setClauses.join(', ')} WHERE id = $${paramIdx} AND deleted_at IS NULL${branchGuard}`,
       params,
     );
 
@@ -543,29 +809,51 @@ export async function updateUser(c: Context) {
 
 export async function deleteUser(c: Context) {
   const userId = c.req.param('id');
+  const currentUserId = c.get('user_id');
+
+  if (userId === currentUserId) {
+    return errorResponse(c, 'You cannot delete your own account', 'cannot_delete_self', 400);
+  }
 
   try {
-    // Check if user has orders
-    const orderCount = await pool.query(
-      `SELECT COUNT(*) FROM orders WHERE user_id = $1`,
-      [userId],
+    // Soft delete keeps the user's orders and audit references valid;
+    // deleted users are excluded from login and staff lookups.
+    const res = await pool.query(
+      `UPDATE users SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NULL`,
+      [userId, currentUserId],
     );
 
-    if (Number(orderCount.rows[0].count) > 0) {
-      return errorResponse(c, 'Cannot delete user with associated orders', 'user_has_orders', 400);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'User not found', 'not_found', 404);
     }
 
+    return successResponse(c, 'User deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete user', (err as Error).message);
+  }
+}
+
+export async function restoreUser(c: Context) {
+  const userId = c.req.param('id');
+
+  try {
     const res = await pool.query(
-      `DELETE FROM users WHERE id = $1`,
+      `UPDATE users SET deleted_at = NULL, deleted_by = NULL, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $1 AND deleted_at IS NOT NULL`,
       [userId],
     );
 
     if (res.rowCount === 0) {
-      return errorResponse(c, 'User not found', 'not_found', 404);
+      return errorResponse(c, 'Deleted user not found', 'not_found', 404);
     }
 
-    return successResponse(c, 'User deleted successfully');
+    return successResponse(c, 'User restored successfully');
   } catch (err) {
-    return errorResponse(c, 'Failed to delete user', (err as Error).message);
+    // Another live user took the username or email since the delete
+    if ((err as { code?: string }).code === '23505') {
+      return errorResponse(c, 'Another user already uses this username or email', 'duplicate_user', 409);
+    }
+    return errorResponse(c, 'Failed to restore user', (err as Error).message);
   }
 }
@@ -0,0 +1,648 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock, addDays, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { toCsv } from '../lib/csv.js';
+import { logExport, exportFileHeaders } from '../services/data-exports.js';
+import { isUUID, resolveBranchScope, resolveWriteBranch } from '../services/branches.js';
+import { MAX_SHIFT_HOURS } from '../services/sections.js';
+import { isDeviceId } from '../services/devices.js';
+import {
+  ENTRY_SELECT,
+  MAX_BREAK_MINUTES,
+  MAX_TIMESHEET_DAYS,
+  buildTimesheets,
+  checkClockCredentials,
+  formatEntry,
+  hashAttendancePin,
+  isAttendancePin,
+  loadAttendanceSettings,
+  matchShift,
+} from '../services/attendance.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+const MAX_NOTE_LENGTH = 500;
+// Shown on the staff member's own attendance page
+const RECENT_DAYS = 14;
+const UPCOMING_DAYS = 7;
+
+type ClockBody = { pin?: string; device_id?: string; note?: string | null };
+
+function validTimestamp(value: unknown): value is string {
+  return typeof value === 'string' && !Number.isNaN(Date.parse(value));
+}
+
+function validNote(note: unknown): boolean {
+  return note === undefined || note === null || (typeof note === 'string' && note.length <= MAX_NOTE_LENGTH);
+}
+
+async function readClockBody(c: Context): Promise<ClockBody | null> {
+  try {
+    return await c.req.json();
+  } catch {
+    return null;
+  }
+}
+
+// from/to for timesheets: the current month so far by default
+function timesheetRange(c: Context): { from: string; to: string } | { message: string; code: string } {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to || addDays(from, MAX_TIMESHEET_DAYS - 1) < to) {
+    return {
+      message: `from and to must be YYYY-MM-DD dates with from <= to, covering at most ${MAX_TIMESHEET_DAYS} days`,
+      code: 'invalid_date_range',
+    };
+  }
+  return { from, to };
+}
+
+const SHIFT_SELECT = `
+  SELECT s.id, s.user_id, u.username, u.first_name, u.last_name, u.role, s.branch_id,
+         s.starts_at, s.ends_at, s.break_minutes, s.note, s.created_by, s.created_at, s.updated_at,
+         EXISTS (SELECT 1 FROM attendance a WHERE a.shift_id = s.id) AS attended
+  FROM staff_shifts s
+  JOIN users u ON u.id = s.user_id`;
+
+// ── ClockIn ─────────────────────────────────────────────────────────────────
+// Opens an attendance entry for the caller, matched to their scheduled shift
+// if there is one (services/attendance.ts).
+
+export async function clockIn(c: Context) {
+  const userId = c.get('user_id');
+  const body = await readClockBody(c);
+  if (!body) {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (!validNote(body.note)) {
+    return errorResponse(c, `Note must be at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  try {
+    const settings = await loadAttendanceSettings(pool);
+    const credentials = await checkClockCredentials(
+      pool,
+      { userId, branchId: c.get('branch_id') ?? null, pin: body.pin, deviceId: body.device_id },
+      settings,
+    );
+    if (!credentials.ok) {
+      return errorResponse(c, credentials.failure.message, credentials.failure.code, credentials.failure.status);
+    }
+
+    const result = await withTransaction(async (client) => {
+      // Serializes clock-ins of the same user
+      const userRes = await client.query('SELECT branch_id FROM users WHERE id = $1 FOR UPDATE', [userId]);
+      const open = await client.query('SELECT id FROM attendance WHERE user_id = $1 AND clock_out_at IS NULL', [userId]);
+      if (open.rows.length > 0) {
+        return txFailure('You are already clocked in', 'already_clocked_in', 409);
+      }
+
+      const shift = await matchShift(client, userId);
+      const branchId = shift?.branch_id ?? userRes.rows[0]?.branch_id ?? credentials.deviceBranchId;
+      const res = await client.query(
+        `INSERT INTO attendance (user_id, branch_id, shift_id, clock_in_device_id, note)
+         VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+        [userId, branchId, shift?.id ?? null, body.device_id ?? null, body.note?.trim() || null],
+      );
+      return { ok: true as const, id: res.rows[0].id as string };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const entry = await pool.query(`${ENTRY_SELECT} WHERE a.id = $1`, [result.id]);
+    return successResponse(c, 'Clocked in successfully', formatEntry(entry.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to clock in', (err as Error).message);
+  }
+}
+
+// ── ClockOut ────────────────────────────────────────────────────────────────
+
+export async function clockOut(c: Context) {
+  const userId = c.get('user_id');
+  const body = await readClockBody(c);
+  if (!body) {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (!validNote(body.note)) {
+    return errorResponse(c, `Note must be at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  try {
+    const settings = await loadAttendanceSettings(pool);
+    const credentials = await checkClockCredentials(
+      pool,
+      { userId, branchId: c.get('branch_id') ?? null, pin: body.pin, deviceId: body.device_id },
+      settings,
+    );
+    if (!credentials.ok) {
+      return errorResponse(c, credentials.failure.message, credentials.failure.code, credentials.failure.status);
+    }
+
+    const res = await pool.query(
+      `UPDATE attendance
+       SET clock_out_at = GREATEST(NOW(), clock_in_at + INTERVAL '1 second'), clock_out_device_id = $2,
+           note = COALESCE($3, note)
+       WHERE user_id = $1 AND clock_out_at IS NULL
+       RETURNING id`,
+      [userId, body.device_id ?? null, body.note?.trim() || null],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'You are not clocked in', 'not_clocked_in', 409);
+    }
+
+    const entry = await pool.query(`${ENTRY_SELECT} WHERE a.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Clocked out successfully', formatEntry(entry.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to clock out', (err as Error).message);
+  }
+}
+
+// ── GetMyAttendance ─────────────────────────────────────────────────────────
+// The caller's open entry, their entries of the last RECENT_DAYS days and
+// their shifts of the next UPCOMING_DAYS.
+
+export async function getMyAttendance(c: Context) {
+  const userId = c.get('user_id');
+
+  try {
+    const [settings, entries, shifts] = await Promise.all([
+      loadAttendanceSettings(pool),
+      pool.query(
+        `${ENTRY_SELECT}
+         WHERE a.user_id = $1 AND (a.clock_out_at IS NULL OR a.clock_in_at >= NOW() - make_interval(days => $2))
+         ORDER BY a.clock_in_at DESC`,
+        [userId, RECENT_DAYS],
+      ),
+      pool.query(
+        `${SHIFT_SELECT}
+         WHERE s.user_id = $1 AND s.ends_at > NOW() AND s.starts_at < NOW() + make_interval(days => $2)
+         ORDER BY s.starts_at ASC`,
+        [userId, UPCOMING_DAYS],
+      ),
+    ]);
+    const formatted = entries.rows.map(formatEntry);
+    return successResponse(c, 'Attendance retrieved successfully', {
+      clocked_in: formatted.some((e) => e.open),
+      open_entry: formatted.find((e) => e.open) ?? null,
+      requires_pin: settings.requirePin,
+      requires_terminal: settings.requireTerminal,
+      recent_entries: formatted.filter((e) => !e.open),
+      upcoming_shifts: shifts.rows,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch attendance', (err as Error).message);
+  }
+}
+
+// ── GetStaffShifts ──────────────────────────────────────────────────────────
+// Shifts starting over [from, to] (local dates; this week by default).
+
+export async function getStaffShifts(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || today;
+  const to = c.req.query('to') || addDays(from, 6);
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+  const userId = c.req.query('user_id') || null;
+  if (userId && !isUUID(userId)) {
+    return errorResponse(c, 'Invalid user_id', 'invalid_user_id', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `${SHIFT_SELECT}
+       WHERE DATE(s.starts_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR s.branch_id = $4)
+         AND ($5::uuid IS NULL OR s.user_id = $5)
+       ORDER BY s.starts_at ASC, u.last_name ASC`,
+      [from, to, RESTAURANT_TIMEZONE, scope.branchId, userId],
+    );
+    return successResponse(c, 'Shifts retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch shifts', (err as Error).message);
+  }
+}
+
+// ── CreateStaffShift ────────────────────────────────────────────────────────
+// Schedules a staff member's shift. Their shifts can't overlap.
+
+export async function createStaffShift(c: Context) {
+  const createdBy = c.get('user_id');
+
+  let body: { user_id?: string; branch_id?: string; starts_at?: string; ends_at?: string; break_minutes?: number; note?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.user_id || !isUUID(body.user_id)) {
+    return errorResponse(c, 'user_id is required', 'invalid_user_id', 400);
+  }
+  if (!validTimestamp(body.starts_at) || !validTimestamp(body.ends_at)) {
+    return errorResponse(c, 'starts_at and ends_at must be ISO 8601 timestamps', 'invalid_window', 400);
+  }
+  const minutes = (Date.parse(body.ends_at) - Date.parse(body.starts_at)) / 60_000;
+  if (minutes <= 0) {
+    return errorResponse(c, 'ends_at must be after starts_at', 'invalid_window', 400);
+  }
+  if (minutes > MAX_SHIFT_HOURS * 60) {
+    return errorResponse(c, `A shift can be at most ${MAX_SHIFT_HOURS} hours`, 'shift_too_long', 400);
+  }
+  const breakMinutes = body.break_minutes ?? 0;
+  if (!Number.isInteger(breakMinutes) || breakMinutes < 0 || breakMinutes > MAX_BREAK_MINUTES || breakMinutes >= minutes) {
+    return errorResponse(c, `break_minutes must be a whole number from 0 to ${MAX_BREAK_MINUTES}, shorter than the shift`, 'invalid_break_minutes', 400);
+  }
+  if (body.note != null && (typeof body.note !== 'string' || body.note.length > 200)) {
+    return errorResponse(c, 'Note must be at most 200 characters', 'invalid_shift_note', 400);
+  }
+
+  try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
+    const result = await withTransaction(async (client) => {
+      const userRes = await client.query(
+        `SELECT id FROM users
+         WHERE id = $1 AND is_active = true AND deleted_at IS NULL AND (branch_id IS NULL OR branch_id = $2)
+         FOR UPDATE`,
+        [body.user_id, branch.branchId],
+      );
+      if (userRes.rows.length === 0) {
+        return txFailure('User not found', 'user_not_found', 404);
+      }
+
+      const overlap = await client.query(
+        'SELECT 1 FROM staff_shifts WHERE user_id = $1 AND starts_at < $3 AND ends_at > $2',
+        [body.user_id, body.starts_at, body.ends_at],
+      );
+      if (overlap.rows.length > 0) {
+        return txFailure('This staff member already has an overlapping shift', 'shift_overlap', 409);
+      }
+
+      const res = await client.query(
+        `INSERT INTO staff_shifts (user_id, branch_id, starts_at, ends_at, break_minutes, note, created_by)
+         VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+        [body.user_id, branch.branchId, body.starts_at, body.ends_at, breakMinutes, body.note?.trim() || null, createdBy ?? null],
+      );
+      return { ok: true as const, id: res.rows[0].id as string };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const shift = await pool.query(`${SHIFT_SELECT} WHERE s.id = $1`, [result.id]);
+    return successResponse(c, 'Shift scheduled successfully', shift.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to schedule shift', (err as Error).message);
+  }
+}
+
+// ── DeleteStaffShift ────────────────────────────────────────────────────────
+// Entries already matched to the shift become unscheduled time.
+
+export async function deleteStaffShift(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Shift not found', 'shift_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      'DELETE FROM staff_shifts WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2) RETURNING id',
+      [id, c.get('branch_id') ?? null],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Shift not found', 'shift_not_found', 404);
+    }
+    return successResponse(c, 'Shift deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete shift', (err as Error).message);
+  }
+}
+
+// ── GetAttendance ───────────────────────────────────────────────────────────
+// Entries newest first, by the local date they started; ?open=true for the
+// staff clocked in right now.
+
+export async function getAttendance(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const from = c.req.query('from');
+  const to = c.req.query('to');
+  const userId = c.req.query('user_id');
+  const open = c.req.query('open') === 'true';
+
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to)) || (from && to && from > to)) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+  if (userId && !isUUID(userId)) {
+    return errorResponse(c, 'Invalid user_id', 'invalid_user_id', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (scope.branchId) {
+    conditions.push(`a.branch_id = $${paramIdx++}`);
+    params.push(scope.branchId);
+  }
+  if (userId) {
+    conditions.push(`a.user_id = $${paramIdx++}`);
+    params.push(userId);
+  }
+  if (from) {
+    conditions.push(`DATE(a.clock_in_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') >= $${paramIdx++}`);
+    params.push(from);
+  }
+  if (to) {
+    conditions.push(`DATE(a.clock_in_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') <= $${paramIdx++}`);
+    params.push(to);
+  }
+  if (open) {
+    conditions.push('a.clock_out_at IS NULL');
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM attendance a ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${ENTRY_SELECT} ${where}
+           ORDER BY a.clock_in_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows.map(formatEntry);
+      },
+    );
+    return paginatedResponse(c, 'Attendance retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'clock_in_at:desc',
+      filters: { from, to, user_id: userId, open: open || undefined, branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch attendance', (err as Error).message);
+  }
+}
+
+// ── AdjustAttendance ────────────────────────────────────────────────────────
+// A manager's correction: a forgotten clock-out, or times entered wrong. The
+// reason is kept on the entry. The times can't overlap the staff member's
+// other entries.
+
+export async function adjustAttendance(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Attendance entry not found', 'attendance_not_found', 404);
+  }
+
+  let body: { clock_in_at?: string; clock_out_at?: string | null; reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const reason = typeof body.reason === 'string' ? body.reason.trim() : '';
+  if (!reason || reason.length > MAX_NOTE_LENGTH) {
+    return errorResponse(c, `A reason of at most ${MAX_NOTE_LENGTH} characters is required`, 'invalid_reason', 400);
+  }
+  if (body.clock_in_at === undefined && body.clock_out_at === undefined) {
+    return errorResponse(c, 'Nothing to change', 'no_fields', 400);
+  }
+  if ((body.clock_in_at !== undefined && !validTimestamp(body.clock_in_at))
+      || (body.clock_out_at != null && !validTimestamp(body.clock_out_at))) {
+    return errorResponse(c, 'clock_in_at and clock_out_at must be ISO 8601 timestamps', 'invalid_clock_times', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const current = await client.query(
+        `SELECT user_id, clock_in_at, clock_out_at FROM attendance
+         WHERE id = $1 AND ($2::uuid IS NULL OR branch_id = $2)
+         FOR UPDATE`,
+        [id, c.get('branch_id') ?? null],
+      );
+      const entry = current.rows[0];
+      if (!entry) {
+        return txFailure('Attendance entry not found', 'attendance_not_found', 404);
+      }
+
+      const clockIn = body.clock_in_at !== undefined ? new Date(body.clock_in_at) : new Date(entry.clock_in_at);
+      const clockOut = body.clock_out_at !== undefined
+        ? (body.clock_out_at === null ? null : new Date(body.clock_out_at))
+        : (entry.clock_out_at ? new Date(entry.clock_out_at) : null);
+      const now = Date.now();
+      if (clockIn.getTime() > now || (clockOut && (clockOut.getTime() > now || clockOut.getTime() <= clockIn.getTime()))) {
+        return txFailure('Clock-out must be after clock-in, and neither in the future', 'invalid_clock_times', 400);
+      }
+      if (clockOut && clockOut.getTime() - clockIn.getTime() > MAX_SHIFT_HOURS * 2 * 3_600_000) {
+        return txFailure(`An entry can be at most ${MAX_SHIFT_HOURS * 2} hours`, 'invalid_clock_times', 400);
+      }
+
+      const overlap = await client.query(
+        `SELECT 1 FROM attendance
+         WHERE user_id = $1 AND id <> $2
+           AND clock_in_at < COALESCE($4::timestamptz, 'infinity') AND COALESCE(clock_out_at, 'infinity') > $3`,
+        [entry.user_id, id, clockIn.toISOString(), clockOut?.toISOString() ?? null],
+      );
+      if (overlap.rows.length > 0) {
+        return txFailure('The times overlap another of this staff member\'s entries', 'attendance_overlap', 409);
+      }
+
+      await client.query(
+        `UPDATE attendance
+         SET clock_in_at = $2, clock_out_at = $3, adjusted_by = $4, adjusted_at = NOW(), adjust_reason = $5
+         WHERE id = $1`,
+        [id, clockIn.toISOString(), clockOut?.toISOString() ?? null, c.get('user_id'), reason],
+      );
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${ENTRY_SELECT} WHERE a.id = $1`, [id]);
+    return successResponse(c, 'Attendance entry adjusted successfully', formatEntry(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to adjust attendance entry', (err as Error).message);
+  }
+}
+
+// ── SetAttendancePin ────────────────────────────────────────────────────────
+// { "pin": "1234" } sets a staff member's clock-in PIN, null clears it.
+
+export async function setAttendancePin(c: Context) {
+  const userId = c.req.param('id');
+  if (!isUUID(userId)) {
+    return errorResponse(c, 'User not found', 'user_not_found', 404);
+  }
+
+  let body: { pin?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (body.pin !== null && !isAttendancePin(body.pin)) {
+    return errorResponse(c, 'pin must be 4 to 8 digits, or null to clear it', 'invalid_pin_format', 400);
+  }
+
+  try {
+    const hash = body.pin ? await hashAttendancePin(body.pin) : null;
+    const res = await pool.query(
+      `UPDATE users SET attendance_pin_hash = $2, attendance_pin_failed_attempts = 0, attendance_pin_locked_until = NULL,
+                        updated_at = NOW()
+       WHERE id = $1 AND deleted_at IS NULL AND ($3::uuid IS NULL OR branch_id = $3)
+       RETURNING id`,
+      [userId, hash, c.get('branch_id') ?? null],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'User not found', 'user_not_found', 404);
+    }
+    return successResponse(c, hash ? 'Attendance PIN set successfully' : 'Attendance PIN cleared successfully', {
+      user_id: userId,
+      has_pin: hash !== null,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to set attendance PIN', (err as Error).message);
+  }
+}
+
+// ── SetAttendanceTerminal ───────────────────────────────────────────────────
+// { "attendance_clock": true } lets staff clock in and out on the terminal.
+
+export async function setAttendanceTerminal(c: Context) {
+  const deviceId = c.req.param('device_id');
+  if (!isDeviceId(deviceId)) {
+    return errorResponse(c, 'Device not found', 'not_found', 404);
+  }
+
+  let body: { attendance_clock?: boolean };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (typeof body.attendance_clock !== 'boolean') {
+    return errorResponse(c, 'attendance_clock must be true or false', 'invalid_attendance_clock', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `UPDATE device_print_preferences SET attendance_clock = $2, updated_by = $3, updated_at = NOW()
+       WHERE device_id = $1 AND ($4::uuid IS NULL OR branch_id = $4)
+       RETURNING device_id, name, branch_id, attendance_clock`,
+      [deviceId, body.attendance_clock, c.get('user_id'), c.get('branch_id') ?? null],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Device not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Attendance terminal updated successfully', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update attendance terminal', (err as Error).message);
+  }
+}
+
+// ── GetTimesheets ───────────────────────────────────────────────────────────
+
+export async function getTimesheets(c: Context) {
+  const range = timesheetRange(c);
+  if (!('from' in range)) {
+    return errorResponse(c, range.message, range.code, 400);
+  }
+  const userId = c.req.query('user_id') || null;
+  if (userId && !isUUID(userId)) {
+    return errorResponse(c, 'Invalid user_id', 'invalid_user_id', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const settings = await loadAttendanceSettings(pool);
+    const staff = await buildTimesheets(pool, range.from, range.to, { branchId: scope.branchId, userId }, settings);
+    const totals = {
+      worked_minutes: staff.reduce((sum, s) => sum + s.worked_minutes, 0),
+      scheduled_minutes: staff.reduce((sum, s) => sum + s.scheduled_minutes, 0),
+      overtime_minutes: staff.reduce((sum, s) => sum + s.overtime_minutes, 0),
+      unscheduled_minutes: staff.reduce((sum, s) => sum + s.unscheduled_minutes, 0),
+      open_entries: staff.reduce((sum, s) => sum + s.open_entries, 0),
+      missed_shifts: staff.reduce((sum, s) => sum + s.missed_shifts, 0),
+    };
+    return successResponse(c, 'Timesheets retrieved successfully', {
+      from: range.from,
+      to: range.to,
+      branch_id: scope.branchId,
+      overtime_grace_minutes: settings.overtimeGraceMinutes,
+      totals,
+      staff,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate timesheets', (err as Error).message);
+  }
+}
+
+// ── ExportTimesheets ────────────────────────────────────────────────────────
+// One row per staff member and day, hours to two decimals, for payroll.
+
+export async function exportTimesheets(c: Context) {
+  const range = timesheetRange(c);
+  if (!('from' in range)) {
+    return errorResponse(c, range.message, range.code, 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const hours = (minutes: number) => (minutes / 60).toFixed(2);
+  try {
+    const settings = await loadAttendanceSettings(pool);
+    const staff = await buildTimesheets(pool, range.from, range.to, { branchId: scope.branchId, userId: null }, settings);
+
+    const rows = [
+      [
+        'date', 'user_id', 'username', 'first_name', 'last_name', 'role', 'entries',
+        'worked_hours', 'scheduled_hours', 'overtime_hours', 'unscheduled_hours', 'late_minutes', 'early_leave_minutes',
+      ],
+      ...staff.flatMap((s) => s.days.map((d) => [
+        d.date, s.user_id, s.username, s.first_name, s.last_name, s.role, d.entries,
+        hours(d.worked_minutes), hours(d.scheduled_minutes), hours(d.overtime_minutes), hours(d.unscheduled_minutes),
+        d.late_minutes, d.early_leave_minutes,
+      ])),
+    ];
+    const csv = toCsv(rows);
+    const record = await logExport(pool, c, {
+      type: 'timesheets',
+      format: 'csv',
+      params: { from: range.from, to: range.to, branch_id: scope.branchId },
+      rowCount: rows.length - 1,
+    });
+
+    return c.body(csv, 200, exportFileHeaders('text/csv; charset=utf-8', `timesheets-${range.from}-${range.to}.csv`, record));
+  } catch (err) {
+    return errorResponse(c, 'Failed to export timesheets', (err as Error).message);
+  }
+}
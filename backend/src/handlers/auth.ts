@@ -1,13 +1,35 @@
 import type { Context } from 'hono';
 import bcrypt from 'bcryptjs';
-import { eq, and } from 'drizzle-orm';
-import { db } from '../db/connection.js';
+import { eq, and, isNull } from 'drizzle-orm';
+import { db, pool } from '../db/connection.js';
 import { users } from '../db/schema.js';
 import { generateToken } from '../lib/jwt.js';
+import { setSessionCookies, clearSessionCookies } from '../lib/session.js';
 import { successResponse, errorResponse } from '../lib/response.js';
+import { checkPassword, passwordPolicy, weakPasswordResponse } from '../lib/password.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { isDeviceId, registerDeviceLogin } from '../services/devices.js';
+import { lockedUntil, recordFailedLogin, clearFailedLogins } from '../services/login-lockout.js';
+import { issuePasswordReset, findPasswordResetUser, consumePasswordReset } from '../services/password-reset.js';
+
+const EMAIL_RE = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
+
+function clientIp(c: Context): string | null {
+  return c.req.header('x-forwarded-for')?.split(',')[0]?.trim() || c.req.header('x-real-ip') || null;
+}
+
+function accountLocked(c: Context, until: Date) {
+  c.header('Retry-After', String(Math.max(1, Math.ceil((until.getTime() - Date.now()) / 1000))));
+  return errorResponse(
+    c,
+    `Too many failed login attempts. Try again after ${until.toISOString()} or reset your password.`,
+    'account_locked',
+    429,
+  );
+}
 
 export async function login(c: Context) {
-  let body: { username?: string; password?: string };
+  let body: { username?: string; password?: string; device_id?: string };
   try {
     body = await c.req.json();
   } catch {
@@ -17,24 +39,40 @@ export async function login(c: Context) {
   if (!body.username || !body.password) {
     return errorResponse(c, 'Username and password are required', 'missing_credentials', 400);
   }
+  if (body.device_id !== undefined && !isDeviceId(body.device_id)) {
+    return errorResponse(c, 'Invalid device_id', 'invalid_device_id', 400);
+  }
 
   try {
     const [user] = await db
       .select()
       .from(users)
-      .where(and(eq(users.username, body.username), eq(users.isActive, true)))
+      .where(and(eq(users.username, body.username), eq(users.isActive, true), isNull(users.deletedAt)))
       .limit(1);
 
     if (!user) {
       return errorResponse(c, 'Invalid username or password', 'invalid_credentials', 401);
     }
 
+    // Checked before the password, so a locked account can't be guessed at
+    const locked = lockedUntil(user);
+    if (locked) {
+      return accountLocked(c, locked);
+    }
+
     const validPassword = await bcrypt.compare(body.password, user.passwordHash);
     if (!validPassword) {
+      const lockedNow = await recordFailedLogin(pool, user.id);
+      if (lockedNow) return accountLocked(c, lockedNow);
       return errorResponse(c, 'Invalid username or password', 'invalid_credentials', 401);
     }
+    if (user.failedLoginAttempts > 0 || user.lockedUntil) {
+      await clearFailedLogins(pool, user.id);
+    }
 
-    const token = generateToken({ id: user.id, username: user.username, role: user.role });
+    const token = generateToken({
+      id: user.id, username: user.username, role: user.role, branchId: user.branchId, tokenVersion: user.tokenVersion,
+    });
 
     const userData = {
       id: user.id,
@@ -43,12 +81,26 @@ export async function login(c: Context) {
       first_name: user.firstName,
       last_name: user.lastName,
       role: user.role,
+      branch_id: user.branchId,
       is_active: user.isActive,
       created_at: user.createdAt,
       updated_at: user.updatedAt,
     };
 
-    return successResponse(c, 'Login successful', { token, user: userData });
+    // The device's print preferences, so a terminal picks up its printers on login
+    const printPreferences = body.device_id
+      ? await registerDeviceLogin(pool, body.device_id, user.id, user.branchId)
+      : null;
+
+    // Browsers get the token as a cookie as well; API clients keep using it as a Bearer token
+    const csrfToken = setSessionCookies(c, token);
+
+    return successResponse(c, 'Login successful', {
+      token,
+      csrf_token: csrfToken,
+      user: userData,
+      print_preferences: printPreferences,
+    });
   } catch (err) {
     return errorResponse(c, 'Database error', (err as Error).message);
   }
@@ -66,12 +118,13 @@ export async function getCurrentUser(c: Context) {
         firstName: users.firstName,
         lastName: users.lastName,
         role: users.role,
+        branchId: users.branchId,
         isActive: users.isActive,
         createdAt: users.createdAt,
         updatedAt: users.updatedAt,
       })
       .from(users)
-      .where(eq(users.id, userId))
+      .where(and(eq(users.id, userId), isNull(users.deletedAt)))
       .limit(1);
 
     if (!user) {
@@ -85,6 +138,8 @@ export async function getCurrentUser(c: Context) {
       first_name: user.firstName,
       last_name: user.lastName,
       role: user.role,
+      branch_id: user.branchId,
+      permissions: [...c.get('permissions')].sort(),
       is_active: user.isActive,
       created_at: user.createdAt,
       updated_at: user.updatedAt,
@@ -95,5 +150,103 @@ export async function getCurrentUser(c: Context) {
 }
 
 export async function logout(c: Context) {
+  clearSessionCookies(c);
   return successResponse(c, 'Logout successful');
 }
+
+// ── RequestPasswordReset ────────────────────────────────────────────────────
+// Always answers the same, whether or not the address belongs to an
+// account, so the form can't be used to find out who has one.
+
+const RESET_REQUESTED = 'If an account exists for that email, a password reset link has been sent to it';
+
+export async function requestPasswordReset(c: Context) {
+  let body: { email?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const email = body.email?.trim().toLowerCase() ?? '';
+  if (!EMAIL_RE.test(email)) {
+    return errorResponse(c, 'A valid email is required', 'invalid_email', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT id, email, NULLIF(TRIM(CONCAT(first_name, ' ', last_name)), '') AS name, username
+       FROM users
+       WHERE LOWER(email) = $1 AND is_active = true AND deleted_at IS NULL`,
+      [email],
+    );
+    const user = res.rows[0];
+    if (user) {
+      await withTransaction((client) =>
+        issuePasswordReset(client, { id: user.id, email: user.email, name: user.name || user.username }, clientIp(c)),
+      );
+    }
+    return successResponse(c, RESET_REQUESTED);
+  } catch (err) {
+    return errorResponse(c, 'Failed to request password reset', (err as Error).message);
+  }
+}
+
+// ── ConfirmPasswordReset ────────────────────────────────────────────────────
+// Also ends the account's sessions, in case the reset is over a stolen one.
+
+export async function confirmPasswordReset(c: Context) {
+  let body: { token?: string; new_password?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.token || !body.new_password) {
+    return errorResponse(c, 'token and new_password are required', 'missing_fields', 400);
+  }
+
+  try {
+    const owner = await findPasswordResetUser(pool, body.token);
+    if (!owner) {
+      return errorResponse(c, 'This password reset link is invalid or has expired', 'invalid_reset_token', 400);
+    }
+    const policyCheck = await checkPassword(body.new_password, owner);
+    if (policyCheck) {
+      return weakPasswordResponse(c, policyCheck, 'new_password');
+    }
+
+    const passwordHash = await bcrypt.hash(body.new_password, 12);
+    const token = body.token;
+    const result = await withTransaction(async (client) => {
+      const userId = await consumePasswordReset(client, token);
+      if (!userId) {
+        return txFailure('This password reset link is invalid or has expired', 'invalid_reset_token', 400);
+      }
+      const updated = await client.query(
+        `UPDATE users SET password_hash = $2, failed_login_attempts = 0, locked_until = NULL,
+                          token_version = token_version + 1, updated_at = NOW()
+         WHERE id = $1 AND is_active = true AND deleted_at IS NULL
+         RETURNING id`,
+        [userId, passwordHash],
+      );
+      if (updated.rows.length === 0) {
+        return txFailure('This password reset link is invalid or has expired', 'invalid_reset_token', 400);
+      }
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    return successResponse(c, 'Password has been reset. You can now log in with the new password');
+  } catch (err) {
+    return errorResponse(c, 'Failed to reset password', (err as Error).message);
+  }
+}
+
+// ── GetPasswordPolicy ───────────────────────────────────────────────────────
+// Public, so the reset form can show the rules before the first attempt.
+
+export async function getPasswordPolicy(c: Context) {
+  return successResponse(c, 'Password policy retrieved successfully', passwordPolicy());
+}
@@ -0,0 +1,333 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+
+const CODE_RE = /^[A-Z0-9_-]{2,20}$/;
+
+const BRANCH_SELECT = `
+  SELECT b.id, b.code, b.name, b.address, b.phone, b.latitude, b.longitude, b.is_default, b.is_active, b.created_at, b.updated_at,
+         (SELECT COUNT(*) FROM dining_tables t WHERE t.branch_id = b.id AND t.deleted_at IS NULL) AS table_count,
+         (SELECT COUNT(*) FROM users u WHERE u.branch_id = b.id AND u.deleted_at IS NULL) AS staff_count
+  FROM branches b`;
+
+function formatBranch(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    code: row.code,
+    name: row.name,
+    address: row.address,
+    phone: row.phone,
+    latitude: row.latitude !== null ? Number(row.latitude) : null,
+    longitude: row.longitude !== null ? Number(row.longitude) : null,
+    is_default: row.is_default,
+    is_active: row.is_active,
+    table_count: Number(row.table_count),
+    staff_count: Number(row.staff_count),
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+// Branches are set up by head office; branch managers only see their own.
+function requireHeadOffice(c: Context) {
+  if (c.get('branch_id')) {
+    return errorResponse(c, 'Only head office can manage branches', 'branch_forbidden', 403);
+  }
+  return null;
+}
+
+function canSeeBranch(c: Context, branchId: string): boolean {
+  const own = c.get('branch_id');
+  return !own || own === branchId;
+}
+
+// ── GetBranches ─────────────────────────────────────────────────────────────
+
+export async function getBranches(c: Context) {
+  const own = c.get('branch_id');
+  const includeInactive = c.req.query('include_inactive') === 'true';
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  if (own) {
+    params.push(own);
+    conditions.push(`b.id = $${params.length}`);
+  } else if (!includeInactive) {
+    conditions.push('b.is_active = true');
+  }
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const res = await pool.query(`${BRANCH_SELECT} ${where} ORDER BY b.is_default DESC, b.name ASC`, params);
+    return successResponse(c, 'Branches retrieved successfully', res.rows.map(formatBranch));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch branches', (err as Error).message);
+  }
+}
+
+// ── GetPublicBranches ───────────────────────────────────────────────────────
+// For the online menu's branch picker.
+
+export async function getPublicBranches(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT id, code, name, address, phone, is_default
+       FROM branches WHERE is_active = true
+       ORDER BY is_default DESC, name ASC`,
+    );
+    return successResponse(c, 'Branches retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch branches', (err as Error).message);
+  }
+}
+
+// ── CreateBranch ────────────────────────────────────────────────────────────
+
+export async function createBranch(c: Context) {
+  const forbidden = requireHeadOffice(c);
+  if (forbidden) return forbidden;
+
+  let body: { code?: string; name?: string; address?: string; phone?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const code = (body.code || '').trim().toUpperCase();
+  const name = (body.name || '').trim();
+  if (!CODE_RE.test(code)) {
+    return errorResponse(c, 'Code must be 2-20 letters, digits, dashes or underscores', 'invalid_code', 400);
+  }
+  if (!name || name.length > 100) {
+    return errorResponse(c, 'Name is required (max 100 characters)', 'invalid_name', 400);
+  }
+
+  try {
+    const existing = await pool.query('SELECT 1 FROM branches WHERE code = $1', [code]);
+    if (existing.rows.length > 0) {
+      return errorResponse(c, 'A branch with this code already exists', 'duplicate_code', 409);
+    }
+
+    const res = await pool.query(
+      `INSERT INTO branches (code, name, address, phone) VALUES ($1, $2, $3, $4) RETURNING id`,
+      [code, name, body.address?.trim() || null, body.phone?.trim() || null],
+    );
+
+    const created = await pool.query(`${BRANCH_SELECT} WHERE b.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Branch created successfully', formatBranch(created.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create branch', (err as Error).message);
+  }
+}
+
+// ── UpdateBranch ────────────────────────────────────────────────────────────
+// The default branch can't be deactivated: website orders and the menu's
+// stock figures fall back to it.
+
+export async function updateBranch(c: Context) {
+  const forbidden = requireHeadOffice(c);
+  if (forbidden) return forbidden;
+
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+  }
+
+  let body: {
+    name?: string;
+    address?: string | null;
+    phone?: string | null;
+    latitude?: number | null;
+    longitude?: number | null;
+    is_active?: boolean;
+    is_default?: boolean;
+  };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.name !== undefined && (!body.name.trim() || body.name.length > 100)) {
+    return errorResponse(c, 'Name is required (max 100 characters)', 'invalid_name', 400);
+  }
+  // The venue location is set or cleared as a pair
+  if (body.latitude !== undefined || body.longitude !== undefined) {
+    const cleared = body.latitude === null && body.longitude === null;
+    const valid = typeof body.latitude === 'number' && Math.abs(body.latitude) <= 90
+      && typeof body.longitude === 'number' && Math.abs(body.longitude) <= 180;
+    if (!cleared && !valid) {
+      return errorResponse(c, 'latitude and longitude must be given together as valid coordinates, or both null', 'invalid_location', 400);
+    }
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.name !== undefined) {
+    setClauses.push(`name = $${paramIdx++}`);
+    params.push(body.name.trim());
+  }
+  if (body.address !== undefined) {
+    setClauses.push(`address = $${paramIdx++}`);
+    params.push(body.address?.trim() || null);
+  }
+  if (body.phone !== undefined) {
+    setClauses.push(`phone = $${paramIdx++}`);
+    params.push(body.phone?.trim() || null);
+  }
+  if (body.latitude !== undefined) {
+    setClauses.push(`latitude = $${paramIdx++}`, `longitude = $${paramIdx++}`);
+    params.push(body.latitude, body.longitude);
+  }
+  if (body.is_active !== undefined) {
+    setClauses.push(`is_active = $${paramIdx++}`);
+    params.push(body.is_active);
+  }
+
+  if (setClauses.length === 0 && body.is_default !== true) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const currentRes = await client.query('SELECT is_default FROM branches WHERE id = $1 FOR UPDATE', [id]);
+      if (currentRes.rows.length === 0) {
+        return txFailure('Branch not found', 'branch_not_found', 404);
+      }
+      const willBeDefault = body.is_default === true || currentRes.rows[0].is_default;
+      if (willBeDefault && body.is_active === false) {
+        return txFailure('The default branch cannot be deactivated', 'default_branch', 400);
+      }
+
+      if (body.is_default === true && !currentRes.rows[0].is_default) {
+        await client.query('UPDATE branches SET is_default = false, updated_at = NOW() WHERE is_default = true');
+        setClauses.push('is_default = true');
+      }
+
+      setClauses.push('updated_at = NOW()');
+      params.push(id);
+      await client.query(`UPDATE branches SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`, params);
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${BRANCH_SELECT} WHERE b.id = $1`, [id]);
+    return successResponse(c, 'Branch updated successfully', formatBranch(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update branch', (err as Error).message);
+  }
+}
+
+// ── GetBranchSettings ───────────────────────────────────────────────────────
+// Every setting as it applies to the branch, marking which are overridden.
+
+export async function getBranchSettings(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id) || !canSeeBranch(c, id)) {
+    return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+  }
+
+  try {
+    const branchRes = await pool.query('SELECT id FROM branches WHERE id = $1', [id]);
+    if (branchRes.rows.length === 0) {
+      return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+    }
+
+    const res = await pool.query(
+      `SELECT s.setting_key, s.setting_type, s.category, s.description,
+              s.setting_value AS default_value, bs.setting_value AS branch_value, bs.updated_at
+       FROM system_settings s
+       LEFT JOIN branch_settings bs ON bs.setting_key = s.setting_key AND bs.branch_id = $1
+       ORDER BY s.category ASC, s.setting_key ASC`,
+      [id],
+    );
+
+    const settings = res.rows.map((row: Record<string, unknown>) => ({
+      setting_key: row.setting_key,
+      setting_type: row.setting_type,
+      category: row.category,
+      description: row.description,
+      value: row.branch_value ?? row.default_value,
+      default_value: row.default_value,
+      overridden: row.branch_value !== null,
+      updated_at: row.branch_value !== null ? row.updated_at : null,
+    }));
+
+    return successResponse(c, 'Branch settings retrieved successfully', settings);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch branch settings', (err as Error).message);
+  }
+}
+
+// ── UpdateBranchSettings ────────────────────────────────────────────────────
+// Body maps setting keys to the branch's value; null removes the override so
+// the branch follows the restaurant-wide setting again.
+
+export async function updateBranchSettings(c: Context) {
+  const forbidden = requireHeadOffice(c);
+  if (forbidden) return forbidden;
+
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+  }
+
+  let body: Record<string, unknown>;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const keys = Object.keys(body ?? {});
+  if (keys.length === 0) {
+    return errorResponse(c, 'No settings to update', 'no_fields', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const branchRes = await client.query('SELECT id FROM branches WHERE id = $1', [id]);
+      if (branchRes.rows.length === 0) {
+        return txFailure('Branch not found', 'branch_not_found', 404);
+      }
+
+      const knownRes = await client.query(
+        'SELECT setting_key FROM system_settings WHERE setting_key = ANY($1::text[])',
+        [keys],
+      );
+      const known = new Set(knownRes.rows.map((r) => r.setting_key));
+      const unknown = keys.find((k) => !known.has(k));
+      if (unknown) {
+        return txFailure(`Unknown setting: ${unknown}`, 'unknown_setting', 400);
+      }
+
+      for (const key of keys) {
+        const value = body[key];
+        if (value === null) {
+          await client.query('DELETE FROM branch_settings WHERE branch_id = $1 AND setting_key = $2', [id, key]);
+          continue;
+        }
+        await client.query(
+          `INSERT INTO branch_settings (branch_id, setting_key, setting_value, updated_by, updated_at)
+           VALUES ($1, $2, $3, $4, NOW())
+           ON CONFLICT (branch_id, setting_key)
+           DO UPDATE SET setting_value = EXCLUDED.setting_value, updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+          [id, key, String(value), userId],
+        );
+      }
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    return successResponse(c, 'Branch settings updated successfully', { updated: keys.length });
+  } catch (err) {
+    return errorResponse(c, 'Failed to update branch settings', (err as Error).message);
+  }
+}
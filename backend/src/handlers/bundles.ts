@@ -0,0 +1,127 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { invalidateCache } from '../lib/cache.js';
+import { getDefaultBranchId, isUUID, resolveBranchScope } from '../services/branches.js';
+import { loadBundleComponents, setBundleComponents, type BundleComponentInput } from '../services/bundles.js';
+import { getProductAvailability } from '../services/stock.js';
+import { syncStockAvailability } from '../services/stock-availability.js';
+
+// Components with their stock and the bundle's own availability at a branch,
+// so admins can see which component is holding the bundle back
+async function loadBundleDetail(productId: string, branchId: string) {
+  const components = (await loadBundleComponents(pool, [productId])).get(productId) ?? [];
+  const availability = await getProductAvailability(
+    pool, [productId, ...components.map((component) => component.product_id)], branchId,
+  );
+  const bundle = availability.get(productId);
+
+  return {
+    product_id: productId,
+    branch_id: branchId,
+    is_bundle: components.length > 0,
+    components: components.map((component) => {
+      const stock = availability.get(component.product_id);
+      return {
+        ...component,
+        in_stock: stock?.in_stock ?? true,
+        remaining_quantity: stock?.remaining ?? null,
+      };
+    }),
+    in_stock: bundle?.in_stock ?? true,
+    remaining_quantity: bundle?.remaining ?? null,
+    limiting_component: bundle?.limiting_component ?? null,
+  };
+}
+
+// ── GetProductBundle ────────────────────────────────────────────────────────
+// ?branch_id= picks the branch whose stock is shown (default: the main one).
+
+export async function getProductBundle(c: Context) {
+  const productId = c.req.param('id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const exists = await pool.query('SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL', [productId]);
+    if (exists.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+
+    const detail = await loadBundleDetail(productId, scope.branchId ?? await getDefaultBranchId(pool));
+    return successResponse(c, 'Product bundle retrieved successfully', detail);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch product bundle', (err as Error).message);
+  }
+}
+
+// ── SetProductBundle ────────────────────────────────────────────────────────
+// { components: [{ product_id, quantity }] } replaces the bundle's components;
+// an empty list makes it a plain product again.
+
+export async function setProductBundle(c: Context) {
+  const productId = c.req.param('id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  let body: { components?: BundleComponentInput[] };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const components = body.components;
+  if (!Array.isArray(components)) {
+    return errorResponse(c, 'components is required', 'missing_components', 400);
+  }
+  for (const component of components) {
+    if (!component || !isUUID(component.product_id)) {
+      return errorResponse(c, 'Each component needs a product_id', 'invalid_component', 400);
+    }
+    if (component.product_id === productId) {
+      return errorResponse(c, 'A bundle cannot contain itself', 'invalid_component', 400);
+    }
+    if (!Number.isInteger(component.quantity) || component.quantity < 1 || component.quantity > 100) {
+      return errorResponse(c, 'Component quantity must be a whole number from 1 to 100', 'invalid_quantity', 400);
+    }
+  }
+  const ids = components.map((component) => component.product_id);
+  if (new Set(ids).size !== ids.length) {
+    return errorResponse(c, 'Each product can only be listed once', 'duplicate_component', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const result = await setBundleComponents(client, productId, components);
+      if (!result.ok) {
+        return result;
+      }
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    // The new components may already be sold out, or no longer hold it back
+    await syncStockAvailability(pool, { productIds: [productId] });
+    invalidateCache('menu');
+
+    const detail = await loadBundleDetail(productId, scope.branchId ?? await getDefaultBranchId(pool));
+    return successResponse(c, 'Product bundle updated successfully', detail);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update product bundle', (err as Error).message);
+  }
+}
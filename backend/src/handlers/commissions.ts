@@ -0,0 +1,321 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { localClock, addDays } from '../lib/clock.js';
+import { toCsv } from '../lib/csv.js';
+import { logExport, exportFileHeaders } from '../services/data-exports.js';
+import {
+  COMMISSION_RULE_TYPES,
+  calculateCommissions,
+  monthBounds,
+  type CommissionRuleType,
+} from '../services/commissions.js';
+import { resolveBranchScope } from '../services/branches.js';
+
+const MONTH_RE = /^\d{4}-(0[1-9]|1[0-2])$/;
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+const COMMISSION_ROLES = ['admin', 'manager', 'server', 'counter', 'kitchen'];
+
+type RuleBody = {
+  name?: string;
+  rule_type?: string;
+  category_id?: string | null;
+  product_id?: string | null;
+  percentage?: number | null;
+  bonus_amount?: number | null;
+  role?: string | null;
+  effective_from?: string;
+};
+
+function formatRule(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    name: row.name,
+    rule_type: row.rule_type,
+    category_id: row.category_id,
+    category_name: row.category_name ?? null,
+    product_id: row.product_id,
+    product_name: row.product_name ?? null,
+    percentage: row.percentage !== null ? Number(row.percentage) : null,
+    bonus_amount: row.bonus_amount !== null ? Number(row.bonus_amount) : null,
+    role: row.role,
+    effective_from: row.effective_from,
+    effective_until: row.effective_until,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+const RULE_SELECT = `
+  SELECT r.id, r.name, r.rule_type, r.category_id, r.product_id, r.percentage, r.bonus_amount, r.role,
+         to_char(r.effective_from, 'YYYY-MM-DD') AS effective_from,
+         to_char(r.effective_until, 'YYYY-MM-DD') AS effective_until,
+         r.created_at, r.updated_at, c.name AS category_name, p.name AS product_name
+  FROM commission_rules r
+  LEFT JOIN categories c ON c.id = r.category_id
+  LEFT JOIN products p ON p.id = r.product_id`;
+
+function validateRuleBody(body: RuleBody): { message: string; code: string } | null {
+  if (!body.name) return { message: 'Rule name is required', code: 'missing_name' };
+  if (!COMMISSION_RULE_TYPES.includes(body.rule_type as CommissionRuleType)) {
+    return { message: 'Rule type must be category_percentage or item_bonus', code: 'invalid_rule_type' };
+  }
+  if (body.rule_type === 'category_percentage') {
+    if (!body.category_id) return { message: 'Category is required for percentage rules', code: 'missing_category_id' };
+    if (typeof body.percentage !== 'number' || body.percentage <= 0 || body.percentage > 100) {
+      return { message: 'Percentage must be greater than 0 and at most 100', code: 'invalid_percentage' };
+    }
+  } else {
+    if (!body.product_id) return { message: 'Product is required for item bonus rules', code: 'missing_product_id' };
+    if (typeof body.bonus_amount !== 'number' || body.bonus_amount <= 0) {
+      return { message: 'Bonus amount must be greater than zero', code: 'invalid_bonus_amount' };
+    }
+  }
+  if (body.role && !COMMISSION_ROLES.includes(body.role)) {
+    return { message: 'Invalid role', code: 'invalid_role' };
+  }
+  if (body.effective_from !== undefined && !DATE_RE.test(body.effective_from)) {
+    return { message: 'Effective date must be YYYY-MM-DD', code: 'invalid_effective_from' };
+  }
+  return null;
+}
+
+// ── GetCommissionRules ──────────────────────────────────────────────────────
+
+export async function getCommissionRules(c: Context) {
+  const includeHistory = c.req.query('include_history') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${RULE_SELECT} ${includeHistory ? '' : 'WHERE r.effective_until IS NULL'}
+       ORDER BY r.name ASC, r.effective_from DESC`,
+    );
+    return successResponse(c, 'Commission rules retrieved successfully', res.rows.map(formatRule));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch commission rules', (err as Error).message);
+  }
+}
+
+// ── CreateCommissionRule ────────────────────────────────────────────────────
+
+export async function createCommissionRule(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: RuleBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateRuleBody(body);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  const isPercentage = body.rule_type === 'category_percentage';
+  try {
+    const res = await pool.query(
+      `INSERT INTO commission_rules
+         (name, rule_type, category_id, product_id, percentage, bonus_amount, role, effective_from, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+      [
+        body.name,
+        body.rule_type,
+        isPercentage ? body.category_id : null,
+        isPercentage ? null : body.product_id,
+        isPercentage ? body.percentage : null,
+        isPercentage ? null : body.bonus_amount,
+        body.role || null,
+        body.effective_from || localClock().date,
+        userId,
+      ],
+    );
+
+    const created = await pool.query(`${RULE_SELECT} WHERE r.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Commission rule created successfully', formatRule(created.rows[0]), 201);
+  } catch (err) {
+    const message = (err as Error).message;
+    if (message.includes('foreign key')) {
+      return errorResponse(c, 'Category or product not found', 'not_found', 400);
+    }
+    return errorResponse(c, 'Failed to create commission rule', message);
+  }
+}
+
+// ── UpdateCommissionRule ────────────────────────────────────────────────────
+// Renames apply in place. A changed rate or role takes effect today: the old
+// version is ended yesterday so months already worked keep their payout.
+
+export async function updateCommissionRule(c: Context) {
+  const ruleId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { name?: string; percentage?: number; bonus_amount?: number; role?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const today = localClock().date;
+  try {
+    const result = await withTransaction(async (client) => {
+      const currentRes = await client.query(
+        `SELECT id, name, rule_type, category_id, product_id, percentage, bonus_amount, role,
+                to_char(effective_from, 'YYYY-MM-DD') AS effective_from
+         FROM commission_rules
+         WHERE id = $1 AND effective_until IS NULL
+         FOR UPDATE`,
+        [ruleId],
+      );
+      if (currentRes.rows.length === 0) {
+        return txFailure('Commission rule not found', 'not_found', 404);
+      }
+
+      const current = currentRes.rows[0];
+      const next: RuleBody = {
+        name: body.name ?? current.name,
+        rule_type: current.rule_type,
+        category_id: current.category_id,
+        product_id: current.product_id,
+        percentage: body.percentage ?? (current.percentage !== null ? Number(current.percentage) : null),
+        bonus_amount: body.bonus_amount ?? (current.bonus_amount !== null ? Number(current.bonus_amount) : null),
+        role: body.role !== undefined ? body.role : current.role,
+      };
+
+      const invalid = validateRuleBody(next);
+      if (invalid) {
+        return txFailure(invalid.message, invalid.code, 400);
+      }
+
+      const termsChanged =
+        next.percentage !== (current.percentage !== null ? Number(current.percentage) : null) ||
+        next.bonus_amount !== (current.bonus_amount !== null ? Number(current.bonus_amount) : null) ||
+        (next.role || null) !== current.role;
+
+      let savedId = current.id as string;
+      if (!termsChanged || current.effective_from >= today) {
+        await client.query(
+          'UPDATE commission_rules SET name = $1, percentage = $2, bonus_amount = $3, role = $4 WHERE id = $5',
+          [next.name, next.percentage, next.bonus_amount, next.role || null, current.id],
+        );
+      } else {
+        await client.query('UPDATE commission_rules SET effective_until = $1 WHERE id = $2', [addDays(today, -1), current.id]);
+        const insertRes = await client.query(
+          `INSERT INTO commission_rules
+             (name, rule_type, category_id, product_id, percentage, bonus_amount, role, effective_from, created_by)
+           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+          [next.name, next.rule_type, next.category_id, next.product_id, next.percentage, next.bonus_amount, next.role || null, today, userId],
+        );
+        savedId = insertRes.rows[0].id;
+      }
+
+      return { ok: true as const, savedId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { savedId } = result;
+
+    const saved = await pool.query(`${RULE_SELECT} WHERE r.id = $1`, [savedId]);
+    return successResponse(c, 'Commission rule updated successfully', formatRule(saved.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update commission rule', (err as Error).message);
+  }
+}
+
+// ── DeleteCommissionRule ────────────────────────────────────────────────────
+
+export async function deleteCommissionRule(c: Context) {
+  const ruleId = c.req.param('id');
+  const today = localClock().date;
+
+  try {
+    // Rules that haven't started yet are dropped outright; others end yesterday
+    const deleted = await pool.query(
+      'DELETE FROM commission_rules WHERE id = $1 AND effective_until IS NULL AND effective_from >= $2',
+      [ruleId, today],
+    );
+    const closed = await pool.query(
+      'UPDATE commission_rules SET effective_until = $2 WHERE id = $1 AND effective_until IS NULL',
+      [ruleId, addDays(today, -1)],
+    );
+
+    if ((deleted.rowCount ?? 0) + (closed.rowCount ?? 0) === 0) {
+      return errorResponse(c, 'Commission rule not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Commission rule removed successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to remove commission rule', (err as Error).message);
+  }
+}
+
+// ── GetCommissionReport ─────────────────────────────────────────────────────
+// Monthly payout per staff member with the rule breakdown behind it.
+
+export async function getCommissionReport(c: Context) {
+  const month = c.req.query('month') || localClock().date.slice(0, 7);
+  if (!MONTH_RE.test(month)) {
+    return errorResponse(c, 'Month must be YYYY-MM', 'invalid_month', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const { start, end } = monthBounds(month);
+  try {
+    const staff = await calculateCommissions(pool, start, end, scope.branchId);
+    return successResponse(c, 'Commission report retrieved successfully', {
+      month,
+      period_start: start,
+      period_end: end,
+      branch_id: scope.branchId,
+      staff,
+      total_commission: Math.round(staff.reduce((sum, s) => sum + s.commission_total, 0) * 100) / 100,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate commission report', (err as Error).message);
+  }
+}
+
+// ── ExportCommissionReport ──────────────────────────────────────────────────
+// One row per staff member, for import into payroll.
+
+export async function exportCommissionReport(c: Context) {
+  const month = c.req.query('month') || localClock().date.slice(0, 7);
+  if (!MONTH_RE.test(month)) {
+    return errorResponse(c, 'Month must be YYYY-MM', 'invalid_month', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const { start, end } = monthBounds(month);
+  try {
+    const staff = await calculateCommissions(pool, start, end, scope.branchId);
+
+    const rows = [
+      ['period', 'user_id', 'username', 'first_name', 'last_name', 'role', 'orders', 'net_sales', 'commission'],
+      ...staff.map((s) => [
+        month, s.user_id, s.username, s.first_name, s.last_name, s.role,
+        s.orders, s.net_sales.toFixed(2), s.commission_total.toFixed(2),
+      ]),
+    ];
+    const csv = toCsv(rows);
+    const record = await logExport(pool, c, {
+      type: 'commissions',
+      format: 'csv',
+      params: { month, branch_id: scope.branchId },
+      rowCount: staff.length,
+    });
+
+    return c.body(csv, 200, exportFileHeaders('text/csv; charset=utf-8', `commissions-${month}.csv`, record));
+  } catch (err) {
+    return errorResponse(c, 'Failed to export commission report', (err as Error).message);
+  }
+}
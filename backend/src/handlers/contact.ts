@@ -1,6 +1,27 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { emailConfigured, loadRestaurantName, queueEmail } from '../services/email.js';
+import { contactReplyEmail } from '../services/email-templates.js';
+
+const CONTACT_STATUSES = ['new', 'in_progress', 'resolved', 'spam'];
+
+type ContactSubmission = {
+  id: string;
+  name: string;
+  email: string;
+  phone: string | null;
+  subject: string;
+  message: string;
+  status: string;
+  created_at: string;
+  updated_at: string;
+};
+
+const CONTACT_COLUMNS = 'id, name, email, phone, subject, message, status, created_at, updated_at';
 
 // ── GetNewContactsCount ──────────────────────────────────────────────────────
 
@@ -10,18 +31,11 @@ export async function getNewContactsCount(c: Context) {
       SELECT COUNT(*) as count FROM contact_submissions WHERE status = 'new'
     `);
 
-    return c.json({
-      success: true,
-      data: {
-        new_contacts: Number(res.rows[0].count),
-      },
+    return successResponse(c, 'New contacts count retrieved successfully', {
+      new_contacts: Number(res.rows[0].count),
     });
   } catch (err) {
-    return c.json({
-      success: false,
-      message: 'Failed to fetch new contacts count',
-      error: (err as Error).message,
-    }, 500);
+    return errorResponse(c, 'Failed to fetch new contacts count', (err as Error).message);
   }
 }
 
@@ -32,12 +46,15 @@ export async function getContactSubmissions(c: Context) {
   const startDate = c.req.query('start_date') || '';
   const endDate = c.req.query('end_date') || '';
 
+  if (status && !CONTACT_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${CONTACT_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+  if ((startDate && isNaN(Date.parse(startDate))) || (endDate && isNaN(Date.parse(endDate)))) {
+    return errorResponse(c, 'start_date and end_date must be dates', 'invalid_contact_dates', 400);
+  }
+
   try {
-    let query = `
-      SELECT id, name, email, phone, subject, message, status, created_at, updated_at
-      FROM contact_submissions
-      WHERE 1=1
-    `;
+    let query = `SELECT ${CONTACT_COLUMNS} FROM contact_submissions WHERE 1=1`;
     const params: unknown[] = [];
     let argIndex = 1;
 
@@ -61,67 +78,123 @@ export async function getContactSubmissions(c: Context) {
 
     query += ' ORDER BY created_at DESC';
 
-    const res = await pool.query(query, params);
-
-    const submissions = res.rows.map((row: Record<string, unknown>) => ({
-      id: row.id,
-      name: row.name,
-      email: row.email,
-      phone: row.phone,
-      subject: row.subject,
-      message: row.message,
-      status: row.status,
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }));
-
-    // Return raw array (matches Go behavior)
-    return c.json(submissions, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch contact submissions' }, 500);
+    const res = await pool.query<ContactSubmission>(query, params);
+    return successResponse(c, 'Contact submissions retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch contact submissions', (err as Error).message);
   }
 }
 
 // ── GetContactSubmission ──────────────────────────────────────────────────────
+// With the replies sent from the admin panel, oldest first.
 
 export async function getContactSubmission(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
+  }
 
   try {
-    const res = await db.execute<{
-      id: string;
-      name: string;
-      email: string;
-      phone: string;
-      subject: string;
-      message: string;
-      status: string;
-      created_at: string;
-      updated_at: string;
-    }>(sql`
-      SELECT id, name, email, phone, subject, message, status, created_at, updated_at
-      FROM contact_submissions WHERE id = ${id}
-    `);
-
+    const res = await pool.query<ContactSubmission>(
+      `SELECT ${CONTACT_COLUMNS} FROM contact_submissions WHERE id = $1`,
+      [id],
+    );
     if (res.rows.length === 0) {
-      return c.json({ error: 'Contact submission not found' }, 404);
+      return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
     }
 
-    const row = res.rows[0];
-    // Return raw object (matches Go behavior)
-    return c.json({
-      id: row.id,
-      name: row.name,
-      email: row.email,
-      phone: row.phone,
-      subject: row.subject,
-      message: row.message,
-      status: row.status,
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }, 200);
+    const replies = await pool.query(
+      `SELECT id, subject, body, status, last_error, created_by, created_at, sent_at
+       FROM email_outbox
+       WHERE related_type = 'contact_submission' AND related_id = $1
+       ORDER BY created_at ASC`,
+      [id],
+    );
+
+    return successResponse(c, 'Contact submission retrieved successfully', { ...res.rows[0], replies: replies.rows });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch contact submission', (err as Error).message);
+  }
+}
+
+// ── ReplyToContact ───────────────────────────────────────────────────────────
+// Emails a reply to the person who sent the form (quoting their message) and
+// moves the submission to in_progress, or resolved with `resolve: true`.
+
+export async function replyToContact(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
+  }
+
+  let body: { message?: string; resolve?: boolean };
+  try {
+    body = await c.req.json();
   } catch {
-    return c.json({ error: 'Contact submission not found' }, 404);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const message = (body.message || '').trim();
+  if (!message) {
+    return errorResponse(c, 'Message is required', 'message_required', 400);
+  }
+  if (message.length > 5000) {
+    return errorResponse(c, 'Message must be at most 5000 characters', 'message_too_long', 400);
+  }
+
+  try {
+    if (!(await emailConfigured(pool))) {
+      return errorResponse(c, 'Email is not configured', 'email_not_configured', 400);
+    }
+
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        'SELECT id, name, email, subject, message, status FROM contact_submissions WHERE id = $1 FOR UPDATE',
+        [id],
+      );
+      const submission = res.rows[0];
+      if (!submission) {
+        return txFailure('Contact submission not found', 'contact_not_found', 404);
+      }
+      if (submission.status === 'spam') {
+        return txFailure('Cannot reply to a submission marked as spam', 'submission_is_spam', 400);
+      }
+
+      const staff = await client.query(
+        "SELECT TRIM(CONCAT(first_name, ' ', last_name)) AS name FROM users WHERE id = $1",
+        [userId],
+      );
+      const email = contactReplyEmail(await loadRestaurantName(client), {
+        name: submission.name,
+        subject: submission.subject,
+        message: submission.message,
+        reply: message,
+        staffName: staff.rows[0]?.name || null,
+      });
+
+      const outboxId = await queueEmail(client, {
+        to: [submission.email],
+        template: 'contact_reply',
+        ...email,
+        relatedType: 'contact_submission',
+        relatedId: submission.id,
+        createdBy: userId,
+      });
+
+      const status = body.resolve ? 'resolved' : 'in_progress';
+      await client.query(
+        'UPDATE contact_submissions SET status = $2, updated_at = NOW() WHERE id = $1',
+        [submission.id, status],
+      );
+      return { ok: true as const, outboxId, status };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { outboxId, status } = result;
+
+    return successResponse(c, 'Reply queued for sending', { email_id: outboxId, status }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to send reply', (err as Error).message);
   }
 }
 
@@ -129,60 +202,38 @@ export async function getContactSubmission(c: Context) {
 
 export async function updateContactStatus(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
+  }
 
-  let body: { status: string };
+  let body: { status?: string };
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
   if (!body.status) {
-    return c.json({ error: 'Status is required' }, 400);
+    return errorResponse(c, 'Status is required', 'missing_status', 400);
   }
-
-  const validStatuses = ['new', 'in_progress', 'resolved', 'spam'];
-  if (!validStatuses.includes(body.status)) {
-    return c.json({ error: 'Invalid status. Must be one of: new, in_progress, resolved, spam' }, 400);
+  if (!CONTACT_STATUSES.includes(body.status)) {
+    return errorResponse(c, `Invalid status. Must be one of: ${CONTACT_STATUSES.join(', ')}`, 'invalid_status', 400);
   }
 
   try {
-    const res = await db.execute<{
-      id: string;
-      name: string;
-      email: string;
-      phone: string;
-      subject: string;
-      message: string;
-      status: string;
-      created_at: string;
-      updated_at: string;
-    }>(sql`
-      UPDATE contact_submissions
-      SET status = ${body.status}, updated_at = NOW()
-      WHERE id = ${id}
-      RETURNING id, name, email, phone, subject, message, status, created_at, updated_at
-    `);
-
+    const res = await pool.query<ContactSubmission>(
+      `UPDATE contact_submissions
+       SET status = $2, updated_at = NOW()
+       WHERE id = $1
+       RETURNING ${CONTACT_COLUMNS}`,
+      [id, body.status],
+    );
     if (res.rows.length === 0) {
-      return c.json({ error: 'Contact submission not found' }, 404);
+      return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
     }
-
-    const row = res.rows[0];
-    // Return raw object (matches Go behavior)
-    return c.json({
-      id: row.id,
-      name: row.name,
-      email: row.email,
-      phone: row.phone,
-      subject: row.subject,
-      message: row.message,
-      status: row.status,
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }, 200);
-  } catch {
-    return c.json({ error: 'Contact submission not found' }, 404);
+    return successResponse(c, 'Contact status updated successfully', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update contact status', (err as Error).message);
   }
 }
 
@@ -190,18 +241,17 @@ export async function updateContactStatus(c: Context) {
 
 export async function deleteContactSubmission(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
+  }
 
   try {
-    const res = await db.execute(sql`
-      DELETE FROM contact_submissions WHERE id = ${id}
-    `);
-
+    const res = await pool.query('DELETE FROM contact_submissions WHERE id = $1', [id]);
     if (res.rowCount === 0) {
-      return c.json({ error: 'Contact submission not found' }, 404);
+      return errorResponse(c, 'Contact submission not found', 'contact_not_found', 404);
     }
-
-    return c.json({ message: 'Contact submission deleted successfully' }, 200);
-  } catch {
-    return c.json({ error: 'Failed to delete contact submission' }, 500);
+    return successResponse(c, 'Contact submission deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete contact submission', (err as Error).message);
   }
 }
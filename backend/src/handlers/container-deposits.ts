@@ -0,0 +1,289 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { localClock, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { isUUID, resolveBranchScope, branchCondition } from '../services/branches.js';
+import {
+  CONTAINER_REFUND_METHODS,
+  DEPOSIT_HELD_STATUSES,
+  depositLiability,
+  loadOrderContainerDeposits,
+} from '../services/container-deposits.js';
+
+type ContainerTypeBody = { name?: string; deposit_amount?: number; is_active?: boolean };
+
+// ── GetContainerTypes ───────────────────────────────────────────────────────
+
+export async function getContainerTypes(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT id, name, deposit_amount::float8 AS deposit_amount, is_active, created_at, updated_at
+       FROM container_types
+       ORDER BY is_active DESC, name ASC`,
+    );
+    return successResponse(c, 'Container types retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch container types', (err as Error).message);
+  }
+}
+
+// ── CreateContainerType ─────────────────────────────────────────────────────
+
+export async function createContainerType(c: Context) {
+  let body: ContainerTypeBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const name = body.name?.trim();
+  if (!name || name.length > 100) {
+    return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+  }
+  if (typeof body.deposit_amount !== 'number' || !(body.deposit_amount > 0)) {
+    return errorResponse(c, 'deposit_amount must be greater than 0', 'invalid_deposit_amount', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO container_types (name, deposit_amount, is_active)
+       VALUES ($1, $2, $3)
+       ON CONFLICT (name) DO NOTHING
+       RETURNING id, name, deposit_amount::float8 AS deposit_amount, is_active, created_at, updated_at`,
+      [name, body.deposit_amount, body.is_active ?? true],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'A container type with this name already exists', 'duplicate_name', 409);
+    }
+    return successResponse(c, 'Container type created successfully', res.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create container type', (err as Error).message);
+  }
+}
+
+// ── UpdateContainerType ─────────────────────────────────────────────────────
+// A new deposit applies to new orders; containers already out are refunded
+// at the deposit their order paid.
+
+export async function updateContainerType(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Container type not found', 'not_found', 404);
+  }
+
+  let body: ContainerTypeBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.name !== undefined) {
+    const name = body.name.trim();
+    if (!name || name.length > 100) {
+      return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+    }
+    setClauses.push(`name = $${paramIdx++}`);
+    params.push(name);
+  }
+  if (body.deposit_amount !== undefined) {
+    if (typeof body.deposit_amount !== 'number' || !(body.deposit_amount > 0)) {
+      return errorResponse(c, 'deposit_amount must be greater than 0', 'invalid_deposit_amount', 400);
+    }
+    setClauses.push(`deposit_amount = $${paramIdx++}`);
+    params.push(body.deposit_amount);
+  }
+  if (body.is_active !== undefined) {
+    setClauses.push(`is_active = $${paramIdx++}`);
+    params.push(body.is_active);
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE container_types SET ${setClauses.join(', ')} WHERE id = $${paramIdx}
+       RETURNING id, name, deposit_amount::float8 AS deposit_amount, is_active, created_at, updated_at`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Container type not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Container type updated successfully', res.rows[0]);
+  } catch (err) {
+    if ((err as { code?: string }).code === '23505') {
+      return errorResponse(c, 'A container type with this name already exists', 'duplicate_name', 409);
+    }
+    return errorResponse(c, 'Failed to update container type', (err as Error).message);
+  }
+}
+
+// ── ReturnContainers ────────────────────────────────────────────────────────
+// Refunds the deposit for containers brought back against an order. Several
+// returns may be made until every container on the order is back.
+
+export async function returnContainers(c: Context) {
+  const orderId = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  let body: { containers?: { container_type_id?: string; quantity?: number }[]; refund_method?: string; notes?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!Array.isArray(body.containers) || body.containers.length === 0) {
+    return errorResponse(c, 'List the containers being returned', 'missing_containers', 400);
+  }
+  for (const entry of body.containers) {
+    if (!entry.container_type_id || !Number.isInteger(entry.quantity) || entry.quantity! < 1) {
+      return errorResponse(c, 'Each container needs a container_type_id and a positive whole quantity', 'invalid_containers', 400);
+    }
+  }
+  const refundMethod = body.refund_method || 'cash';
+  if (!CONTAINER_REFUND_METHODS.includes(refundMethod)) {
+    return errorResponse(c, `Refund method must be one of: ${CONTAINER_REFUND_METHODS.join(', ')}`, 'invalid_refund_method', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        'SELECT order_number, status, branch_id FROM orders WHERE id = $1 FOR UPDATE',
+        [orderId],
+      );
+      if (orderRes.rows.length === 0) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
+      const order = orderRes.rows[0];
+      if (!DEPOSIT_HELD_STATUSES.includes(order.status)) {
+        return txFailure('Deposits can only be refunded once the order is paid', 'deposit_not_paid', 400);
+      }
+
+      // Refunds come out of the drawer of the branch taking the containers back
+      const branchId = c.get('branch_id') ?? order.branch_id;
+      let refunded = 0;
+
+      for (const entry of body.containers) {
+        const depositRes = await client.query(
+          `SELECT d.id, t.name, d.quantity, d.returned_quantity, d.unit_deposit
+           FROM order_container_deposits d
+           JOIN container_types t ON t.id = d.container_type_id
+           WHERE d.order_id = $1 AND d.container_type_id::text = $2
+           FOR UPDATE OF d`,
+          [orderId, entry.container_type_id],
+        );
+        const deposit = depositRes.rows[0];
+        if (!deposit) {
+          return txFailure('This order has no deposit for that container type', 'deposit_not_found', 400);
+        }
+        const outstanding = deposit.quantity - deposit.returned_quantity;
+        if (entry.quantity! > outstanding) {
+          return txFailure(`Only ${outstanding} ${deposit.name} still to be returned on order ${order.order_number}`, 'exceeds_outstanding', 400);
+        }
+
+        const amount = Math.round(Number(deposit.unit_deposit) * entry.quantity! * 100) / 100;
+        await client.query(
+          `UPDATE order_container_deposits SET returned_quantity = returned_quantity + $2, updated_at = NOW()
+           WHERE id = $1`,
+          [deposit.id, entry.quantity],
+        );
+        await client.query(
+          `INSERT INTO container_returns (order_deposit_id, branch_id, quantity, refund_amount, refund_method, notes, returned_by)
+           VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+          [deposit.id, branchId, entry.quantity, amount, refundMethod, body.notes?.trim() || null, userId],
+        );
+        refunded += amount;
+      }
+
+      return { ok: true as const, order, refunded };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { order, refunded } = result;
+
+    return successResponse(c, 'Containers returned and deposit refunded', {
+      order_id: orderId,
+      order_number: order.order_number,
+      refund_method: refundMethod,
+      refund_amount: Math.round(refunded * 100) / 100,
+      containers: await loadOrderContainerDeposits(pool, orderId),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to return containers', (err as Error).message);
+  }
+}
+
+// ── GetContainerDepositReport ───────────────────────────────────────────────
+// Deposits taken and refunded over the period, and what is still owed to
+// customers now.
+
+export async function getContainerDepositReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const issuedParams: unknown[] = [from, to, RESTAURANT_TIMEZONE, DEPOSIT_HELD_STATUSES];
+    const issuedRes = await pool.query(
+      `SELECT t.id AS container_type_id, t.name, SUM(d.quantity)::int AS quantity,
+              SUM(d.quantity * d.unit_deposit)::float8 AS amount
+       FROM order_container_deposits d
+       JOIN container_types t ON t.id = d.container_type_id
+       JOIN orders o ON o.id = d.order_id
+       WHERE o.status = ANY($4::text[]) AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+             ${branchCondition('o.branch_id', scope.branchId, issuedParams)}
+       GROUP BY t.id, t.name
+       ORDER BY t.name ASC`,
+      issuedParams,
+    );
+
+    const returnedParams: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+    const returnedRes = await pool.query(
+      `SELECT t.id AS container_type_id, t.name, r.refund_method, SUM(r.quantity)::int AS quantity,
+              SUM(r.refund_amount)::float8 AS amount
+       FROM container_returns r
+       JOIN order_container_deposits d ON d.id = r.order_deposit_id
+       JOIN container_types t ON t.id = d.container_type_id
+       WHERE DATE(r.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+             ${branchCondition('r.branch_id', scope.branchId, returnedParams)}
+       GROUP BY t.id, t.name, r.refund_method
+       ORDER BY t.name ASC, r.refund_method ASC`,
+      returnedParams,
+    );
+
+    const sum = (rows: { amount: number }[]) => Math.round(rows.reduce((s, r) => s + Number(r.amount), 0) * 100) / 100;
+    return successResponse(c, 'Container deposit report retrieved successfully', {
+      from,
+      to,
+      branch_id: scope.branchId,
+      issued: issuedRes.rows,
+      issued_amount: sum(issuedRes.rows),
+      returned: returnedRes.rows,
+      returned_amount: sum(returnedRes.rows),
+      outstanding: await depositLiability(pool, scope.branchId),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate container deposit report', (err as Error).message);
+  }
+}
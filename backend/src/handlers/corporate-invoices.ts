@@ -0,0 +1,330 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { getCreditPosition } from '../services/corporate-billing.js';
+
+function formatInvoice(row: Record<string, unknown>) {
+  const total = Number(row.total_amount);
+  const paid = Number(row.amount_paid);
+  return {
+    id: row.id,
+    account_id: row.account_id,
+    company_name: row.company_name,
+    invoice_number: row.invoice_number,
+    period_start: row.period_start,
+    period_end: row.period_end,
+    total_amount: total,
+    amount_paid: paid,
+    balance_due: total - paid,
+    status: row.status,
+    issued_at: row.issued_at,
+    due_date: row.due_date,
+    is_overdue: row.is_overdue ?? false,
+  };
+}
+
+const INVOICE_SELECT = `
+  SELECT i.*, a.company_name,
+         (i.status IN ('issued', 'partially_paid') AND i.due_date < (NOW() AT TIME ZONE 'Asia/Jakarta')::date) AS is_overdue
+  FROM corporate_invoices i
+  JOIN corporate_accounts a ON a.id = i.account_id`;
+
+// ── GetCorporateCredit ──────────────────────────────────────────────────────
+
+export async function getCorporateCredit(c: Context) {
+  const accountId = c.req.param('id');
+
+  try {
+    const accRes = await pool.query(
+      'SELECT id, company_name, on_account_enabled, credit_limit FROM corporate_accounts WHERE id = $1',
+      [accountId],
+    );
+    if (accRes.rows.length === 0) {
+      return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+    }
+
+    const account = accRes.rows[0];
+    const credit = await getCreditPosition(pool, account.id, Number(account.credit_limit));
+
+    return successResponse(c, 'Credit position retrieved successfully', {
+      account_id: account.id,
+      company_name: account.company_name,
+      on_account_enabled: account.on_account_enabled,
+      ...credit,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch credit position', (err as Error).message);
+  }
+}
+
+// ── GenerateCorporateInvoice ────────────────────────────────────────────────
+// Bills every uninvoiced on-account charge that falls inside the month
+// (WIB). One invoice per account per month.
+
+export async function generateCorporateInvoice(c: Context) {
+  const accountId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { month?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.month || !/^\d{4}-(0[1-9]|1[0-2])$/.test(body.month)) {
+    return errorResponse(c, 'Month is required in YYYY-MM format', 'invalid_month', 400);
+  }
+
+  const periodStart = `${body.month}-01`;
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const accRes = await client.query(
+        'SELECT id, payment_terms_days FROM corporate_accounts WHERE id = $1 FOR UPDATE',
+        [accountId],
+      );
+      if (accRes.rows.length === 0) {
+        return txFailure('Corporate account not found', 'not_found', 404);
+      }
+
+      const existingRes = await client.query(
+        'SELECT id FROM corporate_invoices WHERE account_id = $1 AND period_start = $2',
+        [accountId, periodStart],
+      );
+      if (existingRes.rows.length > 0) {
+        return txFailure('Invoice already generated for this period', 'invoice_exists', 409);
+      }
+
+      const chargesRes = await client.query(
+        `SELECT id, amount FROM corporate_account_charges
+         WHERE account_id = $1 AND invoice_id IS NULL
+           AND created_at >= ($2::date AT TIME ZONE 'Asia/Jakarta')
+           AND created_at < (($2::date + INTERVAL '1 month') AT TIME ZONE 'Asia/Jakarta')
+         FOR UPDATE`,
+        [accountId, periodStart],
+      );
+      if (chargesRes.rows.length === 0) {
+        return txFailure('No uninvoiced charges for this period', 'no_charges', 400);
+      }
+
+      const total = chargesRes.rows.reduce((sum, row) => sum + Number(row.amount), 0);
+
+      const seqRes = await client.query(
+        'SELECT COUNT(*) FROM corporate_invoices WHERE period_start = $1',
+        [periodStart],
+      );
+      const invoiceNumber = `INV-${body.month.replace('-', '')}-${String(Number(seqRes.rows[0].count) + 1).padStart(4, '0')}`;
+
+      const invRes = await client.query(
+        `INSERT INTO corporate_invoices
+           (account_id, invoice_number, period_start, period_end, total_amount, due_date, created_by)
+         VALUES ($1, $2, $3::date, ($3::date + INTERVAL '1 month' - INTERVAL '1 day')::date, $4,
+                 (NOW() AT TIME ZONE 'Asia/Jakarta')::date + $5::int, $6)
+         RETURNING id`,
+        [accountId, invoiceNumber, periodStart, total, accRes.rows[0].payment_terms_days, userId],
+      );
+      const invoiceId = invRes.rows[0].id;
+
+      await client.query(
+        'UPDATE corporate_account_charges SET invoice_id = $1 WHERE id = ANY($2::uuid[])',
+        [invoiceId, chargesRes.rows.map((r) => r.id)],
+      );
+
+      return { ok: true as const, chargesRes, invoiceId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { chargesRes, invoiceId } = result;
+
+    const fetchRes = await pool.query(`${INVOICE_SELECT} WHERE i.id = $1`, [invoiceId]);
+    return successResponse(c, 'Invoice generated successfully', {
+      ...formatInvoice(fetchRes.rows[0]),
+      charge_count: chargesRes.rows.length,
+    }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate invoice', (err as Error).message);
+  }
+}
+
+// ── GetCorporateInvoices ────────────────────────────────────────────────────
+
+export async function getCorporateInvoices(c: Context) {
+  const pagination = parsePagination({
+    page: c.req.query('page'),
+    per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
+  });
+  const accountId = c.req.query('account_id');
+  const status = c.req.query('status');
+  const overdueOnly = c.req.query('overdue') === 'true';
+
+  try {
+    const conditions: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    if (accountId) {
+      conditions.push(`i.account_id = $${paramIdx}`);
+      params.push(accountId);
+      paramIdx++;
+    }
+    if (status) {
+      conditions.push(`i.status = $${paramIdx}`);
+      params.push(status);
+      paramIdx++;
+    }
+    if (overdueOnly) {
+      conditions.push(`i.status IN ('issued', 'partially_paid') AND i.due_date < (NOW() AT TIME ZONE 'Asia/Jakarta')::date`);
+    }
+
+    const whereClause = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM corporate_invoices i ${whereClause}`, params);
+        return Number(countRes.rows[0].count);
+      },
+      async (limit) => {
+        const dataRes = await pool.query(
+          `${INVOICE_SELECT} ${whereClause}
+           ORDER BY i.period_start DESC, a.company_name ASC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return dataRes.rows;
+      },
+    );
+
+    return paginatedResponse(c, 'Invoices retrieved successfully', result.rows.map(formatInvoice), pageMeta(pagination, result, {
+      sort: 'period_start:desc,company_name:asc',
+      filters: { account_id: accountId, status, overdue: overdueOnly || undefined },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch invoices', (err as Error).message);
+  }
+}
+
+// ── GetCorporateInvoice ─────────────────────────────────────────────────────
+
+export async function getCorporateInvoice(c: Context) {
+  const invoiceId = c.req.param('id');
+
+  try {
+    const invRes = await pool.query(`${INVOICE_SELECT} WHERE i.id = $1`, [invoiceId]);
+    if (invRes.rows.length === 0) {
+      return errorResponse(c, 'Invoice not found', 'not_found', 404);
+    }
+
+    const chargesRes = await pool.query(
+      `SELECT ch.id, ch.amount, ch.created_at, ch.order_id, o.order_number, o.customer_name
+       FROM corporate_account_charges ch
+       LEFT JOIN orders o ON o.id = ch.order_id
+       WHERE ch.invoice_id = $1
+       ORDER BY ch.created_at ASC`,
+      [invoiceId],
+    );
+
+    const paymentsRes = await pool.query(
+      `SELECT id, amount, payment_method, reference_number, notes, received_at
+       FROM corporate_invoice_payments WHERE invoice_id = $1 ORDER BY received_at ASC`,
+      [invoiceId],
+    );
+
+    return successResponse(c, 'Invoice retrieved successfully', {
+      ...formatInvoice(invRes.rows[0]),
+      charges: chargesRes.rows.map((row) => ({
+        id: row.id,
+        order_id: row.order_id,
+        order_number: row.order_number,
+        customer_name: row.customer_name,
+        amount: Number(row.amount),
+        charged_at: row.created_at,
+      })),
+      payments: paymentsRes.rows.map((row) => ({
+        id: row.id,
+        amount: Number(row.amount),
+        payment_method: row.payment_method,
+        reference_number: row.reference_number,
+        notes: row.notes,
+        received_at: row.received_at,
+      })),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch invoice', (err as Error).message);
+  }
+}
+
+// ── RecordCorporateInvoicePayment ───────────────────────────────────────────
+
+export async function recordCorporateInvoicePayment(c: Context) {
+  const invoiceId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { amount?: number; payment_method?: string; reference_number?: string; notes?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.amount || body.amount <= 0) {
+    return errorResponse(c, 'Payment amount must be greater than zero', 'invalid_amount', 400);
+  }
+  const validMethods = ['bank_transfer', 'cash', 'cheque', 'digital_wallet'];
+  if (!body.payment_method || !validMethods.includes(body.payment_method)) {
+    return errorResponse(c, 'Invalid payment method', 'invalid_payment_method', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const invRes = await client.query(
+        'SELECT id, total_amount, amount_paid, status FROM corporate_invoices WHERE id = $1 FOR UPDATE',
+        [invoiceId],
+      );
+      if (invRes.rows.length === 0) {
+        return txFailure('Invoice not found', 'not_found', 404);
+      }
+
+      const invoice = invRes.rows[0];
+      if (invoice.status === 'paid' || invoice.status === 'void') {
+        return txFailure(`Invoice is already ${invoice.status}`, 'invalid_invoice_status', 400);
+      }
+
+      const balanceDue = Number(invoice.total_amount) - Number(invoice.amount_paid);
+      if (body.amount > balanceDue) {
+        return txFailure('Payment amount exceeds invoice balance', 'amount_exceeds_balance', 400);
+      }
+
+      await client.query(
+        `INSERT INTO corporate_invoice_payments (invoice_id, amount, payment_method, reference_number, notes, received_by)
+         VALUES ($1, $2, $3, $4, $5, $6)`,
+        [invoiceId, body.amount, body.payment_method, body.reference_number || null, body.notes || null, userId],
+      );
+
+      const newPaid = Number(invoice.amount_paid) + body.amount;
+      const newStatus = newPaid >= Number(invoice.total_amount) ? 'paid' : 'partially_paid';
+
+      await client.query(
+        'UPDATE corporate_invoices SET amount_paid = $1, status = $2 WHERE id = $3',
+        [newPaid, newStatus, invoiceId],
+      );
+
+      return { ok: true as const, invoice, newPaid, newStatus };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { invoice, newPaid, newStatus } = result;
+
+    return successResponse(c, 'Invoice payment recorded successfully', {
+      invoice_id: invoiceId,
+      amount: body.amount,
+      amount_paid: newPaid,
+      balance_due: Number(invoice.total_amount) - newPaid,
+      status: newStatus,
+    }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to record invoice payment', (err as Error).message);
+  }
+}
@@ -0,0 +1,584 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { creditWallet, TOPUP_GATEWAY_PREFIX } from '../services/corporate-wallet.js';
+import { createCharge, gatewayOrderId, isGatewayConfigured } from '../services/payment-gateway.js';
+
+function formatAccount(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    company_name: row.company_name,
+    contact_name: row.contact_name,
+    contact_email: row.contact_email,
+    contact_phone: row.contact_phone,
+    billing_address: row.billing_address,
+    balance: Number(row.balance),
+    is_active: row.is_active,
+    notes: row.notes,
+    on_account_enabled: row.on_account_enabled,
+    credit_limit: Number(row.credit_limit ?? 0),
+    payment_terms_days: row.payment_terms_days,
+    employee_count: row.employee_count !== undefined ? Number(row.employee_count) : undefined,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+function formatEmployee(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    account_id: row.account_id,
+    employee_code: row.employee_code,
+    employee_name: row.employee_name,
+    daily_limit: row.daily_limit !== null ? Number(row.daily_limit) : null,
+    is_active: row.is_active,
+    created_at: row.created_at,
+  };
+}
+
+// ── GetCorporateAccounts ────────────────────────────────────────────────────
+
+export async function getCorporateAccounts(c: Context) {
+  const pagination = parsePagination({
+    page: c.req.query('page'),
+    per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
+  });
+  const search = c.req.query('search');
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const conditions: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    if (activeOnly) {
+      conditions.push('a.is_active = true');
+    }
+    if (search) {
+      conditions.push(`(a.company_name ILIKE $${paramIdx} OR a.contact_name ILIKE $${paramIdx})`);
+      params.push(`%${search}%`);
+      paramIdx++;
+    }
+
+    const whereClause = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM corporate_accounts a ${whereClause}`, params);
+        return Number(countRes.rows[0].count);
+      },
+      async (limit) => {
+        const dataRes = await pool.query(
+          `SELECT a.*, (SELECT COUNT(*) FROM corporate_employees e WHERE e.account_id = a.id) AS employee_count
+           FROM corporate_accounts a ${whereClause}
+           ORDER BY a.company_name ASC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return dataRes.rows;
+      },
+    );
+
+    return paginatedResponse(c, 'Corporate accounts retrieved successfully', result.rows.map(formatAccount), pageMeta(pagination, result, {
+      sort: 'company_name:asc',
+      filters: { search, active_only: activeOnly || undefined },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch corporate accounts', (err as Error).message);
+  }
+}
+
+// ── GetCorporateAccount ─────────────────────────────────────────────────────
+
+export async function getCorporateAccount(c: Context) {
+  const accountId = c.req.param('id');
+
+  try {
+    const accRes = await pool.query('SELECT * FROM corporate_accounts WHERE id = $1', [accountId]);
+    if (accRes.rows.length === 0) {
+      return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+    }
+
+    const empRes = await pool.query(
+      'SELECT * FROM corporate_employees WHERE account_id = $1 ORDER BY employee_name ASC',
+      [accountId],
+    );
+
+    return successResponse(c, 'Corporate account retrieved successfully', {
+      ...formatAccount(accRes.rows[0]),
+      employees: empRes.rows.map(formatEmployee),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch corporate account', (err as Error).message);
+  }
+}
+
+// ── CreateCorporateAccount ──────────────────────────────────────────────────
+
+export async function createCorporateAccount(c: Context) {
+  let body: {
+    company_name?: string;
+    contact_name?: string;
+    contact_email?: string;
+    contact_phone?: string;
+    billing_address?: string;
+    notes?: string;
+    on_account_enabled?: boolean;
+    credit_limit?: number;
+    payment_terms_days?: number;
+  };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.company_name) {
+    return errorResponse(c, 'Company name is required', 'missing_company_name', 400);
+  }
+  if (body.credit_limit !== undefined && body.credit_limit < 0) {
+    return errorResponse(c, 'Credit limit cannot be negative', 'invalid_credit_limit', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO corporate_accounts
+         (company_name, contact_name, contact_email, contact_phone, billing_address, notes,
+          on_account_enabled, credit_limit, payment_terms_days)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *`,
+      [
+        body.company_name,
+        body.contact_name || null,
+        body.contact_email || null,
+        body.contact_phone || null,
+        body.billing_address || null,
+        body.notes || null,
+        body.on_account_enabled ?? false,
+        body.credit_limit ?? 0,
+        body.payment_terms_days ?? 30,
+      ],
+    );
+
+    return successResponse(c, 'Corporate account created successfully', formatAccount(res.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create corporate account', (err as Error).message);
+  }
+}
+
+// ── UpdateCorporateAccount ──────────────────────────────────────────────────
+
+export async function updateCorporateAccount(c: Context) {
+  const accountId = c.req.param('id');
+
+  let body: Record<string, unknown>;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const allowed = [
+    'company_name', 'contact_name', 'contact_email', 'contact_phone', 'billing_address', 'notes', 'is_active',
+    'on_account_enabled', 'credit_limit', 'payment_terms_days',
+  ];
+
+  if (typeof body.credit_limit === 'number' && body.credit_limit < 0) {
+    return errorResponse(c, 'Credit limit cannot be negative', 'invalid_credit_limit', 400);
+  }
+
+  try {
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    for (const field of allowed) {
+      if (body[field] !== undefined) {
+        setClauses.push(`${field} = $${paramIdx}`);
+        params.push(body[field]);
+        paramIdx++;
+      }
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    params.push(accountId);
+    const res = await pool.query(
+      `UPDATE corporate_accounts SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING *`,
+      params,
+    );
+
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+    }
+
+    return successResponse(c, 'Corporate account updated successfully', formatAccount(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update corporate account', (err as Error).message);
+  }
+}
+
+// ── CreateCorporateEmployee ─────────────────────────────────────────────────
+
+export async function createCorporateEmployee(c: Context) {
+  const accountId = c.req.param('id');
+
+  let body: { employee_code?: string; employee_name?: string; daily_limit?: number | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.employee_code || !body.employee_name) {
+    return errorResponse(c, 'Employee code and name are required', 'missing_fields', 400);
+  }
+  if (body.daily_limit !== undefined && body.daily_limit !== null && body.daily_limit <= 0) {
+    return errorResponse(c, 'Daily limit must be greater than zero', 'invalid_daily_limit', 400);
+  }
+
+  try {
+    const accRes = await pool.query('SELECT id FROM corporate_accounts WHERE id = $1', [accountId]);
+    if (accRes.rows.length === 0) {
+      return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+    }
+
+    const dupRes = await pool.query(
+      'SELECT id FROM corporate_employees WHERE UPPER(employee_code) = UPPER($1)',
+      [body.employee_code.trim()],
+    );
+    if (dupRes.rows.length > 0) {
+      return errorResponse(c, 'Employee code already in use', 'employee_code_exists', 409);
+    }
+
+    const res = await pool.query(
+      `INSERT INTO corporate_employees (account_id, employee_code, employee_name, daily_limit)
+       VALUES ($1, $2, $3, $4) RETURNING *`,
+      [accountId, body.employee_code.trim().toUpperCase(), body.employee_name, body.daily_limit ?? null],
+    );
+
+    return successResponse(c, 'Employee added successfully', formatEmployee(res.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to add employee', (err as Error).message);
+  }
+}
+
+// ── UpdateCorporateEmployee ─────────────────────────────────────────────────
+
+export async function updateCorporateEmployee(c: Context) {
+  const accountId = c.req.param('id');
+  const employeeId = c.req.param('employee_id');
+
+  let body: { employee_name?: string; daily_limit?: number | null; is_active?: boolean };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  try {
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    if (body.employee_name !== undefined) {
+      setClauses.push(`employee_name = $${paramIdx}`);
+      params.push(body.employee_name);
+      paramIdx++;
+    }
+    if (body.daily_limit !== undefined) {
+      setClauses.push(`daily_limit = $${paramIdx}`);
+      params.push(body.daily_limit);
+      paramIdx++;
+    }
+    if (body.is_active !== undefined) {
+      setClauses.push(`is_active = $${paramIdx}`);
+      params.push(body.is_active);
+      paramIdx++;
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    params.push(employeeId, accountId);
+    const res = await pool.query(
+      `UPDATE corporate_employees SET ${setClauses.join(', ')}
+       WHERE id = $${paramIdx} AND account_id = $${paramIdx + 1} RETURNING *`,
+      params,
+    );
+
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Employee not found', 'not_found', 404);
+    }
+
+    return successResponse(c, 'Employee updated successfully', formatEmployee(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update employee', (err as Error).message);
+  }
+}
+
+// ── CreateCorporateTopup ────────────────────────────────────────────────────
+// method=manual credits immediately (bank transfer / cash received by staff);
+// method=gateway creates a pending top-up settled by the gateway webhook.
+
+export async function createCorporateTopup(c: Context) {
+  const accountId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { amount?: number; method?: 'manual' | 'gateway' | 'adjustment'; notes?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const method = body.method || 'manual';
+  if (!['manual', 'gateway', 'adjustment'].includes(method)) {
+    return errorResponse(c, 'Invalid top-up method', 'invalid_method', 400);
+  }
+  if (!body.amount || (method !== 'adjustment' && body.amount <= 0)) {
+    return errorResponse(c, 'Top-up amount must be greater than zero', 'invalid_amount', 400);
+  }
+
+  if (method === 'gateway') {
+    if (!isGatewayConfigured()) {
+      return errorResponse(c, 'Payment gateway is not configured', 'gateway_not_configured', 400);
+    }
+
+    try {
+      const accRes = await pool.query(
+        'SELECT id, company_name, contact_name, contact_email, contact_phone, is_active FROM corporate_accounts WHERE id = $1',
+        [accountId],
+      );
+      if (accRes.rows.length === 0) {
+        return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+      }
+      const account = accRes.rows[0];
+      if (!account.is_active) {
+        return errorResponse(c, 'Corporate account is inactive', 'corporate_account_inactive', 400);
+      }
+
+      const txRes = await pool.query(
+        `INSERT INTO corporate_wallet_transactions (account_id, transaction_type, amount, status, notes, created_by)
+         VALUES ($1, 'topup', $2, 'pending', $3, $4) RETURNING id`,
+        [accountId, body.amount, body.notes || null, userId],
+      );
+      const txId = txRes.rows[0].id;
+      const reference = gatewayOrderId(TOPUP_GATEWAY_PREFIX, txId);
+
+      await pool.query(
+        'UPDATE corporate_wallet_transactions SET gateway_reference = $1 WHERE id = $2',
+        [reference, txId],
+      );
+
+      let charge;
+      try {
+        charge = await createCharge({
+          orderId: reference,
+          amount: body.amount,
+          description: `Meal wallet top-up ${account.company_name}`,
+          customer: { name: account.contact_name, email: account.contact_email, phone: account.contact_phone },
+          expiryMinutes: 24 * 60,
+        });
+      } catch (err) {
+        await pool.query(
+          "UPDATE corporate_wallet_transactions SET status = 'failed', completed_at = NOW() WHERE id = $1",
+          [txId],
+        );
+        return errorResponse(c, 'Failed to create payment gateway charge', (err as Error).message);
+      }
+
+      return successResponse(c, 'Top-up payment created', {
+        transaction_id: txId,
+        gateway_reference: reference,
+        amount: body.amount,
+        status: 'pending',
+        payment_token: charge.token,
+        payment_url: charge.redirect_url,
+      }, 201);
+    } catch (err) {
+      return errorResponse(c, 'Failed to create top-up', (err as Error).message);
+    }
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const newBalance = await creditWallet(client, {
+        accountId,
+        amount: body.amount,
+        type: method === 'adjustment' ? 'adjustment' : 'topup',
+        notes: body.notes,
+        userId,
+      });
+
+      if (newBalance === null) {
+        return txFailure('Corporate account not found or adjustment exceeds balance', 'invalid_topup', 400);
+      }
+
+      return { ok: true as const, newBalance };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { newBalance } = result;
+
+    return successResponse(c, 'Wallet credited successfully', {
+      account_id: accountId,
+      amount: body.amount,
+      balance: newBalance,
+    }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to credit wallet', (err as Error).message);
+  }
+}
+
+// ── GetCorporateStatement ───────────────────────────────────────────────────
+// Monthly statement: opening/closing balance, ledger entries and spend per
+// employee. month=YYYY-MM, defaults to the current month (WIB).
+
+export async function getCorporateStatement(c: Context) {
+  const accountId = c.req.param('id');
+  const month = c.req.query('month') || new Date().toLocaleDateString('sv-SE', { timeZone: 'Asia/Jakarta' }).slice(0, 7);
+
+  if (!/^\d{4}-(0[1-9]|1[0-2])$/.test(month)) {
+    return errorResponse(c, 'Invalid month format, expected YYYY-MM', 'invalid_month', 400);
+  }
+
+  const periodStart = `${month}-01`;
+
+  try {
+    const accRes = await pool.query('SELECT * FROM corporate_accounts WHERE id = $1', [accountId]);
+    if (accRes.rows.length === 0) {
+      return errorResponse(c, 'Corporate account not found', 'not_found', 404);
+    }
+
+    // Period bounds are WIB calendar month boundaries
+    const bounds = `($2::date AT TIME ZONE 'Asia/Jakarta')`;
+    const boundsEnd = `(($2::date + INTERVAL '1 month') AT TIME ZONE 'Asia/Jakarta')`;
+
+    const openingRes = await pool.query(
+      `SELECT COALESCE(SUM(amount), 0) AS opening
+       FROM corporate_wallet_transactions
+       WHERE account_id = $1 AND status = 'completed' AND completed_at < ${bounds}`,
+      [accountId, periodStart],
+    );
+
+    const txRes = await pool.query(
+      `SELECT t.id, t.transaction_type, t.amount, t.balance_after, t.notes, t.completed_at,
+              t.order_id, o.order_number, e.employee_code, e.employee_name
+       FROM corporate_wallet_transactions t
+       LEFT JOIN orders o ON o.id = t.order_id
+       LEFT JOIN corporate_employees e ON e.id = t.employee_id
+       WHERE t.account_id = $1 AND t.status = 'completed'
+         AND t.completed_at >= ${bounds} AND t.completed_at < ${boundsEnd}
+       ORDER BY t.completed_at ASC`,
+      [accountId, periodStart],
+    );
+
+    const byEmployeeRes = await pool.query(
+      `SELECT e.employee_code, e.employee_name, COUNT(*) AS transactions, COALESCE(SUM(-t.amount), 0) AS total_spent
+       FROM corporate_wallet_transactions t
+       JOIN corporate_employees e ON e.id = t.employee_id
+       WHERE t.account_id = $1 AND t.status = 'completed' AND t.transaction_type = 'redeem'
+         AND t.completed_at >= ${bounds} AND t.completed_at < ${boundsEnd}
+       GROUP BY e.employee_code, e.employee_name
+       ORDER BY total_spent DESC`,
+      [accountId, periodStart],
+    );
+
+    const openingBalance = Number(openingRes.rows[0].opening);
+    let totalTopups = 0;
+    let totalRedeemed = 0;
+    let totalAdjustments = 0;
+
+    const transactions = txRes.rows.map((row) => {
+      const amount = Number(row.amount);
+      if (row.transaction_type === 'topup') totalTopups += amount;
+      else if (row.transaction_type === 'redeem') totalRedeemed += -amount;
+      else totalAdjustments += amount;
+
+      return {
+        id: row.id,
+        transaction_type: row.transaction_type,
+        amount,
+        balance_after: row.balance_after !== null ? Number(row.balance_after) : null,
+        order_id: row.order_id,
+        order_number: row.order_number,
+        employee_code: row.employee_code,
+        employee_name: row.employee_name,
+        notes: row.notes,
+        completed_at: row.completed_at,
+      };
+    });
+
+    return successResponse(c, 'Corporate statement generated successfully', {
+      account: formatAccount(accRes.rows[0]),
+      period: month,
+      opening_balance: openingBalance,
+      total_topups: totalTopups,
+      total_redeemed: totalRedeemed,
+      total_adjustments: totalAdjustments,
+      closing_balance: openingBalance + totalTopups - totalRedeemed + totalAdjustments,
+      transactions,
+      by_employee: byEmployeeRes.rows.map((row) => ({
+        employee_code: row.employee_code,
+        employee_name: row.employee_name,
+        transactions: Number(row.transactions),
+        total_spent: Number(row.total_spent),
+      })),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate statement', (err as Error).message);
+  }
+}
+
+// ── LookupEmployeeCode (counter) ────────────────────────────────────────────
+// Lets the cashier confirm the code and available balance before charging.
+
+export async function lookupEmployeeCode(c: Context) {
+  const code = c.req.param('code');
+
+  try {
+    const res = await pool.query(
+      `SELECT e.id, e.employee_code, e.employee_name, e.daily_limit, e.is_active,
+              a.id AS account_id, a.company_name, a.balance, a.is_active AS account_active,
+              COALESCE((
+                SELECT SUM(-t.amount) FROM corporate_wallet_transactions t
+                WHERE t.employee_id = e.id AND t.transaction_type = 'redeem' AND t.status = 'completed'
+                  AND t.created_at >= date_trunc('day', NOW() AT TIME ZONE 'Asia/Jakarta') AT TIME ZONE 'Asia/Jakarta'
+              ), 0) AS spent_today
+       FROM corporate_employees e
+       JOIN corporate_accounts a ON a.id = e.account_id
+       WHERE UPPER(e.employee_code) = UPPER($1)`,
+      [code],
+    );
+
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Employee code not found', 'employee_code_not_found', 404);
+    }
+
+    const row = res.rows[0];
+    const balance = Number(row.balance);
+    const spentToday = Number(row.spent_today);
+    const dailyLimit = row.daily_limit !== null ? Number(row.daily_limit) : null;
+    const available = dailyLimit !== null ? Math.min(balance, Math.max(0, dailyLimit - spentToday)) : balance;
+
+    return successResponse(c, 'Employee code retrieved successfully', {
+      employee_code: row.employee_code,
+      employee_name: row.employee_name,
+      company_name: row.company_name,
+      account_id: row.account_id,
+      is_active: row.is_active && row.account_active,
+      balance,
+      daily_limit: dailyLimit,
+      spent_today: spentToday,
+      available_amount: available,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to look up employee code', (err as Error).message);
+  }
+}
@@ -0,0 +1,283 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock } from '../lib/clock.js';
+import { isUUID, resolveBranchScope, resolveWriteBranch, branchCondition } from '../services/branches.js';
+import { RECIPE_COSTS, unitCost, buildCogsReport } from '../services/costing.js';
+import { buildMenuEngineeringReport } from '../services/menu-engineering.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+const PRODUCT_COST_SELECT = `
+  SELECT p.id, p.name, p.price, cat.name AS category_name, p.cost_override,
+         r.recipe_cost, COALESCE(r.ingredients, 0)::int AS recipe_ingredients
+  FROM products p
+  LEFT JOIN categories cat ON cat.id = p.category_id
+  LEFT JOIN (${RECIPE_COSTS}) r ON r.product_id = p.id`;
+
+function formatProductCost(row: Record<string, unknown>) {
+  const price = Number(row.price);
+  const cost = unitCost(row as { cost_override: unknown; recipe_cost: unknown });
+  return {
+    product_id: row.id,
+    product_name: row.name,
+    category_name: row.category_name ?? 'Uncategorised',
+    price,
+    cost_override: row.cost_override !== null ? Number(row.cost_override) : null,
+    recipe_cost: row.recipe_cost !== null ? Math.round(Number(row.recipe_cost) * 100) / 100 : null,
+    recipe_ingredients: row.recipe_ingredients,
+    ...cost,
+    margin_pct: cost.unit_cost !== null && price > 0
+      ? Math.round(((price - cost.unit_cost) / price) * 1000) / 10
+      : null,
+  };
+}
+
+// ── GetProductCosts ─────────────────────────────────────────────────────────
+// Unit cost of every product and where it comes from. ?uncosted=true lists
+// only products with neither a recipe nor an override.
+
+export async function getProductCosts(c: Context) {
+  const uncosted = c.req.query('uncosted') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${PRODUCT_COST_SELECT}
+       WHERE p.deleted_at IS NULL ${uncosted ? 'AND p.cost_override IS NULL AND r.recipe_cost IS NULL' : ''}
+       ORDER BY cat.name ASC NULLS LAST, p.name ASC`,
+    );
+    return successResponse(c, 'Product costs retrieved successfully', res.rows.map(formatProductCost));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch product costs', (err as Error).message);
+  }
+}
+
+// ── UpdateProductCost ───────────────────────────────────────────────────────
+// Sets or (with null) clears a product's cost override. Clearing it goes
+// back to the recipe cost.
+
+export async function updateProductCost(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  let body: { cost_override?: number | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.cost_override === undefined) {
+    return errorResponse(c, 'cost_override is required (null clears it)', 'missing_cost_override', 400);
+  }
+  if (body.cost_override !== null
+      && (typeof body.cost_override !== 'number' || !Number.isFinite(body.cost_override) || body.cost_override < 0)) {
+    return errorResponse(c, 'cost_override must be a non-negative amount or null', 'invalid_cost_override', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      'UPDATE products SET cost_override = $2, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING id',
+      [id, body.cost_override],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+
+    const updated = await pool.query(`${PRODUCT_COST_SELECT} WHERE p.id = $1`, [id]);
+    return successResponse(c, 'Product cost updated successfully', formatProductCost(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update product cost', (err as Error).message);
+  }
+}
+
+// ── GetCogsAdjustments ──────────────────────────────────────────────────────
+
+export async function getCogsAdjustments(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const from = c.req.query('from') || '';
+  const to = c.req.query('to') || '';
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to))) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const params: unknown[] = [];
+  let where = 'WHERE 1=1';
+  if (from) {
+    params.push(from);
+    where += ` AND a.adjustment_date >= $${params.length}`;
+  }
+  if (to) {
+    params.push(to);
+    where += ` AND a.adjustment_date <= $${params.length}`;
+  }
+  where += branchCondition('a.branch_id', scope.branchId, params);
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM cogs_adjustments a ${where}`, params);
+        return parseInt(countRes.rows[0].count, 10);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `SELECT a.id, a.branch_id, a.product_id, p.name AS product_name,
+                  to_char(a.adjustment_date, 'YYYY-MM-DD') AS adjustment_date, a.amount::float8 AS amount, a.reason,
+                  a.created_by, u.username AS created_by_username, a.created_at
+           FROM cogs_adjustments a
+           LEFT JOIN products p ON p.id = a.product_id
+           LEFT JOIN users u ON u.id = a.created_by
+           ${where}
+           ORDER BY a.adjustment_date DESC, a.created_at DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'COGS adjustments retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'adjustment_date:desc',
+      filters: { from, to, branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch COGS adjustments', (err as Error).message);
+  }
+}
+
+// ── CreateCogsAdjustment ────────────────────────────────────────────────────
+// A positive amount adds to the period's COGS, a negative one reduces it.
+
+export async function createCogsAdjustment(c: Context) {
+  let body: { branch_id?: string; product_id?: string | null; adjustment_date?: string; amount?: number; reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const adjustmentDate = body.adjustment_date || localClock().date;
+  if (!DATE_RE.test(adjustmentDate) || isNaN(Date.parse(adjustmentDate))) {
+    return errorResponse(c, 'adjustment_date must be a YYYY-MM-DD date', 'invalid_date', 400);
+  }
+  if (typeof body.amount !== 'number' || !Number.isFinite(body.amount) || body.amount === 0) {
+    return errorResponse(c, 'amount must be a non-zero amount', 'invalid_amount', 400);
+  }
+  const reason = body.reason?.trim();
+  if (!reason || reason.length > 255) {
+    return errorResponse(c, 'A reason is required (at most 255 characters)', 'invalid_reason', 400);
+  }
+  if (body.product_id && !isUUID(body.product_id)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 400);
+  }
+
+  try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id') ?? null, body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
+    if (body.product_id) {
+      const product = await pool.query('SELECT 1 FROM products WHERE id = $1', [body.product_id]);
+      if (product.rows.length === 0) {
+        return errorResponse(c, 'Product not found', 'product_not_found', 400);
+      }
+    }
+
+    const res = await pool.query(
+      `INSERT INTO cogs_adjustments (branch_id, product_id, adjustment_date, amount, reason, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6)
+       RETURNING id, branch_id, product_id, to_char(adjustment_date, 'YYYY-MM-DD') AS adjustment_date,
+                 amount::float8 AS amount, reason, created_by, created_at`,
+      [branch.branchId, body.product_id || null, adjustmentDate, body.amount, reason, c.get('user_id')],
+    );
+    return successResponse(c, 'COGS adjustment recorded successfully', res.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to record COGS adjustment', (err as Error).message);
+  }
+}
+
+// ── DeleteCogsAdjustment ────────────────────────────────────────────────────
+
+export async function deleteCogsAdjustment(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'COGS adjustment not found', 'not_found', 404);
+  }
+
+  const params: unknown[] = [id];
+  const ownBranch = c.get('branch_id') ?? null;
+  try {
+    const res = await pool.query(
+      `DELETE FROM cogs_adjustments a WHERE a.id = $1${branchCondition('a.branch_id', ownBranch, params)}`,
+      params,
+    );
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'COGS adjustment not found', 'not_found', 404);
+    }
+    return successResponse(c, 'COGS adjustment deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete COGS adjustment', (err as Error).message);
+  }
+}
+
+// ── GetCogsReport ───────────────────────────────────────────────────────────
+// COGS and margin over [from, to] (default: this month so far).
+
+export async function getCogsReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const report = await buildCogsReport(pool, from, to, scope.branchId);
+    return successResponse(c, 'COGS report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate COGS report', (err as Error).message);
+  }
+}
+
+// ── GetMenuEngineeringReport ────────────────────────────────────────────────
+// Stars, plowhorses, puzzles and dogs over [from, to] (default: this month
+// so far); ?category_id= rates one category's products against each other.
+
+export async function getMenuEngineeringReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+  const categoryId = c.req.query('category_id') || null;
+  if (categoryId && !isUUID(categoryId)) {
+    return errorResponse(c, 'Invalid category ID', 'invalid_category_id', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const report = await buildMenuEngineeringReport(pool, from, to, { branchId: scope.branchId, categoryId });
+    return successResponse(c, 'Menu engineering report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate menu engineering report', (err as Error).message);
+  }
+}
@@ -0,0 +1,207 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { invalidateCache } from '../lib/cache.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { CURRENCY_SELECT, SETTLEMENT_CURRENCY, isCurrencyCode } from '../services/currencies.js';
+
+type CurrencyBody = {
+  code?: string;
+  name?: string;
+  symbol?: string;
+  rate_to_idr?: number;
+  decimal_places?: number;
+  is_active?: boolean;
+};
+
+function validRate(rate: unknown): boolean {
+  return typeof rate === 'number' && Number.isFinite(rate) && rate > 0 && rate < 1e12;
+}
+
+function validDecimals(decimals: unknown): boolean {
+  return Number.isInteger(decimals) && (decimals as number) >= 0 && (decimals as number) <= 4;
+}
+
+// ── GetPublicCurrencies ─────────────────────────────────────────────────────
+// The currencies the menu can show prices in, for the guest's selector.
+
+export async function getPublicCurrencies(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT code, name, symbol, rate_to_idr::float8 AS rate_to_idr, decimal_places, rate_updated_at
+       FROM currencies WHERE is_active = true
+       ORDER BY code ASC`,
+    );
+    return successResponse(c, 'Currencies retrieved successfully', {
+      settlement_currency: SETTLEMENT_CURRENCY,
+      currencies: res.rows,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch currencies', (err as Error).message);
+  }
+}
+
+// ── GetCurrencies ───────────────────────────────────────────────────────────
+
+export async function getCurrencies(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(`${CURRENCY_SELECT} ${activeOnly ? 'WHERE is_active = true' : ''} ORDER BY code ASC`);
+    return successResponse(c, 'Currencies retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch currencies', (err as Error).message);
+  }
+}
+
+// ── CreateCurrency ──────────────────────────────────────────────────────────
+
+export async function createCurrency(c: Context) {
+  let body: CurrencyBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const code = body.code?.trim().toUpperCase() ?? '';
+  if (!isCurrencyCode(code)) {
+    return errorResponse(c, 'code must be a three-letter ISO 4217 code', 'invalid_code', 400);
+  }
+  if (code === SETTLEMENT_CURRENCY) {
+    return errorResponse(c, `${SETTLEMENT_CURRENCY} is the settlement currency and needs no rate`, 'settlement_currency', 400);
+  }
+  const name = body.name?.trim();
+  if (!name || name.length > 100) {
+    return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+  }
+  const symbol = body.symbol?.trim() || code;
+  if (symbol.length > 10) {
+    return errorResponse(c, 'Symbol must be at most 10 characters', 'invalid_symbol', 400);
+  }
+  if (!validRate(body.rate_to_idr)) {
+    return errorResponse(c, 'rate_to_idr must be the positive Rupiah value of one unit', 'invalid_rate', 400);
+  }
+  if (body.decimal_places !== undefined && !validDecimals(body.decimal_places)) {
+    return errorResponse(c, 'decimal_places must be a whole number from 0 to 4', 'invalid_decimal_places', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO currencies (code, name, symbol, rate_to_idr, decimal_places, is_active)
+       VALUES ($1, $2, $3, $4, $5, $6)
+       ON CONFLICT (code) DO NOTHING
+       RETURNING id`,
+      [code, name, symbol, body.rate_to_idr, body.decimal_places ?? 2, body.is_active ?? true],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'This currency already exists', 'duplicate_code', 409);
+    }
+
+    invalidateCache('menu');
+    const created = await pool.query(`${CURRENCY_SELECT} WHERE id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Currency created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create currency', (err as Error).message);
+  }
+}
+
+// ── UpdateCurrency ──────────────────────────────────────────────────────────
+// A new rate applies to the menu and to orders placed from now on; existing
+// orders keep the rate they were placed at.
+
+export async function updateCurrency(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Currency not found', 'not_found', 404);
+  }
+
+  let body: CurrencyBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.name !== undefined) {
+    const name = body.name.trim();
+    if (!name || name.length > 100) {
+      return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+    }
+    setClauses.push(`name = $${paramIdx++}`);
+    params.push(name);
+  }
+  if (body.symbol !== undefined) {
+    const symbol = body.symbol.trim();
+    if (!symbol || symbol.length > 10) {
+      return errorResponse(c, 'Symbol is required (at most 10 characters)', 'invalid_symbol', 400);
+    }
+    setClauses.push(`symbol = $${paramIdx++}`);
+    params.push(symbol);
+  }
+  if (body.rate_to_idr !== undefined) {
+    if (!validRate(body.rate_to_idr)) {
+      return errorResponse(c, 'rate_to_idr must be the positive Rupiah value of one unit', 'invalid_rate', 400);
+    }
+    setClauses.push(`rate_to_idr = $${paramIdx++}`, 'rate_updated_at = NOW()');
+    params.push(body.rate_to_idr);
+  }
+  if (body.decimal_places !== undefined) {
+    if (!validDecimals(body.decimal_places)) {
+      return errorResponse(c, 'decimal_places must be a whole number from 0 to 4', 'invalid_decimal_places', 400);
+    }
+    setClauses.push(`decimal_places = $${paramIdx++}`);
+    params.push(body.decimal_places);
+  }
+  if (body.is_active !== undefined) {
+    setClauses.push(`is_active = $${paramIdx++}`);
+    params.push(body.is_active);
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE currencies SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING id`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Currency not found', 'not_found', 404);
+    }
+
+    invalidateCache('menu');
+    const updated = await pool.query(`${CURRENCY_SELECT} WHERE id = $1`, [id]);
+    return successResponse(c, 'Currency updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update currency', (err as Error).message);
+  }
+}
+
+// ── DeleteCurrency ──────────────────────────────────────────────────────────
+// Orders placed in it keep their code and rate.
+
+export async function deleteCurrency(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Currency not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM currencies WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Currency not found', 'not_found', 404);
+    }
+    invalidateCache('menu');
+    return successResponse(c, 'Currency deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete currency', (err as Error).message);
+  }
+}
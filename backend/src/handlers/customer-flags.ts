@@ -0,0 +1,276 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { CUSTOMER_FLAG_REASONS, findCustomerFlags, flagPhone, type CustomerFlagReason } from '../services/customer-flags.js';
+
+const MAX_REASON_LENGTH = 1000;
+
+function formatFlag(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    phone: row.phone,
+    customer_name: row.customer_name,
+    reason_type: row.reason_type,
+    reason: row.reason,
+    is_active: row.is_active,
+    flagged_by: row.flagged_by ? { id: row.flagged_by, name: row.flagged_by_name } : null,
+    cleared_by: row.cleared_by ? { id: row.cleared_by, name: row.cleared_by_name } : null,
+    cleared_at: row.cleared_at,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+const FLAG_SELECT = `
+  SELECT f.*,
+         NULLIF(TRIM(CONCAT(fu.first_name, ' ', fu.last_name)), '') AS flagged_by_name,
+         NULLIF(TRIM(CONCAT(cu.first_name, ' ', cu.last_name)), '') AS cleared_by_name
+  FROM customer_flags f
+  LEFT JOIN users fu ON fu.id = f.flagged_by
+  LEFT JOIN users cu ON cu.id = f.cleared_by`;
+
+function validReason(reason: unknown): reason is string {
+  return typeof reason === 'string' && reason.trim().length > 0 && reason.length <= MAX_REASON_LENGTH;
+}
+
+// ── GetCustomerFlags ────────────────────────────────────────────────────────
+
+export async function getCustomerFlags(c: Context) {
+  const phone = c.req.query('phone');
+  const includeCleared = c.req.query('include_cleared') === 'true';
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  if (!includeCleared) conditions.push('f.is_active = true');
+  if (phone) {
+    params.push(flagPhone(phone));
+    conditions.push(`f.phone = $${params.length}`);
+  }
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const res = await pool.query(`${FLAG_SELECT} ${where} ORDER BY f.created_at DESC LIMIT 500`, params);
+    return successResponse(c, 'Customer flags retrieved successfully', res.rows.map(formatFlag));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch customer flags', (err as Error).message);
+  }
+}
+
+// ── GetCustomerFlag ─────────────────────────────────────────────────────────
+// Includes the audit trail of who raised, edited and cleared the flag.
+
+export async function getCustomerFlag(c: Context) {
+  const id = c.req.param('id');
+
+  try {
+    const res = await pool.query(`${FLAG_SELECT} WHERE f.id = $1`, [id]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Customer flag not found', 'flag_not_found', 404);
+    }
+
+    const eventsRes = await pool.query(
+      `SELECT e.id, e.action, e.reason_type, e.reason, e.created_at, e.changed_by,
+              NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS changed_by_name
+       FROM customer_flag_events e
+       LEFT JOIN users u ON u.id = e.changed_by
+       WHERE e.flag_id = $1
+       ORDER BY e.created_at ASC`,
+      [id],
+    );
+
+    return successResponse(c, 'Customer flag retrieved successfully', {
+      ...formatFlag(res.rows[0]),
+      history: eventsRes.rows,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch customer flag', (err as Error).message);
+  }
+}
+
+// ── CheckCustomerPhone ──────────────────────────────────────────────────────
+// Lookup for staff taking a booking or delivery order over the phone.
+
+export async function checkCustomerPhone(c: Context) {
+  const phone = c.req.query('phone') || '';
+  if (flagPhone(phone).length < 8) {
+    return errorResponse(c, 'A valid phone number is required', 'invalid_phone', 400);
+  }
+
+  try {
+    const flags = await findCustomerFlags(pool, phone);
+    return successResponse(c, flags.length > 0 ? 'Customer is flagged' : 'No flags for this customer', {
+      flagged: flags.length > 0,
+      flags,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to check customer', (err as Error).message);
+  }
+}
+
+// ── CreateCustomerFlag ──────────────────────────────────────────────────────
+
+export async function createCustomerFlag(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { phone?: string; customer_name?: string; reason_type?: string; reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const phone = flagPhone(body.phone || '');
+  if (phone.length < 8 || phone.length > 15) {
+    return errorResponse(c, 'A valid phone number is required', 'invalid_phone', 400);
+  }
+  if (!CUSTOMER_FLAG_REASONS.includes(body.reason_type as CustomerFlagReason)) {
+    return errorResponse(c, 'Reason type must be no_show, chargeback, abuse or other', 'invalid_reason_type', 400);
+  }
+  if (!validReason(body.reason)) {
+    return errorResponse(c, 'A reason is required (max 1000 characters)', 'invalid_reason', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `INSERT INTO customer_flags (phone, customer_name, reason_type, reason, flagged_by)
+         VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+        [phone, body.customer_name?.trim() || null, body.reason_type, body.reason.trim(), userId],
+      );
+      const flagId = res.rows[0].id;
+
+      await client.query(
+        `INSERT INTO customer_flag_events (flag_id, action, reason_type, reason, changed_by)
+         VALUES ($1, 'flagged', $2, $3, $4)`,
+        [flagId, body.reason_type, body.reason.trim(), userId],
+      );
+
+      return { ok: true as const, flagId };
+    });
+    const { flagId } = result;
+
+    const created = await pool.query(`${FLAG_SELECT} WHERE f.id = $1`, [flagId]);
+    return successResponse(c, 'Customer flagged successfully', formatFlag(created.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to flag customer', (err as Error).message);
+  }
+}
+
+// ── UpdateCustomerFlag ──────────────────────────────────────────────────────
+
+export async function updateCustomerFlag(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { customer_name?: string; reason_type?: string; reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.reason_type !== undefined && !CUSTOMER_FLAG_REASONS.includes(body.reason_type as CustomerFlagReason)) {
+    return errorResponse(c, 'Reason type must be no_show, chargeback, abuse or other', 'invalid_reason_type', 400);
+  }
+  if (body.reason !== undefined && !validReason(body.reason)) {
+    return errorResponse(c, 'A reason is required (max 1000 characters)', 'invalid_reason', 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.customer_name !== undefined) {
+    setClauses.push(`customer_name = $${paramIdx++}`);
+    params.push(body.customer_name.trim() || null);
+  }
+  if (body.reason_type !== undefined) {
+    setClauses.push(`reason_type = $${paramIdx++}`);
+    params.push(body.reason_type);
+  }
+  if (body.reason !== undefined) {
+    setClauses.push(`reason = $${paramIdx++}`);
+    params.push(body.reason.trim());
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  setClauses.push('updated_at = NOW()');
+  params.push(id);
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `UPDATE customer_flags SET ${setClauses.join(', ')}
+         WHERE id = $${paramIdx} AND is_active = true
+         RETURNING reason_type, reason`,
+        params,
+      );
+      if (res.rows.length === 0) {
+        return txFailure('Active customer flag not found', 'flag_not_found', 404);
+      }
+
+      await client.query(
+        `INSERT INTO customer_flag_events (flag_id, action, reason_type, reason, changed_by)
+         VALUES ($1, 'updated', $2, $3, $4)`,
+        [id, res.rows[0].reason_type, res.rows[0].reason, userId],
+      );
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${FLAG_SELECT} WHERE f.id = $1`, [id]);
+    return successResponse(c, 'Customer flag updated successfully', formatFlag(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update customer flag', (err as Error).message);
+  }
+}
+
+// ── ClearCustomerFlag ───────────────────────────────────────────────────────
+// Flags are cleared rather than deleted so the audit trail survives.
+
+export async function clearCustomerFlag(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!validReason(body.reason)) {
+    return errorResponse(c, 'A reason for clearing the flag is required', 'invalid_reason', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `UPDATE customer_flags SET is_active = false, cleared_by = $1, cleared_at = NOW(), updated_at = NOW()
+         WHERE id = $2 AND is_active = true
+         RETURNING id`,
+        [userId, id],
+      );
+      if (res.rows.length === 0) {
+        return txFailure('Active customer flag not found', 'flag_not_found', 404);
+      }
+
+      await client.query(
+        `INSERT INTO customer_flag_events (flag_id, action, reason, changed_by)
+         VALUES ($1, 'cleared', $2, $3)`,
+        [id, body.reason.trim(), userId],
+      );
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    return successResponse(c, 'Customer flag cleared successfully', { id });
+  } catch (err) {
+    return errorResponse(c, 'Failed to clear customer flag', (err as Error).message);
+  }
+}
@@ -0,0 +1,233 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { localClock } from '../lib/clock.js';
+import { invalidateCache } from '../lib/cache.js';
+
+function formatSpecial(row: Record<string, unknown>) {
+  const daily = Number(row.daily_quantity);
+  const remaining = Number(row.remaining_quantity);
+  return {
+    id: row.id,
+    product_id: row.product_id,
+    product_name: row.product_name,
+    price: Number(row.price),
+    image_url: row.image_url || null,
+    daily_quantity: daily,
+    remaining_quantity: remaining,
+    sold_quantity: daily - remaining,
+    sold_out: remaining === 0,
+    sold_out_at: row.sold_out_at,
+    special_date: row.special_date,
+    is_active: row.is_active,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+const SPECIAL_SELECT = `
+  SELECT ds.*, p.name AS product_name, p.price, p.image_url
+  FROM daily_specials ds
+  JOIN products p ON p.id = ds.product_id`;
+
+// ── GetDailySpecials ────────────────────────────────────────────────────────
+
+export async function getDailySpecials(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${SPECIAL_SELECT} ${activeOnly ? 'WHERE ds.is_active = true' : ''} ORDER BY p.name ASC`,
+    );
+    return successResponse(c, 'Daily specials retrieved successfully', res.rows.map(formatSpecial));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch daily specials', (err as Error).message);
+  }
+}
+
+// ── CreateDailySpecial ──────────────────────────────────────────────────────
+
+export async function createDailySpecial(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { product_id?: string; daily_quantity?: number; is_active?: boolean };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.product_id) {
+    return errorResponse(c, 'Product ID is required', 'missing_product_id', 400);
+  }
+  if (!Number.isInteger(body.daily_quantity) || body.daily_quantity! < 1) {
+    return errorResponse(c, 'Daily quantity must be a positive whole number', 'invalid_daily_quantity', 400);
+  }
+
+  try {
+    const productRes = await pool.query(
+      'SELECT id, sale_unit FROM products WHERE id = $1 AND deleted_at IS NULL',
+      [body.product_id],
+    );
+    if (productRes.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+    // Specials are capped in whole portions
+    if (productRes.rows[0].sale_unit !== 'each') {
+      return errorResponse(c, 'Products sold by weight cannot be daily specials', 'sold_by_weight', 400);
+    }
+
+    const existing = await pool.query('SELECT id FROM daily_specials WHERE product_id = $1', [body.product_id]);
+    if (existing.rows.length > 0) {
+      return errorResponse(c, 'Product is already a daily special', 'special_exists', 409);
+    }
+
+    const res = await pool.query(
+      `INSERT INTO daily_specials (product_id, daily_quantity, remaining_quantity, special_date, is_active, created_by)
+       VALUES ($1, $2, $2, $3, $4, $5) RETURNING id`,
+      [body.product_id, body.daily_quantity, localClock().date, body.is_active ?? true, userId],
+    );
+
+    const created = await pool.query(`${SPECIAL_SELECT} WHERE ds.id = $1`, [res.rows[0].id]);
+    invalidateCache('menu');
+    return successResponse(c, 'Daily special created successfully', formatSpecial(created.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create daily special', (err as Error).message);
+  }
+}
+
+// ── UpdateDailySpecial ──────────────────────────────────────────────────────
+// Changing the cap mid-day moves today's remaining count by the same amount,
+// so portions already sold stay counted.
+
+export async function updateDailySpecial(c: Context) {
+  const specialId = c.req.param('id');
+
+  let body: { daily_quantity?: number; is_active?: boolean };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.daily_quantity !== undefined && (!Number.isInteger(body.daily_quantity) || body.daily_quantity < 1)) {
+    return errorResponse(c, 'Daily quantity must be a positive whole number', 'invalid_daily_quantity', 400);
+  }
+
+  try {
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    if (body.daily_quantity !== undefined) {
+      setClauses.push(`remaining_quantity = GREATEST(0, remaining_quantity + $${paramIdx} - daily_quantity)`);
+      setClauses.push(`daily_quantity = $${paramIdx}`);
+      params.push(body.daily_quantity);
+      paramIdx++;
+    }
+    if (body.is_active !== undefined) {
+      setClauses.push(`is_active = $${paramIdx}`);
+      params.push(body.is_active);
+      paramIdx++;
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    params.push(specialId);
+    const res = await pool.query(
+      `UPDATE daily_specials SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING id`,
+      params,
+    );
+
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Daily special not found', 'not_found', 404);
+    }
+
+    // Refresh the sold-out marker against the new count
+    await pool.query(
+      `UPDATE daily_specials
+       SET sold_out_at = CASE WHEN remaining_quantity = 0 THEN COALESCE(sold_out_at, NOW()) ELSE NULL END
+       WHERE id = $1`,
+      [specialId],
+    );
+
+    const updated = await pool.query(`${SPECIAL_SELECT} WHERE ds.id = $1`, [specialId]);
+    invalidateCache('menu');
+    return successResponse(c, 'Daily special updated successfully', formatSpecial(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update daily special', (err as Error).message);
+  }
+}
+
+// ── DeleteDailySpecial ──────────────────────────────────────────────────────
+
+export async function deleteDailySpecial(c: Context) {
+  const specialId = c.req.param('id');
+
+  try {
+    const res = await pool.query('DELETE FROM daily_specials WHERE id = $1', [specialId]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Daily special not found', 'not_found', 404);
+    }
+    invalidateCache('menu');
+    return successResponse(c, 'Daily special deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete daily special', (err as Error).message);
+  }
+}
+
+// ── ResetDailySpecial ───────────────────────────────────────────────────────
+// Manual restock, e.g. when the kitchen prepares a second batch.
+
+export async function resetDailySpecial(c: Context) {
+  const specialId = c.req.param('id');
+
+  try {
+    const res = await pool.query(
+      `UPDATE daily_specials
+       SET remaining_quantity = daily_quantity, special_date = $2, sold_out_at = NULL
+       WHERE id = $1 RETURNING id`,
+      [specialId, localClock().date],
+    );
+
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Daily special not found', 'not_found', 404);
+    }
+
+    const updated = await pool.query(`${SPECIAL_SELECT} WHERE ds.id = $1`, [specialId]);
+    invalidateCache('menu');
+    return successResponse(c, 'Daily special reset successfully', formatSpecial(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to reset daily special', (err as Error).message);
+  }
+}
+
+// ── GetPublicSpecials ───────────────────────────────────────────────────────
+// Today's specials for the customer menu countdown, sold-out ones included.
+
+export async function getPublicSpecials(c: Context) {
+  try {
+    const res = await pool.query(
+      `${SPECIAL_SELECT}
+       WHERE ds.is_active = true AND p.is_available = true AND p.deleted_at IS NULL
+       ORDER BY (ds.remaining_quantity = 0) ASC, p.name ASC`,
+    );
+
+    return successResponse(c, 'Daily specials retrieved successfully', res.rows.map((row) => {
+      const special = formatSpecial(row);
+      return {
+        product_id: special.product_id,
+        name: special.product_name,
+        price: special.price,
+        image_url: special.image_url,
+        daily_quantity: special.daily_quantity,
+        remaining_quantity: special.remaining_quantity,
+        sold_out: special.sold_out,
+      };
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch daily specials', (err as Error).message);
+  }
+}
@@ -1,35 +1,78 @@
 import type { Context } from 'hono';
 import { pool } from '../db/connection.js';
+import { localClock, addDays, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { errorResponse } from '../lib/response.js';
+import { responseCache } from '../lib/cache.js';
+import { loadFormatter, withFormatted, type Formatter } from '../lib/format.js';
+import { weekStart, getTargetResults } from '../services/sales-targets.js';
+import { resolveBranchScope, branchCondition } from '../services/branches.js';
+import { getSlaReport as buildSlaReport } from '../services/order-sla.js';
+import { ITEM_NET } from '../services/tax.js';
+import { ORDER_TYPES } from '../services/data-model.js';
+import { buildDashboardStats, type DashboardStats } from '../services/dashboard.js';
 
-// ── GetDashboardStats ────────────────────────────────────────────────────────
+// Reports cover the caller's branch, or every branch for head office (with a
+// per-branch breakdown) unless narrowed with ?branch_id=. Amounts come with
+// <field>_formatted display strings in the configured locale and currency
+// (lib/format.ts).
 
-export async function getDashboardStats(c: Context) {
-  try {
-    const stats: Record<string, unknown> = {};
+function scopeError(c: Context, failure: { message: string; code: string; status: 400 | 403 }) {
+  return c.json({ success: false, message: failure.message, error: failure.code }, failure.status);
+}
 
-    // Today's orders
-    const todayOrdersRes = await pool.query(
-      `SELECT COUNT(*) FROM orders WHERE DATE(created_at) = CURRENT_DATE`,
-    );
-    stats.today_orders = Number(todayOrdersRes.rows[0].count);
+// Completed sales per branch for a consolidated report; `window` filters o.created_at
+async function salesByBranch(window: string, fmt: Formatter) {
+  const res = await pool.query(`
+    SELECT b.id, b.code, b.name,
+           COUNT(o.id) as order_count,
+           COALESCE(SUM(o.total_amount), 0) as revenue,
+           COALESCE(SUM(o.tax_amount), 0) as tax_collected
+    FROM branches b
+    LEFT JOIN orders o ON o.branch_id = b.id AND o.status = 'completed' AND ${window}
+    GROUP BY b.id
+    ORDER BY b.is_default DESC, b.name ASC
+  `);
+  return res.rows.map((row: Record<string, unknown>) => withFormatted({
+    branch_id: row.id,
+    branch_code: row.code,
+    branch_name: row.name,
+    order_count: Number(row.order_count),
+    revenue: Number(row.revenue),
+    tax_collected: Number(row.tax_collected),
+  }, ['revenue', 'tax_collected'], fmt.money));
+}
 
-    // Today's revenue
-    const todayRevenueRes = await pool.query(
-      `SELECT COALESCE(SUM(total_amount), 0) as total FROM orders WHERE DATE(created_at) = CURRENT_DATE AND status = 'completed'`,
-    );
-    stats.today_revenue = Number(todayRevenueRes.rows[0].total);
+const DASHBOARD_CACHE = 'dashboard';
+const DASHBOARD_CACHE_TTL_MS = 30_000;
 
-    // Active orders
-    const activeOrdersRes = await pool.query(
-      `SELECT COUNT(*) FROM orders WHERE status NOT IN ('completed', 'cancelled')`,
-    );
-    stats.active_orders = Number(activeOrdersRes.rows[0].count);
+// ── GetDashboardStats ────────────────────────────────────────────────────────
+// Today so far against yesterday and the same weekday last week up to the
+// same time (services/dashboard.ts). Every open dashboard polls this, so the
+// result is shared through the response cache for DASHBOARD_CACHE_TTL_MS per
+// branch scope, across instances when Redis is configured.
 
-    // Occupied tables
-    const occupiedTablesRes = await pool.query(
-      `SELECT COUNT(*) FROM dining_tables WHERE is_occupied = true`,
-    );
-    stats.occupied_tables = Number(occupiedTablesRes.rows[0].count);
+export async function getDashboardStats(c: Context) {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+  const cacheKey = scope.branchId ?? '*';
+
+  try {
+    const cached = await responseCache.get(DASHBOARD_CACHE, cacheKey);
+    if (cached) {
+      return c.json({
+        success: true,
+        message: 'Dashboard stats retrieved successfully',
+        data: JSON.parse(cached),
+      });
+    }
+
+    const fmt = await loadFormatter(pool);
+    const stats: DashboardStats & { by_branch?: unknown[] } = await buildDashboardStats(pool, scope.branchId, fmt);
+    if (!scope.branchId) {
+      // Both are ours (the timezone constant and a YYYY-MM-DD date), not user input
+      stats.by_branch = await salesByBranch(`DATE(o.created_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') = '${stats.date}'`, fmt);
+    }
+    await responseCache.set(DASHBOARD_CACHE, cacheKey, JSON.stringify(stats), DASHBOARD_CACHE_TTL_MS);
 
     return c.json({
       success: true,
@@ -50,49 +93,63 @@ export async function getDashboardStats(c: Context) {
 export async function getSalesReport(c: Context) {
   const period = c.req.query('period') || 'today';
 
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  const params: unknown[] = [];
+  const branchFilter = branchCondition('branch_id', scope.branchId, params);
+
   let query: string;
+  let salesWindow: string;
   switch (period) {
     case 'week':
       query = `
         SELECT DATE(created_at) as date, COUNT(*) as order_count, SUM(total_amount) as revenue
         FROM orders
-        WHERE created_at >= CURRENT_DATE - INTERVAL '7 days' AND status = 'completed'
+        WHERE created_at >= CURRENT_DATE - INTERVAL '7 days' AND status = 'completed'${branchFilter}
         GROUP BY DATE(created_at)
         ORDER BY date DESC
       `;
+      salesWindow = "o.created_at >= CURRENT_DATE - INTERVAL '7 days'";
       break;
     case 'month':
       query = `
         SELECT DATE(created_at) as date, COUNT(*) as order_count, SUM(total_amount) as revenue
         FROM orders
-        WHERE created_at >= CURRENT_DATE - INTERVAL '30 days' AND status = 'completed'
+        WHERE created_at >= CURRENT_DATE - INTERVAL '30 days' AND status = 'completed'${branchFilter}
         GROUP BY DATE(created_at)
         ORDER BY date DESC
       `;
+      salesWindow = "o.created_at >= CURRENT_DATE - INTERVAL '30 days'";
       break;
     default: // today
       query = `
         SELECT DATE_TRUNC('hour', created_at) as hour, COUNT(*) as order_count, SUM(total_amount) as revenue
         FROM orders
-        WHERE DATE(created_at) = CURRENT_DATE AND status = 'completed'
+        WHERE DATE(created_at) = CURRENT_DATE AND status = 'completed'${branchFilter}
         GROUP BY DATE_TRUNC('hour', created_at)
         ORDER BY hour DESC
       `;
+      salesWindow = 'DATE(o.created_at) = CURRENT_DATE';
   }
 
   try {
-    const res = await pool.query(query);
-    const report = res.rows.map((row: Record<string, unknown>) => ({
+    const [res, fmt] = await Promise.all([pool.query(query, params), loadFormatter(pool)]);
+    const report = res.rows.map((row: Record<string, unknown>) => withFormatted({
       date: row.date || row.hour,
       order_count: Number(row.order_count),
       revenue: Number(row.revenue),
-    }));
+    }, ['revenue'], fmt.money));
 
-    return c.json({
+    const response: Record<string, unknown> = {
       success: true,
       message: 'Sales report retrieved successfully',
       data: report,
-    });
+    };
+    if (!scope.branchId) {
+      response.by_branch = await salesByBranch(salesWindow, fmt);
+    }
+    return c.json(response);
   } catch (err) {
     return c.json({
       success: false,
@@ -105,22 +162,27 @@ export async function getSalesReport(c: Context) {
 // ── GetOrdersReport ──────────────────────────────────────────────────────────
 
 export async function getOrdersReport(c: Context) {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
   try {
+    const params: unknown[] = [];
     const res = await pool.query(`
       SELECT
         status,
         COUNT(*) as count,
         AVG(total_amount) as avg_amount
       FROM orders
-      WHERE DATE(created_at) = CURRENT_DATE
+      WHERE DATE(created_at) = CURRENT_DATE${branchCondition('branch_id', scope.branchId, params)}
       GROUP BY status
-    `);
+    `, params);
+    const fmt = await loadFormatter(pool);
 
-    const report = res.rows.map((row: Record<string, unknown>) => ({
+    const report = res.rows.map((row: Record<string, unknown>) => withFormatted({
       status: row.status,
       count: Number(row.count),
       avg_amount: Number(row.avg_amount),
-    }));
+    }, ['avg_amount'], fmt.money));
 
     return c.json({
       success: true,
@@ -136,87 +198,160 @@ export async function getOrdersReport(c: Context) {
   }
 }
 
+// Refunds are negative payments dated when they were issued, not when the
+// original order was placed, so the same bucket can hold both. They count
+// against the branch of the refunded order.
+function refundsQuery(bucket: string, window: string, branchFilter: string): string {
+  return `
+    SELECT
+      DATE_TRUNC('${bucket}', p.created_at) as period,
+      COUNT(*) as refund_count,
+      SUM(-p.amount) as refunds
+    FROM payments p
+    JOIN orders o ON o.id = p.order_id
+    WHERE ${window}
+      AND p.refund_of IS NOT NULL
+      AND p.status = 'completed'${branchFilter}
+    GROUP BY DATE_TRUNC('${bucket}', p.created_at)
+  `;
+}
+
+// Tax collected and service charge per tax class, as itemized on receipts.
+// Items from before per-item tax fall under the default label.
+const ITEM_TAX_LABEL = `COALESCE(oi.tax_label, CASE WHEN oi.tax_exempt THEN 'Tax exempt' ELSE 'Tax' END)`;
+
+function taxClassQuery(window: string, branchFilter: string): string {
+  return `
+    SELECT oi.tax_class_id, ${ITEM_TAX_LABEL} as label, oi.tax_rate as rate,
+           COUNT(*) as items, SUM(oi.tax_amount) as tax, SUM(oi.service_charge_amount) as service_charge
+    FROM order_items oi
+    JOIN orders o ON o.id = oi.order_id
+    WHERE ${window}
+      AND o.status = 'completed'${branchFilter}
+    GROUP BY oi.tax_class_id, 2, oi.tax_rate
+    ORDER BY oi.tax_rate DESC NULLS LAST, label ASC
+  `;
+}
+
+const INCOME_SUMMARY_AMOUNTS = [
+  'gross_income', 'tax_collected', 'service_charge_collected', 'net_income', 'refunds_total', 'net_income_after_refunds',
+];
+const INCOME_BREAKDOWN_AMOUNTS = ['gross', 'tax', 'net', 'refunds', 'net_after_refunds'];
+
 // ── GetIncomeReport ──────────────────────────────────────────────────────────
 
 export async function getIncomeReport(c: Context) {
   const period = c.req.query('period') || 'today';
 
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  // Every query takes the same single parameter, if any
+  const params: unknown[] = [];
+  const branchFilter = branchCondition('branch_id', scope.branchId, params);
+  const refundBranchFilter = scope.branchId ? ' AND o.branch_id = $1' : '';
+
   let query: string;
+  let refundQuery: string;
+  let salesWindow: string;
   switch (period) {
     case 'week':
       query = `
         SELECT
           DATE_TRUNC('day', created_at) as period,
           COUNT(*) as total_orders,
-          SUM(total_amount) as gross_income,
+          SUM(total_amount - deposit_amount) as gross_income,
           SUM(tax_amount) as tax_collected,
-          SUM(total_amount - tax_amount) as net_income
+          SUM(total_amount - deposit_amount - tax_amount) as net_income
         FROM orders
         WHERE created_at >= CURRENT_DATE - INTERVAL '7 days'
-          AND status = 'completed'
+          AND status = 'completed'${branchFilter}
         GROUP BY DATE_TRUNC('day', created_at)
         ORDER BY period DESC
       `;
+      refundQuery = refundsQuery('day', "p.created_at >= CURRENT_DATE - INTERVAL '7 days'", refundBranchFilter);
+      salesWindow = "o.created_at >= CURRENT_DATE - INTERVAL '7 days'";
       break;
     case 'month':
       query = `
         SELECT
           DATE_TRUNC('day', created_at) as period,
           COUNT(*) as total_orders,
-          SUM(total_amount) as gross_income,
+          SUM(total_amount - deposit_amount) as gross_income,
           SUM(tax_amount) as tax_collected,
-          SUM(total_amount - tax_amount) as net_income
+          SUM(total_amount - deposit_amount - tax_amount) as net_income
         FROM orders
         WHERE created_at >= CURRENT_DATE - INTERVAL '30 days'
-          AND status = 'completed'
+          AND status = 'completed'${branchFilter}
         GROUP BY DATE_TRUNC('day', created_at)
         ORDER BY period DESC
       `;
+      refundQuery = refundsQuery('day', "p.created_at >= CURRENT_DATE - INTERVAL '30 days'", refundBranchFilter);
+      salesWindow = "o.created_at >= CURRENT_DATE - INTERVAL '30 days'";
       break;
     case 'year':
       query = `
         SELECT
           DATE_TRUNC('month', created_at) as period,
           COUNT(*) as total_orders,
-          SUM(total_amount) as gross_income,
+          SUM(total_amount - deposit_amount) as gross_income,
           SUM(tax_amount) as tax_collected,
-          SUM(total_amount - tax_amount) as net_income
+          SUM(total_amount - deposit_amount - tax_amount) as net_income
         FROM orders
         WHERE created_at >= CURRENT_DATE - INTERVAL '1 year'
-          AND status = 'completed'
+          AND status = 'completed'${branchFilter}
         GROUP BY DATE_TRUNC('month', created_at)
         ORDER BY period DESC
       `;
+      refundQuery = refundsQuery('month', "p.created_at >= CURRENT_DATE - INTERVAL '1 year'", refundBranchFilter);
+      salesWindow = "o.created_at >= CURRENT_DATE - INTERVAL '1 year'";
       break;
     default: // today
       query = `
         SELECT
           DATE_TRUNC('hour', created_at) as period,
           COUNT(*) as total_orders,
-          SUM(total_amount) as gross_income,
+          SUM(total_amount - deposit_amount) as gross_income,
           SUM(tax_amount) as tax_collected,
-          SUM(total_amount - tax_amount) as net_income
+          SUM(total_amount - deposit_amount - tax_amount) as net_income
         FROM orders
         WHERE DATE(created_at) = CURRENT_DATE
-          AND status = 'completed'
+          AND status = 'completed'${branchFilter}
         GROUP BY DATE_TRUNC('hour', created_at)
         ORDER BY period DESC
       `;
+      refundQuery = refundsQuery('hour', 'DATE(p.created_at) = CURRENT_DATE', refundBranchFilter);
+      salesWindow = 'DATE(o.created_at) = CURRENT_DATE';
   }
 
   try {
-    const res = await pool.query(query);
+    const [res, refundRes, taxClassRes, fmt] = await Promise.all([
+      pool.query(query, params),
+      pool.query(refundQuery, params),
+      pool.query(taxClassQuery(salesWindow, refundBranchFilter), params),
+      loadFormatter(pool),
+    ]);
+
+    const refundsByPeriod = new Map<number, { refunds: number; count: number }>();
+    for (const row of refundRes.rows) {
+      refundsByPeriod.set(new Date(row.period).getTime(), { refunds: Number(row.refunds), count: Number(row.refund_count) });
+    }
 
     let totalOrders = 0;
     let totalGross = 0;
     let totalTax = 0;
     let totalNet = 0;
+    let totalRefunds = 0;
+    let totalRefundCount = 0;
 
     const breakdown = res.rows.map((row: Record<string, unknown>) => {
       const orders = Number(row.total_orders);
       const gross = Number(row.gross_income);
       const tax = Number(row.tax_collected);
       const net = Number(row.net_income);
+      const key = new Date(row.period as string).getTime();
+      const refunds = refundsByPeriod.get(key)?.refunds ?? 0;
+      refundsByPeriod.delete(key);
 
       totalOrders += orders;
       totalGross += gross;
@@ -229,21 +364,50 @@ export async function getIncomeReport(c: Context) {
         gross,
         tax,
         net,
+        refunds,
+        net_after_refunds: net - refunds,
       };
     });
 
+    // Buckets that only contain refunds
+    for (const [key, r] of refundsByPeriod) {
+      breakdown.push({ period: new Date(key), orders: 0, gross: 0, tax: 0, net: 0, refunds: r.refunds, net_after_refunds: -r.refunds });
+    }
+    breakdown.sort((a, b) => new Date(b.period as string).getTime() - new Date(a.period as string).getTime());
+
+    const taxByClass = taxClassRes.rows.map((row: Record<string, unknown>) => ({
+      tax_class_id: row.tax_class_id,
+      label: row.label,
+      rate: row.rate === null ? null : Number(row.rate),
+      items: Number(row.items),
+      tax: Number(row.tax),
+      service_charge: Number(row.service_charge),
+    }));
+
+    for (const row of refundRes.rows) {
+      totalRefunds += Number(row.refunds);
+      totalRefundCount += Number(row.refund_count);
+    }
+
     return c.json({
       success: true,
       message: 'Income report retrieved successfully',
       data: {
-        summary: {
+        summary: withFormatted({
           total_orders: totalOrders,
           gross_income: totalGross,
           tax_collected: totalTax,
+          service_charge_collected: taxByClass.reduce((sum, row) => sum + row.service_charge, 0),
           net_income: totalNet,
-        },
-        breakdown,
+          refunds_total: totalRefunds,
+          refund_count: totalRefundCount,
+          net_income_after_refunds: totalNet - totalRefunds,
+        }, INCOME_SUMMARY_AMOUNTS, fmt.money),
+        breakdown: breakdown.map((row) => withFormatted(row, INCOME_BREAKDOWN_AMOUNTS, fmt.money)),
+        tax_by_class: taxByClass.map((row) => withFormatted(row, ['tax', 'service_charge'], fmt.money)),
         period,
+        branch_id: scope.branchId,
+        ...(scope.branchId ? {} : { by_branch: await salesByBranch(salesWindow, fmt) }),
       },
     });
   } catch (err) {
@@ -254,3 +418,646 @@ export async function getIncomeReport(c: Context) {
     }, 500);
   }
 }
+
+// ── GetStaffPerformanceReport ────────────────────────────────────────────────
+// Per staff member over [from, to] (default: this week so far): orders taken,
+// net sales, and how many daily / weekly targets they hit.
+
+export async function getStaffPerformanceReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || weekStart(today);
+  const to = c.req.query('to') || today;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  try {
+    const res = await pool.query(
+      `SELECT u.id, u.username, u.first_name, u.last_name, u.role,
+              COUNT(o.id) FILTER (WHERE o.status = 'completed') AS completed_orders,
+              COUNT(o.id) FILTER (WHERE o.status = 'cancelled') AS cancelled_orders,
+              COALESCE(SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount) FILTER (WHERE o.status = 'completed'), 0) AS net_sales
+       FROM users u
+       LEFT JOIN orders o
+         ON o.user_id = u.id AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR o.branch_id = $4)
+       WHERE u.deleted_at IS NULL
+         AND ($4::uuid IS NULL OR u.branch_id IS NULL OR u.branch_id = $4)
+       GROUP BY u.id
+       HAVING COUNT(o.id) > 0 OR EXISTS (SELECT 1 FROM sales_targets t WHERE t.user_id = u.id)
+       ORDER BY net_sales DESC`,
+      [from, to, RESTAURANT_TIMEZONE, scope.branchId],
+    );
+
+    const results = await getTargetResults(pool, from, to, today);
+    const fmt = await loadFormatter(pool);
+
+    const staff = res.rows.map((row: Record<string, unknown>) => {
+      const completed = Number(row.completed_orders);
+      const netSales = Number(row.net_sales);
+      const targets = results.get(row.id as string) ?? { days_with_target: 0, days_met: 0, weeks_with_target: 0, weeks_met: 0 };
+      return withFormatted({
+        user_id: row.id,
+        username: row.username,
+        first_name: row.first_name,
+        last_name: row.last_name,
+        role: row.role,
+        completed_orders: completed,
+        cancelled_orders: Number(row.cancelled_orders),
+        net_sales: netSales,
+        average_order_value: completed > 0 ? Math.round(netSales / completed) : 0,
+        targets: {
+          ...targets,
+          daily_hit_rate: targets.days_with_target > 0 ? Math.round((targets.days_met / targets.days_with_target) * 1000) / 10 : null,
+          weekly_hit_rate: targets.weeks_with_target > 0 ? Math.round((targets.weeks_met / targets.weeks_with_target) * 1000) / 10 : null,
+        },
+      }, ['net_sales', 'average_order_value'], fmt.money);
+    });
+
+    return c.json({
+      success: true,
+      message: 'Staff performance report retrieved successfully',
+      data: { from, to, branch_id: scope.branchId, staff },
+    });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch staff performance report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
+
+// ── GetServerReport ──────────────────────────────────────────────────────────
+// Per server over [from, to] (default: this week so far): the completed
+// orders they are credited with (orders.server_id), covers, net sales,
+// service charge and average ticket, and the hours they were assigned to a
+// section, for tip pooling and reviews. Sales per cover only counts orders
+// that recorded covers.
+
+export async function getServerReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || weekStart(today);
+  const to = c.req.query('to') || today;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  try {
+    const res = await pool.query(
+      `WITH sales AS (
+         SELECT o.server_id,
+                COUNT(*) AS orders,
+                COUNT(*) FILTER (WHERE o.order_type = 'dine_in') AS dine_in_orders,
+                COALESCE(SUM(o.covers), 0) AS covers,
+                COUNT(*) FILTER (WHERE o.order_type = 'dine_in' AND o.covers IS NULL) AS orders_without_covers,
+                SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount) AS net_sales,
+                COALESCE(SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount)
+                  FILTER (WHERE o.covers IS NOT NULL), 0) AS covered_sales,
+                SUM(o.service_charge_amount) AS service_charge
+         FROM orders o
+         WHERE o.status = 'completed' AND o.server_id IS NOT NULL
+           AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+           AND ($4::uuid IS NULL OR o.branch_id = $4)
+         GROUP BY o.server_id
+       ),
+       shifts AS (
+         SELECT sa.user_id,
+                SUM(EXTRACT(EPOCH FROM LEAST(sa.ends_at, ($2::date + 1)::timestamp AT TIME ZONE $3)
+                                     - GREATEST(sa.starts_at, $1::date::timestamp AT TIME ZONE $3))) / 3600 AS hours
+         FROM section_assignments sa
+         JOIN server_sections s ON s.id = sa.section_id
+         WHERE sa.starts_at < ($2::date + 1)::timestamp AT TIME ZONE $3
+           AND sa.ends_at > $1::date::timestamp AT TIME ZONE $3
+           AND ($4::uuid IS NULL OR s.branch_id = $4)
+         GROUP BY sa.user_id
+       )
+       SELECT u.id, u.username, u.first_name, u.last_name, u.role,
+              COALESCE(sales.orders, 0) AS orders, COALESCE(sales.dine_in_orders, 0) AS dine_in_orders,
+              COALESCE(sales.covers, 0) AS covers, COALESCE(sales.orders_without_covers, 0) AS orders_without_covers,
+              COALESCE(sales.net_sales, 0) AS net_sales, COALESCE(sales.covered_sales, 0) AS covered_sales,
+              COALESCE(sales.service_charge, 0) AS service_charge, COALESCE(shifts.hours, 0) AS section_hours
+       FROM users u
+       LEFT JOIN sales ON sales.server_id = u.id
+       LEFT JOIN shifts ON shifts.user_id = u.id
+       WHERE sales.server_id IS NOT NULL OR shifts.user_id IS NOT NULL
+       ORDER BY net_sales DESC, u.first_name ASC`,
+      [from, to, RESTAURANT_TIMEZONE, scope.branchId],
+    );
+
+    const fmt = await loadFormatter(pool);
+    const servers = res.rows.map((row: Record<string, unknown>) => {
+      const orders = Number(row.orders);
+      const covers = Number(row.covers);
+      const netSales = Number(row.net_sales);
+      return withFormatted({
+        user_id: row.id,
+        username: row.username,
+        first_name: row.first_name,
+        last_name: row.last_name,
+        role: row.role,
+        orders,
+        dine_in_orders: Number(row.dine_in_orders),
+        covers,
+        // Dine-in orders placed without a cover count
+        orders_without_covers: Number(row.orders_without_covers),
+        net_sales: netSales,
+        service_charge: Number(row.service_charge),
+        average_ticket: orders > 0 ? Math.round(netSales / orders) : 0,
+        sales_per_cover: covers > 0 ? Math.round(Number(row.covered_sales) / covers) : null,
+        section_hours: Math.round(Number(row.section_hours) * 100) / 100,
+      }, ['net_sales', 'service_charge', 'average_ticket', 'sales_per_cover'], fmt.money);
+    });
+
+    const totalOrders = servers.reduce((sum, s) => sum + s.orders, 0);
+    const totalSales = servers.reduce((sum, s) => sum + s.net_sales, 0);
+    const totals = withFormatted({
+      orders: totalOrders,
+      covers: servers.reduce((sum, s) => sum + s.covers, 0),
+      net_sales: totalSales,
+      service_charge: servers.reduce((sum, s) => sum + s.service_charge, 0),
+      average_ticket: totalOrders > 0 ? Math.round(totalSales / totalOrders) : 0,
+    }, ['net_sales', 'service_charge', 'average_ticket'], fmt.money);
+
+    return c.json({
+      success: true,
+      message: 'Server report retrieved successfully',
+      data: { from, to, branch_id: scope.branchId, servers, totals },
+    });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch server report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
+
+// ── GetTipReport ─────────────────────────────────────────────────────────────
+// Tips for payout over [from, to] (default: today), per recipient
+// (payments.tip_recipient_id) and, within that, per section shift the tip
+// was taken in; tips taken outside any shift are under a null shift. Cash
+// tips are already in the drawer, non-cash ones are paid out of it.
+
+const TIP_AMOUNTS = ['tips', 'cash_tips', 'non_cash_tips', 'tipped_sales'];
+
+export async function getTipReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || today;
+  const to = c.req.query('to') || from;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  try {
+    const res = await pool.query(
+      `SELECT p.tip_recipient_id, u.username, u.first_name, u.last_name, u.role,
+              shift.id AS assignment_id, shift.section_name, shift.starts_at, shift.ends_at,
+              COUNT(*) AS tipped_payments,
+              SUM(p.amount) AS tipped_sales,
+              SUM(p.tip_amount) AS tips,
+              COALESCE(SUM(p.tip_amount) FILTER (WHERE p.payment_method = 'cash'), 0) AS cash_tips
+       FROM payments p
+       JOIN orders o ON o.id = p.order_id
+       LEFT JOIN users u ON u.id = p.tip_recipient_id
+       LEFT JOIN LATERAL (
+         SELECT sa.id, s.name AS section_name, sa.starts_at, sa.ends_at
+         FROM section_assignments sa
+         JOIN server_sections s ON s.id = sa.section_id
+         WHERE sa.user_id = p.tip_recipient_id AND s.branch_id = o.branch_id
+           AND COALESCE(p.processed_at, p.created_at) >= sa.starts_at
+           AND COALESCE(p.processed_at, p.created_at) < sa.ends_at
+         ORDER BY sa.starts_at ASC
+         LIMIT 1
+       ) shift ON true
+       WHERE p.status = 'completed' AND p.tip_amount > 0
+         AND DATE(COALESCE(p.processed_at, p.created_at) AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR o.branch_id = $4)
+       GROUP BY p.tip_recipient_id, u.id, shift.id, shift.section_name, shift.starts_at, shift.ends_at
+       ORDER BY u.first_name ASC NULLS LAST, shift.starts_at ASC NULLS LAST`,
+      [from, to, RESTAURANT_TIMEZONE, scope.branchId],
+    );
+
+    const fmt = await loadFormatter(pool);
+    const amounts = (row: Record<string, unknown>) => {
+      const tips = Number(row.tips);
+      const cashTips = Number(row.cash_tips);
+      const tippedSales = Number(row.tipped_sales);
+      return {
+        tipped_payments: Number(row.tipped_payments),
+        tipped_sales: tippedSales,
+        tips,
+        cash_tips: cashTips,
+        non_cash_tips: tips - cashTips,
+        tip_percent: tippedSales > 0 ? Math.round((tips / tippedSales) * 1000) / 10 : 0,
+      };
+    };
+    const sum = (rows: ReturnType<typeof amounts>[]) => {
+      const tips = rows.reduce((t, r) => t + r.tips, 0);
+      const tippedSales = rows.reduce((t, r) => t + r.tipped_sales, 0);
+      return {
+        tipped_payments: rows.reduce((t, r) => t + r.tipped_payments, 0),
+        tipped_sales: tippedSales,
+        tips,
+        cash_tips: rows.reduce((t, r) => t + r.cash_tips, 0),
+        non_cash_tips: rows.reduce((t, r) => t + r.non_cash_tips, 0),
+        tip_percent: tippedSales > 0 ? Math.round((tips / tippedSales) * 1000) / 10 : 0,
+      };
+    };
+
+    // A recipient deleted since has a null user_id and no name
+    type ShiftTips = ReturnType<typeof amounts> & Record<string, unknown>;
+    const byRecipient = new Map<string, { user: Record<string, unknown>; shifts: ShiftTips[] }>();
+    for (const row of res.rows) {
+      const key = row.tip_recipient_id ?? '';
+      if (!byRecipient.has(key)) {
+        byRecipient.set(key, {
+          user: {
+            user_id: row.tip_recipient_id,
+            username: row.username,
+            first_name: row.first_name,
+            last_name: row.last_name,
+            role: row.role,
+          },
+          shifts: [],
+        });
+      }
+      byRecipient.get(key)!.shifts.push({
+        assignment_id: row.assignment_id,
+        section_name: row.section_name,
+        starts_at: row.starts_at,
+        ends_at: row.ends_at,
+        ...amounts(row),
+      });
+    }
+
+    const recipients = [...byRecipient.values()].map(({ user, shifts }) => ({
+      ...user,
+      ...withFormatted(sum(shifts), TIP_AMOUNTS, fmt.money),
+      shifts: shifts.map((shift) => withFormatted(shift, TIP_AMOUNTS, fmt.money)),
+    }));
+    const totals = withFormatted(sum(recipients), TIP_AMOUNTS, fmt.money);
+
+    return c.json({
+      success: true,
+      message: 'Tip report retrieved successfully',
+      data: { from, to, branch_id: scope.branchId, recipients, totals },
+    });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch tip report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
+
+const TAX_REPORT_AMOUNTS = [
+  'net_sales', 'taxable_sales', 'tax_exempt_sales', 'service_exempt_sales', 'service_charge', 'surcharges', 'tax_collected', 'tax',
+];
+
+// ── GetTaxReport ─────────────────────────────────────────────────────────────
+// Tax and service charge over [from, to] (default: this month so far), with
+// sales split into taxable and exempt, per day, per category and per tax
+// class, and the exempt products. Item amounts are net of the order's
+// discounts (spread pro rata). Totals come from the orders; items from
+// before per-item tax was recorded count as taxable.
+
+export async function getTaxReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const where = `o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2${branchCondition('o.branch_id', scope.branchId, params)}`;
+
+  try {
+    const dailyRes = await pool.query(
+      `SELECT d.day, d.orders, d.net_sales, d.service_charge, d.surcharges, d.tax_collected,
+              COALESCE(i.tax_exempt_sales, 0) AS tax_exempt_sales,
+              COALESCE(i.service_exempt_sales, 0) AS service_exempt_sales
+       FROM (
+         SELECT to_char(DATE(o.created_at AT TIME ZONE $3), 'YYYY-MM-DD') AS day, COUNT(*) AS orders,
+                SUM(o.subtotal - o.discount_amount) AS net_sales,
+                SUM(o.service_charge_amount) AS service_charge, SUM(o.surcharge_amount) AS surcharges,
+                SUM(o.tax_amount) AS tax_collected
+         FROM orders o WHERE ${where}
+         GROUP BY 1
+       ) d
+       LEFT JOIN (
+         SELECT to_char(DATE(o.created_at AT TIME ZONE $3), 'YYYY-MM-DD') AS day,
+                SUM(${ITEM_NET}) FILTER (WHERE oi.tax_exempt) AS tax_exempt_sales,
+                SUM(${ITEM_NET}) FILTER (WHERE oi.service_exempt) AS service_exempt_sales
+         FROM order_items oi JOIN orders o ON o.id = oi.order_id
+         WHERE ${where}
+         GROUP BY 1
+       ) i ON i.day = d.day
+       ORDER BY d.day ASC`,
+      params,
+    );
+
+    const categoryRes = await pool.query(
+      `SELECT cat.id AS category_id, COALESCE(cat.name, 'Uncategorised') AS category_name,
+              SUM(${ITEM_NET}) AS net_sales,
+              COALESCE(SUM(${ITEM_NET}) FILTER (WHERE oi.tax_exempt), 0) AS tax_exempt_sales,
+              COALESCE(SUM(${ITEM_NET}) FILTER (WHERE oi.service_exempt), 0) AS service_exempt_sales,
+              SUM(oi.service_charge_amount) AS service_charge, SUM(oi.tax_amount) AS tax
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       JOIN products p ON p.id = oi.product_id
+       LEFT JOIN categories cat ON cat.id = p.category_id
+       WHERE ${where}
+       GROUP BY cat.id, cat.name
+       ORDER BY net_sales DESC`,
+      params,
+    );
+
+    const taxClassRes = await pool.query(
+      `SELECT oi.tax_class_id, ${ITEM_TAX_LABEL} AS label, oi.tax_rate AS rate,
+              SUM(${ITEM_NET}) AS net_sales, SUM(oi.service_charge_amount) AS service_charge, SUM(oi.tax_amount) AS tax
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       WHERE ${where}
+       GROUP BY oi.tax_class_id, 2, oi.tax_rate
+       ORDER BY oi.tax_rate DESC NULLS LAST, label ASC`,
+      params,
+    );
+
+    const exemptRes = await pool.query(
+      `SELECT p.id AS product_id, p.name, oi.tax_exempt, oi.service_exempt,
+              SUM(oi.quantity) AS quantity, SUM(${ITEM_NET}) AS net_sales
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       JOIN products p ON p.id = oi.product_id
+       WHERE ${where} AND (oi.tax_exempt OR oi.service_exempt)
+       GROUP BY p.id, p.name, oi.tax_exempt, oi.service_exempt
+       ORDER BY net_sales DESC`,
+      params,
+    );
+
+    const fmt = await loadFormatter(pool);
+    const money = (row: Record<string, unknown>) => withFormatted(row, TAX_REPORT_AMOUNTS, fmt.money);
+    const round = (n: unknown) => Math.round(Number(n ?? 0) * 100) / 100;
+    const totals = {
+      orders: 0, net_sales: 0, taxable_sales: 0, tax_exempt_sales: 0, service_exempt_sales: 0,
+      service_charge: 0, surcharges: 0, tax_collected: 0,
+    };
+    const daily = dailyRes.rows.map((row: Record<string, unknown>) => {
+      const day = {
+        date: row.day,
+        orders: Number(row.orders),
+        net_sales: round(row.net_sales),
+        taxable_sales: round(Number(row.net_sales) - Number(row.tax_exempt_sales)),
+        tax_exempt_sales: round(row.tax_exempt_sales),
+        service_exempt_sales: round(row.service_exempt_sales),
+        service_charge: round(row.service_charge),
+        // Holiday and event surcharges; their tax is in tax_collected
+        surcharges: round(row.surcharges),
+        tax_collected: round(row.tax_collected),
+      };
+      totals.orders += day.orders;
+      totals.net_sales += day.net_sales;
+      totals.taxable_sales += day.taxable_sales;
+      totals.tax_exempt_sales += day.tax_exempt_sales;
+      totals.service_exempt_sales += day.service_exempt_sales;
+      totals.service_charge += day.service_charge;
+      totals.surcharges += day.surcharges;
+      totals.tax_collected += day.tax_collected;
+      return day;
+    });
+
+    return c.json({
+      success: true,
+      message: 'Tax report retrieved successfully',
+      data: {
+        from,
+        to,
+        branch_id: scope.branchId,
+        totals: money(Object.fromEntries(Object.entries(totals).map(([k, v]) => [k, round(v)]))),
+        daily: daily.map(money),
+        by_category: categoryRes.rows.map((row: Record<string, unknown>) => money({
+          category_id: row.category_id,
+          category_name: row.category_name,
+          net_sales: round(row.net_sales),
+          tax_exempt_sales: round(row.tax_exempt_sales),
+          service_exempt_sales: round(row.service_exempt_sales),
+          service_charge: round(row.service_charge),
+          tax: round(row.tax),
+        })),
+        by_tax_class: taxClassRes.rows.map((row: Record<string, unknown>) => money({
+          tax_class_id: row.tax_class_id,
+          label: row.label,
+          rate: row.rate === null ? null : Number(row.rate),
+          net_sales: round(row.net_sales),
+          service_charge: round(row.service_charge),
+          tax: round(row.tax),
+        })),
+        exempt_products: exemptRes.rows.map((row: Record<string, unknown>) => money({
+          product_id: row.product_id,
+          name: row.name,
+          tax_exempt: row.tax_exempt,
+          service_exempt: row.service_exempt,
+          quantity: Number(row.quantity),
+          net_sales: round(row.net_sales),
+        })),
+      },
+    });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch tax report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
+
+// ── GetSlaReport ─────────────────────────────────────────────────────────────
+// How quickly customer orders placed over [from, to] (default: the last 7
+// days) were accepted, started, ready and served, against the SLA targets,
+// per daypart.
+
+export async function getSlaReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || addDays(today, -6);
+  const to = c.req.query('to') || today;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  try {
+    const report = await buildSlaReport(pool, from, to, scope.branchId);
+    return c.json({ success: true, message: 'SLA report retrieved successfully', data: report });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch SLA report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
+
+// ── GetHeatmapReport ─────────────────────────────────────────────────────────
+// Completed orders over [from, to] (default: the last four weeks) by
+// weekday and hour they were placed, for staffing: orders, revenue and
+// dine-in covers, in total, per order type and per occurrence of the
+// weekday in the range (the average Monday 19:00). Days run 1 (Monday) to
+// 7 (Sunday); every one of the 168 cells is returned, empty ones as zeros.
+
+const HEATMAP_WEEKDAYS = ['monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday'];
+const HEATMAP_MAX_DAYS = 366;
+const HEATMAP_PEAKS = 5;
+
+export async function getHeatmapReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || addDays(today, -27);
+  const to = c.req.query('to') || today;
+  const orderType = c.req.query('order_type') || null;
+
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(from) || !/^\d{4}-\d{2}-\d{2}$/.test(to) || from > to
+    || addDays(from, HEATMAP_MAX_DAYS - 1) < to) {
+    return errorResponse(c, `from and to must be YYYY-MM-DD dates with from <= to, at most ${HEATMAP_MAX_DAYS} days apart`, 'invalid_date_range', 400);
+  }
+  if (orderType && !ORDER_TYPES.includes(orderType)) {
+    return errorResponse(c, `Order type must be one of: ${ORDER_TYPES.join(', ')}`, 'invalid_order_type', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scopeError(c, scope.failure);
+
+  try {
+    const res = await pool.query(
+      `SELECT EXTRACT(ISODOW FROM o.created_at AT TIME ZONE $3)::int AS day_of_week,
+              EXTRACT(HOUR FROM o.created_at AT TIME ZONE $3)::int AS hour,
+              o.order_type,
+              COUNT(*) AS orders,
+              COALESCE(SUM(o.total_amount), 0) AS revenue,
+              COALESCE(SUM(o.covers) FILTER (WHERE o.order_type = 'dine_in'), 0) AS covers
+       FROM orders o
+       WHERE o.status = 'completed'
+         AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR o.branch_id = $4)
+         AND ($5::text IS NULL OR o.order_type = $5)
+       GROUP BY 1, 2, 3`,
+      [from, to, RESTAURANT_TIMEZONE, scope.branchId, orderType],
+    );
+
+    // How often each weekday occurs in the range, to average a cell per day
+    const occurrences = new Array(7).fill(0);
+    for (let date = from; date <= to; date = addDays(date, 1)) {
+      occurrences[(new Date(`${date}T00:00:00Z`).getUTCDay() + 6) % 7]++;
+    }
+
+    const types = orderType ? [orderType] : ORDER_TYPES;
+    const emptyTypes = () => Object.fromEntries(types.map((t) => [t, { orders: 0, revenue: 0, covers: 0 }]));
+    const cells = Array.from({ length: 7 * 24 }, (_, i) => ({
+      day_of_week: Math.floor(i / 24) + 1,
+      hour: i % 24,
+      orders: 0,
+      revenue: 0,
+      covers: 0,
+      by_order_type: emptyTypes() as Record<string, { orders: number; revenue: number; covers: number }>,
+    }));
+    for (const row of res.rows) {
+      const cell = cells[(row.day_of_week - 1) * 24 + row.hour];
+      const orders = Number(row.orders);
+      const revenue = Number(row.revenue);
+      const covers = Number(row.covers);
+      cell.orders += orders;
+      cell.revenue += revenue;
+      cell.covers += covers;
+      const byType = cell.by_order_type[row.order_type] ?? (cell.by_order_type[row.order_type] = { orders: 0, revenue: 0, covers: 0 });
+      byType.orders += orders;
+      byType.revenue += revenue;
+      byType.covers += covers;
+    }
+
+    const fmt = await loadFormatter(pool);
+    const perDay = (value: number, days: number) => (days > 0 ? Math.round((value / days) * 100) / 100 : 0);
+    const heatmap = cells.map((cell) => {
+      const days = occurrences[cell.day_of_week - 1];
+      return withFormatted({
+        ...cell,
+        average_orders: perDay(cell.orders, days),
+        average_revenue: Math.round(perDay(cell.revenue, days)),
+        average_covers: perDay(cell.covers, days),
+      }, ['revenue', 'average_revenue'], fmt.money);
+    });
+
+    const sum = (list: typeof cells) => ({
+      orders: list.reduce((t, cell) => t + cell.orders, 0),
+      revenue: list.reduce((t, cell) => t + cell.revenue, 0),
+      covers: list.reduce((t, cell) => t + cell.covers, 0),
+    });
+    const byDay = HEATMAP_WEEKDAYS.map((name, i) => {
+      const total = sum(cells.slice(i * 24, i * 24 + 24));
+      return withFormatted({
+        day_of_week: i + 1,
+        name,
+        days_in_range: occurrences[i],
+        ...total,
+        average_orders: perDay(total.orders, occurrences[i]),
+        average_revenue: Math.round(perDay(total.revenue, occurrences[i])),
+      }, ['revenue', 'average_revenue'], fmt.money);
+    });
+    const byHour = Array.from({ length: 24 }, (_, hour) => withFormatted({
+      hour,
+      ...sum(cells.filter((cell) => cell.hour === hour)),
+    }, ['revenue'], fmt.money));
+
+    // The busiest cells by average orders per day, for the staffing summary
+    const peaks = heatmap
+      .filter((cell) => cell.orders > 0)
+      .sort((a, b) => b.average_orders - a.average_orders || b.revenue - a.revenue)
+      .slice(0, HEATMAP_PEAKS)
+      .map(({ day_of_week, hour, orders, revenue, covers, average_orders, average_revenue, average_covers }) => withFormatted({
+        day_of_week, day: HEATMAP_WEEKDAYS[day_of_week - 1], hour,
+        orders, revenue, covers, average_orders, average_revenue, average_covers,
+      }, ['revenue', 'average_revenue'], fmt.money));
+
+    return c.json({
+      success: true,
+      message: 'Heatmap report retrieved successfully',
+      data: {
+        from,
+        to,
+        branch_id: scope.branchId,
+        order_type: orderType,
+        timezone: RESTAURANT_TIMEZONE,
+        cells: heatmap,
+        by_day: byDay,
+        by_hour: byHour,
+        peaks,
+        totals: withFormatted(sum(cells), ['revenue'], fmt.money),
+      },
+    });
+  } catch (err) {
+    return c.json({
+      success: false,
+      message: 'Failed to fetch heatmap report',
+      error: (err as Error).message,
+    }, 500);
+  }
+}
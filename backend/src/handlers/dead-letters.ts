@@ -0,0 +1,284 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { isUUID } from '../services/branches.js';
+import {
+  DEAD_LETTER_SELECT,
+  DEAD_LETTER_STATUSES,
+  MAX_BULK_REPLAY,
+  isReplayable,
+  loadDeadLetterSubject,
+  requeueDeadLetter,
+} from '../services/dead-letters.js';
+
+const MAX_NOTE_LENGTH = 500;
+
+function readNote(body: { note?: unknown } | null): { ok: true; note: string | null } | { ok: false } {
+  if (body?.note === undefined || body.note === null) return { ok: true, note: null };
+  if (typeof body.note !== 'string' || body.note.length > MAX_NOTE_LENGTH) return { ok: false };
+  return { ok: true, note: body.note.trim() || null };
+}
+
+function withReplayable<T extends { job_type: string }>(row: T) {
+  return { ...row, replayable: isReplayable(row.job_type) };
+}
+
+// ── GetDeadLetters ──────────────────────────────────────────────────────────
+// Newest failure first; ?status=open is what still needs a decision. Payloads
+// are included (they are small: the ID of the record the job is for), the
+// attempt history is left for the single dead letter.
+
+export async function getDeadLetters(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status');
+  const jobType = c.req.query('job_type');
+
+  if (status && !DEAD_LETTER_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${DEAD_LETTER_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (status) {
+    conditions.push(`dl.status = $${paramIdx++}`);
+    params.push(status);
+  }
+  if (jobType) {
+    conditions.push(`dl.job_type = $${paramIdx++}`);
+    params.push(jobType);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM dead_letters dl ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${DEAD_LETTER_SELECT} ${where}
+           ORDER BY dl.last_failed_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows.map(withReplayable);
+      },
+    );
+    return paginatedResponse(c, 'Dead letters retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'last_failed_at:desc',
+      filters: { status, job_type: jobType },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch dead letters', (err as Error).message);
+  }
+}
+
+// ── GetDeadLetterStats ──────────────────────────────────────────────────────
+// Open dead letters per job type, with the most recent failure reason.
+
+export async function getDeadLetterStats(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT job_type, COUNT(*)::int AS open, MIN(first_failed_at) AS oldest_failed_at,
+              MAX(last_failed_at) AS last_failed_at,
+              (ARRAY_AGG(last_error ORDER BY last_failed_at DESC))[1] AS last_error
+       FROM dead_letters
+       WHERE status = 'open'
+       GROUP BY job_type
+       ORDER BY job_type ASC`,
+    );
+    return successResponse(c, 'Dead letter stats retrieved successfully', res.rows.map(withReplayable));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch dead letter stats', (err as Error).message);
+  }
+}
+
+// ── GetDeadLetter ───────────────────────────────────────────────────────────
+// With every attempt's error and the record the job was for as it is now
+// (the email, the webhook delivery with its payload and last response, ...).
+
+export async function getDeadLetter(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Dead letter not found', 'dead_letter_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT dl.*, j.status AS job_status
+       FROM dead_letters dl
+       LEFT JOIN jobs j ON j.id = dl.job_id
+       WHERE dl.id = $1`,
+      [id],
+    );
+    const letter = res.rows[0];
+    if (!letter) {
+      return errorResponse(c, 'Dead letter not found', 'dead_letter_not_found', 404);
+    }
+    const subject = await loadDeadLetterSubject(pool, letter.job_type, letter.payload);
+    return successResponse(c, 'Dead letter retrieved successfully', { ...withReplayable(letter), subject });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch dead letter', (err as Error).message);
+  }
+}
+
+// ── ReplayDeadLetter ────────────────────────────────────────────────────────
+// Queues the job again with a fresh set of attempts; an optional note says
+// what was fixed.
+
+export async function replayDeadLetter(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Dead letter not found', 'dead_letter_not_found', 404);
+  }
+
+  // The body is optional
+  let body: { note?: unknown } = {};
+  try {
+    const text = await c.req.text();
+    if (text.trim()) body = JSON.parse(text);
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const note = readNote(body);
+  if (!note.ok) {
+    return errorResponse(c, `note must be text of at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  try {
+    const result = await withTransaction((client) => requeueDeadLetter(client, id, { userId: userId ?? null, note: note.note }));
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${DEAD_LETTER_SELECT} WHERE dl.id = $1`, [id]);
+    return successResponse(c, 'Dead letter queued for replay', withReplayable(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to replay dead letter', (err as Error).message);
+  }
+}
+
+// ── ReplayDeadLetters ───────────────────────────────────────────────────────
+// Bulk replay of the listed open dead letters, or of every open one of a job
+// type, up to MAX_BULK_REPLAY at a time. Each is replayed on its own, so one
+// that can't be (already replayed, not replayable) is reported in `skipped`
+// without holding up the rest.
+
+export async function replayDeadLetters(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { ids?: unknown; job_type?: unknown; note?: unknown };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const hasIds = body.ids !== undefined;
+  const hasType = body.job_type !== undefined;
+  if (hasIds === hasType) {
+    return errorResponse(c, 'Send either ids or job_type', 'invalid_replay_selection', 400);
+  }
+  if (hasIds && (!Array.isArray(body.ids) || body.ids.length === 0 || body.ids.length > MAX_BULK_REPLAY
+      || !body.ids.every((id) => typeof id === 'string' && isUUID(id)))) {
+    return errorResponse(c, `ids must be a list of 1 to ${MAX_BULK_REPLAY} dead letter IDs`, 'invalid_ids', 400);
+  }
+  if (hasType && (typeof body.job_type !== 'string' || !body.job_type)) {
+    return errorResponse(c, 'job_type must be a job type', 'invalid_job_type', 400);
+  }
+  const note = readNote(body);
+  if (!note.ok) {
+    return errorResponse(c, `note must be text of at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  try {
+    let ids: string[];
+    let remaining = 0;
+    if (hasIds) {
+      ids = [...new Set(body.ids as string[])];
+    } else {
+      const res = await pool.query(
+        `SELECT id, COUNT(*) OVER () AS total FROM dead_letters
+         WHERE status = 'open' AND job_type = $1
+         ORDER BY first_failed_at ASC
+         LIMIT $2`,
+        [body.job_type, MAX_BULK_REPLAY],
+      );
+      ids = res.rows.map((row) => row.id);
+      remaining = res.rows.length > 0 ? Number(res.rows[0].total) - ids.length : 0;
+    }
+
+    const replayed: { id: string; job_id: string }[] = [];
+    const skipped: { id: string; code: string; message: string }[] = [];
+    for (const id of ids) {
+      const result = await withTransaction((client) => requeueDeadLetter(client, id, { userId: userId ?? null, note: note.note }));
+      if (result.ok) {
+        replayed.push({ id, job_id: result.jobId });
+      } else {
+        skipped.push({ id, code: result.failure.code, message: result.failure.message });
+      }
+    }
+
+    return successResponse(c, `${replayed.length} dead letter(s) queued for replay`, {
+      replayed,
+      skipped,
+      // Open dead letters of the job type left for another call
+      remaining,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to replay dead letters', (err as Error).message);
+  }
+}
+
+// ── DiscardDeadLetter ───────────────────────────────────────────────────────
+// For failures that don't need replaying (the email went out another way,
+// the endpoint is gone for good). The note is kept as the reason.
+
+export async function discardDeadLetter(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Dead letter not found', 'dead_letter_not_found', 404);
+  }
+
+  // The body is optional
+  let body: { note?: unknown } = {};
+  try {
+    const text = await c.req.text();
+    if (text.trim()) body = JSON.parse(text);
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const note = readNote(body);
+  if (!note.ok) {
+    return errorResponse(c, `note must be text of at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `UPDATE dead_letters
+       SET status = 'discarded', discarded_at = NOW(), discarded_by = $2, note = COALESCE($3, note), updated_at = NOW()
+       WHERE id = $1 AND status = 'open'
+       RETURNING id`,
+      [id, userId ?? null, note.note],
+    );
+    if (res.rows.length === 0) {
+      const exists = await pool.query('SELECT status FROM dead_letters WHERE id = $1', [id]);
+      if (exists.rows.length === 0) {
+        return errorResponse(c, 'Dead letter not found', 'dead_letter_not_found', 404);
+      }
+      return errorResponse(c, `Only open dead letters can be discarded; this one is ${exists.rows[0].status}`, 'invalid_dead_letter_status', 409);
+    }
+
+    const updated = await pool.query(`${DEAD_LETTER_SELECT} WHERE dl.id = $1`, [id]);
+    return successResponse(c, 'Dead letter discarded', withReplayable(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to discard dead letter', (err as Error).message);
+  }
+}
@@ -0,0 +1,338 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { createNotification } from '../services/notification.js';
+import { normalizePhone, type DeliveryStatus } from '../services/delivery.js';
+import { flaggedPhoneSql } from '../services/customer-flags.js';
+import { can } from '../middleware/roles.js';
+
+const ACTIVE_DELIVERY_STATUSES: DeliveryStatus[] = ['unassigned', 'assigned', 'picked_up'];
+
+function formatDeliveryOrder(row: Record<string, unknown>) {
+  return {
+    order_id: row.id,
+    order_number: row.order_number,
+    order_status: row.status,
+    customer_name: row.customer_name,
+    total_amount: Number(row.total_amount),
+    delivery_fee: Number(row.delivery_fee),
+    address: row.delivery_address,
+    phone: row.delivery_phone,
+    notes: row.delivery_notes,
+    delivery_status: row.delivery_status,
+    customer_flagged: row.customer_flagged,
+    courier: row.courier_id
+      ? { id: row.courier_id, first_name: row.courier_first_name, last_name: row.courier_last_name }
+      : null,
+    scheduled_at: row.scheduled_at,
+    created_at: row.created_at,
+    courier_assigned_at: row.courier_assigned_at,
+    picked_up_at: row.picked_up_at,
+    delivered_at: row.delivered_at,
+  };
+}
+
+const DELIVERY_SELECT = `
+  SELECT o.id, o.order_number, o.status, o.customer_name, o.total_amount, o.delivery_fee,
+         o.delivery_address, o.delivery_phone, o.delivery_notes, o.delivery_status, o.courier_id,
+         o.scheduled_at, o.created_at, o.courier_assigned_at, o.picked_up_at, o.delivered_at,
+         cu.first_name AS courier_first_name, cu.last_name AS courier_last_name,
+         ${flaggedPhoneSql('o.delivery_phone')} AS customer_flagged
+  FROM orders o
+  LEFT JOIN users cu ON cu.id = o.courier_id`;
+
+// ── GetDeliveries ───────────────────────────────────────────────────────────
+// Dispatch board: open delivery orders, oldest due first.
+
+export async function getDeliveries(c: Context) {
+  const status = c.req.query('delivery_status');
+
+  if (status && ![...ACTIVE_DELIVERY_STATUSES, 'delivered'].includes(status as DeliveryStatus)) {
+    return errorResponse(c, 'Invalid delivery status', 'invalid_delivery_status', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `${DELIVERY_SELECT}
+       WHERE o.order_type = 'delivery' AND o.status <> 'cancelled'
+         AND ${status ? 'o.delivery_status = $1' : 'o.delivery_status = ANY($1::text[])'}
+       ORDER BY COALESCE(o.scheduled_at, o.created_at) ASC
+       LIMIT 200`,
+      [status || ACTIVE_DELIVERY_STATUSES],
+    );
+    return successResponse(c, 'Deliveries retrieved successfully', res.rows.map(formatDeliveryOrder));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch deliveries', (err as Error).message);
+  }
+}
+
+// ── GetCouriers ─────────────────────────────────────────────────────────────
+
+export async function getCouriers(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT u.id, u.username, u.first_name, u.last_name,
+              COUNT(o.id) AS active_deliveries
+       FROM users u
+       LEFT JOIN orders o ON o.courier_id = u.id AND o.delivery_status IN ('assigned', 'picked_up')
+       WHERE u.role = 'courier' AND u.is_active = true AND u.deleted_at IS NULL
+       GROUP BY u.id
+       ORDER BY active_deliveries ASC, u.first_name ASC`,
+    );
+    return successResponse(c, 'Couriers retrieved successfully', res.rows.map((r) => ({
+      id: r.id,
+      username: r.username,
+      first_name: r.first_name,
+      last_name: r.last_name,
+      active_deliveries: Number(r.active_deliveries),
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch couriers', (err as Error).message);
+  }
+}
+
+// ── AssignCourier ───────────────────────────────────────────────────────────
+// Assigns or reassigns a courier until the order has been picked up.
+// courier_id null unassigns.
+
+export async function assignCourier(c: Context) {
+  const orderId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { courier_id?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.courier_id === undefined) {
+    return errorResponse(c, 'Courier ID is required', 'missing_courier_id', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        `SELECT order_number, order_type, status, delivery_status, delivery_address, branch_id
+         FROM orders WHERE id = $1 FOR UPDATE`,
+        [orderId],
+      );
+      const branchId = c.get('branch_id');
+      if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
+
+      const order = orderRes.rows[0];
+      if (order.order_type !== 'delivery') {
+        return txFailure('Only delivery orders can have a courier', 'not_delivery_order', 400);
+      }
+      if (order.status === 'cancelled') {
+        return txFailure('Order is cancelled', 'invalid_order_status', 409);
+      }
+      if (order.delivery_status === 'picked_up' || order.delivery_status === 'delivered') {
+        return txFailure('Courier cannot be changed after pickup', 'already_picked_up', 409);
+      }
+
+      if (body.courier_id) {
+        const courierRes = await client.query(
+          `SELECT id FROM users WHERE id = $1 AND role = 'courier' AND is_active = true AND deleted_at IS NULL`,
+          [body.courier_id],
+        );
+        if (courierRes.rows.length === 0) {
+          return txFailure('Courier not found', 'courier_not_found', 404);
+        }
+      }
+
+      await client.query(
+        `UPDATE orders
+         SET courier_id = $1, delivery_status = $2, courier_assigned_at = $3, updated_at = CURRENT_TIMESTAMP
+         WHERE id = $4`,
+        [body.courier_id || null, body.courier_id ? 'assigned' : 'unassigned', body.courier_id ? new Date() : null, orderId],
+      );
+
+      await client.query(
+        `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+         VALUES ($1, $2, $2, $3, $4)`,
+        [orderId, order.status, userId, body.courier_id ? 'Courier assigned' : 'Courier unassigned'],
+      );
+
+      return { ok: true as const, order };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { order } = result;
+
+    if (body.courier_id) {
+      createNotification(
+        body.courier_id,
+        'order_update',
+        'New Delivery',
+        `Delivery ${order.order_number}: ${order.delivery_address}`,
+        'delivery_assigned',
+      );
+    }
+
+    const updated = await pool.query(`${DELIVERY_SELECT} WHERE o.id = $1`, [orderId]);
+    return successResponse(c, 'Courier assignment updated successfully', formatDeliveryOrder(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to assign courier', (err as Error).message);
+  }
+}
+
+// ── GetMyDeliveries ─────────────────────────────────────────────────────────
+
+export async function getMyDeliveries(c: Context) {
+  const userId = c.get('user_id');
+  const includeDelivered = c.req.query('include_delivered') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${DELIVERY_SELECT}
+       WHERE o.courier_id = $1
+         AND (o.delivery_status IN ('assigned', 'picked_up')
+              ${includeDelivered ? "OR (o.delivery_status = 'delivered' AND o.delivered_at >= NOW() - INTERVAL '24 hours')" : ''})
+       ORDER BY (o.delivery_status = 'delivered') ASC, COALESCE(o.scheduled_at, o.created_at) ASC`,
+      [userId],
+    );
+    return successResponse(c, 'Deliveries retrieved successfully', res.rows.map(formatDeliveryOrder));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch deliveries', (err as Error).message);
+  }
+}
+
+// ── UpdateDeliveryStatus ────────────────────────────────────────────────────
+// Courier hand-off: assigned → picked_up once the kitchen has the order
+// ready, then picked_up → delivered. Delivery marks the order served.
+
+const DELIVERY_TRANSITIONS: Record<string, DeliveryStatus> = {
+  picked_up: 'assigned',
+  delivered: 'picked_up',
+};
+
+export async function updateDeliveryStatus(c: Context) {
+  const orderId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: { status?: string; notes?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const status = body.status as DeliveryStatus;
+  if (!DELIVERY_TRANSITIONS[status]) {
+    return errorResponse(c, 'Status must be picked_up or delivered', 'invalid_delivery_status', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        'SELECT status, order_type, delivery_status, courier_id FROM orders WHERE id = $1 FOR UPDATE',
+        [orderId],
+      );
+      const order = orderRes.rows[0];
+      if (!order || order.order_type !== 'delivery') {
+        return txFailure('Delivery not found', 'not_found', 404);
+      }
+      if (order.courier_id !== userId && !can(c, 'deliveries.manage')) {
+        return txFailure('This delivery is assigned to another courier', 'not_assigned_courier', 403);
+      }
+      if (order.delivery_status !== DELIVERY_TRANSITIONS[status]) {
+        return txFailure(`Delivery is ${order.delivery_status}, cannot mark ${status}`, 'invalid_transition', 409);
+      }
+      if (status === 'picked_up' && !['ready', 'served', 'completed'].includes(order.status)) {
+        return txFailure('Order is not ready for pickup yet', 'order_not_ready', 409);
+      }
+
+      if (status === 'picked_up') {
+        await client.query(
+          `UPDATE orders SET delivery_status = 'picked_up', picked_up_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+           WHERE id = $1`,
+          [orderId],
+        );
+      } else {
+        await client.query(
+          `UPDATE orders SET delivery_status = 'delivered', delivered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+           WHERE id = $1`,
+          [orderId],
+        );
+      }
+
+      // Handing the order to the customer counts as serving it
+      const newOrderStatus = status === 'delivered' && order.status === 'ready' ? 'served' : order.status;
+      if (newOrderStatus !== order.status) {
+        await client.query(
+          "UPDATE orders SET status = 'served', served_at = CURRENT_TIMESTAMP WHERE id = $1",
+          [orderId],
+        );
+      }
+
+      await client.query(
+        `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+         VALUES ($1, $2, $3, $4, $5)`,
+        [orderId, order.status, newOrderStatus, userId, body.notes || (status === 'picked_up' ? 'Picked up by courier' : 'Delivered to customer')],
+      );
+
+      await client.query(
+        'INSERT INTO order_notifications (order_id, status, message, is_read) VALUES ($1, $2, $3, false)',
+        [
+          orderId,
+          newOrderStatus,
+          status === 'picked_up' ? 'Your order is on its way!' : 'Your order has been delivered. Enjoy your meal!',
+        ],
+      );
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${DELIVERY_SELECT} WHERE o.id = $1`, [orderId]);
+    return successResponse(c, 'Delivery status updated successfully', formatDeliveryOrder(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update delivery status', (err as Error).message);
+  }
+}
+
+// ── TrackDeliveryOrder ──────────────────────────────────────────────────────
+// Public tracking by order number. The contact phone given at checkout must
+// match, so order numbers alone don't expose anything.
+
+export async function trackDeliveryOrder(c: Context) {
+  const orderNumber = c.req.param('order_number');
+  const phone = normalizePhone(c.req.query('phone') || '');
+
+  if (phone.length < 8) {
+    return errorResponse(c, 'Phone number is required', 'phone_required', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT o.order_number, o.status, o.delivery_phone, o.delivery_status, o.scheduled_at,
+              o.created_at, o.picked_up_at, o.delivered_at, o.total_amount, cu.first_name AS courier_first_name
+       FROM orders o
+       LEFT JOIN users cu ON cu.id = o.courier_id
+       WHERE o.order_number = $1 AND o.order_type = 'delivery'`,
+      [orderNumber],
+    );
+
+    const row = res.rows[0];
+    if (!row || normalizePhone(row.delivery_phone || '') !== phone) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+
+    return successResponse(c, 'Order status retrieved successfully', {
+      order_number: row.order_number,
+      status: row.status,
+      delivery_status: row.delivery_status,
+      courier_name: row.courier_first_name ?? null,
+      total_amount: Number(row.total_amount),
+      scheduled_at: row.scheduled_at,
+      created_at: row.created_at,
+      picked_up_at: row.picked_up_at,
+      delivered_at: row.delivered_at,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order status', (err as Error).message);
+  }
+}
@@ -0,0 +1,177 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { resolveBranchScope, branchCondition } from '../services/branches.js';
+import { getPrintPreferences, isDeviceId, PRINT_PREFERENCE_COLUMNS } from '../services/devices.js';
+
+type PreferencesBody = {
+  name?: string | null;
+  receipt_printer?: string | null;
+  auto_print_receipt?: boolean | null;
+  receipt_copies?: number | null;
+  kitchen_printer?: string | null;
+  auto_print_kitchen?: boolean | null;
+  kitchen_copies?: number | null;
+};
+
+const DEVICE_SELECT = `
+  SELECT d.device_id, d.name, d.branch_id, b.name AS branch_name,
+         d.receipt_printer, d.auto_print_receipt, d.receipt_copies,
+         d.kitchen_printer, d.auto_print_kitchen, d.kitchen_copies, d.attendance_clock,
+         d.last_seen_at, d.last_user_id, u.username AS last_username,
+         d.updated_by, d.created_at, d.updated_at
+  FROM device_print_preferences d
+  LEFT JOIN branches b ON b.id = d.branch_id
+  LEFT JOIN users u ON u.id = d.last_user_id`;
+
+// null clears a preference back to the system setting
+function validatePreferencesBody(body: PreferencesBody): { message: string; code: string } | null {
+  for (const key of ['name', 'receipt_printer', 'kitchen_printer'] as const) {
+    const value = body[key];
+    if (value != null && (typeof value !== 'string' || value.length > 100)) {
+      return { message: `${key} must be a string of at most 100 characters`, code: `invalid_${key}` };
+    }
+  }
+  for (const key of ['auto_print_receipt', 'auto_print_kitchen'] as const) {
+    if (body[key] != null && typeof body[key] !== 'boolean') {
+      return { message: `${key} must be true, false or null`, code: `invalid_${key}` };
+    }
+  }
+  for (const key of ['receipt_copies', 'kitchen_copies'] as const) {
+    const value = body[key];
+    if (value != null && (!Number.isInteger(value) || value < 1 || value > 5)) {
+      return { message: `${key} must be between 1 and 5`, code: `invalid_${key}` };
+    }
+  }
+  return null;
+}
+
+// Branch staff manage only the devices of their own branch
+async function findScopedDevice(c: Context, deviceId: string): Promise<boolean> {
+  const own = c.get('branch_id') ?? null;
+  const res = await pool.query(
+    'SELECT 1 FROM device_print_preferences WHERE device_id = $1 AND ($2::uuid IS NULL OR branch_id = $2)',
+    [deviceId, own],
+  );
+  return res.rows.length > 0;
+}
+
+// ── GetDevices ──────────────────────────────────────────────────────────────
+
+export async function getDevices(c: Context) {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const params: unknown[] = [];
+    const res = await pool.query(
+      `${DEVICE_SELECT}
+       WHERE true${branchCondition('d.branch_id', scope.branchId, params)}
+       ORDER BY d.name ASC NULLS LAST, d.last_seen_at DESC`,
+      params,
+    );
+    return successResponse(c, 'Devices retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch devices', (err as Error).message);
+  }
+}
+
+// ── GetDevicePrintPreferences ───────────────────────────────────────────────
+// For the terminal itself, to refresh its preferences without logging in
+// again.
+
+export async function getDevicePrintPreferences(c: Context) {
+  const deviceId = c.req.param('device_id');
+  if (!isDeviceId(deviceId)) {
+    return errorResponse(c, 'Device not found', 'not_found', 404);
+  }
+
+  try {
+    const prefs = await getPrintPreferences(pool, deviceId);
+    if (!prefs) {
+      return errorResponse(c, 'Device not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Print preferences retrieved successfully', prefs);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch print preferences', (err as Error).message);
+  }
+}
+
+// ── UpdateDevicePrintPreferences ────────────────────────────────────────────
+// The device picks the change up on its next login.
+
+export async function updateDevicePrintPreferences(c: Context) {
+  const userId = c.get('user_id');
+  const deviceId = c.req.param('device_id');
+  if (!isDeviceId(deviceId)) {
+    return errorResponse(c, 'Device not found', 'not_found', 404);
+  }
+
+  let body: PreferencesBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validatePreferencesBody(body);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    if (!(await findScopedDevice(c, deviceId))) {
+      return errorResponse(c, 'Device not found', 'not_found', 404);
+    }
+
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    for (const col of PRINT_PREFERENCE_COLUMNS) {
+      if (body[col] !== undefined) {
+        setClauses.push(`${col} = $${paramIdx}`);
+        params.push(typeof body[col] === 'string' ? (body[col] as string).trim() || null : body[col]);
+        paramIdx++;
+      }
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    setClauses.push(`updated_by = $${paramIdx++}`, 'updated_at = NOW()');
+    params.push(userId, deviceId);
+    await pool.query(
+      `UPDATE device_print_preferences SET ${setClauses.join(', ')} WHERE device_id = $${paramIdx}`,
+      params,
+    );
+
+    const updated = await pool.query(`${DEVICE_SELECT} WHERE d.device_id = $1`, [deviceId]);
+    return successResponse(c, 'Print preferences updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update print preferences', (err as Error).message);
+  }
+}
+
+// ── DeleteDevice ────────────────────────────────────────────────────────────
+// For retired terminals; a device that logs in again is registered afresh.
+
+export async function deleteDevice(c: Context) {
+  const deviceId = c.req.param('device_id');
+  if (!isDeviceId(deviceId)) {
+    return errorResponse(c, 'Device not found', 'not_found', 404);
+  }
+
+  try {
+    if (!(await findScopedDevice(c, deviceId))) {
+      return errorResponse(c, 'Device not found', 'not_found', 404);
+    }
+    await pool.query('DELETE FROM device_print_preferences WHERE device_id = $1', [deviceId]);
+    return successResponse(c, 'Device deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete device', (err as Error).message);
+  }
+}
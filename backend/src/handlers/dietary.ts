@@ -0,0 +1,108 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { invalidateCache } from '../lib/cache.js';
+import { isUUID } from '../services/branches.js';
+import {
+  ALLERGENS,
+  DIETARY_TAGS,
+  MAX_SPICY_LEVEL,
+  formatDietaryInfo,
+  isAllergen,
+  isDietaryTag,
+  isSpicyLevel,
+} from '../services/dietary.js';
+
+// ── GetProductDietary ───────────────────────────────────────────────────────
+
+export async function getProductDietary(c: Context) {
+  const productId = c.req.param('id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      'SELECT id, allergens, dietary_tags, spicy_level FROM products WHERE id = $1 AND deleted_at IS NULL',
+      [productId],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+    return successResponse(c, 'Product dietary info retrieved successfully', {
+      product_id: productId,
+      ...formatDietaryInfo(res.rows[0]),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch product dietary info', (err as Error).message);
+  }
+}
+
+// ── SetProductDietary ───────────────────────────────────────────────────────
+// Replaces the fields given; spicy_level null clears it.
+
+export async function setProductDietary(c: Context) {
+  const productId = c.req.param('id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  let body: { allergens?: string[]; dietary_tags?: string[]; spicy_level?: number | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.allergens !== undefined && (!Array.isArray(body.allergens) || !body.allergens.every(isAllergen))) {
+    return errorResponse(c, `allergens must be a list of: ${ALLERGENS.join(', ')}`, 'invalid_allergen', 400);
+  }
+  if (body.dietary_tags !== undefined && (!Array.isArray(body.dietary_tags) || !body.dietary_tags.every(isDietaryTag))) {
+    return errorResponse(c, `dietary_tags must be a list of: ${DIETARY_TAGS.join(', ')}`, 'invalid_tag', 400);
+  }
+  if (body.spicy_level !== undefined && body.spicy_level !== null && !isSpicyLevel(body.spicy_level)) {
+    return errorResponse(c, `spicy_level must be a whole number from 0 to ${MAX_SPICY_LEVEL}`, 'invalid_spicy_level', 400);
+  }
+  if (body.dietary_tags?.includes('vegan') && body.allergens?.some((a) => a === 'milk' || a === 'egg' || a === 'fish' || a === 'shellfish')) {
+    return errorResponse(c, 'A vegan product cannot contain milk, egg, fish or shellfish', 'conflicting_dietary_info', 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  if (body.allergens !== undefined) {
+    params.push([...new Set(body.allergens)]);
+    setClauses.push(`allergens = $${params.length}`);
+  }
+  if (body.dietary_tags !== undefined) {
+    params.push([...new Set(body.dietary_tags)]);
+    setClauses.push(`dietary_tags = $${params.length}`);
+  }
+  if (body.spicy_level !== undefined) {
+    params.push(body.spicy_level);
+    setClauses.push(`spicy_level = $${params.length}`);
+  }
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    params.push(productId);
+    const res = await pool.query(
+      `UPDATE products SET ${setClauses.join(', ')}, updated_at = CURRENT_TIMESTAMP
+       WHERE id = $${params.length} AND deleted_at IS NULL
+       RETURNING id, allergens, dietary_tags, spicy_level`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+
+    invalidateCache('menu');
+    return successResponse(c, 'Product dietary info updated successfully', {
+      product_id: productId,
+      ...formatDietaryInfo(res.rows[0]),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to update product dietary info', (err as Error).message);
+  }
+}
@@ -0,0 +1,422 @@
+import type { Context, Hono } from 'hono';
+import { authMiddleware } from '../middleware/auth.js';
+import { buildOpenApiDocument, documentRoute } from '../lib/openapi.js';
+
+const PAGE_QUERY = {
+  page: 'Page number, starting at 1',
+  per_page: 'Items per page',
+};
+
+documentRoute('POST', '/api/v1/auth/login', {
+  summary: 'Log in',
+  description: 'Returns a JWT to send as `Authorization: Bearer <token>` on the protected routes.',
+  body: {
+    type: 'object',
+    required: ['username', 'password'],
+    properties: {
+      username: { type: 'string' },
+      password: { type: 'string', format: 'password' },
+      device_id: { type: 'string', description: "The terminal's ID; its print preferences are returned as print_preferences" },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/auth/password-reset/request', {
+  summary: 'Request a password reset',
+  description: 'Emails a single-use reset link to the account with this address. Answers the same whether or not the address has an account.',
+  body: { type: 'object', required: ['email'], properties: { email: { type: 'string', format: 'email' } } },
+});
+documentRoute('POST', '/api/v1/auth/password-reset/confirm', {
+  summary: 'Set a new password with a reset token',
+  description: 'Uses up the token from the reset link and unlocks the account if too many failed logins had locked it.',
+  body: {
+    type: 'object',
+    required: ['token', 'new_password'],
+    properties: {
+      token: { type: 'string' },
+      new_password: { type: 'string', format: 'password' },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/orders', {
+  paginated: true,
+  query: {
+    ...PAGE_QUERY,
+    status: 'Filter by order status',
+    order_type: 'dine_in, takeaway or delivery',
+    cursor: 'meta.next_cursor of the previous page; replaces page',
+  },
+});
+documentRoute('GET', '/api/v1/products', {
+  paginated: true,
+  query: { ...PAGE_QUERY, category_id: 'Filter by category', available: 'true or false', search: 'Search term' },
+});
+documentRoute('GET', '/api/v1/products/search', {
+  description: 'Ranked full-text and fuzzy search. Returns `{ products, facets, meta }`.',
+  query: { ...PAGE_QUERY, q: 'Search term', category_id: 'Filter by category', available: 'true or false' },
+});
+documentRoute('GET', '/api/v1/public/menu', {
+  query: { category_id: 'Filter by category', search: 'Search term', branch_id: 'Branch (defaults to the main branch)' },
+});
+documentRoute('GET', '/api/v1/public/menu/search', {
+  description: 'Returns `{ products, facets }`, best match first.',
+  query: { q: 'Search term', category_id: 'Filter by category', branch_id: 'Branch (defaults to the main branch)' },
+});
+documentRoute('GET', '/api/v1/public/status', {
+  summary: 'Service status',
+  description: 'Overall status (operational, degraded, outage or maintenance), uptime, 30-day availability, a status per component and open or recently resolved incidents. Served in maintenance mode too.',
+});
+documentRoute('GET', '/api/v1/admin/status/incidents', {
+  query: { include_resolved: 'true to include resolved incidents' },
+});
+documentRoute('POST', '/api/v1/admin/notification-defaults/:role/apply', {
+  summary: "Apply a role's notification defaults to its users",
+  description: 'Users without preferences get the defaults; others get the listed fields (all by default) except those they changed themselves, which are returned in kept_overrides. override_personal=true replaces those too and clears the override.',
+  body: {
+    type: 'object',
+    properties: {
+      fields: { type: 'array', items: { type: 'string', enum: ['email_enabled', 'quiet_hours', 'order_update', 'low_stock', 'payment', 'system_alert', 'daily_report'] } },
+      override_personal: { type: 'boolean' },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/admin/tables/:id/qr', {
+  summary: "Generate a table's QR code",
+  description: 'Returns the signed code, the ordering URL it encodes and the image as `svg` and a PNG data URI in `png`. A table with a valid code keeps it unless `rotate` is true.',
+  body: { type: 'object', properties: { rotate: { type: 'boolean' } } },
+});
+documentRoute('GET', '/api/v1/admin/tables/:id/qr', {
+  summary: "A table's QR code image",
+  query: { format: 'svg (default) or png', scale: 'PNG pixels per module, 1 to 40' },
+});
+documentRoute('POST', '/api/v1/admin/tables/qr', {
+  summary: 'Generate QR codes for every table',
+  description: 'Tables without a valid signed code get one; `rotate: true` gives every table a new code and invalidates the old ones.',
+  query: { branch_id: 'Branch (head office only)' },
+  body: { type: 'object', properties: { rotate: { type: 'boolean' } } },
+});
+documentRoute('GET', '/api/v1/customer/orders/:token', {
+  summary: 'Customer order from a link token',
+  description: 'token is the order_token from POST /customer/orders (ORDER_LINK_TTL_HOURS) or the token in a survey invitation (SURVEY_LINK_TTL_DAYS). The payment, survey and notification endpoints under it take the same token; an expired one returns 410 order_link_expired.',
+});
+documentRoute('GET', '/api/v1/admin/meta/schema', {
+  summary: 'Data model',
+  description: 'Entities (tables, columns, keys) from the database schema, enum values such as order statuses, the active payment methods and adjust reasons, and the validation limits requests are checked against. Build forms and filters from this rather than copying the lists.',
+  query: { section: 'entities, enums or validation to return only that part' },
+});
+documentRoute('GET', '/api/v1/payment-methods', {
+  summary: 'Active payment methods',
+  description: 'The methods a payment can be taken with, in display order, with their surcharge_percent. A surcharge is charged on top of the amount applied to the order and recorded as the payment\'s surcharge_amount.',
+});
+documentRoute('GET', '/api/v1/customer/payment-methods', {
+  summary: 'Payment methods offered to guests',
+  description: 'Active methods marked customer_selectable; POST /customer/orders/:token/payment accepts only these.',
+});
+documentRoute('POST', '/api/v1/admin/payment-methods', {
+  summary: 'Add a payment method',
+  description: 'code is 2-20 lowercase letters, digits or underscores and can\'t be changed later. Methods that payments were taken with, and the system methods, can\'t be deleted; set is_active to false instead.',
+  body: {
+    type: 'object',
+    required: ['code', 'display_name'],
+    properties: {
+      code: { type: 'string' },
+      display_name: { type: 'string', maxLength: 50 },
+      surcharge_percent: { type: 'number', minimum: 0, maximum: 100 },
+      is_active: { type: 'boolean' },
+      customer_selectable: { type: 'boolean' },
+      sort_order: { type: 'integer' },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/server/orders/:id/fire', {
+  summary: 'Fire a held course',
+  description: 'Dine-in order items take a course (1-9). Courses after the first, or every course when the order is created with hold: true, stay off the kitchen display until fired. Without ?course the lowest held course is fired.',
+  query: { course: 'Course number to fire' },
+});
+documentRoute('GET', '/api/v1/orders/:id/courses', {
+  summary: "An order's courses with timings",
+  description: 'Per course: held, fired, ready or served, when it was fired, ready and served, and the wait since the previous course was served.',
+});
+documentRoute('GET', '/api/v1/public/note-phrases', {
+  summary: 'Quick phrases for item instructions',
+  description: 'One-tap instructions ("no onion", "extra spicy") the customer app can offer. Send the chosen codes as an item\'s note_phrases when ordering; free-text special_instructions still work alongside them. GET /note-phrases is the staff list, including phrases kept off the customer app.',
+  query: { category_id: 'Only phrases for products of this category (plus those for every category)' },
+});
+documentRoute('POST', '/api/v1/admin/note-phrases', {
+  summary: 'Add a quick phrase',
+  description: 'code is 2-40 lowercase letters, digits or underscores and can\'t be changed later; without category_id the phrase is offered for every product. kitchen_flag allergy shows items given the phrase as allergy alerts on the kitchen display and marks their ticket (has_allergy_alert); attention highlights them. Phrases order items were given can\'t be deleted; set is_active to false instead.',
+  body: {
+    type: 'object',
+    required: ['code', 'label'],
+    properties: {
+      code: { type: 'string' },
+      label: { type: 'string', maxLength: 60 },
+      category_id: { type: 'string', format: 'uuid', nullable: true },
+      customer_visible: { type: 'boolean' },
+      kitchen_flag: { type: 'string', enum: ['allergy', 'attention'], nullable: true },
+      is_active: { type: 'boolean' },
+      sort_order: { type: 'integer' },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/admin/sections', {
+  summary: 'Add a server section',
+  description: 'A named group of dining tables in one branch; a table is in at most one section, so listing it here moves it out of its old one. Servers are assigned to a section per shift with POST /admin/sections/:id/assignments.',
+  body: {
+    type: 'object',
+    required: ['name'],
+    properties: {
+      name: { type: 'string', maxLength: 50 },
+      branch_id: { type: 'string', format: 'uuid' },
+      table_ids: { type: 'array', items: { type: 'string', format: 'uuid' } },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/admin/sections/:id/assignments', {
+  summary: 'Assign a server to a section for a shift',
+  description: 'A shift is at most 16 hours and can\'t overlap another of the same server in that section. While on shift, staff without orders.view_all_sections see the orders of the section\'s tables, and new orders at those tables are credited to them.',
+  body: {
+    type: 'object',
+    required: ['user_id', 'starts_at', 'ends_at'],
+    properties: {
+      user_id: { type: 'string', format: 'uuid' },
+      starts_at: { type: 'string', format: 'date-time' },
+      ends_at: { type: 'string', format: 'date-time' },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/sections/mine', {
+  summary: "The caller's section shifts",
+  description: 'Current shifts and those starting in the next 24 hours, with the tables of each section.',
+});
+documentRoute('GET', '/api/v1/admin/reports/servers', {
+  summary: 'Sales per server',
+  description: 'Completed orders credited to each server (the server on shift in the table\'s section when the order was placed, otherwise whoever rang it in), with covers, net sales, service charge, average ticket, sales per cover and hours assigned to sections. Defaults to this week so far.',
+  query: { from: 'YYYY-MM-DD', to: 'YYYY-MM-DD', branch_id: 'Branch (head office only)' },
+});
+documentRoute('PUT', '/api/v1/orders/:id/payments/:payment_id/tip', {
+  summary: 'Set the tip on a payment',
+  description: 'For a tip added on the card slip after the charge, or 0 to give one back. Completed payments only, within 24 hours of the payment; not corporate wallet or on-account payments, which bill the company for the tip with the payment. A tip can also be sent as tip_amount when the payment is taken; GET /orders/:id/payment-summary suggests tips on the balance from the tip_suggestions setting.',
+  body: {
+    type: 'object',
+    required: ['tip_amount'],
+    properties: {
+      tip_amount: { type: 'number', minimum: 0 },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/admin/reports/tips', {
+  summary: 'Tips for payout',
+  description: 'Tips per recipient (the order\'s server, or the cashier without one) and per section shift they were taken in, split into cash tips already in the drawer and non-cash tips to pay out. Defaults to today.',
+  query: { from: 'YYYY-MM-DD', to: 'YYYY-MM-DD', branch_id: 'Branch (head office only)' },
+});
+documentRoute('GET', '/api/v1/admin/analytics-export', {
+  summary: 'Analytics export status',
+  description: 'Where the incremental export of orders, order_items, payments and inventory_movements goes (ELT_EXPORT_TARGET), and each stream\'s watermark and last run. Files are gzipped CSV under <prefix>/<stream>/dt=YYYY-MM-DD/. GET /admin/analytics-export/runs lists the batches with their files; POST /admin/analytics-export/run exports now (?stream= for one).',
+});
+documentRoute('POST', '/api/v1/admin/analytics-export/streams/:stream/rewind', {
+  summary: 'Re-export a stream',
+  description: 'Moves the stream\'s watermark back so the next runs export again everything changed from since (a business date), or everything when since is null.',
+  body: {
+    type: 'object',
+    required: ['since'],
+    properties: {
+      since: { type: 'string', format: 'date', nullable: true },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/admin/surveys', {
+  paginated: true,
+  summary: 'Satisfaction surveys',
+  description: 'Newest first, with the order, its server and the follow-up status. GET /admin/surveys/:id adds what was ordered and the replies sent.',
+  query: {
+    ...PAGE_QUERY,
+    status: 'new, acknowledged or replied',
+    min_rating: 'Lowest overall rating, 1-5',
+    max_rating: 'Highest overall rating, 1-5',
+    from: 'Submitted on or after this date (YYYY-MM-DD)',
+    to: 'Submitted on or before this date (YYYY-MM-DD)',
+    has_comments: 'true or false',
+    server_id: 'Surveys of this server\'s orders',
+  },
+});
+documentRoute('POST', '/api/v1/admin/surveys/:id/acknowledge', {
+  summary: 'Acknowledge a survey',
+  description: 'Marks a new survey acknowledged. note sets the internal follow-up note (null clears it) and can be changed on a survey in any status.',
+  body: {
+    type: 'object',
+    properties: {
+      note: { type: 'string', maxLength: 500, nullable: true },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/admin/surveys/:id/reply', {
+  summary: 'Reply to a guest\'s survey',
+  description: 'Emails the guest at the address they left, quoting their rating and comments, and marks the survey replied. 400 survey_has_no_email when they left none.',
+  body: {
+    type: 'object',
+    required: ['message'],
+    properties: {
+      message: { type: 'string', maxLength: 5000 },
+      note: { type: 'string', maxLength: 500, nullable: true },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/admin/surveys/trends', {
+  summary: 'Survey ratings per day',
+  description: 'Surveys, average ratings, recommendation rate and low (1-2) ratings for each day from from to to, the last 30 days by default; averages are null on days without surveys.',
+  query: { from: 'YYYY-MM-DD', to: 'YYYY-MM-DD, at most 366 days after from' },
+});
+documentRoute('GET', '/api/v1/admin/surveys/by-server', {
+  summary: 'Survey ratings by server',
+  description: 'Average ratings of each server\'s surveyed orders against the average of all surveys in the range (rating_difference). GET /admin/surveys/by-product does the same per product, for the surveys of orders that included it.',
+  query: {
+    from: 'YYYY-MM-DD',
+    to: 'YYYY-MM-DD',
+    min_surveys: 'Leave out servers or products with fewer surveys (default 3)',
+  },
+});
+documentRoute('GET', '/api/v1/admin/reports/menu-engineering', {
+  summary: 'Menu engineering report',
+  description: 'Per product on the menu: portions sold, net sales, food cost from recipes at current ingredient prices (or the cost override), contribution margin and menu mix. Costed products are classed star, plowhorse, puzzle or dog by popularity (menu mix against 70% of an even share) and contribution margin per portion (against the average); products without a cost are left unclassified.',
+  query: {
+    from: 'YYYY-MM-DD, default the first of this month',
+    to: 'YYYY-MM-DD, default today',
+    category_id: 'Rate only this category\'s products, against each other',
+  },
+});
+documentRoute('GET', '/api/v1/admin/reports/heatmap', {
+  summary: 'Orders by weekday and hour',
+  description: 'Completed orders, revenue and dine-in covers for each of the 168 weekday (1 = Monday) and hour cells, in total, per order type and averaged over the times that weekday occurs in the range; with per-day and per-hour totals and the five busiest cells. Hours are in the restaurant\'s timezone.',
+  query: {
+    from: 'YYYY-MM-DD, default four weeks ago',
+    to: 'YYYY-MM-DD, default today; at most 366 days after from',
+    order_type: 'dine_in, takeout or delivery',
+  },
+});
+documentRoute('GET', '/api/v1/admin/dashboard/stats', {
+  summary: 'Live dashboard figures',
+  description: 'Orders, completed revenue, average ticket and covers today so far, with the same figures for yesterday and the same weekday last week up to the same time of day and the percent change against each (vs_yesterday, vs_last_week; null when there was nothing to compare with). Also active orders, occupied tables, today\'s five best sellers and the kitchen display\'s open tickets. Results are shared for 30 seconds per branch scope.',
+});
+documentRoute('POST', '/api/v1/staff/clock-in', {
+  summary: 'Clock in',
+  description: 'Opens an attendance entry for the caller, matched to their scheduled shift under way or starting within the hour. With attendance_require_pin the PIN a manager set is needed (5 wrong PINs in a row lock it for 15 minutes, or until a manager sets it again), and with attendance_require_terminal the request must come from a terminal enabled for attendance (PUT /admin/devices/:device_id/attendance). POST /staff/clock-out takes the same body and closes the open entry; GET /staff/attendance has the caller\'s open entry, recent entries and upcoming shifts.',
+  body: {
+    type: 'object',
+    properties: {
+      pin: { type: 'string', description: '4 to 8 digits' },
+      device_id: { type: 'string', description: "The terminal's ID, as sent on login" },
+      note: { type: 'string', maxLength: 500 },
+    },
+  },
+});
+documentRoute('POST', '/api/v1/admin/staff-shifts', {
+  summary: 'Schedule a shift',
+  description: 'Overtime is worked time past the shift\'s length less its unpaid break, once it exceeds attendance_overtime_grace_minutes. A staff member\'s shifts can\'t overlap. GET /admin/staff-shifts lists them (?from=&to=&user_id=), DELETE /admin/staff-shifts/:id removes one.',
+  body: {
+    type: 'object',
+    required: ['user_id', 'starts_at', 'ends_at'],
+    properties: {
+      user_id: { type: 'string', format: 'uuid' },
+      branch_id: { type: 'string', format: 'uuid', description: 'Head office only; defaults to the main branch' },
+      starts_at: { type: 'string', format: 'date-time' },
+      ends_at: { type: 'string', format: 'date-time', description: 'At most 16 hours after starts_at' },
+      break_minutes: { type: 'integer', minimum: 0, maximum: 240 },
+      note: { type: 'string', maxLength: 200 },
+    },
+  },
+});
+documentRoute('PUT', '/api/v1/admin/attendance/:id', {
+  summary: 'Correct an attendance entry',
+  description: 'Sets the clock-in or clock-out time (a forgotten clock-out, a wrong time), keeping the reason on the entry. GET /admin/attendance lists entries (?from=&to=&user_id=, ?open=true for staff clocked in now).',
+  body: {
+    type: 'object',
+    required: ['reason'],
+    properties: {
+      clock_in_at: { type: 'string', format: 'date-time' },
+      clock_out_at: { type: 'string', format: 'date-time', nullable: true, description: 'null reopens the entry' },
+      reason: { type: 'string', maxLength: 500 },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/admin/timesheets', {
+  summary: 'Staff timesheets',
+  description: 'Per staff member and day: minutes worked, scheduled, overtime and unscheduled (worked without a shift), late arrivals and early departures, plus open entries and missed shifts. A shift\'s entries count on the day of the first one. GET /admin/timesheets/export is the CSV for payroll, one row per staff member and day with hours to two decimals.',
+  query: {
+    from: 'YYYY-MM-DD, default the first of this month',
+    to: 'YYYY-MM-DD, default today; at most 62 days from from',
+    user_id: 'One staff member',
+  },
+});
+documentRoute('GET', '/api/v1/admin/dead-letters', {
+  paginated: true,
+  summary: 'Background jobs that ran out of attempts',
+  description: 'Failed email, webhook, inbound event, alert and refund jobs, with the reason of the last attempt. GET /admin/dead-letters/:id adds every attempt\'s error and the record the job was for; /admin/dead-letters/stats counts open ones per job type.',
+  query: {
+    ...PAGE_QUERY,
+    status: 'open, replayed or discarded',
+    job_type: 'e.g. send_email, deliver_webhook',
+  },
+});
+documentRoute('POST', '/api/v1/admin/dead-letters/replay', {
+  summary: 'Replay dead letters in bulk',
+  description: 'Queues the listed open dead letters, or the oldest 200 open ones of a job type, again with a fresh set of attempts. Ones that can\'t be replayed are returned in skipped. POST /admin/dead-letters/:id/replay and /discard act on one.',
+  body: {
+    type: 'object',
+    properties: {
+      ids: { type: 'array', items: { type: 'string', format: 'uuid' }, maxItems: 200 },
+      job_type: { type: 'string' },
+      note: { type: 'string', maxLength: 500 },
+    },
+  },
+});
+documentRoute('GET', '/api/v1/kitchen/orders', {
+  query: {
+    status: 'Item status, or all',
+    station: 'Kitchen station',
+    note_phrase: 'Only items given this quick phrase code',
+    per_page: 'Page size; the list is unpaged without it or cursor',
+    cursor: 'meta.next_cursor of the previous page',
+  },
+});
+
+// ── GetOpenApiSpec ──────────────────────────────────────────────────────────
+// Built from the app's route table on first request, once every route is
+// registered.
+
+export function getOpenApiSpec(app: Hono) {
+  let spec: ReturnType<typeof buildOpenApiDocument> | null = null;
+  return async (c: Context) => {
+    spec ??= buildOpenApiDocument(app.routes, {
+      title: 'Steak Kenangan Restaurant POS API',
+      version: '1.0.0',
+      basePath: '/api/v1',
+      authMiddleware,
+    });
+    return c.json(spec);
+  };
+}
+
+// ── GetApiDocs ──────────────────────────────────────────────────────────────
+// Swagger UI for the spec above, loaded from the CDN so the backend needs
+// no extra package.
+
+const SWAGGER_UI_VERSION = '5.17.14';
+
+export async function getApiDocs(c: Context) {
+  const asset = `https://unpkg.com/swagger-ui-dist@${SWAGGER_UI_VERSION}`;
+  return c.html(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>Steak Kenangan Restaurant POS API</title>
+  <link rel="stylesheet" href="${asset}/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="${asset}/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: '/api/v1/docs/openapi.json', dom_id: '#swagger-ui', persistAuthorization: true });
+  </script>
+</body>
+</html>`);
+}
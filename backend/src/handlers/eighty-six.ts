@@ -0,0 +1,105 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { localClock } from '../lib/clock.js';
+import { invalidateCache } from '../lib/cache.js';
+import { isUUID } from '../services/branches.js';
+import { queueMenuSync } from '../services/menu-sync.js';
+import {
+  EIGHTY_SIX_SELECT,
+  eightySixProduct,
+  restoreEightySixed,
+  buildEightySixReport,
+} from '../services/eighty-six.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+// ── SetEightySix ────────────────────────────────────────────────────────────
+// { eighty_sixed: true (default), reason? } takes the product off the menu
+// until the business-day rollover; { eighty_sixed: false } puts it back.
+
+export async function setEightySix(c: Context) {
+  const productId = c.req.param('id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  let body: { eighty_sixed?: boolean; reason?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const eightySixed = body.eighty_sixed ?? true;
+  if (typeof eightySixed !== 'boolean') {
+    return errorResponse(c, 'eighty_sixed must be a boolean', 'invalid_eighty_sixed', 400);
+  }
+  const reason = body.reason?.trim() || null;
+  if (reason && reason.length > 500) {
+    return errorResponse(c, 'Reason must be at most 500 characters', 'invalid_reason', 400);
+  }
+
+  const userId = c.get('user_id') ?? null;
+  try {
+    const result = await withTransaction(async (client) => {
+      if (eightySixed) {
+        const result = await eightySixProduct(client, productId, reason, userId);
+        if (!result.ok) {
+          return result;
+        }
+      } else if (!(await restoreEightySixed(client, productId, userId))) {
+        return txFailure("Product is not 86'd", 'not_eighty_sixed', 409);
+      }
+      await queueMenuSync(client, { productIds: [productId] });
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    invalidateCache('menu');
+
+    const res = await pool.query(
+      `SELECT id, name, is_available, eighty_sixed_at FROM products WHERE id = $1`,
+      [productId],
+    );
+    return successResponse(c, eightySixed ? "Product 86'd for the day" : 'Product back on the menu', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update 86 list', (err as Error).message);
+  }
+}
+
+// ── GetEightySixList ────────────────────────────────────────────────────────
+// What is 86'd right now, most recent first.
+
+export async function getEightySixList(c: Context) {
+  try {
+    const res = await pool.query(
+      `${EIGHTY_SIX_SELECT}
+       WHERE e.restored_at IS NULL AND p.eighty_sixed_at IS NOT NULL
+       ORDER BY e.eighty_sixed_at DESC`,
+    );
+    return successResponse(c, '86 list retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch 86 list', (err as Error).message);
+  }
+}
+
+// ── GetEightySixReport ──────────────────────────────────────────────────────
+// How often products got 86'd over [from, to] (default: this month so far).
+
+export async function getEightySixReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  try {
+    const report = await buildEightySixReport(pool, from, to);
+    return successResponse(c, '86 report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate 86 report', (err as Error).message);
+  }
+}
@@ -0,0 +1,153 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { isObjectStorageConfigured, objectStorageLocation } from '../lib/object-storage.js';
+import { ELT_STREAMS, exportStream, rewindStream } from '../services/elt-export.js';
+
+const RUN_STATUSES = ['succeeded', 'failed'];
+
+// ── GetAnalyticsExport ──────────────────────────────────────────────────────
+// Where exports go and how often, and each stream's watermark with the
+// outcome of its last run.
+
+export async function getAnalyticsExport(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT stream, watermark_at, watermark_id, rows_exported, last_run_at, last_status, last_error
+       FROM elt_watermarks
+       ORDER BY stream ASC`,
+    );
+    return successResponse(c, 'Analytics export retrieved successfully', {
+      enabled: isObjectStorageConfigured(),
+      target: env.ELT_EXPORT_TARGET,
+      location: objectStorageLocation(),
+      prefix: env.ELT_EXPORT_PREFIX,
+      interval_minutes: env.ELT_EXPORT_INTERVAL_MINUTES,
+      settle_seconds: env.ELT_EXPORT_SETTLE_SECONDS,
+      batch_rows: env.ELT_EXPORT_BATCH_ROWS,
+      streams: res.rows.map((row) => ({ ...row, rows_exported: Number(row.rows_exported) })),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch analytics export', (err as Error).message);
+  }
+}
+
+// ── GetAnalyticsExportRuns ──────────────────────────────────────────────────
+// Newest first, with the files each batch wrote.
+
+export async function getAnalyticsExportRuns(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const stream = c.req.query('stream');
+  const status = c.req.query('status');
+
+  if (stream && !ELT_STREAMS.includes(stream)) {
+    return errorResponse(c, `Stream must be one of: ${ELT_STREAMS.join(', ')}`, 'invalid_stream', 400);
+  }
+  if (status && !RUN_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${RUN_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (stream) {
+    conditions.push(`stream = $${paramIdx++}`);
+    params.push(stream);
+  }
+  if (status) {
+    conditions.push(`status = $${paramIdx++}`);
+    params.push(status);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM elt_export_runs ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `SELECT id, stream, status, from_at, from_id, to_at, to_id, row_count, files, error, started_at, finished_at
+           FROM elt_export_runs ${where}
+           ORDER BY started_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Analytics export runs retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'started_at:desc',
+      filters: { stream, status },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch analytics export runs', (err as Error).message);
+  }
+}
+
+// ── RunAnalyticsExport ──────────────────────────────────────────────────────
+// Exports now instead of waiting for the schedule: every stream, or the one
+// in ?stream=. A stream another instance is exporting is reported skipped.
+
+export async function runAnalyticsExport(c: Context) {
+  if (!isObjectStorageConfigured()) {
+    return errorResponse(c, 'Analytics export is not configured (ELT_EXPORT_TARGET)', 'export_not_configured', 503);
+  }
+  const stream = c.req.query('stream');
+  if (stream && !ELT_STREAMS.includes(stream)) {
+    return errorResponse(c, `Stream must be one of: ${ELT_STREAMS.join(', ')}`, 'invalid_stream', 400);
+  }
+
+  try {
+    const results = [];
+    for (const name of stream ? [stream] : ELT_STREAMS) {
+      results.push(await exportStream(pool, name));
+    }
+    const failed = results.filter((r) => r.error).length;
+    return successResponse(c, failed > 0 ? `Analytics export finished with ${failed} failed stream(s)` : 'Analytics export finished', results);
+  } catch (err) {
+    return errorResponse(c, 'Failed to run analytics export', (err as Error).message);
+  }
+}
+
+// ── RewindAnalyticsStream ───────────────────────────────────────────────────
+// For a warehouse table that was lost or loaded wrong: the stream's next runs
+// export again everything changed from `since` (YYYY-MM-DD), or all of it
+// when since is null.
+
+export async function rewindAnalyticsStream(c: Context) {
+  const stream = c.req.param('stream');
+  if (!ELT_STREAMS.includes(stream)) {
+    return errorResponse(c, 'Stream not found', 'stream_not_found', 404);
+  }
+
+  let body: { since?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (body.since === undefined || (body.since !== null && (typeof body.since !== 'string' || !/^\d{4}-\d{2}-\d{2}$/.test(body.since)))) {
+    return errorResponse(c, 'since must be a YYYY-MM-DD date, or null for everything', 'invalid_since', 400);
+  }
+
+  try {
+    if (!(await rewindStream(pool, stream, body.since))) {
+      return errorResponse(c, 'Stream not found', 'stream_not_found', 404);
+    }
+    const res = await pool.query(
+      'SELECT stream, watermark_at, watermark_id, rows_exported, last_run_at, last_status, last_error FROM elt_watermarks WHERE stream = $1',
+      [stream],
+    );
+    const row = res.rows[0];
+    return successResponse(c, 'Stream rewound; the next runs export it again', { ...row, rows_exported: Number(row.rows_exported) });
+  } catch (err) {
+    return errorResponse(c, 'Failed to rewind stream', (err as Error).message);
+  }
+}
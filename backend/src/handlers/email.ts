@@ -0,0 +1,185 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { enqueueJob } from '../lib/jobs.js';
+import { sendMail, smtpConfigured } from '../lib/mailer.js';
+import { isUUID } from '../services/branches.js';
+import { EMAIL_STATUSES, SEND_EMAIL_JOB, loadRestaurantName, loadSmtpConfig } from '../services/email.js';
+import { closeDeadLetters } from '../services/dead-letters.js';
+import { smtpTestEmail } from '../services/email-templates.js';
+import { PASSWORD_RESET_TEMPLATE } from '../services/password-reset.js';
+
+const EMAIL_SELECT = `
+  SELECT e.id, e.template, e.recipients, e.subject, e.body, e.status, e.attempts, e.last_error,
+         e.related_type, e.related_id, e.created_by, e.created_at, e.updated_at, e.sent_at,
+         NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS created_by_name
+  FROM email_outbox e
+  LEFT JOIN users u ON u.id = e.created_by`;
+
+const EMAIL_RE = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
+
+// A password reset email carries a working reset link; an admin reading the
+// outbox must not be able to take over the account with it
+function readableEmail(row: Record<string, unknown>) {
+  return row.template === PASSWORD_RESET_TEMPLATE ? { ...row, body: null, body_hidden: true } : row;
+}
+
+// ── GetEmails ───────────────────────────────────────────────────────────────
+// The outbox, newest first. The list leaves out bodies; open one to read it.
+
+export async function getEmails(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status');
+  const template = c.req.query('template');
+
+  if (status && !EMAIL_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${EMAIL_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (status) {
+    conditions.push(`e.status = $${paramIdx}`);
+    params.push(status);
+    paramIdx++;
+  }
+  if (template) {
+    conditions.push(`e.template = $${paramIdx}`);
+    params.push(template);
+    paramIdx++;
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM email_outbox e ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${EMAIL_SELECT} ${where}
+           ORDER BY e.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    const emails = result.rows.map(({ body: _body, ...row }: Record<string, unknown>) => row);
+
+    return paginatedResponse(c, 'Emails retrieved successfully', emails, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { status, template },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch emails', (err as Error).message);
+  }
+}
+
+// ── GetEmail ────────────────────────────────────────────────────────────────
+
+export async function getEmail(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Email not found', 'email_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(`${EMAIL_SELECT} WHERE e.id = $1`, [id]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Email not found', 'email_not_found', 404);
+    }
+    return successResponse(c, 'Email retrieved successfully', readableEmail(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch email', (err as Error).message);
+  }
+}
+
+// ── RetryEmail ──────────────────────────────────────────────────────────────
+// Queues a failed email for delivery again, e.g. after fixing the SMTP
+// settings.
+
+export async function retryEmail(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Email not found', 'email_not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `UPDATE email_outbox SET status = 'queued', updated_at = NOW()
+         WHERE id = $1 AND status = 'failed'
+         RETURNING id`,
+        [id],
+      );
+      if (res.rows.length === 0) {
+        const exists = await client.query('SELECT status FROM email_outbox WHERE id = $1', [id]);
+        if (exists.rows.length === 0) {
+          return txFailure('Email not found', 'email_not_found', 404);
+        }
+        return txFailure(`Only failed emails can be retried; this email is ${exists.rows[0].status}`, 'invalid_email_status', 400);
+      }
+      await enqueueJob(client, SEND_EMAIL_JOB, { outbox_id: id });
+      await closeDeadLetters(client, { jobType: SEND_EMAIL_JOB, payload: { outbox_id: id } }, userId ?? null);
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${EMAIL_SELECT} WHERE e.id = $1`, [id]);
+    return successResponse(c, 'Email queued for retry', readableEmail(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to retry email', (err as Error).message);
+  }
+}
+
+// ── SendTestEmail ───────────────────────────────────────────────────────────
+// Sends straight away, bypassing the outbox, so the admin sees the SMTP
+// server's answer. Defaults to the signed-in user's own address.
+
+export async function sendTestEmail(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { to?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    body = {};
+  }
+
+  try {
+    const userRes = await pool.query(
+      "SELECT email, TRIM(CONCAT(first_name, ' ', last_name)) AS name, username FROM users WHERE id = $1",
+      [userId],
+    );
+    const user = userRes.rows[0];
+    const to = (body.to || user?.email || '').trim();
+    if (!EMAIL_RE.test(to)) {
+      return errorResponse(c, 'A valid recipient email is required', 'invalid_email', 400);
+    }
+
+    const config = await loadSmtpConfig(pool);
+    if (!smtpConfigured(config)) {
+      return errorResponse(c, 'SMTP host and from address must be set', 'email_not_configured', 400);
+    }
+
+    const email = smtpTestEmail(await loadRestaurantName(pool), user?.name || user?.username || 'an administrator');
+    try {
+      await sendMail({ to: [to], ...email }, config);
+    } catch (err) {
+      return errorResponse(c, `Test email failed: ${(err as Error).message}`, 'smtp_error', 502);
+    }
+
+    return successResponse(c, 'Test email sent', { to, host: config.host, port: config.port });
+  } catch (err) {
+    return errorResponse(c, 'Failed to send test email', (err as Error).message);
+  }
+}
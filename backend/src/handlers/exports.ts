@@ -0,0 +1,104 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock } from '../lib/clock.js';
+import { isUUID, resolveBranchScope } from '../services/branches.js';
+import { logExport, exportFileHeaders, exportWatermark } from '../services/data-exports.js';
+import { JOURNAL_FORMATS, buildDailyJournal, journalCsv, type JournalFormat } from '../services/accounting-export.js';
+
+// ── ExportJournal ───────────────────────────────────────────────────────────
+// The day's sales journal as an Accurate or Jurnal import file, or as JSON
+// to review before importing.
+
+export async function exportJournal(c: Context) {
+  const date = c.req.query('date') || localClock().date;
+  if (!/^\d{4}-\d{2}-\d{2}$/.test(date)) {
+    return errorResponse(c, 'Date must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+  const format = (c.req.query('format') || 'accurate') as JournalFormat;
+  if (!JOURNAL_FORMATS.includes(format)) {
+    return errorResponse(c, `Format must be one of: ${JOURNAL_FORMATS.join(', ')}`, 'invalid_format', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const journal = await buildDailyJournal(pool, date, scope.branchId);
+    const record = await logExport(pool, c, {
+      type: 'journal',
+      format,
+      params: { date, branch_id: scope.branchId },
+      rowCount: journal.lines.length,
+    });
+    if (format === 'json') {
+      return successResponse(c, 'Journal retrieved successfully', { ...journal, export: exportWatermark(record) });
+    }
+
+    return c.body(
+      journalCsv(journal, format),
+      200,
+      exportFileHeaders('text/csv; charset=utf-8', `journal-${format}-${journal.reference.toLowerCase()}.csv`, record),
+    );
+  } catch (err) {
+    return errorResponse(c, 'Failed to export journal', (err as Error).message);
+  }
+}
+
+// ── GetExportLog ────────────────────────────────────────────────────────────
+// Who downloaded which export, newest first.
+
+export async function getExportLog(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const exportType = c.req.query('export_type');
+  const userId = c.req.query('user_id');
+
+  if (userId && !isUUID(userId)) {
+    return errorResponse(c, 'Invalid user_id', 'invalid_user_id', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (exportType) {
+    conditions.push(`l.export_type = $${paramIdx++}`);
+    params.push(exportType);
+  }
+  if (userId) {
+    conditions.push(`l.user_id = $${paramIdx++}`);
+    params.push(userId);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM export_log l ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `SELECT l.id, l.export_type, l.format, l.user_id, l.username, l.branch_id, l.params,
+                  l.row_count, l.ip_address, l.created_at
+           FROM export_log l ${where}
+           ORDER BY l.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Export log retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { export_type: exportType, user_id: userId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch export log', (err as Error).message);
+  }
+}
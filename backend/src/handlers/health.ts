@@ -1,41 +1,116 @@
 import type { Context } from 'hono';
 import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import { isShuttingDown, inFlightRequests } from '../lib/lifecycle.js';
+import { getSchemaVersion } from '../db/migrate.js';
+import { errorResponse } from '../lib/response.js';
+import { validateToken } from '../lib/jwt.js';
+import { BUILD, runHealthChecks } from '../lib/health.js';
+import { loadRolePermissions } from '../services/permissions.js';
+import { UPLOAD_DIR } from './upload.js';
 
-export async function getSystemHealth(c: Context) {
-  const startTime = Date.now();
+// ── GetSystemHealth ─────────────────────────────────────────────────────────
+// Overall status plus one status per dependency. ?verbose=true adds each
+// check's details (errors, latencies, disk space, queue depth), which say
+// more about the deployment than a public endpoint should, so it takes a
+// token whose role may manage settings.
 
-  // Check database connection
-  let dbHealth: { connected: boolean; latency?: string; error?: string };
+async function authorizeVerbose(c: Context): Promise<Response | null> {
+  const header = c.req.header('Authorization');
+  if (!header?.startsWith('Bearer ')) {
+    return errorResponse(c, 'Verbose health requires an admin token', 'missing_auth_header', 401);
+  }
+  let role: string;
   try {
-    const dbStart = Date.now();
-    const client = await pool.connect();
-    try {
-      await client.query('SELECT 1');
-      const latency = Date.now() - dbStart;
-      dbHealth = { connected: true, latency: `${latency}ms` };
-    } finally {
-      client.release();
-    }
-  } catch (err) {
-    dbHealth = { connected: false, error: (err as Error).message };
+    role = validateToken(header.slice(7)).role;
+  } catch {
+    return errorResponse(c, 'Invalid or expired token', 'invalid_token', 401);
+  }
+  if (!(await loadRolePermissions(role)).has('settings.manage')) {
+    return errorResponse(c, 'Insufficient permissions', 'insufficient_permissions', 403);
   }
+  return null;
+}
 
-  const status = dbHealth.connected ? 'healthy' : 'unhealthy';
-  const statusCode = dbHealth.connected ? 200 : 503;
+export async function getSystemHealth(c: Context) {
+  const verbose = c.req.query('verbose') === 'true';
+  if (verbose) {
+    const denied = await authorizeVerbose(c);
+    if (denied) return denied;
+  }
+
+  const { status, checks } = await runHealthChecks({ uploadsDir: UPLOAD_DIR });
+  const database = checks.database;
+  const connected = database.status !== 'down';
+  const schema = connected ? await getSchemaVersion() : null;
+  const uptimeSeconds = Math.round(process.uptime());
 
   const response: Record<string, unknown> = {
     status,
     timestamp: new Date().toISOString(),
-    version: '1.0.0',
-    database: dbHealth,
+    version: BUILD.version,
+    build: BUILD,
+    uptime_seconds: uptimeSeconds,
+    database: {
+      connected,
+      latency: `${database.latency_ms}ms`,
+      ...(verbose && database.error && { error: database.error }),
+    },
+    schema,
+    checks: verbose
+      ? checks
+      : Object.fromEntries(Object.entries(checks).map(([name, check]) => [name, { status: check.status }])),
     services: {
       api: {
         status: 'operational',
-        uptime: `${Date.now() - startTime}ms`,
-        environment: process.env.NODE_ENV || 'production',
+        uptime: `${uptimeSeconds}s`,
+        environment: env.NODE_ENV,
       },
     },
   };
+  if (verbose) {
+    const memory = process.memoryUsage();
+    response.process = {
+      pid: process.pid,
+      node: process.version,
+      rss_mb: Math.round(memory.rss / 1024 / 1024),
+      heap_used_mb: Math.round(memory.heapUsed / 1024 / 1024),
+      in_flight_requests: inFlightRequests(),
+      shutting_down: isShuttingDown(),
+    };
+  }
+
+  return c.json(response, status === 'unhealthy' ? 503 : 200);
+}
+
+// ── GetReadiness ────────────────────────────────────────────────────────────
+// Readiness differs from /health: it turns 503 as soon as shutdown begins so
+// load balancers stop routing here while in-flight requests drain.
+
+export async function getReadiness(c: Context) {
+  if (isShuttingDown()) {
+    return c.json({
+      ready: false,
+      reason: 'shutting_down',
+      timestamp: new Date().toISOString(),
+    }, 503);
+  }
 
-  return c.json(response, statusCode as 200);
+  try {
+    const dbStart = Date.now();
+    await pool.query('SELECT 1');
+    return c.json({
+      ready: true,
+      database: { connected: true, latency: `${Date.now() - dbStart}ms` },
+      pool: { total: pool.totalCount, idle: pool.idleCount, waiting: pool.waitingCount },
+      timestamp: new Date().toISOString(),
+    });
+  } catch (err) {
+    return c.json({
+      ready: false,
+      reason: 'database_unavailable',
+      database: { connected: false, error: (err as Error).message },
+      timestamp: new Date().toISOString(),
+    }, 503);
+  }
 }
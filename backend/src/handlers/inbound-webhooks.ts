@@ -0,0 +1,181 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { enqueueJob } from '../lib/jobs.js';
+import { isUUID } from '../services/branches.js';
+import {
+  INBOUND_WEBHOOK_MAX_ATTEMPTS,
+  INBOUND_WEBHOOK_PROVIDERS,
+  INBOUND_WEBHOOK_STATUSES,
+  PROCESS_INBOUND_WEBHOOK_JOB,
+  answerInboundChallenge,
+  receiveInboundWebhook,
+} from '../services/inbound-webhooks.js';
+import { closeDeadLetters } from '../services/dead-letters.js';
+
+const EVENT_SELECT = `
+  SELECT e.id, e.provider, e.external_id, e.event_type, e.status, e.attempts, e.duplicate_count,
+         e.last_error, e.received_at, e.processed_at, e.updated_at
+  FROM inbound_webhook_events e`;
+
+// ── HandleInboundWebhook ────────────────────────────────────────────────────
+// Public and signature-verified. A duplicate is acknowledged like a new
+// event so the provider stops resending it.
+
+export async function handleInboundWebhook(c: Context) {
+  const provider = c.req.param('provider');
+
+  let rawBody: string;
+  try {
+    rawBody = await c.req.text();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  try {
+    const result = await receiveInboundWebhook(provider, { rawBody, header: (name) => c.req.header(name) });
+    if (!result.ok) {
+      if (result.failure.code === 'invalid_signature') {
+        console.log(`INBOUND_WEBHOOK_ALERT: Invalid signature on a ${provider} callback`);
+      }
+      return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    }
+    return successResponse(c, result.duplicate ? 'Event already received' : 'Event received', {
+      id: result.eventId,
+      duplicate: result.duplicate,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to receive webhook', (err as Error).message);
+  }
+}
+
+// ── VerifyInboundSubscription ───────────────────────────────────────────────
+// Providers that confirm a callback URL with a GET (WhatsApp's hub.challenge)
+// expect the challenge echoed back as plain text.
+
+export async function verifyInboundSubscription(c: Context) {
+  const challenge = answerInboundChallenge(c.req.param('provider'), c.req.query());
+  if (challenge === null) {
+    return errorResponse(c, 'Subscription verification failed', 'invalid_verify_token', 403);
+  }
+  return c.text(challenge);
+}
+
+// ── GetInboundWebhookEvents ─────────────────────────────────────────────────
+
+export async function getInboundWebhookEvents(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const provider = c.req.query('provider');
+  const status = c.req.query('status');
+  const eventType = c.req.query('event_type');
+
+  if (provider && !INBOUND_WEBHOOK_PROVIDERS.includes(provider)) {
+    return errorResponse(c, `Provider must be one of: ${INBOUND_WEBHOOK_PROVIDERS.join(', ')}`, 'invalid_provider', 400);
+  }
+  if (status && !INBOUND_WEBHOOK_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${INBOUND_WEBHOOK_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (provider) {
+    conditions.push(`e.provider = $${paramIdx++}`);
+    params.push(provider);
+  }
+  if (status) {
+    conditions.push(`e.status = $${paramIdx++}`);
+    params.push(status);
+  }
+  if (eventType) {
+    conditions.push(`e.event_type = $${paramIdx++}`);
+    params.push(eventType);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM inbound_webhook_events e ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${EVENT_SELECT} ${where}
+           ORDER BY e.received_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Inbound webhook events retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'received_at:desc',
+      filters: { provider, status, event_type: eventType },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch inbound webhook events', (err as Error).message);
+  }
+}
+
+// ── GetInboundWebhookEvent ──────────────────────────────────────────────────
+
+export async function getInboundWebhookEvent(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Inbound webhook event not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('SELECT * FROM inbound_webhook_events WHERE id = $1', [id]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Inbound webhook event not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Inbound webhook event retrieved successfully', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch inbound webhook event', (err as Error).message);
+  }
+}
+
+// ── RetryInboundWebhookEvent ────────────────────────────────────────────────
+// Failed events, and ignored ones once a handler for them exists.
+
+export async function retryInboundWebhookEvent(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Inbound webhook event not found', 'not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `UPDATE inbound_webhook_events SET status = 'queued', updated_at = NOW()
+         WHERE id = $1 AND status IN ('failed', 'ignored')
+         RETURNING id`,
+        [id],
+      );
+      if (res.rows.length === 0) {
+        const exists = await client.query('SELECT status FROM inbound_webhook_events WHERE id = $1', [id]);
+        if (exists.rows.length === 0) {
+          return txFailure('Inbound webhook event not found', 'not_found', 404);
+        }
+        return txFailure(`Only failed or ignored events can be retried; this event is ${exists.rows[0].status}`, 'invalid_event_status', 400);
+      }
+      await enqueueJob(client, PROCESS_INBOUND_WEBHOOK_JOB, { event_id: id }, { maxAttempts: INBOUND_WEBHOOK_MAX_ATTEMPTS });
+      await closeDeadLetters(client, { jobType: PROCESS_INBOUND_WEBHOOK_JOB, payload: { event_id: id } }, userId ?? null);
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${EVENT_SELECT} WHERE e.id = $1`, [id]);
+    return successResponse(c, 'Inbound webhook event queued for retry', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to retry inbound webhook event', (err as Error).message);
+  }
+}
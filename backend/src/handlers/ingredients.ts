@@ -1,455 +1,424 @@
 import type { Context } from 'hono';
-import { sql } from 'drizzle-orm';
-import { db, pool } from '../db/connection.js';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { createIngredientBatch, listExpiringBatches, loadExpiryWarningDays } from '../services/ingredient-batches.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+type IngredientRow = {
+  id: string;
+  name: string;
+  description: string | null;
+  unit: string;
+  current_stock: string;
+  minimum_stock: string;
+  maximum_stock: string;
+  unit_cost: string;
+  supplier: string | null;
+  last_restocked: string;
+  is_active: boolean;
+  status: 'ok' | 'low' | 'out';
+  total_value: string;
+  created_at: string;
+  updated_at: string;
+};
+
+interface Ingredient {
+  id: string;
+  name: string;
+  description: string;
+  unit: string;
+  current_stock: number;
+  minimum_stock: number;
+  maximum_stock: number;
+  unit_cost: number;
+  supplier: string;
+  last_restocked: string;
+  is_active: boolean;
+  status: 'ok' | 'low' | 'out';
+  total_value: number;
+  created_at: string;
+  updated_at: string;
+}
+
+interface IngredientHistoryRecord {
+  id: string;
+  operation: string;
+  quantity: number;
+  previous_stock: number;
+  new_stock: number;
+  reason: string;
+  notes: string;
+  adjusted_by: string;
+  created_at: string;
+}
+
+interface IngredientInput {
+  name?: string;
+  description?: string;
+  unit?: string;
+  current_stock?: number;
+  minimum_stock?: number;
+  maximum_stock?: number;
+  unit_cost?: number;
+  supplier?: string;
+}
+
+interface RestockRequest {
+  ingredient_id?: string;
+  quantity?: number;
+  notes?: string;
+  expiry_date?: string | null;
+  batch_code?: string | null;
+}
+
+const INGREDIENT_COLUMNS = `
+  i.id, i.name, i.description, i.unit, i.current_stock, i.minimum_stock, i.maximum_stock, i.unit_cost,
+  i.supplier, COALESCE(i.last_restocked_at, i.created_at) AS last_restocked, i.is_active,
+  CASE
+    WHEN i.current_stock = 0 THEN 'out'
+    WHEN i.current_stock < i.minimum_stock THEN 'low'
+    ELSE 'ok'
+  END AS status,
+  i.current_stock * i.unit_cost AS total_value,
+  i.created_at, i.updated_at`;
+
+function formatIngredient(row: IngredientRow): Ingredient {
+  return {
+    id: row.id,
+    name: row.name,
+    description: row.description ?? '',
+    unit: row.unit,
+    current_stock: Number(row.current_stock),
+    minimum_stock: Number(row.minimum_stock),
+    maximum_stock: Number(row.maximum_stock),
+    unit_cost: Number(row.unit_cost),
+    supplier: row.supplier ?? '',
+    last_restocked: row.last_restocked,
+    is_active: row.is_active,
+    status: row.status,
+    total_value: Number(row.total_value),
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+function validNumber(value: unknown): boolean {
+  return value === undefined || (typeof value === 'number' && isFinite(value) && value >= 0);
+}
+
+function validateIngredientInput(body: IngredientInput): { message: string; code: string } | null {
+  for (const field of ['current_stock', 'minimum_stock', 'maximum_stock', 'unit_cost'] as const) {
+    if (!validNumber(body[field])) {
+      return { message: `${field} must be a non-negative number`, code: `invalid_${field}` };
+    }
+  }
+  if (body.name !== undefined && body.name.trim().length > 100) {
+    return { message: 'name must be at most 100 characters', code: 'invalid_name' };
+  }
+  return null;
+}
 
 // ── GetIngredients ──────────────────────────────────────────────────────────
+// Active ingredients, those running low first. ?search= matches the name,
+// ?low_stock=true keeps only those below their minimum.
 
 export async function getIngredients(c: Context) {
-  try {
-    const rows = await db.execute<{
-      id: string;
-      name: string;
-      description: string;
-      unit: string;
-      current_stock: string;
-      minimum_stock: string;
-      maximum_stock: string;
-      unit_cost: string;
-      supplier: string;
-      last_restocked: string;
-      is_active: boolean;
-      created_at: string;
-      updated_at: string;
-      status: string;
-      total_value: string;
-    }>(sql`
-      SELECT
-        id, name, COALESCE(description, '') as description, unit,
-        current_stock, minimum_stock, maximum_stock, unit_cost,
-        COALESCE(supplier, '') as supplier, COALESCE(last_restocked_at, created_at) as last_restocked,
-        is_active, created_at, updated_at,
-        CASE
-          WHEN current_stock = 0 THEN 'out'
-          WHEN current_stock < minimum_stock THEN 'low'
-          ELSE 'ok'
-        END as status,
-        current_stock * unit_cost as total_value
-      FROM ingredients
-      WHERE is_active = true
-      ORDER BY status DESC, name ASC
-    `);
-
-    const ingredients = rows.rows.map((row) => ({
-      id: row.id,
-      name: row.name,
-      description: row.description,
-      unit: row.unit,
-      current_stock: Number(row.current_stock),
-      minimum_stock: Number(row.minimum_stock),
-      maximum_stock: Number(row.maximum_stock),
-      unit_cost: Number(row.unit_cost),
-      supplier: row.supplier,
-      last_restocked: row.last_restocked,
-      is_active: row.is_active,
-      status: row.status,
-      total_value: Number(row.total_value),
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }));
+  const search = c.req.query('search')?.trim();
+  const lowStock = c.req.query('low_stock') === 'true';
+
+  const conditions = ['i.is_active = true'];
+  const params: unknown[] = [];
+  if (search) {
+    params.push(`%${search}%`);
+    conditions.push(`i.name ILIKE $${params.length}`);
+  }
+  if (lowStock) {
+    conditions.push('i.current_stock < i.minimum_stock');
+  }
 
-    // Return raw array (matches Go behavior)
-    return c.json(ingredients, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch ingredients' }, 500);
+  try {
+    const res = await pool.query<IngredientRow>(
+      `SELECT ${INGREDIENT_COLUMNS}
+       FROM ingredients i
+       WHERE ${conditions.join(' AND ')}
+       ORDER BY status DESC, i.name ASC`,
+      params,
+    );
+    return successResponse(c, 'Ingredients retrieved successfully', res.rows.map(formatIngredient));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch ingredients', (err as Error).message);
   }
 }
 
-// ── GetIngredient ──────────────────────────────────────────────────────────
+// ── GetIngredient ───────────────────────────────────────────────────────────
 
 export async function getIngredient(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
 
   try {
-    const rows = await db.execute<{
-      id: string;
-      name: string;
-      description: string;
-      unit: string;
-      current_stock: string;
-      minimum_stock: string;
-      maximum_stock: string;
-      unit_cost: string;
-      supplier: string;
-      last_restocked: string;
-      is_active: boolean;
-      created_at: string;
-      updated_at: string;
-      status: string;
-      total_value: string;
-    }>(sql`
-      SELECT
-        id, name, COALESCE(description, '') as description, unit,
-        current_stock, minimum_stock, maximum_stock, unit_cost,
-        COALESCE(supplier, '') as supplier, COALESCE(last_restocked_at, created_at) as last_restocked,
-        is_active, created_at, updated_at,
-        CASE
-          WHEN current_stock = 0 THEN 'out'
-          WHEN current_stock < minimum_stock THEN 'low'
-          ELSE 'ok'
-        END as status,
-        current_stock * unit_cost as total_value
-      FROM ingredients
-      WHERE id = ${id}
-    `);
-
-    if (rows.rows.length === 0) {
-      return c.json({ error: 'Ingredient not found' }, 404);
+    const res = await pool.query<IngredientRow>(`SELECT ${INGREDIENT_COLUMNS} FROM ingredients i WHERE i.id = $1`, [id]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
     }
-
-    const row = rows.rows[0];
-    return c.json({
-      id: row.id,
-      name: row.name,
-      description: row.description,
-      unit: row.unit,
-      current_stock: Number(row.current_stock),
-      minimum_stock: Number(row.minimum_stock),
-      maximum_stock: Number(row.maximum_stock),
-      unit_cost: Number(row.unit_cost),
-      supplier: row.supplier,
-      last_restocked: row.last_restocked,
-      is_active: row.is_active,
-      status: row.status,
-      total_value: Number(row.total_value),
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }, 200);
-  } catch {
-    return c.json({ error: 'Ingredient not found' }, 404);
+    return successResponse(c, 'Ingredient retrieved successfully', formatIngredient(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch ingredient', (err as Error).message);
   }
 }
 
-// ── CreateIngredient ──────────────────────────────────────────────────────────
+// ── CreateIngredient ────────────────────────────────────────────────────────
 
 export async function createIngredient(c: Context) {
-  let body: {
-    name: string;
-    description?: string;
-    unit: string;
-    current_stock?: number;
-    minimum_stock?: number;
-    maximum_stock?: number;
-    unit_cost?: number;
-    supplier?: string;
-  };
-
+  let body: IngredientInput;
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
-  if (!body.name) {
-    return c.json({ error: 'name is required' }, 400);
+  if (!body.name?.trim()) {
+    return errorResponse(c, 'name is required', 'missing_name', 400);
   }
-  if (!body.unit) {
-    return c.json({ error: 'unit is required' }, 400);
+  if (!body.unit?.trim()) {
+    return errorResponse(c, 'unit is required', 'missing_unit', 400);
+  }
+  const invalid = validateIngredientInput(body);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
   }
 
   try {
-    const rows = await db.execute<{
-      id: string;
-      name: string;
-      description: string | null;
-      unit: string;
-      current_stock: string;
-      minimum_stock: string;
-      maximum_stock: string;
-      unit_cost: string;
-      supplier: string | null;
-      created_at: string;
-      updated_at: string;
-    }>(sql`
-      INSERT INTO ingredients (name, description, unit, current_stock, minimum_stock, maximum_stock, unit_cost, supplier)
-      VALUES (${body.name}, ${body.description || null}, ${body.unit}, ${body.current_stock ?? 0},
-              ${body.minimum_stock ?? 0}, ${body.maximum_stock ?? 0}, ${body.unit_cost ?? 0}, ${body.supplier || null})
-      RETURNING id, name, description, unit, current_stock, minimum_stock, maximum_stock, unit_cost, supplier, created_at, updated_at
-    `);
-
-    const row = rows.rows[0];
-    return c.json({
-      id: row.id,
-      name: row.name,
-      description: row.description ?? '',
-      unit: row.unit,
-      current_stock: Number(row.current_stock),
-      minimum_stock: Number(row.minimum_stock),
-      maximum_stock: Number(row.maximum_stock),
-      unit_cost: Number(row.unit_cost),
-      supplier: row.supplier ?? '',
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }, 201);
-  } catch {
-    return c.json({ error: 'Failed to create ingredient' }, 500);
+    const res = await pool.query<IngredientRow>(
+      `WITH i AS (
+         INSERT INTO ingredients (name, description, unit, current_stock, minimum_stock, maximum_stock, unit_cost, supplier)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+         RETURNING *
+       )
+       SELECT ${INGREDIENT_COLUMNS} FROM i`,
+      [
+        body.name.trim(),
+        body.description || null,
+        body.unit.trim(),
+        body.current_stock ?? 0,
+        body.minimum_stock ?? 0,
+        body.maximum_stock ?? 0,
+        body.unit_cost ?? 0,
+        body.supplier || null,
+      ],
+    );
+    return successResponse(c, 'Ingredient created successfully', formatIngredient(res.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create ingredient', (err as Error).message);
   }
 }
 
-// ── UpdateIngredient ──────────────────────────────────────────────────────────
+// ── UpdateIngredient ────────────────────────────────────────────────────────
+// Only the fields sent are changed; stock moves through restocks and
+// adjustments, not here.
 
 export async function updateIngredient(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
 
-  let body: {
-    name?: string;
-    description?: string;
-    unit?: string;
-    minimum_stock?: number;
-    maximum_stock?: number;
-    unit_cost?: number;
-    supplier?: string;
-    is_active?: boolean;
-  };
-
+  let body: Omit<IngredientInput, 'current_stock'> & { is_active?: boolean };
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateIngredientInput(body);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
   }
 
   try {
-    const rows = await db.execute<{
-      id: string;
-      name: string;
-      description: string | null;
-      unit: string;
-      current_stock: string;
-      minimum_stock: string;
-      maximum_stock: string;
-      unit_cost: string;
-      supplier: string | null;
-      is_active: boolean;
-      created_at: string;
-      updated_at: string;
-    }>(sql`
-      UPDATE ingredients
-      SET name = COALESCE(NULLIF(${body.name ?? ''}, ''), name),
-          description = COALESCE(NULLIF(${body.description ?? ''}, ''), description),
-          unit = COALESCE(NULLIF(${body.unit ?? ''}, ''), unit),
-          minimum_stock = COALESCE(NULLIF(${body.minimum_stock ?? 0}, 0), minimum_stock),
-          maximum_stock = COALESCE(NULLIF(${body.maximum_stock ?? 0}, 0), maximum_stock),
-          unit_cost = COALESCE(NULLIF(${body.unit_cost ?? 0}, 0), unit_cost),
-          supplier = COALESCE(NULLIF(${body.supplier ?? ''}, ''), supplier),
-          updated_at = NOW()
-      WHERE id = ${id}
-      RETURNING id, name, description, unit, current_stock, minimum_stock, maximum_stock, unit_cost, supplier, is_active, created_at, updated_at
-    `);
-
-    if (rows.rows.length === 0) {
-      return c.json({ error: 'Ingredient not found' }, 404);
+    const res = await pool.query<IngredientRow>(
+      `WITH i AS (
+         UPDATE ingredients
+         SET name = COALESCE(NULLIF($2, ''), name),
+             description = COALESCE(NULLIF($3, ''), description),
+             unit = COALESCE(NULLIF($4, ''), unit),
+             minimum_stock = COALESCE($5, minimum_stock),
+             maximum_stock = COALESCE($6, maximum_stock),
+             unit_cost = COALESCE($7, unit_cost),
+             supplier = COALESCE(NULLIF($8, ''), supplier),
+             is_active = COALESCE($9, is_active),
+             updated_at = NOW()
+         WHERE id = $1
+         RETURNING *
+       )
+       SELECT ${INGREDIENT_COLUMNS} FROM i`,
+      [
+        id,
+        body.name?.trim() ?? null,
+        body.description ?? null,
+        body.unit?.trim() ?? null,
+        body.minimum_stock ?? null,
+        body.maximum_stock ?? null,
+        body.unit_cost ?? null,
+        body.supplier ?? null,
+        typeof body.is_active === 'boolean' ? body.is_active : null,
+      ],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
     }
-
-    const row = rows.rows[0];
-    return c.json({
-      id: row.id,
-      name: row.name,
-      description: row.description ?? '',
-      unit: row.unit,
-      current_stock: Number(row.current_stock),
-      minimum_stock: Number(row.minimum_stock),
-      maximum_stock: Number(row.maximum_stock),
-      unit_cost: Number(row.unit_cost),
-      supplier: row.supplier ?? '',
-      is_active: row.is_active,
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }, 200);
-  } catch {
-    return c.json({ error: 'Ingredient not found' }, 404);
+    return successResponse(c, 'Ingredient updated successfully', formatIngredient(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update ingredient', (err as Error).message);
   }
 }
 
-// ── DeleteIngredient ──────────────────────────────────────────────────────────
+// ── DeleteIngredient ────────────────────────────────────────────────────────
+// Soft delete: recipes and history keep pointing at the ingredient.
 
 export async function deleteIngredient(c: Context) {
   const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
 
   try {
-    const res = await db.execute(sql`
-      UPDATE ingredients SET is_active = false, updated_at = NOW() WHERE id = ${id}
-    `);
-
+    const res = await pool.query('UPDATE ingredients SET is_active = false, updated_at = NOW() WHERE id = $1', [id]);
     if (res.rowCount === 0) {
-      return c.json({ error: 'Ingredient not found' }, 404);
+      return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
     }
-
-    return c.json({ message: 'Ingredient deleted successfully' }, 200);
-  } catch {
-    return c.json({ error: 'Failed to delete ingredient' }, 500);
+    return successResponse(c, 'Ingredient deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete ingredient', (err as Error).message);
   }
 }
 
-// ── RestockIngredient ──────────────────────────────────────────────────────────
+// ── RestockIngredient ───────────────────────────────────────────────────────
 
 export async function restockIngredient(c: Context) {
-  let body: {
-    ingredient_id: string;
-    quantity: number;
-    notes?: string;
-  };
-
+  let body: RestockRequest;
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
   if (!body.ingredient_id) {
-    return c.json({ error: 'ingredient_id is required' }, 400);
+    return errorResponse(c, 'ingredient_id is required', 'missing_ingredient_id', 400);
   }
-  if (!body.quantity || body.quantity <= 0) {
-    return c.json({ error: 'quantity must be greater than 0' }, 400);
+  if (!isUUID(body.ingredient_id)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
+  if (typeof body.quantity !== 'number' || !(body.quantity > 0)) {
+    return errorResponse(c, 'quantity must be greater than 0', 'invalid_quantity', 400);
+  }
+  if (body.expiry_date && (!DATE_RE.test(body.expiry_date) || isNaN(Date.parse(body.expiry_date)))) {
+    return errorResponse(c, 'expiry_date must be a YYYY-MM-DD date', 'invalid_expiry_date', 400);
+  }
+  const batchCode = body.batch_code?.trim() || null;
+  if (batchCode && batchCode.length > 50) {
+    return errorResponse(c, 'batch_code must be at most 50 characters', 'invalid_batch_code', 400);
   }
 
+  const ingredientId = body.ingredient_id;
+  const quantity = body.quantity;
   const userId = c.get('user_id');
 
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
-
-    // Get current stock
-    const ingRes = await client.query(
-      'SELECT current_stock, name FROM ingredients WHERE id = $1',
-      [body.ingredient_id],
-    );
-
-    if (ingRes.rows.length === 0) {
-      await client.query('ROLLBACK');
-      return c.json({ error: 'Ingredient not found' }, 404);
-    }
-
-    const currentStock = Number(ingRes.rows[0].current_stock);
-    const newStock = currentStock + body.quantity;
-
-    // Update stock
-    await client.query(
-      'UPDATE ingredients SET current_stock = $1, last_restocked_at = NOW(), updated_at = NOW() WHERE id = $2',
-      [newStock, body.ingredient_id],
-    );
-
-    // Create history record
-    await client.query(
-      `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
-       VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-      [body.ingredient_id, 'restock', body.quantity, currentStock, newStock, 'restock', body.notes || null, userId],
-    );
-
-    await client.query('COMMIT');
-
-    return c.json({
-      message: 'Ingredient restocked successfully',
+    const result = await withTransaction(async (client) => {
+      const ingRes = await client.query(
+        'SELECT current_stock FROM ingredients WHERE id = $1 FOR UPDATE',
+        [ingredientId],
+      );
+      if (ingRes.rows.length === 0) {
+        return txFailure('Ingredient not found', 'ingredient_not_found', 404);
+      }
+
+      const currentStock = Number(ingRes.rows[0].current_stock);
+      const newStock = currentStock + quantity;
+
+      await client.query(
+        'UPDATE ingredients SET current_stock = $1, last_restocked_at = NOW(), updated_at = NOW() WHERE id = $2',
+        [newStock, ingredientId],
+      );
+
+      await client.query(
+        `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+        [ingredientId, 'restock', quantity, currentStock, newStock, 'restock', body.notes || null, userId],
+      );
+
+      const batch = await createIngredientBatch(client, {
+        ingredientId,
+        quantity,
+        expiryDate: body.expiry_date || null,
+        batchCode,
+        userId: userId ?? null,
+      });
+
+      return { ok: true as const, currentStock, newStock, batch };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { currentStock, newStock, batch } = result;
+    await refreshStockAvailability({ ingredientIds: [ingredientId] });
+
+    return successResponse(c, 'Ingredient restocked successfully', {
+      ingredient_id: ingredientId,
       previous_stock: currentStock,
+      added_quantity: quantity,
       new_stock: newStock,
-    }, 200);
-  } catch {
-    await client.query('ROLLBACK');
-    return c.json({ error: 'Failed to restock ingredient' }, 500);
-  } finally {
-    client.release();
+      batch,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to restock ingredient', (err as Error).message);
   }
 }
 
-// ── GetLowStockIngredients ──────────────────────────────────────────────────────
+// ── GetLowStockIngredients ──────────────────────────────────────────────────
 
 export async function getLowStockIngredients(c: Context) {
   try {
-    const rows = await db.execute<{
-      id: string;
-      name: string;
-      description: string;
-      unit: string;
-      current_stock: string;
-      minimum_stock: string;
-      maximum_stock: string;
-      unit_cost: string;
-      supplier: string;
-      last_restocked: string;
-      is_active: boolean;
-      created_at: string;
-      updated_at: string;
-      status: string;
-      total_value: string;
-    }>(sql`
-      SELECT
-        id, name, COALESCE(description, '') as description, unit,
-        current_stock, minimum_stock, maximum_stock, unit_cost,
-        COALESCE(supplier, '') as supplier, COALESCE(last_restocked_at, created_at) as last_restocked,
-        is_active, created_at, updated_at,
-        CASE
-          WHEN current_stock = 0 THEN 'out'
-          ELSE 'low'
-        END as status,
-        current_stock * unit_cost as total_value
-      FROM ingredients
-      WHERE is_active = true AND current_stock < minimum_stock
-      ORDER BY current_stock ASC, name ASC
-    `);
-
-    const ingredients = rows.rows.map((row) => ({
-      id: row.id,
-      name: row.name,
-      description: row.description,
-      unit: row.unit,
-      current_stock: Number(row.current_stock),
-      minimum_stock: Number(row.minimum_stock),
-      maximum_stock: Number(row.maximum_stock),
-      unit_cost: Number(row.unit_cost),
-      supplier: row.supplier,
-      last_restocked: row.last_restocked,
-      is_active: row.is_active,
-      status: row.status,
-      total_value: Number(row.total_value),
-      created_at: row.created_at,
-      updated_at: row.updated_at,
-    }));
-
-    // Return raw array (matches Go behavior)
-    return c.json(ingredients, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch low stock ingredients' }, 500);
+    const res = await pool.query<IngredientRow>(
+      `SELECT ${INGREDIENT_COLUMNS}
+       FROM ingredients i
+       WHERE i.is_active = true AND i.current_stock < i.minimum_stock
+       ORDER BY i.current_stock ASC, i.name ASC`,
+    );
+    return successResponse(c, 'Low stock ingredients retrieved successfully', res.rows.map(formatIngredient));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch low stock ingredients', (err as Error).message);
   }
 }
 
-// ── GetIngredientHistory ──────────────────────────────────────────────────────
+// ── GetIngredientHistory ────────────────────────────────────────────────────
+// The 100 most recent stock movements.
 
 export async function getIngredientHistory(c: Context) {
   const ingredientId = c.req.param('id');
+  if (!isUUID(ingredientId)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
 
   try {
-    const rows = await db.execute<{
-      id: string;
-      operation: string;
-      quantity: string;
-      previous_stock: string;
-      new_stock: string;
-      reason: string;
-      notes: string;
-      adjusted_by: string;
-      created_at: string;
-    }>(sql`
-      SELECT
-        ih.id, ih.operation, ih.quantity, ih.previous_stock, ih.new_stock,
-        COALESCE(ih.reason, '') as reason, COALESCE(ih.notes, '') as notes,
-        COALESCE(u.username, 'System') as adjusted_by,
-        ih.created_at
-      FROM ingredient_history ih
-      LEFT JOIN users u ON ih.adjusted_by = u.id
-      WHERE ih.ingredient_id = ${ingredientId}
-      ORDER BY ih.created_at DESC
-      LIMIT 100
-    `);
-
-    const history = rows.rows.map((row) => ({
+    const res = await pool.query(
+      `SELECT ih.id, ih.operation, ih.quantity, ih.previous_stock, ih.new_stock,
+              COALESCE(ih.reason, '') AS reason, COALESCE(ih.notes, '') AS notes,
+              COALESCE(u.username, 'System') AS adjusted_by, ih.created_at
+       FROM ingredient_history ih
+       LEFT JOIN users u ON ih.adjusted_by = u.id
+       WHERE ih.ingredient_id = $1
+       ORDER BY ih.created_at DESC
+       LIMIT 100`,
+      [ingredientId],
+    );
+
+    const history: IngredientHistoryRecord[] = res.rows.map((row) => ({
       id: row.id,
       operation: row.operation,
       quantity: Number(row.quantity),
@@ -460,10 +429,35 @@ export async function getIngredientHistory(c: Context) {
       adjusted_by: row.adjusted_by,
       created_at: row.created_at,
     }));
+    return successResponse(c, 'Ingredient history retrieved successfully', history);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch history', (err as Error).message);
+  }
+}
 
-    // Return raw array (matches Go behavior)
-    return c.json(history, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch history' }, 500);
+// ── GetExpiringIngredients ──────────────────────────────────────────────────
+// Open batches expiring within ?days= days (default: the
+// ingredient_expiry_warning_days setting), expired ones included.
+
+export async function getExpiringIngredients(c: Context) {
+  const daysParam = c.req.query('days');
+  let days: number | null = null;
+  if (daysParam !== undefined) {
+    days = parseInt(daysParam, 10);
+    if (isNaN(days) || days < 0 || days > 365) {
+      return errorResponse(c, 'days must be between 0 and 365', 'invalid_days', 400);
+    }
+  }
+
+  try {
+    const windowDays = days ?? await loadExpiryWarningDays(pool);
+    const batches = await listExpiringBatches(pool, windowDays);
+    return successResponse(c, 'Expiring ingredients retrieved successfully', {
+      days: windowDays,
+      expired: batches.filter((b) => b.expired).length,
+      batches,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch expiring ingredients', (err as Error).message);
   }
 }
@@ -1,60 +1,118 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { fetchPage, pageMeta, parsePagination } from '../lib/pagination.js';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { getDefaultBranchId, isUUID, resolveBranchScope, resolveWriteBranch } from '../services/branches.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+import { INVENTORY_ADJUST_REASONS } from '../services/data-model.js';
+
+// Stock is counted per branch. Head office looks at the main branch unless
+// it asks for another with ?branch_id=.
+async function inventoryBranch(
+  c: Context,
+): Promise<{ ok: true; branchId: string } | { ok: false; failure: { message: string; code: string; status: 400 | 403 } }> {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) return scope;
+  return { ok: true, branchId: scope.branchId ?? await getDefaultBranchId(pool) };
+}
+
+type InventoryRow = {
+  product_id: string;
+  product_name: string;
+  category_name: string | null;
+  current_stock: number;
+  min_stock: number;
+  max_stock: number;
+  last_restocked: string;
+  price: string;
+  status: 'ok' | 'low' | 'out';
+};
+
+interface InventoryItem {
+  product_id: string;
+  product_name: string;
+  category_name: string | null;
+  current_stock: number;
+  min_stock: number;
+  max_stock: number;
+  unit: string;
+  last_restocked: string;
+  price: number;
+  status: 'ok' | 'low' | 'out';
+}
+
+interface StockHistoryRecord {
+  id: string;
+  operation: string;
+  quantity: number;
+  previous_stock: number;
+  new_stock: number;
+  reason: string;
+  notes: string;
+  adjusted_by: string;
+  created_at: string;
+}
+
+interface AdjustStockRequest {
+  product_id?: string;
+  operation?: string;
+  quantity?: number;
+  reason?: string;
+  notes?: string;
+  branch_id?: string;
+}
+
+// Product stock is counted in pieces
+const INVENTORY_COLUMNS = sql`
+  p.id AS product_id, p.name AS product_name, c.name AS category_name,
+  COALESCE(i.current_stock, 0) AS current_stock,
+  COALESCE(i.minimum_stock, 10) AS min_stock,
+  COALESCE(i.maximum_stock, 100) AS max_stock,
+  COALESCE(i.last_restocked_at, p.created_at) AS last_restocked,
+  p.price,
+  CASE
+    WHEN COALESCE(i.current_stock, 0) = 0 THEN 'out'
+    WHEN COALESCE(i.current_stock, 0) < COALESCE(i.minimum_stock, 10) THEN 'low'
+    ELSE 'ok'
+  END AS status`;
+
+function formatInventoryItem(row: InventoryRow): InventoryItem {
+  return {
+    product_id: row.product_id,
+    product_name: row.product_name,
+    category_name: row.category_name,
+    current_stock: Number(row.current_stock),
+    min_stock: Number(row.min_stock),
+    max_stock: Number(row.max_stock),
+    unit: 'pcs',
+    last_restocked: row.last_restocked,
+    price: Number(row.price),
+    status: row.status,
+  };
+}
 
 // ── GetInventory ──────────────────────────────────────────────────────────
 
 export async function getInventory(c: Context) {
   try {
-    const rows = await db.execute<{
-      product_id: string;
-      product_name: string;
-      category_name: string;
-      current_stock: number;
-      min_stock: number;
-      max_stock: number;
-      unit: string;
-      last_restocked: string;
-      price: string;
-      status: string;
-    }>(sql`
-      SELECT
-        p.id as product_id, p.name as product_name, c.name as category_name,
-        COALESCE(i.current_stock, 0) as current_stock,
-        COALESCE(i.minimum_stock, 10) as min_stock,
-        COALESCE(i.maximum_stock, 100) as max_stock,
-        'pcs' as unit,
-        COALESCE(i.last_restocked_at, p.created_at) as last_restocked,
-        p.price,
-        CASE
-          WHEN COALESCE(i.current_stock, 0) = 0 THEN 'out'
-          WHEN COALESCE(i.current_stock, 0) < COALESCE(i.minimum_stock, 10) THEN 'low'
-          ELSE 'ok'
-        END as status
+    const branch = await inventoryBranch(c);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+
+    const rows = await db.execute<InventoryRow>(sql`
+      SELECT ${INVENTORY_COLUMNS}
       FROM products p
       LEFT JOIN categories c ON p.category_id = c.id
-      LEFT JOIN inventory i ON p.id = i.product_id
-      WHERE p.is_available = true
+      LEFT JOIN inventory i ON p.id = i.product_id AND i.branch_id = ${branch.branchId}
+      WHERE p.is_available = true AND p.deleted_at IS NULL
       ORDER BY status DESC, c.name, p.name
     `);
 
-    const items = rows.rows.map((row) => ({
-      product_id: row.product_id,
-      product_name: row.product_name,
-      category_name: row.category_name,
-      current_stock: Number(row.current_stock),
-      min_stock: Number(row.min_stock),
-      max_stock: Number(row.max_stock),
-      unit: row.unit,
-      last_restocked: row.last_restocked,
-      price: Number(row.price),
-      status: row.status,
-    }));
-
-    // Return raw array (matches Go behavior)
-    return c.json(items, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch inventory' }, 500);
+    return successResponse(c, 'Inventory retrieved successfully', rows.rows.map(formatInventoryItem));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch inventory', (err as Error).message);
   }
 }
 
@@ -62,163 +120,138 @@ export async function getInventory(c: Context) {
 
 export async function getProductInventory(c: Context) {
   const productId = c.req.param('product_id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
 
   try {
-    const rows = await db.execute<{
-      product_id: string;
-      product_name: string;
-      category_name: string;
-      current_stock: number;
-      min_stock: number;
-      max_stock: number;
-      unit: string;
-      last_restocked: string;
-      price: string;
-      status: string;
-    }>(sql`
-      SELECT
-        p.id as product_id, p.name as product_name, c.name as category_name,
-        COALESCE(i.current_stock, 0) as current_stock,
-        COALESCE(i.minimum_stock, 10) as min_stock,
-        COALESCE(i.maximum_stock, 100) as max_stock,
-        COALESCE(i.unit_cost, '0') as unit,
-        COALESCE(i.last_restocked_at, p.created_at) as last_restocked,
-        p.price,
-        CASE
-          WHEN COALESCE(i.current_stock, 0) = 0 THEN 'out'
-          WHEN COALESCE(i.current_stock, 0) < COALESCE(i.minimum_stock, 10) THEN 'low'
-          ELSE 'ok'
-        END as status
+    const branch = await inventoryBranch(c);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+
+    const rows = await db.execute<InventoryRow>(sql`
+      SELECT ${INVENTORY_COLUMNS}
       FROM products p
       LEFT JOIN categories c ON p.category_id = c.id
-      LEFT JOIN inventory i ON p.id = i.product_id
+      LEFT JOIN inventory i ON p.id = i.product_id AND i.branch_id = ${branch.branchId}
       WHERE p.id = ${productId}
     `);
 
     if (rows.rows.length === 0) {
-      return c.json({ error: 'Product not found' }, 404);
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
     }
-
-    const row = rows.rows[0];
-    return c.json({
-      product_id: row.product_id,
-      product_name: row.product_name,
-      category_name: row.category_name,
-      current_stock: Number(row.current_stock),
-      min_stock: Number(row.min_stock),
-      max_stock: Number(row.max_stock),
-      unit: 'pcs',
-      last_restocked: row.last_restocked,
-      price: Number(row.price),
-      status: row.status,
-    }, 200);
-  } catch {
-    return c.json({ error: 'Product not found' }, 404);
+    return successResponse(c, 'Product inventory retrieved successfully', formatInventoryItem(rows.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch product inventory', (err as Error).message);
   }
 }
 
 // ── AdjustStock ──────────────────────────────────────────────────────────
 
 export async function adjustStock(c: Context) {
-  let body: {
-    product_id: string;
-    operation: string;
-    quantity: number;
-    reason: string;
-    notes?: string;
-  };
+  let body: AdjustStockRequest;
 
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
   if (!body.product_id) {
-    return c.json({ error: 'product_id is required' }, 400);
+    return errorResponse(c, 'product_id is required', 'missing_product_id', 400);
   }
   if (!body.operation) {
-    return c.json({ error: 'operation is required' }, 400);
+    return errorResponse(c, 'operation is required', 'missing_operation', 400);
   }
-  if (!body.quantity || body.quantity <= 0) {
-    return c.json({ error: 'quantity must be greater than 0' }, 400);
+  if (typeof body.quantity !== 'number' || !(body.quantity > 0)) {
+    return errorResponse(c, 'quantity must be greater than 0', 'invalid_quantity', 400);
   }
   if (!body.reason) {
-    return c.json({ error: 'reason is required' }, 400);
+    return errorResponse(c, 'reason is required', 'missing_reason', 400);
   }
 
   // Validate operation
   if (body.operation !== 'add' && body.operation !== 'remove') {
-    return c.json({ error: "Operation must be 'add' or 'remove'" }, 400);
+    return errorResponse(c, "Operation must be 'add' or 'remove'", 'invalid_operation', 400);
   }
 
   // Validate reason
-  const validReasons = ['purchase', 'sale', 'spoilage', 'manual_adjustment', 'inventory_count', 'return', 'damage', 'theft', 'expired'];
-  if (!validReasons.includes(body.reason)) {
-    return c.json({ error: 'Invalid reason' }, 400);
+  if (!INVENTORY_ADJUST_REASONS.includes(body.reason)) {
+    return errorResponse(c, 'Invalid reason', 'invalid_reason', 400);
+  }
+  if (!isUUID(body.product_id)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
   }
 
+  const productId = body.product_id;
+  const quantity = body.quantity;
   const userId = c.get('user_id');
 
-  const client = await pool.connect();
+  let branchId: string;
   try {
-    await client.query('BEGIN');
-
-    // Get or create inventory record
-    let currentStock = 0;
-    const checkRes = await client.query(
-      'SELECT id, current_stock FROM inventory WHERE product_id = $1',
-      [body.product_id],
-    );
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), body.branch_id);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    branchId = branch.branchId;
+  } catch (err) {
+    return errorResponse(c, 'Failed to adjust stock', (err as Error).message);
+  }
 
-    if (checkRes.rows.length === 0) {
-      // Create new inventory record
-      await client.query(
-        'INSERT INTO inventory (product_id, current_stock, minimum_stock, maximum_stock) VALUES ($1, 0, 10, 100)',
-        [body.product_id],
+  try {
+    const result = await withTransaction(async (client) => {
+      // Get or create inventory record
+      let currentStock = 0;
+      const checkRes = await client.query(
+        'SELECT id, current_stock FROM inventory WHERE product_id = $1 AND branch_id = $2 FOR UPDATE',
+        [productId, branchId],
       );
-    } else {
-      currentStock = Number(checkRes.rows[0].current_stock);
-    }
 
-    // Calculate new stock
-    const previousStock = currentStock;
-    let newStock: number;
-    if (body.operation === 'add') {
-      newStock = currentStock + body.quantity;
-    } else {
-      newStock = currentStock - body.quantity;
-      if (newStock < 0) {
-        await client.query('ROLLBACK');
-        return c.json({ error: 'Insufficient stock' }, 400);
+      if (checkRes.rows.length === 0) {
+        // Create new inventory record
+        await client.query(
+          'INSERT INTO inventory (product_id, branch_id, current_stock, minimum_stock, maximum_stock) VALUES ($1, $2, 0, 10, 100)',
+          [productId, branchId],
+        );
+      } else {
+        currentStock = Number(checkRes.rows[0].current_stock);
       }
-    }
 
-    // Update inventory
-    await client.query(
-      'UPDATE inventory SET current_stock = $1, last_restocked_at = NOW(), updated_at = NOW() WHERE product_id = $2',
-      [newStock, body.product_id],
-    );
+      // Calculate new stock
+      const previousStock = currentStock;
+      let newStock: number;
+      if (body.operation === 'add') {
+        newStock = currentStock + quantity;
+      } else {
+        newStock = currentStock - quantity;
+        if (newStock < 0) {
+          return txFailure('Insufficient stock', 'insufficient_stock', 400);
+        }
+      }
 
-    // Create history record
-    await client.query(
-      `INSERT INTO inventory_history (product_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
-       VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-      [body.product_id, body.operation, body.quantity, previousStock, newStock, body.reason, body.notes || null, userId],
-    );
+      // Update inventory
+      await client.query(
+        'UPDATE inventory SET current_stock = $1, last_restocked_at = NOW(), updated_at = NOW() WHERE product_id = $2 AND branch_id = $3',
+        [newStock, productId, branchId],
+      );
 
-    await client.query('COMMIT');
+      // Create history record
+      await client.query(
+        `INSERT INTO inventory_history (product_id, branch_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+        [productId, branchId, body.operation, quantity, previousStock, newStock, body.reason, body.notes || null, userId],
+      );
 
-    return c.json({
-      message: 'Stock adjusted successfully',
-      previous_stock: previousStock,
-      new_stock: newStock,
-    }, 200);
-  } catch {
-    await client.query('ROLLBACK');
-    return c.json({ error: 'Failed to adjust stock' }, 500);
-  } finally {
-    client.release();
+      return { ok: true as const, previousStock, newStock };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    await refreshStockAvailability({ productIds: [productId] });
+
+    return successResponse(c, 'Stock adjusted successfully', {
+      product_id: productId,
+      branch_id: branchId,
+      previous_stock: result.previousStock,
+      new_stock: result.newStock,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to adjust stock', (err as Error).message);
   }
 }
 
@@ -226,55 +259,22 @@ export async function adjustStock(c: Context) {
 
 export async function getLowStock(c: Context) {
   try {
-    const rows = await db.execute<{
-      product_id: string;
-      product_name: string;
-      category_name: string;
-      current_stock: number;
-      min_stock: number;
-      max_stock: number;
-      unit: string;
-      last_restocked: string;
-      price: string;
-      status: string;
-    }>(sql`
-      SELECT
-        p.id as product_id, p.name as product_name, c.name as category_name,
-        COALESCE(i.current_stock, 0) as current_stock,
-        COALESCE(i.minimum_stock, 10) as min_stock,
-        COALESCE(i.maximum_stock, 100) as max_stock,
-        'pcs' as unit,
-        COALESCE(i.last_restocked_at, p.created_at) as last_restocked,
-        p.price,
-        CASE
-          WHEN COALESCE(i.current_stock, 0) = 0 THEN 'out'
-          ELSE 'low'
-        END as status
+    const branch = await inventoryBranch(c);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+
+    const rows = await db.execute<InventoryRow>(sql`
+      SELECT ${INVENTORY_COLUMNS}
       FROM products p
       LEFT JOIN categories c ON p.category_id = c.id
-      LEFT JOIN inventory i ON p.id = i.product_id
-      WHERE p.is_available = true
+      LEFT JOIN inventory i ON p.id = i.product_id AND i.branch_id = ${branch.branchId}
+      WHERE p.is_available = true AND p.deleted_at IS NULL
         AND COALESCE(i.current_stock, 0) < COALESCE(i.minimum_stock, 10)
       ORDER BY COALESCE(i.current_stock, 0) ASC, p.name
     `);
 
-    const items = rows.rows.map((row) => ({
-      product_id: row.product_id,
-      product_name: row.product_name,
-      category_name: row.category_name,
-      current_stock: Number(row.current_stock),
-      min_stock: Number(row.min_stock),
-      max_stock: Number(row.max_stock),
-      unit: row.unit,
-      last_restocked: row.last_restocked,
-      price: Number(row.price),
-      status: row.status,
-    }));
-
-    // Return raw array (matches Go behavior)
-    return c.json(items, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch low stock items' }, 500);
+    return successResponse(c, 'Low stock items retrieved successfully', rows.rows.map(formatInventoryItem));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch low stock items', (err as Error).message);
   }
 }
 
@@ -282,8 +282,14 @@ export async function getLowStock(c: Context) {
 
 export async function getStockHistory(c: Context) {
   const productId = c.req.param('product_id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
 
   try {
+    const branch = await inventoryBranch(c);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+
     const rows = await db.execute<{
       id: string;
       operation: string;
@@ -302,12 +308,12 @@ export async function getStockHistory(c: Context) {
         ih.created_at
       FROM inventory_history ih
       LEFT JOIN users u ON ih.adjusted_by = u.id
-      WHERE ih.product_id = ${productId}
+      WHERE ih.product_id = ${productId} AND ih.branch_id = ${branch.branchId}
       ORDER BY ih.created_at DESC
       LIMIT 100
     `);
 
-    const history = rows.rows.map((row) => ({
+    const history: StockHistoryRecord[] = rows.rows.map((row) => ({
       id: row.id,
       operation: row.operation,
       quantity: Number(row.quantity),
@@ -319,9 +325,177 @@ export async function getStockHistory(c: Context) {
       created_at: row.created_at,
     }));
 
-    // Return raw array (matches Go behavior)
-    return c.json(history, 200);
-  } catch {
-    return c.json({ error: 'Failed to fetch history' }, 500);
+    return successResponse(c, 'Stock history retrieved successfully', history);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch history', (err as Error).message);
+  }
+}
+
+// ── GetInventoryLedger ───────────────────────────────────────────────────
+// Every stock movement of a product at a branch, oldest first, each with the
+// balance it left. The balance runs from the stock before the first recorded
+// movement; recorded_stock is what the movement itself wrote, so a row where
+// the two differ points at a change made outside the history. Filters:
+// from / to (local dates) and type. opening_balance and closing_balance
+// bracket the date range whatever the type filter.
+
+const LEDGER_TYPES = ['sale', 'return', 'restock', 'waste', 'transfer', 'adjustment'];
+
+const MOVEMENT_TYPE = `
+  CASE
+    WHEN ih.reason = 'sale' THEN 'sale'
+    WHEN ih.reason = 'return' THEN 'return'
+    WHEN ih.reason = 'purchase' THEN 'restock'
+    WHEN ih.reason IN ('spoilage', 'damage', 'expired', 'theft', 'waste') THEN 'waste'
+    WHEN ih.reason LIKE 'transfer%' THEN 'transfer'
+    ELSE 'adjustment'
+  END`;
+
+const SIGNED_QUANTITY = "CASE WHEN ih.operation = 'add' THEN ih.quantity ELSE -ih.quantity END";
+
+const LEDGER = `
+  WITH ledger AS (
+    SELECT ih.id, ih.operation, ih.quantity, ih.reason, ih.notes, ih.order_id, ih.adjusted_by,
+           ih.new_stock AS recorded_stock, ih.created_at,
+           DATE(ih.created_at AT TIME ZONE $3) AS local_date,
+           ${MOVEMENT_TYPE} AS movement_type,
+           ${SIGNED_QUANTITY} AS change,
+           FIRST_VALUE(ih.previous_stock) OVER w + SUM(${SIGNED_QUANTITY}) OVER w AS running_balance
+    FROM inventory_history ih
+    WHERE ih.product_id = $1 AND ih.branch_id = $2
+    WINDOW w AS (ORDER BY ih.created_at, ih.id)
+  )`;
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+export async function getInventoryLedger(c: Context) {
+  const productId = c.req.param('product_id');
+  if (!isUUID(productId)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+
+  const from = c.req.query('from') || '';
+  const to = c.req.query('to') || '';
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to)) || (from && to && from > to)) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+  const type = c.req.query('type') || '';
+  if (type && !LEDGER_TYPES.includes(type)) {
+    return errorResponse(c, `type must be one of: ${LEDGER_TYPES.join(', ')}`, 'invalid_ledger_type', 400);
+  }
+  const pagination = parsePagination(c.req.query());
+
+  try {
+    const branch = await inventoryBranch(c);
+    if (!branch.ok) return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+
+    const product = await pool.query(
+      `SELECT p.name, COALESCE(i.current_stock, 0) AS current_stock
+       FROM products p
+       LEFT JOIN inventory i ON i.product_id = p.id AND i.branch_id = $2
+       WHERE p.id = $1`,
+      [productId, branch.branchId],
+    );
+    if (product.rows.length === 0) {
+      return errorResponse(c, 'Product not found', 'product_not_found', 404);
+    }
+    const currentStock = Number(product.rows[0].current_stock);
+
+    const params: unknown[] = [productId, branch.branchId, RESTAURANT_TIMEZONE];
+    let range = 'TRUE';
+    if (from) {
+      params.push(from);
+      range += ` AND l.local_date >= $${params.length}`;
+    }
+    if (to) {
+      params.push(to);
+      range += ` AND l.local_date <= $${params.length}`;
+    }
+    const rangeParams = [...params];
+    let where = range;
+    if (type) {
+      params.push(type);
+      where += ` AND l.movement_type = $${params.length}`;
+    }
+
+    const balances = await pool.query(
+      `${LEDGER}
+       SELECT
+         (SELECT l.running_balance - l.change FROM ledger l WHERE ${range}
+          ORDER BY l.created_at, l.id LIMIT 1) AS opening_in_range,
+         (SELECT l.running_balance FROM ledger l WHERE ${from ? 'l.local_date < $4' : 'FALSE'}
+          ORDER BY l.created_at DESC, l.id DESC LIMIT 1) AS before_range,
+         (SELECT l.running_balance - l.change FROM ledger l ORDER BY l.created_at, l.id LIMIT 1) AS first_previous,
+         (SELECT l.running_balance FROM ledger l WHERE ${range}
+          ORDER BY l.created_at DESC, l.id DESC LIMIT 1) AS closing_in_range,
+         (SELECT COALESCE(json_object_agg(t.movement_type, t.net), '{}')
+          FROM (SELECT l.movement_type, SUM(l.change)::int AS net FROM ledger l WHERE ${range}
+                GROUP BY l.movement_type) t) AS net_by_type`,
+      rangeParams,
+    );
+    // With no movement in the range the balance is what the last one before
+    // it left, or the stock before the first one, or with no history at all
+    // the current stock
+    const b = balances.rows[0];
+    const openingBalance = Number(b.opening_in_range ?? b.before_range ?? b.first_previous ?? currentStock);
+    const closingBalance = b.closing_in_range !== null ? Number(b.closing_in_range) : openingBalance;
+
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`${LEDGER} SELECT COUNT(*) FROM ledger l WHERE ${where}`, params);
+        return parseInt(countRes.rows[0].count, 10);
+      },
+      async (limit) => {
+        const rows = await pool.query(
+          `${LEDGER}
+           SELECT l.id, l.movement_type, l.operation, l.quantity, l.change, l.running_balance, l.recorded_stock,
+                  l.reason, COALESCE(l.notes, '') AS notes, l.order_id,
+                  COALESCE(u.username, 'System') AS adjusted_by, l.created_at
+           FROM ledger l
+           LEFT JOIN users u ON u.id = l.adjusted_by
+           WHERE ${where}
+           ORDER BY l.created_at ASC, l.id ASC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return rows.rows;
+      },
+    );
+
+    const movements = result.rows.map((row) => ({
+      id: row.id,
+      type: row.movement_type,
+      operation: row.operation,
+      quantity: Number(row.quantity),
+      change: Number(row.change),
+      running_balance: Number(row.running_balance),
+      recorded_stock: Number(row.recorded_stock),
+      reason: row.reason,
+      notes: row.notes,
+      order_id: row.order_id,
+      adjusted_by: row.adjusted_by,
+      created_at: row.created_at,
+    }));
+
+    return successResponse(c, 'Inventory ledger retrieved successfully', {
+      product_id: productId,
+      product_name: product.rows[0].name,
+      branch_id: branch.branchId,
+      from: from || null,
+      to: to || null,
+      type: type || null,
+      current_stock: currentStock,
+      opening_balance: openingBalance,
+      closing_balance: closingBalance,
+      net_by_type: b.net_by_type,
+      movements,
+      meta: pageMeta(pagination, result, {
+        sort: 'created_at:asc',
+        filters: { from, to, type, branch_id: branch.branchId },
+      }),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch inventory ledger', (err as Error).message);
   }
 }
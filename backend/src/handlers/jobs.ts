@@ -0,0 +1,147 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { isUUID } from '../services/branches.js';
+import { closeDeadLetters } from '../services/dead-letters.js';
+
+const JOB_STATUSES = ['pending', 'running', 'succeeded', 'failed'];
+
+const JOB_SELECT = `
+  SELECT id, type, payload, status, attempts, max_attempts, run_at, locked_at, locked_by,
+         last_error, created_at, updated_at, completed_at
+  FROM jobs`;
+
+// ── GetJobs ─────────────────────────────────────────────────────────────────
+// Newest first; ?status=failed lists the jobs that ran out of attempts.
+
+export async function getJobs(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status');
+  const type = c.req.query('type');
+
+  if (status && !JOB_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${JOB_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (status) {
+    conditions.push(`status = $${paramIdx}`);
+    params.push(status);
+    paramIdx++;
+  }
+  if (type) {
+    conditions.push(`type = $${paramIdx}`);
+    params.push(type);
+    paramIdx++;
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM jobs ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${JOB_SELECT} ${where}
+           ORDER BY created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+
+    return paginatedResponse(c, 'Jobs retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { status, type },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch jobs', (err as Error).message);
+  }
+}
+
+// ── GetJobStats ─────────────────────────────────────────────────────────────
+// Queue depth per type and status, for a glance at whether the worker keeps up.
+
+export async function getJobStats(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT type, status, COUNT(*) AS count, MIN(run_at) AS oldest_run_at
+       FROM jobs
+       GROUP BY type, status
+       ORDER BY type ASC, status ASC`,
+    );
+
+    const stats = res.rows.map((row: Record<string, unknown>) => ({
+      type: row.type,
+      status: row.status,
+      count: Number(row.count),
+      oldest_run_at: row.oldest_run_at,
+    }));
+
+    return successResponse(c, 'Job stats retrieved successfully', stats);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch job stats', (err as Error).message);
+  }
+}
+
+// ── GetJob ──────────────────────────────────────────────────────────────────
+
+export async function getJob(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Job not found', 'job_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(`${JOB_SELECT} WHERE id = $1`, [id]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Job not found', 'job_not_found', 404);
+    }
+    return successResponse(c, 'Job retrieved successfully', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch job', (err as Error).message);
+  }
+}
+
+// ── RetryJob ────────────────────────────────────────────────────────────────
+// Puts a failed job back on the queue with a fresh set of attempts.
+
+export async function retryJob(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Job not found', 'job_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      `UPDATE jobs
+       SET status = 'pending', attempts = 0, run_at = NOW(), completed_at = NULL, updated_at = NOW()
+       WHERE id = $1 AND status = 'failed'
+       RETURNING id`,
+      [id],
+    );
+    if (res.rows.length === 0) {
+      const exists = await pool.query('SELECT status FROM jobs WHERE id = $1', [id]);
+      if (exists.rows.length === 0) {
+        return errorResponse(c, 'Job not found', 'job_not_found', 404);
+      }
+      return errorResponse(c, `Only failed jobs can be retried; this job is ${exists.rows[0].status}`, 'invalid_job_status', 400);
+    }
+    await closeDeadLetters(pool, { jobId: id }, userId ?? null);
+
+    const updated = await pool.query(`${JOB_SELECT} WHERE id = $1`, [id]);
+    return successResponse(c, 'Job queued for retry', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to retry job', (err as Error).message);
+  }
+}
@@ -1,77 +1,198 @@
 import type { Context } from 'hono';
-import { sql } from 'drizzle-orm';
-import { db, pool } from '../db/connection.js';
-import { successResponse, errorResponse } from '../lib/response.js';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, buildCursorMeta, encodeCursor, decodeCursor, isTimestampKey } from '../lib/pagination.js';
+import { computeKitchenLoad } from '../services/wait-time.js';
+import { getDefaultBranchId, resolveBranchScope, isUUID } from '../services/branches.js';
+import { KITCHEN_STATIONS, isKitchenStation } from '../services/kitchen-routing.js';
+import { ORDER_ITEM_STATUSES } from '../services/data-model.js';
+import { completedCourse } from '../services/courses.js';
+import { NOTE_PHRASE_CODE_RE, notePhraseFlagsSQL, notePhraseLabelsSQL } from '../services/note-phrases.js';
+import { courseReadyDuration } from '../lib/metrics.js';
+import {
+  KITCHEN_DISPLAY_COLUMNS,
+  KITCHEN_DISPLAY_JOIN,
+  KITCHEN_GROUPINGS,
+  groupTicketItems,
+  type KitchenDisplayItem,
+  isDisplayColor,
+  isKitchenGrouping,
+  loadKitchenDisplayRules,
+} from '../services/kitchen-display.js';
 
 // ── GetKitchenOrders ──────────────────────────────────────────────────────────
+// ?station=kitchen|bar shows one station's items, in display priority order. Items held for acceptance
+// or in a course that hasn't been fired are left out; an order only appears once something on it is released.
+// ?note_phrase=no_onion narrows the board to the items given that quick phrase. Items given a flagged phrase
+// carry its kitchen_flags; a ticket with an allergy item has has_allergy_alert.
+// Every active ticket is returned unless ?per_page= or ?cursor= asks for a
+// page; meta.next_cursor continues after the last ticket of one.
 
 export async function getKitchenOrders(c: Context) {
   const status = c.req.query('status') || 'all';
+  const station = c.req.query('station') || null;
+  if (station && !isKitchenStation(station)) {
+    return errorResponse(c, `Station must be one of: ${KITCHEN_STATIONS.join(', ')}`, 'invalid_station', 400);
+  }
+  const notePhrase = c.req.query('note_phrase') || null;
+  if (notePhrase && !NOTE_PHRASE_CODE_RE.test(notePhrase)) {
+    return errorResponse(c, 'Invalid note phrase code', 'invalid_note_phrase', 400);
+  }
+
+  const cursorParam = c.req.query('cursor');
+  const paged = cursorParam !== undefined || c.req.query('per_page') !== undefined;
+  const { perPage } = parsePagination({ per_page: c.req.query('per_page') });
+  let cursor: string[] | null = null;
+  if (cursorParam) {
+    cursor = decodeCursor(cursorParam, 2);
+    if (!cursor || !isTimestampKey(cursor[0]) || !isUUID(cursor[1])) {
+      return errorResponse(c, 'Invalid cursor', 'invalid_cursor', 400);
+    }
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
 
   try {
     let query = `
-      SELECT DISTINCT o.id::text, o.order_number, o.table_id::text, o.order_type, o.status,
-             o.created_at, o.customer_name,
-             t.table_number
+      SELECT o.id::text, o.order_number, o.table_id::text, o.order_type, o.status,
+             o.created_at, o.scheduled_at, COALESCE(o.scheduled_at, o.created_at) AS due_at,
+             COALESCE(o.scheduled_at, o.created_at)::text AS due_at_key, o.customer_name,
+             t.table_number,
+             (SELECT COUNT(*) FROM order_items h WHERE h.order_id = o.id AND h.released_at IS NULL) AS held_item_count,
+             (SELECT array_agg(hc.course ORDER BY hc.course) FROM order_courses hc
+              WHERE hc.order_id = o.id AND hc.fired_at IS NULL) AS held_courses
       FROM orders o
       LEFT JOIN dining_tables t ON o.table_id = t.id
       WHERE o.status IN ('pending', 'confirmed', 'preparing', 'ready')
     `;
 
-    const params: string[] = [];
+    const params: (string | number)[] = [];
+    let released = `SELECT 1 FROM order_items ri
+      LEFT JOIN products rp ON rp.id = ri.product_id
+      LEFT JOIN categories rc ON rc.id = rp.category_id
+      WHERE ri.order_id = o.id AND ri.released_at IS NOT NULL`;
+    if (station) {
+      params.push(station);
+      released += ` AND COALESCE(rc.station, 'kitchen') = $${params.length}`;
+    }
+    if (notePhrase) {
+      params.push(notePhrase);
+      released += ` AND ri.note_phrases @> ARRAY[$${params.length}]::text[]`;
+    }
+    query += ` AND EXISTS (${released})`;
+    if (scope.branchId) {
+      params.push(scope.branchId);
+      query += ` AND o.branch_id = $${params.length}`;
+    }
     if (status !== 'all') {
       params.push(status);
       query += ` AND o.status = $${params.length}`;
     }
+    if (cursor) {
+      params.push(cursor[0], cursor[1]);
+      query += ` AND (COALESCE(o.scheduled_at, o.created_at), o.id) > ($${params.length - 1}::timestamptz, $${params.length}::uuid)`;
+    }
 
-    query += ` ORDER BY o.created_at ASC`;
+    // Released scheduled orders queue by their pickup time, not when they were placed
+    query += ` ORDER BY due_at ASC, o.id ASC`;
+    if (paged) {
+      params.push(perPage + 1);
+      query += ` LIMIT $${params.length}`;
+    }
 
     const orderRes = await pool.query(query, params);
+    const rows = paged ? orderRes.rows.slice(0, perPage) : orderRes.rows;
+    const last = rows[rows.length - 1];
+    const nextCursor = paged && orderRes.rows.length > perPage ? encodeCursor([last.due_at_key, last.id]) : null;
+
+    // All the tickets' items in one query
+    const itemRes = await pool.query(
+      `SELECT oi.id, oi.order_id::text, oi.product_id, oi.quantity, oi.weight_grams, oi.special_instructions, oi.status,
+              oi.course, p.name as product_name, p.description as product_description, p.sale_unit,
+              oi.note_phrases, ${notePhraseLabelsSQL('oi.note_phrases')} AS note_phrase_labels,
+              ${notePhraseFlagsSQL('oi.note_phrases')} AS kitchen_flags,
+              EXISTS (
+                SELECT 1 FROM order_item_changes ch WHERE ch.order_item_id = oi.id AND ch.action = 'add'
+              ) as is_addition,
+              oi.is_remake, rm.reason_type as remake_reason_type, rm.reason as remake_reason,
+              COALESCE(cat.station, 'kitchen') as station,
+              ${KITCHEN_DISPLAY_COLUMNS}
+       FROM order_items oi
+       LEFT JOIN products p ON oi.product_id = p.id
+       LEFT JOIN categories cat ON p.category_id = cat.id
+       LEFT JOIN order_item_remakes rm ON rm.remake_item_id = oi.id
+       ${KITCHEN_DISPLAY_JOIN}
+       WHERE oi.order_id = ANY($1::uuid[]) AND oi.released_at IS NOT NULL
+         AND ($2::text IS NULL OR COALESCE(cat.station, 'kitchen') = $2)
+         AND ($3::text IS NULL OR oi.note_phrases @> ARRAY[$3]::text[])
+       ORDER BY oi.course ASC, sort_priority ASC, oi.created_at ASC`,
+      [rows.map((row) => row.id), station, notePhrase],
+    );
 
-    const orders: Record<string, unknown>[] = [];
-
-    for (const row of orderRes.rows) {
-      // Fetch order items for this order
-      const itemRes = await db.execute<{
-        id: string;
-        product_id: string;
-        quantity: number;
-        special_instructions: string | null;
-        status: string | null;
-        product_name: string | null;
-        product_description: string | null;
-      }>(sql`
-        SELECT oi.id, oi.product_id, oi.quantity, oi.special_instructions, oi.status,
-               p.name as product_name, p.description as product_description
-        FROM order_items oi
-        LEFT JOIN products p ON oi.product_id = p.id
-        WHERE oi.order_id = ${row.id}
-        ORDER BY oi.created_at ASC
-      `);
-
-      const items = itemRes.rows.map((item) => ({
+    const itemsByOrder = new Map<string, (KitchenDisplayItem & Record<string, unknown>)[]>();
+    for (const item of itemRes.rows) {
+      const list = itemsByOrder.get(item.order_id) ?? [];
+      list.push({
         id: item.id,
         product_id: item.product_id,
-        quantity: item.quantity,
+        quantity: Number(item.quantity),
+        sale_unit: item.sale_unit ?? 'each',
+        weight_grams: item.weight_grams === null ? null : Number(item.weight_grams),
         special_instructions: item.special_instructions ?? '',
+        // Quick phrases picked for the item, with their labels in the same order
+        note_phrases: item.note_phrases,
+        note_phrase_labels: item.note_phrase_labels,
+        // From the phrases' kitchen flags; allergy items are shown as alerts
+        kitchen_flags: item.kitchen_flags,
+        allergy_alert: item.kitchen_flags.includes('allergy'),
         status: item.status ?? '',
+        course: Number(item.course),
         product_name: item.product_name ?? '',
         product_description: item.product_description ?? '',
-      }));
-
-      orders.push({
-        id: row.id,
-        order_number: row.order_number ?? '',
-        table_id: row.table_id ?? null,
-        table_number: row.table_number ?? '',
-        order_type: row.order_type ?? '',
-        status: row.status ?? '',
-        customer_name: row.customer_name ?? '',
-        created_at: row.created_at,
-        items,
+        // Added after the ticket was first sent
+        is_addition: item.is_addition,
+        // Sent back by the customer; remake_reason_type says why
+        is_remake: item.is_remake,
+        remake_reason_type: item.remake_reason_type ?? null,
+        remake_reason: item.remake_reason ?? null,
+        station: item.station,
+        // From the category, falling back to its station's display rules
+        display_color: item.display_color ?? null,
+        sort_priority: Number(item.sort_priority),
+        display_group: item.display_group ?? null,
       });
+      itemsByOrder.set(item.order_id, list);
     }
 
+    const orders = rows.map((row) => ({
+      id: row.id,
+      order_number: row.order_number ?? '',
+      table_id: row.table_id ?? null,
+      table_number: row.table_number ?? '',
+      order_type: row.order_type ?? '',
+      status: row.status ?? '',
+      customer_name: row.customer_name ?? '',
+      created_at: row.created_at,
+      scheduled_at: row.scheduled_at ?? null,
+      // Still waiting for the order to be accepted or their course to be fired
+      held_item_count: Number(row.held_item_count),
+      held_courses: (row.held_courses ?? []).map(Number),
+      has_allergy_alert: (itemsByOrder.get(row.id) ?? []).some((item) => item.allergy_alert),
+      items: itemsByOrder.get(row.id) ?? [],
+      // Headings for the items, in display order
+      groups: groupTicketItems(itemsByOrder.get(row.id) ?? []),
+    }));
+
+    if (paged) {
+      return paginatedResponse(c, 'Kitchen orders retrieved successfully', orders, buildCursorMeta(perPage, nextCursor, {
+        sort: 'due_at:asc',
+        filters: { status: status === 'all' ? undefined : status, station, note_phrase: notePhrase, branch_id: scope.branchId },
+      }));
+    }
     return successResponse(c, 'Kitchen orders retrieved successfully', orders);
   } catch (err) {
     return errorResponse(c, 'Failed to fetch kitchen orders', (err as Error).message);
@@ -83,6 +204,7 @@ export async function getKitchenOrders(c: Context) {
 export async function updateOrderItemStatus(c: Context) {
   const orderID = c.req.param('id');
   const itemID = c.req.param('item_id');
+  const userId = c.get('user_id');
 
   let body: { status: string };
   try {
@@ -94,16 +216,143 @@ export async function updateOrderItemStatus(c: Context) {
   if (!body.status) {
     return errorResponse(c, 'Status is required', 'missing_status', 400);
   }
+  if (!ORDER_ITEM_STATUSES.includes(body.status)) {
+    return errorResponse(c, `Status must be one of: ${ORDER_ITEM_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+  if (!isUUID(orderID) || !isUUID(itemID)) {
+    return errorResponse(c, 'Order item not found or awaiting acceptance', 'order_item_not_found', 404);
+  }
 
   try {
-    await db.execute(sql`
-      UPDATE order_items
-      SET status = ${body.status}, updated_at = CURRENT_TIMESTAMP
-      WHERE id = ${itemID} AND order_id = ${orderID}
-    `);
+    const result = await withTransaction(async (client) => {
+      // Held items aren't on any station's screen yet
+      const current = await client.query(
+        `SELECT oi.status, COALESCE(cat.station, 'kitchen') AS station, o.branch_id
+         FROM order_items oi
+         JOIN orders o ON o.id = oi.order_id
+         LEFT JOIN products p ON p.id = oi.product_id
+         LEFT JOIN categories cat ON cat.id = p.category_id
+         WHERE oi.id = $1 AND oi.order_id = $2 AND oi.released_at IS NOT NULL
+         FOR UPDATE OF oi`,
+        [itemID, orderID],
+      );
+      const branchId = c.get('branch_id');
+      if (current.rows.length === 0 || (branchId && current.rows[0].branch_id !== branchId)) {
+        return txFailure('Order item not found or awaiting acceptance', 'order_item_not_found', 404);
+      }
+      const item = current.rows[0];
+
+      await client.query(
+        'UPDATE order_items SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2',
+        [body.status, itemID],
+      );
+      if (item.status !== body.status) {
+        await client.query(
+          `INSERT INTO order_item_status_history (order_id, order_item_id, previous_status, new_status, station, changed_by)
+           VALUES ($1, $2, $3, $4, $5, $6)`,
+          [orderID, itemID, item.status, body.status, item.station, userId],
+        );
+      }
 
+      // The item that finishes a fired course times the course
+      const finishing = ['ready', 'served'].includes(body.status) && !['ready', 'served'].includes(item.status);
+      const course = finishing ? await completedCourse(client, itemID) : null;
+
+      return { ok: true as const, item, course };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { course } = result;
+    if (course) {
+      courseReadyDuration.observe(course.seconds, { course: String(course.course) });
+    }
     return successResponse(c, 'Order item status updated successfully');
   } catch (err) {
     return errorResponse(c, 'Failed to update order item status', (err as Error).message);
   }
 }
+
+// ── GetKitchenLoad ────────────────────────────────────────────────────────────
+// Queue depth and the wait a new order can expect, for counters to quote.
+
+export async function getKitchenLoad(c: Context) {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    // Each branch has its own kitchen; head office sees the main one by default
+    const load = await computeKitchenLoad(pool, scope.branchId ?? await getDefaultBranchId(pool));
+    return successResponse(c, 'Kitchen load retrieved successfully', load);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch kitchen load', (err as Error).message);
+  }
+}
+
+// ── GetKitchenDisplayRules ────────────────────────────────────────────────────
+// Station defaults and each category's resolved color, priority and group.
+
+export async function getKitchenDisplayRules(c: Context) {
+  try {
+    const rules = await loadKitchenDisplayRules(pool);
+    return successResponse(c, 'Kitchen display rules retrieved successfully', rules);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch kitchen display rules', (err as Error).message);
+  }
+}
+
+// ── UpdateStationDisplay ──────────────────────────────────────────────────────
+
+export async function updateStationDisplay(c: Context) {
+  const station = c.req.param('station');
+  if (!isKitchenStation(station)) {
+    return errorResponse(c, `Station must be one of: ${KITCHEN_STATIONS.join(', ')}`, 'invalid_station', 400);
+  }
+
+  let body: { color?: string | null; sort_priority?: number; group_items_by?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.color !== undefined && body.color !== null && !isDisplayColor(body.color)) {
+    return errorResponse(c, 'Color must be a hex color like #DC2626', 'invalid_color', 400);
+  }
+  if (body.sort_priority !== undefined && !Number.isInteger(body.sort_priority)) {
+    return errorResponse(c, 'sort_priority must be a whole number', 'invalid_sort_priority', 400);
+  }
+  if (body.group_items_by !== undefined && !isKitchenGrouping(body.group_items_by)) {
+    return errorResponse(c, `group_items_by must be one of: ${KITCHEN_GROUPINGS.join(', ')}`, 'invalid_grouping', 400);
+  }
+  if (body.color === undefined && body.sort_priority === undefined && body.group_items_by === undefined) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    // A station without a row yet starts from the column defaults
+    const res = await pool.query(
+      `INSERT INTO kitchen_station_display (station, color, sort_priority, group_items_by, updated_by, updated_at)
+       VALUES ($1, $2, COALESCE($3, 0), COALESCE($4, 'category'), $5, NOW())
+       ON CONFLICT (station) DO UPDATE SET
+         color = CASE WHEN $6 THEN EXCLUDED.color ELSE kitchen_station_display.color END,
+         sort_priority = COALESCE($3, kitchen_station_display.sort_priority),
+         group_items_by = COALESCE($4, kitchen_station_display.group_items_by),
+         updated_by = EXCLUDED.updated_by,
+         updated_at = NOW()
+       RETURNING station, color, sort_priority, group_items_by, updated_by, updated_at`,
+      [
+        station,
+        body.color ?? null,
+        body.sort_priority ?? null,
+        body.group_items_by ?? null,
+        c.get('user_id') ?? null,
+        body.color !== undefined,
+      ],
+    );
+    const row = res.rows[0];
+    return successResponse(c, 'Station display updated successfully', { ...row, sort_priority: Number(row.sort_priority) });
+  } catch (err) {
+    return errorResponse(c, 'Failed to update station display', (err as Error).message);
+  }
+}
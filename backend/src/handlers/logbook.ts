@@ -0,0 +1,364 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock, addDays } from '../lib/clock.js';
+import {
+  LOGBOOK_CATEGORIES,
+  LOGBOOK_SHIFTS,
+  LOGBOOK_PRIORITIES,
+  LOGBOOK_SELECT,
+  formatLogbookEntry,
+  getDaySummary,
+  sendLogbookDigest,
+} from '../services/logbook.js';
+import { can } from '../middleware/roles.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+interface EntryBody {
+  entry_date?: string;
+  shift?: string | null;
+  category?: string;
+  title?: string;
+  body?: string;
+  tags?: string[];
+  priority?: string;
+  status?: string;
+}
+
+// Tags are stored lower-case without the leading '#', so searches match
+function normalizeTags(tags: string[]): string[] {
+  return [...new Set(tags.map((t) => t.trim().replace(/^#/, '').toLowerCase()).filter(Boolean))];
+}
+
+function validateEntry(body: EntryBody, partial: boolean): string | null {
+  if (!partial || body.category !== undefined) {
+    if (!LOGBOOK_CATEGORIES.includes(body.category ?? '')) return `Category must be one of: ${LOGBOOK_CATEGORIES.join(', ')}`;
+  }
+  if (!partial || body.title !== undefined) {
+    if (!body.title?.trim()) return 'Title is required';
+    if (body.title.length > 200) return 'Title must be at most 200 characters';
+  }
+  if (!partial || body.body !== undefined) {
+    if (!body.body?.trim()) return 'Body is required';
+  }
+  if (body.entry_date !== undefined && !DATE_RE.test(body.entry_date)) return 'Entry date must be YYYY-MM-DD';
+  if (body.shift !== undefined && body.shift !== null && !LOGBOOK_SHIFTS.includes(body.shift)) {
+    return `Shift must be one of: ${LOGBOOK_SHIFTS.join(', ')}`;
+  }
+  if (body.priority !== undefined && !LOGBOOK_PRIORITIES.includes(body.priority)) {
+    return `Priority must be one of: ${LOGBOOK_PRIORITIES.join(', ')}`;
+  }
+  if (body.status !== undefined && !['open', 'resolved'].includes(body.status)) return 'Status must be open or resolved';
+  if (body.tags !== undefined && (!Array.isArray(body.tags) || body.tags.some((t) => typeof t !== 'string'))) {
+    return 'Tags must be a list of strings';
+  }
+  return null;
+}
+
+// ── GetLogbookEntries ───────────────────────────────────────────────────────
+// Filters: q (title/body text), category, shift, status, tag, from/to dates.
+
+export async function getLogbookEntries(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const search = c.req.query('q')?.trim();
+  const category = c.req.query('category');
+  const shift = c.req.query('shift');
+  const status = c.req.query('status');
+  const tag = c.req.query('tag');
+  const from = c.req.query('from');
+  const to = c.req.query('to');
+
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to))) {
+    return errorResponse(c, 'Dates must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (search) {
+    conditions.push(`(e.title ILIKE $${paramIdx} OR e.body ILIKE $${paramIdx})`);
+    params.push(`%${search}%`);
+    paramIdx++;
+  }
+  if (category) {
+    conditions.push(`e.category = $${paramIdx}`);
+    params.push(category);
+    paramIdx++;
+  }
+  if (shift) {
+    conditions.push(`e.shift = $${paramIdx}`);
+    params.push(shift);
+    paramIdx++;
+  }
+  if (status) {
+    conditions.push(`e.status = $${paramIdx}`);
+    params.push(status);
+    paramIdx++;
+  }
+  if (tag) {
+    conditions.push(`e.tags @> ARRAY[$${paramIdx}]::text[]`);
+    params.push(normalizeTags([tag])[0] ?? '');
+    paramIdx++;
+  }
+  if (from) {
+    conditions.push(`e.entry_date >= $${paramIdx}`);
+    params.push(from);
+    paramIdx++;
+  }
+  if (to) {
+    conditions.push(`e.entry_date <= $${paramIdx}`);
+    params.push(to);
+    paramIdx++;
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM logbook_entries e ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${LOGBOOK_SELECT} ${where}
+           ORDER BY e.entry_date DESC, e.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+
+    return paginatedResponse(c, 'Log book entries retrieved successfully', result.rows.map(formatLogbookEntry), pageMeta(pagination, result, {
+      sort: 'entry_date:desc',
+      filters: { q: search, category, shift, status, tag, from, to },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch log book entries', (err as Error).message);
+  }
+}
+
+// ── GetLogbookTags ──────────────────────────────────────────────────────────
+
+export async function getLogbookTags(c: Context) {
+  try {
+    const res = await pool.query(
+      `SELECT tag, COUNT(*) AS count
+       FROM logbook_entries, unnest(tags) AS tag
+       GROUP BY tag
+       ORDER BY count DESC, tag ASC`,
+    );
+    return successResponse(c, 'Log book tags retrieved successfully', res.rows.map((r) => ({ tag: r.tag, count: Number(r.count) })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch log book tags', (err as Error).message);
+  }
+}
+
+// ── GetLogbookDay ───────────────────────────────────────────────────────────
+// One business day: its entries grouped by shift plus the day's sales close.
+
+export async function getLogbookDay(c: Context) {
+  const date = c.req.param('date');
+  if (!DATE_RE.test(date)) {
+    return errorResponse(c, 'Date must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+
+  try {
+    const res = await pool.query(`${LOGBOOK_SELECT} WHERE e.entry_date = $1 ORDER BY e.created_at ASC`, [date]);
+    const entries = res.rows.map(formatLogbookEntry);
+
+    const shifts: Record<string, unknown[]> = {};
+    for (const entry of entries) {
+      const key = (entry.shift as string | null) ?? 'unassigned';
+      (shifts[key] ??= []).push(entry);
+    }
+
+    return successResponse(c, 'Log book day retrieved successfully', {
+      date,
+      day_close: await getDaySummary(pool, date),
+      entries,
+      shifts,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch log book day', (err as Error).message);
+  }
+}
+
+// ── CreateLogbookEntry ──────────────────────────────────────────────────────
+
+export async function createLogbookEntry(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: EntryBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateEntry(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid, 'validation_error', 400);
+  }
+
+  try {
+    const resolved = body.status === 'resolved';
+    const res = await pool.query(
+      `INSERT INTO logbook_entries
+         (entry_date, shift, category, title, body, tags, priority, status, resolved_at, resolved_by, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+       RETURNING id`,
+      [
+        body.entry_date ?? localClock().date,
+        body.shift ?? null,
+        body.category,
+        body.title!.trim(),
+        body.body!.trim(),
+        normalizeTags(body.tags ?? []),
+        body.priority ?? 'normal',
+        body.status ?? 'open',
+        resolved ? new Date() : null,
+        resolved ? userId : null,
+        userId,
+      ],
+    );
+
+    const created = await pool.query(`${LOGBOOK_SELECT} WHERE e.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Log book entry created successfully', formatLogbookEntry(created.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create log book entry', (err as Error).message);
+  }
+}
+
+// ── UpdateLogbookEntry ──────────────────────────────────────────────────────
+
+export async function updateLogbookEntry(c: Context) {
+  const entryId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: EntryBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateEntry(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid, 'validation_error', 400);
+  }
+
+  try {
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    const fields: [string, unknown][] = [
+      ['entry_date', body.entry_date],
+      ['shift', body.shift],
+      ['category', body.category],
+      ['title', body.title?.trim()],
+      ['body', body.body?.trim()],
+      ['tags', body.tags !== undefined ? normalizeTags(body.tags) : undefined],
+      ['priority', body.priority],
+    ];
+    for (const [column, value] of fields) {
+      if (value === undefined) continue;
+      setClauses.push(`${column} = $${paramIdx}`);
+      params.push(value);
+      paramIdx++;
+    }
+
+    if (body.status !== undefined) {
+      setClauses.push(`status = $${paramIdx}`);
+      params.push(body.status);
+      paramIdx++;
+      if (body.status === 'resolved') {
+        setClauses.push(`resolved_at = COALESCE(resolved_at, NOW()), resolved_by = COALESCE(resolved_by, $${paramIdx})`);
+        params.push(userId);
+        paramIdx++;
+      } else {
+        setClauses.push('resolved_at = NULL, resolved_by = NULL');
+      }
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    params.push(entryId);
+    const res = await pool.query(
+      `UPDATE logbook_entries SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING id`,
+      params,
+    );
+
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Log book entry not found', 'not_found', 404);
+    }
+
+    const updated = await pool.query(`${LOGBOOK_SELECT} WHERE e.id = $1`, [entryId]);
+    return successResponse(c, 'Log book entry updated successfully', formatLogbookEntry(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update log book entry', (err as Error).message);
+  }
+}
+
+// ── DeleteLogbookEntry ──────────────────────────────────────────────────────
+// Managers can remove their own entries; logbook.delete_any removes any.
+
+export async function deleteLogbookEntry(c: Context) {
+  const entryId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  try {
+    const res = await pool.query('SELECT created_by FROM logbook_entries WHERE id = $1', [entryId]);
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Log book entry not found', 'not_found', 404);
+    }
+    if (!can(c, 'logbook.delete_any') && res.rows[0].created_by !== userId) {
+      return errorResponse(c, 'Only the author or an admin can delete this entry', 'forbidden', 403);
+    }
+
+    await pool.query('DELETE FROM logbook_entries WHERE id = $1', [entryId]);
+    return successResponse(c, 'Log book entry deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete log book entry', (err as Error).message);
+  }
+}
+
+// ── SendLogbookDigestNow ────────────────────────────────────────────────────
+// Manual (re)send; defaults to yesterday like the scheduled digest.
+
+export async function sendLogbookDigestNow(c: Context) {
+  let body: { date?: string } = {};
+  try {
+    body = await c.req.json();
+  } catch {
+    // Empty body is fine
+  }
+
+  const date = body.date ?? addDays(localClock().date, -1);
+  if (!DATE_RE.test(date)) {
+    return errorResponse(c, 'Date must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+
+  try {
+    const result = await sendLogbookDigest(pool, date);
+    if (!result.sent) {
+      return errorResponse(
+        c,
+        result.recipients.length === 0 ? 'No digest recipients configured' : 'Email is not configured on this server',
+        'digest_not_sent',
+        400,
+      );
+    }
+    return successResponse(c, 'Log book digest queued for sending', result);
+  } catch (err) {
+    return errorResponse(c, 'Failed to send log book digest', (err as Error).message);
+  }
+}
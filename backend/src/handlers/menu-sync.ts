@@ -0,0 +1,151 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { DELIVERY_PLATFORMS, isDeliveryPlatform, platformConfigured, type DeliveryPlatform } from '../lib/delivery-platforms.js';
+import { buildReconciliation, queueMenuSync } from '../services/menu-sync.js';
+
+const MAPPING_SELECT = `
+  SELECT m.id, m.platform, m.product_id, p.name AS product_name, p.sku, p.price,
+         m.platform_item_id, m.synced_price, m.synced_available, m.sync_status, m.last_error,
+         m.last_synced_at, m.created_by, m.created_at, m.updated_at
+  FROM delivery_platform_items m
+  JOIN products p ON p.id = m.product_id`;
+
+// ── GetPlatformMappings ─────────────────────────────────────────────────────
+
+export async function getPlatformMappings(c: Context) {
+  const platform = c.req.query('platform') || null;
+  if (platform && !isDeliveryPlatform(platform)) {
+    return errorResponse(c, `Platform must be one of: ${DELIVERY_PLATFORMS.join(', ')}`, 'invalid_platform', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `${MAPPING_SELECT} WHERE ($1::text IS NULL OR m.platform = $1) ORDER BY m.platform ASC, p.name ASC`,
+      [platform],
+    );
+    return successResponse(c, 'Platform mappings retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch platform mappings', (err as Error).message);
+  }
+}
+
+// ── CreatePlatformMapping ───────────────────────────────────────────────────
+// The new item is pushed right away so the platform matches the product.
+
+export async function createPlatformMapping(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { platform?: string; product_id?: string; platform_item_id?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!isDeliveryPlatform(body.platform)) {
+    return errorResponse(c, `Platform must be one of: ${DELIVERY_PLATFORMS.join(', ')}`, 'invalid_platform', 400);
+  }
+  if (!body.product_id || !isUUID(body.product_id)) {
+    return errorResponse(c, 'Invalid product_id', 'invalid_product_id', 400);
+  }
+  const platformItemId = body.platform_item_id?.trim();
+  if (!platformItemId || platformItemId.length > 100) {
+    return errorResponse(c, 'platform_item_id is required (at most 100 characters)', 'invalid_platform_item_id', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const product = await client.query('SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL', [body.product_id]);
+      if (product.rows.length === 0) {
+        return txFailure('Product not found', 'product_not_found', 400);
+      }
+
+      const duplicate = await client.query(
+        `SELECT product_id FROM delivery_platform_items
+         WHERE platform = $1 AND (product_id = $2 OR platform_item_id = $3)`,
+        [body.platform, body.product_id, platformItemId],
+      );
+      if (duplicate.rows.length > 0) {
+        const message = duplicate.rows[0].product_id === body.product_id
+          ? 'This product is already mapped on this platform'
+          : 'This platform item is already mapped to another product';
+        return txFailure(message, 'duplicate_mapping', 409);
+      }
+
+      const res = await client.query(
+        `INSERT INTO delivery_platform_items (platform, product_id, platform_item_id, created_by)
+         VALUES ($1, $2, $3, $4)
+         RETURNING id`,
+        [body.platform, body.product_id, platformItemId, userId],
+      );
+      await queueMenuSync(client, { productIds: [body.product_id] });
+      return { ok: true as const, mappingId: res.rows[0].id as string };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const created = await pool.query(`${MAPPING_SELECT} WHERE m.id = $1`, [result.mappingId]);
+    return successResponse(c, 'Platform mapping created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create platform mapping', (err as Error).message);
+  }
+}
+
+// ── DeletePlatformMapping ───────────────────────────────────────────────────
+// Only stops syncing; the item stays listed on the platform.
+
+export async function deletePlatformMapping(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Platform mapping not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM delivery_platform_items WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Platform mapping not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Platform mapping deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete platform mapping', (err as Error).message);
+  }
+}
+
+// ── SyncPlatformMenu ────────────────────────────────────────────────────────
+// Pushes every mapped item, e.g. after connecting a platform or fixing
+// failed syncs.
+
+export async function syncPlatformMenu(c: Context) {
+  const platform = c.req.param('platform');
+  if (!isDeliveryPlatform(platform)) {
+    return errorResponse(c, `Platform must be one of: ${DELIVERY_PLATFORMS.join(', ')}`, 'invalid_platform', 400);
+  }
+  if (!platformConfigured(platform)) {
+    return errorResponse(c, `${platform} is not configured`, 'platform_not_configured', 400);
+  }
+
+  try {
+    const queued = await queueMenuSync(pool, { platform });
+    return successResponse(c, 'Menu sync queued', { platform, queued });
+  } catch (err) {
+    return errorResponse(c, 'Failed to queue menu sync', (err as Error).message);
+  }
+}
+
+// ── GetMenuReconciliation ───────────────────────────────────────────────────
+
+export async function getMenuReconciliation(c: Context) {
+  const platform = c.req.query('platform') || null;
+  if (platform && !isDeliveryPlatform(platform)) {
+    return errorResponse(c, `Platform must be one of: ${DELIVERY_PLATFORMS.join(', ')}`, 'invalid_platform', 400);
+  }
+
+  try {
+    const report = await buildReconciliation(pool, platform ? [platform as DeliveryPlatform] : DELIVERY_PLATFORMS);
+    return successResponse(c, 'Menu reconciliation retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to build menu reconciliation', (err as Error).message);
+  }
+}
@@ -0,0 +1,25 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { describeDataModel } from '../services/data-model.js';
+
+// ── GetSchemaMeta ───────────────────────────────────────────────────────────
+// Tables, enum values and validation limits, described from the code that
+// enforces them. ?section=entities|enums|validation returns just that part.
+
+const SECTIONS = ['entities', 'enums', 'validation'] as const;
+
+export async function getSchemaMeta(c: Context) {
+  const section = c.req.query('section');
+  if (section && !(SECTIONS as readonly string[]).includes(section)) {
+    return errorResponse(c, `section must be one of: ${SECTIONS.join(', ')}`, 'invalid_section', 400);
+  }
+
+  try {
+    const model = await describeDataModel(pool);
+    const data = section ? { [section]: model[section as (typeof SECTIONS)[number]] } : model;
+    return successResponse(c, 'Data model retrieved successfully', data);
+  } catch (err) {
+    return errorResponse(c, 'Failed to describe data model', (err as Error).message);
+  }
+}
@@ -0,0 +1,36 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import { Gauge, onCollect, renderMetrics } from '../lib/metrics.js';
+import { inFlightRequests } from '../lib/lifecycle.js';
+
+const dbPoolConnections = new Gauge('db_pool_connections', 'Database pool connections by state');
+const dbPoolWaiting = new Gauge('db_pool_waiting_requests', 'Queries waiting for a free pool connection');
+const httpInFlight = new Gauge('http_requests_in_flight', 'Requests currently being served');
+const processUptime = new Gauge('process_uptime_seconds', 'Seconds since this instance started');
+
+onCollect(() => {
+  dbPoolConnections.set(pool.totalCount, { state: 'total' });
+  dbPoolConnections.set(pool.idleCount, { state: 'idle' });
+  dbPoolConnections.set(pool.totalCount - pool.idleCount, { state: 'in_use' });
+  dbPoolWaiting.set(pool.waitingCount);
+  httpInFlight.set(inFlightRequests());
+  processUptime.set(Math.round(process.uptime()));
+});
+
+// ── GetMetrics ──────────────────────────────────────────────────────────────
+// Prometheus scrape endpoint. When METRICS_TOKEN is set the scraper must send
+// it as a bearer token.
+
+export async function getMetrics(c: Context) {
+  if (env.METRICS_TOKEN) {
+    const auth = c.req.header('Authorization');
+    if (auth !== `Bearer ${env.METRICS_TOKEN}`) {
+      return c.text('Unauthorized', 401);
+    }
+  }
+
+  return c.text(renderMetrics(), 200, {
+    'Content-Type': 'text/plain; version=0.0.4; charset=utf-8',
+  });
+}
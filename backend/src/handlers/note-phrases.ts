@@ -0,0 +1,226 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { invalidateCache } from '../lib/cache.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import {
+  KITCHEN_FLAGS,
+  MAX_NOTE_PHRASE_LABEL,
+  NOTE_PHRASE_CODE_RE,
+  NOTE_PHRASE_SELECT,
+  loadNotePhrases,
+} from '../services/note-phrases.js';
+
+interface NotePhraseBody {
+  code?: string;
+  label?: string;
+  category_id?: string | null;
+  customer_visible?: boolean;
+  kitchen_flag?: string | null;
+  is_active?: boolean;
+  sort_order?: number;
+}
+
+// Checks the fields that are present; `code` is only read on create.
+function validateBody(body: NotePhraseBody, creating: boolean): { message: string; code: string } | null {
+  if (creating || body.label !== undefined) {
+    const label = typeof body.label === 'string' ? body.label.trim() : '';
+    if (!label || label.length > MAX_NOTE_PHRASE_LABEL) {
+      return { message: `Label is required (max ${MAX_NOTE_PHRASE_LABEL} characters)`, code: 'invalid_label' };
+    }
+  }
+  if (body.category_id !== undefined && body.category_id !== null && !isUUID(body.category_id)) {
+    return { message: 'Invalid category ID', code: 'invalid_category_id' };
+  }
+  for (const flag of ['customer_visible', 'is_active'] as const) {
+    if (body[flag] !== undefined && typeof body[flag] !== 'boolean') {
+      return { message: `${flag} must be true or false`, code: `invalid_${flag}` };
+    }
+  }
+  if (body.kitchen_flag !== undefined && body.kitchen_flag !== null && !KITCHEN_FLAGS.includes(body.kitchen_flag)) {
+    return { message: `kitchen_flag must be one of: ${KITCHEN_FLAGS.join(', ')}, or null`, code: 'invalid_kitchen_flag' };
+  }
+  if (body.sort_order !== undefined && !Number.isInteger(body.sort_order)) {
+    return { message: 'sort_order must be a whole number', code: 'invalid_sort_order' };
+  }
+  return null;
+}
+
+async function categoryExists(categoryId: string): Promise<boolean> {
+  const res = await pool.query('SELECT 1 FROM categories WHERE id = $1 AND deleted_at IS NULL', [categoryId]);
+  return res.rows.length > 0;
+}
+
+// ── GetPublicNotePhrases ────────────────────────────────────────────────────
+// The one-tap instructions the customer app offers; ?category_id= narrows
+// them to what fits a product of that category.
+
+export async function getPublicNotePhrases(c: Context) {
+  const categoryId = c.req.query('category_id') || null;
+  if (categoryId && !isUUID(categoryId)) {
+    return errorResponse(c, 'Invalid category ID', 'invalid_category_id', 400);
+  }
+
+  try {
+    const phrases = await loadNotePhrases(pool, { activeOnly: true, customerOnly: true, categoryId });
+    return successResponse(c, 'Note phrases retrieved successfully', phrases.map((p) => ({
+      code: p.code,
+      label: p.label,
+      category_id: p.category_id,
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch note phrases', (err as Error).message);
+  }
+}
+
+// ── GetNotePhrases ──────────────────────────────────────────────────────────
+// What the POS offers, staff-only phrases included.
+
+export async function getNotePhrases(c: Context) {
+  const categoryId = c.req.query('category_id') || null;
+  if (categoryId && !isUUID(categoryId)) {
+    return errorResponse(c, 'Invalid category ID', 'invalid_category_id', 400);
+  }
+
+  try {
+    const phrases = await loadNotePhrases(pool, { activeOnly: true, categoryId });
+    return successResponse(c, 'Note phrases retrieved successfully', phrases);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch note phrases', (err as Error).message);
+  }
+}
+
+// ── GetAllNotePhrases ───────────────────────────────────────────────────────
+
+export async function getAllNotePhrases(c: Context) {
+  try {
+    const phrases = await loadNotePhrases(pool);
+    return successResponse(c, 'Note phrases retrieved successfully', phrases);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch note phrases', (err as Error).message);
+  }
+}
+
+// ── CreateNotePhrase ────────────────────────────────────────────────────────
+// Without a category_id the phrase is offered for every product; without a
+// kitchen_flag the kitchen display shows it like any other note.
+
+export async function createNotePhrase(c: Context) {
+  let body: NotePhraseBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const code = typeof body.code === 'string' ? body.code.trim() : '';
+  if (!NOTE_PHRASE_CODE_RE.test(code)) {
+    return errorResponse(c, 'Code must be 2-40 lowercase letters, digits or underscores, starting with a letter', 'invalid_code', 400);
+  }
+  const invalid = validateBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    if (body.category_id && !(await categoryExists(body.category_id))) {
+      return errorResponse(c, 'Category not found', 'category_not_found', 400);
+    }
+
+    const res = await pool.query(
+      `INSERT INTO kitchen_note_phrases (code, label, category_id, customer_visible, kitchen_flag, is_active, sort_order)
+       VALUES ($1, $2, $3, $4, $5, $6, $7)
+       ON CONFLICT (code) DO NOTHING
+       RETURNING code`,
+      [
+        code, body.label!.trim(), body.category_id ?? null, body.customer_visible ?? true,
+        body.kitchen_flag ?? null, body.is_active ?? true, body.sort_order ?? 0,
+      ],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'A note phrase with this code already exists', 'duplicate_code', 409);
+    }
+    invalidateCache('menu');
+    const created = await pool.query(`${NOTE_PHRASE_SELECT} WHERE np.code = $1`, [code]);
+    return successResponse(c, 'Note phrase created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create note phrase', (err as Error).message);
+  }
+}
+
+// ── UpdateNotePhrase ────────────────────────────────────────────────────────
+// Fields left out keep their value; category_id: null offers the phrase for
+// every product and kitchen_flag: null clears the flag. The code can't change: order items point at it.
+
+export async function updateNotePhrase(c: Context) {
+  const code = c.req.param('code');
+
+  let body: NotePhraseBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    if (body.category_id && !(await categoryExists(body.category_id))) {
+      return errorResponse(c, 'Category not found', 'category_not_found', 400);
+    }
+
+    const res = await pool.query(
+      `UPDATE kitchen_note_phrases
+       SET label = COALESCE($2, label),
+           category_id = CASE WHEN $3 THEN $4::uuid ELSE category_id END,
+           customer_visible = COALESCE($5, customer_visible),
+           is_active = COALESCE($6, is_active),
+           sort_order = COALESCE($7, sort_order),
+           kitchen_flag = CASE WHEN $8 THEN $9 ELSE kitchen_flag END
+       WHERE code = $1
+       RETURNING code`,
+      [
+        code, body.label?.trim() ?? null, body.category_id !== undefined, body.category_id ?? null,
+        body.customer_visible ?? null, body.is_active ?? null, body.sort_order ?? null,
+        body.kitchen_flag !== undefined, body.kitchen_flag ?? null,
+      ],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Note phrase not found', 'note_phrase_not_found', 404);
+    }
+    invalidateCache('menu');
+    const updated = await pool.query(`${NOTE_PHRASE_SELECT} WHERE np.code = $1`, [code]);
+    return successResponse(c, 'Note phrase updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update note phrase', (err as Error).message);
+  }
+}
+
+// ── DeleteNotePhrase ────────────────────────────────────────────────────────
+// Only phrases no order item has used; others can be switched off instead.
+
+export async function deleteNotePhrase(c: Context) {
+  const code = c.req.param('code');
+
+  try {
+    const existing = await pool.query('SELECT 1 FROM kitchen_note_phrases WHERE code = $1', [code]);
+    if (existing.rows.length === 0) {
+      return errorResponse(c, 'Note phrase not found', 'note_phrase_not_found', 404);
+    }
+    const used = await pool.query(
+      'SELECT EXISTS(SELECT 1 FROM order_items WHERE note_phrases @> ARRAY[$1]::text[]) AS used',
+      [code],
+    );
+    if (used.rows[0].used) {
+      return errorResponse(c, 'Order items were given this phrase; deactivate it instead', 'note_phrase_in_use', 409);
+    }
+
+    await pool.query('DELETE FROM kitchen_note_phrases WHERE code = $1', [code]);
+    invalidateCache('menu');
+    return successResponse(c, 'Note phrase deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete note phrase', (err as Error).message);
+  }
+}
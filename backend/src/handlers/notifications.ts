@@ -1,7 +1,29 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
 import { successResponse, errorResponse } from '../lib/response.js';
+import { roleExists } from '../services/permissions.js';
+import { isUUID } from '../services/branches.js';
+import { resolveOrderToken } from '../services/order-links.js';
+import {
+  NOTIFICATION_EVENTS,
+  NOTIFICATION_SEVERITIES,
+  isNotificationSeverity,
+  loadNotificationSeverities,
+} from '../services/notification-severity.js';
+import {
+  BUILT_IN_PREFERENCES,
+  NOTIFICATION_TYPES,
+  PREFERENCE_FIELDS,
+  applyRoleDefaults,
+  changedFields,
+  ensurePreferences,
+  isPreferenceField,
+  loadRoleDefaults,
+  preferenceValues,
+  type PreferenceField,
+} from '../services/notification-preferences.js';
 
 // ── GetUnreadCounts ──────────────────────────────────────────────────────────
 
@@ -9,14 +31,24 @@ export async function getUnreadCounts(c: Context) {
   const userId = c.get('user_id');
 
   try {
-    const res = await db.execute<{ count: string }>(sql`
-      SELECT COUNT(*) as count FROM notifications WHERE user_id = ${userId} AND is_read = false
+    const res = await db.execute<{ count: string; warning: string; critical: string }>(sql`
+      SELECT COUNT(*) as count,
+             COUNT(*) FILTER (WHERE severity = 'warning') as warning,
+             COUNT(*) FILTER (WHERE severity = 'critical') as critical
+      FROM notifications WHERE user_id = ${userId} AND is_read = false
     `);
 
+    const row = res.rows[0];
     return c.json({
       success: true,
       data: {
-        notifications: Number(res.rows[0].count),
+        notifications: Number(row.count),
+        // Unread by severity, so a client can keep alerting until critical ones are read
+        by_severity: {
+          info: Number(row.count) - Number(row.warning) - Number(row.critical),
+          warning: Number(row.warning),
+          critical: Number(row.critical),
+        },
       },
     });
   } catch (err) {
@@ -34,10 +66,15 @@ export async function getNotifications(c: Context) {
   const userId = c.get('user_id');
   const notifType = c.req.query('type') || '';
   const isRead = c.req.query('is_read') || '';
+  const severity = c.req.query('severity') || '';
+
+  if (severity && !isNotificationSeverity(severity)) {
+    return errorResponse(c, `Severity must be one of: ${NOTIFICATION_SEVERITIES.join(', ')}`, 'invalid_severity', 400);
+  }
 
   try {
     let query = `
-      SELECT id, user_id, type, title, message, is_read, read_at, created_at
+      SELECT id, user_id, type, event, severity, title, message, is_read, read_at, created_at
       FROM notifications
       WHERE user_id = $1
     `;
@@ -50,6 +87,12 @@ export async function getNotifications(c: Context) {
       argIndex++;
     }
 
+    if (severity) {
+      query += ` AND severity = $${argIndex}`;
+      params.push(severity);
+      argIndex++;
+    }
+
     if (isRead === 'true') {
       query += ' AND is_read = true';
     } else if (isRead === 'false') {
@@ -64,6 +107,8 @@ export async function getNotifications(c: Context) {
       id: row.id,
       user_id: row.user_id,
       type: row.type,
+      event: row.event ?? row.type,
+      severity: row.severity,
       title: row.title,
       message: row.message,
       is_read: row.is_read,
@@ -134,11 +179,13 @@ export async function deleteNotification(c: Context) {
 }
 
 // ── GetNotificationPreferences ──────────────────────────────────────────────
+// Created from the role's defaults the first time they're asked for.
 
 export async function getNotificationPreferences(c: Context) {
   const userId = c.get('user_id');
 
   try {
+    await ensurePreferences(pool, userId);
     const res = await db.execute<{
       id: string;
       user_id: string;
@@ -147,51 +194,17 @@ export async function getNotificationPreferences(c: Context) {
       quiet_hours_start: string | null;
       quiet_hours_end: string | null;
       notification_email: string | null;
+      overridden_fields: string[];
       created_at: string;
       updated_at: string;
     }>(sql`
       SELECT id, user_id, email_enabled, types_enabled, quiet_hours_start, quiet_hours_end,
-             notification_email, created_at, updated_at
+             notification_email, overridden_fields, created_at, updated_at
       FROM notification_preferences
       WHERE user_id = ${userId}
     `);
 
-    if (res.rows.length > 0) {
-      const row = res.rows[0];
-      return successResponse(c, 'Preferences retrieved successfully', {
-        id: row.id,
-        user_id: row.user_id,
-        email_enabled: row.email_enabled,
-        types_enabled: row.types_enabled,
-        ...(row.quiet_hours_start != null && { quiet_hours_start: row.quiet_hours_start }),
-        ...(row.quiet_hours_end != null && { quiet_hours_end: row.quiet_hours_end }),
-        ...(row.notification_email != null && { notification_email: row.notification_email }),
-        created_at: row.created_at,
-        updated_at: row.updated_at,
-      });
-    }
-
-    // Create default preferences if none exist
-    const defaultTypes = '{"order_update": true, "low_stock": true, "payment": true, "system_alert": true, "daily_report": true}';
-
-    const insertRes = await db.execute<{
-      id: string;
-      user_id: string;
-      email_enabled: boolean;
-      types_enabled: string;
-      quiet_hours_start: string | null;
-      quiet_hours_end: string | null;
-      notification_email: string | null;
-      created_at: string;
-      updated_at: string;
-    }>(sql`
-      INSERT INTO notification_preferences (user_id, email_enabled, types_enabled)
-      VALUES (${userId}, true, ${defaultTypes})
-      RETURNING id, user_id, email_enabled, types_enabled, quiet_hours_start, quiet_hours_end,
-                notification_email, created_at, updated_at
-    `);
-
-    const row = insertRes.rows[0];
+    const row = res.rows[0];
     return successResponse(c, 'Preferences retrieved successfully', {
       id: row.id,
       user_id: row.user_id,
@@ -200,6 +213,7 @@ export async function getNotificationPreferences(c: Context) {
       ...(row.quiet_hours_start != null && { quiet_hours_start: row.quiet_hours_start }),
       ...(row.quiet_hours_end != null && { quiet_hours_end: row.quiet_hours_end }),
       ...(row.notification_email != null && { notification_email: row.notification_email }),
+      overridden_fields: row.overridden_fields,
       created_at: row.created_at,
       updated_at: row.updated_at,
     });
@@ -213,13 +227,15 @@ export async function getNotificationPreferences(c: Context) {
 }
 
 // ── UpdateNotificationPreferences ──────────────────────────────────────────
+// Whatever the user changes is marked as their own, so applying role
+// defaults later doesn't undo it.
 
 export async function updateNotificationPreferences(c: Context) {
   const userId = c.get('user_id');
 
   let body: {
     email_enabled: boolean;
-    types_enabled: string;
+    types_enabled: string | Record<string, boolean>;
     quiet_hours_start?: string | null;
     quiet_hours_end?: string | null;
     notification_email?: string | null;
@@ -228,20 +244,39 @@ export async function updateNotificationPreferences(c: Context) {
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ success: false, message: 'Invalid request format' }, 400);
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
   }
 
   try {
-    await db.execute(sql`
-      UPDATE notification_preferences
-      SET email_enabled = ${body.email_enabled},
-          types_enabled = ${body.types_enabled},
-          quiet_hours_start = ${body.quiet_hours_start ?? null},
-          quiet_hours_end = ${body.quiet_hours_end ?? null},
-          notification_email = ${body.notification_email ?? null},
-          updated_at = NOW()
-      WHERE user_id = ${userId}
-    `);
+    await ensurePreferences(pool, userId);
+    const current = await pool.query(
+      `SELECT email_enabled, types_enabled, quiet_hours_start, quiet_hours_end
+       FROM notification_preferences WHERE user_id = $1`,
+      [userId],
+    );
+    const next = preferenceValues(body as Record<string, unknown>);
+    const changed = changedFields(preferenceValues(current.rows[0]), next);
+
+    await pool.query(
+      `UPDATE notification_preferences
+       SET email_enabled = $2,
+           types_enabled = $3,
+           quiet_hours_start = $4,
+           quiet_hours_end = $5,
+           notification_email = $6,
+           overridden_fields = ARRAY(SELECT DISTINCT f FROM unnest(overridden_fields || $7::text[]) AS f ORDER BY f),
+           updated_at = NOW()
+       WHERE user_id = $1`,
+      [
+        userId,
+        next.email_enabled,
+        JSON.stringify(next.types_enabled),
+        body.quiet_hours_start ?? null,
+        body.quiet_hours_end ?? null,
+        body.notification_email ?? null,
+        changed,
+      ],
+    );
 
     return c.json({ success: true, message: 'Preferences updated successfully' });
   } catch (err) {
@@ -253,10 +288,268 @@ export async function updateNotificationPreferences(c: Context) {
   }
 }
 
+// ── GetNotificationSeverities ───────────────────────────────────────────────
+// Every notification event with the severity it is sent with, per role
+// where that differs, and the built-in defaults.
+
+export async function getNotificationSeverities(c: Context) {
+  try {
+    const events = await loadNotificationSeverities(pool);
+    return successResponse(c, 'Notification severities retrieved successfully', events);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch notification severities', (err as Error).message);
+  }
+}
+
+// ── UpdateNotificationSeverity ──────────────────────────────────────────────
+// Sets an event's severity for every role, or with `role` for that role only.
+
+export async function updateNotificationSeverity(c: Context) {
+  const event = c.req.param('event');
+  if (!NOTIFICATION_EVENTS[event]) {
+    return errorResponse(c, 'Notification event not found', 'notification_event_not_found', 404);
+  }
+
+  let body: { severity?: string; role?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!isNotificationSeverity(body.severity)) {
+    return errorResponse(c, `Severity must be one of: ${NOTIFICATION_SEVERITIES.join(', ')}`, 'invalid_severity', 400);
+  }
+
+  try {
+    const role = body.role || '';
+    if (role && !(await roleExists(pool, role))) {
+      return errorResponse(c, 'Role not found', 'invalid_role', 400);
+    }
+
+    await pool.query(
+      `INSERT INTO notification_severity_rules (event, role, severity, updated_by, updated_at)
+       VALUES ($1, $2, $3, $4, NOW())
+       ON CONFLICT (event, role) DO UPDATE SET
+         severity = EXCLUDED.severity,
+         updated_by = EXCLUDED.updated_by,
+         updated_at = NOW()`,
+      [event, role, body.severity, c.get('user_id') ?? null],
+    );
+
+    const events = await loadNotificationSeverities(pool);
+    return successResponse(c, 'Notification severity updated successfully', events.find((e) => e.event === event));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update notification severity', (err as Error).message);
+  }
+}
+
+// ── ResetNotificationSeverity ───────────────────────────────────────────────
+// Drops the overrides of an event (?role= for one role's only), going back
+// to the built-in severity.
+
+export async function resetNotificationSeverity(c: Context) {
+  const event = c.req.param('event');
+  if (!NOTIFICATION_EVENTS[event]) {
+    return errorResponse(c, 'Notification event not found', 'notification_event_not_found', 404);
+  }
+  const role = c.req.query('role');
+
+  try {
+    if (role !== undefined) {
+      await pool.query('DELETE FROM notification_severity_rules WHERE event = $1 AND role = $2', [event, role]);
+    } else {
+      await pool.query('DELETE FROM notification_severity_rules WHERE event = $1', [event]);
+    }
+
+    const events = await loadNotificationSeverities(pool);
+    return successResponse(c, 'Notification severity reset successfully', events.find((e) => e.event === event));
+  } catch (err) {
+    return errorResponse(c, 'Failed to reset notification severity', (err as Error).message);
+  }
+}
+
+// ── Notification preference defaults per role ───────────────────────────────
+
+const TIME_RE = /^([01]\d|2[0-3]):[0-5]\d(:[0-5]\d)?$/;
+
+const ROLE_DEFAULTS_SELECT = `
+  SELECT r.name AS role, r.display_name, d.role IS NOT NULL AS customized,
+         d.email_enabled, d.types_enabled, d.quiet_hours_start, d.quiet_hours_end, d.updated_at,
+         (SELECT COUNT(*) FROM users u WHERE u.role = r.name AND u.deleted_at IS NULL) AS user_count,
+         (SELECT COUNT(*) FROM users u JOIN notification_preferences np ON np.user_id = u.id
+          WHERE u.role = r.name AND u.deleted_at IS NULL AND cardinality(np.overridden_fields) > 0) AS users_with_overrides
+  FROM roles r
+  LEFT JOIN notification_role_defaults d ON d.role = r.name`;
+
+function formatRoleDefaults(row: Record<string, unknown>) {
+  const values = row.customized ? preferenceValues(row) : BUILT_IN_PREFERENCES;
+  return {
+    role: row.role,
+    display_name: row.display_name,
+    customized: row.customized,
+    ...values,
+    user_count: Number(row.user_count),
+    users_with_overrides: Number(row.users_with_overrides),
+    updated_at: row.updated_at ?? null,
+  };
+}
+
+// ── GetNotificationRoleDefaults ─────────────────────────────────────────────
+// Every role with the preferences its users start with; roles without their
+// own show the built-in defaults.
+
+export async function getNotificationRoleDefaults(c: Context) {
+  try {
+    const res = await pool.query(`${ROLE_DEFAULTS_SELECT} ORDER BY r.is_system DESC, r.name ASC`);
+    return successResponse(c, 'Notification role defaults retrieved successfully', res.rows.map(formatRoleDefaults));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch notification role defaults', (err as Error).message);
+  }
+}
+
+// ── UpdateNotificationRoleDefaults ──────────────────────────────────────────
+// Fields left out keep their current default. Existing users are only
+// changed by applying the defaults.
+
+export async function updateNotificationRoleDefaults(c: Context) {
+  const role = c.req.param('role');
+
+  let body: {
+    email_enabled?: boolean;
+    types_enabled?: Record<string, unknown>;
+    quiet_hours_start?: string | null;
+    quiet_hours_end?: string | null;
+  };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.email_enabled !== undefined && typeof body.email_enabled !== 'boolean') {
+    return errorResponse(c, 'email_enabled must be true or false', 'invalid_email_enabled', 400);
+  }
+  if (body.types_enabled !== undefined) {
+    const types = body.types_enabled;
+    const valid = types !== null && typeof types === 'object' && !Array.isArray(types) &&
+      Object.entries(types).every(([type, flag]) => (NOTIFICATION_TYPES as readonly string[]).includes(type) && typeof flag === 'boolean');
+    if (!valid) {
+      return errorResponse(c, `types_enabled must map notification types (${NOTIFICATION_TYPES.join(', ')}) to true or false`, 'invalid_notification_types', 400);
+    }
+  }
+  for (const time of [body.quiet_hours_start, body.quiet_hours_end]) {
+    if (time !== undefined && time !== null && !TIME_RE.test(time)) {
+      return errorResponse(c, 'Quiet hours must be in HH:MM format', 'invalid_quiet_hours', 400);
+    }
+  }
+
+  try {
+    if (!(await roleExists(pool, role))) {
+      return errorResponse(c, 'Role not found', 'role_not_found', 404);
+    }
+
+    const current = await loadRoleDefaults(pool, role);
+    const quietStart = body.quiet_hours_start !== undefined ? body.quiet_hours_start : current.quiet_hours_start;
+    const quietEnd = body.quiet_hours_end !== undefined ? body.quiet_hours_end : current.quiet_hours_end;
+    if (!quietStart !== !quietEnd) {
+      return errorResponse(c, 'Quiet hours need both a start and an end, or neither', 'invalid_quiet_hours', 400);
+    }
+
+    await pool.query(
+      `INSERT INTO notification_role_defaults (role, email_enabled, types_enabled, quiet_hours_start, quiet_hours_end, updated_by, updated_at)
+       VALUES ($1, $2, $3, $4, $5, $6, NOW())
+       ON CONFLICT (role) DO UPDATE SET
+         email_enabled = EXCLUDED.email_enabled,
+         types_enabled = EXCLUDED.types_enabled,
+         quiet_hours_start = EXCLUDED.quiet_hours_start,
+         quiet_hours_end = EXCLUDED.quiet_hours_end,
+         updated_by = EXCLUDED.updated_by,
+         updated_at = NOW()`,
+      [
+        role,
+        body.email_enabled ?? current.email_enabled,
+        JSON.stringify({ ...current.types_enabled, ...body.types_enabled }),
+        quietStart,
+        quietEnd,
+        c.get('user_id') ?? null,
+      ],
+    );
+
+    const res = await pool.query(`${ROLE_DEFAULTS_SELECT} WHERE r.name = $1`, [role]);
+    return successResponse(c, 'Notification role defaults updated successfully', formatRoleDefaults(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update notification role defaults', (err as Error).message);
+  }
+}
+
+// ── ResetNotificationRoleDefaults ───────────────────────────────────────────
+// Back to the built-in defaults. Existing users keep their preferences.
+
+export async function resetNotificationRoleDefaults(c: Context) {
+  const role = c.req.param('role');
+
+  try {
+    if (!(await roleExists(pool, role))) {
+      return errorResponse(c, 'Role not found', 'role_not_found', 404);
+    }
+    await pool.query('DELETE FROM notification_role_defaults WHERE role = $1', [role]);
+
+    const res = await pool.query(`${ROLE_DEFAULTS_SELECT} WHERE r.name = $1`, [role]);
+    return successResponse(c, 'Notification role defaults reset successfully', formatRoleDefaults(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to reset notification role defaults', (err as Error).message);
+  }
+}
+
+// ── ApplyNotificationRoleDefaults ───────────────────────────────────────────
+// Applies the role's defaults to its existing users: every field, or just
+// `fields`. Preferences a user set themselves are kept, and listed in the
+// result, unless `override_personal` is true.
+
+export async function applyNotificationRoleDefaults(c: Context) {
+  const role = c.req.param('role');
+
+  let body: { fields?: unknown; override_personal?: boolean } = {};
+  try {
+    const text = await c.req.text();
+    if (text.trim()) body = JSON.parse(text);
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  let fields: readonly PreferenceField[] = PREFERENCE_FIELDS;
+  if (body.fields !== undefined) {
+    if (!Array.isArray(body.fields) || body.fields.length === 0 || !body.fields.every(isPreferenceField)) {
+      return errorResponse(c, `fields must list some of: ${PREFERENCE_FIELDS.join(', ')}`, 'invalid_preference_fields', 400);
+    }
+    fields = [...new Set(body.fields as PreferenceField[])];
+  }
+
+  try {
+    if (!(await roleExists(pool, role))) {
+      return errorResponse(c, 'Role not found', 'role_not_found', 404);
+    }
+    const overridePersonal = body.override_personal === true;
+    const applied = await withTransaction((client) => applyRoleDefaults(client, role, fields, overridePersonal));
+    return successResponse(
+      c,
+      `Notification defaults applied to ${applied.created + applied.updated} of ${applied.users} users`,
+      { ...applied, fields },
+    );
+  } catch (err) {
+    return errorResponse(c, 'Failed to apply notification role defaults', (err as Error).message);
+  }
+}
+
 // ── GetOrderNotifications (customer-facing) ──────────────────────────────────
 
 export async function getOrderNotifications(c: Context) {
-  const orderId = c.req.param('id');
+  const link = resolveOrderToken(c, ['order']);
+  if (!link.ok) {
+    return errorResponse(c, link.failure.message, link.failure.code, link.failure.status);
+  }
+  const orderId = link.orderId;
 
   try {
     // Verify order exists
@@ -306,13 +599,22 @@ export async function getOrderNotifications(c: Context) {
 }
 
 // ── MarkOrderNotificationAsRead ──────────────────────────────────────────────
+// Only notifications of the order the link is for.
 
 export async function markOrderNotificationAsRead(c: Context) {
+  const link = resolveOrderToken(c, ['order']);
+  if (!link.ok) {
+    return errorResponse(c, link.failure.message, link.failure.code, link.failure.status);
+  }
   const notificationId = c.req.param('id');
+  if (!isUUID(notificationId)) {
+    return errorResponse(c, 'Notification not found', 'notification_not_found', 404);
+  }
 
   try {
     const res = await db.execute(sql`
-      UPDATE order_notifications SET is_read = true WHERE id = ${notificationId}
+      UPDATE order_notifications SET is_read = true
+      WHERE id = ${notificationId} AND order_id = ${link.orderId}
     `);
 
     if (res.rowCount === 0) {
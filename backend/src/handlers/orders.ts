@@ -1,9 +1,43 @@
 import type { Context } from 'hono';
-import { eq, and, sql, not, inArray } from 'drizzle-orm';
+import type { PoolClient } from 'pg';
+import { eq, and, sql, not, inArray, isNull } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
 import { orders, orderItems, products, diningTables, users, orderStatusHistory, orderNotifications, systemSettings } from '../db/schema.js';
 import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
-import { parsePagination, buildMeta } from '../lib/pagination.js';
+import { parsePagination, pageMeta, buildCursorMeta, encodeCursor, decodeCursor, isTimestampKey } from '../lib/pagination.js';
+import { loadFormatter, withFormatted, type Formatter } from '../lib/format.js';
+import { ordersCreatedTotal, orderStatusTransitionsTotal, kitchenTicketDuration } from '../lib/metrics.js';
+import { priceOrder, recordPricingAdjustments, type PricingLine } from '../services/pricing.js';
+import { deductStockForOrder, releaseStockForOrder, restockOrderItems } from '../services/stock.js';
+import { releaseSpecialPortionsForProduct } from '../services/daily-specials.js';
+import { notifyCourseFired, notifyOrderCreated, notifyOrderItemsAdded } from '../services/notification.js';
+import { resolveSchedule, type ScheduleResult } from '../services/scheduled-orders.js';
+import { loadDeliverySettings, computeDeliveryFee, validateDeliveryDetails, type DeliveryDetails } from '../services/delivery.js';
+import { findCustomerFlags } from '../services/customer-flags.js';
+import { estimateOrderWait } from '../services/wait-time.js';
+import { loadOrderPaymentLinks } from '../services/payment-links.js';
+import { releaseHeldItems } from '../services/kitchen-routing.js';
+import { resolveBranchScope, resolveWriteBranch, branchCondition, isUUID } from '../services/branches.js';
+import { addSurchargeTaxes, computeOrderTaxes, summarizeItemTaxes, taxLines } from '../services/tax.js';
+import { emitWebhookEvent, orderEventData } from '../services/webhooks.js';
+import { resolveContainerDeposits, recordContainerDeposits, loadOrderContainerDeposits } from '../services/container-deposits.js';
+import { loadOrderSource, formatRiskFlags } from '../services/order-source.js';
+import { findActiveCurrency, orderCurrency, SETTLEMENT_CURRENCY, type Currency } from '../services/currencies.js';
+import {
+  resolveItemQuantity, hasQuantityInput, lineTotal, type ItemQuantityInput, type ResolvedQuantity,
+} from '../services/weighed-items.js';
+import { isReceiptLanguage, resolveReceiptLanguage, saveCustomerReceiptLanguage } from '../services/receipt-language.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+import { computeOrderSurcharges, recordOrderSurcharges, loadOrderSurcharges } from '../services/surcharges.js';
+import { applyPriceSchedules, loadScheduledPrices } from '../services/price-schedules.js';
+import { parkPendingOrder, resumeParkedOrder, MAX_PARK_MINUTES } from '../services/order-parking.js';
+import { addOrderToTab } from '../services/tabs.js';
+import { fireCourse, holdAddedItems, holdOrderCourses, isCourse, loadOrderCourses, MAX_COURSE } from '../services/courses.js';
+import { findUnusableNotePhrase, normalizeItemNotePhrases, MAX_ITEM_NOTE_PHRASES } from '../services/note-phrases.js';
+import { canSeeOrder, isCovers, resolveOrderServer, MAX_COVERS } from '../services/sections.js';
+import { can } from '../middleware/roles.js';
+import { ORDER_STATUS_UPDATES } from '../services/data-model.js';
 
 function generateOrderNumber(): string {
   const now = new Date();
@@ -12,47 +46,96 @@ function generateOrderNumber(): string {
   return `ORD${timestamp}${rand}`;
 }
 
-async function loadOrderItems(orderId: string) {
+// Items for several orders in one query, keyed by order ID
+async function loadOrderItemsByOrder(orderIds: string[]) {
+  const byOrder = new Map<string, Record<string, unknown>[]>();
+  if (orderIds.length === 0) return byOrder;
+
   const rows = await db
     .select({
       id: orderItems.id,
+      orderId: orderItems.orderId,
       productId: orderItems.productId,
       quantity: orderItems.quantity,
+      weightGrams: orderItems.weightGrams,
+      scaleDevice: orderItems.scaleDevice,
       unitPrice: orderItems.unitPrice,
       totalPrice: orderItems.totalPrice,
+      taxAmount: orderItems.taxAmount,
+      serviceChargeAmount: orderItems.serviceChargeAmount,
+      taxExempt: orderItems.taxExempt,
+      serviceExempt: orderItems.serviceExempt,
+      taxClassId: orderItems.taxClassId,
+      taxLabel: orderItems.taxLabel,
+      taxRate: orderItems.taxRate,
       specialInstructions: orderItems.specialInstructions,
       status: orderItems.status,
+      isRemake: orderItems.isRemake,
+      priceScheduleId: orderItems.priceScheduleId,
+      releasedAt: orderItems.releasedAt,
+      course: orderItems.course,
+      notePhrases: orderItems.notePhrases,
       createdAt: orderItems.createdAt,
       updatedAt: orderItems.updatedAt,
       productName: products.name,
       productDescription: products.description,
       productPrice: products.price,
       productPreparationTime: products.preparationTime,
+      productSaleUnit: products.saleUnit,
     })
     .from(orderItems)
     .innerJoin(products, eq(orderItems.productId, products.id))
-    .where(eq(orderItems.orderId, orderId))
+    .where(inArray(orderItems.orderId, orderIds))
     .orderBy(orderItems.createdAt);
 
-  return rows.map((item) => ({
-    id: item.id,
-    order_id: orderId,
-    product_id: item.productId,
-    quantity: item.quantity,
-    unit_price: Number(item.unitPrice),
-    total_price: Number(item.totalPrice),
-    special_instructions: item.specialInstructions,
-    status: item.status,
-    created_at: item.createdAt,
-    updated_at: item.updatedAt,
-    product: {
-      id: item.productId,
-      name: item.productName,
-      description: item.productDescription,
-      price: Number(item.productPrice),
-      preparation_time: item.productPreparationTime,
-    },
-  }));
+  for (const item of rows) {
+    const orderId = item.orderId!;
+    const list = byOrder.get(orderId) ?? [];
+    list.push({
+      id: item.id,
+      order_id: orderId,
+      product_id: item.productId,
+      quantity: Number(item.quantity),
+      sale_unit: item.productSaleUnit,
+      // Set when the quantity was read off a scale
+      weight_grams: item.weightGrams === null ? null : Number(item.weightGrams),
+      scale_device: item.scaleDevice,
+      unit_price: Number(item.unitPrice),
+      total_price: Number(item.totalPrice),
+      tax_amount: Number(item.taxAmount),
+      service_charge_amount: Number(item.serviceChargeAmount),
+      tax_exempt: item.taxExempt,
+      service_exempt: item.serviceExempt,
+      tax_class_id: item.taxClassId,
+      tax_label: item.taxLabel,
+      tax_rate: item.taxRate === null ? null : Number(item.taxRate),
+      special_instructions: item.specialInstructions,
+      // Codes of the quick phrases picked for the item
+      note_phrases: item.notePhrases,
+      status: item.status,
+      // A free replacement for a sent-back dish
+      is_remake: item.isRemake,
+      price_schedule_id: item.priceScheduleId,
+      course: item.course,
+      // Null while held for the order to be accepted or for its course to be fired
+      released_at: item.releasedAt,
+      created_at: item.createdAt,
+      updated_at: item.updatedAt,
+      product: {
+        id: item.productId,
+        name: item.productName,
+        description: item.productDescription,
+        price: Number(item.productPrice),
+        preparation_time: item.productPreparationTime,
+      },
+    });
+    byOrder.set(orderId, list);
+  }
+  return byOrder;
+}
+
+async function loadOrderItems(orderId: string) {
+  return (await loadOrderItemsByOrder([orderId])).get(orderId) ?? [];
 }
 
 async function loadOrderPayments(orderId: string) {
@@ -60,6 +143,7 @@ async function loadOrderPayments(orderId: string) {
     id: string;
     payment_method: string;
     amount: string;
+    rounding_adjustment: string;
     reference_number: string | null;
     status: string;
     processed_by: string | null;
@@ -69,7 +153,7 @@ async function loadOrderPayments(orderId: string) {
     first_name: string | null;
     last_name: string | null;
   }>(sql`
-    SELECT p.id, p.payment_method, p.amount, p.reference_number, p.status,
+    SELECT p.id, p.payment_method, p.amount, p.rounding_adjustment, p.reference_number, p.status,
            p.processed_by, p.processed_at, p.created_at,
            u.username, u.first_name, u.last_name
     FROM payments p
@@ -84,6 +168,7 @@ async function loadOrderPayments(orderId: string) {
       order_id: orderId,
       payment_method: row.payment_method,
       amount: Number(row.amount),
+      rounding_adjustment: Number(row.rounding_adjustment),
       reference_number: row.reference_number,
       status: row.status,
       processed_by: row.processed_by,
@@ -103,38 +188,109 @@ async function loadOrderPayments(orderId: string) {
   });
 }
 
+const DELIVERY_COLUMNS = sql.raw(`o.delivery_address, o.delivery_phone, o.delivery_notes, o.delivery_fee,
+           o.delivery_status, o.courier_id, o.courier_assigned_at, o.picked_up_at, o.delivered_at,
+           cu.first_name as courier_first_name, cu.last_name as courier_last_name`);
+
+// Display currency of the order; needs LEFT JOIN currencies cur
+const CURRENCY_COLUMNS = sql.raw(`o.display_currency, o.exchange_rate,
+           cur.symbol as currency_symbol, cur.decimal_places as currency_decimals`);
+
+// Delivery details for delivery orders, null otherwise
+function formatDelivery(row: {
+  delivery_address: string | null;
+  delivery_phone: string | null;
+  delivery_notes: string | null;
+  delivery_fee: string;
+  delivery_status: string | null;
+  courier_id: string | null;
+  courier_assigned_at: string | null;
+  picked_up_at: string | null;
+  delivered_at: string | null;
+  courier_first_name: string | null;
+  courier_last_name: string | null;
+}) {
+  if (!row.delivery_status) return null;
+  return {
+    address: row.delivery_address,
+    phone: row.delivery_phone,
+    notes: row.delivery_notes,
+    fee: Number(row.delivery_fee),
+    status: row.delivery_status,
+    courier: row.courier_id
+      ? { id: row.courier_id, first_name: row.courier_first_name, last_name: row.courier_last_name }
+      : null,
+    courier_assigned_at: row.courier_assigned_at,
+    picked_up_at: row.picked_up_at,
+    delivered_at: row.delivered_at,
+  };
+}
+
 async function getOrderByID(orderId: string) {
   const [row] = await db.execute<{
     id: string;
     order_number: string;
     table_id: string | null;
     user_id: string | null;
+    branch_id: string;
     customer_name: string | null;
     order_type: string;
     status: string;
     subtotal: string;
     tax_amount: string;
+    service_charge_amount: string;
     discount_amount: string;
     total_amount: string;
+    deposit_amount: string;
+    surcharge_amount: string;
     notes: string | null;
+    scheduled_at: string | null;
+    delivery_address: string | null;
+    delivery_phone: string | null;
+    delivery_notes: string | null;
+    delivery_fee: string;
+    delivery_status: string | null;
+    courier_id: string | null;
+    courier_assigned_at: string | null;
+    picked_up_at: string | null;
+    delivered_at: string | null;
+    courier_first_name: string | null;
+    courier_last_name: string | null;
     created_at: string | null;
     updated_at: string | null;
     served_at: string | null;
     completed_at: string | null;
+    parked_at: string | null;
+    park_reason: string | null;
+    park_expires_at: string | null;
+    tab_id: string | null;
+    server_id: string | null;
+    covers: number | null;
+    display_currency: string | null;
+    exchange_rate: string | null;
+    currency_symbol: string | null;
+    currency_decimals: number | null;
+    receipt_language: string | null;
     table_number: string | null;
     table_location: string | null;
     username: string | null;
     first_name: string | null;
     last_name: string | null;
   }>(sql`
-    SELECT o.id, o.order_number, o.table_id, o.user_id, o.customer_name,
-           o.order_type, o.status, o.subtotal, o.tax_amount, o.discount_amount,
-           o.total_amount, o.notes, o.created_at, o.updated_at, o.served_at, o.completed_at,
+    SELECT o.id, o.order_number, o.table_id, o.user_id, o.branch_id, o.customer_name,
+           o.order_type, o.status, o.subtotal, o.tax_amount, o.service_charge_amount, o.discount_amount,
+           o.total_amount, o.deposit_amount, o.surcharge_amount, o.notes, o.scheduled_at, o.created_at, o.updated_at,
+           o.served_at, o.completed_at, o.receipt_language, o.parked_at, o.park_reason, o.park_expires_at,
+           o.tab_id, o.server_id, o.covers,
+           ${DELIVERY_COLUMNS},
+           ${CURRENCY_COLUMNS},
            t.table_number, t.location as table_location,
            u.username, u.first_name, u.last_name
     FROM orders o
     LEFT JOIN dining_tables t ON o.table_id = t.id
     LEFT JOIN users u ON o.user_id = u.id
+    LEFT JOIN users cu ON o.courier_id = cu.id
+    LEFT JOIN currencies cur ON cur.code = o.display_currency
     WHERE o.id = ${orderId}
   `).then(r => [r.rows[0]]);
 
@@ -145,20 +301,37 @@ async function getOrderByID(orderId: string) {
     order_number: row.order_number,
     table_id: row.table_id,
     user_id: row.user_id,
+    branch_id: row.branch_id,
     customer_name: row.customer_name,
     order_type: row.order_type,
     status: row.status,
     subtotal: Number(row.subtotal),
     tax_amount: Number(row.tax_amount),
+    service_charge_amount: Number(row.service_charge_amount),
     discount_amount: Number(row.discount_amount),
     total_amount: Number(row.total_amount),
+    deposit_amount: Number(row.deposit_amount),
+    surcharge_amount: Number(row.surcharge_amount),
     notes: row.notes,
+    scheduled_at: row.scheduled_at,
     created_at: row.created_at,
     updated_at: row.updated_at,
     served_at: row.served_at,
     completed_at: row.completed_at,
+    tab_id: row.tab_id,
+    // Credited with the order in the server report
+    server_id: row.server_id,
+    covers: row.covers,
   };
 
+  if (row.parked_at) {
+    order.parked = {
+      parked_at: row.parked_at,
+      reason: row.park_reason,
+      expires_at: row.park_expires_at,
+    };
+  }
+
   if (row.table_number) {
     order.table = {
       table_number: row.table_number,
@@ -174,10 +347,46 @@ async function getOrderByID(orderId: string) {
     };
   }
 
+  order.delivery = formatDelivery(row);
+  order.currency = orderCurrency(row);
+  order.receipt = await resolveReceiptLanguage(pool, row);
+
   order.items = await loadOrderItems(row.id);
+  const surcharges = await loadOrderSurcharges(pool, row.id);
+  order.surcharges = surcharges;
+  order.tax_lines = addSurchargeTaxes(summarizeItemTaxes(order.items as Record<string, unknown>[]), surcharges);
   order.payments = await loadOrderPayments(row.id);
+  order.payment_links = await loadOrderPaymentLinks(pool, row.id);
+  order.container_deposits = await loadOrderContainerDeposits(pool, row.id);
+  order.source = await loadOrderSource(pool, row.id);
 
-  return order;
+  return formatOrderAmounts(order, row.currency_decimals, await loadFormatter(pool));
+}
+
+const ORDER_AMOUNTS = [
+  'subtotal', 'tax_amount', 'service_charge_amount', 'discount_amount', 'total_amount', 'deposit_amount', 'surcharge_amount',
+];
+
+// Display strings for everything the receipt prints: the totals, items,
+// tax lines, surcharges, payments and delivery fee, and the total in the
+// guest's display currency
+function formatOrderAmounts(order: Record<string, unknown>, displayDecimals: number | null, fmt: Formatter) {
+  const rows = (key: string, fields: string[]) =>
+    (order[key] as Record<string, unknown>[]).map((row) => withFormatted(row, fields, fmt.money));
+  const currency = order.currency as ReturnType<typeof orderCurrency>;
+
+  return {
+    ...withFormatted(order, ORDER_AMOUNTS, fmt.money),
+    items: rows('items', ['unit_price', 'total_price', 'tax_amount', 'service_charge_amount']),
+    tax_lines: rows('tax_lines', ['tax_amount']).map((line) => withFormatted(line, ['rate'], fmt.percent)),
+    surcharges: rows('surcharges', ['amount', 'tax_amount']),
+    payments: rows('payments', ['amount', 'rounding_adjustment']),
+    delivery: order.delivery ? withFormatted(order.delivery as Record<string, unknown>, ['fee'], fmt.money) : null,
+    currency: currency.display_total === null ? currency : {
+      ...currency,
+      display_total_formatted: fmt.money(currency.display_total, currency.display_currency, displayDecimals ?? undefined),
+    },
+  };
 }
 
 async function createOrderNotification(orderId: string, status: string, message: string) {
@@ -194,86 +403,154 @@ async function createOrderNotification(orderId: string, status: string, message:
 }
 
 // ── GetOrders ──────────────────────────────────────────────────────────
+// Newest first. ?cursor= (from meta.next_cursor) continues after the previous
+// page without counting or offsetting, which stays fast as orders grow; page
+// numbers still work for the admin tables that show a total.
 
 export async function getOrders(c: Context) {
   const status = c.req.query('status');
   const orderType = c.req.query('order_type');
-  const { page, perPage, offset } = parsePagination({
+  const cursorParam = c.req.query('cursor');
+  const pagination = parsePagination({
     page: c.req.query('page'),
     per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
   });
+  const { perPage, offset } = pagination;
+
+  let cursor: string[] | null = null;
+  if (cursorParam) {
+    cursor = decodeCursor(cursorParam, 2);
+    if (!cursor || !isTimestampKey(cursor[0]) || !isUUID(cursor[1])) {
+      return errorResponse(c, 'Invalid cursor', 'invalid_cursor', 400);
+    }
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
 
   try {
     // Build conditions
     const conditions = [];
+    if (scope.branchId) conditions.push(eq(orders.branchId, scope.branchId));
     if (status) conditions.push(eq(orders.status, status));
     if (orderType) conditions.push(eq(orders.orderType, orderType));
-    const whereClause = conditions.length > 0 ? and(...conditions) : undefined;
-
-    // Count total
-    const [countResult] = await db
-      .select({ count: sql<number>`count(DISTINCT ${orders.id})` })
-      .from(orders)
-      .where(whereClause);
+    if (!can(c, 'orders.view_all_sections')) conditions.push(sectionCondition(c.get('user_id')));
+    const filterClause = conditions.length > 0 ? and(...conditions) : undefined;
+
+    // Count total; cursor pages and ?include_total=false skip it
+    let total: number | null = null;
+    if (!cursor && pagination.includeTotal) {
+      const [countResult] = await db
+        .select({ count: sql<number>`count(*)` })
+        .from(orders)
+        .where(filterClause);
+      total = Number(countResult.count);
+    }
 
-    const total = Number(countResult.count);
+    // (created_at, id) matches idx_orders_created_id, so the cursor is a seek
+    if (cursor) {
+      conditions.push(sql`(${orders.createdAt}, ${orders.id}) < (${cursor[0]}::timestamptz, ${cursor[1]}::uuid)`);
+    }
+    const whereClause = conditions.length > 0 ? and(...conditions) : undefined;
 
-    // Fetch orders
+    // Fetch one extra row to know whether there is a next page
     const rows = await db.execute<{
       id: string;
       order_number: string;
       table_id: string | null;
       user_id: string | null;
+      branch_id: string;
       customer_name: string | null;
       order_type: string;
       status: string;
       subtotal: string;
       tax_amount: string;
+      service_charge_amount: string;
       discount_amount: string;
       total_amount: string;
       notes: string | null;
+      scheduled_at: string | null;
+      delivery_address: string | null;
+      delivery_phone: string | null;
+      delivery_notes: string | null;
+      delivery_fee: string;
+      delivery_status: string | null;
+      courier_id: string | null;
+      courier_assigned_at: string | null;
+      picked_up_at: string | null;
+      delivered_at: string | null;
+      courier_first_name: string | null;
+      courier_last_name: string | null;
       created_at: string | null;
+      created_at_key: string;
       updated_at: string | null;
       served_at: string | null;
       completed_at: string | null;
+      display_currency: string | null;
+      exchange_rate: string | null;
+      currency_symbol: string | null;
+      currency_decimals: number | null;
       table_number: string | null;
       table_location: string | null;
       username: string | null;
       first_name: string | null;
       last_name: string | null;
+      risk_flags: string[] | null;
+      server_id: string | null;
+      covers: number | null;
     }>(sql`
-      SELECT DISTINCT o.id, o.order_number, o.table_id, o.user_id, o.customer_name,
-             o.order_type, o.status, o.subtotal, o.tax_amount, o.discount_amount,
-             o.total_amount, o.notes, o.created_at, o.updated_at, o.served_at, o.completed_at,
+      SELECT o.id, o.order_number, o.table_id, o.user_id, o.branch_id, o.customer_name,
+             o.order_type, o.status, o.subtotal, o.tax_amount, o.service_charge_amount, o.discount_amount,
+             o.total_amount, o.notes, o.scheduled_at, o.created_at, o.updated_at, o.served_at, o.completed_at,
+             o.server_id, o.covers,
+             o.created_at::text AS created_at_key,
+           ${DELIVERY_COLUMNS},
+           ${CURRENCY_COLUMNS},
              t.table_number, t.location as table_location,
-             u.username, u.first_name, u.last_name
+             u.username, u.first_name, u.last_name, src.risk_flags
       FROM orders o
       LEFT JOIN dining_tables t ON o.table_id = t.id
       LEFT JOIN users u ON o.user_id = u.id
+      LEFT JOIN users cu ON o.courier_id = cu.id
+      LEFT JOIN currencies cur ON cur.code = o.display_currency
+      LEFT JOIN order_source_details src ON src.order_id = o.id
       ${whereClause ? sql`WHERE ${whereClause}` : sql``}
-      ORDER BY o.created_at DESC
-      LIMIT ${perPage} OFFSET ${offset}
+      ORDER BY o.created_at DESC, o.id DESC
+      LIMIT ${perPage + 1} OFFSET ${cursor ? 0 : offset}
     `);
 
+    const pageRows = rows.rows.slice(0, perPage);
+    const last = pageRows[pageRows.length - 1];
+    const nextCursor = rows.rows.length > perPage ? encodeCursor([last.created_at_key, last.id]) : null;
+    const itemsByOrder = await loadOrderItemsByOrder(pageRows.map((row) => row.id));
+
     const orderList = [];
-    for (const row of rows.rows) {
+    for (const row of pageRows) {
       const order: Record<string, unknown> = {
         id: row.id,
         order_number: row.order_number,
         table_id: row.table_id,
         user_id: row.user_id,
+        branch_id: row.branch_id,
         customer_name: row.customer_name,
         order_type: row.order_type,
         status: row.status,
         subtotal: Number(row.subtotal),
         tax_amount: Number(row.tax_amount),
+        service_charge_amount: Number(row.service_charge_amount),
         discount_amount: Number(row.discount_amount),
         total_amount: Number(row.total_amount),
         notes: row.notes,
+        scheduled_at: row.scheduled_at,
         created_at: row.created_at,
         updated_at: row.updated_at,
         served_at: row.served_at,
         completed_at: row.completed_at,
+        server_id: row.server_id,
+        covers: row.covers,
       };
 
       if (row.table_number) {
@@ -291,16 +568,43 @@ export async function getOrders(c: Context) {
         };
       }
 
-      order.items = await loadOrderItems(row.id);
+      order.delivery = formatDelivery(row);
+      order.currency = orderCurrency(row);
+
+      order.items = itemsByOrder.get(row.id) ?? [];
+      order.tax_lines = summarizeItemTaxes(order.items as Record<string, unknown>[]);
+      // Customer orders only; the counter sees these while accepting
+      order.risk_flags = formatRiskFlags(row.risk_flags);
       orderList.push(order);
     }
 
-    return paginatedResponse(c, 'Orders retrieved successfully', orderList, buildMeta(page, perPage, total));
+    const context = {
+      sort: 'created_at:desc',
+      filters: { status, order_type: orderType, branch_id: scope.branchId },
+    };
+    if (cursor) {
+      return paginatedResponse(c, 'Orders retrieved successfully', orderList, buildCursorMeta(perPage, nextCursor, context));
+    }
+    return paginatedResponse(c, 'Orders retrieved successfully', orderList, {
+      ...pageMeta(pagination, { rows: pageRows, total, hasNext: nextCursor !== null }, context),
+      next_cursor: nextCursor,
+    });
   } catch (err) {
     return errorResponse(c, 'Failed to fetch orders', (err as Error).message);
   }
 }
 
+// Staff without orders.view_all_sections see the orders of the tables in
+// the sections they are on shift in, and the ones they rang in or are
+// credited with (see services/sections.ts)
+function sectionCondition(userId: string) {
+  return sql`(${orders.userId} = ${userId} OR ${orders.serverId} = ${userId} OR ${orders.tableId} IN (
+    SELECT st.id FROM dining_tables st
+    JOIN section_assignments sa ON sa.section_id = st.section_id
+    WHERE sa.user_id = ${userId} AND NOW() >= sa.starts_at AND NOW() < sa.ends_at
+  ))`;
+}
+
 // ── GetOrder ──────────────────────────────────────────────────────────
 
 export async function getOrder(c: Context) {
@@ -308,7 +612,11 @@ export async function getOrder(c: Context) {
 
   try {
     const order = await getOrderByID(orderId);
-    if (!order) {
+    const branchId = c.get('branch_id');
+    if (!order || (branchId && order.branch_id !== branchId)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+    if (!can(c, 'orders.view_all_sections') && !(await canSeeOrder(pool, c.get('user_id'), order))) {
       return errorResponse(c, 'Order not found', 'order_not_found', 404);
     }
     return successResponse(c, 'Order retrieved successfully', order);
@@ -327,7 +635,23 @@ export async function createOrder(c: Context) {
     customer_name?: string;
     order_type: string;
     notes?: string;
-    items: { product_id: string; quantity: number; special_instructions?: string }[];
+    scheduled_at?: string | null;
+    delivery_address?: string;
+    delivery_phone?: string;
+    delivery_notes?: string;
+    branch_id?: string;
+    items: ({ product_id: string; special_instructions?: string; note_phrases?: string[]; course?: number } & ItemQuantityInput)[];
+    containers?: { container_type_id?: string; quantity?: number }[];
+    currency?: string;
+    receipt_language?: string | null;
+    remember_receipt_language?: boolean;
+    park?: boolean;
+    park_reason?: string;
+    tab_id?: string;
+    /** Hold every course, the first included, until a server fires it */
+    hold?: boolean;
+    /** Guests at the table */
+    covers?: number | null;
   };
 
   try {
@@ -345,122 +669,378 @@ export async function createOrder(c: Context) {
   if (!body.items || body.items.length === 0) {
     return errorResponse(c, 'Order must contain at least one item', 'empty_order', 400);
   }
+  if (body.items.some((item) => !hasQuantityInput(item))) {
+    return errorResponse(c, 'Each item needs a quantity or a weight_grams scale reading', 'invalid_quantity', 400);
+  }
 
   // T008: dine_in requires table_id
   if (body.order_type === 'dine_in' && !body.table_id) {
     return errorResponse(c, 'Table selection is required for dine-in orders', 'table_required_for_dine_in', 400);
   }
 
+  const courseError = validateCourses(c, body.order_type, body.items, body.hold === true);
+  if (courseError) {
+    return errorResponse(c, courseError.message, courseError.code, courseError.status);
+  }
+  if (!normalizeItemNotePhrases(body.items)) {
+    return errorResponse(c, `note_phrases must be a list of at most ${MAX_ITEM_NOTE_PHRASES} phrase codes`, 'invalid_note_phrases', 400);
+  }
+  if (body.covers != null && !isCovers(body.covers)) {
+    return errorResponse(c, `covers must be a whole number from 1 to ${MAX_COVERS}`, 'invalid_covers', 400);
+  }
+
+  let delivery: DeliveryDetails | null = null;
+  if (body.order_type === 'delivery') {
+    const checked = validateDeliveryDetails(body);
+    if (!checked.ok) {
+      return errorResponse(c, checked.message, checked.code, 400);
+    }
+    delivery = checked.details;
+  }
+
+  let schedule: ScheduleResult;
+  try {
+    schedule = await resolveSchedule(pool, body.order_type, body.scheduled_at);
+  } catch (err) {
+    return errorResponse(c, 'Failed to validate scheduled time', (err as Error).message);
+  }
+  if (!schedule.ok) {
+    return errorResponse(c, schedule.failure.message, schedule.failure.code, schedule.failure.status);
+  }
+
+  // Parking at creation keeps the order off the kitchen board from the start
+  if (body.park) {
+    if (schedule.status !== 'pending') {
+      return errorResponse(c, 'A scheduled order cannot be parked', 'invalid_park', 400);
+    }
+    if (!can(c, 'orders.park')) {
+      return errorResponse(c, 'You do not have permission to park orders', 'insufficient_permissions', 403);
+    }
+  }
+
+  // A tab is settled when it closes, so its orders are served and paid then
+  if (body.tab_id) {
+    if (!isUUID(body.tab_id)) {
+      return errorResponse(c, 'Invalid tab_id', 'invalid_tab_id', 400);
+    }
+    if (body.order_type === 'delivery' || schedule.status !== 'pending' || body.park) {
+      return errorResponse(c, 'Only immediate dine-in and takeaway orders can be added to a tab', 'invalid_tab_order', 400);
+    }
+    if (!can(c, 'tabs.manage')) {
+      return errorResponse(c, 'You do not have permission to add orders to tabs', 'insufficient_permissions', 403);
+    }
+  }
+
+  // A guest who wants their bill shown in another currency; still charged in IDR
+  let currency: Currency | null = null;
+  if (body.currency && body.currency.toUpperCase() !== SETTLEMENT_CURRENCY) {
+    try {
+      currency = await findActiveCurrency(pool, body.currency);
+    } catch (err) {
+      return errorResponse(c, 'Failed to validate currency', (err as Error).message);
+    }
+    if (!currency) {
+      return errorResponse(c, 'Currency not supported', 'unsupported_currency', 400);
+    }
+  }
+
+  if (body.receipt_language != null && !isReceiptLanguage(body.receipt_language)) {
+    return errorResponse(c, "receipt_language must be 'id' or 'id_en'", 'invalid_receipt_language', 400);
+  }
+
   // T007: Validate table exists if provided
+  let tableBranchId: string | null = null;
   if (body.table_id) {
     try {
       const [tableRow] = await db
-        .select({ id: diningTables.id })
+        .select({ id: diningTables.id, branchId: diningTables.branchId })
         .from(diningTables)
-        .where(eq(diningTables.id, body.table_id))
+        .where(and(eq(diningTables.id, body.table_id), isNull(diningTables.deletedAt)))
         .limit(1);
 
       if (!tableRow) {
         return errorResponse(c, 'Selected table does not exist', 'table_not_found', 400);
       }
+      tableBranchId = tableRow.branchId;
     } catch (err) {
       return errorResponse(c, 'Failed to validate table', (err as Error).message);
     }
   }
 
-  // Use raw pg client for transaction
-  const client = await pool.connect();
+  // Dine-in orders belong to the table's branch, others to the staff
+  // member's (head office may pick one)
+  let branchId: string;
   try {
-    await client.query('BEGIN');
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), tableBranchId ?? body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+    branchId = branch.branchId;
+  } catch (err) {
+    return errorResponse(c, 'Failed to resolve branch', (err as Error).message);
+  }
 
-    const orderNumber = generateOrderNumber();
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderNumber = generateOrderNumber();
+
+      // Validate products exist and are available, then price the basket
+      const lines: PricingLine[] = [];
+      const quantities: ResolvedQuantity[] = [];
+      for (const item of body.items) {
+        const productRes = await client.query(
+          'SELECT name, price, is_available, category_id, sale_unit FROM products WHERE id = $1 AND deleted_at IS NULL',
+          [item.product_id],
+        );
+
+        if (productRes.rows.length === 0) {
+          return txFailure(`Product with ID '${item.product_id}' not found`, 'product_not_found', 400);
+        }
+
+        const prod = productRes.rows[0];
+        if (!prod.is_available) {
+          return txFailure(`Product '${prod.name}' is currently not available`, 'product_not_available', 400);
+        }
+        const unusable = await findUnusableNotePhrase(client, item.note_phrases ?? [], prod.category_id);
+        if (unusable) {
+          return txFailure(`Note phrase '${unusable}' is not available for ${prod.name}`, 'note_phrase_unavailable', 400);
+        }
+
+        const qty = resolveItemQuantity(prod, item);
+        if (!qty.ok) {
+          return txFailure(qty.message, qty.code, 400);
+        }
+        quantities.push(qty.value);
+        lines.push({
+          product_id: item.product_id,
+          category_id: prod.category_id,
+          name: prod.name,
+          unit_price: Number(prod.price),
+          quantity: qty.value.quantity,
+        });
+      }
 
-    // Calculate subtotal — validate products exist and are available
-    let subtotal = 0;
-    for (const item of body.items) {
-      const productRes = await client.query(
-        'SELECT name, price, is_available FROM products WHERE id = $1',
-        [item.product_id],
-      );
+      // Happy hour prices replace menu prices before any rule discounts
+      const priceScheduleIds = await applyPriceSchedules(client, lines);
+      const pricing = await priceOrder(client, lines);
+      const subtotal = pricing.subtotal;
+      const discountAmount = pricing.discount_amount;
+
+      const taxes = await computeOrderTaxes(client, branchId, body.order_type, taxLines(lines, pricing.line_discounts));
+
+      let deliveryFee = 0;
+      if (delivery) {
+        const deliverySettings = await loadDeliverySettings(client);
+        if (subtotal - discountAmount < deliverySettings.minOrder) {
+          return txFailure(`Delivery orders must be at least ${deliverySettings.minOrder}`, 'below_delivery_minimum', 400);
+        }
+        deliveryFee = computeDeliveryFee(deliverySettings, subtotal - discountAmount);
+      }
 
-      if (productRes.rows.length === 0) {
-        await client.query('ROLLBACK');
-        return errorResponse(c, `Product with ID '${item.product_id}' not found`, 'product_not_found', 400);
+      // Container deposits are collected with the order but never taxed
+      const deposits = await resolveContainerDeposits(client, body.containers);
+      if (!deposits.ok) {
+        return deposits;
       }
 
-      const prod = productRes.rows[0];
-      if (!prod.is_available) {
-        await client.query('ROLLBACK');
-        return errorResponse(c, `Product '${prod.name}' is currently not available`, 'product_not_available', 400);
+      // A scheduled order pays the surcharges in force when it's due
+      const surcharges = await computeOrderSurcharges(
+        client, branchId, body.order_type, subtotal - discountAmount,
+        schedule.scheduledAt ? new Date(schedule.scheduledAt) : new Date(),
+      );
+
+      // Tax and service charge apply to the discounted amount
+      const taxAmount = taxes.tax_amount + surcharges.tax_amount;
+      const serviceChargeAmount = taxes.service_charge_amount;
+      const totalAmount = subtotal - discountAmount + serviceChargeAmount + surcharges.amount + taxAmount
+        + deliveryFee + deposits.total;
+
+      if (body.tab_id) {
+        const tab = await addOrderToTab(client, { tabId: body.tab_id, branchId, amount: totalAmount });
+        if (!tab.ok) {
+          return tab;
+        }
       }
 
-      subtotal += Number(prod.price) * item.quantity;
-    }
+      // Credited to the server of the table's section, if one is on shift
+      const serverId = await resolveOrderServer(client, body.table_id || null, userId ?? null);
+
+      // Insert order
+      const orderRes = await client.query(
+        `INSERT INTO orders (order_number, table_id, user_id, customer_name, order_type, status,
+                             subtotal, tax_amount, discount_amount, total_amount, notes, scheduled_at,
+                             delivery_address, delivery_phone, delivery_notes, delivery_fee, delivery_status, branch_id,
+                             service_charge_amount, deposit_amount, display_currency, exchange_rate, receipt_language,
+                             surcharge_amount, tab_id, server_id, covers)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25,
+                 $26, $27)
+         RETURNING id`,
+        [
+          orderNumber,
+          body.table_id || null,
+          userId,
+          body.customer_name || null,
+          body.order_type,
+          schedule.status,
+          subtotal,
+          taxAmount,
+          discountAmount,
+          totalAmount,
+          body.notes || null,
+          schedule.scheduledAt,
+          delivery?.address ?? null,
+          delivery?.phone ?? null,
+          delivery?.notes ?? null,
+          deliveryFee,
+          delivery ? 'unassigned' : null,
+          branchId,
+          serviceChargeAmount,
+          deposits.total,
+          currency?.code ?? null,
+          currency?.rate_to_idr ?? null,
+          body.receipt_language ?? null,
+          surcharges.amount,
+          body.tab_id || null,
+          serverId,
+          body.covers ?? null,
+        ],
+      );
 
-    // Get tax rate from system settings (default 11% Indonesian VAT)
-    let taxRate = 0.11;
-    const taxRes = await client.query(
-      "SELECT setting_value FROM system_settings WHERE setting_key = 'tax_rate'",
-    );
-    if (taxRes.rows.length > 0) {
-      const parsed = parseFloat(taxRes.rows[0].setting_value);
-      if (!isNaN(parsed)) taxRate = parsed / 100;
-    }
+      const orderId = orderRes.rows[0].id;
 
-    const taxAmount = subtotal * taxRate;
-    const totalAmount = subtotal + taxAmount;
+      if (body.remember_receipt_language && isReceiptLanguage(body.receipt_language)) {
+        await saveCustomerReceiptLanguage(client, delivery?.phone, body.receipt_language);
+      }
 
-    // Insert order
-    const orderRes = await client.query(
-      `INSERT INTO orders (order_number, table_id, user_id, customer_name, order_type, status,
-                           subtotal, tax_amount, discount_amount, total_amount, notes)
-       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-       RETURNING id`,
-      [
-        orderNumber,
-        body.table_id || null,
-        userId,
-        body.customer_name || null,
-        body.order_type,
-        'pending',
-        subtotal,
-        taxAmount,
-        0,
-        totalAmount,
-        body.notes || null,
-      ],
-    );
+      // Counter orders take stock like QR orders, weighed cuts by their
+      // recipe per kg
+      const shortage = await deductStockForOrder(client, orderId, lines);
+      if (shortage) {
+        return txFailure(stockShortageMessage(shortage), 'insufficient_stock', 409);
+      }
 
-    const orderId = orderRes.rows[0].id;
+      // Insert order items
+      for (const [idx, item] of body.items.entries()) {
+        const price = lines[idx].unit_price;
+        const qty = quantities[idx];
+        const tax = taxes.lines[idx];
+
+        await client.query(
+          `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions,
+                                    tax_amount, service_charge_amount, tax_exempt, service_exempt,
+                                    tax_class_id, tax_label, tax_rate, weight_grams, scale_device, price_schedule_id, course,
+                                    note_phrases)
+           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+          [
+            orderId, item.product_id, qty.quantity, price, lineTotal(price, qty.quantity), item.special_instructions || null,
+            tax.tax_amount, tax.service_charge_amount, tax.tax_exempt, tax.service_exempt,
+            tax.tax_class_id, tax.tax_label, tax.tax_rate, qty.weight_grams, qty.scale_device, priceScheduleIds[idx],
+            item.course ?? 1, item.note_phrases ?? [],
+          ],
+        );
+      }
 
-    // Insert order items
-    for (const item of body.items) {
-      const priceRes = await client.query('SELECT price FROM products WHERE id = $1', [item.product_id]);
-      const price = Number(priceRes.rows[0].price);
-      const totalPrice = price * item.quantity;
+      // Later courses wait for a server to fire them
+      if (body.order_type === 'dine_in') {
+        await holdOrderCourses(client, { orderId, holdAll: body.hold === true, userId: userId ?? null });
+      }
 
-      await client.query(
-        `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions)
-         VALUES ($1, $2, $3, $4, $5, $6)`,
-        [orderId, item.product_id, item.quantity, price, totalPrice, item.special_instructions || null],
-      );
-    }
+      // Audit applied pricing rules
+      await recordPricingAdjustments(client, orderId, pricing.adjustments);
+      await recordOrderSurcharges(client, orderId, surcharges.lines);
+      await recordContainerDeposits(client, orderId, deposits.lines);
 
-    // Update table status if dine-in
-    if (body.order_type === 'dine_in' && body.table_id) {
-      await client.query('UPDATE dining_tables SET is_occupied = true WHERE id = $1', [body.table_id]);
-    }
+      // Update table status if dine-in
+      if (body.order_type === 'dine_in' && body.table_id) {
+        await client.query('UPDATE dining_tables SET is_occupied = true WHERE id = $1', [body.table_id]);
+      }
 
-    await client.query('COMMIT');
+      if (body.park) {
+        const parked = await parkPendingOrder(client, {
+          orderId, reason: body.park_reason?.trim() || null, minutes: null, userId: userId ?? null,
+        });
+        if (!parked.ok) {
+          return parked;
+        }
+      }
 
-    // Fetch and return the created order
+      await emitWebhookEvent(client, 'order.created', () => orderEventData(client, orderId));
+
+      return { ok: true as const, orderId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { orderId } = result;
+    await refreshStockAvailability({ orderId });
+    ordersCreatedTotal.inc({ order_type: body.order_type, source: 'staff' });
+
+    // Fetch and return the created order with a wait estimate to quote the
+    // customer (none while it's parked). The staff member taking a delivery
+    // order is also shown any flags on the customer's phone number.
     const order = await getOrderByID(orderId);
-    return successResponse(c, 'Order created successfully', order, 201);
+    const waitEstimate = body.park ? null : await estimateOrderWait(pool, orderId).catch(() => null);
+    const flags = delivery ? await findCustomerFlags(pool, delivery.phone) : [];
+    const data = { ...order, wait_estimate: waitEstimate, ...(flags.length > 0 && { customer_flags: flags }) };
+    const message = flags.length > 0 ? 'Order created successfully; this customer is flagged' : 'Order created successfully';
+    return successResponse(c, message, data, 201);
   } catch (err) {
-    await client.query('ROLLBACK');
     return errorResponse(c, 'Failed to create order', (err as Error).message);
-  } finally {
-    client.release();
+  }
+}
+
+// ── UpdateOrderReceiptLanguage ──────────────────────────────────────────────
+// Sets the language of an order's receipt; null goes back to the customer's
+// preference or the default. remember_for_customer also keeps the choice
+// for the customer's next orders (delivery orders, which carry a phone).
+
+export async function updateOrderReceiptLanguage(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  let body: { receipt_language?: string | null; remember_for_customer?: boolean };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.receipt_language === undefined) {
+    return errorResponse(c, 'receipt_language is required (null clears it)', 'missing_receipt_language', 400);
+  }
+  if (body.receipt_language !== null && !isReceiptLanguage(body.receipt_language)) {
+    return errorResponse(c, "receipt_language must be 'id' or 'id_en'", 'invalid_receipt_language', 400);
+  }
+  if (body.remember_for_customer && body.receipt_language === null) {
+    return errorResponse(c, 'Choose a language to remember for the customer', 'invalid_receipt_language', 400);
+  }
+
+  const params: unknown[] = [orderId];
+  const ownBranch = c.get('branch_id') ?? null;
+  try {
+    const orderRes = await pool.query(
+      `SELECT o.delivery_phone FROM orders o WHERE o.id = $1${branchCondition('o.branch_id', ownBranch, params)}`,
+      params,
+    );
+    if (orderRes.rows.length === 0) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+    const phone: string | null = orderRes.rows[0].delivery_phone;
+
+    // Remembering writes nothing when there is no usable phone, so it goes first
+    if (body.remember_for_customer && isReceiptLanguage(body.receipt_language)
+        && !(await saveCustomerReceiptLanguage(pool, phone, body.receipt_language))) {
+      return errorResponse(c, 'This order has no customer phone number to remember the language for', 'no_customer_phone', 400);
+    }
+    await pool.query(
+      'UPDATE orders SET receipt_language = $2, updated_at = NOW() WHERE id = $1',
+      [orderId, body.receipt_language],
+    );
+
+    const receipt = await resolveReceiptLanguage(pool, { receipt_language: body.receipt_language, delivery_phone: phone });
+    return successResponse(c, 'Receipt language updated successfully', receipt);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update receipt language', (err as Error).message);
   }
 }
 
@@ -477,54 +1057,81 @@ export async function updateOrderStatus(c: Context) {
     return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
-  const validStatuses = ['pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'];
-  if (!validStatuses.includes(body.status)) {
+  if (!ORDER_STATUS_UPDATES.includes(body.status)) {
     return errorResponse(c, 'Invalid order status', 'invalid_status', 400);
   }
 
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
-
-    // Get current status
-    const currentRes = await client.query('SELECT status FROM orders WHERE id = $1', [orderId]);
-    if (currentRes.rows.length === 0) {
-      await client.query('ROLLBACK');
-      return errorResponse(c, 'Order not found', 'order_not_found', 404);
-    }
+    const result = await withTransaction(async (client) => {
+      // Get current status
+      const currentRes = await client.query('SELECT status, created_at, branch_id FROM orders WHERE id = $1', [orderId]);
+      const branchId = c.get('branch_id');
+      if (currentRes.rows.length === 0 || (branchId && currentRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
 
-    const currentStatus = currentRes.rows[0].status;
+      const currentStatus = currentRes.rows[0].status;
 
-    // Build update query
-    let updateQuery = 'UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP';
-    const args: unknown[] = [body.status, orderId];
+      // A parked order goes back to the kitchen through resume only
+      if (currentStatus === 'parked' && body.status !== 'cancelled') {
+        return txFailure('Order is parked; resume it before changing its status', 'order_parked', 409);
+      }
 
-    if (body.status === 'served') {
-      updateQuery += ', served_at = CURRENT_TIMESTAMP';
-    } else if (body.status === 'completed') {
-      updateQuery += ', completed_at = CURRENT_TIMESTAMP';
-    }
+      // Build update query
+      let updateQuery = 'UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP';
+      const args: unknown[] = [body.status, orderId];
 
-    updateQuery += ' WHERE id = $2';
-    await client.query(updateQuery, args);
+      if (body.status === 'served') {
+        updateQuery += ', served_at = CURRENT_TIMESTAMP';
+      } else if (body.status === 'completed') {
+        updateQuery += ', completed_at = CURRENT_TIMESTAMP';
+      }
 
-    // Log status change
-    await client.query(
-      `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
-       VALUES ($1, $2, $3, $4, $5)`,
-      [orderId, currentStatus, body.status, userId, body.notes || null],
-    );
+      updateQuery += ' WHERE id = $2';
+      await client.query(updateQuery, args);
 
-    // Free table if completed or cancelled
-    if (body.status === 'completed' || body.status === 'cancelled') {
+      // Log status change
       await client.query(
-        `UPDATE dining_tables SET is_occupied = false
-         WHERE id IN (SELECT table_id FROM orders WHERE id = $1 AND table_id IS NOT NULL)`,
-        [orderId],
+        `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+         VALUES ($1, $2, $3, $4, $5)`,
+        [orderId, currentStatus, body.status, userId, body.notes || null],
       );
+
+      // Accepting a held customer order sends the rest of it to the stations
+      if (currentStatus === 'pending' && !['pending', 'cancelled'].includes(body.status)) {
+        await releaseHeldItems(client, orderId);
+      }
+
+      // Return the order's stock
+      if (body.status === 'cancelled' && currentStatus !== 'cancelled') {
+        await releaseStockForOrder(client, orderId, userId);
+      }
+
+      // Free table if completed or cancelled
+      if (body.status === 'completed' || body.status === 'cancelled') {
+        await client.query(
+          `UPDATE dining_tables SET is_occupied = false
+           WHERE id IN (SELECT table_id FROM orders WHERE id = $1 AND table_id IS NOT NULL)`,
+          [orderId],
+        );
+      }
+
+      if (body.status === 'completed' && currentStatus !== 'completed') {
+        await emitWebhookEvent(client, 'order.completed', () => orderEventData(client, orderId));
+      }
+
+      return { ok: true as const, currentStatus, currentRes };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { currentStatus, currentRes } = result;
+    if (body.status === 'cancelled' && currentStatus !== 'cancelled') {
+      await refreshStockAvailability({ orderId });
     }
 
-    await client.query('COMMIT');
+    orderStatusTransitionsTotal.inc({ status: body.status });
+    if (body.status === 'ready' && currentStatus !== 'ready') {
+      kitchenTicketDuration.observe((Date.now() - new Date(currentRes.rows[0].created_at).getTime()) / 1000);
+    }
 
     // Create customer notifications for key status changes
     if (body.status === 'ready') {
@@ -539,10 +1146,666 @@ export async function updateOrderStatus(c: Context) {
     const order = await getOrderByID(orderId);
     return successResponse(c, 'Order status updated successfully', order);
   } catch (err) {
-    await client.query('ROLLBACK');
     return errorResponse(c, 'Failed to update order status', (err as Error).message);
-  } finally {
-    client.release();
+  }
+}
+
+// ── ParkOrder ───────────────────────────────────────────────────────────────
+// Takes a pending order off the kitchen board without cancelling it. It is
+// cancelled automatically if not resumed within expires_in_minutes
+// (parked_order_expiry_minutes by default).
+
+export async function parkOrder(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  let body: { reason?: string; expires_in_minutes?: number };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const minutes = body.expires_in_minutes;
+  if (minutes !== undefined && (!Number.isInteger(minutes) || minutes < 1 || minutes > MAX_PARK_MINUTES)) {
+    return errorResponse(c, `expires_in_minutes must be a whole number from 1 to ${MAX_PARK_MINUTES}`, 'invalid_expiry', 400);
+  }
+
+  const branchId = c.get('branch_id');
+  try {
+    const result = await withTransaction(async (client) => {
+      if (branchId) {
+        const scoped = await client.query('SELECT 1 FROM orders WHERE id = $1 AND branch_id = $2', [orderId, branchId]);
+        if (scoped.rows.length === 0) {
+          return txFailure('Order not found', 'order_not_found', 404);
+        }
+      }
+
+      return parkPendingOrder(client, {
+        orderId,
+        reason: body.reason?.trim() || null,
+        minutes: minutes ?? null,
+        userId: c.get('user_id') ?? null,
+      });
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const order = await getOrderByID(orderId);
+    return successResponse(c, 'Order parked successfully', order);
+  } catch (err) {
+    return errorResponse(c, 'Failed to park order', (err as Error).message);
+  }
+}
+
+// ── ResumeOrder ─────────────────────────────────────────────────────────────
+// Sends a parked order to the kitchen as a new pending order.
+
+export async function resumeOrder(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  const branchId = c.get('branch_id');
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        'SELECT order_number, order_type, branch_id FROM orders WHERE id = $1 FOR UPDATE',
+        [orderId],
+      );
+      if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
+
+      const resumed = await resumeParkedOrder(client, orderId, c.get('user_id') ?? null);
+      if (!resumed.ok) {
+        return resumed;
+      }
+
+      return { ok: true as const, orderRes };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { orderRes } = result;
+
+    const { order_number: orderNumber, order_type: orderType } = orderRes.rows[0];
+    notifyOrderCreated(orderNumber, orderType);
+
+    const order = await getOrderByID(orderId);
+    const waitEstimate = await estimateOrderWait(pool, orderId).catch(() => null);
+    return successResponse(c, 'Order resumed successfully', { ...order, wait_estimate: waitEstimate });
+  } catch (err) {
+    return errorResponse(c, 'Failed to resume order', (err as Error).message);
+  }
+}
+
+// ── FireOrderCourse ─────────────────────────────────────────────────────────
+// Sends a held course to the kitchen: ?course=2, or the next held course
+// when no course is given.
+
+export async function fireOrderCourse(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  const courseParam = c.req.query('course');
+  const course = courseParam === undefined || courseParam === '' ? null : Number(courseParam);
+  if (course !== null && !isCourse(course)) {
+    return errorResponse(c, `course must be a whole number from 1 to ${MAX_COURSE}`, 'invalid_course', 400);
+  }
+
+  const branchId = c.get('branch_id');
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        `SELECT o.order_number, o.branch_id, t.table_number
+         FROM orders o
+         LEFT JOIN dining_tables t ON t.id = o.table_id
+         WHERE o.id = $1`,
+        [orderId],
+      );
+      if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
+
+      const fired = await fireCourse(client, { orderId, course, userId: c.get('user_id') ?? null });
+      if (!fired.ok) {
+        return fired;
+      }
+      return { ...fired, order: orderRes.rows[0] };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    notifyCourseFired(result.order.order_number, result.order.table_number, result.course, result.releasedItems);
+
+    return successResponse(c, `Course ${result.course} fired`, {
+      order_id: orderId,
+      course: result.course,
+      fired_at: result.firedAt,
+      released_items: result.releasedItems,
+      held_courses: result.heldCourses,
+      courses: await loadOrderCourses(pool, orderId),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fire course', (err as Error).message);
+  }
+}
+
+// ── GetOrderCourses ─────────────────────────────────────────────────────────
+// Per-course status and timing (fired, ready, served, the wait between
+// courses). Empty for an order that isn't coursed.
+
+export async function getOrderCourses(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  try {
+    const orderRes = await pool.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+    const branchId = c.get('branch_id');
+    if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+    return successResponse(c, 'Order courses retrieved successfully', await loadOrderCourses(pool, orderId));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order courses', (err as Error).message);
+  }
+}
+
+// ── UpdateOrderItems ───────────────────────────────────────────────────────────
+// Adds, re-quantifies or voids items on an open order in one transaction and
+// reprices the whole basket, so pricing rules see the final item list.
+// Additions and increases take stock like a new order; cuts and voids give it
+// back.
+// Quantity cuts and voids on items the kitchen has started need a manager.
+// An update can carry a scale reading (weight_grams) instead of a quantity,
+// which is how a weighed cut gets its final quantity once it is on the scale;
+// the difference from the earlier quantity is taken from or returned to stock.
+
+const ITEM_EDIT_LOCKED_STATUSES = ['completed', 'cancelled'];
+
+export async function updateOrderItems(c: Context) {
+  const orderId = c.req.param('id');
+  const userId = c.get('user_id');
+
+  let body: {
+    add?: ({ product_id: string; special_instructions?: string; note_phrases?: string[]; course?: number } & ItemQuantityInput)[];
+    update?: ({ item_id: string } & ItemQuantityInput)[];
+    void?: { item_id: string }[];
+    reason?: string;
+  };
+
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const adds = body.add ?? [];
+  const updates = body.update ?? [];
+  const voids = body.void ?? [];
+
+  if (adds.length === 0 && updates.length === 0 && voids.length === 0) {
+    return errorResponse(c, 'No item changes provided', 'no_changes', 400);
+  }
+
+  if ([...adds, ...updates].some((item) => !hasQuantityInput(item))) {
+    return errorResponse(c, 'Each item needs a quantity or a weight_grams scale reading', 'invalid_quantity', 400);
+  }
+
+  // The order type is checked once the order is loaded
+  const courseError = validateCourses(c, null, adds, false);
+  if (courseError) {
+    return errorResponse(c, courseError.message, courseError.code, courseError.status);
+  }
+  if (!normalizeItemNotePhrases(adds)) {
+    return errorResponse(c, `note_phrases must be a list of at most ${MAX_ITEM_NOTE_PHRASES} phrase codes`, 'invalid_note_phrases', 400);
+  }
+
+  const touched = [...updates.map((u) => u.item_id), ...voids.map((v) => v.item_id)];
+  if (new Set(touched).size !== touched.length) {
+    return errorResponse(c, 'Each item can only be changed once per request', 'duplicate_item_change', 400);
+  }
+
+  const reason = body.reason?.trim() || null;
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const orderRes = await client.query(
+        `SELECT o.order_number, o.status, o.order_type, o.branch_id, o.tab_id, t.table_number
+         FROM orders o
+         LEFT JOIN dining_tables t ON t.id = o.table_id
+         WHERE o.id = $1
+         FOR UPDATE OF o`,
+        [orderId],
+      );
+      const branchId = c.get('branch_id');
+      if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
+
+      const order = orderRes.rows[0];
+      if (ITEM_EDIT_LOCKED_STATUSES.includes(order.status)) {
+        return txFailure(`Order items cannot be changed - order is ${order.status}`, 'invalid_order_status', 400);
+      }
+      if (order.order_type !== 'dine_in' && adds.some((a) => (a.course ?? 1) > 1)) {
+        return txFailure('Courses can only be used on dine-in orders', 'courses_dine_in_only', 400);
+      }
+
+      const itemsRes = await client.query(
+        `SELECT oi.id, oi.product_id, oi.quantity, oi.unit_price, oi.status, p.name, p.sale_unit
+         FROM order_items oi
+         JOIN products p ON p.id = oi.product_id
+         WHERE oi.order_id = $1
+         FOR UPDATE OF oi`,
+        [orderId],
+      );
+      const existing = new Map<string, Record<string, any>>(
+        itemsRes.rows.map((r) => [r.id, { ...r, quantity: Number(r.quantity) }]),
+      );
+
+      for (const id of touched) {
+        if (!existing.has(id)) {
+          return txFailure(`Order item '${id}' not found on this order`, 'order_item_not_found', 404);
+        }
+      }
+
+      const newQuantities = new Map<string, ResolvedQuantity>();
+      for (const u of updates) {
+        const qty = resolveItemQuantity(existing.get(u.item_id)! as { name: string; sale_unit: string }, u);
+        if (!qty.ok) {
+          return txFailure(qty.message, qty.code, 400);
+        }
+        newQuantities.set(u.item_id, qty.value);
+      }
+
+      for (const id of touched) {
+        const item = existing.get(id)!;
+        const reduces = voids.some((v) => v.item_id === id)
+          || (newQuantities.has(id) && newQuantities.get(id)!.quantity < item.quantity);
+        if (reduces && item.status !== 'pending' && !can(c, 'orders.edit_sent_items')) {
+          return txFailure(`'${item.name}' is already ${item.status}; a manager must reduce or void it`, 'item_in_progress', 403);
+        }
+      }
+
+      if (voids.length === itemsRes.rows.length && adds.length === 0) {
+        return txFailure('An order must keep at least one item; cancel the order instead', 'empty_order', 400);
+      }
+
+      // Voids
+      for (const v of voids) {
+        const item = existing.get(v.item_id)!;
+        await client.query('DELETE FROM order_items WHERE id = $1', [v.item_id]);
+        await returnItemStock(client, orderId, item.product_id, item.quantity, userId);
+        await recordItemChange(client, orderId, item, 'void', item.quantity, 0, reason, userId);
+      }
+
+      // Quantity changes
+      for (const u of updates) {
+        const item = existing.get(u.item_id)!;
+        const qty = newQuantities.get(u.item_id)!;
+        if (qty.quantity === item.quantity) continue;
+
+        if (qty.quantity > item.quantity) {
+          const shortage = await deductStockForOrder(client, orderId, [
+            { product_id: item.product_id, name: item.name, quantity: qty.quantity - item.quantity },
+          ]);
+          if (shortage) {
+            return txFailure(stockShortageMessage(shortage), 'insufficient_stock', 409);
+          }
+        } else {
+          await returnItemStock(client, orderId, item.product_id, item.quantity - qty.quantity, userId);
+        }
+
+        await client.query(
+          `UPDATE order_items
+           SET quantity = $1, total_price = ROUND(unit_price * $1, 2), weight_grams = $3, scale_device = $4, updated_at = NOW()
+           WHERE id = $2`,
+          [qty.quantity, u.item_id, qty.weight_grams, qty.scale_device],
+        );
+        await recordItemChange(client, orderId, item, 'update_quantity', item.quantity, qty.quantity, reason, userId);
+      }
+
+      // Additions are priced at the current menu price, like a new order
+      const added: { name: string; quantity: number }[] = [];
+      const addedIds: string[] = [];
+      for (const a of adds) {
+        const productRes = await client.query(
+          'SELECT name, price, is_available, sale_unit, category_id FROM products WHERE id = $1 AND deleted_at IS NULL',
+          [a.product_id],
+        );
+        if (productRes.rows.length === 0) {
+          return txFailure(`Product with ID '${a.product_id}' not found`, 'product_not_found', 400);
+        }
+
+        const prod = productRes.rows[0];
+        if (!prod.is_available) {
+          return txFailure(`Product '${prod.name}' is currently not available`, 'product_not_available', 400);
+        }
+        const unusable = await findUnusableNotePhrase(client, a.note_phrases ?? [], prod.category_id);
+        if (unusable) {
+          return txFailure(`Note phrase '${unusable}' is not available for ${prod.name}`, 'note_phrase_unavailable', 400);
+        }
+
+        const resolved = resolveItemQuantity(prod, a);
+        if (!resolved.ok) {
+          return txFailure(resolved.message, resolved.code, 400);
+        }
+        const qty = resolved.value;
+
+        const shortage = await deductStockForOrder(client, orderId, [
+          { product_id: a.product_id, name: prod.name, quantity: qty.quantity },
+        ]);
+        if (shortage) {
+          return txFailure(stockShortageMessage(shortage), 'insufficient_stock', 409);
+        }
+
+        const scheduled = (await loadScheduledPrices(
+          client, [{ id: a.product_id, category_id: prod.category_id, price: Number(prod.price) }],
+        )).get(a.product_id);
+        const price = scheduled?.price ?? Number(prod.price);
+        const itemRes = await client.query(
+          `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions, weight_grams, scale_device,
+                                    price_schedule_id, course, note_phrases)
+           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+          [orderId, a.product_id, qty.quantity, price, lineTotal(price, qty.quantity), a.special_instructions || null,
+            qty.weight_grams, qty.scale_device, scheduled?.price_schedule_id ?? null, a.course ?? 1, a.note_phrases ?? []],
+        );
+        addedIds.push(itemRes.rows[0].id);
+        await recordItemChange(
+          client, orderId,
+          { id: itemRes.rows[0].id, product_id: a.product_id, name: prod.name, unit_price: price },
+          'add', 0, qty.quantity, reason, userId,
+        );
+        added.push({ name: prod.name, quantity: qty.quantity });
+      }
+
+      // Items for a held course wait with it
+      const heldAdded = order.order_type === 'dine_in' ? await holdAddedItems(client, orderId, addedIds) : 0;
+
+      const totals = await repriceOrder(client, orderId);
+
+      const paidRes = await client.query(
+        "SELECT COALESCE(SUM(amount), 0) AS total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
+        [orderId],
+      );
+      if (Number(paidRes.rows[0].total_paid) > totals.total_amount) {
+        return txFailure('New order total is lower than the amount already paid; refund first', 'total_below_paid', 409);
+      }
+
+      // The repriced order is already in the tab's balance
+      if (order.tab_id) {
+        const tab = await addOrderToTab(client, { tabId: order.tab_id, branchId: order.branch_id, amount: 0 });
+        if (!tab.ok) {
+          return tab;
+        }
+      }
+
+      // Finished tickets go back on the kitchen board when new items arrive
+      if (added.length > heldAdded && (order.status === 'ready' || order.status === 'served')) {
+        await client.query(
+          "UPDATE orders SET status = 'preparing', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+          [orderId],
+        );
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+           VALUES ($1, $2, 'preparing', $3, 'Items added to order')`,
+          [orderId, order.status, userId],
+        );
+      }
+
+      return { ok: true as const, order, existing, added, heldAdded };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { order, existing, added, heldAdded } = result;
+    // Voided items are gone from the order by now
+    await refreshStockAvailability({
+      productIds: [...new Set([...[...existing.values()].map((item) => item.product_id), ...adds.map((a) => a.product_id)])],
+    });
+
+    // Scheduled and parked orders aren't on the kitchen board
+    if (added.length > heldAdded && order.status !== 'scheduled' && order.status !== 'parked') {
+      notifyOrderItemsAdded(order.order_number, order.table_number, added);
+    }
+
+    const updated = await getOrderByID(orderId);
+    return successResponse(c, 'Order items updated successfully', updated);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update order items', (err as Error).message);
+  }
+}
+
+// Courses are for dine-in service, and someone has to fire the held ones,
+// so coursing an order takes orders.fire_courses
+function validateCourses(
+  c: Context,
+  orderType: string | null,
+  items: { course?: number }[],
+  hold: boolean,
+): { message: string; code: string; status: 400 | 403 } | null {
+  if (items.some((item) => item.course !== undefined && !isCourse(item.course))) {
+    return { message: `course must be a whole number from 1 to ${MAX_COURSE}`, code: 'invalid_course', status: 400 };
+  }
+  if (!hold && !items.some((item) => (item.course ?? 1) > 1)) return null;
+  if (orderType !== null && orderType !== 'dine_in') {
+    return { message: 'Courses can only be used on dine-in orders', code: 'courses_dine_in_only', status: 400 };
+  }
+  if (!can(c, 'orders.fire_courses')) {
+    return { message: 'You do not have permission to hold or fire courses', code: 'insufficient_permissions', status: 403 };
+  }
+  return null;
+}
+
+// A deductStockForOrder shortage: a daily special, or product or ingredient stock
+function stockShortageMessage(shortage: { name: string; remaining: number }): string {
+  return shortage.remaining === 0
+    ? `'${shortage.name}' is sold out`
+    : `Only ${shortage.remaining} of '${shortage.name}' left`;
+}
+
+async function returnItemStock(client: PoolClient, orderId: string, productId: string, quantity: number, userId: string | null) {
+  await releaseSpecialPortionsForProduct(client, orderId, productId, quantity);
+  await restockOrderItems(client, orderId, [{ product_id: productId, quantity }], userId, 'Item removed from order');
+}
+
+async function recordItemChange(
+  client: PoolClient,
+  orderId: string,
+  item: { id: string; product_id: string; name: string; unit_price: number | string },
+  action: 'add' | 'update_quantity' | 'void',
+  previousQuantity: number,
+  newQuantity: number,
+  reason: string | null,
+  userId: string | null,
+) {
+  await client.query(
+    `INSERT INTO order_item_changes
+       (order_id, order_item_id, product_id, product_name, action, previous_quantity, new_quantity, unit_price, reason, changed_by)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+    [orderId, item.id, item.product_id, item.name, action, previousQuantity, newQuantity, item.unit_price, reason, userId],
+  );
+}
+
+// Recomputes subtotal, discounts, service charge and tax from the current
+// items and replaces the order's pricing audit rows. Remakes are free and
+// stay out of it.
+async function repriceOrder(client: PoolClient, orderId: string): Promise<{ total_amount: number }> {
+  const itemsRes = await client.query(
+    `SELECT oi.id, oi.product_id, oi.quantity, oi.unit_price, p.name, p.category_id
+     FROM order_items oi
+     JOIN products p ON p.id = oi.product_id
+     WHERE oi.order_id = $1 AND oi.is_remake = false
+     ORDER BY oi.created_at`,
+    [orderId],
+  );
+
+  const lines: PricingLine[] = itemsRes.rows.map((r) => ({
+    product_id: r.product_id,
+    category_id: r.category_id,
+    name: r.name,
+    unit_price: Number(r.unit_price),
+    quantity: Number(r.quantity),
+  }));
+
+  const orderRes = await client.query(
+    `SELECT order_type, delivery_fee, deposit_amount, branch_id, COALESCE(scheduled_at, created_at) AS priced_at
+     FROM orders WHERE id = $1`,
+    [orderId],
+  );
+
+  // Pricing rules and surcharges stay those of the window the order was
+  // placed (or is due) in
+  const pricedAt = new Date(orderRes.rows[0].priced_at);
+  const pricing = await priceOrder(client, lines, pricedAt);
+  const taxes = await computeOrderTaxes(
+    client, orderRes.rows[0].branch_id, orderRes.rows[0].order_type, taxLines(lines, pricing.line_discounts),
+  );
+  const surcharges = await computeOrderSurcharges(
+    client, orderRes.rows[0].branch_id, orderRes.rows[0].order_type, pricing.subtotal - pricing.discount_amount, pricedAt,
+  );
+  const taxAmount = taxes.tax_amount + surcharges.tax_amount;
+  const serviceChargeAmount = taxes.service_charge_amount;
+
+  // Delivery orders can cross the free-delivery threshold either way
+  let deliveryFee = Number(orderRes.rows[0].delivery_fee);
+  if (orderRes.rows[0].order_type === 'delivery') {
+    deliveryFee = computeDeliveryFee(await loadDeliverySettings(client), pricing.subtotal - pricing.discount_amount);
+  }
+  const totalAmount = pricing.subtotal - pricing.discount_amount + serviceChargeAmount + surcharges.amount + taxAmount
+    + deliveryFee + Number(orderRes.rows[0].deposit_amount);
+
+  await client.query(
+    `UPDATE orders SET subtotal = $1, discount_amount = $2, tax_amount = $3, delivery_fee = $4, total_amount = $5,
+                       service_charge_amount = $6, surcharge_amount = $7, updated_at = CURRENT_TIMESTAMP
+     WHERE id = $8`,
+    [pricing.subtotal, pricing.discount_amount, taxAmount, deliveryFee, totalAmount, serviceChargeAmount, surcharges.amount, orderId],
+  );
+  for (const [idx, item] of itemsRes.rows.entries()) {
+    const tax = taxes.lines[idx];
+    await client.query(
+      `UPDATE order_items SET tax_amount = $2, service_charge_amount = $3, tax_exempt = $4, service_exempt = $5,
+                              tax_class_id = $6, tax_label = $7, tax_rate = $8
+       WHERE id = $1`,
+      [
+        item.id, tax.tax_amount, tax.service_charge_amount, tax.tax_exempt, tax.service_exempt,
+        tax.tax_class_id, tax.tax_label, tax.tax_rate,
+      ],
+    );
+  }
+  await client.query('DELETE FROM order_pricing_adjustments WHERE order_id = $1', [orderId]);
+  await recordPricingAdjustments(client, orderId, pricing.adjustments);
+  await recordOrderSurcharges(client, orderId, surcharges.lines);
+
+  return { total_amount: totalAmount };
+}
+
+// ── GetOrderItemHistory ────────────────────────────────────────────────────────
+
+export async function getOrderItemHistory(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  try {
+    const orderRes = await pool.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+    const branchId = c.get('branch_id');
+    if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+
+    const res = await pool.query(
+      `SELECT ch.*, u.username AS changed_by_username
+       FROM order_item_changes ch
+       LEFT JOIN users u ON u.id = ch.changed_by
+       WHERE ch.order_id = $1
+       ORDER BY ch.created_at ASC`,
+      [orderId],
+    );
+
+    return successResponse(c, 'Order item history retrieved successfully', res.rows.map((row) => ({
+      id: row.id,
+      order_id: row.order_id,
+      order_item_id: row.order_item_id,
+      product_id: row.product_id,
+      product_name: row.product_name,
+      action: row.action,
+      previous_quantity: Number(row.previous_quantity),
+      new_quantity: Number(row.new_quantity),
+      unit_price: Number(row.unit_price),
+      reason: row.reason,
+      changed_by: row.changed_by_username ?? 'System',
+      created_at: row.created_at,
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order item history', (err as Error).message);
+  }
+}
+
+// ── GetOrderItemStatusHistory ──────────────────────────────────────────────────
+// One item's kitchen status changes, oldest first. duration_seconds is how
+// long the item spent in previous_status; the first change counts from when
+// the item reached its station.
+
+export async function getOrderItemStatusHistory(c: Context) {
+  const orderId = c.req.param('id');
+  const itemId = c.req.param('item_id');
+  if (!isUUID(orderId) || !isUUID(itemId)) {
+    return errorResponse(c, 'Order item not found', 'order_item_not_found', 404);
+  }
+
+  try {
+    const orderRes = await pool.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+    const branchId = c.get('branch_id');
+    if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+      return errorResponse(c, 'Order item not found', 'order_item_not_found', 404);
+    }
+
+    const historyRes = await pool.query(
+      `SELECT h.id, h.previous_status, h.new_status, h.station, h.created_at,
+              h.changed_by, u.username AS changed_by_username,
+              EXTRACT(EPOCH FROM h.created_at - COALESCE(
+                LAG(h.created_at) OVER (ORDER BY h.created_at), oi.released_at, oi.created_at, ch.created_at
+              ))::int AS duration_seconds
+       FROM order_item_status_history h
+       LEFT JOIN users u ON u.id = h.changed_by
+       LEFT JOIN order_items oi ON oi.id = h.order_item_id
+       LEFT JOIN order_item_changes ch ON ch.order_item_id = h.order_item_id AND ch.action = 'add'
+       WHERE h.order_id = $1 AND h.order_item_id = $2
+       ORDER BY h.created_at ASC`,
+      [orderId, itemId],
+    );
+
+    const itemRes = await pool.query(
+      `SELECT oi.id, oi.product_id, p.name AS product_name, oi.quantity::float8 AS quantity, oi.status, oi.created_at, oi.released_at
+       FROM order_items oi
+       LEFT JOIN products p ON p.id = oi.product_id
+       WHERE oi.id = $1 AND oi.order_id = $2`,
+      [itemId, orderId],
+    );
+    // A voided item is gone from order_items but its history remains
+    if (itemRes.rows.length === 0 && historyRes.rows.length === 0) {
+      return errorResponse(c, 'Order item not found', 'order_item_not_found', 404);
+    }
+
+    return successResponse(c, 'Order item status history retrieved successfully', {
+      item: itemRes.rows[0] ?? null,
+      history: historyRes.rows.map((row) => ({
+        id: row.id,
+        previous_status: row.previous_status,
+        new_status: row.new_status,
+        station: row.station,
+        changed_by: row.changed_by,
+        changed_by_username: row.changed_by_username ?? 'System',
+        duration_seconds: row.duration_seconds,
+        created_at: row.created_at,
+      })),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order item status history', (err as Error).message);
   }
 }
 
@@ -0,0 +1,177 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { isUUID } from '../services/branches.js';
+import {
+  verifyNotification,
+  notificationOutcome,
+  parseGatewayOrderId,
+  isRefundNotification,
+  type GatewayNotification,
+} from '../services/payment-gateway.js';
+import { settleGatewayTopup, TOPUP_GATEWAY_PREFIX } from '../services/corporate-wallet.js';
+import { settlePaymentLink, PAYMENT_LINK_GATEWAY_PREFIX } from '../services/payment-links.js';
+import { settleTabNotification, TAB_GATEWAY_PREFIX } from '../services/tabs.js';
+import {
+  GATEWAY_REFUND_JOB,
+  GATEWAY_REFUND_SELECT,
+  GATEWAY_REFUND_STATUSES,
+  confirmGatewayRefunds,
+  requeueGatewayRefund,
+} from '../services/gateway-refunds.js';
+import { closeDeadLetters } from '../services/dead-letters.js';
+
+// ── HandleGatewayNotification ───────────────────────────────────────────────
+// Webhook called by the payment gateway. The gateway retries on non-2xx, so
+// unknown references are acknowledged rather than rejected.
+
+export async function handleGatewayNotification(c: Context) {
+  let body: GatewayNotification;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!verifyNotification(body)) {
+    console.log(`PAYMENT_GATEWAY_ALERT: Invalid notification signature for ${body.order_id}`);
+    return errorResponse(c, 'Invalid signature', 'invalid_signature', 403);
+  }
+
+  const ref = parseGatewayOrderId(body.order_id);
+  const outcome = notificationOutcome(body);
+
+  try {
+    // Refunds of a charge are reported under the charge's order id
+    if (isRefundNotification(body)) {
+      await confirmGatewayRefunds(body);
+    } else if (ref?.prefix === TOPUP_GATEWAY_PREFIX) {
+      await settleGatewayTopup(ref.id, outcome);
+    } else if (ref?.prefix === PAYMENT_LINK_GATEWAY_PREFIX) {
+      await settlePaymentLink(ref.id, outcome, body);
+    } else if (ref?.prefix === TAB_GATEWAY_PREFIX) {
+      await settleTabNotification(ref.id, body);
+    } else {
+      console.log(`Payment gateway notification for unknown reference ${body.order_id}`);
+    }
+
+    return successResponse(c, 'Notification processed');
+  } catch (err) {
+    return errorResponse(c, 'Failed to process notification', (err as Error).message);
+  }
+}
+
+// ── GetGatewayRefunds ───────────────────────────────────────────────────────
+
+export async function getGatewayRefunds(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status');
+  const orderId = c.req.query('order_id');
+
+  if (status && !GATEWAY_REFUND_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${GATEWAY_REFUND_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+  if (orderId && !isUUID(orderId)) {
+    return errorResponse(c, 'Invalid order_id', 'invalid_order_id', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (status) {
+    conditions.push(`r.status = $${paramIdx++}`);
+    params.push(status);
+  }
+  if (orderId) {
+    conditions.push(`p.order_id = $${paramIdx++}`);
+    params.push(orderId);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(
+          `SELECT COUNT(*) AS total FROM gateway_refunds r JOIN payments p ON p.id = r.refund_payment_id ${where}`,
+          params,
+        );
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${GATEWAY_REFUND_SELECT} ${where}
+           ORDER BY r.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Gateway refunds retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { status, order_id: orderId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch gateway refunds', (err as Error).message);
+  }
+}
+
+// ── RetryGatewayRefund ──────────────────────────────────────────────────────
+// Sends a failed refund to the gateway again, if the payment hasn't been
+// refunded some other way meanwhile.
+
+export async function retryGatewayRefund(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Gateway refund not found', 'not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const refundRes = await client.query(
+        `SELECT r.id, r.status, r.amount, r.refund_payment_id, p.refund_of
+         FROM gateway_refunds r
+         JOIN payments p ON p.id = r.refund_payment_id
+         WHERE r.id = $1
+         FOR UPDATE OF r`,
+        [id],
+      );
+      const refund = refundRes.rows[0];
+      if (!refund) {
+        return txFailure('Gateway refund not found', 'not_found', 404);
+      }
+      if (refund.status !== 'failed') {
+        return txFailure(`Only failed refunds can be retried - refund is ${refund.status}`, 'invalid_refund_status', 409);
+      }
+
+      // Same lock and balance as a new refund of the payment
+      const originalRes = await client.query('SELECT amount FROM payments WHERE id = $1 FOR UPDATE', [refund.refund_of]);
+      const refundedRes = await client.query(
+        `SELECT COALESCE(SUM(-amount), 0) AS refunded FROM payments
+         WHERE refund_of = $1 AND status IN ('completed', 'pending') AND id <> $2`,
+        [refund.refund_of, refund.refund_payment_id],
+      );
+      const refundable = Number(originalRes.rows[0].amount) - Number(refundedRes.rows[0].refunded);
+      if (Number(refund.amount) > refundable) {
+        return txFailure(`Refund amount exceeds refundable balance of ${refundable}`, 'amount_exceeds_refundable', 409);
+      }
+
+      await requeueGatewayRefund(client, id);
+      await closeDeadLetters(client, { jobType: GATEWAY_REFUND_JOB, payload: { gateway_refund_id: id } }, userId ?? null);
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${GATEWAY_REFUND_SELECT} WHERE r.id = $1`, [id]);
+    return successResponse(c, 'Refund resubmitted to the payment gateway', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to retry gateway refund', (err as Error).message);
+  }
+}
@@ -0,0 +1,142 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { messagingConfigured, sendTextMessage, toInternationalPhone } from '../lib/messaging.js';
+import { createCharge, gatewayOrderId, isGatewayConfigured } from '../services/payment-gateway.js';
+import {
+  PAYMENT_LINK_GATEWAY_PREFIX,
+  loadPaymentLinkExpiryMinutes,
+  loadOrderPaymentLinks,
+} from '../services/payment-links.js';
+
+// ── CreatePaymentLink ───────────────────────────────────────────────────────
+// Link for the order's outstanding balance. An order has one live link at a
+// time: creating a new one supersedes the previous pending link (a payment
+// still made through it is recorded, see settlePaymentLink). With send: true
+// the link is texted to `phone`, or the delivery phone on the order.
+
+export async function createPaymentLink(c: Context) {
+  const orderId = c.req.param('id');
+  const userId = c.get('user_id');
+  const ownBranch = c.get('branch_id');
+
+  let body: { send?: boolean; phone?: string; email?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!isGatewayConfigured()) {
+    return errorResponse(c, 'Online payments are not configured', 'gateway_not_configured', 503);
+  }
+
+  try {
+    const orderRes = await pool.query(
+      `SELECT o.id, o.order_number, o.status, o.total_amount, o.customer_name, o.delivery_phone, o.branch_id,
+              (SELECT COALESCE(SUM(p.amount), 0) FROM payments p WHERE p.order_id = o.id AND p.status = 'completed') AS total_paid
+       FROM orders o WHERE o.id = $1`,
+      [orderId],
+    );
+    const order = orderRes.rows[0];
+    if (!order || (ownBranch && order.branch_id !== ownBranch)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+    if (order.status === 'cancelled' || order.status === 'completed') {
+      return errorResponse(c, `Order cannot be paid - order is ${order.status}`, 'invalid_order_status', 400);
+    }
+
+    const balance = Math.round((Number(order.total_amount) - Number(order.total_paid)) * 100) / 100;
+    if (balance <= 0) {
+      return errorResponse(c, 'Order is already fully paid', 'order_fully_paid', 400);
+    }
+
+    const phone = body.phone?.trim() || order.delivery_phone || null;
+    if (body.send && !phone) {
+      return errorResponse(c, 'A phone number is required to send the link', 'phone_required', 400);
+    }
+    if (body.send && !messagingConfigured()) {
+      return errorResponse(c, 'Text messaging is not configured; share the link another way', 'messaging_not_configured', 400);
+    }
+
+    const expiryMinutes = await loadPaymentLinkExpiryMinutes(pool);
+
+    const linkRes = await pool.query(
+      `INSERT INTO payment_links (order_id, amount, gateway_reference, payment_url, expires_at, created_by)
+       VALUES ($1, $2, gen_random_uuid()::text, '', NOW() + make_interval(mins => $3), $4)
+       RETURNING id, expires_at`,
+      [orderId, balance, expiryMinutes, userId],
+    );
+    const linkId = linkRes.rows[0].id;
+    const reference = gatewayOrderId(PAYMENT_LINK_GATEWAY_PREFIX, linkId);
+
+    let charge;
+    try {
+      charge = await createCharge({
+        orderId: reference,
+        amount: balance,
+        description: `Order ${order.order_number}`,
+        customer: { name: order.customer_name || undefined, email: body.email || undefined, phone: phone || undefined },
+        expiryMinutes,
+      });
+    } catch (err) {
+      await pool.query('DELETE FROM payment_links WHERE id = $1', [linkId]);
+      return errorResponse(c, 'Failed to create payment link', (err as Error).message);
+    }
+
+    await pool.query(
+      `UPDATE payment_links SET status = 'cancelled', updated_at = NOW()
+       WHERE order_id = $1 AND id <> $2 AND status = 'pending'`,
+      [orderId, linkId],
+    );
+    await pool.query(
+      'UPDATE payment_links SET gateway_reference = $1, payment_url = $2, updated_at = NOW() WHERE id = $3',
+      [reference, charge.redirect_url, linkId],
+    );
+
+    let sent = false;
+    if (body.send && phone) {
+      try {
+        await sendTextMessage({
+          to: phone,
+          text: `Pay for your order ${order.order_number} (${balance}) here: ${charge.redirect_url}`,
+        });
+        sent = true;
+        await pool.query('UPDATE payment_links SET sent_to = $1 WHERE id = $2', [toInternationalPhone(phone), linkId]);
+      } catch (err) {
+        console.error(`Payment link for ${order.order_number} could not be sent:`, (err as Error).message);
+      }
+    }
+
+    return successResponse(c, sent ? 'Payment link created and sent' : 'Payment link created', {
+      id: linkId,
+      order_id: orderId,
+      amount: balance,
+      status: 'pending',
+      payment_url: charge.redirect_url,
+      expires_at: linkRes.rows[0].expires_at,
+      sent,
+    }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create payment link', (err as Error).message);
+  }
+}
+
+// ── GetOrderPaymentLinks ────────────────────────────────────────────────────
+
+export async function getOrderPaymentLinks(c: Context) {
+  const orderId = c.req.param('id');
+  const ownBranch = c.get('branch_id');
+
+  try {
+    const orderRes = await pool.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+    if (orderRes.rows.length === 0 || (ownBranch && orderRes.rows[0].branch_id !== ownBranch)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+
+    const links = await loadOrderPaymentLinks(pool, orderId);
+    return successResponse(c, 'Payment links retrieved successfully', links);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch payment links', (err as Error).message);
+  }
+}
@@ -0,0 +1,195 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import {
+  MAX_SURCHARGE_PERCENT,
+  PAYMENT_METHOD_CODE_RE,
+  PAYMENT_METHOD_SELECT,
+  loadPaymentMethods,
+} from '../services/payment-methods.js';
+
+interface PaymentMethodBody {
+  code?: string;
+  display_name?: string;
+  surcharge_percent?: number;
+  is_active?: boolean;
+  customer_selectable?: boolean;
+  sort_order?: number;
+}
+
+// Checks the fields that are present; `code` is only read on create.
+function validateBody(body: PaymentMethodBody, creating: boolean): { message: string; code: string } | null {
+  if (creating || body.display_name !== undefined) {
+    const name = typeof body.display_name === 'string' ? body.display_name.trim() : '';
+    if (!name || name.length > 50) {
+      return { message: 'Display name is required (max 50 characters)', code: 'invalid_display_name' };
+    }
+  }
+  if (body.surcharge_percent !== undefined) {
+    const pct = body.surcharge_percent;
+    if (typeof pct !== 'number' || !Number.isFinite(pct) || pct < 0 || pct > MAX_SURCHARGE_PERCENT) {
+      return { message: `surcharge_percent must be between 0 and ${MAX_SURCHARGE_PERCENT}`, code: 'invalid_surcharge_percent' };
+    }
+  }
+  for (const flag of ['is_active', 'customer_selectable'] as const) {
+    if (body[flag] !== undefined && typeof body[flag] !== 'boolean') {
+      return { message: `${flag} must be true or false`, code: `invalid_${flag}` };
+    }
+  }
+  if (body.sort_order !== undefined && !Number.isInteger(body.sort_order)) {
+    return { message: 'sort_order must be a whole number', code: 'invalid_sort_order' };
+  }
+  return null;
+}
+
+// ── GetPaymentMethods ───────────────────────────────────────────────────────
+// The methods a payment can be taken with now, in display order.
+
+export async function getPaymentMethods(c: Context) {
+  try {
+    const methods = await loadPaymentMethods(pool, { activeOnly: true });
+    return successResponse(c, 'Payment methods retrieved successfully', methods);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch payment methods', (err as Error).message);
+  }
+}
+
+// ── GetCustomerPaymentMethods ───────────────────────────────────────────────
+// What the QR order page offers guests.
+
+export async function getCustomerPaymentMethods(c: Context) {
+  try {
+    const methods = await loadPaymentMethods(pool, { activeOnly: true, customerOnly: true });
+    return successResponse(c, 'Payment methods retrieved successfully', methods.map((m) => ({
+      code: m.code,
+      display_name: m.display_name,
+      surcharge_percent: m.surcharge_percent,
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch payment methods', (err as Error).message);
+  }
+}
+
+// ── GetAllPaymentMethods ────────────────────────────────────────────────────
+
+export async function getAllPaymentMethods(c: Context) {
+  try {
+    const methods = await loadPaymentMethods(pool);
+    return successResponse(c, 'Payment methods retrieved successfully', methods);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch payment methods', (err as Error).message);
+  }
+}
+
+// ── CreatePaymentMethod ─────────────────────────────────────────────────────
+
+export async function createPaymentMethod(c: Context) {
+  let body: PaymentMethodBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const code = typeof body.code === 'string' ? body.code.trim() : '';
+  if (!PAYMENT_METHOD_CODE_RE.test(code)) {
+    return errorResponse(c, 'Code must be 2-20 lowercase letters, digits or underscores, starting with a letter', 'invalid_code', 400);
+  }
+  const invalid = validateBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO payment_methods (code, display_name, surcharge_percent, is_active, customer_selectable, sort_order)
+       VALUES ($1, $2, $3, $4, $5, $6)
+       ON CONFLICT (code) DO NOTHING
+       RETURNING code`,
+      [
+        code, body.display_name!.trim(), body.surcharge_percent ?? 0, body.is_active ?? true,
+        body.customer_selectable ?? false, body.sort_order ?? 0,
+      ],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'A payment method with this code already exists', 'duplicate_code', 409);
+    }
+    const created = await pool.query(`${PAYMENT_METHOD_SELECT} WHERE code = $1`, [code]);
+    return successResponse(c, 'Payment method created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create payment method', (err as Error).message);
+  }
+}
+
+// ── UpdatePaymentMethod ─────────────────────────────────────────────────────
+// Fields left out keep their value. The code can't change: payments point
+// at it.
+
+export async function updatePaymentMethod(c: Context) {
+  const code = c.req.param('code');
+
+  let body: PaymentMethodBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `UPDATE payment_methods
+       SET display_name = COALESCE($2, display_name),
+           surcharge_percent = COALESCE($3, surcharge_percent),
+           is_active = COALESCE($4, is_active),
+           customer_selectable = COALESCE($5, customer_selectable),
+           sort_order = COALESCE($6, sort_order)
+       WHERE code = $1
+       RETURNING code`,
+      [
+        code, body.display_name?.trim() ?? null, body.surcharge_percent ?? null, body.is_active ?? null,
+        body.customer_selectable ?? null, body.sort_order ?? null,
+      ],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Payment method not found', 'payment_method_not_found', 404);
+    }
+    const updated = await pool.query(`${PAYMENT_METHOD_SELECT} WHERE code = $1`, [code]);
+    return successResponse(c, 'Payment method updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update payment method', (err as Error).message);
+  }
+}
+
+// ── DeletePaymentMethod ─────────────────────────────────────────────────────
+// Only methods no payment has used; others can be switched off instead.
+
+export async function deletePaymentMethod(c: Context) {
+  const code = c.req.param('code');
+
+  try {
+    const existing = await pool.query('SELECT is_system FROM payment_methods WHERE code = $1', [code]);
+    if (existing.rows.length === 0) {
+      return errorResponse(c, 'Payment method not found', 'payment_method_not_found', 404);
+    }
+    if (existing.rows[0].is_system) {
+      return errorResponse(c, 'System payment methods cannot be deleted; deactivate it instead', 'system_payment_method', 409);
+    }
+    const used = await pool.query(
+      `SELECT EXISTS(SELECT 1 FROM payments WHERE payment_method = $1)
+           OR EXISTS(SELECT 1 FROM payment_batches WHERE payment_method = $1) AS used`,
+      [code],
+    );
+    if (used.rows[0].used) {
+      return errorResponse(c, 'Payments were taken with this method; deactivate it instead', 'payment_method_in_use', 409);
+    }
+
+    await pool.query('DELETE FROM payment_methods WHERE code = $1', [code]);
+    return successResponse(c, 'Payment method deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete payment method', (err as Error).message);
+  }
+}
@@ -1,13 +1,49 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
-import { successResponse, errorResponse } from '../lib/response.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, validationErrorResponse } from '../lib/response.js';
+import { requestLocale, validationFieldError } from '../lib/validation-messages.js';
+import { redeemFromWallet, refundToWallet, type WalletRedemption } from '../services/corporate-wallet.js';
+import { chargeOnAccount, refundOnAccount } from '../services/corporate-billing.js';
+import { restockOrderItems } from '../services/stock.js';
+import { loadCashRounding, roundCash } from '../services/cash-rounding.js';
+import { findGatewayCharge, queueGatewayRefund } from '../services/gateway-refunds.js';
+import { isGatewayConfigured } from '../services/payment-gateway.js';
+import { emitWebhookEvent, orderEventData, paymentEventData } from '../services/webhooks.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+import { MAX_BATCH_ORDERS, payOrderBatch, loadBatchReceipt } from '../services/payment-batches.js';
+import { isUUID } from '../services/branches.js';
+import { resolveOrderToken } from '../services/order-links.js';
+import { REFUND_REASONS } from '../services/data-model.js';
+import { findActivePaymentMethod, paymentSurcharge } from '../services/payment-methods.js';
+import { COMPANY_BILLED_METHODS, TIP_ADJUST_HOURS, companyRefundCredit, loadTipPercents, tipSuggestions, validateTip } from '../services/tips.js';
+import { loadFormatter } from '../lib/format.js';
+import { paymentsProcessedTotal, paymentsAmountTotal, paymentsRefundedTotal } from '../lib/metrics.js';
 
 // T094: Fraud detection constants
 const MAX_PAYMENTS_PER_MINUTE = 5;
 const MAX_PAYMENT_AMOUNT = 50_000_000; // 50 million IDR
 const MAX_FAILED_PAYMENT_ATTEMPTS = 3;
 
+// T094: Rapid payment attempts by one cashier
+async function tooManyRecentPayments(userId: string): Promise<boolean> {
+  try {
+    const rateRes = await db.execute<{ count: string }>(sql`
+      SELECT COUNT(*) as count FROM payments
+      WHERE processed_by = ${userId} AND created_at > NOW() - INTERVAL '1 minute'
+    `);
+    const recentCount = Number(rateRes.rows[0]?.count ?? 0);
+    if (recentCount >= MAX_PAYMENTS_PER_MINUTE) {
+      console.log(`FRAUD_ALERT: Rate limit exceeded - User: ${userId}, Payments in last minute: ${recentCount}`);
+      return true;
+    }
+  } catch {
+    // Non-blocking — log and continue
+  }
+  return false;
+}
+
 // ── ProcessPayment ──────────────────────────────────────────────────────────
 
 export async function processPayment(c: Context) {
@@ -18,6 +54,10 @@ export async function processPayment(c: Context) {
     payment_method: string;
     amount: number;
     reference_number?: string;
+    employee_code?: string;
+    corporate_account_id?: string;
+    /** On top of amount, for the order's server */
+    tip_amount?: number;
   };
 
   try {
@@ -27,34 +67,40 @@ export async function processPayment(c: Context) {
   }
 
   // Validate payment method
-  const validMethods = ['cash', 'credit_card', 'debit_card', 'digital_wallet'];
-  if (!validMethods.includes(body.payment_method)) {
+  const method = await findActivePaymentMethod(pool, body.payment_method);
+  if (!method) {
     return errorResponse(c, 'Invalid payment method', 'invalid_payment_method', 400);
   }
 
+  if (body.payment_method === 'corporate_wallet' && !body.employee_code) {
+    return errorResponse(c, 'Employee code is required for corporate wallet payments', 'missing_employee_code', 400);
+  }
+
+  if (body.payment_method === 'on_account' && !body.corporate_account_id) {
+    return errorResponse(c, 'Corporate account is required for on-account payments', 'missing_corporate_account', 400);
+  }
+
   if (!body.amount || body.amount <= 0) {
     return errorResponse(c, 'Payment amount must be greater than zero', 'invalid_amount', 400);
   }
 
+  if (body.tip_amount !== undefined) {
+    const invalidTip = validateTip(body.tip_amount, body.amount);
+    if (invalidTip) {
+      return errorResponse(c, invalidTip.message, invalidTip.code, 400);
+    }
+  }
+  const tipAmount = Math.round((body.tip_amount ?? 0) * 100) / 100;
+
   // T094: Fraud detection - check suspicious amount
-  if (body.amount > MAX_PAYMENT_AMOUNT) {
+  if (body.amount + tipAmount > MAX_PAYMENT_AMOUNT) {
     console.log(`FRAUD_ALERT: Suspicious large payment attempt - User: ${userId}, Amount: ${body.amount}`);
     return errorResponse(c, 'Payment amount exceeds maximum allowed limit', 'amount_exceeds_limit', 400);
   }
 
   // T094: Rate limiting - check rapid payment attempts
-  try {
-    const rateRes = await db.execute<{ count: string }>(sql`
-      SELECT COUNT(*) as count FROM payments
-      WHERE processed_by = ${userId} AND created_at > NOW() - INTERVAL '1 minute'
-    `);
-    const recentCount = Number(rateRes.rows[0]?.count ?? 0);
-    if (recentCount >= MAX_PAYMENTS_PER_MINUTE) {
-      console.log(`FRAUD_ALERT: Rate limit exceeded - User: ${userId}, Payments in last minute: ${recentCount}`);
-      return errorResponse(c, 'Too many payment attempts. Please wait a moment before trying again.', 'rate_limit_exceeded', 429);
-    }
-  } catch {
-    // Non-blocking — log and continue
+  if (await tooManyRecentPayments(userId)) {
+    return errorResponse(c, 'Too many payment attempts. Please wait a moment before trying again.', 'rate_limit_exceeded', 429);
   }
 
   // T094: Check for failed payment pattern (log only)
@@ -71,82 +117,148 @@ export async function processPayment(c: Context) {
     // Non-blocking
   }
 
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
-
-    // Check order exists and get total
-    const orderRes = await client.query(
-      'SELECT total_amount, status FROM orders WHERE id = $1',
-      [orderId],
-    );
-    if (orderRes.rows.length === 0) {
-      await client.query('ROLLBACK');
-      return errorResponse(c, 'Order not found', 'order_not_found', 404);
-    }
+    const result = await withTransaction(async (client) => {
+      let amount = body.amount;
 
-    const { total_amount: orderTotalAmount, status: orderStatus } = orderRes.rows[0];
-    const orderTotal = Number(orderTotalAmount);
+      // Check order exists and get total. Locked like a batch payment locks
+      // its orders, so concurrent payments see each other's totals.
+      const orderRes = await client.query(
+        'SELECT total_amount, status, branch_id, server_id FROM orders WHERE id = $1 FOR UPDATE',
+        [orderId],
+      );
+      const branchId = c.get('branch_id');
+      if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Order not found', 'order_not_found', 404);
+      }
 
-    // Check valid state
-    if (orderStatus === 'cancelled' || orderStatus === 'completed') {
-      await client.query('ROLLBACK');
-      return errorResponse(c, `Order cannot be paid - order is ${orderStatus}`, 'invalid_order_status', 400);
-    }
+      const {
+        total_amount: orderTotalAmount, status: orderStatus, branch_id: orderBranchId, server_id: orderServerId,
+      } = orderRes.rows[0];
+      const orderTotal = Number(orderTotalAmount);
 
-    // Check already fully paid
-    const paidRes = await client.query(
-      "SELECT COALESCE(SUM(amount), 0) as total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
-      [orderId],
-    );
-    const totalPaid = Number(paidRes.rows[0].total_paid);
+      // Check valid state
+      if (orderStatus === 'cancelled' || orderStatus === 'completed') {
+        return txFailure(`Order cannot be paid - order is ${orderStatus}`, 'invalid_order_status', 400);
+      }
 
-    if (totalPaid >= orderTotal) {
-      await client.query('ROLLBACK');
-      return errorResponse(c, 'Order is already fully paid', 'order_fully_paid', 400);
-    }
+      // Check already fully paid
+      const paidRes = await client.query(
+        "SELECT COALESCE(SUM(amount), 0) as total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
+        [orderId],
+      );
+      const totalPaid = Number(paidRes.rows[0].total_paid);
 
-    // Check amount doesn't exceed remaining
-    const remainingAmount = orderTotal - totalPaid;
-    if (body.amount > remainingAmount) {
-      await client.query('ROLLBACK');
-      return errorResponse(c, 'Payment amount exceeds remaining balance', 'amount_exceeds_balance', 400);
-    }
+      if (totalPaid >= orderTotal) {
+        return txFailure('Order is already fully paid', 'order_fully_paid', 400);
+      }
 
-    // Create payment record
-    const paymentRes = await client.query(
-      `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at)
-       VALUES ($1, $2, $3, $4, $5, $6, NOW())
-       RETURNING id`,
-      [orderId, body.payment_method, body.amount, body.reference_number || null, 'completed', userId],
-    );
+      // Cash that settles the balance is rounded: either the exact balance or
+      // the rounded figure settles it, and only the balance is applied
+      const remainingAmount = orderTotal - totalPaid;
+      let roundingAdjustment = 0;
+      if (body.payment_method === 'cash') {
+        const cashDue = roundCash(remainingAmount, await loadCashRounding(client, orderBranchId));
+        if (amount === remainingAmount || amount === cashDue) {
+          roundingAdjustment = Math.round((cashDue - remainingAmount) * 100) / 100;
+          amount = remainingAmount;
+        }
+      }
 
-    const paymentId = paymentRes.rows[0].id;
+      // Check amount doesn't exceed remaining
+      if (amount > remainingAmount) {
+        return txFailure('Payment amount exceeds remaining balance', 'amount_exceeds_balance', 400);
+      }
 
-    // If fully paid after this payment, complete the order
-    const newTotalPaid = totalPaid + body.amount;
-    if (newTotalPaid >= orderTotal) {
-      await client.query(
-        `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
-        [orderId],
+      // The method's surcharge is paid on top of what is applied to the order
+      const surcharge = paymentSurcharge(method, amount);
+
+      // Create payment record
+      // The tip goes to the order's server, or the cashier without one
+      const paymentRes = await client.query(
+        `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at,
+                               rounding_adjustment, surcharge_amount, tip_amount, tip_recipient_id)
+         VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7, $8, $9, $10)
+         RETURNING id`,
+        [
+          orderId, body.payment_method, amount,
+          (body.payment_method === 'corporate_wallet' ? body.employee_code : body.reference_number) || null,
+          'completed', userId, roundingAdjustment, surcharge, tipAmount, orderServerId ?? userId,
+        ],
       );
 
-      // Free up the table
-      await client.query(
-        `UPDATE dining_tables SET is_occupied = false
-         WHERE id IN (SELECT table_id FROM orders WHERE id = $1 AND table_id IS NOT NULL)`,
-        [orderId],
-      );
+      const paymentId = paymentRes.rows[0].id;
+
+      // The company is billed for all the payment collects, surcharge and tip included
+      const billed = Math.round((amount + surcharge + tipAmount) * 100) / 100;
+
+      // Corporate wallet: debit the company balance in the same transaction
+      let walletRedemption: WalletRedemption | undefined;
+      if (body.payment_method === 'corporate_wallet') {
+        const result = await redeemFromWallet(client, {
+          employeeCode: body.employee_code!,
+          amount: billed,
+          orderId,
+          paymentId,
+          userId,
+        });
+        if (!result.ok) {
+          return result;
+        }
+        walletRedemption = result.redemption;
+      }
 
-      // Log status change
-      await client.query(
-        `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
-         VALUES ($1, $2, 'completed', $3, 'Order completed after payment')`,
-        [orderId, orderStatus, userId],
-      );
-    }
+      // On account: record the receivable against the company's credit line
+      let accountCharge: Record<string, unknown> | undefined;
+      if (body.payment_method === 'on_account') {
+        const result = await chargeOnAccount(client, {
+          accountId: body.corporate_account_id!,
+          amount: billed,
+          orderId,
+          paymentId,
+          userId,
+        });
+        if (!result.ok) {
+          return result;
+        }
+        accountCharge = { company_name: result.company_name, ...result.credit };
+      }
+
+      // If fully paid after this payment, complete the order. Prepaid scheduled
+      // orders stay open so they still reach the kitchen at their lead time, and
+      // prepaid parked orders until they're resumed.
+      const newTotalPaid = totalPaid + amount;
+      if (newTotalPaid >= orderTotal && orderStatus !== 'scheduled' && orderStatus !== 'parked') {
+        await client.query(
+          `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+          [orderId],
+        );
+
+        // Free up the table
+        await client.query(
+          `UPDATE dining_tables SET is_occupied = false
+           WHERE id IN (SELECT table_id FROM orders WHERE id = $1 AND table_id IS NOT NULL)`,
+          [orderId],
+        );
+
+        // Log status change
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+           VALUES ($1, $2, 'completed', $3, 'Order completed after payment')`,
+          [orderId, orderStatus, userId],
+        );
+
+        await emitWebhookEvent(client, 'order.completed', () => orderEventData(client, orderId));
+      }
 
-    await client.query('COMMIT');
+      await emitWebhookEvent(client, 'payment.processed', () => paymentEventData(client, paymentId, 'staff'));
+
+      return { ok: true as const, paymentId, amount, walletRedemption, accountCharge };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { paymentId, amount, walletRedemption, accountCharge } = result;
+    paymentsProcessedTotal.inc({ method: body.payment_method, status: 'completed', source: 'staff' });
+    paymentsAmountTotal.inc({ method: body.payment_method }, amount);
 
     // Fetch the created payment with user info
     const fetchRes = await db.execute<{
@@ -154,6 +266,10 @@ export async function processPayment(c: Context) {
       order_id: string;
       payment_method: string;
       amount: string;
+      rounding_adjustment: string;
+      surcharge_amount: string;
+      tip_amount: string;
+      tip_recipient_id: string | null;
       reference_number: string | null;
       status: string;
       processed_by: string | null;
@@ -163,7 +279,8 @@ export async function processPayment(c: Context) {
       first_name: string | null;
       last_name: string | null;
     }>(sql`
-      SELECT p.id, p.order_id, p.payment_method, p.amount, p.reference_number, p.status,
+      SELECT p.id, p.order_id, p.payment_method, p.amount, p.rounding_adjustment, p.surcharge_amount,
+             p.tip_amount, p.tip_recipient_id, p.reference_number, p.status,
              p.processed_by, p.processed_at, p.created_at,
              u.username, u.first_name, u.last_name
       FROM payments p
@@ -177,6 +294,10 @@ export async function processPayment(c: Context) {
       order_id: row.order_id,
       payment_method: row.payment_method,
       amount: Number(row.amount),
+      rounding_adjustment: Number(row.rounding_adjustment),
+      surcharge_amount: Number(row.surcharge_amount),
+      tip_amount: Number(row.tip_amount),
+      tip_recipient_id: row.tip_recipient_id,
       reference_number: row.reference_number,
       status: row.status,
       processed_by: row.processed_by,
@@ -192,12 +313,115 @@ export async function processPayment(c: Context) {
       };
     }
 
+    // What the cashier collects for the order, after rounding, tip included
+    if (row.payment_method === 'cash') {
+      payment.cash_collected = Number(row.amount) + Number(row.rounding_adjustment) + Number(row.surcharge_amount)
+        + Number(row.tip_amount);
+    }
+    if (walletRedemption) {
+      payment.corporate_wallet = walletRedemption;
+    }
+    if (accountCharge) {
+      payment.on_account = accountCharge;
+    }
+
     return successResponse(c, 'Payment processed successfully', payment, 201);
   } catch (err) {
-    await client.query('ROLLBACK');
     return errorResponse(c, 'Failed to process payment', (err as Error).message);
-  } finally {
-    client.release();
+  }
+}
+
+// ── ProcessBatchPayment ─────────────────────────────────────────────────────
+// One tender for several orders (see services/payment-batches.ts). Answers
+// with the combined receipt.
+
+export async function processBatchPayment(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: {
+    order_ids?: string[];
+    payment_method: string;
+    amount: number;
+    reference_number?: string;
+    employee_code?: string;
+    corporate_account_id?: string;
+  };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const orderIds = Array.isArray(body.order_ids) ? [...new Set(body.order_ids)] : [];
+  if (orderIds.length < 2 || orderIds.length > MAX_BATCH_ORDERS) {
+    return errorResponse(c, `order_ids must list between 2 and ${MAX_BATCH_ORDERS} orders`, 'invalid_order_ids', 400);
+  }
+  if (!orderIds.every((id) => typeof id === 'string' && isUUID(id))) {
+    return errorResponse(c, 'order_ids must be order IDs', 'invalid_order_ids', 400);
+  }
+  const method = await findActivePaymentMethod(pool, body.payment_method);
+  if (!method) {
+    return errorResponse(c, 'Invalid payment method', 'invalid_payment_method', 400);
+  }
+  if (body.payment_method === 'corporate_wallet' && !body.employee_code) {
+    return errorResponse(c, 'Employee code is required for corporate wallet payments', 'missing_employee_code', 400);
+  }
+  if (body.payment_method === 'on_account' && !body.corporate_account_id) {
+    return errorResponse(c, 'Corporate account is required for on-account payments', 'missing_corporate_account', 400);
+  }
+  if (!body.amount || body.amount <= 0) {
+    return errorResponse(c, 'Payment amount must be greater than zero', 'invalid_amount', 400);
+  }
+  if (body.amount > MAX_PAYMENT_AMOUNT) {
+    console.log(`FRAUD_ALERT: Suspicious large payment attempt - User: ${userId}, Amount: ${body.amount}`);
+    return errorResponse(c, 'Payment amount exceeds maximum allowed limit', 'amount_exceeds_limit', 400);
+  }
+  if (await tooManyRecentPayments(userId)) {
+    return errorResponse(c, 'Too many payment attempts. Please wait a moment before trying again.', 'rate_limit_exceeded', 429);
+  }
+
+  try {
+    const result = await withTransaction((client) => payOrderBatch(client, {
+      orderIds,
+      paymentMethod: body.payment_method,
+      surchargePercent: method.surcharge_percent,
+      amount: body.amount,
+      referenceNumber: body.reference_number,
+      employeeCode: body.employee_code,
+      corporateAccountId: body.corporate_account_id,
+      userId,
+      branchId: c.get('branch_id') ?? null,
+    }));
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const paid = result.allocations.reduce((sum, a) => sum + a.amount, 0);
+    paymentsProcessedTotal.inc({ method: body.payment_method, status: 'completed', source: 'staff' }, result.allocations.length);
+    paymentsAmountTotal.inc({ method: body.payment_method }, paid);
+
+    const receipt = await loadBatchReceipt(pool, result.batchId, await loadFormatter(pool));
+    return successResponse(c, 'Batch payment processed successfully', receipt, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to process batch payment', (err as Error).message);
+  }
+}
+
+// ── GetPaymentBatch ─────────────────────────────────────────────────────────
+// The combined receipt again, for a reprint.
+
+export async function getPaymentBatch(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Payment batch not found', 'payment_batch_not_found', 404);
+  }
+
+  try {
+    const receipt = await loadBatchReceipt(pool, id, await loadFormatter(pool), c.get('branch_id') ?? null);
+    if (!receipt) {
+      return errorResponse(c, 'Payment batch not found', 'payment_batch_not_found', 404);
+    }
+    return successResponse(c, 'Payment batch retrieved successfully', receipt);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch payment batch', (err as Error).message);
   }
 }
 
@@ -208,11 +432,12 @@ export async function getPayments(c: Context) {
 
   try {
     // Check order exists
-    const orderRes = await db.execute<{ id: string }>(sql`
-      SELECT id FROM orders WHERE id = ${orderId} LIMIT 1
+    const orderRes = await db.execute<{ id: string; branch_id: string | null }>(sql`
+      SELECT id, branch_id FROM orders WHERE id = ${orderId} LIMIT 1
     `);
 
-    if (orderRes.rows.length === 0) {
+    const branchId = c.get('branch_id');
+    if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
       return errorResponse(c, 'Order not found', 'order_not_found', 404);
     }
 
@@ -221,17 +446,25 @@ export async function getPayments(c: Context) {
       id: string;
       payment_method: string;
       amount: string;
+      surcharge_amount: string;
+      tip_amount: string;
+      tip_recipient_id: string | null;
       reference_number: string | null;
       status: string;
       processed_by: string | null;
       processed_at: string | null;
       created_at: string | null;
+      refund_of: string | null;
+      refund_reason: string | null;
+      refund_notes: string | null;
+      approved_by: string | null;
       username: string | null;
       first_name: string | null;
       last_name: string | null;
     }>(sql`
-      SELECT p.id, p.payment_method, p.amount, p.reference_number, p.status,
-             p.processed_by, p.processed_at, p.created_at,
+      SELECT p.id, p.payment_method, p.amount, p.surcharge_amount, p.tip_amount, p.tip_recipient_id,
+             p.reference_number, p.status, p.processed_by, p.processed_at, p.created_at,
+             p.refund_of, p.refund_reason, p.refund_notes, p.approved_by,
              u.username, u.first_name, u.last_name
       FROM payments p
       LEFT JOIN users u ON p.processed_by = u.id
@@ -245,6 +478,9 @@ export async function getPayments(c: Context) {
         order_id: orderId,
         payment_method: row.payment_method,
         amount: Number(row.amount),
+        surcharge_amount: Number(row.surcharge_amount),
+        tip_amount: Number(row.tip_amount),
+        tip_recipient_id: row.tip_recipient_id,
         reference_number: row.reference_number,
         status: row.status,
         processed_by: row.processed_by,
@@ -252,6 +488,15 @@ export async function getPayments(c: Context) {
         created_at: row.created_at,
       };
 
+      if (row.refund_of) {
+        payment.refund = {
+          refund_of: row.refund_of,
+          reason: row.refund_reason,
+          notes: row.refund_notes,
+          approved_by: row.approved_by,
+        };
+      }
+
       if (row.username) {
         payment.processed_by_user = {
           username: row.username,
@@ -277,22 +522,26 @@ export async function getPaymentSummary(c: Context) {
   try {
     const rows = await db.execute<{
       total_amount: string;
+      branch_id: string | null;
       total_paid: string;
       pending_amount: string;
+      tip_total: string;
       payment_count: string;
     }>(sql`
       SELECT
-        o.total_amount,
+        o.total_amount, o.branch_id,
         COALESCE(SUM(CASE WHEN p.status = 'completed' THEN p.amount ELSE 0 END), 0) as total_paid,
         COALESCE(SUM(CASE WHEN p.status = 'pending' THEN p.amount ELSE 0 END), 0) as pending_amount,
+        COALESCE(SUM(CASE WHEN p.status = 'completed' THEN p.tip_amount ELSE 0 END), 0) as tip_total,
         COUNT(p.id) as payment_count
       FROM orders o
       LEFT JOIN payments p ON o.id = p.order_id
       WHERE o.id = ${orderId}
-      GROUP BY o.id, o.total_amount
+      GROUP BY o.id, o.total_amount, o.branch_id
     `);
 
-    if (rows.rows.length === 0) {
+    const branchId = c.get('branch_id');
+    if (rows.rows.length === 0 || (branchId && rows.rows[0].branch_id !== branchId)) {
       return errorResponse(c, 'Order not found', 'order_not_found', 404);
     }
 
@@ -309,18 +558,268 @@ export async function getPaymentSummary(c: Context) {
       total_paid: totalPaid,
       pending_amount: pendingAmount,
       remaining_amount: remainingAmount,
+      // Cash to ask for when settling the balance in cash
+      cash_amount_due: isFullyPaid ? 0 : roundCash(remainingAmount, await loadCashRounding(pool, row.branch_id)),
       is_fully_paid: isFullyPaid,
       payment_count: Number(row.payment_count),
+      tip_total: Number(row.tip_total),
+      // Tips to offer on the balance, from the tip_suggestions setting
+      tip_suggestions: isFullyPaid ? [] : tipSuggestions(remainingAmount, await loadTipPercents(pool, row.branch_id)),
     });
   } catch (err) {
     return errorResponse(c, 'Failed to fetch payment summary', (err as Error).message);
   }
 }
 
+// ── AdjustPaymentTip ────────────────────────────────────────────────────────
+// Sets the tip on a completed payment, for a tip added on the card slip
+// after the charge or one given back (0). Only within TIP_ADJUST_HOURS of
+// the payment, so tips already paid out aren't changed afterwards.
+
+export async function adjustPaymentTip(c: Context) {
+  const orderId = c.req.param('id');
+  const paymentId = c.req.param('payment_id');
+  if (!isUUID(orderId) || !isUUID(paymentId)) {
+    return errorResponse(c, 'Payment not found', 'payment_not_found', 404);
+  }
+
+  let body: { tip_amount?: number };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const paymentRes = await client.query(
+        `SELECT p.id, p.payment_method, p.amount, p.status, p.refund_of, o.branch_id,
+                p.processed_at < NOW() - make_interval(hours => $3) AS window_closed
+         FROM payments p
+         JOIN orders o ON o.id = p.order_id
+         WHERE p.id = $1 AND p.order_id = $2
+         FOR UPDATE OF p`,
+        [paymentId, orderId, TIP_ADJUST_HOURS],
+      );
+      const payment = paymentRes.rows[0];
+      const branchId = c.get('branch_id');
+      if (!payment || (branchId && payment.branch_id !== branchId)) {
+        return txFailure('Payment not found', 'payment_not_found', 404);
+      }
+      if (payment.refund_of || payment.status !== 'completed') {
+        return txFailure('Tips can only be set on completed payments', 'invalid_payment_status', 400);
+      }
+      if (COMPANY_BILLED_METHODS.includes(payment.payment_method)) {
+        return txFailure('The tip was billed to the company with the payment and cannot be changed', 'tip_not_adjustable', 409);
+      }
+      if (payment.window_closed) {
+        return txFailure(`Tips can only be changed within ${TIP_ADJUST_HOURS} hours of the payment`, 'tip_adjust_window_closed', 409);
+      }
+      const invalidTip = validateTip(body.tip_amount, Number(payment.amount));
+      if (invalidTip) {
+        return txFailure(invalidTip.message, invalidTip.code, 400);
+      }
+
+      const updated = await client.query(
+        'UPDATE payments SET tip_amount = $2 WHERE id = $1 RETURNING id, order_id, amount, tip_amount, tip_recipient_id',
+        [paymentId, Math.round(body.tip_amount! * 100) / 100],
+      );
+      return { ok: true as const, payment: updated.rows[0] };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const { payment } = result;
+    return successResponse(c, 'Tip updated successfully', {
+      ...payment,
+      amount: Number(payment.amount),
+      tip_amount: Number(payment.tip_amount),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to update tip', (err as Error).message);
+  }
+}
+
+// ── RefundPayment ──────────────────────────────────────────────────────────
+// Refunds are new payment rows with a negative amount pointing at the
+// original, so every "sum of completed payments" query nets them out without
+// changes. Only admins and managers reach this route, which is the approval.
+// A payment taken through the gateway is refunded through it: the row stays
+// pending until the gateway confirms (see services/gateway-refunds.ts).
+
+export async function refundPayment(c: Context) {
+  const orderId = c.req.param('id');
+  const paymentId = c.req.param('payment_id');
+  const userId = c.get('user_id');
+
+  let body: {
+    reason: string;
+    amount?: number;
+    notes?: string;
+    restock_items?: { product_id: string; quantity: number }[];
+  };
+
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!REFUND_REASONS.includes(body.reason)) {
+    return errorResponse(c, `Refund reason must be one of: ${REFUND_REASONS.join(', ')}`, 'invalid_refund_reason', 400);
+  }
+
+  if (body.reason === 'other' && !body.notes?.trim()) {
+    return errorResponse(c, 'Notes are required when the refund reason is other', 'missing_refund_notes', 400);
+  }
+
+  if (body.amount !== undefined && (typeof body.amount !== 'number' || body.amount <= 0)) {
+    return errorResponse(c, 'Refund amount must be greater than zero', 'invalid_amount', 400);
+  }
+
+  const restockItems = body.restock_items ?? [];
+  for (const item of restockItems) {
+    if (!item.product_id || typeof item.quantity !== 'number' || !(item.quantity > 0)) {
+      return errorResponse(c, 'Restock items need a product ID and a positive quantity', 'invalid_restock_items', 400);
+    }
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const paymentRes = await client.query(
+        `SELECT p.id, p.payment_method, p.amount, p.surcharge_amount, p.tip_amount, p.status, p.refund_of, o.branch_id
+         FROM payments p
+         JOIN orders o ON o.id = p.order_id
+         WHERE p.id = $1 AND p.order_id = $2
+         FOR UPDATE OF p`,
+        [paymentId, orderId],
+      );
+      const branchId = c.get('branch_id');
+      if (paymentRes.rows.length === 0 || (branchId && paymentRes.rows[0].branch_id !== branchId)) {
+        return txFailure('Payment not found', 'payment_not_found', 404);
+      }
+
+      const original = paymentRes.rows[0];
+      if (original.refund_of) {
+        return txFailure('A refund cannot itself be refunded', 'cannot_refund_refund', 400);
+      }
+      if (original.status !== 'completed') {
+        return txFailure(`Payment cannot be refunded - payment is ${original.status}`, 'invalid_payment_status', 400);
+      }
+
+      // Refunds still waiting on the gateway count as refunded here
+      const refundedRes = await client.query(
+        "SELECT COALESCE(SUM(-amount), 0) AS refunded FROM payments WHERE refund_of = $1 AND status IN ('completed', 'pending')",
+        [paymentId],
+      );
+      const refunded = Number(refundedRes.rows[0].refunded);
+      const refundable = Number(original.amount) - refunded;
+      if (refundable <= 0) {
+        return txFailure('Payment has already been fully refunded', 'payment_fully_refunded', 409);
+      }
+
+      const amount = body.amount ?? refundable;
+      if (amount > refundable) {
+        return txFailure(`Refund amount exceeds refundable balance of ${refundable}`, 'amount_exceeds_refundable', 400);
+      }
+
+      if (restockItems.length > 0) {
+        const itemsRes = await client.query(
+          'SELECT product_id, SUM(quantity) AS quantity FROM order_items WHERE order_id = $1 GROUP BY product_id',
+          [orderId],
+        );
+        const ordered = new Map<string, number>(itemsRes.rows.map((r) => [r.product_id, Number(r.quantity)]));
+        for (const item of restockItems) {
+          if ((ordered.get(item.product_id) ?? 0) < item.quantity) {
+            return txFailure('Restock items must match items on the order', 'invalid_restock_items', 400);
+          }
+        }
+      }
+
+      const charge = await findGatewayCharge(client, paymentId);
+      if (charge && !isGatewayConfigured()) {
+        return txFailure('Payment gateway is not configured', 'gateway_not_configured', 503);
+      }
+
+      const refundRes = await client.query(
+        `INSERT INTO payments
+           (order_id, payment_method, amount, status, processed_by, processed_at, refund_of, refund_reason, refund_notes, approved_by)
+         VALUES ($1, $2, $3, $8, $4, NOW(), $5, $6, $7, $4)
+         RETURNING id, status, created_at`,
+        [orderId, original.payment_method, -amount, userId, paymentId, body.reason, body.notes?.trim() || null, charge ? 'pending' : 'completed'],
+      );
+      const refundPaymentId = refundRes.rows[0].id;
+
+      let gatewayRefundId: string | null = null;
+      if (charge) {
+        gatewayRefundId = await queueGatewayRefund(client, {
+          refundPaymentId,
+          charge,
+          amount,
+          reason: body.notes?.trim() || body.reason,
+        });
+      }
+
+      // Money goes back where it came from for the house payment methods,
+      // with the share of the surcharge and tip the company was billed
+      const credit = companyRefundCredit({
+        amount: Number(original.amount),
+        surcharge_amount: Number(original.surcharge_amount),
+        tip_amount: Number(original.tip_amount),
+      }, refunded, amount);
+      if (original.payment_method === 'corporate_wallet') {
+        const result = await refundToWallet(client, { paymentId, refundPaymentId, amount: credit, userId });
+        if (!result.ok) {
+          return result;
+        }
+      }
+      if (original.payment_method === 'on_account') {
+        const result = await refundOnAccount(client, { paymentId, refundPaymentId, amount: credit, userId });
+        if (!result.ok) {
+          return result;
+        }
+      }
+
+      if (restockItems.length > 0) {
+        await restockOrderItems(client, orderId, restockItems, userId, `Refund: ${body.reason}`);
+      }
+
+      return { ok: true as const, original, refundable, amount, charge, refundPaymentId, refundRes, gatewayRefundId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { original, refundable, amount, charge, refundPaymentId, refundRes, gatewayRefundId } = result;
+    paymentsRefundedTotal.inc({ method: original.payment_method, reason: body.reason });
+    if (restockItems.length > 0) {
+      await refreshStockAvailability({ productIds: restockItems.map((i) => i.product_id) });
+    }
+
+    return successResponse(c, charge ? 'Refund submitted to the payment gateway' : 'Payment refunded successfully', {
+      id: refundPaymentId,
+      order_id: orderId,
+      refund_of: paymentId,
+      payment_method: original.payment_method,
+      amount: -amount,
+      status: refundRes.rows[0].status,
+      gateway_refund: gatewayRefundId ? { id: gatewayRefundId, status: 'queued' } : null,
+      reason: body.reason,
+      notes: body.notes?.trim() || null,
+      approved_by: userId,
+      remaining_refundable: refundable - amount,
+      restocked_items: restockItems,
+      created_at: refundRes.rows[0].created_at,
+    }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to refund payment', (err as Error).message);
+  }
+}
+
 // ── CreateCustomerPayment (QR-based, no auth) ──────────────────────────────────
 
 export async function createCustomerPayment(c: Context) {
-  const orderId = c.req.param('id');
+  const link = resolveOrderToken(c, ['order']);
+  if (!link.ok) {
+    return errorResponse(c, link.failure.message, link.failure.code, link.failure.status);
+  }
+  const orderId = link.orderId;
 
   let body: {
     payment_method: string;
@@ -331,91 +830,100 @@ export async function createCustomerPayment(c: Context) {
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ success: false, error: 'Invalid request body' }, 400);
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  // Only methods offered to guests on the QR order page
+  const method = await findActivePaymentMethod(pool, body.payment_method);
+  if (!method || !method.customer_selectable) {
+    return errorResponse(c, 'Invalid payment method', 'invalid_payment_method', 400);
   }
 
   // T100: Authorization check — verify table ownership
   const tableIDHeader = c.req.header('X-Table-ID');
 
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
+    const result = await withTransaction(async (client) => {
+      // Get order info, locked like processPayment does, so two guests
+      // paying at once can't both see the order unpaid
+      const orderRes = await client.query(
+        'SELECT total_amount, status, order_type, table_id FROM orders WHERE id = $1 FOR UPDATE',
+        [orderId],
+      );
 
-    // Get order info
-    const orderRes = await client.query(
-      'SELECT total_amount, status, order_type, table_id FROM orders WHERE id = $1',
-      [orderId],
-    );
+      if (orderRes.rows.length === 0) {
+        return { ok: false as const, response: c.json({ success: false, error: 'Order not found' }, 404) };
+      }
 
-    if (orderRes.rows.length === 0) {
-      await client.query('ROLLBACK');
-      return c.json({ success: false, error: 'Order not found' }, 404);
-    }
+      const { total_amount, status: orderStatus, table_id: orderTableId } = orderRes.rows[0];
+      const orderTotal = Number(total_amount);
 
-    const { total_amount, status: orderStatus, table_id: orderTableId } = orderRes.rows[0];
-    const orderTotal = Number(total_amount);
+      // T100: Cross-table check
+      if (orderTableId && tableIDHeader && orderTableId !== tableIDHeader) {
+        console.log(`AUTHORIZATION_ALERT: Cross-table payment attempt - Order table: ${orderTableId}, Request table: ${tableIDHeader}`);
+        return { ok: false as const, response: c.json({ success: false, error: 'You can only pay for orders from your table' }, 403) };
+      }
 
-    // T100: Cross-table check
-    if (orderTableId && tableIDHeader && orderTableId !== tableIDHeader) {
-      console.log(`AUTHORIZATION_ALERT: Cross-table payment attempt - Order table: ${orderTableId}, Request table: ${tableIDHeader}`);
-      await client.query('ROLLBACK');
-      return c.json({ success: false, error: 'You can only pay for orders from your table' }, 403);
-    }
+      if (orderStatus === 'cancelled') {
+        return { ok: false as const, response: errorResponse(c, 'Cannot pay for cancelled order', 'order_cancelled', 400) };
+      }
 
-    if (orderStatus === 'cancelled') {
-      await client.query('ROLLBACK');
-      return c.json({ success: false, error: 'Cannot pay for cancelled order' }, 400);
-    }
+      // Check already paid
+      const paidRes = await client.query(
+        "SELECT COALESCE(SUM(amount), 0) as total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
+        [orderId],
+      );
+      const totalPaid = Number(paidRes.rows[0].total_paid);
 
-    // Check already paid
-    const paidRes = await client.query(
-      "SELECT COALESCE(SUM(amount), 0) as total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
-      [orderId],
-    );
-    const totalPaid = Number(paidRes.rows[0].total_paid);
+      if (totalPaid >= orderTotal) {
+        return { ok: false as const, response: errorResponse(c, 'Order is already fully paid', 'order_fully_paid', 400) };
+      }
 
-    if (totalPaid >= orderTotal) {
-      await client.query('ROLLBACK');
-      return c.json({ success: false, error: 'Order is already fully paid' }, 400);
-    }
+      // T078: Amount must match remaining
+      const remainingAmount = orderTotal - totalPaid;
+      if (body.amount !== remainingAmount) {
+        const response = validationErrorResponse(
+          c,
+          [validationFieldError(requestLocale(c), 'amount_mismatch', 'Payment amount must match remaining balance')],
+          { required_amount: remainingAmount, provided_amount: body.amount },
+        );
+        return { ok: false as const, response };
+      }
 
-    // T078: Amount must match remaining
-    const remainingAmount = orderTotal - totalPaid;
-    if (body.amount !== remainingAmount) {
-      await client.query('ROLLBACK');
-      return c.json({
-        success: false,
-        error: 'Payment amount must match remaining balance',
-        details: { required_amount: remainingAmount, provided_amount: body.amount },
-      }, 400);
-    }
+      // Create payment
+      const surcharge = paymentSurcharge(method, body.amount);
+      const paymentRes = await client.query(
+        `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_at, surcharge_amount)
+         VALUES ($1, $2, $3, $4, 'completed', NOW(), $5) RETURNING id`,
+        [orderId, body.payment_method, body.amount, body.reference_number || null, surcharge],
+      );
+      const paymentId = paymentRes.rows[0].id;
 
-    // Create payment
-    const paymentRes = await client.query(
-      `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_at)
-       VALUES ($1, $2, $3, $4, 'completed', NOW()) RETURNING id`,
-      [orderId, body.payment_method, body.amount, body.reference_number || null],
-    );
-    const paymentId = paymentRes.rows[0].id;
-
-    // Update order status to paid
-    await client.query(
-      "UPDATE orders SET status = 'paid', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
-      [orderId],
-    );
-
-    // Log status change (non-critical)
-    try {
+      // Update order status to paid
       await client.query(
-        `INSERT INTO order_status_history (order_id, previous_status, new_status, notes)
-         VALUES ($1, $2, 'paid', 'Customer paid via ' || $3)`,
-        [orderId, orderStatus, body.payment_method],
+        "UPDATE orders SET status = 'paid', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+        [orderId],
       );
-    } catch {
-      // Non-critical
-    }
 
-    await client.query('COMMIT');
+      // Log status change (non-critical)
+      try {
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, notes)
+           VALUES ($1, $2, 'paid', 'Customer paid via ' || $3)`,
+          [orderId, orderStatus, body.payment_method],
+        );
+      } catch {
+        // Non-critical
+      }
+
+      await emitWebhookEvent(client, 'payment.processed', () => paymentEventData(client, paymentId, 'customer'));
+
+      return { ok: true as const, paymentId, surcharge };
+    });
+    if (!result.ok) return result.response;
+    const { paymentId, surcharge } = result;
+    paymentsProcessedTotal.inc({ method: body.payment_method, status: 'completed', source: 'customer' });
+    paymentsAmountTotal.inc({ method: body.payment_method }, body.amount);
 
     return c.json({
       success: true,
@@ -424,14 +932,12 @@ export async function createCustomerPayment(c: Context) {
         payment_id: paymentId,
         order_id: orderId,
         amount: body.amount,
+        surcharge_amount: surcharge,
         payment_method: body.payment_method,
         status: 'completed',
       },
     }, 201);
   } catch (err) {
-    await client.query('ROLLBACK');
     return c.json({ success: false, error: 'Failed to process payment' }, 500);
-  } finally {
-    client.release();
   }
 }
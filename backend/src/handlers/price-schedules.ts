@@ -0,0 +1,232 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { invalidateCache } from '../lib/cache.js';
+import { isUUID } from '../services/branches.js';
+import {
+  SCHEDULE_PRICE_TYPES,
+  formatPriceSchedule,
+  isSchedulePriceType,
+  scheduleInEffect,
+} from '../services/price-schedules.js';
+
+type ScheduleBody = {
+  name?: string;
+  description?: string | null;
+  product_ids?: string[];
+  category_ids?: string[];
+  price_type?: string;
+  value?: number;
+  days_of_week?: number[];
+  start_time?: string;
+  end_time?: string;
+  is_active?: boolean;
+};
+
+const TIME_RE = /^([01]\d|2[0-3]):[0-5]\d(:[0-5]\d)?$/;
+
+// Checks the fields present; creating also requires the mandatory ones
+function validateScheduleBody(body: ScheduleBody, partial: boolean): { message: string; code: string } | null {
+  if (!partial) {
+    if (!body.name?.trim()) return { message: 'Name is required', code: 'missing_name' };
+    if (body.price_type === undefined) return { message: 'price_type is required', code: 'missing_price_type' };
+    if (body.value === undefined) return { message: 'value is required', code: 'missing_value' };
+    if (!body.start_time || !body.end_time) return { message: 'start_time and end_time are required', code: 'missing_window' };
+    if (!body.product_ids?.length && !body.category_ids?.length) {
+      return { message: 'Give product_ids or category_ids', code: 'missing_targets' };
+    }
+  }
+  if (body.name !== undefined && (!body.name.trim() || body.name.trim().length > 100)) {
+    return { message: 'Name is required (at most 100 characters)', code: 'invalid_name' };
+  }
+  if (body.price_type !== undefined && !isSchedulePriceType(body.price_type)) {
+    return { message: `price_type must be one of: ${SCHEDULE_PRICE_TYPES.join(', ')}`, code: 'invalid_price_type' };
+  }
+  if (body.value !== undefined) {
+    if (typeof body.value !== 'number' || !Number.isFinite(body.value) || body.value <= 0) {
+      return { message: 'value must be greater than zero', code: 'invalid_value' };
+    }
+    if (body.price_type === 'percentage' && body.value > 100) {
+      return { message: 'A percentage cannot exceed 100', code: 'invalid_value' };
+    }
+  }
+  for (const ids of [body.product_ids, body.category_ids]) {
+    if (ids !== undefined && (!Array.isArray(ids) || ids.some((id) => !isUUID(id)))) {
+      return { message: 'product_ids and category_ids must be lists of IDs', code: 'invalid_targets' };
+    }
+  }
+  if (body.days_of_week !== undefined
+      && (!Array.isArray(body.days_of_week) || body.days_of_week.some((d) => !Number.isInteger(d) || d < 0 || d > 6))) {
+    return { message: 'Days of week must be between 0 (Sunday) and 6 (Saturday)', code: 'invalid_days_of_week' };
+  }
+  for (const t of [body.start_time, body.end_time]) {
+    if (t !== undefined && !TIME_RE.test(t)) return { message: 'Times must be in HH:MM format', code: 'invalid_time' };
+  }
+  return null;
+}
+
+// Constraint violations the body checks can't see on a partial update
+function scheduleConstraintError(c: Context, err: unknown) {
+  if ((err as { code?: string }).code === '23514') {
+    return errorResponse(c, 'A schedule needs products or categories, and a percentage cannot exceed 100', 'invalid_schedule', 400);
+  }
+  return null;
+}
+
+function serializeSchedule(row: Record<string, unknown>, now: Date) {
+  const schedule = formatPriceSchedule(row);
+  return {
+    ...schedule,
+    in_effect: scheduleInEffect(schedule, now),
+    created_by: row.created_by ?? null,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+// ── GetPriceSchedules ───────────────────────────────────────────────────────
+// ?active_only=true leaves out inactive ones; in_effect says whether the
+// window is open right now.
+
+export async function getPriceSchedules(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `SELECT * FROM price_schedules ${activeOnly ? 'WHERE is_active = true' : ''}
+       ORDER BY start_time ASC, name ASC`,
+    );
+    const now = new Date();
+    return successResponse(c, 'Price schedules retrieved successfully', res.rows.map((row) => serializeSchedule(row, now)));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch price schedules', (err as Error).message);
+  }
+}
+
+// ── CreatePriceSchedule ─────────────────────────────────────────────────────
+
+export async function createPriceSchedule(c: Context) {
+  let body: ScheduleBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateScheduleBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO price_schedules (name, description, product_ids, category_ids, price_type, value, days_of_week,
+                                    start_time, end_time, is_active, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+       RETURNING *`,
+      [
+        body.name!.trim(),
+        body.description?.trim() || null,
+        body.product_ids ?? [],
+        body.category_ids ?? [],
+        body.price_type,
+        body.value,
+        body.days_of_week ?? [],
+        body.start_time,
+        body.end_time,
+        body.is_active ?? true,
+        c.get('user_id') ?? null,
+      ],
+    );
+
+    invalidateCache('menu');
+    return successResponse(c, 'Price schedule created successfully', serializeSchedule(res.rows[0], new Date()), 201);
+  } catch (err) {
+    return scheduleConstraintError(c, err) ?? errorResponse(c, 'Failed to create price schedule', (err as Error).message);
+  }
+}
+
+// ── UpdatePriceSchedule ─────────────────────────────────────────────────────
+// Items already ordered keep the price they were charged.
+
+export async function updatePriceSchedule(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Price schedule not found', 'not_found', 404);
+  }
+
+  let body: ScheduleBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateScheduleBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  const values: Record<string, unknown> = {
+    name: body.name?.trim(),
+    description: body.description === undefined ? undefined : body.description?.trim() || null,
+    product_ids: body.product_ids,
+    category_ids: body.category_ids,
+    price_type: body.price_type,
+    value: body.value,
+    days_of_week: body.days_of_week,
+    start_time: body.start_time,
+    end_time: body.end_time,
+    is_active: body.is_active,
+  };
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+  for (const [col, value] of Object.entries(values)) {
+    if (value !== undefined) {
+      setClauses.push(`${col} = $${paramIdx++}`);
+      params.push(value);
+    }
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE price_schedules SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING *`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Price schedule not found', 'not_found', 404);
+    }
+
+    invalidateCache('menu');
+    return successResponse(c, 'Price schedule updated successfully', serializeSchedule(res.rows[0], new Date()));
+  } catch (err) {
+    return scheduleConstraintError(c, err) ?? errorResponse(c, 'Failed to update price schedule', (err as Error).message);
+  }
+}
+
+// ── DeletePriceSchedule ─────────────────────────────────────────────────────
+
+export async function deletePriceSchedule(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Price schedule not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM price_schedules WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Price schedule not found', 'not_found', 404);
+    }
+    invalidateCache('menu');
+    return successResponse(c, 'Price schedule deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete price schedule', (err as Error).message);
+  }
+}
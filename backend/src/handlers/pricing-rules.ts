@@ -0,0 +1,302 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { formatPricingRule, priceOrder, type PricingLine } from '../services/pricing.js';
+
+type RuleBody = {
+  name?: string;
+  description?: string | null;
+  rule_type?: string;
+  discount_type?: string;
+  discount_value?: number;
+  priority?: number;
+  stackable?: boolean;
+  product_ids?: string[];
+  category_ids?: string[];
+  bundle_product_ids?: string[];
+  min_quantity?: number;
+  days_of_week?: number[];
+  start_time?: string | null;
+  end_time?: string | null;
+  valid_from?: string | null;
+  valid_until?: string | null;
+  is_active?: boolean;
+};
+
+const TIME_RE = /^([01]\d|2[0-3]):[0-5]\d(:[0-5]\d)?$/;
+
+function validateRuleBody(body: RuleBody, partial: boolean): { message: string; code: string } | null {
+  if (!partial) {
+    if (!body.name) return { message: 'Rule name is required', code: 'missing_name' };
+    if (!body.rule_type) return { message: 'Rule type is required', code: 'missing_rule_type' };
+    if (!body.discount_type) return { message: 'Discount type is required', code: 'missing_discount_type' };
+    if (body.discount_value === undefined) return { message: 'Discount value is required', code: 'missing_discount_value' };
+  }
+  if (body.rule_type !== undefined && !['item_discount', 'bundle'].includes(body.rule_type)) {
+    return { message: 'Rule type must be item_discount or bundle', code: 'invalid_rule_type' };
+  }
+  if (body.discount_type !== undefined && !['percentage', 'fixed'].includes(body.discount_type)) {
+    return { message: 'Discount type must be percentage or fixed', code: 'invalid_discount_type' };
+  }
+  if (body.discount_value !== undefined) {
+    if (body.discount_value <= 0) return { message: 'Discount value must be greater than zero', code: 'invalid_discount_value' };
+    if (body.discount_type === 'percentage' && body.discount_value > 100) {
+      return { message: 'Percentage discount cannot exceed 100', code: 'invalid_discount_value' };
+    }
+  }
+  if (body.rule_type === 'bundle' && (!body.bundle_product_ids || body.bundle_product_ids.length < 2)) {
+    return { message: 'Bundle rules need at least two products', code: 'invalid_bundle' };
+  }
+  if (body.days_of_week && body.days_of_week.some((d) => !Number.isInteger(d) || d < 0 || d > 6)) {
+    return { message: 'Days of week must be between 0 (Sunday) and 6 (Saturday)', code: 'invalid_days_of_week' };
+  }
+  for (const t of [body.start_time, body.end_time]) {
+    if (t && !TIME_RE.test(t)) return { message: 'Times must be in HH:MM format', code: 'invalid_time' };
+  }
+  if ((body.start_time && !body.end_time) || (!body.start_time && body.end_time)) {
+    if (!partial) return { message: 'Both start_time and end_time are required for a daily window', code: 'invalid_time_window' };
+  }
+  if (body.valid_from && body.valid_until && new Date(body.valid_from) >= new Date(body.valid_until)) {
+    return { message: 'valid_until must be after valid_from', code: 'invalid_validity' };
+  }
+  return null;
+}
+
+const RULE_COLUMNS = [
+  'name', 'description', 'rule_type', 'discount_type', 'discount_value', 'priority', 'stackable',
+  'product_ids', 'category_ids', 'bundle_product_ids', 'min_quantity', 'days_of_week',
+  'start_time', 'end_time', 'valid_from', 'valid_until', 'is_active',
+] as const;
+
+function serializeRule(row: Record<string, unknown>) {
+  return {
+    ...formatPricingRule(row),
+    description: row.description,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+// ── GetPricingRules ─────────────────────────────────────────────────────────
+
+export async function getPricingRules(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `SELECT * FROM pricing_rules ${activeOnly ? 'WHERE is_active = true' : ''}
+       ORDER BY priority DESC, created_at ASC`,
+    );
+    return successResponse(c, 'Pricing rules retrieved successfully', res.rows.map(serializeRule));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch pricing rules', (err as Error).message);
+  }
+}
+
+// ── CreatePricingRule ───────────────────────────────────────────────────────
+
+export async function createPricingRule(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: RuleBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateRuleBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO pricing_rules
+         (name, description, rule_type, discount_type, discount_value, priority, stackable,
+          product_ids, category_ids, bundle_product_ids, min_quantity, days_of_week,
+          start_time, end_time, valid_from, valid_until, is_active, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+       RETURNING *`,
+      [
+        body.name,
+        body.description || null,
+        body.rule_type,
+        body.discount_type,
+        body.discount_value,
+        body.priority ?? 0,
+        body.stackable ?? false,
+        body.product_ids ?? [],
+        body.category_ids ?? [],
+        body.bundle_product_ids ?? [],
+        body.min_quantity ?? 1,
+        body.days_of_week ?? [],
+        body.start_time || null,
+        body.end_time || null,
+        body.valid_from || null,
+        body.valid_until || null,
+        body.is_active ?? true,
+        userId,
+      ],
+    );
+
+    return successResponse(c, 'Pricing rule created successfully', serializeRule(res.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create pricing rule', (err as Error).message);
+  }
+}
+
+// ── UpdatePricingRule ───────────────────────────────────────────────────────
+
+export async function updatePricingRule(c: Context) {
+  const ruleId = c.req.param('id');
+
+  let body: RuleBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateRuleBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    for (const col of RULE_COLUMNS) {
+      if (body[col] !== undefined) {
+        setClauses.push(`${col} = $${paramIdx}`);
+        params.push(body[col]);
+        paramIdx++;
+      }
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    params.push(ruleId);
+    const res = await pool.query(
+      `UPDATE pricing_rules SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING *`,
+      params,
+    );
+
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Pricing rule not found', 'not_found', 404);
+    }
+
+    return successResponse(c, 'Pricing rule updated successfully', serializeRule(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update pricing rule', (err as Error).message);
+  }
+}
+
+// ── DeletePricingRule ───────────────────────────────────────────────────────
+// Audit rows keep rule_name, so deleting a rule doesn't lose order history.
+
+export async function deletePricingRule(c: Context) {
+  const ruleId = c.req.param('id');
+
+  try {
+    const res = await pool.query('DELETE FROM pricing_rules WHERE id = $1', [ruleId]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Pricing rule not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Pricing rule deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete pricing rule', (err as Error).message);
+  }
+}
+
+// ── PreviewPricing ──────────────────────────────────────────────────────────
+// Dry-runs the engine for a basket, optionally at a given time, so managers
+// can check a rule before it goes live.
+
+export async function previewPricing(c: Context) {
+  let body: { items?: { product_id: string; quantity: number }[]; at?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.items || body.items.length === 0) {
+    return errorResponse(c, 'At least one item is required', 'items_required', 400);
+  }
+
+  const at = body.at ? new Date(body.at) : new Date();
+  if (Number.isNaN(at.getTime())) {
+    return errorResponse(c, 'Invalid preview time', 'invalid_time', 400);
+  }
+
+  try {
+    const productRes = await pool.query(
+      'SELECT id, name, price, category_id FROM products WHERE id = ANY($1::uuid[])',
+      [body.items.map((i) => i.product_id)],
+    );
+    const byId = new Map(productRes.rows.map((p) => [p.id, p]));
+
+    const lines: PricingLine[] = [];
+    for (const item of body.items) {
+      const prod = byId.get(item.product_id);
+      if (!prod) {
+        return errorResponse(c, `Product with ID '${item.product_id}' not found`, 'product_not_found', 400);
+      }
+      lines.push({
+        product_id: prod.id,
+        category_id: prod.category_id,
+        name: prod.name,
+        unit_price: Number(prod.price),
+        quantity: item.quantity,
+      });
+    }
+
+    const result = await priceOrder(pool, lines, at);
+    return successResponse(c, 'Pricing preview calculated', {
+      at: at.toISOString(),
+      subtotal: result.subtotal,
+      discount_amount: result.discount_amount,
+      net_subtotal: result.subtotal - result.discount_amount,
+      lines: lines.map((l, i) => ({ ...l, discount: result.line_discounts[i] })),
+      applied_rules: result.adjustments,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to preview pricing', (err as Error).message);
+  }
+}
+
+// ── GetOrderPricingAdjustments ──────────────────────────────────────────────
+
+export async function getOrderPricingAdjustments(c: Context) {
+  const orderId = c.req.param('id');
+  if (!isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  try {
+    const orderRes = await pool.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+    const branchId = c.get('branch_id');
+    if (orderRes.rows.length === 0 || (branchId && orderRes.rows[0].branch_id !== branchId)) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+
+    const res = await pool.query(
+      `SELECT id, rule_id, rule_name, rule_type, amount, details, created_at
+       FROM order_pricing_adjustments WHERE order_id = $1 ORDER BY created_at ASC`,
+      [orderId],
+    );
+
+    return successResponse(c, 'Pricing adjustments retrieved successfully', res.rows.map((row) => ({
+      ...row,
+      amount: Number(row.amount),
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch pricing adjustments', (err as Error).message);
+  }
+}
@@ -1,10 +1,18 @@
 import type { Context } from 'hono';
-import { eq, and, sql, ilike, or } from 'drizzle-orm';
-import { db } from '../db/connection.js';
-import { products, categories, orderItems } from '../db/schema.js';
+import { eq, and, sql, isNull, isNotNull, inArray } from 'drizzle-orm';
+import { db, pool } from '../db/connection.js';
+import { products, categories, orderItems, taxClasses } from '../db/schema.js';
 import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
-import { parsePagination, buildMeta } from '../lib/pagination.js';
+import { parsePagination, buildMeta, fetchPage, pageMeta } from '../lib/pagination.js';
 import { numericFields } from '../lib/validation.js';
+import { includeDeleted } from '../lib/soft-delete.js';
+import { invalidateCache } from '../lib/cache.js';
+import { queueMenuSync } from '../services/menu-sync.js';
+import { searchProducts } from '../services/product-search.js';
+import { isUUID } from '../services/branches.js';
+import { SALE_UNITS, isSaleUnit } from '../services/weighed-items.js';
+import { syncStockAvailability } from '../services/stock-availability.js';
+import { closeEightySix } from '../services/eighty-six.js';
 
 // Decimal fields that must be converted to numbers for JSON responses
 const PRODUCT_DECIMAL_FIELDS = ['price'] as const;
@@ -21,8 +29,16 @@ function formatProduct(row: {
   isAvailable: boolean | null;
   preparationTime: number | null;
   sortOrder: number | null;
+  taxClassId?: string | null;
+  saleUnit?: string;
+  availabilityOverride?: boolean;
+  stockUnavailableAt?: string | null;
+  allergens?: string[];
+  dietaryTags?: string[];
+  spicyLevel?: number | null;
   createdAt: string | null;
   updatedAt: string | null;
+  deletedAt?: string | null;
   categoryName?: string | null;
   categoryColor?: string | null;
 }) {
@@ -38,10 +54,21 @@ function formatProduct(row: {
     is_available: row.isAvailable,
     preparation_time: row.preparationTime ?? 0,
     sort_order: row.sortOrder ?? 0,
+    tax_class_id: row.taxClassId ?? null,
+    sale_unit: row.saleUnit ?? 'each',
+    availability_override: row.availabilityOverride ?? false,
+    stock_unavailable_at: row.stockUnavailableAt ?? null,
+    allergens: row.allergens ?? [],
+    dietary_tags: row.dietaryTags ?? [],
+    spicy_level: row.spicyLevel ?? null,
     created_at: row.createdAt,
     updated_at: row.updatedAt,
   };
 
+  if (row.deletedAt) {
+    product.deleted_at = row.deletedAt;
+  }
+
   if (row.categoryName) {
     product.category = {
       id: row.categoryId,
@@ -53,6 +80,58 @@ function formatProduct(row: {
   return product;
 }
 
+const PRODUCT_LIST_FIELDS = {
+  id: products.id,
+  categoryId: products.categoryId,
+  name: products.name,
+  description: products.description,
+  price: products.price,
+  imageUrl: products.imageUrl,
+  barcode: products.barcode,
+  sku: products.sku,
+  isAvailable: products.isAvailable,
+  preparationTime: products.preparationTime,
+  sortOrder: products.sortOrder,
+  taxClassId: products.taxClassId,
+  saleUnit: products.saleUnit,
+  availabilityOverride: products.availabilityOverride,
+  stockUnavailableAt: products.stockUnavailableAt,
+  allergens: products.allergens,
+  dietaryTags: products.dietaryTags,
+  spicyLevel: products.spicyLevel,
+  createdAt: products.createdAt,
+  updatedAt: products.updatedAt,
+  deletedAt: products.deletedAt,
+  categoryName: categories.name,
+  categoryColor: categories.color,
+};
+
+// Null clears the class, so the product takes the default tax rate
+async function findTaxClass(taxClassId: string | null | undefined): Promise<boolean> {
+  if (!taxClassId) return true;
+  if (!isUUID(taxClassId)) return false;
+  const [row] = await db.select({ id: taxClasses.id }).from(taxClasses).where(eq(taxClasses.id, taxClassId)).limit(1);
+  return Boolean(row);
+}
+
+function parseAvailable(value: string | undefined): boolean | undefined {
+  if (value === 'true') return true;
+  if (value === 'false') return false;
+  return undefined;
+}
+
+// Search results come back as ranked IDs; load them keeping that order
+async function loadProductsInOrder(ids: string[]) {
+  if (ids.length === 0) return [];
+  const rows = await db
+    .select(PRODUCT_LIST_FIELDS)
+    .from(products)
+    .leftJoin(categories, eq(products.categoryId, categories.id))
+    .where(inArray(products.id, ids));
+  const byId = new Map(rows.map((row) => [row.id, row]));
+  return ids.map((id) => byId.get(id)).filter((row) => row !== undefined);
+}
+
 function formatCategory(row: {
   id: string;
   name: string;
@@ -79,77 +158,124 @@ export async function getProducts(c: Context) {
   const categoryID = c.req.query('category_id');
   const available = c.req.query('available');
   const search = c.req.query('search');
-  const { page, perPage, offset } = parsePagination({
+  const pagination = parsePagination({
     page: c.req.query('page'),
     per_page: c.req.query('per_page'),
+    include_total: c.req.query('include_total'),
   });
+  const { page, perPage, offset } = pagination;
+  const filters = { search, category_id: categoryID, available };
 
   try {
+    // Searches are ranked by relevance instead of sort order
+    if (search) {
+      const result = await searchProducts(pool, {
+        term: search,
+        categoryId: categoryID,
+        available: parseAvailable(available),
+        includeDeleted: includeDeleted(c),
+        limit: perPage,
+        offset,
+      });
+      const data = (await loadProductsInOrder(result.ids)).map(formatProduct);
+      // The search ranks and counts in one statement, so its total is always exact
+      return paginatedResponse(c, 'Products retrieved successfully', data, buildMeta(page, perPage, result.total, {
+        sort: 'relevance:desc',
+        filters,
+      }));
+    }
+
     // Build conditions
     const conditions = [];
 
-    // Always filter out deleted products unless explicitly requested
-    conditions.push(eq(products.isDeleted, false));
+    // Deleted products stay hidden unless an admin asks for them
+    if (!includeDeleted(c)) {
+      conditions.push(isNull(products.deletedAt));
+    }
 
     if (categoryID) {
       conditions.push(eq(products.categoryId, categoryID));
     }
-    if (available === 'true') {
-      conditions.push(eq(products.isAvailable, true));
-    } else if (available === 'false') {
-      conditions.push(eq(products.isAvailable, false));
-    }
-    if (search) {
-      const pattern = `%${search}%`;
-      conditions.push(
-        or(
-          ilike(products.name, pattern),
-          ilike(products.description, pattern),
-        )!,
-      );
+    const availableFilter = parseAvailable(available);
+    if (availableFilter !== undefined) {
+      conditions.push(eq(products.isAvailable, availableFilter));
     }
 
     const whereClause = conditions.length > 0 ? and(...conditions) : undefined;
 
-    // Count total
-    const [countResult] = await db
-      .select({ count: sql<number>`count(*)` })
-      .from(products)
-      .where(whereClause);
-
-    const total = Number(countResult.count);
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const [countResult] = await db
+          .select({ count: sql<number>`count(*)` })
+          .from(products)
+          .where(whereClause);
+        return Number(countResult.count);
+      },
+      // Fetch products with category join
+      async (limit) =>
+        db
+          .select(PRODUCT_LIST_FIELDS)
+          .from(products)
+          .leftJoin(categories, eq(products.categoryId, categories.id))
+          .where(whereClause)
+          .orderBy(products.sortOrder, products.name)
+          .limit(limit)
+          .offset(offset),
+    );
+
+    const data = result.rows.map(formatProduct);
+
+    return paginatedResponse(c, 'Products retrieved successfully', data, pageMeta(pagination, result, {
+      sort: 'sort_order:asc,name:asc',
+      filters,
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch products', (err as Error).message);
+  }
+}
 
-    // Fetch products with category join
-    const rows = await db
-      .select({
-        id: products.id,
-        categoryId: products.categoryId,
-        name: products.name,
-        description: products.description,
-        price: products.price,
-        imageUrl: products.imageUrl,
-        barcode: products.barcode,
-        sku: products.sku,
-        isAvailable: products.isAvailable,
-        preparationTime: products.preparationTime,
-        sortOrder: products.sortOrder,
-        createdAt: products.createdAt,
-        updatedAt: products.updatedAt,
-        categoryName: categories.name,
-        categoryColor: categories.color,
-      })
-      .from(products)
-      .leftJoin(categories, eq(products.categoryId, categories.id))
-      .where(whereClause)
-      .orderBy(products.sortOrder, products.name)
-      .limit(perPage)
-      .offset(offset);
+// Typo-tolerant, ranked search with per-category counts for filter chips.
+// ?q= is required; category_id and available narrow the results.
+export async function getProductSearch(c: Context) {
+  const term = c.req.query('q')?.trim() ?? '';
+  const categoryID = c.req.query('category_id');
+  const { page, perPage, offset } = parsePagination({
+    page: c.req.query('page'),
+    per_page: c.req.query('per_page'),
+  });
 
-    const data = rows.map(formatProduct);
+  if (!term) {
+    return errorResponse(c, 'Search term is required', 'missing_query', 400);
+  }
+  if (term.length > 100) {
+    return errorResponse(c, 'Search term must be at most 100 characters', 'invalid_query', 400);
+  }
+  if (categoryID && !isUUID(categoryID)) {
+    return errorResponse(c, 'Invalid category ID', 'invalid_category_id', 400);
+  }
 
-    return paginatedResponse(c, 'Products retrieved successfully', data, buildMeta(page, perPage, total));
+  try {
+    const result = await searchProducts(pool, {
+      term,
+      categoryId: categoryID,
+      available: parseAvailable(c.req.query('available')),
+      includeDeleted: includeDeleted(c),
+      limit: perPage,
+      offset,
+    });
+    const data = (await loadProductsInOrder(result.ids)).map(formatProduct);
+
+    return successResponse(c, 'Products retrieved successfully', {
+      products: data,
+      facets: result.facets,
+      meta: buildMeta(page, perPage, result.total, {
+        sort: 'relevance:desc',
+        filters: { q: term, category_id: categoryID, available: c.req.query('available') },
+      }),
+    });
   } catch (err) {
-    return errorResponse(c, 'Failed to fetch products', (err as Error).message);
+    return errorResponse(c, 'Failed to search products', (err as Error).message);
   }
 }
 
@@ -172,12 +298,13 @@ export async function getProduct(c: Context) {
         sortOrder: products.sortOrder,
         createdAt: products.createdAt,
         updatedAt: products.updatedAt,
+        deletedAt: products.deletedAt,
         categoryName: categories.name,
         categoryColor: categories.color,
       })
       .from(products)
       .leftJoin(categories, eq(products.categoryId, categories.id))
-      .where(eq(products.id, productId))
+      .where(includeDeleted(c) ? eq(products.id, productId) : and(eq(products.id, productId), isNull(products.deletedAt)))
       .limit(1);
 
     if (!row) {
@@ -194,7 +321,11 @@ export async function getCategories(c: Context) {
   const activeOnly = c.req.query('active_only') === 'true';
 
   try {
-    const whereClause = activeOnly ? eq(categories.isActive, true) : undefined;
+    const conditions = [isNull(categories.deletedAt)];
+    if (activeOnly) {
+      conditions.push(eq(categories.isActive, true));
+    }
+    const whereClause = and(...conditions);
 
     const rows = await db
       .select({
@@ -222,7 +353,7 @@ export async function getProductsByCategory(c: Context) {
   const availableOnly = c.req.query('available_only') === 'true';
 
   try {
-    const conditions = [eq(products.categoryId, categoryId)];
+    const conditions = [eq(products.categoryId, categoryId), isNull(products.deletedAt)];
     if (availableOnly) {
       conditions.push(eq(products.isAvailable, true));
     }
@@ -240,6 +371,10 @@ export async function getProductsByCategory(c: Context) {
         isAvailable: products.isAvailable,
         preparationTime: products.preparationTime,
         sortOrder: products.sortOrder,
+        taxClassId: products.taxClassId,
+        saleUnit: products.saleUnit,
+        availabilityOverride: products.availabilityOverride,
+        stockUnavailableAt: products.stockUnavailableAt,
         createdAt: products.createdAt,
         updatedAt: products.updatedAt,
         categoryName: categories.name,
@@ -268,6 +403,9 @@ export async function createProduct(c: Context) {
     is_available?: boolean;
     preparation_time?: number;
     sort_order?: number;
+    tax_class_id?: string | null;
+    sale_unit?: string;
+    availability_override?: boolean;
   };
 
   try {
@@ -285,18 +423,24 @@ export async function createProduct(c: Context) {
   if (!body.price || body.price <= 0) {
     return errorResponse(c, 'Price must be greater than 0', 'invalid_price', 400);
   }
+  if (body.sale_unit !== undefined && !isSaleUnit(body.sale_unit)) {
+    return errorResponse(c, `sale_unit must be one of: ${SALE_UNITS.join(', ')}`, 'invalid_sale_unit', 400);
+  }
 
   try {
     // Verify category exists
     const [cat] = await db
       .select({ id: categories.id })
       .from(categories)
-      .where(eq(categories.id, body.category_id))
+      .where(and(eq(categories.id, body.category_id), isNull(categories.deletedAt)))
       .limit(1);
 
     if (!cat) {
       return errorResponse(c, 'Category not found', 'category_not_found', 400);
     }
+    if (!(await findTaxClass(body.tax_class_id))) {
+      return errorResponse(c, 'Tax class not found', 'tax_class_not_found', 400);
+    }
 
     // Insert product
     const [created] = await db
@@ -312,6 +456,9 @@ export async function createProduct(c: Context) {
         isAvailable: body.is_available ?? true,
         preparationTime: body.preparation_time ?? 15,
         sortOrder: body.sort_order ?? 0,
+        taxClassId: body.tax_class_id || null,
+        saleUnit: body.sale_unit ?? 'each',
+        availabilityOverride: body.availability_override ?? false,
       })
       .returning();
 
@@ -327,10 +474,15 @@ export async function createProduct(c: Context) {
       is_available: created.isAvailable,
       preparation_time: created.preparationTime,
       sort_order: created.sortOrder,
+      tax_class_id: created.taxClassId,
+      sale_unit: created.saleUnit,
+      availability_override: created.availabilityOverride,
+      stock_unavailable_at: created.stockUnavailableAt,
       created_at: created.createdAt,
       updated_at: created.updatedAt,
     };
 
+    invalidateCache('menu');
     return successResponse(c, 'Product created successfully', product, 201);
   } catch (err) {
     return errorResponse(c, 'Failed to create product', (err as Error).message);
@@ -351,6 +503,9 @@ export async function updateProduct(c: Context) {
     is_available?: boolean;
     preparation_time?: number;
     sort_order?: number;
+    tax_class_id?: string | null;
+    sale_unit?: string;
+    availability_override?: boolean;
   };
 
   try {
@@ -364,7 +519,7 @@ export async function updateProduct(c: Context) {
     const [existing] = await db
       .select({ id: products.id })
       .from(products)
-      .where(eq(products.id, productId))
+      .where(and(eq(products.id, productId), isNull(products.deletedAt)))
       .limit(1);
 
     if (!existing) {
@@ -376,7 +531,7 @@ export async function updateProduct(c: Context) {
       const [cat] = await db
         .select({ id: categories.id })
         .from(categories)
-        .where(eq(categories.id, body.category_id))
+        .where(and(eq(categories.id, body.category_id), isNull(categories.deletedAt)))
         .limit(1);
 
       if (!cat) {
@@ -388,6 +543,13 @@ export async function updateProduct(c: Context) {
     if (body.price !== undefined && body.price <= 0) {
       return errorResponse(c, 'Price must be greater than 0', 'invalid_price', 400);
     }
+    if (body.sale_unit !== undefined && !isSaleUnit(body.sale_unit)) {
+      return errorResponse(c, `sale_unit must be one of: ${SALE_UNITS.join(', ')}`, 'invalid_sale_unit', 400);
+    }
+
+    if (!(await findTaxClass(body.tax_class_id))) {
+      return errorResponse(c, 'Tax class not found', 'tax_class_not_found', 400);
+    }
 
     // Build update set
     const updateSet: Record<string, unknown> = { updatedAt: sql`NOW()` };
@@ -398,14 +560,30 @@ export async function updateProduct(c: Context) {
     if (body.image_url !== undefined) updateSet.imageUrl = body.image_url;
     if (body.barcode !== undefined) updateSet.barcode = body.barcode;
     if (body.sku !== undefined) updateSet.sku = body.sku;
-    if (body.is_available !== undefined) updateSet.isAvailable = body.is_available;
+    // A product hidden by hand stays hidden on restock and at the 86 rollover
+    if (body.is_available !== undefined) {
+      updateSet.isAvailable = body.is_available;
+      updateSet.stockUnavailableAt = null;
+      updateSet.eightySixedAt = null;
+    }
+    if (body.availability_override !== undefined) updateSet.availabilityOverride = body.availability_override;
     if (body.preparation_time !== undefined) updateSet.preparationTime = body.preparation_time;
     if (body.sort_order !== undefined) updateSet.sortOrder = body.sort_order;
+    if (body.tax_class_id !== undefined) updateSet.taxClassId = body.tax_class_id || null;
+    if (body.sale_unit !== undefined) updateSet.saleUnit = body.sale_unit;
 
     await db
       .update(products)
       .set(updateSet)
       .where(eq(products.id, productId));
+    if (body.is_available !== undefined) {
+      await closeEightySix(pool, productId, c.get('user_id') ?? null);
+    }
+
+    // Back under the stock check: take it off now if it's already sold out
+    if (body.availability_override === false) {
+      await syncStockAvailability(pool, { productIds: [productId] });
+    }
 
     // Fetch updated product with category
     const [row] = await db
@@ -421,6 +599,10 @@ export async function updateProduct(c: Context) {
         isAvailable: products.isAvailable,
         preparationTime: products.preparationTime,
         sortOrder: products.sortOrder,
+        taxClassId: products.taxClassId,
+        saleUnit: products.saleUnit,
+        availabilityOverride: products.availabilityOverride,
+        stockUnavailableAt: products.stockUnavailableAt,
         createdAt: products.createdAt,
         updatedAt: products.updatedAt,
         categoryName: categories.name,
@@ -431,6 +613,10 @@ export async function updateProduct(c: Context) {
       .where(eq(products.id, productId))
       .limit(1);
 
+    invalidateCache('menu');
+    if (body.price !== undefined || body.is_available !== undefined) {
+      await queueMenuSync(pool, { productIds: [productId] });
+    }
     return successResponse(c, 'Product updated successfully', formatProduct(row));
   } catch (err) {
     return errorResponse(c, 'Failed to update product', (err as Error).message);
@@ -439,26 +625,30 @@ export async function updateProduct(c: Context) {
 
 export async function deleteProduct(c: Context) {
   const productId = c.req.param('id');
+  const userId = c.get('user_id');
 
   try {
     // Check if product exists
     const [existing] = await db
       .select({ id: products.id })
       .from(products)
-      .where(eq(products.id, productId))
+      .where(and(eq(products.id, productId), isNull(products.deletedAt)))
       .limit(1);
 
     if (!existing) {
       return errorResponse(c, 'Product not found', 'product_not_found', 404);
     }
 
-    // Always soft delete by setting is_deleted flag
-    // This preserves order history integrity
+    // Always soft delete so order history keeps its product references.
+    // is_deleted is kept in sync for anything still reading the old flag.
     await db
       .update(products)
-      .set({ isDeleted: true, updatedAt: sql`NOW()` })
+      .set({ isDeleted: true, deletedAt: sql`NOW()`, deletedBy: userId, updatedAt: sql`NOW()` })
       .where(eq(products.id, productId));
 
+    invalidateCache('menu');
+    // Delivery platforms list it as unavailable
+    await queueMenuSync(pool, { productIds: [productId] });
     return successResponse(c, 'Product deleted successfully', {
       product_id: productId,
       deleted: true,
@@ -467,3 +657,28 @@ export async function deleteProduct(c: Context) {
     return errorResponse(c, 'Failed to delete product', (err as Error).message);
   }
 }
+
+export async function restoreProduct(c: Context) {
+  const productId = c.req.param('id');
+
+  try {
+    const [restored] = await db
+      .update(products)
+      .set({ isDeleted: false, deletedAt: null, deletedBy: null, updatedAt: sql`NOW()` })
+      .where(and(eq(products.id, productId), isNotNull(products.deletedAt)))
+      .returning({ id: products.id });
+
+    if (!restored) {
+      return errorResponse(c, 'Deleted product not found', 'product_not_found', 404);
+    }
+
+    invalidateCache('menu');
+    await queueMenuSync(pool, { productIds: [productId] });
+    return successResponse(c, 'Product restored successfully', {
+      product_id: productId,
+      deleted: false,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to restore product', (err as Error).message);
+  }
+}
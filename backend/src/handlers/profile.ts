@@ -1,43 +1,12 @@
 import type { Context } from 'hono';
 import bcrypt from 'bcryptjs';
-import { eq, and, ne } from 'drizzle-orm';
+import { eq, and, ne, sql } from 'drizzle-orm';
 import { db } from '../db/connection.js';
 import { users } from '../db/schema.js';
+import { generateToken } from '../lib/jwt.js';
+import { setSessionCookies } from '../lib/session.js';
 import { successResponse, errorResponse } from '../lib/response.js';
-
-// Password strength validation
-interface PasswordStrengthError {
-  min_length: boolean;
-  has_upper: boolean;
-  has_lower: boolean;
-  has_number: boolean;
-  has_special: boolean;
-}
-
-function validatePasswordStrength(password: string): PasswordStrengthError | null {
-  const errors: PasswordStrengthError = {
-    min_length: password.length >= 8,
-    has_upper: /[A-Z]/.test(password),
-    has_lower: /[a-z]/.test(password),
-    has_number: /[0-9]/.test(password),
-    has_special: /[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?~]/.test(password),
-  };
-
-  if (errors.min_length && errors.has_upper && errors.has_lower && errors.has_number && errors.has_special) {
-    return null;
-  }
-  return errors;
-}
-
-function getPasswordStrengthMessage(errors: PasswordStrengthError): string {
-  const missing: string[] = [];
-  if (!errors.min_length) missing.push('at least 8 characters');
-  if (!errors.has_upper) missing.push('one uppercase letter');
-  if (!errors.has_lower) missing.push('one lowercase letter');
-  if (!errors.has_number) missing.push('one number');
-  if (!errors.has_special) missing.push('one special character (!@#$%^&*()_+-=[]{}|;\':",./<>?~)');
-  return 'Password must contain: ' + missing.join(', ');
-}
+import { checkPassword, weakPasswordResponse } from '../lib/password.js';
 
 function formatUser(user: {
   id: string;
@@ -165,20 +134,10 @@ export async function changePassword(c: Context) {
     return errorResponse(c, 'Invalid request body - current_password and new_password are required (min 8 chars)', 'missing_fields', 400);
   }
 
-  // Validate password strength
-  const strengthErrors = validatePasswordStrength(body.new_password);
-  if (strengthErrors) {
-    return c.json({
-      success: false,
-      message: getPasswordStrengthMessage(strengthErrors),
-      data: { password_requirements: strengthErrors },
-    }, 400);
-  }
-
   try {
     // Get current password hash
     const [user] = await db
-      .select({ passwordHash: users.passwordHash })
+      .select({ passwordHash: users.passwordHash, username: users.username, email: users.email })
       .from(users)
       .where(eq(users.id, userId))
       .limit(1);
@@ -187,6 +146,11 @@ export async function changePassword(c: Context) {
       return errorResponse(c, 'User not found', undefined, 404);
     }
 
+    const policyCheck = await checkPassword(body.new_password, user);
+    if (policyCheck) {
+      return weakPasswordResponse(c, policyCheck, 'new_password');
+    }
+
     // Verify current password
     const validPassword = await bcrypt.compare(body.current_password, user.passwordHash);
     if (!validPassword) {
@@ -196,18 +160,23 @@ export async function changePassword(c: Context) {
     // Hash new password
     const newHash = await bcrypt.hash(body.new_password, 10);
 
-    // Update password
-    const result = await db
+    // Update password, ending the user's other sessions
+    const [updated] = await db
       .update(users)
-      .set({ passwordHash: newHash })
+      .set({ passwordHash: newHash, tokenVersion: sql`${users.tokenVersion} + 1` })
       .where(eq(users.id, userId))
-      .returning({ id: users.id });
+      .returning({
+        id: users.id, username: users.username, role: users.role, branchId: users.branchId, tokenVersion: users.tokenVersion,
+      });
 
-    if (result.length === 0) {
+    if (!updated) {
       return errorResponse(c, 'User not found', undefined, 404);
     }
 
-    return successResponse(c, 'Password changed successfully');
+    // This session carries on with a new token
+    const token = generateToken(updated);
+    const csrfToken = setSessionCookies(c, token);
+    return successResponse(c, 'Password changed successfully', { token, csrf_token: csrfToken });
   } catch (err) {
     return errorResponse(c, 'Failed to update password', (err as Error).message);
   }
@@ -1,7 +1,43 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { env } from '../env.js';
 import { successResponse, errorResponse } from '../lib/response.js';
+import { ordersCreatedTotal } from '../lib/metrics.js';
+import { priceOrder, recordPricingAdjustments, type PricingLine } from '../services/pricing.js';
+import { getProductAvailability, deductStockForOrder } from '../services/stock.js';
+import { findActiveBranch, getDefaultBranchId, isUUID } from '../services/branches.js';
+import { searchProducts } from '../services/product-search.js';
+import { computeOrderTaxes, taxLines } from '../services/tax.js';
+import { resolveSchedule, type ScheduleResult } from '../services/scheduled-orders.js';
+import { loadDeliverySettings, computeDeliveryFee, validateDeliveryDetails, type DeliveryDetails } from '../services/delivery.js';
+import { warnFlaggedCustomer } from '../services/customer-flags.js';
+import { estimateOrderWait } from '../services/wait-time.js';
+import { holdCustomerOrderItems } from '../services/kitchen-routing.js';
+import { createNotificationForRole } from '../services/notification.js';
+import { emitWebhookEvent, orderEventData } from '../services/webhooks.js';
+import { recordOrderSource, ORDER_RISK_FLAGS } from '../services/order-source.js';
+import { findActiveCurrency, convertFromIDR, orderCurrency, SETTLEMENT_CURRENCY, type Currency } from '../services/currencies.js';
+import { resolveItemQuantity, lineTotal } from '../services/weighed-items.js';
+import { isReceiptLanguage, saveCustomerReceiptLanguage } from '../services/receipt-language.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+import { computeOrderSurcharges, recordOrderSurcharges } from '../services/surcharges.js';
+import { applyPriceSchedules, loadScheduledPrices } from '../services/price-schedules.js';
+import { findTableByCode, verifyTableCode } from '../services/table-qr.js';
+import { orderStatusLink, resolveOrderToken, signOrderToken } from '../services/order-links.js';
+import { CUSTOMER_ORDER_LIMITS, ORDER_TYPES } from '../services/data-model.js';
+import { resolveOrderServer } from '../services/sections.js';
+import { findUnusableNotePhrase, normalizeItemNotePhrases, notePhraseLabelsSQL, MAX_ITEM_NOTE_PHRASES } from '../services/note-phrases.js';
+import {
+  ALLERGENS,
+  DIETARY_TAGS,
+  MAX_SPICY_LEVEL,
+  dietaryConditions,
+  formatDietaryInfo,
+  parseDietaryFilter,
+  type DietaryFilter,
+} from '../services/dietary.js';
 import { randomUUID } from 'node:crypto';
 
 // ── CSRF Token Management ────────────────────────────────────────────────────
@@ -47,11 +83,6 @@ function checkRateLimit(key: string, limit: number, windowMs: number): boolean {
   return true;
 }
 
-// Constants
-const maxCustomerNameLength = 100;
-const maxNotesLength = 500;
-const maxSpecialInstructionsLength = 500;
-
 // ── Helper: stripHTMLTags ────────────────────────────────────────────────────
 
 function stripHTMLTags(input: string): string {
@@ -117,17 +148,115 @@ function calculateIsOpenNow(hours: OperatingHoursEntry[], checkTime: Date): bool
 
 // ── GetPublicMenu ────────────────────────────────────────────────────────────
 
+const MENU_SELECT = `
+  SELECT p.id, p.name, p.description, p.price, p.sale_unit, p.image_url, p.category_id, c.name as category_name,
+         p.allergens, p.dietary_tags, p.spicy_level
+  FROM products p
+  LEFT JOIN categories c ON p.category_id = c.id
+  WHERE p.is_available = true AND p.deleted_at IS NULL AND c.deleted_at IS NULL
+`;
+
+const MENU_SEARCH_LIMIT = 50;
+
+// Stock is per branch; the menu shows the main branch unless told otherwise.
+// Null when the requested branch doesn't exist.
+async function resolveMenuBranch(branchParam: string): Promise<string | null> {
+  if (!branchParam) return getDefaultBranchId(pool);
+  const branch = await findActiveBranch(pool, branchParam);
+  return branch?.id ?? null;
+}
+
+// ?currency= adds prices converted for display; null means Rupiah only and
+// undefined a currency that isn't offered
+async function resolveDisplayCurrency(code: string): Promise<Currency | null | undefined> {
+  if (!code || code.toUpperCase() === SETTLEMENT_CURRENCY) return null;
+  return (await findActiveCurrency(pool, code)) ?? undefined;
+}
+
+async function formatMenuItems(rows: Record<string, unknown>[], branchId: string, currency: Currency | null = null) {
+  const availability = await getProductAvailability(pool, rows.map((row) => row.id as string), branchId);
+  const scheduledPrices = await loadScheduledPrices(pool, rows.map((row) => ({
+    id: row.id as string,
+    category_id: (row.category_id as string) ?? null,
+    price: Number(row.price),
+  })));
+
+  return rows.map((row) => {
+    const stock = availability.get(row.id as string);
+    const scheduled = scheduledPrices.get(row.id as string);
+    const price = scheduled?.price ?? Number(row.price);
+    return {
+      id: row.id,
+      name: row.name,
+      description: row.description || null,
+      price,
+      // Menu price and schedule name while a happy hour price applies
+      regular_price: scheduled ? scheduled.regular_price : null,
+      price_schedule: scheduled ? scheduled.price_schedule_name : null,
+      // Price is per this unit unless 'each'
+      sale_unit: row.sale_unit ?? 'each',
+      ...(currency && {
+        display_price: convertFromIDR(price, currency.rate_to_idr, currency.decimal_places),
+        display_currency: currency.code,
+      }),
+      image_url: row.image_url || null,
+      category_id: row.category_id || null,
+      category_name: row.category_name || '',
+      in_stock: stock?.in_stock ?? true,
+      remaining_quantity: stock?.remaining ?? null,
+      daily_special: stock?.daily_special ?? null,
+      // Badges: allergens, dietary_tags, spicy_level
+      ...formatDietaryInfo(row),
+    };
+  });
+}
+
+// Ranked, typo-tolerant menu rows for a search term
+async function searchMenuRows(term: string, categoryId: string, limit: number, dietary: DietaryFilter | null = null) {
+  const result = await searchProducts(pool, { term, categoryId: categoryId || null, menuOnly: true, limit, offset: 0 });
+  const params: unknown[] = [result.ids];
+  const dietarySql = dietary ? dietaryConditions(dietary, params) : '';
+  const res = await pool.query(`${MENU_SELECT} AND p.id = ANY($1::uuid[])${dietarySql}`, params);
+  const byId = new Map(res.rows.map((row) => [row.id, row]));
+  return {
+    rows: result.ids.map((id) => byId.get(id)).filter((row) => row !== undefined),
+    facets: result.facets,
+  };
+}
+
+// ?exclude_allergens=peanut,milk&tags=vegetarian&max_spicy=1 filter on
+// dietary info; see services/dietary.ts.
+
 export async function getPublicMenu(c: Context) {
   const categoryId = c.req.query('category_id') || '';
   const search = c.req.query('search') || '';
+  const branchParam = c.req.query('branch_id') || '';
+
+  const dietary = parseDietaryFilter({
+    exclude_allergens: c.req.query('exclude_allergens'),
+    tags: c.req.query('tags'),
+    max_spicy: c.req.query('max_spicy'),
+  });
+  if (!dietary.ok) {
+    return errorResponse(c, dietary.message, dietary.code, 400);
+  }
 
   try {
-    let query = `
-      SELECT p.id, p.name, p.description, p.price, p.image_url, p.category_id, c.name as category_name
-      FROM products p
-      LEFT JOIN categories c ON p.category_id = c.id
-      WHERE p.is_available = true
-    `;
+    const branchId = await resolveMenuBranch(branchParam);
+    if (!branchId) {
+      return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+    }
+    const currency = await resolveDisplayCurrency(c.req.query('currency') || '');
+    if (currency === undefined) {
+      return errorResponse(c, 'Currency not supported', 'unsupported_currency', 400);
+    }
+
+    if (search) {
+      const { rows } = await searchMenuRows(search, categoryId, MENU_SEARCH_LIMIT, dietary.filter);
+      return successResponse(c, 'Menu retrieved successfully', await formatMenuItems(rows, branchId, currency));
+    }
+
+    let query = MENU_SELECT;
     const params: unknown[] = [];
     let argIndex = 0;
 
@@ -136,32 +265,66 @@ export async function getPublicMenu(c: Context) {
       query += ` AND p.category_id = $${argIndex}`;
       params.push(categoryId);
     }
-
-    if (search) {
-      argIndex++;
-      query += ` AND (p.name ILIKE $${argIndex} OR p.description ILIKE $${argIndex})`;
-      params.push(`%${search}%`);
-    }
+    query += dietaryConditions(dietary.filter, params);
 
     query += ' ORDER BY p.sort_order ASC, p.name ASC';
 
     const res = await pool.query(query, params);
-    const menuItems = res.rows.map((row: Record<string, unknown>) => ({
-      id: row.id,
-      name: row.name,
-      description: row.description || null,
-      price: Number(row.price),
-      image_url: row.image_url || null,
-      category_id: row.category_id || null,
-      category_name: row.category_name || '',
-    }));
-
-    return successResponse(c, 'Menu retrieved successfully', menuItems);
+    return successResponse(c, 'Menu retrieved successfully', await formatMenuItems(res.rows, branchId, currency));
   } catch (err) {
     return errorResponse(c, 'Failed to fetch menu items', (err as Error).message);
   }
 }
 
+// ── GetPublicDietaryOptions ──────────────────────────────────────────────────
+// The values the menu's dietary filters accept, for the website's filter UI.
+
+export async function getPublicDietaryOptions(c: Context) {
+  return successResponse(c, 'Dietary options retrieved successfully', {
+    allergens: ALLERGENS,
+    dietary_tags: DIETARY_TAGS,
+    max_spicy_level: MAX_SPICY_LEVEL,
+  });
+}
+
+// ── GetPublicMenuSearch ──────────────────────────────────────────────────────
+// The menu search box: best matches first, with per-category counts.
+
+export async function getPublicMenuSearch(c: Context) {
+  const term = c.req.query('q')?.trim() ?? '';
+  const categoryId = c.req.query('category_id') || '';
+  const branchParam = c.req.query('branch_id') || '';
+
+  if (!term) {
+    return errorResponse(c, 'Search term is required', 'missing_query', 400);
+  }
+  if (term.length > 100) {
+    return errorResponse(c, 'Search term must be at most 100 characters', 'invalid_query', 400);
+  }
+  if (categoryId && !isUUID(categoryId)) {
+    return errorResponse(c, 'Invalid category ID', 'invalid_category_id', 400);
+  }
+
+  try {
+    const branchId = await resolveMenuBranch(branchParam);
+    if (!branchId) {
+      return errorResponse(c, 'Branch not found', 'branch_not_found', 404);
+    }
+    const currency = await resolveDisplayCurrency(c.req.query('currency') || '');
+    if (currency === undefined) {
+      return errorResponse(c, 'Currency not supported', 'unsupported_currency', 400);
+    }
+
+    const { rows, facets } = await searchMenuRows(term, categoryId, MENU_SEARCH_LIMIT);
+    return successResponse(c, 'Menu search completed', {
+      items: await formatMenuItems(rows, branchId, currency),
+      facets,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to search menu', (err as Error).message);
+  }
+}
+
 // ── GetPublicCategories ──────────────────────────────────────────────────────
 
 export async function getPublicCategories(c: Context) {
@@ -169,7 +332,7 @@ export async function getPublicCategories(c: Context) {
     const res = await pool.query(`
       SELECT id, name, description, color, sort_order
       FROM categories
-      WHERE is_active = true
+      WHERE is_active = true AND deleted_at IS NULL
       ORDER BY sort_order ASC, name ASC
     `);
 
@@ -330,16 +493,12 @@ export async function getTableByQRCode(c: Context) {
   }
 
   try {
-    const res = await pool.query(
-      `SELECT id, table_number, seating_capacity, location, qr_code FROM dining_tables WHERE qr_code = $1`,
-      [qrCode],
-    );
-
-    if (res.rows.length === 0) {
+    // Signed codes only, and only the table's current one
+    const row = await findTableByCode(pool, qrCode);
+    if (!row) {
       return errorResponse(c, 'Table not found. Please scan a valid QR code.', 'table_not_found', 404);
     }
 
-    const row = res.rows[0];
     const tableInfo: Record<string, unknown> = {
       id: row.id,
       table_number: row.table_number,
@@ -355,6 +514,160 @@ export async function getTableByQRCode(c: Context) {
   }
 }
 
+// ── GetCustomerOrderStatus ───────────────────────────────────────────────────
+// Progress for a QR self-order. The order number alone is guessable, so the
+// table's QR code must match too. Only what the guest needs is returned: no
+// staff, notes or payment details.
+
+const customerStatusMessages: Record<string, string> = {
+  scheduled: 'Your order is scheduled',
+  pending: 'Your order has been received',
+  confirmed: 'Your order has been confirmed',
+  preparing: 'Your order is being prepared',
+  ready: 'Your order is ready',
+  served: 'Your order has been served',
+  completed: 'Your order is complete. Thank you!',
+  cancelled: 'Your order has been cancelled',
+};
+
+const waitingStatuses = ['pending', 'confirmed', 'preparing'];
+
+export async function getCustomerOrderStatus(c: Context) {
+  const clientIP = c.req.header('x-forwarded-for') || c.req.header('x-real-ip') || 'unknown';
+  if (!checkRateLimit(`status:${clientIP}`, 30, 60_000)) {
+    return errorResponse(c, 'Too many requests. Please wait a moment before checking again.', 'rate_limit_exceeded', 429);
+  }
+
+  const orderNumber = c.req.param('order_number');
+  const qrCode = c.req.query('qr_code') || c.req.header('x-table-qr') || '';
+  if (!qrCode) {
+    return errorResponse(c, 'QR code is required', 'qr_code_required', 400);
+  }
+
+  const claim = verifyTableCode(qrCode);
+  if (!claim) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  try {
+    const orderRes = await pool.query(
+      `SELECT o.id, o.order_number, o.status, o.created_at, o.total_amount, o.display_currency, o.exchange_rate,
+              t.table_number, cur.symbol AS currency_symbol, cur.decimal_places AS currency_decimals
+       FROM orders o
+       JOIN dining_tables t ON t.id = o.table_id
+       LEFT JOIN currencies cur ON cur.code = o.display_currency
+       WHERE o.order_number = $1 AND t.id = $2 AND t.qr_version = $3`,
+      [orderNumber, claim.tableId, claim.version],
+    );
+
+    if (orderRes.rows.length === 0) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+    const order = orderRes.rows[0];
+
+    const itemsRes = await pool.query(
+      `SELECT p.name, p.sale_unit, oi.quantity, oi.status
+       FROM order_items oi
+       JOIN products p ON p.id = oi.product_id
+       WHERE oi.order_id = $1
+       ORDER BY oi.created_at ASC`,
+      [order.id],
+    );
+
+    const items = itemsRes.rows.map((r) => ({
+      name: r.name as string,
+      quantity: Number(r.quantity),
+      sale_unit: r.sale_unit as string,
+      status: (r.status as string) || 'pending',
+      ready: r.status === 'ready' || r.status === 'served',
+    }));
+
+    // Same estimate the counter quotes, so it accounts for the queue ahead
+    const waitEstimate = waitingStatuses.includes(order.status) ? await estimateOrderWait(pool, order.id) : null;
+
+    return successResponse(c, 'Order status retrieved successfully', {
+      order_number: order.order_number,
+      table_number: order.table_number,
+      status: order.status,
+      status_message: customerStatusMessages[order.status] ?? 'Your order is being processed',
+      items,
+      items_ready: items.filter((i) => i.ready).length,
+      items_total: items.length,
+      estimated_wait_minutes: waitEstimate?.estimated_wait_minutes ?? null,
+      estimated_ready_at: waitEstimate?.estimated_ready_at ?? null,
+      total_amount: Number(order.total_amount),
+      currency: orderCurrency(order),
+      created_at: order.created_at,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order status', (err as Error).message);
+  }
+}
+
+// ── GetCustomerOrder ────────────────────────────────────────────────────────
+// The order behind a customer link: the tracking page polls it with the
+// order token, the survey page reads the order number with a survey token.
+
+export async function getCustomerOrder(c: Context) {
+  const link = resolveOrderToken(c, ['order', 'survey']);
+  if (!link.ok) {
+    return errorResponse(c, link.failure.message, link.failure.code, link.failure.status);
+  }
+
+  try {
+    const orderRes = await pool.query(
+      `SELECT o.id, o.order_number, o.order_type, o.status, o.subtotal, o.tax_amount, o.total_amount,
+              o.display_currency, o.exchange_rate, o.created_at, t.table_number,
+              cur.symbol AS currency_symbol, cur.decimal_places AS currency_decimals,
+              EXISTS(SELECT 1 FROM satisfaction_surveys s WHERE s.order_id = o.id) AS survey_submitted
+       FROM orders o
+       LEFT JOIN dining_tables t ON t.id = o.table_id
+       LEFT JOIN currencies cur ON cur.code = o.display_currency
+       WHERE o.id = $1`,
+      [link.orderId],
+    );
+    const order = orderRes.rows[0];
+    if (!order) {
+      return errorResponse(c, 'Order not found', 'order_not_found', 404);
+    }
+
+    const itemsRes = await pool.query(
+      `SELECT p.name, p.sale_unit, oi.quantity, oi.total_price, oi.special_instructions, oi.status,
+              ${notePhraseLabelsSQL('oi.note_phrases')} AS note_phrase_labels
+       FROM order_items oi
+       JOIN products p ON p.id = oi.product_id
+       WHERE oi.order_id = $1
+       ORDER BY oi.created_at ASC`,
+      [order.id],
+    );
+
+    return successResponse(c, 'Order retrieved successfully', {
+      order_number: order.order_number,
+      order_type: order.order_type,
+      table_number: order.table_number,
+      status: order.status,
+      status_message: customerStatusMessages[order.status] ?? 'Your order is being processed',
+      items: itemsRes.rows.map((r) => ({
+        product: { name: r.name as string },
+        quantity: Number(r.quantity),
+        sale_unit: r.sale_unit as string,
+        total_price: Number(r.total_price),
+        special_instructions: r.special_instructions || null,
+        note_phrase_labels: r.note_phrase_labels as string[],
+        status: (r.status as string) || 'pending',
+      })),
+      subtotal: Number(order.subtotal),
+      tax_amount: Number(order.tax_amount),
+      total_amount: Number(order.total_amount),
+      currency: orderCurrency(order),
+      survey_submitted: order.survey_submitted,
+      created_at: order.created_at,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch order', (err as Error).message);
+  }
+}
+
 // ── CreateCustomerOrder ──────────────────────────────────────────────────────
 
 export async function createCustomerOrder(c: Context) {
@@ -366,7 +679,7 @@ export async function createCustomerOrder(c: Context) {
 
   // CSRF validation
   const csrfToken = c.req.header('x-csrf-token') || '';
-  const csrfEnabled = process.env.CSRF_ENABLED !== 'false';
+  const csrfEnabled = env.CSRF_ENABLED;
   if (csrfEnabled && csrfToken) {
     if (!validateCSRFToken(csrfToken)) {
       return errorResponse(c, 'Invalid or expired security token. Please refresh and try again.', 'invalid_csrf_token', 403);
@@ -374,14 +687,25 @@ export async function createCustomerOrder(c: Context) {
   }
 
   let body: {
-    table_id: string;
+    table_id?: string;
+    order_type?: 'dine_in' | 'takeout' | 'delivery';
     customer_name?: string;
+    scheduled_at?: string | null;
+    delivery_address?: string;
+    delivery_phone?: string;
+    delivery_notes?: string;
+    branch_id?: string;
     items: Array<{
       product_id: string;
       quantity: number;
       special_instructions?: string;
+      note_phrases?: string[];
     }>;
     notes?: string;
+    currency?: string;
+    receipt_language?: string;
+    device_fingerprint?: string;
+    location?: { latitude?: number; longitude?: number; accuracy?: number } | null;
   };
 
   try {
@@ -390,7 +714,14 @@ export async function createCustomerOrder(c: Context) {
     return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
   }
 
-  if (!body.table_id) {
+  // Table QR codes place dine-in orders; the online menu places takeout and
+  // delivery orders, optionally for a later pickup/delivery time
+  const orderType = body.order_type || 'dine_in';
+  if (!ORDER_TYPES.includes(orderType)) {
+    return errorResponse(c, 'Order type must be dine_in, takeout or delivery', 'invalid_order_type', 400);
+  }
+
+  if (orderType === 'dine_in' && !body.table_id) {
     return errorResponse(c, 'Table ID is required', 'table_id_required', 400);
   }
 
@@ -400,23 +731,53 @@ export async function createCustomerOrder(c: Context) {
 
   // Input length validation
   let customerName = (body.customer_name || '').trim();
-  if (customerName.length > maxCustomerNameLength) {
+  if (customerName.length > CUSTOMER_ORDER_LIMITS.customer_name) {
     return errorResponse(c, 'Customer name is too long (max 100 characters)', 'customer_name_too_long', 400);
   }
+  if (orderType !== 'dine_in' && !customerName) {
+    return errorResponse(c, 'Customer name is required for takeout and delivery orders', 'customer_name_required', 400);
+  }
+
+  let delivery: DeliveryDetails | null = null;
+  if (orderType === 'delivery') {
+    const checked = validateDeliveryDetails(body);
+    if (!checked.ok) {
+      return errorResponse(c, checked.message, checked.code, 400);
+    }
+    delivery = {
+      ...checked.details,
+      address: stripHTMLTags(checked.details.address),
+      notes: checked.details.notes ? stripHTMLTags(checked.details.notes) : null,
+    };
+  }
 
   let notes = (body.notes || '').trim();
-  if (notes.length > maxNotesLength) {
+  if (notes.length > CUSTOMER_ORDER_LIMITS.notes) {
     return errorResponse(c, 'Notes are too long (max 500 characters)', 'notes_too_long', 400);
   }
 
+  if (body.receipt_language !== undefined && !isReceiptLanguage(body.receipt_language)) {
+    return errorResponse(c, "Receipt language must be 'id' or 'id_en'", 'invalid_receipt_language', 400);
+  }
+
+  // Whole numbers are checked per product once its sale unit is known
+  for (const item of body.items) {
+    if (typeof item.quantity !== 'number' || !(item.quantity > 0)) {
+      return errorResponse(c, 'Item quantity must be a positive number', 'invalid_quantity', 400);
+    }
+  }
+
   // Validate special instructions length
   for (const item of body.items) {
     const si = (item.special_instructions || '').trim();
-    if (si.length > maxSpecialInstructionsLength) {
+    if (si.length > CUSTOMER_ORDER_LIMITS.special_instructions) {
       return errorResponse(c, 'Special instructions too long (max 500 characters)', 'special_instructions_too_long', 400);
     }
     item.special_instructions = si;
   }
+  if (!normalizeItemNotePhrases(body.items)) {
+    return errorResponse(c, `note_phrases must be a list of at most ${MAX_ITEM_NOTE_PHRASES} phrase codes`, 'invalid_note_phrases', 400);
+  }
 
   // Sanitize text inputs
   customerName = stripHTMLTags(customerName);
@@ -425,85 +786,277 @@ export async function createCustomerOrder(c: Context) {
     item.special_instructions = stripHTMLTags(item.special_instructions || '');
   }
 
+  let schedule: ScheduleResult;
+  let currency: Currency | null | undefined;
   try {
-    // Verify table exists
-    const tableRes = await pool.query(
-      `SELECT table_number FROM dining_tables WHERE id = $1`,
-      [body.table_id],
-    );
+    schedule = await resolveSchedule(pool, orderType, body.scheduled_at);
+    currency = await resolveDisplayCurrency(typeof body.currency === 'string' ? body.currency : '');
+  } catch (err) {
+    return errorResponse(c, 'Failed to validate order', (err as Error).message);
+  }
+  if (!schedule.ok) {
+    return errorResponse(c, schedule.failure.message, schedule.failure.code, schedule.failure.status);
+  }
+  if (currency === undefined) {
+    return errorResponse(c, 'Currency not supported', 'unsupported_currency', 400);
+  }
 
-    if (tableRes.rows.length === 0) {
-      return errorResponse(c, 'Invalid table ID', 'table_not_found', 400);
-    }
+  // Stock is checked and deducted with the order in one transaction so two
+  // tables can't both order the last portion
+  try {
+    const result = await withTransaction(async (client) => {
+      // Verify table exists. Dine-in orders go to the table's branch; online
+      // orders to the branch the customer picked, or the main branch.
+      let tableNumber: string | null = null;
+      let branchId: string;
+      if (orderType === 'dine_in') {
+        const tableRes = await client.query(
+          `SELECT table_number, branch_id FROM dining_tables WHERE id = $1 AND deleted_at IS NULL`,
+          [body.table_id],
+        );
+
+        if (tableRes.rows.length === 0) {
+          return txFailure('Invalid table ID', 'table_not_found', 400);
+        }
+
+        tableNumber = tableRes.rows[0].table_number;
+        branchId = tableRes.rows[0].branch_id;
+      } else if (body.branch_id) {
+        const branch = await findActiveBranch(client, body.branch_id);
+        if (!branch) {
+          return txFailure('Branch not found', 'branch_not_found', 400);
+        }
+        branchId = branch.id;
+      } else {
+        branchId = await getDefaultBranchId(client);
+      }
+
+      // Generate order number
+      const now = new Date();
+      const dateStr = now.toISOString().slice(2, 10).replace(/-/g, '');
+      const nano = now.getTime() % 10000;
+      const orderNumber = `QR${dateStr}-${nano}`;
+
+      // Validate products and price the basket
+      const lines: PricingLine[] = [];
+      for (const item of body.items) {
+        const productRes = await client.query(
+          `SELECT name, price, category_id, sale_unit FROM products WHERE id = $1 AND is_available = true AND deleted_at IS NULL`,
+          [item.product_id],
+        );
+
+        if (productRes.rows.length === 0) {
+          return txFailure('Product not found or unavailable', 'product_not_found', 400);
+        }
+
+        const prod = productRes.rows[0];
+        const unusable = await findUnusableNotePhrase(client, item.note_phrases ?? [], prod.category_id, { customer: true });
+        if (unusable) {
+          return txFailure(`Note phrase '${unusable}' is not available for ${prod.name}`, 'note_phrase_unavailable', 400);
+        }
+        const qty = resolveItemQuantity(prod, { quantity: item.quantity });
+        if (!qty.ok) {
+          return txFailure(qty.message, qty.code, 400);
+        }
+        lines.push({
+          product_id: item.product_id,
+          category_id: prod.category_id,
+          name: prod.name,
+          unit_price: Number(prod.price),
+          quantity: qty.value.quantity,
+        });
+      }
 
-    const tableNumber = tableRes.rows[0].table_number;
+      const priceScheduleIds = await applyPriceSchedules(client, lines);
+      const pricing = await priceOrder(client, lines);
+      const subtotal = pricing.subtotal;
+      const discountAmount = pricing.discount_amount;
 
-    // Generate order number
-    const now = new Date();
-    const dateStr = now.toISOString().slice(2, 10).replace(/-/g, '');
-    const nano = now.getTime() % 10000;
-    const orderNumber = `QR${dateStr}-${nano}`;
-
-    // Calculate subtotal
-    let subtotal = 0;
-    for (const item of body.items) {
-      const productRes = await pool.query(
-        `SELECT price FROM products WHERE id = $1 AND is_available = true`,
-        [item.product_id],
-      );
+      const taxes = await computeOrderTaxes(client, branchId, orderType, taxLines(lines, pricing.line_discounts));
 
-      if (productRes.rows.length === 0) {
-        return errorResponse(c, 'Product not found or unavailable', 'product_not_found', 400);
+      let deliveryFee = 0;
+      if (delivery) {
+        const deliverySettings = await loadDeliverySettings(client);
+        if (subtotal - discountAmount < deliverySettings.minOrder) {
+          return txFailure(`Delivery orders must be at least ${deliverySettings.minOrder}`, 'below_delivery_minimum', 400);
+        }
+        deliveryFee = computeDeliveryFee(deliverySettings, subtotal - discountAmount);
       }
 
-      subtotal += Number(productRes.rows[0].price) * item.quantity;
-    }
+      const surcharges = await computeOrderSurcharges(
+        client, branchId, orderType, subtotal - discountAmount,
+        schedule.scheduledAt ? new Date(schedule.scheduledAt) : new Date(),
+      );
 
-    // Get tax rate from settings (default 11%)
-    let taxRate = 11.0;
-    const taxRes = await pool.query(
-      `SELECT setting_value FROM system_settings WHERE setting_key = 'tax_rate'`,
-    );
-    if (taxRes.rows.length > 0) {
-      const parsed = parseFloat(taxRes.rows[0].setting_value);
-      if (!isNaN(parsed)) taxRate = parsed;
-    }
+      const taxAmount = taxes.tax_amount + surcharges.tax_amount;
+      const serviceChargeAmount = taxes.service_charge_amount;
+      const totalAmount = subtotal - discountAmount + serviceChargeAmount + surcharges.amount + taxAmount + deliveryFee;
+
+      // A table's order goes to the server on shift in its section
+      const serverId = orderType === 'dine_in' ? await resolveOrderServer(client, body.table_id!, null) : null;
+
+      // Create order
+      const orderRes = await client.query(
+        `INSERT INTO orders (order_number, table_id, customer_name, order_type, status, subtotal, tax_amount, discount_amount, total_amount, notes, scheduled_at,
+                             delivery_address, delivery_phone, delivery_notes, delivery_fee, delivery_status, branch_id, service_charge_amount,
+                             display_currency, exchange_rate, receipt_language, surcharge_amount, server_id)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+         RETURNING id`,
+        [
+          orderNumber,
+          orderType === 'dine_in' ? body.table_id : null,
+          customerName || null,
+          orderType,
+          schedule.status,
+          subtotal,
+          taxAmount,
+          discountAmount,
+          totalAmount,
+          notes || null,
+          schedule.scheduledAt,
+          delivery?.address ?? null,
+          delivery?.phone ?? null,
+          delivery?.notes ?? null,
+          deliveryFee,
+          delivery ? 'unassigned' : null,
+          branchId,
+          serviceChargeAmount,
+          currency?.code ?? null,
+          currency?.rate_to_idr ?? null,
+          body.receipt_language ?? null,
+          surcharges.amount,
+          serverId,
+        ],
+      );
 
-    const taxAmount = subtotal * (taxRate / 100);
-    const totalAmount = subtotal + taxAmount;
+      const orderId = orderRes.rows[0].id;
 
-    // Create order
-    const orderRes = await pool.query(
-      `INSERT INTO orders (order_number, table_id, customer_name, order_type, status, subtotal, tax_amount, total_amount, notes)
-       VALUES ($1, $2, $3, 'dine_in', 'pending', $4, $5, $6, $7)
-       RETURNING id`,
-      [orderNumber, body.table_id, customerName || null, subtotal, taxAmount, totalAmount, notes || null],
-    );
+      // The customer's pick is remembered for their next delivery order
+      if (isReceiptLanguage(body.receipt_language)) {
+        await saveCustomerReceiptLanguage(client, delivery?.phone, body.receipt_language);
+      }
 
-    const orderId = orderRes.rows[0].id;
+      const shortage = await deductStockForOrder(client, orderId, lines);
+      if (shortage) {
+        const message = shortage.remaining === 0
+          ? `Sorry, ${shortage.name} is sold out`
+          : `Sorry, only ${shortage.remaining} ${shortage.name} left`;
+        return txFailure(message, 'insufficient_stock', 409);
+      }
 
-    // Create order items
-    for (const item of body.items) {
-      const priceRes = await pool.query(`SELECT price FROM products WHERE id = $1`, [item.product_id]);
-      const price = Number(priceRes.rows[0].price);
+      // Create order items
+      for (const [idx, item] of body.items.entries()) {
+        const price = lines[idx].unit_price;
+        const tax = taxes.lines[idx];
+
+        await client.query(
+          `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions,
+                                    tax_amount, service_charge_amount, tax_exempt, service_exempt,
+                                    tax_class_id, tax_label, tax_rate, price_schedule_id, note_phrases)
+           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+          [
+            orderId, item.product_id, lines[idx].quantity, price, lineTotal(price, lines[idx].quantity), item.special_instructions || null,
+            tax.tax_amount, tax.service_charge_amount, tax.tax_exempt, tax.service_exempt,
+            tax.tax_class_id, tax.tax_label, tax.tax_rate, priceScheduleIds[idx], item.note_phrases ?? [],
+          ],
+        );
+      }
 
-      await pool.query(
-        `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions)
-         VALUES ($1, $2, $3, $4, $5, $6)`,
-        [orderId, item.product_id, item.quantity, price, price * item.quantity, item.special_instructions || null],
+      await recordPricingAdjustments(client, orderId, pricing.adjustments);
+      await recordOrderSurcharges(client, orderId, surcharges.lines);
+
+      const riskFlags = await recordOrderSource(
+        client,
+        { id: orderId, branchId, tableId: orderType === 'dine_in' ? body.table_id! : null, orderType },
+        {
+          ipAddress: clientIP === 'unknown' ? null : clientIP.split(',')[0].trim(),
+          userAgent: c.req.header('user-agent') ?? null,
+          fingerprint: body.device_fingerprint,
+          location: body.location,
+        },
       );
+
+      // Scheduled orders are released to the kitchen by the scheduler instead
+      const heldItems = schedule.status === 'scheduled' ? 0 : await holdCustomerOrderItems(client, orderId, branchId);
+
+      // Mark table as occupied
+      if (orderType === 'dine_in') {
+        await client.query(`UPDATE dining_tables SET is_occupied = true WHERE id = $1`, [body.table_id]);
+      }
+
+      await emitWebhookEvent(client, 'order.created', () => orderEventData(client, orderId));
+
+      return {
+        ok: true as const,
+        tableNumber, orderNumber, pricing, subtotal, discountAmount, deliveryFee,
+        surcharges, taxAmount, serviceChargeAmount, totalAmount, orderId, riskFlags, heldItems,
+      };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const {
+      tableNumber, orderNumber, pricing, subtotal, discountAmount, deliveryFee,
+      surcharges, taxAmount, serviceChargeAmount, totalAmount, orderId, riskFlags, heldItems,
+    } = result;
+    await refreshStockAvailability({ orderId });
+
+    ordersCreatedTotal.inc({ order_type: orderType, source: 'customer' });
+
+    const where = tableNumber ? `table ${tableNumber}` : orderType.replace('_', ' ');
+    const flagNote = riskFlags.length > 0
+      ? ` Flagged: ${riskFlags.map((f) => ORDER_RISK_FLAGS[f].toLowerCase()).join('; ')}.`
+      : '';
+    if (heldItems > 0) {
+      for (const role of ['counter', 'server']) {
+        await createNotificationForRole(role, 'order_update', 'Order Awaiting Acceptance', `Order ${orderNumber} (${where}) is waiting to be accepted.${flagNote}`, 'order_awaiting_acceptance');
+      }
+    } else if (riskFlags.length > 0) {
+      // Nobody reviews the order before the kitchen starts on it
+      for (const role of ['counter', 'manager']) {
+        await createNotificationForRole(role, 'order_update', 'Order Flagged', `Order ${orderNumber} (${where}) was sent to the kitchen.${flagNote}`, 'order_flagged');
+      }
+    }
+
+    if (delivery) {
+      await warnFlaggedCustomer(pool, delivery.phone, ['counter', 'manager'], `Delivery order ${orderNumber}`);
     }
 
-    // Mark table as occupied
-    await pool.query(`UPDATE dining_tables SET is_occupied = true WHERE id = $1`, [body.table_id]);
+    const waitEstimate = schedule.status === 'scheduled' ? null : await estimateOrderWait(pool, orderId).catch(() => null);
 
-    return successResponse(c, 'Order placed successfully! Your order will be prepared shortly.', {
+    const message = schedule.status === 'scheduled'
+      ? 'Order scheduled successfully! We will start preparing it shortly before your pickup time.'
+      : heldItems > 0
+        ? 'Order placed successfully! Our staff will confirm it shortly.'
+        : 'Order placed successfully! Your order will be prepared shortly.';
+    const orderLink = signOrderToken(orderId, 'order');
+    return successResponse(c, message, {
       order_id: orderId,
       order_number: orderNumber,
+      order_token: orderLink.token,
+      order_token_expires_at: orderLink.expires_at,
+      status_url: orderStatusLink(orderLink.token),
+      order_type: orderType,
+      status: schedule.status,
+      scheduled_at: schedule.scheduledAt,
       table_number: tableNumber,
       subtotal,
+      discount_amount: discountAmount,
+      service_charge_amount: serviceChargeAmount,
+      surcharge_amount: surcharges.amount,
+      surcharges: surcharges.lines,
       tax_amount: taxAmount,
+      delivery_fee: deliveryFee,
       total_amount: totalAmount,
+      currency: orderCurrency({
+        total_amount: totalAmount,
+        display_currency: currency?.code ?? null,
+        exchange_rate: currency?.rate_to_idr ?? null,
+        currency_symbol: currency?.symbol,
+        currency_decimals: currency?.decimal_places,
+      }),
+      applied_promotions: pricing.adjustments.map((a) => ({ name: a.rule_name, amount: a.amount })),
+      estimated_wait_minutes: waitEstimate?.estimated_wait_minutes ?? null,
+      estimated_ready_at: waitEstimate?.estimated_ready_at ?? null,
+      awaiting_acceptance: heldItems > 0,
     }, 201);
   } catch (err) {
     return errorResponse(c, 'Failed to create order', (err as Error).message);
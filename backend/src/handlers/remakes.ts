@@ -0,0 +1,177 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { isUUID, resolveBranchScope, branchCondition } from '../services/branches.js';
+import { notifyOrderItemRemake } from '../services/notification.js';
+import {
+  REMAKE_REASONS,
+  REMAKE_SELECT,
+  isRemakeReason,
+  recordRemake,
+  buildKitchenQualityReport,
+} from '../services/remakes.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+// ── RemakeOrderItem ─────────────────────────────────────────────────────────
+// Logs a sent-back dish and sends a free remake of it to the kitchen.
+
+export async function remakeOrderItem(c: Context) {
+  const orderId = c.req.param('id');
+  const itemId = c.req.param('item_id');
+  if (!isUUID(orderId) || !isUUID(itemId)) {
+    return errorResponse(c, 'Order item not found', 'order_item_not_found', 404);
+  }
+
+  let body: { reason_type?: string; reason?: string; quantity?: number };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!isRemakeReason(body.reason_type)) {
+    return errorResponse(c, `reason_type must be one of: ${REMAKE_REASONS.join(', ')}`, 'invalid_reason_type', 400);
+  }
+  const reason = body.reason?.trim() || null;
+  if (reason && reason.length > 500) {
+    return errorResponse(c, 'Reason must be at most 500 characters', 'invalid_reason', 400);
+  }
+  if (body.reason_type === 'other' && !reason) {
+    return errorResponse(c, "Describe the reason when reason_type is 'other'", 'invalid_reason', 400);
+  }
+  if (body.quantity !== undefined && (typeof body.quantity !== 'number' || !Number.isInteger(body.quantity) || body.quantity < 1)) {
+    return errorResponse(c, 'Quantity must be a positive whole number', 'invalid_quantity', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const remake = await recordRemake(client, {
+        orderId,
+        itemId,
+        quantity: body.quantity ?? null,
+        reasonType: body.reason_type,
+        reason,
+        userId: c.get('user_id') ?? null,
+        branchId: c.get('branch_id') ?? null,
+      });
+      if (!remake.ok) {
+        return remake;
+      }
+
+      return { ok: true as const, remake };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { remake } = result;
+
+    const { result } = remake;
+    notifyOrderItemRemake(result.orderNumber, result.tableNumber, {
+      name: result.productName,
+      quantity: result.quantity,
+      reason: reason ? `${body.reason_type}: ${reason}` : body.reason_type,
+    });
+
+    const res = await pool.query(`${REMAKE_SELECT} WHERE r.id = $1`, [result.remakeId]);
+    return successResponse(c, 'Remake sent to the kitchen', res.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to log remake', (err as Error).message);
+  }
+}
+
+// ── GetRemakes ──────────────────────────────────────────────────────────────
+// Newest first; ?from= / ?to= dates, ?reason_type=, ?order_id=.
+
+export async function getRemakes(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const from = c.req.query('from') || '';
+  const to = c.req.query('to') || '';
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to))) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates', 'invalid_date_range', 400);
+  }
+  const reasonType = c.req.query('reason_type');
+  if (reasonType && !isRemakeReason(reasonType)) {
+    return errorResponse(c, `reason_type must be one of: ${REMAKE_REASONS.join(', ')}`, 'invalid_reason_type', 400);
+  }
+  const orderId = c.req.query('order_id');
+  if (orderId && !isUUID(orderId)) {
+    return errorResponse(c, 'Order not found', 'order_not_found', 404);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const params: unknown[] = [RESTAURANT_TIMEZONE];
+  let where = 'WHERE 1=1';
+  if (from) {
+    params.push(from);
+    where += ` AND DATE(r.created_at AT TIME ZONE $1) >= $${params.length}`;
+  }
+  if (to) {
+    params.push(to);
+    where += ` AND DATE(r.created_at AT TIME ZONE $1) <= $${params.length}`;
+  }
+  if (reasonType) {
+    params.push(reasonType);
+    where += ` AND r.reason_type = $${params.length}`;
+  }
+  if (orderId) {
+    params.push(orderId);
+    where += ` AND r.order_id = $${params.length}`;
+  }
+  where += branchCondition('r.branch_id', scope.branchId, params);
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM order_item_remakes r ${where}`, params);
+        return parseInt(countRes.rows[0].count, 10);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${REMAKE_SELECT}
+           ${where}
+           ORDER BY r.created_at DESC, r.id DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Remakes retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { from, to, reason_type: reasonType, order_id: orderId, branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch remakes', (err as Error).message);
+  }
+}
+
+// ── GetKitchenQualityReport ─────────────────────────────────────────────────
+// Remakes over [from, to] (default: this month so far).
+
+export async function getKitchenQualityReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const report = await buildKitchenQualityReport(pool, from, to, scope.branchId);
+    return successResponse(c, 'Kitchen quality report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate kitchen quality report', (err as Error).message);
+  }
+}
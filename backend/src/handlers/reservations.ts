@@ -2,6 +2,9 @@ import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
 import { successResponse, errorResponse } from '../lib/response.js';
+import { generateResponseToken, customerNoShowStats, RESERVATION_START_SQL } from '../services/reservations.js';
+import { findCustomerFlags, flaggedPhoneSql, warnFlaggedCustomer } from '../services/customer-flags.js';
+import { RESERVATION_STATUS_UPDATES } from '../services/data-model.js';
 
 // ── Constants ──────────────────────────────────────────────────────────────────
 const RESTAURANT_OPEN_HOUR = 10;
@@ -25,21 +28,21 @@ export async function createReservation(c: Context) {
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ success: false, error: 'Invalid request format' }, 400);
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
   }
 
   // Validate
   const validationError = validateReservationRequest(body);
   if (validationError) {
-    return c.json({ success: false, error: validationError }, 400);
+    return errorResponse(c, validationError, 'validation_error', 400);
   }
 
   try {
     const res = await pool.query(
       `INSERT INTO reservations (
         customer_name, email, phone, party_size,
-        reservation_date, reservation_time, special_requests
-      ) VALUES ($1, $2, $3, $4, $5::date, $6::time, $7)
+        reservation_date, reservation_time, special_requests, response_token
+      ) VALUES ($1, $2, $3, $4, $5::date, $6::time, $7, $8)
       RETURNING id, customer_name, email, phone, party_size,
         to_char(reservation_date, 'YYYY-MM-DD') as reservation_date,
         to_char(reservation_time, 'HH24:MI') as reservation_time,
@@ -52,11 +55,20 @@ export async function createReservation(c: Context) {
         body.reservation_date,
         body.reservation_time,
         body.special_requests || null,
+        generateResponseToken(),
       ],
     );
 
     const row = res.rows[0];
 
+    // Staff are warned; the guest is not told their number is flagged
+    await warnFlaggedCustomer(
+      pool,
+      row.phone,
+      ['admin', 'manager'],
+      `Reservation for ${row.party_size} on ${row.reservation_date} ${row.reservation_time}`,
+    );
+
     return c.json({
       success: true,
       message: 'Reservation created successfully',
@@ -119,7 +131,8 @@ export async function getReservations(c: Context) {
       SELECT id, customer_name, email, phone, party_size,
         to_char(reservation_date, 'YYYY-MM-DD') as reservation_date,
         to_char(reservation_time, 'HH24:MI') as reservation_time,
-        special_requests, status, notes, confirmed_by, confirmed_at, created_at, updated_at
+        special_requests, status, notes, confirmed_by, confirmed_at, table_id, reminder_sent_at, customer_responded_at, created_at, updated_at,
+        ${flaggedPhoneSql('reservations.phone')} AS customer_flagged
       FROM reservations
       WHERE 1=1
     `;
@@ -156,6 +169,10 @@ export async function getReservations(c: Context) {
       ...(r.notes != null && { notes: r.notes }),
       ...(r.confirmed_by != null && { confirmed_by: r.confirmed_by }),
       ...(r.confirmed_at != null && { confirmed_at: r.confirmed_at }),
+      ...(r.table_id != null && { table_id: r.table_id }),
+      ...(r.reminder_sent_at != null && { reminder_sent_at: r.reminder_sent_at }),
+      ...(r.customer_responded_at != null && { customer_responded_at: r.customer_responded_at }),
+      customer_flagged: r.customer_flagged,
       created_at: r.created_at,
       updated_at: r.updated_at,
     }));
@@ -187,7 +204,7 @@ export async function getReservation(c: Context) {
       `SELECT id, customer_name, email, phone, party_size,
         to_char(reservation_date, 'YYYY-MM-DD') as reservation_date,
         to_char(reservation_time, 'HH24:MI') as reservation_time,
-        special_requests, status, notes, confirmed_by, confirmed_at, created_at, updated_at
+        special_requests, status, notes, confirmed_by, confirmed_at, table_id, reminder_sent_at, customer_responded_at, created_at, updated_at
       FROM reservations WHERE id = $1`,
       [id],
     );
@@ -197,6 +214,7 @@ export async function getReservation(c: Context) {
     }
 
     const r = res.rows[0];
+    const flags = await findCustomerFlags(pool, r.phone);
 
     return c.json({
       success: true,
@@ -213,6 +231,10 @@ export async function getReservation(c: Context) {
         ...(r.notes != null && { notes: r.notes }),
         ...(r.confirmed_by != null && { confirmed_by: r.confirmed_by }),
         ...(r.confirmed_at != null && { confirmed_at: r.confirmed_at }),
+        ...(r.table_id != null && { table_id: r.table_id }),
+        ...(r.reminder_sent_at != null && { reminder_sent_at: r.reminder_sent_at }),
+        ...(r.customer_responded_at != null && { customer_responded_at: r.customer_responded_at }),
+        customer_flags: flags,
         created_at: r.created_at,
         updated_at: r.updated_at,
       },
@@ -231,19 +253,15 @@ export async function updateReservationStatus(c: Context) {
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ success: false, error: 'Invalid request format' }, 400);
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
   }
 
   if (!body.status) {
-    return c.json({ success: false, error: 'Status is required' }, 400);
+    return errorResponse(c, 'Status is required', 'missing_status', 400);
   }
 
-  const validStatuses = ['confirmed', 'cancelled', 'completed', 'no_show'];
-  if (!validStatuses.includes(body.status)) {
-    return c.json({
-      success: false,
-      error: 'Invalid status. Must be one of: confirmed, cancelled, completed, no_show',
-    }, 400);
+  if (!RESERVATION_STATUS_UPDATES.includes(body.status)) {
+    return errorResponse(c, `Invalid status. Must be one of: ${RESERVATION_STATUS_UPDATES.join(', ')}`, 'invalid_status', 400);
   }
 
   const userId = c.get('user_id');
@@ -260,7 +278,7 @@ export async function updateReservationStatus(c: Context) {
         RETURNING id, customer_name, email, phone, party_size,
           to_char(reservation_date, 'YYYY-MM-DD') as reservation_date,
           to_char(reservation_time, 'HH24:MI') as reservation_time,
-          special_requests, status, notes, confirmed_by, confirmed_at, created_at, updated_at
+          special_requests, status, notes, confirmed_by, confirmed_at, table_id, reminder_sent_at, customer_responded_at, created_at, updated_at
       `;
       params = [body.status, body.notes ?? null, userId, id];
     } else {
@@ -271,7 +289,7 @@ export async function updateReservationStatus(c: Context) {
         RETURNING id, customer_name, email, phone, party_size,
           to_char(reservation_date, 'YYYY-MM-DD') as reservation_date,
           to_char(reservation_time, 'HH24:MI') as reservation_time,
-          special_requests, status, notes, confirmed_by, confirmed_at, created_at, updated_at
+          special_requests, status, notes, confirmed_by, confirmed_at, table_id, reminder_sent_at, customer_responded_at, created_at, updated_at
       `;
       params = [body.status, body.notes ?? null, id];
     }
@@ -300,6 +318,9 @@ export async function updateReservationStatus(c: Context) {
         ...(r.notes != null && { notes: r.notes }),
         ...(r.confirmed_by != null && { confirmed_by: r.confirmed_by }),
         ...(r.confirmed_at != null && { confirmed_at: r.confirmed_at }),
+        ...(r.table_id != null && { table_id: r.table_id }),
+        ...(r.reminder_sent_at != null && { reminder_sent_at: r.reminder_sent_at }),
+        ...(r.customer_responded_at != null && { customer_responded_at: r.customer_responded_at }),
         created_at: r.created_at,
         updated_at: r.updated_at,
       },
@@ -346,6 +367,190 @@ export async function getPendingReservationsCount(c: Context) {
   }
 }
 
+// ── GetReservationResponse (public) ─────────────────────────────────────────
+// The page behind a reminder link. Links aren't acted on by GET so message
+// previews can't confirm or cancel a booking on the guest's behalf.
+
+export async function getReservationResponse(c: Context) {
+  const token = c.req.param('token');
+
+  try {
+    const res = await pool.query(
+      `SELECT r.customer_name, r.party_size, r.status,
+        to_char(r.reservation_date, 'YYYY-MM-DD') as reservation_date,
+        to_char(r.reservation_time, 'HH24:MI') as reservation_time,
+        r.customer_responded_at,
+        r.status IN ('pending', 'confirmed') AND ${RESERVATION_START_SQL} > NOW() AS can_respond
+      FROM reservations r WHERE r.response_token = $1`,
+      [token],
+    );
+
+    if (res.rows.length === 0) {
+      return c.json({ success: false, error: 'Reservation not found' }, 404);
+    }
+
+    const r = res.rows[0];
+
+    return c.json({
+      success: true,
+      data: {
+        customer_name: r.customer_name,
+        party_size: r.party_size,
+        reservation_date: r.reservation_date,
+        reservation_time: r.reservation_time,
+        status: r.status,
+        can_respond: r.can_respond,
+        ...(r.customer_responded_at != null && { customer_responded_at: r.customer_responded_at }),
+      },
+    });
+  } catch {
+    return c.json({ success: false, error: 'Failed to fetch reservation' }, 500);
+  }
+}
+
+// ── RespondToReservation (public) ───────────────────────────────────────────
+
+export async function respondToReservation(c: Context) {
+  const token = c.req.param('token');
+
+  let body: { action: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
+  }
+
+  if (body.action !== 'confirm' && body.action !== 'cancel') {
+    return errorResponse(c, 'Action must be confirm or cancel', 'invalid_action', 400);
+  }
+
+  const status = body.action === 'confirm' ? 'confirmed' : 'cancelled';
+
+  try {
+    // A guest can change their mind (confirm, then cancel) until the booking
+    // starts; anything staff already closed out stays as it is.
+    const res = await pool.query(
+      `UPDATE reservations r
+      SET status = $1,
+        confirmed_at = CASE WHEN $1 = 'confirmed' THEN COALESCE(r.confirmed_at, NOW()) ELSE r.confirmed_at END,
+        customer_responded_at = NOW(), updated_at = NOW()
+      WHERE r.response_token = $2 AND r.status IN ('pending', 'confirmed') AND ${RESERVATION_START_SQL} > NOW()
+      RETURNING r.id, r.status,
+        to_char(r.reservation_date, 'YYYY-MM-DD') as reservation_date,
+        to_char(r.reservation_time, 'HH24:MI') as reservation_time`,
+      [status, token],
+    );
+
+    if (res.rows.length === 0) {
+      const exists = await pool.query('SELECT 1 FROM reservations WHERE response_token = $1', [token]);
+      if (exists.rows.length === 0) {
+        return c.json({ success: false, error: 'Reservation not found' }, 404);
+      }
+      return c.json({ success: false, error: 'This reservation can no longer be changed' }, 409);
+    }
+
+    const r = res.rows[0];
+
+    return c.json({
+      success: true,
+      message: status === 'confirmed' ? 'Reservation confirmed' : 'Reservation cancelled',
+      data: {
+        status: r.status,
+        reservation_date: r.reservation_date,
+        reservation_time: r.reservation_time,
+      },
+    });
+  } catch {
+    return c.json({ success: false, error: 'Failed to update reservation' }, 500);
+  }
+}
+
+// ── AssignReservationTable (admin) ──────────────────────────────────────────
+// Holds a table for the booking. The hold lapses on its own once the
+// reservation is no longer pending/confirmed (seated, cancelled or no-show).
+
+export async function assignReservationTable(c: Context) {
+  const id = c.req.param('id');
+
+  let body: { table_id: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
+  }
+
+  if (body.table_id === undefined) {
+    return errorResponse(c, 'table_id is required (null to release)', 'table_id_required', 400);
+  }
+
+  try {
+    if (body.table_id !== null) {
+      const tableRes = await pool.query(
+        'SELECT id FROM dining_tables WHERE id = $1 AND deleted_at IS NULL',
+        [body.table_id],
+      );
+      if (tableRes.rows.length === 0) {
+        return c.json({ success: false, error: 'Table not found' }, 404);
+      }
+
+      const clash = await pool.query(
+        `SELECT r.customer_name, to_char(r.reservation_time, 'HH24:MI') as reservation_time
+        FROM reservations r, reservations target
+        WHERE target.id = $1 AND r.id <> target.id AND r.table_id = $2
+          AND r.status IN ('pending', 'confirmed')
+          AND r.reservation_date = target.reservation_date
+          AND ABS(EXTRACT(EPOCH FROM (r.reservation_time - target.reservation_time))) < 7200
+        LIMIT 1`,
+        [id, body.table_id],
+      );
+      if (clash.rows.length > 0) {
+        const other = clash.rows[0];
+        return c.json({
+          success: false,
+          error: `Table is already held for ${other.customer_name} at ${other.reservation_time}`,
+        }, 409);
+      }
+    }
+
+    const res = await pool.query(
+      `UPDATE reservations SET table_id = $1, updated_at = NOW()
+      WHERE id = $2 AND status IN ('pending', 'confirmed')
+      RETURNING id, table_id, status`,
+      [body.table_id, id],
+    );
+
+    if (res.rows.length === 0) {
+      return c.json({ success: false, error: 'Reservation not found or no longer open' }, 404);
+    }
+
+    return c.json({
+      success: true,
+      message: body.table_id ? 'Table held for reservation' : 'Table released',
+      data: res.rows[0],
+    });
+  } catch {
+    return c.json({ success: false, error: 'Failed to assign table' }, 500);
+  }
+}
+
+// ── GetNoShowStats (admin) ──────────────────────────────────────────────────
+
+export async function getNoShowStats(c: Context) {
+  const phone = c.req.query('phone') || '';
+  let minReservations = parseInt(c.req.query('min_reservations') || '1', 10);
+  let limit = parseInt(c.req.query('limit') || '50', 10);
+
+  if (isNaN(minReservations) || minReservations < 1) minReservations = 1;
+  if (isNaN(limit) || limit < 1 || limit > 200) limit = 50;
+
+  try {
+    const stats = await customerNoShowStats(pool, { phone, minReservations, limit });
+    return c.json({ success: true, data: stats });
+  } catch {
+    return c.json({ success: false, error: 'Failed to fetch no-show statistics' }, 500);
+  }
+}
+
 // ── Validation helpers ──────────────────────────────────────────────────────
 
 function stripHTMLTags(input: string): string {
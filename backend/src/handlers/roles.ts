@@ -0,0 +1,213 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import type { Queryable } from '../services/pricing.js';
+import { PERMISSIONS, isPermission, invalidatePermissionCache } from '../services/permissions.js';
+
+const ROLE_NAME_RE = /^[a-z][a-z0-9_]{1,19}$/;
+
+const ROLE_SELECT = `
+  SELECT r.name, r.display_name, r.description, r.is_system, r.created_at, r.updated_at,
+         COALESCE((SELECT array_agg(rp.permission ORDER BY rp.permission) FROM role_permissions rp WHERE rp.role = r.name), '{}') AS permissions,
+         (SELECT COUNT(*) FROM users u WHERE u.role = r.name AND u.deleted_at IS NULL) AS user_count
+  FROM roles r`;
+
+function formatRole(row: Record<string, unknown>) {
+  return {
+    name: row.name,
+    display_name: row.display_name,
+    description: row.description,
+    is_system: row.is_system,
+    permissions: row.permissions,
+    user_count: Number(row.user_count),
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+function validatePermissions(permissions: unknown): string | null {
+  if (!Array.isArray(permissions)) return 'Permissions must be an array';
+  const unknown = permissions.find((p) => typeof p !== 'string' || !isPermission(p));
+  if (unknown !== undefined) return `Unknown permission: ${unknown}`;
+  return null;
+}
+
+async function replacePermissions(q: Queryable, role: string, permissions: string[], userId: string) {
+  await q.query('DELETE FROM role_permissions WHERE role = $1', [role]);
+  await q.query(
+    `INSERT INTO role_permissions (role, permission, granted_by)
+     SELECT $1, p, $3 FROM unnest($2::text[]) AS p
+     ON CONFLICT DO NOTHING`,
+    [role, permissions, userId],
+  );
+}
+
+// ── GetPermissions ──────────────────────────────────────────────────────────
+
+export async function getPermissions(c: Context) {
+  const definitions = Object.entries(PERMISSIONS).map(([name, description]) => ({ name, description }));
+  return successResponse(c, 'Permissions retrieved successfully', definitions);
+}
+
+// ── GetRoles ────────────────────────────────────────────────────────────────
+
+export async function getRoles(c: Context) {
+  try {
+    const res = await pool.query(`${ROLE_SELECT} ORDER BY r.is_system DESC, r.name ASC`);
+    return successResponse(c, 'Roles retrieved successfully', res.rows.map(formatRole));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch roles', (err as Error).message);
+  }
+}
+
+// ── CreateRole ──────────────────────────────────────────────────────────────
+
+export async function createRole(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { name?: string; display_name?: string; description?: string; permissions?: string[] };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const name = (body.name || '').trim();
+  const displayName = (body.display_name || '').trim();
+  if (!ROLE_NAME_RE.test(name)) {
+    return errorResponse(c, 'Name must be 2-20 lowercase letters, digits or underscores, starting with a letter', 'invalid_name', 400);
+  }
+  if (!displayName || displayName.length > 50) {
+    return errorResponse(c, 'Display name is required (max 50 characters)', 'invalid_display_name', 400);
+  }
+  const permissions = body.permissions ?? [];
+  const permissionError = validatePermissions(permissions);
+  if (permissionError) {
+    return errorResponse(c, permissionError, 'invalid_permission', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const existing = await client.query('SELECT 1 FROM roles WHERE name = $1', [name]);
+      if (existing.rows.length > 0) {
+        return txFailure('A role with this name already exists', 'duplicate_name', 409);
+      }
+
+      await client.query(
+        'INSERT INTO roles (name, display_name, description) VALUES ($1, $2, $3)',
+        [name, displayName, body.description?.trim() || null],
+      );
+      await replacePermissions(client, name, permissions, userId);
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    invalidatePermissionCache();
+
+    const created = await pool.query(`${ROLE_SELECT} WHERE r.name = $1`, [name]);
+    return successResponse(c, 'Role created successfully', formatRole(created.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create role', (err as Error).message);
+  }
+}
+
+// ── UpdateRole ──────────────────────────────────────────────────────────────
+// `permissions` replaces the role's whole grant list. The admin role always
+// keeps roles.manage so the system can't be locked out of this screen.
+
+export async function updateRole(c: Context) {
+  const name = c.req.param('name');
+  const userId = c.get('user_id');
+
+  let body: { display_name?: string; description?: string | null; permissions?: string[] };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (body.display_name !== undefined && (!body.display_name.trim() || body.display_name.length > 50)) {
+    return errorResponse(c, 'Display name is required (max 50 characters)', 'invalid_display_name', 400);
+  }
+  if (body.permissions !== undefined) {
+    const permissionError = validatePermissions(body.permissions);
+    if (permissionError) {
+      return errorResponse(c, permissionError, 'invalid_permission', 400);
+    }
+    if (name === 'admin' && !body.permissions.includes('roles.manage')) {
+      return errorResponse(c, 'The admin role must keep roles.manage', 'admin_lockout', 400);
+    }
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.display_name !== undefined) {
+    setClauses.push(`display_name = $${paramIdx++}`);
+    params.push(body.display_name.trim());
+  }
+  if (body.description !== undefined) {
+    setClauses.push(`description = $${paramIdx++}`);
+    params.push(body.description?.trim() || null);
+  }
+
+  if (setClauses.length === 0 && body.permissions === undefined) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const currentRes = await client.query('SELECT name FROM roles WHERE name = $1 FOR UPDATE', [name]);
+      if (currentRes.rows.length === 0) {
+        return txFailure('Role not found', 'role_not_found', 404);
+      }
+
+      setClauses.push('updated_at = NOW()');
+      params.push(name);
+      await client.query(`UPDATE roles SET ${setClauses.join(', ')} WHERE name = $${paramIdx}`, params);
+
+      if (body.permissions !== undefined) {
+        await replacePermissions(client, name, body.permissions, userId);
+      }
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    invalidatePermissionCache();
+
+    const updated = await pool.query(`${ROLE_SELECT} WHERE r.name = $1`, [name]);
+    return successResponse(c, 'Role updated successfully', formatRole(updated.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update role', (err as Error).message);
+  }
+}
+
+// ── DeleteRole ──────────────────────────────────────────────────────────────
+// Only custom roles nobody holds; move their staff to another role first.
+
+export async function deleteRole(c: Context) {
+  const name = c.req.param('name');
+
+  try {
+    const res = await pool.query(`${ROLE_SELECT} WHERE r.name = $1`, [name]);
+    const role = res.rows[0];
+    if (!role) {
+      return errorResponse(c, 'Role not found', 'role_not_found', 404);
+    }
+    if (role.is_system) {
+      return errorResponse(c, 'Built-in roles cannot be deleted', 'system_role', 400);
+    }
+    const holders = await pool.query('SELECT COUNT(*) AS count FROM users WHERE role = $1', [name]);
+    if (Number(holders.rows[0].count) > 0) {
+      return errorResponse(c, 'Role is still assigned to users', 'role_in_use', 409);
+    }
+
+    await pool.query('DELETE FROM roles WHERE name = $1', [name]);
+    invalidatePermissionCache();
+    return successResponse(c, 'Role deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete role', (err as Error).message);
+  }
+}
@@ -0,0 +1,31 @@
+import type { Context } from 'hono';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { reloadRuntimeConfig, runtimeConfigState } from '../lib/runtime-config.js';
+
+// ── GetRuntimeConfig ────────────────────────────────────────────────────────
+// What this instance is running with right now.
+
+export async function getRuntimeConfig(c: Context) {
+  return successResponse(c, 'Runtime config retrieved successfully', runtimeConfigState());
+}
+
+// ── ReloadRuntimeConfig ─────────────────────────────────────────────────────
+// Reloads this instance at once; the others pick the change up within
+// RUNTIME_CONFIG_REFRESH_MS, or on SIGHUP.
+
+export async function reloadRuntimeConfigNow(c: Context) {
+  try {
+    const result = await reloadRuntimeConfig();
+    if (!result.ok) {
+      return c.json({
+        success: false,
+        message: `Runtime config not reloaded: ${result.errors.map((e) => `${e.key}: ${e.message}`).join('; ')}`,
+        error: 'invalid_runtime_config',
+        data: { errors: result.errors },
+      }, 409);
+    }
+    return successResponse(c, 'Runtime config reloaded', { ...runtimeConfigState(), changed: result.changed });
+  } catch (err) {
+    return errorResponse(c, 'Failed to reload runtime config', (err as Error).message);
+  }
+}
@@ -0,0 +1,234 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { localClock, addDays } from '../lib/clock.js';
+import { TARGET_PERIODS, getTargetProgress, type TargetPeriod } from '../services/sales-targets.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+// Roles that take orders and are shown on the team progress board
+const SELLING_ROLES = ['server', 'counter'];
+
+function formatTarget(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    user_id: row.user_id,
+    username: row.username,
+    first_name: row.first_name,
+    last_name: row.last_name,
+    period_type: row.period_type,
+    target_amount: Number(row.target_amount),
+    effective_from: row.effective_from,
+    effective_until: row.effective_until,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+const TARGET_SELECT = `
+  SELECT t.id, t.user_id, t.period_type, t.target_amount,
+         to_char(t.effective_from, 'YYYY-MM-DD') AS effective_from,
+         to_char(t.effective_until, 'YYYY-MM-DD') AS effective_until,
+         t.created_at, t.updated_at, u.username, u.first_name, u.last_name
+  FROM sales_targets t
+  JOIN users u ON u.id = t.user_id`;
+
+// ── GetSalesTargets ─────────────────────────────────────────────────────────
+
+export async function getSalesTargets(c: Context) {
+  const includeHistory = c.req.query('include_history') === 'true';
+  const userId = c.req.query('user_id');
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  if (!includeHistory) conditions.push('t.effective_until IS NULL');
+  if (userId) {
+    params.push(userId);
+    conditions.push(`t.user_id = $${params.length}`);
+  }
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const res = await pool.query(
+      `${TARGET_SELECT} ${where} ORDER BY u.first_name ASC, t.period_type ASC, t.effective_from DESC`,
+      params,
+    );
+    return successResponse(c, 'Sales targets retrieved successfully', res.rows.map(formatTarget));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch sales targets', (err as Error).message);
+  }
+}
+
+// ── SetSalesTarget ──────────────────────────────────────────────────────────
+// A new amount takes effect today. The previous target is closed off rather
+// than overwritten so earlier periods keep their original goal.
+
+export async function setSalesTarget(c: Context) {
+  const targetUserId = c.req.param('user_id');
+  const userId = c.get('user_id');
+
+  let body: { period_type?: string; target_amount?: number };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!TARGET_PERIODS.includes(body.period_type as TargetPeriod)) {
+    return errorResponse(c, 'Period type must be daily or weekly', 'invalid_period_type', 400);
+  }
+  if (typeof body.target_amount !== 'number' || body.target_amount <= 0) {
+    return errorResponse(c, 'Target amount must be greater than zero', 'invalid_target_amount', 400);
+  }
+
+  const today = localClock().date;
+  try {
+    const result = await withTransaction(async (client) => {
+      const userRes = await client.query(
+        'SELECT id FROM users WHERE id = $1 AND is_active = true AND deleted_at IS NULL',
+        [targetUserId],
+      );
+      if (userRes.rows.length === 0) {
+        return txFailure('User not found', 'user_not_found', 404);
+      }
+
+      const currentRes = await client.query(
+        `SELECT id, to_char(effective_from, 'YYYY-MM-DD') AS effective_from
+         FROM sales_targets
+         WHERE user_id = $1 AND period_type = $2 AND effective_until IS NULL
+         FOR UPDATE`,
+        [targetUserId, body.period_type],
+      );
+
+      let targetId: string;
+      const current = currentRes.rows[0];
+      if (current && current.effective_from >= today) {
+        // Set earlier today — nothing has been measured against it yet
+        await client.query('UPDATE sales_targets SET target_amount = $1 WHERE id = $2', [body.target_amount, current.id]);
+        targetId = current.id;
+      } else {
+        if (current) {
+          await client.query('UPDATE sales_targets SET effective_until = $1 WHERE id = $2', [addDays(today, -1), current.id]);
+        }
+        const insertRes = await client.query(
+          `INSERT INTO sales_targets (user_id, period_type, target_amount, effective_from, created_by)
+           VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+          [targetUserId, body.period_type, body.target_amount, today, userId],
+        );
+        targetId = insertRes.rows[0].id;
+      }
+
+      return { ok: true as const, targetId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { targetId } = result;
+
+    const saved = await pool.query(`${TARGET_SELECT} WHERE t.id = $1`, [targetId]);
+    return successResponse(c, 'Sales target saved successfully', formatTarget(saved.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to save sales target', (err as Error).message);
+  }
+}
+
+// ── RemoveSalesTarget ───────────────────────────────────────────────────────
+
+export async function removeSalesTarget(c: Context) {
+  const targetUserId = c.req.param('user_id');
+  const period = c.req.param('period_type');
+
+  if (!TARGET_PERIODS.includes(period as TargetPeriod)) {
+    return errorResponse(c, 'Period type must be daily or weekly', 'invalid_period_type', 400);
+  }
+
+  const today = localClock().date;
+  try {
+    // Targets created today are dropped outright; older ones end yesterday
+    const deleted = await pool.query(
+      `DELETE FROM sales_targets
+       WHERE user_id = $1 AND period_type = $2 AND effective_until IS NULL AND effective_from >= $3`,
+      [targetUserId, period, today],
+    );
+    const closed = await pool.query(
+      `UPDATE sales_targets SET effective_until = $3
+       WHERE user_id = $1 AND period_type = $2 AND effective_until IS NULL`,
+      [targetUserId, period, addDays(today, -1)],
+    );
+
+    if ((deleted.rowCount ?? 0) + (closed.rowCount ?? 0) === 0) {
+      return errorResponse(c, 'Sales target not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Sales target removed successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to remove sales target', (err as Error).message);
+  }
+}
+
+// ── GetMyTargetProgress ─────────────────────────────────────────────────────
+// Used by the POS header to show a server how close they are to today's and
+// this week's target.
+
+export async function getMyTargetProgress(c: Context) {
+  const userId = c.get('user_id');
+  const date = c.req.query('date') || localClock().date;
+
+  if (!DATE_RE.test(date)) {
+    return errorResponse(c, 'Date must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+
+  try {
+    const [daily, weekly] = await Promise.all([
+      getTargetProgress(pool, 'daily', date, [userId]),
+      getTargetProgress(pool, 'weekly', date, [userId]),
+    ]);
+
+    return successResponse(c, 'Target progress retrieved successfully', {
+      user_id: userId,
+      date,
+      daily: daily.get(userId) ?? null,
+      weekly: weekly.get(userId) ?? null,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch target progress', (err as Error).message);
+  }
+}
+
+// ── GetTeamTargetProgress ───────────────────────────────────────────────────
+
+export async function getTeamTargetProgress(c: Context) {
+  const date = c.req.query('date') || localClock().date;
+
+  if (!DATE_RE.test(date)) {
+    return errorResponse(c, 'Date must be YYYY-MM-DD', 'invalid_date', 400);
+  }
+
+  try {
+    // Everyone in a selling role, plus anyone else who has been given a target
+    const usersRes = await pool.query(
+      `SELECT id, username, first_name, last_name, role
+       FROM users
+       WHERE is_active = true AND deleted_at IS NULL
+         AND (role = ANY($1::text[]) OR id IN (SELECT user_id FROM sales_targets WHERE effective_until IS NULL))
+       ORDER BY first_name ASC`,
+      [SELLING_ROLES],
+    );
+    const ids = usersRes.rows.map((u) => u.id);
+
+    const [daily, weekly] = await Promise.all([
+      getTargetProgress(pool, 'daily', date, ids),
+      getTargetProgress(pool, 'weekly', date, ids),
+    ]);
+
+    return successResponse(c, 'Team target progress retrieved successfully', usersRes.rows.map((u) => ({
+      user_id: u.id,
+      username: u.username,
+      first_name: u.first_name,
+      last_name: u.last_name,
+      role: u.role,
+      daily: daily.get(u.id) ?? null,
+      weekly: weekly.get(u.id) ?? null,
+    })));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch team target progress', (err as Error).message);
+  }
+}
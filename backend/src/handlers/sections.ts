@@ -0,0 +1,313 @@
+import type { Context } from 'hono';
+import type { PoolClient } from 'pg';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID, resolveBranchScope, resolveWriteBranch } from '../services/branches.js';
+import { MAX_SHIFT_HOURS, loadSections } from '../services/sections.js';
+import type { Queryable } from '../services/pricing.js';
+
+type SectionBody = {
+  name?: string;
+  branch_id?: string;
+  table_ids?: string[];
+};
+
+function validTimestamp(value: unknown): value is string {
+  return typeof value === 'string' && !Number.isNaN(Date.parse(value));
+}
+
+function validateSectionBody(body: SectionBody, creating: boolean): { message: string; code: string } | null {
+  if (creating || body.name !== undefined) {
+    const name = typeof body.name === 'string' ? body.name.trim() : '';
+    if (!name || name.length > 50) {
+      return { message: 'Name is required (at most 50 characters)', code: 'invalid_name' };
+    }
+  }
+  if (body.table_ids !== undefined
+      && (!Array.isArray(body.table_ids) || !body.table_ids.every((id) => typeof id === 'string' && isUUID(id)))) {
+    return { message: 'table_ids must be a list of table IDs', code: 'invalid_table_ids' };
+  }
+  return null;
+}
+
+// The section, if the caller's branch can see it
+async function findSection(q: Queryable, id: string, userBranchId: string | null) {
+  if (!isUUID(id)) return null;
+  const res = await q.query('SELECT id, branch_id FROM server_sections WHERE id = $1', [id]);
+  const section = res.rows[0];
+  if (!section || (userBranchId && section.branch_id !== userBranchId)) return null;
+  return section as { id: string; branch_id: string };
+}
+
+// Moves the listed tables into the section and the rest of its tables out.
+// A table taken from another section leaves that one.
+async function setSectionTables(client: PoolClient, sectionId: string, branchId: string, tableIds: string[]) {
+  const ids = [...new Set(tableIds)];
+  const found = await client.query(
+    'SELECT id FROM dining_tables WHERE id = ANY($1::uuid[]) AND branch_id = $2 AND deleted_at IS NULL',
+    [ids, branchId],
+  );
+  if (found.rows.length !== ids.length) {
+    return txFailure("Some tables were not found in the section's branch", 'table_not_found', 400);
+  }
+  await client.query(
+    'UPDATE dining_tables SET section_id = NULL WHERE section_id = $1 AND id <> ALL($2::uuid[])',
+    [sectionId, ids],
+  );
+  await client.query('UPDATE dining_tables SET section_id = $1 WHERE id = ANY($2::uuid[])', [sectionId, ids]);
+  return { ok: true as const };
+}
+
+// ── GetSections ─────────────────────────────────────────────────────────────
+// Sections with their tables and current and upcoming server assignments.
+
+export async function getSections(c: Context) {
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const sections = await loadSections(pool, { branchId: scope.branchId });
+    return successResponse(c, 'Sections retrieved successfully', sections);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch sections', (err as Error).message);
+  }
+}
+
+// ── GetMySections ───────────────────────────────────────────────────────────
+// The sections the caller is assigned to now or later today, with their
+// tables, for the server's floor view.
+
+export async function getMySections(c: Context) {
+  const userId = c.get('user_id');
+
+  try {
+    const res = await pool.query(
+      `SELECT sa.id AS assignment_id, sa.starts_at, sa.ends_at,
+              NOW() >= sa.starts_at AND NOW() < sa.ends_at AS on_shift,
+              s.id AS section_id, s.name AS section_name,
+              COALESCE((
+                SELECT json_agg(json_build_object('id', t.id, 'table_number', t.table_number) ORDER BY t.table_number)
+                FROM dining_tables t WHERE t.section_id = s.id AND t.deleted_at IS NULL
+              ), '[]'::json) AS tables
+       FROM section_assignments sa
+       JOIN server_sections s ON s.id = sa.section_id
+       WHERE sa.user_id = $1 AND sa.ends_at > NOW() AND sa.starts_at < NOW() + INTERVAL '1 day'
+       ORDER BY sa.starts_at ASC`,
+      [userId],
+    );
+    return successResponse(c, 'Sections retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch sections', (err as Error).message);
+  }
+}
+
+// ── CreateSection ───────────────────────────────────────────────────────────
+
+export async function createSection(c: Context) {
+  let body: SectionBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateSectionBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `INSERT INTO server_sections (branch_id, name) VALUES ($1, $2)
+         ON CONFLICT (branch_id, name) DO NOTHING
+         RETURNING id`,
+        [branch.branchId, body.name!.trim()],
+      );
+      if (res.rows.length === 0) {
+        return txFailure('A section with this name already exists', 'duplicate_name', 409);
+      }
+      const sectionId: string = res.rows[0].id;
+      if (body.table_ids) {
+        const tables = await setSectionTables(client, sectionId, branch.branchId, body.table_ids);
+        if (!tables.ok) return tables;
+      }
+      return { ok: true as const, sectionId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const [section] = await loadSections(pool, { sectionId: result.sectionId });
+    return successResponse(c, 'Section created successfully', section, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create section', (err as Error).message);
+  }
+}
+
+// ── UpdateSection ───────────────────────────────────────────────────────────
+// table_ids, when given, is the section's full list of tables.
+
+export async function updateSection(c: Context) {
+  const id = c.req.param('id');
+
+  let body: SectionBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateSectionBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const section = await findSection(client, id, c.get('branch_id'));
+      if (!section) {
+        return txFailure('Section not found', 'section_not_found', 404);
+      }
+      if (body.name !== undefined) {
+        const clash = await client.query(
+          'SELECT 1 FROM server_sections WHERE branch_id = $1 AND name = $2 AND id <> $3',
+          [section.branch_id, body.name.trim(), section.id],
+        );
+        if (clash.rows.length > 0) {
+          return txFailure('A section with this name already exists', 'duplicate_name', 409);
+        }
+        await client.query('UPDATE server_sections SET name = $1 WHERE id = $2', [body.name.trim(), section.id]);
+      }
+      if (body.table_ids) {
+        const tables = await setSectionTables(client, section.id, section.branch_id, body.table_ids);
+        if (!tables.ok) return tables;
+      }
+      return { ok: true as const, sectionId: section.id };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const [section] = await loadSections(pool, { sectionId: result.sectionId });
+    return successResponse(c, 'Section updated successfully', section);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update section', (err as Error).message);
+  }
+}
+
+// ── DeleteSection ───────────────────────────────────────────────────────────
+// Its tables are left without a section and its assignments go with it.
+
+export async function deleteSection(c: Context) {
+  const id = c.req.param('id');
+
+  try {
+    const section = await findSection(pool, id, c.get('branch_id'));
+    if (!section) {
+      return errorResponse(c, 'Section not found', 'section_not_found', 404);
+    }
+    await pool.query('DELETE FROM server_sections WHERE id = $1', [section.id]);
+    return successResponse(c, 'Section deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete section', (err as Error).message);
+  }
+}
+
+// ── AssignSectionServer ─────────────────────────────────────────────────────
+// Puts a server on a section for one shift. The same server can't have two
+// overlapping shifts in one section.
+
+export async function assignSectionServer(c: Context) {
+  const id = c.req.param('id');
+  const assignedBy = c.get('user_id');
+
+  let body: { user_id?: string; starts_at?: string; ends_at?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.user_id || !isUUID(body.user_id)) {
+    return errorResponse(c, 'user_id is required', 'invalid_user_id', 400);
+  }
+  if (!validTimestamp(body.starts_at) || !validTimestamp(body.ends_at)) {
+    return errorResponse(c, 'starts_at and ends_at must be ISO 8601 timestamps', 'invalid_window', 400);
+  }
+  const hours = (Date.parse(body.ends_at) - Date.parse(body.starts_at)) / 3_600_000;
+  if (hours <= 0) {
+    return errorResponse(c, 'ends_at must be after starts_at', 'invalid_window', 400);
+  }
+  if (hours > MAX_SHIFT_HOURS) {
+    return errorResponse(c, `A shift can be at most ${MAX_SHIFT_HOURS} hours`, 'shift_too_long', 400);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const section = await findSection(client, id, c.get('branch_id'));
+      if (!section) {
+        return txFailure('Section not found', 'section_not_found', 404);
+      }
+      const userRes = await client.query(
+        `SELECT id FROM users
+         WHERE id = $1 AND is_active = true AND deleted_at IS NULL AND (branch_id IS NULL OR branch_id = $2)
+         FOR UPDATE`,
+        [body.user_id, section.branch_id],
+      );
+      if (userRes.rows.length === 0) {
+        return txFailure('User not found', 'user_not_found', 404);
+      }
+
+      const overlap = await client.query(
+        `SELECT 1 FROM section_assignments
+         WHERE section_id = $1 AND user_id = $2 AND starts_at < $4 AND ends_at > $3`,
+        [section.id, body.user_id, body.starts_at, body.ends_at],
+      );
+      if (overlap.rows.length > 0) {
+        return txFailure('This server already has an overlapping shift in the section', 'assignment_overlap', 409);
+      }
+
+      const res = await client.query(
+        `INSERT INTO section_assignments (section_id, user_id, starts_at, ends_at, assigned_by)
+         VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+        [section.id, body.user_id, body.starts_at, body.ends_at, assignedBy ?? null],
+      );
+      return { ok: true as const, sectionId: section.id, assignmentId: res.rows[0].id as string };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const [section] = await loadSections(pool, { sectionId: result.sectionId });
+    return successResponse(c, 'Server assigned successfully', { assignment_id: result.assignmentId, section }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to assign server', (err as Error).message);
+  }
+}
+
+// ── RemoveSectionAssignment ─────────────────────────────────────────────────
+
+export async function removeSectionAssignment(c: Context) {
+  const id = c.req.param('assignment_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Assignment not found', 'assignment_not_found', 404);
+  }
+
+  try {
+    const branchId = c.get('branch_id');
+    const res = await pool.query(
+      `DELETE FROM section_assignments sa
+       USING server_sections s
+       WHERE sa.id = $1 AND s.id = sa.section_id AND ($2::uuid IS NULL OR s.branch_id = $2)
+       RETURNING sa.id`,
+      [id, branchId ?? null],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Assignment not found', 'assignment_not_found', 404);
+    }
+    return successResponse(c, 'Assignment removed successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to remove assignment', (err as Error).message);
+  }
+}
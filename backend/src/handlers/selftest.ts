@@ -0,0 +1,58 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { errorResponse } from '../lib/response.js';
+import { getSchemaVersion } from '../db/migrate.js';
+import { SELFTEST_LOCK_KEY, runSelftestSteps, verifyRollback, type SelftestStep } from '../services/selftest.js';
+
+// ── RunSelftest ─────────────────────────────────────────────────────────────
+// POST /admin/selftest. 200 when every step passes and 503 otherwise, so a
+// deploy script can gate on the status code; the body reports each step.
+
+export async function runSelftest(c: Context) {
+  const started = Date.now();
+  const steps: SelftestStep[] = [];
+
+  const schema = await getSchemaVersion();
+  steps.push({
+    name: 'database',
+    status: schema.error || schema.pending > 0 ? 'failed' : 'passed',
+    duration_ms: Date.now() - started,
+    detail: schema.error ? null : `Schema ${schema.version ?? 'empty'} (${schema.applied} applied, ${schema.pending} pending)`,
+    error: schema.error ?? (schema.pending > 0 ? `${schema.pending} migration(s) not applied` : null),
+  });
+
+  let orderId: string | null = null;
+  const client = await pool.connect();
+  try {
+    await client.query('BEGIN');
+    const lockRes = await client.query('SELECT pg_try_advisory_xact_lock(hashtext($1)) AS locked', [SELFTEST_LOCK_KEY]);
+    if (!lockRes.rows[0].locked) {
+      await client.query('ROLLBACK');
+      return errorResponse(c, 'A self-test is already running', 'selftest_running', 409);
+    }
+
+    const run = await runSelftestSteps(client, c.get('user_id') ?? null);
+    steps.push(...run.steps);
+    orderId = run.orderId;
+  } catch (err) {
+    steps.push({ name: 'transaction', status: 'failed', duration_ms: 0, detail: null, error: (err as Error).message });
+  } finally {
+    // Always rolled back: the sandbox data must never be kept
+    await client.query('ROLLBACK').catch(() => undefined);
+    client.release();
+  }
+
+  steps.push(await verifyRollback(pool, orderId));
+
+  const passed = steps.every((step) => step.status === 'passed');
+  return c.json({
+    success: passed,
+    message: passed ? 'Self-test passed' : 'Self-test failed',
+    data: {
+      passed,
+      duration_ms: Date.now() - started,
+      timestamp: new Date().toISOString(),
+      steps,
+    },
+  }, passed ? 200 : 503);
+}
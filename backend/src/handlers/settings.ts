@@ -1,9 +1,20 @@
 import type { Context } from 'hono';
+import type { PoolClient } from 'pg';
 import { sql } from 'drizzle-orm';
 import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { RUNTIME_SETTING_KEYS, reloadRuntimeConfig, validateRuntimeSettings } from '../lib/runtime-config.js';
+import { isUUID } from '../services/branches.js';
 
 // ── GetSettings ──────────────────────────────────────────────────────────────
 
+// Secrets are never sent back; the form shows the mask and sends it back
+// unchanged when the secret isn't being edited.
+const SECRET_SETTINGS = ['smtp_password'];
+const SECRET_MASK = '********';
+
 export async function getSettings(c: Context) {
   try {
     const res = await db.execute<{
@@ -18,6 +29,10 @@ export async function getSettings(c: Context) {
 
     const settings: Record<string, unknown> = {};
     for (const row of res.rows) {
+      if (SECRET_SETTINGS.includes(row.setting_key)) {
+        settings[row.setting_key] = row.setting_value ? SECRET_MASK : '';
+        continue;
+      }
       switch (row.setting_type) {
         case 'number': {
           const num = parseFloat(row.setting_value);
@@ -50,37 +65,38 @@ export async function getSettings(c: Context) {
 }
 
 // ── UpdateSettings ──────────────────────────────────────────────────────────
+// Only settings whose value actually changes are written, and they are
+// recorded together as one change set in the settings history.
 
 export async function updateSettings(c: Context) {
   let request: Record<string, string>;
   try {
     request = await c.req.json();
   } catch {
-    return c.json({
-      success: false,
-      message: 'Invalid request format',
-    }, 400);
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
   }
 
   const userId = c.get('user_id');
+  const values: Record<string, string> = {};
+  for (const [key, value] of Object.entries(request)) {
+    if (SECRET_SETTINGS.includes(key) && value === SECRET_MASK) continue;
+    values[key] = String(value);
+  }
+
+  const runtimeErrors = validateRuntimeSettings(values);
+  if (runtimeErrors.length > 0) {
+    return errorResponse(c, `${runtimeErrors[0].key}: ${runtimeErrors[0].message}`, 'invalid_runtime_setting', 400);
+  }
 
   try {
-    for (const [key, value] of Object.entries(request)) {
-      const settingType = determineSettingType(String(value));
-      const category = determineCategoryFromKey(key);
-
-      await pool.query(
-        `INSERT INTO system_settings (setting_key, setting_value, setting_type, category, updated_by, updated_at)
-         VALUES ($1, $2, $3, $4, $5, NOW())
-         ON CONFLICT (setting_key) DO UPDATE SET
-           setting_value = EXCLUDED.setting_value,
-           updated_by = EXCLUDED.updated_by,
-           updated_at = EXCLUDED.updated_at`,
-        [key, String(value), settingType, category, userId],
-      );
-    }
+    const changeSetId = await withTransaction((client) => saveSettings(client, userId, values));
+    if (changeSetId) await applyRuntimeConfig();
 
-    return c.json({ success: true, message: 'Settings updated successfully' });
+    return c.json({
+      success: true,
+      message: 'Settings updated successfully',
+      data: { change_set_id: changeSetId },
+    });
   } catch (err) {
     return c.json({
       success: false,
@@ -90,6 +106,120 @@ export async function updateSettings(c: Context) {
   }
 }
 
+// ── GetSettingsHistory ──────────────────────────────────────────────────────
+// Newest first. With ?key= only the change sets that touched that setting
+// are listed, each still with all of its changes.
+
+export async function getSettingsHistory(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const key = c.req.query('key');
+
+  const where = key
+    ? 'WHERE EXISTS (SELECT 1 FROM settings_changes k WHERE k.change_set_id = s.id AND k.setting_key = $1)'
+    : '';
+  const params: unknown[] = key ? [key] : [];
+  const paramIdx = params.length + 1;
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM settings_change_sets s ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${CHANGE_SET_SELECT} ${where}
+           GROUP BY s.id, u.first_name, u.last_name
+           ORDER BY s.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Settings history retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { key },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch settings history', (err as Error).message);
+  }
+}
+
+// ── RollbackSettingsChange ──────────────────────────────────────────────────
+// Puts the settings a change set changed back to their values before it,
+// recorded as a new change set. Refused while any of them has been changed
+// again since, so a later edit isn't undone unseen; roll that one back
+// first. Secrets (no value kept) and settings the change set created have
+// nothing to go back to and are left as they are.
+
+export async function rollbackSettingsChange(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Settings change not found', 'not_found', 404);
+  }
+
+  const userId = c.get('user_id');
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const changesRes = await client.query(
+        `SELECT setting_key, old_value, new_value, is_secret
+         FROM settings_changes
+         WHERE change_set_id = $1
+         ORDER BY setting_key`,
+        [id],
+      );
+      if (changesRes.rows.length === 0) {
+        return txFailure('Settings change not found', 'not_found', 404);
+      }
+      const currentRes = await client.query(
+        'SELECT setting_key, setting_value FROM system_settings WHERE setting_key = ANY($1::text[]) FOR UPDATE',
+        [changesRes.rows.map((row) => row.setting_key)],
+      );
+      const current = new Map<string, string>(currentRes.rows.map((row) => [row.setting_key, row.setting_value]));
+
+      const restore: Record<string, string> = {};
+      const skipped: string[] = [];
+      const changedSince: string[] = [];
+      for (const row of changesRes.rows) {
+        if (row.is_secret || row.old_value === null) {
+          skipped.push(row.setting_key);
+        } else if (current.get(row.setting_key) !== row.new_value) {
+          changedSince.push(row.setting_key);
+        } else {
+          restore[row.setting_key] = row.old_value;
+        }
+      }
+      if (changedSince.length > 0) {
+        return txFailure(
+          `These settings have been changed since: ${changedSince.join(', ')}. Roll back the later change first`,
+          'settings_changed_since',
+          409,
+        );
+      }
+
+      const changeSetId = await saveSettings(client, userId, restore, id);
+      if (changeSetId === null) {
+        return txFailure('This change has nothing that can be rolled back', 'nothing_to_roll_back', 400);
+      }
+      return { ok: true as const, changeSetId, restored: Object.keys(restore), skipped };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    await applyRuntimeConfig();
+
+    return successResponse(c, 'Settings change rolled back successfully', {
+      change_set_id: result.changeSetId,
+      rollback_of: id,
+      restored: result.restored,
+      skipped: result.skipped,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to roll back settings change', (err as Error).message);
+  }
+}
+
 // ── GetSystemHealth ──────────────────────────────────────────────────────────
 
 export async function getSystemHealth(c: Context) {
@@ -132,6 +262,80 @@ export async function getSystemHealth(c: Context) {
 
 // ── Helpers ──────────────────────────────────────────────────────────────────
 
+const CHANGE_SET_SELECT = `
+  SELECT s.id, s.changed_by, s.rollback_of, s.created_at,
+         NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS changed_by_name,
+         json_agg(json_build_object(
+           'setting_key', ch.setting_key,
+           'old_value', ch.old_value,
+           'new_value', ch.new_value,
+           'is_secret', ch.is_secret
+         ) ORDER BY ch.setting_key) AS changes
+  FROM settings_change_sets s
+  JOIN settings_changes ch ON ch.change_set_id = s.id
+  LEFT JOIN users u ON u.id = s.changed_by`;
+
+// Saved settings may include runtime config; apply it on this instance now
+// rather than at the next refresh
+async function applyRuntimeConfig(): Promise<void> {
+  try {
+    const result = await reloadRuntimeConfig();
+    if (!result.ok) {
+      console.error(`Runtime config reload after a settings save rejected: ${result.errors.map((e) => `${e.key}: ${e.message}`).join('; ')}`);
+    }
+  } catch (err) {
+    console.error('Runtime config reload after a settings save failed:', (err as Error).message);
+  }
+}
+
+// Writes the settings whose value differs from the stored one and records
+// them as one change set. Returns its ID, or null when nothing changed.
+async function saveSettings(
+  client: PoolClient,
+  userId: string | null,
+  values: Record<string, string>,
+  rollbackOf: string | null = null,
+): Promise<string | null> {
+  const keys = Object.keys(values);
+  if (keys.length === 0) return null;
+
+  const current = await client.query(
+    'SELECT setting_key, setting_value FROM system_settings WHERE setting_key = ANY($1::text[]) FOR UPDATE',
+    [keys],
+  );
+  const before = new Map<string, string>(current.rows.map((row) => [row.setting_key, row.setting_value]));
+  const changed = keys.filter((key) => before.get(key) !== values[key]);
+  if (changed.length === 0) return null;
+
+  const setRes = await client.query(
+    'INSERT INTO settings_change_sets (changed_by, rollback_of) VALUES ($1, $2) RETURNING id',
+    [userId, rollbackOf],
+  );
+  const changeSetId = setRes.rows[0].id as string;
+
+  for (const key of changed) {
+    const value = values[key];
+    await client.query(
+      `INSERT INTO system_settings (setting_key, setting_value, setting_type, category, updated_by, updated_at)
+       VALUES ($1, $2, $3, $4, $5, NOW())
+       ON CONFLICT (setting_key) DO UPDATE SET
+         setting_value = EXCLUDED.setting_value,
+         updated_by = EXCLUDED.updated_by,
+         updated_at = EXCLUDED.updated_at`,
+      [key, value, determineSettingType(value), determineCategoryFromKey(key), userId],
+    );
+
+    // The history must not become a second copy of the secrets
+    const secret = SECRET_SETTINGS.includes(key);
+    await client.query(
+      `INSERT INTO settings_changes (change_set_id, setting_key, old_value, new_value, is_secret)
+       VALUES ($1, $2, $3, $4, $5)`,
+      [changeSetId, key, secret ? null : before.get(key) ?? null, secret ? null : value, secret],
+    );
+  }
+  return changeSetId;
+}
+
 function determineSettingType(value: string): string {
   if (value === 'true' || value === 'false') return 'boolean';
   if (!isNaN(parseFloat(value)) && isFinite(Number(value))) return 'number';
@@ -142,16 +346,20 @@ function determineCategoryFromKey(key: string): string {
   if (['restaurant_name', 'default_language', 'currency'].includes(key)) {
     return 'restaurant';
   }
-  if (['tax_rate', 'service_charge', 'tax_calculation_method', 'enable_rounding'].includes(key)) {
+  if (['tax_rate', 'service_charge', 'service_charge_order_types', 'tax_calculation_method', 'enable_rounding'].includes(key)) {
     return 'financial';
   }
-  if (['receipt_header', 'receipt_footer', 'paper_size', 'show_logo', 'auto_print_customer_copy', 'printer_name', 'print_copies'].includes(key)) {
+  if (['receipt_header', 'receipt_footer', 'receipt_language', 'paper_size', 'show_logo', 'auto_print_customer_copy', 'printer_name', 'print_copies'].includes(key)) {
     return 'receipt';
   }
   if (['kitchen_paper_size', 'auto_print_kitchen', 'show_prices_kitchen', 'kitchen_print_categories', 'kitchen_urgent_time'].includes(key)) {
     return 'kitchen';
   }
-  if (['backup_frequency', 'session_timeout', 'data_retention_days', 'low_stock_threshold', 'enable_audit_logging'].includes(key)) {
+  if (key.startsWith('smtp_') || ['daily_sales_summary_recipients', 'low_stock_digest_recipients'].includes(key)) {
+    return 'email';
+  }
+  if (['backup_frequency', 'session_timeout', 'data_retention_days', 'low_stock_threshold', 'enable_audit_logging'].includes(key)
+    || RUNTIME_SETTING_KEYS.includes(key)) {
     return 'system';
   }
   return 'general';
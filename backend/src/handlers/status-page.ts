@@ -0,0 +1,200 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import {
+  INCIDENT_COMPONENTS,
+  INCIDENT_SELECT,
+  INCIDENT_SEVERITIES,
+  invalidatePublicStatus,
+  loadPublicStatus,
+  type IncidentComponent,
+  type IncidentSeverity,
+} from '../services/status-page.js';
+import { UPLOAD_DIR } from './upload.js';
+
+const MAX_TITLE_LENGTH = 150;
+const MAX_MESSAGE_LENGTH = 2000;
+
+function formatIncident(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    title: row.title,
+    message: row.message,
+    severity: row.severity,
+    component: row.component,
+    started_at: row.started_at,
+    resolved_at: row.resolved_at,
+    created_by: row.created_by ? { id: row.created_by, name: row.created_by_name } : null,
+    resolved_by: row.resolved_by ? { id: row.resolved_by, name: row.resolved_by_name } : null,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+interface IncidentBody {
+  title?: string;
+  message?: string | null;
+  severity?: string;
+  component?: string;
+}
+
+// Checks the fields present; `partial` allows any of them to be left out
+function validateIncident(c: Context, body: IncidentBody, partial: boolean): Response | null {
+  if (!partial || body.title !== undefined) {
+    if (typeof body.title !== 'string' || !body.title.trim() || body.title.trim().length > MAX_TITLE_LENGTH) {
+      return errorResponse(c, 'A title is required (max 150 characters)', 'invalid_incident_title', 400);
+    }
+  }
+  if (body.message !== undefined && body.message !== null) {
+    if (typeof body.message !== 'string' || body.message.length > MAX_MESSAGE_LENGTH) {
+      return errorResponse(c, 'Message must be at most 2000 characters', 'invalid_incident_message', 400);
+    }
+  }
+  if (!partial || body.severity !== undefined) {
+    if (!INCIDENT_SEVERITIES.includes(body.severity as IncidentSeverity)) {
+      return errorResponse(c, 'Severity must be minor, major or critical', 'invalid_incident_severity', 400);
+    }
+  }
+  if (!partial || body.component !== undefined) {
+    if (!INCIDENT_COMPONENTS.includes(body.component as IncidentComponent)) {
+      return errorResponse(c, `Component must be one of ${INCIDENT_COMPONENTS.join(', ')}`, 'invalid_incident_component', 400);
+    }
+  }
+  return null;
+}
+
+// ── GetPublicStatus ─────────────────────────────────────────────────────────
+// No auth, and still served in maintenance mode, when it matters most.
+
+export async function getPublicStatus(c: Context) {
+  try {
+    const status = await loadPublicStatus(pool, UPLOAD_DIR);
+    c.header('Cache-Control', 'no-cache');
+    return successResponse(c, 'Status retrieved successfully', status);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch status', (err as Error).message);
+  }
+}
+
+// ── GetStatusIncidents ──────────────────────────────────────────────────────
+// Open incidents; ?include_resolved=true adds the last 200 resolved ones.
+
+export async function getStatusIncidents(c: Context) {
+  const where = c.req.query('include_resolved') === 'true' ? '' : 'WHERE i.resolved_at IS NULL';
+
+  try {
+    const res = await pool.query(
+      `${INCIDENT_SELECT} ${where} ORDER BY i.resolved_at IS NULL DESC, i.started_at DESC LIMIT 200`,
+    );
+    return successResponse(c, 'Status incidents retrieved successfully', res.rows.map(formatIncident));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch status incidents', (err as Error).message);
+  }
+}
+
+// ── CreateStatusIncident ────────────────────────────────────────────────────
+
+export async function createStatusIncident(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: IncidentBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateIncident(c, body, false);
+  if (invalid) return invalid;
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO status_incidents (title, message, severity, component, created_by)
+       VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+      [body.title!.trim(), body.message?.trim() || null, body.severity, body.component, userId],
+    );
+    invalidatePublicStatus();
+
+    const row = await pool.query(`${INCIDENT_SELECT} WHERE i.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Status incident created', formatIncident(row.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create status incident', (err as Error).message);
+  }
+}
+
+// ── UpdateStatusIncident ────────────────────────────────────────────────────
+// For progress updates while the incident is open.
+
+export async function updateStatusIncident(c: Context) {
+  const id = c.req.param('id');
+
+  let body: IncidentBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  const invalid = validateIncident(c, body, true);
+  if (invalid) return invalid;
+
+  try {
+    const existing = await pool.query('SELECT resolved_at FROM status_incidents WHERE id = $1', [id]);
+    if (existing.rows.length === 0) {
+      return errorResponse(c, 'Status incident not found', 'incident_not_found', 404);
+    }
+    if (existing.rows[0].resolved_at) {
+      return errorResponse(c, 'A resolved incident cannot be changed', 'incident_resolved', 409);
+    }
+
+    await pool.query(
+      `UPDATE status_incidents SET
+         title = COALESCE($2, title),
+         message = CASE WHEN $3::boolean THEN $4 ELSE message END,
+         severity = COALESCE($5, severity),
+         component = COALESCE($6, component),
+         updated_at = NOW()
+       WHERE id = $1`,
+      [
+        id,
+        body.title?.trim() ?? null,
+        body.message !== undefined,
+        body.message?.trim() || null,
+        body.severity ?? null,
+        body.component ?? null,
+      ],
+    );
+    invalidatePublicStatus();
+
+    const row = await pool.query(`${INCIDENT_SELECT} WHERE i.id = $1`, [id]);
+    return successResponse(c, 'Status incident updated', formatIncident(row.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to update status incident', (err as Error).message);
+  }
+}
+
+// ── ResolveStatusIncident ───────────────────────────────────────────────────
+
+export async function resolveStatusIncident(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+
+  try {
+    const res = await pool.query(
+      `UPDATE status_incidents SET resolved_at = NOW(), resolved_by = $2, updated_at = NOW()
+       WHERE id = $1 AND resolved_at IS NULL
+       RETURNING id`,
+      [id, userId],
+    );
+    if (res.rows.length === 0) {
+      const exists = await pool.query('SELECT 1 FROM status_incidents WHERE id = $1', [id]);
+      return exists.rows.length === 0
+        ? errorResponse(c, 'Status incident not found', 'incident_not_found', 404)
+        : errorResponse(c, 'Status incident is already resolved', 'incident_resolved', 409);
+    }
+    invalidatePublicStatus();
+
+    const row = await pool.query(`${INCIDENT_SELECT} WHERE i.id = $1`, [id]);
+    return successResponse(c, 'Status incident resolved', formatIncident(row.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to resolve status incident', (err as Error).message);
+  }
+}
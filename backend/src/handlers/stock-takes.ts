@@ -0,0 +1,343 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock } from '../lib/clock.js';
+import { isUUID, resolveBranchScope, resolveWriteBranch, branchCondition } from '../services/branches.js';
+import type { Queryable } from '../services/pricing.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+import {
+  STOCK_TAKE_SCOPES, STOCK_TAKE_SELECT, STOCK_TAKE_LINE_SELECT, type StockTakeScope,
+  formatStockTakeLine, summarizeStockTakeLines, snapshotStockTake, postStockTake, buildStockVarianceReport,
+} from '../services/stock-takes.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+type CountEntry = { product_id?: string; ingredient_id?: string; counted_quantity?: number | null };
+
+// A stock take with its lines, or null when it doesn't exist or belongs to
+// another branch than the caller's own
+async function loadStockTake(q: Queryable, id: string, ownBranch: string | null, itemType?: string) {
+  const params: unknown[] = [id];
+  const takeRes = await q.query(
+    `${STOCK_TAKE_SELECT} WHERE st.id = $1${branchCondition('st.branch_id', ownBranch, params)}`,
+    params,
+  );
+  if (takeRes.rows.length === 0) return null;
+
+  const linesRes = await q.query(
+    `${STOCK_TAKE_LINE_SELECT}
+     WHERE l.stock_take_id = $1
+           ${itemType === 'product' ? 'AND l.product_id IS NOT NULL' : itemType === 'ingredient' ? 'AND l.ingredient_id IS NOT NULL' : ''}
+     ORDER BY item_type DESC, item_name ASC`,
+    [id],
+  );
+  const lines = linesRes.rows.map(formatStockTakeLine);
+  return { ...takeRes.rows[0], summary: summarizeStockTakeLines(lines), lines };
+}
+
+// ── GetStockTakes ───────────────────────────────────────────────────────────
+
+export async function getStockTakes(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status') || '';
+  if (status && !['open', 'posted', 'cancelled'].includes(status)) {
+    return errorResponse(c, "status must be 'open', 'posted' or 'cancelled'", 'invalid_status', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const params: unknown[] = [];
+  let where = 'WHERE 1=1';
+  if (status) {
+    params.push(status);
+    where += ` AND st.status = $${params.length}`;
+  }
+  where += branchCondition('st.branch_id', scope.branchId, params);
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM stock_takes st ${where}`, params);
+        return parseInt(countRes.rows[0].count, 10);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${STOCK_TAKE_SELECT}
+           ${where}
+           ORDER BY st.started_at DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Stock takes retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'started_at:desc',
+      filters: { status, branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch stock takes', (err as Error).message);
+  }
+}
+
+// ── GetStockTake ────────────────────────────────────────────────────────────
+// A stock take with every line and its variance. ?item_type=product or
+// ?item_type=ingredient narrows the lines (the summary follows).
+
+export async function getStockTake(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Stock take not found', 'not_found', 404);
+  }
+  const itemType = c.req.query('item_type') || undefined;
+  if (itemType && itemType !== 'product' && itemType !== 'ingredient') {
+    return errorResponse(c, "item_type must be 'product' or 'ingredient'", 'invalid_item_type', 400);
+  }
+
+  try {
+    const take = await loadStockTake(pool, id, c.get('branch_id') ?? null, itemType);
+    if (!take) {
+      return errorResponse(c, 'Stock take not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Stock take retrieved successfully', take);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch stock take', (err as Error).message);
+  }
+}
+
+// ── StartStockTake ──────────────────────────────────────────────────────────
+
+export async function startStockTake(c: Context) {
+  let body: { branch_id?: string; scope?: string; notes?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const scope = (body.scope ?? 'all') as StockTakeScope;
+  if (!STOCK_TAKE_SCOPES.includes(scope)) {
+    return errorResponse(c, `scope must be one of: ${STOCK_TAKE_SCOPES.join(', ')}`, 'invalid_scope', 400);
+  }
+
+  let branchId: string;
+  try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id') ?? null, body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+    branchId = branch.branchId;
+  } catch (err) {
+    return errorResponse(c, 'Failed to start stock take', (err as Error).message);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `INSERT INTO stock_takes (branch_id, scope, notes, started_by)
+         VALUES ($1, $2, $3, $4)
+         RETURNING id`,
+        [branchId, scope, body.notes?.trim() || null, c.get('user_id')],
+      );
+      const id = res.rows[0].id;
+      await snapshotStockTake(client, id, branchId, scope);
+
+      return { ok: true as const, id };
+    });
+    const { id } = result;
+
+    const take = await loadStockTake(pool, id, null);
+    return successResponse(c, 'Stock take started successfully', take, 201);
+  } catch (err) {
+    if ((err as { code?: string }).code === '23505') {
+      const ingredients = (err as { constraint?: string }).constraint === 'stock_takes_open_ingredients_key';
+      return errorResponse(
+        c,
+        ingredients
+          ? 'Another open stock take is already counting ingredients'
+          : 'This branch already has an open stock take',
+        'stock_take_open',
+        409,
+      );
+    }
+    return errorResponse(c, 'Failed to start stock take', (err as Error).message);
+  }
+}
+
+// ── RecordStockTakeCounts ───────────────────────────────────────────────────
+// Enters counted quantities, as many lines at a time as the counter likes.
+// A null counted_quantity clears a count. Products are counted in whole
+// units.
+
+export async function recordStockTakeCounts(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Stock take not found', 'not_found', 404);
+  }
+
+  let body: { counts?: CountEntry[] };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const counts = body.counts;
+  if (!Array.isArray(counts) || counts.length === 0) {
+    return errorResponse(c, 'counts must be a non-empty list', 'missing_counts', 400);
+  }
+  for (const entry of counts) {
+    const itemId = entry.product_id ?? entry.ingredient_id;
+    if (!itemId || (entry.product_id && entry.ingredient_id) || !isUUID(itemId)) {
+      return errorResponse(c, 'Each count needs exactly one of product_id or ingredient_id', 'invalid_item', 400);
+    }
+    const qty = entry.counted_quantity;
+    if (qty === undefined) {
+      return errorResponse(c, 'counted_quantity is required (null clears it)', 'missing_counted_quantity', 400);
+    }
+    if (qty !== null && (typeof qty !== 'number' || !Number.isFinite(qty) || qty < 0 || qty >= 1e8)) {
+      return errorResponse(c, 'counted_quantity must be a non-negative quantity or null', 'invalid_counted_quantity', 400);
+    }
+    if (qty !== null && entry.product_id && !Number.isInteger(qty)) {
+      return errorResponse(c, 'Products are counted in whole units', 'invalid_counted_quantity', 400);
+    }
+  }
+
+  const ownBranch = c.get('branch_id') ?? null;
+  const userId = c.get('user_id');
+  try {
+    const result = await withTransaction(async (client) => {
+      const params: unknown[] = [id];
+      const takeRes = await client.query(
+        `SELECT st.status FROM stock_takes st
+         WHERE st.id = $1${branchCondition('st.branch_id', ownBranch, params)}
+         FOR UPDATE`,
+        params,
+      );
+      if (takeRes.rows.length === 0) {
+        return txFailure('Stock take not found', 'not_found', 404);
+      }
+      if (takeRes.rows[0].status !== 'open') {
+        return txFailure(`Stock take is already ${takeRes.rows[0].status}`, 'stock_take_closed', 409);
+      }
+
+      const unknown: string[] = [];
+      for (const entry of counts) {
+        const column = entry.product_id ? 'product_id' : 'ingredient_id';
+        const itemId = entry.product_id ?? entry.ingredient_id!;
+        const counted = entry.counted_quantity ?? null;
+        const res = await client.query(
+          `UPDATE stock_take_lines
+           SET counted_quantity = $3,
+               counted_by = CASE WHEN $3::numeric IS NULL THEN NULL ELSE $4::uuid END,
+               counted_at = CASE WHEN $3::numeric IS NULL THEN NULL ELSE NOW() END
+           WHERE stock_take_id = $1 AND ${column} = $2`,
+          [id, itemId, counted, userId],
+        );
+        if (res.rowCount === 0) unknown.push(itemId);
+      }
+      if (unknown.length > 0) {
+        return txFailure(`Not part of this stock take: ${unknown.join(', ')}`, 'item_not_in_stock_take', 400);
+      }
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const take = await loadStockTake(pool, id, null);
+    return successResponse(c, 'Counts recorded successfully', take);
+  } catch (err) {
+    return errorResponse(c, 'Failed to record counts', (err as Error).message);
+  }
+}
+
+// ── PostStockTake ───────────────────────────────────────────────────────────
+// Applies every counted variance as a stock adjustment, all or nothing.
+
+export async function postStockTakeHandler(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Stock take not found', 'not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const result = await postStockTake(client, id, c.get('branch_id') ?? null, c.get('user_id') ?? null);
+      if (!result.ok) {
+        return result;
+      }
+
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    await refreshStockAvailability({});
+
+    const take = await loadStockTake(pool, id, null);
+    return successResponse(c, 'Stock take posted successfully', take);
+  } catch (err) {
+    return errorResponse(c, 'Failed to post stock take', (err as Error).message);
+  }
+}
+
+// ── CancelStockTake ─────────────────────────────────────────────────────────
+// Abandons an open stock take without touching stock. Its counts are kept
+// for reference.
+
+export async function cancelStockTake(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Stock take not found', 'not_found', 404);
+  }
+
+  const params: unknown[] = [id];
+  const ownBranch = c.get('branch_id') ?? null;
+  try {
+    const res = await pool.query(
+      `UPDATE stock_takes st SET status = 'cancelled', cancelled_at = NOW()
+       WHERE st.id = $1 AND st.status = 'open'${branchCondition('st.branch_id', ownBranch, params)}
+       RETURNING st.id`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      const existing = await loadStockTake(pool, id, ownBranch);
+      if (!existing) {
+        return errorResponse(c, 'Stock take not found', 'not_found', 404);
+      }
+      return errorResponse(c, `Stock take is already ${existing.status}`, 'stock_take_closed', 409);
+    }
+    return successResponse(c, 'Stock take cancelled successfully', await loadStockTake(pool, id, null));
+  } catch (err) {
+    return errorResponse(c, 'Failed to cancel stock take', (err as Error).message);
+  }
+}
+
+// ── GetStockVarianceReport ──────────────────────────────────────────────────
+// Variances of the stock takes posted over [from, to] (default: this month
+// so far).
+
+export async function getStockVarianceReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const report = await buildStockVarianceReport(pool, from, to, scope.branchId);
+    return successResponse(c, 'Stock variance report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate stock variance report', (err as Error).message);
+  }
+}
@@ -0,0 +1,218 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { TAX_ORDER_TYPES } from '../services/tax.js';
+import { SURCHARGE_SELECT, SURCHARGE_TYPES, isSurchargeType } from '../services/surcharges.js';
+
+type SurchargeBody = {
+  name?: string;
+  description?: string | null;
+  surcharge_type?: string;
+  value?: number;
+  starts_at?: string;
+  ends_at?: string;
+  branch_id?: string | null;
+  order_types?: string[];
+  is_active?: boolean;
+};
+
+function validTimestamp(value: unknown): value is string {
+  return typeof value === 'string' && !Number.isNaN(Date.parse(value));
+}
+
+// Checks the fields present; creating also requires the mandatory ones
+function validateSurchargeBody(body: SurchargeBody, partial: boolean): { message: string; code: string } | null {
+  if (!partial) {
+    if (!body.name?.trim()) return { message: 'Name is required', code: 'missing_name' };
+    if (body.surcharge_type === undefined) return { message: 'surcharge_type is required', code: 'missing_surcharge_type' };
+    if (body.value === undefined) return { message: 'value is required', code: 'missing_value' };
+    if (!body.starts_at || !body.ends_at) return { message: 'starts_at and ends_at are required', code: 'missing_window' };
+  }
+  if (body.name !== undefined && (!body.name.trim() || body.name.trim().length > 100)) {
+    return { message: 'Name is required (at most 100 characters)', code: 'invalid_name' };
+  }
+  if (body.surcharge_type !== undefined && !isSurchargeType(body.surcharge_type)) {
+    return { message: `surcharge_type must be one of: ${SURCHARGE_TYPES.join(', ')}`, code: 'invalid_surcharge_type' };
+  }
+  if (body.value !== undefined) {
+    if (typeof body.value !== 'number' || !Number.isFinite(body.value) || body.value <= 0) {
+      return { message: 'value must be greater than zero', code: 'invalid_value' };
+    }
+    if (body.surcharge_type === 'percentage' && body.value > 100) {
+      return { message: 'A percentage surcharge cannot exceed 100', code: 'invalid_value' };
+    }
+  }
+  for (const t of [body.starts_at, body.ends_at]) {
+    if (t !== undefined && !validTimestamp(t)) {
+      return { message: 'starts_at and ends_at must be ISO 8601 timestamps', code: 'invalid_window' };
+    }
+  }
+  if (body.starts_at && body.ends_at && Date.parse(body.ends_at) <= Date.parse(body.starts_at)) {
+    return { message: 'ends_at must be after starts_at', code: 'invalid_window' };
+  }
+  if (body.branch_id && !isUUID(body.branch_id)) {
+    return { message: 'Branch not found', code: 'branch_not_found' };
+  }
+  if (body.order_types !== undefined
+      && (!Array.isArray(body.order_types) || body.order_types.some((t) => !TAX_ORDER_TYPES.includes(t)))) {
+    return { message: `order_types may only contain: ${TAX_ORDER_TYPES.join(', ')}`, code: 'invalid_order_types' };
+  }
+  return null;
+}
+
+// Constraint violations the body checks can't see on a partial update
+function surchargeConstraintError(c: Context, err: unknown) {
+  const code = (err as { code?: string }).code;
+  if (code === '23514') {
+    return errorResponse(c, 'ends_at must be after starts_at, and a percentage cannot exceed 100', 'invalid_window', 400);
+  }
+  if (code === '23503') {
+    return errorResponse(c, 'Branch not found', 'branch_not_found', 400);
+  }
+  return null;
+}
+
+// ── GetSurcharges ───────────────────────────────────────────────────────────
+// Latest window first; ?active_only=true leaves out inactive and ended ones.
+
+export async function getSurcharges(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${SURCHARGE_SELECT} ${activeOnly ? 'WHERE s.is_active = true AND s.ends_at > NOW()' : ''}
+       ORDER BY s.starts_at DESC, s.name ASC`,
+    );
+    return successResponse(c, 'Surcharges retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch surcharges', (err as Error).message);
+  }
+}
+
+// ── CreateSurcharge ─────────────────────────────────────────────────────────
+
+export async function createSurcharge(c: Context) {
+  let body: SurchargeBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateSurchargeBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO surcharges (name, description, surcharge_type, value, starts_at, ends_at, branch_id, order_types,
+                               is_active, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+       RETURNING id`,
+      [
+        body.name!.trim(),
+        body.description?.trim() || null,
+        body.surcharge_type,
+        body.value,
+        body.starts_at,
+        body.ends_at,
+        body.branch_id || null,
+        body.order_types ?? [],
+        body.is_active ?? true,
+        c.get('user_id') ?? null,
+      ],
+    );
+
+    const created = await pool.query(`${SURCHARGE_SELECT} WHERE s.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Surcharge created successfully', created.rows[0], 201);
+  } catch (err) {
+    return surchargeConstraintError(c, err) ?? errorResponse(c, 'Failed to create surcharge', (err as Error).message);
+  }
+}
+
+// ── UpdateSurcharge ─────────────────────────────────────────────────────────
+// Orders already placed keep the surcharges they were charged.
+
+export async function updateSurcharge(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Surcharge not found', 'not_found', 404);
+  }
+
+  let body: SurchargeBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateSurchargeBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  const values: Record<string, unknown> = {
+    name: body.name?.trim(),
+    description: body.description === undefined ? undefined : body.description?.trim() || null,
+    surcharge_type: body.surcharge_type,
+    value: body.value,
+    starts_at: body.starts_at,
+    ends_at: body.ends_at,
+    branch_id: body.branch_id === undefined ? undefined : body.branch_id || null,
+    order_types: body.order_types,
+    is_active: body.is_active,
+  };
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+  for (const [col, value] of Object.entries(values)) {
+    if (value !== undefined) {
+      setClauses.push(`${col} = $${paramIdx++}`);
+      params.push(value);
+    }
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE surcharges SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING id`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Surcharge not found', 'not_found', 404);
+    }
+
+    const updated = await pool.query(`${SURCHARGE_SELECT} WHERE s.id = $1`, [id]);
+    return successResponse(c, 'Surcharge updated successfully', updated.rows[0]);
+  } catch (err) {
+    return surchargeConstraintError(c, err) ?? errorResponse(c, 'Failed to update surcharge', (err as Error).message);
+  }
+}
+
+// ── DeleteSurcharge ─────────────────────────────────────────────────────────
+// Order surcharge lines keep the name and amount, so receipts don't change.
+
+export async function deleteSurcharge(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Surcharge not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM surcharges WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Surcharge not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Surcharge deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete surcharge', (err as Error).message);
+  }
+}
@@ -1,6 +1,102 @@
 import type { Context } from 'hono';
 import { sql } from 'drizzle-orm';
-import { db } from '../db/connection.js';
+import { db, pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { localClock, addDays, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { isUUID, resolveBranchScope } from '../services/branches.js';
+import { SURVEY_STATUSES } from '../services/data-model.js';
+import { emailConfigured, loadRestaurantName, queueEmail } from '../services/email.js';
+import { surveyReplyEmail } from '../services/email-templates.js';
+import { resolveOrderToken } from '../services/order-links.js';
+
+// Survey follow-up: a submitted survey is new until an admin acknowledges it
+// (with an internal note) or emails the guest a reply, which also counts as
+// acknowledged. Replies are kept in email_outbox against the survey. Lists
+// and analytics cover the caller's branch, by the surveyed order's branch.
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+const DEFAULT_RANGE_DAYS = 30;
+const MAX_RANGE_DAYS = 366;
+// Averages over fewer surveys than this are mostly noise
+const DEFAULT_MIN_SURVEYS = 3;
+const MAX_NOTE_LENGTH = 500;
+
+const SURVEY_SELECT = `
+  SELECT s.id, s.order_id, o.order_number, o.order_type, o.branch_id, o.server_id,
+         NULLIF(TRIM(CONCAT(su.first_name, ' ', su.last_name)), '') AS server_name,
+         s.overall_rating, s.food_quality, s.service_quality, s.ambiance, s.value_for_money,
+         s.comments, s.would_recommend, s.customer_name, s.customer_email,
+         s.status, s.staff_note, s.acknowledged_at, s.acknowledged_by, s.replied_at, s.replied_by,
+         s.submitted_at, s.updated_at
+  FROM satisfaction_surveys s
+  JOIN orders o ON o.id = s.order_id
+  LEFT JOIN users su ON su.id = o.server_id`;
+
+function round2(value: unknown): number | null {
+  return value === null || value === undefined ? null : Math.round(Number(value) * 100) / 100;
+}
+
+function difference(value: number | null, baseline: number | null): number | null {
+  return value === null || baseline === null ? null : round2(value - baseline);
+}
+
+// from/to as local business dates, the last DEFAULT_RANGE_DAYS days by default
+function parseRange(c: Context): { from: string; to: string } | null {
+  const to = c.req.query('to') || localClock().date;
+  const from = c.req.query('from') || addDays(to, -(DEFAULT_RANGE_DAYS - 1));
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to || addDays(from, MAX_RANGE_DAYS - 1) < to) {
+    return null;
+  }
+  return { from, to };
+}
+
+const RANGE_ERROR = `from and to must be YYYY-MM-DD dates with from <= to, at most ${MAX_RANGE_DAYS} days apart`;
+
+function parseMinSurveys(value: string | undefined): number | null {
+  if (value === undefined || value === '') return DEFAULT_MIN_SURVEYS;
+  const n = Number(value);
+  return Number.isInteger(n) && n >= 1 ? n : null;
+}
+
+// Averages over a set of surveys `s`; also the baseline the breakdowns compare with
+const RATING_AGGREGATES = `
+  COUNT(s.id) AS surveys,
+  AVG(s.overall_rating) AS average_rating,
+  AVG(s.food_quality) AS average_food_quality,
+  AVG(s.service_quality) AS average_service_quality,
+  AVG(s.ambiance) AS average_ambiance,
+  AVG(s.value_for_money) AS average_value_for_money,
+  AVG(CASE WHEN s.would_recommend THEN 100.0 ELSE 0 END) FILTER (WHERE s.would_recommend IS NOT NULL) AS recommendation_rate,
+  COUNT(s.id) FILTER (WHERE s.overall_rating <= 2) AS low_ratings`;
+
+function ratingAggregates(row: Record<string, unknown>) {
+  return {
+    surveys: Number(row.surveys),
+    average_rating: round2(row.average_rating),
+    average_food_quality: round2(row.average_food_quality),
+    average_service_quality: round2(row.average_service_quality),
+    average_ambiance: round2(row.average_ambiance),
+    average_value_for_money: round2(row.average_value_for_money),
+    recommendation_rate: round2(row.recommendation_rate),
+    low_ratings: Number(row.low_ratings),
+  };
+}
+
+// Surveys submitted in the range ($1..$2, local dates) in the branch ($3, null for all)
+const RANGE_CONDITION = `DATE(s.submitted_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') BETWEEN $1 AND $2
+  AND ($3::uuid IS NULL OR o.branch_id = $3)`;
+
+async function loadBaseline(range: { from: string; to: string }, branchId: string | null) {
+  const res = await pool.query(
+    `SELECT ${RATING_AGGREGATES}
+     FROM satisfaction_surveys s JOIN orders o ON o.id = s.order_id
+     WHERE ${RANGE_CONDITION}`,
+    [range.from, range.to, branchId],
+  );
+  return ratingAggregates(res.rows[0]);
+}
 
 // ── Helper: stripHTMLTags ──────────────────────────────────────────────────
 
@@ -14,7 +110,11 @@ function stripHTMLTags(input: string): string {
 // ── CreateSurvey (customer-facing) ──────────────────────────────────────────
 
 export async function createSurvey(c: Context) {
-  const orderId = c.req.param('id');
+  const link = resolveOrderToken(c, ['order', 'survey']);
+  if (!link.ok) {
+    return errorResponse(c, link.failure.message, link.failure.code, link.failure.status);
+  }
+  const orderId = link.orderId;
 
   let body: {
     overall_rating: number;
@@ -31,7 +131,7 @@ export async function createSurvey(c: Context) {
   try {
     body = await c.req.json();
   } catch {
-    return c.json({ success: false, error: 'Invalid request format' }, 400);
+    return errorResponse(c, 'Invalid request format', 'invalid_json', 400);
   }
 
   // Sanitize comments
@@ -41,7 +141,7 @@ export async function createSurvey(c: Context) {
 
   // Validate overall_rating
   if (!body.overall_rating || body.overall_rating < 1 || body.overall_rating > 5) {
-    return c.json({ success: false, error: 'Overall rating must be between 1 and 5' }, 400);
+    return errorResponse(c, 'Overall rating must be between 1 and 5', 'invalid_rating', 400);
   }
 
   try {
@@ -56,7 +156,7 @@ export async function createSurvey(c: Context) {
 
     const orderStatus = orderRes.rows[0].status;
     if (orderStatus !== 'completed' && orderStatus !== 'paid') {
-      return c.json({ success: false, error: 'Survey can only be submitted for completed orders' }, 400);
+      return errorResponse(c, 'Survey can only be submitted for completed orders', 'order_not_completed', 400);
     }
 
     // Check if survey already exists
@@ -159,3 +259,446 @@ export async function getSurveyStats(c: Context) {
     return c.json({ success: false, error: 'Failed to fetch survey statistics' }, 500);
   }
 }
+
+// ── GetSurveys (admin) ──────────────────────────────────────────────────────
+// Newest first. Filters: status, min_rating/max_rating (overall), from/to
+// (submission date), has_comments=true|false and server_id.
+
+export async function getSurveys(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status') || null;
+  const minRating = c.req.query('min_rating');
+  const maxRating = c.req.query('max_rating');
+  const from = c.req.query('from') || null;
+  const to = c.req.query('to') || null;
+  const hasComments = c.req.query('has_comments');
+  const serverId = c.req.query('server_id') || null;
+
+  if (status && !SURVEY_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${SURVEY_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+  const [low, high] = [minRating, maxRating].map((v) => (v === undefined || v === '' ? null : Number(v)));
+  const isRating = (r: number | null) => r === null || (Number.isInteger(r) && r >= 1 && r <= 5);
+  if (!isRating(low) || !isRating(high) || (low !== null && high !== null && low > high)) {
+    return errorResponse(c, 'min_rating and max_rating must be whole numbers from 1 to 5, min <= max', 'invalid_rating_range', 400);
+  }
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to)) || (from && to && from > to)) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+  if (hasComments !== undefined && hasComments !== 'true' && hasComments !== 'false') {
+    return errorResponse(c, 'has_comments must be true or false', 'invalid_has_comments', 400);
+  }
+  if (serverId && !isUUID(serverId)) {
+    return errorResponse(c, 'Invalid server ID', 'invalid_server_id', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  const add = (condition: string, value: unknown) => {
+    params.push(value);
+    conditions.push(condition.replace('?', `$${params.length}`));
+  };
+  if (status) add('s.status = ?', status);
+  if (low !== null) add('s.overall_rating >= ?', low);
+  if (high !== null) add('s.overall_rating <= ?', high);
+  if (from) add(`DATE(s.submitted_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') >= ?`, from);
+  if (to) add(`DATE(s.submitted_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') <= ?`, to);
+  if (hasComments !== undefined) {
+    conditions.push(`NULLIF(TRIM(s.comments), '') IS ${hasComments === 'true' ? 'NOT NULL' : 'NULL'}`);
+  }
+  if (serverId) add('o.server_id = ?', serverId);
+  if (scope.branchId) add('o.branch_id = ?', scope.branchId);
+
+  const where = conditions.length > 0 ? ` WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(
+          `SELECT COUNT(*) AS total FROM satisfaction_surveys s JOIN orders o ON o.id = s.order_id${where}`,
+          params,
+        );
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${SURVEY_SELECT}${where}
+           ORDER BY s.submitted_at DESC, s.id DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Surveys retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'submitted_at:desc',
+      filters: {
+        status, min_rating: low, max_rating: high, from, to,
+        has_comments: hasComments, server_id: serverId, branch_id: scope.branchId,
+      },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch surveys', (err as Error).message);
+  }
+}
+
+// ── GetSurvey (admin) ───────────────────────────────────────────────────────
+// With what was ordered and the replies sent, oldest first.
+
+export async function getSurvey(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Survey not found', 'survey_not_found', 404);
+  }
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `${SURVEY_SELECT} WHERE s.id = $1 AND ($2::uuid IS NULL OR o.branch_id = $2)`,
+      [id, scope.branchId],
+    );
+    const survey = res.rows[0];
+    if (!survey) {
+      return errorResponse(c, 'Survey not found', 'survey_not_found', 404);
+    }
+
+    const [items, replies] = await Promise.all([
+      pool.query(
+        `SELECT oi.product_id, p.name AS product_name, oi.quantity, oi.special_instructions
+         FROM order_items oi LEFT JOIN products p ON p.id = oi.product_id
+         WHERE oi.order_id = $1
+         ORDER BY oi.created_at ASC`,
+        [survey.order_id],
+      ),
+      pool.query(
+        `SELECT id, subject, body, status, last_error, created_by, created_at, sent_at
+         FROM email_outbox
+         WHERE related_type = 'satisfaction_survey' AND related_id = $1
+         ORDER BY created_at ASC`,
+        [id],
+      ),
+    ]);
+
+    return successResponse(c, 'Survey retrieved successfully', {
+      ...survey,
+      items: items.rows.map((item) => ({ ...item, quantity: Number(item.quantity) })),
+      replies: replies.rows,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch survey', (err as Error).message);
+  }
+}
+
+// ── AcknowledgeSurvey (admin) ───────────────────────────────────────────────
+// Marks a new survey acknowledged; `note` sets the internal note, on a
+// survey in any status.
+
+export async function acknowledgeSurvey(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Survey not found', 'survey_not_found', 404);
+  }
+
+  let body: { note?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+  if (body.note !== undefined && body.note !== null
+    && (typeof body.note !== 'string' || body.note.length > MAX_NOTE_LENGTH)) {
+    return errorResponse(c, `note must be text of at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `UPDATE satisfaction_surveys s
+       SET status = CASE WHEN s.status = 'new' THEN 'acknowledged' ELSE s.status END,
+           acknowledged_at = COALESCE(s.acknowledged_at, NOW()),
+           acknowledged_by = CASE WHEN s.acknowledged_at IS NULL THEN $2::uuid ELSE s.acknowledged_by END,
+           staff_note = CASE WHEN $3 THEN NULLIF(TRIM($4), '') ELSE s.staff_note END
+       FROM orders o
+       WHERE s.id = $1 AND o.id = s.order_id AND ($5::uuid IS NULL OR o.branch_id = $5)
+       RETURNING s.id`,
+      [id, userId, body.note !== undefined, body.note ?? null, scope.branchId],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Survey not found', 'survey_not_found', 404);
+    }
+    const updated = await pool.query(`${SURVEY_SELECT} WHERE s.id = $1`, [id]);
+    return successResponse(c, 'Survey acknowledged', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to acknowledge survey', (err as Error).message);
+  }
+}
+
+// ── ReplyToSurvey (admin) ───────────────────────────────────────────────────
+// Emails the guest (quoting their rating and comments) and marks the survey
+// replied. Only for surveys left with an email address.
+
+export async function replyToSurvey(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Survey not found', 'survey_not_found', 404);
+  }
+
+  let body: { message?: string; note?: string | null };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const message = (typeof body.message === 'string' ? body.message : '').trim();
+  if (!message) {
+    return errorResponse(c, 'Message is required', 'message_required', 400);
+  }
+  if (message.length > 5000) {
+    return errorResponse(c, 'Message must be at most 5000 characters', 'message_too_long', 400);
+  }
+  if (body.note !== undefined && body.note !== null
+    && (typeof body.note !== 'string' || body.note.length > MAX_NOTE_LENGTH)) {
+    return errorResponse(c, `note must be text of at most ${MAX_NOTE_LENGTH} characters`, 'invalid_note', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    if (!(await emailConfigured(pool))) {
+      return errorResponse(c, 'Email is not configured', 'email_not_configured', 400);
+    }
+
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `SELECT s.id, s.overall_rating, s.comments, s.customer_name, s.customer_email, o.order_number
+         FROM satisfaction_surveys s JOIN orders o ON o.id = s.order_id
+         WHERE s.id = $1 AND ($2::uuid IS NULL OR o.branch_id = $2)
+         FOR UPDATE OF s`,
+        [id, scope.branchId],
+      );
+      const survey = res.rows[0];
+      if (!survey) {
+        return txFailure('Survey not found', 'survey_not_found', 404);
+      }
+      if (!survey.customer_email) {
+        return txFailure('The guest left no email address to reply to', 'survey_has_no_email', 400);
+      }
+
+      const staff = await client.query(
+        "SELECT TRIM(CONCAT(first_name, ' ', last_name)) AS name FROM users WHERE id = $1",
+        [userId],
+      );
+      const email = surveyReplyEmail(await loadRestaurantName(client), {
+        name: survey.customer_name,
+        orderNumber: survey.order_number,
+        rating: survey.overall_rating,
+        comments: survey.comments,
+        reply: message,
+        staffName: staff.rows[0]?.name || null,
+      });
+
+      const outboxId = await queueEmail(client, {
+        to: [survey.customer_email],
+        template: 'survey_reply',
+        ...email,
+        relatedType: 'satisfaction_survey',
+        relatedId: survey.id,
+        createdBy: userId,
+      });
+
+      await client.query(
+        `UPDATE satisfaction_surveys
+         SET status = 'replied', replied_at = NOW(), replied_by = $2,
+             acknowledged_at = COALESCE(acknowledged_at, NOW()), acknowledged_by = COALESCE(acknowledged_by, $2),
+             staff_note = CASE WHEN $3 THEN NULLIF(TRIM($4), '') ELSE staff_note END
+         WHERE id = $1`,
+        [survey.id, userId, body.note !== undefined, body.note ?? null],
+      );
+      return { ok: true as const, outboxId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    return successResponse(c, 'Reply queued for sending', { email_id: result.outboxId, status: 'replied' }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to send reply', (err as Error).message);
+  }
+}
+
+// ── GetSurveyTrends (admin) ─────────────────────────────────────────────────
+// One point per local day from `from` to `to` (the last 30 days by default),
+// with null averages on days without surveys so charts show the gap.
+
+export async function getSurveyTrends(c: Context) {
+  const range = parseRange(c);
+  if (!range) {
+    return errorResponse(c, RANGE_ERROR, 'invalid_date_range', 400);
+  }
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT d::date::text AS date, ${RATING_AGGREGATES}
+       FROM generate_series($1::date, $2::date, interval '1 day') d
+       LEFT JOIN (
+         SELECT s.* FROM satisfaction_surveys s JOIN orders o ON o.id = s.order_id
+         WHERE ${RANGE_CONDITION}
+       ) s ON DATE(s.submitted_at AT TIME ZONE '${RESTAURANT_TIMEZONE}') = d::date
+       GROUP BY d
+       ORDER BY d ASC`,
+      [range.from, range.to, scope.branchId],
+    );
+
+    return successResponse(c, 'Survey trends retrieved successfully', {
+      from: range.from,
+      to: range.to,
+      branch_id: scope.branchId,
+      summary: await loadBaseline(range, scope.branchId),
+      days: res.rows.map((row) => ({ date: row.date, ...ratingAggregates(row) })),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch survey trends', (err as Error).message);
+  }
+}
+
+// ── GetSurveysByServer (admin) ──────────────────────────────────────────────
+// Ratings per server of the surveyed orders, against the average of all
+// surveys in the range (rating_difference). Orders without a server are
+// one row with server_id null. Servers with fewer than min_surveys surveys
+// are left out.
+
+export async function getSurveysByServer(c: Context) {
+  const range = parseRange(c);
+  if (!range) {
+    return errorResponse(c, RANGE_ERROR, 'invalid_date_range', 400);
+  }
+  const minSurveys = parseMinSurveys(c.req.query('min_surveys'));
+  if (minSurveys === null) {
+    return errorResponse(c, 'min_surveys must be a whole number of 1 or more', 'invalid_min_surveys', 400);
+  }
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT o.server_id, u.username, u.first_name, u.last_name, ${RATING_AGGREGATES}
+       FROM satisfaction_surveys s
+       JOIN orders o ON o.id = s.order_id
+       LEFT JOIN users u ON u.id = o.server_id
+       WHERE ${RANGE_CONDITION}
+       GROUP BY o.server_id, u.id
+       HAVING COUNT(*) >= $4
+       ORDER BY AVG(s.overall_rating) DESC, COUNT(*) DESC`,
+      [range.from, range.to, scope.branchId, minSurveys],
+    );
+    const baseline = await loadBaseline(range, scope.branchId);
+
+    return successResponse(c, 'Survey ratings by server retrieved successfully', {
+      from: range.from,
+      to: range.to,
+      branch_id: scope.branchId,
+      min_surveys: minSurveys,
+      baseline,
+      servers: res.rows.map((row) => {
+        const ratings = ratingAggregates(row);
+        return {
+          server_id: row.server_id,
+          username: row.username ?? null,
+          first_name: row.first_name ?? null,
+          last_name: row.last_name ?? null,
+          ...ratings,
+          rating_difference: difference(ratings.average_rating, baseline.average_rating),
+        };
+      }),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch survey ratings by server', (err as Error).message);
+  }
+}
+
+// ── GetSurveysByProduct (admin) ─────────────────────────────────────────────
+// For each product, the surveys of orders that included it: how the guests
+// who had it rated the visit and the food against the average of all
+// surveys in the range, and the share of surveyed orders it was in. An
+// order counts once per product however many it had.
+
+export async function getSurveysByProduct(c: Context) {
+  const range = parseRange(c);
+  if (!range) {
+    return errorResponse(c, RANGE_ERROR, 'invalid_date_range', 400);
+  }
+  const minSurveys = parseMinSurveys(c.req.query('min_surveys'));
+  if (minSurveys === null) {
+    return errorResponse(c, 'min_surveys must be a whole number of 1 or more', 'invalid_min_surveys', 400);
+  }
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT sp.product_id, p.name AS product_name, cat.name AS category_name, ${RATING_AGGREGATES}
+       FROM (
+         SELECT DISTINCT s.id AS survey_id, oi.product_id
+         FROM satisfaction_surveys s
+         JOIN orders o ON o.id = s.order_id
+         JOIN order_items oi ON oi.order_id = o.id
+         WHERE ${RANGE_CONDITION} AND oi.product_id IS NOT NULL
+       ) sp
+       JOIN satisfaction_surveys s ON s.id = sp.survey_id
+       LEFT JOIN products p ON p.id = sp.product_id
+       LEFT JOIN categories cat ON cat.id = p.category_id
+       GROUP BY sp.product_id, p.id, cat.id
+       HAVING COUNT(*) >= $4
+       ORDER BY AVG(s.food_quality) DESC NULLS LAST, COUNT(*) DESC`,
+      [range.from, range.to, scope.branchId, minSurveys],
+    );
+    const baseline = await loadBaseline(range, scope.branchId);
+
+    return successResponse(c, 'Survey ratings by product retrieved successfully', {
+      from: range.from,
+      to: range.to,
+      branch_id: scope.branchId,
+      min_surveys: minSurveys,
+      baseline,
+      products: res.rows.map((row) => {
+        const ratings = ratingAggregates(row);
+        return {
+          product_id: row.product_id,
+          product_name: row.product_name ?? null,
+          category_name: row.category_name ?? null,
+          ...ratings,
+          survey_share: baseline.surveys > 0 ? round2((ratings.surveys / baseline.surveys) * 100) : 0,
+          rating_difference: difference(ratings.average_rating, baseline.average_rating),
+          food_quality_difference: difference(ratings.average_food_quality, baseline.average_food_quality),
+        };
+      }),
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch survey ratings by product', (err as Error).message);
+  }
+}
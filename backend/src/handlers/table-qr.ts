@@ -0,0 +1,146 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { encodeQr, qrPng, qrSvg } from '../lib/qr.js';
+import { isUUID, resolveBranchScope } from '../services/branches.js';
+import { issueTableCode, tableOrderUrl, verifyTableCode, type TableCode } from '../services/table-qr.js';
+
+const MAX_PNG_SCALE = 40;
+
+function withImages(code: TableCode) {
+  const matrix = encodeQr(code.url);
+  return {
+    ...code,
+    svg: qrSvg(matrix),
+    png: `data:image/png;base64,${qrPng(matrix).toString('base64')}`,
+  };
+}
+
+async function readRotate(c: Context): Promise<boolean | null> {
+  try {
+    const text = await c.req.text();
+    if (!text.trim()) return false;
+    return JSON.parse(text).rotate === true;
+  } catch {
+    return null;
+  }
+}
+
+async function issueOne(c: Context, rotate: boolean) {
+  const tableId = c.req.param('id');
+  if (!isUUID(tableId)) {
+    return errorResponse(c, 'Table not found', 'table_not_found', 404);
+  }
+
+  try {
+    const code = await withTransaction((client) => issueTableCode(client, tableId, rotate));
+    if (!code) {
+      return errorResponse(c, 'Table not found', 'table_not_found', 404);
+    }
+    const message = rotate ? 'QR code rotated; earlier codes no longer work' : 'QR code generated successfully';
+    return successResponse(c, message, withImages(code), code.generated ? 201 : 200);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate QR code', (err as Error).message);
+  }
+}
+
+// ── GenerateTableQr ─────────────────────────────────────────────────────────
+// The table's signed code, its ordering link and the QR image as SVG and a
+// PNG data URI. A table that already has a valid code keeps it unless the
+// body asks to rotate.
+
+export async function generateTableQr(c: Context) {
+  const rotate = await readRotate(c);
+  if (rotate === null) return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  return issueOne(c, rotate);
+}
+
+// ── RotateTableQr ───────────────────────────────────────────────────────────
+
+export async function rotateTableQr(c: Context) {
+  return issueOne(c, true);
+}
+
+// ── GetTableQrImage ─────────────────────────────────────────────────────────
+// The current code as a file for printing: ?format=svg (default) or png,
+// with ?scale= pixels per module for PNG.
+
+export async function getTableQrImage(c: Context) {
+  const tableId = c.req.param('id');
+  const format = c.req.query('format') || 'svg';
+  if (format !== 'svg' && format !== 'png') {
+    return errorResponse(c, 'Format must be svg or png', 'invalid_format', 400);
+  }
+  const scale = Math.min(MAX_PNG_SCALE, Math.max(1, Number(c.req.query('scale')) || 10));
+  if (!isUUID(tableId)) {
+    return errorResponse(c, 'Table not found', 'table_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      'SELECT table_number, qr_code, qr_version FROM dining_tables WHERE id = $1 AND deleted_at IS NULL',
+      [tableId],
+    );
+    const table = res.rows[0];
+    if (!table) {
+      return errorResponse(c, 'Table not found', 'table_not_found', 404);
+    }
+    const claim = table.qr_code ? verifyTableCode(table.qr_code) : null;
+    if (!claim || claim.tableId !== tableId || claim.version !== table.qr_version) {
+      return errorResponse(c, 'This table has no signed QR code yet; generate one first', 'qr_not_generated', 404);
+    }
+
+    const matrix = encodeQr(tableOrderUrl(table.qr_code));
+    const filename = `table-${String(table.table_number).replace(/[^A-Za-z0-9_-]/g, '_')}-qr.${format}`;
+    const headers = { 'Content-Disposition': `inline; filename="${filename}"`, 'Cache-Control': 'no-store' };
+    if (format === 'png') {
+      return c.body(new Uint8Array(qrPng(matrix, scale)), 200, { ...headers, 'Content-Type': 'image/png' });
+    }
+    return c.body(qrSvg(matrix), 200, { ...headers, 'Content-Type': 'image/svg+xml' });
+  } catch (err) {
+    return errorResponse(c, 'Failed to render QR code', (err as Error).message);
+  }
+}
+
+// ── GenerateAllTableQr ──────────────────────────────────────────────────────
+// Every table in the branch scope: tables without a valid code get one, and
+// with `rotate` every table gets a new one. Images aren't included; fetch
+// them per table.
+
+export async function generateAllTableQr(c: Context) {
+  const rotate = await readRotate(c);
+  if (rotate === null) return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const codes = await withTransaction(async (client) => {
+      const params: unknown[] = [];
+      let where = 'deleted_at IS NULL';
+      if (scope.branchId) {
+        params.push(scope.branchId);
+        where += ` AND branch_id = $${params.length}`;
+      }
+      const tables = await client.query(`SELECT id FROM dining_tables WHERE ${where} ORDER BY table_number`, params);
+      const issued: TableCode[] = [];
+      for (const table of tables.rows) {
+        const code = await issueTableCode(client, table.id, rotate);
+        if (code) issued.push(code);
+      }
+      return issued;
+    });
+
+    const generated = codes.filter((code) => code.generated).length;
+    return successResponse(c, `QR codes ${rotate ? 'rotated' : 'generated'} for ${generated} of ${codes.length} tables`, {
+      generated,
+      unchanged: codes.length - generated,
+      tables: codes,
+    });
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate QR codes', (err as Error).message);
+  }
+}
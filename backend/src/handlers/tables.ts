@@ -1,16 +1,25 @@
 import type { Context } from 'hono';
-import { eq, and, not, inArray, ilike, sql } from 'drizzle-orm';
+import { eq, and, not, inArray, ilike, sql, isNull } from 'drizzle-orm';
 import { db } from '../db/connection.js';
 import { diningTables, orders } from '../db/schema.js';
 import { successResponse, errorResponse } from '../lib/response.js';
+import { resolveBranchScope } from '../services/branches.js';
 
 export async function getTables(c: Context) {
   const location = c.req.query('location');
   const occupiedOnly = c.req.query('occupied_only') === 'true';
   const availableOnly = c.req.query('available_only') === 'true';
 
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
   try {
-    const conditions = [];
+    const conditions = [isNull(diningTables.deletedAt)];
+    if (scope.branchId) {
+      conditions.push(eq(diningTables.branchId, scope.branchId));
+    }
 
     if (location) {
       conditions.push(ilike(diningTables.location, `%${location}%`));
@@ -21,7 +30,7 @@ export async function getTables(c: Context) {
       conditions.push(eq(diningTables.isOccupied, false));
     }
 
-    const whereClause = conditions.length > 0 ? and(...conditions) : undefined;
+    const whereClause = and(...conditions);
 
     const rows = await db
       .select({
@@ -31,6 +40,7 @@ export async function getTables(c: Context) {
         location: diningTables.location,
         isOccupied: diningTables.isOccupied,
         qrCode: diningTables.qrCode,
+        branchId: diningTables.branchId,
         createdAt: diningTables.createdAt,
         updatedAt: diningTables.updatedAt,
       })
@@ -45,6 +55,7 @@ export async function getTables(c: Context) {
       location: row.location,
       is_occupied: row.isOccupied,
       qr_code: row.qrCode,
+      branch_id: row.branchId,
       created_at: row.createdAt,
       updated_at: row.updatedAt,
       current_order: null,
@@ -72,7 +83,7 @@ export async function getTable(c: Context) {
         updatedAt: diningTables.updatedAt,
       })
       .from(diningTables)
-      .where(eq(diningTables.id, tableId))
+      .where(and(eq(diningTables.id, tableId), isNull(diningTables.deletedAt)))
       .limit(1);
 
     if (!row) {
@@ -90,6 +101,7 @@ export async function getTable(c: Context) {
         status: orders.status,
         subtotal: orders.subtotal,
         taxAmount: orders.taxAmount,
+        serviceChargeAmount: orders.serviceChargeAmount,
         totalAmount: orders.totalAmount,
         createdAt: orders.createdAt,
         updatedAt: orders.updatedAt,
@@ -113,6 +125,7 @@ export async function getTable(c: Context) {
         status: activeOrder.status,
         subtotal: Number(activeOrder.subtotal),
         tax_amount: Number(activeOrder.taxAmount),
+        service_charge_amount: Number(activeOrder.serviceChargeAmount),
         total_amount: Number(activeOrder.totalAmount),
         created_at: activeOrder.createdAt,
         updated_at: activeOrder.updatedAt,
@@ -151,6 +164,7 @@ export async function getTablesByLocation(c: Context) {
         updatedAt: diningTables.updatedAt,
       })
       .from(diningTables)
+      .where(isNull(diningTables.deletedAt))
       .orderBy(diningTables.location, diningTables.tableNumber);
 
     // Group tables by location
@@ -196,6 +210,7 @@ export async function getTableStatus(c: Context) {
         COUNT(CASE WHEN is_occupied = false THEN 1 END) as available_tables,
         COALESCE(location, 'General') as location
       FROM dining_tables
+      WHERE deleted_at IS NULL
       GROUP BY COALESCE(location, 'General')
       ORDER BY location
     `);
@@ -0,0 +1,205 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { createCharge, gatewayOrderId, isGatewayConfigured } from '../services/payment-gateway.js';
+import { loadPaymentLinkExpiryMinutes } from '../services/payment-links.js';
+import { resolveBranchScope, resolveWriteBranch, branchCondition, isUUID } from '../services/branches.js';
+import {
+  TAB_GATEWAY_PREFIX,
+  TAB_SELECT,
+  TAB_STATUSES,
+  formatTab,
+  loadTabSettings,
+  closeTab as closeTabService,
+} from '../services/tabs.js';
+
+// ── OpenTab ─────────────────────────────────────────────────────────────────
+// Creates a pending tab and a gateway card page that authorizes (holds)
+// `amount`, by default the tab_preauth_amount setting. The guest completes
+// the page on their phone or the counter terminal; the gateway notification
+// opens the tab.
+
+export async function openTab(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: { customer_name?: string; table_id?: string; amount?: number; email?: string; phone?: string; branch_id?: string };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const customerName = body.customer_name?.trim();
+  if (!customerName) {
+    return errorResponse(c, 'customer_name is required', 'tab_customer_required', 400);
+  }
+  if (body.amount !== undefined && (typeof body.amount !== 'number' || !(body.amount > 0))) {
+    return errorResponse(c, 'amount must be a positive number', 'invalid_amount', 400);
+  }
+  if (body.table_id && !isUUID(body.table_id)) {
+    return errorResponse(c, 'Invalid table_id', 'invalid_table_id', 400);
+  }
+
+  if (!isGatewayConfigured()) {
+    return errorResponse(c, 'Online payments are not configured', 'gateway_not_configured', 503);
+  }
+
+  try {
+    let tableBranchId: string | undefined;
+    if (body.table_id) {
+      const tableRes = await pool.query('SELECT branch_id FROM dining_tables WHERE id = $1', [body.table_id]);
+      if (tableRes.rows.length === 0) {
+        return errorResponse(c, 'Table not found', 'table_not_found', 404);
+      }
+      tableBranchId = tableRes.rows[0].branch_id;
+    }
+    const branch = await resolveWriteBranch(pool, c.get('branch_id'), tableBranchId ?? body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
+    const { preauthAmount } = await loadTabSettings(pool);
+    const amount = Math.round((body.amount ?? preauthAmount) * 100) / 100;
+    const expiryMinutes = await loadPaymentLinkExpiryMinutes(pool);
+
+    const tabRes = await pool.query(
+      `INSERT INTO tabs (branch_id, customer_name, table_id, authorized_amount, gateway_reference, payment_url, opened_by)
+       VALUES ($1, $2, $3, $4, gen_random_uuid()::text, '', $5)
+       RETURNING id`,
+      [branch.branchId, customerName, body.table_id || null, amount, userId],
+    );
+    const tabId = tabRes.rows[0].id;
+    const reference = gatewayOrderId(TAB_GATEWAY_PREFIX, tabId);
+
+    let charge;
+    try {
+      charge = await createCharge({
+        orderId: reference,
+        amount,
+        description: `Tab for ${customerName}`,
+        customer: { name: customerName, email: body.email || undefined, phone: body.phone || undefined },
+        expiryMinutes,
+        authorizeOnly: true,
+      });
+    } catch (err) {
+      await pool.query('DELETE FROM tabs WHERE id = $1', [tabId]);
+      return errorResponse(c, 'Failed to open tab', (err as Error).message);
+    }
+
+    await pool.query(
+      'UPDATE tabs SET gateway_reference = $1, payment_url = $2, updated_at = NOW() WHERE id = $3',
+      [reference, charge.redirect_url, tabId],
+    );
+
+    const res = await pool.query(`${TAB_SELECT} WHERE t.id = $1`, [tabId]);
+    return successResponse(c, 'Tab created; waiting for card authorization', formatTab(res.rows[0]), 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to open tab', (err as Error).message);
+  }
+}
+
+// ── GetTabs ─────────────────────────────────────────────────────────────────
+// Open and pending tabs by default; ?status= for others.
+
+export async function getTabs(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const status = c.req.query('status');
+
+  if (status && !TAB_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${TAB_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const params: unknown[] = [];
+  let where: string;
+  if (status) {
+    params.push(status);
+    where = 'WHERE t.status = $1';
+  } else {
+    where = `WHERE t.status IN ('pending', 'open', 'closing')`;
+  }
+  where += branchCondition('t.branch_id', scope.branchId, params);
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM tabs t ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${TAB_SELECT} ${where}
+           ORDER BY t.created_at DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Tabs retrieved successfully', result.rows.map(formatTab), pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { status: status || 'pending,open,closing', branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch tabs', (err as Error).message);
+  }
+}
+
+// ── GetTab ──────────────────────────────────────────────────────────────────
+
+export async function getTab(c: Context) {
+  const id = c.req.param('id');
+  const ownBranch = c.get('branch_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tab not found', 'tab_not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(`${TAB_SELECT} WHERE t.id = $1`, [id]);
+    const tab = res.rows[0];
+    if (!tab || (ownBranch && tab.branch_id !== ownBranch)) {
+      return errorResponse(c, 'Tab not found', 'tab_not_found', 404);
+    }
+
+    const ordersRes = await pool.query(
+      `SELECT o.id, o.order_number, o.status, o.total_amount::float8 AS total_amount, o.created_at,
+              (o.total_amount - COALESCE((SELECT SUM(p.amount) FROM payments p
+                                          WHERE p.order_id = o.id AND p.status = 'completed'), 0))::float8 AS balance
+       FROM orders o
+       WHERE o.tab_id = $1
+       ORDER BY o.created_at ASC`,
+      [id],
+    );
+    return successResponse(c, 'Tab retrieved successfully', { ...formatTab(tab), orders: ordersRes.rows });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch tab', (err as Error).message);
+  }
+}
+
+// ── CloseTab ────────────────────────────────────────────────────────────────
+// Captures what the tab's orders still owe and releases the rest of the
+// hold. Every order on the tab must have been served (or cancelled) first.
+
+export async function closeTab(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tab not found', 'tab_not_found', 404);
+  }
+
+  try {
+    const result = await closeTabService(id, c.get('branch_id') ?? null, c.get('user_id') ?? null);
+    if (!result.ok) {
+      return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    }
+
+    const res = await pool.query(`${TAB_SELECT} WHERE t.id = $1`, [id]);
+    return successResponse(c, 'Tab closed', formatTab(res.rows[0]));
+  } catch (err) {
+    return errorResponse(c, 'Failed to close tab', (err as Error).message);
+  }
+}
@@ -0,0 +1,159 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+
+type TaxClassBody = { name?: string; rate?: number; description?: string | null; is_active?: boolean };
+
+const TAX_CLASS_SELECT = `
+  SELECT tc.id, tc.name, tc.rate::float8 AS rate, tc.description, tc.is_active, tc.created_at, tc.updated_at,
+         (SELECT COUNT(*)::int FROM products p WHERE p.tax_class_id = tc.id AND p.deleted_at IS NULL) AS product_count
+  FROM tax_classes tc`;
+
+function validRate(rate: unknown): boolean {
+  return typeof rate === 'number' && rate >= 0 && rate <= 100;
+}
+
+// ── GetTaxClasses ───────────────────────────────────────────────────────────
+
+export async function getTaxClasses(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${TAX_CLASS_SELECT} ${activeOnly ? 'WHERE tc.is_active = true' : ''}
+       ORDER BY tc.rate DESC, tc.name ASC`,
+    );
+    return successResponse(c, 'Tax classes retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch tax classes', (err as Error).message);
+  }
+}
+
+// ── CreateTaxClass ──────────────────────────────────────────────────────────
+
+export async function createTaxClass(c: Context) {
+  let body: TaxClassBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const name = body.name?.trim();
+  if (!name || name.length > 100) {
+    return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+  }
+  if (!validRate(body.rate)) {
+    return errorResponse(c, 'rate must be a percentage between 0 and 100', 'invalid_rate', 400);
+  }
+
+  try {
+    const res = await pool.query(
+      `INSERT INTO tax_classes (name, rate, description, is_active)
+       VALUES ($1, $2, $3, $4)
+       ON CONFLICT (name) DO NOTHING
+       RETURNING id`,
+      [name, body.rate, body.description?.trim() || null, body.is_active ?? true],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'A tax class with this name already exists', 'duplicate_name', 409);
+    }
+
+    const created = await pool.query(`${TAX_CLASS_SELECT} WHERE tc.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Tax class created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create tax class', (err as Error).message);
+  }
+}
+
+// ── UpdateTaxClass ──────────────────────────────────────────────────────────
+// A new rate applies to orders priced from now on; order items keep the rate
+// and label they were taxed with. A deactivated class's products fall back
+// to the default tax rate.
+
+export async function updateTaxClass(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tax class not found', 'not_found', 404);
+  }
+
+  let body: TaxClassBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.name !== undefined) {
+    const name = body.name.trim();
+    if (!name || name.length > 100) {
+      return errorResponse(c, 'Name is required (at most 100 characters)', 'invalid_name', 400);
+    }
+    setClauses.push(`name = $${paramIdx++}`);
+    params.push(name);
+  }
+  if (body.rate !== undefined) {
+    if (!validRate(body.rate)) {
+      return errorResponse(c, 'rate must be a percentage between 0 and 100', 'invalid_rate', 400);
+    }
+    setClauses.push(`rate = $${paramIdx++}`);
+    params.push(body.rate);
+  }
+  if (body.description !== undefined) {
+    setClauses.push(`description = $${paramIdx++}`);
+    params.push(body.description?.trim() || null);
+  }
+  if (body.is_active !== undefined) {
+    setClauses.push(`is_active = $${paramIdx++}`);
+    params.push(body.is_active);
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE tax_classes SET ${setClauses.join(', ')} WHERE id = $${paramIdx} RETURNING id`,
+      params,
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Tax class not found', 'not_found', 404);
+    }
+
+    const updated = await pool.query(`${TAX_CLASS_SELECT} WHERE tc.id = $1`, [id]);
+    return successResponse(c, 'Tax class updated successfully', updated.rows[0]);
+  } catch (err) {
+    if ((err as { code?: string }).code === '23505') {
+      return errorResponse(c, 'A tax class with this name already exists', 'duplicate_name', 409);
+    }
+    return errorResponse(c, 'Failed to update tax class', (err as Error).message);
+  }
+}
+
+// ── DeleteTaxClass ──────────────────────────────────────────────────────────
+// Its products go back to the default tax rate.
+
+export async function deleteTaxClass(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tax class not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM tax_classes WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Tax class not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Tax class deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete tax class', (err as Error).message);
+  }
+}
@@ -0,0 +1,221 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { successResponse, errorResponse } from '../lib/response.js';
+import { isUUID } from '../services/branches.js';
+import { TAX_ORDER_TYPES } from '../services/tax.js';
+
+type ExemptionBody = {
+  name?: string;
+  product_id?: string | null;
+  category_id?: string | null;
+  branch_id?: string | null;
+  order_type?: string | null;
+  exempt_tax?: boolean;
+  exempt_service?: boolean;
+  is_active?: boolean;
+};
+
+const EXEMPTION_SELECT = `
+  SELECT e.id, e.name, e.product_id, p.name AS product_name, e.category_id, cat.name AS category_name,
+         e.branch_id, b.name AS branch_name, e.order_type, e.exempt_tax, e.exempt_service, e.is_active,
+         e.created_by, e.created_at, e.updated_at
+  FROM tax_exemptions e
+  LEFT JOIN products p ON p.id = e.product_id
+  LEFT JOIN categories cat ON cat.id = e.category_id
+  LEFT JOIN branches b ON b.id = e.branch_id`;
+
+const EXEMPTION_COLUMNS = ['name', 'product_id', 'category_id', 'branch_id', 'order_type', 'exempt_tax', 'exempt_service', 'is_active'] as const;
+
+function validateExemptionBody(body: ExemptionBody, partial: boolean): { message: string; code: string } | null {
+  if ((!partial || body.name !== undefined) && !body.name?.trim()) return { message: 'Name is required', code: 'missing_name' };
+  if (body.name !== undefined && body.name.length > 100) {
+    return { message: 'Name must be at most 100 characters', code: 'invalid_name' };
+  }
+  for (const id of [body.product_id, body.category_id, body.branch_id]) {
+    if (id && !isUUID(id)) return { message: 'Invalid product, category or branch ID', code: 'invalid_id' };
+  }
+  if (body.order_type && !TAX_ORDER_TYPES.includes(body.order_type)) {
+    return { message: `Order type must be one of: ${TAX_ORDER_TYPES.join(', ')}`, code: 'invalid_order_type' };
+  }
+  return null;
+}
+
+// Exactly one of product / category, both of which must exist, and no other
+// rule for the same target and scope
+async function validateTarget(rule: ExemptionBody, excludeId: string | null): Promise<{ message: string; code: string; status: 400 | 409 } | null> {
+  if (!rule.product_id === !rule.category_id) {
+    return { message: 'Set either product_id or category_id', code: 'invalid_target', status: 400 };
+  }
+  if (rule.product_id) {
+    const res = await pool.query('SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL', [rule.product_id]);
+    if (res.rows.length === 0) return { message: 'Product not found', code: 'product_not_found', status: 400 };
+  }
+  if (rule.category_id) {
+    const res = await pool.query('SELECT 1 FROM categories WHERE id = $1 AND deleted_at IS NULL', [rule.category_id]);
+    if (res.rows.length === 0) return { message: 'Category not found', code: 'category_not_found', status: 400 };
+  }
+  if (rule.branch_id) {
+    const res = await pool.query('SELECT 1 FROM branches WHERE id = $1', [rule.branch_id]);
+    if (res.rows.length === 0) return { message: 'Branch not found', code: 'branch_not_found', status: 400 };
+  }
+
+  const duplicate = await pool.query(
+    `SELECT 1 FROM tax_exemptions
+     WHERE COALESCE(product_id, category_id) = $1
+       AND branch_id IS NOT DISTINCT FROM $2 AND order_type IS NOT DISTINCT FROM $3
+       AND ($4::uuid IS NULL OR id <> $4)`,
+    [rule.product_id || rule.category_id, rule.branch_id || null, rule.order_type || null, excludeId],
+  );
+  if (duplicate.rows.length > 0) {
+    return { message: 'An exemption for this item, branch and order type already exists', code: 'duplicate_exemption', status: 409 };
+  }
+  return null;
+}
+
+// ── GetTaxExemptions ────────────────────────────────────────────────────────
+
+export async function getTaxExemptions(c: Context) {
+  const activeOnly = c.req.query('active_only') === 'true';
+
+  try {
+    const res = await pool.query(
+      `${EXEMPTION_SELECT} ${activeOnly ? 'WHERE e.is_active = true' : ''}
+       ORDER BY e.created_at ASC`,
+    );
+    return successResponse(c, 'Tax exemptions retrieved successfully', res.rows);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch tax exemptions', (err as Error).message);
+  }
+}
+
+// ── CreateTaxExemption ──────────────────────────────────────────────────────
+
+export async function createTaxExemption(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: ExemptionBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateExemptionBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const target = await validateTarget(body, null);
+    if (target) {
+      return errorResponse(c, target.message, target.code, target.status);
+    }
+
+    const res = await pool.query(
+      `INSERT INTO tax_exemptions
+         (name, product_id, category_id, branch_id, order_type, exempt_tax, exempt_service, is_active, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+       RETURNING id`,
+      [
+        body.name!.trim(),
+        body.product_id || null,
+        body.category_id || null,
+        body.branch_id || null,
+        body.order_type || null,
+        body.exempt_tax ?? false,
+        body.exempt_service ?? false,
+        body.is_active ?? true,
+        userId,
+      ],
+    );
+
+    const created = await pool.query(`${EXEMPTION_SELECT} WHERE e.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Tax exemption created successfully', created.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create tax exemption', (err as Error).message);
+  }
+}
+
+// ── UpdateTaxExemption ──────────────────────────────────────────────────────
+// Applies to orders priced from now on; existing orders keep their amounts
+// until their items change.
+
+export async function updateTaxExemption(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tax exemption not found', 'not_found', 404);
+  }
+
+  let body: ExemptionBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateExemptionBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const currentRes = await pool.query('SELECT * FROM tax_exemptions WHERE id = $1', [id]);
+    const current = currentRes.rows[0];
+    if (!current) {
+      return errorResponse(c, 'Tax exemption not found', 'not_found', 404);
+    }
+
+    const setClauses: string[] = [];
+    const params: unknown[] = [];
+    let paramIdx = 1;
+
+    for (const col of EXEMPTION_COLUMNS) {
+      if (body[col] !== undefined) {
+        setClauses.push(`${col} = $${paramIdx}`);
+        params.push(typeof body[col] === 'string' ? (body[col] as string).trim() || null : body[col]);
+        paramIdx++;
+      }
+    }
+
+    if (setClauses.length === 0) {
+      return errorResponse(c, 'No fields to update', 'no_fields', 400);
+    }
+
+    const scopeChanged = ['product_id', 'category_id', 'branch_id', 'order_type'].some((k) => k in body);
+    if (scopeChanged) {
+      const target = await validateTarget({ ...current, ...body }, id);
+      if (target) {
+        return errorResponse(c, target.message, target.code, target.status);
+      }
+    }
+
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    await pool.query(`UPDATE tax_exemptions SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`, params);
+
+    const updated = await pool.query(`${EXEMPTION_SELECT} WHERE e.id = $1`, [id]);
+    return successResponse(c, 'Tax exemption updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update tax exemption', (err as Error).message);
+  }
+}
+
+// ── DeleteTaxExemption ──────────────────────────────────────────────────────
+// Order items keep the exempt flags they were priced with.
+
+export async function deleteTaxExemption(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Tax exemption not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM tax_exemptions WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Tax exemption not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Tax exemption deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete tax exemption', (err as Error).message);
+  }
+}
@@ -1,4 +1,5 @@
 import type { Context } from 'hono';
+import { env } from '../env.js';
 import { successResponse, errorResponse } from '../lib/response.js';
 import * as fs from 'node:fs';
 import * as path from 'node:path';
@@ -13,7 +14,7 @@ const ALLOWED_TYPES: Record<string, string> = {
   'image/webp': '.webp',
 };
 
-const UPLOAD_DIR = process.env.UPLOAD_DIR || './uploads';
+export const UPLOAD_DIR = env.UPLOADS_DIR;
 
 // Ensure upload directory exists
 try {
@@ -57,6 +58,7 @@ export async function uploadImage(c: Context) {
     // Save file to disk
     const destPath = path.join(UPLOAD_DIR, newFilename);
     fs.writeFileSync(destPath, buffer);
+    c.set('uploaded_bytes', buffer.length);
 
     // Generate URL path
     const fileURL = '/uploads/' + newFilename;
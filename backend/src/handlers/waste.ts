@@ -0,0 +1,184 @@
+import type { Context } from 'hono';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { localClock, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { isUUID, resolveBranchScope, resolveWriteBranch, branchCondition } from '../services/branches.js';
+import { WASTE_REASONS, WASTE_LOG_SELECT, isWasteReason, recordWaste, buildWasteReport } from '../services/waste.js';
+import { refreshStockAvailability } from '../services/stock-availability.js';
+
+const DATE_RE = /^\d{4}-\d{2}-\d{2}$/;
+
+// An uploaded photo (POST /kitchen/waste/photo) or a link to one
+const PHOTO_URL_RE = /^(\/uploads\/[\w.-]+|https?:\/\/\S+)$/;
+
+// ── LogWaste ────────────────────────────────────────────────────────────────
+// Records waste of a product or an ingredient and takes it out of stock.
+
+export async function logWaste(c: Context) {
+  let body: {
+    branch_id?: string;
+    product_id?: string;
+    ingredient_id?: string;
+    quantity?: number;
+    reason_type?: string;
+    reason?: string;
+    photo_url?: string;
+  };
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  if (!body.product_id === !body.ingredient_id) {
+    return errorResponse(c, 'Give either product_id or ingredient_id', 'invalid_item', 400);
+  }
+  if (body.product_id && !isUUID(body.product_id)) {
+    return errorResponse(c, 'Product not found', 'product_not_found', 404);
+  }
+  if (body.ingredient_id && !isUUID(body.ingredient_id)) {
+    return errorResponse(c, 'Ingredient not found', 'ingredient_not_found', 404);
+  }
+  if (typeof body.quantity !== 'number' || !Number.isFinite(body.quantity) || body.quantity <= 0 || body.quantity > 1_000_000) {
+    return errorResponse(c, 'Quantity must be a positive number', 'invalid_quantity', 400);
+  }
+  if (!isWasteReason(body.reason_type)) {
+    return errorResponse(c, `reason_type must be one of: ${WASTE_REASONS.join(', ')}`, 'invalid_reason_type', 400);
+  }
+  const reason = body.reason?.trim() || null;
+  if (reason && reason.length > 500) {
+    return errorResponse(c, 'Reason must be at most 500 characters', 'invalid_reason', 400);
+  }
+  if (body.reason_type === 'other' && !reason) {
+    return errorResponse(c, "Describe the reason when reason_type is 'other'", 'invalid_reason', 400);
+  }
+  const photoUrl = body.photo_url?.trim() || null;
+  if (photoUrl && (photoUrl.length > 500 || !PHOTO_URL_RE.test(photoUrl))) {
+    return errorResponse(c, 'photo_url must be an uploaded photo or an http(s) link', 'invalid_photo_url', 400);
+  }
+
+  try {
+    const branch = await resolveWriteBranch(pool, c.get('branch_id') ?? null, body.branch_id);
+    if (!branch.ok) {
+      return errorResponse(c, branch.failure.message, branch.failure.code, branch.failure.status);
+    }
+
+    const result = await withTransaction((client) => recordWaste(client, {
+      branchId: branch.branchId,
+      productId: body.product_id ?? null,
+      ingredientId: body.ingredient_id ?? null,
+      quantity: body.quantity,
+      reasonType: body.reason_type,
+      reason,
+      photoUrl,
+      userId: c.get('user_id') ?? null,
+    }));
+    if (!result.ok) {
+      return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    }
+    await refreshStockAvailability(
+      body.product_id ? { productIds: [body.product_id] } : { ingredientIds: [body.ingredient_id!] },
+    );
+
+    const logRes = await pool.query(`${WASTE_LOG_SELECT} WHERE w.id = $1`, [result.wasteLogId]);
+    return successResponse(c, 'Waste logged successfully', { ...logRes.rows[0], stock_movements: result.movements }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to log waste', (err as Error).message);
+  }
+}
+
+// ── GetWasteLogs ────────────────────────────────────────────────────────────
+// Newest first; ?from= / ?to= dates, ?reason_type=, ?item_type=product|ingredient.
+
+export async function getWasteLogs(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const from = c.req.query('from') || '';
+  const to = c.req.query('to') || '';
+  if ((from && !DATE_RE.test(from)) || (to && !DATE_RE.test(to))) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates', 'invalid_date_range', 400);
+  }
+  const reasonType = c.req.query('reason_type');
+  if (reasonType && !isWasteReason(reasonType)) {
+    return errorResponse(c, `reason_type must be one of: ${WASTE_REASONS.join(', ')}`, 'invalid_reason_type', 400);
+  }
+  const itemType = c.req.query('item_type');
+  if (itemType && itemType !== 'product' && itemType !== 'ingredient') {
+    return errorResponse(c, 'item_type must be product or ingredient', 'invalid_item_type', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  const params: unknown[] = [RESTAURANT_TIMEZONE];
+  let where = 'WHERE 1=1';
+  if (from) {
+    params.push(from);
+    where += ` AND DATE(w.created_at AT TIME ZONE $1) >= $${params.length}`;
+  }
+  if (to) {
+    params.push(to);
+    where += ` AND DATE(w.created_at AT TIME ZONE $1) <= $${params.length}`;
+  }
+  if (reasonType) {
+    params.push(reasonType);
+    where += ` AND w.reason_type = $${params.length}`;
+  }
+  if (itemType) {
+    where += itemType === 'ingredient' ? ' AND w.ingredient_id IS NOT NULL' : ' AND w.ingredient_id IS NULL';
+  }
+  where += branchCondition('w.branch_id', scope.branchId, params);
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) FROM waste_logs w ${where}`, params);
+        return parseInt(countRes.rows[0].count, 10);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${WASTE_LOG_SELECT}
+           ${where}
+           ORDER BY w.created_at DESC, w.id DESC
+           LIMIT $${params.length + 1} OFFSET $${params.length + 2}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Waste logs retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { from, to, reason_type: reasonType, item_type: itemType, branch_id: scope.branchId },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch waste logs', (err as Error).message);
+  }
+}
+
+// ── GetWasteReport ──────────────────────────────────────────────────────────
+// Waste cost over [from, to] (default: this month so far).
+
+export async function getWasteReport(c: Context) {
+  const today = localClock().date;
+  const from = c.req.query('from') || `${today.slice(0, 8)}01`;
+  const to = c.req.query('to') || today;
+  if (!DATE_RE.test(from) || !DATE_RE.test(to) || from > to) {
+    return errorResponse(c, 'from and to must be YYYY-MM-DD dates with from <= to', 'invalid_date_range', 400);
+  }
+
+  const scope = resolveBranchScope(c);
+  if (!scope.ok) {
+    return errorResponse(c, scope.failure.message, scope.failure.code, scope.failure.status);
+  }
+
+  try {
+    const report = await buildWasteReport(pool, from, to, scope.branchId);
+    return successResponse(c, 'Waste report retrieved successfully', report);
+  } catch (err) {
+    return errorResponse(c, 'Failed to generate waste report', (err as Error).message);
+  }
+}
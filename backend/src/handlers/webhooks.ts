@@ -0,0 +1,380 @@
+import type { Context } from 'hono';
+import crypto from 'node:crypto';
+import { pool } from '../db/connection.js';
+import { withTransaction, txFailure } from '../db/transaction.js';
+import { successResponse, errorResponse, paginatedResponse } from '../lib/response.js';
+import { parsePagination, fetchPage, pageMeta } from '../lib/pagination.js';
+import { enqueueJob } from '../lib/jobs.js';
+import { isUUID } from '../services/branches.js';
+import {
+  DELIVER_WEBHOOK_JOB,
+  WEBHOOK_DELIVERY_STATUSES,
+  WEBHOOK_EVENTS,
+  WEBHOOK_MAX_ATTEMPTS,
+  WEBHOOK_TEST_EVENT,
+  generateWebhookSecret,
+  queueWebhookDelivery,
+} from '../services/webhooks.js';
+import { closeDeadLetters } from '../services/dead-letters.js';
+
+type WebhookBody = {
+  name?: string;
+  url?: string;
+  events?: string[];
+  is_active?: boolean;
+};
+
+// The secret itself is only returned when it is created or rotated
+const ENDPOINT_SELECT = `
+  SELECT e.id, e.name, e.url, e.events, e.is_active, '…' || RIGHT(e.secret, 4) AS secret_hint,
+         e.created_by, e.created_at, e.updated_at,
+         (SELECT MAX(d.created_at) FROM webhook_deliveries d WHERE d.endpoint_id = e.id) AS last_delivery_at,
+         (SELECT COUNT(*) FROM webhook_deliveries d WHERE d.endpoint_id = e.id AND d.status = 'failed')::int AS failed_count
+  FROM webhook_endpoints e`;
+
+const DELIVERY_SELECT = `
+  SELECT d.id, d.endpoint_id, e.name AS endpoint_name, d.event, d.event_id, d.status, d.attempts,
+         d.response_status, d.duration_ms, d.last_error, d.delivered_at, d.created_at, d.updated_at
+  FROM webhook_deliveries d
+  JOIN webhook_endpoints e ON e.id = d.endpoint_id`;
+
+function validateWebhookBody(body: WebhookBody, partial: boolean): { message: string; code: string } | null {
+  if ((!partial || body.name !== undefined) && !body.name?.trim()) return { message: 'Name is required', code: 'missing_name' };
+  if (body.name !== undefined && body.name.length > 100) {
+    return { message: 'Name must be at most 100 characters', code: 'invalid_name' };
+  }
+  if (!partial || body.url !== undefined) {
+    let url: URL | null = null;
+    try {
+      url = new URL(body.url ?? '');
+    } catch {
+      // handled below
+    }
+    if (!url || !['http:', 'https:'].includes(url.protocol)) {
+      return { message: 'URL must be an http(s) URL', code: 'invalid_url' };
+    }
+  }
+  if (!partial || body.events !== undefined) {
+    if (!Array.isArray(body.events) || body.events.length === 0) {
+      return { message: 'Subscribe to at least one event', code: 'missing_events' };
+    }
+    const unknown = body.events.filter((e) => !(WEBHOOK_EVENTS as readonly string[]).includes(e));
+    if (unknown.length > 0) {
+      return { message: `Unknown events: ${unknown.join(', ')}. Events are: ${WEBHOOK_EVENTS.join(', ')}`, code: 'invalid_events' };
+    }
+  }
+  return null;
+}
+
+// ── GetWebhooks ─────────────────────────────────────────────────────────────
+
+export async function getWebhooks(c: Context) {
+  try {
+    const res = await pool.query(`${ENDPOINT_SELECT} ORDER BY e.created_at ASC`);
+    return successResponse(c, 'Webhooks retrieved successfully', { endpoints: res.rows, events: WEBHOOK_EVENTS });
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch webhooks', (err as Error).message);
+  }
+}
+
+// ── CreateWebhook ───────────────────────────────────────────────────────────
+
+export async function createWebhook(c: Context) {
+  const userId = c.get('user_id');
+
+  let body: WebhookBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateWebhookBody(body, false);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  try {
+    const secret = generateWebhookSecret();
+    const res = await pool.query(
+      `INSERT INTO webhook_endpoints (name, url, secret, events, is_active, created_by)
+       VALUES ($1, $2, $3, $4, $5, $6)
+       RETURNING id`,
+      [body.name!.trim(), body.url, secret, [...new Set(body.events)], body.is_active ?? true, userId],
+    );
+
+    const created = await pool.query(`${ENDPOINT_SELECT} WHERE e.id = $1`, [res.rows[0].id]);
+    return successResponse(c, 'Webhook created successfully', { ...created.rows[0], secret }, 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to create webhook', (err as Error).message);
+  }
+}
+
+// ── UpdateWebhook ───────────────────────────────────────────────────────────
+// Deliveries already queued go to the new URL; disabling an endpoint fails
+// its queued deliveries.
+
+export async function updateWebhook(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook not found', 'not_found', 404);
+  }
+
+  let body: WebhookBody;
+  try {
+    body = await c.req.json();
+  } catch {
+    return errorResponse(c, 'Invalid request body', 'invalid_json', 400);
+  }
+
+  const invalid = validateWebhookBody(body, true);
+  if (invalid) {
+    return errorResponse(c, invalid.message, invalid.code, 400);
+  }
+
+  const setClauses: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (body.name !== undefined) {
+    setClauses.push(`name = $${paramIdx++}`);
+    params.push(body.name.trim());
+  }
+  if (body.url !== undefined) {
+    setClauses.push(`url = $${paramIdx++}`);
+    params.push(body.url);
+  }
+  if (body.events !== undefined) {
+    setClauses.push(`events = $${paramIdx++}`);
+    params.push([...new Set(body.events)]);
+  }
+  if (body.is_active !== undefined) {
+    setClauses.push(`is_active = $${paramIdx++}`);
+    params.push(body.is_active);
+  }
+
+  if (setClauses.length === 0) {
+    return errorResponse(c, 'No fields to update', 'no_fields', 400);
+  }
+
+  try {
+    setClauses.push('updated_at = NOW()');
+    params.push(id);
+    const res = await pool.query(
+      `UPDATE webhook_endpoints SET ${setClauses.join(', ')} WHERE id = $${paramIdx}`,
+      params,
+    );
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Webhook not found', 'not_found', 404);
+    }
+
+    const updated = await pool.query(`${ENDPOINT_SELECT} WHERE e.id = $1`, [id]);
+    return successResponse(c, 'Webhook updated successfully', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to update webhook', (err as Error).message);
+  }
+}
+
+// ── DeleteWebhook ───────────────────────────────────────────────────────────
+// Removes the endpoint with its delivery log.
+
+export async function deleteWebhook(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query('DELETE FROM webhook_endpoints WHERE id = $1', [id]);
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Webhook not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Webhook deleted successfully');
+  } catch (err) {
+    return errorResponse(c, 'Failed to delete webhook', (err as Error).message);
+  }
+}
+
+// ── RotateWebhookSecret ─────────────────────────────────────────────────────
+// The old secret stops working at once, including for queued retries.
+
+export async function rotateWebhookSecret(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook not found', 'not_found', 404);
+  }
+
+  try {
+    const secret = generateWebhookSecret();
+    const res = await pool.query(
+      'UPDATE webhook_endpoints SET secret = $1, updated_at = NOW() WHERE id = $2',
+      [secret, id],
+    );
+    if (res.rowCount === 0) {
+      return errorResponse(c, 'Webhook not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Webhook secret rotated', { id, secret });
+  } catch (err) {
+    return errorResponse(c, 'Failed to rotate webhook secret', (err as Error).message);
+  }
+}
+
+// ── SendTestWebhook ─────────────────────────────────────────────────────────
+// Queues a webhook.test event to this endpoint only; the outcome shows up
+// in its delivery log.
+
+export async function sendTestWebhook(c: Context) {
+  const userId = c.get('user_id');
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook not found', 'not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const endpoint = await client.query('SELECT id, name FROM webhook_endpoints WHERE id = $1', [id]);
+      if (endpoint.rows.length === 0) {
+        return txFailure('Webhook not found', 'not_found', 404);
+      }
+      const deliveryId = await queueWebhookDelivery(client, id, WEBHOOK_TEST_EVENT, crypto.randomUUID(), {
+        endpoint_id: id,
+        endpoint_name: endpoint.rows[0].name,
+        sent_by: userId,
+      });
+
+      return { ok: true as const, deliveryId };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+    const { deliveryId } = result;
+
+    const delivery = await pool.query(`${DELIVERY_SELECT} WHERE d.id = $1`, [deliveryId]);
+    return successResponse(c, 'Test webhook queued', delivery.rows[0], 201);
+  } catch (err) {
+    return errorResponse(c, 'Failed to queue test webhook', (err as Error).message);
+  }
+}
+
+// ── GetWebhookDeliveries ────────────────────────────────────────────────────
+// The delivery log, newest first. Payloads and responses are left out of
+// the list; open a delivery to see them.
+
+export async function getWebhookDeliveries(c: Context) {
+  const pagination = parsePagination(c.req.query());
+  const endpointId = c.req.query('endpoint_id');
+  const status = c.req.query('status');
+  const event = c.req.query('event');
+
+  if (endpointId && !isUUID(endpointId)) {
+    return errorResponse(c, 'Invalid endpoint_id', 'invalid_endpoint_id', 400);
+  }
+  if (status && !WEBHOOK_DELIVERY_STATUSES.includes(status)) {
+    return errorResponse(c, `Status must be one of: ${WEBHOOK_DELIVERY_STATUSES.join(', ')}`, 'invalid_status', 400);
+  }
+
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  let paramIdx = 1;
+
+  if (endpointId) {
+    conditions.push(`d.endpoint_id = $${paramIdx++}`);
+    params.push(endpointId);
+  }
+  if (status) {
+    conditions.push(`d.status = $${paramIdx++}`);
+    params.push(status);
+  }
+  if (event) {
+    conditions.push(`d.event = $${paramIdx++}`);
+    params.push(event);
+  }
+
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  try {
+    const result = await fetchPage(
+      pagination,
+      async () => {
+        const countRes = await pool.query(`SELECT COUNT(*) AS total FROM webhook_deliveries d ${where}`, params);
+        return Number(countRes.rows[0].total);
+      },
+      async (limit) => {
+        const res = await pool.query(
+          `${DELIVERY_SELECT} ${where}
+           ORDER BY d.created_at DESC
+           LIMIT $${paramIdx} OFFSET $${paramIdx + 1}`,
+          [...params, limit, pagination.offset],
+        );
+        return res.rows;
+      },
+    );
+    return paginatedResponse(c, 'Webhook deliveries retrieved successfully', result.rows, pageMeta(pagination, result, {
+      sort: 'created_at:desc',
+      filters: { endpoint_id: endpointId, status, event },
+    }));
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch webhook deliveries', (err as Error).message);
+  }
+}
+
+// ── GetWebhookDelivery ──────────────────────────────────────────────────────
+
+export async function getWebhookDelivery(c: Context) {
+  const id = c.req.param('id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook delivery not found', 'not_found', 404);
+  }
+
+  try {
+    const res = await pool.query(
+      `SELECT d.*, e.name AS endpoint_name, e.url AS endpoint_url
+       FROM webhook_deliveries d
+       JOIN webhook_endpoints e ON e.id = d.endpoint_id
+       WHERE d.id = $1`,
+      [id],
+    );
+    if (res.rows.length === 0) {
+      return errorResponse(c, 'Webhook delivery not found', 'not_found', 404);
+    }
+    return successResponse(c, 'Webhook delivery retrieved successfully', res.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to fetch webhook delivery', (err as Error).message);
+  }
+}
+
+// ── RetryWebhookDelivery ────────────────────────────────────────────────────
+// Sends a failed delivery again with the same payload and event ID.
+
+export async function retryWebhookDelivery(c: Context) {
+  const id = c.req.param('id');
+  const userId = c.get('user_id');
+  if (!isUUID(id)) {
+    return errorResponse(c, 'Webhook delivery not found', 'not_found', 404);
+  }
+
+  try {
+    const result = await withTransaction(async (client) => {
+      const res = await client.query(
+        `UPDATE webhook_deliveries SET status = 'queued', updated_at = NOW()
+         WHERE id = $1 AND status = 'failed'
+         RETURNING id`,
+        [id],
+      );
+      if (res.rows.length === 0) {
+        const exists = await client.query('SELECT status FROM webhook_deliveries WHERE id = $1', [id]);
+        if (exists.rows.length === 0) {
+          return txFailure('Webhook delivery not found', 'not_found', 404);
+        }
+        return txFailure(`Only failed deliveries can be retried; this delivery is ${exists.rows[0].status}`, 'invalid_delivery_status', 400);
+      }
+      await enqueueJob(client, DELIVER_WEBHOOK_JOB, { delivery_id: id }, { maxAttempts: WEBHOOK_MAX_ATTEMPTS });
+      await closeDeadLetters(client, { jobType: DELIVER_WEBHOOK_JOB, payload: { delivery_id: id } }, userId ?? null);
+      return { ok: true as const };
+    });
+    if (!result.ok) return errorResponse(c, result.failure.message, result.failure.code, result.failure.status);
+
+    const updated = await pool.query(`${DELIVERY_SELECT} WHERE d.id = $1`, [id]);
+    return successResponse(c, 'Webhook delivery queued for retry', updated.rows[0]);
+  } catch (err) {
+    return errorResponse(c, 'Failed to retry webhook delivery', (err as Error).message);
+  }
+}
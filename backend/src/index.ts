@@ -3,45 +3,108 @@ import { cors } from 'hono/cors';
 import { serve } from '@hono/node-server';
 import { serveStatic } from '@hono/node-server/serve-static';
 import { env } from './env.js';
+import { configSummary } from './config/index.js';
 import { securityHeaders } from './middleware/security.js';
+import { metricsMiddleware } from './middleware/metrics.js';
+import { requestIdMiddleware, structuredLogger, errorContext } from './middleware/logging.js';
+import { maintenanceMode } from './middleware/maintenance.js';
+import { captureException } from './lib/sentry.js';
+import { invalidateCache } from './lib/cache.js';
+import { startRuntimeConfigRefresh } from './lib/runtime-config.js';
 import { setupRoutes } from './routes/index.js';
+import { pool } from './db/connection.js';
+import { migrateUp, runMigrateCommand } from './db/migrate.js';
+import { scheduleDaily, scheduleEvery, startScheduler, stopScheduler } from './lib/scheduler.js';
+import { JOBS_PURGE_JOB, registerJobHandler, startJobWorker, stopJobWorker, purgeFinishedJobs } from './lib/jobs.js';
+import { SEND_EMAIL_JOB, deliverOutboxEmail } from './services/email.js';
+import { DAILY_SALES_SUMMARY_JOB, LOW_STOCK_DIGEST_JOB, sendDailySalesSummary, sendLowStockDigest } from './services/email-digests.js';
+import { LOW_STOCK_ALERT_JOB, sendLowStockAlert } from './services/ingredient.js';
+import { INGREDIENT_EXPIRY_CHECK_JOB, notifyExpiringBatches } from './services/ingredient-batches.js';
+import { STOCK_AVAILABILITY_JOB, syncStockAvailability } from './services/stock-availability.js';
+import { MENU_SYNC_JOB, syncMenuItem } from './services/menu-sync.js';
+import { DELIVER_WEBHOOK_JOB, deliverWebhook } from './services/webhooks.js';
+import { PROCESS_INBOUND_WEBHOOK_JOB, processInboundWebhook } from './services/inbound-webhooks.js';
+import { GATEWAY_REFUND_JOB, submitGatewayRefund } from './services/gateway-refunds.js';
+import { localClock, addDays } from './lib/clock.js';
+import { DAILY_SPECIALS_RESET_JOB, resetDailySpecials } from './services/daily-specials.js';
+import { EIGHTY_SIX_RESET_JOB, resetEightySixList } from './services/eighty-six.js';
+import { PRICE_SCHEDULE_REFRESH_JOB, activeScheduleKey } from './services/price-schedules.js';
+import { LOGBOOK_DIGEST_JOB, sendLogbookDigest } from './services/logbook.js';
+import { SCHEDULED_ORDERS_PROMOTE_JOB, promoteDueScheduledOrders } from './services/scheduled-orders.js';
+import { PARKED_ORDERS_EXPIRE_JOB, expireParkedOrders } from './services/order-parking.js';
+import { TAB_AUTHORIZATIONS_RELEASE_JOB, releaseExpiredTabAuthorizations } from './services/tabs.js';
+import {
+  RESERVATION_REMINDERS_JOB,
+  RESERVATION_NO_SHOWS_JOB,
+  sendReservationReminders,
+  markNoShowReservations,
+} from './services/reservations.js';
+import { ORDER_AUTO_COMPLETE_JOB, autoCompleteServedOrders } from './services/order-completion.js';
+import { ORDER_SLA_CHECK_JOB, checkOrderSlaBreaches } from './services/order-sla.js';
+import { ELT_EXPORT_JOB, runEltExport } from './services/elt-export.js';
+import {
+  isShuttingDown,
+  markShuttingDown,
+  requestStarted,
+  requestFinished,
+  inFlightRequests,
+  waitForDrain,
+  runShutdownHooks,
+  onShutdown,
+} from './lib/lifecycle.js';
 
 const app = new Hono();
 
 // ── Global middleware ─────────────────────────────────────────────────────────
 
+// Request and trace IDs first, so every response, error and log line of the
+// request carries them
+app.use('*', requestIdMiddleware);
+
+// In-flight tracking: reject new work once shutdown has started so the
+// remaining requests can drain. The readiness probe still answers, with its
+// own not-ready body (see handlers/health.ts).
+app.use('*', async (c, next) => {
+  if (isShuttingDown() && c.req.path !== '/api/v1/ready') {
+    c.header('Connection', 'close');
+    return c.json({
+      success: false,
+      message: 'Server is shutting down',
+      error: 'shutting_down',
+    }, 503);
+  }
+
+  requestStarted();
+  try {
+    await next();
+  } finally {
+    requestFinished();
+  }
+});
+
 // CORS
 const allowedOrigins = env.CORS_ALLOWED_ORIGINS.split(',').map((o) => o.trim());
 
 app.use('*', cors({
   origin: allowedOrigins,
   allowMethods: ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'OPTIONS'],
-  allowHeaders: ['Authorization', 'Content-Type', 'X-CSRF-Token', 'X-Request-ID'],
-  exposeHeaders: ['X-Request-ID'],
+  allowHeaders: ['Authorization', 'Content-Type', 'X-CSRF-Token', 'X-Request-ID', 'traceparent'],
+  exposeHeaders: ['X-Request-ID', 'X-Trace-ID', 'Content-Disposition', 'X-Export-Id', 'X-Exported-By', 'X-Exported-At'],
   credentials: true,
   maxAge: 86400,
 }));
 
+// Prometheus request metrics
+app.use('*', metricsMiddleware);
+
 // Security headers
 app.use('*', securityHeaders);
 
 // Request logging
-app.use('*', async (c, next) => {
-  const start = Date.now();
-  const requestId = crypto.randomUUID();
-  c.header('X-Request-ID', requestId);
+app.use('*', structuredLogger);
 
-  await next();
-
-  const duration = Date.now() - start;
-  const status = c.res.status;
-  const method = c.req.method;
-  const path = c.req.path;
-
-  if (env.NODE_ENV === 'development') {
-    console.log(`[${new Date().toISOString()}] ${method} ${path} ${status} ${duration}ms`);
-  }
-});
+// Maintenance mode (switched in the runtime config)
+app.use('*', maintenanceMode);
 
 // ── Static files (uploads) ────────────────────────────────────────────────────
 
@@ -64,25 +127,199 @@ app.notFound((c) => {
 // ── Error handler ─────────────────────────────────────────────────────────────
 
 app.onError((err, c) => {
-  console.error(`[ERROR] ${c.req.method} ${c.req.path}:`, err.message);
+  console.error(`[ERROR] ${c.req.method} ${c.req.path} request_id=${c.get('requestId')} trace_id=${c.get('traceId')}:`, err.message);
+  captureException(err, { ...errorContext(c), status: 500 });
+  c.set('errorReported', true);
   return c.json({
     success: false,
     message: 'Internal server error',
     error: env.NODE_ENV === 'development' ? err.message : undefined,
+    request_id: c.get('requestId'),
   }, 500);
 });
 
+// ── Migrate subcommand ────────────────────────────────────────────────────────
+// `node dist/index.js migrate [up|down [steps]|status]` runs migrations and
+// exits without starting the HTTP server.
+
+if (process.argv[2] === 'migrate') {
+  const code = await runMigrateCommand(process.argv.slice(3));
+  await pool.end();
+  process.exit(code);
+}
+
+if (env.AUTO_MIGRATE) {
+  try {
+    const ran = await migrateUp();
+    console.log(ran.length === 0 ? 'Database schema is up to date' : `Applied ${ran.length} migration(s) on startup`);
+  } catch (err) {
+    console.error('Startup migration failed:', (err as Error).message);
+    process.exit(1);
+  }
+}
+
+// Runtime config (log level, rate limits, maintenance mode, feature flags)
+// is loaded before the first request
+onShutdown('runtime config', await startRuntimeConfigRefresh());
+
 // ── Start server ──────────────────────────────────────────────────────────────
 
 const port = env.PORT;
 
 console.log(`Starting server on port ${port}...`);
-console.log(`Environment: ${env.NODE_ENV}`);
-console.log(`CORS origins: ${allowedOrigins.join(', ')}`);
+console.log('Effective configuration:');
+for (const line of configSummary(env, process.env)) console.log(`  ${line}`);
 
-serve({
+const server = serve({
   fetch: app.fetch,
   port,
 }, (info) => {
   console.log(`Server running at http://localhost:${info.port}`);
 });
+
+// ── Scheduled jobs ────────────────────────────────────────────────────────────
+
+scheduleDaily(DAILY_SPECIALS_RESET_JOB, env.DAILY_SPECIALS_RESET_TIME, async () => {
+  const count = await resetDailySpecials(pool);
+  invalidateCache('menu');
+  console.log(`Reset ${count} daily special(s)`);
+});
+
+scheduleDaily(EIGHTY_SIX_RESET_JOB, env.EIGHTY_SIX_RESET_TIME, async () => {
+  const count = await resetEightySixList(pool);
+  if (count > 0) console.log(`Put ${count} 86'd product(s) back on the menu`);
+});
+
+// Morning digest covers the previous business day, late shift included
+scheduleDaily(LOGBOOK_DIGEST_JOB, env.LOGBOOK_DIGEST_TIME, async () => {
+  await sendLogbookDigest(pool, addDays(localClock().date, -1));
+});
+
+scheduleDaily(DAILY_SALES_SUMMARY_JOB, env.SALES_SUMMARY_TIME, async () => {
+  await sendDailySalesSummary(pool, addDays(localClock().date, -1));
+});
+
+scheduleDaily(LOW_STOCK_DIGEST_JOB, env.LOW_STOCK_DIGEST_TIME, async () => {
+  await sendLowStockDigest(pool, localClock().date);
+});
+
+scheduleEvery(SCHEDULED_ORDERS_PROMOTE_JOB, 60_000, async () => {
+  const count = await promoteDueScheduledOrders(pool);
+  if (count > 0) console.log(`Released ${count} scheduled order(s) to the kitchen`);
+});
+
+scheduleEvery(PARKED_ORDERS_EXPIRE_JOB, 60_000, async () => {
+  const count = await expireParkedOrders(pool);
+  if (count > 0) console.log(`Cancelled ${count} expired parked order(s)`);
+});
+
+scheduleEvery(TAB_AUTHORIZATIONS_RELEASE_JOB, 60_000, async () => {
+  const count = await releaseExpiredTabAuthorizations(pool);
+  if (count > 0) console.log(`Released ${count} unused tab authorization(s)`);
+});
+
+scheduleEvery(ORDER_AUTO_COMPLETE_JOB, 60_000, async () => {
+  const count = await autoCompleteServedOrders(pool);
+  if (count > 0) console.log(`Auto-completed ${count} served order(s)`);
+});
+
+scheduleEvery(ORDER_SLA_CHECK_JOB, 60_000, async () => {
+  const count = await checkOrderSlaBreaches(pool);
+  if (count > 0) console.log(`Recorded ${count} order SLA breach(es)`);
+});
+
+scheduleEvery(RESERVATION_REMINDERS_JOB, 5 * 60_000, async () => {
+  const count = await sendReservationReminders(pool);
+  if (count > 0) console.log(`Sent ${count} reservation reminder(s)`);
+});
+
+scheduleEvery(RESERVATION_NO_SHOWS_JOB, 5 * 60_000, async () => {
+  const count = await markNoShowReservations(pool);
+  if (count > 0) console.log(`Marked ${count} reservation(s) as no-show`);
+});
+
+scheduleEvery(INGREDIENT_EXPIRY_CHECK_JOB, 60 * 60_000, async () => {
+  const count = await notifyExpiringBatches(pool);
+  if (count > 0) console.log(`Flagged ${count} ingredient batch(es) nearing expiry`);
+});
+
+// Catches stock changes that don't refresh availability themselves
+scheduleEvery(STOCK_AVAILABILITY_JOB, 5 * 60_000, async () => {
+  const count = await syncStockAvailability(pool);
+  if (count > 0) console.log(`Updated menu availability of ${count} product(s) from stock`);
+});
+
+// The cached menu shows scheduled prices, so drop it when a happy hour
+// window opens or closes
+let lastScheduleKey: string | null = null;
+scheduleEvery(PRICE_SCHEDULE_REFRESH_JOB, 60_000, async () => {
+  const key = await activeScheduleKey(pool);
+  if (lastScheduleKey !== null && key !== lastScheduleKey) {
+    invalidateCache('menu');
+    console.log('Price schedules changed; menu cache cleared');
+  }
+  lastScheduleKey = key;
+});
+
+// Safe on every instance: each stream's batch is exported under a row lock
+if (env.ELT_EXPORT_TARGET !== 'off') {
+  scheduleEvery(ELT_EXPORT_JOB, env.ELT_EXPORT_INTERVAL_MINUTES * 60_000, async () => {
+    const results = await runEltExport(pool);
+    for (const r of results) {
+      if (r.error) console.error(`Analytics export of ${r.stream} failed:`, r.error);
+      else if (r.rows > 0) console.log(`Exported ${r.rows} ${r.stream} row(s) in ${r.files} file(s)`);
+    }
+  });
+}
+
+scheduleDaily(JOBS_PURGE_JOB, '03:00', async () => {
+  const count = await purgeFinishedJobs(pool, 7);
+  if (count > 0) console.log(`Purged ${count} finished job(s)`);
+});
+
+if (env.SCHEDULER_ENABLED) {
+  startScheduler();
+  onShutdown('scheduler', stopScheduler);
+}
+
+// ── Background jobs ───────────────────────────────────────────────────────────
+
+registerJobHandler(SEND_EMAIL_JOB, deliverOutboxEmail);
+registerJobHandler(LOW_STOCK_ALERT_JOB, sendLowStockAlert);
+registerJobHandler(MENU_SYNC_JOB, syncMenuItem);
+registerJobHandler(DELIVER_WEBHOOK_JOB, deliverWebhook);
+registerJobHandler(PROCESS_INBOUND_WEBHOOK_JOB, processInboundWebhook);
+registerJobHandler(GATEWAY_REFUND_JOB, submitGatewayRefund);
+
+if (env.JOB_WORKER_ENABLED) {
+  startJobWorker();
+  onShutdown('job worker', stopJobWorker);
+}
+
+// ── Graceful shutdown ─────────────────────────────────────────────────────────
+
+async function shutdown(signal: string) {
+  if (isShuttingDown()) return;
+  markShuttingDown();
+
+  console.log(`${signal} received, draining ${inFlightRequests()} in-flight request(s)...`);
+
+  // Stop accepting new connections; idle keep-alive sockets are closed so
+  // server.close() doesn't wait on them.
+  server.close();
+  if ('closeIdleConnections' in server) {
+    server.closeIdleConnections();
+  }
+
+  const drained = await waitForDrain(env.SHUTDOWN_TIMEOUT_MS);
+  if (!drained) {
+    console.warn(`Shutdown timeout after ${env.SHUTDOWN_TIMEOUT_MS}ms, ${inFlightRequests()} request(s) still running`);
+  }
+
+  await runShutdownHooks();
+  console.log('Shutdown complete');
+  process.exit(drained ? 0 : 1);
+}
+
+process.on('SIGTERM', () => void shutdown('SIGTERM'));
+process.on('SIGINT', () => void shutdown('SIGINT'));
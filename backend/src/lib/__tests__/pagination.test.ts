@@ -0,0 +1,40 @@
+import { describe, it, expect } from 'vitest';
+import { decodeCursor, encodeCursor, isTimestampKey } from '../pagination.js';
+
+describe('decodeCursor', () => {
+  it('round-trips an encoded key', () => {
+    const key = ['2026-10-14 09:30:00.123456+07', '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d'];
+    expect(decodeCursor(encodeCursor(key), 2)).toEqual(key);
+  });
+
+  it('rejects a key of the wrong length', () => {
+    expect(decodeCursor(encodeCursor(['a', 'b']), 3)).toBeNull();
+    expect(decodeCursor(encodeCursor(['a', 'b']), 1)).toBeNull();
+  });
+
+  it('rejects keys that are not all strings', () => {
+    const cursor = Buffer.from(JSON.stringify(['a', 1])).toString('base64url');
+    expect(decodeCursor(cursor, 2)).toBeNull();
+  });
+
+  it('rejects a cursor that is not a JSON array', () => {
+    expect(decodeCursor(Buffer.from('{"a":"b"}').toString('base64url'), 1)).toBeNull();
+    expect(decodeCursor('not-a-cursor', 1)).toBeNull();
+    expect(decodeCursor('', 1)).toBeNull();
+  });
+});
+
+describe('isTimestampKey', () => {
+  it("accepts Postgres' timestamptz text form", () => {
+    expect(isTimestampKey('2026-10-14 09:30:00+07')).toBe(true);
+    expect(isTimestampKey('2026-10-14 09:30:00.123456+07')).toBe(true);
+    expect(isTimestampKey('2026-10-14 09:30:00.5-05:30')).toBe(true);
+  });
+
+  it('rejects other timestamp forms and anything injectable', () => {
+    expect(isTimestampKey('2026-10-14T09:30:00Z')).toBe(false);
+    expect(isTimestampKey('2026-10-14 09:30:00')).toBe(false);
+    expect(isTimestampKey('2026-10-14 09:30:00.1234567+07')).toBe(false);
+    expect(isTimestampKey("2026-10-14 09:30:00+07' OR '1'='1")).toBe(false);
+  });
+});
@@ -0,0 +1,260 @@
+import net from 'node:net';
+import tls from 'node:tls';
+import { env } from '../env.js';
+
+// Response cache for read-heavy public endpoints. Entries live in a
+// namespace (e.g. "menu"); invalidating the namespace drops all of them at
+// once, which is what the admin handlers do after changing the data.
+//
+// In memory by default. With REDIS_URL set the cache is shared through Redis,
+// so an invalidation on one backend instance reaches every instance. Each
+// namespace has a version counter in Redis that is part of every key, and
+// invalidating bumps it; old entries simply expire. Redis being down only
+// costs cache misses.
+
+export interface CacheStore {
+  readonly backend: 'memory' | 'redis';
+  get(namespace: string, key: string): Promise<string | null>;
+  set(namespace: string, key: string, value: string, ttlMs: number): Promise<void>;
+  invalidate(namespace: string): Promise<void>;
+  /** Throws when the store can't be reached (for the health check) */
+  ping(): Promise<void>;
+}
+
+// ── MemoryCache ─────────────────────────────────────────────────────────────
+
+// Per namespace; the oldest entry goes first (e.g. many distinct searches)
+const MEMORY_MAX_ENTRIES = 500;
+
+class MemoryCache implements CacheStore {
+  readonly backend = 'memory';
+  private entries = new Map<string, Map<string, { value: string; expiresAt: number }>>();
+
+  async get(namespace: string, key: string): Promise<string | null> {
+    const entry = this.entries.get(namespace)?.get(key);
+    if (!entry) return null;
+    if (entry.expiresAt <= Date.now()) {
+      this.entries.get(namespace)!.delete(key);
+      return null;
+    }
+    return entry.value;
+  }
+
+  async set(namespace: string, key: string, value: string, ttlMs: number): Promise<void> {
+    let ns = this.entries.get(namespace);
+    if (!ns) {
+      ns = new Map();
+      this.entries.set(namespace, ns);
+    }
+    ns.delete(key);
+    if (ns.size >= MEMORY_MAX_ENTRIES) ns.delete(ns.keys().next().value!);
+    ns.set(key, { value, expiresAt: Date.now() + ttlMs });
+  }
+
+  async invalidate(namespace: string): Promise<void> {
+    this.entries.delete(namespace);
+  }
+
+  async ping(): Promise<void> {}
+}
+
+// ── RedisCache ──────────────────────────────────────────────────────────────
+// Just enough RESP for GET, SET PX, INCR, PING, AUTH and SELECT over one
+// connection, opened on first use and again after it drops.
+
+type RedisReply = string | number | null;
+
+interface PendingCommand {
+  resolve: (reply: RedisReply) => void;
+  reject: (err: Error) => void;
+}
+
+const REDIS_TIMEOUT_MS = 2000;
+// After a failed connect, requests skip Redis this long instead of each
+// waiting on another attempt
+const REDIS_RETRY_MS = 5000;
+
+class RedisConnection {
+  private socket: net.Socket | null = null;
+  private ready: Promise<void> | null = null;
+  private buffer = Buffer.alloc(0);
+  private pending: PendingCommand[] = [];
+  private retryAt = 0;
+
+  constructor(private url: URL) {}
+
+  private connect(): Promise<void> {
+    const host = this.url.hostname;
+    const port = Number(this.url.port) || 6379;
+    const socket = this.url.protocol === 'rediss:'
+      ? tls.connect({ host, port, servername: host })
+      : net.connect({ host, port });
+    this.socket = socket;
+    socket.setTimeout(REDIS_TIMEOUT_MS);
+    socket.on('data', (chunk) => this.onData(chunk));
+    socket.on('timeout', () => socket.destroy(new Error('Redis connection timed out')));
+    socket.on('error', () => {});
+    socket.on('close', () => this.reset(new Error('Redis connection closed')));
+
+    return new Promise<void>((resolve, reject) => {
+      socket.once(this.url.protocol === 'rediss:' ? 'secureConnect' : 'connect', () => {
+        socket.setTimeout(0);
+        resolve();
+      });
+      socket.once('error', reject);
+    }).then(async () => {
+      if (this.url.password) {
+        const user = decodeURIComponent(this.url.username);
+        const password = decodeURIComponent(this.url.password);
+        await this.send(user ? ['AUTH', user, password] : ['AUTH', password]);
+      }
+      const db = this.url.pathname.slice(1);
+      if (db) await this.send(['SELECT', db]);
+    });
+  }
+
+  private reset(err: Error): void {
+    this.socket = null;
+    this.ready = null;
+    this.buffer = Buffer.alloc(0);
+    for (const cmd of this.pending.splice(0)) cmd.reject(err);
+  }
+
+  private onData(chunk: Buffer): void {
+    this.buffer = Buffer.concat([this.buffer, chunk]);
+    for (;;) {
+      const parsed = parseReply(this.buffer);
+      if (!parsed) return;
+      this.buffer = this.buffer.subarray(parsed.length);
+      const cmd = this.pending.shift();
+      if (!cmd) continue;
+      if (parsed.error) cmd.reject(new Error(parsed.error));
+      else cmd.resolve(parsed.value);
+    }
+  }
+
+  private send(args: string[]): Promise<RedisReply> {
+    const socket = this.socket;
+    if (!socket) return Promise.reject(new Error('Redis is not connected'));
+    const payload = `*${args.length}\r\n` + args.map((a) => `$${Buffer.byteLength(a)}\r\n${a}\r\n`).join('');
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => socket.destroy(new Error('Redis command timed out')), REDIS_TIMEOUT_MS);
+      this.pending.push({
+        resolve: (reply) => { clearTimeout(timer); resolve(reply); },
+        reject: (err) => { clearTimeout(timer); reject(err); },
+      });
+      socket.write(payload);
+    });
+  }
+
+  async command(args: string[]): Promise<RedisReply> {
+    if (!this.ready) {
+      if (Date.now() < this.retryAt) throw new Error('Redis is unavailable');
+      this.ready = this.connect();
+      this.ready.catch(() => {
+        this.retryAt = Date.now() + REDIS_RETRY_MS;
+        this.socket?.destroy();
+      });
+    }
+    await this.ready;
+    return this.send(args);
+  }
+}
+
+// One complete reply at the start of the buffer, or null if more is needed
+function parseReply(buf: Buffer): { value: RedisReply; error?: string; length: number } | null {
+  const lineEnd = buf.indexOf('\r\n');
+  if (lineEnd < 0) return null;
+  const type = String.fromCharCode(buf[0]);
+  const line = buf.subarray(1, lineEnd).toString();
+
+  switch (type) {
+    case '+':
+      return { value: line, length: lineEnd + 2 };
+    case '-':
+      return { value: null, error: line, length: lineEnd + 2 };
+    case ':':
+      return { value: Number(line), length: lineEnd + 2 };
+    case '$': {
+      const size = Number(line);
+      if (size < 0) return { value: null, length: lineEnd + 2 };
+      const end = lineEnd + 2 + size;
+      if (buf.length < end + 2) return null;
+      return { value: buf.subarray(lineEnd + 2, end).toString(), length: end + 2 };
+    }
+    default:
+      // Not something these commands return; drop the rest of the buffer
+      return { value: null, error: `Unsupported Redis reply type: ${type}`, length: buf.length };
+  }
+}
+
+class RedisCache implements CacheStore {
+  readonly backend = 'redis';
+  private redis: RedisConnection;
+  private lastWarning = 0;
+
+  constructor(url: URL) {
+    this.redis = new RedisConnection(url);
+  }
+
+  // At most one warning a minute while Redis is unreachable
+  private warn(err: unknown): void {
+    if (Date.now() - this.lastWarning < 60_000) return;
+    this.lastWarning = Date.now();
+    console.warn('Response cache: Redis unavailable:', (err as Error).message);
+  }
+
+  private async versionedKey(namespace: string, key: string): Promise<string> {
+    const version = await this.redis.command(['GET', `cache:${namespace}:version`]);
+    return `cache:${namespace}:${version ?? 0}:${key}`;
+  }
+
+  async get(namespace: string, key: string): Promise<string | null> {
+    try {
+      const reply = await this.redis.command(['GET', await this.versionedKey(namespace, key)]);
+      return typeof reply === 'string' ? reply : null;
+    } catch (err) {
+      this.warn(err);
+      return null;
+    }
+  }
+
+  async set(namespace: string, key: string, value: string, ttlMs: number): Promise<void> {
+    try {
+      await this.redis.command(['SET', await this.versionedKey(namespace, key), value, 'PX', String(ttlMs)]);
+    } catch (err) {
+      this.warn(err);
+    }
+  }
+
+  async invalidate(namespace: string): Promise<void> {
+    try {
+      await this.redis.command(['INCR', `cache:${namespace}:version`]);
+    } catch (err) {
+      this.warn(err);
+    }
+  }
+
+  async ping(): Promise<void> {
+    const reply = await this.redis.command(['PING']);
+    if (reply !== 'PONG') throw new Error(`Unexpected PING reply: ${reply}`);
+  }
+}
+
+function createCache(): CacheStore {
+  if (env.REDIS_URL) {
+    try {
+      return new RedisCache(new URL(env.REDIS_URL));
+    } catch {
+      console.warn('REDIS_URL is not a valid URL; using the in-memory response cache');
+    }
+  }
+  return new MemoryCache();
+}
+
+export const responseCache = createCache();
+
+/** Drops every cached response in the namespace; call after changing its data. */
+export function invalidateCache(namespace: string): void {
+  void responseCache.invalidate(namespace);
+}
@@ -0,0 +1,59 @@
+export const RESTAURANT_TIMEZONE = 'Asia/Jakarta';
+
+export interface LocalClock {
+  /** Calendar date in the restaurant timezone, YYYY-MM-DD */
+  date: string;
+  /** 0=Sunday … 6=Saturday, matching operating_hours.day_of_week */
+  dayOfWeek: number;
+  /** Seconds since local midnight */
+  secondsOfDay: number;
+}
+
+const WEEKDAYS = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
+
+/** Wall-clock reading of `at` in the restaurant's timezone (WIB by default). */
+export function localClock(at: Date = new Date(), timeZone = RESTAURANT_TIMEZONE): LocalClock {
+  const parts = new Intl.DateTimeFormat('en-US', {
+    timeZone,
+    year: 'numeric',
+    month: '2-digit',
+    day: '2-digit',
+    weekday: 'short',
+    hour: '2-digit',
+    minute: '2-digit',
+    second: '2-digit',
+    hourCycle: 'h23',
+  }).formatToParts(at);
+
+  const get = (type: string) => parts.find((p) => p.type === type)?.value ?? '';
+
+  return {
+    date: `${get('year')}-${get('month')}-${get('day')}`,
+    dayOfWeek: WEEKDAYS.indexOf(get('weekday')),
+    secondsOfDay: Number(get('hour')) * 3600 + Number(get('minute')) * 60 + Number(get('second')),
+  };
+}
+
+/** Parses HH:MM or HH:MM:SS into seconds since midnight. */
+export function timeToSeconds(time: string): number {
+  const [h, m, s] = time.split(':').map((p) => parseInt(p, 10) || 0);
+  return (h ?? 0) * 3600 + (m ?? 0) * 60 + (s ?? 0);
+}
+
+/**
+ * True when secondsOfDay falls inside [start, end). Windows whose end is
+ * before their start wrap past midnight (e.g. 21:00–02:00).
+ */
+export function inDailyWindow(secondsOfDay: number, start: string, end: string): boolean {
+  const s = timeToSeconds(start);
+  const e = timeToSeconds(end);
+  if (s === e) return true;
+  return s < e ? secondsOfDay >= s && secondsOfDay < e : secondsOfDay >= s || secondsOfDay < e;
+}
+
+/** Adds `days` (may be negative) to a YYYY-MM-DD calendar date. */
+export function addDays(date: string, days: number): string {
+  const d = new Date(`${date}T00:00:00Z`);
+  d.setUTCDate(d.getUTCDate() + days);
+  return d.toISOString().slice(0, 10);
+}
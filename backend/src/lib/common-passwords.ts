@@ -0,0 +1,24 @@
+// Passwords refused outright by the password policy, lower case. They are
+// compared after stripping the digits and symbols people tack on, so
+// "Password123!" counts as "password". The usual top of the leaked-password
+// lists, plus Indonesian favourites and the words staff reach for here.
+
+export const COMMON_PASSWORDS: ReadonlySet<string> = new Set([
+  '123456', '123456789', '12345678', '12345', '1234567', '1234567890', '111111', '000000',
+  '123123', '654321', '666666', '121212', '112233', '987654321', 'password', 'passw0rd',
+  'password1', 'password12', 'password123', 'p@ssw0rd', 'p@ssword', 'pass1234', 'letmein',
+  'welcome', 'welcome1', 'admin', 'admin123', 'administrator', 'root', 'toor', 'qwerty',
+  'qwerty123', 'qwertyuiop', 'asdfgh', 'asdfghjkl', 'zxcvbnm', '1q2w3e4r', '1qaz2wsx', 'qazwsx',
+  'abc123', 'abcd1234', 'iloveyou', 'monkey', 'dragon', 'master', 'shadow', 'sunshine', 'princess',
+  'football', 'baseball', 'superman', 'batman', 'trustno1', 'freedom', 'whatever', 'starwars',
+  'login', 'access', 'secret', 'changeme', 'default', 'guest', 'test', 'test123', 'user', 'demo',
+  'sample', 'temp', 'temp123', 'hello', 'hello123', 'michael', 'jennifer', 'jordan', 'hunter',
+  'ranger', 'buster', 'soccer', 'harley', 'charlie', 'thomas', 'daniel', 'andrew', 'jessica',
+  'ashley', 'killer', 'pepper', 'summer', 'winter', 'spring', 'autumn', 'flower', 'computer',
+  'internet', 'samsung', 'iphone', 'google', 'facebook', 'indonesia', 'jakarta', 'bandung',
+  'surabaya', 'bali', 'merdeka', 'garuda', 'bismillah', 'sayang', 'sayangku', 'cinta', 'cintaku',
+  'rahasia', 'katasandi', 'sandi', 'kasir', 'kasir123', 'pelayan', 'dapur', 'restoran',
+  'restaurant', 'resto', 'steak', 'steakhouse', 'beef', 'manager', 'manager123', 'server',
+  'server123', 'kitchen', 'kitchen123', 'counter', 'counter123', 'cashier', 'cashier123', 'waiter',
+  'staff', 'staff123', 'pos', 'pos123', 'possystem', 'modernsteak',
+]);
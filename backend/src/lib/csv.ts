@@ -0,0 +1,11 @@
+// CSV for spreadsheet and accounting imports: RFC 4180 quoting, CRLF line
+// endings, and a trailing newline.
+
+function csvField(value: unknown): string {
+  const s = value === null || value === undefined ? '' : String(value);
+  return /[",\r\n]/.test(s) ? `"${s.replace(/"/g, '""')}"` : s;
+}
+
+export function toCsv(rows: unknown[][]): string {
+  return rows.map((r) => r.map(csvField).join(',')).join('\r\n') + '\r\n';
+}
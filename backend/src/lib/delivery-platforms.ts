@@ -0,0 +1,90 @@
+import { env } from '../env.js';
+
+// Menu updates to the delivery platforms' merchant APIs (GoFood through
+// GoBiz, GrabFood through the Grab partner API). Only price and
+// availability are pushed; names, photos and modifiers stay managed in the
+// platforms' own merchant portals. Each platform is configured by API URL,
+// access token and outlet (merchant) ID.
+
+export const DELIVERY_PLATFORMS = ['gofood', 'grabfood'] as const;
+export type DeliveryPlatform = (typeof DELIVERY_PLATFORMS)[number];
+
+export interface PlatformItemUpdate {
+  platformItemId: string;
+  price: number;
+  available: boolean;
+}
+
+interface PlatformConfig {
+  apiUrl: string;
+  token: string;
+  outletId: string;
+}
+
+const REQUEST_TIMEOUT_MS = 10_000;
+// Grab takes prices in minor units
+const GRAB_PRICE_MULTIPLIER = 100;
+
+export function isDeliveryPlatform(value: unknown): value is DeliveryPlatform {
+  return DELIVERY_PLATFORMS.includes(value as DeliveryPlatform);
+}
+
+function platformConfig(platform: DeliveryPlatform): PlatformConfig {
+  return platform === 'gofood'
+    ? { apiUrl: env.GOFOOD_API_URL, token: env.GOFOOD_API_TOKEN, outletId: env.GOFOOD_OUTLET_ID }
+    : { apiUrl: env.GRABFOOD_API_URL, token: env.GRABFOOD_API_TOKEN, outletId: env.GRABFOOD_MERCHANT_ID };
+}
+
+export function platformConfigured(platform: DeliveryPlatform): boolean {
+  const config = platformConfig(platform);
+  return config.token !== '' && config.outletId !== '';
+}
+
+function buildRequest(platform: DeliveryPlatform, config: PlatformConfig, item: PlatformItemUpdate) {
+  const base = config.apiUrl.replace(/\/+$/, '');
+  if (platform === 'gofood') {
+    return {
+      method: 'PATCH',
+      url: `${base}/integrations/gofood/outlets/${encodeURIComponent(config.outletId)}/v1/menu_items/${encodeURIComponent(item.platformItemId)}`,
+      body: { price: Math.round(item.price), in_stock: item.available },
+    };
+  }
+  return {
+    method: 'PUT',
+    url: `${base}/partner/v1/menu`,
+    body: {
+      merchantID: config.outletId,
+      field: 'ITEM',
+      id: item.platformItemId,
+      price: Math.round(item.price * GRAB_PRICE_MULTIPLIER),
+      availableStatus: item.available ? 'AVAILABLE' : 'UNAVAILABLE',
+    },
+  };
+}
+
+// ── PushItemUpdate ──────────────────────────────────────────────────────────
+// Throws when the platform is unreachable or rejects the update.
+
+export async function pushItemUpdate(platform: DeliveryPlatform, item: PlatformItemUpdate): Promise<void> {
+  const config = platformConfig(platform);
+  if (!platformConfigured(platform)) {
+    throw new Error(`${platform} is not configured`);
+  }
+
+  const req = buildRequest(platform, config, item);
+  const res = await fetch(req.url, {
+    method: req.method,
+    headers: {
+      'Content-Type': 'application/json',
+      Accept: 'application/json',
+      Authorization: `Bearer ${config.token}`,
+    },
+    body: JSON.stringify(req.body),
+    signal: AbortSignal.timeout(REQUEST_TIMEOUT_MS),
+  });
+
+  if (!res.ok) {
+    const detail = await res.text().catch(() => '');
+    throw new Error(`${platform} rejected update of item ${item.platformItemId}: ${res.status} ${detail.slice(0, 200)}`);
+  }
+}
@@ -0,0 +1,103 @@
+import type { Queryable } from '../services/pricing.js';
+
+// Display formatting for amounts in API responses, so every screen and
+// receipt shows "Rp 185.000" the same way instead of each re-implementing
+// it. The locale and currency come from the default_language and currency
+// settings. Raw numbers stay in the payload; the formatted strings sit next
+// to them as <field>_formatted and are for display only. Non-breaking
+// spaces become plain ones, which the receipt printers' code pages lack.
+
+const DEFAULT_LOCALE = 'id-ID';
+const DEFAULT_CURRENCY = 'IDR';
+
+// Rupiah is handled in whole units everywhere, whatever ISO 4217 says
+const CURRENCY_DECIMALS: Record<string, number> = { IDR: 0 };
+
+export interface Formatter {
+  locale: string;
+  currency: string;
+  /** In the settings currency, or another one (a guest's display currency) */
+  money(amount: number, currency?: string, decimals?: number): string;
+  number(value: number, decimals?: number): string;
+  /** 11 → "11%" */
+  percent(rate: number): string;
+}
+
+const cache = new Map<string, Intl.NumberFormat>();
+
+function numberFormat(locale: string, options: Intl.NumberFormatOptions): Intl.NumberFormat {
+  const key = `${locale}|${JSON.stringify(options)}`;
+  let format = cache.get(key);
+  if (!format) {
+    format = new Intl.NumberFormat(locale, options);
+    cache.set(key, format);
+  }
+  return format;
+}
+
+function plainSpaces(text: string): string {
+  return text.replace(/[\u00a0\u202f]/g, ' ');
+}
+
+function supportedLocale(value: string): string {
+  try {
+    return Intl.NumberFormat.supportedLocalesOf(value)[0] ?? DEFAULT_LOCALE;
+  } catch {
+    return DEFAULT_LOCALE;
+  }
+}
+
+// ── CreateFormatter ─────────────────────────────────────────────────────────
+
+export function createFormatter(locale: string, currency: string): Formatter {
+  const resolvedLocale = supportedLocale(locale);
+  const code = currency.toUpperCase();
+  const settingsCurrency = /^[A-Z]{3}$/.test(code) ? code : DEFAULT_CURRENCY;
+
+  return {
+    locale: resolvedLocale,
+    currency: settingsCurrency,
+    money(amount, other = settingsCurrency, decimals) {
+      const fraction = decimals ?? CURRENCY_DECIMALS[other];
+      return plainSpaces(numberFormat(resolvedLocale, {
+        style: 'currency',
+        currency: other,
+        ...(fraction !== undefined ? { minimumFractionDigits: fraction, maximumFractionDigits: fraction } : {}),
+      }).format(amount));
+    },
+    number(value, decimals) {
+      return plainSpaces(numberFormat(resolvedLocale, {
+        ...(decimals !== undefined ? { minimumFractionDigits: decimals, maximumFractionDigits: decimals } : {}),
+      }).format(value));
+    },
+    percent(rate) {
+      return plainSpaces(numberFormat(resolvedLocale, { style: 'percent', maximumFractionDigits: 2 }).format(rate / 100));
+    },
+  };
+}
+
+export async function loadFormatter(q: Queryable): Promise<Formatter> {
+  const res = await q.query(
+    "SELECT setting_key, setting_value FROM system_settings WHERE setting_key IN ('default_language', 'currency')",
+  );
+  const settings = new Map<string, string>(res.rows.map((r) => [r.setting_key, String(r.setting_value ?? '').trim()]));
+  return createFormatter(settings.get('default_language') || DEFAULT_LOCALE, settings.get('currency') || DEFAULT_CURRENCY);
+}
+
+// ── WithFormatted ───────────────────────────────────────────────────────────
+// A copy of `row` with <field>_formatted next to each listed amount; null
+// stays null.
+
+export function withFormatted<T extends object>(
+  row: T,
+  fields: readonly string[],
+  format: (value: number) => string,
+): T & Record<`${string}_formatted`, string | null> {
+  const out: Record<string, unknown> = { ...row };
+  for (const field of fields) {
+    const value = (row as Record<string, unknown>)[field];
+    if (value === undefined) continue;
+    out[`${field}_formatted`] = value === null ? null : format(Number(value));
+  }
+  return out as T & Record<`${string}_formatted`, string | null>;
+}
@@ -0,0 +1,116 @@
+import fs from 'node:fs/promises';
+import { constants as fsConstants } from 'node:fs';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import { responseCache } from './cache.js';
+import { jobQueueStatus } from './jobs.js';
+import { pingSentry, sentryConfigured } from './sentry.js';
+
+// Health checks for /health. Every dependency reports `up`, `degraded`
+// (working, but someone should look), `down`, or `disabled` when it isn't
+// configured on this instance. The database is the only dependency the API
+// can't serve without, so only it being down makes the instance unhealthy
+// (503); anything else down or degraded makes it degraded.
+
+export type CheckStatus = 'up' | 'degraded' | 'down' | 'disabled';
+
+export interface CheckResult {
+  status: CheckStatus;
+  latency_ms?: number;
+  error?: string;
+  [detail: string]: unknown;
+}
+
+const CHECK_TIMEOUT_MS = 3000;
+// Slower than this and requests are noticeably waiting on the database
+const DB_SLOW_MS = 500;
+
+export const BUILD = {
+  version: env.BUILD_VERSION || env.SENTRY_RELEASE || '1.0.0',
+  commit: env.BUILD_COMMIT || null,
+  started_at: new Date().toISOString(),
+};
+
+// A check that hangs is reported down instead of holding up the response
+async function timed(check: () => Promise<CheckResult>): Promise<CheckResult> {
+  const start = Date.now();
+  let timer: ReturnType<typeof setTimeout> | undefined;
+  try {
+    const result = await Promise.race([
+      check(),
+      new Promise<never>((_, reject) => {
+        timer = setTimeout(() => reject(new Error(`Timed out after ${CHECK_TIMEOUT_MS}ms`)), CHECK_TIMEOUT_MS);
+      }),
+    ]);
+    return { ...result, latency_ms: Date.now() - start };
+  } catch (err) {
+    return { status: 'down', latency_ms: Date.now() - start, error: (err as Error).message };
+  } finally {
+    clearTimeout(timer);
+  }
+}
+
+async function checkDatabase(): Promise<CheckResult> {
+  const start = Date.now();
+  await pool.query('SELECT 1');
+  return {
+    status: Date.now() - start > DB_SLOW_MS ? 'degraded' : 'up',
+    pool: { total: pool.totalCount, idle: pool.idleCount, waiting: pool.waitingCount },
+  };
+}
+
+async function checkUploads(dir: string): Promise<CheckResult> {
+  await fs.access(dir, fsConstants.W_OK);
+  const stats = await fs.statfs(dir);
+  const freeMb = Math.floor((stats.bavail * stats.bsize) / 1024 / 1024);
+  const totalMb = Math.floor((stats.blocks * stats.bsize) / 1024 / 1024);
+  const low = freeMb < env.HEALTH_MIN_FREE_DISK_MB;
+  return {
+    status: low ? 'degraded' : 'up',
+    ...(low && { error: `Only ${freeMb} MB free (minimum ${env.HEALTH_MIN_FREE_DISK_MB} MB)` }),
+    path: dir,
+    writable: true,
+    free_mb: freeMb,
+    total_mb: totalMb,
+  };
+}
+
+async function checkSentry(): Promise<CheckResult> {
+  if (!sentryConfigured()) return { status: 'disabled' };
+  await pingSentry(CHECK_TIMEOUT_MS);
+  return { status: 'up' };
+}
+
+async function checkCache(): Promise<CheckResult> {
+  await responseCache.ping();
+  return { status: 'up', backend: responseCache.backend };
+}
+
+async function checkQueue(): Promise<CheckResult> {
+  const queue = await jobQueueStatus(pool);
+  return {
+    status: queue.overdue > 0 ? 'degraded' : 'up',
+    ...(queue.overdue > 0 && { error: `${queue.overdue} jobs are more than a minute overdue` }),
+    ...queue,
+  };
+}
+
+// ── RunHealthChecks ─────────────────────────────────────────────────────────
+
+export async function runHealthChecks(options: { uploadsDir: string }) {
+  const [database, uploads, sentry, cache, queue] = await Promise.all([
+    timed(checkDatabase),
+    timed(() => checkUploads(options.uploadsDir)),
+    timed(checkSentry),
+    timed(checkCache),
+    timed(checkQueue),
+  ]);
+  const checks: Record<string, CheckResult> = { database, uploads, sentry, cache, queue };
+
+  const status = database.status === 'down'
+    ? 'unhealthy'
+    : Object.values(checks).some((check) => check.status === 'down' || check.status === 'degraded')
+      ? 'degraded'
+      : 'healthy';
+  return { status, checks };
+}
@@ -0,0 +1,270 @@
+import os from 'node:os';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import type { Queryable } from '../services/pricing.js';
+import { jobsProcessedTotal } from './metrics.js';
+
+// Background job queue, stored in the jobs table. Work that talks to other
+// systems (mail, alerts) is enqueued instead of run inline, so requests don't
+// wait on it and a failure is retried with backoff instead of being lost.
+// Enqueueing with a transaction's client makes the job part of that
+// transaction: it only exists if the transaction commits.
+//
+// Every backend instance runs a worker. Jobs are claimed with
+// FOR UPDATE SKIP LOCKED, so each job runs on one instance at a time.
+//
+// Each failed attempt is appended to jobs.errors. A job that runs out of
+// attempts is copied to dead_letters, where an admin can read why it failed
+// and replay it (services/dead-letters.ts).
+
+export interface JobAttempt {
+  /** 1-based */
+  attempt: number;
+  maxAttempts: number;
+}
+
+// eslint-disable-next-line @typescript-eslint/no-explicit-any
+export type JobHandler = (payload: any, attempt: JobAttempt) => Promise<void>;
+
+export interface EnqueueOptions {
+  /** Earliest time to run; defaults to now */
+  runAt?: Date;
+  maxAttempts?: number;
+}
+
+const handlers = new Map<string, JobHandler>();
+
+const BASE_BACKOFF_MS = 30_000;
+const MAX_BACKOFF_MS = 60 * 60_000;
+// A running job not finished after this is assumed lost with its instance
+const STALE_AFTER_MINUTES = 10;
+
+// The attempt history kept on a job; older entries are dropped
+const MAX_ERROR_HISTORY = 20;
+
+const workerId = `${os.hostname()}:${process.pid}`;
+let timer: ReturnType<typeof setInterval> | null = null;
+let active = 0;
+let polling = false;
+
+export function registerJobHandler(type: string, handler: JobHandler): void {
+  handlers.set(type, handler);
+}
+
+export async function enqueueJob(
+  q: Queryable,
+  type: string,
+  payload: Record<string, unknown>,
+  options: EnqueueOptions = {},
+): Promise<string> {
+  const res = await q.query(
+    `INSERT INTO jobs (type, payload, run_at, max_attempts)
+     VALUES ($1, $2, COALESCE($3, NOW()), COALESCE($4, 5))
+     RETURNING id`,
+    [type, JSON.stringify(payload), options.runAt ?? null, options.maxAttempts ?? null],
+  );
+  return res.rows[0].id;
+}
+
+/** 30s, 1m, 2m, 4m … capped at an hour. */
+export function backoffMs(attempts: number): number {
+  return Math.min(BASE_BACKOFF_MS * 2 ** Math.max(0, attempts - 1), MAX_BACKOFF_MS);
+}
+
+interface ClaimedJob {
+  id: string;
+  type: string;
+  payload: Record<string, unknown>;
+  attempts: number;
+  max_attempts: number;
+}
+
+// Only types this instance has a handler for are claimed, so an older
+// instance mid-deploy leaves new job types to the instances that know them
+async function claimJob(): Promise<ClaimedJob | null> {
+  const res = await pool.query(
+    `UPDATE jobs
+     SET status = 'running', attempts = attempts + 1, locked_at = NOW(), locked_by = $1, updated_at = NOW()
+     WHERE id = (
+       SELECT id FROM jobs
+       WHERE status = 'pending' AND run_at <= NOW() AND type = ANY($2::text[])
+       ORDER BY run_at ASC
+       LIMIT 1
+       FOR UPDATE SKIP LOCKED
+     )
+     RETURNING id, type, payload, attempts, max_attempts`,
+    [workerId, [...handlers.keys()]],
+  );
+  return res.rows[0] ?? null;
+}
+
+// SQL for jobs.errors with one more failure; `attempt` and `error` are SQL
+// expressions for that attempt's number and message
+function appendError(attempt: string, error: string): string {
+  return `(CASE WHEN jsonb_array_length(errors) >= ${MAX_ERROR_HISTORY} THEN errors - 0 ELSE errors END)
+          || jsonb_build_array(jsonb_build_object('attempt', ${attempt}, 'error', ${error}, 'at', NOW()))`;
+}
+
+// Copies failed jobs to dead_letters; a job that was replayed and failed
+// again reopens its dead letter
+async function recordDeadLetters(jobIds: string[]): Promise<void> {
+  if (jobIds.length === 0) return;
+  await pool.query(
+    `INSERT INTO dead_letters (job_id, job_type, payload, max_attempts, attempts, last_error, errors)
+     SELECT id, type, payload, max_attempts, attempts, last_error, errors
+     FROM jobs WHERE id = ANY($1::uuid[]) AND status = 'failed'
+     ON CONFLICT (job_id) DO UPDATE
+     SET status = 'open', payload = EXCLUDED.payload, attempts = EXCLUDED.attempts,
+         last_error = EXCLUDED.last_error, errors = EXCLUDED.errors,
+         failure_count = dead_letters.failure_count + 1, last_failed_at = NOW(), updated_at = NOW()`,
+    [jobIds],
+  );
+}
+
+async function finishJob(job: ClaimedJob, error: string | null): Promise<void> {
+  if (!error) {
+    await pool.query(
+      `UPDATE jobs SET status = 'succeeded', locked_at = NULL, last_error = NULL, completed_at = NOW(), updated_at = NOW()
+       WHERE id = $1`,
+      [job.id],
+    );
+    return;
+  }
+
+  if (job.attempts >= job.max_attempts) {
+    await pool.query(
+      `UPDATE jobs
+       SET status = 'failed', locked_at = NULL, last_error = $2, errors = ${appendError('attempts', '$2::text')},
+           completed_at = NOW(), updated_at = NOW()
+       WHERE id = $1`,
+      [job.id, error],
+    );
+    await recordDeadLetters([job.id]);
+    return;
+  }
+
+  await pool.query(
+    `UPDATE jobs
+     SET status = 'pending', locked_at = NULL, last_error = $2, errors = ${appendError('attempts', '$2::text')},
+         run_at = NOW() + make_interval(secs => $3), updated_at = NOW()
+     WHERE id = $1`,
+    [job.id, error, backoffMs(job.attempts) / 1000],
+  );
+}
+
+async function runJob(job: ClaimedJob): Promise<void> {
+  const handler = handlers.get(job.type);
+  let error: string | null = null;
+  try {
+    if (!handler) throw new Error(`No handler for job type ${job.type}`);
+    await handler(job.payload, { attempt: job.attempts, maxAttempts: job.max_attempts });
+  } catch (err) {
+    error = (err as Error).message || String(err);
+  }
+
+  const outcome = !error ? 'succeeded' : job.attempts >= job.max_attempts ? 'failed' : 'retried';
+  jobsProcessedTotal.inc({ type: job.type, outcome });
+  if (error) {
+    console.error(`Job ${job.type} ${job.id} attempt ${job.attempts}/${job.max_attempts} failed:`, error);
+  }
+  await finishJob(job, error);
+}
+
+// Jobs left running by an instance that died go back to the queue; the
+// attempt they used counts towards max_attempts
+async function releaseStaleJobs(): Promise<void> {
+  const res = await pool.query(
+    `UPDATE jobs
+     SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'pending' END,
+         last_error = COALESCE(last_error, 'Worker stopped while running the job'),
+         errors = ${appendError('attempts', "'Worker stopped while running the job'::text")},
+         completed_at = CASE WHEN attempts >= max_attempts THEN NOW() ELSE NULL END,
+         locked_at = NULL, updated_at = NOW()
+     WHERE status = 'running' AND locked_at < NOW() - make_interval(mins => $1)
+     RETURNING id, status`,
+    [STALE_AFTER_MINUTES],
+  );
+  await recordDeadLetters(res.rows.filter((row) => row.status === 'failed').map((row) => row.id));
+}
+
+async function poll(): Promise<void> {
+  if (polling) return;
+  polling = true;
+  try {
+    await releaseStaleJobs();
+    while (timer && active < env.JOB_CONCURRENCY) {
+      const job = await claimJob();
+      if (!job) return;
+      active++;
+      void runJob(job)
+        .catch((err) => console.error(`Job ${job.id} could not be recorded:`, (err as Error).message))
+        .finally(() => { active--; });
+    }
+  } catch (err) {
+    // Database unavailable; retried on the next poll
+    console.error('Job worker poll failed:', (err as Error).message);
+  } finally {
+    polling = false;
+  }
+}
+
+export function startJobWorker(): void {
+  if (timer || handlers.size === 0) return;
+  timer = setInterval(() => void poll(), env.JOB_POLL_INTERVAL_MS);
+  void poll();
+  console.log(`Job worker started (${workerId}): ${[...handlers.keys()].join(', ')}`);
+}
+
+/** Stops claiming jobs and waits for the ones already running. */
+export async function stopJobWorker(): Promise<void> {
+  if (timer) {
+    clearInterval(timer);
+    timer = null;
+  }
+  while (active > 0) {
+    await new Promise((resolve) => setTimeout(resolve, 100));
+  }
+}
+
+// ── JobQueueStatus ──────────────────────────────────────────────────────────
+// For the health check. `overdue` counts pending jobs that should have run a
+// minute ago or more, which means no worker is keeping up.
+
+export async function jobQueueStatus(q: Queryable) {
+  const res = await q.query(
+    `SELECT COUNT(*) FILTER (WHERE status = 'pending')::int AS pending,
+            COUNT(*) FILTER (WHERE status = 'pending' AND run_at < NOW() - INTERVAL '1 minute')::int AS overdue,
+            COUNT(*) FILTER (WHERE status = 'running')::int AS running,
+            COUNT(*) FILTER (WHERE status = 'failed' AND completed_at > NOW() - INTERVAL '1 hour')::int AS failed_last_hour,
+            EXTRACT(EPOCH FROM NOW() - MIN(run_at) FILTER (WHERE status = 'pending' AND run_at <= NOW()))::float8
+              AS oldest_due_seconds,
+            (SELECT COUNT(*) FROM dead_letters WHERE status = 'open')::int AS dead_letters
+     FROM jobs
+     WHERE status IN ('pending', 'running') OR (status = 'failed' AND completed_at > NOW() - INTERVAL '1 hour')`,
+  );
+  const row = res.rows[0];
+  return {
+    worker: { running: timer !== null, id: workerId, active, concurrency: env.JOB_CONCURRENCY },
+    pending: row.pending as number,
+    overdue: row.overdue as number,
+    running: row.running as number,
+    failed_last_hour: row.failed_last_hour as number,
+    // Waiting to be replayed or discarded
+    dead_letters: row.dead_letters as number,
+    oldest_due_seconds: row.oldest_due_seconds === null ? null : Math.round(row.oldest_due_seconds),
+  };
+}
+
+// ── PurgeFinishedJobs ───────────────────────────────────────────────────────
+// Succeeded jobs are only kept for a while; failed ones stay until retried
+// or looked at.
+
+export const JOBS_PURGE_JOB = 'jobs_purge';
+
+export async function purgeFinishedJobs(q: Queryable, olderThanDays: number): Promise<number> {
+  const res = await q.query(
+    `DELETE FROM jobs WHERE status = 'succeeded' AND completed_at < NOW() - make_interval(days => $1)`,
+    [olderThanDays],
+  );
+  return res.rowCount ?? 0;
+}
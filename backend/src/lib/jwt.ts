@@ -5,16 +5,24 @@ export interface JWTClaims {
   user_id: string;
   username: string;
   role: string;
+  /** Branch the user works at; null for head office. Absent in tokens issued before branches existed. */
+  branch_id?: string | null;
+  /** users.token_version when issued; absent (0) in tokens issued before it existed */
+  token_version?: number;
   iss: string;
   iat: number;
   exp: number;
 }
 
-export function generateToken(user: { id: string; username: string; role: string }): string {
+export function generateToken(user: {
+  id: string; username: string; role: string; branchId?: string | null; tokenVersion: number;
+}): string {
   const payload = {
     user_id: user.id,
     username: user.username,
     role: user.role,
+    branch_id: user.branchId ?? null,
+    token_version: user.tokenVersion,
   };
   return jwt.sign(payload, env.JWT_SECRET, {
     expiresIn: '24h',
@@ -0,0 +1,72 @@
+// Process lifecycle: tracks in-flight requests and shutdown hooks so the
+// server can drain cleanly on SIGTERM/SIGINT.
+
+type ShutdownHook = {
+  name: string;
+  fn: () => Promise<void> | void;
+};
+
+let shuttingDown = false;
+let inFlight = 0;
+const hooks: ShutdownHook[] = [];
+const drainWaiters: Array<() => void> = [];
+
+export function isShuttingDown(): boolean {
+  return shuttingDown;
+}
+
+export function markShuttingDown(): void {
+  shuttingDown = true;
+}
+
+export function inFlightRequests(): number {
+  return inFlight;
+}
+
+export function requestStarted(): void {
+  inFlight++;
+}
+
+export function requestFinished(): void {
+  inFlight = Math.max(0, inFlight - 1);
+  if (inFlight === 0) {
+    while (drainWaiters.length > 0) {
+      drainWaiters.shift()!();
+    }
+  }
+}
+
+/**
+ * Resolves once all in-flight requests have completed, or false after
+ * timeoutMs if some are still running.
+ */
+export function waitForDrain(timeoutMs: number): Promise<boolean> {
+  if (inFlight === 0) return Promise.resolve(true);
+
+  return new Promise((resolve) => {
+    const timer = setTimeout(() => resolve(false), timeoutMs);
+    drainWaiters.push(() => {
+      clearTimeout(timer);
+      resolve(true);
+    });
+  });
+}
+
+/**
+ * Registers cleanup to run during shutdown after HTTP traffic has drained.
+ * Hooks run in reverse registration order (last registered, first closed).
+ */
+export function onShutdown(name: string, fn: () => Promise<void> | void): void {
+  hooks.push({ name, fn });
+}
+
+export async function runShutdownHooks(): Promise<void> {
+  for (const hook of [...hooks].reverse()) {
+    try {
+      await hook.fn();
+      console.log(`Shutdown: ${hook.name} closed`);
+    } catch (err) {
+      console.error(`Shutdown: ${hook.name} failed:`, (err as Error).message);
+    }
+  }
+}
@@ -0,0 +1,209 @@
+import net from 'node:net';
+import tls from 'node:tls';
+import os from 'node:os';
+import { randomUUID } from 'node:crypto';
+import { env } from '../env.js';
+
+// Minimal SMTP client for transactional mail (digests, summaries). Supports
+// implicit TLS (port 465), STARTTLS and AUTH LOGIN — enough for Gmail,
+// Mailgun, SES and most hosting providers without pulling in a dependency.
+// Plain-text bodies only. The server settings come from the caller
+// (services/email.ts merges the admin settings over the SMTP_* env vars).
+
+export interface MailMessage {
+  to: string[];
+  subject: string;
+  text: string;
+}
+
+interface SmtpReply {
+  code: number;
+  lines: string[];
+}
+
+export interface SmtpConfig {
+  host: string;
+  port: number;
+  secure: boolean;
+  user: string;
+  password: string;
+  from: string;
+}
+
+export function envSmtpConfig(): SmtpConfig {
+  return {
+    host: env.SMTP_HOST,
+    port: env.SMTP_PORT,
+    secure: env.SMTP_SECURE,
+    user: env.SMTP_USER,
+    password: env.SMTP_PASSWORD,
+    from: env.SMTP_FROM,
+  };
+}
+
+export function smtpConfigured(config: SmtpConfig): boolean {
+  return config.host !== '' && config.from !== '';
+}
+
+class SmtpSession {
+  private socket!: net.Socket;
+  private buffer = '';
+  private waiting: { resolve: (r: SmtpReply) => void; reject: (e: Error) => void } | null = null;
+  private failure: Error | null = null;
+
+  attach(socket: net.Socket): void {
+    this.socket = socket;
+    this.buffer = '';
+    socket.setTimeout(env.SMTP_TIMEOUT_MS);
+    socket.on('data', (chunk: Buffer) => {
+      this.buffer += chunk.toString('utf8');
+      this.flush();
+    });
+    socket.on('timeout', () => this.fail(new Error('SMTP connection timed out')));
+    socket.on('error', (err) => this.fail(err));
+  }
+
+  private fail(err: Error): void {
+    this.failure = err;
+    this.socket.destroy();
+    if (this.waiting) {
+      this.waiting.reject(err);
+      this.waiting = null;
+    }
+  }
+
+  // A reply is complete at the first "NNN " line; "NNN-" lines continue it
+  private flush(): void {
+    if (!this.waiting) return;
+    const lines = this.buffer.split('\r\n');
+    for (let i = 0; i < lines.length - 1; i++) {
+      if (/^\d{3} /.test(lines[i]) || /^\d{3}$/.test(lines[i])) {
+        const reply = { code: Number(lines[i].slice(0, 3)), lines: lines.slice(0, i + 1).map((l) => l.slice(4)) };
+        this.buffer = lines.slice(i + 1).join('\r\n');
+        const { resolve } = this.waiting;
+        this.waiting = null;
+        resolve(reply);
+        return;
+      }
+    }
+  }
+
+  read(): Promise<SmtpReply> {
+    if (this.failure) return Promise.reject(this.failure);
+    return new Promise((resolve, reject) => {
+      this.waiting = { resolve, reject };
+      this.flush();
+    });
+  }
+
+  /** `label` names the command in errors, so credentials never reach the logs */
+  async command(line: string, expect: number[], label = line.split(' ')[0]): Promise<SmtpReply> {
+    this.socket.write(`${line}\r\n`);
+    const reply = await this.read();
+    if (!expect.includes(reply.code)) {
+      throw new Error(`SMTP ${label} failed: ${reply.code} ${reply.lines.join(' ')}`);
+    }
+    return reply;
+  }
+
+  /** Hands the plain socket over for a STARTTLS upgrade */
+  detach(): net.Socket {
+    this.socket.removeAllListeners('data');
+    this.socket.removeAllListeners('timeout');
+    this.socket.removeAllListeners('error');
+    this.socket.setTimeout(0);
+    return this.socket;
+  }
+
+  close(): void {
+    this.socket.end();
+  }
+}
+
+function connect(config: SmtpConfig): Promise<net.Socket> {
+  return new Promise((resolve, reject) => {
+    const onError = (err: Error) => reject(err);
+    const socket = config.secure
+      ? tls.connect({ host: config.host, port: config.port, servername: config.host }, () => resolve(socket))
+      : net.connect({ host: config.host, port: config.port }, () => resolve(socket));
+    socket.once('error', onError);
+    socket.setTimeout(env.SMTP_TIMEOUT_MS, () => socket.destroy(new Error('SMTP connection timed out')));
+  });
+}
+
+function upgrade(socket: net.Socket, config: SmtpConfig): Promise<net.Socket> {
+  return new Promise((resolve, reject) => {
+    const secure = tls.connect({ socket, servername: config.host }, () => resolve(secure));
+    secure.once('error', reject);
+  });
+}
+
+function encodeHeader(value: string): string {
+  return /^[\x20-\x7e]*$/.test(value) ? value : `=?UTF-8?B?${Buffer.from(value, 'utf8').toString('base64')}?=`;
+}
+
+function buildMessage(msg: MailMessage, config: SmtpConfig): string {
+  const body = Buffer.from(msg.text, 'utf8').toString('base64').replace(/.{76}/g, '$&\r\n');
+  const domain = config.from.replace(/>.*$/, '').split('@')[1] || os.hostname();
+  return [
+    `From: ${config.from}`,
+    `To: ${msg.to.join(', ')}`,
+    `Subject: ${encodeHeader(msg.subject)}`,
+    `Date: ${new Date().toUTCString()}`,
+    `Message-ID: <${randomUUID()}@${domain}>`,
+    'MIME-Version: 1.0',
+    'Content-Type: text/plain; charset=utf-8',
+    'Content-Transfer-Encoding: base64',
+    '',
+    body,
+  ].join('\r\n');
+}
+
+// ── SendMail ────────────────────────────────────────────────────────────────
+// Throws on any SMTP error; callers decide whether a failed send matters.
+
+export async function sendMail(msg: MailMessage, config: SmtpConfig = envSmtpConfig()): Promise<void> {
+  if (!smtpConfigured(config)) {
+    throw new Error('SMTP is not configured (SMTP host / from address)');
+  }
+  if (msg.to.length === 0) {
+    throw new Error('No recipients');
+  }
+
+  const session = new SmtpSession();
+  session.attach(await connect(config));
+
+  try {
+    let reply = await session.read();
+    if (reply.code !== 220) throw new Error(`SMTP greeting failed: ${reply.code} ${reply.lines.join(' ')}`);
+
+    const hello = `EHLO ${os.hostname()}`;
+    reply = await session.command(hello, [250]);
+
+    if (!config.secure && reply.lines.some((l) => l.toUpperCase().startsWith('STARTTLS'))) {
+      await session.command('STARTTLS', [220]);
+      session.attach(await upgrade(session.detach(), config));
+      await session.command(hello, [250]);
+    }
+
+    if (config.user) {
+      await session.command('AUTH LOGIN', [334]);
+      await session.command(Buffer.from(config.user).toString('base64'), [334], 'AUTH username');
+      await session.command(Buffer.from(config.password).toString('base64'), [235], 'AUTH password');
+    }
+
+    await session.command(`MAIL FROM:<${config.from.replace(/^.*<|>.*$/g, '')}>`, [250]);
+    for (const rcpt of msg.to) {
+      await session.command(`RCPT TO:<${rcpt}>`, [250, 251]);
+    }
+    await session.command('DATA', [354]);
+
+    // Dot-stuffing: a leading "." on any line would end the message early
+    const data = buildMessage(msg, config).replace(/\r\n\./g, '\r\n..');
+    await session.command(`${data}\r\n.`, [250], 'message');
+    await session.command('QUIT', [221]).catch(() => undefined);
+  } finally {
+    session.close();
+  }
+}
+
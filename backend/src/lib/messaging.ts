@@ -0,0 +1,51 @@
+import { env } from '../env.js';
+
+// Outbound WhatsApp/SMS through an HTTP messaging gateway. Most Indonesian
+// providers (and Twilio-style relays) accept a JSON POST with a recipient
+// and text, so the gateway is configured by URL and bearer token rather than
+// a vendor SDK.
+
+export interface TextMessage {
+  to: string;
+  text: string;
+}
+
+export function messagingConfigured(): boolean {
+  return env.MESSAGING_API_URL !== '' && env.MESSAGING_API_TOKEN !== '';
+}
+
+/** Indonesian numbers in international form without "+": 0812… → 62812… */
+export function toInternationalPhone(phone: string): string {
+  const digits = phone.replace(/\D/g, '');
+  return digits.startsWith('0') ? `62${digits.slice(1)}` : digits;
+}
+
+// ── SendTextMessage ─────────────────────────────────────────────────────────
+// Throws when the gateway is unreachable or rejects the message.
+
+export async function sendTextMessage(msg: TextMessage): Promise<void> {
+  if (!messagingConfigured()) {
+    throw new Error('Messaging is not configured (MESSAGING_API_URL / MESSAGING_API_TOKEN)');
+  }
+
+  const res = await fetch(env.MESSAGING_API_URL, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Accept: 'application/json',
+      Authorization: `Bearer ${env.MESSAGING_API_TOKEN}`,
+    },
+    body: JSON.stringify({
+      channel: env.MESSAGING_CHANNEL,
+      from: env.MESSAGING_SENDER || undefined,
+      to: toInternationalPhone(msg.to),
+      message: msg.text,
+    }),
+    signal: AbortSignal.timeout(env.MESSAGING_TIMEOUT_MS),
+  });
+
+  if (!res.ok) {
+    const detail = await res.text().catch(() => '');
+    throw new Error(`Messaging gateway rejected message: ${res.status} ${detail.slice(0, 200)}`);
+  }
+}
@@ -0,0 +1,204 @@
+// Minimal Prometheus instrumentation (text exposition format 0.0.4).
+// Metrics register themselves on creation; renderMetrics() serialises the
+// whole registry for the /metrics endpoint.
+
+type Labels = Record<string, string>;
+
+interface Metric {
+  name: string;
+  help: string;
+  type: 'counter' | 'gauge' | 'histogram';
+  render(): string[];
+}
+
+const registry: Metric[] = [];
+const collectors: Array<() => void> = [];
+
+function labelKey(labels: Labels): string {
+  return Object.keys(labels).sort().map((k) => `${k}=${labels[k]}`).join(',');
+}
+
+function escapeLabel(value: string): string {
+  return value.replace(/\\/g, '\\\\').replace(/\n/g, '\\n').replace(/"/g, '\\"');
+}
+
+function formatLabels(labels: Labels, extra?: Labels): string {
+  const all = { ...labels, ...extra };
+  const keys = Object.keys(all);
+  if (keys.length === 0) return '';
+  return `{${keys.map((k) => `${k}="${escapeLabel(all[k])}"`).join(',')}}`;
+}
+
+// ── Counter ─────────────────────────────────────────────────────────────────
+
+export class Counter implements Metric {
+  readonly type = 'counter';
+  private values = new Map<string, { labels: Labels; value: number }>();
+
+  constructor(readonly name: string, readonly help: string) {
+    registry.push(this);
+  }
+
+  inc(labels: Labels = {}, value = 1): void {
+    const key = labelKey(labels);
+    const entry = this.values.get(key);
+    if (entry) entry.value += value;
+    else this.values.set(key, { labels, value });
+  }
+
+  render(): string[] {
+    return [...this.values.values()].map((e) => `${this.name}${formatLabels(e.labels)} ${e.value}`);
+  }
+}
+
+// ── Gauge ───────────────────────────────────────────────────────────────────
+
+export class Gauge implements Metric {
+  readonly type = 'gauge';
+  private values = new Map<string, { labels: Labels; value: number }>();
+
+  constructor(readonly name: string, readonly help: string) {
+    registry.push(this);
+  }
+
+  set(value: number, labels: Labels = {}): void {
+    this.values.set(labelKey(labels), { labels, value });
+  }
+
+  render(): string[] {
+    return [...this.values.values()].map((e) => `${this.name}${formatLabels(e.labels)} ${e.value}`);
+  }
+}
+
+// ── Histogram ───────────────────────────────────────────────────────────────
+
+export class Histogram implements Metric {
+  readonly type = 'histogram';
+  private series = new Map<string, { labels: Labels; counts: number[]; sum: number; count: number }>();
+
+  constructor(readonly name: string, readonly help: string, private readonly buckets: number[]) {
+    registry.push(this);
+  }
+
+  observe(value: number, labels: Labels = {}): void {
+    const key = labelKey(labels);
+    let s = this.series.get(key);
+    if (!s) {
+      s = { labels, counts: new Array(this.buckets.length).fill(0), sum: 0, count: 0 };
+      this.series.set(key, s);
+    }
+    for (let i = 0; i < this.buckets.length; i++) {
+      if (value <= this.buckets[i]) s.counts[i]++;
+    }
+    s.sum += value;
+    s.count++;
+  }
+
+  render(): string[] {
+    const lines: string[] = [];
+    for (const s of this.series.values()) {
+      this.buckets.forEach((b, i) => {
+        lines.push(`${this.name}_bucket${formatLabels(s.labels, { le: String(b) })} ${s.counts[i]}`);
+      });
+      lines.push(`${this.name}_bucket${formatLabels(s.labels, { le: '+Inf' })} ${s.count}`);
+      lines.push(`${this.name}_sum${formatLabels(s.labels)} ${s.sum}`);
+      lines.push(`${this.name}_count${formatLabels(s.labels)} ${s.count}`);
+    }
+    return lines;
+  }
+}
+
+/**
+ * Registers a callback run before each scrape, for gauges sampled on demand
+ * (e.g. pool stats) rather than updated inline.
+ */
+export function onCollect(fn: () => void): void {
+  collectors.push(fn);
+}
+
+export function renderMetrics(): string {
+  for (const fn of collectors) fn();
+
+  const out: string[] = [];
+  for (const m of registry) {
+    out.push(`# HELP ${m.name} ${m.help}`);
+    out.push(`# TYPE ${m.name} ${m.type}`);
+    out.push(...m.render());
+  }
+  return out.join('\n') + '\n';
+}
+
+// ── Application metrics ─────────────────────────────────────────────────────
+
+export const httpRequestsTotal = new Counter(
+  'http_requests_total',
+  'Total HTTP requests by method, route and status code',
+);
+
+export const httpRequestDuration = new Histogram(
+  'http_request_duration_seconds',
+  'HTTP request latency by method and route',
+  [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10],
+);
+
+export const ordersCreatedTotal = new Counter(
+  'pos_orders_created_total',
+  'Orders created by order type and source',
+);
+
+export const paymentsProcessedTotal = new Counter(
+  'pos_payments_processed_total',
+  'Payments processed by method and status',
+);
+
+export const paymentsAmountTotal = new Counter(
+  'pos_payments_amount_idr_total',
+  'Sum of completed payment amounts in IDR by method',
+);
+
+export const paymentsRefundedTotal = new Counter(
+  'pos_payments_refunded_total',
+  'Refunds issued by payment method and reason',
+);
+
+export const orderStatusTransitionsTotal = new Counter(
+  'pos_order_status_transitions_total',
+  'Order status changes by target status',
+);
+
+export const kitchenTicketDuration = new Histogram(
+  'pos_kitchen_ticket_seconds',
+  'Time from order creation until the order is marked ready',
+  [60, 180, 300, 600, 900, 1200, 1800, 2700, 3600],
+);
+
+export const courseReadyDuration = new Histogram(
+  'pos_course_ready_seconds',
+  'Time from a course being fired until its last item is ready, by course number',
+  [60, 180, 300, 600, 900, 1200, 1800, 2700, 3600],
+);
+
+export const jobsProcessedTotal = new Counter(
+  'pos_jobs_processed_total',
+  'Background job attempts by job type and outcome (succeeded, retried, failed)',
+);
+
+export const reportRequestsTotal = new Counter(
+  'pos_report_requests_total',
+  'Report requests by outcome (computed, cache_hit, shared, rate_limited, busy)',
+);
+
+export const dbTransactionRetriesTotal = new Counter(
+  'pos_db_transaction_retries_total',
+  'Transactions run again after a serialization failure or deadlock, by reason',
+);
+
+export const inboundWebhooksTotal = new Counter(
+  'pos_inbound_webhooks_total',
+  'Inbound webhook callbacks by provider and outcome (accepted, duplicate, rejected)',
+);
+
+export const requestsRejectedTotal = new Counter(
+  'pos_requests_rejected_total',
+  'Requests refused before reaching their handler, by reason (body_too_large, upload_quota)',
+);
@@ -0,0 +1,115 @@
+import crypto from 'node:crypto';
+import { mkdir, writeFile } from 'node:fs/promises';
+import path from 'node:path';
+import { env } from '../env.js';
+
+// Object storage for the analytics export. `s3` talks to any S3-compatible
+// API (AWS S3, Cloudflare R2, MinIO, Google Cloud Storage with HMAC keys)
+// with path-style URLs and Signature Version 4; `file` writes under
+// ELT_EXPORT_DIR, for a mounted bucket or local testing. Keys use "/" as the
+// separator either way.
+
+const REQUEST_TIMEOUT_MS = 60_000;
+
+export function isObjectStorageConfigured(): boolean {
+  return env.ELT_EXPORT_TARGET !== 'off';
+}
+
+/** Where objects go, for display: a bucket URL or a directory. */
+export function objectStorageLocation(): string | null {
+  switch (env.ELT_EXPORT_TARGET) {
+    case 's3':
+      return `${env.ELT_S3_ENDPOINT.replace(/\/+$/, '')}/${env.ELT_S3_BUCKET}`;
+    case 'file':
+      return path.resolve(env.ELT_EXPORT_DIR);
+    default:
+      return null;
+  }
+}
+
+function sha256(data: string | Buffer): string {
+  return crypto.createHash('sha256').update(data).digest('hex');
+}
+
+function hmac(key: string | Buffer, data: string): Buffer {
+  return crypto.createHmac('sha256', key).update(data).digest();
+}
+
+// RFC 3986 encoding, which SigV4 expects in the canonical path
+function encodeSegment(segment: string): string {
+  return encodeURIComponent(segment).replace(/[!'()*]/g, (ch) => `%${ch.charCodeAt(0).toString(16).toUpperCase()}`);
+}
+
+async function putS3(key: string, body: Buffer, contentType: string): Promise<void> {
+  const endpoint = new URL(env.ELT_S3_ENDPOINT);
+  const basePath = endpoint.pathname.replace(/\/+$/, '');
+  const canonicalPath = `${basePath}/${encodeSegment(env.ELT_S3_BUCKET)}/${key.split('/').map(encodeSegment).join('/')}`;
+
+  const amzDate = new Date().toISOString().replace(/[-:]/g, '').replace(/\.\d+Z$/, 'Z');
+  const day = amzDate.slice(0, 8);
+  const scope = `${day}/${env.ELT_S3_REGION}/s3/aws4_request`;
+  const payloadHash = sha256(body);
+
+  const headers: Record<string, string> = {
+    'content-type': contentType,
+    host: endpoint.host,
+    'x-amz-content-sha256': payloadHash,
+    'x-amz-date': amzDate,
+  };
+  const signedHeaders = Object.keys(headers).join(';');
+  const canonicalRequest = [
+    'PUT',
+    canonicalPath,
+    '',
+    ...Object.entries(headers).map(([name, value]) => `${name}:${value}`),
+    '',
+    signedHeaders,
+    payloadHash,
+  ].join('\n');
+  const stringToSign = ['AWS4-HMAC-SHA256', amzDate, scope, sha256(canonicalRequest)].join('\n');
+
+  const signingKey = ['s3', 'aws4_request'].reduce(
+    (k, part) => hmac(k, part),
+    hmac(hmac(`AWS4${env.ELT_S3_SECRET_ACCESS_KEY}`, day), env.ELT_S3_REGION),
+  );
+  const signature = crypto.createHmac('sha256', signingKey).update(stringToSign).digest('hex');
+
+  const res = await fetch(`${endpoint.origin}${canonicalPath}`, {
+    method: 'PUT',
+    headers: {
+      ...headers,
+      authorization: `AWS4-HMAC-SHA256 Credential=${env.ELT_S3_ACCESS_KEY_ID}/${scope}, SignedHeaders=${signedHeaders}, Signature=${signature}`,
+    },
+    body,
+    signal: AbortSignal.timeout(REQUEST_TIMEOUT_MS),
+  });
+  if (!res.ok) {
+    const detail = (await res.text().catch(() => '')).slice(0, 300);
+    throw new Error(`Object storage PUT ${key} failed with HTTP ${res.status}${detail ? `: ${detail}` : ''}`);
+  }
+}
+
+async function putFile(key: string, body: Buffer): Promise<void> {
+  const root = path.resolve(env.ELT_EXPORT_DIR);
+  const target = path.resolve(root, ...key.split('/'));
+  if (!target.startsWith(root + path.sep)) {
+    throw new Error(`Object key ${key} is outside ELT_EXPORT_DIR`);
+  }
+  await mkdir(path.dirname(target), { recursive: true });
+  await writeFile(target, body);
+}
+
+// ── PutObject ───────────────────────────────────────────────────────────────
+// Writes an object, replacing one with the same key. Throws when storage is
+// off or the write fails.
+
+export async function putObject(key: string, body: Buffer, contentType: string): Promise<void> {
+  switch (env.ELT_EXPORT_TARGET) {
+    case 's3':
+      return putS3(key, body, contentType);
+    case 'file':
+      return putFile(key, body);
+    default:
+      throw new Error('Object storage is not configured (ELT_EXPORT_TARGET=off)');
+  }
+}
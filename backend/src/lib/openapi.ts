@@ -0,0 +1,180 @@
+// OpenAPI 3 document generated from the registered Hono routes, so every
+// route is listed without keeping a spec file in sync by hand. From the
+// route table alone we know the method, path and parameters, whether the
+// route sits behind authMiddleware, and the permission requirePermission
+// checks (it tags its middleware). Routes can add a summary, query
+// parameters, a request body or the paginated response shape with
+// documentRoute.
+
+export interface RouteEntry {
+  method: string;
+  path: string;
+  handler: unknown;
+}
+
+type Schema = Record<string, unknown>;
+
+export interface RouteDoc {
+  summary?: string;
+  description?: string;
+  query?: Record<string, string>;
+  body?: Schema;
+  /** Responds with PaginatedResponse instead of APIResponse */
+  paginated?: boolean;
+}
+
+const docs = new Map<string, RouteDoc>();
+
+/** Adds detail to a route's entry; `path` is the full path, e.g. /api/v1/orders. */
+export function documentRoute(method: string, path: string, doc: RouteDoc): void {
+  docs.set(`${method.toUpperCase()} ${path}`, doc);
+}
+
+// Route groups whose second segment names the resource, e.g. /admin/products
+const GROUP_PREFIXES = ['admin', 'public', 'customer', 'kitchen'];
+
+const COMPONENTS = {
+  securitySchemes: {
+    bearerAuth: { type: 'http', scheme: 'bearer', bearerFormat: 'JWT' },
+  },
+  schemas: {
+    APIResponse: {
+      type: 'object',
+      required: ['success', 'message'],
+      properties: {
+        success: { type: 'boolean' },
+        message: { type: 'string' },
+        data: {},
+        error: { type: 'string', description: 'Machine-readable error code' },
+        request_id: { type: 'string', description: 'Set on errors; matches the X-Request-ID header' },
+      },
+    },
+    PageMeta: {
+      type: 'object',
+      properties: {
+        current_page: { type: 'integer' },
+        per_page: { type: 'integer' },
+        total: { type: 'integer' },
+        total_pages: { type: 'integer' },
+        next_cursor: { type: 'string', nullable: true },
+      },
+    },
+    CursorMeta: {
+      type: 'object',
+      properties: {
+        per_page: { type: 'integer' },
+        next_cursor: { type: 'string', nullable: true },
+        has_more: { type: 'boolean' },
+      },
+    },
+    PaginatedResponse: {
+      type: 'object',
+      required: ['success', 'message', 'data', 'meta'],
+      properties: {
+        success: { type: 'boolean' },
+        message: { type: 'string' },
+        data: { type: 'array', items: {} },
+        meta: { oneOf: [{ $ref: '#/components/schemas/PageMeta' }, { $ref: '#/components/schemas/CursorMeta' }] },
+      },
+    },
+  },
+};
+
+const ref = (name: string) => ({ $ref: `#/components/schemas/${name}` });
+
+// getOrderStatusHistory → "Get order status history"
+function summaryFromName(name: string): string {
+  const words = name.replace(/([a-z0-9])([A-Z])/g, '$1 $2').toLowerCase();
+  return words.charAt(0).toUpperCase() + words.slice(1);
+}
+
+function tagFor(path: string, basePath: string): string {
+  const segments = path.slice(basePath.length).split('/').filter(Boolean);
+  if (GROUP_PREFIXES.includes(segments[0]) && segments[1] && !segments[1].startsWith(':')) {
+    return `${segments[0]}/${segments[1]}`;
+  }
+  return segments[0] ?? 'root';
+}
+
+function errorResponse(description: string) {
+  return { description, content: { 'application/json': { schema: ref('APIResponse') } } };
+}
+
+// ── BuildOpenApiDocument ────────────────────────────────────────────────────
+// `authMiddleware` identifies the protected groups: a route is secured when
+// a `use('*', authMiddleware)` entry covers its path.
+
+export function buildOpenApiDocument(
+  routes: RouteEntry[],
+  opts: { title: string; version: string; basePath: string; authMiddleware: unknown },
+) {
+  const securedPrefixes = routes
+    .filter((r) => r.handler === opts.authMiddleware && r.path.endsWith('*'))
+    .map((r) => r.path.slice(0, -1));
+
+  // Middleware and handler of one route are separate entries; the handler
+  // is the last one registered for the method and path
+  const grouped = new Map<string, RouteEntry[]>();
+  for (const route of routes) {
+    if (route.method === 'ALL' || route.path.includes('*') || !route.path.startsWith(opts.basePath)) continue;
+    const key = `${route.method} ${route.path}`;
+    grouped.set(key, [...(grouped.get(key) ?? []), route]);
+  }
+
+  const paths: Record<string, Record<string, unknown>> = {};
+  for (const [key, entries] of grouped) {
+    const { method, path } = entries[0];
+    const handler = entries[entries.length - 1].handler as { name?: string };
+    const permissions = entries
+      .map((e) => (e.handler as { permission?: string }).permission)
+      .filter((p): p is string => !!p);
+    const secured = securedPrefixes.some((prefix) => path.startsWith(prefix));
+    const doc = docs.get(key) ?? {};
+
+    const parameters: Schema[] = [];
+    const openApiPath = path.replace(/:([A-Za-z0-9_]+)/g, (_, name: string) => {
+      parameters.push({ name, in: 'path', required: true, schema: { type: 'string' } });
+      return `{${name}}`;
+    });
+    for (const [name, description] of Object.entries(doc.query ?? {})) {
+      parameters.push({ name, in: 'query', required: false, description, schema: { type: 'string' } });
+    }
+
+    const responses: Record<string, unknown> = {
+      200: {
+        description: 'Success',
+        content: { 'application/json': { schema: ref(doc.paginated ? 'PaginatedResponse' : 'APIResponse') } },
+      },
+      400: errorResponse('Invalid request'),
+    };
+    if (secured) responses[401] = errorResponse('Missing or invalid token');
+    if (permissions.length > 0) {
+      responses[403] = errorResponse(`Requires the ${permissions.join(' and ')} permission${permissions.length > 1 ? 's' : ''}`);
+    }
+
+    const operation: Record<string, unknown> = {
+      tags: [tagFor(path, opts.basePath)],
+      summary: doc.summary ?? (handler.name ? summaryFromName(handler.name) : `${method} ${path}`),
+      operationId: handler.name || undefined,
+      description: [doc.description, permissions.length > 0 && `Permission: ${permissions.map((p) => `\`${p}\``).join(', ')}`].filter(Boolean).join('\n\n') || undefined,
+      parameters,
+      responses,
+    };
+    if (secured) operation.security = [{ bearerAuth: [] }];
+    if (doc.body || ['POST', 'PUT', 'PATCH'].includes(method)) {
+      operation.requestBody = {
+        content: { 'application/json': { schema: doc.body ?? { type: 'object' } } },
+      };
+    }
+
+    paths[openApiPath] = { ...paths[openApiPath], [method.toLowerCase()]: operation };
+  }
+
+  return {
+    openapi: '3.0.3',
+    info: { title: opts.title, version: opts.version },
+    servers: [{ url: '/' }],
+    paths,
+    components: COMPONENTS,
+  };
+}
@@ -1,16 +1,145 @@
-export function parsePagination(query: { page?: string; per_page?: string; limit?: string; offset?: string }) {
+type PaginationQuery = { page?: string; per_page?: string; limit?: string; offset?: string; include_total?: string };
+
+export interface Pagination {
+  page: number;
+  perPage: number;
+  offset: number;
+  /** false with ?include_total=false: the COUNT(*) is skipped and has_next comes from fetching one extra row */
+  includeTotal: boolean;
+}
+
+export function parsePagination(query: PaginationQuery): Pagination {
   const page = Math.max(1, Number(query.page || '1'));
   const perPage = Math.min(100, Math.max(1, Number(query.per_page || query.limit || '20')));
   const offset = query.offset !== undefined ? Number(query.offset) : (page - 1) * perPage;
+  const includeTotal = !['false', '0'].includes(query.include_total ?? '');
 
-  return { page, perPage, offset };
+  return { page, perPage, offset, includeTotal };
 }
 
-export function buildMeta(page: number, perPage: number, total: number) {
-  return {
+/** What a list was sorted and filtered by, echoed in its meta. */
+export interface ListContext {
+  sort?: string;
+  filters?: Record<string, string | number | boolean | null | undefined>;
+}
+
+export interface PageMeta {
+  current_page: number;
+  per_page: number;
+  /** null when the client asked to skip the count */
+  total: number | null;
+  total_pages: number | null;
+  has_next: boolean;
+  has_prev: boolean;
+  sort?: string;
+  filters?: Record<string, string | number | boolean>;
+  /** Where a keyset-paginated list continues after this page */
+  next_cursor?: string | null;
+}
+
+export interface CursorMeta {
+  per_page: number;
+  next_cursor: string | null;
+  has_more: boolean;
+  sort?: string;
+  filters?: Record<string, string | number | boolean>;
+}
+
+// Only the filters actually applied are reported
+function appliedFilters(filters: ListContext['filters']): Record<string, string | number | boolean> | undefined {
+  if (!filters) return undefined;
+  const applied: Record<string, string | number | boolean> = {};
+  for (const [key, value] of Object.entries(filters)) {
+    if (value !== undefined && value !== null && value !== '') applied[key] = value;
+  }
+  return applied;
+}
+
+function withContext<T extends object>(meta: T, context?: ListContext): T {
+  if (!context) return meta;
+  return { ...meta, sort: context.sort, filters: appliedFilters(context.filters) };
+}
+
+export function buildMeta(page: number, perPage: number, total: number, context?: ListContext): PageMeta {
+  const totalPages = Math.ceil(total / perPage);
+  return withContext({
     current_page: page,
     per_page: perPage,
     total,
-    total_pages: Math.ceil(total / perPage),
+    total_pages: totalPages,
+    has_next: page < totalPages,
+    has_prev: page > 1,
+  }, context);
+}
+
+export interface Page<T> {
+  rows: T[];
+  total: number | null;
+  hasNext: boolean;
+}
+
+// ── FetchPage ───────────────────────────────────────────────────────────────
+// Runs a list's count and page queries. `rows` is given the LIMIT to use:
+// one more than the page size, so has_next is known without the count,
+// which is skipped when the client passed ?include_total=false.
+
+export async function fetchPage<T>(
+  pagination: Pagination,
+  count: () => Promise<number>,
+  rows: (limit: number) => Promise<T[]>,
+): Promise<Page<T>> {
+  const [total, fetched] = await Promise.all([
+    pagination.includeTotal ? count() : Promise.resolve(null),
+    rows(pagination.perPage + 1),
+  ]);
+  return {
+    rows: fetched.slice(0, pagination.perPage),
+    total,
+    hasNext: fetched.length > pagination.perPage,
   };
 }
+
+export function pageMeta(pagination: Pagination, page: Page<unknown>, context?: ListContext): PageMeta {
+  const { page: current, perPage } = pagination;
+  return withContext({
+    current_page: current,
+    per_page: perPage,
+    total: page.total,
+    total_pages: page.total === null ? null : Math.ceil(page.total / perPage),
+    has_next: page.hasNext,
+    has_prev: current > 1,
+  }, context);
+}
+
+// Keyset pagination. A cursor is the sort key of the last row of a page,
+// opaque to clients; the next page is the rows after that key. Unlike OFFSET
+// it stays fast deep into a large table and doesn't skip or repeat rows when
+// new ones arrive between pages.
+
+export function encodeCursor(key: string[]): string {
+  return Buffer.from(JSON.stringify(key)).toString('base64url');
+}
+
+/** The key, or null for a cursor that isn't exactly `length` strings. */
+export function decodeCursor(cursor: string, length: number): string[] | null {
+  try {
+    const key = JSON.parse(Buffer.from(cursor, 'base64url').toString('utf8'));
+    if (!Array.isArray(key) || key.length !== length || !key.every((k) => typeof k === 'string')) return null;
+    return key;
+  } catch {
+    return null;
+  }
+}
+
+/** Postgres' text form of a timestamptz (`ts::text`), the usual sort key. */
+export function isTimestampKey(value: string): boolean {
+  return /^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(\.\d{1,6})?[+-]\d{2}(:\d{2})?$/.test(value);
+}
+
+export function buildCursorMeta(perPage: number, nextCursor: string | null, context?: ListContext): CursorMeta {
+  return withContext({
+    per_page: perPage,
+    next_cursor: nextCursor,
+    has_more: nextCursor !== null,
+  }, context);
+}
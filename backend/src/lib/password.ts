@@ -0,0 +1,154 @@
+import crypto from 'node:crypto';
+import type { Context } from 'hono';
+import { env } from '../env.js';
+import { validationErrorResponse } from './response.js';
+import { requestLocale, validationFieldError } from './validation-messages.js';
+import { COMMON_PASSWORDS } from './common-passwords.js';
+
+// Password policy, applied wherever a password is set: user creation and
+// updates, profile password changes and password resets. The rules come
+// from the PASSWORD_* settings. A failed check lists every rule the
+// password broke, not just the first, so the form can show them all.
+//
+// The breach check (PASSWORD_BREACH_CHECK) asks the Have I Been Pwned
+// range API whether the password appears in a known breach. Only the first
+// five characters of its SHA-1 hash leave the server. If the service can't
+// be reached the check is skipped, so an outage doesn't stop anyone setting
+// a password.
+
+const SPECIAL = /[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?~]/;
+const BREACH_API_URL = 'https://api.pwnedpasswords.com/range/';
+const BREACH_TIMEOUT_MS = 3000;
+
+export type PasswordRule =
+  | 'min_length'
+  | 'max_length'
+  | 'has_upper'
+  | 'has_lower'
+  | 'has_number'
+  | 'has_special'
+  | 'not_common'
+  | 'not_personal'
+  | 'not_breached';
+
+export interface PasswordPolicy {
+  min_length: number;
+  max_length: number;
+  require_upper: boolean;
+  require_lower: boolean;
+  require_number: boolean;
+  require_special: boolean;
+  deny_common: boolean;
+  breach_check: boolean;
+}
+
+export interface PasswordRuleFailure {
+  rule: PasswordRule;
+  message: string;
+}
+
+export interface PasswordCheck {
+  /** Every rule in force, and whether the password passed it */
+  requirements: Partial<Record<PasswordRule, boolean>>;
+  failed: PasswordRuleFailure[];
+}
+
+/** Who the password is for; it may not contain their username or email name */
+export interface PasswordOwner {
+  username?: string | null;
+  email?: string | null;
+}
+
+export function passwordPolicy(): PasswordPolicy {
+  return {
+    min_length: env.PASSWORD_MIN_LENGTH,
+    // bcrypt ignores everything past 72 bytes
+    max_length: 72,
+    require_upper: env.PASSWORD_REQUIRE_UPPER,
+    require_lower: env.PASSWORD_REQUIRE_LOWER,
+    require_number: env.PASSWORD_REQUIRE_NUMBER,
+    require_special: env.PASSWORD_REQUIRE_SPECIAL,
+    deny_common: env.PASSWORD_DENY_COMMON,
+    breach_check: env.PASSWORD_BREACH_CHECK,
+  };
+}
+
+function isCommon(password: string): boolean {
+  const lower = password.toLowerCase();
+  const core = lower.replace(/^[^a-z]+|[^a-z]+$/g, '');
+  const unleet = core.replace(/@/g, 'a').replace(/0/g, 'o').replace(/1/g, 'i').replace(/3/g, 'e').replace(/\$/g, 's');
+  return COMMON_PASSWORDS.has(lower) || COMMON_PASSWORDS.has(core) || COMMON_PASSWORDS.has(unleet);
+}
+
+function isPersonal(password: string, owner: PasswordOwner): boolean {
+  const lower = password.toLowerCase();
+  const names = [owner.username, owner.email?.split('@')[0]]
+    .map((name) => name?.trim().toLowerCase() ?? '')
+    .filter((name) => name.length >= 3);
+  return names.some((name) => lower.includes(name));
+}
+
+async function isBreached(password: string): Promise<boolean | null> {
+  const hash = crypto.createHash('sha1').update(password).digest('hex').toUpperCase();
+  try {
+    const res = await fetch(BREACH_API_URL + hash.slice(0, 5), {
+      headers: { 'Add-Padding': 'true' },
+      signal: AbortSignal.timeout(BREACH_TIMEOUT_MS),
+    });
+    if (!res.ok) throw new Error(`HTTP ${res.status}`);
+    const suffix = hash.slice(5);
+    return (await res.text()).split('\n').some((line) => {
+      const [candidate, count] = line.trim().split(':');
+      return candidate === suffix && Number(count) > 0;
+    });
+  } catch (err) {
+    console.warn('Password breach check skipped:', (err as Error).message);
+    return null;
+  }
+}
+
+// ── CheckPassword ───────────────────────────────────────────────────────────
+// Null when the password meets the policy.
+
+export async function checkPassword(password: string, owner: PasswordOwner = {}): Promise<PasswordCheck | null> {
+  const policy = passwordPolicy();
+  const requirements: PasswordCheck['requirements'] = {};
+  const failed: PasswordRuleFailure[] = [];
+  const rule = (name: PasswordRule, passed: boolean, message: string) => {
+    requirements[name] = passed;
+    if (!passed) failed.push({ rule: name, message });
+  };
+
+  rule('min_length', password.length >= policy.min_length, `must be at least ${policy.min_length} characters long`);
+  rule('max_length', Buffer.byteLength(password) <= policy.max_length, `must be at most ${policy.max_length} characters long`);
+  if (policy.require_upper) rule('has_upper', /[A-Z]/.test(password), 'must contain an uppercase letter');
+  if (policy.require_lower) rule('has_lower', /[a-z]/.test(password), 'must contain a lowercase letter');
+  if (policy.require_number) rule('has_number', /[0-9]/.test(password), 'must contain a number');
+  if (policy.require_special) {
+    rule('has_special', SPECIAL.test(password), 'must contain a special character (!@#$%^&*()_+-=[]{}|;\':",./<>?~)');
+  }
+  if (policy.deny_common) rule('not_common', !isCommon(password), 'must not be a commonly used password');
+  if (owner.username || owner.email) {
+    rule('not_personal', !isPersonal(password, owner), 'must not contain the username or email name');
+  }
+  // Only worth asking about a password that passes everything else
+  if (policy.breach_check && failed.length === 0) {
+    const breached = await isBreached(password);
+    if (breached !== null) rule('not_breached', !breached, 'must not appear in a known data breach');
+  }
+
+  return failed.length === 0 ? null : { requirements, failed };
+}
+
+export function passwordPolicyMessage(check: PasswordCheck): string {
+  return 'Password ' + check.failed.map((f) => f.message).join('; ');
+}
+
+/** The 400 for a password that doesn't meet the policy, on the given field. */
+export function weakPasswordResponse(c: Context, check: PasswordCheck, field: string) {
+  return validationErrorResponse(
+    c,
+    [validationFieldError(requestLocale(c), 'weak_password', passwordPolicyMessage(check), field)],
+    { password_requirements: check.requirements, failed_rules: check.failed },
+  );
+}
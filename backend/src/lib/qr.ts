@@ -0,0 +1,405 @@
+import zlib from 'node:zlib';
+
+// QR code encoder for the table codes, with SVG and PNG output, so the
+// backend needs no extra package. It covers what table links need: byte
+// mode, error correction level M (about 15% of the code can be damaged or
+// covered by a logo and still scan) and versions 1 to 10, which hold up to
+// 213 bytes.
+
+const MAX_VERSION = 10;
+
+// Per version at level M: error correction codewords per block, then the
+// block groups as [block count, data codewords per block]
+const EC_BLOCKS: Record<number, { ec: number; groups: [number, number][] }> = {
+  1: { ec: 10, groups: [[1, 16]] },
+  2: { ec: 16, groups: [[1, 28]] },
+  3: { ec: 26, groups: [[1, 44]] },
+  4: { ec: 18, groups: [[2, 32]] },
+  5: { ec: 24, groups: [[2, 43]] },
+  6: { ec: 16, groups: [[4, 27]] },
+  7: { ec: 18, groups: [[4, 31]] },
+  8: { ec: 22, groups: [[2, 38], [2, 39]] },
+  9: { ec: 22, groups: [[3, 36], [2, 37]] },
+  10: { ec: 26, groups: [[4, 43], [1, 44]] },
+};
+
+const ALIGNMENT_POSITIONS: Record<number, number[]> = {
+  1: [],
+  2: [6, 18],
+  3: [6, 22],
+  4: [6, 26],
+  5: [6, 30],
+  6: [6, 34],
+  7: [6, 22, 38],
+  8: [6, 24, 42],
+  9: [6, 26, 46],
+  10: [6, 28, 50],
+};
+
+const QUIET_ZONE = 4;
+
+export type QrMatrix = boolean[][];
+
+// ── Reed-Solomon over GF(256) ───────────────────────────────────────────────
+
+function gfMultiply(x: number, y: number): number {
+  let z = 0;
+  for (let i = 7; i >= 0; i--) {
+    z = (z << 1) ^ ((z >>> 7) * 0x11d);
+    z ^= ((y >>> i) & 1) * x;
+  }
+  return z;
+}
+
+function rsDivisor(degree: number): number[] {
+  const result = new Array<number>(degree).fill(0);
+  result[degree - 1] = 1;
+  let root = 1;
+  for (let i = 0; i < degree; i++) {
+    for (let j = 0; j < result.length; j++) {
+      result[j] = gfMultiply(result[j], root);
+      if (j + 1 < result.length) result[j] ^= result[j + 1];
+    }
+    root = gfMultiply(root, 0x02);
+  }
+  return result;
+}
+
+function rsRemainder(data: number[], divisor: number[]): number[] {
+  const result = new Array<number>(divisor.length).fill(0);
+  for (const b of data) {
+    const factor = b ^ (result.shift() as number);
+    result.push(0);
+    divisor.forEach((coef, i) => {
+      result[i] ^= gfMultiply(coef, factor);
+    });
+  }
+  return result;
+}
+
+// ── Codewords ───────────────────────────────────────────────────────────────
+
+function dataCapacity(version: number): number {
+  return EC_BLOCKS[version].groups.reduce((sum, [count, size]) => sum + count * size, 0);
+}
+
+function chooseVersion(byteLength: number): number {
+  for (let version = 1; version <= MAX_VERSION; version++) {
+    const countBits = version < 10 ? 8 : 16;
+    if (4 + countBits + byteLength * 8 <= dataCapacity(version) * 8) return version;
+  }
+  throw new Error(`Too much data for a QR code (${byteLength} bytes)`);
+}
+
+function dataCodewords(bytes: Uint8Array, version: number): number[] {
+  const bits: number[] = [];
+  const put = (value: number, length: number) => {
+    for (let i = length - 1; i >= 0; i--) bits.push((value >>> i) & 1);
+  };
+  put(0b0100, 4);
+  put(bytes.length, version < 10 ? 8 : 16);
+  for (const b of bytes) put(b, 8);
+
+  const capacityBits = dataCapacity(version) * 8;
+  put(0, Math.min(4, capacityBits - bits.length));
+  put(0, (8 - (bits.length % 8)) % 8);
+
+  const codewords: number[] = [];
+  for (let i = 0; i < bits.length; i += 8) {
+    codewords.push(bits.slice(i, i + 8).reduce((acc, bit) => (acc << 1) | bit, 0));
+  }
+  for (let pad = 0xec; codewords.length < capacityBits / 8; pad ^= 0xec ^ 0x11) codewords.push(pad);
+  return codewords;
+}
+
+function interleave(data: number[], version: number): number[] {
+  const { ec, groups } = EC_BLOCKS[version];
+  const divisor = rsDivisor(ec);
+  const blocks: { data: number[]; ecc: number[] }[] = [];
+  let offset = 0;
+  for (const [count, size] of groups) {
+    for (let i = 0; i < count; i++) {
+      const block = data.slice(offset, offset + size);
+      offset += size;
+      blocks.push({ data: block, ecc: rsRemainder(block, divisor) });
+    }
+  }
+
+  const result: number[] = [];
+  const longest = Math.max(...blocks.map((b) => b.data.length));
+  for (let i = 0; i < longest; i++) {
+    for (const block of blocks) if (i < block.data.length) result.push(block.data[i]);
+  }
+  for (let i = 0; i < ec; i++) {
+    for (const block of blocks) result.push(block.ecc[i]);
+  }
+  return result;
+}
+
+// ── Matrix ──────────────────────────────────────────────────────────────────
+
+const MASKS: ((x: number, y: number) => boolean)[] = [
+  (x, y) => (x + y) % 2 === 0,
+  (_x, y) => y % 2 === 0,
+  (x) => x % 3 === 0,
+  (x, y) => (x + y) % 3 === 0,
+  (x, y) => (Math.floor(x / 3) + Math.floor(y / 2)) % 2 === 0,
+  (x, y) => ((x * y) % 2) + ((x * y) % 3) === 0,
+  (x, y) => (((x * y) % 2) + ((x * y) % 3)) % 2 === 0,
+  (x, y) => (((x + y) % 2) + ((x * y) % 3)) % 2 === 0,
+];
+
+class Builder {
+  readonly size: number;
+  readonly modules: QrMatrix;
+  readonly reserved: boolean[][];
+
+  constructor(readonly version: number) {
+    this.size = version * 4 + 17;
+    this.modules = Array.from({ length: this.size }, () => new Array<boolean>(this.size).fill(false));
+    this.reserved = Array.from({ length: this.size }, () => new Array<boolean>(this.size).fill(false));
+  }
+
+  private set(x: number, y: number, dark: boolean): void {
+    this.modules[y][x] = dark;
+    this.reserved[y][x] = true;
+  }
+
+  drawFunctionPatterns(): void {
+    const { size } = this;
+    for (let i = 0; i < size; i++) {
+      this.set(6, i, i % 2 === 0);
+      this.set(i, 6, i % 2 === 0);
+    }
+    this.drawFinder(3, 3);
+    this.drawFinder(size - 4, 3);
+    this.drawFinder(3, size - 4);
+
+    const positions = ALIGNMENT_POSITIONS[this.version];
+    const last = positions.length - 1;
+    positions.forEach((x, i) => {
+      positions.forEach((y, j) => {
+        // Not where the finders are
+        if ((i === 0 && j === 0) || (i === 0 && j === last) || (i === last && j === 0)) return;
+        for (let dy = -2; dy <= 2; dy++) {
+          for (let dx = -2; dx <= 2; dx++) this.set(x + dx, y + dy, Math.max(Math.abs(dx), Math.abs(dy)) !== 1);
+        }
+      });
+    });
+
+    // Reserved now, written once the mask is known
+    this.drawFormatBits(0);
+    this.drawVersion();
+  }
+
+  private drawFinder(cx: number, cy: number): void {
+    for (let dy = -4; dy <= 4; dy++) {
+      for (let dx = -4; dx <= 4; dx++) {
+        const x = cx + dx;
+        const y = cy + dy;
+        if (x < 0 || y < 0 || x >= this.size || y >= this.size) continue;
+        const dist = Math.max(Math.abs(dx), Math.abs(dy));
+        this.set(x, y, dist !== 2 && dist !== 4);
+      }
+    }
+  }
+
+  drawFormatBits(mask: number): void {
+    // Level M is 00 in the format bits
+    const data = mask;
+    let rem = data;
+    for (let i = 0; i < 10; i++) rem = (rem << 1) ^ ((rem >>> 9) * 0x537);
+    const bits = ((data << 10) | rem) ^ 0x5412;
+    const bit = (i: number) => ((bits >>> i) & 1) !== 0;
+    const { size } = this;
+
+    for (let i = 0; i <= 5; i++) this.set(8, i, bit(i));
+    this.set(8, 7, bit(6));
+    this.set(8, 8, bit(7));
+    this.set(7, 8, bit(8));
+    for (let i = 9; i < 15; i++) this.set(14 - i, 8, bit(i));
+
+    for (let i = 0; i < 8; i++) this.set(size - 1 - i, 8, bit(i));
+    for (let i = 8; i < 15; i++) this.set(8, size - 15 + i, bit(i));
+    this.set(8, size - 8, true);
+  }
+
+  private drawVersion(): void {
+    if (this.version < 7) return;
+    let rem = this.version;
+    for (let i = 0; i < 12; i++) rem = (rem << 1) ^ ((rem >>> 11) * 0x1f25);
+    const bits = (this.version << 12) | rem;
+    for (let i = 0; i < 18; i++) {
+      const dark = ((bits >>> i) & 1) !== 0;
+      const a = this.size - 11 + (i % 3);
+      const b = Math.floor(i / 3);
+      this.set(a, b, dark);
+      this.set(b, a, dark);
+    }
+  }
+
+  drawCodewords(codewords: number[]): void {
+    const { size } = this;
+    let i = 0;
+    for (let right = size - 1; right >= 1; right -= 2) {
+      if (right === 6) right = 5;
+      for (let vert = 0; vert < size; vert++) {
+        for (let j = 0; j < 2; j++) {
+          const x = right - j;
+          const upward = ((right + 1) & 2) === 0;
+          const y = upward ? size - 1 - vert : vert;
+          if (this.reserved[y][x] || i >= codewords.length * 8) continue;
+          this.modules[y][x] = ((codewords[i >>> 3] >>> (7 - (i & 7))) & 1) !== 0;
+          i++;
+        }
+      }
+    }
+  }
+
+  applyMask(mask: number): void {
+    for (let y = 0; y < this.size; y++) {
+      for (let x = 0; x < this.size; x++) {
+        if (!this.reserved[y][x] && MASKS[mask](x, y)) this.modules[y][x] = !this.modules[y][x];
+      }
+    }
+  }
+
+  // The standard's penalty score; the mask with the lowest is used
+  penalty(): number {
+    const { size, modules } = this;
+    let score = 0;
+
+    const line = (get: (i: number) => boolean) => {
+      let run = 1;
+      for (let i = 1; i <= size; i++) {
+        if (i < size && get(i) === get(i - 1)) {
+          run++;
+          continue;
+        }
+        if (run >= 5) score += run - 2;
+        run = 1;
+      }
+      // Finder-like 1:1:3:1:1 with four light modules on one side
+      for (let i = 0; i + 11 <= size; i++) {
+        const seq = Array.from({ length: 11 }, (_, k) => get(i + k));
+        const core = seq[0] && !seq[1] && seq[2] && seq[3] && seq[4] && !seq[5] && seq[6];
+        const coreLate = seq[4] && !seq[5] && seq[6] && seq[7] && seq[8] && !seq[9] && seq[10];
+        if (core && !seq[7] && !seq[8] && !seq[9] && !seq[10]) score += 40;
+        if (coreLate && !seq[0] && !seq[1] && !seq[2] && !seq[3]) score += 40;
+      }
+    };
+    for (let y = 0; y < size; y++) line((x) => modules[y][x]);
+    for (let x = 0; x < size; x++) line((y) => modules[y][x]);
+
+    let dark = 0;
+    for (let y = 0; y < size; y++) {
+      for (let x = 0; x < size; x++) {
+        if (modules[y][x]) dark++;
+        if (x + 1 < size && y + 1 < size) {
+          const c = modules[y][x];
+          if (c === modules[y][x + 1] && c === modules[y + 1][x] && c === modules[y + 1][x + 1]) score += 3;
+        }
+      }
+    }
+    score += Math.floor(Math.abs((dark * 100) / (size * size) - 50) / 5) * 10;
+    return score;
+  }
+}
+
+// ── EncodeQr ────────────────────────────────────────────────────────────────
+// The module matrix for `text`, [row][column], true for dark. `mask` forces
+// a mask pattern; by default the best scoring one is picked.
+
+export function encodeQr(text: string, mask?: number): QrMatrix {
+  const bytes = new TextEncoder().encode(text);
+  const version = chooseVersion(bytes.length);
+  const codewords = interleave(dataCodewords(bytes, version), version);
+
+  const build = (m: number) => {
+    const builder = new Builder(version);
+    builder.drawFunctionPatterns();
+    builder.drawCodewords(codewords);
+    builder.applyMask(m);
+    builder.drawFormatBits(m);
+    return builder;
+  };
+
+  if (mask !== undefined) return build(mask).modules;
+  let best = build(0);
+  let bestScore = best.penalty();
+  for (let m = 1; m < MASKS.length; m++) {
+    const candidate = build(m);
+    const score = candidate.penalty();
+    if (score < bestScore) {
+      best = candidate;
+      bestScore = score;
+    }
+  }
+  return best.modules;
+}
+
+// ── QrSvg ───────────────────────────────────────────────────────────────────
+// One path of unit squares, with the quiet zone, scaling to any size.
+
+export function qrSvg(matrix: QrMatrix, size = 512): string {
+  const dim = matrix.length + QUIET_ZONE * 2;
+  const parts: string[] = [];
+  matrix.forEach((row, y) => {
+    row.forEach((dark, x) => {
+      if (dark) parts.push(`M${x + QUIET_ZONE},${y + QUIET_ZONE}h1v1h-1z`);
+    });
+  });
+  return `<svg xmlns="http://www.w3.org/2000/svg" width="${size}" height="${size}" viewBox="0 0 ${dim} ${dim}" shape-rendering="crispEdges">` +
+    `<rect width="${dim}" height="${dim}" fill="#fff"/><path fill="#000" d="${parts.join('')}"/></svg>`;
+}
+
+// ── QrPng ───────────────────────────────────────────────────────────────────
+// 8-bit greyscale, `scale` pixels per module.
+
+const CRC_TABLE = Array.from({ length: 256 }, (_, n) => {
+  let c = n;
+  for (let k = 0; k < 8; k++) c = c & 1 ? 0xedb88320 ^ (c >>> 1) : c >>> 1;
+  return c >>> 0;
+});
+
+function crc32(buf: Buffer): number {
+  let crc = 0xffffffff;
+  for (const b of buf) crc = CRC_TABLE[(crc ^ b) & 0xff] ^ (crc >>> 8);
+  return (crc ^ 0xffffffff) >>> 0;
+}
+
+function pngChunk(type: string, data: Buffer): Buffer {
+  const length = Buffer.alloc(4);
+  length.writeUInt32BE(data.length);
+  const body = Buffer.concat([Buffer.from(type, 'ascii'), data]);
+  const crc = Buffer.alloc(4);
+  crc.writeUInt32BE(crc32(body));
+  return Buffer.concat([length, body, crc]);
+}
+
+export function qrPng(matrix: QrMatrix, scale = 10): Buffer {
+  const dim = (matrix.length + QUIET_ZONE * 2) * scale;
+  const raw = Buffer.alloc((dim + 1) * dim, 0xff);
+  for (let py = 0; py < dim; py++) {
+    const rowStart = py * (dim + 1);
+    raw[rowStart] = 0; // filter: none
+    const y = Math.floor(py / scale) - QUIET_ZONE;
+    if (y < 0 || y >= matrix.length) continue;
+    for (let px = 0; px < dim; px++) {
+      const x = Math.floor(px / scale) - QUIET_ZONE;
+      if (x >= 0 && x < matrix.length && matrix[y][x]) raw[rowStart + 1 + px] = 0;
+    }
+  }
+
+  const header = Buffer.alloc(13);
+  header.writeUInt32BE(dim, 0);
+  header.writeUInt32BE(dim, 4);
+  header[8] = 8; // bit depth
+  header[9] = 0; // greyscale
+  return Buffer.concat([
+    Buffer.from([0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a]),
+    pngChunk('IHDR', header),
+    pngChunk('IDAT', zlib.deflateSync(raw)),
+    pngChunk('IEND', Buffer.alloc(0)),
+  ]);
+}
@@ -1,6 +1,8 @@
 import type { Context } from 'hono';
+import type { PageMeta, CursorMeta } from './pagination.js';
+import { requestLocale, validationFieldError, validationSummary, type FieldError } from './validation-messages.js';
 
-type StatusCode = 200 | 201 | 400 | 401 | 403 | 404 | 409 | 429 | 500;
+type StatusCode = 200 | 201 | 400 | 401 | 403 | 404 | 409 | 413 | 429 | 500 | 502 | 503;
 
 export function successResponse(c: Context, message: string, data?: unknown, status: StatusCode = 200) {
   const body: Record<string, unknown> = { success: true, message };
@@ -8,17 +10,42 @@ export function successResponse(c: Context, message: string, data?: unknown, sta
   return c.json(body, status);
 }
 
+// A 400 is a validation error: its message follows the client's
+// Accept-Language and `errors` names the field at fault (see
+// validation-messages.ts).
 export function errorResponse(c: Context, message: string, error?: string, status: StatusCode = 500) {
   const body: Record<string, unknown> = { success: false, message };
   if (error !== undefined && error !== '') body.error = error;
+  if (status === 400 && error) {
+    const fieldError = validationFieldError(requestLocale(c), error, message);
+    body.message = fieldError.message;
+    body.errors = [fieldError];
+  }
+  const requestId = c.get('requestId');
+  if (requestId) body.request_id = requestId;
   return c.json(body, status);
 }
 
+// A 400 listing every field error found at once; data carries any details
+// a client needs to correct the request
+export function validationErrorResponse(c: Context, errors: FieldError[], data?: unknown) {
+  const body: Record<string, unknown> = {
+    success: false,
+    message: errors.length === 1 ? errors[0].message : validationSummary(requestLocale(c)),
+    error: errors.length === 1 ? errors[0].code : 'validation_failed',
+    errors,
+  };
+  if (data !== undefined) body.data = data;
+  const requestId = c.get('requestId');
+  if (requestId) body.request_id = requestId;
+  return c.json(body, 400);
+}
+
 export function paginatedResponse(
   c: Context,
   message: string,
   data: unknown[],
-  meta: { current_page: number; per_page: number; total: number; total_pages: number },
+  meta: PageMeta | CursorMeta,
 ) {
   return c.json({ success: true, message, data, meta });
 }
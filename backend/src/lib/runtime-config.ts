@@ -0,0 +1,150 @@
+import { z } from 'zod';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+
+// Runtime configuration: the knobs that change without a restart (log
+// level, rate limits, maintenance mode, feature flags). They are ordinary
+// system settings, so they are edited, audited and rolled back like any
+// other, and they are reloaded on SIGHUP, from POST /admin/config/reload,
+// after a settings save on this instance and every
+// RUNTIME_CONFIG_REFRESH_MS so the other instances follow. A reload
+// validates every value before swapping in the new config as one object, so
+// middleware never sees half of a reload; if anything is invalid the old
+// config stays and the reload reports why.
+
+export const LOG_LEVELS = ['debug', 'info', 'warn', 'error'] as const;
+export type LogLevel = (typeof LOG_LEVELS)[number];
+
+function count(fallback: number) {
+  return z.coerce.number().int().min(1).max(100000).default(fallback);
+}
+
+const runtimeSchema = z.object({
+  log_level: z.enum(LOG_LEVELS).default('info'),
+  maintenance_mode: z.enum(['true', 'false']).default('false').transform((v) => v === 'true'),
+  maintenance_message: z.string().max(500).default('The system is undergoing maintenance. Please try again shortly.'),
+  // Requests per minute per client IP, on the public API, login, and the contact and reservation forms (per 5 minutes)
+  rate_limit_public: count(30),
+  rate_limit_login: count(5),
+  rate_limit_contact: count(3),
+  feature_flags: z
+    .string()
+    .default('{}')
+    .transform((raw, ctx) => {
+      try {
+        const parsed = JSON.parse(raw);
+        if (parsed && typeof parsed === 'object' && !Array.isArray(parsed)
+          && Object.values(parsed).every((v) => typeof v === 'boolean')) {
+          return parsed as Record<string, boolean>;
+        }
+      } catch {
+        // Reported below
+      }
+      ctx.addIssue({ code: z.ZodIssueCode.custom, message: 'Must be a JSON object of true/false flags' });
+      return z.NEVER;
+    }),
+});
+
+export type RuntimeConfig = Readonly<z.infer<typeof runtimeSchema>>;
+
+export const RUNTIME_SETTING_KEYS = Object.keys(runtimeSchema.shape);
+
+let current: RuntimeConfig = Object.freeze(runtimeSchema.parse({}));
+let version = 0;
+let loadedAt: string | null = null;
+
+export function runtimeConfig(): RuntimeConfig {
+  return current;
+}
+
+export function runtimeConfigState() {
+  return { version, loaded_at: loadedAt, config: current };
+}
+
+export function featureEnabled(flag: string): boolean {
+  return current.feature_flags[flag] === true;
+}
+
+function parse(values: Record<string, string>) {
+  const result = runtimeSchema.safeParse(values);
+  if (result.success) return { ok: true as const, config: result.data };
+  return {
+    ok: false as const,
+    errors: result.error.issues.map((issue) => ({ key: String(issue.path[0]), message: issue.message })),
+  };
+}
+
+/**
+ * Checks runtime settings about to be saved, so an invalid value is refused
+ * up front instead of failing the next reload. Other keys are ignored.
+ */
+export function validateRuntimeSettings(values: Record<string, string>): { key: string; message: string }[] {
+  const runtime = Object.entries(values).filter(([key]) => RUNTIME_SETTING_KEYS.includes(key));
+  if (runtime.length === 0) return [];
+  const result = parse({ ...currentAsSettings(), ...Object.fromEntries(runtime) });
+  return result.ok ? [] : result.errors;
+}
+
+function currentAsSettings(): Record<string, string> {
+  return {
+    ...Object.fromEntries(Object.entries(current).map(([key, value]) => [key, String(value)])),
+    feature_flags: JSON.stringify(current.feature_flags),
+  };
+}
+
+// ── ReloadRuntimeConfig ─────────────────────────────────────────────────────
+
+export async function reloadRuntimeConfig(): Promise<
+  { ok: true; version: number; changed: string[] } | { ok: false; errors: { key: string; message: string }[] }
+> {
+  const res = await pool.query(
+    'SELECT setting_key, setting_value FROM system_settings WHERE setting_key = ANY($1::text[])',
+    [RUNTIME_SETTING_KEYS],
+  );
+  const values: Record<string, string> = {};
+  for (const row of res.rows) {
+    // A blank value means the default
+    if (row.setting_value !== '') values[row.setting_key] = row.setting_value;
+  }
+
+  const result = parse(values);
+  if (!result.ok) return result;
+
+  const next = Object.freeze(result.config);
+  const changed = RUNTIME_SETTING_KEYS.filter(
+    (key) => JSON.stringify(next[key as keyof RuntimeConfig]) !== JSON.stringify(current[key as keyof RuntimeConfig]),
+  );
+  current = next;
+  loadedAt = new Date().toISOString();
+  if (changed.length > 0) version++;
+  return { ok: true, version, changed };
+}
+
+// Logs the outcome of a reload that nobody is waiting on
+async function backgroundReload(trigger: string): Promise<void> {
+  try {
+    const result = await reloadRuntimeConfig();
+    if (!result.ok) {
+      console.error(`Runtime config reload (${trigger}) rejected: ${result.errors.map((e) => `${e.key}: ${e.message}`).join('; ')}`);
+    } else if (result.changed.length > 0) {
+      console.log(`Runtime config reloaded (${trigger}), version ${result.version}: ${result.changed.join(', ')} changed`);
+    }
+  } catch (err) {
+    console.error(`Runtime config reload (${trigger}) failed:`, (err as Error).message);
+  }
+}
+
+// ── StartRuntimeConfigRefresh ───────────────────────────────────────────────
+// Loads the config now, then on SIGHUP and on the refresh interval.
+
+export async function startRuntimeConfigRefresh(): Promise<() => void> {
+  await backgroundReload('startup');
+  const timer = setInterval(() => void backgroundReload('refresh'), env.RUNTIME_CONFIG_REFRESH_MS);
+  timer.unref();
+  const onHup = () => void backgroundReload('SIGHUP');
+  process.on('SIGHUP', onHup);
+  return () => {
+    clearInterval(timer);
+    process.off('SIGHUP', onHup);
+  };
+}
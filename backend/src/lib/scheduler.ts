@@ -0,0 +1,130 @@
+import { pool } from '../db/connection.js';
+import { localClock, timeToSeconds } from './clock.js';
+
+// In-process scheduler for daily jobs. Each job runs once per business day at
+// or after its local (WIB) time. Runs are claimed in scheduled_job_runs, so a
+// job still runs after a restart that missed its slot and never runs twice
+// when several backend instances are up.
+//
+// Interval jobs (scheduleEvery) are for short periodic sweeps. They are not
+// claimed and run on every instance, so their work must be safe to race —
+// typically a single conditional UPDATE ... RETURNING.
+
+interface DailyJob {
+  name: string;
+  /** Local time of day, HH:MM */
+  at: string;
+  run: () => Promise<void>;
+}
+
+const CHECK_INTERVAL_MS = 30_000;
+
+interface IntervalJob {
+  name: string;
+  everyMs: number;
+  run: () => Promise<void>;
+}
+
+const jobs: DailyJob[] = [];
+const intervalJobs: IntervalJob[] = [];
+const lastRunAt = new Map<string, number>();
+const running = new Set<string>();
+const lastChecked = new Map<string, string>();
+let timer: ReturnType<typeof setInterval> | null = null;
+
+export function scheduleDaily(name: string, at: string, run: () => Promise<void>): void {
+  jobs.push({ name, at, run });
+}
+
+/** Runs `run` roughly every `everyMs` (checked on the scheduler tick). */
+export function scheduleEvery(name: string, everyMs: number, run: () => Promise<void>): void {
+  intervalJobs.push({ name, everyMs, run });
+}
+
+async function claimRun(name: string, date: string): Promise<boolean> {
+  const res = await pool.query(
+    `INSERT INTO scheduled_job_runs (job_name, run_date) VALUES ($1, $2)
+     ON CONFLICT DO NOTHING RETURNING job_name`,
+    [name, date],
+  );
+  return res.rows.length > 0;
+}
+
+async function finishRun(name: string, date: string, error: string | null): Promise<void> {
+  await pool.query(
+    `UPDATE scheduled_job_runs SET finished_at = NOW(), status = $3, error = $4
+     WHERE job_name = $1 AND run_date = $2`,
+    [name, date, error ? 'failed' : 'succeeded', error],
+  );
+}
+
+async function runIfDue(job: DailyJob): Promise<void> {
+  const clock = localClock();
+  if (lastChecked.get(job.name) === clock.date || clock.secondsOfDay < timeToSeconds(job.at)) return;
+
+  running.add(job.name);
+  try {
+    const claimed = await claimRun(job.name, clock.date);
+    lastChecked.set(job.name, clock.date);
+    if (!claimed) return;
+
+    try {
+      await job.run();
+      await finishRun(job.name, clock.date, null);
+      console.log(`Scheduler: ${job.name} completed for ${clock.date}`);
+    } catch (err) {
+      await finishRun(job.name, clock.date, (err as Error).message);
+      console.error(`Scheduler: ${job.name} failed:`, (err as Error).message);
+    }
+  } catch (err) {
+    // Claim failed (e.g. database unavailable); retried on the next tick
+    console.error(`Scheduler: could not start ${job.name}:`, (err as Error).message);
+  } finally {
+    running.delete(job.name);
+  }
+}
+
+async function runInterval(job: IntervalJob): Promise<void> {
+  running.add(job.name);
+  lastRunAt.set(job.name, Date.now());
+  try {
+    await job.run();
+  } catch (err) {
+    console.error(`Scheduler: ${job.name} failed:`, (err as Error).message);
+  } finally {
+    running.delete(job.name);
+  }
+}
+
+function tick(): void {
+  for (const job of jobs) {
+    if (!running.has(job.name)) void runIfDue(job);
+  }
+  const now = Date.now();
+  for (const job of intervalJobs) {
+    if (running.has(job.name) || now - (lastRunAt.get(job.name) ?? 0) < job.everyMs) continue;
+    void runInterval(job);
+  }
+}
+
+export function startScheduler(): void {
+  if (timer || jobs.length + intervalJobs.length === 0) return;
+  timer = setInterval(tick, CHECK_INTERVAL_MS);
+  tick();
+  const names = [
+    ...jobs.map((j) => `${j.name}@${j.at}`),
+    ...intervalJobs.map((j) => `${j.name}/${Math.round(j.everyMs / 1000)}s`),
+  ];
+  console.log(`Scheduler started: ${names.join(', ')}`);
+}
+
+/** Stops scheduling new runs and waits for any job already running. */
+export async function stopScheduler(): Promise<void> {
+  if (timer) {
+    clearInterval(timer);
+    timer = null;
+  }
+  while (running.size > 0) {
+    await new Promise((resolve) => setTimeout(resolve, 100));
+  }
+}
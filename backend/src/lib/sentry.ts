@@ -0,0 +1,160 @@
+import os from 'node:os';
+import { randomUUID } from 'node:crypto';
+import { env } from '../env.js';
+
+// Error reporting to Sentry over its envelope HTTP API, without the SDK.
+// Each event is tagged with the request ID and carries the request's trace
+// context, so an event links to the log lines and the trace of the request
+// that raised it. Reporting is fire-and-forget: a Sentry outage only costs a
+// warning in the log and never fails the request.
+
+export interface ErrorContext {
+  requestId?: string;
+  traceId?: string;
+  spanId?: string;
+  method?: string;
+  path?: string;
+  status?: number;
+  userId?: string;
+}
+
+interface Dsn {
+  key: string;
+  envelopeUrl: string;
+}
+
+interface StackFrame {
+  function?: string;
+  filename: string;
+  lineno?: number;
+  colno?: number;
+  in_app: boolean;
+}
+
+const SEND_TIMEOUT_MS = 5000;
+
+// https://<key>@<host>/<project>
+function parseDsn(dsn: string): Dsn | null {
+  try {
+    const url = new URL(dsn);
+    const project = url.pathname.replace(/^\/+|\/+$/g, '');
+    if (!url.username || !project) return null;
+    return {
+      key: url.username,
+      envelopeUrl: `${url.protocol}//${url.host}/api/${project}/envelope/`,
+    };
+  } catch {
+    return null;
+  }
+}
+
+const dsn = env.SENTRY_DSN ? parseDsn(env.SENTRY_DSN) : null;
+if (env.SENTRY_DSN && !dsn) {
+  console.warn('SENTRY_DSN is not a valid DSN; error reporting is disabled');
+}
+
+export function sentryConfigured(): boolean {
+  return dsn !== null;
+}
+
+// ── PingSentry ──────────────────────────────────────────────────────────────
+// For the health check: any HTTP answer from the Sentry host means events
+// can get there. Throws when it can't be reached.
+
+export async function pingSentry(timeoutMs = SEND_TIMEOUT_MS): Promise<void> {
+  if (!dsn) return;
+  await fetch(new URL(dsn.envelopeUrl).origin, { method: 'HEAD', signal: AbortSignal.timeout(timeoutMs) });
+}
+
+// V8 stack lines look like "    at fn (file:line:col)" or "    at file:line:col".
+// Sentry wants the outermost frame first, the reverse of V8's order.
+function parseStack(stack: string | undefined): StackFrame[] {
+  if (!stack) return [];
+  const frames: StackFrame[] = [];
+  for (const line of stack.split('\n').slice(1)) {
+    const match = /^\s*at (?:(.+?) \()?(.+?):(\d+):(\d+)\)?$/.exec(line);
+    if (!match) continue;
+    frames.push({
+      function: match[1],
+      filename: match[2],
+      lineno: Number(match[3]),
+      colno: Number(match[4]),
+      in_app: !match[2].includes('node_modules') && !match[2].startsWith('node:'),
+    });
+  }
+  return frames.reverse();
+}
+
+function buildEvent(ctx: ErrorContext, level: 'error' | 'warning', body: Record<string, unknown>) {
+  const tags: Record<string, string> = {};
+  if (ctx.requestId) tags.request_id = ctx.requestId;
+  if (ctx.method) tags.method = ctx.method;
+  if (ctx.status) tags.status = String(ctx.status);
+
+  return {
+    event_id: randomUUID().replace(/-/g, ''),
+    timestamp: Date.now() / 1000,
+    platform: 'node',
+    level,
+    environment: env.NODE_ENV,
+    release: env.SENTRY_RELEASE || undefined,
+    server_name: os.hostname(),
+    transaction: ctx.method && ctx.path ? `${ctx.method} ${ctx.path}` : undefined,
+    tags,
+    user: ctx.userId ? { id: ctx.userId } : undefined,
+    contexts: ctx.traceId
+      ? { trace: { trace_id: ctx.traceId, span_id: ctx.spanId, op: 'http.server' } }
+      : undefined,
+    ...body,
+  };
+}
+
+async function send(event: ReturnType<typeof buildEvent>): Promise<void> {
+  if (!dsn) return;
+  const envelope = [
+    JSON.stringify({ event_id: event.event_id, sent_at: new Date().toISOString() }),
+    JSON.stringify({ type: 'event' }),
+    JSON.stringify(event),
+  ].join('\n');
+
+  try {
+    const res = await fetch(dsn.envelopeUrl, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/x-sentry-envelope',
+        'X-Sentry-Auth': `Sentry sentry_version=7, sentry_key=${dsn.key}, sentry_client=pos-backend/1.0`,
+      },
+      body: envelope,
+      signal: AbortSignal.timeout(SEND_TIMEOUT_MS),
+    });
+    if (!res.ok) {
+      console.warn(`Sentry rejected event ${event.event_id}: HTTP ${res.status}`);
+    }
+  } catch (err) {
+    console.warn(`Sentry event ${event.event_id} could not be sent:`, (err as Error).message);
+  }
+}
+
+// ── CaptureException ────────────────────────────────────────────────────────
+
+export function captureException(err: Error, ctx: ErrorContext = {}): void {
+  if (!dsn) return;
+  void send(buildEvent(ctx, 'error', {
+    exception: {
+      values: [{
+        type: err.name,
+        value: err.message,
+        stacktrace: { frames: parseStack(err.stack) },
+      }],
+    },
+  }));
+}
+
+// ── CaptureMessage ──────────────────────────────────────────────────────────
+// For failures a handler caught and turned into an error response, where
+// only the message is left.
+
+export function captureMessage(message: string, ctx: ErrorContext = {}): void {
+  if (!dsn) return;
+  void send(buildEvent(ctx, 'error', { message: { formatted: message } }));
+}
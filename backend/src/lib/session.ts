@@ -0,0 +1,64 @@
+import crypto from 'node:crypto';
+import type { Context } from 'hono';
+import { getCookie, setCookie, deleteCookie } from 'hono/cookie';
+import { env } from '../env.js';
+
+// Cookie sessions. Login hands out the JWT both in the response body, for
+// API clients that send it as a Bearer token, and as an HttpOnly session
+// cookie for browsers. Because the browser attaches the cookie to any
+// request, including ones a hostile page triggers, a state-changing request
+// authenticated by the cookie must also carry the CSRF token in
+// X-CSRF-Token (double submit). The token is an HMAC of the session token,
+// so it can't be forged or carried over from another session, and it is
+// readable by the app from the pos_csrf cookie or the login response.
+// Requests with an Authorization header never look at the cookies.
+
+export const SESSION_COOKIE = 'pos_session';
+export const CSRF_COOKIE = 'pos_csrf';
+export const CSRF_HEADER = 'X-CSRF-Token';
+
+// Matches the JWT lifetime
+const SESSION_MAX_AGE_SECONDS = 24 * 60 * 60;
+
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+export function csrfTokenFor(sessionToken: string): string {
+  return crypto.createHmac('sha256', env.JWT_SECRET).update(`csrf:${sessionToken}`).digest('hex');
+}
+
+function cookieOptions(httpOnly: boolean) {
+  return {
+    path: '/',
+    httpOnly,
+    secure: env.NODE_ENV === 'production',
+    sameSite: 'Lax' as const,
+    maxAge: SESSION_MAX_AGE_SECONDS,
+  };
+}
+
+/** Sets the session and CSRF cookies for `token`; returns the CSRF token. */
+export function setSessionCookies(c: Context, token: string): string {
+  const csrfToken = csrfTokenFor(token);
+  setCookie(c, SESSION_COOKIE, token, cookieOptions(true));
+  setCookie(c, CSRF_COOKIE, csrfToken, cookieOptions(false));
+  return csrfToken;
+}
+
+export function clearSessionCookies(c: Context): void {
+  deleteCookie(c, SESSION_COOKIE, { path: '/' });
+  deleteCookie(c, CSRF_COOKIE, { path: '/' });
+}
+
+export function sessionCookie(c: Context): string | undefined {
+  return getCookie(c, SESSION_COOKIE) || undefined;
+}
+
+/** Whether a request authenticated by `sessionToken` may proceed. */
+export function csrfCheck(c: Context, sessionToken: string): 'ok' | 'missing' | 'invalid' {
+  if (SAFE_METHODS.includes(c.req.method)) return 'ok';
+  const sent = c.req.header(CSRF_HEADER);
+  if (!sent) return 'missing';
+  const expected = Buffer.from(csrfTokenFor(sessionToken));
+  const actual = Buffer.from(sent);
+  return actual.length === expected.length && crypto.timingSafeEqual(actual, expected) ? 'ok' : 'invalid';
+}
@@ -0,0 +1,11 @@
+import type { Context } from 'hono';
+import { can } from '../middleware/roles.js';
+
+/**
+ * True when soft-deleted rows should be listed: `?include_deleted=true` from
+ * a role with records.view_deleted. Other roles always get live rows only, since some list
+ * endpoints are shared with staff routes.
+ */
+export function includeDeleted(c: Context): boolean {
+  return c.req.query('include_deleted') === 'true' && can(c, 'records.view_deleted');
+}
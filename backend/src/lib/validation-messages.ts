@@ -0,0 +1,409 @@
+import type { Context } from 'hono';
+
+// Validation error catalog. Handlers reject bad input with an English message
+// and a stable code; the code also names the field at fault and carries the
+// Bahasa Indonesia text sent to clients that ask for it (Accept-Language:
+// id). The English message stays the handler's own, as it is usually more
+// specific than a catalog entry can be.
+
+export type Locale = 'en' | 'id';
+
+export interface FieldError {
+  /** Request field at fault (dotted for nested ones); null for the request as a whole */
+  field: string | null;
+  code: string;
+  message: string;
+}
+
+type Entry = [field: string | null, id: string];
+
+const CATALOG: Record<string, Entry> = {
+  // Request
+  invalid_json: [null, 'Isi permintaan tidak valid'],
+  validation_error: [null, 'Data yang dikirim tidak valid'],
+  validation_failed: [null, 'Validasi gagal'],
+  no_fields: [null, 'Tidak ada data yang diubah'],
+  missing_fields: [null, 'Semua kolom wajib harus diisi'],
+  invalid_cursor: ['cursor', 'Kursor halaman tidak valid'],
+  invalid_id: [null, 'ID tidak valid'],
+  not_found: [null, 'Data tidak ditemukan'],
+  unknown_setting: ['settings', 'Pengaturan tidak dikenal'],
+
+  // Generic field rules (used for schema-validated bodies)
+  required: [null, '{field} wajib diisi'],
+  invalid_type: [null, '{field} memiliki tipe yang salah'],
+  too_short: [null, '{field} minimal {min} karakter'],
+  too_long: [null, '{field} maksimal {max} karakter'],
+  too_small: [null, '{field} minimal {min}'],
+  too_large: [null, '{field} maksimal {max}'],
+  too_few: [null, '{field} minimal berisi {min} item'],
+  too_many: [null, '{field} maksimal berisi {max} item'],
+  invalid_option: [null, '{field} harus salah satu dari: {options}'],
+  invalid_format: [null, 'Format {field} tidak valid'],
+  invalid_value: ['value', 'Nilai tidak valid'],
+
+  // Names, text and contact details
+  invalid_name: ['name', 'Nama wajib diisi dan tidak boleh terlalu panjang'],
+  missing_name: ['name', 'Nama wajib diisi'],
+  name_required: ['name', 'Nama wajib diisi'],
+  invalid_display_name: ['display_name', 'Nama tampilan wajib diisi (maksimal 50 karakter)'],
+  invalid_code: ['code', 'Kode tidak valid'],
+  invalid_symbol: ['symbol', 'Simbol wajib diisi (maksimal 10 karakter)'],
+  invalid_email: ['email', 'Format email tidak valid'],
+  email_required: ['email', 'Email wajib diisi'],
+  invalid_phone: ['phone', 'Nomor telepon tidak valid'],
+  phone_required: ['phone', 'Nomor telepon wajib diisi'],
+  subject_required: ['subject', 'Subjek wajib diisi'],
+  message_required: ['message', 'Pesan wajib diisi'],
+  message_too_long: ['message', 'Pesan maksimal 5000 karakter'],
+  invalid_reason: ['reason', 'Alasan wajib diisi dan tidak boleh terlalu panjang'],
+  invalid_reason_type: ['reason_type', 'Jenis alasan tidak valid'],
+  missing_reason: ['reason', 'Alasan wajib diisi'],
+  notes_too_long: ['notes', 'Catatan terlalu panjang (maksimal 500 karakter)'],
+  special_instructions_too_long: ['special_instructions', 'Instruksi khusus terlalu panjang (maksimal 500 karakter)'],
+  invalid_url: ['url', 'URL harus diawali http:// atau https://'],
+  invalid_photo_url: ['photo_url', 'Foto harus berupa unggahan atau tautan http(s)'],
+  invalid_query: ['q', 'Kata kunci pencarian maksimal 100 karakter'],
+  missing_query: ['q', 'Kata kunci pencarian wajib diisi'],
+  missing_credentials: [null, 'Nama pengguna dan kata sandi wajib diisi'],
+  invalid_filename: ['filename', 'Nama file tidak valid'],
+  missing_file: ['file', 'Tidak ada file gambar yang dikirim'],
+  file_too_large: ['file', 'Ukuran file terlalu besar'],
+  invalid_file_type: ['file', 'Jenis file tidak didukung. Gunakan JPEG, PNG, GIF atau WebP'],
+
+  // Dates and times
+  invalid_date: ['date', 'Tanggal harus berformat YYYY-MM-DD'],
+  invalid_date_range: [null, 'Tanggal from dan to harus berformat YYYY-MM-DD, dengan from tidak melebihi to'],
+  invalid_month: ['month', 'Bulan harus berformat YYYY-MM'],
+  invalid_time: ['time', 'Waktu harus berformat HH:MM'],
+  invalid_time_range: [null, 'Jam buka harus sebelum jam tutup'],
+  invalid_time_window: [null, 'start_time dan end_time wajib diisi untuk jadwal harian'],
+  invalid_open_time: ['open_time', 'Format jam buka tidak valid (gunakan HH:MM)'],
+  invalid_close_time: ['close_time', 'Format jam tutup tidak valid (gunakan HH:MM)'],
+  open_time_required: ['open_time', 'Jam buka wajib diisi jika tidak tutup'],
+  close_time_required: ['close_time', 'Jam tutup wajib diisi jika tidak tutup'],
+  invalid_zero_time: [null, '00:00 bukan waktu yang valid untuk hari buka'],
+  invalid_hours_count: ['hours', 'Jam operasional harus mencakup ketujuh hari'],
+  invalid_day_of_week: ['day_of_week', 'Hari harus bernilai 0 sampai 6'],
+  invalid_days_of_week: ['days_of_week', 'Hari harus bernilai 0 (Minggu) sampai 6 (Sabtu)'],
+  invalid_window: [null, 'Waktu selesai harus setelah waktu mulai'],
+  missing_window: [null, 'Waktu mulai dan selesai wajib diisi'],
+  invalid_validity: ['valid_until', 'valid_until harus setelah valid_from'],
+  invalid_effective_from: ['effective_from', 'Tanggal berlaku harus berformat YYYY-MM-DD'],
+  invalid_period_type: ['period_type', 'Jenis periode harus harian atau mingguan'],
+  invalid_expiry: ['expires_in_minutes', 'Batas waktu parkir tidak valid'],
+
+  // Orders
+  empty_order: ['items', 'Pesanan harus berisi minimal satu item'],
+  items_required: ['items', 'Minimal satu item wajib diisi'],
+  no_changes: ['items', 'Tidak ada perubahan item'],
+  duplicate_item_change: ['items', 'Setiap item hanya boleh diubah sekali per permintaan'],
+  invalid_item: ['items', 'Item tidak valid'],
+  invalid_quantity: ['quantity', 'Jumlah tidak valid'],
+  invalid_weight: ['weight_grams', 'Berat tidak valid'],
+  invalid_scale_device: ['scale_device', 'Nama timbangan maksimal 100 karakter'],
+  not_sold_by_weight: ['weight_grams', 'Produk ini dijual per satuan, bukan per berat'],
+  invalid_order_type: ['order_type', 'Jenis pesanan harus dine_in, takeout atau delivery'],
+  invalid_order_types: ['order_types', 'Jenis pesanan tidak valid'],
+  invalid_status: ['status', 'Status tidak valid'],
+  missing_status: ['status', 'Status wajib diisi'],
+  invalid_order_status: [null, 'Pesanan tidak dapat diproses pada status saat ini'],
+  invalid_order_id: ['order_id', 'order_id tidak valid'],
+  table_required_for_dine_in: ['table_id', 'Meja wajib dipilih untuk pesanan makan di tempat'],
+  table_id_required: ['table_id', 'ID meja wajib diisi'],
+  table_not_found: ['table_id', 'Meja tidak ditemukan'],
+  missing_table_number: ['table_number', 'Nomor meja wajib diisi'],
+  duplicate_table_number: ['table_number', 'Nomor meja sudah digunakan meja lain'],
+  duplicate_user: [null, 'Username atau email sudah digunakan pengguna lain'],
+  table_has_active_orders: [null, 'Meja yang masih memiliki pesanan aktif tidak dapat dihapus'],
+  qr_code_required: ['qr_code', 'Kode QR wajib diisi'],
+  customer_name_required: ['customer_name', 'Nama pelanggan wajib diisi untuk pesanan bawa pulang dan antar'],
+  customer_name_too_long: ['customer_name', 'Nama pelanggan terlalu panjang (maksimal 100 karakter)'],
+  delivery_address_required: ['delivery_address', 'Alamat pengiriman wajib diisi untuk pesanan antar'],
+  delivery_address_too_long: ['delivery_address', 'Alamat pengiriman terlalu panjang (maksimal 500 karakter)'],
+  delivery_notes_too_long: ['delivery_notes', 'Catatan pengiriman terlalu panjang (maksimal 500 karakter)'],
+  invalid_delivery_phone: ['delivery_phone', 'Nomor telepon yang valid wajib diisi untuk pesanan antar'],
+  below_delivery_minimum: [null, 'Total pesanan belum mencapai minimum pesanan antar'],
+  invalid_delivery_status: ['status', 'Status pengiriman tidak valid'],
+  invalid_event_status: ['status', 'Status event webhook tidak valid'],
+  not_delivery_order: [null, 'Hanya pesanan antar yang dapat memiliki kurir'],
+  missing_courier_id: ['courier_id', 'ID kurir wajib diisi'],
+  invalid_scheduled_at: ['scheduled_at', 'Waktu terjadwal harus berupa timestamp ISO 8601'],
+  scheduled_at_in_past: ['scheduled_at', 'Waktu terjadwal harus di masa depan'],
+  scheduled_at_too_far: ['scheduled_at', 'Waktu terjadwal terlalu jauh ke depan'],
+  schedule_not_supported: ['scheduled_at', 'Hanya pesanan bawa pulang dan antar yang dapat dijadwalkan; gunakan reservasi untuk makan di tempat'],
+  outside_operating_hours: ['scheduled_at', 'Waktu terjadwal di luar jam operasional'],
+  invalid_park: ['park', 'Pesanan terjadwal tidak dapat diparkir'],
+  invalid_tab_id: ['tab_id', 'ID tab tidak valid'],
+  invalid_tab_order: ['tab_id', 'Hanya pesanan makan di tempat dan bawa pulang langsung yang dapat ditambahkan ke tab'],
+  tab_customer_required: ['customer_name', 'Nama tamu wajib diisi untuk membuka tab'],
+  invalid_table_id: ['table_id', 'ID meja tidak valid'],
+  invalid_containers: ['containers', 'Setiap wadah membutuhkan container_type_id dan jumlah bulat positif'],
+  missing_containers: ['containers', 'Sebutkan wadah yang dikembalikan'],
+  container_type_not_found: ['containers', 'Jenis wadah tidak dikenal atau tidak aktif'],
+  unsupported_currency: ['currency', 'Mata uang tidak didukung'],
+  invalid_receipt_language: ['receipt_language', "Bahasa struk harus 'id' atau 'id_en'"],
+  missing_receipt_language: ['receipt_language', 'receipt_language wajib diisi (null untuk menghapus)'],
+  no_customer_phone: [null, 'Pesanan ini tidak memiliki nomor telepon pelanggan'],
+  invalid_restock_items: ['restock_items', 'Item restock tidak valid'],
+  order_not_completed: [null, 'Survei hanya dapat diisi untuk pesanan yang sudah selesai'],
+  invalid_rating: ['overall_rating', 'Penilaian harus antara 1 dan 5'],
+  invalid_action: ['action', 'Aksi harus confirm atau cancel'],
+
+  // Products and menu
+  product_not_found: ['product_id', 'Produk tidak ditemukan'],
+  product_not_available: ['product_id', 'Produk sedang tidak tersedia'],
+  invalid_product_id: ['product_id', 'product_id tidak valid'],
+  missing_product_id: ['product_id', 'ID produk wajib diisi'],
+  category_not_found: ['category_id', 'Kategori tidak ditemukan'],
+  invalid_category_id: ['category_id', 'ID kategori tidak valid'],
+  missing_category_id: ['category_id', 'ID kategori wajib diisi'],
+  invalid_price: ['price', 'Harga harus lebih dari 0'],
+  invalid_sale_unit: ['sale_unit', 'Satuan jual tidak valid'],
+  invalid_station: ['station', 'Stasiun dapur tidak valid'],
+  invalid_color: ['color', 'Warna harus berupa kode hex seperti #DC2626'],
+  invalid_sort_priority: ['sort_priority', 'Prioritas urutan harus berupa bilangan bulat'],
+  invalid_grouping: ['group_items_by', 'Pengelompokan item tidak valid'],
+  invalid_severity: ['severity', 'Tingkat notifikasi harus info, warning, atau critical'],
+  invalid_group: ['kds_group', 'Grup tampilan dapur maksimal 50 karakter'],
+  invalid_eighty_sixed: ['eighty_sixed', 'eighty_sixed harus bernilai boolean'],
+  invalid_daily_quantity: ['daily_quantity', 'Porsi harian harus bilangan bulat positif'],
+  sold_by_weight: ['product_id', 'Produk yang dijual per berat tidak dapat menjadi menu spesial harian'],
+  invalid_allergen: ['allergens', 'Alergen tidak dikenal'],
+  invalid_tag: ['dietary_tags', 'Label diet tidak dikenal'],
+  invalid_spicy_level: ['spicy_level', 'Tingkat pedas harus bilangan bulat dari 0 sampai 3'],
+  invalid_max_spicy: ['max_spicy', 'Tingkat pedas maksimum harus bilangan bulat dari 0 sampai 3'],
+  conflicting_dietary_info: ['dietary_tags', 'Produk vegan tidak boleh mengandung susu, telur, ikan atau kerang'],
+  missing_components: ['components', 'Komponen wajib diisi'],
+  invalid_component: ['components', 'Komponen paket tidak valid'],
+  component_not_found: ['components', 'Produk komponen tidak ditemukan'],
+  duplicate_component: ['components', 'Setiap produk hanya boleh dicantumkan sekali'],
+  nested_bundle: ['components', 'Paket tidak dapat berisi paket lain'],
+  invalid_platform: ['platform', 'Platform pengiriman tidak dikenal'],
+  invalid_provider: ['provider', 'Penyedia webhook tidak dikenal'],
+  invalid_platform_item_id: ['platform_item_id', 'platform_item_id wajib diisi (maksimal 100 karakter)'],
+  platform_not_configured: ['platform', 'Platform belum dikonfigurasi'],
+
+  // Pricing, discounts and taxes
+  missing_rule_type: ['rule_type', 'Jenis aturan wajib diisi'],
+  invalid_rule_type: ['rule_type', 'Jenis aturan tidak valid'],
+  invalid_bundle: ['product_ids', 'Aturan paket membutuhkan minimal dua produk'],
+  missing_discount_type: ['discount_type', 'Jenis diskon wajib diisi'],
+  invalid_discount_type: ['discount_type', 'Jenis diskon harus persentase atau nominal tetap'],
+  missing_discount_value: ['discount_value', 'Nilai diskon wajib diisi'],
+  invalid_discount_value: ['discount_value', 'Nilai diskon tidak valid'],
+  invalid_percentage: ['percentage', 'Persentase harus lebih dari 0 dan maksimal 100'],
+  invalid_rate: ['rate', 'Tarif tidak valid'],
+  invalid_target: [null, 'Isi salah satu dari product_id atau category_id'],
+  invalid_targets: [null, 'product_ids dan category_ids harus berupa daftar ID'],
+  missing_targets: [null, 'Isi product_ids atau category_ids'],
+  missing_price_type: ['price_type', 'Jenis harga wajib diisi'],
+  invalid_price_type: ['price_type', 'Jenis harga tidak valid'],
+  missing_value: ['value', 'Nilai wajib diisi'],
+  invalid_schedule: [null, 'Jadwal membutuhkan produk atau kategori, dan persentase tidak boleh melebihi 100'],
+  missing_surcharge_type: ['surcharge_type', 'Jenis biaya tambahan wajib diisi'],
+  invalid_surcharge_type: ['surcharge_type', 'Jenis biaya tambahan tidak valid'],
+  tax_class_not_found: ['tax_class_id', 'Kelas pajak tidak ditemukan'],
+  invalid_decimal_places: ['decimal_places', 'Jumlah desimal harus bilangan bulat dari 0 sampai 4'],
+  settlement_currency: ['code', 'IDR adalah mata uang penyelesaian dan tidak memerlukan kurs'],
+
+  // Payments and accounts
+  invalid_amount: ['amount', 'Jumlah harus lebih dari nol'],
+  amount_exceeds_balance: ['amount', 'Jumlah pembayaran melebihi sisa tagihan'],
+  amount_exceeds_limit: ['amount', 'Jumlah pembayaran melebihi batas maksimum'],
+  amount_exceeds_refundable: ['amount', 'Jumlah refund melebihi saldo yang dapat dikembalikan'],
+  amount_mismatch: ['amount', 'Jumlah pembayaran harus sama dengan sisa tagihan'],
+  invalid_payment_method: ['payment_method', 'Metode pembayaran tidak valid'],
+  invalid_payment_status: [null, 'Pembayaran tidak dapat direfund pada status saat ini'],
+  order_fully_paid: [null, 'Pesanan sudah lunas'],
+  invalid_order_ids: ['order_ids', 'order_ids harus berisi 2 sampai 20 ID pesanan'],
+  mixed_branches: ['order_ids', 'Semua pesanan dalam satu pembayaran harus dari cabang yang sama'],
+  order_cancelled: [null, 'Pesanan yang dibatalkan tidak dapat dibayar'],
+  cannot_refund_refund: [null, 'Refund tidak dapat direfund lagi'],
+  invalid_refund_method: ['refund_method', 'Metode refund tidak valid'],
+  invalid_refund_reason: ['reason', 'Alasan refund tidak valid'],
+  missing_refund_notes: ['notes', 'Catatan wajib diisi jika alasan refund adalah lainnya'],
+  deposit_not_found: ['container_type_id', 'Pesanan ini tidak memiliki deposit untuk jenis wadah tersebut'],
+  deposit_not_paid: [null, 'Deposit hanya dapat dikembalikan setelah pesanan dibayar'],
+  invalid_deposit_amount: ['deposit_amount', 'Jumlah deposit harus lebih dari 0'],
+  gateway_not_configured: [null, 'Payment gateway belum dikonfigurasi'],
+  missing_corporate_account: ['corporate_account_id', 'Akun korporat wajib diisi untuk pembayaran tagihan'],
+  corporate_account_inactive: [null, 'Akun korporat tidak aktif'],
+  on_account_not_enabled: [null, 'Akun korporat belum disetujui untuk pembayaran tagihan'],
+  credit_limit_exceeded: ['amount', 'Tagihan melebihi sisa batas kredit'],
+  invalid_credit_limit: ['credit_limit', 'Batas kredit tidak boleh negatif'],
+  missing_company_name: ['company_name', 'Nama perusahaan wajib diisi'],
+  missing_employee_code: ['employee_code', 'Kode karyawan wajib diisi untuk pembayaran dompet korporat'],
+  insufficient_balance: ['amount', 'Saldo dompet korporat tidak mencukupi'],
+  daily_limit_exceeded: ['amount', 'Batas harian karyawan terlampaui'],
+  invalid_daily_limit: ['daily_limit', 'Batas harian harus lebih dari nol'],
+  invalid_method: ['method', 'Metode isi ulang tidak valid'],
+  invalid_topup: [null, 'Akun korporat tidak ditemukan atau penyesuaian melebihi saldo'],
+  invalid_invoice_status: [null, 'Tagihan tidak dapat diproses pada status saat ini'],
+  no_charges: [null, 'Tidak ada tagihan yang belum ditagihkan pada periode ini'],
+  invalid_bonus_amount: ['bonus_amount', 'Jumlah bonus harus lebih dari nol'],
+  invalid_target_amount: ['target_amount', 'Target harus lebih dari nol'],
+
+  // Stock
+  missing_ingredient_id: ['ingredient_id', 'ingredient_id wajib diisi'],
+  invalid_item_type: ['item_type', "item_type harus 'product' atau 'ingredient'"],
+  invalid_cost_override: ['cost_override', 'cost_override harus nominal tidak negatif atau null'],
+  missing_cost_override: ['cost_override', 'cost_override wajib diisi (null untuk menghapus)'],
+  invalid_counted_quantity: ['counted_quantity', 'Jumlah hitungan tidak valid'],
+  missing_counted_quantity: ['counted_quantity', 'counted_quantity wajib diisi (null untuk menghapus)'],
+  missing_counts: ['counts', 'counts harus berupa daftar yang tidak kosong'],
+  item_not_in_stock_take: ['counts', 'Item tidak termasuk dalam stock take ini'],
+  invalid_scope: ['scope', 'Cakupan stock take tidak valid'],
+  invalid_batch_code: ['batch_code', 'Kode batch maksimal 50 karakter'],
+  invalid_days: ['days', 'Jumlah hari harus antara 0 dan 365'],
+  missing_unit: ['unit', 'Satuan wajib diisi'],
+  invalid_current_stock: ['current_stock', 'Stok saat ini harus berupa angka tidak negatif'],
+  invalid_minimum_stock: ['minimum_stock', 'Stok minimum harus berupa angka tidak negatif'],
+  invalid_maximum_stock: ['maximum_stock', 'Stok maksimum harus berupa angka tidak negatif'],
+  invalid_unit_cost: ['unit_cost', 'Biaya per satuan harus berupa angka tidak negatif'],
+  invalid_expiry_date: ['expiry_date', 'Tanggal kedaluwarsa harus berformat YYYY-MM-DD'],
+  missing_operation: ['operation', 'Operasi wajib diisi'],
+  invalid_operation: ['operation', "Operasi harus 'add' atau 'remove'"],
+  insufficient_stock: ['quantity', 'Stok tidak mencukupi'],
+  invalid_ledger_type: ['type', 'Jenis mutasi stok tidak valid'],
+
+  // Staff, roles and administration
+  invalid_role: ['role', 'Peran tidak valid'],
+  invalid_permission: ['permissions', 'Izin tidak dikenal'],
+  admin_lockout: ['permissions', 'Peran admin harus tetap memiliki izin roles.manage'],
+  system_role: [null, 'Peran bawaan tidak dapat dihapus'],
+  cannot_delete_self: [null, 'Anda tidak dapat menghapus akun sendiri'],
+  weak_password: ['new_password', 'Kata sandi terlalu lemah'],
+  invalid_reset_token: ['token', 'Tautan atur ulang kata sandi tidak valid atau sudah kedaluwarsa'],
+  branch_not_found: ['branch_id', 'Cabang tidak ditemukan'],
+  invalid_branch_id: ['branch_id', 'branch_id tidak valid'],
+  invalid_user_id: ['user_id', 'user_id tidak valid'],
+  default_branch: [null, 'Cabang utama tidak dapat dinonaktifkan'],
+  invalid_location: [null, 'latitude dan longitude harus diisi bersamaan dengan koordinat yang valid, atau keduanya null'],
+  invalid_device_id: ['device_id', 'device_id tidak valid'],
+  invalid_endpoint_id: ['endpoint_id', 'endpoint_id tidak valid'],
+  invalid_events: ['events', 'Event tidak dikenal'],
+  missing_events: ['events', 'Pilih minimal satu event'],
+  invalid_format: ['format', 'Format tidak valid'],
+  email_not_configured: [null, 'Email belum dikonfigurasi'],
+  submission_is_spam: [null, 'Pesan yang ditandai sebagai spam tidak dapat dibalas'],
+  invalid_contact_dates: [null, 'start_date dan end_date harus berupa tanggal'],
+  invalid_runtime_setting: [null, 'Nilai pengaturan runtime tidak valid'],
+  nothing_to_roll_back: [null, 'Tidak ada pengaturan yang dapat dikembalikan dari perubahan ini'],
+  invalid_email_enabled: ['email_enabled', 'email_enabled harus bernilai true atau false'],
+  invalid_notification_types: ['types_enabled', 'Jenis notifikasi tidak dikenal atau nilainya bukan true/false'],
+  invalid_quiet_hours: [null, 'Jam tenang harus berformat HH:MM dan diisi awal serta akhirnya'],
+  invalid_preference_fields: ['fields', 'Daftar preferensi tidak valid'],
+  invalid_incident_title: ['title', 'Judul insiden wajib diisi (maksimal 150 karakter)'],
+  invalid_incident_message: ['message', 'Keterangan insiden maksimal 2000 karakter'],
+  invalid_incident_severity: ['severity', 'Tingkat insiden harus minor, major, atau critical'],
+  invalid_incident_component: ['component', 'Komponen insiden tidak valid'],
+  invalid_section: ['section', 'Bagian tidak valid'],
+  invalid_surcharge_percent: ['surcharge_percent', 'surcharge_percent harus antara 0 dan 100'],
+  invalid_is_active: ['is_active', 'is_active harus bernilai true atau false'],
+  invalid_customer_selectable: ['customer_selectable', 'customer_selectable harus bernilai true atau false'],
+  invalid_sort_order: ['sort_order', 'sort_order harus berupa bilangan bulat'],
+  invalid_course: ['course', 'course harus berupa bilangan bulat dari 1 sampai 9'],
+  courses_dine_in_only: ['course', 'Course hanya dapat digunakan untuk pesanan dine-in'],
+  invalid_label: ['label', 'Label wajib diisi (maksimal 60 karakter)'],
+  invalid_customer_visible: ['customer_visible', 'customer_visible harus bernilai true atau false'],
+  invalid_note_phrases: ['note_phrases', 'note_phrases harus berupa daftar kode frasa (maksimal 10)'],
+  invalid_note_phrase: ['note_phrase', 'Kode frasa catatan tidak valid'],
+  note_phrase_unavailable: ['note_phrases', 'Frasa catatan tidak tersedia untuk produk ini'],
+  invalid_kitchen_flag: ['kitchen_flag', 'kitchen_flag harus allergy, attention, atau null'],
+  invalid_table_ids: ['table_ids', 'table_ids harus berupa daftar ID meja'],
+  duplicate_name: ['name', 'Nama sudah digunakan'],
+  section_not_found: [null, 'Seksi tidak ditemukan'],
+  user_not_found: ['user_id', 'Pengguna tidak ditemukan'],
+  shift_too_long: ['ends_at', 'Satu shift maksimal 16 jam'],
+  assignment_overlap: ['starts_at', 'Pelayan ini sudah memiliki shift yang bertumpang tindih di seksi ini'],
+  assignment_not_found: [null, 'Penugasan tidak ditemukan'],
+  invalid_covers: ['covers', 'covers harus berupa bilangan bulat dari 1 sampai 100'],
+  invalid_note: ['note', 'Catatan maksimal 500 karakter'],
+  invalid_replay_selection: [null, 'Kirim ids atau job_type, tidak keduanya'],
+  invalid_ids: ['ids', 'ids harus berupa daftar 1 sampai 200 ID dead letter'],
+  invalid_job_type: ['job_type', 'job_type harus berupa jenis job'],
+  invalid_tip_amount: ['tip_amount', 'tip_amount harus berupa jumlah nol atau lebih'],
+  tip_too_large: ['tip_amount', 'Tip melebihi jumlah pembayaran'],
+  tip_not_adjustable: ['tip_amount', 'Tip sudah ditagihkan ke perusahaan bersama pembayaran dan tidak dapat diubah'],
+  invalid_stream: ['stream', 'stream harus salah satu dari orders, order_items, payments, inventory_movements'],
+  stream_not_found: [null, 'Stream ekspor tidak ditemukan'],
+  invalid_since: ['since', 'since harus berupa tanggal YYYY-MM-DD atau null'],
+  export_not_configured: [null, 'Ekspor analitik belum dikonfigurasi'],
+  invalid_rating_range: ['min_rating', 'min_rating dan max_rating harus bilangan bulat 1 sampai 5, dengan min tidak melebihi max'],
+  invalid_has_comments: ['has_comments', 'has_comments harus bernilai true atau false'],
+  invalid_server_id: ['server_id', 'ID server tidak valid'],
+  invalid_min_surveys: ['min_surveys', 'min_surveys harus bilangan bulat minimal 1'],
+  survey_not_found: [null, 'Survei tidak ditemukan'],
+  survey_has_no_email: [null, 'Tamu tidak meninggalkan alamat email untuk dibalas'],
+  pin_required: ['pin', 'Masukkan PIN absensi Anda (4 sampai 8 digit)'],
+  invalid_pin: ['pin', 'PIN absensi salah'],
+  pin_locked: ['pin', 'Terlalu banyak PIN salah; coba lagi nanti atau minta manajer mengatur ulang PIN Anda'],
+  attendance_pin_not_set: ['pin', 'PIN absensi Anda belum diatur; hubungi manajer'],
+  invalid_pin_format: ['pin', 'pin harus 4 sampai 8 digit, atau null untuk menghapusnya'],
+  terminal_required: ['device_id', 'Absen masuk dan keluar di terminal absensi cabang'],
+  terminal_not_allowed: ['device_id', 'Terminal ini tidak diaktifkan untuk absensi di cabang Anda'],
+  invalid_attendance_clock: ['attendance_clock', 'attendance_clock harus bernilai true atau false'],
+  already_clocked_in: [null, 'Anda sudah absen masuk'],
+  not_clocked_in: [null, 'Anda belum absen masuk'],
+  invalid_break_minutes: ['break_minutes', 'break_minutes harus bilangan bulat 0 sampai 240 dan lebih pendek dari shift'],
+  invalid_shift_note: ['note', 'Catatan shift maksimal 200 karakter'],
+  shift_overlap: ['starts_at', 'Staf ini sudah memiliki shift yang bertumpang tindih'],
+  shift_not_found: [null, 'Shift tidak ditemukan'],
+  attendance_not_found: [null, 'Catatan absensi tidak ditemukan'],
+  invalid_clock_times: ['clock_out_at', 'Waktu keluar harus setelah waktu masuk, dan keduanya tidak boleh di masa depan'],
+  attendance_overlap: ['clock_in_at', 'Waktu ini bertumpang tindih dengan catatan absensi lain milik staf ini'],
+};
+
+// ── RequestLocale ───────────────────────────────────────────────────────────
+// Highest-weighted supported language in Accept-Language; English otherwise.
+// 'in' is the legacy code some Android devices still send for Indonesian.
+
+export function requestLocale(c: Context): Locale {
+  const header = c.req.header('Accept-Language');
+  if (!header) return 'en';
+
+  const ranked = header
+    .split(',')
+    .map((part) => {
+      const [tag, ...params] = part.trim().toLowerCase().split(';');
+      const q = params.find((p) => p.trim().startsWith('q='));
+      return { lang: tag.split('-')[0], weight: q ? Number(q.trim().slice(2)) : 1 };
+    })
+    .filter((entry) => entry.lang && !isNaN(entry.weight) && entry.weight > 0)
+    .sort((a, b) => b.weight - a.weight);
+
+  for (const { lang } of ranked) {
+    if (lang === 'id' || lang === 'in') return 'id';
+    if (lang === 'en') return 'en';
+  }
+  return 'en';
+}
+
+function fill(template: string, params: Record<string, string | number>): string {
+  return template.replace(/\{(\w+)\}/g, (match, key) => (key in params ? String(params[key]) : match));
+}
+
+// ── ValidationFieldError ────────────────────────────────────────────────────
+// The field-level form of an error with this code. `message` is the English
+// text, used as is unless the client asked for Indonesian.
+
+export function validationFieldError(
+  locale: Locale,
+  code: string,
+  message: string,
+  field?: string | null,
+  params: Record<string, string | number> = {},
+): FieldError {
+  const entry = CATALOG[code];
+  const resolvedField = field !== undefined ? field : entry?.[0] ?? null;
+  const text = locale === 'id' && entry
+    ? fill(entry[1], { field: resolvedField ?? '', ...params })
+    : message;
+  return { field: resolvedField, code, message: text };
+}
+
+// The summary message for a response carrying several field errors
+export function validationSummary(locale: Locale): string {
+  return locale === 'id' ? CATALOG.validation_failed[1] : 'Validation failed';
+}
@@ -1,21 +1,71 @@
 import type { Context } from 'hono';
-import type { ZodSchema, ZodError } from 'zod';
-import { errorResponse } from './response.js';
+import type { ZodSchema, ZodError, ZodIssue } from 'zod';
+import { errorResponse, validationErrorResponse } from './response.js';
+import { requestLocale, validationFieldError, type FieldError, type Locale } from './validation-messages.js';
 
-export async function validateBody<T>(c: Context, schema: ZodSchema<T>): Promise<T | null> {
-  try {
-    const body = await c.req.json();
-    const result = schema.safeParse(body);
-    if (!result.success) {
-      const messages = (result.error as ZodError).errors.map((e) => `${e.path.join('.')}: ${e.message}`).join(', ');
-      errorResponse(c, 'Validation failed', messages, 400);
-      return null;
+// English text for schema rule failures; Indonesian is in the catalog
+const RULE_MESSAGES: Record<string, string> = {
+  required: '{field} is required',
+  invalid_type: '{field} has the wrong type',
+  too_short: '{field} must be at least {min} characters',
+  too_long: '{field} must be at most {max} characters',
+  too_small: '{field} must be at least {min}',
+  too_large: '{field} must be at most {max}',
+  too_few: '{field} must have at least {min} items',
+  too_many: '{field} must have at most {max} items',
+  invalid_option: '{field} must be one of: {options}',
+  invalid_format: '{field} has an invalid format',
+  validation_error: '{field} is invalid',
+};
+
+function ruleFor(issue: ZodIssue): { code: string; params: Record<string, string | number> } {
+  switch (issue.code) {
+    case 'invalid_type':
+      return { code: issue.received === 'undefined' ? 'required' : 'invalid_type', params: {} };
+    case 'too_small': {
+      const code = issue.type === 'string' ? 'too_short' : issue.type === 'array' ? 'too_few' : 'too_small';
+      return { code, params: { min: Number(issue.minimum) } };
+    }
+    case 'too_big': {
+      const code = issue.type === 'string' ? 'too_long' : issue.type === 'array' ? 'too_many' : 'too_large';
+      return { code, params: { max: Number(issue.maximum) } };
     }
-    return result.data;
+    case 'invalid_enum_value':
+      return { code: 'invalid_option', params: { options: issue.options.join(', ') } };
+    case 'invalid_string':
+    case 'invalid_date':
+      return { code: 'invalid_format', params: {} };
+    default:
+      return { code: 'validation_error', params: {} };
+  }
+}
+
+/** Field-level errors for a failed schema parse, in the client's language */
+export function zodFieldErrors(locale: Locale, error: ZodError): FieldError[] {
+  return error.errors.map((issue) => {
+    const field = issue.path.length > 0 ? issue.path.join('.') : null;
+    const { code, params } = ruleFor(issue);
+    const english = RULE_MESSAGES[code].replace(/\{(\w+)\}/g, (_, key) =>
+      key === 'field' ? field ?? 'Request body' : String(params[key]));
+    return validationFieldError(locale, code, english, field, { ...params, field: field ?? 'body' });
+  });
+}
+
+export async function validateBody<T>(
+  c: Context,
+  schema: ZodSchema<T>,
+): Promise<{ ok: true; data: T } | { ok: false; response: Response }> {
+  let body: unknown;
+  try {
+    body = await c.req.json();
   } catch {
-    errorResponse(c, 'Invalid JSON body', 'Request body must be valid JSON', 400);
-    return null;
+    return { ok: false, response: errorResponse(c, 'Invalid request body', 'invalid_json', 400) };
+  }
+  const result = schema.safeParse(body);
+  if (!result.success) {
+    return { ok: false, response: validationErrorResponse(c, zodFieldErrors(requestLocale(c), result.error)) };
   }
+  return { ok: true, data: result.data };
 }
 
 /** Convert Drizzle decimal string values to numbers for JSON response */
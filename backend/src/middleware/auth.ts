@@ -1,36 +1,72 @@
 import { createMiddleware } from 'hono/factory';
+import { pool } from '../db/connection.js';
 import { validateToken, type JWTClaims } from '../lib/jwt.js';
+import { loadRolePermissions } from '../services/permissions.js';
+import { CSRF_HEADER, csrfCheck, sessionCookie } from '../lib/session.js';
 
 declare module 'hono' {
   interface ContextVariableMap {
     user_id: string;
     username: string;
     role: string;
+    branch_id: string | null;
+    permissions: Set<string>;
     jwtClaims: JWTClaims;
   }
 }
 
+// A Bearer token in the Authorization header, or failing that the session
+// cookie set at login. Cookie-authenticated writes need the CSRF token too
+// (see lib/session.ts). A token issued before the user's token_version was
+// last bumped (password reset or change, role or branch change) is
+// rejected, so those end every earlier session. So is any token of a
+// deactivated or deleted account.
 export const authMiddleware = createMiddleware(async (c, next) => {
   const authHeader = c.req.header('Authorization');
+  const cookieToken = authHeader ? undefined : sessionCookie(c);
 
-  if (!authHeader) {
+  if (!authHeader && !cookieToken) {
     return c.json({ success: false, message: 'Authorization header is required', error: 'missing_auth_header' }, 401);
   }
 
-  if (!authHeader.startsWith('Bearer ')) {
+  if (authHeader && !authHeader.startsWith('Bearer ')) {
     return c.json({ success: false, message: 'Invalid authorization header format', error: 'invalid_auth_format' }, 401);
   }
 
-  const token = authHeader.slice(7);
+  const token = authHeader ? authHeader.slice(7) : cookieToken!;
 
+  let claims: JWTClaims;
   try {
-    const claims = validateToken(token);
-    c.set('user_id', claims.user_id);
-    c.set('username', claims.username);
-    c.set('role', claims.role);
-    c.set('jwtClaims', claims);
-    await next();
+    claims = validateToken(token);
   } catch {
     return c.json({ success: false, message: 'Invalid or expired token', error: 'invalid_token' }, 401);
   }
+
+  if (cookieToken) {
+    const csrf = csrfCheck(c, cookieToken);
+    if (csrf !== 'ok') {
+      return c.json({
+        success: false,
+        message: csrf === 'missing' ? `${CSRF_HEADER} header is required` : 'Invalid CSRF token',
+        error: csrf === 'missing' ? 'missing_csrf_token' : 'invalid_csrf_token',
+      }, 403);
+    }
+  }
+
+  const userRes = await pool.query('SELECT token_version, is_active, deleted_at FROM users WHERE id = $1', [claims.user_id]);
+  const account = userRes.rows[0];
+  if (!account || account.deleted_at || !account.is_active) {
+    return c.json({ success: false, message: 'Account is disabled', error: 'account_disabled' }, 401);
+  }
+  if (account.token_version !== (claims.token_version ?? 0)) {
+    return c.json({ success: false, message: 'Session has ended; log in again', error: 'session_revoked' }, 401);
+  }
+
+  c.set('user_id', claims.user_id);
+  c.set('username', claims.username);
+  c.set('role', claims.role);
+  c.set('branch_id', claims.branch_id ?? null);
+  c.set('permissions', await loadRolePermissions(claims.role));
+  c.set('jwtClaims', claims);
+  await next();
 });
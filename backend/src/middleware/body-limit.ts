@@ -0,0 +1,44 @@
+import type { Context } from 'hono';
+import { createMiddleware } from 'hono/factory';
+import { bodyLimit } from 'hono/body-limit';
+import { env } from '../env.js';
+import { errorResponse } from '../lib/response.js';
+import { requestsRejectedTotal } from '../lib/metrics.js';
+
+// Request body size limits. The API takes JSON almost everywhere, which
+// never needs more than MAX_BODY_KB; multipart bodies are image uploads and
+// get MAX_UPLOAD_BODY_MB, a little over the upload handler's own per-file
+// limit. A body over its limit is refused with 413 as soon as its
+// Content-Length (or, for a chunked body, the bytes read so far) gives it
+// away, so nothing oversized is buffered.
+
+export const JSON_BODY_LIMIT_BYTES = env.MAX_BODY_KB * 1024;
+export const UPLOAD_BODY_LIMIT_BYTES = env.MAX_UPLOAD_BODY_MB * 1024 * 1024;
+
+function formatSize(bytes: number): string {
+  return bytes >= 1024 * 1024 ? `${bytes / (1024 * 1024)} MB` : `${bytes / 1024} KB`;
+}
+
+function tooLarge(maxBytes: number) {
+  return (c: Context) => {
+    requestsRejectedTotal.inc({ reason: 'body_too_large' });
+    return errorResponse(c, `Request body too large. Maximum size is ${formatSize(maxBytes)}`, 'payload_too_large', 413);
+  };
+}
+
+/** Refuses bodies over `maxBytes` with 413. */
+export function bodySizeLimit(maxBytes: number) {
+  return bodyLimit({ maxSize: maxBytes, onError: tooLarge(maxBytes) });
+}
+
+const jsonLimit = bodySizeLimit(JSON_BODY_LIMIT_BYTES);
+const uploadLimit = bodySizeLimit(UPLOAD_BODY_LIMIT_BYTES);
+
+// ── RequestBodyLimit ────────────────────────────────────────────────────────
+// For the whole API: the upload limit for multipart bodies, the JSON limit
+// for everything else.
+
+export const requestBodyLimit = createMiddleware(async (c, next) => {
+  const multipart = c.req.header('Content-Type')?.toLowerCase().startsWith('multipart/form-data');
+  return (multipart ? uploadLimit : jsonLimit)(c, next);
+});
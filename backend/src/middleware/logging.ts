@@ -1,19 +1,89 @@
+import type { Context } from 'hono';
 import { createMiddleware } from 'hono/factory';
+import { randomBytes } from 'node:crypto';
 import { v4 as uuidv4 } from 'uuid';
+import { captureMessage, type ErrorContext } from '../lib/sentry.js';
+import { LOG_LEVELS, runtimeConfig, type LogLevel } from '../lib/runtime-config.js';
 
 declare module 'hono' {
   interface ContextVariableMap {
     requestId: string;
+    /** W3C trace context: the caller's trace, or a new one */
+    traceId: string;
+    spanId: string;
+    /** Set once the error has been sent to Sentry */
+    errorReported: boolean;
   }
 }
 
+// Client-supplied IDs end up in logs, so only accept plain tokens
+const REQUEST_ID_RE = /^[A-Za-z0-9._:-]{1,128}$/;
+const TRACEPARENT_RE = /^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$/;
+
+export function errorContext(c: Context): ErrorContext {
+  return {
+    requestId: c.get('requestId'),
+    traceId: c.get('traceId'),
+    spanId: c.get('spanId'),
+    method: c.req.method,
+    path: c.req.routePath || c.req.path,
+    status: c.res.status,
+    userId: c.get('user_id'),
+  };
+}
+
+// Error bodies built without errorResponse (middleware, 404s, raw c.json in
+// handlers) get the request ID added here, so every error a client sees can
+// be matched to its log line and Sentry event
+async function withRequestId(res: Response, requestId: string): Promise<Response> {
+  if (!res.headers.get('content-type')?.includes('application/json')) return res;
+  let body: unknown;
+  try {
+    body = await res.clone().json();
+  } catch {
+    return res;
+  }
+  if (!body || typeof body !== 'object' || Array.isArray(body) || 'request_id' in body) return res;
+  return new Response(JSON.stringify({ ...body, request_id: requestId }), {
+    status: res.status,
+    headers: res.headers,
+  });
+}
+
+// Adopts the caller's X-Request-ID and traceparent when present, so the
+// frontend, proxies, logs and Sentry all use the same IDs for one request
 export const requestIdMiddleware = createMiddleware(async (c, next) => {
-  const requestId = c.req.header('X-Request-ID') || uuidv4();
+  const incoming = c.req.header('X-Request-ID');
+  const requestId = incoming && REQUEST_ID_RE.test(incoming) ? incoming : uuidv4();
+  const trace = TRACEPARENT_RE.exec(c.req.header('traceparent') ?? '');
+  const traceId = trace && !/^0+$/.test(trace[1]) ? trace[1] : randomBytes(16).toString('hex');
+
   c.set('requestId', requestId);
+  c.set('traceId', traceId);
+  c.set('spanId', randomBytes(8).toString('hex'));
   c.header('X-Request-ID', requestId);
+  c.header('X-Trace-ID', traceId);
+
   await next();
+
+  if (c.res.status < 400) return;
+
+  // Handlers catch their own errors and answer with an error response, so a
+  // 5xx that didn't reach onError is reported from here
+  if (c.res.status >= 500 && !c.get('errorReported')) {
+    const body = await c.res.clone().json().catch(() => null);
+    const message = [body?.message, body?.error].filter(Boolean).join(': ') || `HTTP ${c.res.status}`;
+    captureMessage(message, errorContext(c));
+  }
+
+  c.res = await withRequestId(c.res, requestId);
 });
 
+// Request lines below the runtime log level are dropped
+function shouldLog(level: LogLevel): boolean {
+  return LOG_LEVELS.indexOf(level) >= LOG_LEVELS.indexOf(runtimeConfig().log_level);
+}
+
 export const structuredLogger = createMiddleware(async (c, next) => {
   const start = Date.now();
   const method = c.req.method;
@@ -26,11 +96,14 @@ export const structuredLogger = createMiddleware(async (c, next) => {
   const requestId = c.get('requestId') || '-';
 
   const logLevel = status >= 500 ? 'ERROR' : status >= 400 ? 'WARN' : 'INFO';
+  if (!shouldLog(logLevel.toLowerCase() as LogLevel)) return;
 
   console.log(JSON.stringify({
     level: logLevel,
     timestamp: new Date().toISOString(),
     request_id: requestId,
+    trace_id: c.get('traceId'),
+    span_id: c.get('spanId'),
     method,
     path,
     status,
@@ -0,0 +1,30 @@
+import { createMiddleware } from 'hono/factory';
+import { runtimeConfig } from '../lib/runtime-config.js';
+
+// Maintenance mode (runtime config maintenance_mode). Everything answers 503
+// except what has to keep working while the system is down for staff and
+// customers: health checks, metrics and the status page, uploaded images,
+// login, the admin API (where maintenance mode is switched off again) and
+// provider callbacks, which would otherwise be lost or pile up as retries.
+const EXEMPT = [
+  /^\/metrics$/,
+  /^\/uploads\//,
+  /^\/api\/v1\/(health|ready)$/,
+  /^\/api\/v1\/public\/status$/,
+  /^\/api\/v1\/auth\//,
+  /^\/api\/v1\/admin\//,
+  /^\/api\/v1\/payments\/gateway\/notification$/,
+  /^\/api\/v1\/webhooks\/inbound\//,
+];
+
+export const maintenanceMode = createMiddleware(async (c, next) => {
+  const config = runtimeConfig();
+  if (!config.maintenance_mode || c.req.method === 'OPTIONS' || EXEMPT.some((re) => re.test(c.req.path))) {
+    return next();
+  }
+  return c.json({
+    success: false,
+    message: config.maintenance_message,
+    error: 'maintenance',
+  }, 503, { 'Retry-After': '300' });
+});
@@ -0,0 +1,19 @@
+import { createMiddleware } from 'hono/factory';
+import { httpRequestsTotal, httpRequestDuration } from '../lib/metrics.js';
+
+// Records request count and latency per matched route pattern (e.g.
+// /api/v1/orders/:id) so label cardinality stays bounded.
+export const metricsMiddleware = createMiddleware(async (c, next) => {
+  const start = process.hrtime.bigint();
+
+  await next();
+
+  // routePath reflects the last matched handler once next() has returned;
+  // unmatched requests collapse into a single series.
+  const route = c.res.status === 404 && c.req.routePath.endsWith('*') ? 'unmatched' : c.req.routePath;
+  const seconds = Number(process.hrtime.bigint() - start) / 1e9;
+  const labels = { method: c.req.method, route };
+
+  httpRequestsTotal.inc({ ...labels, status: String(c.res.status) });
+  httpRequestDuration.observe(seconds, labels);
+});
@@ -1,18 +1,21 @@
 import { createMiddleware } from 'hono/factory';
+import { runtimeConfig } from '../lib/runtime-config.js';
 
 interface Visitor {
   tokens: number;
   lastVisit: number;
 }
 
+// The bucket size is read on every request, so a limit changed in the
+// runtime config applies straight away
 class RateLimiter {
   private visitors = new Map<string, Visitor>();
-  private maxTokens: number;
+  private limit: () => number;
   private refillIntervalMs: number;
   private cleanupTimer: ReturnType<typeof setInterval>;
 
-  constructor(maxTokens: number, refillIntervalMs: number) {
-    this.maxTokens = maxTokens;
+  constructor(limit: () => number, refillIntervalMs: number) {
+    this.limit = limit;
     this.refillIntervalMs = refillIntervalMs;
     this.cleanupTimer = setInterval(() => this.cleanup(), 60000);
   }
@@ -28,17 +31,18 @@ class RateLimiter {
 
   allow(ip: string): boolean {
     const now = Date.now();
+    const maxTokens = this.limit();
     let v = this.visitors.get(ip);
 
     if (!v) {
-      v = { tokens: this.maxTokens - 1, lastVisit: now };
+      v = { tokens: maxTokens - 1, lastVisit: now };
       this.visitors.set(ip, v);
       return true;
     }
 
     const elapsed = now - v.lastVisit;
-    const tokensToAdd = Math.floor((elapsed / this.refillIntervalMs) * this.maxTokens);
-    v.tokens = Math.min(v.tokens + tokensToAdd, this.maxTokens);
+    const tokensToAdd = Math.floor((elapsed / this.refillIntervalMs) * maxTokens);
+    v.tokens = Math.min(v.tokens + tokensToAdd, maxTokens);
     v.lastVisit = now;
 
     if (v.tokens > 0) {
@@ -59,8 +63,8 @@ function getClientIp(c: { req: { header: (name: string) => string | undefined }
     || 'unknown';
 }
 
-export function rateLimitMiddleware(maxTokens: number, refillIntervalMs: number) {
-  const limiter = new RateLimiter(maxTokens, refillIntervalMs);
+export function rateLimitMiddleware(maxTokens: number | (() => number), refillIntervalMs: number) {
+  const limiter = new RateLimiter(typeof maxTokens === 'number' ? () => maxTokens : maxTokens, refillIntervalMs);
   return createMiddleware(async (c, next) => {
     const ip = getClientIp(c);
     if (!limiter.allow(ip)) {
@@ -74,6 +78,6 @@ export function rateLimitMiddleware(maxTokens: number, refillIntervalMs: number)
   });
 }
 
-export const publicRateLimiter = () => rateLimitMiddleware(30, 60000);
-export const strictRateLimiter = () => rateLimitMiddleware(5, 60000);
-export const contactFormRateLimiter = () => rateLimitMiddleware(3, 300000);
+export const publicRateLimiter = () => rateLimitMiddleware(() => runtimeConfig().rate_limit_public, 60000);
+export const strictRateLimiter = () => rateLimitMiddleware(() => runtimeConfig().rate_limit_login, 60000);
+export const contactFormRateLimiter = () => rateLimitMiddleware(() => runtimeConfig().rate_limit_contact, 300000);
@@ -0,0 +1,181 @@
+import type { Context } from 'hono';
+import { createMiddleware } from 'hono/factory';
+import { env } from '../env.js';
+import { reportRequestsTotal } from '../lib/metrics.js';
+
+// Throttling for expensive report endpoints. Each user gets a per-minute
+// request budget and a small number of reports running at once; a request
+// over the concurrency limit waits briefly for a slot before being turned
+// away with 429 and Retry-After.
+//
+// Identical requests (same path, query and branch scope) reuse one result:
+// a request arriving while the same report is being computed waits for it,
+// and successful results are kept for REPORT_CACHE_TTL_MS. Place the
+// middleware after the route's permission check so a cached result is never
+// served to someone who couldn't run the report.
+
+interface CachedReport {
+  status: number;
+  body: string;
+  headers: Record<string, string>;
+  expiresAt: number;
+}
+
+interface UserState {
+  /** Request timestamps within the last minute */
+  recent: number[];
+  running: number;
+  waiters: Array<() => void>;
+}
+
+const WINDOW_MS = 60_000;
+const CACHED_HEADERS = ['content-type', 'content-disposition'];
+
+const users = new Map<string, UserState>();
+const cache = new Map<string, CachedReport>();
+const inFlight = new Map<string, Promise<CachedReport | null>>();
+
+function userState(userId: string): UserState {
+  let state = users.get(userId);
+  if (!state) {
+    state = { recent: [], running: 0, waiters: [] };
+    users.set(userId, state);
+  }
+  return state;
+}
+
+// Results depend on the caller's branch (head office sees every branch), so
+// the branch is part of the key along with the path and sorted query
+function reportKey(path: string, query: Record<string, string>, branchId: string | null): string {
+  const params = Object.keys(query).sort().map((k) => `${k}=${query[k]}`).join('&');
+  return `${branchId ?? '*'}|${path}?${params}`;
+}
+
+function rejected(c: Context, retryAfterSeconds: number, message: string) {
+  return c.json({
+    success: false,
+    message,
+    error: 'report_rate_limited',
+  }, 429, { 'Retry-After': String(Math.max(1, retryAfterSeconds)) });
+}
+
+function serve(cached: CachedReport, hit: 'hit' | 'shared'): Response {
+  return new Response(cached.body, {
+    status: cached.status,
+    headers: { ...cached.headers, 'X-Report-Cache': hit },
+  });
+}
+
+// Waits for a free slot; false if none frees up within the timeout
+function acquireSlot(state: UserState, timeoutMs: number): Promise<boolean> {
+  if (state.running < env.REPORT_MAX_CONCURRENT) {
+    state.running++;
+    return Promise.resolve(true);
+  }
+  return new Promise((resolve) => {
+    const grant = () => {
+      clearTimeout(timer);
+      state.running++;
+      resolve(true);
+    };
+    const timer = setTimeout(() => {
+      state.waiters = state.waiters.filter((w) => w !== grant);
+      resolve(false);
+    }, timeoutMs);
+    state.waiters.push(grant);
+  });
+}
+
+function releaseSlot(state: UserState): void {
+  state.running--;
+  const next = state.waiters.shift();
+  if (next) next();
+}
+
+// Drops expired results and idle users so the maps don't grow unbounded
+setInterval(() => {
+  const now = Date.now();
+  for (const [key, entry] of cache) {
+    if (entry.expiresAt <= now) cache.delete(key);
+  }
+  for (const [userId, state] of users) {
+    if (state.running === 0 && state.waiters.length === 0 && state.recent.every((t) => now - t > WINDOW_MS)) {
+      users.delete(userId);
+    }
+  }
+}, WINDOW_MS).unref();
+
+export interface ReportThrottleOptions {
+  /**
+   * Serve identical requests from one result (default). Turn off for
+   * responses that are specific to the requester, such as watermarked
+   * exports, which keeps only the rate and concurrency limits.
+   */
+  shareResults?: boolean;
+}
+
+export function reportThrottle(options: ReportThrottleOptions = {}) {
+  const shareResults = options.shareResults ?? true;
+  return createMiddleware(async (c, next) => {
+    const key = reportKey(c.req.path, c.req.query(), c.get('branch_id') ?? null);
+
+    const cached = shareResults ? cache.get(key) : undefined;
+    if (cached && cached.expiresAt > Date.now()) {
+      reportRequestsTotal.inc({ outcome: 'cache_hit' });
+      return serve(cached, 'hit');
+    }
+
+    // Same report already being computed: wait for that result instead
+    const pending = shareResults ? inFlight.get(key) : undefined;
+    if (pending) {
+      const shared = await pending;
+      if (shared) {
+        reportRequestsTotal.inc({ outcome: 'shared' });
+        return serve(shared, 'shared');
+      }
+    }
+
+    const state = userState(c.get('user_id'));
+    const now = Date.now();
+    state.recent = state.recent.filter((t) => now - t < WINDOW_MS);
+    if (state.recent.length >= env.REPORT_RATE_LIMIT) {
+      reportRequestsTotal.inc({ outcome: 'rate_limited' });
+      return rejected(c, Math.ceil((state.recent[0] + WINDOW_MS - now) / 1000), 'Too many report requests. Please wait before refreshing.');
+    }
+
+    if (!(await acquireSlot(state, env.REPORT_QUEUE_TIMEOUT_MS))) {
+      reportRequestsTotal.inc({ outcome: 'busy' });
+      return rejected(c, Math.ceil(env.REPORT_QUEUE_TIMEOUT_MS / 1000), 'Your other reports are still running. Please try again shortly.');
+    }
+    state.recent.push(Date.now());
+
+    let settle: (result: CachedReport | null) => void = () => {};
+    if (shareResults) inFlight.set(key, new Promise((resolve) => { settle = resolve; }));
+
+    let result: CachedReport | null = null;
+    try {
+      await next();
+      if (shareResults && c.res.status === 200) {
+        const headers: Record<string, string> = {};
+        for (const name of CACHED_HEADERS) {
+          const value = c.res.headers.get(name);
+          if (value) headers[name] = value;
+        }
+        result = {
+          status: c.res.status,
+          body: await c.res.clone().text(),
+          headers,
+          expiresAt: Date.now() + env.REPORT_CACHE_TTL_MS,
+        };
+        cache.set(key, result);
+      }
+      reportRequestsTotal.inc({ outcome: 'computed' });
+    } finally {
+      if (shareResults) inFlight.delete(key);
+      settle(result);
+      releaseSlot(state);
+    }
+
+    c.header('X-Report-Cache', 'miss');
+  });
+}
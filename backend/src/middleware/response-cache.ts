@@ -0,0 +1,58 @@
+import { createHash } from 'node:crypto';
+import { createMiddleware } from 'hono/factory';
+import { env } from '../env.js';
+import { responseCache } from '../lib/cache.js';
+
+// Caches successful GET responses in a lib/cache namespace, keyed by path
+// and query, and answers If-None-Match with 304 from the ETag. Handlers that
+// change the underlying data call invalidateCache(namespace).
+
+interface CachedBody {
+  body: string;
+  etag: string;
+  contentType: string;
+}
+
+function cacheKey(path: string, query: Record<string, string>): string {
+  const params = Object.keys(query).sort().map((k) => `${k}=${query[k]}`).join('&');
+  return `${path}?${params}`;
+}
+
+function etagMatches(header: string | undefined, etag: string): boolean {
+  if (!header) return false;
+  return header === '*' || header.split(',').some((tag) => tag.trim().replace(/^W\//, '') === etag);
+}
+
+export function cachedResponse(namespace: string) {
+  return createMiddleware(async (c, next) => {
+    const key = cacheKey(c.req.path, c.req.query());
+    let entry: CachedBody;
+
+    const hit = await responseCache.get(namespace, key);
+    if (hit) {
+      entry = JSON.parse(hit);
+      c.header('X-Cache', 'HIT');
+    } else {
+      await next();
+      if (c.res.status !== 200) return;
+      const body = await c.res.clone().text();
+      entry = {
+        body,
+        etag: `"${createHash('sha1').update(body).digest('base64url')}"`,
+        contentType: c.res.headers.get('content-type') ?? 'application/json',
+      };
+      await responseCache.set(namespace, key, JSON.stringify(entry), env.RESPONSE_CACHE_TTL_MS);
+      c.header('X-Cache', 'MISS');
+    }
+
+    // Browsers revalidate every time; unchanged data costs a 304
+    c.header('ETag', entry.etag);
+    c.header('Cache-Control', 'public, no-cache');
+    if (etagMatches(c.req.header('If-None-Match'), entry.etag)) {
+      return c.body(null, 304);
+    }
+    if (hit) {
+      return c.body(entry.body, 200, { 'Content-Type': entry.contentType });
+    }
+  });
+}
@@ -1,17 +1,23 @@
+import type { Context } from 'hono';
 import { createMiddleware } from 'hono/factory';
 
-export function requireRoles(roles: string[]) {
-  return createMiddleware(async (c, next) => {
-    const role = c.get('role');
+/** Whether the authenticated user's role grants `permission` (loaded by authMiddleware). */
+export function can(c: Context, permission: string): boolean {
+  return c.get('permissions')?.has(permission) ?? false;
+}
 
-    if (!role) {
+// The permission is kept on the middleware so the API docs can list it
+export function requirePermission(permission: string) {
+  const middleware = createMiddleware(async (c, next) => {
+    if (!c.get('role')) {
       return c.json({ success: false, message: 'Role information not found', error: 'missing_role' }, 403);
     }
 
-    if (!roles.includes(role)) {
+    if (!can(c, permission)) {
       return c.json({ success: false, message: 'Insufficient permissions', error: 'insufficient_permissions' }, 403);
     }
 
     await next();
   });
+  return Object.assign(middleware, { permission });
 }
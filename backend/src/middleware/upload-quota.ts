@@ -0,0 +1,69 @@
+import { createMiddleware } from 'hono/factory';
+import { pool } from '../db/connection.js';
+import { env } from '../env.js';
+import { localClock } from '../lib/clock.js';
+import { requestsRejectedTotal } from '../lib/metrics.js';
+
+declare module 'hono' {
+  interface ContextVariableMap {
+    /** Set by an upload handler once the file is saved, to be counted against the quota */
+    uploaded_bytes: number;
+  }
+}
+
+// Daily upload quota per user, so one account (or a stolen token) can't
+// fill the uploads directory. Every user may upload UPLOAD_DAILY_QUOTA_COUNT
+// images and UPLOAD_DAILY_QUOTA_MB in total per day in the restaurant's
+// timezone; 0 turns either limit off. Place it after authMiddleware on
+// image upload routes. Deleting an image doesn't give the quota back.
+//
+// The check happens before the upload and the usage is recorded after it,
+// so uploads running at the same moment can go slightly over.
+
+const QUOTA_BYTES = env.UPLOAD_DAILY_QUOTA_MB * 1024 * 1024;
+
+export const uploadQuota = createMiddleware(async (c, next) => {
+  const userId = c.get('user_id');
+  const clock = localClock();
+
+  const usageRes = await pool.query(
+    'SELECT uploads, bytes FROM upload_usage WHERE user_id = $1 AND usage_date = $2',
+    [userId, clock.date],
+  );
+  const uploads = Number(usageRes.rows[0]?.uploads ?? 0);
+  const bytes = Number(usageRes.rows[0]?.bytes ?? 0);
+  const incoming = Number(c.req.header('Content-Length')) || 0;
+
+  const overCount = env.UPLOAD_DAILY_QUOTA_COUNT > 0 && uploads >= env.UPLOAD_DAILY_QUOTA_COUNT;
+  const overBytes = QUOTA_BYTES > 0 && bytes + incoming > QUOTA_BYTES;
+  if (overCount || overBytes) {
+    requestsRejectedTotal.inc({ reason: 'upload_quota' });
+    return c.json({
+      success: false,
+      message: overCount
+        ? `Daily upload limit of ${env.UPLOAD_DAILY_QUOTA_COUNT} images reached. Try again tomorrow.`
+        : `Daily upload limit of ${env.UPLOAD_DAILY_QUOTA_MB} MB reached. Try again tomorrow.`,
+      error: 'upload_quota_exceeded',
+      data: {
+        uploads,
+        bytes,
+        max_uploads: env.UPLOAD_DAILY_QUOTA_COUNT || null,
+        max_bytes: QUOTA_BYTES || null,
+      },
+    }, 429, { 'Retry-After': String(Math.max(1, 86400 - clock.secondsOfDay)) });
+  }
+
+  await next();
+
+  const uploaded = c.get('uploaded_bytes');
+  if (uploaded === undefined) return;
+  await pool.query(
+    `INSERT INTO upload_usage (user_id, usage_date, uploads, bytes)
+     VALUES ($1, $2, 1, $3)
+     ON CONFLICT (user_id, usage_date) DO UPDATE SET
+       uploads = upload_usage.uploads + 1,
+       bytes = upload_usage.bytes + EXCLUDED.bytes,
+       updated_at = NOW()`,
+    [userId, clock.date, uploaded],
+  );
+});
@@ -1,31 +1,164 @@
 import { Hono } from 'hono';
 import { authMiddleware } from '../middleware/auth.js';
-import { requireRoles } from '../middleware/roles.js';
+import { requirePermission } from '../middleware/roles.js';
 import { publicRateLimiter, strictRateLimiter, contactFormRateLimiter } from '../middleware/ratelimit.js';
 import { csrfProtection } from '../middleware/security.js';
+import { reportThrottle } from '../middleware/report-throttle.js';
+import { cachedResponse } from '../middleware/response-cache.js';
+import { requestBodyLimit } from '../middleware/body-limit.js';
+import { uploadQuota } from '../middleware/upload-quota.js';
 
 // Handlers
-import { login, getCurrentUser, logout } from '../handlers/auth.js';
+import { login, getCurrentUser, logout, requestPasswordReset, confirmPasswordReset, getPasswordPolicy } from '../handlers/auth.js';
 import { getProfile, updateProfile, changePassword } from '../handlers/profile.js';
-import { getProducts, getProduct, getCategories, getProductsByCategory, createProduct, updateProduct, deleteProduct } from '../handlers/products.js';
+import { getProducts, getProductSearch, getProduct, getCategories, getProductsByCategory, createProduct, updateProduct, deleteProduct, restoreProduct } from '../handlers/products.js';
 import { getTables, getTable, getTablesByLocation, getTableStatus } from '../handlers/tables.js';
-import { getOrders, getOrder, createOrder, updateOrderStatus, getOrderStatusHistory } from '../handlers/orders.js';
-import { processPayment, getPayments, getPaymentSummary, createCustomerPayment } from '../handlers/payments.js';
-import { getKitchenOrders, updateOrderItemStatus } from '../handlers/kitchen.js';
-import { getInventory, getProductInventory, adjustStock, getLowStock, getStockHistory } from '../handlers/inventory.js';
-import { getIngredients, getIngredient, createIngredient, updateIngredient, deleteIngredient, restockIngredient, getLowStockIngredients, getIngredientHistory } from '../handlers/ingredients.js';
+import { getOrders, getOrder, createOrder, updateOrderStatus, getOrderStatusHistory, updateOrderItems, getOrderItemHistory, getOrderItemStatusHistory, updateOrderReceiptLanguage, parkOrder, resumeOrder, fireOrderCourse, getOrderCourses } from '../handlers/orders.js';
+import { processPayment, processBatchPayment, getPaymentBatch, getPayments, getPaymentSummary, createCustomerPayment, refundPayment, adjustPaymentTip } from '../handlers/payments.js';
+import {
+  getKitchenOrders,
+  updateOrderItemStatus,
+  getKitchenLoad,
+  getKitchenDisplayRules,
+  updateStationDisplay,
+} from '../handlers/kitchen.js';
+import { getInventory, getProductInventory, adjustStock, getLowStock, getStockHistory, getInventoryLedger } from '../handlers/inventory.js';
+import { getIngredients, getIngredient, createIngredient, updateIngredient, deleteIngredient, restockIngredient, getLowStockIngredients, getIngredientHistory, getExpiringIngredients } from '../handlers/ingredients.js';
 import { getProductIngredients, addProductIngredient, updateProductIngredient, deleteProductIngredient } from '../handlers/recipes.js';
-import { getNotifications, getUnreadCounts, markNotificationRead, deleteNotification, getNotificationPreferences, updateNotificationPreferences, getOrderNotifications, markOrderNotificationAsRead } from '../handlers/notifications.js';
-import { createReservation, getReservations, getReservation, updateReservationStatus, deleteReservation, getPendingReservationsCount } from '../handlers/reservations.js';
-import { getContactSubmissions, getContactSubmission, getNewContactsCount, updateContactStatus, deleteContactSubmission } from '../handlers/contact.js';
+import { getProductBundle, setProductBundle } from '../handlers/bundles.js';
+import { getProductDietary, setProductDietary } from '../handlers/dietary.js';
+import {
+  getNotifications, getUnreadCounts, markNotificationRead, deleteNotification, getNotificationPreferences, updateNotificationPreferences,
+  getOrderNotifications, markOrderNotificationAsRead, getNotificationSeverities, updateNotificationSeverity, resetNotificationSeverity,
+  getNotificationRoleDefaults, updateNotificationRoleDefaults, resetNotificationRoleDefaults, applyNotificationRoleDefaults,
+} from '../handlers/notifications.js';
+import {
+  createReservation, getReservations, getReservation, updateReservationStatus, deleteReservation, getPendingReservationsCount,
+  getReservationResponse, respondToReservation, assignReservationTable, getNoShowStats,
+} from '../handlers/reservations.js';
+import { getContactSubmissions, getContactSubmission, getNewContactsCount, updateContactStatus, replyToContact, deleteContactSubmission } from '../handlers/contact.js';
 import { updateRestaurantInfo, updateOperatingHours } from '../handlers/restaurant-info.js';
-import { getSettings, updateSettings, getSystemHealth as getAdminSystemHealth } from '../handlers/settings.js';
-import { createSurvey, getSurveyStats } from '../handlers/surveys.js';
+import {
+  getSettings,
+  updateSettings,
+  getSettingsHistory,
+  rollbackSettingsChange,
+  getSystemHealth as getAdminSystemHealth,
+} from '../handlers/settings.js';
+import { getRuntimeConfig, reloadRuntimeConfigNow } from '../handlers/runtime-config.js';
+import {
+  createSurvey,
+  getSurveyStats,
+  getSurveys,
+  getSurvey,
+  acknowledgeSurvey,
+  replyToSurvey,
+  getSurveyTrends,
+  getSurveysByServer,
+  getSurveysByProduct,
+} from '../handlers/surveys.js';
 import { uploadImage, deleteImage } from '../handlers/upload.js';
-import { getDashboardStats, getSalesReport, getOrdersReport, getIncomeReport } from '../handlers/dashboard.js';
-import { getPublicMenu, getPublicCategories, getRestaurantInfo, submitContactForm, getCSRFToken, getTableByQRCode, createCustomerOrder } from '../handlers/public.js';
-import { getAdminCategories, createCategory, updateCategory, deleteCategory, getAdminTables, createTable, updateTable, deleteTable, getAdminUsers, createUser, updateUser, deleteUser } from '../handlers/admin.js';
-import { getSystemHealth } from '../handlers/health.js';
+import { getDashboardStats, getSalesReport, getOrdersReport, getIncomeReport, getStaffPerformanceReport, getServerReport, getTipReport, getTaxReport, getSlaReport, getHeatmapReport } from '../handlers/dashboard.js';
+import { getPublicMenu, getPublicMenuSearch, getPublicDietaryOptions, getPublicCategories, getRestaurantInfo, submitContactForm, getCSRFToken, getTableByQRCode, createCustomerOrder, getCustomerOrderStatus, getCustomerOrder } from '../handlers/public.js';
+import { getDailySpecials, createDailySpecial, updateDailySpecial, deleteDailySpecial, resetDailySpecial, getPublicSpecials } from '../handlers/daily-specials.js';
+import {
+  getSalesTargets,
+  setSalesTarget,
+  removeSalesTarget,
+  getMyTargetProgress,
+  getTeamTargetProgress,
+} from '../handlers/sales-targets.js';
+import {
+  getSections,
+  getMySections,
+  createSection,
+  updateSection,
+  deleteSection,
+  assignSectionServer,
+  removeSectionAssignment,
+} from '../handlers/sections.js';
+import { getDeliveries, getCouriers, assignCourier, getMyDeliveries, updateDeliveryStatus, trackDeliveryOrder } from '../handlers/delivery.js';
+import {
+  getCommissionRules,
+  createCommissionRule,
+  updateCommissionRule,
+  deleteCommissionRule,
+  getCommissionReport,
+  exportCommissionReport,
+} from '../handlers/commissions.js';
+import { exportJournal, getExportLog } from '../handlers/exports.js';
+import { getContainerTypes, createContainerType, updateContainerType, returnContainers, getContainerDepositReport } from '../handlers/container-deposits.js';
+import {
+  getLogbookEntries,
+  getLogbookTags,
+  getLogbookDay,
+  createLogbookEntry,
+  updateLogbookEntry,
+  deleteLogbookEntry,
+  sendLogbookDigestNow,
+} from '../handlers/logbook.js';
+import { getAdminCategories, createCategory, updateCategory, deleteCategory, restoreCategory, getAdminTables, createTable, updateTable, deleteTable, restoreTable, getAdminUsers, createUser, updateUser, deleteUser, restoreUser } from '../handlers/admin.js';
+import { getSystemHealth, getReadiness } from '../handlers/health.js';
+import { generateTableQr, rotateTableQr, getTableQrImage, generateAllTableQr } from '../handlers/table-qr.js';
+import { getPublicStatus, getStatusIncidents, createStatusIncident, updateStatusIncident, resolveStatusIncident } from '../handlers/status-page.js';
+import { getSchemaMeta } from '../handlers/meta.js';
+import { runSelftest } from '../handlers/selftest.js';
+import { getCorporateAccounts, getCorporateAccount, createCorporateAccount, updateCorporateAccount, createCorporateEmployee, updateCorporateEmployee, createCorporateTopup, getCorporateStatement, lookupEmployeeCode } from '../handlers/corporate.js';
+import { getCorporateCredit, generateCorporateInvoice, getCorporateInvoices, getCorporateInvoice, recordCorporateInvoicePayment } from '../handlers/corporate-invoices.js';
+import { handleGatewayNotification, getGatewayRefunds, retryGatewayRefund } from '../handlers/payment-gateway.js';
+import { createPaymentLink, getOrderPaymentLinks } from '../handlers/payment-links.js';
+import { openTab, getTabs, getTab, closeTab } from '../handlers/tabs.js';
+import { getMetrics } from '../handlers/metrics.js';
+import { getOpenApiSpec, getApiDocs } from '../handlers/docs.js';
+import { getCustomerFlags, getCustomerFlag, checkCustomerPhone, createCustomerFlag, updateCustomerFlag, clearCustomerFlag } from '../handlers/customer-flags.js';
+import { getBranches, getPublicBranches, createBranch, updateBranch, getBranchSettings, updateBranchSettings } from '../handlers/branches.js';
+import { getJobs, getJobStats, getJob, retryJob } from '../handlers/jobs.js';
+import {
+  getDeadLetters,
+  getDeadLetterStats,
+  getDeadLetter,
+  replayDeadLetter,
+  replayDeadLetters,
+  discardDeadLetter,
+} from '../handlers/dead-letters.js';
+import {
+  getAnalyticsExport,
+  getAnalyticsExportRuns,
+  runAnalyticsExport,
+  rewindAnalyticsStream,
+} from '../handlers/elt-export.js';
+import { getEmails, getEmail, retryEmail, sendTestEmail } from '../handlers/email.js';
+import { getPermissions, getRoles, createRole, updateRole, deleteRole } from '../handlers/roles.js';
+import { getPricingRules, createPricingRule, updatePricingRule, deletePricingRule, previewPricing, getOrderPricingAdjustments } from '../handlers/pricing-rules.js';
+import { getTaxExemptions, createTaxExemption, updateTaxExemption, deleteTaxExemption } from '../handlers/tax-exemptions.js';
+import { getTaxClasses, createTaxClass, updateTaxClass, deleteTaxClass } from '../handlers/tax-classes.js';
+import { getSurcharges, createSurcharge, updateSurcharge, deleteSurcharge } from '../handlers/surcharges.js';
+import { getPriceSchedules, createPriceSchedule, updatePriceSchedule, deletePriceSchedule } from '../handlers/price-schedules.js';
+import { getPublicCurrencies, getCurrencies, createCurrency, updateCurrency, deleteCurrency } from '../handlers/currencies.js';
+import { getPaymentMethods, getCustomerPaymentMethods, getAllPaymentMethods, createPaymentMethod, updatePaymentMethod, deletePaymentMethod } from '../handlers/payment-methods.js';
+import { getPublicNotePhrases, getNotePhrases, getAllNotePhrases, createNotePhrase, updateNotePhrase, deleteNotePhrase } from '../handlers/note-phrases.js';
+import {
+  getProductCosts, updateProductCost, getCogsAdjustments, createCogsAdjustment, deleteCogsAdjustment, getCogsReport, getMenuEngineeringReport,
+} from '../handlers/costing.js';
+import {
+  getStockTakes, getStockTake, startStockTake, recordStockTakeCounts, postStockTakeHandler, cancelStockTake, getStockVarianceReport,
+} from '../handlers/stock-takes.js';
+import { logWaste, getWasteLogs, getWasteReport } from '../handlers/waste.js';
+import { setEightySix, getEightySixList, getEightySixReport } from '../handlers/eighty-six.js';
+import { remakeOrderItem, getRemakes, getKitchenQualityReport } from '../handlers/remakes.js';
+import { getPlatformMappings, createPlatformMapping, deletePlatformMapping, syncPlatformMenu, getMenuReconciliation } from '../handlers/menu-sync.js';
+import { getDevices, getDevicePrintPreferences, updateDevicePrintPreferences, deleteDevice } from '../handlers/devices.js';
+import {
+  clockIn, clockOut, getMyAttendance, getStaffShifts, createStaffShift, deleteStaffShift,
+  getAttendance, adjustAttendance, setAttendancePin, setAttendanceTerminal, getTimesheets, exportTimesheets,
+} from '../handlers/attendance.js';
+import {
+  getWebhooks, createWebhook, updateWebhook, deleteWebhook, rotateWebhookSecret, sendTestWebhook,
+  getWebhookDeliveries, getWebhookDelivery, retryWebhookDelivery,
+} from '../handlers/webhooks.js';
+import {
+  handleInboundWebhook, verifyInboundSubscription, getInboundWebhookEvents, getInboundWebhookEvent, retryInboundWebhookEvent,
+} from '../handlers/inbound-webhooks.js';
 
 // Middleware that sets force_order_type so createOrder forces dine_in
 import { createMiddleware } from 'hono/factory';
@@ -35,24 +168,46 @@ const forceDineIn = createMiddleware(async (c, next) => {
 });
 
 export function setupRoutes(app: Hono) {
+  // Prometheus scrape endpoint (outside the versioned API)
+  app.get('/metrics', getMetrics);
+
   const api = new Hono();
 
+  // Body size limits for every route: small for JSON, larger for uploads
+  api.use('*', requestBodyLimit);
+
   // ── Public routes (no authentication) ───────────────────────────────────────
 
   api.post('/auth/login', strictRateLimiter(), login);
   api.post('/auth/logout', logout);
+  api.post('/auth/password-reset/request', strictRateLimiter(), requestPasswordReset);
+  api.post('/auth/password-reset/confirm', strictRateLimiter(), confirmPasswordReset);
+  api.get('/auth/password-policy', getPasswordPolicy);
 
   // ── Public website API (/public/*) ──────────────────────────────────────────
 
   const publicAPI = new Hono();
   publicAPI.use('*', publicRateLimiter());
 
-  publicAPI.get('/menu', getPublicMenu);
-  publicAPI.get('/categories', getPublicCategories);
+  // Cached until an admin changes products, categories or specials; stock
+  // shown on the menu can lag by up to RESPONSE_CACHE_TTL_MS
+  publicAPI.get('/menu', cachedResponse('menu'), getPublicMenu);
+  publicAPI.get('/categories', cachedResponse('menu'), getPublicCategories);
+  publicAPI.get('/currencies', cachedResponse('menu'), getPublicCurrencies);
+  publicAPI.get('/note-phrases', cachedResponse('menu'), getPublicNotePhrases);
+  publicAPI.get('/menu/search', getPublicMenuSearch);
+  publicAPI.get('/menu/dietary-options', getPublicDietaryOptions);
+  publicAPI.get('/specials', getPublicSpecials);
   publicAPI.get('/restaurant', getRestaurantInfo);
+  publicAPI.get('/branches', getPublicBranches);
+  // Service status and admin-raised incidents, for front-of-house staff
+  publicAPI.get('/status', getPublicStatus);
   publicAPI.get('/health/open-status', getRestaurantInfo); // Debug endpoint
   publicAPI.post('/contact', contactFormRateLimiter(), submitContactForm);
   publicAPI.post('/reservations', contactFormRateLimiter(), csrfProtection, createReservation);
+  publicAPI.get('/reservations/respond/:token', getReservationResponse);
+  publicAPI.post('/reservations/respond/:token', contactFormRateLimiter(), csrfProtection, respondToReservation);
+  publicAPI.get('/orders/:order_number/track', trackDeliveryOrder);
 
   api.route('/public', publicAPI);
 
@@ -63,16 +218,32 @@ export function setupRoutes(app: Hono) {
 
   customerAPI.get('/csrf-token', getCSRFToken);
   customerAPI.get('/table/:qr_code', getTableByQRCode);
+  customerAPI.get('/payment-methods', getCustomerPaymentMethods);
   customerAPI.post('/orders', csrfProtection, createCustomerOrder);
-  customerAPI.post('/orders/:id/payment', csrfProtection, createCustomerPayment);
-  customerAPI.post('/orders/:id/survey', csrfProtection, createSurvey);
-  customerAPI.get('/orders/:id/notifications', getOrderNotifications);
-  customerAPI.put('/notifications/:id/read', markOrderNotificationAsRead);
+  customerAPI.get('/orders/:order_number/status', getCustomerOrderStatus);
+  // :token is the signed order link token from order creation or the survey invitation
+  customerAPI.get('/orders/:token', getCustomerOrder);
+  customerAPI.post('/orders/:token/payment', csrfProtection, createCustomerPayment);
+  customerAPI.post('/orders/:token/survey', csrfProtection, createSurvey);
+  customerAPI.get('/orders/:token/notifications', getOrderNotifications);
+  customerAPI.put('/orders/:token/notifications/:id/read', markOrderNotificationAsRead);
 
   api.route('/customer', customerAPI);
 
+  // ── API documentation (OpenAPI 3 spec and Swagger UI) ───────────────────────
+  api.get('/docs', getApiDocs);
+  api.get('/docs/openapi.json', getOpenApiSpec(app));
+
   // ── Health check (no auth, no prefix) ───────────────────────────────────────
   api.get('/health', getSystemHealth);
+  api.get('/ready', getReadiness);
+
+  // ── Payment gateway webhook (signature-verified, no auth) ───────────────────
+  api.post('/payments/gateway/notification', handleGatewayNotification);
+
+  // ── Inbound webhooks from other providers (signature-verified, no auth) ─────
+  api.get('/webhooks/inbound/:provider', verifyInboundSubscription);
+  api.post('/webhooks/inbound/:provider', handleInboundWebhook);
 
   // ── Protected routes (authentication required) ──────────────────────────────
 
@@ -86,6 +257,11 @@ export function setupRoutes(app: Hono) {
   protectedRoutes.get('/profile', getProfile);
   protectedRoutes.put('/profile', updateProfile);
   protectedRoutes.put('/profile/password', changePassword);
+  protectedRoutes.get('/sales-targets/me', getMyTargetProgress);
+  protectedRoutes.get('/sections/mine', getMySections);
+
+  // This terminal's print preferences (also returned on login)
+  protectedRoutes.get('/devices/:device_id/print-preferences', getDevicePrintPreferences);
 
   // Notifications
   protectedRoutes.get('/notifications', getNotifications);
@@ -97,8 +273,10 @@ export function setupRoutes(app: Hono) {
 
   // Products & Categories (read-only for all authenticated users)
   protectedRoutes.get('/products', getProducts);
+  protectedRoutes.get('/products/search', getProductSearch);
   protectedRoutes.get('/products/:id', getProduct);
   protectedRoutes.get('/categories', getCategories);
+  protectedRoutes.get('/container-types', getContainerTypes);
   protectedRoutes.get('/categories/:id/products', getProductsByCategory);
 
   // Tables (read-only for all authenticated users)
@@ -111,136 +289,467 @@ export function setupRoutes(app: Hono) {
   protectedRoutes.get('/orders', getOrders);
   protectedRoutes.get('/orders/:id', getOrder);
   protectedRoutes.get('/orders/:id/status-history', getOrderStatusHistory);
-  protectedRoutes.patch('/orders/:id/status', updateOrderStatus);
+  protectedRoutes.get('/orders/:id/pricing-adjustments', getOrderPricingAdjustments);
+  protectedRoutes.patch('/orders/:id/status', requirePermission('orders.update_status'), updateOrderStatus);
+  protectedRoutes.get('/orders/:id/items/history', getOrderItemHistory);
+  protectedRoutes.get('/orders/:id/items/:item_id/history', getOrderItemStatusHistory);
+  protectedRoutes.get('/orders/:id/courses', getOrderCourses);
+  protectedRoutes.patch('/orders/:id/items', requirePermission('orders.edit_items'), updateOrderItems);
+  protectedRoutes.post('/orders/:id/items/:item_id/remake', requirePermission('orders.remake'), remakeOrderItem);
+
+  // Kitchen load is quoted by front-of-house as well as watched by the kitchen
+  protectedRoutes.get('/kitchen/load', requirePermission('kitchen.load'), getKitchenLoad);
 
   // Payments (read-only for all authenticated users)
   protectedRoutes.get('/orders/:id/payments', getPayments);
   protectedRoutes.get('/orders/:id/payment-summary', getPaymentSummary);
+  protectedRoutes.get('/payment-methods', getPaymentMethods);
+  protectedRoutes.get('/note-phrases', getNotePhrases);
 
   api.route('/', protectedRoutes);
 
-  // ── Server routes (server/admin/manager) ────────────────────────────────────
+  // ── Server routes ───────────────────────────────────────────────────────────
 
   const serverRoutes = new Hono();
   serverRoutes.use('*', authMiddleware);
-  serverRoutes.use('*', requireRoles(['server', 'admin', 'manager']));
 
-  serverRoutes.post('/orders', forceDineIn, createOrder);
-  serverRoutes.post('/products', createProduct);
-  serverRoutes.put('/products/:id', updateProduct);
+  serverRoutes.post('/orders', requirePermission('orders.create_dine_in'), forceDineIn, createOrder);
+  serverRoutes.post('/orders/:id/fire', requirePermission('orders.fire_courses'), fireOrderCourse);
+  serverRoutes.post('/products', requirePermission('menu.edit_products'), createProduct);
+  serverRoutes.put('/products/:id', requirePermission('menu.edit_products'), updateProduct);
 
   api.route('/server', serverRoutes);
 
-  // ── Counter routes (counter/admin/manager) ──────────────────────────────────
+  // ── Counter routes ──────────────────────────────────────────────────────────
 
   const counterRoutes = new Hono();
   counterRoutes.use('*', authMiddleware);
-  counterRoutes.use('*', requireRoles(['counter', 'admin', 'manager']));
 
-  counterRoutes.post('/orders', createOrder);
-  counterRoutes.post('/orders/:id/payments', processPayment);
+  counterRoutes.post('/orders', requirePermission('orders.create'), createOrder);
+  counterRoutes.post('/orders/:id/park', requirePermission('orders.park'), parkOrder);
+  counterRoutes.post('/orders/:id/resume', requirePermission('orders.park'), resumeOrder);
+  counterRoutes.post('/orders/:id/payments', requirePermission('payments.process'), processPayment);
+  counterRoutes.put('/orders/:id/payments/:payment_id/tip', requirePermission('payments.process'), adjustPaymentTip);
+  counterRoutes.post('/payments/batch', requirePermission('payments.process'), processBatchPayment);
+  counterRoutes.get('/payments/batch/:id', requirePermission('payments.process'), getPaymentBatch);
+  counterRoutes.post('/orders/:id/container-returns', requirePermission('payments.process'), returnContainers);
+  counterRoutes.put('/orders/:id/receipt-language', requirePermission('payments.process'), updateOrderReceiptLanguage);
+  counterRoutes.post('/orders/:id/payment-link', requirePermission('payments.links'), createPaymentLink);
+  counterRoutes.get('/orders/:id/payment-links', requirePermission('payments.links'), getOrderPaymentLinks);
+  counterRoutes.post('/tabs', requirePermission('tabs.manage'), openTab);
+  counterRoutes.get('/tabs', requirePermission('tabs.manage'), getTabs);
+  counterRoutes.get('/tabs/:id', requirePermission('tabs.manage'), getTab);
+  counterRoutes.post('/tabs/:id/close', requirePermission('tabs.manage'), closeTab);
+  counterRoutes.get('/corporate-wallet/:code', requirePermission('payments.process'), lookupEmployeeCode);
+  counterRoutes.get('/deliveries', requirePermission('deliveries.manage'), getDeliveries);
+  counterRoutes.get('/couriers', requirePermission('deliveries.manage'), getCouriers);
+  counterRoutes.put('/orders/:id/courier', requirePermission('deliveries.manage'), assignCourier);
+  counterRoutes.get('/customer-flags/check', requirePermission('customer_flags.check'), checkCustomerPhone);
 
   api.route('/counter', counterRoutes);
 
-  // ── Admin routes (admin/manager) ────────────────────────────────────────────
+  // ── Courier routes ──────────────────────────────────────────────────────────
+
+  const courierRoutes = new Hono();
+  courierRoutes.use('*', authMiddleware);
+
+  courierRoutes.get('/deliveries', requirePermission('deliveries.courier'), getMyDeliveries);
+  courierRoutes.patch('/deliveries/:id/status', requirePermission('deliveries.courier'), updateDeliveryStatus);
+
+  api.route('/courier', courierRoutes);
+
+  // ── Staff routes ────────────────────────────────────────────────────────────
+
+  const staffRoutes = new Hono();
+  staffRoutes.use('*', authMiddleware);
+
+  staffRoutes.post('/clock-in', requirePermission('attendance.clock'), clockIn);
+  staffRoutes.post('/clock-out', requirePermission('attendance.clock'), clockOut);
+  staffRoutes.get('/attendance', requirePermission('attendance.clock'), getMyAttendance);
+
+  api.route('/staff', staffRoutes);
+
+  // ── Admin routes ────────────────────────────────────────────────────────────
 
   const adminRoutes = new Hono();
   adminRoutes.use('*', authMiddleware);
-  adminRoutes.use('*', requireRoles(['admin', 'manager']));
 
-  // Dashboard & Reports
-  adminRoutes.get('/dashboard/stats', getDashboardStats);
-  adminRoutes.get('/reports/sales', getSalesReport);
-  adminRoutes.get('/reports/orders', getOrdersReport);
-  adminRoutes.get('/reports/income', getIncomeReport);
-  adminRoutes.get('/surveys/stats', getSurveyStats);
+  // Dashboard & Reports (throttled per user, identical requests share a result)
+  const reports = reportThrottle();
+  // Exports are watermarked per user and logged, so each request runs on its own
+  const exportThrottle = reportThrottle({ shareResults: false });
+  adminRoutes.get('/dashboard/stats', requirePermission('reports.view'), reports, getDashboardStats);
+  adminRoutes.get('/reports/sales', requirePermission('reports.view'), reports, getSalesReport);
+  adminRoutes.get('/reports/orders', requirePermission('reports.view'), reports, getOrdersReport);
+  adminRoutes.get('/reports/income', requirePermission('reports.view'), reports, getIncomeReport);
+  adminRoutes.get('/reports/staff-performance', requirePermission('reports.view'), reports, getStaffPerformanceReport);
+  adminRoutes.get('/reports/servers', requirePermission('reports.view'), reports, getServerReport);
+  adminRoutes.get('/reports/tips', requirePermission('reports.view'), reports, getTipReport);
+  adminRoutes.get('/reports/tax', requirePermission('reports.view'), reports, getTaxReport);
+  adminRoutes.get('/reports/sla', requirePermission('reports.view'), reports, getSlaReport);
+  adminRoutes.get('/reports/heatmap', requirePermission('reports.view'), reports, getHeatmapReport);
+  adminRoutes.get('/surveys/stats', requirePermission('reports.view'), reports, getSurveyStats);
+  adminRoutes.get('/surveys/trends', requirePermission('reports.view'), reports, getSurveyTrends);
+  adminRoutes.get('/surveys/by-server', requirePermission('reports.view'), reports, getSurveysByServer);
+  adminRoutes.get('/surveys/by-product', requirePermission('reports.view'), reports, getSurveysByProduct);
+  adminRoutes.get('/surveys', requirePermission('surveys.manage'), getSurveys);
+  adminRoutes.get('/surveys/:id', requirePermission('surveys.manage'), getSurvey);
+  adminRoutes.post('/surveys/:id/acknowledge', requirePermission('surveys.manage'), acknowledgeSurvey);
+  adminRoutes.post('/surveys/:id/reply', requirePermission('surveys.manage'), replyToSurvey);
+  adminRoutes.get('/reports/container-deposits', requirePermission('reports.view'), reports, getContainerDepositReport);
+  adminRoutes.get('/reports/cogs', requirePermission('reports.view'), reports, getCogsReport);
+  adminRoutes.get('/reports/menu-engineering', requirePermission('reports.view'), reports, getMenuEngineeringReport);
+  adminRoutes.get('/reports/stock-variance', requirePermission('reports.view'), reports, getStockVarianceReport);
+  adminRoutes.get('/reports/waste', requirePermission('reports.view'), reports, getWasteReport);
+  adminRoutes.get('/reports/eighty-six', requirePermission('reports.view'), reports, getEightySixReport);
+  adminRoutes.get('/reports/kitchen-quality', requirePermission('reports.view'), reports, getKitchenQualityReport);
 
   // System settings & health
-  adminRoutes.get('/settings', getSettings);
-  adminRoutes.put('/settings', updateSettings);
-  adminRoutes.get('/health', getAdminSystemHealth);
+  adminRoutes.get('/settings', requirePermission('settings.manage'), getSettings);
+  adminRoutes.put('/settings', requirePermission('settings.manage'), updateSettings);
+  adminRoutes.get('/settings/history', requirePermission('settings.manage'), getSettingsHistory);
+  adminRoutes.post('/settings/history/:id/rollback', requirePermission('settings.manage'), rollbackSettingsChange);
+  adminRoutes.get('/health', requirePermission('settings.manage'), getAdminSystemHealth);
+  adminRoutes.get('/config', requirePermission('settings.manage'), getRuntimeConfig);
+  adminRoutes.post('/config/reload', requirePermission('settings.manage'), reloadRuntimeConfigNow);
+  // Entities, enums and validation limits for the frontend and integrators; any signed-in user
+  adminRoutes.get('/meta/schema', getSchemaMeta);
+  // Incidents shown on the public status endpoint
+  adminRoutes.get('/status/incidents', requirePermission('settings.manage'), getStatusIncidents);
+  adminRoutes.post('/status/incidents', requirePermission('settings.manage'), createStatusIncident);
+  adminRoutes.put('/status/incidents/:id', requirePermission('settings.manage'), updateStatusIncident);
+  adminRoutes.post('/status/incidents/:id/resolve', requirePermission('settings.manage'), resolveStatusIncident);
+  // Runs the order path on sandbox data and rolls it back, for deploy checks
+  adminRoutes.post('/selftest', requirePermission('system.selftest'), runSelftest);
+
+  // Notification severity per event (sounds and prominence on POS/kitchen screens)
+  adminRoutes.get('/notification-severities', requirePermission('settings.manage'), getNotificationSeverities);
+  adminRoutes.put('/notification-severities/:event', requirePermission('settings.manage'), updateNotificationSeverity);
+  adminRoutes.delete('/notification-severities/:event', requirePermission('settings.manage'), resetNotificationSeverity);
+
+  // Notification preferences new users of a role start with, and applying them to existing users
+  adminRoutes.get('/notification-defaults', requirePermission('users.manage'), getNotificationRoleDefaults);
+  adminRoutes.put('/notification-defaults/:role', requirePermission('users.manage'), updateNotificationRoleDefaults);
+  adminRoutes.delete('/notification-defaults/:role', requirePermission('users.manage'), resetNotificationRoleDefaults);
+  adminRoutes.post('/notification-defaults/:role/apply', requirePermission('users.manage'), applyNotificationRoleDefaults);
+
+  // POS terminals and their print preferences
+  adminRoutes.get('/devices', requirePermission('settings.manage'), getDevices);
+  adminRoutes.put('/devices/:device_id/print-preferences', requirePermission('settings.manage'), updateDevicePrintPreferences);
+  adminRoutes.delete('/devices/:device_id', requirePermission('settings.manage'), deleteDevice);
+
+  // Outbound webhooks and their delivery log
+  adminRoutes.get('/webhooks', requirePermission('settings.manage'), getWebhooks);
+  adminRoutes.post('/webhooks', requirePermission('settings.manage'), createWebhook);
+  adminRoutes.get('/webhooks/deliveries', requirePermission('settings.manage'), getWebhookDeliveries);
+  adminRoutes.get('/webhooks/deliveries/:id', requirePermission('settings.manage'), getWebhookDelivery);
+  adminRoutes.post('/webhooks/deliveries/:id/retry', requirePermission('settings.manage'), retryWebhookDelivery);
+  adminRoutes.get('/webhooks/inbound-events', requirePermission('settings.manage'), getInboundWebhookEvents);
+  adminRoutes.get('/webhooks/inbound-events/:id', requirePermission('settings.manage'), getInboundWebhookEvent);
+  adminRoutes.post('/webhooks/inbound-events/:id/retry', requirePermission('settings.manage'), retryInboundWebhookEvent);
+  adminRoutes.put('/webhooks/:id', requirePermission('settings.manage'), updateWebhook);
+  adminRoutes.delete('/webhooks/:id', requirePermission('settings.manage'), deleteWebhook);
+  adminRoutes.post('/webhooks/:id/rotate-secret', requirePermission('settings.manage'), rotateWebhookSecret);
+  adminRoutes.post('/webhooks/:id/test', requirePermission('settings.manage'), sendTestWebhook);
 
   // Restaurant info & hours
-  adminRoutes.put('/restaurant-info', updateRestaurantInfo);
-  adminRoutes.put('/operating-hours', updateOperatingHours);
+  adminRoutes.put('/restaurant-info', requirePermission('settings.manage'), updateRestaurantInfo);
+  adminRoutes.put('/operating-hours', requirePermission('settings.manage'), updateOperatingHours);
 
   // Contact management
-  adminRoutes.get('/contacts', getContactSubmissions);
-  adminRoutes.get('/contacts/:id', getContactSubmission);
-  adminRoutes.get('/contacts/counts/new', getNewContactsCount);
-  adminRoutes.put('/contacts/:id/status', updateContactStatus);
-  adminRoutes.delete('/contacts/:id', deleteContactSubmission);
+  adminRoutes.get('/contacts', requirePermission('contacts.manage'), getContactSubmissions);
+  adminRoutes.get('/contacts/:id', requirePermission('contacts.manage'), getContactSubmission);
+  adminRoutes.get('/contacts/counts/new', requirePermission('contacts.manage'), getNewContactsCount);
+  adminRoutes.put('/contacts/:id/status', requirePermission('contacts.manage'), updateContactStatus);
+  adminRoutes.post('/contacts/:id/reply', requirePermission('contacts.manage'), replyToContact);
+  adminRoutes.delete('/contacts/:id', requirePermission('contacts.manage'), deleteContactSubmission);
 
   // Reservation management
-  adminRoutes.get('/reservations', getReservations);
-  adminRoutes.get('/reservations/:id', getReservation);
-  adminRoutes.get('/reservations/counts/pending', getPendingReservationsCount);
-  adminRoutes.patch('/reservations/:id/status', updateReservationStatus);
-  adminRoutes.delete('/reservations/:id', deleteReservation);
+  adminRoutes.get('/reservations', requirePermission('reservations.manage'), getReservations);
+  adminRoutes.get('/reservations/no-show-stats', requirePermission('reservations.manage'), getNoShowStats);
+  adminRoutes.get('/reservations/:id', requirePermission('reservations.manage'), getReservation);
+  adminRoutes.get('/reservations/counts/pending', requirePermission('reservations.manage'), getPendingReservationsCount);
+  adminRoutes.patch('/reservations/:id/status', requirePermission('reservations.manage'), updateReservationStatus);
+  adminRoutes.patch('/reservations/:id/table', requirePermission('reservations.manage'), assignReservationTable);
+  adminRoutes.delete('/reservations/:id', requirePermission('reservations.manage'), deleteReservation);
+
+  // Customer flags
+  adminRoutes.get('/customer-flags', requirePermission('customer_flags.manage'), getCustomerFlags);
+  adminRoutes.get('/customer-flags/:id', requirePermission('customer_flags.manage'), getCustomerFlag);
+  adminRoutes.post('/customer-flags', requirePermission('customer_flags.manage'), createCustomerFlag);
+  adminRoutes.put('/customer-flags/:id', requirePermission('customer_flags.manage'), updateCustomerFlag);
+  adminRoutes.post('/customer-flags/:id/clear', requirePermission('customer_flags.manage'), clearCustomerFlag);
+
+  // Branches
+  adminRoutes.get('/branches', requirePermission('branches.manage'), getBranches);
+  adminRoutes.post('/branches', requirePermission('branches.manage'), createBranch);
+  adminRoutes.put('/branches/:id', requirePermission('branches.manage'), updateBranch);
+  adminRoutes.get('/branches/:id/settings', requirePermission('branches.manage'), getBranchSettings);
+  adminRoutes.put('/branches/:id/settings', requirePermission('branches.manage'), updateBranchSettings);
 
   // Inventory management
-  adminRoutes.get('/inventory', getInventory);
-  adminRoutes.get('/inventory/low-stock', getLowStock);
-  adminRoutes.get('/inventory/:product_id', getProductInventory);
-  adminRoutes.post('/inventory/adjust', adjustStock);
-  adminRoutes.get('/inventory/history/:product_id', getStockHistory);
+  adminRoutes.get('/inventory', requirePermission('inventory.manage'), getInventory);
+  adminRoutes.get('/inventory/low-stock', requirePermission('inventory.manage'), getLowStock);
+  adminRoutes.get('/inventory/:product_id', requirePermission('inventory.manage'), getProductInventory);
+  adminRoutes.post('/inventory/adjust', requirePermission('inventory.manage'), adjustStock);
+  adminRoutes.get('/inventory/history/:product_id', requirePermission('inventory.manage'), getStockHistory);
+  adminRoutes.get('/inventory/ledger/:product_id', requirePermission('inventory.manage'), getInventoryLedger);
+
+  // Stock takes
+  adminRoutes.get('/stock-takes', requirePermission('inventory.manage'), getStockTakes);
+  adminRoutes.get('/stock-takes/:id', requirePermission('inventory.manage'), getStockTake);
+  adminRoutes.post('/stock-takes', requirePermission('inventory.manage'), startStockTake);
+  adminRoutes.put('/stock-takes/:id/counts', requirePermission('inventory.manage'), recordStockTakeCounts);
+  adminRoutes.post('/stock-takes/:id/post', requirePermission('stock_takes.post'), postStockTakeHandler);
+  adminRoutes.post('/stock-takes/:id/cancel', requirePermission('stock_takes.post'), cancelStockTake);
 
   // Ingredients management
-  adminRoutes.get('/ingredients', getIngredients);
-  adminRoutes.get('/ingredients/low-stock', getLowStockIngredients);
-  adminRoutes.get('/ingredients/:id', getIngredient);
-  adminRoutes.post('/ingredients', createIngredient);
-  adminRoutes.put('/ingredients/:id', updateIngredient);
-  adminRoutes.delete('/ingredients/:id', deleteIngredient);
-  adminRoutes.post('/ingredients/restock', restockIngredient);
-  adminRoutes.get('/ingredients/:id/history', getIngredientHistory);
+  adminRoutes.get('/ingredients', requirePermission('inventory.manage'), getIngredients);
+  adminRoutes.get('/ingredients/low-stock', requirePermission('inventory.manage'), getLowStockIngredients);
+  adminRoutes.get('/ingredients/expiring', requirePermission('inventory.manage'), getExpiringIngredients);
+  adminRoutes.get('/ingredients/:id', requirePermission('inventory.manage'), getIngredient);
+  adminRoutes.post('/ingredients', requirePermission('inventory.manage'), createIngredient);
+  adminRoutes.put('/ingredients/:id', requirePermission('inventory.manage'), updateIngredient);
+  adminRoutes.delete('/ingredients/:id', requirePermission('inventory.manage'), deleteIngredient);
+  adminRoutes.post('/ingredients/restock', requirePermission('inventory.manage'), restockIngredient);
+  adminRoutes.get('/ingredients/:id/history', requirePermission('inventory.manage'), getIngredientHistory);
 
   // Menu management (admin paginated versions)
-  adminRoutes.get('/products', getProducts);
-  adminRoutes.get('/categories', getAdminCategories);
-  adminRoutes.post('/categories', createCategory);
-  adminRoutes.put('/categories/:id', updateCategory);
-  adminRoutes.delete('/categories/:id', deleteCategory);
-  adminRoutes.post('/products', createProduct);
-  adminRoutes.put('/products/:id', updateProduct);
-  adminRoutes.delete('/products/:id', deleteProduct);
+  adminRoutes.get('/products', requirePermission('menu.manage'), getProducts);
+  adminRoutes.get('/products/search', requirePermission('menu.manage'), getProductSearch);
+  adminRoutes.get('/categories', requirePermission('menu.manage'), getAdminCategories);
+  adminRoutes.post('/categories', requirePermission('menu.manage'), createCategory);
+  adminRoutes.put('/categories/:id', requirePermission('menu.manage'), updateCategory);
+  adminRoutes.delete('/categories/:id', requirePermission('menu.manage'), deleteCategory);
+  adminRoutes.post('/categories/:id/restore', requirePermission('menu.manage'), restoreCategory);
+  adminRoutes.get('/kitchen-display', requirePermission('menu.manage'), getKitchenDisplayRules);
+  adminRoutes.put('/kitchen-display/stations/:station', requirePermission('menu.manage'), updateStationDisplay);
+  adminRoutes.post('/products', requirePermission('menu.edit_products'), createProduct);
+  adminRoutes.put('/products/:id', requirePermission('menu.edit_products'), updateProduct);
+  adminRoutes.delete('/products/:id', requirePermission('menu.manage'), deleteProduct);
+  adminRoutes.post('/products/:id/restore', requirePermission('menu.manage'), restoreProduct);
+  adminRoutes.post('/container-types', requirePermission('menu.manage'), createContainerType);
+  adminRoutes.put('/container-types/:id', requirePermission('menu.manage'), updateContainerType);
+
+  // Delivery platform (GoFood / GrabFood) menu sync
+  adminRoutes.get('/delivery-platforms/mappings', requirePermission('menu.manage'), getPlatformMappings);
+  adminRoutes.post('/delivery-platforms/mappings', requirePermission('menu.manage'), createPlatformMapping);
+  adminRoutes.delete('/delivery-platforms/mappings/:id', requirePermission('menu.manage'), deletePlatformMapping);
+  adminRoutes.post('/delivery-platforms/:platform/sync', requirePermission('menu.manage'), syncPlatformMenu);
+  adminRoutes.get('/delivery-platforms/reconciliation', requirePermission('menu.manage'), getMenuReconciliation);
 
   // Recipe/Ingredient configuration for products
-  adminRoutes.get('/products/:id/ingredients', getProductIngredients);
-  adminRoutes.post('/products/:id/ingredients', addProductIngredient);
-  adminRoutes.put('/products/:id/ingredients/:ingredient_id', updateProductIngredient);
-  adminRoutes.delete('/products/:id/ingredients/:ingredient_id', deleteProductIngredient);
+  adminRoutes.get('/products/:id/ingredients', requirePermission('menu.manage'), getProductIngredients);
+  adminRoutes.post('/products/:id/ingredients', requirePermission('menu.manage'), addProductIngredient);
+  adminRoutes.put('/products/:id/ingredients/:ingredient_id', requirePermission('menu.manage'), updateProductIngredient);
+  adminRoutes.delete('/products/:id/ingredients/:ingredient_id', requirePermission('menu.manage'), deleteProductIngredient);
+
+  // Bundle components, with the component that limits the bundle's stock
+  adminRoutes.get('/products/:id/bundle', requirePermission('menu.manage'), getProductBundle);
+  adminRoutes.put('/products/:id/bundle', requirePermission('menu.manage'), setProductBundle);
+  // Allergens, dietary tags and spice level shown on the public menu
+  adminRoutes.get('/products/:id/dietary', requirePermission('menu.edit_products'), getProductDietary);
+  adminRoutes.put('/products/:id/dietary', requirePermission('menu.edit_products'), setProductDietary);
 
   // Table management (admin paginated version)
-  adminRoutes.get('/tables', getAdminTables);
-  adminRoutes.post('/tables', createTable);
-  adminRoutes.put('/tables/:id', updateTable);
-  adminRoutes.delete('/tables/:id', deleteTable);
+  adminRoutes.get('/tables', requirePermission('tables.manage'), getAdminTables);
+  adminRoutes.post('/tables', requirePermission('tables.manage'), createTable);
+  adminRoutes.put('/tables/:id', requirePermission('tables.manage'), updateTable);
+  adminRoutes.delete('/tables/:id', requirePermission('tables.manage'), deleteTable);
+  adminRoutes.post('/tables/:id/restore', requirePermission('tables.manage'), restoreTable);
+  // Signed QR codes for self-ordering; rotating invalidates the printed ones
+  adminRoutes.post('/tables/qr', requirePermission('tables.manage'), generateAllTableQr);
+  adminRoutes.post('/tables/:id/qr', requirePermission('tables.manage'), generateTableQr);
+  adminRoutes.get('/tables/:id/qr', requirePermission('tables.manage'), getTableQrImage);
+  adminRoutes.post('/tables/:id/qr/rotate', requirePermission('tables.manage'), rotateTableQr);
 
   // User management
-  adminRoutes.get('/users', getAdminUsers);
-  adminRoutes.post('/users', createUser);
-  adminRoutes.put('/users/:id', updateUser);
-  adminRoutes.delete('/users/:id', deleteUser);
+  adminRoutes.get('/users', requirePermission('users.manage'), getAdminUsers);
+  adminRoutes.post('/users', requirePermission('users.manage'), createUser);
+  adminRoutes.put('/users/:id', requirePermission('users.manage'), updateUser);
+  adminRoutes.delete('/users/:id', requirePermission('users.manage'), deleteUser);
+  adminRoutes.post('/users/:id/restore', requirePermission('users.manage'), restoreUser);
+
+  // Roles & permissions
+  adminRoutes.get('/permissions', requirePermission('roles.manage'), getPermissions);
+  adminRoutes.get('/roles', requirePermission('roles.manage'), getRoles);
+  adminRoutes.post('/roles', requirePermission('roles.manage'), createRole);
+  adminRoutes.put('/roles/:name', requirePermission('roles.manage'), updateRole);
+  adminRoutes.delete('/roles/:name', requirePermission('roles.manage'), deleteRole);
 
   // Advanced order management (admins can create any order + process payments)
-  adminRoutes.post('/orders', createOrder);
-  adminRoutes.post('/orders/:id/payments', processPayment);
+  adminRoutes.post('/orders', requirePermission('orders.create'), createOrder);
+  adminRoutes.post('/orders/:id/payments', requirePermission('payments.process'), processPayment);
+  adminRoutes.put('/orders/:id/payments/:payment_id/tip', requirePermission('payments.process'), adjustPaymentTip);
+  adminRoutes.post('/orders/:id/payments/:payment_id/refund', requirePermission('payments.refund'), refundPayment);
+  adminRoutes.get('/gateway-refunds', requirePermission('payments.refund'), getGatewayRefunds);
+  adminRoutes.post('/gateway-refunds/:id/retry', requirePermission('payments.refund'), retryGatewayRefund);
+
+  // Pricing rules
+  adminRoutes.get('/pricing-rules', requirePermission('pricing.manage'), getPricingRules);
+  adminRoutes.post('/pricing-rules', requirePermission('pricing.manage'), createPricingRule);
+  adminRoutes.post('/pricing-rules/preview', requirePermission('pricing.manage'), previewPricing);
+  adminRoutes.put('/pricing-rules/:id', requirePermission('pricing.manage'), updatePricingRule);
+  adminRoutes.delete('/pricing-rules/:id', requirePermission('pricing.manage'), deletePricingRule);
+
+  // Holiday and event surcharges
+  adminRoutes.get('/surcharges', requirePermission('pricing.manage'), getSurcharges);
+  adminRoutes.post('/surcharges', requirePermission('pricing.manage'), createSurcharge);
+  adminRoutes.put('/surcharges/:id', requirePermission('pricing.manage'), updateSurcharge);
+  adminRoutes.delete('/surcharges/:id', requirePermission('pricing.manage'), deleteSurcharge);
+
+  // Happy hour and other time-based prices
+  adminRoutes.get('/price-schedules', requirePermission('pricing.manage'), getPriceSchedules);
+  adminRoutes.post('/price-schedules', requirePermission('pricing.manage'), createPriceSchedule);
+  adminRoutes.put('/price-schedules/:id', requirePermission('pricing.manage'), updatePriceSchedule);
+  adminRoutes.delete('/price-schedules/:id', requirePermission('pricing.manage'), deletePriceSchedule);
+
+  // Tax and service charge exemptions
+  adminRoutes.get('/tax-exemptions', requirePermission('tax.manage'), getTaxExemptions);
+  adminRoutes.post('/tax-exemptions', requirePermission('tax.manage'), createTaxExemption);
+  adminRoutes.put('/tax-exemptions/:id', requirePermission('tax.manage'), updateTaxExemption);
+  adminRoutes.delete('/tax-exemptions/:id', requirePermission('tax.manage'), deleteTaxExemption);
+
+  // Tax classes
+  adminRoutes.get('/tax-classes', requirePermission('tax.manage'), getTaxClasses);
+  adminRoutes.post('/tax-classes', requirePermission('tax.manage'), createTaxClass);
+  adminRoutes.put('/tax-classes/:id', requirePermission('tax.manage'), updateTaxClass);
+  adminRoutes.delete('/tax-classes/:id', requirePermission('tax.manage'), deleteTaxClass);
+
+  // Display currencies
+  adminRoutes.get('/currencies', requirePermission('currencies.manage'), getCurrencies);
+  adminRoutes.post('/currencies', requirePermission('currencies.manage'), createCurrency);
+  adminRoutes.put('/currencies/:id', requirePermission('currencies.manage'), updateCurrency);
+  adminRoutes.delete('/currencies/:id', requirePermission('currencies.manage'), deleteCurrency);
+
+  // Payment methods
+  adminRoutes.get('/payment-methods', requirePermission('payment_methods.manage'), getAllPaymentMethods);
+  adminRoutes.post('/payment-methods', requirePermission('payment_methods.manage'), createPaymentMethod);
+  adminRoutes.put('/payment-methods/:code', requirePermission('payment_methods.manage'), updatePaymentMethod);
+  adminRoutes.delete('/payment-methods/:code', requirePermission('payment_methods.manage'), deletePaymentMethod);
+  adminRoutes.get('/note-phrases', requirePermission('menu.manage'), getAllNotePhrases);
+  adminRoutes.post('/note-phrases', requirePermission('menu.manage'), createNotePhrase);
+  adminRoutes.put('/note-phrases/:code', requirePermission('menu.manage'), updateNotePhrase);
+  adminRoutes.delete('/note-phrases/:code', requirePermission('menu.manage'), deleteNotePhrase);
+
+  // Product costs and COGS adjustments
+  adminRoutes.get('/product-costs', requirePermission('costing.manage'), getProductCosts);
+  adminRoutes.put('/product-costs/:id', requirePermission('costing.manage'), updateProductCost);
+  adminRoutes.get('/cogs-adjustments', requirePermission('costing.manage'), getCogsAdjustments);
+  adminRoutes.post('/cogs-adjustments', requirePermission('costing.manage'), createCogsAdjustment);
+  adminRoutes.delete('/cogs-adjustments/:id', requirePermission('costing.manage'), deleteCogsAdjustment);
+
+  // Daily specials
+  adminRoutes.get('/daily-specials', requirePermission('menu.manage'), getDailySpecials);
+  adminRoutes.post('/daily-specials', requirePermission('menu.manage'), createDailySpecial);
+  adminRoutes.put('/daily-specials/:id', requirePermission('menu.manage'), updateDailySpecial);
+  adminRoutes.delete('/daily-specials/:id', requirePermission('menu.manage'), deleteDailySpecial);
+  adminRoutes.post('/daily-specials/:id/reset', requirePermission('menu.manage'), resetDailySpecial);
+
+  // Staff sales targets
+  adminRoutes.get('/sales-targets', requirePermission('sales_targets.manage'), getSalesTargets);
+  adminRoutes.get('/sales-targets/progress', requirePermission('sales_targets.manage'), getTeamTargetProgress);
+  adminRoutes.put('/sales-targets/:user_id', requirePermission('sales_targets.manage'), setSalesTarget);
+  adminRoutes.delete('/sales-targets/:user_id/:period_type', requirePermission('sales_targets.manage'), removeSalesTarget);
+  adminRoutes.get('/sections', requirePermission('sections.manage'), getSections);
+  adminRoutes.post('/sections', requirePermission('sections.manage'), createSection);
+  adminRoutes.put('/sections/:id', requirePermission('sections.manage'), updateSection);
+  adminRoutes.delete('/sections/:id', requirePermission('sections.manage'), deleteSection);
+  adminRoutes.post('/sections/:id/assignments', requirePermission('sections.manage'), assignSectionServer);
+  adminRoutes.delete('/sections/assignments/:assignment_id', requirePermission('sections.manage'), removeSectionAssignment);
+
+  // Staff commissions
+  adminRoutes.get('/commissions/rules', requirePermission('commissions.manage'), getCommissionRules);
+  adminRoutes.post('/commissions/rules', requirePermission('commissions.manage'), createCommissionRule);
+  adminRoutes.put('/commissions/rules/:id', requirePermission('commissions.manage'), updateCommissionRule);
+  adminRoutes.delete('/commissions/rules/:id', requirePermission('commissions.manage'), deleteCommissionRule);
+  adminRoutes.get('/commissions/report', requirePermission('commissions.manage'), reports, getCommissionReport);
+  adminRoutes.get('/commissions/report/export', requirePermission('commissions.manage'), requirePermission('data.export'), exportThrottle, exportCommissionReport);
+
+  // Staff attendance
+  adminRoutes.get('/staff-shifts', requirePermission('attendance.manage'), getStaffShifts);
+  adminRoutes.post('/staff-shifts', requirePermission('attendance.manage'), createStaffShift);
+  adminRoutes.delete('/staff-shifts/:id', requirePermission('attendance.manage'), deleteStaffShift);
+  adminRoutes.get('/attendance', requirePermission('attendance.manage'), getAttendance);
+  adminRoutes.put('/attendance/:id', requirePermission('attendance.manage'), adjustAttendance);
+  adminRoutes.put('/users/:id/attendance-pin', requirePermission('attendance.manage'), setAttendancePin);
+  adminRoutes.put('/devices/:device_id/attendance', requirePermission('attendance.manage'), setAttendanceTerminal);
+  adminRoutes.get('/timesheets', requirePermission('attendance.manage'), reports, getTimesheets);
+  adminRoutes.get('/timesheets/export', requirePermission('attendance.manage'), requirePermission('data.export'), exportThrottle, exportTimesheets);
+
+  // Accounting exports (daily sales journal for Accurate / Jurnal)
+  adminRoutes.get('/exports/journal', requirePermission('accounting.export'), requirePermission('data.export'), exportThrottle, exportJournal);
+  adminRoutes.get('/exports/log', requirePermission('settings.manage'), getExportLog);
+
+  // Manager log book
+  adminRoutes.get('/logbook', requirePermission('logbook.manage'), getLogbookEntries);
+  adminRoutes.get('/logbook/tags', requirePermission('logbook.manage'), getLogbookTags);
+  adminRoutes.get('/logbook/days/:date', requirePermission('logbook.manage'), getLogbookDay);
+  adminRoutes.post('/logbook', requirePermission('logbook.manage'), createLogbookEntry);
+  adminRoutes.put('/logbook/:id', requirePermission('logbook.manage'), updateLogbookEntry);
+  adminRoutes.delete('/logbook/:id', requirePermission('logbook.manage'), deleteLogbookEntry);
+  adminRoutes.post('/logbook/digest', requirePermission('logbook.manage'), sendLogbookDigestNow);
+
+  // Corporate meal accounts
+  adminRoutes.get('/corporate-accounts', requirePermission('corporate.manage'), getCorporateAccounts);
+  adminRoutes.post('/corporate-accounts', requirePermission('corporate.manage'), createCorporateAccount);
+  adminRoutes.get('/corporate-accounts/:id', requirePermission('corporate.manage'), getCorporateAccount);
+  adminRoutes.put('/corporate-accounts/:id', requirePermission('corporate.manage'), updateCorporateAccount);
+  adminRoutes.post('/corporate-accounts/:id/employees', requirePermission('corporate.manage'), createCorporateEmployee);
+  adminRoutes.put('/corporate-accounts/:id/employees/:employee_id', requirePermission('corporate.manage'), updateCorporateEmployee);
+  adminRoutes.post('/corporate-accounts/:id/topups', requirePermission('corporate.manage'), createCorporateTopup);
+  adminRoutes.get('/corporate-accounts/:id/statement', requirePermission('corporate.manage'), getCorporateStatement);
+  adminRoutes.get('/corporate-accounts/:id/credit', requirePermission('corporate.manage'), getCorporateCredit);
+  adminRoutes.post('/corporate-accounts/:id/invoices', requirePermission('corporate.manage'), generateCorporateInvoice);
+  adminRoutes.get('/corporate-invoices', requirePermission('corporate.manage'), getCorporateInvoices);
+  adminRoutes.get('/corporate-invoices/:id', requirePermission('corporate.manage'), getCorporateInvoice);
+  adminRoutes.post('/corporate-invoices/:id/payments', requirePermission('corporate.manage'), recordCorporateInvoicePayment);
+
+  // Background jobs
+  adminRoutes.get('/jobs', requirePermission('jobs.manage'), getJobs);
+  adminRoutes.get('/jobs/stats', requirePermission('jobs.manage'), getJobStats);
+  adminRoutes.get('/jobs/:id', requirePermission('jobs.manage'), getJob);
+  adminRoutes.post('/jobs/:id/retry', requirePermission('jobs.manage'), retryJob);
+
+  // Jobs that ran out of attempts, for inspection and replay
+  adminRoutes.get('/dead-letters', requirePermission('jobs.manage'), getDeadLetters);
+  adminRoutes.get('/dead-letters/stats', requirePermission('jobs.manage'), getDeadLetterStats);
+  adminRoutes.post('/dead-letters/replay', requirePermission('jobs.manage'), replayDeadLetters);
+  adminRoutes.get('/dead-letters/:id', requirePermission('jobs.manage'), getDeadLetter);
+  adminRoutes.post('/dead-letters/:id/replay', requirePermission('jobs.manage'), replayDeadLetter);
+  adminRoutes.post('/dead-letters/:id/discard', requirePermission('jobs.manage'), discardDeadLetter);
+
+  // Analytics export to object storage
+  adminRoutes.get('/analytics-export', requirePermission('analytics.export'), getAnalyticsExport);
+  adminRoutes.get('/analytics-export/runs', requirePermission('analytics.export'), getAnalyticsExportRuns);
+  adminRoutes.post('/analytics-export/run', requirePermission('analytics.export'), runAnalyticsExport);
+  adminRoutes.post('/analytics-export/streams/:stream/rewind', requirePermission('analytics.export'), rewindAnalyticsStream);
+
+  // Email outbox
+  adminRoutes.get('/emails', requirePermission('email.manage'), getEmails);
+  adminRoutes.get('/emails/:id', requirePermission('email.manage'), getEmail);
+  adminRoutes.post('/emails/:id/retry', requirePermission('email.manage'), retryEmail);
+  adminRoutes.post('/email/test', requirePermission('email.manage'), sendTestEmail);
 
   // File upload
-  adminRoutes.post('/upload', uploadImage);
-  adminRoutes.delete('/upload/:filename', deleteImage);
+  adminRoutes.post('/upload', requirePermission('menu.manage'), uploadQuota, uploadImage);
+  adminRoutes.delete('/upload/:filename', requirePermission('menu.manage'), deleteImage);
 
   api.route('/admin', adminRoutes);
 
-  // ── Kitchen routes (kitchen/admin/manager) ──────────────────────────────────
+  // ── Kitchen routes ──────────────────────────────────────────────────────────
 
   const kitchenRoutes = new Hono();
   kitchenRoutes.use('*', authMiddleware);
-  kitchenRoutes.use('*', requireRoles(['kitchen', 'admin', 'manager']));
 
-  kitchenRoutes.get('/orders', getKitchenOrders);
-  kitchenRoutes.patch('/orders/:id/items/:item_id/status', updateOrderItemStatus);
+  kitchenRoutes.get('/orders', requirePermission('kitchen.view'), getKitchenOrders);
+  kitchenRoutes.patch('/orders/:id/items/:item_id/status', requirePermission('kitchen.update'), updateOrderItemStatus);
+  kitchenRoutes.get('/waste', requirePermission('kitchen.waste'), getWasteLogs);
+  kitchenRoutes.post('/waste', requirePermission('kitchen.waste'), logWaste);
+  kitchenRoutes.post('/waste/photo', requirePermission('kitchen.waste'), uploadQuota, uploadImage);
+  kitchenRoutes.get('/eighty-six', requirePermission('kitchen.view'), getEightySixList);
+  kitchenRoutes.get('/remakes', requirePermission('kitchen.view'), getRemakes);
+  kitchenRoutes.patch('/products/:id/eighty-six', requirePermission('kitchen.eighty_six'), setEightySix);
 
   api.route('/kitchen', kitchenRoutes);
 
@@ -0,0 +1,25 @@
+import { describe, it, expect } from 'vitest';
+import { roundCash } from '../cash-rounding.js';
+
+describe('roundCash', () => {
+  it('leaves the amount alone when rounding is off', () => {
+    expect(roundCash(12_345, { increment: 0, mode: 'nearest' })).toBe(12_345);
+  });
+
+  it('rounds to the nearest increment', () => {
+    expect(roundCash(12_349, { increment: 100, mode: 'nearest' })).toBe(12_300);
+    expect(roundCash(12_350, { increment: 100, mode: 'nearest' })).toBe(12_400);
+    expect(roundCash(12_250, { increment: 500, mode: 'nearest' })).toBe(12_500);
+  });
+
+  it('rounds down and up', () => {
+    expect(roundCash(12_399, { increment: 100, mode: 'down' })).toBe(12_300);
+    expect(roundCash(12_301, { increment: 100, mode: 'up' })).toBe(12_400);
+  });
+
+  it('keeps amounts already on the increment', () => {
+    for (const mode of ['nearest', 'down', 'up']) {
+      expect(roundCash(12_500, { increment: 500, mode })).toBe(12_500);
+    }
+  });
+});
@@ -0,0 +1,55 @@
+import { describe, it, expect, vi } from 'vitest';
+import { signOrderToken, verifyOrderToken } from '../order-links.js';
+
+vi.mock('../../env.js', () => ({
+  env: {
+    JWT_SECRET: 'test-jwt-secret',
+    LINK_TOKEN_SECRET: 'test-link-secret',
+    ORDER_LINK_TTL_HOURS: 48,
+    SURVEY_LINK_TTL_DAYS: 30,
+    PUBLIC_APP_URL: 'https://pos.example.com/',
+  },
+}));
+
+const ORDER_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+const NOW = new Date('2026-10-14T12:00:00Z');
+
+describe('signOrderToken / verifyOrderToken', () => {
+  it('round-trips the order and purpose', () => {
+    const { token, expires_at } = signOrderToken(ORDER_ID, 'order', NOW);
+    expect(token).toMatch(/^[A-Za-z0-9_-]{66}$/);
+    expect(expires_at).toBe('2026-10-16T12:00:00.000Z');
+    expect(verifyOrderToken(token, ['order'], NOW)).toEqual({ ok: true, orderId: ORDER_ID, purpose: 'order' });
+  });
+
+  it('gives survey tokens the survey lifetime', () => {
+    expect(signOrderToken(ORDER_ID, 'survey', NOW).expires_at).toBe('2026-11-13T12:00:00.000Z');
+  });
+
+  it('does not reveal the order ID', () => {
+    const { token } = signOrderToken(ORDER_ID, 'order', NOW);
+    expect(Buffer.from(token, 'base64url').toString('hex')).not.toContain(ORDER_ID.replace(/-/g, ''));
+  });
+
+  it('rejects a token for another purpose as not found', () => {
+    const { token } = signOrderToken(ORDER_ID, 'survey', NOW);
+    const check = verifyOrderToken(token, ['order'], NOW);
+    expect(check.ok).toBe(false);
+    if (!check.ok) expect(check.failure).toMatchObject({ code: 'order_not_found', status: 404 });
+  });
+
+  it('answers an expired token with 410', () => {
+    const { token } = signOrderToken(ORDER_ID, 'order', NOW);
+    const check = verifyOrderToken(token, ['order'], new Date('2026-10-16T12:00:00Z'));
+    expect(check.ok).toBe(false);
+    if (!check.ok) expect(check.failure).toMatchObject({ code: 'order_link_expired', status: 410 });
+  });
+
+  it('rejects an altered or malformed token', () => {
+    const { token } = signOrderToken(ORDER_ID, 'order', NOW);
+    const flipped = token.slice(0, 20) + (token[20] === 'A' ? 'B' : 'A') + token.slice(21);
+    expect(verifyOrderToken(flipped, ['order'], NOW).ok).toBe(false);
+    expect(verifyOrderToken(ORDER_ID, ['order'], NOW).ok).toBe(false);
+    expect(verifyOrderToken('', ['order'], NOW).ok).toBe(false);
+  });
+});
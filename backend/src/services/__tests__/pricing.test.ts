@@ -0,0 +1,124 @@
+import { describe, it, expect } from 'vitest';
+import { applyPricingRules, priceOrder, ruleInEffect, type PricingLine, type PricingRule, type Queryable } from '../pricing.js';
+
+function rule(overrides: Partial<PricingRule>): PricingRule {
+  return {
+    id: 'rule',
+    name: 'Rule',
+    rule_type: 'item_discount',
+    discount_type: 'percentage',
+    discount_value: 10,
+    priority: 0,
+    stackable: false,
+    product_ids: [],
+    category_ids: [],
+    bundle_product_ids: [],
+    min_quantity: 1,
+    days_of_week: [],
+    start_time: null,
+    end_time: null,
+    valid_from: null,
+    valid_until: null,
+    is_active: true,
+    ...overrides,
+  };
+}
+
+const STEAK: PricingLine = { product_id: 'steak', category_id: 'mains', name: 'Ribeye', unit_price: 200_000, quantity: 1 };
+const WINE: PricingLine = { product_id: 'wine', category_id: 'drinks', name: 'House red', unit_price: 80_000, quantity: 2 };
+
+describe('applyPricingRules', () => {
+  it('applies a percentage discount to matching products only', () => {
+    const result = applyPricingRules([STEAK, WINE], [rule({ product_ids: ['wine'] })]);
+    expect(result.subtotal).toBe(360_000);
+    expect(result.line_discounts).toEqual([0, 16_000]);
+    expect(result.discount_amount).toBe(16_000);
+    expect(result.adjustments).toHaveLength(1);
+  });
+
+  it('matches by category and respects min_quantity', () => {
+    const byCategory = applyPricingRules([STEAK, WINE], [rule({ category_ids: ['mains'], discount_type: 'fixed', discount_value: 25_000 })]);
+    expect(byCategory.line_discounts).toEqual([25_000, 0]);
+
+    const tooFew = applyPricingRules([STEAK, WINE], [rule({ min_quantity: 3 })]);
+    expect(tooFew.discount_amount).toBe(0);
+    expect(tooFew.adjustments).toEqual([]);
+  });
+
+  it('lets a non-stackable rule lock its lines against later rules', () => {
+    const first = rule({ id: 'first', product_ids: ['steak'], discount_value: 20 });
+    const second = rule({ id: 'second', stackable: true, discount_value: 10 });
+    const result = applyPricingRules([STEAK, WINE], [first, second]);
+    expect(result.line_discounts).toEqual([40_000, 16_000]);
+    expect(result.adjustments.map((a) => a.rule_id)).toEqual(['first', 'second']);
+  });
+
+  it('stacks stackable rules on the same line', () => {
+    const first = rule({ id: 'first', stackable: true, discount_type: 'fixed', discount_value: 10_000 });
+    const second = rule({ id: 'second', stackable: true, discount_type: 'fixed', discount_value: 5_000 });
+    expect(applyPricingRules([STEAK], [first, second]).line_discounts).toEqual([15_000]);
+  });
+
+  it('skips a non-stackable rule on a line already discounted', () => {
+    const first = rule({ id: 'first', stackable: true, discount_type: 'fixed', discount_value: 10_000 });
+    const second = rule({ id: 'second', discount_value: 50 });
+    const result = applyPricingRules([STEAK], [first, second]);
+    expect(result.line_discounts).toEqual([10_000]);
+    expect(result.adjustments.map((a) => a.rule_id)).toEqual(['first']);
+  });
+
+  it('never discounts more than the line is worth', () => {
+    const result = applyPricingRules([STEAK], [rule({ discount_type: 'fixed', discount_value: 500_000 })]);
+    expect(result.line_discounts).toEqual([200_000]);
+  });
+
+  it('spreads a bundle discount over its members by price', () => {
+    const bundle = rule({
+      rule_type: 'bundle', discount_type: 'fixed', discount_value: 36_000, bundle_product_ids: ['steak', 'wine'],
+    });
+    const result = applyPricingRules([STEAK, WINE], [bundle]);
+    expect(result.line_discounts).toEqual([25_714.29, 10_285.71]);
+    expect(result.adjustments[0].details.bundles).toBe(1);
+  });
+
+  it('skips a bundle with a member missing', () => {
+    const bundle = rule({ rule_type: 'bundle', bundle_product_ids: ['steak', 'dessert'] });
+    expect(applyPricingRules([STEAK, WINE], [bundle]).adjustments).toEqual([]);
+  });
+});
+
+describe('ruleInEffect', () => {
+  // 2026-10-14 is a Wednesday; 12:00 UTC is 19:00 in Jakarta
+  const AT = new Date('2026-10-14T12:00:00Z');
+
+  it('checks is_active and the validity period', () => {
+    expect(ruleInEffect(rule({}), AT)).toBe(true);
+    expect(ruleInEffect(rule({ is_active: false }), AT)).toBe(false);
+    expect(ruleInEffect(rule({ valid_from: '2026-10-15T00:00:00Z' }), AT)).toBe(false);
+    expect(ruleInEffect(rule({ valid_until: '2026-10-14T12:00:00Z' }), AT)).toBe(false);
+  });
+
+  it('checks the weekday and daily window in restaurant time', () => {
+    expect(ruleInEffect(rule({ days_of_week: [3] }), AT)).toBe(true);
+    expect(ruleInEffect(rule({ days_of_week: [0, 6] }), AT)).toBe(false);
+    expect(ruleInEffect(rule({ start_time: '17:00', end_time: '20:00' }), AT)).toBe(true);
+    expect(ruleInEffect(rule({ start_time: '11:00', end_time: '14:00' }), AT)).toBe(false);
+    expect(ruleInEffect(rule({ start_time: '18:00', end_time: '02:00' }), AT)).toBe(true);
+  });
+});
+
+describe('priceOrder', () => {
+  it('applies only the rules in effect at the given time', async () => {
+    const rows = [
+      { ...rule({ id: 'lunch', start_time: '11:00', end_time: '14:00', discount_value: 50 }) },
+      { ...rule({ id: 'dinner', start_time: '17:00', end_time: '22:00', discount_value: 10 }) },
+    ];
+    const q = { query: async () => ({ rows }) } as unknown as Queryable;
+
+    const dinner = await priceOrder(q, [STEAK], new Date('2026-10-14T12:00:00Z'));
+    expect(dinner.adjustments.map((a) => a.rule_id)).toEqual(['dinner']);
+
+    const lunch = await priceOrder(q, [STEAK], new Date('2026-10-14T05:00:00Z'));
+    expect(lunch.adjustments.map((a) => a.rule_id)).toEqual(['lunch']);
+  });
+});
@@ -0,0 +1,35 @@
+import { describe, it, expect, vi } from 'vitest';
+import { signTableCode, verifyTableCode } from '../table-qr.js';
+
+vi.mock('../../env.js', () => ({
+  env: {
+    JWT_SECRET: 'test-jwt-secret',
+    QR_SIGNING_SECRET: 'test-qr-secret',
+    PUBLIC_APP_URL: 'https://pos.example.com',
+  },
+}));
+
+const TABLE_ID = '0b5c3b1e-2f1a-4a7e-9d3c-6f1e2a3b4c5d';
+
+describe('verifyTableCode', () => {
+  it('returns the table and version of a signed code', () => {
+    expect(verifyTableCode(signTableCode(TABLE_ID, 3))).toEqual({ tableId: TABLE_ID, version: 3 });
+  });
+
+  it('rejects a code whose version was changed', () => {
+    const [table, , signature] = signTableCode(TABLE_ID, 3).split('.');
+    expect(verifyTableCode(`${table}.4.${signature}`)).toBeNull();
+  });
+
+  it('rejects a code for another table with the same signature', () => {
+    const signature = signTableCode(TABLE_ID, 1).split('.')[2];
+    const other = signTableCode('7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d', 1).split('.')[0];
+    expect(verifyTableCode(`${other}.1.${signature}`)).toBeNull();
+  });
+
+  it('rejects a bare table ID and malformed codes', () => {
+    expect(verifyTableCode(TABLE_ID)).toBeNull();
+    expect(verifyTableCode('')).toBeNull();
+    expect(verifyTableCode(`${signTableCode(TABLE_ID, 1)}x`)).toBeNull();
+  });
+});
@@ -0,0 +1,44 @@
+import { describe, it, expect } from 'vitest';
+import { companyRefundCredit, validateTip } from '../tips.js';
+
+describe('validateTip', () => {
+  it('accepts a tip from zero up to the payment itself', () => {
+    expect(validateTip(0, 100_000)).toBeNull();
+    expect(validateTip(15_000, 100_000)).toBeNull();
+    expect(validateTip(100_000, 100_000)).toBeNull();
+  });
+
+  it('rejects a tip that is not an amount of zero or more', () => {
+    for (const tip of [-1, '5000', null, undefined, NaN, Infinity]) {
+      expect(validateTip(tip, 100_000)?.code).toBe('invalid_tip_amount');
+    }
+  });
+
+  it('rejects a tip larger than the payment', () => {
+    expect(validateTip(100_001, 100_000)?.code).toBe('tip_too_large');
+  });
+});
+
+describe('companyRefundCredit', () => {
+  // Rp 100.000 settled on the order, billed with a Rp 2.000 surcharge and a Rp 10.000 tip
+  const payment = { amount: 100_000, surcharge_amount: 2_000, tip_amount: 10_000 };
+
+  it('credits everything billed on a full refund', () => {
+    expect(companyRefundCredit(payment, 0, 100_000)).toBe(112_000);
+  });
+
+  it('credits the same share of the surcharge and tip on a partial refund', () => {
+    expect(companyRefundCredit(payment, 0, 25_000)).toBe(28_000);
+  });
+
+  it('adds up to what was billed over several partial refunds', () => {
+    const first = companyRefundCredit(payment, 0, 33_333);
+    const second = companyRefundCredit(payment, 33_333, 33_333);
+    const third = companyRefundCredit(payment, 66_666, 33_334);
+    expect(Math.round((first + second + third) * 100) / 100).toBe(112_000);
+  });
+
+  it('credits only the amount without a surcharge or tip', () => {
+    expect(companyRefundCredit({ amount: 50_000, surcharge_amount: 0, tip_amount: 0 }, 0, 50_000)).toBe(50_000);
+  });
+});
@@ -0,0 +1,234 @@
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { toCsv } from '../lib/csv.js';
+import { branchCondition } from './branches.js';
+import type { Queryable } from './pricing.js';
+
+// Daily sales journal for the accounting system. One balanced entry per
+// day (and branch) covers the completed orders created that day, the same
+// orders the tax report counts:
+//   debit   what was received, per payment method (refunds net out),
+//           including any payment method surcharge
+//   debit   anything still unpaid, to receivables
+//   credit  sales, service charge, surcharges, delivery fee and tax payable
+//   credit  container deposits collected, which are owed back
+// plus the container deposits refunded that day, from the branch's drawer.
+// Account codes come from the accounting_accounts setting, so they can be
+// matched to the restaurant's chart of accounts.
+
+export const JOURNAL_FORMATS = ['accurate', 'jurnal', 'json'] as const;
+export type JournalFormat = (typeof JOURNAL_FORMATS)[number];
+
+export interface Account {
+  code: string;
+  name: string;
+}
+
+export type AccountKey =
+  | 'cash' | 'credit_card' | 'debit_card' | 'digital_wallet' | 'corporate_wallet' | 'on_account'
+  | 'receivable' | 'sales' | 'service_charge' | 'surcharges' | 'delivery_fee' | 'tax_payable' | 'container_deposits';
+
+const DEFAULT_ACCOUNTS: Record<AccountKey, Account> = {
+  cash: { code: '1-1100', name: 'Kas' },
+  credit_card: { code: '1-1200', name: 'Bank - EDC Kartu Kredit' },
+  debit_card: { code: '1-1201', name: 'Bank - EDC Kartu Debit' },
+  digital_wallet: { code: '1-1202', name: 'Bank - Dompet Digital' },
+  // Spending a corporate wallet uses up the company's prepaid deposit
+  corporate_wallet: { code: '2-1400', name: 'Deposit Pelanggan Korporat' },
+  on_account: { code: '1-1310', name: 'Piutang Usaha Korporat' },
+  receivable: { code: '1-1300', name: 'Piutang Usaha' },
+  sales: { code: '4-1000', name: 'Pendapatan Penjualan' },
+  service_charge: { code: '4-1100', name: 'Pendapatan Service Charge' },
+  surcharges: { code: '4-1300', name: 'Pendapatan Surcharge' },
+  delivery_fee: { code: '4-1200', name: 'Pendapatan Ongkos Kirim' },
+  tax_payable: { code: '2-1300', name: 'Hutang Pajak Restoran (PB1)' },
+  container_deposits: { code: '2-1500', name: 'Hutang Deposit Wadah' },
+};
+
+const PAYMENT_LABELS: Record<string, string> = {
+  cash: 'Cash sales',
+  credit_card: 'Credit card sales',
+  debit_card: 'Debit card sales',
+  digital_wallet: 'Digital wallet sales',
+  corporate_wallet: 'Corporate wallet redemptions',
+  on_account: 'Sales on corporate account',
+};
+
+export interface JournalLine {
+  account_code: string;
+  account_name: string;
+  description: string;
+  debit: number;
+  credit: number;
+}
+
+export interface DailyJournal {
+  date: string;
+  branch_id: string | null;
+  reference: string;
+  memo: string;
+  orders: number;
+  lines: JournalLine[];
+  total_debit: number;
+  total_credit: number;
+}
+
+const round = (n: unknown) => Math.round(Number(n ?? 0) * 100) / 100;
+
+// ── LoadAccountMap ──────────────────────────────────────────────────────────
+// Setting entries override the defaults one account at a time.
+
+export async function loadAccountMap(q: Queryable): Promise<Record<AccountKey, Account>> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'accounting_accounts'`);
+  const accounts = { ...DEFAULT_ACCOUNTS };
+  if (res.rows.length === 0) return accounts;
+
+  let configured: Record<string, Partial<Account>>;
+  try {
+    configured = JSON.parse(res.rows[0].setting_value || '{}');
+  } catch {
+    return accounts;
+  }
+  for (const key of Object.keys(accounts) as AccountKey[]) {
+    const entry = configured?.[key];
+    if (entry?.code) {
+      accounts[key] = { code: String(entry.code), name: String(entry.name ?? accounts[key].name) };
+    }
+  }
+  return accounts;
+}
+
+// ── BuildDailyJournal ───────────────────────────────────────────────────────
+
+export async function buildDailyJournal(q: Queryable, date: string, branchId: string | null): Promise<DailyJournal> {
+  const accounts = await loadAccountMap(q);
+  const params: unknown[] = [date, RESTAURANT_TIMEZONE];
+  const where = `o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $2) = $1${branchCondition('o.branch_id', branchId, params)}`;
+
+  const totalsRes = await q.query(
+    `SELECT COUNT(*) AS orders,
+            COALESCE(SUM(o.total_amount), 0) AS total,
+            COALESCE(SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount), 0) AS net_sales,
+            COALESCE(SUM(o.service_charge_amount), 0) AS service_charge,
+            COALESCE(SUM(o.surcharge_amount), 0) AS surcharges,
+            COALESCE(SUM(o.delivery_fee), 0) AS delivery_fee,
+            COALESCE(SUM(o.tax_amount), 0) AS tax,
+            COALESCE(SUM(o.deposit_amount), 0) AS deposits
+     FROM orders o WHERE ${where}`,
+    params,
+  );
+  const paymentsRes = await q.query(
+    `SELECT p.payment_method, SUM(p.amount + p.surcharge_amount) AS amount, SUM(p.surcharge_amount) AS surcharges
+     FROM payments p
+     JOIN orders o ON o.id = p.order_id
+     WHERE p.status = 'completed' AND ${where}
+     GROUP BY p.payment_method
+     ORDER BY p.payment_method ASC`,
+    params,
+  );
+
+  const refundParams: unknown[] = [date, RESTAURANT_TIMEZONE];
+  const refundsRes = await q.query(
+    `SELECT r.refund_method, SUM(r.refund_amount) AS amount
+     FROM container_returns r
+     WHERE DATE(r.created_at AT TIME ZONE $2) = $1${branchCondition('r.branch_id', branchId, refundParams)}
+     GROUP BY r.refund_method
+     ORDER BY r.refund_method ASC`,
+    refundParams,
+  );
+
+  let branchCode: string | null = null;
+  if (branchId) {
+    const branch = await q.query('SELECT code FROM branches WHERE id = $1', [branchId]);
+    branchCode = branch.rows[0]?.code ?? null;
+  }
+
+  const totals = totalsRes.rows[0];
+  const line = (account: Account, description: string, debit: number, credit: number): JournalLine => ({
+    account_code: account.code,
+    account_name: account.name,
+    description,
+    debit,
+    credit,
+  });
+
+  const debits: JournalLine[] = [];
+  let received = 0;
+  let methodSurcharges = 0;
+  for (const row of paymentsRes.rows) {
+    methodSurcharges = round(methodSurcharges + round(row.surcharges));
+    const amount = round(row.amount);
+    if (amount === 0) continue;
+    received = round(received + amount);
+    // A method without its own account is still money owed to the restaurant
+    const account = accounts[row.payment_method as AccountKey] ?? accounts.receivable;
+    const label = PAYMENT_LABELS[row.payment_method] ?? `Sales paid by ${row.payment_method}`;
+    debits.push(amount > 0 ? line(account, label, amount, 0) : line(account, `${label} (net refunds)`, 0, -amount));
+  }
+
+  const credits: JournalLine[] = [];
+  const addCredit = (account: Account, description: string, amount: number) => {
+    if (amount !== 0) credits.push(line(account, description, 0, amount));
+  };
+  addCredit(accounts.sales, 'Food and beverage sales', round(totals.net_sales));
+  addCredit(accounts.service_charge, 'Service charge', round(totals.service_charge));
+  addCredit(accounts.surcharges, 'Holiday and event surcharges', round(totals.surcharges));
+  addCredit(accounts.surcharges, 'Payment method surcharges', methodSurcharges);
+  addCredit(accounts.delivery_fee, 'Delivery fees', round(totals.delivery_fee));
+  addCredit(accounts.tax_payable, 'Restaurant tax collected', round(totals.tax));
+  addCredit(accounts.container_deposits, 'Container deposits collected', round(totals.deposits));
+
+  // Whatever the payments don't cover (or overpaid) balances the entry
+  const billed = round(credits.reduce((sum, l) => sum + l.credit, 0));
+  const outstanding = round(billed - received);
+  if (outstanding > 0) {
+    debits.push(line(accounts.receivable, 'Unpaid balance of completed orders', outstanding, 0));
+  } else if (outstanding < 0) {
+    debits.push(line(accounts.receivable, 'Payments in excess of order totals', 0, -outstanding));
+  }
+
+  const refunds: JournalLine[] = [];
+  for (const row of refundsRes.rows) {
+    const amount = round(row.amount);
+    const account = accounts[row.refund_method as AccountKey] ?? accounts.cash;
+    refunds.push(line(accounts.container_deposits, 'Container deposits refunded', amount, 0));
+    refunds.push(line(account, `Container deposit refunds (${row.refund_method})`, 0, amount));
+  }
+
+  const lines = [...debits, ...credits, ...refunds];
+  const compact = date.replace(/-/g, '');
+  return {
+    date,
+    branch_id: branchId,
+    reference: branchCode ? `POS-${compact}-${branchCode}` : `POS-${compact}`,
+    memo: `POS sales ${date}${branchCode ? ` (${branchCode})` : ''}`,
+    orders: Number(totals.orders),
+    lines,
+    total_debit: round(lines.reduce((sum, l) => sum + l.debit, 0)),
+    total_credit: round(lines.reduce((sum, l) => sum + l.credit, 0)),
+  };
+}
+
+// ── JournalCsv ──────────────────────────────────────────────────────────────
+// Column layouts of the Accurate general journal import and the Jurnal
+// journal entry import. Both take dd/mm/yyyy dates.
+
+export function journalCsv(journal: DailyJournal, format: 'accurate' | 'jurnal'): string {
+  const [y, m, d] = journal.date.split('-');
+  const date = `${d}/${m}/${y}`;
+  const amount = (n: number) => n.toFixed(2);
+
+  if (format === 'accurate') {
+    return toCsv([
+      ['Tanggal', 'No. Bukti', 'Keterangan', 'No. Akun', 'Nama Akun', 'Catatan', 'Debit', 'Kredit'],
+      ...journal.lines.map((l) => [
+        date, journal.reference, journal.memo, l.account_code, l.account_name, l.description, amount(l.debit), amount(l.credit),
+      ]),
+    ]);
+  }
+  return toCsv([
+    ['Transaction Date', 'Transaction No', 'Account Code', 'Account Name', 'Description', 'Debit', 'Credit', 'Memo'],
+    ...journal.lines.map((l) => [
+      date, journal.reference, l.account_code, l.account_name, l.description, amount(l.debit), amount(l.credit), journal.memo,
+    ]),
+  ]);
+}
@@ -0,0 +1,386 @@
+import bcrypt from 'bcryptjs';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { isDeviceId } from './devices.js';
+import type { Queryable } from './pricing.js';
+
+// Staff attendance. Staff clock in and out themselves (POST /staff/clock-in
+// and /clock-out); each stint is an attendance entry, and a staff member has
+// at most one open entry. Two settings tighten this: attendance_require_pin
+// asks for the PIN a manager set for them, and attendance_require_terminal
+// only accepts POS terminals of their branch that a manager enabled for
+// attendance (device_print_preferences.attendance_clock). Any browser that
+// logs in is registered as a device, so registration alone isn't enough to
+// keep staff from clocking in from home. MAX_PIN_ATTEMPTS wrong PINs in a
+// row lock the staff member's PIN for PIN_LOCKOUT_MINUTES, as wrong
+// passwords lock a login (services/login-lockout.ts).
+//
+// Managers schedule shifts in staff_shifts. Clocking in matches the entry to
+// the shift it falls in, or one starting within EARLY_CLOCK_IN_MINUTES;
+// several entries on one shift (out for a break and back) count together.
+// A shift's break_minutes are unpaid and deducted once from the time worked
+// on it. Time worked past the scheduled length counts as overtime once it
+// exceeds attendance_overtime_grace_minutes, and then in full. Entries with
+// no shift are unscheduled time, reported apart from overtime for a manager
+// to approve. Timesheets cover finished entries by the local date they
+// started; open entries are counted but not added up.
+
+export const EARLY_CLOCK_IN_MINUTES = 60;
+export const MAX_TIMESHEET_DAYS = 62;
+export const MAX_BREAK_MINUTES = 240;
+export const MAX_PIN_ATTEMPTS = 5;
+export const PIN_LOCKOUT_MINUTES = 15;
+
+const PIN_RE = /^\d{4,8}$/;
+
+const DEFAULT_GRACE_MINUTES = 15;
+
+export function isAttendancePin(value: unknown): value is string {
+  return typeof value === 'string' && PIN_RE.test(value);
+}
+
+export function hashAttendancePin(pin: string): Promise<string> {
+  return bcrypt.hash(pin, 10);
+}
+
+export interface AttendanceSettings {
+  requirePin: boolean;
+  requireTerminal: boolean;
+  overtimeGraceMinutes: number;
+}
+
+export async function loadAttendanceSettings(q: Queryable): Promise<AttendanceSettings> {
+  const res = await q.query(
+    `SELECT setting_key, setting_value FROM system_settings
+     WHERE setting_key IN ('attendance_require_pin', 'attendance_require_terminal', 'attendance_overtime_grace_minutes')`,
+  );
+  const values: Record<string, string> = Object.fromEntries(res.rows.map((row) => [row.setting_key, row.setting_value]));
+  const grace = parseInt(values.attendance_overtime_grace_minutes, 10);
+  return {
+    requirePin: values.attendance_require_pin === 'true',
+    requireTerminal: values.attendance_require_terminal === 'true',
+    overtimeGraceMinutes: isNaN(grace) || grace < 0 ? DEFAULT_GRACE_MINUTES : grace,
+  };
+}
+
+export interface ClockFailure {
+  message: string;
+  code: string;
+  status: 400 | 403 | 429;
+}
+
+// ── CheckClockCredentials ───────────────────────────────────────────────────
+// The PIN and terminal checks the settings ask for. Returns the branch of
+// the terminal, if one was named and is enabled for attendance.
+
+export async function checkClockCredentials(
+  q: Queryable,
+  input: { userId: string; branchId: string | null; pin?: unknown; deviceId?: unknown },
+  settings: AttendanceSettings,
+): Promise<{ ok: true; deviceBranchId: string | null } | { ok: false; failure: ClockFailure }> {
+  if (input.deviceId !== undefined && input.deviceId !== null && !isDeviceId(input.deviceId)) {
+    return { ok: false, failure: { message: 'Invalid device_id', code: 'invalid_device_id', status: 400 } };
+  }
+
+  if (settings.requirePin) {
+    if (!isAttendancePin(input.pin)) {
+      return { ok: false, failure: { message: 'Enter your attendance PIN (4 to 8 digits)', code: 'pin_required', status: 400 } };
+    }
+    const res = await q.query(
+      `SELECT attendance_pin_hash, attendance_pin_failed_attempts,
+              CASE WHEN attendance_pin_locked_until > NOW() THEN attendance_pin_locked_until END AS locked_until
+       FROM users WHERE id = $1`,
+      [input.userId],
+    );
+    const user = res.rows[0];
+    if (!user?.attendance_pin_hash) {
+      return { ok: false, failure: { message: 'No attendance PIN is set for you; ask a manager', code: 'attendance_pin_not_set', status: 403 } };
+    }
+    // Checked before the PIN, so a locked PIN can't be guessed at. Not 401,
+    // which clients take for an expired session.
+    if (user.locked_until) {
+      return { ok: false, failure: pinLocked(new Date(user.locked_until)) };
+    }
+    if (!(await bcrypt.compare(input.pin, user.attendance_pin_hash))) {
+      const lockedNow = await recordFailedPin(q, input.userId);
+      if (lockedNow) return { ok: false, failure: pinLocked(lockedNow) };
+      return { ok: false, failure: { message: 'Wrong attendance PIN', code: 'invalid_pin', status: 403 } };
+    }
+    if (user.attendance_pin_failed_attempts > 0) {
+      await q.query('UPDATE users SET attendance_pin_failed_attempts = 0 WHERE id = $1', [input.userId]);
+    }
+  }
+
+  let deviceBranchId: string | null = null;
+  if (typeof input.deviceId === 'string') {
+    const res = await q.query(
+      'SELECT branch_id, attendance_clock FROM device_print_preferences WHERE device_id = $1',
+      [input.deviceId],
+    );
+    const device = res.rows[0];
+    const allowed = device?.attendance_clock === true
+      && (!input.branchId || !device.branch_id || device.branch_id === input.branchId);
+    if (allowed) {
+      deviceBranchId = device.branch_id ?? null;
+    } else if (settings.requireTerminal) {
+      return { ok: false, failure: { message: 'This terminal is not enabled for clocking in at your branch', code: 'terminal_not_allowed', status: 403 } };
+    }
+  } else if (settings.requireTerminal) {
+    return { ok: false, failure: { message: 'Clock in and out on one of the branch\'s attendance terminals', code: 'terminal_required', status: 400 } };
+  }
+  return { ok: true, deviceBranchId };
+}
+
+function pinLocked(until: Date): ClockFailure {
+  return {
+    message: `Too many wrong PINs. Try again after ${until.toISOString()} or ask a manager to reset your PIN.`,
+    code: 'pin_locked',
+    status: 429,
+  };
+}
+
+// Counts a wrong PIN; returns the lock's end when this one locked the PIN
+async function recordFailedPin(q: Queryable, userId: string): Promise<Date | null> {
+  const res = await q.query(
+    `UPDATE users SET
+       attendance_pin_locked_until = CASE WHEN attendance_pin_failed_attempts + 1 >= $2
+                                          THEN NOW() + make_interval(mins => $3) ELSE attendance_pin_locked_until END,
+       attendance_pin_failed_attempts = CASE WHEN attendance_pin_failed_attempts + 1 >= $2
+                                             THEN 0 ELSE attendance_pin_failed_attempts + 1 END
+     WHERE id = $1
+     RETURNING attendance_pin_failed_attempts, attendance_pin_locked_until`,
+    [userId, MAX_PIN_ATTEMPTS, PIN_LOCKOUT_MINUTES],
+  );
+  const row = res.rows[0];
+  return row && row.attendance_pin_failed_attempts === 0 && row.attendance_pin_locked_until
+    ? new Date(row.attendance_pin_locked_until)
+    : null;
+}
+
+// ── MatchShift ──────────────────────────────────────────────────────────────
+// The scheduled shift a clock-in now belongs to: one under way, or starting
+// within EARLY_CLOCK_IN_MINUTES, the earliest first.
+
+export async function matchShift(q: Queryable, userId: string): Promise<{ id: string; branch_id: string } | null> {
+  const res = await q.query(
+    `SELECT id, branch_id FROM staff_shifts
+     WHERE user_id = $1 AND starts_at - make_interval(mins => $2) <= NOW() AND ends_at > NOW()
+     ORDER BY starts_at ASC
+     LIMIT 1`,
+    [userId, EARLY_CLOCK_IN_MINUTES],
+  );
+  return res.rows[0] ?? null;
+}
+
+export const ENTRY_SELECT = `
+  SELECT a.id, a.user_id, u.username, u.first_name, u.last_name, u.role, a.branch_id, a.shift_id,
+         a.clock_in_at, a.clock_out_at, a.clock_in_device_id, a.clock_out_device_id, a.note,
+         a.adjusted_by, a.adjusted_at, a.adjust_reason, a.created_at, a.updated_at,
+         to_char(a.clock_in_at AT TIME ZONE '${RESTAURANT_TIMEZONE}', 'YYYY-MM-DD') AS work_date,
+         FLOOR(EXTRACT(EPOCH FROM COALESCE(a.clock_out_at, NOW()) - a.clock_in_at) / 60)::int AS elapsed_minutes,
+         s.starts_at AS shift_starts_at, s.ends_at AS shift_ends_at, s.break_minutes AS shift_break_minutes
+  FROM attendance a
+  JOIN users u ON u.id = a.user_id
+  LEFT JOIN staff_shifts s ON s.id = a.shift_id`;
+
+export function formatEntry(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    user_id: row.user_id,
+    username: row.username,
+    first_name: row.first_name,
+    last_name: row.last_name,
+    role: row.role,
+    branch_id: row.branch_id,
+    work_date: row.work_date,
+    clock_in_at: row.clock_in_at,
+    clock_out_at: row.clock_out_at,
+    open: row.clock_out_at === null,
+    elapsed_minutes: Number(row.elapsed_minutes),
+    shift: row.shift_id
+      ? { id: row.shift_id, starts_at: row.shift_starts_at, ends_at: row.shift_ends_at, break_minutes: Number(row.shift_break_minutes) }
+      : null,
+    clock_in_device_id: row.clock_in_device_id,
+    clock_out_device_id: row.clock_out_device_id,
+    note: row.note,
+    adjusted_by: row.adjusted_by,
+    adjusted_at: row.adjusted_at,
+    adjust_reason: row.adjust_reason,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+// ── Timesheets ──────────────────────────────────────────────────────────────
+
+export interface TimesheetFigures {
+  worked_minutes: number;
+  scheduled_minutes: number;
+  overtime_minutes: number;
+  unscheduled_minutes: number;
+  late_minutes: number;
+  early_leave_minutes: number;
+}
+
+export interface TimesheetDay extends TimesheetFigures {
+  date: string;
+  entries: number;
+}
+
+export interface Timesheet extends TimesheetFigures {
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  entries: number;
+  open_entries: number;
+  /** Scheduled shifts in the range, already over, with no entry */
+  missed_shifts: number;
+  days: TimesheetDay[];
+}
+
+const minutesBetween = (from: Date, to: Date) => Math.max(0, Math.floor((to.getTime() - from.getTime()) / 60_000));
+
+function emptyFigures(): TimesheetFigures {
+  return { worked_minutes: 0, scheduled_minutes: 0, overtime_minutes: 0, unscheduled_minutes: 0, late_minutes: 0, early_leave_minutes: 0 };
+}
+
+function addFigures(into: TimesheetFigures, add: TimesheetFigures) {
+  for (const key of Object.keys(into) as (keyof TimesheetFigures)[]) into[key] += add[key];
+}
+
+interface WorkBlock {
+  userId: string;
+  date: string;
+  entries: { in: Date; out: Date }[];
+  shift: { startsAt: Date; endsAt: Date; breakMinutes: number } | null;
+}
+
+// One shift's entries, or one unscheduled entry
+function blockFigures(block: WorkBlock, graceMinutes: number): TimesheetFigures {
+  const figures = emptyFigures();
+  const elapsed = block.entries.reduce((sum, e) => sum + minutesBetween(e.in, e.out), 0);
+  if (!block.shift) {
+    figures.worked_minutes = elapsed;
+    figures.unscheduled_minutes = elapsed;
+    return figures;
+  }
+  const { startsAt, endsAt, breakMinutes } = block.shift;
+  figures.worked_minutes = Math.max(0, elapsed - breakMinutes);
+  figures.scheduled_minutes = Math.max(0, minutesBetween(startsAt, endsAt) - breakMinutes);
+  const excess = figures.worked_minutes - figures.scheduled_minutes;
+  figures.overtime_minutes = excess > graceMinutes ? excess : 0;
+  const firstIn = new Date(Math.min(...block.entries.map((e) => e.in.getTime())));
+  const lastOut = new Date(Math.max(...block.entries.map((e) => e.out.getTime())));
+  figures.late_minutes = minutesBetween(startsAt, firstIn);
+  figures.early_leave_minutes = minutesBetween(lastOut, endsAt);
+  return figures;
+}
+
+// ── BuildTimesheets ─────────────────────────────────────────────────────────
+// Per staff member over [from, to] (local dates), with a row per day worked.
+// A shift's entries count on the day of its first entry.
+
+export async function buildTimesheets(
+  q: Queryable,
+  from: string,
+  to: string,
+  filter: { branchId: string | null; userId: string | null },
+  settings: AttendanceSettings,
+): Promise<Timesheet[]> {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE, filter.branchId, filter.userId];
+  const [entryRes, missedRes] = await Promise.all([
+    q.query(
+      `SELECT a.user_id, u.username, u.first_name, u.last_name, u.role, a.shift_id, a.id,
+              a.clock_in_at, a.clock_out_at,
+              to_char(a.clock_in_at AT TIME ZONE $3, 'YYYY-MM-DD') AS work_date,
+              s.starts_at AS shift_starts_at, s.ends_at AS shift_ends_at, s.break_minutes
+       FROM attendance a
+       JOIN users u ON u.id = a.user_id
+       LEFT JOIN staff_shifts s ON s.id = a.shift_id
+       WHERE DATE(a.clock_in_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR a.branch_id = $4)
+         AND ($5::uuid IS NULL OR a.user_id = $5)
+       ORDER BY a.clock_in_at ASC`,
+      params,
+    ),
+    q.query(
+      `SELECT s.user_id, u.username, u.first_name, u.last_name, u.role, COUNT(*) AS missed
+       FROM staff_shifts s
+       JOIN users u ON u.id = s.user_id
+       WHERE DATE(s.starts_at AT TIME ZONE $3) BETWEEN $1 AND $2 AND s.ends_at < NOW()
+         AND ($4::uuid IS NULL OR s.branch_id = $4)
+         AND ($5::uuid IS NULL OR s.user_id = $5)
+         AND NOT EXISTS (SELECT 1 FROM attendance a WHERE a.shift_id = s.id)
+       GROUP BY s.user_id, u.username, u.first_name, u.last_name, u.role`,
+      params,
+    ),
+  ]);
+
+  const sheets = new Map<string, Timesheet>();
+  const sheetFor = (row: Record<string, unknown>): Timesheet => {
+    let sheet = sheets.get(row.user_id as string);
+    if (!sheet) {
+      sheet = {
+        user_id: row.user_id as string,
+        username: row.username as string,
+        first_name: row.first_name as string,
+        last_name: row.last_name as string,
+        role: row.role as string,
+        ...emptyFigures(),
+        entries: 0,
+        open_entries: 0,
+        missed_shifts: 0,
+        days: [],
+      };
+      sheets.set(sheet.user_id, sheet);
+    }
+    return sheet;
+  };
+
+  const blocks = new Map<string, WorkBlock>();
+  for (const row of entryRes.rows) {
+    const sheet = sheetFor(row);
+    if (row.clock_out_at === null) {
+      sheet.open_entries++;
+      continue;
+    }
+    sheet.entries++;
+    const entry = { in: new Date(row.clock_in_at), out: new Date(row.clock_out_at) };
+    const key = row.shift_id ?? `entry:${row.id}`;
+    const block = blocks.get(key);
+    if (block) {
+      block.entries.push(entry);
+    } else {
+      blocks.set(key, {
+        userId: row.user_id,
+        date: row.work_date,
+        entries: [entry],
+        shift: row.shift_id
+          ? { startsAt: new Date(row.shift_starts_at), endsAt: new Date(row.shift_ends_at), breakMinutes: Number(row.break_minutes) }
+          : null,
+      });
+    }
+  }
+
+  for (const block of blocks.values()) {
+    const sheet = sheets.get(block.userId)!;
+    const figures = blockFigures(block, settings.overtimeGraceMinutes);
+    addFigures(sheet, figures);
+    let day = sheet.days.find((d) => d.date === block.date);
+    if (!day) {
+      day = { date: block.date, entries: 0, ...emptyFigures() };
+      sheet.days.push(day);
+    }
+    day.entries += block.entries.length;
+    addFigures(day, figures);
+  }
+
+  for (const row of missedRes.rows) {
+    sheetFor(row).missed_shifts = Number(row.missed);
+  }
+
+  return [...sheets.values()]
+    .map((sheet) => ({ ...sheet, days: sheet.days.sort((a, b) => a.date.localeCompare(b.date)) }))
+    .sort((a, b) => a.last_name.localeCompare(b.last_name) || a.first_name.localeCompare(b.first_name));
+}
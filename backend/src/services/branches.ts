@@ -0,0 +1,94 @@
+import type { Context } from 'hono';
+import type { Queryable } from './pricing.js';
+
+// Branches. Every table, order and inventory row belongs to one branch.
+// Staff are either attached to a branch (and only ever see that branch) or
+// work at head office (users.branch_id NULL), where reports are consolidated
+// unless a ?branch_id= filter is given.
+
+const UUID_RE = /^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/i;
+
+export function isUUID(value: string): boolean {
+  return UUID_RE.test(value);
+}
+
+export async function getDefaultBranchId(q: Queryable): Promise<string> {
+  const res = await q.query('SELECT id FROM branches WHERE is_default = true LIMIT 1');
+  if (res.rows.length === 0) throw new Error('No default branch configured');
+  return res.rows[0].id;
+}
+
+export async function findActiveBranch(q: Queryable, branchId: string): Promise<{ id: string; name: string } | null> {
+  if (!isUUID(branchId)) return null;
+  const res = await q.query('SELECT id, name FROM branches WHERE id = $1 AND is_active = true', [branchId]);
+  return res.rows[0] ?? null;
+}
+
+export type BranchScope =
+  | { ok: true; branchId: string | null }
+  | { ok: false; failure: { message: string; code: string; status: 400 | 403 } };
+
+// ── ResolveBranchScope ──────────────────────────────────────────────────────
+// Branch for a read request: branch staff are pinned to their own branch,
+// head office gets the requested branch or null for all branches.
+
+export function resolveBranchScope(c: Context): BranchScope {
+  const own = c.get('branch_id') ?? null;
+  const requested = c.req.query('branch_id') || null;
+
+  if (own) {
+    if (requested && requested !== own) {
+      return { ok: false, failure: { message: 'You can only access your own branch', code: 'branch_forbidden', status: 403 } };
+    }
+    return { ok: true, branchId: own };
+  }
+  if (requested && !isUUID(requested)) {
+    return { ok: false, failure: { message: 'Invalid branch_id', code: 'invalid_branch_id', status: 400 } };
+  }
+  return { ok: true, branchId: requested };
+}
+
+/** ` AND <column> = $n` for a branch scope (pushing the param), '' for all branches. */
+export function branchCondition(column: string, branchId: string | null, params: unknown[]): string {
+  if (!branchId) return '';
+  params.push(branchId);
+  return ` AND ${column} = $${params.length}`;
+}
+
+// ── ResolveWriteBranch ──────────────────────────────────────────────────────
+// Branch a new record is created in. Branch staff always write to their own
+// branch; head office may name one and otherwise writes to the default.
+
+export async function resolveWriteBranch(
+  q: Queryable,
+  userBranchId: string | null,
+  requested: string | null | undefined,
+): Promise<{ ok: true; branchId: string } | { ok: false; failure: { message: string; code: string; status: 400 | 403 } }> {
+  if (userBranchId) {
+    if (requested && requested !== userBranchId) {
+      return { ok: false, failure: { message: 'You can only access your own branch', code: 'branch_forbidden', status: 403 } };
+    }
+    return { ok: true, branchId: userBranchId };
+  }
+  if (requested) {
+    const branch = await findActiveBranch(q, requested);
+    if (!branch) return { ok: false, failure: { message: 'Branch not found', code: 'branch_not_found', status: 400 } };
+    return { ok: true, branchId: branch.id };
+  }
+  return { ok: true, branchId: await getDefaultBranchId(q) };
+}
+
+// ── LoadBranchSetting ───────────────────────────────────────────────────────
+// A setting as it applies to one branch: the branch override when there is
+// one, otherwise the restaurant-wide value.
+
+export async function loadBranchSetting(q: Queryable, branchId: string | null, key: string): Promise<string | null> {
+  const res = await q.query(
+    `SELECT COALESCE(bs.setting_value, s.setting_value) AS setting_value
+     FROM system_settings s
+     LEFT JOIN branch_settings bs ON bs.setting_key = s.setting_key AND bs.branch_id = $2
+     WHERE s.setting_key = $1`,
+    [key, branchId],
+  );
+  return res.rows[0]?.setting_value ?? null;
+}
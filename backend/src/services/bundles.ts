@@ -0,0 +1,140 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+
+// Product bundles. A bundle is a product sold as a set of other products
+// (e.g. a steak set of steak, side and drink), priced on its own. It holds no
+// stock of its own: ordering one takes its components out of stock, and it is
+// only in stock while every component is. Components are single-portion
+// ('each') products and can't be bundles themselves.
+
+export interface BundleComponent {
+  product_id: string;
+  name: string;
+  /** Portions of the component in one bundle */
+  quantity: number;
+}
+
+export interface BundleComponentInput {
+  product_id: string;
+  quantity: number;
+}
+
+export interface BundleFailure {
+  message: string;
+  code: string;
+  status: 400 | 404;
+}
+
+// ── LoadBundleComponents ────────────────────────────────────────────────────
+// Components keyed by bundle; products that aren't bundles are left out.
+
+export async function loadBundleComponents(q: Queryable, productIds: string[]): Promise<Map<string, BundleComponent[]>> {
+  const byBundle = new Map<string, BundleComponent[]>();
+  if (productIds.length === 0) return byBundle;
+
+  const res = await q.query(
+    `SELECT bc.bundle_product_id, bc.component_product_id, p.name, bc.quantity
+     FROM product_bundle_components bc
+     JOIN products p ON p.id = bc.component_product_id
+     WHERE bc.bundle_product_id = ANY($1::uuid[])
+     ORDER BY p.name ASC`,
+    [productIds],
+  );
+  for (const row of res.rows) {
+    const list = byBundle.get(row.bundle_product_id) ?? [];
+    list.push({ product_id: row.component_product_id, name: row.name, quantity: Number(row.quantity) });
+    byBundle.set(row.bundle_product_id, list);
+  }
+  return byBundle;
+}
+
+// ── ExpandBundles ───────────────────────────────────────────────────────────
+// Replaces bundle lines with their components, for stock movements.
+
+export async function expandBundles<T extends { product_id: string; quantity: number }>(
+  q: Queryable,
+  items: T[],
+): Promise<T[]> {
+  const bundles = await loadBundleComponents(q, [...new Set(items.map((item) => item.product_id))]);
+  if (bundles.size === 0) return items;
+
+  return items.flatMap((item) => {
+    const components = bundles.get(item.product_id);
+    if (!components) return [item];
+    return components.map((component) => ({
+      ...item,
+      product_id: component.product_id,
+      name: component.name,
+      quantity: item.quantity * component.quantity,
+    }));
+  });
+}
+
+// ── SetBundleComponents ─────────────────────────────────────────────────────
+// Runs in the caller's transaction. Replaces the bundle's components; an
+// empty list turns it back into a plain product.
+
+export async function setBundleComponents(
+  client: PoolClient,
+  bundleId: string,
+  components: BundleComponentInput[],
+): Promise<{ ok: true } | { ok: false; failure: BundleFailure }> {
+  const bundleRes = await client.query(
+    'SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE',
+    [bundleId],
+  );
+  if (bundleRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Product not found', code: 'product_not_found', status: 404 } };
+  }
+
+  if (components.length > 0) {
+    const usedRes = await client.query(
+      'SELECT 1 FROM product_bundle_components WHERE component_product_id = $1 LIMIT 1',
+      [bundleId],
+    );
+    if (usedRes.rows.length > 0) {
+      return {
+        ok: false,
+        failure: { message: 'A bundle component cannot itself be a bundle', code: 'nested_bundle', status: 400 },
+      };
+    }
+
+    const ids = components.map((component) => component.product_id);
+    const productsRes = await client.query(
+      `SELECT p.id, p.name, p.sale_unit,
+              EXISTS (SELECT 1 FROM product_bundle_components bc WHERE bc.bundle_product_id = p.id) AS is_bundle
+       FROM products p
+       WHERE p.id = ANY($1::uuid[]) AND p.deleted_at IS NULL`,
+      [ids],
+    );
+    const byId = new Map(productsRes.rows.map((row) => [row.id, row]));
+    for (const id of ids) {
+      const product = byId.get(id);
+      if (!product) {
+        return { ok: false, failure: { message: `Product with ID '${id}' not found`, code: 'component_not_found', status: 400 } };
+      }
+      if (product.is_bundle) {
+        return {
+          ok: false,
+          failure: { message: `'${product.name}' is a bundle and cannot be a component`, code: 'nested_bundle', status: 400 },
+        };
+      }
+      if (product.sale_unit !== 'each') {
+        return {
+          ok: false,
+          failure: { message: `'${product.name}' is sold by weight and cannot be a component`, code: 'invalid_component', status: 400 },
+        };
+      }
+    }
+  }
+
+  await client.query('DELETE FROM product_bundle_components WHERE bundle_product_id = $1', [bundleId]);
+  for (const component of components) {
+    await client.query(
+      `INSERT INTO product_bundle_components (bundle_product_id, component_product_id, quantity)
+       VALUES ($1, $2, $3)`,
+      [bundleId, component.product_id, component.quantity],
+    );
+  }
+  return { ok: true };
+}
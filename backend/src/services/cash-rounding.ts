@@ -0,0 +1,33 @@
+import { loadBranchSetting } from './branches.js';
+import type { Queryable } from './pricing.js';
+
+// Cash rounding. Rupiah coins below 100 are rarely in circulation, so the
+// cash payment that settles an order is rounded to cash_rounding_increment
+// (e.g. 100 or 500; 0 disables it) in the direction of cash_rounding_mode.
+// The payment's amount stays what it settles on the order and the difference
+// to the cash handed over is kept in payments.rounding_adjustment, so order
+// balances are unaffected and the drawer reconciles with the rounding total.
+
+export const CASH_ROUNDING_MODES = ['nearest', 'down', 'up'];
+
+export interface CashRoundingPolicy {
+  increment: number;
+  mode: string;
+}
+
+export async function loadCashRounding(q: Queryable, branchId: string | null): Promise<CashRoundingPolicy> {
+  const increment = parseInt((await loadBranchSetting(q, branchId, 'cash_rounding_increment')) ?? '', 10);
+  const mode = (await loadBranchSetting(q, branchId, 'cash_rounding_mode')) ?? 'nearest';
+  return {
+    increment: isNaN(increment) || increment < 0 ? 0 : increment,
+    mode: CASH_ROUNDING_MODES.includes(mode) ? mode : 'nearest',
+  };
+}
+
+/** The cash to collect for an amount due; unchanged when rounding is off. */
+export function roundCash(amount: number, policy: CashRoundingPolicy): number {
+  if (policy.increment <= 0) return amount;
+  const units = amount / policy.increment;
+  const rounded = policy.mode === 'down' ? Math.floor(units) : policy.mode === 'up' ? Math.ceil(units) : Math.round(units);
+  return rounded * policy.increment;
+}
@@ -0,0 +1,132 @@
+import type { Queryable } from './pricing.js';
+import { RESTAURANT_TIMEZONE, addDays } from '../lib/clock.js';
+
+// Staff commissions. Like sales targets, sales are credited to the staff
+// member who took the order (orders.user_id) and counted per business day in
+// the restaurant timezone, completed orders only. Item amounts are net of the
+// order's discounts (spread pro rata over its items) and exclude tax. Every
+// rule matching an item pays out; rules don't compete with each other.
+
+export type CommissionRuleType = 'category_percentage' | 'item_bonus';
+
+export const COMMISSION_RULE_TYPES: CommissionRuleType[] = ['category_percentage', 'item_bonus'];
+
+export interface CommissionLine {
+  rule_id: string;
+  rule_name: string;
+  rule_type: CommissionRuleType;
+  percentage: number | null;
+  bonus_amount: number | null;
+  /** Net sales the percentage was taken from */
+  sales_amount: number;
+  units: number;
+  amount: number;
+}
+
+export interface StaffCommission {
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  net_sales: number;
+  orders: number;
+  commission_total: number;
+  lines: CommissionLine[];
+}
+
+/** First and last day of a YYYY-MM month. */
+export function monthBounds(month: string): { start: string; end: string } {
+  const start = `${month}-01`;
+  const [y, m] = month.split('-').map(Number);
+  const next = m === 12 ? `${y + 1}-01-01` : `${y}-${String(m + 1).padStart(2, '0')}-01`;
+  return { start, end: addDays(next, -1) };
+}
+
+const round2 = (n: number) => Math.round(n * 100) / 100;
+
+// ── CalculateCommissions ────────────────────────────────────────────────────
+// Commission per staff member for orders placed between from and to
+// (inclusive). Each item is judged against the rules in force on its order's
+// business day. Staff who sold but earned nothing are included with a zero
+// total so the payroll export lists everyone who worked the period. A branch
+// limits it to orders taken at that branch.
+
+export async function calculateCommissions(
+  q: Queryable,
+  from: string,
+  to: string,
+  branchId: string | null = null,
+): Promise<StaffCommission[]> {
+  const salesRes = await q.query(
+    `SELECT u.id AS user_id, u.username, u.first_name, u.last_name, u.role,
+            SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount) AS net_sales, COUNT(o.id) AS orders
+     FROM orders o
+     JOIN users u ON u.id = o.user_id
+     WHERE o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+       AND ($4::uuid IS NULL OR o.branch_id = $4)
+     GROUP BY u.id
+     ORDER BY u.first_name ASC, u.last_name ASC`,
+    [from, to, RESTAURANT_TIMEZONE, branchId],
+  );
+
+  const linesRes = await q.query(
+    `WITH items AS (
+       SELECT o.user_id, DATE(o.created_at AT TIME ZONE $3) AS day,
+              oi.product_id, p.category_id, oi.quantity,
+              oi.total_price * CASE WHEN o.subtotal > 0 THEN (o.subtotal - o.discount_amount) / o.subtotal ELSE 1 END AS net_amount
+       FROM orders o
+       JOIN order_items oi ON oi.order_id = o.id
+       LEFT JOIN products p ON p.id = oi.product_id
+       WHERE o.status = 'completed' AND o.user_id IS NOT NULL
+         AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         AND ($4::uuid IS NULL OR o.branch_id = $4)
+     )
+     SELECT i.user_id, r.id AS rule_id, r.name AS rule_name, r.rule_type, r.percentage, r.bonus_amount,
+            SUM(i.net_amount) AS sales_amount, SUM(i.quantity) AS units,
+            SUM(CASE WHEN r.rule_type = 'category_percentage'
+                     THEN i.net_amount * r.percentage / 100
+                     ELSE i.quantity * r.bonus_amount END) AS amount
+     FROM items i
+     JOIN users u ON u.id = i.user_id
+     JOIN commission_rules r
+       ON r.effective_from <= i.day AND (r.effective_until IS NULL OR r.effective_until >= i.day)
+      AND (r.role IS NULL OR r.role = u.role)
+      AND ((r.rule_type = 'category_percentage' AND r.category_id = i.category_id)
+        OR (r.rule_type = 'item_bonus' AND r.product_id = i.product_id))
+     GROUP BY i.user_id, r.id, r.name, r.rule_type, r.percentage, r.bonus_amount
+     ORDER BY r.name ASC`,
+    [from, to, RESTAURANT_TIMEZONE, branchId],
+  );
+
+  const lines = new Map<string, CommissionLine[]>();
+  for (const row of linesRes.rows) {
+    const list = lines.get(row.user_id) ?? [];
+    list.push({
+      rule_id: row.rule_id,
+      rule_name: row.rule_name,
+      rule_type: row.rule_type,
+      percentage: row.percentage !== null ? Number(row.percentage) : null,
+      bonus_amount: row.bonus_amount !== null ? Number(row.bonus_amount) : null,
+      sales_amount: round2(Number(row.sales_amount)),
+      units: Number(row.units),
+      amount: round2(Number(row.amount)),
+    });
+    lines.set(row.user_id, list);
+  }
+
+  return salesRes.rows.map((row) => {
+    const staffLines = lines.get(row.user_id) ?? [];
+    return {
+      user_id: row.user_id,
+      username: row.username,
+      first_name: row.first_name,
+      last_name: row.last_name,
+      role: row.role,
+      net_sales: round2(Number(row.net_sales)),
+      orders: Number(row.orders),
+      commission_total: round2(staffLines.reduce((sum, l) => sum + l.amount, 0)),
+      lines: staffLines,
+    };
+  });
+}
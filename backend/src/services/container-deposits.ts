@@ -0,0 +1,117 @@
+import { branchCondition } from './branches.js';
+import type { Queryable } from './pricing.js';
+
+// Reusable container deposits. A takeaway order can carry containers; their
+// deposit is added to the order total (so it is paid with the order) and
+// kept in orders.deposit_amount, apart from sales. Bringing containers back
+// refunds the deposit paid for them, at whichever branch takes them back.
+// Until then the deposit is owed to the customer: deposits on paid and
+// completed orders that haven't been returned are the outstanding liability.
+
+export const CONTAINER_REFUND_METHODS = ['cash', 'digital_wallet'];
+
+// Orders whose deposit has been collected
+export const DEPOSIT_HELD_STATUSES = ['paid', 'completed'];
+
+export interface ContainerDepositLine {
+  container_type_id: string;
+  name: string;
+  quantity: number;
+  unit_deposit: number;
+}
+
+type DepositResult =
+  | { ok: true; lines: ContainerDepositLine[]; total: number }
+  | { ok: false; failure: { message: string; code: string; status: 400 } };
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+// ── ResolveContainerDeposits ────────────────────────────────────────────────
+// Validates the containers requested on a new order against the active
+// container types. The same type listed twice is merged.
+
+export async function resolveContainerDeposits(
+  q: Queryable,
+  requested: { container_type_id?: string; quantity?: number }[] | undefined,
+): Promise<DepositResult> {
+  if (!requested || requested.length === 0) return { ok: true, lines: [], total: 0 };
+  if (!Array.isArray(requested)) {
+    return { ok: false, failure: { message: 'containers must be a list', code: 'invalid_containers', status: 400 } };
+  }
+
+  const quantities = new Map<string, number>();
+  for (const entry of requested) {
+    if (!entry.container_type_id || !Number.isInteger(entry.quantity) || entry.quantity! < 1) {
+      return {
+        ok: false,
+        failure: { message: 'Each container needs a container_type_id and a positive whole quantity', code: 'invalid_containers', status: 400 },
+      };
+    }
+    quantities.set(entry.container_type_id, (quantities.get(entry.container_type_id) ?? 0) + entry.quantity!);
+  }
+
+  const res = await q.query(
+    `SELECT id, name, deposit_amount FROM container_types
+     WHERE id::text = ANY($1::text[]) AND is_active = true`,
+    [[...quantities.keys()]],
+  );
+  if (res.rows.length !== quantities.size) {
+    return { ok: false, failure: { message: 'Unknown or inactive container type', code: 'container_type_not_found', status: 400 } };
+  }
+
+  const lines = res.rows.map((row) => ({
+    container_type_id: row.id,
+    name: row.name,
+    quantity: quantities.get(row.id)!,
+    unit_deposit: Number(row.deposit_amount),
+  }));
+  return { ok: true, lines, total: round(lines.reduce((sum, l) => sum + l.unit_deposit * l.quantity, 0)) };
+}
+
+export async function recordContainerDeposits(q: Queryable, orderId: string, lines: ContainerDepositLine[]): Promise<void> {
+  for (const line of lines) {
+    await q.query(
+      `INSERT INTO order_container_deposits (order_id, container_type_id, quantity, unit_deposit)
+       VALUES ($1, $2, $3, $4)`,
+      [orderId, line.container_type_id, line.quantity, line.unit_deposit],
+    );
+  }
+}
+
+export async function loadOrderContainerDeposits(q: Queryable, orderId: string) {
+  const res = await q.query(
+    `SELECT d.id, d.container_type_id, t.name, d.quantity, d.unit_deposit::float8 AS unit_deposit,
+            d.returned_quantity, (d.quantity - d.returned_quantity) AS outstanding_quantity
+     FROM order_container_deposits d
+     JOIN container_types t ON t.id = d.container_type_id
+     WHERE d.order_id = $1
+     ORDER BY t.name ASC`,
+    [orderId],
+  );
+  return res.rows;
+}
+
+// ── DepositLiability ────────────────────────────────────────────────────────
+// Deposits still owed as of now, per container type, for orders of the
+// branch (or all branches).
+
+export async function depositLiability(q: Queryable, branchId: string | null) {
+  const params: unknown[] = [DEPOSIT_HELD_STATUSES];
+  const res = await q.query(
+    `SELECT t.id AS container_type_id, t.name,
+            SUM(d.quantity - d.returned_quantity)::int AS outstanding_quantity,
+            SUM((d.quantity - d.returned_quantity) * d.unit_deposit)::float8 AS outstanding_amount
+     FROM order_container_deposits d
+     JOIN container_types t ON t.id = d.container_type_id
+     JOIN orders o ON o.id = d.order_id
+     WHERE o.status = ANY($1::text[]) AND d.returned_quantity < d.quantity${branchCondition('o.branch_id', branchId, params)}
+     GROUP BY t.id, t.name
+     ORDER BY t.name ASC`,
+    params,
+  );
+  return {
+    by_container: res.rows,
+    outstanding_quantity: res.rows.reduce((sum, r) => sum + Number(r.outstanding_quantity), 0),
+    outstanding_amount: round(res.rows.reduce((sum, r) => sum + Number(r.outstanding_amount), 0)),
+  };
+}
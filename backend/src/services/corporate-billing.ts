@@ -0,0 +1,109 @@
+import type { Pool, PoolClient } from 'pg';
+import type { WalletFailure } from './corporate-wallet.js';
+
+export interface CreditPosition {
+  credit_limit: number;
+  uninvoiced: number;
+  open_invoices: number;
+  outstanding: number;
+  available_credit: number;
+}
+
+// ── GetCreditPosition ───────────────────────────────────────────────────────
+// Outstanding = charges not yet invoiced + unpaid part of issued invoices.
+
+export async function getCreditPosition(q: Pool | PoolClient, accountId: string, creditLimit: number): Promise<CreditPosition> {
+  const res = await q.query(
+    `SELECT
+       (SELECT COALESCE(SUM(amount), 0) FROM corporate_account_charges
+        WHERE account_id = $1 AND invoice_id IS NULL) AS uninvoiced,
+       (SELECT COALESCE(SUM(total_amount - amount_paid), 0) FROM corporate_invoices
+        WHERE account_id = $1 AND status IN ('issued', 'partially_paid')) AS open_invoices`,
+    [accountId],
+  );
+
+  const uninvoiced = Number(res.rows[0].uninvoiced);
+  const openInvoices = Number(res.rows[0].open_invoices);
+  const outstanding = uninvoiced + openInvoices;
+
+  return {
+    credit_limit: creditLimit,
+    uninvoiced,
+    open_invoices: openInvoices,
+    outstanding,
+    available_credit: Math.max(0, creditLimit - outstanding),
+  };
+}
+
+// ── ChargeOnAccount ─────────────────────────────────────────────────────────
+// Runs inside the caller's payment transaction; the account row is locked so
+// two concurrent charges can't both squeeze under the credit limit.
+
+export async function chargeOnAccount(
+  client: PoolClient,
+  params: { accountId: string; amount: number; orderId: string; paymentId: string; userId: string | null },
+): Promise<{ ok: true; company_name: string; credit: CreditPosition } | { ok: false; failure: WalletFailure }> {
+  const accRes = await client.query(
+    `SELECT id, company_name, is_active, on_account_enabled, credit_limit
+     FROM corporate_accounts WHERE id = $1 FOR UPDATE`,
+    [params.accountId],
+  );
+
+  if (accRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Corporate account not found', code: 'corporate_account_not_found', status: 404 } };
+  }
+
+  const account = accRes.rows[0];
+  if (!account.is_active || !account.on_account_enabled) {
+    return { ok: false, failure: { message: 'Corporate account is not approved for on-account billing', code: 'on_account_not_enabled', status: 400 } };
+  }
+
+  const credit = await getCreditPosition(client, account.id, Number(account.credit_limit));
+  if (params.amount > credit.available_credit) {
+    return { ok: false, failure: { message: 'Charge exceeds available credit limit', code: 'credit_limit_exceeded', status: 400 } };
+  }
+
+  await client.query(
+    `INSERT INTO corporate_account_charges (account_id, order_id, payment_id, amount, charged_by)
+     VALUES ($1, $2, $3, $4, $5)`,
+    [account.id, params.orderId, params.paymentId, params.amount, params.userId],
+  );
+
+  return {
+    ok: true,
+    company_name: account.company_name,
+    credit: {
+      ...credit,
+      uninvoiced: credit.uninvoiced + params.amount,
+      outstanding: credit.outstanding + params.amount,
+      available_credit: credit.available_credit - params.amount,
+    },
+  };
+}
+
+// ── RefundOnAccount ─────────────────────────────────────────────────────────
+// Records a negative charge so the refund is netted off the company's next
+// invoice (or its uninvoiced balance) instead of editing billed charges.
+
+export async function refundOnAccount(
+  client: PoolClient,
+  params: { paymentId: string; refundPaymentId: string; amount: number; userId: string | null },
+): Promise<{ ok: true; account_id: string } | { ok: false; failure: WalletFailure }> {
+  const chargeRes = await client.query(
+    'SELECT account_id, order_id FROM corporate_account_charges WHERE payment_id = $1 AND amount > 0',
+    [params.paymentId],
+  );
+
+  if (chargeRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'On-account charge for this payment not found', code: 'account_charge_not_found', status: 404 } };
+  }
+
+  const charge = chargeRes.rows[0];
+  await client.query(
+    `INSERT INTO corporate_account_charges (account_id, order_id, payment_id, amount, charged_by)
+     VALUES ($1, $2, $3, $4, $5)`,
+    [charge.account_id, charge.order_id, params.refundPaymentId, -params.amount, params.userId],
+  );
+
+  return { ok: true, account_id: charge.account_id };
+}
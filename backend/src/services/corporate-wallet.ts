@@ -0,0 +1,190 @@
+import type { PoolClient } from 'pg';
+import { withTransaction } from '../db/transaction.js';
+
+export const TOPUP_GATEWAY_PREFIX = 'TOPUP';
+
+export interface WalletFailure {
+  message: string;
+  code: string;
+  status: 400 | 404 | 409;
+}
+
+export interface WalletRedemption {
+  account_id: string;
+  company_name: string;
+  employee_id: string;
+  employee_name: string;
+  balance_after: number;
+}
+
+// ── RedeemFromWallet ────────────────────────────────────────────────────────
+// Runs inside the caller's payment transaction. Locks the account row so
+// concurrent redemptions can't overdraw the balance.
+
+export async function redeemFromWallet(
+  client: PoolClient,
+  params: { employeeCode: string; amount: number; orderId: string; paymentId: string; userId: string | null },
+): Promise<{ ok: true; redemption: WalletRedemption } | { ok: false; failure: WalletFailure }> {
+  const empRes = await client.query(
+    `SELECT e.id, e.employee_name, e.daily_limit, e.is_active, e.account_id,
+            a.company_name, a.balance, a.is_active AS account_active
+     FROM corporate_employees e
+     JOIN corporate_accounts a ON a.id = e.account_id
+     WHERE UPPER(e.employee_code) = UPPER($1)
+     FOR UPDATE OF a`,
+    [params.employeeCode.trim()],
+  );
+
+  if (empRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Employee code not found', code: 'employee_code_not_found', status: 404 } };
+  }
+
+  const emp = empRes.rows[0];
+  if (!emp.is_active || !emp.account_active) {
+    return { ok: false, failure: { message: 'Corporate account or employee code is inactive', code: 'corporate_account_inactive', status: 400 } };
+  }
+
+  const balance = Number(emp.balance);
+  if (balance < params.amount) {
+    return { ok: false, failure: { message: 'Insufficient corporate wallet balance', code: 'insufficient_balance', status: 400 } };
+  }
+
+  if (emp.daily_limit !== null) {
+    const spentRes = await client.query(
+      `SELECT COALESCE(SUM(-amount), 0) AS spent
+       FROM corporate_wallet_transactions
+       WHERE employee_id = $1 AND transaction_type = 'redeem' AND status = 'completed'
+         AND created_at >= date_trunc('day', NOW() AT TIME ZONE 'Asia/Jakarta') AT TIME ZONE 'Asia/Jakarta'`,
+      [emp.id],
+    );
+    const spentToday = Number(spentRes.rows[0].spent);
+    if (spentToday + params.amount > Number(emp.daily_limit)) {
+      return { ok: false, failure: { message: 'Employee daily limit exceeded', code: 'daily_limit_exceeded', status: 400 } };
+    }
+  }
+
+  const newBalance = balance - params.amount;
+  await client.query(
+    'UPDATE corporate_accounts SET balance = $1 WHERE id = $2',
+    [newBalance, emp.account_id],
+  );
+
+  await client.query(
+    `INSERT INTO corporate_wallet_transactions
+       (account_id, employee_id, transaction_type, amount, balance_after, status, order_id, payment_id, created_by, completed_at)
+     VALUES ($1, $2, 'redeem', $3, $4, 'completed', $5, $6, $7, NOW())`,
+    [emp.account_id, emp.id, -params.amount, newBalance, params.orderId, params.paymentId, params.userId],
+  );
+
+  return {
+    ok: true,
+    redemption: {
+      account_id: emp.account_id,
+      company_name: emp.company_name,
+      employee_id: emp.id,
+      employee_name: emp.employee_name,
+      balance_after: newBalance,
+    },
+  };
+}
+
+// ── CreditWallet ────────────────────────────────────────────────────────────
+// Adds funds and records the ledger entry. Used for manual top-ups and
+// adjustments made by admins.
+
+export async function creditWallet(
+  client: PoolClient,
+  params: { accountId: string; amount: number; type: 'topup' | 'adjustment'; notes?: string; userId: string | null },
+): Promise<number | null> {
+  const accRes = await client.query(
+    'SELECT balance FROM corporate_accounts WHERE id = $1 FOR UPDATE',
+    [params.accountId],
+  );
+  if (accRes.rows.length === 0) return null;
+
+  const newBalance = Number(accRes.rows[0].balance) + params.amount;
+  if (newBalance < 0) return null;
+
+  await client.query('UPDATE corporate_accounts SET balance = $1 WHERE id = $2', [newBalance, params.accountId]);
+  await client.query(
+    `INSERT INTO corporate_wallet_transactions
+       (account_id, transaction_type, amount, balance_after, status, notes, created_by, completed_at)
+     VALUES ($1, $2, $3, $4, 'completed', $5, $6, NOW())`,
+    [params.accountId, params.type, params.amount, newBalance, params.notes || null, params.userId],
+  );
+
+  return newBalance;
+}
+
+// ── RefundToWallet ──────────────────────────────────────────────────────────
+// Returns a refunded wallet payment to the company balance. The ledger entry
+// points at the refund payment; the employee's daily limit is not restored.
+
+export async function refundToWallet(
+  client: PoolClient,
+  params: { paymentId: string; refundPaymentId: string; amount: number; userId: string | null },
+): Promise<{ ok: true; balance_after: number } | { ok: false; failure: WalletFailure }> {
+  const txRes = await client.query(
+    `SELECT t.account_id, t.employee_id, t.order_id, a.balance
+     FROM corporate_wallet_transactions t
+     JOIN corporate_accounts a ON a.id = t.account_id
+     WHERE t.payment_id = $1 AND t.transaction_type = 'redeem' AND t.status = 'completed'
+     FOR UPDATE OF a`,
+    [params.paymentId],
+  );
+
+  if (txRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Wallet redemption for this payment not found', code: 'wallet_redemption_not_found', status: 404 } };
+  }
+
+  const tx = txRes.rows[0];
+  const newBalance = Number(tx.balance) + params.amount;
+  await client.query('UPDATE corporate_accounts SET balance = $1 WHERE id = $2', [newBalance, tx.account_id]);
+  await client.query(
+    `INSERT INTO corporate_wallet_transactions
+       (account_id, employee_id, transaction_type, amount, balance_after, status, order_id, payment_id, created_by, completed_at)
+     VALUES ($1, $2, 'refund', $3, $4, 'completed', $5, $6, $7, NOW())`,
+    [tx.account_id, tx.employee_id, params.amount, newBalance, tx.order_id, params.refundPaymentId, params.userId],
+  );
+
+  return { ok: true, balance_after: newBalance };
+}
+
+// ── SettleGatewayTopup ──────────────────────────────────────────────────────
+// Applies a payment gateway notification to a pending top-up. Idempotent:
+// repeated notifications for an already settled top-up are ignored.
+
+export async function settleGatewayTopup(transactionId: string, outcome: 'paid' | 'pending' | 'failed'): Promise<void> {
+  if (outcome === 'pending') return;
+
+  await withTransaction(async (client) => {
+    const txRes = await client.query(
+      `SELECT id, account_id, amount, status FROM corporate_wallet_transactions
+       WHERE id = $1 AND transaction_type = 'topup'
+       FOR UPDATE`,
+      [transactionId],
+    );
+
+    if (txRes.rows.length === 0 || txRes.rows[0].status !== 'pending') return;
+
+    const tx = txRes.rows[0];
+
+    if (outcome === 'failed') {
+      await client.query(
+        "UPDATE corporate_wallet_transactions SET status = 'failed', completed_at = NOW() WHERE id = $1",
+        [tx.id],
+      );
+    } else {
+      const accRes = await client.query(
+        'UPDATE corporate_accounts SET balance = balance + $1 WHERE id = $2 RETURNING balance',
+        [tx.amount, tx.account_id],
+      );
+      await client.query(
+        `UPDATE corporate_wallet_transactions
+         SET status = 'completed', balance_after = $1, completed_at = NOW()
+         WHERE id = $2`,
+        [accRes.rows[0].balance, tx.id],
+      );
+    }
+  });
+}
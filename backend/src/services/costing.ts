@@ -0,0 +1,136 @@
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition } from './branches.js';
+import type { Queryable } from './pricing.js';
+import { ITEM_NET } from './tax.js';
+
+// Cost of goods sold. A product's unit cost is its cost override when set —
+// meant for bought-in items such as bottled drinks that have no recipe —
+// otherwise the cost of its recipe at current ingredient prices. The report
+// is theoretical: portions sold are costed at today's unit costs. Manual
+// adjustments (waste, stock count differences, supplier credits) then bring
+// a period's total in line with what was actually used.
+
+export type CostSource = 'override' | 'recipe';
+
+// Per product with a recipe: the cost of one portion
+export const RECIPE_COSTS = `
+  SELECT pi.product_id, SUM(pi.quantity_required * i.unit_cost) AS recipe_cost, COUNT(*) AS ingredients
+  FROM product_ingredients pi
+  JOIN ingredients i ON i.id = pi.ingredient_id
+  GROUP BY pi.product_id`;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+export function unitCost(row: { cost_override: unknown; recipe_cost: unknown }): {
+  unit_cost: number | null;
+  cost_source: CostSource | null;
+} {
+  if (row.cost_override !== null && row.cost_override !== undefined) {
+    return { unit_cost: Number(row.cost_override), cost_source: 'override' };
+  }
+  if (row.recipe_cost !== null && row.recipe_cost !== undefined) {
+    return { unit_cost: round(Number(row.recipe_cost)), cost_source: 'recipe' };
+  }
+  return { unit_cost: null, cost_source: null };
+}
+
+function marginPct(sales: number, cost: number): number | null {
+  return sales > 0 ? Math.round(((sales - cost) / sales) * 1000) / 10 : null;
+}
+
+// ── BuildCogsReport ─────────────────────────────────────────────────────────
+// Completed orders created over [from, to], per product: portions sold, net
+// sales (after discounts, before tax), theoretical COGS and margin. A
+// product's adjustments are shown on its row; the rest only in the totals.
+// Sales of products with no cost are listed but leave COGS understated, so
+// they are totalled as uncosted_sales.
+
+export async function buildCogsReport(q: Queryable, from: string, to: string, branchId: string | null) {
+  const salesParams: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const salesRes = await q.query(
+    `WITH sold AS (
+       SELECT oi.product_id, SUM(oi.quantity) AS quantity, SUM(${ITEM_NET}) AS net_sales
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       WHERE o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+             ${branchCondition('o.branch_id', branchId, salesParams)}
+       GROUP BY oi.product_id
+     )
+     SELECT p.id AS product_id, p.name AS product_name, cat.name AS category_name,
+            s.quantity, s.net_sales, p.cost_override, r.recipe_cost
+     FROM sold s
+     JOIN products p ON p.id = s.product_id
+     LEFT JOIN categories cat ON cat.id = p.category_id
+     LEFT JOIN (${RECIPE_COSTS}) r ON r.product_id = p.id
+     ORDER BY s.net_sales DESC`,
+    salesParams,
+  );
+
+  const adjParams: unknown[] = [from, to];
+  const adjRes = await q.query(
+    `SELECT a.id, a.branch_id, a.product_id, p.name AS product_name, to_char(a.adjustment_date, 'YYYY-MM-DD') AS adjustment_date,
+            a.amount::float8 AS amount, a.reason, a.created_at
+     FROM cogs_adjustments a
+     LEFT JOIN products p ON p.id = a.product_id
+     WHERE a.adjustment_date BETWEEN $1 AND $2${branchCondition('a.branch_id', branchId, adjParams)}
+     ORDER BY a.adjustment_date ASC, a.created_at ASC`,
+    adjParams,
+  );
+
+  const adjustmentsByProduct = new Map<string, number>();
+  for (const adj of adjRes.rows) {
+    if (!adj.product_id) continue;
+    adjustmentsByProduct.set(adj.product_id, (adjustmentsByProduct.get(adj.product_id) ?? 0) + Number(adj.amount));
+  }
+
+  let netSales = 0;
+  let theoreticalCogs = 0;
+  let uncostedSales = 0;
+  const products = salesRes.rows.map((row) => {
+    const quantity = Number(row.quantity);
+    const sales = round(Number(row.net_sales));
+    const cost = unitCost(row);
+    const cogs = cost.unit_cost !== null ? round(cost.unit_cost * quantity) : null;
+    const adjustments = round(adjustmentsByProduct.get(row.product_id) ?? 0);
+    const totalCogs = round((cogs ?? 0) + adjustments);
+
+    netSales += sales;
+    if (cogs === null) uncostedSales += sales;
+    else theoreticalCogs += cogs;
+
+    return {
+      product_id: row.product_id,
+      product_name: row.product_name,
+      category_name: row.category_name ?? 'Uncategorised',
+      quantity,
+      net_sales: sales,
+      unit_cost: cost.unit_cost,
+      cost_source: cost.cost_source,
+      theoretical_cogs: cogs,
+      adjustments,
+      total_cogs: totalCogs,
+      gross_margin: cogs !== null ? round(sales - totalCogs) : null,
+      margin_pct: cogs !== null ? marginPct(sales, totalCogs) : null,
+    };
+  });
+
+  const adjustmentsTotal = round(adjRes.rows.reduce((sum, a) => sum + Number(a.amount), 0));
+  const totalCogs = round(theoreticalCogs + adjustmentsTotal);
+  return {
+    from,
+    to,
+    branch_id: branchId,
+    summary: {
+      net_sales: round(netSales),
+      theoretical_cogs: round(theoreticalCogs),
+      adjustments: adjustmentsTotal,
+      total_cogs: totalCogs,
+      gross_margin: round(netSales - totalCogs),
+      margin_pct: marginPct(netSales, totalCogs),
+      uncosted_products: products.filter((p) => p.unit_cost === null).length,
+      uncosted_sales: round(uncostedSales),
+    },
+    products,
+    adjustments: adjRes.rows,
+  };
+}
@@ -0,0 +1,241 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+
+// Course firing for dine-in orders. Items carry a course number (1 by
+// default); an order with items in more than one course, or one placed on
+// hold, gets an order_courses row per course. A course whose row has no
+// fired_at is held: its items keep released_at NULL, so no station sees
+// them, until a server fires it. Without hold the first course is fired as
+// the order is placed.
+//
+// Items added later join their course: held if it is held, straight to the
+// kitchen if it has been fired, and a course the order didn't have yet
+// starts out held. Accepting a held customer order doesn't release held
+// courses (see releaseHeldItems).
+
+export const MAX_COURSE = 9;
+
+export function isCourse(value: unknown): value is number {
+  return Number.isInteger(value) && (value as number) >= 1 && (value as number) <= MAX_COURSE;
+}
+
+export interface CourseFailure {
+  message: string;
+  code: string;
+  status: 404 | 409;
+}
+
+async function holdUnfiredItems(client: PoolClient, orderId: string): Promise<number> {
+  const res = await client.query(
+    `UPDATE order_items oi SET released_at = NULL
+     WHERE oi.order_id = $1 AND oi.released_at IS NOT NULL
+       AND EXISTS (
+         SELECT 1 FROM order_courses oc
+         WHERE oc.order_id = oi.order_id AND oc.course = oi.course AND oc.fired_at IS NULL
+       )`,
+    [orderId],
+  );
+  return res.rowCount ?? 0;
+}
+
+// ── HoldOrderCourses ────────────────────────────────────────────────────────
+// Called on the order's transaction after its items are inserted. Returns how
+// many items are held (0 for an order with a single course and no hold).
+
+export async function holdOrderCourses(
+  client: PoolClient,
+  input: { orderId: string; holdAll: boolean; userId: string | null },
+): Promise<number> {
+  const res = await client.query(
+    `INSERT INTO order_courses (order_id, course, fired_at, fired_by)
+     SELECT $1, c.course,
+            CASE WHEN NOT $2 AND c.course = MIN(c.course) OVER () THEN NOW() END,
+            CASE WHEN NOT $2 AND c.course = MIN(c.course) OVER () THEN $3::uuid END
+     FROM (SELECT DISTINCT course FROM order_items WHERE order_id = $1) c
+     WHERE $2 OR (SELECT COUNT(DISTINCT course) FROM order_items WHERE order_id = $1) > 1
+     ON CONFLICT (order_id, course) DO NOTHING`,
+    [input.orderId, input.holdAll, input.userId],
+  );
+  if ((res.rowCount ?? 0) === 0) return 0;
+  return holdUnfiredItems(client, input.orderId);
+}
+
+// ── HoldAddedItems ──────────────────────────────────────────────────────────
+// For items added to an open order. An order that wasn't coursed yet becomes
+// coursed once an item lands in another course; the courses it already had
+// count as fired.
+
+export async function holdAddedItems(client: PoolClient, orderId: string, itemIds: string[]): Promise<number> {
+  if (itemIds.length === 0) return 0;
+
+  const coursed = await client.query('SELECT 1 FROM order_courses WHERE order_id = $1 LIMIT 1', [orderId]);
+  if (coursed.rows.length === 0) {
+    const courses = await client.query('SELECT COUNT(DISTINCT course) AS count FROM order_items WHERE order_id = $1', [orderId]);
+    if (Number(courses.rows[0].count) < 2) return 0;
+
+    await client.query(
+      `INSERT INTO order_courses (order_id, course, fired_at)
+       SELECT $1, course, COALESCE(MIN(released_at), NOW())
+       FROM order_items
+       WHERE order_id = $1 AND id <> ALL($2::uuid[])
+       GROUP BY course
+       ON CONFLICT (order_id, course) DO NOTHING`,
+      [orderId, itemIds],
+    );
+  }
+
+  await client.query(
+    `INSERT INTO order_courses (order_id, course)
+     SELECT DISTINCT $1::uuid, course FROM order_items WHERE id = ANY($2::uuid[])
+     ON CONFLICT (order_id, course) DO NOTHING`,
+    [orderId, itemIds],
+  );
+  return holdUnfiredItems(client, orderId);
+}
+
+// ── FireCourse ──────────────────────────────────────────────────────────────
+// Runs in the caller's transaction. Sends a held course's items to their
+// stations; without a course number the lowest held course is fired. A
+// ticket the kitchen had finished goes back on the board.
+
+export async function fireCourse(
+  client: PoolClient,
+  input: { orderId: string; course: number | null; userId: string | null },
+): Promise<{ ok: true; course: number; firedAt: string; releasedItems: number; heldCourses: number[] } | { ok: false; failure: CourseFailure }> {
+  const orderRes = await client.query('SELECT status FROM orders WHERE id = $1 FOR UPDATE', [input.orderId]);
+  if (orderRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Order not found', code: 'order_not_found', status: 404 } };
+  }
+  const status = orderRes.rows[0].status;
+  if (['scheduled', 'parked', 'completed', 'cancelled'].includes(status)) {
+    return {
+      ok: false,
+      failure: { message: `Courses cannot be fired - order is ${status}`, code: 'invalid_order_status', status: 409 },
+    };
+  }
+
+  const coursesRes = await client.query(
+    'SELECT course, fired_at FROM order_courses WHERE order_id = $1 ORDER BY course',
+    [input.orderId],
+  );
+  const held = coursesRes.rows.filter((row) => row.fired_at === null).map((row) => Number(row.course));
+  let course = input.course;
+  if (course === null) {
+    if (held.length === 0) {
+      return { ok: false, failure: { message: 'This order has no held courses', code: 'no_held_courses', status: 409 } };
+    }
+    course = held[0];
+  } else if (!coursesRes.rows.some((row) => Number(row.course) === course)) {
+    return { ok: false, failure: { message: `Order has no course ${course}`, code: 'course_not_found', status: 404 } };
+  } else if (!held.includes(course)) {
+    return { ok: false, failure: { message: `Course ${course} has already been fired`, code: 'course_already_fired', status: 409 } };
+  }
+
+  const firedRes = await client.query(
+    `UPDATE order_courses SET fired_at = NOW(), fired_by = $3
+     WHERE order_id = $1 AND course = $2
+     RETURNING fired_at`,
+    [input.orderId, course, input.userId],
+  );
+  const released = await client.query(
+    `UPDATE order_items SET released_at = NOW(), updated_at = NOW()
+     WHERE order_id = $1 AND course = $2 AND released_at IS NULL`,
+    [input.orderId, course],
+  );
+
+  if (status === 'ready' || status === 'served') {
+    await client.query(
+      "UPDATE orders SET status = 'preparing', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+      [input.orderId],
+    );
+    await client.query(
+      `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+       VALUES ($1, $2, 'preparing', $3, $4)`,
+      [input.orderId, status, input.userId, `Course ${course} fired`],
+    );
+  }
+
+  return {
+    ok: true,
+    course,
+    firedAt: firedRes.rows[0].fired_at,
+    releasedItems: released.rowCount ?? 0,
+    heldCourses: held.filter((c) => c !== course),
+  };
+}
+
+// ── LoadOrderCourses ────────────────────────────────────────────────────────
+// Each course of a coursed order with its timings: when it was fired, when
+// its last item was ready and served, and how long the table waited between
+// the previous course being served and this one being fired. Empty for an
+// order that isn't coursed.
+
+export async function loadOrderCourses(q: Queryable, orderId: string) {
+  const res = await q.query(
+    `SELECT oc.course, oc.fired_at, oc.fired_by, u.username AS fired_by_username,
+            COUNT(oi.id) AS item_count,
+            COUNT(oi.id) FILTER (WHERE oi.status IN ('ready', 'served')) AS ready_count,
+            COUNT(oi.id) FILTER (WHERE oi.status = 'served') AS served_count,
+            MAX(h.ready_at) AS ready_at, MAX(h.served_at) AS served_at
+     FROM order_courses oc
+     LEFT JOIN users u ON u.id = oc.fired_by
+     LEFT JOIN order_items oi ON oi.order_id = oc.order_id AND oi.course = oc.course
+     LEFT JOIN LATERAL (
+       SELECT MIN(sh.created_at) FILTER (WHERE sh.new_status IN ('ready', 'served')) AS ready_at,
+              MIN(sh.created_at) FILTER (WHERE sh.new_status = 'served') AS served_at
+       FROM order_item_status_history sh
+       WHERE sh.order_item_id = oi.id
+     ) h ON true
+     WHERE oc.order_id = $1
+     GROUP BY oc.course, oc.fired_at, oc.fired_by, u.username
+     ORDER BY oc.course`,
+    [orderId],
+  );
+
+  const seconds = (from: string | null, to: string | null) =>
+    from && to ? Math.round((new Date(to).getTime() - new Date(from).getTime()) / 1000) : null;
+
+  let previousServedAt: string | null = null;
+  return res.rows.map((row) => {
+    const items = Number(row.item_count);
+    const readyAt = items > 0 && Number(row.ready_count) === items ? row.ready_at : null;
+    const servedAt = items > 0 && Number(row.served_count) === items ? row.served_at : null;
+    const status = !row.fired_at ? 'held' : servedAt ? 'served' : readyAt ? 'ready' : 'fired';
+    const course = {
+      course: Number(row.course),
+      status,
+      item_count: items,
+      fired_at: row.fired_at,
+      fired_by: row.fired_by,
+      fired_by_username: row.fired_by_username ?? null,
+      ready_at: readyAt,
+      served_at: servedAt,
+      fire_to_ready_seconds: seconds(row.fired_at, readyAt),
+      fire_to_served_seconds: seconds(row.fired_at, servedAt),
+      // The table's wait between courses
+      gap_after_previous_seconds: seconds(previousServedAt, row.fired_at),
+    };
+    previousServedAt = servedAt;
+    return course;
+  });
+}
+
+// ── CompletedCourse ─────────────────────────────────────────────────────────
+// After an item is marked ready: its course and the seconds since the course
+// was fired, if that item was the course's last one still in preparation.
+
+export async function completedCourse(q: Queryable, itemId: string): Promise<{ course: number; seconds: number } | null> {
+  const res = await q.query(
+    `SELECT oi.course, EXTRACT(EPOCH FROM NOW() - oc.fired_at)::float8 AS seconds
+     FROM order_items oi
+     JOIN order_courses oc ON oc.order_id = oi.order_id AND oc.course = oi.course
+     WHERE oi.id = $1 AND oc.fired_at IS NOT NULL
+       AND NOT EXISTS (
+         SELECT 1 FROM order_items o2
+         WHERE o2.order_id = oi.order_id AND o2.course = oi.course AND o2.status NOT IN ('ready', 'served')
+       )`,
+    [itemId],
+  );
+  const row = res.rows[0];
+  return row ? { course: Number(row.course), seconds: Number(row.seconds) } : null;
+}
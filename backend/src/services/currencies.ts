@@ -0,0 +1,79 @@
+import type { Queryable } from './pricing.js';
+
+// Display currencies. Everything is priced, charged and settled in Rupiah;
+// a foreign currency only changes what the guest is shown. The menu converts
+// at the current rate, and an order keeps the rate it was placed at, so its
+// converted total doesn't drift while the guest is still at the table.
+
+export const SETTLEMENT_CURRENCY = 'IDR';
+
+export interface Currency {
+  code: string;
+  name: string;
+  symbol: string;
+  /** Rupiah per one unit of the currency */
+  rate_to_idr: number;
+  decimal_places: number;
+  rate_updated_at: string | null;
+}
+
+export const CURRENCY_SELECT = `
+  SELECT id, code, name, symbol, rate_to_idr::float8 AS rate_to_idr, decimal_places, is_active,
+         rate_updated_at, created_at, updated_at
+  FROM currencies`;
+
+export function isCurrencyCode(value: string): boolean {
+  return /^[A-Z]{3}$/.test(value);
+}
+
+/** An active display currency by code; null for IDR or an unknown code. */
+export async function findActiveCurrency(q: Queryable, code: string): Promise<Currency | null> {
+  const res = await q.query(`${CURRENCY_SELECT} WHERE code = $1 AND is_active = true`, [code.toUpperCase()]);
+  const row = res.rows[0];
+  if (!row) return null;
+  return {
+    code: row.code,
+    name: row.name,
+    symbol: row.symbol,
+    rate_to_idr: Number(row.rate_to_idr),
+    decimal_places: Number(row.decimal_places),
+    rate_updated_at: row.rate_updated_at,
+  };
+}
+
+export function convertFromIDR(amount: number, rate: number, decimals: number): number {
+  const factor = 10 ** decimals;
+  return Math.round((amount / rate) * factor) / factor;
+}
+
+// ── OrderCurrency ───────────────────────────────────────────────────────────
+// The currency block of an order response, from the order's
+// display_currency and exchange_rate (and the currency's symbol and decimal
+// places, joined as currency_symbol and currency_decimals).
+
+export function orderCurrency(row: {
+  total_amount: unknown;
+  display_currency: string | null;
+  exchange_rate: unknown;
+  currency_symbol?: string | null;
+  currency_decimals?: unknown;
+}) {
+  const rate = row.exchange_rate !== null && row.exchange_rate !== undefined ? Number(row.exchange_rate) : null;
+  if (!row.display_currency || !rate) {
+    return {
+      settlement_currency: SETTLEMENT_CURRENCY,
+      display_currency: SETTLEMENT_CURRENCY,
+      symbol: 'Rp',
+      exchange_rate: null,
+      display_total: null,
+    };
+  }
+  const decimals = row.currency_decimals !== null && row.currency_decimals !== undefined ? Number(row.currency_decimals) : 2;
+  return {
+    settlement_currency: SETTLEMENT_CURRENCY,
+    display_currency: row.display_currency,
+    symbol: row.currency_symbol ?? row.display_currency,
+    exchange_rate: rate,
+    display_total: convertFromIDR(Number(row.total_amount), rate, decimals),
+  };
+}
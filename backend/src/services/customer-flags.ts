@@ -0,0 +1,71 @@
+import type { Queryable } from './pricing.js';
+import { toInternationalPhone } from '../lib/messaging.js';
+import { createNotificationForRole } from './notification.js';
+
+// Flagged customers. Reservations and delivery orders are made without an
+// account, so a customer is identified by phone number. A flag never blocks
+// a booking or order; it warns the staff handling it.
+
+export type CustomerFlagReason = 'no_show' | 'chargeback' | 'abuse' | 'other';
+
+export const CUSTOMER_FLAG_REASONS: CustomerFlagReason[] = ['no_show', 'chargeback', 'abuse', 'other'];
+
+export interface CustomerFlagMatch {
+  id: string;
+  reason_type: CustomerFlagReason;
+  reason: string;
+  flagged_at: string;
+  flagged_by: string | null;
+}
+
+/** Canonical form flags are stored and matched under: 0812…, +62 812… → 62812… */
+export function flagPhone(phone: string): string {
+  return toInternationalPhone(phone);
+}
+
+/** SQL boolean: whether the phone in `column` has an active flag. Mirrors flagPhone(). */
+export function flaggedPhoneSql(column: string): string {
+  return `EXISTS (SELECT 1 FROM customer_flags cf
+    WHERE cf.is_active = true
+      AND cf.phone = regexp_replace(regexp_replace(${column}, '\\D', '', 'g'), '^0', '62'))`;
+}
+
+// ── FindCustomerFlags ───────────────────────────────────────────────────────
+
+export async function findCustomerFlags(q: Queryable, phone: string | null | undefined): Promise<CustomerFlagMatch[]> {
+  if (!phone) return [];
+  const canonical = flagPhone(phone);
+  if (canonical.length < 8) return [];
+
+  const res = await q.query(
+    `SELECT f.id, f.reason_type, f.reason, f.created_at AS flagged_at,
+            NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS flagged_by
+     FROM customer_flags f
+     LEFT JOIN users u ON u.id = f.flagged_by
+     WHERE f.phone = $1 AND f.is_active = true
+     ORDER BY f.created_at DESC`,
+    [canonical],
+  );
+  return res.rows;
+}
+
+// ── WarnFlaggedCustomer ─────────────────────────────────────────────────────
+// Used where no staff member sees the request as it comes in (website
+// bookings, self-service delivery orders), so the warning goes to the people
+// who will handle it. Returns the matching flags.
+
+export async function warnFlaggedCustomer(
+  q: Queryable,
+  phone: string | null | undefined,
+  roles: string[],
+  subject: string,
+): Promise<CustomerFlagMatch[]> {
+  const flags = await findCustomerFlags(q, phone);
+  if (flags.length === 0) return flags;
+
+  const reasons = flags.map((f) => `${f.reason_type.replace('_', '-')}: ${f.reason}`).join('; ');
+  for (const role of roles) {
+    await createNotificationForRole(role, 'system_alert', 'Flagged Customer', `${subject} from a flagged customer (${reasons})`, 'customer_flagged');
+  }
+  return flags;
+}
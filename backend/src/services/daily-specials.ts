@@ -0,0 +1,140 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+import type { StockRequest, StockShortage } from './stock.js';
+import { localClock } from '../lib/clock.js';
+
+// Daily specials are products sold in a fixed number of portions per
+// business day. Orders claim portions, cancellations on the same day return
+// them, and the scheduler resets every active special each morning.
+
+export const DAILY_SPECIALS_RESET_JOB = 'daily_specials_reset';
+
+// ── ClaimSpecialPortions ────────────────────────────────────────────────────
+// Runs inside the order transaction. Every special is checked before any
+// count is written, so a shortage leaves the counts untouched.
+
+export async function claimSpecialPortions(
+  client: PoolClient,
+  orderId: string,
+  requests: StockRequest[],
+): Promise<StockShortage | null> {
+  const wanted = new Map<string, StockRequest>();
+  for (const r of requests) {
+    const prev = wanted.get(r.product_id);
+    wanted.set(r.product_id, { ...r, quantity: (prev?.quantity ?? 0) + r.quantity });
+  }
+
+  const res = await client.query(
+    `SELECT id, product_id, remaining_quantity, special_date
+     FROM daily_specials
+     WHERE product_id = ANY($1::uuid[]) AND is_active = true
+     ORDER BY id FOR UPDATE`,
+    [[...wanted.keys()]],
+  );
+
+  for (const row of res.rows) {
+    const req = wanted.get(row.product_id)!;
+    if (Number(row.remaining_quantity) < req.quantity) {
+      return {
+        product_id: req.product_id,
+        name: req.name,
+        requested: req.quantity,
+        remaining: Number(row.remaining_quantity),
+      };
+    }
+  }
+
+  for (const row of res.rows) {
+    const req = wanted.get(row.product_id)!;
+    await client.query(
+      `UPDATE daily_specials
+       SET remaining_quantity = remaining_quantity - $1,
+           sold_out_at = CASE WHEN remaining_quantity - $1 = 0 THEN NOW() ELSE sold_out_at END
+       WHERE id = $2`,
+      [req.quantity, row.id],
+    );
+    await client.query(
+      `INSERT INTO daily_special_sales (special_id, order_id, special_date, quantity)
+       VALUES ($1, $2, $3, $4)`,
+      [row.id, orderId, row.special_date, req.quantity],
+    );
+  }
+
+  return null;
+}
+
+// ── ReleaseSpecialPortions ──────────────────────────────────────────────────
+// Portions only go back if the special hasn't been reset since the sale;
+// yesterday's cancellations must not push today's count over the cap.
+
+export async function releaseSpecialPortions(client: PoolClient, orderId: string): Promise<void> {
+  const res = await client.query(
+    `SELECT s.id, s.special_id, s.quantity
+     FROM daily_special_sales s
+     JOIN daily_specials d ON d.id = s.special_id
+     WHERE s.order_id = $1 AND s.released_at IS NULL AND s.special_date = d.special_date
+     FOR UPDATE OF s, d`,
+    [orderId],
+  );
+
+  for (const row of res.rows) {
+    await client.query(
+      `UPDATE daily_specials
+       SET remaining_quantity = LEAST(daily_quantity, remaining_quantity + $1), sold_out_at = NULL
+       WHERE id = $2`,
+      [row.quantity, row.special_id],
+    );
+    await client.query('UPDATE daily_special_sales SET released_at = NOW() WHERE id = $1', [row.id]);
+  }
+}
+
+// ── ReleaseSpecialPortionsForProduct ────────────────────────────────────────
+// Partial release for one product, used when items are voided or reduced on
+// an open order. Same-day rule as releaseSpecialPortions.
+
+export async function releaseSpecialPortionsForProduct(
+  client: PoolClient,
+  orderId: string,
+  productId: string,
+  quantity: number,
+): Promise<void> {
+  const res = await client.query(
+    `SELECT s.id, s.special_id, s.quantity
+     FROM daily_special_sales s
+     JOIN daily_specials d ON d.id = s.special_id
+     WHERE s.order_id = $1 AND d.product_id = $2 AND s.released_at IS NULL AND s.special_date = d.special_date
+     ORDER BY s.created_at DESC
+     FOR UPDATE OF s, d`,
+    [orderId, productId],
+  );
+
+  let left = quantity;
+  for (const row of res.rows) {
+    if (left <= 0) break;
+    const qty = Math.min(left, Number(row.quantity));
+    await client.query(
+      `UPDATE daily_specials
+       SET remaining_quantity = LEAST(daily_quantity, remaining_quantity + $1), sold_out_at = NULL
+       WHERE id = $2`,
+      [qty, row.special_id],
+    );
+    if (qty === Number(row.quantity)) {
+      await client.query('UPDATE daily_special_sales SET released_at = NOW() WHERE id = $1', [row.id]);
+    } else {
+      await client.query('UPDATE daily_special_sales SET quantity = quantity - $1 WHERE id = $2', [qty, row.id]);
+    }
+    left -= qty;
+  }
+}
+
+// ── ResetDailySpecials ──────────────────────────────────────────────────────
+
+export async function resetDailySpecials(q: Queryable, date: string = localClock().date): Promise<number> {
+  const res = await q.query(
+    `UPDATE daily_specials
+     SET remaining_quantity = daily_quantity, special_date = $1, sold_out_at = NULL
+     WHERE is_active = true`,
+    [date],
+  );
+  return res.rowCount ?? 0;
+}
@@ -0,0 +1,209 @@
+import { localClock, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { withFormatted, type Formatter } from '../lib/format.js';
+import type { Queryable } from './pricing.js';
+import { ITEM_NET } from './tax.js';
+
+// The admin dashboard's live figures. Today is compared with yesterday and
+// the same weekday last week up to the same time of day, so a morning
+// dashboard isn't measured against a whole day. Orders and revenue follow
+// the reports: revenue is completed orders' totals, orders count every
+// order placed except cancelled ones. Everything is read in one batch of
+// concurrent queries.
+
+const TOP_PRODUCTS = 5;
+
+// Compared periods: days before today
+const PERIODS = { today: 0, yesterday: 1, last_week: 7 } as const;
+type PeriodName = keyof typeof PERIODS;
+const PERIOD_VALUES = Object.entries(PERIODS).map(([name, daysBack]) => `('${name}', ${daysBack})`).join(', ');
+
+export interface DashboardPeriod {
+  date: string;
+  orders: number;
+  completed_orders: number;
+  cancelled_orders: number;
+  revenue: number;
+  revenue_formatted: string;
+  average_ticket: number;
+  average_ticket_formatted: string;
+  /** Guests on dine-in orders */
+  covers: number;
+}
+
+/** Percent change of today against a period; null when that period had none */
+export interface DashboardDelta {
+  orders: number | null;
+  revenue: number | null;
+  average_ticket: number | null;
+  covers: number | null;
+}
+
+export interface DashboardTopProduct {
+  product_id: string;
+  product_name: string;
+  quantity: number;
+  revenue: number;
+  revenue_formatted: string;
+}
+
+export interface DashboardKitchen {
+  /** Tickets on the kitchen display: orders with released items not yet served */
+  pending_tickets: number;
+  preparing_tickets: number;
+  ready_tickets: number;
+  /** Minutes the longest-waiting ticket not yet ready has been open */
+  oldest_ticket_minutes: number | null;
+}
+
+export interface DashboardStats {
+  date: string;
+  /** Local time of day the comparisons run up to (HH:MM) */
+  as_of: string;
+  generated_at: string;
+  branch_id: string | null;
+  today_orders: number;
+  today_revenue: number;
+  today_revenue_formatted: string;
+  active_orders: number;
+  occupied_tables: number;
+  total_tables: number;
+  today: DashboardPeriod;
+  yesterday: DashboardPeriod;
+  last_week: DashboardPeriod;
+  vs_yesterday: DashboardDelta;
+  vs_last_week: DashboardDelta;
+  top_products: DashboardTopProduct[];
+  kitchen: DashboardKitchen;
+}
+
+const pad = (n: number) => String(n).padStart(2, '0');
+
+function change(current: number, previous: number): number | null {
+  return previous > 0 ? Math.round(((current - previous) / previous) * 1000) / 10 : null;
+}
+
+function delta(today: DashboardPeriod, other: DashboardPeriod): DashboardDelta {
+  return {
+    orders: change(today.orders, other.orders),
+    revenue: change(today.revenue, other.revenue),
+    average_ticket: change(today.average_ticket, other.average_ticket),
+    covers: change(today.covers, other.covers),
+  };
+}
+
+// ── BuildDashboardStats ─────────────────────────────────────────────────────
+
+export async function buildDashboardStats(q: Queryable, branchId: string | null, fmt: Formatter): Promise<DashboardStats> {
+  const now = localClock();
+  const tz = RESTAURANT_TIMEZONE;
+
+  const [periodRes, activeRes, tableRes, topRes, kitchenRes] = await Promise.all([
+    // Each period from its local midnight for as long as today has run so far
+    q.query(
+      `WITH periods AS (
+         SELECT p.name, ($1::date - p.days_back) AS day,
+                ($1::date - p.days_back)::timestamp AT TIME ZONE $2 AS starts_at,
+                ($1::date - p.days_back)::timestamp AT TIME ZONE $2 + (NOW() - $1::date::timestamp AT TIME ZONE $2) AS ends_at
+         FROM (VALUES ${PERIOD_VALUES}) AS p(name, days_back)
+       )
+       SELECT p.name, p.day::text AS date,
+              COUNT(o.id) FILTER (WHERE o.status <> 'cancelled') AS orders,
+              COUNT(o.id) FILTER (WHERE o.status = 'completed') AS completed_orders,
+              COUNT(o.id) FILTER (WHERE o.status = 'cancelled') AS cancelled_orders,
+              COALESCE(SUM(o.total_amount) FILTER (WHERE o.status = 'completed'), 0) AS revenue,
+              COALESCE(SUM(o.covers) FILTER (WHERE o.status <> 'cancelled' AND o.order_type = 'dine_in'), 0) AS covers
+       FROM periods p
+       LEFT JOIN orders o ON o.created_at >= p.starts_at AND o.created_at < p.ends_at
+                          AND ($3::uuid IS NULL OR o.branch_id = $3)
+       GROUP BY p.name, p.day`,
+      [now.date, tz, branchId],
+    ),
+    q.query(
+      `SELECT COUNT(*) AS active,
+              COUNT(*) FILTER (WHERE DATE(created_at AT TIME ZONE $1) = $2) AS today_all
+       FROM orders
+       WHERE ($3::uuid IS NULL OR branch_id = $3)
+         AND (status NOT IN ('completed', 'cancelled') OR DATE(created_at AT TIME ZONE $1) = $2)`,
+      [tz, now.date, branchId],
+    ),
+    q.query(
+      `SELECT COUNT(*) FILTER (WHERE is_occupied = true) AS occupied, COUNT(*) AS total
+       FROM dining_tables
+       WHERE deleted_at IS NULL AND ($1::uuid IS NULL OR branch_id = $1)`,
+      [branchId],
+    ),
+    // Best sellers so far today by portions, cancelled orders left out
+    q.query(
+      `SELECT oi.product_id, p.name AS product_name, SUM(oi.quantity) AS quantity, SUM(${ITEM_NET}) AS revenue
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       JOIN products p ON p.id = oi.product_id
+       WHERE o.status <> 'cancelled' AND DATE(o.created_at AT TIME ZONE $1) = $2
+         AND ($3::uuid IS NULL OR o.branch_id = $3)
+       GROUP BY oi.product_id, p.name
+       ORDER BY SUM(oi.quantity) DESC, SUM(${ITEM_NET}) DESC
+       LIMIT $4`,
+      [tz, now.date, branchId, TOP_PRODUCTS],
+    ),
+    // The same tickets the kitchen display shows
+    q.query(
+      `SELECT COUNT(*) FILTER (WHERE o.status IN ('pending', 'confirmed')) AS pending,
+              COUNT(*) FILTER (WHERE o.status = 'preparing') AS preparing,
+              COUNT(*) FILTER (WHERE o.status = 'ready') AS ready,
+              EXTRACT(EPOCH FROM NOW() - MIN(COALESCE(o.scheduled_at, o.created_at))
+                FILTER (WHERE o.status <> 'ready')) / 60 AS oldest_minutes
+       FROM orders o
+       WHERE o.status IN ('pending', 'confirmed', 'preparing', 'ready')
+         AND EXISTS (SELECT 1 FROM order_items ri WHERE ri.order_id = o.id AND ri.released_at IS NOT NULL)
+         AND ($1::uuid IS NULL OR o.branch_id = $1)`,
+      [branchId],
+    ),
+  ]);
+
+  const periods = {} as Record<PeriodName, DashboardPeriod>;
+  for (const row of periodRes.rows) {
+    const completed = Number(row.completed_orders);
+    const revenue = Number(row.revenue);
+    periods[row.name as PeriodName] = withFormatted({
+      date: row.date,
+      orders: Number(row.orders),
+      completed_orders: completed,
+      cancelled_orders: Number(row.cancelled_orders),
+      revenue,
+      average_ticket: completed > 0 ? Math.round(revenue / completed) : 0,
+      covers: Number(row.covers),
+    }, ['revenue', 'average_ticket'], fmt.money) as DashboardPeriod;
+  }
+
+  const kitchen = kitchenRes.rows[0];
+  const today = periods.today;
+  return {
+    date: now.date,
+    as_of: `${pad(Math.floor(now.secondsOfDay / 3600))}:${pad(Math.floor(now.secondsOfDay / 60) % 60)}`,
+    generated_at: new Date().toISOString(),
+    branch_id: branchId,
+    today_orders: Number(activeRes.rows[0].today_all),
+    today_revenue: today.revenue,
+    today_revenue_formatted: today.revenue_formatted,
+    active_orders: Number(activeRes.rows[0].active),
+    occupied_tables: Number(tableRes.rows[0].occupied),
+    total_tables: Number(tableRes.rows[0].total),
+    today,
+    yesterday: periods.yesterday,
+    last_week: periods.last_week,
+    vs_yesterday: delta(today, periods.yesterday),
+    vs_last_week: delta(today, periods.last_week),
+    top_products: topRes.rows.map((row) => withFormatted({
+      product_id: row.product_id,
+      product_name: row.product_name,
+      quantity: Number(row.quantity),
+      revenue: Math.round(Number(row.revenue)),
+    }, ['revenue'], fmt.money) as DashboardTopProduct),
+    kitchen: {
+      pending_tickets: Number(kitchen.pending),
+      preparing_tickets: Number(kitchen.preparing),
+      ready_tickets: Number(kitchen.ready),
+      oldest_ticket_minutes: kitchen.oldest_minutes === null ? null : Math.floor(Number(kitchen.oldest_minutes)),
+    },
+  };
+}
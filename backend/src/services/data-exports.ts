@@ -0,0 +1,74 @@
+import type { Context } from 'hono';
+import type { Queryable } from './pricing.js';
+
+// Data exports. Every download is logged in export_log (who, when, what and
+// with which query) and watermarked with the requesting user, the time and
+// the log entry's ID. The accounting and payroll systems import these files
+// as they are, so the watermark can't go inside a CSV: it is in the file
+// name and the X-Export-* headers, and in an `export` object on JSON
+// responses. The export ID in a file name leads back to its log entry.
+
+export interface ExportRecord {
+  id: string;
+  exportedBy: string;
+  exportedAt: string;
+}
+
+export interface ExportEntry {
+  type: string;
+  format: string;
+  params: Record<string, string | null | undefined>;
+  rowCount: number;
+}
+
+// ── LogExport ───────────────────────────────────────────────────────────────
+
+export async function logExport(q: Queryable, c: Context, entry: ExportEntry): Promise<ExportRecord> {
+  const username = c.get('username');
+  const ip = c.req.header('x-forwarded-for')?.split(',')[0]?.trim() || c.req.header('x-real-ip') || null;
+  const res = await q.query(
+    `INSERT INTO export_log (export_type, format, user_id, username, branch_id, params, row_count, ip_address)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+     RETURNING id, created_at`,
+    [
+      entry.type,
+      entry.format,
+      c.get('user_id'),
+      username,
+      c.get('branch_id') ?? null,
+      JSON.stringify(entry.params),
+      entry.rowCount,
+      ip,
+    ],
+  );
+  const row = res.rows[0];
+  return { id: row.id, exportedBy: username, exportedAt: new Date(row.created_at).toISOString() };
+}
+
+// ── Watermarks ──────────────────────────────────────────────────────────────
+
+/** commissions-2026-10.csv → commissions-2026-10_jdoe_20261014T031500Z_1a2b3c4d.csv */
+export function watermarkedFilename(filename: string, record: ExportRecord): string {
+  const dot = filename.lastIndexOf('.');
+  const base = dot > 0 ? filename.slice(0, dot) : filename;
+  const ext = dot > 0 ? filename.slice(dot) : '';
+  const user = record.exportedBy.replace(/[^A-Za-z0-9._-]/g, '_');
+  const stamp = record.exportedAt.replace(/[-:]/g, '').replace(/\.\d+Z$/, 'Z');
+  return `${base}_${user}_${stamp}_${record.id.slice(0, 8)}${ext}`;
+}
+
+/** Headers for a downloaded export file. */
+export function exportFileHeaders(contentType: string, filename: string, record: ExportRecord): Record<string, string> {
+  return {
+    'Content-Type': contentType,
+    'Content-Disposition': `attachment; filename="${watermarkedFilename(filename, record)}"`,
+    'X-Export-Id': record.id,
+    'X-Exported-By': record.exportedBy,
+    'X-Exported-At': record.exportedAt,
+  };
+}
+
+/** The watermark on a JSON export. */
+export function exportWatermark(record: ExportRecord) {
+  return { id: record.id, exported_by: record.exportedBy, exported_at: record.exportedAt };
+}
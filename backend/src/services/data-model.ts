@@ -0,0 +1,199 @@
+import { getTableName, is } from 'drizzle-orm';
+import { PgTable, getTableConfig } from 'drizzle-orm/pg-core';
+import * as schema from '../db/schema.js';
+import { passwordPolicy } from '../lib/password.js';
+import { ALLERGENS, DIETARY_TAGS, MAX_SPICY_LEVEL } from './dietary.js';
+import { MAX_DELIVERY_ADDRESS_LENGTH, MAX_DELIVERY_NOTES_LENGTH } from './delivery.js';
+import { WASTE_REASONS } from './waste.js';
+import { REMAKE_REASONS } from './remakes.js';
+import { CUSTOMER_FLAG_REASONS } from './customer-flags.js';
+import { TAB_STATUSES } from './tabs.js';
+import { MAX_QUANTITY, SALE_UNITS } from './weighed-items.js';
+import { RECEIPT_LANGUAGES } from './receipt-language.js';
+import { NOTIFICATION_TYPES } from './notification-preferences.js';
+import { NOTIFICATION_SEVERITIES } from './notification-severity.js';
+import { INCIDENT_COMPONENTS, INCIDENT_SEVERITIES } from './status-page.js';
+import { KITCHEN_STATIONS } from './kitchen-routing.js';
+import { LOGBOOK_CATEGORIES, LOGBOOK_PRIORITIES, LOGBOOK_SHIFTS } from './logbook.js';
+import { WEBHOOK_EVENTS } from './webhooks.js';
+import { CASH_ROUNDING_MODES } from './cash-rounding.js';
+import { loadPaymentMethods } from './payment-methods.js';
+import { MAX_COVERS, MAX_SHIFT_HOURS } from './sections.js';
+import { MAX_ITEM_NOTE_PHRASES, MAX_NOTE_PHRASE_LABEL } from './note-phrases.js';
+import { MAX_BREAK_MINUTES } from './attendance.js';
+import type { Queryable } from './pricing.js';
+
+// The data model as the code sees it, for GET /admin/meta/schema: the tables
+// declared in db/schema.ts, the values enumerated columns accept and the
+// limits requests are checked against. The frontend and integrations read it
+// instead of keeping their own copies of these lists.
+//
+// Lists that used to be spelled out inside a handler live here; lists that
+// belong to a service stay in it and are collected below. Payment methods
+// are managed in the database, so their enum is the active ones at the time.
+
+export const ORDER_STATUSES = [
+  'scheduled', 'parked', 'pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled',
+];
+/** Statuses staff can move an order to with PATCH /orders/:id/status */
+export const ORDER_STATUS_UPDATES = ['pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'];
+export const ORDER_TYPES = ['dine_in', 'takeout', 'delivery'];
+export const ORDER_ITEM_STATUSES = ['pending', 'preparing', 'ready', 'served'];
+export const DELIVERY_STATUSES = ['unassigned', 'assigned', 'picked_up', 'delivered'];
+
+export const REFUND_REASONS = [
+  'wrong_amount',
+  'wrong_method',
+  'duplicate_charge',
+  'customer_complaint',
+  'item_unavailable',
+  'order_cancelled',
+  'other',
+];
+
+export const INVENTORY_OPERATIONS = ['add', 'remove'];
+export const INVENTORY_ADJUST_REASONS = [
+  'purchase', 'sale', 'spoilage', 'manual_adjustment', 'inventory_count', 'return', 'damage', 'theft', 'expired',
+];
+export const RESERVATION_STATUS_UPDATES = ['confirmed', 'cancelled', 'completed', 'no_show'];
+export const SURVEY_STATUSES = ['new', 'acknowledged', 'replied'];
+
+/** Longest text a customer can send with a self-service order */
+export const CUSTOMER_ORDER_LIMITS = { customer_name: 100, notes: 500, special_instructions: 500 };
+
+const ENUMS: Record<string, readonly string[]> = {
+  order_status: ORDER_STATUSES,
+  order_status_update: ORDER_STATUS_UPDATES,
+  order_type: ORDER_TYPES,
+  order_item_status: ORDER_ITEM_STATUSES,
+  delivery_status: DELIVERY_STATUSES,
+  refund_reason: REFUND_REASONS,
+  inventory_operation: INVENTORY_OPERATIONS,
+  inventory_adjust_reason: INVENTORY_ADJUST_REASONS,
+  reservation_status_update: RESERVATION_STATUS_UPDATES,
+  survey_status: SURVEY_STATUSES,
+  waste_reason: WASTE_REASONS,
+  remake_reason: REMAKE_REASONS,
+  customer_flag_reason: CUSTOMER_FLAG_REASONS,
+  tab_status: TAB_STATUSES,
+  sale_unit: SALE_UNITS,
+  receipt_language: RECEIPT_LANGUAGES,
+  allergen: ALLERGENS,
+  dietary_tag: DIETARY_TAGS,
+  kitchen_station: KITCHEN_STATIONS,
+  cash_rounding_mode: CASH_ROUNDING_MODES,
+  notification_type: NOTIFICATION_TYPES,
+  notification_severity: NOTIFICATION_SEVERITIES,
+  incident_severity: INCIDENT_SEVERITIES,
+  incident_component: INCIDENT_COMPONENTS,
+  logbook_category: LOGBOOK_CATEGORIES,
+  logbook_shift: LOGBOOK_SHIFTS,
+  logbook_priority: LOGBOOK_PRIORITIES,
+  webhook_event: WEBHOOK_EVENTS,
+};
+
+// table.column → the enum its values come from
+const ENUM_COLUMNS: Record<string, string> = {
+  'orders.status': 'order_status',
+  'orders.order_type': 'order_type',
+  'orders.delivery_status': 'delivery_status',
+  'orders.receipt_language': 'receipt_language',
+  'order_items.status': 'order_item_status',
+  'payments.payment_method': 'payment_method',
+  'inventory_history.operation': 'inventory_operation',
+  'inventory_history.reason': 'inventory_adjust_reason',
+  'products.sale_unit': 'sale_unit',
+  'tabs.status': 'tab_status',
+  'satisfaction_surveys.status': 'survey_status',
+  'status_incidents.severity': 'incident_severity',
+  'status_incidents.component': 'incident_component',
+};
+
+export interface EntityColumn {
+  name: string;
+  type: string;
+  nullable: boolean;
+  has_default: boolean;
+  primary_key: boolean;
+  unique: boolean;
+  /** Name of the enum in `enums` the column's values come from */
+  enum?: string;
+}
+
+export interface Entity {
+  name: string;
+  columns: EntityColumn[];
+  primary_key: string[];
+  foreign_keys: { columns: string[]; references: { table: string; columns: string[] } }[];
+}
+
+function describeTable(table: PgTable): Entity {
+  const config = getTableConfig(table);
+  const compositeKey = config.primaryKeys[0]?.columns.map((col) => col.name) ?? [];
+  const columns = config.columns.map((col): EntityColumn => {
+    const enumName = ENUM_COLUMNS[`${config.name}.${col.name}`];
+    return {
+      name: col.name,
+      type: col.getSQLType(),
+      nullable: !col.notNull,
+      has_default: col.hasDefault,
+      primary_key: col.primary || compositeKey.includes(col.name),
+      unique: col.isUnique,
+      ...(enumName ? { enum: enumName } : {}),
+    };
+  });
+
+  return {
+    name: config.name,
+    columns,
+    primary_key: columns.filter((col) => col.primary_key).map((col) => col.name),
+    foreign_keys: config.foreignKeys.map((fk) => {
+      const ref = fk.reference();
+      return {
+        columns: ref.columns.map((col) => col.name),
+        references: { table: getTableName(ref.foreignTable), columns: ref.foreignColumns.map((col) => col.name) },
+      };
+    }),
+  };
+}
+
+let entities: Entity[] | null = null;
+
+// The schema module doesn't change while the process runs
+function describeEntities(): Entity[] {
+  if (!entities) {
+    entities = Object.values(schema)
+      .filter((value): value is PgTable => is(value, PgTable))
+      .map(describeTable)
+      .sort((a, b) => a.name.localeCompare(b.name));
+  }
+  return entities;
+}
+
+// ── DescribeDataModel ───────────────────────────────────────────────────────
+
+export async function describeDataModel(q: Queryable) {
+  const paymentMethods = await loadPaymentMethods(q, { activeOnly: true });
+  return {
+    entities: describeEntities(),
+    enums: { ...ENUMS, payment_method: paymentMethods.map((m) => m.code) },
+    validation: {
+      password: passwordPolicy(),
+      customer_order: {
+        max_length: CUSTOMER_ORDER_LIMITS,
+        delivery_address_max_length: MAX_DELIVERY_ADDRESS_LENGTH,
+        delivery_notes_max_length: MAX_DELIVERY_NOTES_LENGTH,
+        // Whole numbers for items sold each, up to three decimals for weighed ones
+        quantity: { min_exclusive: 0, max: MAX_QUANTITY, weighed_max_decimals: 3 },
+      },
+      order: { covers: { min: 1, max: MAX_COVERS } },
+      order_item: { note_phrases_max: MAX_ITEM_NOTE_PHRASES },
+      section_assignment: { shift_max_hours: MAX_SHIFT_HOURS },
+      staff_shift: { max_hours: MAX_SHIFT_HOURS, break_minutes: { min: 0, max: MAX_BREAK_MINUTES } },
+      attendance_pin: { digits: { min: 4, max: 8 } },
+      note_phrase: { label_max_length: MAX_NOTE_PHRASE_LABEL },
+      product: { spicy_level: { min: 0, max: MAX_SPICY_LEVEL } },
+      survey: { rating: { min: 1, max: 5 } },
+    },
+  };
+}
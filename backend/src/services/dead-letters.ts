@@ -0,0 +1,190 @@
+import type { PoolClient } from 'pg';
+import { enqueueJob } from '../lib/jobs.js';
+import { SEND_EMAIL_JOB } from './email.js';
+import { GATEWAY_REFUND_JOB } from './gateway-refunds.js';
+import { PROCESS_INBOUND_WEBHOOK_JOB } from './inbound-webhooks.js';
+import { DELIVER_WEBHOOK_JOB } from './webhooks.js';
+import type { Queryable } from './pricing.js';
+
+// Dead letters: background jobs that ran out of attempts (lib/jobs.ts copies
+// them here). Each keeps the job's payload and every attempt's error, stays
+// open until an admin replays or discards it, and reopens if the replayed
+// job fails again.
+//
+// Replaying puts the same job back on the queue with a fresh set of
+// attempts. The email, webhook delivery or inbound event behind it is set
+// back to queued first, since their handlers skip anything that isn't.
+// Failed gateway refunds aren't replayed from here: a retry needs a new
+// refund key and the amount checked again, which the refund's own retry
+// does. Retrying the record from its own admin page closes its dead letter.
+
+export const DEAD_LETTER_STATUSES = ['open', 'replayed', 'discarded'];
+
+// At most this many dead letters per bulk replay
+export const MAX_BULK_REPLAY = 200;
+
+export interface DeadLetterFailure {
+  message: string;
+  code: string;
+  status: 404 | 409;
+}
+
+// The record a job works on: its table, the payload key holding the ID and
+// the columns worth showing next to the dead letter
+interface JobSubject {
+  key: string;
+  table: string;
+  select: string;
+  // Sets a failed record back to queued for the replayed job
+  requeue?: string;
+}
+
+const SUBJECTS: Record<string, JobSubject> = {
+  [SEND_EMAIL_JOB]: {
+    key: 'outbox_id',
+    table: 'email_outbox',
+    select: 'SELECT id, template, recipients, subject, status, last_error, attempts FROM email_outbox WHERE id = $1',
+    requeue: "UPDATE email_outbox SET status = 'queued', updated_at = NOW() WHERE id = $1 AND status = 'failed'",
+  },
+  [DELIVER_WEBHOOK_JOB]: {
+    key: 'delivery_id',
+    table: 'webhook_deliveries',
+    select: `SELECT d.id, d.event, d.event_id, d.payload, d.status, d.response_status, d.response_body, d.last_error,
+                    e.name AS endpoint_name, e.url AS endpoint_url
+             FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+             WHERE d.id = $1`,
+    requeue: "UPDATE webhook_deliveries SET status = 'queued', updated_at = NOW() WHERE id = $1 AND status = 'failed'",
+  },
+  [PROCESS_INBOUND_WEBHOOK_JOB]: {
+    key: 'event_id',
+    table: 'inbound_webhook_events',
+    select: `SELECT id, provider, external_id, event_type, payload, status, last_error
+             FROM inbound_webhook_events WHERE id = $1`,
+    requeue: `UPDATE inbound_webhook_events SET status = 'queued', updated_at = NOW()
+              WHERE id = $1 AND status IN ('failed', 'ignored')`,
+  },
+  [GATEWAY_REFUND_JOB]: {
+    key: 'gateway_refund_id',
+    table: 'gateway_refunds',
+    select: 'SELECT id, gateway_order_id, amount, reason, status, last_error, attempts FROM gateway_refunds WHERE id = $1',
+  },
+};
+
+const NOT_REPLAYABLE: Record<string, string> = {
+  [GATEWAY_REFUND_JOB]: 'Failed refunds are retried from /admin/gateway-refunds/:id/retry, which issues a new refund key',
+};
+
+export const DEAD_LETTER_SELECT = `
+  SELECT dl.id, dl.job_id, dl.job_type, dl.payload, dl.status, dl.attempts, dl.max_attempts, dl.last_error,
+         dl.failure_count, dl.first_failed_at, dl.last_failed_at, dl.replay_count, dl.replayed_at, dl.replayed_by,
+         dl.discarded_at, dl.discarded_by, dl.note, dl.created_at, dl.updated_at
+  FROM dead_letters dl`;
+
+/** Whether dead letters of this job type can be replayed from the dead-letter store. */
+export function isReplayable(jobType: string): boolean {
+  return !(jobType in NOT_REPLAYABLE);
+}
+
+// ── LoadDeadLetterSubject ───────────────────────────────────────────────────
+// The email, webhook delivery, inbound event or refund the failed job was
+// for, as it is now; null for other job types or a record since deleted.
+
+export async function loadDeadLetterSubject(
+  q: Queryable,
+  jobType: string,
+  payload: Record<string, unknown>,
+): Promise<{ type: string; record: Record<string, unknown> | null } | null> {
+  const subject = SUBJECTS[jobType];
+  if (!subject) return null;
+  const id = payload[subject.key];
+  if (typeof id !== 'string') return { type: subject.table, record: null };
+  const res = await q.query(subject.select, [id]);
+  return { type: subject.table, record: res.rows[0] ?? null };
+}
+
+// ── RequeueDeadLetter ───────────────────────────────────────────────────────
+// Runs in the caller's transaction. The job is reset in place when it still
+// exists (jobs are only purged once they succeed), otherwise enqueued again
+// with the same payload.
+
+export async function requeueDeadLetter(
+  client: PoolClient,
+  id: string,
+  input: { userId: string | null; note?: string | null },
+): Promise<{ ok: true; jobId: string } | { ok: false; failure: DeadLetterFailure }> {
+  const res = await client.query(
+    'SELECT id, job_id, job_type, payload, max_attempts, status FROM dead_letters WHERE id = $1 FOR UPDATE',
+    [id],
+  );
+  const letter = res.rows[0];
+  if (!letter) {
+    return { ok: false, failure: { message: 'Dead letter not found', code: 'dead_letter_not_found', status: 404 } };
+  }
+  if (letter.status !== 'open') {
+    return {
+      ok: false,
+      failure: { message: `Only open dead letters can be replayed; this one is ${letter.status}`, code: 'invalid_dead_letter_status', status: 409 },
+    };
+  }
+  if (!isReplayable(letter.job_type)) {
+    return { ok: false, failure: { message: NOT_REPLAYABLE[letter.job_type], code: 'not_replayable', status: 409 } };
+  }
+
+  const subject = SUBJECTS[letter.job_type];
+  if (subject?.requeue && typeof letter.payload[subject.key] === 'string') {
+    await client.query(subject.requeue, [letter.payload[subject.key]]);
+  }
+
+  let jobId: string | null = null;
+  if (letter.job_id) {
+    const reset = await client.query(
+      `UPDATE jobs
+       SET status = 'pending', attempts = 0, run_at = NOW(), completed_at = NULL, locked_at = NULL, updated_at = NOW()
+       WHERE id = $1 AND status = 'failed'
+       RETURNING id`,
+      [letter.job_id],
+    );
+    jobId = reset.rows[0]?.id ?? null;
+  }
+  if (!jobId) {
+    jobId = await enqueueJob(client, letter.job_type, letter.payload, { maxAttempts: letter.max_attempts });
+  }
+
+  await client.query(
+    `UPDATE dead_letters
+     SET status = 'replayed', job_id = $2, replay_count = replay_count + 1, replayed_at = NOW(), replayed_by = $3,
+         note = COALESCE($4, note), updated_at = NOW()
+     WHERE id = $1`,
+    [letter.id, jobId, input.userId, input.note ?? null],
+  );
+  return { ok: true, jobId };
+}
+
+// ── CloseDeadLetters ────────────────────────────────────────────────────────
+// For the retry actions that requeue a record themselves (a failed email,
+// webhook delivery, inbound event or refund, or a job from /admin/jobs):
+// marks the open dead letters for it replayed. `match` is a job ID, or a job
+// type with the payload it was queued with.
+
+export async function closeDeadLetters(
+  q: Queryable,
+  match: { jobId: string } | { jobType: string; payload: Record<string, unknown> },
+  userId: string | null,
+): Promise<void> {
+  let condition: string;
+  let params: string[];
+  if ('jobId' in match) {
+    condition = 'job_id = $1';
+    params = [match.jobId];
+  } else {
+    condition = 'job_type = $1 AND payload @> $2::jsonb';
+    params = [match.jobType, JSON.stringify(match.payload)];
+  }
+  await q.query(
+    `UPDATE dead_letters
+     SET status = 'replayed', replay_count = replay_count + 1, replayed_at = NOW(),
+         replayed_by = $${params.length + 1}, updated_at = NOW()
+     WHERE status = 'open' AND ${condition}`,
+    [...params, userId],
+  );
+}
@@ -0,0 +1,78 @@
+import type { Queryable } from './pricing.js';
+
+// Delivery orders. The fee comes from system settings and is added on top of
+// the taxed total; the courier hand-off is tracked separately from the order
+// status so the kitchen flow (pending → ready) stays the same for every
+// order type.
+
+export type DeliveryStatus = 'unassigned' | 'assigned' | 'picked_up' | 'delivered';
+
+export const MAX_DELIVERY_ADDRESS_LENGTH = 500;
+export const MAX_DELIVERY_NOTES_LENGTH = 500;
+
+export interface DeliverySettings {
+  fee: number;
+  freeThreshold: number;
+  minOrder: number;
+}
+
+export async function loadDeliverySettings(q: Queryable): Promise<DeliverySettings> {
+  const res = await q.query(
+    `SELECT setting_key, setting_value FROM system_settings
+     WHERE setting_key IN ('delivery_fee', 'delivery_free_threshold', 'delivery_min_order')`,
+  );
+  const settings: DeliverySettings = { fee: 0, freeThreshold: 0, minOrder: 0 };
+  for (const row of res.rows) {
+    const value = parseFloat(row.setting_value);
+    if (isNaN(value) || value < 0) continue;
+    if (row.setting_key === 'delivery_fee') settings.fee = value;
+    if (row.setting_key === 'delivery_free_threshold') settings.freeThreshold = value;
+    if (row.setting_key === 'delivery_min_order') settings.minOrder = value;
+  }
+  return settings;
+}
+
+/** Fee for an order worth `itemsTotal` after discounts, before tax. */
+export function computeDeliveryFee(settings: DeliverySettings, itemsTotal: number): number {
+  if (settings.freeThreshold > 0 && itemsTotal >= settings.freeThreshold) return 0;
+  return settings.fee;
+}
+
+export interface DeliveryDetails {
+  address: string;
+  phone: string;
+  notes: string | null;
+}
+
+/** Digits only, so "+62 812-3456" and "628123456" compare equal. */
+export function normalizePhone(phone: string): string {
+  return phone.replace(/\D/g, '');
+}
+
+// ── ValidateDeliveryDetails ─────────────────────────────────────────────────
+
+export function validateDeliveryDetails(body: {
+  delivery_address?: string;
+  delivery_phone?: string;
+  delivery_notes?: string;
+}): { ok: true; details: DeliveryDetails } | { ok: false; message: string; code: string } {
+  const address = (body.delivery_address || '').trim();
+  const phone = (body.delivery_phone || '').trim();
+  const notes = (body.delivery_notes || '').trim();
+
+  if (!address) {
+    return { ok: false, message: 'Delivery address is required for delivery orders', code: 'delivery_address_required' };
+  }
+  if (address.length > MAX_DELIVERY_ADDRESS_LENGTH) {
+    return { ok: false, message: 'Delivery address is too long (max 500 characters)', code: 'delivery_address_too_long' };
+  }
+  const digits = normalizePhone(phone);
+  if (digits.length < 8 || digits.length > 15 || phone.length > 20) {
+    return { ok: false, message: 'A valid contact phone number is required for delivery orders', code: 'invalid_delivery_phone' };
+  }
+  if (notes.length > MAX_DELIVERY_NOTES_LENGTH) {
+    return { ok: false, message: 'Delivery notes are too long (max 500 characters)', code: 'delivery_notes_too_long' };
+  }
+
+  return { ok: true, details: { address, phone, notes: notes || null } };
+}
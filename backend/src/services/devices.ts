@@ -0,0 +1,57 @@
+import type { Queryable } from './pricing.js';
+
+// POS terminals. Each browser running the POS generates a device ID once and
+// sends it on login; the device is registered then, so a manager can find it
+// in the admin panel and set its printers. Preferences left NULL follow the
+// receipt and kitchen system settings.
+
+const DEVICE_ID_RE = /^[A-Za-z0-9_-]{8,64}$/;
+
+export function isDeviceId(value: unknown): value is string {
+  return typeof value === 'string' && DEVICE_ID_RE.test(value);
+}
+
+export interface PrintPreferences {
+  device_id: string;
+  name: string | null;
+  receipt_printer: string | null;
+  auto_print_receipt: boolean | null;
+  receipt_copies: number | null;
+  kitchen_printer: string | null;
+  auto_print_kitchen: boolean | null;
+  kitchen_copies: number | null;
+}
+
+export const PRINT_PREFERENCE_COLUMNS = [
+  'name', 'receipt_printer', 'auto_print_receipt', 'receipt_copies',
+  'kitchen_printer', 'auto_print_kitchen', 'kitchen_copies',
+] as const;
+
+const PRINT_PREFERENCE_SELECT = `device_id, ${PRINT_PREFERENCE_COLUMNS.join(', ')}`;
+
+export async function getPrintPreferences(q: Queryable, deviceId: string): Promise<PrintPreferences | null> {
+  const res = await q.query(`SELECT ${PRINT_PREFERENCE_SELECT} FROM device_print_preferences WHERE device_id = $1`, [deviceId]);
+  return res.rows[0] ?? null;
+}
+
+// ── RegisterDeviceLogin ─────────────────────────────────────────────────────
+// Records who last logged in on the device and returns its preferences. A
+// device is assigned to the branch of the first user seen on it.
+
+export async function registerDeviceLogin(
+  q: Queryable,
+  deviceId: string,
+  userId: string,
+  branchId: string | null,
+): Promise<PrintPreferences> {
+  const res = await q.query(
+    `INSERT INTO device_print_preferences (device_id, branch_id, last_seen_at, last_user_id)
+     VALUES ($1, $2, NOW(), $3)
+     ON CONFLICT (device_id) DO UPDATE
+       SET last_seen_at = NOW(), last_user_id = $3,
+           branch_id = COALESCE(device_print_preferences.branch_id, EXCLUDED.branch_id)
+     RETURNING ${PRINT_PREFERENCE_SELECT}`,
+    [deviceId, branchId, userId],
+  );
+  return res.rows[0];
+}
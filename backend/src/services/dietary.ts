@@ -0,0 +1,100 @@
+// Allergens and dietary tags. Products carry allergens they contain, tags
+// describing them (halal, vegetarian...) and an optional spice level from 0
+// to 3. The public menu shows them as badges and can be filtered on them:
+// ?exclude_allergens= drops products containing any of the listed allergens,
+// ?tags= keeps products carrying every listed tag and ?max_spicy= drops
+// anything hotter. Products with no stated spice level pass ?max_spicy=.
+
+export const ALLERGENS = [
+  'peanut', 'tree_nut', 'milk', 'egg', 'wheat', 'gluten', 'soy', 'fish', 'shellfish', 'sesame',
+] as const;
+export type Allergen = (typeof ALLERGENS)[number];
+
+export const DIETARY_TAGS = ['halal', 'vegetarian', 'vegan', 'gluten_free', 'dairy_free', 'contains_alcohol'] as const;
+export type DietaryTag = (typeof DIETARY_TAGS)[number];
+
+export const MAX_SPICY_LEVEL = 3;
+
+export interface DietaryFilter {
+  excludeAllergens: Allergen[];
+  tags: DietaryTag[];
+  maxSpicy: number | null;
+}
+
+export function isAllergen(value: unknown): value is Allergen {
+  return typeof value === 'string' && (ALLERGENS as readonly string[]).includes(value);
+}
+
+export function isDietaryTag(value: unknown): value is DietaryTag {
+  return typeof value === 'string' && (DIETARY_TAGS as readonly string[]).includes(value);
+}
+
+export function isSpicyLevel(value: unknown): value is number {
+  return typeof value === 'number' && Number.isInteger(value) && value >= 0 && value <= MAX_SPICY_LEVEL;
+}
+
+function splitList(value: string | undefined): string[] {
+  return (value ?? '').split(',').map((s) => s.trim().toLowerCase()).filter(Boolean);
+}
+
+// ── ParseDietaryFilter ──────────────────────────────────────────────────────
+// From the menu's query string; a message for the first bad value.
+
+export function parseDietaryFilter(query: {
+  exclude_allergens?: string;
+  tags?: string;
+  max_spicy?: string;
+}): { ok: true; filter: DietaryFilter } | { ok: false; message: string; code: string } {
+  const allergens = splitList(query.exclude_allergens);
+  const badAllergen = allergens.find((a) => !isAllergen(a));
+  if (badAllergen) {
+    return { ok: false, message: `Unknown allergen '${badAllergen}'; use: ${ALLERGENS.join(', ')}`, code: 'invalid_allergen' };
+  }
+
+  const tags = splitList(query.tags);
+  const badTag = tags.find((t) => !isDietaryTag(t));
+  if (badTag) {
+    return { ok: false, message: `Unknown tag '${badTag}'; use: ${DIETARY_TAGS.join(', ')}`, code: 'invalid_tag' };
+  }
+
+  let maxSpicy: number | null = null;
+  if (query.max_spicy !== undefined && query.max_spicy !== '') {
+    maxSpicy = Number(query.max_spicy);
+    if (!isSpicyLevel(maxSpicy)) {
+      return { ok: false, message: `max_spicy must be a whole number from 0 to ${MAX_SPICY_LEVEL}`, code: 'invalid_max_spicy' };
+    }
+  }
+
+  return {
+    ok: true,
+    filter: { excludeAllergens: allergens as Allergen[], tags: tags as DietaryTag[], maxSpicy },
+  };
+}
+
+// ── DietaryConditions ───────────────────────────────────────────────────────
+// SQL to AND onto a query over products aliased p; pushes its parameters.
+
+export function dietaryConditions(filter: DietaryFilter, params: unknown[]): string {
+  let sql = '';
+  if (filter.excludeAllergens.length > 0) {
+    params.push(filter.excludeAllergens);
+    sql += ` AND NOT (p.allergens && $${params.length}::text[])`;
+  }
+  if (filter.tags.length > 0) {
+    params.push(filter.tags);
+    sql += ` AND p.dietary_tags @> $${params.length}::text[]`;
+  }
+  if (filter.maxSpicy !== null) {
+    params.push(filter.maxSpicy);
+    sql += ` AND COALESCE(p.spicy_level, 0) <= $${params.length}`;
+  }
+  return sql;
+}
+
+export function formatDietaryInfo(row: Record<string, unknown>) {
+  return {
+    allergens: (row.allergens as string[]) ?? [],
+    dietary_tags: (row.dietary_tags as string[]) ?? [],
+    spicy_level: row.spicy_level === null || row.spicy_level === undefined ? null : Number(row.spicy_level),
+  };
+}
@@ -0,0 +1,138 @@
+import type { PoolClient } from 'pg';
+import { localClock } from '../lib/clock.js';
+import { invalidateCache } from '../lib/cache.js';
+import { queueMenuSync } from './menu-sync.js';
+import type { Queryable } from './pricing.js';
+import { syncStockAvailability } from './stock-availability.js';
+
+// The 86 list. Kitchen staff "86" a product when it can't be made for the
+// rest of the day: it becomes unavailable, so the public menu, the POS and
+// the delivery platforms stop offering it, until someone puts it back or the
+// daily rollover does. Every 86 is kept as an event for the history report.
+
+export const EIGHTY_SIX_RESET_JOB = 'eighty_six_reset';
+
+export interface EightySixFailure {
+  message: string;
+  code: string;
+  status: 404 | 409;
+}
+
+export const EIGHTY_SIX_SELECT = `
+  SELECT e.id, e.product_id, p.name AS product_name, to_char(e.business_date, 'YYYY-MM-DD') AS business_date,
+         e.reason, e.eighty_sixed_by, u.username AS eighty_sixed_by_username, e.eighty_sixed_at,
+         e.restored_at, e.restored_by, r.username AS restored_by_username
+  FROM eighty_six_events e
+  JOIN products p ON p.id = e.product_id
+  LEFT JOIN users u ON u.id = e.eighty_sixed_by
+  LEFT JOIN users r ON r.id = e.restored_by`;
+
+// ── EightySixProduct ────────────────────────────────────────────────────────
+// Runs in the caller's transaction. Only an available product can be 86'd,
+// so putting it back never shows a dish that was off the menu for another
+// reason.
+
+export async function eightySixProduct(
+  client: PoolClient,
+  productId: string,
+  reason: string | null,
+  userId: string | null,
+): Promise<{ ok: true; eventId: string } | { ok: false; failure: EightySixFailure }> {
+  const res = await client.query(
+    'SELECT name, is_available, eighty_sixed_at FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE',
+    [productId],
+  );
+  const product = res.rows[0];
+  if (!product) {
+    return { ok: false, failure: { message: 'Product not found', code: 'product_not_found', status: 404 } };
+  }
+  if (product.eighty_sixed_at) {
+    return { ok: false, failure: { message: `'${product.name}' is already 86'd`, code: 'already_eighty_sixed', status: 409 } };
+  }
+  if (!product.is_available) {
+    return { ok: false, failure: { message: `'${product.name}' is already off the menu`, code: 'product_not_available', status: 409 } };
+  }
+
+  await client.query(
+    'UPDATE products SET is_available = false, eighty_sixed_at = NOW(), updated_at = NOW() WHERE id = $1',
+    [productId],
+  );
+  const eventRes = await client.query(
+    `INSERT INTO eighty_six_events (product_id, business_date, reason, eighty_sixed_by)
+     VALUES ($1, $2, $3, $4)
+     RETURNING id`,
+    [productId, localClock().date, reason, userId],
+  );
+  return { ok: true, eventId: eventRes.rows[0].id };
+}
+
+// ── RestoreEightySixed ──────────────────────────────────────────────────────
+// Puts an 86'd product back on the menu. Returns false when it wasn't 86'd.
+
+export async function restoreEightySixed(client: PoolClient, productId: string, userId: string | null): Promise<boolean> {
+  const res = await client.query(
+    `UPDATE products SET is_available = true, eighty_sixed_at = NULL, updated_at = NOW()
+     WHERE id = $1 AND eighty_sixed_at IS NOT NULL
+     RETURNING id`,
+    [productId],
+  );
+  if (res.rows.length === 0) return false;
+  await closeEightySix(client, productId, userId);
+  return true;
+}
+
+/** Closes the product's open 86 event, e.g. when staff set its availability by hand. */
+export async function closeEightySix(q: Queryable, productId: string, userId: string | null): Promise<void> {
+  await q.query(
+    'UPDATE eighty_six_events SET restored_at = NOW(), restored_by = $2 WHERE product_id = $1 AND restored_at IS NULL',
+    [productId, userId],
+  );
+}
+
+// ── ResetEightySixList ──────────────────────────────────────────────────────
+// The daily rollover: every 86'd product goes back on the menu, unless it has
+// since run out of stock. Returns the number of products put back.
+
+export async function resetEightySixList(q: Queryable): Promise<number> {
+  const res = await q.query(
+    `UPDATE products SET is_available = true, eighty_sixed_at = NULL, updated_at = NOW()
+     WHERE eighty_sixed_at IS NOT NULL
+     RETURNING id`,
+  );
+  await q.query('UPDATE eighty_six_events SET restored_at = NOW() WHERE restored_at IS NULL');
+  if (res.rows.length === 0) return 0;
+
+  const productIds = res.rows.map((row) => row.id);
+  invalidateCache('menu');
+  await queueMenuSync(q, { productIds });
+  await syncStockAvailability(q, { productIds });
+  return productIds.length;
+}
+
+// ── BuildEightySixReport ────────────────────────────────────────────────────
+// How often each product was 86'd over [from, to], and for how long on
+// average, most often first.
+
+export async function buildEightySixReport(q: Queryable, from: string, to: string) {
+  const res = await q.query(
+    `SELECT e.product_id, p.name AS product_name, c.name AS category_name,
+            COUNT(*)::int AS times_eighty_sixed,
+            COUNT(DISTINCT e.business_date)::int AS days_eighty_sixed,
+            ROUND(AVG(EXTRACT(EPOCH FROM (e.restored_at - e.eighty_sixed_at)) / 60)
+                  FILTER (WHERE e.restored_at IS NOT NULL))::int AS avg_minutes_off_menu,
+            MAX(e.eighty_sixed_at) AS last_eighty_sixed_at
+     FROM eighty_six_events e
+     JOIN products p ON p.id = e.product_id
+     LEFT JOIN categories c ON c.id = p.category_id
+     WHERE e.business_date BETWEEN $1 AND $2
+     GROUP BY e.product_id, p.name, c.name
+     ORDER BY times_eighty_sixed DESC, product_name ASC`,
+    [from, to],
+  );
+  return {
+    from,
+    to,
+    total_events: res.rows.reduce((sum, row) => sum + row.times_eighty_sixed, 0),
+    products: res.rows,
+  };
+}
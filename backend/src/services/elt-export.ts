@@ -0,0 +1,240 @@
+import { gzipSync } from 'node:zlib';
+import { withTransaction } from '../db/transaction.js';
+import { env } from '../env.js';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { toCsv } from '../lib/csv.js';
+import { putObject } from '../lib/object-storage.js';
+import type { Queryable } from './pricing.js';
+
+// Analytics export (ELT). Every ELT_EXPORT_INTERVAL_MINUTES each stream
+// writes the rows changed since its watermark to object storage as gzipped
+// CSV, one file per local business date under Hive-style partitions:
+//
+//   <prefix>/<stream>/dt=2026-10-14/<stream>_<last change>_<last id>.csv.gz
+//
+// so BigQuery external tables and ClickHouse's s3() can prune by date. A
+// changed row is exported again in full; the warehouse keeps the latest
+// version per id by the stream's change column (updated_at, or created_at
+// for the append-only stock movements). Customer contact details and free
+// text notes stay out of the files.
+//
+// The watermark is the (change time, id) of the last row written, advanced
+// in the same transaction that holds a lock on it, so instances never export
+// the same batch concurrently. Files are written before the watermark
+// commits: a failed commit leaves a file that the next run writes again
+// under the same name. Rows changed in the last ELT_EXPORT_SETTLE_SECONDS
+// wait for the next run, since a transaction that started earlier may still
+// commit rows stamped before them.
+
+export const ELT_EXPORT_JOB = 'elt_export';
+
+// A run exports at most this many batches per stream; a backlog continues next run
+const MAX_BATCHES_PER_RUN = 10;
+
+// Sorts before every id, for a watermark rewound to a point in time
+const MIN_UUID = '00000000-0000-0000-0000-000000000000';
+
+interface Stream {
+  /** Rows with their columns, as a FROM item */
+  from: string;
+  /** Change time and id, the stream's sort and watermark */
+  changedAt: string;
+  id: string;
+  columns: string;
+}
+
+const STREAMS: Record<string, Stream> = {
+  orders: {
+    from: 'orders o',
+    changedAt: 'o.updated_at',
+    id: 'o.id',
+    columns: `o.id, o.order_number, o.branch_id, o.table_id, o.user_id, o.server_id, o.courier_id, o.tab_id,
+              o.order_type, o.status, o.covers, o.subtotal, o.tax_amount, o.service_charge_amount, o.discount_amount,
+              o.surcharge_amount, o.delivery_fee, o.deposit_amount, o.total_amount, o.delivery_status,
+              o.display_currency, o.exchange_rate, o.scheduled_at, o.served_at, o.completed_at, o.created_at, o.updated_at`,
+  },
+  order_items: {
+    from: 'order_items oi JOIN orders o ON o.id = oi.order_id',
+    changedAt: 'oi.updated_at',
+    id: 'oi.id',
+    columns: `oi.id, oi.order_id, o.branch_id, oi.product_id, oi.quantity, oi.weight_grams, oi.unit_price, oi.total_price,
+              oi.tax_amount, oi.service_charge_amount, oi.tax_exempt, oi.service_exempt, oi.tax_class_id, oi.tax_rate,
+              oi.status, oi.is_remake, oi.course, oi.price_schedule_id, oi.note_phrases, oi.released_at,
+              oi.created_at, oi.updated_at`,
+  },
+  payments: {
+    from: 'payments p LEFT JOIN orders o ON o.id = p.order_id',
+    changedAt: 'p.updated_at',
+    id: 'p.id',
+    columns: `p.id, p.order_id, o.branch_id, p.payment_method, p.amount, p.rounding_adjustment, p.surcharge_amount,
+              p.tip_amount, p.tip_recipient_id, p.status, p.processed_by, p.processed_at, p.refund_of, p.refund_reason,
+              p.batch_id, p.created_at, p.updated_at`,
+  },
+  // Product and ingredient stock changes; item_type says which item_id is
+  inventory_movements: {
+    from: `(
+      SELECT id, 'product' AS item_type, product_id AS item_id, branch_id, operation,
+             quantity::numeric AS quantity, previous_stock::numeric AS previous_stock, new_stock::numeric AS new_stock,
+             reason, adjusted_by, order_id, waste_log_id, created_at
+      FROM inventory_history
+      UNION ALL
+      SELECT id, 'ingredient', ingredient_id, NULL::uuid, operation, quantity, previous_stock, new_stock,
+             reason, adjusted_by, order_id, waste_log_id, created_at
+      FROM ingredient_history
+    ) m`,
+    changedAt: 'm.created_at',
+    id: 'm.id',
+    columns: `m.id, m.item_type, m.item_id, m.branch_id, m.operation, m.quantity, m.previous_stock, m.new_stock,
+              m.reason, m.adjusted_by, m.order_id, m.waste_log_id, m.created_at`,
+  },
+};
+
+export const ELT_STREAMS = Object.keys(STREAMS);
+
+export interface StreamRunResult {
+  stream: string;
+  rows: number;
+  files: number;
+  /** Another instance was exporting the stream */
+  skipped?: boolean;
+  error?: string;
+}
+
+function csvValue(value: unknown): unknown {
+  if (value instanceof Date) return value.toISOString();
+  if (value !== null && typeof value === 'object') return JSON.stringify(value);
+  return value;
+}
+
+// Everything changed after the watermark and before the settle cutoff
+function batchQuery(stream: Stream, fromWatermark: boolean): string {
+  return `SELECT ${stream.columns},
+                 (${stream.changedAt})::text AS _elt_changed_at,
+                 to_char(${stream.changedAt} AT TIME ZONE 'UTC', 'YYYYMMDD"T"HH24MISSUS"Z"') AS _elt_stamp,
+                 to_char(${stream.changedAt} AT TIME ZONE $3, 'YYYY-MM-DD') AS _elt_date
+          FROM ${stream.from}
+          WHERE ${stream.changedAt} < NOW() - make_interval(secs => $1)
+            ${fromWatermark ? `AND (${stream.changedAt}, ${stream.id}) > ($4::timestamptz, $5::uuid)` : ''}
+          ORDER BY ${stream.changedAt} ASC, ${stream.id} ASC
+          LIMIT $2`;
+}
+
+// ── ExportBatch ─────────────────────────────────────────────────────────────
+// One batch of a stream: null when another instance holds it.
+
+async function exportBatch(name: string): Promise<{ rows: number; files: number; more: boolean } | null> {
+  const stream = STREAMS[name];
+  const startedAt = new Date();
+
+  return withTransaction(async (client) => {
+    const lock = await client.query(
+      'SELECT watermark_at::text AS watermark_at, watermark_id FROM elt_watermarks WHERE stream = $1 FOR UPDATE SKIP LOCKED',
+      [name],
+    );
+    if (lock.rows.length === 0) return null;
+    const { watermark_at: watermarkAt, watermark_id: watermarkId } = lock.rows[0];
+
+    const params: unknown[] = [env.ELT_EXPORT_SETTLE_SECONDS, env.ELT_EXPORT_BATCH_ROWS, RESTAURANT_TIMEZONE];
+    if (watermarkAt) params.push(watermarkAt, watermarkId ?? MIN_UUID);
+    const res = await client.query(batchQuery(stream, !!watermarkAt), params);
+
+    if (res.rows.length === 0) {
+      await client.query(
+        "UPDATE elt_watermarks SET last_run_at = $2, last_status = 'succeeded', last_error = NULL WHERE stream = $1",
+        [name, startedAt],
+      );
+      return { rows: 0, files: 0, more: false };
+    }
+
+    const columns = res.fields.map((f) => f.name).filter((n) => !n.startsWith('_elt_'));
+    const last = res.rows[res.rows.length - 1];
+    const byDate = new Map<string, unknown[][]>();
+    for (const row of res.rows) {
+      const rows = byDate.get(row._elt_date) ?? [];
+      rows.push(columns.map((col) => csvValue(row[col])));
+      byDate.set(row._elt_date, rows);
+    }
+
+    // Named after the batch's last row, so writing a batch again replaces its files
+    const files: { key: string; date: string; rows: number }[] = [];
+    for (const [date, rows] of byDate) {
+      const key = `${env.ELT_EXPORT_PREFIX ? `${env.ELT_EXPORT_PREFIX}/` : ''}${name}/dt=${date}/${name}_${last._elt_stamp}_${String(last.id).slice(0, 8)}.csv.gz`;
+      await putObject(key, gzipSync(toCsv([columns, ...rows])), 'application/gzip');
+      files.push({ key, date, rows: rows.length });
+    }
+
+    await client.query(
+      `UPDATE elt_watermarks
+       SET watermark_at = $2::timestamptz, watermark_id = $3, rows_exported = rows_exported + $4,
+           last_run_at = $5, last_status = 'succeeded', last_error = NULL
+       WHERE stream = $1`,
+      [name, last._elt_changed_at, last.id, res.rows.length, startedAt],
+    );
+    await client.query(
+      `INSERT INTO elt_export_runs (stream, status, from_at, from_id, to_at, to_id, row_count, files, started_at)
+       VALUES ($1, 'succeeded', $2::timestamptz, $3, $4::timestamptz, $5, $6, $7, $8)`,
+      [name, watermarkAt, watermarkId, last._elt_changed_at, last.id, res.rows.length, JSON.stringify(files), startedAt],
+    );
+    return { rows: res.rows.length, files: files.length, more: res.rows.length === env.ELT_EXPORT_BATCH_ROWS };
+  });
+}
+
+// ── ExportStream ────────────────────────────────────────────────────────────
+// Exports a stream's backlog, up to MAX_BATCHES_PER_RUN batches. A failure
+// is recorded on the stream and in elt_export_runs, and the watermark stays
+// where it was.
+
+export async function exportStream(q: Queryable, name: string): Promise<StreamRunResult> {
+  const result: StreamRunResult = { stream: name, rows: 0, files: 0 };
+  const startedAt = new Date();
+  try {
+    for (let batch = 0; batch < MAX_BATCHES_PER_RUN; batch++) {
+      const exported = await exportBatch(name);
+      if (!exported) {
+        if (batch === 0) result.skipped = true;
+        break;
+      }
+      result.rows += exported.rows;
+      result.files += exported.files;
+      if (!exported.more) break;
+    }
+  } catch (err) {
+    result.error = (err as Error).message;
+    await q.query(
+      "UPDATE elt_watermarks SET last_run_at = $2, last_status = 'failed', last_error = $3 WHERE stream = $1",
+      [name, startedAt, result.error],
+    );
+    await q.query(
+      `INSERT INTO elt_export_runs (stream, status, from_at, from_id, error, started_at)
+       SELECT stream, 'failed', watermark_at, watermark_id, $2, $3 FROM elt_watermarks WHERE stream = $1`,
+      [name, result.error, startedAt],
+    );
+  }
+  return result;
+}
+
+/** Runs every stream in turn; one failing doesn't stop the others. */
+export async function runEltExport(q: Queryable): Promise<StreamRunResult[]> {
+  const results: StreamRunResult[] = [];
+  for (const name of ELT_STREAMS) {
+    results.push(await exportStream(q, name));
+  }
+  return results;
+}
+
+// ── RewindStream ────────────────────────────────────────────────────────────
+// Moves a stream's watermark back so its next runs export again everything
+// changed from `since` (a local business date) on, or everything when null.
+// Returns false for an unknown stream.
+
+export async function rewindStream(q: Queryable, name: string, since: string | null): Promise<boolean> {
+  const res = await q.query(
+    `UPDATE elt_watermarks
+     SET watermark_at = CASE WHEN $2::date IS NULL THEN NULL ELSE $2::date::timestamp AT TIME ZONE $3 END,
+         watermark_id = CASE WHEN $2::date IS NULL THEN NULL ELSE $4::uuid END
+     WHERE stream = $1
+     RETURNING stream`,
+    [name, since, RESTAURANT_TIMEZONE, MIN_UUID],
+  );
+  return res.rows.length > 0;
+}
@@ -0,0 +1,94 @@
+import type { Queryable } from './pricing.js';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { getDaySummary } from './logbook.js';
+import { checkLowStock } from './ingredient.js';
+import { emailConfigured, loadRestaurantName, queueEmail, settingRecipients } from './email.js';
+import { dailySalesSummaryEmail, lowStockDigestEmail } from './email-templates.js';
+
+// Scheduled emails for managers: yesterday's sales each morning and a digest
+// of stock to reorder. Both are skipped (not failed) when email isn't
+// configured or nobody would receive them.
+
+export const DAILY_SALES_SUMMARY_JOB = 'daily_sales_summary';
+export const LOW_STOCK_DIGEST_JOB = 'low_stock_digest';
+
+async function canSend(q: Queryable, name: string, recipients: string[]): Promise<boolean> {
+  if (recipients.length === 0) {
+    console.log(`${name} not sent: no recipients`);
+    return false;
+  }
+  if (!(await emailConfigured(q))) {
+    console.log(`${name} not sent: SMTP not configured`);
+    return false;
+  }
+  return true;
+}
+
+// ── SendDailySalesSummary ───────────────────────────────────────────────────
+
+export async function sendDailySalesSummary(q: Queryable, date: string): Promise<boolean> {
+  const recipients = await settingRecipients(q, 'daily_sales_summary_recipients', 'reports.view');
+  if (!(await canSend(q, `Sales summary for ${date}`, recipients))) return false;
+
+  const summary = await getDaySummary(q, date);
+  const paymentsRes = await q.query(
+    `SELECT payment_method, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount
+     FROM payments
+     WHERE status = 'completed' AND refund_of IS NULL AND DATE(created_at AT TIME ZONE $2) = $1
+     GROUP BY payment_method
+     ORDER BY amount DESC`,
+    [date, RESTAURANT_TIMEZONE],
+  );
+  const productsRes = await q.query(
+    `SELECT p.name, SUM(oi.quantity) AS quantity, SUM(oi.total_price) AS revenue
+     FROM order_items oi
+     JOIN orders o ON o.id = oi.order_id
+     JOIN products p ON p.id = oi.product_id
+     WHERE o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $2) = $1
+     GROUP BY p.id, p.name
+     ORDER BY quantity DESC
+     LIMIT 5`,
+    [date, RESTAURANT_TIMEZONE],
+  );
+
+  const email = dailySalesSummaryEmail(await loadRestaurantName(q), {
+    summary,
+    payments: paymentsRes.rows.map((r) => ({ method: r.payment_method, count: Number(r.count), amount: Number(r.amount) })),
+    topProducts: productsRes.rows.map((r) => ({ name: r.name, quantity: Number(r.quantity), revenue: Number(r.revenue) })),
+  });
+  await queueEmail(q, { to: recipients, template: 'daily_sales_summary', ...email });
+  return true;
+}
+
+// ── SendLowStockDigest ──────────────────────────────────────────────────────
+// Ingredients plus each branch's product stock. Nothing is sent when
+// everything is above its minimum.
+
+export async function sendLowStockDigest(q: Queryable, date: string): Promise<boolean> {
+  const ingredients = await checkLowStock();
+  const productsRes = await q.query(
+    `SELECT b.name AS branch_name, p.name, i.current_stock, i.minimum_stock
+     FROM inventory i
+     JOIN products p ON p.id = i.product_id
+     JOIN branches b ON b.id = i.branch_id
+     WHERE i.current_stock <= i.minimum_stock AND i.minimum_stock > 0 AND p.deleted_at IS NULL
+     ORDER BY b.name ASC, p.name ASC`,
+  );
+  if (ingredients.length === 0 && productsRes.rows.length === 0) return false;
+
+  const recipients = await settingRecipients(q, 'low_stock_digest_recipients', 'inventory.manage');
+  if (!(await canSend(q, `Low-stock digest for ${date}`, recipients))) return false;
+
+  const email = lowStockDigestEmail(await loadRestaurantName(q), {
+    date,
+    ingredients,
+    products: productsRes.rows.map((r) => ({
+      branch_name: r.branch_name,
+      name: r.name,
+      current_stock: Number(r.current_stock),
+      minimum_stock: Number(r.minimum_stock),
+    })),
+  });
+  await queueEmail(q, { to: recipients, template: 'low_stock_digest', ...email });
+  return true;
+}
@@ -0,0 +1,195 @@
+import type { DaySummary } from './logbook.js';
+
+// Plain-text email templates. Each returns the subject and body; layout()
+// adds the shared header and sign-off so every email reads the same.
+
+export interface RenderedEmail {
+  subject: string;
+  text: string;
+}
+
+function formatIDR(n: number): string {
+  return `Rp ${Math.round(n).toLocaleString('id-ID')}`;
+}
+
+function layout(restaurantName: string, lines: string[]): string {
+  return [restaurantName, '', ...lines, '', '—', restaurantName].join('\n');
+}
+
+function quote(text: string): string {
+  return text.split('\n').map((line) => `> ${line}`).join('\n');
+}
+
+// ── ContactReply ────────────────────────────────────────────────────────────
+
+export interface ContactReplyData {
+  name: string;
+  subject: string;
+  message: string;
+  reply: string;
+  staffName: string | null;
+}
+
+export function contactReplyEmail(restaurantName: string, data: ContactReplyData): RenderedEmail {
+  return {
+    subject: `Re: ${data.subject}`,
+    text: layout(restaurantName, [
+      `Hi ${data.name},`,
+      '',
+      data.reply.trim(),
+      '',
+      data.staffName ? `Best regards,\n${data.staffName}` : 'Best regards,',
+      '',
+      'Your message:',
+      quote(data.message),
+    ]),
+  };
+}
+
+// ── SurveyReply ─────────────────────────────────────────────────────────────
+
+export interface SurveyReplyData {
+  name: string | null;
+  orderNumber: string;
+  rating: number;
+  comments: string | null;
+  reply: string;
+  staffName: string | null;
+}
+
+export function surveyReplyEmail(restaurantName: string, data: SurveyReplyData): RenderedEmail {
+  const lines = [
+    data.name ? `Hi ${data.name},` : 'Hi,',
+    '',
+    `Thank you for your feedback on order ${data.orderNumber}.`,
+    '',
+    data.reply.trim(),
+    '',
+    data.staffName ? `Best regards,\n${data.staffName}` : 'Best regards,',
+    '',
+    `Your rating: ${data.rating} of 5`,
+  ];
+  if (data.comments) lines.push('', 'Your comments:', quote(data.comments));
+  return {
+    subject: `Your visit to ${restaurantName} (${data.orderNumber})`,
+    text: layout(restaurantName, lines),
+  };
+}
+
+// ── DailySalesSummary ───────────────────────────────────────────────────────
+
+export interface SalesSummaryData {
+  summary: DaySummary;
+  payments: Array<{ method: string; count: number; amount: number }>;
+  topProducts: Array<{ name: string; quantity: number; revenue: number }>;
+}
+
+export function dailySalesSummaryEmail(restaurantName: string, data: SalesSummaryData): RenderedEmail {
+  const { summary } = data;
+  const lines = [
+    `Sales summary for ${summary.date}`,
+    '',
+    `  Completed orders: ${summary.completed_orders}`,
+    `  Cancelled orders: ${summary.cancelled_orders}`,
+    `  Gross sales:      ${formatIDR(summary.gross_sales)}`,
+    `  Refunds:          ${formatIDR(summary.refunds)}`,
+    `  Net sales:        ${formatIDR(summary.net_sales)}`,
+  ];
+  if (summary.completed_orders > 0) {
+    lines.push(`  Average order:    ${formatIDR(summary.gross_sales / summary.completed_orders)}`);
+  }
+  if (summary.cash_rounding.payments > 0) {
+    lines.push(`  Cash rounding:    ${formatIDR(summary.cash_rounding.net)} over ${summary.cash_rounding.payments} payment(s)`);
+  }
+
+  if (data.payments.length > 0) {
+    lines.push('', 'Payments');
+    for (const p of data.payments) {
+      lines.push(`  ${p.method}: ${p.count} × ${formatIDR(p.amount)}`);
+    }
+  }
+
+  if (data.topProducts.length > 0) {
+    lines.push('', 'Best sellers');
+    data.topProducts.forEach((p, i) => {
+      lines.push(`  ${i + 1}. ${p.name} — ${p.quantity} sold, ${formatIDR(p.revenue)}`);
+    });
+  }
+
+  if (summary.completed_orders === 0 && summary.cancelled_orders === 0) {
+    lines.push('', 'No orders were closed on this day.');
+  }
+
+  return {
+    subject: `Sales ${summary.date}: ${formatIDR(summary.net_sales)} net, ${summary.completed_orders} order(s)`,
+    text: layout(restaurantName, lines),
+  };
+}
+
+// ── LowStockDigest ──────────────────────────────────────────────────────────
+
+export interface LowStockDigestData {
+  date: string;
+  ingredients: Array<{ name: string; current_stock: number; minimum_stock: number }>;
+  products: Array<{ branch_name: string; name: string; current_stock: number; minimum_stock: number }>;
+}
+
+export function lowStockDigestEmail(restaurantName: string, data: LowStockDigestData): RenderedEmail {
+  const lines = [`Stock at or below minimum on ${data.date}`];
+
+  if (data.ingredients.length > 0) {
+    lines.push('', `Ingredients (${data.ingredients.length})`);
+    for (const i of data.ingredients) {
+      lines.push(`  - ${i.name}: ${i.current_stock} (minimum ${i.minimum_stock})`);
+    }
+  }
+
+  if (data.products.length > 0) {
+    lines.push('', `Products (${data.products.length})`);
+    for (const p of data.products) {
+      lines.push(`  - ${p.branch_name}: ${p.name}: ${p.current_stock} (minimum ${p.minimum_stock})`);
+    }
+  }
+
+  const total = data.ingredients.length + data.products.length;
+  return {
+    subject: `Low stock ${data.date}: ${total} item(s) to reorder`,
+    text: layout(restaurantName, lines),
+  };
+}
+
+// ── PasswordReset ───────────────────────────────────────────────────────────
+
+export interface PasswordResetData {
+  name: string;
+  link: string;
+  expiresMinutes: number;
+}
+
+export function passwordResetEmail(restaurantName: string, data: PasswordResetData): RenderedEmail {
+  return {
+    subject: `Reset your ${restaurantName} password`,
+    text: layout(restaurantName, [
+      `Hi ${data.name},`,
+      '',
+      'Someone asked to reset the password for your account. To choose a new one, open this link:',
+      '',
+      data.link,
+      '',
+      `The link works once and expires in ${data.expiresMinutes} minutes.`,
+      'If you did not ask for this, ignore this email; your password stays the same.',
+    ]),
+  };
+}
+
+// ── SmtpTest ────────────────────────────────────────────────────────────────
+
+export function smtpTestEmail(restaurantName: string, sentBy: string): RenderedEmail {
+  return {
+    subject: `Test email from ${restaurantName}`,
+    text: layout(restaurantName, [
+      'This is a test email sent from the admin panel to check the SMTP settings.',
+      `Sent by ${sentBy} at ${new Date().toISOString()}.`,
+    ]),
+  };
+}
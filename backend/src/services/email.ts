@@ -0,0 +1,138 @@
+import { pool } from '../db/connection.js';
+import { enqueueJob, type JobAttempt } from '../lib/jobs.js';
+import { envSmtpConfig, smtpConfigured, sendMail, type SmtpConfig } from '../lib/mailer.js';
+import type { Queryable } from './pricing.js';
+
+// Email service. Every email goes through email_outbox: queueEmail records it
+// and enqueues a delivery job, so a request never waits on SMTP and a message
+// that keeps failing ends up as a failed outbox row an admin can retry.
+//
+// SMTP settings come from system_settings (category email); a blank setting
+// falls back to the matching SMTP_* environment variable.
+
+export const SEND_EMAIL_JOB = 'send_email';
+
+export const EMAIL_STATUSES = ['queued', 'sent', 'failed'];
+
+const SMTP_SETTING_KEYS = ['smtp_host', 'smtp_port', 'smtp_secure', 'smtp_user', 'smtp_password', 'smtp_from'];
+
+export interface QueueEmailInput {
+  to: string[];
+  template: string;
+  subject: string;
+  text: string;
+  relatedType?: string;
+  relatedId?: string;
+  createdBy?: string | null;
+}
+
+// ── LoadSmtpConfig ──────────────────────────────────────────────────────────
+
+export async function loadSmtpConfig(q: Queryable): Promise<SmtpConfig> {
+  const res = await q.query(
+    'SELECT setting_key, setting_value FROM system_settings WHERE setting_key = ANY($1::text[])',
+    [SMTP_SETTING_KEYS],
+  );
+  const settings = new Map<string, string>(
+    res.rows.map((r) => [r.setting_key, String(r.setting_value ?? '').trim()]),
+  );
+  const fallback = envSmtpConfig();
+  const value = (key: string) => settings.get(key) || null;
+
+  return {
+    host: value('smtp_host') ?? fallback.host,
+    port: Number(value('smtp_port')) || fallback.port,
+    secure: value('smtp_secure') !== null ? value('smtp_secure') === 'true' : fallback.secure,
+    user: value('smtp_user') ?? fallback.user,
+    password: value('smtp_password') ?? fallback.password,
+    from: value('smtp_from') ?? fallback.from,
+  };
+}
+
+export async function emailConfigured(q: Queryable): Promise<boolean> {
+  return smtpConfigured(await loadSmtpConfig(q));
+}
+
+export async function loadRestaurantName(q: Queryable): Promise<string> {
+  const res = await q.query("SELECT setting_value FROM system_settings WHERE setting_key = 'restaurant_name'");
+  return String(res.rows[0]?.setting_value ?? '').trim() || 'Restaurant';
+}
+
+// ── SettingRecipients ───────────────────────────────────────────────────────
+// A comma-separated recipients setting wins; otherwise every active user
+// whose role has the given permission.
+
+export async function settingRecipients(q: Queryable, settingKey: string, permission: string): Promise<string[]> {
+  const setting = await q.query('SELECT setting_value FROM system_settings WHERE setting_key = $1', [settingKey]);
+  const configured = String(setting.rows[0]?.setting_value ?? '')
+    .split(',')
+    .map((s) => s.trim())
+    .filter(Boolean);
+  if (configured.length > 0) return configured;
+
+  const users = await q.query(
+    `SELECT DISTINCT u.email FROM users u
+     JOIN role_permissions rp ON rp.role = u.role AND rp.permission = $1
+     WHERE u.is_active = true AND u.deleted_at IS NULL AND u.email IS NOT NULL`,
+    [permission],
+  );
+  return users.rows.map((r) => r.email);
+}
+
+// ── QueueEmail ──────────────────────────────────────────────────────────────
+// Records the email and enqueues its delivery. With a transaction's client
+// both only happen if the transaction commits.
+
+export async function queueEmail(q: Queryable, input: QueueEmailInput): Promise<string> {
+  const res = await q.query(
+    `INSERT INTO email_outbox (template, recipients, subject, body, related_type, related_id, created_by)
+     VALUES ($1, $2, $3, $4, $5, $6, $7)
+     RETURNING id`,
+    [
+      input.template,
+      input.to,
+      input.subject,
+      input.text,
+      input.relatedType ?? null,
+      input.relatedId ?? null,
+      input.createdBy ?? null,
+    ],
+  );
+  const id = res.rows[0].id;
+  await enqueueJob(q, SEND_EMAIL_JOB, { outbox_id: id });
+  return id;
+}
+
+// ── DeliverOutboxEmail ──────────────────────────────────────────────────────
+// Job handler. Each failure is recorded on the outbox row; the last allowed
+// attempt marks it failed so it shows up for a manual retry.
+
+export async function deliverOutboxEmail(payload: { outbox_id: string }, attempt: JobAttempt): Promise<void> {
+  const res = await pool.query(
+    "SELECT id, recipients, subject, body FROM email_outbox WHERE id = $1 AND status = 'queued'",
+    [payload.outbox_id],
+  );
+  const email = res.rows[0];
+  // Already sent, or removed; nothing to do
+  if (!email) return;
+
+  try {
+    await sendMail({ to: email.recipients, subject: email.subject, text: email.body }, await loadSmtpConfig(pool));
+  } catch (err) {
+    const final = attempt.attempt >= attempt.maxAttempts;
+    await pool.query(
+      `UPDATE email_outbox
+       SET attempts = attempts + 1, last_error = $2, status = $3, updated_at = NOW()
+       WHERE id = $1`,
+      [email.id, (err as Error).message, final ? 'failed' : 'queued'],
+    );
+    throw err;
+  }
+
+  await pool.query(
+    `UPDATE email_outbox
+     SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW(), updated_at = NOW()
+     WHERE id = $1`,
+    [email.id],
+  );
+}
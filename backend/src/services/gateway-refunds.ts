@@ -0,0 +1,200 @@
+import crypto from 'node:crypto';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { enqueueJob, type JobAttempt } from '../lib/jobs.js';
+import { requestRefund, type GatewayNotification } from './payment-gateway.js';
+import type { Queryable } from './pricing.js';
+
+// Refunds of payments taken through the payment gateway (payment links).
+// Approving the refund records the negative payment as pending and queues a
+// job that calls the gateway's refund API. The refund only completes once
+// the gateway confirms it, either in its response or, for channels that
+// refund asynchronously, in a refund notification; until then the pending
+// row holds the amount so it can't be refunded twice, but doesn't count
+// towards the order's payments. A refund the gateway rejects fails the
+// payment row and can be retried from the refund list.
+
+export const GATEWAY_REFUND_JOB = 'gateway_refund';
+export const GATEWAY_REFUND_STATUSES = ['queued', 'submitted', 'succeeded', 'failed'];
+
+const GATEWAY_REFUND_MAX_ATTEMPTS = 6;
+
+export interface GatewayCharge {
+  payment_link_id: string;
+  gateway_order_id: string;
+}
+
+/** The gateway charge behind a payment, if it was paid through a payment link. */
+export async function findGatewayCharge(q: Queryable, paymentId: string): Promise<GatewayCharge | null> {
+  const res = await q.query(
+    'SELECT id, gateway_reference FROM payment_links WHERE payment_id = $1',
+    [paymentId],
+  );
+  const link = res.rows[0];
+  return link ? { payment_link_id: link.id, gateway_order_id: link.gateway_reference } : null;
+}
+
+export const GATEWAY_REFUND_SELECT = `
+  SELECT r.id, r.refund_payment_id, p.refund_of AS payment_id, p.order_id, o.order_number,
+         r.payment_link_id, r.gateway_order_id, r.amount::float8 AS amount, r.reason, r.status,
+         r.attempts, r.gateway_status_code, r.last_error, r.submitted_at, r.confirmed_at,
+         r.created_at, r.updated_at
+  FROM gateway_refunds r
+  JOIN payments p ON p.id = r.refund_payment_id
+  JOIN orders o ON o.id = p.order_id`;
+
+// ── QueueGatewayRefund ──────────────────────────────────────────────────────
+// Called on the refund's transaction, so the job only exists if it commits.
+
+export async function queueGatewayRefund(
+  q: Queryable,
+  refund: { refundPaymentId: string; charge: GatewayCharge; amount: number; reason: string },
+): Promise<string> {
+  const res = await q.query(
+    `INSERT INTO gateway_refunds (refund_payment_id, payment_link_id, gateway_order_id, refund_key, amount, reason)
+     VALUES ($1, $2, $3, $4, $5, $6)
+     RETURNING id`,
+    [
+      refund.refundPaymentId, refund.charge.payment_link_id, refund.charge.gateway_order_id,
+      crypto.randomUUID(), refund.amount, refund.reason,
+    ],
+  );
+  const id = res.rows[0].id;
+  await enqueueJob(q, GATEWAY_REFUND_JOB, { gateway_refund_id: id }, { maxAttempts: GATEWAY_REFUND_MAX_ATTEMPTS });
+  return id;
+}
+
+// ── RequeueGatewayRefund ────────────────────────────────────────────────────
+// Retries a failed refund under a new key: the gateway remembers the old
+// key's rejection. The caller checks the amount is still refundable.
+
+export async function requeueGatewayRefund(q: Queryable, id: string): Promise<void> {
+  const res = await q.query(
+    `UPDATE gateway_refunds
+     SET status = 'queued', refund_key = $2, attempts = 0, last_error = NULL, gateway_status_code = NULL,
+         submitted_at = NULL, updated_at = NOW()
+     WHERE id = $1 AND status = 'failed'
+     RETURNING refund_payment_id`,
+    [id, crypto.randomUUID()],
+  );
+  if (res.rows.length === 0) return;
+  await q.query(`UPDATE payments SET status = 'pending' WHERE id = $1`, [res.rows[0].refund_payment_id]);
+  await enqueueJob(q, GATEWAY_REFUND_JOB, { gateway_refund_id: id }, { maxAttempts: GATEWAY_REFUND_MAX_ATTEMPTS });
+}
+
+// Ends a refund one way or the other. The payment row follows: completed
+// once confirmed, failed if the gateway won't refund.
+async function finishGatewayRefund(
+  q: Queryable,
+  id: string,
+  outcome: 'succeeded' | 'failed',
+  details: { statusCode?: string | null; error?: string | null } = {},
+): Promise<void> {
+  const res = await q.query(
+    `UPDATE gateway_refunds
+     SET status = $2, gateway_status_code = COALESCE($3, gateway_status_code), last_error = $4,
+         confirmed_at = CASE WHEN $2 = 'succeeded' THEN NOW() END, updated_at = NOW()
+     WHERE id = $1 AND status IN ('queued', 'submitted')
+     RETURNING refund_payment_id`,
+    [id, outcome, details.statusCode ?? null, details.error ?? null],
+  );
+  if (res.rows.length === 0) return;
+  await q.query(
+    `UPDATE payments SET status = $2, processed_at = CASE WHEN $2 = 'completed' THEN NOW() ELSE processed_at END
+     WHERE id = $1 AND status = 'pending'`,
+    [res.rows[0].refund_payment_id, outcome === 'succeeded' ? 'completed' : 'failed'],
+  );
+}
+
+// ── SubmitGatewayRefund ─────────────────────────────────────────────────────
+// Job handler. Outages are retried with the same key; the last allowed
+// attempt fails the refund for a manual retry.
+
+export async function submitGatewayRefund(payload: { gateway_refund_id: string }, attempt: JobAttempt): Promise<void> {
+  const res = await pool.query(
+    `SELECT id, gateway_order_id, refund_key, amount, reason FROM gateway_refunds
+     WHERE id = $1 AND status = 'queued'`,
+    [payload.gateway_refund_id],
+  );
+  const refund = res.rows[0];
+  if (!refund) return;
+
+  let result;
+  try {
+    result = await requestRefund(refund.gateway_order_id, {
+      refundKey: refund.refund_key,
+      amount: Number(refund.amount),
+      reason: refund.reason,
+    });
+  } catch (err) {
+    const error = (err as Error).message;
+    await pool.query(
+      'UPDATE gateway_refunds SET attempts = attempts + 1, last_error = $2, updated_at = NOW() WHERE id = $1',
+      [refund.id, error],
+    );
+    if (attempt.attempt >= attempt.maxAttempts) {
+      await withTransaction((client) => finishGatewayRefund(client, refund.id, 'failed', { error }));
+    }
+    throw err;
+  }
+
+  await withTransaction(async (client) => {
+    await client.query('UPDATE gateway_refunds SET attempts = attempts + 1, updated_at = NOW() WHERE id = $1', [refund.id]);
+    if (result.outcome === 'refunded') {
+      await finishGatewayRefund(client, refund.id, 'succeeded', { statusCode: result.status_code });
+    } else if (result.outcome === 'rejected') {
+      await finishGatewayRefund(client, refund.id, 'failed', { statusCode: result.status_code, error: result.message });
+    } else {
+      await client.query(
+        `UPDATE gateway_refunds SET status = 'submitted', gateway_status_code = $2, submitted_at = NOW(), updated_at = NOW()
+         WHERE id = $1 AND status = 'queued'`,
+        [refund.id, result.status_code],
+      );
+    }
+  });
+}
+
+// ── ConfirmGatewayRefunds ───────────────────────────────────────────────────
+// Applies a refund notification. It names the refunds by key when the
+// gateway lists them; otherwise its refund_amount (the total refunded on the
+// charge) confirms open refunds oldest first. Repeated notifications find
+// nothing left to confirm.
+
+export async function confirmGatewayRefunds(notification: GatewayNotification): Promise<number> {
+  return withTransaction(async (client) => {
+    const openRes = await client.query(
+      `SELECT id, refund_key, amount FROM gateway_refunds
+       WHERE gateway_order_id = $1 AND status IN ('queued', 'submitted')
+       ORDER BY created_at ASC
+       FOR UPDATE`,
+      [notification.order_id],
+    );
+    if (openRes.rows.length === 0) return 0;
+
+    const keys = new Set((notification.refunds ?? []).map((r) => r.refund_key).filter(Boolean));
+    let confirmed: string[];
+    if (keys.size > 0) {
+      confirmed = openRes.rows.filter((r) => keys.has(r.refund_key)).map((r) => r.id);
+    } else {
+      const doneRes = await client.query(
+        `SELECT COALESCE(SUM(amount), 0) AS amount FROM gateway_refunds
+         WHERE gateway_order_id = $1 AND status = 'succeeded'`,
+        [notification.order_id],
+      );
+      // The gateway refunds whole rupiah
+      let unconfirmed = Math.round(Number(notification.refund_amount ?? 0)) - Math.round(Number(doneRes.rows[0].amount));
+      confirmed = [];
+      for (const row of openRes.rows) {
+        const amount = Math.round(Number(row.amount));
+        if (amount > unconfirmed) break;
+        confirmed.push(row.id);
+        unconfirmed -= amount;
+      }
+    }
+
+    for (const id of confirmed) {
+      await finishGatewayRefund(client, id, 'succeeded', { statusCode: notification.status_code });
+    }
+    return confirmed.length;
+  });
+}
@@ -0,0 +1,270 @@
+import crypto from 'node:crypto';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { env } from '../env.js';
+import { enqueueJob, type JobAttempt } from '../lib/jobs.js';
+import { inboundWebhooksTotal } from '../lib/metrics.js';
+import { signWebhookPayload } from './webhooks.js';
+
+// Inbound webhooks: callbacks from WhatsApp, the delivery platforms and the
+// accounting system (the payment gateway keeps its own endpoint). Every
+// provider posts to /webhooks/inbound/<provider>. The request is checked
+// against that provider's signature over the raw body, stored once per
+// provider event ID and handed to the job queue, so the provider gets its
+// 200 straight away and a failing handler is retried with backoff. A
+// provider resending an event that is already stored only bumps its
+// duplicate_count.
+//
+// What an event does is up to the handlers registered for its provider and
+// type with registerInboundHandler; an event nobody handles is kept as
+// `ignored` and can be retried from the admin log once a handler exists.
+
+export const PROCESS_INBOUND_WEBHOOK_JOB = 'process_inbound_webhook';
+
+export const INBOUND_WEBHOOK_STATUSES = ['queued', 'processed', 'ignored', 'failed'];
+
+// Retries span about half an hour; after that the event needs a manual retry
+export const INBOUND_WEBHOOK_MAX_ATTEMPTS = 6;
+
+// Signed timestamps older than this are treated as replays
+const SIGNATURE_TOLERANCE_SECONDS = 300;
+
+export interface InboundRequest {
+  rawBody: string;
+  header(name: string): string | undefined;
+}
+
+export interface InboundEvent {
+  id: string;
+  provider: string;
+  externalId: string;
+  type: string;
+  payload: any;
+}
+
+export type InboundHandler = (event: InboundEvent, attempt: JobAttempt) => Promise<void>;
+
+interface InboundProvider {
+  /** Signing secret; callbacks are refused while it is empty */
+  secret(): string;
+  verify(req: InboundRequest, secret: string): boolean;
+  /** The provider's own event ID, when the callback carries one */
+  externalId(payload: any, req: InboundRequest): string | undefined;
+  eventType(payload: any, req: InboundRequest): string;
+  /** Answers the provider's subscription check (a GET), if it makes one */
+  challenge?(query: Record<string, string>): string | null;
+}
+
+export interface InboundFailure {
+  message: string;
+  code: string;
+  status: 400 | 403 | 404 | 503;
+}
+
+// ── Signatures ──────────────────────────────────────────────────────────────
+
+function safeEqual(a: string, b: string): boolean {
+  const x = Buffer.from(a);
+  const y = Buffer.from(b);
+  return x.length === y.length && crypto.timingSafeEqual(x, y);
+}
+
+function bodyHmac(secret: string, body: string): string {
+  return crypto.createHmac('sha256', secret).update(body).digest('hex');
+}
+
+// X-Hub-Signature-256: sha256=<hex HMAC-SHA256 of the body>, keyed with the app secret
+function verifyHubSignature(req: InboundRequest, secret: string): boolean {
+  const signature = req.header('X-Hub-Signature-256');
+  return !!signature && safeEqual(signature.toLowerCase(), `sha256=${bodyHmac(secret, req.rawBody)}`);
+}
+
+// X-Signature: <hex HMAC-SHA256 of the body>, keyed with the callback secret from the partner portal
+function verifyBodySignature(req: InboundRequest, secret: string): boolean {
+  const signature = req.header('X-Signature');
+  return !!signature && safeEqual(signature.toLowerCase(), bodyHmac(secret, req.rawBody));
+}
+
+// X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">,
+// the scheme our own outbound webhooks use
+function verifyTimestampedSignature(req: InboundRequest, secret: string): boolean {
+  const header = req.header('X-Webhook-Signature');
+  const timestamp = Number(/(?:^|,)t=(\d+)/.exec(header ?? '')?.[1]);
+  const signature = /(?:^|,)v1=([0-9a-f]+)/i.exec(header ?? '')?.[1];
+  if (!timestamp || !signature) return false;
+  if (Math.abs(Date.now() / 1000 - timestamp) > SIGNATURE_TOLERANCE_SECONDS) return false;
+  return safeEqual(`t=${timestamp},v1=${signature.toLowerCase()}`, signWebhookPayload(secret, timestamp, req.rawBody));
+}
+
+// ── Providers ───────────────────────────────────────────────────────────────
+
+function deliveryPlatform(secret: () => string): InboundProvider {
+  return {
+    secret,
+    verify: verifyBodySignature,
+    externalId: (payload, req) => payload?.event_id ?? payload?.id ?? req.header('X-Event-Id'),
+    eventType: (payload) => payload?.event_type ?? payload?.type ?? 'unknown',
+  };
+}
+
+const PROVIDERS: Record<string, InboundProvider> = {
+  whatsapp: {
+    secret: () => env.WHATSAPP_APP_SECRET,
+    verify: verifyHubSignature,
+    // Message and status callbacks have no ID of their own; the body hash dedupes them
+    externalId: () => undefined,
+    eventType: (payload) => payload?.entry?.[0]?.changes?.[0]?.field ?? 'unknown',
+    challenge: (query) => (
+      query['hub.mode'] === 'subscribe' && env.WHATSAPP_VERIFY_TOKEN !== '' && query['hub.verify_token'] === env.WHATSAPP_VERIFY_TOKEN
+        ? query['hub.challenge'] ?? ''
+        : null
+    ),
+  },
+  gofood: deliveryPlatform(() => env.GOFOOD_WEBHOOK_SECRET),
+  grabfood: deliveryPlatform(() => env.GRABFOOD_WEBHOOK_SECRET),
+  accounting: {
+    secret: () => env.ACCOUNTING_WEBHOOK_SECRET,
+    verify: verifyTimestampedSignature,
+    externalId: (payload, req) => req.header('X-Webhook-Id') ?? payload?.id,
+    eventType: (payload, req) => req.header('X-Webhook-Event') ?? payload?.type ?? 'unknown',
+  },
+};
+
+export const INBOUND_WEBHOOK_PROVIDERS = Object.keys(PROVIDERS);
+
+export function inboundProviderConfigured(provider: string): boolean {
+  return PROVIDERS[provider] !== undefined && PROVIDERS[provider].secret() !== '';
+}
+
+// ── Handlers ────────────────────────────────────────────────────────────────
+
+const handlers = new Map<string, InboundHandler[]>();
+
+/**
+ * Runs `handler` for every `type` event from `provider`; '*' matches any type.
+ * A failed attempt runs all of the event's handlers again, so they must be
+ * safe to repeat.
+ */
+export function registerInboundHandler(provider: string, type: string, handler: InboundHandler): void {
+  const key = `${provider}:${type}`;
+  handlers.set(key, [...(handlers.get(key) ?? []), handler]);
+}
+
+function handlersFor(provider: string, type: string): InboundHandler[] {
+  return [...(handlers.get(`${provider}:${type}`) ?? []), ...(handlers.get(`${provider}:*`) ?? [])];
+}
+
+// ── ReceiveInboundWebhook ───────────────────────────────────────────────────
+
+export async function receiveInboundWebhook(
+  provider: string,
+  req: InboundRequest,
+): Promise<{ ok: true; eventId: string; duplicate: boolean } | { ok: false; failure: InboundFailure }> {
+  const definition = PROVIDERS[provider];
+  if (!definition) {
+    return { ok: false, failure: { message: 'Unknown webhook provider', code: 'unknown_provider', status: 404 } };
+  }
+  const secret = definition.secret();
+  if (!secret) {
+    return { ok: false, failure: { message: `Webhooks from ${provider} are not configured`, code: 'provider_not_configured', status: 503 } };
+  }
+  if (!definition.verify(req, secret)) {
+    inboundWebhooksTotal.inc({ provider, outcome: 'rejected' });
+    return { ok: false, failure: { message: 'Invalid signature', code: 'invalid_signature', status: 403 } };
+  }
+
+  let payload: unknown;
+  try {
+    payload = JSON.parse(req.rawBody);
+  } catch {
+    return { ok: false, failure: { message: 'Invalid request body', code: 'invalid_json', status: 400 } };
+  }
+
+  const externalId = String(
+    definition.externalId(payload, req) ?? crypto.createHash('sha256').update(req.rawBody).digest('hex'),
+  ).slice(0, 255);
+  const eventType = String(definition.eventType(payload, req)).slice(0, 100);
+
+  const stored = await withTransaction(async (client) => {
+    const inserted = await client.query(
+      `INSERT INTO inbound_webhook_events (provider, external_id, event_type, payload)
+       VALUES ($1, $2, $3, $4)
+       ON CONFLICT (provider, external_id) DO NOTHING
+       RETURNING id`,
+      [provider, externalId, eventType, JSON.stringify(payload)],
+    );
+    if (inserted.rows.length > 0) {
+      const id = inserted.rows[0].id as string;
+      await enqueueJob(client, PROCESS_INBOUND_WEBHOOK_JOB, { event_id: id }, { maxAttempts: INBOUND_WEBHOOK_MAX_ATTEMPTS });
+      return { id, duplicate: false };
+    }
+
+    const existing = await client.query(
+      `UPDATE inbound_webhook_events SET duplicate_count = duplicate_count + 1, updated_at = NOW()
+       WHERE provider = $1 AND external_id = $2
+       RETURNING id`,
+      [provider, externalId],
+    );
+    return { id: existing.rows[0].id as string, duplicate: true };
+  });
+
+  inboundWebhooksTotal.inc({ provider, outcome: stored.duplicate ? 'duplicate' : 'accepted' });
+  return { ok: true, eventId: stored.id, duplicate: stored.duplicate };
+}
+
+// ── AnswerInboundChallenge ──────────────────────────────────────────────────
+// The echo for a provider's subscription check, or null to refuse it.
+
+export function answerInboundChallenge(provider: string, query: Record<string, string>): string | null {
+  return PROVIDERS[provider]?.challenge?.(query) ?? null;
+}
+
+// ── ProcessInboundWebhook ───────────────────────────────────────────────────
+// Job handler. The last allowed attempt marks the event failed for a manual
+// retry.
+
+export async function processInboundWebhook(payload: { event_id: string }, attempt: JobAttempt): Promise<void> {
+  const res = await pool.query(
+    `SELECT id, provider, external_id, event_type, payload
+     FROM inbound_webhook_events
+     WHERE id = $1 AND status = 'queued'`,
+    [payload.event_id],
+  );
+  const row = res.rows[0];
+  if (!row) return;
+
+  const eventHandlers = handlersFor(row.provider, row.event_type);
+  if (eventHandlers.length === 0) {
+    await pool.query(
+      `UPDATE inbound_webhook_events SET status = 'ignored', processed_at = NOW(), updated_at = NOW() WHERE id = $1`,
+      [row.id],
+    );
+    return;
+  }
+
+  const event: InboundEvent = {
+    id: row.id,
+    provider: row.provider,
+    externalId: row.external_id,
+    type: row.event_type,
+    payload: row.payload,
+  };
+  let error: string | null = null;
+  try {
+    for (const handler of eventHandlers) {
+      await handler(event, attempt);
+    }
+  } catch (err) {
+    error = (err as Error).message || String(err);
+  }
+
+  const final = attempt.attempt >= attempt.maxAttempts;
+  await pool.query(
+    `UPDATE inbound_webhook_events
+     SET attempts = attempts + 1, last_error = $2, status = $3,
+         processed_at = CASE WHEN $2::text IS NULL THEN NOW() END, updated_at = NOW()
+     WHERE id = $1`,
+    [row.id, error, error ? (final ? 'failed' : 'queued') : 'processed'],
+  );
+  if (error) throw new Error(error);
+}
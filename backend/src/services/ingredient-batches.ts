@@ -0,0 +1,171 @@
+import type { PoolClient } from 'pg';
+import { localClock, addDays } from '../lib/clock.js';
+import { createNotificationForRole } from './notification.js';
+import type { Queryable } from './pricing.js';
+
+// Ingredient batches (lots). Every restock is received as a batch with an
+// optional expiry date; orders and stock count losses draw from the open
+// batches first-expired-first-out, so the oldest stock is used up before it
+// goes off. ingredients.current_stock stays the figure everything else
+// reads; stock from before batches were tracked, or found by a stock take,
+// is simply not in any batch and is used once the batches run dry.
+
+export const INGREDIENT_EXPIRY_CHECK_JOB = 'ingredient_expiry_check';
+
+const DEFAULT_EXPIRY_WARNING_DAYS = 3;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+export const INGREDIENT_BATCH_SELECT = `
+  SELECT b.id, b.ingredient_id, i.name AS ingredient_name, i.unit, b.batch_code,
+         b.quantity_received::float8 AS quantity_received, b.quantity_remaining::float8 AS quantity_remaining,
+         to_char(b.expiry_date, 'YYYY-MM-DD') AS expiry_date, b.received_at, b.received_by,
+         u.username AS received_by_username, b.expiry_notified_at
+  FROM ingredient_batches b
+  JOIN ingredients i ON i.id = b.ingredient_id
+  LEFT JOIN users u ON u.id = b.received_by`;
+
+export async function loadExpiryWarningDays(q: Queryable): Promise<number> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'ingredient_expiry_warning_days'`);
+  if (res.rows.length === 0) return DEFAULT_EXPIRY_WARNING_DAYS;
+  const value = parseInt(res.rows[0].setting_value, 10);
+  return isNaN(value) || value < 0 ? DEFAULT_EXPIRY_WARNING_DAYS : value;
+}
+
+// ── CreateIngredientBatch ───────────────────────────────────────────────────
+// Records a received lot. Runs in the restock transaction.
+
+export async function createIngredientBatch(
+  client: PoolClient,
+  batch: { ingredientId: string; quantity: number; expiryDate: string | null; batchCode: string | null; userId: string | null },
+) {
+  const res = await client.query(
+    `INSERT INTO ingredient_batches (ingredient_id, batch_code, quantity_received, quantity_remaining, expiry_date, received_by)
+     VALUES ($1, $2, $3, $3, $4, $5)
+     RETURNING id, batch_code, quantity_received::float8 AS quantity_received, to_char(expiry_date, 'YYYY-MM-DD') AS expiry_date`,
+    [batch.ingredientId, batch.batchCode, batch.quantity, batch.expiryDate, batch.userId],
+  );
+  return res.rows[0];
+}
+
+// ── ConsumeIngredientBatches ────────────────────────────────────────────────
+// Takes qty from the ingredient's open batches, soonest expiry first (then
+// oldest received; batches that don't expire last). Whatever the batches
+// can't cover comes out of untracked stock. Usage is recorded against the
+// order, when there is one, so a cancellation can put it back.
+
+export async function consumeIngredientBatches(
+  client: PoolClient,
+  ingredientId: string,
+  qty: number,
+  orderId: string | null,
+): Promise<void> {
+  const res = await client.query(
+    `SELECT id, quantity_remaining FROM ingredient_batches
+     WHERE ingredient_id = $1 AND quantity_remaining > 0
+     ORDER BY expiry_date ASC NULLS LAST, received_at ASC, id ASC
+     FOR UPDATE`,
+    [ingredientId],
+  );
+
+  let left = round(qty);
+  for (const batch of res.rows) {
+    if (left <= 0) break;
+    const take = round(Math.min(left, Number(batch.quantity_remaining)));
+    await client.query(
+      'UPDATE ingredient_batches SET quantity_remaining = quantity_remaining - $2 WHERE id = $1',
+      [batch.id, take],
+    );
+    await client.query(
+      'INSERT INTO ingredient_batch_usage (batch_id, order_id, quantity) VALUES ($1, $2, $3)',
+      [batch.id, orderId, take],
+    );
+    left = round(left - take);
+  }
+}
+
+// ── ReturnIngredientBatches ─────────────────────────────────────────────────
+// Puts up to qty of an order's ingredient back into the batches the order
+// took it from, latest-expiring first. Anything beyond what the order drew
+// from batches was untracked stock and stays that way.
+
+export async function returnIngredientBatches(
+  client: PoolClient,
+  orderId: string,
+  ingredientId: string,
+  qty: number,
+): Promise<void> {
+  const res = await client.query(
+    `SELECT b.id, SUM(u.quantity) AS taken
+     FROM ingredient_batch_usage u
+     JOIN ingredient_batches b ON b.id = u.batch_id
+     WHERE u.order_id = $1 AND b.ingredient_id = $2
+     GROUP BY b.id, b.expiry_date, b.received_at
+     HAVING SUM(u.quantity) > 0
+     ORDER BY b.expiry_date DESC NULLS FIRST, b.received_at DESC, b.id DESC`,
+    [orderId, ingredientId],
+  );
+
+  let left = round(qty);
+  for (const batch of res.rows) {
+    if (left <= 0) break;
+    const give = round(Math.min(left, Number(batch.taken)));
+    await client.query(
+      'UPDATE ingredient_batches SET quantity_remaining = quantity_remaining + $2 WHERE id = $1',
+      [batch.id, give],
+    );
+    await client.query(
+      'INSERT INTO ingredient_batch_usage (batch_id, order_id, quantity) VALUES ($1, $2, $3)',
+      [batch.id, orderId, -give],
+    );
+    left = round(left - give);
+  }
+}
+
+// ── ListExpiringBatches ─────────────────────────────────────────────────────
+// Open batches of active ingredients that expire within `days` days of
+// today, already-expired ones included. Soonest first.
+
+export async function listExpiringBatches(q: Queryable, days: number) {
+  const today = localClock().date;
+  const res = await q.query(
+    `${INGREDIENT_BATCH_SELECT}
+     WHERE b.quantity_remaining > 0 AND b.expiry_date IS NOT NULL AND b.expiry_date <= $1 AND i.is_active = true
+     ORDER BY b.expiry_date ASC, i.name ASC`,
+    [addDays(today, days)],
+  );
+  return res.rows.map((row) => ({
+    ...row,
+    days_until_expiry: Math.round((Date.parse(row.expiry_date) - Date.parse(today)) / 86_400_000),
+    expired: row.expiry_date < today,
+  }));
+}
+
+// ── NotifyExpiringBatches ───────────────────────────────────────────────────
+// Warns admins and managers once per batch when it comes within the warning
+// window. Claiming the batches with the UPDATE keeps overlapping runs from
+// warning twice. Returns the number of batches flagged.
+
+export async function notifyExpiringBatches(q: Queryable): Promise<number> {
+  const days = await loadExpiryWarningDays(q);
+  const res = await q.query(
+    `UPDATE ingredient_batches b SET expiry_notified_at = NOW()
+     FROM ingredients i
+     WHERE i.id = b.ingredient_id AND i.is_active = true
+           AND b.quantity_remaining > 0 AND b.expiry_date IS NOT NULL AND b.expiry_date <= $1
+           AND b.expiry_notified_at IS NULL
+     RETURNING i.name, i.unit, b.batch_code, b.quantity_remaining::float8 AS quantity_remaining,
+               to_char(b.expiry_date, 'YYYY-MM-DD') AS expiry_date`,
+    [addDays(localClock().date, days)],
+  );
+  if (res.rows.length === 0) return 0;
+
+  const lines = res.rows
+    .sort((a, b) => a.expiry_date.localeCompare(b.expiry_date))
+    .map((row) => `${row.name}${row.batch_code ? ` (${row.batch_code})` : ''}: ${row.quantity_remaining} ${row.unit} by ${row.expiry_date}`);
+  const message = `${res.rows.length} ingredient batch(es) expire within ${days} day(s): ${lines.join('; ')}`;
+  for (const role of ['admin', 'manager']) {
+    await createNotificationForRole(role, 'low_stock', 'Ingredients Expiring Soon', message, 'ingredients_expiring');
+  }
+  return res.rows.length;
+}
@@ -1,83 +1,89 @@
 import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import { enqueueJob } from '../lib/jobs.js';
+import { emitWebhookEvent } from './webhooks.js';
 
 // ── DeductIngredientsForOrder ────────────────────────────────────────────────
 // Called when an order is created. Deducts ingredient stock based on recipes.
 
 export async function deductIngredientsForOrder(orderId: string): Promise<void> {
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
-
-    // Get order items
-    const itemsRes = await client.query(
-      `SELECT oi.product_id, oi.quantity
-       FROM order_items oi
-       WHERE oi.order_id = $1`,
-      [orderId],
-    );
-
-    for (const item of itemsRes.rows) {
-      // Get recipe (product ingredients)
-      const recipesRes = await client.query(
-        `SELECT pi.ingredient_id, pi.quantity_needed
-         FROM product_ingredients pi
-         WHERE pi.product_id = $1`,
-        [item.product_id],
+    await withTransaction(async (client) => {
+      // Get order items
+      const itemsRes = await client.query(
+        `SELECT oi.product_id, oi.quantity
+         FROM order_items oi
+         WHERE oi.order_id = $1`,
+        [orderId],
       );
 
-      for (const recipe of recipesRes.rows) {
-        const deductionAmount = Number(recipe.quantity_needed) * item.quantity;
-
-        // Get current stock
-        const stockRes = await client.query(
-          `SELECT current_stock FROM ingredients WHERE id = $1`,
-          [recipe.ingredient_id],
+      for (const item of itemsRes.rows) {
+        // Get recipe (product ingredients)
+        const recipesRes = await client.query(
+          `SELECT pi.ingredient_id, pi.quantity_needed
+           FROM product_ingredients pi
+           WHERE pi.product_id = $1`,
+          [item.product_id],
         );
 
-        if (stockRes.rows.length === 0) continue;
+        for (const recipe of recipesRes.rows) {
+          const deductionAmount = Number(recipe.quantity_needed) * item.quantity;
 
-        const currentStock = Number(stockRes.rows[0].current_stock);
-        const newStock = currentStock - deductionAmount;
+          // Get current stock
+          const stockRes = await client.query(
+            `SELECT current_stock FROM ingredients WHERE id = $1`,
+            [recipe.ingredient_id],
+          );
 
-        // Update stock
-        await client.query(
-          `UPDATE ingredients SET current_stock = $1 WHERE id = $2`,
-          [newStock, recipe.ingredient_id],
-        );
+          if (stockRes.rows.length === 0) continue;
 
-        // Record history
-        await client.query(
-          `INSERT INTO ingredient_stock_history (ingredient_id, type, quantity, previous_stock, new_stock, notes, order_id)
-           VALUES ($1, 'order_deduction', $2, $3, $4, $5, $6)`,
-          [recipe.ingredient_id, deductionAmount, currentStock, newStock, `Deducted for order ${orderId}`, orderId],
-        );
+          const currentStock = Number(stockRes.rows[0].current_stock);
+          const newStock = currentStock - deductionAmount;
 
-        // Check low stock
-        const minRes = await client.query(
-          `SELECT name, minimum_stock FROM ingredients WHERE id = $1`,
-          [recipe.ingredient_id],
-        );
+          // Update stock
+          await client.query(
+            `UPDATE ingredients SET current_stock = $1 WHERE id = $2`,
+            [newStock, recipe.ingredient_id],
+          );
+
+          // Record history
+          await client.query(
+            `INSERT INTO ingredient_stock_history (ingredient_id, type, quantity, previous_stock, new_stock, notes, order_id)
+             VALUES ($1, 'order_deduction', $2, $3, $4, $5, $6)`,
+            [recipe.ingredient_id, deductionAmount, currentStock, newStock, `Deducted for order ${orderId}`, orderId],
+          );
 
-        if (minRes.rows.length > 0 && newStock <= Number(minRes.rows[0].minimum_stock)) {
-          // Generate low stock notification
-          await generateLowStockNotification(
-            client,
-            recipe.ingredient_id,
-            minRes.rows[0].name,
-            newStock,
-            Number(minRes.rows[0].minimum_stock),
+          // Check low stock
+          const minRes = await client.query(
+            `SELECT name, minimum_stock FROM ingredients WHERE id = $1`,
+            [recipe.ingredient_id],
           );
+
+          if (minRes.rows.length > 0 && newStock <= Number(minRes.rows[0].minimum_stock)) {
+            await enqueueJob(client, LOW_STOCK_ALERT_JOB, {
+              ingredient_id: recipe.ingredient_id,
+              ingredient_name: minRes.rows[0].name,
+              current_stock: newStock,
+              minimum_stock: Number(minRes.rows[0].minimum_stock),
+            });
+
+            // Webhooks hear about each ingredient once, when it drops below the minimum
+            if (currentStock > Number(minRes.rows[0].minimum_stock)) {
+              await emitWebhookEvent(client, 'inventory.low_stock', {
+                ingredient_id: recipe.ingredient_id,
+                ingredient_name: minRes.rows[0].name,
+                current_stock: newStock,
+                minimum_stock: Number(minRes.rows[0].minimum_stock),
+                order_id: orderId,
+              });
+            }
+          }
         }
       }
-    }
-
-    await client.query('COMMIT');
+    });
   } catch (err) {
-    await client.query('ROLLBACK');
     console.error('Failed to deduct ingredients for order:', (err as Error).message);
     // Don't throw — ingredient deduction failure should not block order creation
-  } finally {
-    client.release();
   }
 }
 
@@ -85,62 +91,56 @@ export async function deductIngredientsForOrder(orderId: string): Promise<void>
 // Called when an order is cancelled. Restores ingredient stock.
 
 export async function restoreIngredientsForOrder(orderId: string): Promise<void> {
-  const client = await pool.connect();
   try {
-    await client.query('BEGIN');
-
-    // Get order items
-    const itemsRes = await client.query(
-      `SELECT oi.product_id, oi.quantity
-       FROM order_items oi
-       WHERE oi.order_id = $1`,
-      [orderId],
-    );
-
-    for (const item of itemsRes.rows) {
-      // Get recipe (product ingredients)
-      const recipesRes = await client.query(
-        `SELECT pi.ingredient_id, pi.quantity_needed
-         FROM product_ingredients pi
-         WHERE pi.product_id = $1`,
-        [item.product_id],
+    await withTransaction(async (client) => {
+      // Get order items
+      const itemsRes = await client.query(
+        `SELECT oi.product_id, oi.quantity
+         FROM order_items oi
+         WHERE oi.order_id = $1`,
+        [orderId],
       );
 
-      for (const recipe of recipesRes.rows) {
-        const restoreAmount = Number(recipe.quantity_needed) * item.quantity;
-
-        // Get current stock
-        const stockRes = await client.query(
-          `SELECT current_stock FROM ingredients WHERE id = $1`,
-          [recipe.ingredient_id],
+      for (const item of itemsRes.rows) {
+        // Get recipe (product ingredients)
+        const recipesRes = await client.query(
+          `SELECT pi.ingredient_id, pi.quantity_needed
+           FROM product_ingredients pi
+           WHERE pi.product_id = $1`,
+          [item.product_id],
         );
 
-        if (stockRes.rows.length === 0) continue;
+        for (const recipe of recipesRes.rows) {
+          const restoreAmount = Number(recipe.quantity_needed) * item.quantity;
 
-        const currentStock = Number(stockRes.rows[0].current_stock);
-        const newStock = currentStock + restoreAmount;
+          // Get current stock
+          const stockRes = await client.query(
+            `SELECT current_stock FROM ingredients WHERE id = $1`,
+            [recipe.ingredient_id],
+          );
 
-        // Update stock
-        await client.query(
-          `UPDATE ingredients SET current_stock = $1 WHERE id = $2`,
-          [newStock, recipe.ingredient_id],
-        );
+          if (stockRes.rows.length === 0) continue;
 
-        // Record history
-        await client.query(
-          `INSERT INTO ingredient_stock_history (ingredient_id, type, quantity, previous_stock, new_stock, notes, order_id)
-           VALUES ($1, 'order_cancelled', $2, $3, $4, $5, $6)`,
-          [recipe.ingredient_id, restoreAmount, currentStock, newStock, `Restored from cancelled order ${orderId}`, orderId],
-        );
-      }
-    }
+          const currentStock = Number(stockRes.rows[0].current_stock);
+          const newStock = currentStock + restoreAmount;
+
+          // Update stock
+          await client.query(
+            `UPDATE ingredients SET current_stock = $1 WHERE id = $2`,
+            [newStock, recipe.ingredient_id],
+          );
 
-    await client.query('COMMIT');
+          // Record history
+          await client.query(
+            `INSERT INTO ingredient_stock_history (ingredient_id, type, quantity, previous_stock, new_stock, notes, order_id)
+             VALUES ($1, 'order_cancelled', $2, $3, $4, $5, $6)`,
+            [recipe.ingredient_id, restoreAmount, currentStock, newStock, `Restored from cancelled order ${orderId}`, orderId],
+          );
+        }
+      }
+    });
   } catch (err) {
-    await client.query('ROLLBACK');
     console.error('Failed to restore ingredients for order:', (err as Error).message);
-  } finally {
-    client.release();
   }
 }
 
@@ -192,33 +192,34 @@ export async function getIngredientUsageReport(
   }));
 }
 
-// ── generateLowStockNotification (internal helper) ───────────────────────────
+// ── Low stock alerts ─────────────────────────────────────────────────────────
+// Queued on the deduction's transaction, so an alert only goes out for stock
+// that was actually deducted; the job notifies everyone who manages inventory.
 
-async function generateLowStockNotification(
-  // eslint-disable-next-line @typescript-eslint/no-explicit-any
-  client: any,
-  _ingredientId: string,
-  ingredientName: string,
-  currentStock: number,
-  minimumStock: number,
-): Promise<void> {
-  try {
-    // Create notification for admin and manager roles
-    const usersRes = await client.query(
-      `SELECT id FROM users WHERE role IN ('admin', 'manager') AND is_active = true`,
-    );
+export const LOW_STOCK_ALERT_JOB = 'low_stock_alert';
 
-    const message = `Low stock alert: ${ingredientName} is at ${currentStock} (minimum: ${minimumStock})`;
+interface LowStockAlert {
+  ingredient_id: string;
+  ingredient_name: string;
+  current_stock: number;
+  minimum_stock: number;
+}
 
-    for (const user of usersRes.rows) {
-      await client.query(
-        `INSERT INTO notifications (user_id, type, title, message)
-         VALUES ($1, 'low_stock', 'Low Stock Alert', $2)
-         ON CONFLICT DO NOTHING`,
-        [user.id, message],
-      );
-    }
-  } catch (err) {
-    console.error('Failed to generate low stock notification:', (err as Error).message);
+export async function sendLowStockAlert(alert: LowStockAlert): Promise<void> {
+  const usersRes = await pool.query(
+    `SELECT u.id FROM users u
+     JOIN role_permissions rp ON rp.role = u.role AND rp.permission = 'inventory.manage'
+     WHERE u.is_active = true AND u.deleted_at IS NULL`,
+  );
+
+  const message = `Low stock alert: ${alert.ingredient_name} is at ${alert.current_stock} (minimum: ${alert.minimum_stock})`;
+
+  for (const user of usersRes.rows) {
+    await pool.query(
+      `INSERT INTO notifications (user_id, type, title, message)
+       VALUES ($1, 'low_stock', 'Low Stock Alert', $2)
+       ON CONFLICT DO NOTHING`,
+      [user.id, message],
+    );
   }
 }
@@ -0,0 +1,99 @@
+import type { Queryable } from './pricing.js';
+
+// Kitchen display rules. Each station has a default color and sort priority
+// and a rule for grouping a ticket's items; a category can override the color
+// and priority and name the group its items go under. The kitchen payload
+// carries the resolved values, so screens don't hardcode which items are
+// quick (drinks) and which take long (steaks). Lower priority comes first.
+
+export const KITCHEN_GROUPINGS = ['category', 'station', 'none'] as const;
+export type KitchenGrouping = (typeof KITCHEN_GROUPINGS)[number];
+
+const COLOR_RE = /^#[0-9A-Fa-f]{6}$/;
+
+export function isKitchenGrouping(value: unknown): value is KitchenGrouping {
+  return typeof value === 'string' && (KITCHEN_GROUPINGS as readonly string[]).includes(value);
+}
+
+export function isDisplayColor(value: unknown): value is string {
+  return typeof value === 'string' && COLOR_RE.test(value);
+}
+
+// Joined onto order items, products and categories (aliased oi, p, cat) to
+// resolve each item's display values: the category's override, then its menu
+// color, then the station default
+export const KITCHEN_DISPLAY_JOIN =
+  `LEFT JOIN kitchen_station_display sd ON sd.station = COALESCE(cat.station, 'kitchen')`;
+
+export const KITCHEN_DISPLAY_COLUMNS = `
+  COALESCE(cat.kds_color, cat.color, sd.color) AS display_color,
+  COALESCE(cat.kds_priority, sd.sort_priority, 0) AS sort_priority,
+  CASE COALESCE(sd.group_items_by, 'category')
+    WHEN 'none' THEN NULL
+    WHEN 'station' THEN INITCAP(COALESCE(cat.station, 'kitchen'))
+    ELSE COALESCE(cat.kds_group, cat.name, 'Other')
+  END AS display_group`;
+
+export interface KitchenGroup {
+  name: string;
+  color: string | null;
+  sort_priority: number;
+  item_ids: string[];
+}
+
+export interface KitchenDisplayItem {
+  id: string;
+  display_color: string | null;
+  sort_priority: number;
+  display_group: string | null;
+}
+
+// ── GroupTicketItems ────────────────────────────────────────────────────────
+// Items already in display order; a group takes the color and priority of
+// its first item. Ungrouped items are left out.
+
+export function groupTicketItems(items: KitchenDisplayItem[]): KitchenGroup[] {
+  const groups = new Map<string, KitchenGroup>();
+  for (const item of items) {
+    if (item.display_group === null) continue;
+    const group = groups.get(item.display_group);
+    if (group) {
+      group.item_ids.push(item.id);
+    } else {
+      groups.set(item.display_group, {
+        name: item.display_group,
+        color: item.display_color,
+        sort_priority: item.sort_priority,
+        item_ids: [item.id],
+      });
+    }
+  }
+  return [...groups.values()];
+}
+
+// ── LoadKitchenDisplayRules ─────────────────────────────────────────────────
+
+export async function loadKitchenDisplayRules(q: Queryable) {
+  const stationRes = await q.query(
+    `SELECT station, color, sort_priority, group_items_by, updated_by, updated_at
+     FROM kitchen_station_display
+     ORDER BY sort_priority ASC, station ASC`,
+  );
+  const categoryRes = await q.query(
+    `SELECT cat.id, cat.name, cat.station, cat.color, cat.kds_color, cat.kds_priority, cat.kds_group,
+            ${KITCHEN_DISPLAY_COLUMNS}
+     FROM categories cat
+     ${KITCHEN_DISPLAY_JOIN}
+     WHERE cat.deleted_at IS NULL
+     ORDER BY sort_priority ASC, cat.sort_order ASC, cat.name ASC`,
+  );
+
+  return {
+    stations: stationRes.rows.map((row) => ({ ...row, sort_priority: Number(row.sort_priority) })),
+    categories: categoryRes.rows.map((row) => ({
+      ...row,
+      kds_priority: row.kds_priority === null ? null : Number(row.kds_priority),
+      sort_priority: Number(row.sort_priority),
+    })),
+  };
+}
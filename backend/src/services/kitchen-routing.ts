@@ -0,0 +1,64 @@
+import type { Queryable } from './pricing.js';
+import { loadBranchSetting } from './branches.js';
+
+// Station routing. Each category is prepared at a station (kitchen or bar)
+// and the kitchen display can be filtered to one station. When a branch
+// requires staff to accept customer (QR and online) orders, the order's items
+// are held — hidden from the stations — until the order is confirmed, except
+// items at an auto-released station, so drinks go to the bar straight away
+// while food waits for acceptance.
+
+export const KITCHEN_STATIONS = ['kitchen', 'bar'] as const;
+export type KitchenStation = (typeof KITCHEN_STATIONS)[number];
+
+export function isKitchenStation(value: string): value is KitchenStation {
+  return (KITCHEN_STATIONS as readonly string[]).includes(value);
+}
+
+export async function loadAcceptanceRequired(q: Queryable, branchId: string | null): Promise<boolean> {
+  return (await loadBranchSetting(q, branchId, 'customer_order_acceptance_required')) === 'true';
+}
+
+async function loadAutoReleaseStations(q: Queryable, branchId: string | null): Promise<string[]> {
+  const value = (await loadBranchSetting(q, branchId, 'auto_release_stations')) ?? '';
+  return value.split(',').map((s) => s.trim()).filter(isKitchenStation);
+}
+
+// ── HoldCustomerOrderItems ──────────────────────────────────────────────────
+// Called on the order's transaction after its items are inserted. Returns how
+// many items now wait for acceptance (0 when the branch doesn't hold orders).
+// Uncategorised products are held like kitchen items.
+
+export async function holdCustomerOrderItems(q: Queryable, orderId: string, branchId: string | null): Promise<number> {
+  if (!(await loadAcceptanceRequired(q, branchId))) return 0;
+
+  const autoStations = await loadAutoReleaseStations(q, branchId);
+  const res = await q.query(
+    `UPDATE order_items oi SET released_at = NULL
+     WHERE oi.order_id = $1
+       AND NOT COALESCE((
+         SELECT COALESCE(cat.auto_release, cat.station = ANY($2::text[]))
+         FROM products p JOIN categories cat ON cat.id = p.category_id
+         WHERE p.id = oi.product_id
+       ), false)`,
+    [orderId, autoStations],
+  );
+  return res.rowCount ?? 0;
+}
+
+// ── ReleaseHeldItems ────────────────────────────────────────────────────────
+// Sends an accepted order's held items to their stations. Items of a course
+// that hasn't been fired stay held (see services/courses.ts).
+
+export async function releaseHeldItems(q: Queryable, orderId: string): Promise<number> {
+  const res = await q.query(
+    `UPDATE order_items oi SET released_at = NOW(), updated_at = NOW()
+     WHERE oi.order_id = $1 AND oi.released_at IS NULL
+       AND NOT EXISTS (
+         SELECT 1 FROM order_courses oc
+         WHERE oc.order_id = oi.order_id AND oc.course = oi.course AND oc.fired_at IS NULL
+       )`,
+    [orderId],
+  );
+  return res.rowCount ?? 0;
+}
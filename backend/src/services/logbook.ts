@@ -0,0 +1,213 @@
+import type { Queryable } from './pricing.js';
+import { emailConfigured, queueEmail } from './email.js';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+
+// Manager log book. Entries are filed under a business day and, optionally,
+// a shift; the day view and the morning digest pair them with that day's
+// sales close so owners read notes next to the numbers they explain.
+
+export const LOGBOOK_DIGEST_JOB = 'logbook_daily_digest';
+
+export const LOGBOOK_CATEGORIES = ['shift_note', 'incident', 'maintenance'];
+export const LOGBOOK_SHIFTS = ['morning', 'afternoon', 'evening', 'night'];
+export const LOGBOOK_PRIORITIES = ['low', 'normal', 'high'];
+
+export interface DaySummary {
+  date: string;
+  completed_orders: number;
+  cancelled_orders: number;
+  gross_sales: number;
+  refunds: number;
+  net_sales: number;
+  /** Cash rounding: rounded up, rounded down (as a positive amount), and net */
+  cash_rounding: { payments: number; rounded_up: number; rounded_down: number; net: number };
+}
+
+export interface DigestResult {
+  date: string;
+  entries: number;
+  recipients: string[];
+  /** Queued in the email outbox */
+  sent: boolean;
+}
+
+export function formatLogbookEntry(row: Record<string, unknown>) {
+  return {
+    id: row.id,
+    entry_date: row.entry_date,
+    shift: row.shift,
+    category: row.category,
+    title: row.title,
+    body: row.body,
+    tags: row.tags ?? [],
+    priority: row.priority,
+    status: row.status,
+    resolved_at: row.resolved_at,
+    resolved_by: row.resolved_by,
+    created_by: row.created_by,
+    created_by_name: row.created_by_name ?? null,
+    created_at: row.created_at,
+    updated_at: row.updated_at,
+  };
+}
+
+export const LOGBOOK_SELECT = `
+  SELECT e.id, to_char(e.entry_date, 'YYYY-MM-DD') AS entry_date, e.shift, e.category, e.title, e.body,
+         e.tags, e.priority, e.status, e.resolved_at, e.resolved_by, e.created_by, e.created_at, e.updated_at,
+         TRIM(CONCAT(u.first_name, ' ', u.last_name)) AS created_by_name
+  FROM logbook_entries e
+  LEFT JOIN users u ON u.id = e.created_by`;
+
+// ── GetDaySummary ───────────────────────────────────────────────────────────
+
+export async function getDaySummary(q: Queryable, date: string): Promise<DaySummary> {
+  const res = await q.query(
+    `SELECT
+       (SELECT COUNT(*) FROM orders
+        WHERE status = 'completed' AND DATE(created_at AT TIME ZONE $2) = $1) AS completed_orders,
+       (SELECT COUNT(*) FROM orders
+        WHERE status = 'cancelled' AND DATE(created_at AT TIME ZONE $2) = $1) AS cancelled_orders,
+       (SELECT COALESCE(SUM(total_amount), 0) FROM orders
+        WHERE status = 'completed' AND DATE(created_at AT TIME ZONE $2) = $1) AS gross_sales,
+       (SELECT COALESCE(SUM(-amount), 0) FROM payments
+        WHERE refund_of IS NOT NULL AND status = 'completed' AND DATE(created_at AT TIME ZONE $2) = $1) AS refunds`,
+    [date, RESTAURANT_TIMEZONE],
+  );
+  const roundingRes = await q.query(
+    `SELECT COUNT(*) FILTER (WHERE rounding_adjustment <> 0) AS payments,
+            COALESCE(SUM(rounding_adjustment) FILTER (WHERE rounding_adjustment > 0), 0) AS rounded_up,
+            COALESCE(SUM(-rounding_adjustment) FILTER (WHERE rounding_adjustment < 0), 0) AS rounded_down
+     FROM payments
+     WHERE payment_method = 'cash' AND status = 'completed' AND DATE(created_at AT TIME ZONE $2) = $1`,
+    [date, RESTAURANT_TIMEZONE],
+  );
+
+  const row = res.rows[0];
+  const gross = Number(row.gross_sales);
+  const refunds = Number(row.refunds);
+  const rounding = roundingRes.rows[0];
+  return {
+    date,
+    completed_orders: Number(row.completed_orders),
+    cancelled_orders: Number(row.cancelled_orders),
+    gross_sales: gross,
+    refunds,
+    net_sales: gross - refunds,
+    cash_rounding: {
+      payments: Number(rounding.payments),
+      rounded_up: Number(rounding.rounded_up),
+      rounded_down: Number(rounding.rounded_down),
+      net: Number(rounding.rounded_up) - Number(rounding.rounded_down),
+    },
+  };
+}
+
+// ── DigestRecipients ────────────────────────────────────────────────────────
+// The logbook_digest_recipients setting wins; otherwise every active admin.
+
+async function digestRecipients(q: Queryable): Promise<string[]> {
+  const setting = await q.query(
+    "SELECT setting_value FROM system_settings WHERE setting_key = 'logbook_digest_recipients'",
+  );
+  const configured = String(setting.rows[0]?.setting_value ?? '')
+    .split(',')
+    .map((s) => s.trim())
+    .filter(Boolean);
+  if (configured.length > 0) return configured;
+
+  const admins = await q.query(
+    "SELECT email FROM users WHERE role = 'admin' AND is_active = true AND deleted_at IS NULL",
+  );
+  return admins.rows.map((r) => r.email);
+}
+
+const CATEGORY_TITLES: Record<string, string> = {
+  incident: 'Incidents',
+  maintenance: 'Maintenance',
+  shift_note: 'Shift notes',
+};
+
+function formatIDR(n: number): string {
+  return `Rp ${Math.round(n).toLocaleString('id-ID')}`;
+}
+
+function buildDigestText(summary: DaySummary, entries: Record<string, unknown>[], openIssues: Record<string, unknown>[]): string {
+  const out: string[] = [
+    `Log book digest for ${summary.date}`,
+    '',
+    'Day close',
+    `  Completed orders: ${summary.completed_orders}`,
+    `  Cancelled orders: ${summary.cancelled_orders}`,
+    `  Gross sales:      ${formatIDR(summary.gross_sales)}`,
+    `  Refunds:          ${formatIDR(summary.refunds)}`,
+    `  Net sales:        ${formatIDR(summary.net_sales)}`,
+  ];
+  if (summary.cash_rounding.payments > 0) {
+    out.push(`  Cash rounding:    ${formatIDR(summary.cash_rounding.net)} over ${summary.cash_rounding.payments} payment(s)`);
+  }
+
+  if (entries.length === 0) {
+    out.push('', 'No log book entries were filed for this day.');
+  }
+
+  for (const category of ['incident', 'maintenance', 'shift_note']) {
+    const group = entries.filter((e) => e.category === category);
+    if (group.length === 0) continue;
+    out.push('', `${CATEGORY_TITLES[category]} (${group.length})`);
+    for (const e of group) {
+      const flags = [e.shift, e.priority === 'high' ? 'HIGH' : null, e.status === 'resolved' ? 'resolved' : null]
+        .filter(Boolean)
+        .join(', ');
+      const tags = (e.tags as string[]).length > 0 ? ` #${(e.tags as string[]).join(' #')}` : '';
+      out.push(`- ${e.title}${flags ? ` [${flags}]` : ''}${tags}`);
+      out.push(`  ${String(e.body).replace(/\n/g, '\n  ')}`);
+      if (e.created_by_name) out.push(`  — ${e.created_by_name}`);
+    }
+  }
+
+  if (openIssues.length > 0) {
+    out.push('', `Still open from earlier days (${openIssues.length})`);
+    for (const e of openIssues) {
+      out.push(`- ${e.entry_date}: ${e.title} [${e.category}${e.priority === 'high' ? ', HIGH' : ''}]`);
+    }
+  }
+
+  return out.join('\n');
+}
+
+// ── SendLogbookDigest ───────────────────────────────────────────────────────
+// Emails one business day's entries and sales close to the owners. Skipped
+// (not failed) when SMTP isn't configured, so installs without mail still
+// get a clean scheduler history.
+
+export async function sendLogbookDigest(q: Queryable, date: string): Promise<DigestResult> {
+  const entriesRes = await q.query(
+    `${LOGBOOK_SELECT} WHERE e.entry_date = $1 ORDER BY e.created_at ASC`,
+    [date],
+  );
+  const openRes = await q.query(
+    `${LOGBOOK_SELECT}
+     WHERE e.entry_date < $1 AND e.status = 'open' AND e.category IN ('incident', 'maintenance')
+     ORDER BY e.entry_date ASC LIMIT 20`,
+    [date],
+  );
+  const summary = await getDaySummary(q, date);
+  const recipients = await digestRecipients(q);
+
+  const result: DigestResult = { date, entries: entriesRes.rows.length, recipients, sent: false };
+  if (recipients.length === 0 || !(await emailConfigured(q))) {
+    console.log(`Log book digest for ${date} not sent: ${recipients.length === 0 ? 'no recipients' : 'SMTP not configured'}`);
+    return result;
+  }
+
+  const incidents = entriesRes.rows.filter((e) => e.category === 'incident').length;
+  await queueEmail(q, {
+    to: recipients,
+    template: 'logbook_digest',
+    subject: `Log book ${date}: ${entriesRes.rows.length} entr${entriesRes.rows.length === 1 ? 'y' : 'ies'}${incidents > 0 ? `, ${incidents} incident(s)` : ''}`,
+    text: buildDigestText(summary, entriesRes.rows, openRes.rows),
+  });
+
+  result.sent = true;
+  return result;
+}
@@ -0,0 +1,43 @@
+import { env } from '../env.js';
+import type { Queryable } from './pricing.js';
+
+// Login lockout. LOGIN_MAX_FAILED_ATTEMPTS wrong passwords in a row lock the
+// account for LOGIN_LOCKOUT_MINUTES; the counter starts again after a lock,
+// a successful login or a password reset. This sits on top of the per-IP
+// login rate limit, which doesn't stop a guesser spread over many addresses.
+
+/** The time the account is locked until, or null when it isn't. */
+export function lockedUntil(user: { lockedUntil: string | null }): Date | null {
+  if (!user.lockedUntil) return null;
+  const until = new Date(user.lockedUntil);
+  return until > new Date() ? until : null;
+}
+
+// ── RecordFailedLogin ───────────────────────────────────────────────────────
+// Returns the lock's end when this failure locked the account.
+
+export async function recordFailedLogin(q: Queryable, userId: string): Promise<Date | null> {
+  const max = env.LOGIN_MAX_FAILED_ATTEMPTS;
+  if (max === 0) return null;
+
+  const res = await q.query(
+    `UPDATE users SET
+       locked_until = CASE WHEN failed_login_attempts + 1 >= $2
+                           THEN NOW() + make_interval(mins => $3) ELSE locked_until END,
+       failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2
+                                    THEN 0 ELSE failed_login_attempts + 1 END
+     WHERE id = $1
+     RETURNING failed_login_attempts, locked_until`,
+    [userId, max, env.LOGIN_LOCKOUT_MINUTES],
+  );
+  const row = res.rows[0];
+  return row && row.failed_login_attempts === 0 && row.locked_until ? new Date(row.locked_until) : null;
+}
+
+export async function clearFailedLogins(q: Queryable, userId: string): Promise<void> {
+  await q.query(
+    `UPDATE users SET failed_login_attempts = 0, locked_until = NULL
+     WHERE id = $1 AND (failed_login_attempts > 0 OR locked_until IS NOT NULL)`,
+    [userId],
+  );
+}
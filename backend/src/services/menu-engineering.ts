@@ -0,0 +1,163 @@
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition } from './branches.js';
+import { RECIPE_COSTS, unitCost, type CostSource } from './costing.js';
+import type { Queryable } from './pricing.js';
+import { ITEM_NET } from './tax.js';
+
+// Menu engineering (Kasavana–Smith). Each costed product on the menu is
+// rated on two axes over a period:
+//
+//   popularity    its share of portions sold (menu mix) against 70% of an
+//                 even share, 0.7 / number of products
+//   profitability its contribution margin per portion (net price less food
+//                 cost) against the average over every portion sold
+//
+// giving stars (popular, profitable), plowhorses (popular, not profitable),
+// puzzles (profitable, not popular) and dogs (neither). The menu is the
+// products on sale now plus any sold in the period; products that sold
+// nothing count towards the average share. Food cost comes from
+// services/costing.ts at today's ingredient prices. Products without a cost
+// can't be rated and are listed unclassified, outside the averages.
+
+export const MENU_CLASSES = ['star', 'plowhorse', 'puzzle', 'dog'];
+
+// The share of an even menu mix a product needs to count as popular
+const POPULARITY_FACTOR = 0.7;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+export interface MenuEngineeringProduct {
+  product_id: string;
+  product_name: string;
+  category_id: string | null;
+  category_name: string;
+  quantity: number;
+  net_sales: number;
+  /** Net sales per portion, or the list price when none sold */
+  average_price: number;
+  unit_cost: number | null;
+  cost_source: CostSource | null;
+  food_cost: number | null;
+  food_cost_pct: number | null;
+  contribution_margin: number | null;
+  unit_contribution_margin: number | null;
+  /** Percent of portions sold among the classified products */
+  menu_mix_pct: number | null;
+  popularity: 'high' | 'low' | null;
+  profitability: 'high' | 'low' | null;
+  classification: string | null;
+}
+
+function classify(popular: boolean, profitable: boolean): string {
+  if (popular) return profitable ? 'star' : 'plowhorse';
+  return profitable ? 'puzzle' : 'dog';
+}
+
+// ── BuildMenuEngineeringReport ──────────────────────────────────────────────
+// Completed orders created over [from, to], optionally for one category's
+// products (the usual way to compare like with like).
+
+export async function buildMenuEngineeringReport(
+  q: Queryable,
+  from: string,
+  to: string,
+  filter: { branchId: string | null; categoryId: string | null },
+) {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const soldBranch = branchCondition('o.branch_id', filter.branchId, params);
+  let categoryCondition = '';
+  if (filter.categoryId) {
+    params.push(filter.categoryId);
+    categoryCondition = ` AND p.category_id = $${params.length}`;
+  }
+
+  const res = await q.query(
+    `WITH sold AS (
+       SELECT oi.product_id, SUM(oi.quantity) AS quantity, SUM(${ITEM_NET}) AS net_sales
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       WHERE o.status = 'completed' AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2${soldBranch}
+       GROUP BY oi.product_id
+     )
+     SELECT p.id AS product_id, p.name AS product_name, p.category_id, cat.name AS category_name, p.price,
+            COALESCE(s.quantity, 0) AS quantity, COALESCE(s.net_sales, 0) AS net_sales,
+            p.cost_override, r.recipe_cost
+     FROM products p
+     LEFT JOIN sold s ON s.product_id = p.id
+     LEFT JOIN categories cat ON cat.id = p.category_id
+     LEFT JOIN (${RECIPE_COSTS}) r ON r.product_id = p.id
+     WHERE (s.product_id IS NOT NULL OR (p.deleted_at IS NULL AND p.is_available = true))${categoryCondition}
+     ORDER BY COALESCE(s.net_sales, 0) DESC, p.name ASC`,
+    params,
+  );
+
+  const products: MenuEngineeringProduct[] = res.rows.map((row) => {
+    const quantity = Number(row.quantity);
+    const netSales = round(Number(row.net_sales));
+    const averagePrice = quantity > 0 ? round(netSales / quantity) : Number(row.price);
+    const cost = unitCost(row);
+    const foodCost = cost.unit_cost !== null ? round(cost.unit_cost * quantity) : null;
+    return {
+      product_id: row.product_id,
+      product_name: row.product_name,
+      category_id: row.category_id ?? null,
+      category_name: row.category_name ?? 'Uncategorised',
+      quantity,
+      net_sales: netSales,
+      average_price: averagePrice,
+      ...cost,
+      food_cost: foodCost,
+      food_cost_pct: foodCost !== null && netSales > 0 ? Math.round((foodCost / netSales) * 1000) / 10 : null,
+      contribution_margin: foodCost !== null ? round(netSales - foodCost) : null,
+      unit_contribution_margin: cost.unit_cost !== null ? round(averagePrice - cost.unit_cost) : null,
+      menu_mix_pct: null,
+      popularity: null,
+      profitability: null,
+      classification: null,
+    };
+  });
+
+  const costed = products.filter((p) => p.unit_cost !== null);
+  const totalQuantity = costed.reduce((sum, p) => sum + p.quantity, 0);
+  const totalMargin = costed.reduce((sum, p) => sum + (p.contribution_margin ?? 0), 0);
+  const averageMargin = totalQuantity > 0 ? round(totalMargin / totalQuantity) : null;
+  const popularityThreshold = costed.length > 0 ? (POPULARITY_FACTOR / costed.length) * 100 : null;
+
+  const counts: Record<string, number> = Object.fromEntries(MENU_CLASSES.map((cls) => [cls, 0]));
+  if (totalQuantity > 0 && averageMargin !== null && popularityThreshold !== null) {
+    for (const product of costed) {
+      const mix = (product.quantity / totalQuantity) * 100;
+      const popular = mix >= popularityThreshold;
+      const profitable = product.unit_contribution_margin! >= averageMargin;
+      product.menu_mix_pct = Math.round(mix * 10) / 10;
+      product.popularity = popular ? 'high' : 'low';
+      product.profitability = profitable ? 'high' : 'low';
+      product.classification = classify(popular, profitable);
+      counts[product.classification]++;
+    }
+  }
+
+  const netSales = round(costed.reduce((sum, p) => sum + p.net_sales, 0));
+  const foodCost = round(costed.reduce((sum, p) => sum + (p.food_cost ?? 0), 0));
+  return {
+    from,
+    to,
+    branch_id: filter.branchId,
+    category_id: filter.categoryId,
+    summary: {
+      products: products.length,
+      classified_products: totalQuantity > 0 ? costed.length : 0,
+      quantity: totalQuantity,
+      net_sales: netSales,
+      food_cost: foodCost,
+      food_cost_pct: netSales > 0 ? Math.round((foodCost / netSales) * 1000) / 10 : null,
+      contribution_margin: round(totalMargin),
+      average_contribution_margin: averageMargin,
+      popularity_threshold_pct: popularityThreshold !== null ? Math.round(popularityThreshold * 10) / 10 : null,
+      classes: counts,
+      uncosted_products: products.length - costed.length,
+      uncosted_sales: round(products.filter((p) => p.unit_cost === null).reduce((sum, p) => sum + p.net_sales, 0)),
+    },
+    products,
+  };
+}
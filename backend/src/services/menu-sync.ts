@@ -0,0 +1,129 @@
+import { pool } from '../db/connection.js';
+import { enqueueJob, type JobAttempt } from '../lib/jobs.js';
+import { DELIVERY_PLATFORMS, platformConfigured, pushItemUpdate, type DeliveryPlatform } from '../lib/delivery-platforms.js';
+import type { Queryable } from './pricing.js';
+
+// Delivery platform menu sync. Products mapped to a platform item
+// (delivery_platform_items) are pushed whenever their price or availability
+// changes: queueMenuSync enqueues one job per mapping, so a platform outage
+// is retried with backoff and never blocks the product edit. A deleted
+// product is pushed as unavailable. The mapping remembers what was last
+// pushed, which the reconciliation report compares against the products.
+
+export const MENU_SYNC_JOB = 'delivery_menu_sync';
+
+// ── QueueMenuSync ───────────────────────────────────────────────────────────
+// For mapped products on configured platforms; returns the number of jobs
+// queued. With a transaction's client the jobs only exist if it commits.
+
+export async function queueMenuSync(
+  q: Queryable,
+  filter: { productIds: string[] } | { platform: DeliveryPlatform },
+): Promise<number> {
+  const candidates: readonly DeliveryPlatform[] = 'platform' in filter ? [filter.platform] : DELIVERY_PLATFORMS;
+  const platforms = candidates.filter(platformConfigured);
+  if (platforms.length === 0) return 0;
+
+  const res = await q.query(
+    `UPDATE delivery_platform_items SET sync_status = 'pending', updated_at = NOW()
+     WHERE platform = ANY($1::text[]) AND ($2::uuid[] IS NULL OR product_id = ANY($2::uuid[]))
+     RETURNING id`,
+    [platforms, 'productIds' in filter ? filter.productIds : null],
+  );
+  for (const row of res.rows) {
+    await enqueueJob(q, MENU_SYNC_JOB, { mapping_id: row.id });
+  }
+  return res.rows.length;
+}
+
+// ── SyncMenuItem ────────────────────────────────────────────────────────────
+// Job handler. Pushes the product's current state, not the state when the
+// job was queued, so jobs queued by quick successive edits all send the
+// latest price.
+
+export async function syncMenuItem(payload: { mapping_id: string }, attempt: JobAttempt): Promise<void> {
+  const res = await pool.query(
+    `SELECT m.id, m.platform, m.platform_item_id, p.price,
+            (p.is_available = true AND p.deleted_at IS NULL) AS available
+     FROM delivery_platform_items m
+     JOIN products p ON p.id = m.product_id
+     WHERE m.id = $1`,
+    [payload.mapping_id],
+  );
+  const item = res.rows[0];
+  // Mapping removed since; nothing to do
+  if (!item) return;
+
+  try {
+    await pushItemUpdate(item.platform, {
+      platformItemId: item.platform_item_id,
+      price: Number(item.price),
+      available: item.available,
+    });
+  } catch (err) {
+    const final = attempt.attempt >= attempt.maxAttempts;
+    await pool.query(
+      `UPDATE delivery_platform_items
+       SET last_error = $2, sync_status = $3, updated_at = NOW()
+       WHERE id = $1`,
+      [item.id, (err as Error).message, final ? 'failed' : 'pending'],
+    );
+    throw err;
+  }
+
+  await pool.query(
+    `UPDATE delivery_platform_items
+     SET synced_price = $2, synced_available = $3, sync_status = 'synced', last_error = NULL,
+         last_synced_at = NOW(), updated_at = NOW()
+     WHERE id = $1`,
+    [item.id, item.price, item.available],
+  );
+}
+
+// ── BuildReconciliation ─────────────────────────────────────────────────────
+// Per platform: menu products with no platform item, and mapped items whose
+// platform copy differs from the product or whose last push failed.
+
+export async function buildReconciliation(q: Queryable, platforms: readonly DeliveryPlatform[]) {
+  const report = [];
+  for (const platform of platforms) {
+    const unmapped = await q.query(
+      `SELECT p.id, p.name, p.sku, p.price, p.is_available, c.name AS category_name
+       FROM products p
+       LEFT JOIN categories c ON c.id = p.category_id
+       WHERE p.deleted_at IS NULL
+         AND NOT EXISTS (SELECT 1 FROM delivery_platform_items m WHERE m.product_id = p.id AND m.platform = $1)
+       ORDER BY c.name ASC NULLS LAST, p.name ASC`,
+      [platform],
+    );
+    const outOfSync = await q.query(
+      `SELECT m.id, m.product_id, p.name AS product_name, m.platform_item_id,
+              p.price, m.synced_price,
+              (p.is_available = true AND p.deleted_at IS NULL) AS available, m.synced_available,
+              m.sync_status, m.last_error, m.last_synced_at
+       FROM delivery_platform_items m
+       JOIN products p ON p.id = m.product_id
+       WHERE m.platform = $1
+         AND (m.sync_status <> 'synced'
+              OR m.synced_price IS DISTINCT FROM p.price
+              OR m.synced_available IS DISTINCT FROM (p.is_available = true AND p.deleted_at IS NULL))
+       ORDER BY p.name ASC`,
+      [platform],
+    );
+    const counts = await q.query(
+      `SELECT COUNT(*) AS mapped FROM delivery_platform_items WHERE platform = $1`,
+      [platform],
+    );
+
+    report.push({
+      platform,
+      configured: platformConfigured(platform),
+      mapped_count: Number(counts.rows[0].mapped),
+      unmapped_count: unmapped.rows.length,
+      out_of_sync_count: outOfSync.rows.length,
+      unmapped: unmapped.rows,
+      out_of_sync: outOfSync.rows,
+    });
+  }
+  return report;
+}
@@ -0,0 +1,121 @@
+import type { Queryable } from './pricing.js';
+
+// Kitchen note quick phrases: the instructions servers and guests keep
+// typing ("no onion", "extra spicy"), managed by admins and offered as
+// one-tap choices. A phrase can be limited to one category (doneness only
+// for steaks) and kept off the customer app. An order item stores the codes
+// it was given in note_phrases, next to any free-text special_instructions,
+// so the kitchen display can show the current label and filter on the code.
+//
+// Phrases used on an order item can't be deleted, only switched off, so a
+// ticket never refers to a phrase that is gone.
+//
+// A phrase's kitchen_flag changes how the kitchen display shows the items
+// given it: allergy items get an alert and mark their ticket, attention
+// items are highlighted. A flag change applies to tickets already open.
+
+export const NOTE_PHRASE_CODE_RE = /^[a-z][a-z0-9_]{1,39}$/;
+export const MAX_NOTE_PHRASE_LABEL = 60;
+export const MAX_ITEM_NOTE_PHRASES = 10;
+export const KITCHEN_FLAGS = ['allergy', 'attention'];
+
+export interface NotePhrase {
+  code: string;
+  label: string;
+  category_id: string | null;
+  category_name: string | null;
+  customer_visible: boolean;
+  kitchen_flag: string | null;
+  is_active: boolean;
+  sort_order: number;
+}
+
+export const NOTE_PHRASE_SELECT = `
+  SELECT np.code, np.label, np.category_id, cat.name AS category_name, np.customer_visible,
+         np.kitchen_flag, np.is_active, np.sort_order, np.created_at, np.updated_at
+  FROM kitchen_note_phrases np
+  LEFT JOIN categories cat ON cat.id = np.category_id`;
+
+/** The phrase list's order; phrases for every category come first. */
+const ORDER_BY = 'ORDER BY np.category_id NULLS FIRST, np.sort_order ASC, np.label ASC';
+
+// A category filter includes the phrases that apply to every category
+export async function loadNotePhrases(
+  q: Queryable,
+  filter: { activeOnly?: boolean; customerOnly?: boolean; categoryId?: string | null } = {},
+): Promise<NotePhrase[]> {
+  const conditions: string[] = [];
+  const params: string[] = [];
+  if (filter.activeOnly) conditions.push('np.is_active = true');
+  if (filter.customerOnly) conditions.push('np.customer_visible = true');
+  if (filter.categoryId) {
+    params.push(filter.categoryId);
+    conditions.push(`(np.category_id IS NULL OR np.category_id = $${params.length})`);
+  }
+  const where = conditions.length > 0 ? ` WHERE ${conditions.join(' AND ')}` : '';
+  const res = await q.query(`${NOTE_PHRASE_SELECT}${where} ${ORDER_BY}`, params);
+  return res.rows;
+}
+
+/**
+ * The phrase codes sent with an order item, de-duplicated; null when the
+ * value isn't a list of codes. A missing value is no phrases.
+ */
+export function parseNotePhrases(value: unknown): string[] | null {
+  if (value === undefined || value === null) return [];
+  if (!Array.isArray(value) || value.length > MAX_ITEM_NOTE_PHRASES) return null;
+  if (!value.every((code) => typeof code === 'string' && NOTE_PHRASE_CODE_RE.test(code))) return null;
+  return [...new Set(value as string[])];
+}
+
+/** Replaces each item's note_phrases with its parsed codes; false if one isn't a list of codes. */
+export function normalizeItemNotePhrases(items: { note_phrases?: string[] }[]): boolean {
+  for (const item of items) {
+    const codes = parseNotePhrases(item.note_phrases);
+    if (!codes) return false;
+    item.note_phrases = codes;
+  }
+  return true;
+}
+
+/**
+ * The first of an item's phrases it can't be given: unknown, switched off,
+ * limited to another category or, for guests, kept off the customer app.
+ * Null when all of them are fine.
+ */
+export async function findUnusableNotePhrase(
+  q: Queryable,
+  codes: string[],
+  categoryId: string | null,
+  opts: { customer?: boolean } = {},
+): Promise<string | null> {
+  if (codes.length === 0) return null;
+  const res = await q.query(
+    `SELECT code FROM kitchen_note_phrases
+     WHERE code = ANY($1::text[]) AND is_active = true
+       AND (category_id IS NULL OR category_id = $2)
+       AND ($3 = false OR customer_visible = true)`,
+    [codes, categoryId, opts.customer === true],
+  );
+  const usable = new Set(res.rows.map((row) => row.code as string));
+  return codes.find((code) => !usable.has(code)) ?? null;
+}
+
+/**
+ * SQL for the labels of an order item's phrases, in the order they were
+ * picked; `column` is the item's note_phrases column.
+ */
+export function notePhraseLabelsSQL(column: string): string {
+  return `ARRAY(SELECT COALESCE(np.label, u.code)
+                FROM unnest(${column}) WITH ORDINALITY AS u(code, ord)
+                LEFT JOIN kitchen_note_phrases np ON np.code = u.code
+                ORDER BY u.ord)`;
+}
+
+/** SQL for the distinct kitchen flags of an order item's phrases, like notePhraseLabelsSQL. */
+export function notePhraseFlagsSQL(column: string): string {
+  return `ARRAY(SELECT DISTINCT np.kitchen_flag
+                FROM kitchen_note_phrases np
+                WHERE np.code = ANY(${column}) AND np.kitchen_flag IS NOT NULL
+                ORDER BY np.kitchen_flag)`;
+}
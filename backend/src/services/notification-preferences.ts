@@ -0,0 +1,196 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+
+// Notification preference defaults per role. A user's preferences are
+// created from their role's defaults the first time they're needed (or the
+// built-in defaults below when the role has none), and an admin can apply a
+// role's defaults to everyone already in the role.
+//
+// Preferences a user changes themselves are recorded in overridden_fields:
+// 'email_enabled', 'quiet_hours' or a notification type. Applying role
+// defaults leaves those alone unless the admin forces it, which also clears
+// the override so later applies reach the user again.
+
+export const NOTIFICATION_TYPES = ['order_update', 'low_stock', 'payment', 'system_alert', 'daily_report'] as const;
+export type NotificationType = (typeof NOTIFICATION_TYPES)[number];
+
+export type PreferenceField = 'email_enabled' | 'quiet_hours' | NotificationType;
+export const PREFERENCE_FIELDS: readonly PreferenceField[] = ['email_enabled', 'quiet_hours', ...NOTIFICATION_TYPES];
+
+export function isPreferenceField(value: unknown): value is PreferenceField {
+  return typeof value === 'string' && (PREFERENCE_FIELDS as readonly string[]).includes(value);
+}
+
+export interface PreferenceValues {
+  email_enabled: boolean;
+  types_enabled: Record<NotificationType, boolean>;
+  quiet_hours_start: string | null;
+  quiet_hours_end: string | null;
+}
+
+export const BUILT_IN_PREFERENCES: PreferenceValues = {
+  email_enabled: true,
+  types_enabled: { order_update: true, low_stock: true, payment: true, system_alert: true, daily_report: true },
+  quiet_hours_start: null,
+  quiet_hours_end: null,
+};
+
+/**
+ * types_enabled as a flag per type. Older rows hold a JSON string or a list
+ * of the enabled types; a type that isn't mentioned is enabled.
+ */
+export function normalizeTypes(value: unknown): Record<NotificationType, boolean> {
+  let parsed = value;
+  if (typeof parsed === 'string') {
+    try {
+      parsed = JSON.parse(parsed);
+    } catch {
+      parsed = null;
+    }
+  }
+  const types = { ...BUILT_IN_PREFERENCES.types_enabled };
+  if (Array.isArray(parsed)) {
+    for (const type of NOTIFICATION_TYPES) types[type] = parsed.includes(type);
+  } else if (parsed && typeof parsed === 'object') {
+    for (const type of NOTIFICATION_TYPES) {
+      const flag = (parsed as Record<string, unknown>)[type];
+      if (typeof flag === 'boolean') types[type] = flag;
+    }
+  }
+  return types;
+}
+
+// TIME columns come back as HH:MM:SS; requests send HH:MM
+function normalizeTime(value: unknown): string | null {
+  return typeof value === 'string' && value ? value.slice(0, 5) : null;
+}
+
+export function preferenceValues(row: Record<string, unknown>): PreferenceValues {
+  return {
+    email_enabled: row.email_enabled !== false,
+    types_enabled: normalizeTypes(row.types_enabled),
+    quiet_hours_start: normalizeTime(row.quiet_hours_start),
+    quiet_hours_end: normalizeTime(row.quiet_hours_end),
+  };
+}
+
+/** The fields that differ between two sets of preferences. */
+export function changedFields(before: PreferenceValues, after: PreferenceValues): PreferenceField[] {
+  const changed: PreferenceField[] = [];
+  if (before.email_enabled !== after.email_enabled) changed.push('email_enabled');
+  if (before.quiet_hours_start !== after.quiet_hours_start || before.quiet_hours_end !== after.quiet_hours_end) {
+    changed.push('quiet_hours');
+  }
+  for (const type of NOTIFICATION_TYPES) {
+    if (before.types_enabled[type] !== after.types_enabled[type]) changed.push(type);
+  }
+  return changed;
+}
+
+// ── LoadRoleDefaults ────────────────────────────────────────────────────────
+
+export async function loadRoleDefaults(q: Queryable, role: string): Promise<PreferenceValues & { customized: boolean }> {
+  const res = await q.query(
+    `SELECT email_enabled, types_enabled, quiet_hours_start, quiet_hours_end
+     FROM notification_role_defaults WHERE role = $1`,
+    [role],
+  );
+  if (res.rows.length === 0) return { ...BUILT_IN_PREFERENCES, customized: false };
+  return { ...preferenceValues(res.rows[0]), customized: true };
+}
+
+// ── EnsurePreferences ───────────────────────────────────────────────────────
+// Creates the user's preferences from their role's defaults if they have none.
+
+export async function ensurePreferences(q: Queryable, userId: string): Promise<void> {
+  await q.query(
+    `INSERT INTO notification_preferences (user_id, email_enabled, types_enabled, quiet_hours_start, quiet_hours_end)
+     SELECT u.id, COALESCE(d.email_enabled, true), COALESCE(d.types_enabled, $2::jsonb), d.quiet_hours_start, d.quiet_hours_end
+     FROM users u
+     LEFT JOIN notification_role_defaults d ON d.role = u.role
+     WHERE u.id = $1
+     ON CONFLICT (user_id) DO NOTHING`,
+    [userId, JSON.stringify(BUILT_IN_PREFERENCES.types_enabled)],
+  );
+}
+
+// ── ApplyRoleDefaults ───────────────────────────────────────────────────────
+// Copies the role's defaults for `fields` onto every active user in the
+// role. Fields a user overrode are kept unless `overridePersonal` is set.
+
+export interface ApplyRoleDefaultsResult {
+  role: string;
+  users: number;
+  created: number;
+  updated: number;
+  unchanged: number;
+  /** Users who kept some of their own preferences, and which */
+  kept_overrides: { user_id: string; username: string; fields: PreferenceField[] }[];
+}
+
+export async function applyRoleDefaults(
+  client: PoolClient,
+  role: string,
+  fields: readonly PreferenceField[],
+  overridePersonal: boolean,
+): Promise<ApplyRoleDefaultsResult> {
+  const defaults = await loadRoleDefaults(client, role);
+  const usersRes = await client.query(
+    `SELECT u.id, u.username, np.id AS preferences_id, np.email_enabled, np.types_enabled,
+            np.quiet_hours_start, np.quiet_hours_end, np.overridden_fields
+     FROM users u
+     LEFT JOIN notification_preferences np ON np.user_id = u.id
+     WHERE u.role = $1 AND u.deleted_at IS NULL
+     ORDER BY u.username`,
+    [role],
+  );
+
+  const result: ApplyRoleDefaultsResult = {
+    role, users: usersRes.rows.length, created: 0, updated: 0, unchanged: 0, kept_overrides: [],
+  };
+  for (const user of usersRes.rows) {
+    if (!user.preferences_id) {
+      await ensurePreferences(client, user.id);
+      result.created++;
+      continue;
+    }
+
+    const current = preferenceValues(user);
+    const next: PreferenceValues = { ...current, types_enabled: { ...current.types_enabled } };
+    const overridden: string[] = user.overridden_fields ?? [];
+    const kept: PreferenceField[] = [];
+    for (const field of fields) {
+      if (overridden.includes(field) && !overridePersonal) {
+        kept.push(field);
+        continue;
+      }
+      if (field === 'email_enabled') {
+        next.email_enabled = defaults.email_enabled;
+      } else if (field === 'quiet_hours') {
+        next.quiet_hours_start = defaults.quiet_hours_start;
+        next.quiet_hours_end = defaults.quiet_hours_end;
+      } else {
+        next.types_enabled[field] = defaults.types_enabled[field];
+      }
+    }
+    if (kept.length > 0) result.kept_overrides.push({ user_id: user.id, username: user.username, fields: kept });
+
+    const remaining = overridePersonal ? overridden.filter((f) => !fields.includes(f as PreferenceField)) : overridden;
+    if (changedFields(current, next).length === 0 && remaining.length === overridden.length) {
+      result.unchanged++;
+      continue;
+    }
+    await client.query(
+      `UPDATE notification_preferences
+       SET email_enabled = $2, types_enabled = $3, quiet_hours_start = $4, quiet_hours_end = $5,
+           overridden_fields = $6, updated_at = NOW()
+       WHERE id = $1`,
+      [
+        user.preferences_id, next.email_enabled, JSON.stringify(next.types_enabled),
+        next.quiet_hours_start, next.quiet_hours_end, remaining,
+      ],
+    );
+    result.updated++;
+  }
+  return result;
+}
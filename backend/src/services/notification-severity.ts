@@ -0,0 +1,128 @@
+import type { Queryable } from './pricing.js';
+
+// Notification severity. Every notification is raised for an event (a
+// remake, a missed SLA, a lost card hold) and carries the event's severity,
+// so POS and kitchen screens can pick a sound and how prominently to show
+// it instead of guessing from the title. Each event has a default below,
+// optionally a different one for a role; admins override either per event
+// or per event and role in notification_severity_rules, where role '' means
+// every role. Resolution: the role's rule, the event's rule, the role
+// default here, the event default here.
+
+export const NOTIFICATION_SEVERITIES = ['info', 'warning', 'critical'] as const;
+export type NotificationSeverity = (typeof NOTIFICATION_SEVERITIES)[number];
+
+export function isNotificationSeverity(value: unknown): value is NotificationSeverity {
+  return typeof value === 'string' && (NOTIFICATION_SEVERITIES as readonly string[]).includes(value);
+}
+
+interface NotificationEvent {
+  /** The preference type the event is filtered by */
+  type: string;
+  description: string;
+  severity: NotificationSeverity;
+  roles?: Record<string, NotificationSeverity>;
+}
+
+export const NOTIFICATION_EVENTS: Record<string, NotificationEvent> = {
+  order_created: {
+    type: 'order_update',
+    description: 'A new order was placed',
+    severity: 'info',
+    roles: { kitchen: 'warning' },
+  },
+  order_items_added: { type: 'order_update', description: 'Items were added to an open order', severity: 'info' },
+  course_fired: {
+    type: 'order_update',
+    description: 'A held course was fired to the kitchen',
+    severity: 'info',
+    roles: { kitchen: 'warning' },
+  },
+  order_remake: {
+    type: 'order_update',
+    description: 'An item has to be made again',
+    severity: 'warning',
+    roles: { kitchen: 'critical' },
+  },
+  order_awaiting_acceptance: {
+    type: 'order_update',
+    description: 'A customer order is waiting to be accepted',
+    severity: 'warning',
+  },
+  order_flagged: { type: 'order_update', description: 'An order from a flagged customer went to the kitchen', severity: 'warning' },
+  order_sla_missed: { type: 'order_update', description: 'An order missed its service time target', severity: 'warning' },
+  scheduled_order_released: { type: 'order_update', description: 'A scheduled order was released to the kitchen', severity: 'info' },
+  parked_order_expired: { type: 'order_update', description: 'A parked order expired', severity: 'info' },
+  delivery_assigned: { type: 'order_update', description: 'A delivery was assigned to a courier', severity: 'info' },
+  payment_link_paid: { type: 'order_update', description: 'An order was paid through a payment link', severity: 'info' },
+  payment_link_overpaid: {
+    type: 'system_alert',
+    description: 'A payment link was paid after the order was settled',
+    severity: 'critical',
+  },
+  tab_opened: { type: 'order_update', description: 'A card authorization opened a tab', severity: 'info' },
+  tab_authorization_lost: { type: 'system_alert', description: 'An open tab lost its card hold', severity: 'critical' },
+  tab_overcharged: { type: 'system_alert', description: 'A tab owed more than its card hold covered', severity: 'critical' },
+  low_stock: { type: 'low_stock', description: 'An ingredient fell below its minimum stock', severity: 'warning' },
+  ingredients_expiring: { type: 'low_stock', description: 'Ingredient batches are about to expire', severity: 'warning' },
+  menu_sold_out: { type: 'low_stock', description: 'Menu items sold out', severity: 'warning' },
+  menu_back_in_stock: { type: 'low_stock', description: 'Sold-out menu items are available again', severity: 'info' },
+  customer_flagged: { type: 'system_alert', description: 'A flagged customer placed an order or booking', severity: 'warning' },
+  reservation_no_show: { type: 'system_alert', description: 'A reservation did not arrive', severity: 'info' },
+  system_alert: { type: 'system_alert', description: 'Other system alerts', severity: 'warning' },
+};
+
+// ── ResolveSeverity ─────────────────────────────────────────────────────────
+
+export async function resolveSeverity(q: Queryable, event: string, role: string): Promise<NotificationSeverity> {
+  const res = await q.query(
+    `SELECT severity FROM notification_severity_rules
+     WHERE event = $1 AND role IN ($2, '')
+     ORDER BY role DESC
+     LIMIT 1`,
+    [event, role],
+  );
+  if (res.rows.length > 0) return res.rows[0].severity;
+
+  const defaults = NOTIFICATION_EVENTS[event];
+  return defaults?.roles?.[role] ?? defaults?.severity ?? 'info';
+}
+
+// ── LoadNotificationSeverities ──────────────────────────────────────────────
+// Every event with its defaults and admin overrides. `severity` and
+// `roles` are what apply; `default_severity` and `default_roles` are what a
+// reset goes back to.
+
+export async function loadNotificationSeverities(q: Queryable) {
+  const res = await q.query(
+    `SELECT event, role, severity, updated_by, updated_at
+     FROM notification_severity_rules
+     ORDER BY event ASC, role ASC`,
+  );
+  const rules = new Map<string, Map<string, NotificationSeverity>>();
+  for (const row of res.rows) {
+    if (!rules.has(row.event)) rules.set(row.event, new Map());
+    rules.get(row.event)!.set(row.role, row.severity);
+  }
+
+  return Object.entries(NOTIFICATION_EVENTS).map(([event, defaults]) => {
+    const overrides = rules.get(event) ?? new Map<string, NotificationSeverity>();
+    const roles: Record<string, NotificationSeverity> = {};
+    for (const role of Object.keys(defaults.roles ?? {})) {
+      roles[role] = overrides.get(role) ?? overrides.get('') ?? defaults.roles![role];
+    }
+    for (const [role, severity] of overrides) {
+      if (role !== '') roles[role] = severity;
+    }
+    return {
+      event,
+      type: defaults.type,
+      description: defaults.description,
+      severity: overrides.get('') ?? defaults.severity,
+      roles,
+      default_severity: defaults.severity,
+      default_roles: defaults.roles ?? {},
+      overridden: overrides.size > 0,
+    };
+  });
+}
@@ -1,13 +1,17 @@
 import { pool } from '../db/connection.js';
+import { resolveSeverity } from './notification-severity.js';
+import { normalizeTypes, type NotificationType } from './notification-preferences.js';
 
 // ── CreateNotification ───────────────────────────────────────────────────────
 // Creates a notification for a specific user, respecting preferences and quiet hours.
+// `event` picks the severity (see notification-severity.ts); it defaults to the type.
 
 export async function createNotification(
   userId: string,
   type: string,
   title: string,
   message: string,
+  event: string = type,
 ): Promise<void> {
   try {
     // Check quiet hours
@@ -16,10 +20,13 @@ export async function createNotification(
     // Check if user has this type enabled
     if (!(await isTypeEnabled(userId, type))) return;
 
+    const userRes = await pool.query('SELECT role FROM users WHERE id = $1', [userId]);
+    const severity = await resolveSeverity(pool, event, userRes.rows[0]?.role ?? '');
+
     await pool.query(
-      `INSERT INTO notifications (user_id, type, title, message)
-       VALUES ($1, $2, $3, $4)`,
-      [userId, type, title, message],
+      `INSERT INTO notifications (user_id, type, event, severity, title, message)
+       VALUES ($1, $2, $3, $4, $5, $6)`,
+      [userId, type, event, severity, title, message],
     );
   } catch (err) {
     console.error('Failed to create notification:', (err as Error).message);
@@ -34,20 +41,23 @@ export async function createNotificationForRole(
   type: string,
   title: string,
   message: string,
+  event: string = type,
 ): Promise<void> {
   try {
     const usersRes = await pool.query(
-      `SELECT id FROM users WHERE role = $1 AND is_active = true`,
+      `SELECT id FROM users WHERE role = $1 AND is_active = true AND deleted_at IS NULL`,
       [role],
     );
 
     const filteredUsers = await filterUsersByPreferences(usersRes.rows, type);
+    if (filteredUsers.length === 0) return;
+    const severity = await resolveSeverity(pool, event, role);
 
     for (const user of filteredUsers) {
       await pool.query(
-        `INSERT INTO notifications (user_id, type, title, message)
-         VALUES ($1, $2, $3, $4)`,
-        [user.id, type, title, message],
+        `INSERT INTO notifications (user_id, type, event, severity, title, message)
+         VALUES ($1, $2, $3, $4, $5, $6)`,
+        [user.id, type, event, severity, title, message],
       );
     }
   } catch (err) {
@@ -66,7 +76,7 @@ export async function notifyLowStock(
 
   // Notify admins and managers
   for (const role of ['admin', 'manager']) {
-    await createNotificationForRole(role, 'low_stock', 'Low Stock Alert', message);
+    await createNotificationForRole(role, 'low_stock', 'Low Stock Alert', message, 'low_stock');
   }
 }
 
@@ -79,10 +89,51 @@ export async function notifyOrderCreated(
   const message = `New ${orderType.replace('_', ' ')} order: ${orderNumber}`;
 
   // Notify kitchen staff
-  await createNotificationForRole('kitchen', 'order_update', 'New Order', message);
+  await createNotificationForRole('kitchen', 'order_update', 'New Order', message, 'order_created');
 
   // Notify counters
-  await createNotificationForRole('counter', 'order_update', 'New Order', message);
+  await createNotificationForRole('counter', 'order_update', 'New Order', message, 'order_created');
+}
+
+// ── NotifyOrderItemsAdded ────────────────────────────────────────────────────
+
+export async function notifyOrderItemsAdded(
+  orderNumber: string,
+  tableNumber: string | null,
+  items: { name: string; quantity: number }[],
+): Promise<void> {
+  const where = tableNumber ? ` (table ${tableNumber})` : '';
+  const list = items.map((i) => `${i.quantity}x ${i.name}`).join(', ');
+  const message = `Added to order ${orderNumber}${where}: ${list}`;
+
+  await createNotificationForRole('kitchen', 'order_update', 'Items Added', message, 'order_items_added');
+}
+
+// ── NotifyCourseFired ────────────────────────────────────────────────────────
+
+export async function notifyCourseFired(
+  orderNumber: string,
+  tableNumber: string | null,
+  course: number,
+  itemCount: number,
+): Promise<void> {
+  const where = tableNumber ? ` (table ${tableNumber})` : '';
+  const message = `Course ${course} fired for order ${orderNumber}${where}: ${itemCount} item${itemCount === 1 ? '' : 's'}`;
+
+  await createNotificationForRole('kitchen', 'order_update', 'Course Fired', message, 'course_fired');
+}
+
+// ── NotifyOrderItemRemake ────────────────────────────────────────────────────
+
+export async function notifyOrderItemRemake(
+  orderNumber: string,
+  tableNumber: string | null,
+  item: { name: string; quantity: number; reason: string },
+): Promise<void> {
+  const where = tableNumber ? ` (table ${tableNumber})` : '';
+  const message = `Remake for order ${orderNumber}${where}: ${item.quantity}x ${item.name} - ${item.reason}`;
+
+  await createNotificationForRole('kitchen', 'order_update', 'Remake Requested', message, 'order_remake');
 }
 
 // ── NotifySystemAlert ────────────────────────────────────────────────────────
@@ -98,10 +149,14 @@ export async function notifySystemAlert(
 
 async function isQuietHours(userId: string): Promise<boolean> {
   try {
+    // Users who never opened their preferences get their role's defaults
     const res = await pool.query(
-      `SELECT quiet_hours_start, quiet_hours_end
-       FROM notification_preferences
-       WHERE user_id = $1`,
+      `SELECT CASE WHEN np.id IS NULL THEN d.quiet_hours_start ELSE np.quiet_hours_start END AS quiet_hours_start,
+              CASE WHEN np.id IS NULL THEN d.quiet_hours_end ELSE np.quiet_hours_end END AS quiet_hours_end
+       FROM users u
+       LEFT JOIN notification_preferences np ON np.user_id = u.id
+       LEFT JOIN notification_role_defaults d ON d.role = u.role
+       WHERE u.id = $1`,
       [userId],
     );
 
@@ -137,16 +192,19 @@ async function isQuietHours(userId: string): Promise<boolean> {
 async function isTypeEnabled(userId: string, type: string): Promise<boolean> {
   try {
     const res = await pool.query(
-      `SELECT types_enabled FROM notification_preferences WHERE user_id = $1`,
+      `SELECT COALESCE(np.types_enabled, d.types_enabled) AS types_enabled
+       FROM users u
+       LEFT JOIN notification_preferences np ON np.user_id = u.id
+       LEFT JOIN notification_role_defaults d ON d.role = u.role
+       WHERE u.id = $1`,
       [userId],
     );
 
-    if (res.rows.length === 0) return true; // Default: all enabled
-
-    const typesEnabled = res.rows[0].types_enabled;
-    if (!typesEnabled || !Array.isArray(typesEnabled)) return true;
+    const typesEnabled = res.rows[0]?.types_enabled;
+    if (!typesEnabled) return true; // Default: all enabled
 
-    return typesEnabled.includes(type);
+    const types = normalizeTypes(typesEnabled);
+    return types[type as NotificationType] ?? true;
   } catch {
     return true; // Default: enabled on error
   }
@@ -0,0 +1,115 @@
+import type { Pool } from 'pg';
+import type { Queryable } from './pricing.js';
+import { withTransaction } from '../db/transaction.js';
+import { messagingConfigured, sendTextMessage } from '../lib/messaging.js';
+import { surveyLink } from './order-links.js';
+import { emitWebhookEvent, orderEventData } from './webhooks.js';
+
+// Automatic completion of served orders. Staff payments complete an order
+// straight away, but orders served after paying — or settled while still
+// 'served' — wait for someone to close them. A periodic job completes
+// served orders whose payments cover the total once the configured number
+// of minutes has passed, frees the table and invites the guest to the survey.
+
+export const ORDER_AUTO_COMPLETE_JOB = 'order_auto_complete';
+
+const DEFAULT_AUTO_COMPLETE_MINUTES = 30;
+
+export async function loadAutoCompleteMinutes(q: Queryable): Promise<number> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'order_auto_complete_minutes'`);
+  if (res.rows.length === 0) return DEFAULT_AUTO_COMPLETE_MINUTES;
+  const value = parseInt(res.rows[0].setting_value, 10);
+  return isNaN(value) || value < 0 ? DEFAULT_AUTO_COMPLETE_MINUTES : value;
+}
+
+// ── InviteToSurvey ──────────────────────────────────────────────────────────
+// Shown on the customer's order status page; delivery guests, who have left
+// no table to look at, also get a text when messaging is configured.
+
+export async function inviteToSurvey(
+  q: Queryable,
+  order: { id: string; order_number: string; delivery_phone?: string | null },
+): Promise<void> {
+  const link = surveyLink(order.id);
+  await q.query(
+    'INSERT INTO order_notifications (order_id, status, message, is_read) VALUES ($1, $2, $3, false)',
+    [order.id, 'completed', `Thank you for your visit! Tell us how we did: ${link}`],
+  );
+
+  if (order.delivery_phone && messagingConfigured()) {
+    try {
+      await sendTextMessage({
+        to: order.delivery_phone,
+        text: `Thank you for ordering (${order.order_number})! We'd love your feedback: ${link}`,
+      });
+    } catch (err) {
+      console.error(`Survey invitation for ${order.order_number} failed:`, (err as Error).message);
+    }
+  }
+}
+
+// ── AutoCompleteServedOrders ────────────────────────────────────────────────
+// Each order is completed in its own transaction; the status and payment
+// guards in the UPDATE keep a refund, a staff close or another instance
+// running the job from racing it. Refunds are negative completed payments,
+// so a refunded order no longer counts as paid. The webhook and survey go
+// out once the completion has committed.
+
+export async function autoCompleteServedOrders(pool: Pool): Promise<number> {
+  const minutes = await loadAutoCompleteMinutes(pool);
+  if (minutes === 0) return 0;
+
+  const due = await pool.query(
+    `SELECT o.id FROM orders o
+     WHERE o.status = 'served'
+       AND o.served_at <= NOW() - make_interval(mins => $1)
+       AND (SELECT COALESCE(SUM(p.amount), 0) FROM payments p
+            WHERE p.order_id = o.id AND p.status = 'completed') >= o.total_amount`,
+    [minutes],
+  );
+
+  let completed = 0;
+  for (const { id } of due.rows) {
+    try {
+      const order = await withTransaction(async (client) => {
+        const res = await client.query(
+          `UPDATE orders o
+           SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+           WHERE o.id = $1 AND o.status = 'served'
+             AND (SELECT COALESCE(SUM(p.amount), 0) FROM payments p
+                  WHERE p.order_id = o.id AND p.status = 'completed') >= o.total_amount
+           RETURNING o.id, o.order_number, o.table_id, o.delivery_phone`,
+          [id],
+        );
+        if (res.rows.length === 0) return null;
+        const order = res.rows[0];
+
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, notes)
+           VALUES ($1, 'served', 'completed', $2)`,
+          [id, `Completed automatically ${minutes} minutes after serving`],
+        );
+
+        // Another party may already have been seated with a new order
+        if (order.table_id) {
+          await client.query(
+            `UPDATE dining_tables SET is_occupied = false, updated_at = NOW()
+             WHERE id = $1 AND NOT EXISTS (
+               SELECT 1 FROM orders WHERE table_id = $1 AND status IN ('pending', 'confirmed', 'preparing', 'ready', 'served')
+             )`,
+            [order.table_id],
+          );
+        }
+        return order;
+      });
+      if (!order) continue;
+      completed++;
+
+      await emitWebhookEvent(pool, 'order.completed', () => orderEventData(pool, id));
+      await inviteToSurvey(pool, order);
+    } catch (err) {
+      console.error(`Auto-completing order ${id} failed:`, (err as Error).message);
+    }
+  }
+  return completed;
+}
@@ -0,0 +1,108 @@
+import crypto from 'node:crypto';
+import type { Context } from 'hono';
+import { env } from '../env.js';
+
+// Customer order links. Links sent to guests (order tracking, payment, the
+// survey invitation) carry a token instead of the order's UUID: the order
+// ID, what the link is for and when it expires, encrypted and authenticated
+// with AES-256-GCM under LINK_TOKEN_SECRET (or JWT_SECRET). A token can't be
+// guessed, doesn't reveal the order in logs or analytics, can't be altered
+// to point at another order, and stops working once it expires.
+//
+// 'order' tokens are handed out when the order is placed and open the
+// tracking page, its notifications and payment. 'survey' tokens only open
+// the survey, and live longer since the invitation is read after the visit.
+
+export type OrderLinkPurpose = 'order' | 'survey';
+
+const PURPOSE_BYTES: Record<OrderLinkPurpose, number> = { order: 1, survey: 2 };
+const IV_BYTES = 12;
+const TAG_BYTES = 16;
+// purpose (1) + order UUID (16) + expiry in Unix seconds (4)
+const PAYLOAD_BYTES = 21;
+const TOKEN_PATTERN = /^[A-Za-z0-9_-]{66}$/;
+
+let cachedKey: { secret: string; key: Buffer } | null = null;
+
+function key(): Buffer {
+  const secret = env.LINK_TOKEN_SECRET || env.JWT_SECRET;
+  if (cachedKey?.secret !== secret) {
+    cachedKey = { secret, key: crypto.createHash('sha256').update(`order-link:${secret}`).digest() };
+  }
+  return cachedKey.key;
+}
+
+function ttlSeconds(purpose: OrderLinkPurpose): number {
+  return purpose === 'survey' ? env.SURVEY_LINK_TTL_DAYS * 86_400 : env.ORDER_LINK_TTL_HOURS * 3_600;
+}
+
+export interface OrderLinkToken {
+  token: string;
+  expires_at: string;
+}
+
+export function signOrderToken(orderId: string, purpose: OrderLinkPurpose, now = new Date()): OrderLinkToken {
+  const expires = Math.floor(now.getTime() / 1000) + ttlSeconds(purpose);
+  const payload = Buffer.alloc(PAYLOAD_BYTES);
+  payload[0] = PURPOSE_BYTES[purpose];
+  Buffer.from(orderId.replace(/-/g, ''), 'hex').copy(payload, 1);
+  payload.writeUInt32BE(expires, 17);
+
+  const iv = crypto.randomBytes(IV_BYTES);
+  const cipher = crypto.createCipheriv('aes-256-gcm', key(), iv);
+  const encrypted = Buffer.concat([cipher.update(payload), cipher.final()]);
+  return {
+    token: Buffer.concat([iv, encrypted, cipher.getAuthTag()]).toString('base64url'),
+    expires_at: new Date(expires * 1000).toISOString(),
+  };
+}
+
+export type OrderTokenCheck =
+  | { ok: true; orderId: string; purpose: OrderLinkPurpose }
+  | { ok: false; failure: { message: string; code: string; status: 404 | 410 } };
+
+const notFound = { ok: false, failure: { message: 'Order not found', code: 'order_not_found', status: 404 } } as const;
+
+/** The order a token is for, if it is genuine, unexpired and for one of `purposes`. */
+export function verifyOrderToken(token: string, purposes: readonly OrderLinkPurpose[], now = new Date()): OrderTokenCheck {
+  if (!TOKEN_PATTERN.test(token)) return notFound;
+  const raw = Buffer.from(token, 'base64url');
+  if (raw.length !== IV_BYTES + PAYLOAD_BYTES + TAG_BYTES) return notFound;
+
+  let payload: Buffer;
+  try {
+    const decipher = crypto.createDecipheriv('aes-256-gcm', key(), raw.subarray(0, IV_BYTES));
+    decipher.setAuthTag(raw.subarray(IV_BYTES + PAYLOAD_BYTES));
+    payload = Buffer.concat([decipher.update(raw.subarray(IV_BYTES, IV_BYTES + PAYLOAD_BYTES)), decipher.final()]);
+  } catch {
+    return notFound;
+  }
+
+  const purpose = (Object.keys(PURPOSE_BYTES) as OrderLinkPurpose[]).find((p) => PURPOSE_BYTES[p] === payload[0]);
+  if (!purpose || !purposes.includes(purpose)) return notFound;
+  if (payload.readUInt32BE(17) * 1000 <= now.getTime()) {
+    return { ok: false, failure: { message: 'This link has expired', code: 'order_link_expired', status: 410 } };
+  }
+
+  const hex = payload.subarray(1, 17).toString('hex');
+  const orderId = `${hex.slice(0, 8)}-${hex.slice(8, 12)}-${hex.slice(12, 16)}-${hex.slice(16, 20)}-${hex.slice(20)}`;
+  return { ok: true, orderId, purpose };
+}
+
+/** verifyOrderToken for the request's :token parameter. */
+export function resolveOrderToken(c: Context, purposes: readonly OrderLinkPurpose[]): OrderTokenCheck {
+  return verifyOrderToken(c.req.param('token') ?? '', purposes);
+}
+
+function appUrl(): string {
+  return env.PUBLIC_APP_URL.replace(/\/+$/, '');
+}
+
+/** The customer's order tracking page. */
+export function orderStatusLink(token: string): string {
+  return `${appUrl()}/order-status/${token}`;
+}
+
+export function surveyLink(orderId: string): string {
+  return `${appUrl()}/customer/survey?token=${signOrderToken(orderId, 'survey').token}`;
+}
@@ -0,0 +1,168 @@
+import type { Pool, PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+import { withTransaction } from '../db/transaction.js';
+import { releaseStockForOrder } from './stock.js';
+import { refreshStockAvailability } from './stock-availability.js';
+import { createNotificationForRole } from './notification.js';
+
+// Parked orders. Counter staff can park an order that isn't ready to go to
+// the kitchen yet (the customer went to fetch their wallet): it moves to the
+// 'parked' state, which no station feed shows, keeping its items, prices and
+// stock. Resuming sends it to the kitchen as a pending order. An order
+// parked past its expiry is cancelled by a periodic job and its stock
+// returned, unless something has already been paid on it.
+
+export const PARKED_ORDERS_EXPIRE_JOB = 'parked_orders_expire';
+
+const DEFAULT_EXPIRY_MINUTES = 60;
+export const MAX_PARK_MINUTES = 24 * 60;
+
+export interface ParkFailure {
+  message: string;
+  code: string;
+  status: 404 | 409;
+}
+
+export async function loadParkExpiryMinutes(q: Queryable): Promise<number> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'parked_order_expiry_minutes'`);
+  if (res.rows.length === 0) return DEFAULT_EXPIRY_MINUTES;
+  const value = parseInt(res.rows[0].setting_value, 10);
+  return isNaN(value) || value <= 0 ? DEFAULT_EXPIRY_MINUTES : Math.min(value, MAX_PARK_MINUTES);
+}
+
+// ── ParkPendingOrder ────────────────────────────────────────────────────────
+// Runs in the caller's transaction. Only pending orders whose items the
+// kitchen hasn't started can be parked.
+
+export async function parkPendingOrder(
+  client: PoolClient,
+  input: { orderId: string; reason: string | null; minutes: number | null; userId: string | null },
+): Promise<{ ok: true; expiresAt: string } | { ok: false; failure: ParkFailure }> {
+  const orderRes = await client.query('SELECT status FROM orders WHERE id = $1 FOR UPDATE', [input.orderId]);
+  if (orderRes.rows.length === 0) {
+    return { ok: false, failure: { message: 'Order not found', code: 'order_not_found', status: 404 } };
+  }
+  const status = orderRes.rows[0].status;
+  if (status === 'parked') {
+    return { ok: false, failure: { message: 'Order is already parked', code: 'order_already_parked', status: 409 } };
+  }
+  if (status !== 'pending') {
+    return {
+      ok: false,
+      failure: { message: `Only pending orders can be parked - order is ${status}`, code: 'invalid_order_status', status: 409 },
+    };
+  }
+
+  const startedRes = await client.query(
+    `SELECT 1 FROM order_items WHERE order_id = $1 AND (status <> 'pending' OR is_remake) LIMIT 1`,
+    [input.orderId],
+  );
+  if (startedRes.rows.length > 0) {
+    return {
+      ok: false,
+      failure: { message: 'The kitchen has already started this order', code: 'order_in_progress', status: 409 },
+    };
+  }
+
+  const minutes = input.minutes ?? await loadParkExpiryMinutes(client);
+  const res = await client.query(
+    `UPDATE orders
+     SET status = 'parked', parked_at = NOW(), parked_by = $2, park_reason = $3,
+         park_expires_at = NOW() + make_interval(mins => $4), updated_at = NOW()
+     WHERE id = $1
+     RETURNING park_expires_at`,
+    [input.orderId, input.userId, input.reason, minutes],
+  );
+  await client.query(
+    `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+     VALUES ($1, 'pending', 'parked', $2, $3)`,
+    [input.orderId, input.userId, input.reason ? `Parked: ${input.reason}` : 'Parked'],
+  );
+  return { ok: true, expiresAt: res.rows[0].park_expires_at };
+}
+
+// ── ResumeParkedOrder ───────────────────────────────────────────────────────
+// Runs in the caller's transaction; the order goes to the kitchen as pending.
+
+export async function resumeParkedOrder(
+  client: PoolClient,
+  orderId: string,
+  userId: string | null,
+): Promise<{ ok: true } | { ok: false; failure: ParkFailure }> {
+  const res = await client.query(
+    `UPDATE orders
+     SET status = 'pending', parked_at = NULL, parked_by = NULL, park_reason = NULL, park_expires_at = NULL,
+         updated_at = NOW()
+     WHERE id = $1 AND status = 'parked'
+     RETURNING id`,
+    [orderId],
+  );
+  if (res.rows.length === 0) {
+    const exists = await client.query('SELECT status FROM orders WHERE id = $1', [orderId]);
+    if (exists.rows.length === 0) {
+      return { ok: false, failure: { message: 'Order not found', code: 'order_not_found', status: 404 } };
+    }
+    return { ok: false, failure: { message: `Order is not parked - order is ${exists.rows[0].status}`, code: 'order_not_parked', status: 409 } };
+  }
+
+  await client.query(
+    `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+     VALUES ($1, 'parked', 'pending', $2, 'Resumed and sent to the kitchen')`,
+    [orderId, userId],
+  );
+  return { ok: true };
+}
+
+// ── ExpireParkedOrders ──────────────────────────────────────────────────────
+// Each order is cancelled in its own transaction; the status guard keeps a
+// resume (or another instance running the job) from racing the cancel.
+
+export async function expireParkedOrders(pool: Pool): Promise<number> {
+  const due = await pool.query(
+    `SELECT o.id FROM orders o
+     WHERE o.status = 'parked' AND o.park_expires_at <= NOW()
+       AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = o.id AND p.status = 'completed')`,
+  );
+
+  let expired = 0;
+  for (const { id } of due.rows) {
+    try {
+      const order = await withTransaction(async (client) => {
+        const res = await client.query(
+          `UPDATE orders SET status = 'cancelled', updated_at = NOW()
+           WHERE id = $1 AND status = 'parked'
+           RETURNING order_number, table_id`,
+          [id],
+        );
+        if (res.rows.length === 0) return null;
+        const order = res.rows[0];
+
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+           VALUES ($1, 'parked', 'cancelled', NULL, 'Parked order expired')`,
+          [id],
+        );
+        await releaseStockForOrder(client, id, null);
+        if (order.table_id) {
+          await client.query(
+            `UPDATE dining_tables SET is_occupied = false
+             WHERE id = $1 AND NOT EXISTS (
+               SELECT 1 FROM orders WHERE table_id = $1 AND id <> $2 AND status NOT IN ('completed', 'cancelled')
+             )`,
+            [order.table_id, id],
+          );
+        }
+        return order;
+      });
+      if (!order) continue;
+      expired++;
+
+      await refreshStockAvailability({ orderId: id });
+      await createNotificationForRole('counter', 'order_update', 'Parked Order Expired',
+        `Parked order ${order.order_number} was not resumed in time and has been cancelled`, 'parked_order_expired');
+    } catch (err) {
+      console.error(`Expiring parked order ${id} failed:`, (err as Error).message);
+    }
+  }
+  return expired;
+}
@@ -0,0 +1,308 @@
+import { localClock, inDailyWindow, RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition, loadBranchSetting } from './branches.js';
+import { createNotificationForRole } from './notification.js';
+import type { Queryable } from './pricing.js';
+
+// Service levels of customer (QR and online) orders: minutes from the order
+// being placed — or released, for a scheduled order — to staff accepting it,
+// the kitchen starting it, ready and, for dine-in, served. Stage times come
+// from the order's status history. Targets are set in order_sla_targets and
+// can be overridden per daypart (order_sla_dayparts), both per branch. The
+// breach sweep records an open order the first time it misses the target of
+// the stage it is waiting for, and notifies the staff responsible.
+
+export const ORDER_SLA_CHECK_JOB = 'order_sla_check';
+
+export const SLA_STAGES = ['accepted', 'kitchen_started', 'ready', 'served'] as const;
+export type SlaStage = (typeof SLA_STAGES)[number];
+
+const DEFAULT_TARGETS: Record<SlaStage, number> = { accepted: 3, kitchen_started: 8, ready: 25, served: 30 };
+const OTHER_DAYPART = 'Other';
+
+// The order status that means a stage has been reached, even if the status
+// history skips it
+const STAGE_STATUS_RANK: Record<SlaStage, number> = { accepted: 1, kitchen_started: 2, ready: 3, served: 4 };
+const STATUS_RANK: Record<string, number> = { pending: 0, confirmed: 1, preparing: 2, ready: 3, served: 4, completed: 5 };
+
+const STAGE_ROLES: Record<SlaStage, string[]> = {
+  accepted: ['counter', 'server'],
+  kitchen_started: ['kitchen'],
+  ready: ['kitchen'],
+  served: ['server'],
+};
+
+const STAGE_LABELS: Record<SlaStage, string> = {
+  accepted: 'accepted',
+  kitchen_started: 'started in the kitchen',
+  ready: 'ready',
+  served: 'served',
+};
+
+// Longest an order is watched for breaches; older open orders were forgotten
+const MAX_OPEN_HOURS = 12;
+
+export interface Daypart {
+  name: string;
+  start: string;
+  end: string;
+  targets: Record<SlaStage, number>;
+}
+
+export interface SlaConfig {
+  targets: Record<SlaStage, number>;
+  dayparts: Daypart[];
+}
+
+export interface SlaStageResult {
+  stage: SlaStage;
+  target_minutes: number;
+  measured: number;
+  within_target: number;
+  breached: number;
+  compliance_pct: number | null;
+  avg_minutes: number | null;
+  p90_minutes: number | null;
+}
+
+export interface SlaDaypartResult {
+  daypart: string;
+  start: string | null;
+  end: string | null;
+  orders: number;
+  stages: SlaStageResult[];
+}
+
+function parseJson(value: string | null): unknown {
+  try {
+    return JSON.parse(value || 'null');
+  } catch {
+    return null;
+  }
+}
+
+// Positive minute values override the base targets one stage at a time
+function mergeTargets(base: Record<SlaStage, number>, configured: unknown): Record<SlaStage, number> {
+  const targets = { ...base };
+  if (!configured || typeof configured !== 'object') return targets;
+  for (const stage of SLA_STAGES) {
+    const minutes = Number((configured as Record<string, unknown>)[stage]);
+    if (Number.isFinite(minutes) && minutes > 0) targets[stage] = minutes;
+  }
+  return targets;
+}
+
+const HHMM_RE = /^([01]\d|2[0-3]):[0-5]\d$/;
+
+// ── LoadSlaConfig ───────────────────────────────────────────────────────────
+
+export async function loadSlaConfig(q: Queryable, branchId: string | null): Promise<SlaConfig> {
+  const targets = mergeTargets(DEFAULT_TARGETS, parseJson(await loadBranchSetting(q, branchId, 'order_sla_targets')));
+
+  const configured = parseJson(await loadBranchSetting(q, branchId, 'order_sla_dayparts'));
+  const dayparts: Daypart[] = [];
+  for (const entry of Array.isArray(configured) ? configured : []) {
+    const name = typeof entry?.name === 'string' ? entry.name.trim().slice(0, 50) : '';
+    if (!name || !HHMM_RE.test(entry.start) || !HHMM_RE.test(entry.end)) continue;
+    dayparts.push({ name, start: entry.start, end: entry.end, targets: mergeTargets(targets, entry.targets) });
+  }
+  return { targets, dayparts };
+}
+
+/** The daypart an order placed at `at` falls in; the first match wins. */
+export function daypartFor(config: SlaConfig, at: Date): { name: string; targets: Record<SlaStage, number> } {
+  const { secondsOfDay } = localClock(at);
+  const daypart = config.dayparts.find((d) => inDailyWindow(secondsOfDay, d.start, d.end));
+  return daypart ?? { name: OTHER_DAYPART, targets: config.targets };
+}
+
+// Per customer order: when the clock started and when each stage was first
+// reached. A scheduled order's clock starts on its release to the kitchen.
+const ORDER_STAGE_TIMES = `
+  SELECT o.id, o.order_number, o.order_type, o.status, o.branch_id,
+         COALESCE(h.released_at, o.created_at) AS placed_at,
+         h.accepted_at, h.kitchen_started_at, h.ready_at, COALESCE(o.served_at, h.served_at) AS served_at
+  FROM orders o
+  LEFT JOIN LATERAL (
+    SELECT MIN(sh.created_at) FILTER (WHERE sh.previous_status = 'scheduled') AS released_at,
+           MIN(sh.created_at) FILTER (WHERE sh.previous_status = 'pending' AND sh.new_status <> 'cancelled') AS accepted_at,
+           MIN(sh.created_at) FILTER (WHERE sh.new_status = 'preparing') AS kitchen_started_at,
+           MIN(sh.created_at) FILTER (WHERE sh.new_status = 'ready') AS ready_at,
+           MIN(sh.created_at) FILTER (WHERE sh.new_status = 'served') AS served_at
+    FROM order_status_history sh
+    WHERE sh.order_id = o.id
+  ) h ON true`;
+
+const STAGE_COLUMNS: Record<SlaStage, string> = {
+  accepted: 'accepted_at',
+  kitchen_started: 'kitchen_started_at',
+  ready: 'ready_at',
+  served: 'served_at',
+};
+
+function elapsedMinutes(from: unknown, to: unknown): number | null {
+  if (!from || !to) return null;
+  const minutes = (new Date(to as string).getTime() - new Date(from as string).getTime()) / 60_000;
+  return minutes >= 0 ? minutes : null;
+}
+
+function appliesTo(stage: SlaStage, orderType: string): boolean {
+  return stage !== 'served' || orderType === 'dine_in';
+}
+
+// ── GetSlaReport ────────────────────────────────────────────────────────────
+// Compliance per daypart and stage for customer orders placed over
+// [from, to]. An order counts towards a stage once it reached it, or when it
+// breached the stage and never got there (e.g. cancelled while waiting).
+// Targets are the current ones, so changing a target restates the report.
+
+export async function getSlaReport(q: Queryable, from: string, to: string, branchId: string | null) {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const res = await q.query(
+    `WITH timings AS (${ORDER_STAGE_TIMES}
+       WHERE o.user_id IS NULL AND o.status <> 'scheduled'
+         AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2${branchCondition('o.branch_id', branchId, params)}
+     )
+     SELECT t.*, COALESCE(
+              (SELECT array_agg(b.stage) FROM order_sla_breaches b WHERE b.order_id = t.id), '{}'
+            ) AS breached_stages
+     FROM timings t`,
+    params,
+  );
+
+  const configs = new Map<string, SlaConfig>();
+  const configFor = async (id: string | null) => {
+    const key = branchId ?? id ?? '';
+    if (!configs.has(key)) configs.set(key, await loadSlaConfig(q, branchId ?? id));
+    return configs.get(key)!;
+  };
+
+  interface Bucket { orders: number; stages: Map<SlaStage, { times: number[]; within: number; breached: number }> }
+  const newBucket = (): Bucket => ({
+    orders: 0,
+    stages: new Map(SLA_STAGES.map((s) => [s, { times: [], within: 0, breached: 0 }])),
+  });
+  const buckets = new Map<string, Bucket>();
+  const overall = newBucket();
+
+  for (const row of res.rows) {
+    const config = await configFor(row.branch_id);
+    const daypart = daypartFor(config, new Date(row.placed_at));
+    if (!buckets.has(daypart.name)) buckets.set(daypart.name, newBucket());
+    const bucket = buckets.get(daypart.name)!;
+    bucket.orders++;
+    overall.orders++;
+
+    for (const stage of SLA_STAGES) {
+      if (!appliesTo(stage, row.order_type)) continue;
+      const minutes = elapsedMinutes(row.placed_at, row[STAGE_COLUMNS[stage]]);
+      const missed = minutes === null && (row.breached_stages as string[]).includes(stage);
+      if (minutes === null && !missed) continue;
+      for (const b of [bucket, overall]) {
+        const s = b.stages.get(stage)!;
+        if (minutes === null) {
+          s.breached++;
+          continue;
+        }
+        s.times.push(minutes);
+        if (minutes <= daypart.targets[stage]) s.within++;
+        else s.breached++;
+      }
+    }
+  }
+
+  const round1 = (n: number) => Math.round(n * 10) / 10;
+  const summarize = (bucket: Bucket, targets: Record<SlaStage, number>): SlaStageResult[] =>
+    SLA_STAGES.map((stage) => {
+      const s = bucket.stages.get(stage)!;
+      const measured = s.within + s.breached;
+      const sorted = [...s.times].sort((a, b) => a - b);
+      return {
+        stage,
+        target_minutes: targets[stage],
+        measured,
+        within_target: s.within,
+        breached: s.breached,
+        compliance_pct: measured > 0 ? round1((s.within / measured) * 100) : null,
+        avg_minutes: sorted.length > 0 ? round1(sorted.reduce((sum, t) => sum + t, 0) / sorted.length) : null,
+        p90_minutes: sorted.length > 0 ? round1(sorted[Math.ceil(sorted.length * 0.9) - 1]) : null,
+      };
+    });
+
+  // Daypart targets as configured for the report's branch (restaurant-wide
+  // for a consolidated report)
+  const config = await loadSlaConfig(q, branchId);
+  const dayparts: SlaDaypartResult[] = config.dayparts
+    .filter((d) => buckets.has(d.name))
+    .map((d) => {
+      const bucket = buckets.get(d.name)!;
+      return { daypart: d.name, start: d.start, end: d.end, orders: bucket.orders, stages: summarize(bucket, d.targets) };
+    });
+  for (const [name, bucket] of buckets) {
+    if (config.dayparts.some((d) => d.name === name)) continue;
+    dayparts.push({ daypart: name, start: null, end: null, orders: bucket.orders, stages: summarize(bucket, config.targets) });
+  }
+
+  return {
+    from,
+    to,
+    branch_id: branchId,
+    targets: config.targets,
+    dayparts,
+    overall: { orders: overall.orders, stages: summarize(overall, config.targets) },
+  };
+}
+
+// ── CheckOrderSlaBreaches ───────────────────────────────────────────────────
+// Sweeps open customer orders for the stage each is waiting for. Only that
+// stage is checked, so an order stuck at acceptance raises one alert rather
+// than one per stage; the later stages are checked once it moves on. The
+// conditional INSERT keeps concurrent sweeps from notifying twice.
+
+export async function checkOrderSlaBreaches(q: Queryable): Promise<number> {
+  const res = await q.query(
+    `${ORDER_STAGE_TIMES}
+     WHERE o.user_id IS NULL AND o.status IN ('pending', 'confirmed', 'preparing', 'ready')
+       AND o.created_at >= NOW() - make_interval(hours => $1)`,
+    [MAX_OPEN_HOURS],
+  );
+
+  const configs = new Map<string, SlaConfig>();
+  const notify = new Map<string, boolean>();
+  let breaches = 0;
+
+  for (const row of res.rows) {
+    if (!configs.has(row.branch_id)) {
+      configs.set(row.branch_id, await loadSlaConfig(q, row.branch_id));
+      notify.set(row.branch_id, (await loadBranchSetting(q, row.branch_id, 'order_sla_breach_notifications')) !== 'false');
+    }
+    const daypart = daypartFor(configs.get(row.branch_id)!, new Date(row.placed_at));
+
+    const rank = STATUS_RANK[row.status] ?? 0;
+    const stage = SLA_STAGES.find(
+      (s) => appliesTo(s, row.order_type) && rank < STAGE_STATUS_RANK[s] && !row[STAGE_COLUMNS[s]],
+    );
+    if (!stage) continue;
+
+    const waited = elapsedMinutes(row.placed_at, new Date().toISOString());
+    if (waited === null || waited <= daypart.targets[stage]) continue;
+
+    const inserted = await q.query(
+      `INSERT INTO order_sla_breaches (order_id, stage, daypart, target_minutes)
+       VALUES ($1, $2, $3, $4)
+       ON CONFLICT (order_id, stage) DO NOTHING
+       RETURNING id`,
+      [row.id, stage, daypart.name, Math.round(daypart.targets[stage])],
+    );
+    if (inserted.rows.length === 0) continue;
+    breaches++;
+
+    if (!notify.get(row.branch_id)) continue;
+    const message = `Order ${row.order_number} has not been ${STAGE_LABELS[stage]} after ${Math.round(waited)} minutes`
+      + ` (target ${daypart.targets[stage]} min, ${daypart.name})`;
+    for (const role of STAGE_ROLES[stage]) {
+      await createNotificationForRole(role, 'order_update', 'Order SLA Missed', message, 'order_sla_missed');
+    }
+  }
+
+  return breaches;
+}
@@ -0,0 +1,170 @@
+import type { Queryable } from './pricing.js';
+
+// Source details of customer orders, for spotting abuse such as ordering to
+// a table from outside the restaurant. The browser sends a device
+// fingerprint and, when the guest allows it, its location; with the IP and
+// user agent these are checked against the branch's venue location and the
+// device's recent orders. Flags never block an order: they are shown to
+// staff accepting it.
+
+export const ORDER_RISK_FLAGS: Record<string, string> = {
+  outside_venue: 'Placed to a table from too far away from the restaurant',
+  imprecise_location: 'Location too imprecise to tell whether the guest is at the restaurant',
+  no_location: 'Placed to a table without sharing a location',
+  multiple_tables: 'Same device has an open order at another table',
+  rapid_orders: 'Several orders from the same device or network within minutes',
+  automated_client: 'Placed by a script rather than a browser',
+};
+
+const DEFAULT_MAX_DISTANCE_M = 300;
+const RAPID_ORDER_WINDOW_MINUTES = 15;
+const RAPID_ORDER_LIMIT = 3;
+const OPEN_ORDER_WINDOW_HOURS = 3;
+// About 100 m at the equator
+const COORDINATE_DECIMALS = 3;
+const AUTOMATED_UA_RE = /curl|wget|python|httpclient|okhttp|postman|insomnia|go-http|java\/|bot\b|spider|headless/i;
+
+export interface OrderSourceInput {
+  ipAddress: string | null;
+  userAgent: string | null;
+  fingerprint?: unknown;
+  location?: { latitude?: unknown; longitude?: unknown; accuracy?: unknown } | null;
+}
+
+function distanceMeters(lat1: number, lon1: number, lat2: number, lon2: number): number {
+  const rad = (d: number) => (d * Math.PI) / 180;
+  const a = Math.sin(rad(lat2 - lat1) / 2) ** 2
+    + Math.cos(rad(lat1)) * Math.cos(rad(lat2)) * Math.sin(rad(lon2 - lon1) / 2) ** 2;
+  return 2 * 6_371_000 * Math.asin(Math.sqrt(a));
+}
+
+function parseLocation(location: OrderSourceInput['location']) {
+  if (!location) return null;
+  const latitude = Number(location.latitude);
+  const longitude = Number(location.longitude);
+  if (!Number.isFinite(latitude) || !Number.isFinite(longitude)
+      || Math.abs(latitude) > 90 || Math.abs(longitude) > 180) {
+    return null;
+  }
+  const accuracy = Number(location.accuracy);
+  return { latitude, longitude, accuracy: Number.isFinite(accuracy) && accuracy >= 0 ? Math.round(accuracy) : null };
+}
+
+async function loadVenueLocation(q: Queryable, branchId: string): Promise<{ latitude: number; longitude: number } | null> {
+  const res = await q.query(
+    `SELECT COALESCE(b.latitude, r.map_latitude) AS latitude, COALESCE(b.longitude, r.map_longitude) AS longitude
+     FROM branches b
+     LEFT JOIN (SELECT map_latitude, map_longitude FROM restaurant_info LIMIT 1) r ON true
+     WHERE b.id = $1`,
+    [branchId],
+  );
+  const row = res.rows[0];
+  if (!row || row.latitude === null || row.longitude === null) return null;
+  return { latitude: Number(row.latitude), longitude: Number(row.longitude) };
+}
+
+async function loadMaxDistance(q: Queryable): Promise<number> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'qr_order_max_distance_meters'`);
+  const value = parseInt(res.rows[0]?.setting_value, 10);
+  return isNaN(value) || value <= 0 ? DEFAULT_MAX_DISTANCE_M : value;
+}
+
+// ── RecordOrderSource ───────────────────────────────────────────────────────
+// Called on the order's transaction once the order row exists. Returns the
+// risk flags raised.
+
+export async function recordOrderSource(
+  q: Queryable,
+  order: { id: string; branchId: string; tableId: string | null; orderType: string },
+  input: OrderSourceInput,
+): Promise<string[]> {
+  const flags: string[] = [];
+  const userAgent = input.userAgent?.slice(0, 500) || null;
+  const fingerprint = typeof input.fingerprint === 'string' && input.fingerprint.trim()
+    ? input.fingerprint.trim().slice(0, 128)
+    : null;
+  const location = parseLocation(input.location);
+
+  if (!userAgent || AUTOMATED_UA_RE.test(userAgent)) flags.push('automated_client');
+
+  // Only table orders claim the guest is on site
+  let distance: number | null = null;
+  if (order.orderType === 'dine_in') {
+    const venue = await loadVenueLocation(q, order.branchId);
+    if (!location) {
+      flags.push('no_location');
+    } else if (venue) {
+      const maxDistance = await loadMaxDistance(q);
+      distance = Math.round(distanceMeters(venue.latitude, venue.longitude, location.latitude, location.longitude));
+      // A fix whose error circle reaches the venue gets the benefit of the doubt
+      if (location.accuracy !== null && location.accuracy > maxDistance) {
+        flags.push('imprecise_location');
+      } else if (distance - (location.accuracy ?? 0) > maxDistance) {
+        flags.push('outside_venue');
+      }
+    }
+  }
+
+  if (fingerprint && order.tableId) {
+    const open = await q.query(
+      `SELECT 1 FROM order_source_details s
+       JOIN orders o ON o.id = s.order_id
+       WHERE s.device_fingerprint = $1 AND o.table_id IS NOT NULL AND o.table_id <> $2
+         AND o.status NOT IN ('completed', 'cancelled')
+         AND s.created_at >= NOW() - make_interval(hours => $3)
+       LIMIT 1`,
+      [fingerprint, order.tableId, OPEN_ORDER_WINDOW_HOURS],
+    );
+    if (open.rows.length > 0) flags.push('multiple_tables');
+  }
+
+  if (fingerprint || input.ipAddress) {
+    const recent = await q.query(
+      `SELECT COUNT(*) AS orders FROM order_source_details
+       WHERE created_at >= NOW() - make_interval(mins => $3)
+         AND (device_fingerprint = $1 OR ip_address = $2)`,
+      [fingerprint, input.ipAddress, RAPID_ORDER_WINDOW_MINUTES],
+    );
+    // This order is not recorded yet
+    if (Number(recent.rows[0].orders) + 1 >= RAPID_ORDER_LIMIT) flags.push('rapid_orders');
+  }
+
+  const round = (n: number) => Number(n.toFixed(COORDINATE_DECIMALS));
+  await q.query(
+    `INSERT INTO order_source_details
+       (order_id, ip_address, user_agent, device_fingerprint, latitude, longitude, location_accuracy_m, distance_m, risk_flags)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+    [
+      order.id, input.ipAddress?.slice(0, 64) || null, userAgent, fingerprint,
+      location ? round(location.latitude) : null, location ? round(location.longitude) : null,
+      location?.accuracy ?? null, distance, flags,
+    ],
+  );
+  return flags;
+}
+
+// ── LoadOrderSource ─────────────────────────────────────────────────────────
+// What staff see on the order: flags with their explanations, not the raw
+// device details.
+
+export function formatRiskFlags(flags: string[] | null | undefined) {
+  return (flags ?? []).map((flag) => ({ flag, description: ORDER_RISK_FLAGS[flag] ?? flag }));
+}
+
+export async function loadOrderSource(q: Queryable, orderId: string) {
+  const res = await q.query(
+    `SELECT device_fingerprint IS NOT NULL AS has_fingerprint, latitude IS NOT NULL AS has_location,
+            location_accuracy_m, distance_m, risk_flags, created_at
+     FROM order_source_details WHERE order_id = $1`,
+    [orderId],
+  );
+  const row = res.rows[0];
+  if (!row) return null;
+  return {
+    has_fingerprint: row.has_fingerprint,
+    has_location: row.has_location,
+    location_accuracy_m: row.location_accuracy_m,
+    distance_m: row.distance_m,
+    risk_flags: formatRiskFlags(row.risk_flags),
+  };
+}
@@ -0,0 +1,111 @@
+import crypto from 'node:crypto';
+import type { PoolClient } from 'pg';
+import { env } from '../env.js';
+import { emailConfigured, loadRestaurantName, queueEmail } from './email.js';
+import { passwordResetEmail } from './email-templates.js';
+import type { Queryable } from './pricing.js';
+
+// Password resets. A reset token is 32 random bytes handed out once, in the
+// link; only its SHA-256 hash is stored, so a copy of the database can't be
+// used to reset anyone's password. A token works once, until
+// PASSWORD_RESET_TTL_MINUTES after it was issued, and using one cancels the
+// user's other outstanding tokens.
+
+export const PASSWORD_RESET_TEMPLATE = 'password_reset';
+
+// A user gets at most one reset email per this many seconds, however often
+// the form is submitted
+const REQUEST_INTERVAL_SECONDS = 60;
+
+function hashToken(token: string): string {
+  return crypto.createHash('sha256').update(token).digest('hex');
+}
+
+export function passwordResetLink(token: string): string {
+  return `${env.PUBLIC_APP_URL.replace(/\/+$/, '')}/login?reset_token=${encodeURIComponent(token)}`;
+}
+
+// ── IssuePasswordReset ──────────────────────────────────────────────────────
+// Creates a token for the user and emails the link. In development without
+// SMTP settings the link is written to the log instead. Returns false when
+// the user was sent one moments ago.
+
+export interface ResetUser {
+  id: string;
+  email: string;
+  name: string;
+}
+
+export async function issuePasswordReset(q: Queryable, user: ResetUser, ip: string | null): Promise<boolean> {
+  const recent = await q.query(
+    `SELECT 1 FROM password_reset_tokens
+     WHERE user_id = $1 AND created_at > NOW() - make_interval(secs => $2)`,
+    [user.id, REQUEST_INTERVAL_SECONDS],
+  );
+  if (recent.rows.length > 0) return false;
+
+  const token = crypto.randomBytes(32).toString('base64url');
+  await q.query(
+    `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, requested_ip)
+     VALUES ($1, $2, NOW() + make_interval(mins => $3), $4)`,
+    [user.id, hashToken(token), env.PASSWORD_RESET_TTL_MINUTES, ip],
+  );
+
+  const link = passwordResetLink(token);
+  if (env.NODE_ENV === 'development' && !(await emailConfigured(q))) {
+    console.log(`Password reset link for ${user.email} (email is not configured): ${link}`);
+    return true;
+  }
+
+  const email = passwordResetEmail(await loadRestaurantName(q), {
+    name: user.name,
+    link,
+    expiresMinutes: env.PASSWORD_RESET_TTL_MINUTES,
+  });
+  await queueEmail(q, {
+    to: [user.email],
+    template: PASSWORD_RESET_TEMPLATE,
+    subject: email.subject,
+    text: email.text,
+    relatedType: 'user',
+    relatedId: user.id,
+  });
+  return true;
+}
+
+/** The account an unused, unexpired token is for, or null. Doesn't use the token up. */
+export async function findPasswordResetUser(
+  q: Queryable,
+  token: string,
+): Promise<{ id: string; username: string; email: string } | null> {
+  const res = await q.query(
+    `SELECT u.id, u.username, u.email
+     FROM password_reset_tokens t
+     JOIN users u ON u.id = t.user_id
+     WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW()
+       AND u.is_active = true AND u.deleted_at IS NULL`,
+    [hashToken(token)],
+  );
+  return res.rows[0] ?? null;
+}
+
+// ── ConsumePasswordReset ────────────────────────────────────────────────────
+// Marks the token used and cancels the user's other tokens. Returns the
+// user ID, or null when the token is unknown, used or expired.
+
+export async function consumePasswordReset(client: PoolClient, token: string): Promise<string | null> {
+  const res = await client.query(
+    `UPDATE password_reset_tokens SET used_at = NOW()
+     WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+     RETURNING user_id`,
+    [hashToken(token)],
+  );
+  if (res.rows.length === 0) return null;
+
+  const userId: string = res.rows[0].user_id;
+  await client.query(
+    'UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL',
+    [userId],
+  );
+  return userId;
+}
@@ -0,0 +1,257 @@
+import type { PoolClient } from 'pg';
+import { txFailure, type TxFailure } from '../db/transaction.js';
+import { withFormatted, type Formatter } from '../lib/format.js';
+import { redeemFromWallet } from './corporate-wallet.js';
+import { chargeOnAccount } from './corporate-billing.js';
+import { loadCashRounding, roundCash } from './cash-rounding.js';
+import { emitWebhookEvent, orderEventData, paymentEventData } from './webhooks.js';
+import { paymentSurcharge } from './payment-methods.js';
+import type { Queryable } from './pricing.js';
+
+// Batch payments (group billing): one tender settling several orders, e.g. a
+// company guest paying for every table at once. Each order still gets its
+// own payment row, so refunds, reports and the order's balance work as they
+// do for any payment; the rows share a payment_batches entry, which is what
+// the combined receipt is printed from.
+//
+// The tender is allocated in the order the orders were listed, each order's
+// balance in full before the next, so a tender short of the combined
+// balance leaves the last orders partly paid. Cash that settles the
+// combined balance is rounded once, on the combined figure, with the
+// adjustment on the last payment. A method surcharge is worked out on each
+// order's share, so every payment row carries its own.
+
+export const MAX_BATCH_ORDERS = 20;
+
+export interface BatchPaymentInput {
+  orderIds: string[];
+  paymentMethod: string;
+  surchargePercent: number;
+  amount: number;
+  referenceNumber?: string;
+  employeeCode?: string;
+  corporateAccountId?: string;
+  userId: string;
+  /** The cashier's branch; orders of other branches are not found */
+  branchId: string | null;
+}
+
+export interface BatchAllocation {
+  orderId: string;
+  paymentId: string;
+  amount: number;
+  completed: boolean;
+}
+
+// ── PayOrderBatch ───────────────────────────────────────────────────────────
+
+export async function payOrderBatch(
+  client: PoolClient,
+  input: BatchPaymentInput,
+): Promise<{ ok: true; batchId: string; allocations: BatchAllocation[] } | TxFailure> {
+  // Locked in ID order, so two batches sharing orders can't deadlock
+  const orderRes = await client.query(
+    `SELECT o.id, o.order_number, o.status, o.branch_id, o.table_id, o.total_amount::float8 AS total_amount,
+            COALESCE((SELECT SUM(p.amount) FROM payments p WHERE p.order_id = o.id AND p.status = 'completed'), 0)::float8 AS paid
+     FROM orders o
+     WHERE o.id = ANY($1::uuid[])
+     ORDER BY o.id
+     FOR UPDATE OF o`,
+    [input.orderIds],
+  );
+  const byId = new Map(
+    orderRes.rows
+      .filter((row) => !input.branchId || row.branch_id === input.branchId)
+      .map((row) => [row.id as string, row]),
+  );
+  const missing = input.orderIds.filter((id) => !byId.has(id));
+  if (missing.length > 0) {
+    return txFailure(`Orders not found: ${missing.join(', ')}`, 'order_not_found', 404);
+  }
+  const orders = input.orderIds.map((id) => byId.get(id)!);
+
+  const closed = orders.filter((o) => o.status === 'cancelled' || o.status === 'completed');
+  if (closed.length > 0) {
+    const list = closed.map((o) => `${o.order_number} is ${o.status}`).join(', ');
+    return txFailure(`Orders cannot be paid: ${list}`, 'invalid_order_status', 400);
+  }
+  if (new Set(orders.map((o) => o.branch_id)).size > 1) {
+    return txFailure('All orders in a batch must belong to the same branch', 'mixed_branches', 400);
+  }
+  const paidUp = orders.filter((o) => o.paid >= o.total_amount);
+  if (paidUp.length > 0) {
+    return txFailure(`Already fully paid: ${paidUp.map((o) => o.order_number).join(', ')}`, 'order_fully_paid', 400);
+  }
+
+  const round2 = (n: number) => Math.round(n * 100) / 100;
+  const combined = round2(orders.reduce((sum, o) => sum + (o.total_amount - o.paid), 0));
+  let amount = input.amount;
+  let roundingAdjustment = 0;
+  if (input.paymentMethod === 'cash') {
+    const cashDue = roundCash(combined, await loadCashRounding(client, orders[0].branch_id));
+    if (amount === combined || amount === cashDue) {
+      roundingAdjustment = round2(cashDue - combined);
+      amount = combined;
+    }
+  }
+  if (amount > combined) {
+    return txFailure(`Payment amount exceeds the combined balance of ${combined}`, 'amount_exceeds_balance', 400);
+  }
+
+  const reference = (input.paymentMethod === 'corporate_wallet' ? input.employeeCode : input.referenceNumber) || null;
+  const batchRes = await client.query(
+    `INSERT INTO payment_batches (branch_id, payment_method, amount, rounding_adjustment, reference_number, order_count, processed_by)
+     VALUES ($1, $2, $3, $4, $5, $6, $7)
+     RETURNING id`,
+    [orders[0].branch_id, input.paymentMethod, amount, roundingAdjustment, reference, orders.length, input.userId],
+  );
+  const batchId: string = batchRes.rows[0].id;
+
+  const allocations: BatchAllocation[] = [];
+  let remaining = amount;
+  for (const order of orders) {
+    const balance = round2(order.total_amount - order.paid);
+    const share = Math.min(balance, remaining);
+    if (share <= 0) break;
+    remaining = round2(remaining - share);
+    const last = remaining <= 0;
+
+    const surcharge = paymentSurcharge({ surcharge_percent: input.surchargePercent }, share);
+    const paymentRes = await client.query(
+      `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at,
+                             rounding_adjustment, surcharge_amount, batch_id)
+       VALUES ($1, $2, $3, $4, 'completed', $5, NOW(), $6, $7, $8)
+       RETURNING id`,
+      [order.id, input.paymentMethod, share, reference, input.userId, last ? roundingAdjustment : 0, surcharge, batchId],
+    );
+    const paymentId: string = paymentRes.rows[0].id;
+
+    // The company is billed for the surcharge too
+    const billed = round2(share + surcharge);
+    if (input.paymentMethod === 'corporate_wallet') {
+      const result = await redeemFromWallet(client, {
+        employeeCode: input.employeeCode!, amount: billed, orderId: order.id, paymentId, userId: input.userId,
+      });
+      if (!result.ok) return txFailure(result.failure.message, result.failure.code, result.failure.status);
+    }
+    if (input.paymentMethod === 'on_account') {
+      const result = await chargeOnAccount(client, {
+        accountId: input.corporateAccountId!, amount: billed, orderId: order.id, paymentId, userId: input.userId,
+      });
+      if (!result.ok) return txFailure(result.failure.message, result.failure.code, result.failure.status);
+    }
+
+    // Completed as a single payment would complete it; the table is freed
+    // once no other order is still open on it
+    const completed = share >= balance && order.status !== 'scheduled' && order.status !== 'parked';
+    if (completed) {
+      await client.query(
+        `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+        [order.id],
+      );
+      await client.query(
+        `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+         VALUES ($1, $2, 'completed', $3, 'Order completed after batch payment')`,
+        [order.id, order.status, input.userId],
+      );
+      if (order.table_id) {
+        await client.query(
+          `UPDATE dining_tables SET is_occupied = false
+           WHERE id = $1 AND NOT EXISTS (
+             SELECT 1 FROM orders WHERE table_id = $1 AND id <> $2 AND status NOT IN ('completed', 'cancelled')
+           )`,
+          [order.table_id, order.id],
+        );
+      }
+      await emitWebhookEvent(client, 'order.completed', () => orderEventData(client, order.id));
+    }
+    await emitWebhookEvent(client, 'payment.processed', () => paymentEventData(client, paymentId, 'staff'));
+
+    allocations.push({ orderId: order.id, paymentId, amount: share, completed });
+  }
+
+  return { ok: true, batchId, allocations };
+}
+
+// ── LoadBatchReceipt ────────────────────────────────────────────────────────
+// The combined receipt: every order with its items and what this batch paid
+// towards it, the combined totals and the tender. Null for an unknown batch,
+// or one taken in another branch than `branchId` when that is given.
+
+const ORDER_AMOUNTS = ['subtotal', 'tax_amount', 'service_charge_amount', 'discount_amount', 'total_amount', 'paid', 'balance'];
+
+export async function loadBatchReceipt(q: Queryable, batchId: string, fmt: Formatter, branchId: string | null = null) {
+  const batchRes = await q.query(
+    `SELECT pb.id, pb.branch_id, pb.payment_method, pb.amount::float8 AS amount,
+            pb.rounding_adjustment::float8 AS rounding_adjustment,
+            (SELECT COALESCE(SUM(p.surcharge_amount), 0) FROM payments p
+             WHERE p.batch_id = pb.id AND p.refund_of IS NULL)::float8 AS surcharge_amount,
+            pb.reference_number, pb.order_count,
+            pb.processed_by, pb.created_at,
+            NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS processed_by_name
+     FROM payment_batches pb
+     LEFT JOIN users u ON u.id = pb.processed_by
+     WHERE pb.id = $1 AND ($2::uuid IS NULL OR pb.branch_id = $2)`,
+    [batchId, branchId],
+  );
+  const batch = batchRes.rows[0];
+  if (!batch) return null;
+
+  const orderRes = await q.query(
+    `SELECT o.id AS order_id, o.order_number, o.customer_name, o.status, t.table_number,
+            o.subtotal::float8 AS subtotal, o.tax_amount::float8 AS tax_amount,
+            o.service_charge_amount::float8 AS service_charge_amount, o.discount_amount::float8 AS discount_amount,
+            o.total_amount::float8 AS total_amount, p.amount::float8 AS paid,
+            (o.total_amount - COALESCE((SELECT SUM(p2.amount) FROM payments p2
+                                        WHERE p2.order_id = o.id AND p2.status = 'completed'), 0))::float8 AS balance
+     FROM payments p
+     JOIN orders o ON o.id = p.order_id
+     LEFT JOIN dining_tables t ON t.id = o.table_id
+     WHERE p.batch_id = $1 AND p.refund_of IS NULL
+     ORDER BY p.created_at, p.id`,
+    [batchId],
+  );
+  const itemRes = await q.query(
+    `SELECT oi.order_id, pr.name, oi.quantity::float8 AS quantity,
+            oi.unit_price::float8 AS unit_price, oi.total_price::float8 AS total_price
+     FROM order_items oi
+     JOIN products pr ON pr.id = oi.product_id
+     WHERE oi.order_id = ANY($1::uuid[])
+     ORDER BY oi.created_at, oi.id`,
+    [orderRes.rows.map((r) => r.order_id)],
+  );
+  const itemsByOrder = new Map<string, Record<string, unknown>[]>();
+  for (const item of itemRes.rows) {
+    const list = itemsByOrder.get(item.order_id) ?? [];
+    list.push(withFormatted(
+      { name: item.name, quantity: item.quantity, unit_price: item.unit_price, total_price: item.total_price },
+      ['unit_price', 'total_price'],
+      fmt.money,
+    ));
+    itemsByOrder.set(item.order_id, list);
+  }
+
+  const sum = (key: string) => Math.round(orderRes.rows.reduce((s, r) => s + Number(r[key]), 0) * 100) / 100;
+  const totals = {
+    subtotal: sum('subtotal'),
+    tax_amount: sum('tax_amount'),
+    service_charge_amount: sum('service_charge_amount'),
+    discount_amount: sum('discount_amount'),
+    total_amount: sum('total_amount'),
+    paid: sum('paid'),
+    balance: sum('balance'),
+  };
+
+  const collected = batch.amount + batch.rounding_adjustment + batch.surcharge_amount;
+  return {
+    ...withFormatted(batch, ['amount', 'rounding_adjustment', 'surcharge_amount'], fmt.money),
+    ...(batch.payment_method === 'cash'
+      ? { cash_collected: collected, cash_collected_formatted: fmt.money(collected) }
+      : {}),
+    orders: orderRes.rows.map((row) => ({
+      ...withFormatted(row, ORDER_AMOUNTS, fmt.money),
+      items: itemsByOrder.get(row.order_id) ?? [],
+    })),
+    totals: withFormatted(totals, ORDER_AMOUNTS, fmt.money),
+  };
+}
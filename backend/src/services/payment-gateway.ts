@@ -0,0 +1,272 @@
+import crypto from 'node:crypto';
+import { env } from '../env.js';
+
+// Payment gateway client (Midtrans Snap). Online flows such as wallet top-ups
+// create a charge here and are settled asynchronously via the notification
+// webhook, which is verified with verifyNotification(). Refunds of a charge
+// go through the Core API and are confirmed the same way, as are captures
+// and cancellations of card pre-authorizations (bar tabs).
+
+export interface GatewayCustomer {
+  name?: string;
+  email?: string;
+  phone?: string;
+}
+
+export interface GatewayChargeRequest {
+  orderId: string;
+  amount: number;
+  description: string;
+  customer?: GatewayCustomer;
+  expiryMinutes?: number;
+  /** Card only, and the amount is held on the card rather than charged */
+  authorizeOnly?: boolean;
+}
+
+export interface GatewayChargeResult {
+  token: string;
+  redirect_url: string;
+}
+
+export interface GatewayNotification {
+  order_id: string;
+  status_code: string;
+  gross_amount: string;
+  signature_key: string;
+  transaction_status: string;
+  transaction_id?: string;
+  payment_type?: string;
+  fraud_status?: string;
+  /** Refund notifications: total refunded on the transaction so far */
+  refund_amount?: string;
+  refunds?: { refund_key?: string; refund_amount?: string }[];
+}
+
+export interface GatewayRefundRequest {
+  /** Idempotency key; resending the same key never refunds twice */
+  refundKey: string;
+  amount: number;
+  reason: string;
+}
+
+// refunded: done; pending: accepted, confirmed later by notification;
+// rejected: the gateway won't refund (not retried)
+export interface GatewayRefundResult {
+  outcome: 'refunded' | 'pending' | 'rejected';
+  status_code: string;
+  message: string;
+}
+
+// Capture or cancellation of a pre-authorization. done: applied; rejected:
+// the gateway refused it (the authorization lapsed, was already captured...)
+export interface GatewayAuthorizationResult {
+  outcome: 'done' | 'rejected';
+  status_code: string;
+  message: string;
+}
+
+export type GatewayOutcome = 'paid' | 'pending' | 'failed';
+
+const SNAP_SANDBOX_URL = 'https://app.sandbox.midtrans.com/snap/v1/transactions';
+const SNAP_PRODUCTION_URL = 'https://app.midtrans.com/snap/v1/transactions';
+const CORE_SANDBOX_URL = 'https://api.sandbox.midtrans.com/v2';
+const CORE_PRODUCTION_URL = 'https://api.midtrans.com/v2';
+
+export function isGatewayConfigured(): boolean {
+  return env.PAYMENT_GATEWAY_SERVER_KEY !== '';
+}
+
+function authHeader(): string {
+  return 'Basic ' + Buffer.from(`${env.PAYMENT_GATEWAY_SERVER_KEY}:`).toString('base64');
+}
+
+// ── CreateCharge ────────────────────────────────────────────────────────────
+
+export async function createCharge(req: GatewayChargeRequest): Promise<GatewayChargeResult> {
+  if (!isGatewayConfigured()) {
+    throw new Error('Payment gateway is not configured');
+  }
+
+  const payload: Record<string, unknown> = {
+    transaction_details: {
+      order_id: req.orderId,
+      // Snap only accepts whole rupiah amounts
+      gross_amount: Math.round(req.amount),
+    },
+    item_details: [{
+      id: req.orderId,
+      name: req.description.slice(0, 50),
+      price: Math.round(req.amount),
+      quantity: 1,
+    }],
+  };
+
+  if (req.customer) {
+    payload.customer_details = {
+      first_name: req.customer.name,
+      email: req.customer.email,
+      phone: req.customer.phone,
+    };
+  }
+
+  if (req.expiryMinutes) {
+    payload.expiry = { unit: 'minutes', duration: req.expiryMinutes };
+  }
+
+  if (req.authorizeOnly) {
+    payload.enabled_payments = ['credit_card'];
+    payload.credit_card = { secure: true, type: 'authorize' };
+  }
+
+  const res = await fetch(env.PAYMENT_GATEWAY_PRODUCTION ? SNAP_PRODUCTION_URL : SNAP_SANDBOX_URL, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Accept: 'application/json',
+      Authorization: authHeader(),
+    },
+    body: JSON.stringify(payload),
+    signal: AbortSignal.timeout(10_000),
+  });
+
+  const data = await res.json().catch(() => ({})) as Record<string, unknown>;
+  if (!res.ok || typeof data.token !== 'string') {
+    const messages = Array.isArray(data.error_messages) ? data.error_messages.join('; ') : res.statusText;
+    throw new Error(`Payment gateway rejected charge: ${messages}`);
+  }
+
+  return { token: data.token, redirect_url: String(data.redirect_url ?? '') };
+}
+
+// ── RequestRefund ───────────────────────────────────────────────────────────
+// Throws on network errors and gateway outages so the caller can retry with
+// the same refund key.
+
+export async function requestRefund(gatewayOrderId: string, req: GatewayRefundRequest): Promise<GatewayRefundResult> {
+  if (!isGatewayConfigured()) {
+    throw new Error('Payment gateway is not configured');
+  }
+
+  const base = env.PAYMENT_GATEWAY_PRODUCTION ? CORE_PRODUCTION_URL : CORE_SANDBOX_URL;
+  const res = await fetch(`${base}/${encodeURIComponent(gatewayOrderId)}/refund`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Accept: 'application/json',
+      Authorization: authHeader(),
+    },
+    body: JSON.stringify({
+      refund_key: req.refundKey,
+      // Whole rupiah, like the charge
+      amount: Math.round(req.amount),
+      reason: req.reason.slice(0, 255),
+    }),
+    signal: AbortSignal.timeout(10_000),
+  });
+  if (res.status >= 500) {
+    throw new Error(`Payment gateway unavailable: HTTP ${res.status}`);
+  }
+
+  // The Core API reports its own status code in the body
+  const data = await res.json().catch(() => ({})) as Record<string, unknown>;
+  const statusCode = String(data.status_code ?? res.status);
+  const message = String(data.status_message ?? res.statusText);
+  if (statusCode.startsWith('5')) {
+    throw new Error(`Payment gateway unavailable: ${message}`);
+  }
+  if (statusCode === '200') return { outcome: 'refunded', status_code: statusCode, message };
+  if (statusCode === '201') return { outcome: 'pending', status_code: statusCode, message };
+  return { outcome: 'rejected', status_code: statusCode, message };
+}
+
+// ── CaptureAuthorization ────────────────────────────────────────────────────
+// Charges up to the authorized amount; the gateway releases the rest of the
+// hold. Throws on network errors and outages, like requestRefund.
+
+export async function captureAuthorization(transactionId: string, amount: number): Promise<GatewayAuthorizationResult> {
+  return coreRequest('/capture', { transaction_id: transactionId, gross_amount: Math.round(amount) });
+}
+
+// ── CancelAuthorization ─────────────────────────────────────────────────────
+// Releases the whole hold without charging anything.
+
+export async function cancelAuthorization(gatewayOrderId: string): Promise<GatewayAuthorizationResult> {
+  return coreRequest(`/${encodeURIComponent(gatewayOrderId)}/cancel`, null);
+}
+
+async function coreRequest(path: string, body: Record<string, unknown> | null): Promise<GatewayAuthorizationResult> {
+  if (!isGatewayConfigured()) {
+    throw new Error('Payment gateway is not configured');
+  }
+
+  const base = env.PAYMENT_GATEWAY_PRODUCTION ? CORE_PRODUCTION_URL : CORE_SANDBOX_URL;
+  const res = await fetch(`${base}${path}`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Accept: 'application/json',
+      Authorization: authHeader(),
+    },
+    body: body ? JSON.stringify(body) : undefined,
+    signal: AbortSignal.timeout(10_000),
+  });
+  if (res.status >= 500) {
+    throw new Error(`Payment gateway unavailable: HTTP ${res.status}`);
+  }
+
+  const data = await res.json().catch(() => ({})) as Record<string, unknown>;
+  const statusCode = String(data.status_code ?? res.status);
+  const message = String(data.status_message ?? res.statusText);
+  if (statusCode.startsWith('5')) {
+    throw new Error(`Payment gateway unavailable: ${message}`);
+  }
+  return { outcome: statusCode === '200' ? 'done' : 'rejected', status_code: statusCode, message };
+}
+
+export function isRefundNotification(n: GatewayNotification): boolean {
+  return n.transaction_status === 'refund' || n.transaction_status === 'partial_refund';
+}
+
+// ── VerifyNotification ──────────────────────────────────────────────────────
+// signature_key = SHA512(order_id + status_code + gross_amount + server_key)
+
+export function verifyNotification(n: GatewayNotification): boolean {
+  if (!isGatewayConfigured() || !n.signature_key) return false;
+
+  const expected = crypto
+    .createHash('sha512')
+    .update(`${n.order_id}${n.status_code}${n.gross_amount}${env.PAYMENT_GATEWAY_SERVER_KEY}`)
+    .digest('hex');
+
+  const a = Buffer.from(expected);
+  const b = Buffer.from(n.signature_key);
+  return a.length === b.length && crypto.timingSafeEqual(a, b);
+}
+
+export function notificationOutcome(n: GatewayNotification): GatewayOutcome {
+  switch (n.transaction_status) {
+    case 'settlement':
+      return 'paid';
+    case 'capture':
+      return n.fraud_status === 'challenge' ? 'pending' : 'paid';
+    case 'pending':
+      return 'pending';
+    default:
+      // deny, cancel, expire, failure
+      return 'failed';
+  }
+}
+
+/**
+ * Gateway order ids are "<PREFIX>-<uuid>" so the webhook can route each
+ * notification to the flow that created the charge.
+ */
+export function gatewayOrderId(prefix: string, id: string): string {
+  return `${prefix}-${id}`;
+}
+
+export function parseGatewayOrderId(orderId: string): { prefix: string; id: string } | null {
+  const idx = orderId.indexOf('-');
+  if (idx <= 0) return null;
+  return { prefix: orderId.slice(0, idx), id: orderId.slice(idx + 1) };
+}
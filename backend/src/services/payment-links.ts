@@ -0,0 +1,153 @@
+import { withTransaction } from '../db/transaction.js';
+import type { Queryable } from './pricing.js';
+import type { GatewayNotification, GatewayOutcome } from './payment-gateway.js';
+import { createNotificationForRole } from './notification.js';
+import { emitWebhookEvent, orderEventData, paymentEventData } from './webhooks.js';
+import { paymentsProcessedTotal, paymentsAmountTotal } from '../lib/metrics.js';
+
+// Payment links. The counter creates a gateway-hosted payment page for an
+// order's outstanding balance (phone and delivery orders) and sends it to the
+// customer. The gateway webhook settles the link: the payment is recorded
+// against the order and, once it is fully paid, the order completes exactly
+// as it would at the till.
+
+export const PAYMENT_LINK_GATEWAY_PREFIX = 'PAYLINK';
+
+const DEFAULT_EXPIRY_MINUTES = 60;
+
+export type PaymentLinkStatus = 'pending' | 'paid' | 'expired' | 'failed' | 'cancelled';
+
+export async function loadPaymentLinkExpiryMinutes(q: Queryable): Promise<number> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'payment_link_expiry_minutes'`);
+  const value = parseInt(res.rows[0]?.setting_value ?? '', 10);
+  return isNaN(value) || value < 5 ? DEFAULT_EXPIRY_MINUTES : value;
+}
+
+/** Status as shown to staff: pending links past their expiry are expired. */
+export const PAYMENT_LINK_STATUS_SQL = `CASE WHEN pl.status = 'pending' AND pl.expires_at <= NOW() THEN 'expired' ELSE pl.status END`;
+
+export async function loadOrderPaymentLinks(q: Queryable, orderId: string) {
+  const res = await q.query(
+    `SELECT pl.id, pl.amount, ${PAYMENT_LINK_STATUS_SQL} AS status, pl.payment_url, pl.expires_at,
+            pl.sent_to, pl.payment_id, pl.created_at, pl.paid_at
+     FROM payment_links pl
+     WHERE pl.order_id = $1
+     ORDER BY pl.created_at DESC`,
+    [orderId],
+  );
+  return res.rows.map((row: Record<string, unknown>) => ({ ...row, amount: Number(row.amount) }));
+}
+
+// Cards settle as card payments; everything else the gateway offers (QRIS,
+// e-wallets, bank transfer) is recorded as a digital wallet payment.
+function paymentMethodFor(gatewayPaymentType: string | undefined): string {
+  return gatewayPaymentType === 'credit_card' ? 'credit_card' : 'digital_wallet';
+}
+
+// ── SettlePaymentLink ───────────────────────────────────────────────────────
+// Applies a gateway notification to a link. Idempotent: only a pending (or
+// superseded) link is settled, so retried notifications are ignored. A link
+// paid after the balance was settled some other way is still recorded — the
+// money has been taken — and managers are asked to refund the difference.
+
+export async function settlePaymentLink(
+  linkId: string,
+  outcome: GatewayOutcome,
+  notification: GatewayNotification,
+): Promise<void> {
+  if (outcome === 'pending') return;
+
+  const settled = await withTransaction(async (client) => {
+    const linkRes = await client.query(
+      `SELECT pl.id, pl.order_id, pl.amount, pl.status, pl.created_by, o.order_number, o.total_amount, o.status AS order_status
+       FROM payment_links pl
+       JOIN orders o ON o.id = pl.order_id
+       WHERE pl.id = $1
+       FOR UPDATE OF pl, o`,
+      [linkId],
+    );
+    const link = linkRes.rows[0];
+    if (!link || !['pending', 'cancelled'].includes(link.status)) return null;
+
+    if (outcome === 'failed') {
+      const status = notification.transaction_status === 'expire' ? 'expired' : 'failed';
+      await client.query(
+        `UPDATE payment_links SET status = $1, updated_at = NOW() WHERE id = $2 AND status = 'pending'`,
+        [status, link.id],
+      );
+      return null;
+    }
+
+    const amount = Number(link.amount);
+    const method = paymentMethodFor(notification.payment_type);
+
+    const paidRes = await client.query(
+      "SELECT COALESCE(SUM(amount), 0) AS total_paid FROM payments WHERE order_id = $1 AND status = 'completed'",
+      [link.order_id],
+    );
+    const totalPaid = Number(paidRes.rows[0].total_paid);
+    const orderTotal = Number(link.total_amount);
+
+    const paymentRes = await client.query(
+      `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at)
+       VALUES ($1, $2, $3, $4, 'completed', $5, NOW())
+       RETURNING id`,
+      [link.order_id, method, amount, notification.transaction_id || notification.order_id, link.created_by],
+    );
+
+    await client.query(
+      `UPDATE payment_links
+       SET status = 'paid', payment_id = $1, gateway_transaction_id = $2, paid_at = NOW(), updated_at = NOW()
+       WHERE id = $3`,
+      [paymentRes.rows[0].id, notification.transaction_id || null, link.id],
+    );
+
+    // Same completion rule as a counter payment
+    const closed = ['completed', 'cancelled'].includes(link.order_status);
+    if (!closed && totalPaid + amount >= orderTotal && link.order_status !== 'scheduled' && link.order_status !== 'parked') {
+      await client.query(
+        `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+        [link.order_id],
+      );
+      await client.query(
+        `UPDATE dining_tables SET is_occupied = false
+         WHERE id IN (SELECT table_id FROM orders WHERE id = $1 AND table_id IS NOT NULL)`,
+        [link.order_id],
+      );
+      await client.query(
+        `INSERT INTO order_status_history (order_id, previous_status, new_status, notes)
+         VALUES ($1, $2, 'completed', 'Order completed after payment link was paid')`,
+        [link.order_id, link.order_status],
+      );
+      await emitWebhookEvent(client, 'order.completed', () => orderEventData(client, link.order_id));
+    }
+
+    await emitWebhookEvent(client, 'payment.processed', () => paymentEventData(client, paymentRes.rows[0].id, 'payment_link'));
+
+    await client.query(
+      'INSERT INTO order_notifications (order_id, status, message, is_read) VALUES ($1, $2, $3, false)',
+      [link.order_id, 'payment', 'Payment received. Thank you!'],
+    );
+
+    return { link, amount, method, totalPaid, orderTotal, closed };
+  });
+  if (!settled) return;
+  const { link, amount, method, totalPaid, orderTotal, closed } = settled;
+
+  paymentsProcessedTotal.inc({ method, status: 'completed', source: 'payment_link' });
+  paymentsAmountTotal.inc({ method }, amount);
+
+  // A closed order gets nothing from the payment; an open one only what it still owed
+  const refundDue = closed ? amount : Math.max(0, totalPaid + amount - orderTotal);
+  if (refundDue > 0) {
+    await createNotificationForRole(
+      'manager',
+      'system_alert',
+      'Payment Link Overpaid',
+      `Order ${link.order_number} received ${amount} through a payment link after it was settled; refund ${refundDue} to the customer`,
+      'payment_link_overpaid',
+    );
+  } else {
+    await createNotificationForRole('counter', 'order_update', 'Payment Link Paid', `Order ${link.order_number} was paid online (${amount})`, 'payment_link_paid');
+  }
+}
@@ -0,0 +1,56 @@
+import type { Queryable } from './pricing.js';
+
+// Payment methods. The list lives in payment_methods and is managed by
+// admins: display name, a surcharge percentage, whether it can be used at
+// all and whether guests can pick it on the QR order page. The methods the
+// POS shipped with are system methods and can't be deleted; corporate_wallet
+// and on_account keep their special handling (employee code, credit line)
+// however they are named.
+//
+// A surcharge is charged on top of what is applied to the order, e.g. 2%
+// on credit cards: the payment's amount still settles the balance and
+// surcharge_amount records what the guest paid in addition.
+
+export const PAYMENT_METHOD_CODE_RE = /^[a-z][a-z0-9_]{1,19}$/;
+export const MAX_SURCHARGE_PERCENT = 100;
+
+export interface PaymentMethod {
+  code: string;
+  display_name: string;
+  surcharge_percent: number;
+  is_active: boolean;
+  customer_selectable: boolean;
+  sort_order: number;
+  is_system: boolean;
+}
+
+export const PAYMENT_METHOD_SELECT = `
+  SELECT code, display_name, surcharge_percent::float8 AS surcharge_percent, is_active,
+         customer_selectable, sort_order, is_system, created_at, updated_at
+  FROM payment_methods`;
+
+const ORDER_BY = 'ORDER BY sort_order ASC, code ASC';
+
+export async function loadPaymentMethods(
+  q: Queryable,
+  filter: { activeOnly?: boolean; customerOnly?: boolean } = {},
+): Promise<PaymentMethod[]> {
+  const conditions: string[] = [];
+  if (filter.activeOnly) conditions.push('is_active = true');
+  if (filter.customerOnly) conditions.push('customer_selectable = true');
+  const where = conditions.length > 0 ? ` WHERE ${conditions.join(' AND ')}` : '';
+  const res = await q.query(`${PAYMENT_METHOD_SELECT}${where} ${ORDER_BY}`);
+  return res.rows;
+}
+
+/** The method a new payment may use, or null when it is unknown or switched off. */
+export async function findActivePaymentMethod(q: Queryable, code: unknown): Promise<PaymentMethod | null> {
+  if (typeof code !== 'string' || !PAYMENT_METHOD_CODE_RE.test(code)) return null;
+  const res = await q.query(`${PAYMENT_METHOD_SELECT} WHERE code = $1 AND is_active = true`, [code]);
+  return res.rows[0] ?? null;
+}
+
+export function paymentSurcharge(method: Pick<PaymentMethod, 'surcharge_percent'>, amount: number): number {
+  if (!(amount > 0) || !(method.surcharge_percent > 0)) return 0;
+  return Math.round(amount * method.surcharge_percent) / 100;
+}
@@ -0,0 +1,96 @@
+import { pool } from '../db/connection.js';
+import type { Queryable } from './pricing.js';
+
+// Permissions. Routes and handlers check permissions, never role names;
+// which permissions a role has is data (role_permissions), edited from the
+// admin panel. The definitions live here because each one corresponds to a
+// check in the code.
+
+export const PERMISSIONS: Record<string, string> = {
+  'orders.update_status': 'Move orders through their statuses',
+  'orders.edit_items': 'Add, change and remove items on open orders',
+  'orders.edit_sent_items': 'Reduce or remove items the kitchen has already started',
+  'orders.remake': 'Send a returned dish back to the kitchen for a free remake',
+  'orders.create': 'Create any order type at the counter',
+  'orders.create_dine_in': 'Create dine-in orders at the table',
+  'orders.park': 'Park orders at the counter and resume them',
+  'orders.fire_courses': 'Hold dine-in courses and fire them to the kitchen',
+  'orders.view_all_sections': 'See every order, not just those of the tables in your section',
+  'payments.process': 'Take payments',
+  'payments.refund': 'Refund payments',
+  'payments.links': 'Create and view payment links',
+  'tabs.manage': 'Open bar tabs against a card authorization and close them',
+  'kitchen.view': 'See the kitchen display',
+  'kitchen.update': 'Update item status from the kitchen',
+  'kitchen.load': 'See kitchen load and wait estimates',
+  'kitchen.waste': 'Log spoilage and prep waste',
+  'kitchen.eighty_six': 'Mark products sold out (86) for the day',
+  'deliveries.manage': 'Dispatch deliveries and assign couriers',
+  'deliveries.courier': 'Deliver orders as a courier',
+  'customer_flags.check': 'Check a phone number for customer flags',
+  'customer_flags.manage': 'Flag and clear customers',
+  'reports.view': 'View the dashboard and reports',
+  'settings.manage': 'Change system settings and restaurant information',
+  'system.selftest': 'Run the deployment self-test',
+  'branches.manage': 'Manage branches and branch settings',
+  'contacts.manage': 'Handle contact form submissions',
+  'surveys.manage': 'Acknowledge satisfaction surveys and reply to guests',
+  'reservations.manage': 'Manage reservations',
+  'inventory.manage': 'Manage product and ingredient stock',
+  'menu.manage': 'Manage categories, products, recipes, specials and images',
+  'menu.edit_products': 'Create and edit products',
+  'tables.manage': 'Manage dining tables',
+  'users.manage': 'Manage staff accounts',
+  'roles.manage': 'Manage roles and permissions',
+  'pricing.manage': 'Manage pricing rules, price schedules and surcharges',
+  'sales_targets.manage': 'Set staff sales targets',
+  'sections.manage': 'Set up server sections and assign servers to them',
+  'commissions.manage': 'Manage commission rules and reports',
+  'attendance.clock': 'Clock in and out',
+  'attendance.manage': 'Schedule shifts, correct attendance and export timesheets',
+  'logbook.manage': 'Write the manager log book',
+  'logbook.delete_any': 'Delete anyone\'s log book entries',
+  'corporate.manage': 'Manage corporate accounts and invoices',
+  'records.view_deleted': 'List deleted records',
+  'jobs.manage': 'Inspect and retry background jobs and replay dead letters',
+  'email.manage': 'Configure email, view the outbox and retry failed emails',
+  'tax.manage': 'Manage tax and service charge exemptions',
+  'accounting.export': 'Export daily journals for the accounting system',
+  'data.export': 'Download data exports (needed alongside the permission for the data itself)',
+  'analytics.export': 'Monitor, run and rewind the analytics export to object storage',
+  'currencies.manage': 'Manage display currencies and exchange rates',
+  'payment_methods.manage': 'Manage payment methods and their surcharges',
+  'costing.manage': 'Set product costs and post COGS adjustments',
+  'stock_takes.post': 'Post or cancel stock takes',
+};
+
+export function isPermission(name: string): boolean {
+  return Object.prototype.hasOwnProperty.call(PERMISSIONS, name);
+}
+
+export async function roleExists(q: Queryable, name: string): Promise<boolean> {
+  const res = await q.query('SELECT 1 FROM roles WHERE name = $1', [name]);
+  return res.rows.length > 0;
+}
+
+// Permissions are read on every authenticated request, so they are cached
+// briefly per role. Edits invalidate this instance's cache immediately and
+// reach other instances within the TTL.
+const CACHE_TTL_MS = 30_000;
+const cache = new Map<string, { permissions: Set<string>; loadedAt: number }>();
+
+export function invalidatePermissionCache(): void {
+  cache.clear();
+}
+
+// ── LoadRolePermissions ─────────────────────────────────────────────────────
+
+export async function loadRolePermissions(role: string): Promise<Set<string>> {
+  const cached = cache.get(role);
+  if (cached && Date.now() - cached.loadedAt < CACHE_TTL_MS) return cached.permissions;
+
+  const res = await pool.query('SELECT permission FROM role_permissions WHERE role = $1', [role]);
+  const permissions = new Set<string>(res.rows.map((r) => r.permission));
+  cache.set(role, { permissions, loadedAt: Date.now() });
+  return permissions;
+}
@@ -0,0 +1,153 @@
+import { localClock, inDailyWindow } from '../lib/clock.js';
+import type { PricingLine, Queryable } from './pricing.js';
+
+// Price schedules (happy hour and the like). During its daily window (WIB)
+// on its days, a schedule replaces the menu price of its products and of
+// everything in its categories, either with a fixed price or a percentage
+// off. A schedule naming the product wins over one naming its category;
+// between equals the lower price wins. The scheduled price becomes the
+// item's unit price, so pricing rules then discount it like any other.
+
+export type SchedulePriceType = 'fixed_price' | 'percentage';
+
+export const SCHEDULE_PRICE_TYPES: SchedulePriceType[] = ['fixed_price', 'percentage'];
+
+export const PRICE_SCHEDULE_REFRESH_JOB = 'price_schedule_refresh';
+
+export interface PriceSchedule {
+  id: string;
+  name: string;
+  description: string | null;
+  product_ids: string[];
+  category_ids: string[];
+  price_type: SchedulePriceType;
+  value: number;
+  days_of_week: number[];
+  start_time: string;
+  end_time: string;
+  is_active: boolean;
+}
+
+export interface ScheduledPrice {
+  price_schedule_id: string;
+  price_schedule_name: string;
+  price: number;
+  regular_price: number;
+}
+
+export interface SchedulableProduct {
+  id: string;
+  category_id: string | null;
+  price: number;
+}
+
+function round2(n: number): number {
+  return Math.round(n * 100) / 100;
+}
+
+export function isSchedulePriceType(value: unknown): value is SchedulePriceType {
+  return typeof value === 'string' && (SCHEDULE_PRICE_TYPES as string[]).includes(value);
+}
+
+export function formatPriceSchedule(row: Record<string, unknown>): PriceSchedule {
+  return {
+    id: row.id as string,
+    name: row.name as string,
+    description: (row.description as string) ?? null,
+    product_ids: (row.product_ids as string[]) ?? [],
+    category_ids: (row.category_ids as string[]) ?? [],
+    price_type: row.price_type as SchedulePriceType,
+    value: Number(row.value),
+    days_of_week: (row.days_of_week as number[]) ?? [],
+    start_time: row.start_time as string,
+    end_time: row.end_time as string,
+    is_active: Boolean(row.is_active),
+  };
+}
+
+// ── ScheduleInEffect ────────────────────────────────────────────────────────
+
+export function scheduleInEffect(schedule: PriceSchedule, at: Date): boolean {
+  if (!schedule.is_active) return false;
+  const clock = localClock(at);
+  if (schedule.days_of_week.length > 0 && !schedule.days_of_week.includes(clock.dayOfWeek)) return false;
+  return inDailyWindow(clock.secondsOfDay, schedule.start_time, schedule.end_time);
+}
+
+export async function loadActiveSchedules(q: Queryable, at: Date = new Date()): Promise<PriceSchedule[]> {
+  const res = await q.query('SELECT * FROM price_schedules WHERE is_active = true ORDER BY created_at ASC');
+  return res.rows.map(formatPriceSchedule).filter((s) => scheduleInEffect(s, at));
+}
+
+// ── ResolveScheduledPrice ───────────────────────────────────────────────────
+// Null when no schedule covers the product.
+
+export function resolveScheduledPrice(schedules: PriceSchedule[], product: SchedulableProduct): ScheduledPrice | null {
+  const byProduct = schedules.filter((s) => s.product_ids.includes(product.id));
+  const candidates = byProduct.length > 0
+    ? byProduct
+    : schedules.filter((s) => product.category_id !== null && s.category_ids.includes(product.category_id));
+
+  let best: ScheduledPrice | null = null;
+  for (const schedule of candidates) {
+    const price = schedule.price_type === 'fixed_price'
+      ? schedule.value
+      : round2(product.price * (1 - schedule.value / 100));
+    if (!best || price < best.price) {
+      best = {
+        price_schedule_id: schedule.id,
+        price_schedule_name: schedule.name,
+        price,
+        regular_price: product.price,
+      };
+    }
+  }
+  return best;
+}
+
+// ── LoadScheduledPrices ─────────────────────────────────────────────────────
+// Scheduled prices at `at`, keyed by product; unscheduled products are left
+// out.
+
+export async function loadScheduledPrices(
+  q: Queryable,
+  products: SchedulableProduct[],
+  at: Date = new Date(),
+): Promise<Map<string, ScheduledPrice>> {
+  const result = new Map<string, ScheduledPrice>();
+  if (products.length === 0) return result;
+
+  const schedules = await loadActiveSchedules(q, at);
+  if (schedules.length === 0) return result;
+
+  for (const product of products) {
+    const scheduled = resolveScheduledPrice(schedules, product);
+    if (scheduled) result.set(product.id, scheduled);
+  }
+  return result;
+}
+
+// ── ApplyPriceSchedules ─────────────────────────────────────────────────────
+// Sets each line's unit_price to its scheduled price, before pricing rules
+// run. Returns the schedule behind each line, null for the menu price.
+
+export async function applyPriceSchedules(q: Queryable, lines: PricingLine[], at: Date = new Date()): Promise<(string | null)[]> {
+  const prices = await loadScheduledPrices(
+    q, lines.map((line) => ({ id: line.product_id, category_id: line.category_id, price: line.unit_price })), at,
+  );
+  return lines.map((line) => {
+    const scheduled = prices.get(line.product_id);
+    if (!scheduled) return null;
+    line.unit_price = scheduled.price;
+    return scheduled.price_schedule_id;
+  });
+}
+
+// ── ActiveScheduleKey ───────────────────────────────────────────────────────
+// Identifies the set of schedules in effect, so the refresh job can tell
+// when a window opens or closes and the cached menu needs dropping.
+
+export async function activeScheduleKey(q: Queryable, at: Date = new Date()): Promise<string> {
+  const schedules = await loadActiveSchedules(q, at);
+  return schedules.map((s) => s.id).sort().join(',');
+}
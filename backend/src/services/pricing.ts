@@ -0,0 +1,210 @@
+import type { Pool, PoolClient } from 'pg';
+import { localClock, inDailyWindow } from '../lib/clock.js';
+
+// Order pricing pipeline. Handlers build PricingLines from the requested
+// items and priceOrder() applies the active pricing rules; the resulting
+// adjustments are persisted per order for auditing.
+
+export type Queryable = Pool | PoolClient;
+
+export interface PricingLine {
+  product_id: string;
+  category_id: string | null;
+  name: string;
+  unit_price: number;
+  quantity: number;
+}
+
+export interface PricingRule {
+  id: string;
+  name: string;
+  rule_type: 'item_discount' | 'bundle';
+  discount_type: 'percentage' | 'fixed';
+  discount_value: number;
+  priority: number;
+  stackable: boolean;
+  product_ids: string[];
+  category_ids: string[];
+  bundle_product_ids: string[];
+  min_quantity: number;
+  days_of_week: number[];
+  start_time: string | null;
+  end_time: string | null;
+  valid_from: string | null;
+  valid_until: string | null;
+  is_active: boolean;
+}
+
+export interface AppliedAdjustment {
+  rule_id: string;
+  rule_name: string;
+  rule_type: PricingRule['rule_type'];
+  amount: number;
+  details: {
+    lines: { product_id: string; quantity: number; amount: number }[];
+    bundles?: number;
+  };
+}
+
+export interface PricingResult {
+  subtotal: number;
+  discount_amount: number;
+  /** Discount per input line, same order as the lines passed in */
+  line_discounts: number[];
+  adjustments: AppliedAdjustment[];
+}
+
+function round2(n: number): number {
+  return Math.round(n * 100) / 100;
+}
+
+export function formatPricingRule(row: Record<string, unknown>): PricingRule {
+  return {
+    id: row.id as string,
+    name: row.name as string,
+    rule_type: row.rule_type as PricingRule['rule_type'],
+    discount_type: row.discount_type as PricingRule['discount_type'],
+    discount_value: Number(row.discount_value),
+    priority: Number(row.priority),
+    stackable: Boolean(row.stackable),
+    product_ids: (row.product_ids as string[]) ?? [],
+    category_ids: (row.category_ids as string[]) ?? [],
+    bundle_product_ids: (row.bundle_product_ids as string[]) ?? [],
+    min_quantity: Number(row.min_quantity ?? 1),
+    days_of_week: (row.days_of_week as number[]) ?? [],
+    start_time: (row.start_time as string) ?? null,
+    end_time: (row.end_time as string) ?? null,
+    valid_from: (row.valid_from as string) ?? null,
+    valid_until: (row.valid_until as string) ?? null,
+    is_active: Boolean(row.is_active),
+  };
+}
+
+// ── RuleInEffect ────────────────────────────────────────────────────────────
+
+export function ruleInEffect(rule: PricingRule, at: Date): boolean {
+  if (!rule.is_active) return false;
+  if (rule.valid_from && new Date(rule.valid_from) > at) return false;
+  if (rule.valid_until && new Date(rule.valid_until) <= at) return false;
+
+  const clock = localClock(at);
+  if (rule.days_of_week.length > 0 && !rule.days_of_week.includes(clock.dayOfWeek)) return false;
+  if (rule.start_time && rule.end_time && !inDailyWindow(clock.secondsOfDay, rule.start_time, rule.end_time)) {
+    return false;
+  }
+
+  return true;
+}
+
+export async function loadActiveRules(q: Queryable, at: Date = new Date()): Promise<PricingRule[]> {
+  const res = await q.query(
+    `SELECT * FROM pricing_rules
+     WHERE is_active = true
+       AND (valid_from IS NULL OR valid_from <= $1)
+       AND (valid_until IS NULL OR valid_until > $1)
+     ORDER BY priority DESC, created_at ASC`,
+    [at.toISOString()],
+  );
+  return res.rows.map(formatPricingRule).filter((r) => ruleInEffect(r, at));
+}
+
+// ── ApplyPricingRules ───────────────────────────────────────────────────────
+// Rules run in priority order. A non-stackable rule only touches lines no
+// other rule has discounted yet, and locks the lines it discounts. Discounts
+// never exceed a line's gross amount.
+
+export function applyPricingRules(lines: PricingLine[], rules: PricingRule[]): PricingResult {
+  const gross = lines.map((l) => l.unit_price * l.quantity);
+  const discounts = lines.map(() => 0);
+  const locked = lines.map(() => false);
+  const adjustments: AppliedAdjustment[] = [];
+
+  const eligible = (rule: PricingRule, i: number) =>
+    !locked[i] && (rule.stackable || discounts[i] === 0) && gross[i] - discounts[i] > 0;
+
+  for (const rule of rules) {
+    const applied: AppliedAdjustment['details']['lines'] = [];
+    const touched: number[] = [];
+    let bundles: number | undefined;
+
+    if (rule.rule_type === 'item_discount') {
+      lines.forEach((line, i) => {
+        if (!eligible(rule, i) || line.quantity < rule.min_quantity) return;
+        if (rule.product_ids.length > 0 && !rule.product_ids.includes(line.product_id)) return;
+        if (rule.category_ids.length > 0 && (!line.category_id || !rule.category_ids.includes(line.category_id))) return;
+
+        const raw = rule.discount_type === 'percentage'
+          ? gross[i] * (rule.discount_value / 100)
+          : rule.discount_value * line.quantity;
+        const amount = round2(Math.min(raw, gross[i] - discounts[i]));
+        if (amount <= 0) return;
+
+        discounts[i] += amount;
+        touched.push(i);
+        applied.push({ product_id: line.product_id, quantity: line.quantity, amount });
+      });
+    } else {
+      // Each bundled product must be present on an eligible line
+      const members = rule.bundle_product_ids.map((pid) =>
+        lines.findIndex((l, i) => l.product_id === pid && eligible(rule, i)));
+      if (members.length < 2 || members.some((i) => i < 0)) continue;
+
+      const sets = Math.min(...members.map((i) => lines[i].quantity));
+      if (sets < rule.min_quantity) continue;
+      bundles = sets;
+
+      const setValue = members.reduce((sum, i) => sum + lines[i].unit_price * sets, 0);
+      const totalDiscount = rule.discount_type === 'percentage'
+        ? setValue * (rule.discount_value / 100)
+        : Math.min(rule.discount_value * sets, setValue);
+
+      // Spread the bundle discount proportionally to each member's price
+      for (const i of members) {
+        const share = setValue > 0 ? (lines[i].unit_price * sets) / setValue : 0;
+        const amount = round2(Math.min(totalDiscount * share, gross[i] - discounts[i]));
+        if (amount <= 0) continue;
+        discounts[i] += amount;
+        touched.push(i);
+        applied.push({ product_id: lines[i].product_id, quantity: sets, amount });
+      }
+    }
+
+    if (applied.length === 0) continue;
+
+    if (!rule.stackable) {
+      for (const i of touched) locked[i] = true;
+    }
+
+    adjustments.push({
+      rule_id: rule.id,
+      rule_name: rule.name,
+      rule_type: rule.rule_type,
+      amount: round2(applied.reduce((sum, a) => sum + a.amount, 0)),
+      details: bundles !== undefined ? { lines: applied, bundles } : { lines: applied },
+    });
+  }
+
+  return {
+    subtotal: round2(gross.reduce((a, b) => a + b, 0)),
+    discount_amount: round2(discounts.reduce((a, b) => a + b, 0)),
+    line_discounts: discounts.map(round2),
+    adjustments,
+  };
+}
+
+// ── PriceOrder ──────────────────────────────────────────────────────────────
+
+export async function priceOrder(q: Queryable, lines: PricingLine[], at: Date = new Date()): Promise<PricingResult> {
+  const rules = await loadActiveRules(q, at);
+  return applyPricingRules(lines, rules);
+}
+
+export async function recordPricingAdjustments(q: Queryable, orderId: string, adjustments: AppliedAdjustment[]): Promise<void> {
+  for (const adj of adjustments) {
+    await q.query(
+      `INSERT INTO order_pricing_adjustments (order_id, rule_id, rule_name, rule_type, amount, details)
+       VALUES ($1, $2, $3, $4, $5, $6)`,
+      [orderId, adj.rule_id, adj.rule_name, adj.rule_type, adj.amount, JSON.stringify(adj.details)],
+    );
+  }
+}
@@ -0,0 +1,115 @@
+import type { Queryable } from './pricing.js';
+
+// Product search. Matches on the products.search_vector full-text column
+// (name and SKU weighted above description), with every word also matching
+// as a prefix so results appear while typing, and falls back to trigram
+// similarity on the name so typos ("sirloine", "stek") still find the
+// product. Results are ranked by how well they match; facets count the
+// matches per category, ignoring the category filter so the user can switch.
+//
+// The 'simple' text search config is used because the menu mixes Indonesian
+// and English; a language's stemmer would mangle the other one. Menus are a
+// few hundred products, so the similarity fallback scanning names is cheap.
+
+export interface ProductSearchOptions {
+  term: string;
+  categoryId?: string | null;
+  /** Filter on is_available */
+  available?: boolean;
+  includeDeleted?: boolean;
+  /** Only what the public menu shows: available, not deleted, category not deleted */
+  menuOnly?: boolean;
+  limit: number;
+  offset: number;
+}
+
+export interface CategoryFacet {
+  category_id: string | null;
+  category_name: string | null;
+  count: number;
+}
+
+export interface ProductSearchResult {
+  /** Best match first */
+  ids: string[];
+  total: number;
+  facets: CategoryFacet[];
+}
+
+// Word similarity needed for a name to count as a typo'd match
+const SIMILARITY_THRESHOLD = 0.4;
+
+/** Each word of the term as a prefix query ("rib ey" → "rib:* & ey:*"), or null if it has no words. */
+export function prefixQuery(term: string): string | null {
+  const words = term.toLowerCase().match(/[\p{L}\p{N}]+/gu);
+  return words ? words.map((w) => `${w}:*`).join(' & ') : null;
+}
+
+// ── SearchProducts ──────────────────────────────────────────────────────────
+
+export async function searchProducts(q: Queryable, opts: ProductSearchOptions): Promise<ProductSearchResult> {
+  const term = opts.term.trim();
+  const prefix = prefixQuery(term);
+  if (!prefix) return { ids: [], total: 0, facets: [] };
+
+  // $1 term, $2 prefix query, $3 similarity threshold; filters follow
+  const params: unknown[] = [term, prefix, SIMILARITY_THRESHOLD];
+  const filters: string[] = [];
+  if (opts.menuOnly) {
+    filters.push('p.is_available = true AND p.deleted_at IS NULL AND c.deleted_at IS NULL');
+  } else {
+    if (!opts.includeDeleted) filters.push('p.deleted_at IS NULL');
+    if (opts.available !== undefined) {
+      params.push(opts.available);
+      filters.push(`p.is_available = $${params.length}`);
+    }
+  }
+
+  const matchSql = `
+    FROM products p
+    LEFT JOIN categories c ON c.id = p.category_id
+    CROSS JOIN LATERAL (
+      SELECT websearch_to_tsquery('simple', $1) AS exact, to_tsquery('simple', $2) AS prefix
+    ) tq
+    WHERE (p.search_vector @@ tq.prefix
+           OR word_similarity($1, p.name) >= $3
+           OR lower(p.sku) = lower($1) OR p.barcode = $1)
+      ${filters.map((f) => `AND ${f}`).join(' ')}`;
+
+  const facetRes = await q.query(
+    `SELECT p.category_id, c.name AS category_name, COUNT(*) AS count
+     ${matchSql}
+     GROUP BY p.category_id, c.name
+     ORDER BY count DESC, c.name ASC`,
+    params,
+  );
+  const facets = facetRes.rows.map((row) => ({
+    category_id: row.category_id,
+    category_name: row.category_name,
+    count: Number(row.count),
+  }));
+
+  let categoryFilter = '';
+  if (opts.categoryId) {
+    params.push(opts.categoryId);
+    categoryFilter = ` AND p.category_id = $${params.length}`;
+  }
+  const total = opts.categoryId
+    ? facets.find((f) => f.category_id === opts.categoryId)?.count ?? 0
+    : facets.reduce((sum, f) => sum + f.count, 0);
+
+  params.push(opts.limit, opts.offset);
+  const res = await q.query(
+    `SELECT p.id,
+            ts_rank(p.search_vector, tq.exact) * 2
+              + ts_rank(p.search_vector, tq.prefix)
+              + word_similarity($1, p.name)
+              + CASE WHEN lower(p.sku) = lower($1) OR p.barcode = $1 THEN 10 ELSE 0 END AS rank
+     ${matchSql}${categoryFilter}
+     ORDER BY rank DESC, p.name ASC
+     LIMIT $${params.length - 1} OFFSET $${params.length}`,
+    params,
+  );
+
+  return { ids: res.rows.map((row) => row.id), total, facets };
+}
@@ -0,0 +1,72 @@
+import { toInternationalPhone } from '../lib/messaging.js';
+import type { Queryable } from './pricing.js';
+
+// Receipt language. Receipts are always in Bahasa Indonesia; 'id_en' adds
+// English alongside each label and the tax wording. An order's own choice
+// wins, then the preference remembered for the customer's phone number,
+// then the receipt_language setting. The receipt printer does the
+// translating; the backend stores and resolves the choice.
+
+export type ReceiptLanguage = 'id' | 'id_en';
+
+export const RECEIPT_LANGUAGES: ReceiptLanguage[] = ['id', 'id_en'];
+
+const DEFAULT_RECEIPT_LANGUAGE: ReceiptLanguage = 'id';
+
+export type ReceiptLanguageSource = 'order' | 'customer' | 'default';
+
+export function isReceiptLanguage(value: unknown): value is ReceiptLanguage {
+  return typeof value === 'string' && (RECEIPT_LANGUAGES as string[]).includes(value);
+}
+
+export async function loadDefaultReceiptLanguage(q: Queryable): Promise<ReceiptLanguage> {
+  const res = await q.query(`SELECT setting_value FROM system_settings WHERE setting_key = 'receipt_language'`);
+  const value = res.rows[0]?.setting_value;
+  return isReceiptLanguage(value) ? value : DEFAULT_RECEIPT_LANGUAGE;
+}
+
+/** Preferences are kept under the canonical form of the phone: 0812…, +62 812… → 62812… */
+function preferencePhone(phone: string | null | undefined): string | null {
+  if (!phone) return null;
+  const canonical = toInternationalPhone(phone);
+  return canonical.length >= 8 ? canonical : null;
+}
+
+// ── ResolveReceiptLanguage ──────────────────────────────────────────────────
+
+export async function resolveReceiptLanguage(
+  q: Queryable,
+  order: { receipt_language: string | null; delivery_phone: string | null },
+): Promise<{ language: ReceiptLanguage; source: ReceiptLanguageSource }> {
+  if (isReceiptLanguage(order.receipt_language)) {
+    return { language: order.receipt_language, source: 'order' };
+  }
+  const phone = preferencePhone(order.delivery_phone);
+  if (phone) {
+    const res = await q.query('SELECT receipt_language FROM customer_receipt_preferences WHERE phone = $1', [phone]);
+    if (isReceiptLanguage(res.rows[0]?.receipt_language)) {
+      return { language: res.rows[0].receipt_language, source: 'customer' };
+    }
+  }
+  return { language: await loadDefaultReceiptLanguage(q), source: 'default' };
+}
+
+// ── SaveCustomerReceiptLanguage ─────────────────────────────────────────────
+// Remembers a customer's choice for their next orders. Returns false when
+// the phone number is too short to identify anyone.
+
+export async function saveCustomerReceiptLanguage(
+  q: Queryable,
+  phone: string | null | undefined,
+  language: ReceiptLanguage,
+): Promise<boolean> {
+  const canonical = preferencePhone(phone);
+  if (!canonical) return false;
+  await q.query(
+    `INSERT INTO customer_receipt_preferences (phone, receipt_language)
+     VALUES ($1, $2)
+     ON CONFLICT (phone) DO UPDATE SET receipt_language = EXCLUDED.receipt_language, updated_at = NOW()`,
+    [canonical, language],
+  );
+  return true;
+}
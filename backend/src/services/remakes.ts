@@ -0,0 +1,255 @@
+import type { PoolClient } from 'pg';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition } from './branches.js';
+import { RECIPE_COSTS, unitCost } from './costing.js';
+import { claimSpecialPortions } from './daily-specials.js';
+import type { Queryable } from './pricing.js';
+
+// Remakes. When a customer sends a dish back, staff log it with a reason and
+// the kitchen gets a new item for the same product at no charge. Remake items
+// (order_items.is_remake) are priced at zero and left out of repricing, so
+// promotions and taxes only ever see what the customer pays for. Each remake
+// keeps its cost at the time for the kitchen quality report.
+
+export type RemakeReason = 'overcooked' | 'undercooked' | 'wrong_item' | 'cold' | 'quality' | 'foreign_object' | 'other';
+
+export const REMAKE_REASONS: RemakeReason[] = [
+  'overcooked', 'undercooked', 'wrong_item', 'cold', 'quality', 'foreign_object', 'other',
+];
+
+export function isRemakeReason(value: unknown): value is RemakeReason {
+  return typeof value === 'string' && (REMAKE_REASONS as string[]).includes(value);
+}
+
+// Orders that can no longer take a remake; ring up a new order instead
+const REMAKE_LOCKED_STATUSES = ['completed', 'cancelled'];
+
+// Only a dish that has left the pass can be sent back
+const RETURNABLE_ITEM_STATUSES = ['ready', 'served'];
+
+export interface RemakeInput {
+  orderId: string;
+  itemId: string;
+  /** Defaults to the whole item; weighed items are always remade whole */
+  quantity: number | null;
+  reasonType: RemakeReason;
+  reason: string | null;
+  userId: string | null;
+  /** The caller's branch; orders of other branches are not found */
+  branchId: string | null;
+}
+
+export interface RemakeFailure {
+  message: string;
+  code: string;
+  status: 400 | 404 | 409;
+}
+
+export interface RemakeResult {
+  remakeId: string;
+  remakeItemId: string;
+  orderNumber: string;
+  tableNumber: string | null;
+  productName: string;
+  quantity: number;
+}
+
+export const REMAKE_SELECT = `
+  SELECT r.id, r.order_id, o.order_number, r.branch_id, r.order_item_id, r.remake_item_id, r.product_id,
+         r.product_name, r.quantity::float8 AS quantity, r.reason_type, r.reason, r.station,
+         r.unit_cost::float8 AS unit_cost, r.total_cost::float8 AS total_cost,
+         r.created_by, u.username AS created_by_username, r.created_at
+  FROM order_item_remakes r
+  JOIN orders o ON o.id = r.order_id
+  LEFT JOIN users u ON u.id = r.created_by`;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+// ── RecordRemake ────────────────────────────────────────────────────────────
+// Runs in the caller's transaction. A finished ticket goes back on the
+// kitchen board for the remake.
+
+export async function recordRemake(
+  client: PoolClient,
+  input: RemakeInput,
+): Promise<{ ok: true; result: RemakeResult } | { ok: false; failure: RemakeFailure }> {
+  const orderRes = await client.query(
+    `SELECT o.order_number, o.status, o.branch_id, t.table_number
+     FROM orders o
+     LEFT JOIN dining_tables t ON t.id = o.table_id
+     WHERE o.id = $1
+     FOR UPDATE OF o`,
+    [input.orderId],
+  );
+  const order = orderRes.rows[0];
+  if (!order || (input.branchId && order.branch_id !== input.branchId)) {
+    return { ok: false, failure: { message: 'Order not found', code: 'order_not_found', status: 404 } };
+  }
+  if (REMAKE_LOCKED_STATUSES.includes(order.status)) {
+    return {
+      ok: false,
+      failure: { message: `Order is ${order.status}; ring the remake up as a new order`, code: 'invalid_order_status', status: 409 },
+    };
+  }
+
+  const itemRes = await client.query(
+    `SELECT oi.id, oi.product_id, oi.quantity, oi.weight_grams, oi.special_instructions, oi.status, oi.course, oi.note_phrases,
+            p.name, p.sale_unit, p.cost_override, rc.recipe_cost, COALESCE(cat.station, 'kitchen') AS station
+     FROM order_items oi
+     JOIN products p ON p.id = oi.product_id
+     LEFT JOIN categories cat ON cat.id = p.category_id
+     LEFT JOIN (${RECIPE_COSTS}) rc ON rc.product_id = p.id
+     WHERE oi.id = $1 AND oi.order_id = $2
+     FOR UPDATE OF oi`,
+    [input.itemId, input.orderId],
+  );
+  const item = itemRes.rows[0];
+  if (!item) {
+    return { ok: false, failure: { message: 'Order item not found', code: 'order_item_not_found', status: 404 } };
+  }
+  if (!RETURNABLE_ITEM_STATUSES.includes(item.status)) {
+    return {
+      ok: false,
+      failure: { message: `'${item.name}' is still ${item.status}; only a dish that left the kitchen can be sent back`, code: 'item_not_served', status: 409 },
+    };
+  }
+
+  const itemQuantity = Number(item.quantity);
+  const weighed = item.sale_unit !== 'each';
+  const quantity = weighed || input.quantity === null ? itemQuantity : input.quantity;
+  if (!weighed && (!Number.isInteger(quantity) || quantity < 1 || quantity > itemQuantity)) {
+    return {
+      ok: false,
+      failure: { message: `Quantity must be a whole number up to ${itemQuantity}`, code: 'invalid_quantity', status: 400 },
+    };
+  }
+
+  const shortage = await claimSpecialPortions(client, input.orderId, [
+    { product_id: item.product_id, name: item.name, quantity },
+  ]);
+  if (shortage) {
+    return {
+      ok: false,
+      failure: { message: `Daily special '${shortage.name}' is sold out; it can't be remade`, code: 'special_sold_out', status: 409 },
+    };
+  }
+
+  const remakeItemRes = await client.query(
+    `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, special_instructions, weight_grams, is_remake,
+                              course, note_phrases)
+     VALUES ($1, $2, $3, 0, 0, $4, $5, true, $6, $7)
+     RETURNING id`,
+    [input.orderId, item.product_id, quantity, item.special_instructions, weighed ? item.weight_grams : null, item.course,
+      item.note_phrases],
+  );
+  const remakeItemId: string = remakeItemRes.rows[0].id;
+
+  const cost = unitCost(item).unit_cost;
+  const remakeRes = await client.query(
+    `INSERT INTO order_item_remakes (order_id, branch_id, order_item_id, remake_item_id, product_id, product_name, quantity,
+                                     reason_type, reason, station, unit_cost, total_cost, created_by)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+     RETURNING id`,
+    [
+      input.orderId, order.branch_id, item.id, remakeItemId, item.product_id, item.name, quantity,
+      input.reasonType, input.reason, item.station, cost, cost !== null ? round(cost * quantity) : null, input.userId,
+    ],
+  );
+
+  if (order.status === 'ready' || order.status === 'served') {
+    await client.query(
+      "UPDATE orders SET status = 'preparing', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+      [input.orderId],
+    );
+    await client.query(
+      `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+       VALUES ($1, $2, 'preparing', $3, 'Dish sent back for a remake')`,
+      [input.orderId, order.status, input.userId],
+    );
+  }
+
+  return {
+    ok: true,
+    result: {
+      remakeId: remakeRes.rows[0].id,
+      remakeItemId,
+      orderNumber: order.order_number,
+      tableNumber: order.table_number ?? null,
+      productName: item.name,
+      quantity,
+    },
+  };
+}
+
+// ── BuildKitchenQualityReport ───────────────────────────────────────────────
+// Remakes over [from, to] by reason, station and product. A product's remake
+// rate is the quantity remade per quantity sold in the same period.
+
+export async function buildKitchenQualityReport(q: Queryable, from: string, to: string, branchId: string | null) {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const where = `WHERE DATE(r.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+                 ${branchCondition('r.branch_id', branchId, params)}`;
+
+  const reasonsRes = await q.query(
+    `SELECT r.reason_type, COUNT(*)::int AS remakes, SUM(r.quantity)::float8 AS quantity,
+            COALESCE(SUM(r.total_cost), 0)::float8 AS total_cost
+     FROM order_item_remakes r
+     ${where}
+     GROUP BY r.reason_type
+     ORDER BY remakes DESC`,
+    params,
+  );
+
+  const stationsRes = await q.query(
+    `SELECT r.station, COUNT(*)::int AS remakes, COALESCE(SUM(r.total_cost), 0)::float8 AS total_cost
+     FROM order_item_remakes r
+     ${where}
+     GROUP BY r.station
+     ORDER BY remakes DESC`,
+    params,
+  );
+
+  const productsRes = await q.query(
+    `WITH sold AS (
+       SELECT oi.product_id, SUM(oi.quantity) AS quantity
+       FROM order_items oi
+       JOIN orders o ON o.id = oi.order_id
+       WHERE o.status <> 'cancelled' AND oi.is_remake = false
+         AND DATE(o.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+         ${branchId ? 'AND o.branch_id = $4' : ''}
+       GROUP BY oi.product_id
+     )
+     SELECT r.product_id, r.product_name, COUNT(*)::int AS remakes, SUM(r.quantity)::float8 AS quantity,
+            COALESCE(MAX(sold.quantity), 0)::float8 AS quantity_sold,
+            SUM(r.total_cost)::float8 AS total_cost,
+            array_agg(DISTINCT r.reason_type) AS reasons
+     FROM order_item_remakes r
+     LEFT JOIN sold ON sold.product_id = r.product_id
+     ${where}
+     GROUP BY r.product_id, r.product_name
+     ORDER BY remakes DESC, r.product_name ASC`,
+    params,
+  );
+
+  const byReason = reasonsRes.rows.map((row) => ({ ...row, total_cost: round(row.total_cost) }));
+  const products = productsRes.rows.map((row) => ({
+    ...row,
+    quantity: Math.round(row.quantity * 1000) / 1000,
+    total_cost: row.total_cost !== null ? round(row.total_cost) : null,
+    // Percent of what was sold
+    remake_rate: row.quantity_sold > 0 ? round((row.quantity / row.quantity_sold) * 100) : null,
+  }));
+
+  return {
+    from,
+    to,
+    branch_id: branchId,
+    summary: {
+      remakes: byReason.reduce((sum, r) => sum + r.remakes, 0),
+      total_cost: round(byReason.reduce((sum, r) => sum + r.total_cost, 0)),
+    },
+    by_reason: byReason,
+    by_station: stationsRes.rows.map((row) => ({ ...row, total_cost: round(row.total_cost) })),
+    products,
+  };
+}
@@ -0,0 +1,182 @@
+import { randomBytes } from 'node:crypto';
+import type { Queryable } from './pricing.js';
+import { env } from '../env.js';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { messagingConfigured, sendTextMessage } from '../lib/messaging.js';
+import { createNotificationForRole } from './notification.js';
+
+// Reservation reminders and no-shows. A reminder with confirm/cancel links
+// goes out a configurable number of hours before the booking; bookings
+// nobody showed up for are marked no_show after a grace period, which also
+// releases the table held for them.
+
+export const RESERVATION_REMINDERS_JOB = 'reservation_reminders';
+export const RESERVATION_NO_SHOWS_JOB = 'reservation_no_shows';
+
+const DEFAULT_REMINDER_HOURS = 3;
+const DEFAULT_GRACE_MINUTES = 20;
+
+// Reservation start as a timestamp, from the local date and time columns
+export const RESERVATION_START_SQL = `((r.reservation_date + r.reservation_time) AT TIME ZONE '${RESTAURANT_TIMEZONE}')`;
+
+export interface ReservationSettings {
+  reminderHours: number;
+  graceMinutes: number;
+}
+
+export async function loadReservationSettings(q: Queryable): Promise<ReservationSettings> {
+  const res = await q.query(
+    `SELECT setting_key, setting_value FROM system_settings
+     WHERE setting_key IN ('reservation_reminder_hours', 'reservation_no_show_grace_minutes')`,
+  );
+  const settings: ReservationSettings = { reminderHours: DEFAULT_REMINDER_HOURS, graceMinutes: DEFAULT_GRACE_MINUTES };
+  for (const row of res.rows) {
+    const value = parseFloat(row.setting_value);
+    if (isNaN(value) || value < 0) continue;
+    if (row.setting_key === 'reservation_reminder_hours') settings.reminderHours = value;
+    if (row.setting_key === 'reservation_no_show_grace_minutes') settings.graceMinutes = value;
+  }
+  return settings;
+}
+
+export function generateResponseToken(): string {
+  return randomBytes(24).toString('base64url');
+}
+
+/** Customer-facing link that confirms or cancels with one tap. */
+export function responseLink(token: string, action: 'confirm' | 'cancel'): string {
+  return `${env.PUBLIC_APP_URL.replace(/\/$/, '')}/reservation/${token}?action=${action}`;
+}
+
+function reminderText(r: Record<string, unknown>, token: string): string {
+  return [
+    `Hi ${r.customer_name}, this is a reminder of your table for ${r.party_size} on ${r.reservation_date} at ${r.reservation_time}.`,
+    `Confirm: ${responseLink(token, 'confirm')}`,
+    `Cancel: ${responseLink(token, 'cancel')}`,
+  ].join('\n');
+}
+
+// ── SendReservationReminders ────────────────────────────────────────────────
+// Each reminder is claimed by setting reminder_sent_at before sending, so
+// several instances never message the same guest twice. A failed send is
+// recorded on the reservation rather than retried.
+
+export async function sendReservationReminders(q: Queryable): Promise<number> {
+  if (!messagingConfigured()) return 0;
+
+  const { reminderHours } = await loadReservationSettings(q);
+  if (reminderHours === 0) return 0;
+
+  const res = await q.query(
+    `UPDATE reservations r
+     SET reminder_sent_at = NOW(), reminder_error = NULL,
+         response_token = COALESCE(r.response_token,
+           replace(gen_random_uuid()::text, '-', '') || replace(gen_random_uuid()::text, '-', ''))
+     WHERE r.id IN (
+       SELECT r.id FROM reservations r
+       WHERE r.status IN ('pending', 'confirmed') AND r.reminder_sent_at IS NULL
+         AND ${RESERVATION_START_SQL} > NOW()
+         AND ${RESERVATION_START_SQL} <= NOW() + make_interval(secs => $1 * 3600)
+       LIMIT 50
+       FOR UPDATE SKIP LOCKED
+     )
+     RETURNING r.id, r.customer_name, r.phone, r.party_size, r.response_token,
+               to_char(r.reservation_date, 'YYYY-MM-DD') AS reservation_date,
+               to_char(r.reservation_time, 'HH24:MI') AS reservation_time`,
+    [reminderHours],
+  );
+
+  let sent = 0;
+  for (const r of res.rows) {
+    try {
+      await sendTextMessage({ to: r.phone, text: reminderText(r, r.response_token) });
+      sent++;
+    } catch (err) {
+      await q.query('UPDATE reservations SET reminder_error = $1 WHERE id = $2', [(err as Error).message, r.id]);
+    }
+  }
+  return sent;
+}
+
+// ── MarkNoShowReservations ──────────────────────────────────────────────────
+
+export async function markNoShowReservations(q: Queryable): Promise<number> {
+  const { graceMinutes } = await loadReservationSettings(q);
+
+  const res = await q.query(
+    `UPDATE reservations r
+     SET status = 'no_show',
+         notes = CONCAT_WS(E'\\n', NULLIF(r.notes, ''), 'Marked no-show automatically after grace period')
+     WHERE r.status IN ('pending', 'confirmed')
+       AND ${RESERVATION_START_SQL} + make_interval(mins => $1) < NOW()
+     RETURNING r.customer_name, r.party_size, to_char(r.reservation_time, 'HH24:MI') AS reservation_time,
+               (SELECT table_number FROM dining_tables WHERE id = r.table_id) AS table_number`,
+    [graceMinutes],
+  );
+
+  for (const r of res.rows) {
+    const table = r.table_number ? `; table ${r.table_number} released` : '';
+    await createNotificationForRole(
+      'manager',
+      'system_alert',
+      'Reservation No-Show',
+      `${r.customer_name} (${r.party_size} pax, ${r.reservation_time}) did not arrive${table}`,
+      'reservation_no_show',
+    );
+  }
+  return res.rows.length;
+}
+
+// ── CustomerNoShowStats ─────────────────────────────────────────────────────
+// Booking history per customer, keyed by phone number (digits only) since
+// reservations are made without an account. Cancellations count towards the
+// total but not towards no-shows; future bookings are left out.
+
+export interface CustomerNoShowStat {
+  phone: string;
+  customer_name: string;
+  reservations: number;
+  no_shows: number;
+  no_show_rate: number;
+  last_no_show: string | null;
+}
+
+export async function customerNoShowStats(
+  q: Queryable,
+  opts: { phone?: string; minReservations?: number; limit?: number } = {},
+): Promise<CustomerNoShowStat[]> {
+  const params: unknown[] = [opts.minReservations ?? 1, opts.limit ?? 50];
+  let phoneFilter = '';
+  if (opts.phone) {
+    params.push(opts.phone.replace(/\D/g, ''));
+    phoneFilter = `AND regexp_replace(r.phone, '\\D', '', 'g') = $${params.length}`;
+  }
+
+  const res = await q.query(
+    `SELECT regexp_replace(r.phone, '\\D', '', 'g') AS phone,
+            (array_agg(r.customer_name ORDER BY r.created_at DESC))[1] AS customer_name,
+            COUNT(*) AS reservations,
+            COUNT(*) FILTER (WHERE r.status = 'no_show') AS no_shows,
+            to_char(MAX(r.reservation_date) FILTER (WHERE r.status = 'no_show'), 'YYYY-MM-DD') AS last_no_show
+     FROM reservations r
+     WHERE ${RESERVATION_START_SQL} <= NOW() ${phoneFilter}
+     GROUP BY 1
+     HAVING COUNT(*) >= $1
+     ORDER BY COUNT(*) FILTER (WHERE r.status = 'no_show')::numeric / COUNT(*) DESC, COUNT(*) DESC
+     LIMIT $2`,
+    params,
+  );
+
+  return res.rows.map((row) => {
+    const total = Number(row.reservations);
+    const noShows = Number(row.no_shows);
+    return {
+      phone: row.phone,
+      customer_name: row.customer_name,
+      reservations: total,
+      no_shows: noShows,
+      no_show_rate: total > 0 ? Math.round((noShows / total) * 10000) / 100 : 0,
+      last_no_show: row.last_no_show,
+    };
+  });
+}
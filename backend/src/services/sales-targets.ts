@@ -0,0 +1,157 @@
+import type { Queryable } from './pricing.js';
+import { RESTAURANT_TIMEZONE, addDays } from '../lib/clock.js';
+
+// Staff sales targets. Sales are credited to the staff member who took the
+// order (orders.user_id) and measured as net sales — completed order totals
+// excluding tax, service charge and delivery fees — per business day in the
+// restaurant timezone. Weeks run Monday to Sunday.
+
+export type TargetPeriod = 'daily' | 'weekly';
+
+export const TARGET_PERIODS: TargetPeriod[] = ['daily', 'weekly'];
+
+export interface TargetProgress {
+  period_type: TargetPeriod;
+  period_start: string;
+  period_end: string;
+  target_amount: number | null;
+  achieved: number;
+  /** Open orders that will count once completed */
+  pending: number;
+  remaining: number | null;
+  percent: number | null;
+  met: boolean;
+  orders: number;
+}
+
+/** Monday of the week containing `date` (YYYY-MM-DD). */
+export function weekStart(date: string): string {
+  const day = new Date(`${date}T00:00:00Z`).getUTCDay();
+  return addDays(date, -((day + 6) % 7));
+}
+
+export function periodBounds(period: TargetPeriod, date: string): { start: string; end: string } {
+  if (period === 'daily') return { start: date, end: date };
+  const start = weekStart(date);
+  return { start, end: addDays(start, 6) };
+}
+
+// ── GetTargetProgress ───────────────────────────────────────────────────────
+// Progress for each user in the period containing `date`. Users without a
+// target still get their sales, with target fields null.
+
+export async function getTargetProgress(
+  q: Queryable,
+  period: TargetPeriod,
+  date: string,
+  userIds: string[],
+): Promise<Map<string, TargetProgress>> {
+  const { start, end } = periodBounds(period, date);
+  const result = new Map<string, TargetProgress>();
+  if (userIds.length === 0) return result;
+
+  const res = await q.query(
+    `SELECT u.id AS user_id,
+            t.target_amount,
+            COALESCE(SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount) FILTER (WHERE o.status = 'completed'), 0) AS achieved,
+            COALESCE(SUM(o.total_amount - o.tax_amount - o.service_charge_amount - o.surcharge_amount - o.delivery_fee - o.deposit_amount) FILTER (WHERE o.status NOT IN ('completed', 'cancelled')), 0) AS pending,
+            COUNT(o.id) FILTER (WHERE o.status = 'completed') AS orders
+     FROM users u
+     LEFT JOIN sales_targets t
+       ON t.user_id = u.id AND t.period_type = $1
+      AND t.effective_from <= $3 AND (t.effective_until IS NULL OR t.effective_until >= $3)
+     LEFT JOIN orders o
+       ON o.user_id = u.id AND DATE(o.created_at AT TIME ZONE $5) BETWEEN $2 AND $3
+     WHERE u.id = ANY($4::uuid[])
+     GROUP BY u.id, t.target_amount`,
+    [period, start, end, userIds, RESTAURANT_TIMEZONE],
+  );
+
+  for (const row of res.rows) {
+    const target = row.target_amount !== null ? Number(row.target_amount) : null;
+    const achieved = Number(row.achieved);
+    result.set(row.user_id, {
+      period_type: period,
+      period_start: start,
+      period_end: end,
+      target_amount: target,
+      achieved,
+      pending: Number(row.pending),
+      remaining: target !== null ? Math.max(0, target - achieved) : null,
+      percent: target !== null ? Math.round((achieved / target) * 1000) / 10 : null,
+      met: target !== null && achieved >= target,
+      orders: Number(row.orders),
+    });
+  }
+
+  return result;
+}
+
+// ── GetTargetResults ────────────────────────────────────────────────────────
+// How many closed days / weeks inside [from, to] each user had a target for
+// and how many they met. Periods that haven't ended by `today` are left out.
+
+export async function getTargetResults(
+  q: Queryable,
+  from: string,
+  to: string,
+  today: string,
+): Promise<Map<string, { days_with_target: number; days_met: number; weeks_with_target: number; weeks_met: number }>> {
+  const lastClosedDay = addDays(today, -1) < to ? addDays(today, -1) : to;
+
+  const res = await q.query(
+    `WITH sales AS (
+       SELECT user_id, DATE(created_at AT TIME ZONE $3) AS day, SUM(total_amount - tax_amount - service_charge_amount - delivery_fee - deposit_amount) AS net
+       FROM orders
+       WHERE status = 'completed' AND user_id IS NOT NULL
+         AND DATE(created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+       GROUP BY 1, 2
+     ),
+     days AS (
+       SELECT d::date AS day FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+     ),
+     weeks AS (
+       SELECT w::date AS week_start, (w + INTERVAL '6 days')::date AS week_end
+       FROM generate_series(date_trunc('week', $1::date), $2::date, INTERVAL '1 week') w
+       WHERE w::date >= $1::date AND (w + INTERVAL '6 days')::date <= $2::date
+     ),
+     daily AS (
+       SELECT t.user_id, COUNT(*) AS with_target,
+              COUNT(*) FILTER (WHERE COALESCE(s.net, 0) >= t.target_amount) AS met
+       FROM days
+       JOIN sales_targets t
+         ON t.period_type = 'daily' AND t.effective_from <= days.day
+        AND (t.effective_until IS NULL OR t.effective_until >= days.day)
+       LEFT JOIN sales s ON s.user_id = t.user_id AND s.day = days.day
+       GROUP BY t.user_id
+     ),
+     weekly AS (
+       SELECT t.user_id, COUNT(*) AS with_target,
+              COUNT(*) FILTER (WHERE COALESCE(
+                (SELECT SUM(s.net) FROM sales s
+                 WHERE s.user_id = t.user_id AND s.day BETWEEN weeks.week_start AND weeks.week_end), 0
+              ) >= t.target_amount) AS met
+       FROM weeks
+       JOIN sales_targets t
+         ON t.period_type = 'weekly' AND t.effective_from <= weeks.week_end
+        AND (t.effective_until IS NULL OR t.effective_until >= weeks.week_end)
+       GROUP BY t.user_id
+     )
+     SELECT COALESCE(daily.user_id, weekly.user_id) AS user_id,
+            COALESCE(daily.with_target, 0) AS days_with_target, COALESCE(daily.met, 0) AS days_met,
+            COALESCE(weekly.with_target, 0) AS weeks_with_target, COALESCE(weekly.met, 0) AS weeks_met
+     FROM daily FULL OUTER JOIN weekly ON weekly.user_id = daily.user_id`,
+    [from, lastClosedDay, RESTAURANT_TIMEZONE],
+  );
+
+  const result = new Map<string, { days_with_target: number; days_met: number; weeks_with_target: number; weeks_met: number }>();
+  for (const row of res.rows) {
+    result.set(row.user_id, {
+      days_with_target: Number(row.days_with_target),
+      days_met: Number(row.days_met),
+      weeks_with_target: Number(row.weeks_with_target),
+      weeks_met: Number(row.weeks_met),
+    });
+  }
+  return result;
+}
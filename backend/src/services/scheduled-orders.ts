@@ -0,0 +1,134 @@
+import type { Queryable } from './pricing.js';
+import { localClock, inDailyWindow } from '../lib/clock.js';
+import { createNotificationForRole } from './notification.js';
+
+// Scheduled takeaway/delivery orders. An order with a future pickup or
+// delivery time is created in the 'scheduled' state and kept off the kitchen
+// feed; a periodic job confirms it once the pickup time is within the
+// configured lead time. Orders due sooner than the lead time skip the wait
+// and start as 'pending' like any other order.
+
+export const SCHEDULED_ORDERS_PROMOTE_JOB = 'scheduled_orders_promote';
+
+export const SCHEDULABLE_ORDER_TYPES = ['takeout', 'delivery'];
+
+const DEFAULT_LEAD_MINUTES = 30;
+const DEFAULT_MAX_DAYS = 7;
+
+export interface SchedulingSettings {
+  leadMinutes: number;
+  maxDays: number;
+}
+
+export async function loadSchedulingSettings(q: Queryable): Promise<SchedulingSettings> {
+  const res = await q.query(
+    `SELECT setting_key, setting_value FROM system_settings
+     WHERE setting_key IN ('scheduled_order_lead_minutes', 'scheduled_order_max_days')`,
+  );
+  const settings: SchedulingSettings = { leadMinutes: DEFAULT_LEAD_MINUTES, maxDays: DEFAULT_MAX_DAYS };
+  for (const row of res.rows) {
+    const value = parseInt(row.setting_value, 10);
+    if (isNaN(value) || value < 0) continue;
+    if (row.setting_key === 'scheduled_order_lead_minutes') settings.leadMinutes = value;
+    if (row.setting_key === 'scheduled_order_max_days') settings.maxDays = value;
+  }
+  return settings;
+}
+
+export type ScheduleResult =
+  | { ok: true; scheduledAt: string | null; status: 'scheduled' | 'pending' }
+  | { ok: false; failure: { message: string; code: string; status: 400 } };
+
+function reject(message: string, code: string): ScheduleResult {
+  return { ok: false, failure: { message, code, status: 400 } };
+}
+
+// Pickup times must fall inside the opening hours of that day. Restaurants
+// that haven't configured hours accept any time.
+async function withinOperatingHours(q: Queryable, at: Date): Promise<boolean> {
+  const clock = localClock(at);
+  const res = await q.query(
+    `SELECT day_of_week, to_char(open_time, 'HH24:MI:SS') AS open_time,
+            to_char(close_time, 'HH24:MI:SS') AS close_time, is_closed
+     FROM operating_hours`,
+  );
+  if (res.rows.length === 0) return true;
+
+  const today = res.rows.find((h) => h.day_of_week === clock.dayOfWeek);
+  if (!today || today.is_closed) return false;
+  return inDailyWindow(clock.secondsOfDay, today.open_time, today.close_time);
+}
+
+// ── ResolveSchedule ─────────────────────────────────────────────────────────
+// Validates a requested scheduled_at and picks the order's starting status.
+// No scheduled_at means "as soon as possible".
+
+export async function resolveSchedule(
+  q: Queryable,
+  orderType: string,
+  scheduledAt: string | null | undefined,
+): Promise<ScheduleResult> {
+  if (scheduledAt === undefined || scheduledAt === null || scheduledAt === '') {
+    return { ok: true, scheduledAt: null, status: 'pending' };
+  }
+
+  if (!SCHEDULABLE_ORDER_TYPES.includes(orderType)) {
+    return reject('Only takeout and delivery orders can be scheduled; use a reservation for dine-in', 'schedule_not_supported');
+  }
+
+  const at = new Date(scheduledAt);
+  if (isNaN(at.getTime())) {
+    return reject('Scheduled time must be an ISO 8601 timestamp', 'invalid_scheduled_at');
+  }
+
+  const now = Date.now();
+  if (at.getTime() <= now) {
+    return reject('Scheduled time must be in the future', 'scheduled_at_in_past');
+  }
+
+  const settings = await loadSchedulingSettings(q);
+  if (at.getTime() > now + settings.maxDays * 86_400_000) {
+    return reject(`Orders can be scheduled at most ${settings.maxDays} days ahead`, 'scheduled_at_too_far');
+  }
+
+  if (!(await withinOperatingHours(q, at))) {
+    return reject('Scheduled time is outside opening hours', 'outside_operating_hours');
+  }
+
+  const releaseAt = at.getTime() - settings.leadMinutes * 60_000;
+  return { ok: true, scheduledAt: at.toISOString(), status: releaseAt > now ? 'scheduled' : 'pending' };
+}
+
+// ── PromoteDueScheduledOrders ───────────────────────────────────────────────
+// Confirms every scheduled order whose pickup time is within the lead time.
+// The conditional UPDATE makes concurrent runs on several instances safe:
+// each order is promoted (and announced) exactly once.
+
+export async function promoteDueScheduledOrders(q: Queryable): Promise<number> {
+  const { leadMinutes } = await loadSchedulingSettings(q);
+
+  const res = await q.query(
+    `UPDATE orders SET status = 'confirmed', updated_at = CURRENT_TIMESTAMP
+     WHERE status = 'scheduled' AND scheduled_at - make_interval(mins => $1) <= NOW()
+     RETURNING id, order_number, order_type, scheduled_at`,
+    [leadMinutes],
+  );
+
+  for (const order of res.rows) {
+    await q.query(
+      `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+       VALUES ($1, 'scheduled', 'confirmed', NULL, 'Scheduled order released to kitchen')`,
+      [order.id],
+    );
+
+    const clock = localClock(new Date(order.scheduled_at));
+    const hh = String(Math.floor(clock.secondsOfDay / 3600)).padStart(2, '0');
+    const mm = String(Math.floor((clock.secondsOfDay % 3600) / 60)).padStart(2, '0');
+    const message = `Scheduled ${order.order_type} order ${order.order_number} due at ${hh}:${mm}`;
+    for (const role of ['kitchen', 'counter']) {
+      await createNotificationForRole(role, 'order_update', 'Scheduled Order Released', message, 'scheduled_order_released');
+    }
+  }
+
+  return res.rows.length;
+}
@@ -0,0 +1,106 @@
+import type { Queryable } from './pricing.js';
+
+// Server sections. A branch's dining tables are grouped into named sections
+// (a table is in at most one) and servers are assigned to a section for a
+// shift, from starts_at to ends_at; a section can have several servers and
+// a server several sections. Staff without orders.view_all_sections, i.e.
+// the server role, only see the orders of the tables in their sections
+// during their shift, plus the ones they rang in or are credited with.
+//
+// An order is credited to a server when it is placed (orders.server_id):
+// the server on shift in the table's section, preferring the one ringing it
+// in, otherwise whoever rang it in. The server report adds up sales and
+// covers by that column.
+
+export const MAX_SHIFT_HOURS = 16;
+export const MAX_COVERS = 100;
+
+export function isCovers(value: unknown): value is number {
+  return Number.isInteger(value) && (value as number) >= 1 && (value as number) <= MAX_COVERS;
+}
+
+const ON_SHIFT = 'NOW() >= sa.starts_at AND NOW() < sa.ends_at';
+
+/** The tables in the sections the user is on shift in right now. */
+export async function currentSectionTableIds(q: Queryable, userId: string): Promise<string[]> {
+  const res = await q.query(
+    `SELECT DISTINCT t.id
+     FROM section_assignments sa
+     JOIN dining_tables t ON t.section_id = sa.section_id AND t.deleted_at IS NULL
+     WHERE sa.user_id = $1 AND ${ON_SHIFT}`,
+    [userId],
+  );
+  return res.rows.map((row) => row.id as string);
+}
+
+/** Whether a section-restricted user may see the order. */
+export async function canSeeOrder(
+  q: Queryable,
+  userId: string,
+  order: { user_id?: unknown; server_id?: unknown; table_id?: unknown },
+): Promise<boolean> {
+  if (order.user_id === userId || order.server_id === userId) return true;
+  if (typeof order.table_id !== 'string') return false;
+  return (await currentSectionTableIds(q, userId)).includes(order.table_id);
+}
+
+// ── ResolveOrderServer ──────────────────────────────────────────────────────
+// Who a new order is credited to; userId is whoever rings it in (null for a
+// customer's QR order).
+
+export async function resolveOrderServer(q: Queryable, tableId: string | null, userId: string | null): Promise<string | null> {
+  if (tableId) {
+    const res = await q.query(
+      `SELECT sa.user_id
+       FROM dining_tables t
+       JOIN section_assignments sa ON sa.section_id = t.section_id
+       WHERE t.id = $1 AND ${ON_SHIFT}
+       ORDER BY COALESCE(sa.user_id = $2::uuid, false) DESC, sa.starts_at ASC
+       LIMIT 1`,
+      [tableId, userId],
+    );
+    if (res.rows[0]) return res.rows[0].user_id;
+  }
+  return userId;
+}
+
+// ── LoadSections ────────────────────────────────────────────────────────────
+// Sections with their tables and the assignments that haven't ended yet
+// (current and upcoming shifts). Without a branch, every branch's.
+
+export async function loadSections(q: Queryable, filter: { branchId?: string | null; sectionId?: string } = {}) {
+  const conditions: string[] = [];
+  const params: string[] = [];
+  if (filter.branchId) {
+    params.push(filter.branchId);
+    conditions.push(`s.branch_id = $${params.length}`);
+  }
+  if (filter.sectionId) {
+    params.push(filter.sectionId);
+    conditions.push(`s.id = $${params.length}`);
+  }
+  const where = conditions.length > 0 ? `WHERE ${conditions.join(' AND ')}` : '';
+
+  const res = await q.query(
+    `SELECT s.id, s.branch_id, s.name, s.created_at, s.updated_at,
+            COALESCE((
+              SELECT json_agg(json_build_object('id', t.id, 'table_number', t.table_number) ORDER BY t.table_number)
+              FROM dining_tables t WHERE t.section_id = s.id AND t.deleted_at IS NULL
+            ), '[]'::json) AS tables,
+            COALESCE((
+              SELECT json_agg(json_build_object(
+                       'id', sa.id, 'user_id', sa.user_id, 'username', u.username,
+                       'first_name', u.first_name, 'last_name', u.last_name,
+                       'starts_at', sa.starts_at, 'ends_at', sa.ends_at,
+                       'on_shift', ${ON_SHIFT}
+                     ) ORDER BY sa.starts_at)
+              FROM section_assignments sa JOIN users u ON u.id = sa.user_id
+              WHERE sa.section_id = s.id AND sa.ends_at > NOW()
+            ), '[]'::json) AS assignments
+     FROM server_sections s
+     ${where}
+     ORDER BY s.name ASC`,
+    params,
+  );
+  return res.rows;
+}
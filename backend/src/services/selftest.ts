@@ -0,0 +1,265 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+import { priceOrder, type PricingLine } from './pricing.js';
+import { computeOrderTaxes, taxLines } from './tax.js';
+import { deductStockForOrder } from './stock.js';
+import { getDefaultBranchId } from './branches.js';
+
+// Deployment self-test. Runs the order path end to end (order, stock,
+// kitchen, payment) against throwaway sandbox data, inside one transaction
+// that is always rolled back, so a deploy can be checked against the real
+// database without leaving anything behind. Nothing is emitted outside the
+// transaction: no webhooks, notifications or menu sync.
+
+export type SelftestStepStatus = 'passed' | 'failed' | 'skipped';
+
+export interface SelftestStep {
+  name: string;
+  status: SelftestStepStatus;
+  duration_ms: number;
+  detail: string | null;
+  error: string | null;
+}
+
+// Serialises runs, so two deploys checking at once don't collide
+export const SELFTEST_LOCK_KEY = 'selftest';
+
+const SANDBOX_PRICE = 100000;
+const SANDBOX_STOCK = 10;
+
+interface Sandbox {
+  branchId: string;
+  userId: string | null;
+  categoryId: string;
+  productId: string;
+  tableId: string;
+  orderId: string;
+  orderItemId: string;
+  totalAmount: number;
+}
+
+type StepFn = (sandbox: Sandbox) => Promise<string>;
+
+function round2(n: number): number {
+  return Math.round(n * 100) / 100;
+}
+
+function check(condition: boolean, message: string): void {
+  if (!condition) throw new Error(message);
+}
+
+// A short unique suffix keeps sandbox names clear of real data
+function sandboxTag(): string {
+  return `SELFTEST-${Date.now().toString(36).toUpperCase()}`;
+}
+
+async function stepSandbox(client: PoolClient, sandbox: Sandbox): Promise<string> {
+  const tag = sandboxTag();
+  sandbox.branchId = await getDefaultBranchId(client);
+
+  const categoryRes = await client.query(
+    `INSERT INTO categories (name, description, is_active) VALUES ($1, 'Deployment self-test', false) RETURNING id`,
+    [tag],
+  );
+  sandbox.categoryId = categoryRes.rows[0].id;
+
+  const productRes = await client.query(
+    `INSERT INTO products (category_id, name, price, is_available) VALUES ($1, $2, $3, true) RETURNING id`,
+    [sandbox.categoryId, tag, SANDBOX_PRICE],
+  );
+  sandbox.productId = productRes.rows[0].id;
+
+  await client.query(
+    'INSERT INTO inventory (product_id, branch_id, current_stock) VALUES ($1, $2, $3)',
+    [sandbox.productId, sandbox.branchId, SANDBOX_STOCK],
+  );
+
+  const tableRes = await client.query(
+    `INSERT INTO dining_tables (branch_id, table_number, seating_capacity) VALUES ($1, $2, 2) RETURNING id`,
+    [sandbox.branchId, tag.slice(0, 20)],
+  );
+  sandbox.tableId = tableRes.rows[0].id;
+
+  return `Category, product (stock ${SANDBOX_STOCK}) and table ${tag}`;
+}
+
+async function stepCreateOrder(client: PoolClient, sandbox: Sandbox): Promise<string> {
+  const lines: PricingLine[] = [{
+    product_id: sandbox.productId,
+    category_id: sandbox.categoryId,
+    name: 'Self-test item',
+    unit_price: SANDBOX_PRICE,
+    quantity: 2,
+  }];
+  const pricing = await priceOrder(client, lines);
+  const taxes = await computeOrderTaxes(client, sandbox.branchId, 'dine_in', taxLines(lines, pricing.line_discounts));
+  check(pricing.subtotal === SANDBOX_PRICE * 2, `Subtotal ${pricing.subtotal}, expected ${SANDBOX_PRICE * 2}`);
+
+  const total = round2(pricing.subtotal - pricing.discount_amount + taxes.service_charge_amount + taxes.tax_amount);
+  const orderRes = await client.query(
+    `INSERT INTO orders (order_number, table_id, user_id, customer_name, order_type, status, subtotal, tax_amount,
+                         service_charge_amount, discount_amount, total_amount, branch_id)
+     VALUES ($1, $2, $3, 'Self-test', 'dine_in', 'pending', $4, $5, $6, $7, $8, $9)
+     RETURNING id`,
+    [
+      sandboxTag(), sandbox.tableId, sandbox.userId, pricing.subtotal, taxes.tax_amount,
+      taxes.service_charge_amount, pricing.discount_amount, total, sandbox.branchId,
+    ],
+  );
+  sandbox.orderId = orderRes.rows[0].id;
+  sandbox.totalAmount = total;
+
+  const tax = taxes.lines[0];
+  const itemRes = await client.query(
+    `INSERT INTO order_items (order_id, product_id, quantity, unit_price, total_price, tax_amount, service_charge_amount,
+                              tax_exempt, service_exempt, tax_label, tax_rate)
+     VALUES ($1, $2, 2, $3, $4, $5, $6, $7, $8, $9, $10)
+     RETURNING id`,
+    [
+      sandbox.orderId, sandbox.productId, SANDBOX_PRICE, SANDBOX_PRICE * 2, tax.tax_amount, tax.service_charge_amount,
+      tax.tax_exempt, tax.service_exempt, tax.tax_label, tax.tax_rate,
+    ],
+  );
+  sandbox.orderItemId = itemRes.rows[0].id;
+  await client.query('UPDATE dining_tables SET is_occupied = true WHERE id = $1', [sandbox.tableId]);
+
+  return `Order total ${total} (discount ${pricing.discount_amount}, tax ${taxes.tax_amount}, service ${taxes.service_charge_amount})`;
+}
+
+async function stepStock(client: PoolClient, sandbox: Sandbox): Promise<string> {
+  const shortage = await deductStockForOrder(client, sandbox.orderId, [
+    { product_id: sandbox.productId, name: 'Self-test item', quantity: 2 },
+  ]);
+  check(shortage === null, 'Stock deduction reported a shortage');
+
+  const res = await client.query(
+    'SELECT current_stock FROM inventory WHERE product_id = $1 AND branch_id = $2',
+    [sandbox.productId, sandbox.branchId],
+  );
+  const remaining = Number(res.rows[0]?.current_stock);
+  check(remaining === SANDBOX_STOCK - 2, `Stock is ${remaining}, expected ${SANDBOX_STOCK - 2}`);
+  return `Stock ${SANDBOX_STOCK} -> ${remaining}`;
+}
+
+async function stepKitchen(client: PoolClient, sandbox: Sandbox): Promise<string> {
+  const orderFlow = ['confirmed', 'preparing', 'ready', 'served'];
+  const itemFlow = ['preparing', 'ready', 'served'];
+
+  let previous = 'pending';
+  for (const status of orderFlow) {
+    await client.query(
+      `UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP
+       ${status === 'served' ? ', served_at = CURRENT_TIMESTAMP' : ''} WHERE id = $2`,
+      [status, sandbox.orderId],
+    );
+    await client.query(
+      `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+       VALUES ($1, $2, $3, $4, 'Self-test')`,
+      [sandbox.orderId, previous, status, sandbox.userId],
+    );
+    if (status === 'preparing') {
+      let itemPrevious = 'pending';
+      for (const itemStatus of itemFlow) {
+        await client.query(
+          'UPDATE order_items SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2',
+          [itemStatus, sandbox.orderItemId],
+        );
+        await client.query(
+          `INSERT INTO order_item_status_history (order_id, order_item_id, previous_status, new_status, station, changed_by)
+           VALUES ($1, $2, $3, $4, 'kitchen', $5)`,
+          [sandbox.orderId, sandbox.orderItemId, itemPrevious, itemStatus, sandbox.userId],
+        );
+        itemPrevious = itemStatus;
+      }
+    }
+    previous = status;
+  }
+
+  const res = await client.query(
+    `SELECT o.status, (SELECT COUNT(*) FROM order_status_history h WHERE h.order_id = o.id) AS transitions
+     FROM orders o WHERE o.id = $1`,
+    [sandbox.orderId],
+  );
+  check(res.rows[0]?.status === 'served', `Order is ${res.rows[0]?.status}, expected served`);
+  check(Number(res.rows[0].transitions) === orderFlow.length, 'Status history is incomplete');
+  return `pending -> ${orderFlow.join(' -> ')}`;
+}
+
+async function stepPayment(client: PoolClient, sandbox: Sandbox): Promise<string> {
+  await client.query(
+    `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at)
+     VALUES ($1, 'cash', $2, 'SELFTEST', 'completed', $3, NOW())`,
+    [sandbox.orderId, sandbox.totalAmount, sandbox.userId],
+  );
+  await client.query(
+    `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+    [sandbox.orderId],
+  );
+  await client.query('UPDATE dining_tables SET is_occupied = false WHERE id = $1', [sandbox.tableId]);
+
+  const res = await client.query(
+    `SELECT o.status, o.total_amount,
+            (SELECT COALESCE(SUM(p.amount), 0) FROM payments p WHERE p.order_id = o.id AND p.status = 'completed') AS paid
+     FROM orders o WHERE o.id = $1`,
+    [sandbox.orderId],
+  );
+  const row = res.rows[0];
+  check(row?.status === 'completed', `Order is ${row?.status}, expected completed`);
+  check(Number(row.paid) === Number(row.total_amount), `Paid ${row.paid} of ${row.total_amount}`);
+  return `Paid ${Number(row.paid)} in cash; order completed`;
+}
+
+// ── RunSelftestSteps ────────────────────────────────────────────────────────
+// Runs inside the caller's transaction, which the caller must roll back. A
+// failed step aborts the transaction, so the steps after it are skipped.
+
+export async function runSelftestSteps(client: PoolClient, userId: string | null): Promise<{ steps: SelftestStep[]; orderId: string | null }> {
+  const sandbox = { userId } as Sandbox;
+  const plan: [string, StepFn][] = [
+    ['sandbox', (s) => stepSandbox(client, s)],
+    ['create_order', (s) => stepCreateOrder(client, s)],
+    ['stock_deduction', (s) => stepStock(client, s)],
+    ['status_transitions', (s) => stepKitchen(client, s)],
+    ['payment', (s) => stepPayment(client, s)],
+  ];
+
+  const steps: SelftestStep[] = [];
+  let failed = false;
+  for (const [name, run] of plan) {
+    if (failed) {
+      steps.push({ name, status: 'skipped', duration_ms: 0, detail: null, error: null });
+      continue;
+    }
+    const started = Date.now();
+    try {
+      const detail = await run(sandbox);
+      steps.push({ name, status: 'passed', duration_ms: Date.now() - started, detail, error: null });
+    } catch (err) {
+      failed = true;
+      steps.push({ name, status: 'failed', duration_ms: Date.now() - started, detail: null, error: (err as Error).message });
+    }
+  }
+  return { steps, orderId: sandbox.orderId ?? null };
+}
+
+// ── VerifyRollback ──────────────────────────────────────────────────────────
+// Outside the transaction: the sandbox order must be gone.
+
+export async function verifyRollback(q: Queryable, orderId: string | null): Promise<SelftestStep> {
+  const started = Date.now();
+  if (!orderId) {
+    return { name: 'rollback', status: 'passed', duration_ms: 0, detail: 'Nothing was written', error: null };
+  }
+  try {
+    const res = await q.query('SELECT 1 FROM orders WHERE id = $1', [orderId]);
+    if (res.rows.length > 0) {
+      return {
+        name: 'rollback', status: 'failed', duration_ms: Date.now() - started, detail: null,
+        error: `Sandbox order ${orderId} is still in the database`,
+      };
+    }
+    return { name: 'rollback', status: 'passed', duration_ms: Date.now() - started, detail: 'Sandbox data removed', error: null };
+  } catch (err) {
+    return { name: 'rollback', status: 'failed', duration_ms: Date.now() - started, detail: null, error: (err as Error).message };
+  }
+}
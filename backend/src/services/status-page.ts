@@ -0,0 +1,133 @@
+import { BUILD, runHealthChecks, type CheckStatus } from '../lib/health.js';
+import { runtimeConfig } from '../lib/runtime-config.js';
+import type { Queryable } from './pricing.js';
+
+// The public status page: whether the system is up, how each part of it is
+// doing and any incident an admin has raised, so front-of-house staff can
+// tell a problem at their counter from one that affects everyone. Component
+// statuses come from the health checks, reduced to a word each; none of the
+// details /health?verbose=true gives admins are shown here.
+//
+// The result is kept for STATUS_CACHE_MS, so a busy status page doesn't run
+// the health checks on every request. Raising or resolving an incident
+// clears it on this instance; other instances catch up within that time.
+
+export const INCIDENT_SEVERITIES = ['minor', 'major', 'critical'] as const;
+export type IncidentSeverity = (typeof INCIDENT_SEVERITIES)[number];
+
+export const INCIDENT_COMPONENTS = ['ordering', 'payments', 'kitchen', 'delivery', 'printing', 'website', 'other'] as const;
+export type IncidentComponent = (typeof INCIDENT_COMPONENTS)[number];
+
+export type ComponentStatus = 'operational' | 'degraded' | 'outage';
+export type OverallStatus = ComponentStatus | 'maintenance';
+
+const STATUS_CACHE_MS = 15_000;
+// Resolved incidents stay on the page this long, so staff see it was fixed
+const RECENT_RESOLVED_HOURS = 24;
+const AVAILABILITY_WINDOW_DAYS = 30;
+
+// The health checks worth showing, under names that mean something to staff
+const PUBLIC_COMPONENTS: Record<string, string> = {
+  database: 'database',
+  queue: 'background_jobs',
+  uploads: 'image_storage',
+};
+
+function componentStatus(status: CheckStatus): ComponentStatus {
+  if (status === 'down') return 'outage';
+  return status === 'degraded' ? 'degraded' : 'operational';
+}
+
+export const INCIDENT_SELECT = `
+  SELECT i.id, i.title, i.message, i.severity, i.component, i.started_at, i.resolved_at,
+         i.created_by, i.resolved_by, i.created_at, i.updated_at,
+         NULLIF(TRIM(CONCAT(cu.first_name, ' ', cu.last_name)), '') AS created_by_name,
+         NULLIF(TRIM(CONCAT(ru.first_name, ' ', ru.last_name)), '') AS resolved_by_name
+  FROM status_incidents i
+  LEFT JOIN users cu ON cu.id = i.created_by
+  LEFT JOIN users ru ON ru.id = i.resolved_by`;
+
+// ── Availability ────────────────────────────────────────────────────────────
+// The share of the window not covered by a critical incident. Overlapping
+// incidents are merged so the same hour isn't counted twice.
+
+async function availabilityPercent(q: Queryable): Promise<number> {
+  const res = await q.query(
+    `SELECT GREATEST(started_at, NOW() - make_interval(days => $1)) AS from_at, COALESCE(resolved_at, NOW()) AS to_at
+     FROM status_incidents
+     WHERE severity = 'critical' AND COALESCE(resolved_at, NOW()) > NOW() - make_interval(days => $1)
+     ORDER BY 1`,
+    [AVAILABILITY_WINDOW_DAYS],
+  );
+  const windowMs = AVAILABILITY_WINDOW_DAYS * 24 * 60 * 60 * 1000;
+  let downMs = 0;
+  let end = 0;
+  for (const row of res.rows) {
+    const from = new Date(row.from_at).getTime();
+    const to = new Date(row.to_at).getTime();
+    if (to <= end) continue;
+    downMs += to - Math.max(from, end);
+    end = to;
+  }
+  return Math.round(Math.max(0, 1 - downMs / windowMs) * 10000) / 100;
+}
+
+// ── LoadPublicStatus ────────────────────────────────────────────────────────
+// Still answers when the database is down: the incidents can't be read then,
+// but the database shows as an outage, which is what staff need to know.
+
+let cached: { at: number; status: Awaited<ReturnType<typeof buildPublicStatus>> } | null = null;
+
+async function buildPublicStatus(q: Queryable, uploadsDir: string) {
+  const [{ checks }, incidents, availability] = await Promise.all([
+    runHealthChecks({ uploadsDir }),
+    q.query(
+      `SELECT id, title, message, severity, component, started_at, resolved_at
+       FROM status_incidents
+       WHERE resolved_at IS NULL OR resolved_at > NOW() - make_interval(hours => $1)
+       ORDER BY resolved_at IS NULL DESC, started_at DESC`,
+      [RECENT_RESOLVED_HOURS],
+    ).then((res) => res.rows, () => []),
+    availabilityPercent(q).catch(() => null),
+  ]);
+
+  const components: Record<string, ComponentStatus> = { api: 'operational' };
+  for (const [check, name] of Object.entries(PUBLIC_COMPONENTS)) {
+    components[name] = componentStatus(checks[check].status);
+  }
+
+  const active = incidents.filter((i) => !i.resolved_at);
+  const statuses = Object.values(components);
+  let status: OverallStatus = 'operational';
+  if (runtimeConfig().maintenance_mode) {
+    status = 'maintenance';
+  } else if (components.database === 'outage' || active.some((i) => i.severity === 'critical')) {
+    status = 'outage';
+  } else if (active.length > 0 || statuses.some((s) => s !== 'operational')) {
+    status = 'degraded';
+  }
+
+  return {
+    status,
+    checked_at: new Date().toISOString(),
+    started_at: BUILD.started_at,
+    uptime_seconds: Math.round(process.uptime()),
+    availability: { window_days: AVAILABILITY_WINDOW_DAYS, percent: availability },
+    components,
+    incidents: {
+      active,
+      recently_resolved: incidents.filter((i) => i.resolved_at),
+    },
+  };
+}
+
+export async function loadPublicStatus(q: Queryable, uploadsDir: string) {
+  if (cached && Date.now() - cached.at < STATUS_CACHE_MS) return cached.status;
+  const status = await buildPublicStatus(q, uploadsDir);
+  cached = { at: Date.now(), status };
+  return status;
+}
+
+export function invalidatePublicStatus(): void {
+  cached = null;
+}
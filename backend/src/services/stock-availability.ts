@@ -0,0 +1,125 @@
+import { pool } from '../db/connection.js';
+import { invalidateCache } from '../lib/cache.js';
+import { queueMenuSync } from './menu-sync.js';
+import { createNotificationForRole } from './notification.js';
+import type { Queryable } from './pricing.js';
+
+// Stock-driven menu availability. A product is taken off the menu
+// (is_available = false) when it runs out: its inventory is empty in every
+// branch, or a recipe ingredient no longer covers one portion. It goes back
+// on once restocked, but only if it was the stock check that took it off,
+// never a dish hidden by hand. availability_override leaves a product to
+// staff entirely. Daily special caps are per day and left to the specials.
+//
+// Stock changes call refreshStockAvailability for the items they touched;
+// the periodic job catches everything else (recipe edits, deactivated
+// ingredients).
+
+export const STOCK_AVAILABILITY_JOB = 'stock_availability_sync';
+
+export interface StockAvailabilityFilter {
+  productIds?: string[];
+  ingredientIds?: string[];
+  orderId?: string;
+}
+
+// Same rule as getProductAvailability's in_stock, over all branches. A
+// bundle is out when a component can't cover its share of one bundle.
+const OUT_OF_STOCK = `
+  (EXISTS (SELECT 1 FROM inventory inv WHERE inv.product_id = p.id AND p.sale_unit = 'each')
+   AND NOT EXISTS (SELECT 1 FROM inventory inv WHERE inv.product_id = p.id AND inv.current_stock > 0))
+  OR EXISTS (
+    SELECT 1 FROM product_ingredients pi
+    JOIN ingredients i ON i.id = pi.ingredient_id
+    WHERE pi.product_id = p.id AND i.is_active = true AND pi.quantity_required > 0
+          AND CASE WHEN p.sale_unit = 'each' THEN i.current_stock < pi.quantity_required
+                   ELSE ROUND(i.current_stock / pi.quantity_required, 3) <= 0 END)
+  OR EXISTS (
+    SELECT 1 FROM product_bundle_components bc
+    WHERE bc.bundle_product_id = p.id
+      AND ((EXISTS (SELECT 1 FROM inventory inv WHERE inv.product_id = bc.component_product_id)
+            AND NOT EXISTS (SELECT 1 FROM inventory inv
+                            WHERE inv.product_id = bc.component_product_id AND inv.current_stock >= bc.quantity))
+           OR EXISTS (
+             SELECT 1 FROM product_ingredients pi
+             JOIN ingredients i ON i.id = pi.ingredient_id
+             WHERE pi.product_id = bc.component_product_id AND i.is_active = true AND pi.quantity_required > 0
+                   AND i.current_stock < pi.quantity_required * bc.quantity)))`;
+
+// The products named (directly or as an order's items), every product
+// sharing a recipe ingredient with them or using a named ingredient, the
+// components of named bundles and the bundles of named products. No filter
+// means every product.
+const IN_SCOPE = `
+  p.deleted_at IS NULL AND p.availability_override = false
+  AND (($1::uuid[] IS NULL AND $2::uuid[] IS NULL AND $3::uuid IS NULL)
+       OR p.id = ANY($1::uuid[])
+       OR p.id IN (SELECT product_id FROM order_items WHERE order_id = $3)
+       OR p.id IN (
+         SELECT product_id FROM product_ingredients
+         WHERE ingredient_id = ANY($2::uuid[])
+            OR ingredient_id IN (
+              SELECT ingredient_id FROM product_ingredients
+              WHERE product_id = ANY($1::uuid[])
+                 OR product_id IN (SELECT product_id FROM order_items WHERE order_id = $3)))
+       OR p.id IN (
+         SELECT component_product_id FROM product_bundle_components
+         WHERE bundle_product_id = ANY($1::uuid[])
+            OR bundle_product_id IN (SELECT product_id FROM order_items WHERE order_id = $3))
+       OR p.id IN (
+         SELECT bundle_product_id FROM product_bundle_components
+         WHERE component_product_id = ANY($1::uuid[])
+            OR component_product_id IN (SELECT product_id FROM order_items WHERE order_id = $3)
+            OR component_product_id IN (SELECT product_id FROM product_ingredients WHERE ingredient_id = ANY($2::uuid[]))))`;
+
+// ── SyncStockAvailability ───────────────────────────────────────────────────
+// Flips products in scope to match their stock and tells admins and managers
+// what changed. Returns the number of products flipped.
+
+export async function syncStockAvailability(q: Queryable, filter: StockAvailabilityFilter = {}): Promise<number> {
+  const params = [filter.productIds ?? null, filter.ingredientIds ?? null, filter.orderId ?? null];
+
+  const offRes = await q.query(
+    `UPDATE products p SET is_available = false, stock_unavailable_at = NOW(), updated_at = NOW()
+     WHERE ${IN_SCOPE} AND p.is_available = true AND (${OUT_OF_STOCK})
+     RETURNING p.id, p.name`,
+    params,
+  );
+  const onRes = await q.query(
+    `UPDATE products p SET is_available = true, stock_unavailable_at = NULL, updated_at = NOW()
+     WHERE ${IN_SCOPE} AND p.stock_unavailable_at IS NOT NULL AND NOT (${OUT_OF_STOCK})
+     RETURNING p.id, p.name`,
+    params,
+  );
+
+  const changed = [...offRes.rows, ...onRes.rows];
+  if (changed.length === 0) return 0;
+
+  invalidateCache('menu');
+  await queueMenuSync(q, { productIds: changed.map((row) => row.id) });
+
+  const names = (rows: { name: string }[]) => rows.map((row) => row.name).sort().join(', ');
+  for (const role of ['admin', 'manager']) {
+    if (offRes.rows.length > 0) {
+      await createNotificationForRole(role, 'low_stock', 'Menu Items Sold Out',
+        `Out of stock and taken off the menu: ${names(offRes.rows)}`, 'menu_sold_out');
+    }
+    if (onRes.rows.length > 0) {
+      await createNotificationForRole(role, 'low_stock', 'Menu Items Back in Stock',
+        `Restocked and back on the menu: ${names(onRes.rows)}`, 'menu_back_in_stock');
+    }
+  }
+  return changed.length;
+}
+
+// ── RefreshStockAvailability ────────────────────────────────────────────────
+// Called after a stock change has committed. A failure is only logged: the
+// change itself went through and the periodic job will catch up.
+
+export async function refreshStockAvailability(filter: StockAvailabilityFilter): Promise<void> {
+  try {
+    await syncStockAvailability(pool, filter);
+  } catch (err) {
+    console.error('Failed to sync stock availability:', (err as Error).message);
+  }
+}
@@ -0,0 +1,273 @@
+import type { PoolClient } from 'pg';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition } from './branches.js';
+import { RECIPE_COSTS } from './costing.js';
+import { consumeIngredientBatches } from './ingredient-batches.js';
+import type { Queryable } from './pricing.js';
+
+// Stock takes (physical inventory counts). Starting one snapshots the stock
+// on record: the branch's stock-tracked products (those with an inventory
+// row) and/or the shared ingredient pool. Staff then enter what they find on
+// the shelves. Posting applies each line's variance (counted - expected) to
+// the stock as it is at that moment, so sales made while the count was under
+// way still count. Uncounted lines are left alone.
+
+export type StockTakeScope = 'products' | 'ingredients' | 'all';
+
+export const STOCK_TAKE_SCOPES: StockTakeScope[] = ['products', 'ingredients', 'all'];
+
+export interface StockTakeFailure {
+  message: string;
+  code: string;
+  status: 404 | 409;
+}
+
+export const STOCK_TAKE_SELECT = `
+  SELECT st.id, st.branch_id, b.name AS branch_name, st.scope, st.status, st.notes,
+         st.started_by, su.username AS started_by_username, st.started_at,
+         st.posted_by, pu.username AS posted_by_username, st.posted_at, st.cancelled_at,
+         (SELECT COUNT(*) FROM stock_take_lines l WHERE l.stock_take_id = st.id)::int AS total_lines,
+         (SELECT COUNT(*) FROM stock_take_lines l WHERE l.stock_take_id = st.id AND l.counted_quantity IS NOT NULL)::int AS counted_lines
+  FROM stock_takes st
+  JOIN branches b ON b.id = st.branch_id
+  LEFT JOIN users su ON su.id = st.started_by
+  LEFT JOIN users pu ON pu.id = st.posted_by`;
+
+export const STOCK_TAKE_LINE_SELECT = `
+  SELECT l.id, CASE WHEN l.product_id IS NOT NULL THEN 'product' ELSE 'ingredient' END AS item_type,
+         l.product_id, l.ingredient_id, COALESCE(p.name, i.name) AS item_name, COALESCE(i.unit, 'pcs') AS unit,
+         l.expected_quantity::float8 AS expected_quantity, l.counted_quantity::float8 AS counted_quantity,
+         l.unit_cost::float8 AS unit_cost, l.posted_adjustment::float8 AS posted_adjustment,
+         l.counted_by, u.username AS counted_by_username, l.counted_at
+  FROM stock_take_lines l
+  LEFT JOIN products p ON p.id = l.product_id
+  LEFT JOIN ingredients i ON i.id = l.ingredient_id
+  LEFT JOIN users u ON u.id = l.counted_by`;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+export function formatStockTakeLine(row: Record<string, unknown>) {
+  const counted = row.counted_quantity as number | null;
+  const variance = counted !== null ? round(counted - (row.expected_quantity as number)) : null;
+  const unitCost = row.unit_cost as number | null;
+  return {
+    ...row,
+    variance,
+    variance_value: variance !== null && unitCost !== null ? round(variance * unitCost) : null,
+  };
+}
+
+export function summarizeStockTakeLines(lines: ReturnType<typeof formatStockTakeLine>[]) {
+  let gainValue = 0;
+  let lossValue = 0;
+  for (const line of lines) {
+    if (line.variance_value === null) continue;
+    if (line.variance_value > 0) gainValue += line.variance_value;
+    else lossValue += line.variance_value;
+  }
+  return {
+    total_lines: lines.length,
+    counted_lines: lines.filter((l) => l.variance !== null).length,
+    variance_lines: lines.filter((l) => l.variance !== null && l.variance !== 0).length,
+    gain_value: round(gainValue),
+    loss_value: round(lossValue),
+    net_variance_value: round(gainValue + lossValue),
+  };
+}
+
+// ── SnapshotStockTake ───────────────────────────────────────────────────────
+// Writes the expected quantities and unit costs of a new stock take. Runs in
+// the transaction that created it.
+
+export async function snapshotStockTake(
+  client: PoolClient,
+  stockTakeId: string,
+  branchId: string,
+  scope: StockTakeScope,
+): Promise<void> {
+  if (scope !== 'ingredients') {
+    await client.query(
+      `INSERT INTO stock_take_lines (stock_take_id, product_id, expected_quantity, unit_cost)
+       SELECT $1, p.id, inv.current_stock, COALESCE(p.cost_override, ROUND(r.recipe_cost, 2))
+       FROM products p
+       JOIN inventory inv ON inv.product_id = p.id AND inv.branch_id = $2
+       LEFT JOIN (${RECIPE_COSTS}) r ON r.product_id = p.id
+       WHERE p.deleted_at IS NULL`,
+      [stockTakeId, branchId],
+    );
+  }
+  if (scope !== 'products') {
+    await client.query(
+      `INSERT INTO stock_take_lines (stock_take_id, ingredient_id, expected_quantity, unit_cost)
+       SELECT $1, i.id, i.current_stock, i.unit_cost
+       FROM ingredients i
+       WHERE i.is_active = true`,
+      [stockTakeId],
+    );
+  }
+}
+
+// ── PostStockTake ───────────────────────────────────────────────────────────
+// Applies the counted variances in the caller's transaction. A variance that
+// would take stock below zero (because more was sold since the snapshot than
+// was on the shelf) stops at zero; posted_adjustment records what was
+// actually applied. Stock rows are locked in a fixed order.
+
+export async function postStockTake(
+  client: PoolClient,
+  stockTakeId: string,
+  ownBranch: string | null,
+  userId: string | null,
+): Promise<{ ok: true } | { ok: false; failure: StockTakeFailure }> {
+  const params: unknown[] = [stockTakeId];
+  const takeRes = await client.query(
+    `SELECT st.id, st.branch_id, st.status FROM stock_takes st
+     WHERE st.id = $1${branchCondition('st.branch_id', ownBranch, params)}
+     FOR UPDATE`,
+    params,
+  );
+  const take = takeRes.rows[0];
+  if (!take) {
+    return { ok: false, failure: { message: 'Stock take not found', code: 'not_found', status: 404 } };
+  }
+  if (take.status !== 'open') {
+    return { ok: false, failure: { message: `Stock take is already ${take.status}`, code: 'stock_take_closed', status: 409 } };
+  }
+
+  const linesRes = await client.query(
+    `SELECT id, product_id, ingredient_id, expected_quantity, counted_quantity
+     FROM stock_take_lines
+     WHERE stock_take_id = $1 AND counted_quantity IS NOT NULL AND counted_quantity <> expected_quantity
+     ORDER BY product_id NULLS LAST, ingredient_id`,
+    [stockTakeId],
+  );
+
+  const note = `Stock take ${stockTakeId}`;
+  for (const line of linesRes.rows) {
+    const variance = Number(line.counted_quantity) - Number(line.expected_quantity);
+    let applied: number;
+
+    if (line.product_id) {
+      const invRes = await client.query(
+        'SELECT current_stock FROM inventory WHERE product_id = $1 AND branch_id = $2 FOR UPDATE',
+        [line.product_id, take.branch_id],
+      );
+      let current = 0;
+      if (invRes.rows.length === 0) {
+        await client.query(
+          'INSERT INTO inventory (product_id, branch_id, current_stock, minimum_stock, maximum_stock) VALUES ($1, $2, 0, 10, 100)',
+          [line.product_id, take.branch_id],
+        );
+      } else {
+        current = Number(invRes.rows[0].current_stock);
+      }
+      const newStock = Math.max(0, current + variance);
+      applied = newStock - current;
+      if (applied !== 0) {
+        await client.query(
+          'UPDATE inventory SET current_stock = $1, updated_at = NOW() WHERE product_id = $2 AND branch_id = $3',
+          [newStock, line.product_id, take.branch_id],
+        );
+        await client.query(
+          `INSERT INTO inventory_history (product_id, branch_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
+           VALUES ($1, $2, $3, $4, $5, $6, 'inventory_count', $7, $8)`,
+          [line.product_id, take.branch_id, applied > 0 ? 'add' : 'remove', Math.abs(applied), current, newStock, note, userId],
+        );
+      }
+    } else {
+      const ingRes = await client.query('SELECT current_stock FROM ingredients WHERE id = $1 FOR UPDATE', [line.ingredient_id]);
+      const current = Number(ingRes.rows[0].current_stock);
+      const newStock = round(Math.max(0, current + variance));
+      applied = round(newStock - current);
+      if (applied !== 0) {
+        await client.query(
+          'UPDATE ingredients SET current_stock = $1, updated_at = NOW() WHERE id = $2',
+          [newStock, line.ingredient_id],
+        );
+        await client.query(
+          `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by)
+           VALUES ($1, $2, $3, $4, $5, 'inventory_count', $6, $7)`,
+          [line.ingredient_id, applied > 0 ? 'count_gain' : 'count_loss', Math.abs(applied), current, newStock, note, userId],
+        );
+        // Missing stock is written off the batches due to expire first
+        if (applied < 0) await consumeIngredientBatches(client, line.ingredient_id, -applied, null);
+      }
+    }
+
+    await client.query('UPDATE stock_take_lines SET posted_adjustment = $2 WHERE id = $1', [line.id, applied]);
+  }
+
+  await client.query(
+    `UPDATE stock_take_lines SET posted_adjustment = 0
+     WHERE stock_take_id = $1 AND counted_quantity IS NOT NULL AND posted_adjustment IS NULL`,
+    [stockTakeId],
+  );
+  await client.query(
+    "UPDATE stock_takes SET status = 'posted', posted_by = $2, posted_at = NOW() WHERE id = $1",
+    [stockTakeId, userId],
+  );
+  return { ok: true };
+}
+
+// ── BuildStockVarianceReport ────────────────────────────────────────────────
+// Stock takes posted over [from, to], per product / ingredient: how often it
+// was counted, the total variance and its value at the snapshot costs.
+// Largest losses first.
+
+export async function buildStockVarianceReport(q: Queryable, from: string, to: string, branchId: string | null) {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const res = await q.query(
+    `SELECT CASE WHEN l.product_id IS NOT NULL THEN 'product' ELSE 'ingredient' END AS item_type,
+            COALESCE(l.product_id, l.ingredient_id) AS item_id, COALESCE(p.name, i.name) AS item_name,
+            COALESCE(i.unit, 'pcs') AS unit,
+            COUNT(DISTINCT st.id)::int AS stock_takes,
+            SUM(l.expected_quantity)::float8 AS expected_quantity,
+            SUM(l.counted_quantity)::float8 AS counted_quantity,
+            SUM(l.counted_quantity - l.expected_quantity)::float8 AS variance,
+            SUM(l.posted_adjustment)::float8 AS posted_adjustment,
+            SUM((l.counted_quantity - l.expected_quantity) * l.unit_cost)::float8 AS variance_value
+     FROM stock_take_lines l
+     JOIN stock_takes st ON st.id = l.stock_take_id
+     LEFT JOIN products p ON p.id = l.product_id
+     LEFT JOIN ingredients i ON i.id = l.ingredient_id
+     WHERE st.status = 'posted' AND l.counted_quantity IS NOT NULL
+           AND DATE(st.posted_at AT TIME ZONE $3) BETWEEN $1 AND $2
+           ${branchCondition('st.branch_id', branchId, params)}
+     GROUP BY 1, 2, 3, 4
+     HAVING SUM(l.counted_quantity - l.expected_quantity) <> 0
+     ORDER BY variance_value ASC NULLS LAST, item_name ASC`,
+    params,
+  );
+
+  const takesParams: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const takesRes = await q.query(
+    `SELECT COUNT(*)::int AS count FROM stock_takes st
+     WHERE st.status = 'posted' AND DATE(st.posted_at AT TIME ZONE $3) BETWEEN $1 AND $2
+           ${branchCondition('st.branch_id', branchId, takesParams)}`,
+    takesParams,
+  );
+
+  const items = res.rows.map((row) => ({
+    ...row,
+    variance: round(row.variance),
+    variance_value: row.variance_value !== null ? round(row.variance_value) : null,
+  }));
+  const valued = items.filter((item) => item.variance_value !== null);
+  const gainValue = valued.reduce((sum, item) => sum + Math.max(0, item.variance_value!), 0);
+  const lossValue = valued.reduce((sum, item) => sum + Math.min(0, item.variance_value!), 0);
+
+  return {
+    from,
+    to,
+    branch_id: branchId,
+    summary: {
+      stock_takes: takesRes.rows[0].count,
+      items_with_variance: items.length,
+      gain_value: round(gainValue),
+      loss_value: round(lossValue),
+      net_variance_value: round(gainValue + lossValue),
+      unvalued_items: items.length - valued.length,
+    },
+    items,
+  };
+}
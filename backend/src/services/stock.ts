@@ -0,0 +1,360 @@
+import type { PoolClient } from 'pg';
+import type { Queryable } from './pricing.js';
+import { claimSpecialPortions, releaseSpecialPortions } from './daily-specials.js';
+import { consumeIngredientBatches, returnIngredientBatches } from './ingredient-batches.js';
+import { expandBundles, loadBundleComponents } from './bundles.js';
+
+// Sellable stock for menu items. A product is stock-tracked when it has an
+// inventory row (finished goods such as bottled drinks or limited dishes)
+// and/or a recipe in product_ingredients; its remaining portions are the
+// lower of the two. Daily specials add a per-day portion cap on top.
+// Untracked products are always in stock. Product inventory is kept per
+// branch; ingredients are a shared pool. Products sold by weight are tracked
+// through their recipe only (quantity_required per sale unit, e.g. per kg),
+// since inventory rows count whole portions. A bundle's stock is that of
+// its components.
+
+export interface ProductAvailability {
+  in_stock: boolean;
+  /** Portions that can still be sold, or null when the product isn't tracked */
+  remaining: number | null;
+  /** Set when the product is an active daily special */
+  daily_special: { daily_quantity: number; remaining_quantity: number } | null;
+  /** For a bundle, the tracked component that allows the fewest bundles */
+  limiting_component: { product_id: string; name: string; remaining: number } | null;
+}
+
+export interface StockShortage {
+  product_id: string;
+  name: string;
+  requested: number;
+  remaining: number;
+}
+
+export interface StockRequest {
+  product_id: string;
+  name: string;
+  quantity: number;
+}
+
+// ── GetProductAvailability ──────────────────────────────────────────────────
+
+export async function getProductAvailability(
+  q: Queryable,
+  productIds: string[],
+  branchId: string,
+): Promise<Map<string, ProductAvailability>> {
+  const bundles = await loadBundleComponents(q, productIds);
+  const componentIds = [...bundles.values()].flat().map((component) => component.product_id);
+  const result = await loadOwnAvailability(q, [...new Set([...productIds, ...componentIds])], branchId);
+
+  for (const [bundleId, components] of bundles) {
+    const own = result.get(bundleId);
+    if (!own) continue;
+
+    // Whole bundles each component still covers
+    let limiting: ProductAvailability['limiting_component'] = null;
+    let limitingBundles = Infinity;
+    for (const component of components) {
+      const remaining = result.get(component.product_id)?.remaining ?? null;
+      if (remaining === null) continue;
+      const covered = Math.floor(remaining / component.quantity);
+      if (covered < limitingBundles) {
+        limitingBundles = covered;
+        limiting = { product_id: component.product_id, name: component.name, remaining };
+      }
+    }
+    if (!limiting) continue;
+
+    const remaining = own.remaining === null ? limitingBundles : Math.min(own.remaining, limitingBundles);
+    result.set(bundleId, { ...own, in_stock: remaining > 0, remaining, limiting_component: limiting });
+  }
+
+  for (const id of componentIds) {
+    if (!productIds.includes(id)) result.delete(id);
+  }
+  return result;
+}
+
+// A product's own limits, ignoring any bundle components
+async function loadOwnAvailability(
+  q: Queryable,
+  productIds: string[],
+  branchId: string,
+): Promise<Map<string, ProductAvailability>> {
+  const result = new Map<string, ProductAvailability>();
+  if (productIds.length === 0) return result;
+
+  const res = await q.query(
+    `SELECT p.id,
+            (SELECT inv.current_stock FROM inventory inv
+             WHERE inv.product_id = p.id AND inv.branch_id = $2 AND p.sale_unit = 'each' LIMIT 1) AS product_stock,
+            (SELECT MIN(CASE WHEN p.sale_unit = 'each' THEN FLOOR(i.current_stock / pi.quantity_required)
+                             ELSE ROUND(i.current_stock / pi.quantity_required, 3) END)
+             FROM product_ingredients pi
+             JOIN ingredients i ON i.id = pi.ingredient_id
+             WHERE pi.product_id = p.id AND i.is_active = true AND pi.quantity_required > 0) AS recipe_portions,
+            ds.daily_quantity AS special_daily_quantity,
+            ds.remaining_quantity AS special_remaining
+     FROM products p
+     LEFT JOIN daily_specials ds ON ds.product_id = p.id AND ds.is_active = true
+     WHERE p.id = ANY($1::uuid[])`,
+    [productIds, branchId],
+  );
+
+  for (const row of res.rows) {
+    const limits = [row.product_stock, row.recipe_portions, row.special_remaining]
+      .filter((v) => v !== null && v !== undefined)
+      .map((v) => Math.max(0, Number(v)));
+    const remaining = limits.length > 0 ? Math.min(...limits) : null;
+    result.set(row.id, {
+      in_stock: remaining === null || remaining > 0,
+      remaining,
+      daily_special: row.special_daily_quantity !== null
+        ? { daily_quantity: Number(row.special_daily_quantity), remaining_quantity: Number(row.special_remaining) }
+        : null,
+      limiting_component: null,
+    });
+  }
+
+  return result;
+}
+
+// ── DeductStockForOrder ─────────────────────────────────────────────────────
+// Must run inside the caller's transaction, which has to be rolled back when
+// a shortage is returned. Movements are tagged with the order so
+// releaseStockForOrder can undo them. Bundles take their components out of
+// stock, so a shortage may name a component.
+
+export async function deductStockForOrder(
+  client: PoolClient,
+  orderId: string,
+  requests: StockRequest[],
+): Promise<StockShortage | null> {
+  const specialShortage = await claimSpecialPortions(client, orderId, requests);
+  if (specialShortage) return specialShortage;
+
+  // The same product can appear on several lines, and in several bundles
+  const wanted = new Map<string, StockRequest>();
+  for (const r of await expandBundles(client, requests)) {
+    const prev = wanted.get(r.product_id);
+    wanted.set(r.product_id, { ...r, quantity: (prev?.quantity ?? 0) + r.quantity });
+  }
+  const productIds = [...wanted.keys()];
+
+  const invRes = await client.query(
+    `SELECT inv.id, inv.product_id, inv.branch_id, inv.current_stock
+     FROM inventory inv
+     JOIN products p ON p.id = inv.product_id AND p.sale_unit = 'each'
+     WHERE inv.product_id = ANY($1::uuid[]) AND inv.branch_id = (SELECT branch_id FROM orders WHERE id = $2)
+     ORDER BY inv.product_id FOR UPDATE OF inv`,
+    [productIds, orderId],
+  );
+  for (const row of invRes.rows) {
+    const req = wanted.get(row.product_id)!;
+    const stock = Number(row.current_stock);
+    if (stock < req.quantity) {
+      return { product_id: req.product_id, name: req.name, requested: req.quantity, remaining: Math.max(0, stock) };
+    }
+  }
+
+  const recipeRes = await client.query(
+    `SELECT pi.product_id, pi.ingredient_id, pi.quantity_required
+     FROM product_ingredients pi
+     JOIN ingredients i ON i.id = pi.ingredient_id
+     WHERE pi.product_id = ANY($1::uuid[]) AND i.is_active = true AND pi.quantity_required > 0`,
+    [productIds],
+  );
+
+  // Ingredients shared between products are summed before checking
+  const needed = new Map<string, number>();
+  for (const r of recipeRes.rows) {
+    const qty = Number(r.quantity_required) * wanted.get(r.product_id)!.quantity;
+    needed.set(r.ingredient_id, (needed.get(r.ingredient_id) ?? 0) + qty);
+  }
+
+  const ingredientStock = new Map<string, number>();
+  if (needed.size > 0) {
+    const ingRes = await client.query(
+      `SELECT id, current_stock FROM ingredients
+       WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE`,
+      [[...needed.keys()]],
+    );
+    for (const r of ingRes.rows) ingredientStock.set(r.id, Number(r.current_stock));
+  }
+
+  for (const [ingredientId, qty] of needed) {
+    const stock = ingredientStock.get(ingredientId) ?? 0;
+    if (stock >= qty) continue;
+
+    const recipe = recipeRes.rows.find((r) => r.ingredient_id === ingredientId)!;
+    const req = wanted.get(recipe.product_id)!;
+    return {
+      product_id: req.product_id,
+      name: req.name,
+      requested: req.quantity,
+      remaining: Math.max(0, Math.floor(stock / Number(recipe.quantity_required))),
+    };
+  }
+
+  for (const row of invRes.rows) {
+    const req = wanted.get(row.product_id)!;
+    const previous = Number(row.current_stock);
+    await client.query(
+      'UPDATE inventory SET current_stock = current_stock - $1, updated_at = NOW() WHERE id = $2',
+      [req.quantity, row.id],
+    );
+    await client.query(
+      `INSERT INTO inventory_history (product_id, branch_id, operation, quantity, previous_stock, new_stock, reason, notes, order_id)
+       VALUES ($1, $2, 'remove', $3, $4, $5, 'sale', $6, $7)`,
+      [req.product_id, row.branch_id, req.quantity, previous, previous - req.quantity, 'Customer order', orderId],
+    );
+  }
+
+  for (const [ingredientId, qty] of needed) {
+    const previous = ingredientStock.get(ingredientId)!;
+    await client.query(
+      'UPDATE ingredients SET current_stock = current_stock - $1, updated_at = NOW() WHERE id = $2',
+      [qty, ingredientId],
+    );
+    await client.query(
+      `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, order_id)
+       VALUES ($1, 'order_consumption', $2, $3, $4, 'Customer order', $5)`,
+      [ingredientId, qty, previous, previous - qty, orderId],
+    );
+    await consumeIngredientBatches(client, ingredientId, qty, orderId);
+  }
+
+  return null;
+}
+
+// ── ReleaseStockForOrder ────────────────────────────────────────────────────
+// Puts back whatever deductStockForOrder took for an order, e.g. when it is
+// cancelled. Works on net quantities (taken minus already returned), so it is
+// safe to call more than once, after a partial refund restock, and for
+// orders that never deducted.
+
+export async function releaseStockForOrder(client: PoolClient, orderId: string, userId: string | null): Promise<void> {
+  await releaseSpecialPortions(client, orderId);
+
+  const products = await outstandingProductStock(client, orderId);
+  for (const [productId, qty] of products) {
+    await returnProductStock(client, orderId, productId, qty, userId, 'Order cancelled');
+  }
+
+  const ingredients = await outstandingIngredientStock(client, orderId);
+  for (const [ingredientId, qty] of ingredients) {
+    await returnIngredientStock(client, orderId, ingredientId, qty, userId, 'Order cancelled');
+  }
+}
+
+// ── RestockOrderItems ───────────────────────────────────────────────────────
+// Partial counterpart of releaseStockForOrder used by refunds: returns stock
+// for specific items only, never more than the order actually took.
+// Daily special portions are not given back — the dish was served.
+
+export async function restockOrderItems(
+  client: PoolClient,
+  orderId: string,
+  items: { product_id: string; quantity: number }[],
+  userId: string | null,
+  note: string,
+): Promise<void> {
+  items = await expandBundles(client, items);
+  const products = await outstandingProductStock(client, orderId);
+  const ingredients = await outstandingIngredientStock(client, orderId);
+
+  const recipeRes = await client.query(
+    `SELECT product_id, ingredient_id, quantity_required
+     FROM product_ingredients
+     WHERE product_id = ANY($1::uuid[]) AND quantity_required > 0`,
+    [items.map((i) => i.product_id)],
+  );
+
+  for (const item of items) {
+    const productQty = Math.min(item.quantity, products.get(item.product_id) ?? 0);
+    if (productQty > 0) {
+      await returnProductStock(client, orderId, item.product_id, productQty, userId, note);
+      products.set(item.product_id, products.get(item.product_id)! - productQty);
+    }
+
+    for (const r of recipeRes.rows) {
+      if (r.product_id !== item.product_id) continue;
+      const outstanding = ingredients.get(r.ingredient_id) ?? 0;
+      const qty = Math.min(Number(r.quantity_required) * item.quantity, outstanding);
+      if (qty <= 0) continue;
+      await returnIngredientStock(client, orderId, r.ingredient_id, qty, userId, note);
+      ingredients.set(r.ingredient_id, outstanding - qty);
+    }
+  }
+}
+
+// Stock taken by an order and not yet returned, keyed by product / ingredient
+
+async function outstandingProductStock(client: PoolClient, orderId: string): Promise<Map<string, number>> {
+  const res = await client.query(
+    `SELECT product_id,
+            SUM(CASE WHEN reason = 'sale' THEN quantity ELSE -quantity END) AS outstanding
+     FROM inventory_history
+     WHERE order_id = $1 AND reason IN ('sale', 'return')
+     GROUP BY product_id`,
+    [orderId],
+  );
+  const out = new Map<string, number>();
+  for (const row of res.rows) {
+    if (Number(row.outstanding) > 0) out.set(row.product_id, Number(row.outstanding));
+  }
+  return out;
+}
+
+async function outstandingIngredientStock(client: PoolClient, orderId: string): Promise<Map<string, number>> {
+  const res = await client.query(
+    `SELECT ingredient_id,
+            SUM(CASE WHEN operation = 'order_consumption' THEN quantity ELSE -quantity END) AS outstanding
+     FROM ingredient_history
+     WHERE order_id = $1 AND operation IN ('order_consumption', 'order_cancellation')
+     GROUP BY ingredient_id`,
+    [orderId],
+  );
+  const out = new Map<string, number>();
+  for (const row of res.rows) {
+    if (Number(row.outstanding) > 0) out.set(row.ingredient_id, Number(row.outstanding));
+  }
+  return out;
+}
+
+async function returnProductStock(
+  client: PoolClient, orderId: string, productId: string, qty: number, userId: string | null, note: string,
+): Promise<void> {
+  const upd = await client.query(
+    `UPDATE inventory SET current_stock = current_stock + $1, updated_at = NOW()
+     WHERE product_id = $2 AND branch_id = (SELECT branch_id FROM orders WHERE id = $3)
+     RETURNING current_stock, branch_id`,
+    [qty, productId, orderId],
+  );
+  if (upd.rows.length === 0) return;
+  const newStock = Number(upd.rows[0].current_stock);
+  await client.query(
+    `INSERT INTO inventory_history (product_id, branch_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by, order_id)
+     VALUES ($1, $2, 'add', $3, $4, $5, 'return', $6, $7, $8)`,
+    [productId, upd.rows[0].branch_id, qty, newStock - qty, newStock, note, userId, orderId],
+  );
+}
+
+async function returnIngredientStock(
+  client: PoolClient, orderId: string, ingredientId: string, qty: number, userId: string | null, note: string,
+): Promise<void> {
+  const upd = await client.query(
+    `UPDATE ingredients SET current_stock = current_stock + $1, updated_at = NOW()
+     WHERE id = $2 RETURNING current_stock`,
+    [qty, ingredientId],
+  );
+  if (upd.rows.length === 0) return;
+  const newStock = Number(upd.rows[0].current_stock);
+  await client.query(
+    `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, adjusted_by, order_id)
+     VALUES ($1, 'order_cancellation', $2, $3, $4, $5, $6, $7)`,
+    [ingredientId, qty, newStock - qty, newStock, note, userId, orderId],
+  );
+  await returnIngredientBatches(client, orderId, ingredientId, qty);
+}
@@ -0,0 +1,131 @@
+import type { Queryable } from './pricing.js';
+import { loadTaxRates } from './tax.js';
+
+// Holiday and event surcharges. A surcharge is a percentage of the order
+// after discounts, or a fixed amount per order, that applies to orders
+// placed within its window (optionally only at one branch or for some order
+// types). Scheduled orders fall in the window of the time they are due.
+// Surcharges are taxed at the branch's tax_rate, are not subject to the
+// service charge, and are itemized on the order so the receipt shows each.
+
+export type SurchargeType = 'percentage' | 'fixed';
+
+export const SURCHARGE_TYPES: SurchargeType[] = ['percentage', 'fixed'];
+
+export interface Surcharge {
+  id: string;
+  name: string;
+  surcharge_type: SurchargeType;
+  value: number;
+}
+
+export interface OrderSurchargeLine {
+  surcharge_id: string;
+  name: string;
+  amount: number;
+  tax_amount: number;
+  /** Percent */
+  tax_rate: number;
+}
+
+export interface SurchargeResult {
+  amount: number;
+  tax_amount: number;
+  lines: OrderSurchargeLine[];
+}
+
+function round2(n: number): number {
+  return Math.round(n * 100) / 100;
+}
+
+export function isSurchargeType(value: unknown): value is SurchargeType {
+  return typeof value === 'string' && (SURCHARGE_TYPES as string[]).includes(value);
+}
+
+export const SURCHARGE_SELECT = `
+  SELECT s.id, s.name, s.description, s.surcharge_type, s.value::float8 AS value, s.starts_at, s.ends_at,
+         s.branch_id, b.name AS branch_name, s.order_types, s.is_active, s.created_by, s.created_at, s.updated_at
+  FROM surcharges s
+  LEFT JOIN branches b ON b.id = s.branch_id`;
+
+// ── LoadActiveSurcharges ────────────────────────────────────────────────────
+
+export async function loadActiveSurcharges(
+  q: Queryable,
+  branchId: string | null,
+  orderType: string,
+  at: Date,
+): Promise<Surcharge[]> {
+  const res = await q.query(
+    `SELECT id, name, surcharge_type, value::float8 AS value
+     FROM surcharges
+     WHERE is_active = true AND starts_at <= $1 AND ends_at > $1
+       AND (branch_id IS NULL OR branch_id = $2)
+       AND (cardinality(order_types) = 0 OR $3 = ANY(order_types))
+     ORDER BY starts_at ASC, name ASC`,
+    [at.toISOString(), branchId, orderType],
+  );
+  return res.rows;
+}
+
+// ── ApplySurcharges ─────────────────────────────────────────────────────────
+// base is the order's subtotal after discounts; taxRate a fraction.
+
+export function applySurcharges(surcharges: Surcharge[], base: number, taxRate: number): SurchargeResult {
+  const lines = surcharges
+    .map((s) => {
+      const amount = round2(s.surcharge_type === 'percentage' ? (base * s.value) / 100 : s.value);
+      return {
+        surcharge_id: s.id,
+        name: s.name,
+        amount,
+        tax_amount: round2(amount * taxRate),
+        tax_rate: round2(taxRate * 100),
+      };
+    })
+    .filter((line) => line.amount > 0);
+
+  return {
+    amount: round2(lines.reduce((sum, l) => sum + l.amount, 0)),
+    tax_amount: round2(lines.reduce((sum, l) => sum + l.tax_amount, 0)),
+    lines,
+  };
+}
+
+// ── ComputeOrderSurcharges ──────────────────────────────────────────────────
+
+export async function computeOrderSurcharges(
+  q: Queryable,
+  branchId: string | null,
+  orderType: string,
+  base: number,
+  at: Date = new Date(),
+): Promise<SurchargeResult> {
+  const surcharges = await loadActiveSurcharges(q, branchId, orderType, at);
+  if (surcharges.length === 0) return { amount: 0, tax_amount: 0, lines: [] };
+  const rates = await loadTaxRates(q, branchId, orderType);
+  return applySurcharges(surcharges, base, rates.tax_rate);
+}
+
+// ── RecordOrderSurcharges ───────────────────────────────────────────────────
+// Replaces the order's surcharge lines, so repricing can call it again.
+
+export async function recordOrderSurcharges(q: Queryable, orderId: string, lines: OrderSurchargeLine[]): Promise<void> {
+  await q.query('DELETE FROM order_surcharges WHERE order_id = $1', [orderId]);
+  for (const line of lines) {
+    await q.query(
+      `INSERT INTO order_surcharges (order_id, surcharge_id, name, amount, tax_amount, tax_rate)
+       VALUES ($1, $2, $3, $4, $5, $6)`,
+      [orderId, line.surcharge_id, line.name, line.amount, line.tax_amount, line.tax_rate],
+    );
+  }
+}
+
+export async function loadOrderSurcharges(q: Queryable, orderId: string) {
+  const res = await q.query(
+    `SELECT surcharge_id, name, amount::float8 AS amount, tax_amount::float8 AS tax_amount, tax_rate::float8 AS tax_rate
+     FROM order_surcharges WHERE order_id = $1 ORDER BY created_at ASC, name ASC`,
+    [orderId],
+  );
+  return res.rows as OrderSurchargeLine[];
+}
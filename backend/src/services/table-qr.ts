@@ -0,0 +1,136 @@
+import crypto from 'node:crypto';
+import { env } from '../env.js';
+import type { Queryable } from './pricing.js';
+
+// Table QR codes. A code is a signed token, <table>.<version>.<signature>:
+// the table's UUID and the code version, signed with QR_SIGNING_SECRET (or
+// JWT_SECRET). The customer endpoints check the signature and that the
+// version is still the table's current one, so a code can't be guessed or
+// made up from a table ID, and rotating a table's code (bumping the
+// version) stops every earlier copy from working, e.g. after a sticker was
+// taken home or photographed.
+
+const SIGNATURE_BYTES = 16;
+
+function secret(): string {
+  return env.QR_SIGNING_SECRET || env.JWT_SECRET;
+}
+
+function sign(tableId: string, version: number): string {
+  return crypto
+    .createHmac('sha256', secret())
+    .update(`table-qr:${tableId}:${version}`)
+    .digest()
+    .subarray(0, SIGNATURE_BYTES)
+    .toString('base64url');
+}
+
+function encodeUuid(id: string): string {
+  return Buffer.from(id.replace(/-/g, ''), 'hex').toString('base64url');
+}
+
+function decodeUuid(encoded: string): string | null {
+  const hex = Buffer.from(encoded, 'base64url').toString('hex');
+  if (hex.length !== 32) return null;
+  return `${hex.slice(0, 8)}-${hex.slice(8, 12)}-${hex.slice(12, 16)}-${hex.slice(16, 20)}-${hex.slice(20)}`;
+}
+
+export function signTableCode(tableId: string, version: number): string {
+  return `${encodeUuid(tableId)}.${version}.${sign(tableId, version)}`;
+}
+
+/** The table and version a code is for, or null when it isn't a valid signed code. */
+export function verifyTableCode(code: string): { tableId: string; version: number } | null {
+  const match = /^([A-Za-z0-9_-]{22})\.(\d{1,9})\.([A-Za-z0-9_-]{22})$/.exec(code);
+  if (!match) return null;
+  const tableId = decodeUuid(match[1]);
+  if (!tableId) return null;
+  const version = Number(match[2]);
+
+  const expected = Buffer.from(sign(tableId, version), 'base64url');
+  const given = Buffer.from(match[3], 'base64url');
+  if (given.length !== expected.length || !crypto.timingSafeEqual(given, expected)) return null;
+  return { tableId, version };
+}
+
+/** The link the QR code encodes; the customer ordering page reads the code from it. */
+export function tableOrderUrl(code: string): string {
+  return `${env.PUBLIC_APP_URL.replace(/\/+$/, '')}/order/${code}`;
+}
+
+// ── FindTableByCode ─────────────────────────────────────────────────────────
+// The table a scanned code belongs to, if the code is still its current one.
+
+export async function findTableByCode(q: Queryable, code: string) {
+  const claim = verifyTableCode(code);
+  if (!claim) return null;
+  const res = await q.query(
+    `SELECT id, table_number, seating_capacity, location, branch_id
+     FROM dining_tables
+     WHERE id = $1 AND qr_version = $2 AND deleted_at IS NULL`,
+    [claim.tableId, claim.version],
+  );
+  return res.rows[0] ?? null;
+}
+
+// ── IssueTableCode ──────────────────────────────────────────────────────────
+// Gives the table a signed code. Without `rotate` a table whose current code
+// is valid keeps it, so generating again doesn't break printed codes; with
+// it the version is bumped and every earlier code stops working.
+
+export interface TableCode {
+  table_id: string;
+  table_number: string;
+  branch_id: string;
+  qr_code: string;
+  qr_version: number;
+  qr_rotated_at: string | null;
+  url: string;
+  /** False when the table kept the code it had */
+  generated: boolean;
+}
+
+export async function issueTableCode(q: Queryable, tableId: string, rotate: boolean): Promise<TableCode | null> {
+  const res = await q.query(
+    `SELECT id, table_number, branch_id, qr_code, qr_version, qr_rotated_at
+     FROM dining_tables WHERE id = $1 AND deleted_at IS NULL
+     FOR UPDATE`,
+    [tableId],
+  );
+  const table = res.rows[0];
+  if (!table) return null;
+
+  const current = table.qr_code ? verifyTableCode(table.qr_code) : null;
+  if (!rotate && current && current.tableId === table.id && current.version === table.qr_version) {
+    return {
+      table_id: table.id,
+      table_number: table.table_number,
+      branch_id: table.branch_id,
+      qr_code: table.qr_code,
+      qr_version: table.qr_version,
+      qr_rotated_at: table.qr_rotated_at,
+      url: tableOrderUrl(table.qr_code),
+      generated: false,
+    };
+  }
+
+  const version = table.qr_version + 1;
+  const code = signTableCode(table.id, version);
+  const updated = await q.query(
+    `UPDATE dining_tables
+     SET qr_code = $2, qr_version = $3, qr_rotated_at = NOW(), updated_at = NOW()
+     WHERE id = $1
+     RETURNING qr_rotated_at`,
+    [table.id, code, version],
+  );
+  return {
+    table_id: table.id,
+    table_number: table.table_number,
+    branch_id: table.branch_id,
+    qr_code: code,
+    qr_version: version,
+    qr_rotated_at: updated.rows[0].qr_rotated_at,
+    url: tableOrderUrl(code),
+    generated: true,
+  };
+}
@@ -0,0 +1,384 @@
+import type { Pool, PoolClient } from 'pg';
+import { pool } from '../db/connection.js';
+import { withTransaction } from '../db/transaction.js';
+import type { Queryable } from './pricing.js';
+import {
+  captureAuthorization,
+  cancelAuthorization,
+  type GatewayAuthorizationResult,
+  type GatewayNotification,
+} from './payment-gateway.js';
+import { loadPaymentLinkExpiryMinutes } from './payment-links.js';
+import { createNotificationForRole } from './notification.js';
+import { emitWebhookEvent, orderEventData, paymentEventData } from './webhooks.js';
+import { paymentsProcessedTotal, paymentsAmountTotal } from '../lib/metrics.js';
+
+// Bar tabs. Staff open a tab by sending the guest to a gateway card page
+// that places a hold (pre-authorization) on the card. Orders are then added
+// to the open tab without being paid, up to the authorized amount. Closing
+// the tab captures what the orders still owe in one charge, recorded as a
+// card payment on each order; the gateway releases the rest of the hold. A
+// tab closed with nothing owed has its hold cancelled instead, as does an
+// unused tab once the hold period lapses.
+
+export const TAB_GATEWAY_PREFIX = 'TAB';
+export const TAB_AUTHORIZATIONS_RELEASE_JOB = 'tab_authorizations_release';
+export const TAB_STATUSES = ['pending', 'open', 'closing', 'closed', 'released', 'failed'];
+
+const DEFAULT_PREAUTH_AMOUNT = 500000;
+const DEFAULT_HOLD_HOURS = 24;
+
+// Orders the kitchen or bar is still working on; a tab can't close over them
+const UNFINISHED_ORDER_STATUSES = ['scheduled', 'parked', 'pending', 'confirmed', 'preparing', 'ready'];
+
+export interface TabFailure {
+  message: string;
+  code: string;
+  status: 404 | 409 | 502;
+}
+
+export async function loadTabSettings(q: Queryable): Promise<{ preauthAmount: number; holdHours: number }> {
+  const res = await q.query(
+    `SELECT setting_key, setting_value FROM system_settings
+     WHERE setting_key IN ('tab_preauth_amount', 'tab_auth_hold_hours')`,
+  );
+  const settings = { preauthAmount: DEFAULT_PREAUTH_AMOUNT, holdHours: DEFAULT_HOLD_HOURS };
+  for (const row of res.rows) {
+    const value = Number(row.setting_value);
+    if (isNaN(value) || value <= 0) continue;
+    if (row.setting_key === 'tab_preauth_amount') settings.preauthAmount = value;
+    if (row.setting_key === 'tab_auth_hold_hours') settings.holdHours = value;
+  }
+  return settings;
+}
+
+// What each open order on a tab still owes, oldest first
+const TAB_ORDER_BALANCES = `
+  SELECT o.id, o.order_number, o.status, o.table_id,
+         o.total_amount - COALESCE((SELECT SUM(p.amount) FROM payments p
+                                    WHERE p.order_id = o.id AND p.status = 'completed'), 0) AS balance
+  FROM orders o
+  WHERE o.tab_id = $1 AND o.status <> 'cancelled'
+  ORDER BY o.created_at ASC`;
+
+export const TAB_SELECT = `
+  SELECT t.id, t.branch_id, t.customer_name, t.table_id, dt.table_number, t.status,
+         t.authorized_amount::float8 AS authorized_amount, t.captured_amount::float8 AS captured_amount,
+         COALESCE(s.order_count, 0)::int AS order_count, COALESCE(s.balance, 0)::float8 AS balance,
+         t.payment_url, t.auth_expires_at, t.last_error, t.opened_by, t.closed_by,
+         t.created_at, t.authorized_at, t.closed_at, t.updated_at
+  FROM tabs t
+  LEFT JOIN dining_tables dt ON dt.id = t.table_id
+  LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS order_count, SUM(b.balance) AS balance
+    FROM (${TAB_ORDER_BALANCES.replace('$1', 't.id')}) b
+  ) s ON true`;
+
+export function formatTab(row: Record<string, unknown>) {
+  const captured = row.captured_amount === null ? null : Number(row.captured_amount);
+  return {
+    ...row,
+    // Remaining headroom while open; what the gateway let go once closed
+    available_amount: row.status === 'open' ? Math.max(0, Number(row.authorized_amount) - Number(row.balance)) : 0,
+    released_amount: captured !== null ? Number(row.authorized_amount) - captured
+      : row.status === 'released' ? Number(row.authorized_amount) : null,
+  };
+}
+
+async function tabBalance(q: Queryable, tabId: string): Promise<number> {
+  const res = await q.query(`SELECT COALESCE(SUM(b.balance), 0) AS balance FROM (${TAB_ORDER_BALANCES}) b`, [tabId]);
+  return Math.round(Number(res.rows[0].balance) * 100) / 100;
+}
+
+// ── AddOrderToTab ───────────────────────────────────────────────────────────
+// Runs in the order's transaction. Locks the tab so concurrent orders can't
+// push it past the authorized amount. `amount` is what the order adds on top
+// of the tab's current balance: its total for a new order, 0 for an edited
+// order whose new total is already counted.
+
+export async function addOrderToTab(
+  client: PoolClient,
+  input: { tabId: string; branchId: string; amount: number },
+): Promise<{ ok: true } | { ok: false; failure: TabFailure }> {
+  const res = await client.query('SELECT status, branch_id, authorized_amount FROM tabs WHERE id = $1 FOR UPDATE', [input.tabId]);
+  const tab = res.rows[0];
+  if (!tab || tab.branch_id !== input.branchId) {
+    return { ok: false, failure: { message: 'Tab not found', code: 'tab_not_found', status: 404 } };
+  }
+  if (tab.status !== 'open') {
+    return { ok: false, failure: { message: `Orders can only be added to an open tab - tab is ${tab.status}`, code: 'tab_not_open', status: 409 } };
+  }
+
+  const available = Number(tab.authorized_amount) - await tabBalance(client, input.tabId);
+  if (input.amount > available) {
+    return {
+      ok: false,
+      failure: {
+        message: `Order exceeds the tab's remaining authorization of ${Math.max(0, available)}`,
+        code: 'tab_limit_exceeded',
+        status: 409,
+      },
+    };
+  }
+  return { ok: true };
+}
+
+// ── SettleTabNotification ───────────────────────────────────────────────────
+// Gateway notifications for a tab's card hold. Idempotent: each transition
+// only applies from the state it expects.
+
+export async function settleTabNotification(tabId: string, notification: GatewayNotification): Promise<void> {
+  const status = notification.transaction_status;
+
+  if (status === 'authorize') {
+    const { holdHours } = await loadTabSettings(pool);
+    const res = await pool.query(
+      `UPDATE tabs
+       SET status = 'open', gateway_transaction_id = $2, authorized_at = NOW(),
+           auth_expires_at = NOW() + make_interval(hours => $3), updated_at = NOW()
+       WHERE id = $1 AND status = 'pending'
+       RETURNING customer_name`,
+      [tabId, notification.transaction_id ?? null, holdHours],
+    );
+    if (res.rows.length > 0) {
+      await createNotificationForRole('counter', 'order_update', 'Tab Opened', `Card authorized for ${res.rows[0].customer_name}'s tab`, 'tab_opened');
+    }
+    return;
+  }
+
+  // A capture whose response was lost is finished from its notification
+  if (status === 'capture' || status === 'settlement') {
+    await finishTabCapture(tabId, Number(notification.gross_amount));
+    return;
+  }
+
+  if (status !== 'pending') {
+    // deny, cancel, expire, failure
+    const res = await pool.query(
+      `UPDATE tabs SET status = 'failed', last_error = $2, updated_at = NOW()
+       WHERE id = $1 AND status = 'pending'`,
+      [tabId, `Card authorization ${status}`],
+    );
+    if (res.rowCount === 0) await holdLost(tabId, status);
+  }
+}
+
+// The hold on an open tab went away at the gateway: with nothing owed the
+// tab is simply released; otherwise the orders must be paid at the till.
+async function holdLost(tabId: string, status: string): Promise<void> {
+  const balance = await tabBalance(pool, tabId);
+  if (balance === 0) {
+    await pool.query(
+      `UPDATE tabs SET status = 'released', last_error = $2, closed_at = NOW(), updated_at = NOW()
+       WHERE id = $1 AND status = 'open'`,
+      [tabId, `Card authorization ${status}`],
+    );
+    return;
+  }
+
+  const res = await pool.query(
+    `UPDATE tabs SET last_error = $2, updated_at = NOW()
+     WHERE id = $1 AND status = 'open' AND last_error IS NULL
+     RETURNING customer_name`,
+    [tabId, `Card authorization ${status}; settle the tab's orders at the till`],
+  );
+  if (res.rows.length > 0) {
+    await createNotificationForRole('manager', 'system_alert', 'Tab Authorization Lost',
+      `The card hold on ${res.rows[0].customer_name}'s tab is gone (${status}); ${balance} must be paid at the till`, 'tab_authorization_lost');
+  }
+}
+
+// ── CloseTab ────────────────────────────────────────────────────────────────
+// Captures the tab's balance. The tab is moved to 'closing' first so no order
+// can be added while the gateway is called; a failed call reopens it.
+
+export async function closeTab(
+  tabId: string,
+  branchId: string | null,
+  userId: string | null,
+): Promise<{ ok: true } | { ok: false; failure: TabFailure }> {
+  type Hold = { gateway_reference: string; gateway_transaction_id: string | null };
+  const closing = await withTransaction(async (client): Promise<
+    { ok: true; tab: Hold; balance: number } | { ok: false; failure: TabFailure }
+  > => {
+    const res = await client.query(
+      'SELECT status, branch_id, gateway_reference, gateway_transaction_id FROM tabs WHERE id = $1 FOR UPDATE',
+      [tabId],
+    );
+    const tab = res.rows[0];
+    if (!tab || (branchId && tab.branch_id !== branchId)) {
+      return { ok: false, failure: { message: 'Tab not found', code: 'tab_not_found', status: 404 } };
+    }
+    if (tab.status !== 'open') {
+      return { ok: false, failure: { message: `Only open tabs can be closed - tab is ${tab.status}`, code: 'tab_not_open', status: 409 } };
+    }
+
+    const unfinished = await client.query(
+      'SELECT order_number FROM orders WHERE tab_id = $1 AND status = ANY($2::text[]) ORDER BY created_at',
+      [tabId, UNFINISHED_ORDER_STATUSES],
+    );
+    if (unfinished.rows.length > 0) {
+      const numbers = unfinished.rows.map((r) => r.order_number).join(', ');
+      return { ok: false, failure: { message: `Orders still in progress: ${numbers}`, code: 'tab_has_open_orders', status: 409 } };
+    }
+
+    const balance = await tabBalance(client, tabId);
+    await client.query(
+      `UPDATE tabs SET status = 'closing', closed_by = $2, last_error = NULL, updated_at = NOW() WHERE id = $1`,
+      [tabId, userId],
+    );
+    return { ok: true, tab, balance };
+  });
+  if (!closing.ok) return closing;
+  const { tab, balance } = closing;
+
+  let result: GatewayAuthorizationResult;
+  try {
+    result = balance > 0
+      ? await captureAuthorization(tab.gateway_transaction_id ?? tab.gateway_reference, balance)
+      : await cancelAuthorization(tab.gateway_reference);
+  } catch (err) {
+    await reopenTab(tabId, (err as Error).message);
+    return { ok: false, failure: { message: (err as Error).message, code: 'gateway_unavailable', status: 502 } };
+  }
+  if (result.outcome === 'rejected') {
+    await reopenTab(tabId, `${result.status_code}: ${result.message}`);
+    const action = balance > 0 ? 'capture' : 'release';
+    return { ok: false, failure: { message: `Payment gateway refused the ${action}: ${result.message}`, code: 'gateway_rejected', status: 502 } };
+  }
+
+  if (balance > 0) {
+    await finishTabCapture(tabId, balance);
+  } else {
+    await pool.query(
+      `UPDATE tabs SET status = 'released', closed_at = NOW(), updated_at = NOW() WHERE id = $1 AND status = 'closing'`,
+      [tabId],
+    );
+  }
+  return { ok: true };
+}
+
+async function reopenTab(tabId: string, error: string): Promise<void> {
+  await pool.query(
+    `UPDATE tabs SET status = 'open', closed_by = NULL, last_error = $2, updated_at = NOW() WHERE id = $1 AND status = 'closing'`,
+    [tabId, error],
+  );
+}
+
+// ── FinishTabCapture ────────────────────────────────────────────────────────
+// Records the captured amount as card payments on the tab's orders, oldest
+// first, and completes the served ones. Only a closing tab is finished, so a
+// capture reported by both the API response and a notification counts once.
+// Anything captured beyond what the orders still owe (they were partly paid
+// at the till meanwhile) is flagged for a refund.
+
+async function finishTabCapture(tabId: string, captured: number): Promise<void> {
+  const finished = await withTransaction(async (client) => {
+    const paymentIds: string[] = [];
+    const tabRes = await client.query(
+      `UPDATE tabs SET status = 'closed', captured_amount = $2, closed_at = NOW(), updated_at = NOW()
+       WHERE id = $1 AND status = 'closing'
+       RETURNING customer_name, closed_by, gateway_transaction_id, gateway_reference`,
+      [tabId, captured],
+    );
+    if (tabRes.rows.length === 0) return null;
+    const tab = tabRes.rows[0];
+
+    let remaining = captured;
+    const orders = await client.query(TAB_ORDER_BALANCES, [tabId]);
+    for (const order of orders.rows) {
+      const amount = Math.min(Number(order.balance), remaining);
+      if (amount <= 0) continue;
+      remaining = Math.round((remaining - amount) * 100) / 100;
+
+      const paymentRes = await client.query(
+        `INSERT INTO payments (order_id, payment_method, amount, reference_number, status, processed_by, processed_at)
+         VALUES ($1, 'credit_card', $2, $3, 'completed', $4, NOW())
+         RETURNING id`,
+        [order.id, amount, tab.gateway_transaction_id ?? tab.gateway_reference, tab.closed_by],
+      );
+      paymentIds.push(paymentRes.rows[0].id);
+
+      if (order.status === 'served' && amount >= Number(order.balance)) {
+        await client.query(
+          `UPDATE orders SET status = 'completed', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+          [order.id],
+        );
+        await client.query(
+          `INSERT INTO order_status_history (order_id, previous_status, new_status, changed_by, notes)
+           VALUES ($1, 'served', 'completed', $2, 'Completed when the tab was closed')`,
+          [order.id, tab.closed_by],
+        );
+        if (order.table_id) {
+          await client.query(
+            `UPDATE dining_tables SET is_occupied = false
+             WHERE id = $1 AND NOT EXISTS (
+               SELECT 1 FROM orders WHERE table_id = $1 AND id <> $2 AND status NOT IN ('completed', 'cancelled')
+             )`,
+            [order.table_id, order.id],
+          );
+        }
+        await emitWebhookEvent(client, 'order.completed', () => orderEventData(client, order.id));
+      }
+      await emitWebhookEvent(client, 'payment.processed', () => paymentEventData(client, paymentRes.rows[0].id, 'tab'));
+    }
+    return { paymentIds, customerName: tab.customer_name as string, overpaid: remaining };
+  });
+  if (!finished) return;
+  const { paymentIds, customerName, overpaid } = finished;
+
+  paymentsProcessedTotal.inc({ method: 'credit_card', status: 'completed', source: 'tab' }, paymentIds.length);
+  paymentsAmountTotal.inc({ method: 'credit_card' }, captured - overpaid);
+  if (overpaid > 0) {
+    await createNotificationForRole('manager', 'system_alert', 'Tab Overcharged',
+      `${customerName}'s tab captured ${overpaid} more than its orders owed; refund it to the guest`, 'tab_overcharged');
+  }
+}
+
+// ── ReleaseExpiredTabAuthorizations ─────────────────────────────────────────
+// Periodic job. Card pages never completed fail once the payment link
+// expiry has passed; open tabs past their hold period are released if
+// nothing is owed, and otherwise flagged once to managers so the tab is
+// closed before the card issuer drops the hold.
+
+export async function releaseExpiredTabAuthorizations(q: Pool): Promise<number> {
+  const linkExpiryMinutes = await loadPaymentLinkExpiryMinutes(q);
+  await q.query(
+    `UPDATE tabs SET status = 'failed', last_error = 'Card was not authorized in time', updated_at = NOW()
+     WHERE status = 'pending' AND created_at <= NOW() - make_interval(mins => $1)`,
+    [linkExpiryMinutes],
+  );
+
+  const due = await q.query(
+    `SELECT id, gateway_reference FROM tabs WHERE status = 'open' AND auth_expires_at <= NOW()`,
+  );
+
+  let released = 0;
+  for (const tab of due.rows) {
+    if (await tabBalance(q, tab.id) > 0) {
+      await holdLost(tab.id, 'hold period ended');
+      continue;
+    }
+
+    // Claimed first so a concurrent close or another instance can't race it
+    const claim = await q.query(
+      `UPDATE tabs SET status = 'closing', updated_at = NOW() WHERE id = $1 AND status = 'open' RETURNING id`,
+      [tab.id],
+    );
+    if (claim.rows.length === 0) continue;
+
+    try {
+      const result = await cancelAuthorization(tab.gateway_reference);
+      // A hold the gateway no longer knows about is released either way
+      await q.query(
+        `UPDATE tabs SET status = 'released', last_error = $2, closed_at = NOW(), updated_at = NOW() WHERE id = $1`,
+        [tab.id, result.outcome === 'rejected' ? `${result.status_code}: ${result.message}` : null],
+      );
+      released++;
+    } catch (err) {
+      await reopenTab(tab.id, (err as Error).message);
+      console.error(`Releasing tab ${tab.id} failed:`, (err as Error).message);
+    }
+  }
+  return released;
+}
@@ -0,0 +1,254 @@
+import type { Queryable, PricingLine } from './pricing.js';
+import { loadBranchSetting } from './branches.js';
+
+// Tax engine. An order's tax and service charge are worked out per item, on
+// the item's price after its share of the discounts, so exempt items can be
+// left out and each item's amounts itemized on the receipt. The service
+// charge is taxed along with the item it applies to.
+//
+// Rates are settings (tax_rate, service_charge, service_charge_order_types)
+// and can be overridden per branch. A product in a tax class is taxed at the
+// class's rate instead of tax_rate. Exemptions are rules on a product or a
+// category, optionally limited to a branch or order type; for each item the
+// most specific active rule decides, and a tax exemption beats the class.
+
+export const TAX_ORDER_TYPES = ['dine_in', 'takeout', 'delivery'];
+
+/** SQL for an order item's sales net of its pro rata share of the order's discounts (oi, o). */
+export const ITEM_NET = 'oi.total_price * CASE WHEN o.subtotal > 0 THEN (o.subtotal - o.discount_amount) / o.subtotal ELSE 1 END';
+
+export interface TaxRates {
+  /** Fractions, e.g. 0.11 */
+  tax_rate: number;
+  service_rate: number;
+}
+
+export interface TaxExemption {
+  id: string;
+  product_id: string | null;
+  category_id: string | null;
+  branch_id: string | null;
+  order_type: string | null;
+  exempt_tax: boolean;
+  exempt_service: boolean;
+}
+
+export interface TaxClass {
+  id: string;
+  name: string;
+  /** Fraction, e.g. 0.11 */
+  rate: number;
+}
+
+export interface TaxLine {
+  product_id: string;
+  category_id: string | null;
+  /** Line total after discounts */
+  amount: number;
+}
+
+export interface LineTax {
+  tax_exempt: boolean;
+  service_exempt: boolean;
+  tax_amount: number;
+  service_charge_amount: number;
+  /** What the item was taxed as, for receipts; the rate is a percent */
+  tax_class_id: string | null;
+  tax_label: string;
+  tax_rate: number;
+}
+
+export interface TaxResult {
+  tax_amount: number;
+  service_charge_amount: number;
+  /** Same order as the lines passed in */
+  lines: LineTax[];
+}
+
+function round2(n: number): number {
+  return Math.round(n * 100) / 100;
+}
+
+const DEFAULT_TAX_LABEL = 'Tax';
+const EXEMPT_TAX_LABEL = 'Tax exempt';
+
+function parseRate(value: string | null, fallback: number): number {
+  if (value === null) return fallback;
+  const parsed = parseFloat(value);
+  return isNaN(parsed) ? fallback : parsed / 100;
+}
+
+/** Each priced line's amount after its share of the discounts. */
+export function taxLines(lines: PricingLine[], lineDiscounts: number[]): TaxLine[] {
+  return lines.map((l, i) => ({
+    product_id: l.product_id,
+    category_id: l.category_id,
+    amount: l.unit_price * l.quantity - lineDiscounts[i],
+  }));
+}
+
+// ── LoadTaxRates ────────────────────────────────────────────────────────────
+// Tax defaults to 11% Indonesian VAT. The service charge only applies to the
+// order types listed in service_charge_order_types.
+
+export async function loadTaxRates(q: Queryable, branchId: string | null, orderType: string): Promise<TaxRates> {
+  const taxRate = parseRate(await loadBranchSetting(q, branchId, 'tax_rate'), 0.11);
+  const serviceTypes = ((await loadBranchSetting(q, branchId, 'service_charge_order_types')) ?? '')
+    .split(',')
+    .map((s) => s.trim());
+  const serviceRate = serviceTypes.includes(orderType)
+    ? parseRate(await loadBranchSetting(q, branchId, 'service_charge'), 0)
+    : 0;
+  return { tax_rate: taxRate, service_rate: serviceRate };
+}
+
+// ── LoadExemptions ──────────────────────────────────────────────────────────
+
+export async function loadExemptions(q: Queryable, branchId: string | null, orderType: string): Promise<TaxExemption[]> {
+  const res = await q.query(
+    `SELECT id, product_id, category_id, branch_id, order_type, exempt_tax, exempt_service
+     FROM tax_exemptions
+     WHERE is_active = true
+       AND (branch_id IS NULL OR branch_id = $1)
+       AND (order_type IS NULL OR order_type = $2)`,
+    [branchId, orderType],
+  );
+  return res.rows;
+}
+
+// ── LoadProductTaxClasses ───────────────────────────────────────────────────
+// Active classes of the given products, keyed by product ID. Products without
+// one (or whose class is inactive) are left out and take the default rate.
+
+export async function loadProductTaxClasses(q: Queryable, productIds: string[]): Promise<Map<string, TaxClass>> {
+  const classes = new Map<string, TaxClass>();
+  if (productIds.length === 0) return classes;
+
+  const res = await q.query(
+    `SELECT p.id AS product_id, tc.id, tc.name, tc.rate
+     FROM products p
+     JOIN tax_classes tc ON tc.id = p.tax_class_id AND tc.is_active = true
+     WHERE p.id::text = ANY($1::text[])`,
+    [[...new Set(productIds)]],
+  );
+  for (const row of res.rows) {
+    classes.set(row.product_id, { id: row.id, name: row.name, rate: Number(row.rate) / 100 });
+  }
+  return classes;
+}
+
+// Product beats category, then a branch rule beats an all-branch one, then
+// an order-type rule beats an all-types one
+function specificity(rule: TaxExemption): number {
+  return (rule.product_id ? 4 : 0) + (rule.branch_id ? 2 : 0) + (rule.order_type ? 1 : 0);
+}
+
+export function findExemption(line: TaxLine, exemptions: TaxExemption[]): TaxExemption | null {
+  let best: TaxExemption | null = null;
+  for (const rule of exemptions) {
+    const matches = rule.product_id
+      ? rule.product_id === line.product_id
+      : line.category_id !== null && rule.category_id === line.category_id;
+    if (matches && (!best || specificity(rule) > specificity(best))) best = rule;
+  }
+  return best;
+}
+
+// ── ApplyTaxes ──────────────────────────────────────────────────────────────
+
+export function applyTaxes(
+  lines: TaxLine[],
+  rates: TaxRates,
+  exemptions: TaxExemption[],
+  classes: Map<string, TaxClass> = new Map(),
+): TaxResult {
+  const lineTaxes = lines.map((line) => {
+    const rule = findExemption(line, exemptions);
+    const taxExempt = rule?.exempt_tax ?? false;
+    const serviceExempt = rule?.exempt_service ?? false;
+    const taxClass = classes.get(line.product_id) ?? null;
+    const taxRate = taxExempt ? 0 : taxClass?.rate ?? rates.tax_rate;
+    const service = serviceExempt ? 0 : round2(line.amount * rates.service_rate);
+    const tax = round2((line.amount + service) * taxRate);
+    return {
+      tax_exempt: taxExempt,
+      service_exempt: serviceExempt,
+      tax_amount: tax,
+      service_charge_amount: service,
+      tax_class_id: taxExempt ? null : taxClass?.id ?? null,
+      tax_label: taxExempt ? EXEMPT_TAX_LABEL : taxClass?.name ?? DEFAULT_TAX_LABEL,
+      tax_rate: round2(taxRate * 100),
+    };
+  });
+
+  return {
+    tax_amount: round2(lineTaxes.reduce((sum, l) => sum + l.tax_amount, 0)),
+    service_charge_amount: round2(lineTaxes.reduce((sum, l) => sum + l.service_charge_amount, 0)),
+    lines: lineTaxes,
+  };
+}
+
+// ── ComputeOrderTaxes ───────────────────────────────────────────────────────
+
+export async function computeOrderTaxes(
+  q: Queryable,
+  branchId: string | null,
+  orderType: string,
+  lines: TaxLine[],
+): Promise<TaxResult> {
+  const rates = await loadTaxRates(q, branchId, orderType);
+  const exemptions = await loadExemptions(q, branchId, orderType);
+  const classes = await loadProductTaxClasses(q, lines.map((l) => l.product_id));
+  return applyTaxes(lines, rates, exemptions, classes);
+}
+
+// ── SummarizeItemTaxes ──────────────────────────────────────────────────────
+// The tax lines of a receipt: stored item taxes grouped by what they were
+// taxed as. Items priced before tax classes have no label and fall under the
+// default one.
+
+export interface OrderTaxLine {
+  tax_class_id: string | null;
+  label: string;
+  /** Percent; null when older items were taxed at an unrecorded rate */
+  rate: number | null;
+  items: number;
+  tax_amount: number;
+}
+
+export function summarizeItemTaxes(
+  items: { tax_class_id?: unknown; tax_label?: unknown; tax_rate?: unknown; tax_amount?: unknown; tax_exempt?: unknown }[],
+): OrderTaxLine[] {
+  const groups = new Map<string, OrderTaxLine>();
+  for (const item of items) {
+    const label = (item.tax_label as string | null) ?? (item.tax_exempt ? EXEMPT_TAX_LABEL : DEFAULT_TAX_LABEL);
+    const rate = item.tax_rate === null || item.tax_rate === undefined ? null : Number(item.tax_rate);
+    const key = `${item.tax_class_id ?? ''}|${label}|${rate ?? ''}`;
+    const group = groups.get(key)
+      ?? { tax_class_id: (item.tax_class_id as string | null) ?? null, label, rate, items: 0, tax_amount: 0 };
+    group.items += 1;
+    group.tax_amount = round2(group.tax_amount + Number(item.tax_amount ?? 0));
+    groups.set(key, group);
+  }
+  // Highest rate first
+  return [...groups.values()].sort((a, b) => (b.rate ?? -1) - (a.rate ?? -1));
+}
+
+// Surcharges are taxed at the default rate, so their tax joins the default
+// line at that rate on the receipt. They aren't items and don't count as any.
+export function addSurchargeTaxes(
+  lines: OrderTaxLine[],
+  surcharges: { tax_amount: number; tax_rate: number }[],
+): OrderTaxLine[] {
+  const result = lines.map((line) => ({ ...line }));
+  for (const surcharge of surcharges) {
+    if (surcharge.tax_amount <= 0) continue;
+    let line = result.find((l) => l.tax_class_id === null && l.label === DEFAULT_TAX_LABEL && l.rate === surcharge.tax_rate);
+    if (!line) {
+      line = { tax_class_id: null, label: DEFAULT_TAX_LABEL, rate: surcharge.tax_rate, items: 0, tax_amount: 0 };
+      result.push(line);
+    }
+    line.tax_amount = round2(line.tax_amount + surcharge.tax_amount);
+  }
+  return result.sort((a, b) => (b.rate ?? -1) - (a.rate ?? -1));
+}
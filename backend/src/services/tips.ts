@@ -0,0 +1,70 @@
+import { loadBranchSetting } from './branches.js';
+import type { Queryable } from './pricing.js';
+
+// Tips. A payment's tip_amount is paid on top of what it settles on the
+// order, like its surcharge, so order balances and sales never include it.
+// The tip is credited to the order's server (orders.server_id, see
+// services/sections.ts), or to the cashier for an order without one, when
+// the payment is taken (payments.tip_recipient_id); the tip-out report adds
+// up tips per recipient and shift by that column.
+//
+// Card terminals often capture the tip after the charge, so a tip can be
+// entered later with PUT /orders/:id/payments/:payment_id/tip for up to
+// TIP_ADJUST_HOURS. Refunds cover the payment's amount only; a tip given
+// back is set to 0 the same way. Corporate wallet and on-account payments
+// bill the company for the tip with the payment, so theirs is fixed once
+// taken, and a refund credits the company its share of the surcharge and
+// tip along with the amount (companyRefundCredit).
+
+export const TIP_ADJUST_HOURS = 24;
+export const COMPANY_BILLED_METHODS = ['corporate_wallet', 'on_account'];
+// More than the payment itself is almost certainly a typo
+export const MAX_TIP_RATIO = 1;
+const MAX_SUGGESTIONS = 5;
+
+export function isTipAmount(value: unknown): value is number {
+  return typeof value === 'number' && Number.isFinite(value) && value >= 0;
+}
+
+/** Checks a tip given on a payment of `amount`. */
+export function validateTip(tip: unknown, amount: number): { message: string; code: string } | null {
+  if (!isTipAmount(tip)) {
+    return { message: 'tip_amount must be an amount of zero or more', code: 'invalid_tip_amount' };
+  }
+  if (tip > amount * MAX_TIP_RATIO) {
+    return { message: 'The tip is more than the payment itself', code: 'tip_too_large' };
+  }
+  return null;
+}
+
+/**
+ * What a refund of `amount` credits back to the company on a company-billed
+ * payment: the amount plus the same share of the surcharge and tip. Worked
+ * out on the running refunded total, so partial refunds add up to exactly
+ * what was billed.
+ */
+export function companyRefundCredit(
+  payment: { amount: number; surcharge_amount: number; tip_amount: number },
+  refundedBefore: number,
+  amount: number,
+): number {
+  const billed = payment.amount + payment.surcharge_amount + payment.tip_amount;
+  const creditedUpTo = (refunded: number) => Math.round(((billed * refunded) / payment.amount) * 100) / 100;
+  return Math.round((creditedUpTo(refundedBefore + amount) - creditedUpTo(refundedBefore)) * 100) / 100;
+}
+
+/** tip_suggestions (e.g. "5,10,15") as whole percentages from 1 to 100, ascending. */
+export async function loadTipPercents(q: Queryable, branchId: string | null): Promise<number[]> {
+  const setting = (await loadBranchSetting(q, branchId, 'tip_suggestions')) ?? '';
+  const percents = setting
+    .split(',')
+    .map((s) => Number(s.trim()))
+    .filter((n) => Number.isInteger(n) && n >= 1 && n <= 100);
+  return [...new Set(percents)].sort((a, b) => a - b).slice(0, MAX_SUGGESTIONS);
+}
+
+/** The suggested tips on an amount, rounded to whole Rupiah. */
+export function tipSuggestions(amount: number, percents: number[]): { percent: number; amount: number }[] {
+  if (amount <= 0) return [];
+  return percents.map((percent) => ({ percent, amount: Math.round((amount * percent) / 100) }));
+}
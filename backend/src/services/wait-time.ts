@@ -0,0 +1,211 @@
+import type { Queryable } from './pricing.js';
+import { loadBranchSetting } from './branches.js';
+
+// Wait-time estimates. The kitchen is modelled as a fixed number of stations
+// (kitchen_parallel_orders) working through open orders oldest-due first.
+// An order's cooking time is its slowest outstanding item — items on one
+// ticket are cooked in parallel — scaled by how long orders have actually
+// taken from 'preparing' to 'ready' compared with their menu preparation
+// times over the last two weeks. Each branch has its own kitchen and is
+// simulated separately.
+
+export const DEFAULT_PREP_MINUTES = 15;
+const DEFAULT_PARALLEL_ORDERS = 4;
+const HISTORY_DAYS = 14;
+const MIN_HISTORY_SAMPLES = 10;
+// Keeps one slow evening (or a forgotten 'ready' tap) from skewing every estimate
+const MIN_FACTOR = 0.5;
+const MAX_FACTOR = 2.5;
+
+const ACTIVE_STATUSES = ['pending', 'confirmed', 'preparing'];
+
+export interface KitchenCalibration {
+  factor: number;
+  samples: number;
+  avg_cook_minutes: number | null;
+}
+
+export interface WaitEstimate {
+  estimated_wait_minutes: number;
+  estimated_ready_at: string;
+  orders_ahead: number;
+}
+
+export interface KitchenLoad {
+  parallel_orders: number;
+  active_orders: number;
+  preparing_orders: number;
+  queued_orders: number;
+  outstanding_items: number;
+  calibration: KitchenCalibration;
+  /** Minutes until every station is free again */
+  queue_clear_minutes: number;
+  /** Wait for a new order with a typical preparation time */
+  new_order_wait_minutes: number;
+  load_level: 'quiet' | 'normal' | 'busy' | 'slammed';
+}
+
+interface ActiveOrder {
+  id: string;
+  status: string;
+  plannedMinutes: number;
+  startedMinutesAgo: number | null;
+  outstandingItems: number;
+}
+
+interface Simulation {
+  parallel: number;
+  calibration: KitchenCalibration;
+  active: ActiveOrder[];
+  finishes: Map<string, { minutes: number; ahead: number }>;
+  slots: number[];
+}
+
+async function loadParallelOrders(q: Queryable, branchId: string): Promise<number> {
+  const value = parseInt((await loadBranchSetting(q, branchId, 'kitchen_parallel_orders')) ?? '', 10);
+  return isNaN(value) || value < 1 ? DEFAULT_PARALLEL_ORDERS : value;
+}
+
+// ── LoadKitchenCalibration ──────────────────────────────────────────────────
+
+export async function loadKitchenCalibration(q: Queryable): Promise<KitchenCalibration> {
+  const res = await q.query(
+    `WITH cooked AS (
+       SELECT EXTRACT(EPOCH FROM (rd.created_at - pr.created_at)) / 60 AS actual,
+              (SELECT MAX(COALESCE(NULLIF(p.preparation_time, 0), $2))
+               FROM order_items oi JOIN products p ON p.id = oi.product_id
+               WHERE oi.order_id = pr.order_id) AS planned
+       FROM order_status_history pr
+       JOIN LATERAL (
+         SELECT h.created_at FROM order_status_history h
+         WHERE h.order_id = pr.order_id AND h.new_status = 'ready' AND h.created_at > pr.created_at
+         ORDER BY h.created_at ASC LIMIT 1
+       ) rd ON true
+       WHERE pr.new_status = 'preparing' AND pr.created_at >= NOW() - make_interval(days => $1)
+     )
+     SELECT COUNT(*) AS samples, AVG(actual) AS avg_actual, AVG(planned) AS avg_planned
+     FROM cooked
+     WHERE actual > 0 AND actual < 240 AND planned IS NOT NULL`,
+    [HISTORY_DAYS, DEFAULT_PREP_MINUTES],
+  );
+
+  const row = res.rows[0];
+  const samples = Number(row?.samples ?? 0);
+  const avgActual = row?.avg_actual !== null && row?.avg_actual !== undefined ? Number(row.avg_actual) : null;
+  const avgPlanned = Number(row?.avg_planned ?? 0);
+
+  let factor = 1;
+  if (samples >= MIN_HISTORY_SAMPLES && avgActual !== null && avgPlanned > 0) {
+    factor = Math.min(MAX_FACTOR, Math.max(MIN_FACTOR, avgActual / avgPlanned));
+  }
+  return {
+    factor: Math.round(factor * 100) / 100,
+    samples,
+    avg_cook_minutes: avgActual !== null ? Math.round(avgActual * 10) / 10 : null,
+  };
+}
+
+// ── Simulate ────────────────────────────────────────────────────────────────
+// Orders already being prepared hold a station for their remaining time;
+// queued orders then take the first station to come free, in due order.
+
+async function simulate(q: Queryable, branchId: string): Promise<Simulation> {
+  const [parallel, calibration] = await Promise.all([loadParallelOrders(q, branchId), loadKitchenCalibration(q)]);
+
+  const res = await q.query(
+    `SELECT o.id, o.status,
+            COALESCE(MAX(COALESCE(NULLIF(p.preparation_time, 0), $2))
+              FILTER (WHERE oi.status NOT IN ('ready', 'served')), 0) AS planned,
+            COUNT(oi.id) FILTER (WHERE oi.status NOT IN ('ready', 'served')) AS outstanding_items,
+            (SELECT EXTRACT(EPOCH FROM (NOW() - MAX(h.created_at))) / 60
+             FROM order_status_history h
+             WHERE h.order_id = o.id AND h.new_status = 'preparing') AS started_minutes_ago
+     FROM orders o
+     LEFT JOIN order_items oi ON oi.order_id = o.id
+     LEFT JOIN products p ON p.id = oi.product_id
+     WHERE o.status = ANY($1::text[]) AND o.branch_id = $3
+     GROUP BY o.id
+     ORDER BY COALESCE(o.scheduled_at, o.created_at) ASC`,
+    [ACTIVE_STATUSES, DEFAULT_PREP_MINUTES, branchId],
+  );
+
+  const active: ActiveOrder[] = res.rows.map((r) => ({
+    id: r.id,
+    status: r.status,
+    plannedMinutes: Number(r.planned) * calibration.factor,
+    startedMinutesAgo: r.started_minutes_ago !== null ? Number(r.started_minutes_ago) : null,
+    outstandingItems: Number(r.outstanding_items),
+  }));
+
+  const slots: number[] = new Array(parallel).fill(0);
+  const finishes = new Map<string, { minutes: number; ahead: number }>();
+  const take = (minutes: number): number => {
+    const idx = slots.indexOf(Math.min(...slots));
+    slots[idx] += minutes;
+    return slots[idx];
+  };
+
+  let ahead = 0;
+  for (const order of active.filter((o) => o.status === 'preparing')) {
+    const elapsed = order.startedMinutesAgo ?? 0;
+    // Past its estimate but not ready yet: assume it's nearly done
+    const remaining = order.outstandingItems > 0 ? Math.max(1, order.plannedMinutes - elapsed) : 0;
+    finishes.set(order.id, { minutes: take(remaining), ahead });
+    ahead++;
+  }
+  for (const order of active.filter((o) => o.status !== 'preparing')) {
+    finishes.set(order.id, { minutes: take(order.plannedMinutes), ahead });
+    ahead++;
+  }
+
+  return { parallel, calibration, active, finishes, slots };
+}
+
+function toEstimate(minutes: number, ahead: number): WaitEstimate {
+  const wait = Math.max(1, Math.ceil(minutes));
+  return {
+    estimated_wait_minutes: wait,
+    estimated_ready_at: new Date(Date.now() + wait * 60_000).toISOString(),
+    orders_ahead: ahead,
+  };
+}
+
+// ── EstimateOrderWait ───────────────────────────────────────────────────────
+// Null for orders the kitchen isn't working on: scheduled (their time is
+// the pickup time), already ready, or closed.
+
+export async function estimateOrderWait(q: Queryable, orderId: string): Promise<WaitEstimate | null> {
+  const branchRes = await q.query('SELECT branch_id FROM orders WHERE id = $1', [orderId]);
+  if (branchRes.rows.length === 0) return null;
+
+  const sim = await simulate(q, branchRes.rows[0].branch_id);
+  const finish = sim.finishes.get(orderId);
+  return finish ? toEstimate(finish.minutes, finish.ahead) : null;
+}
+
+// ── ComputeKitchenLoad ──────────────────────────────────────────────────────
+
+export async function computeKitchenLoad(q: Queryable, branchId: string): Promise<KitchenLoad> {
+  const sim = await simulate(q, branchId);
+
+  const preparing = sim.active.filter((o) => o.status === 'preparing').length;
+  const queued = sim.active.length - preparing;
+  const nextFree = Math.min(...sim.slots);
+  const newOrderWait = Math.max(1, Math.ceil(nextFree + DEFAULT_PREP_MINUTES * sim.calibration.factor));
+
+  // Measured in rounds of the kitchen: how many orders each station has lined up
+  const perStation = sim.active.length / sim.parallel;
+  const loadLevel = perStation < 0.5 ? 'quiet' : perStation <= 1 ? 'normal' : perStation <= 2 ? 'busy' : 'slammed';
+
+  return {
+    parallel_orders: sim.parallel,
+    active_orders: sim.active.length,
+    preparing_orders: preparing,
+    queued_orders: queued,
+    outstanding_items: sim.active.reduce((sum, o) => sum + o.outstandingItems, 0),
+    calibration: sim.calibration,
+    queue_clear_minutes: Math.ceil(Math.max(...sim.slots)),
+    new_order_wait_minutes: newOrderWait,
+    load_level: loadLevel,
+  };
+}
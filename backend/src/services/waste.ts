@@ -0,0 +1,255 @@
+import type { PoolClient } from 'pg';
+import { RESTAURANT_TIMEZONE } from '../lib/clock.js';
+import { branchCondition } from './branches.js';
+import { RECIPE_COSTS, unitCost } from './costing.js';
+import { consumeIngredientBatches } from './ingredient-batches.js';
+import type { Queryable } from './pricing.js';
+import { resolveItemQuantity } from './weighed-items.js';
+
+// Waste logging. Kitchen staff log a product or ingredient that was thrown
+// away, with a reason and optionally a photo. Logging takes the quantity out
+// of stock the way a sale would: a product's branch inventory and its recipe
+// ingredients, or the ingredient itself (earliest-expiring batch first).
+// Stock stops at zero rather than going negative. The unit cost at the time
+// is kept on the log so the waste cost report doesn't move with later price
+// changes.
+
+export type WasteReason = 'spoilage' | 'prep_waste' | 'expired' | 'damaged' | 'other';
+
+export const WASTE_REASONS: WasteReason[] = ['spoilage', 'prep_waste', 'expired', 'damaged', 'other'];
+
+export function isWasteReason(value: unknown): value is WasteReason {
+  return typeof value === 'string' && (WASTE_REASONS as string[]).includes(value);
+}
+
+export interface WasteInput {
+  branchId: string;
+  productId: string | null;
+  ingredientId: string | null;
+  quantity: number;
+  reasonType: WasteReason;
+  reason: string | null;
+  photoUrl: string | null;
+  userId: string | null;
+}
+
+export interface WasteStockMovement {
+  item_type: 'product' | 'ingredient';
+  item_id: string;
+  name: string;
+  quantity: number;
+  previous_stock: number;
+  new_stock: number;
+}
+
+export interface WasteFailure {
+  message: string;
+  code: string;
+  status: 400 | 404;
+}
+
+export const WASTE_LOG_SELECT = `
+  SELECT w.id, w.branch_id, b.name AS branch_name,
+         CASE WHEN w.ingredient_id IS NOT NULL THEN 'ingredient' ELSE 'product' END AS item_type,
+         w.product_id, w.ingredient_id, COALESCE(p.name, i.name) AS item_name,
+         COALESCE(i.unit, p.sale_unit) AS unit,
+         w.quantity::float8 AS quantity, w.reason_type, w.reason, w.photo_url,
+         w.unit_cost::float8 AS unit_cost, w.total_cost::float8 AS total_cost,
+         w.logged_by, u.username AS logged_by_username, w.created_at
+  FROM waste_logs w
+  JOIN branches b ON b.id = w.branch_id
+  LEFT JOIN products p ON p.id = w.product_id
+  LEFT JOIN ingredients i ON i.id = w.ingredient_id
+  LEFT JOIN users u ON u.id = w.logged_by`;
+
+const round = (n: number) => Math.round(n * 100) / 100;
+
+// ── RecordWaste ─────────────────────────────────────────────────────────────
+// Runs in the caller's transaction. Stock rows are locked in the same order
+// as order deductions (inventory, then ingredients by id).
+
+export async function recordWaste(
+  client: PoolClient,
+  input: WasteInput,
+): Promise<{ ok: true; wasteLogId: string; movements: WasteStockMovement[] } | { ok: false; failure: WasteFailure }> {
+  let quantity = input.quantity;
+  let cost: number | null;
+
+  if (input.productId) {
+    const productRes = await client.query(
+      `SELECT p.id, p.name, p.sale_unit, p.cost_override, r.recipe_cost
+       FROM products p
+       LEFT JOIN (${RECIPE_COSTS}) r ON r.product_id = p.id
+       WHERE p.id = $1 AND p.deleted_at IS NULL`,
+      [input.productId],
+    );
+    const product = productRes.rows[0];
+    if (!product) {
+      return { ok: false, failure: { message: 'Product not found', code: 'product_not_found', status: 404 } };
+    }
+    const resolved = resolveItemQuantity(product, { quantity });
+    if (!resolved.ok) {
+      return { ok: false, failure: { message: resolved.message, code: resolved.code, status: 400 } };
+    }
+    quantity = resolved.value.quantity;
+    cost = unitCost(product).unit_cost;
+  } else {
+    const ingredientRes = await client.query('SELECT unit_cost FROM ingredients WHERE id = $1', [input.ingredientId]);
+    if (ingredientRes.rows.length === 0) {
+      return { ok: false, failure: { message: 'Ingredient not found', code: 'ingredient_not_found', status: 404 } };
+    }
+    quantity = round(quantity);
+    if (quantity <= 0) {
+      return { ok: false, failure: { message: 'Quantity must be at least 0.01', code: 'invalid_quantity', status: 400 } };
+    }
+    cost = ingredientRes.rows[0].unit_cost !== null ? Number(ingredientRes.rows[0].unit_cost) : null;
+  }
+
+  const logRes = await client.query(
+    `INSERT INTO waste_logs (branch_id, product_id, ingredient_id, quantity, reason_type, reason, photo_url,
+                             unit_cost, total_cost, logged_by)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+     RETURNING id`,
+    [
+      input.branchId, input.productId, input.ingredientId, quantity, input.reasonType, input.reason, input.photoUrl,
+      cost, cost !== null ? round(cost * quantity) : null, input.userId,
+    ],
+  );
+  const wasteLogId: string = logRes.rows[0].id;
+  const note = `Waste: ${input.reasonType}${input.reason ? ` - ${input.reason}` : ''}`;
+  const movements: WasteStockMovement[] = [];
+
+  // Ingredient quantities to take out, keyed by ingredient
+  const ingredients = new Map<string, number>();
+
+  if (input.productId) {
+    const invRes = await client.query(
+      `SELECT inv.current_stock, p.name
+       FROM inventory inv
+       JOIN products p ON p.id = inv.product_id AND p.sale_unit = 'each'
+       WHERE inv.product_id = $1 AND inv.branch_id = $2
+       FOR UPDATE OF inv`,
+      [input.productId, input.branchId],
+    );
+    if (invRes.rows.length > 0) {
+      const previous = Number(invRes.rows[0].current_stock);
+      const newStock = Math.max(0, previous - quantity);
+      if (newStock !== previous) {
+        await client.query(
+          'UPDATE inventory SET current_stock = $1, updated_at = NOW() WHERE product_id = $2 AND branch_id = $3',
+          [newStock, input.productId, input.branchId],
+        );
+        await client.query(
+          `INSERT INTO inventory_history (product_id, branch_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by, waste_log_id)
+           VALUES ($1, $2, 'remove', $3, $4, $5, 'waste', $6, $7, $8)`,
+          [input.productId, input.branchId, previous - newStock, previous, newStock, note, input.userId, wasteLogId],
+        );
+      }
+      movements.push({
+        item_type: 'product', item_id: input.productId, name: invRes.rows[0].name,
+        quantity: previous - newStock, previous_stock: previous, new_stock: newStock,
+      });
+    }
+
+    const recipeRes = await client.query(
+      `SELECT pi.ingredient_id, pi.quantity_required
+       FROM product_ingredients pi
+       JOIN ingredients i ON i.id = pi.ingredient_id
+       WHERE pi.product_id = $1 AND i.is_active = true AND pi.quantity_required > 0`,
+      [input.productId],
+    );
+    for (const r of recipeRes.rows) {
+      ingredients.set(r.ingredient_id, (ingredients.get(r.ingredient_id) ?? 0) + Number(r.quantity_required) * quantity);
+    }
+  } else {
+    ingredients.set(input.ingredientId!, quantity);
+  }
+
+  if (ingredients.size > 0) {
+    const ingRes = await client.query(
+      `SELECT id, name, current_stock FROM ingredients
+       WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE`,
+      [[...ingredients.keys()]],
+    );
+    for (const row of ingRes.rows) {
+      const previous = Number(row.current_stock);
+      const newStock = round(Math.max(0, previous - ingredients.get(row.id)!));
+      const taken = round(previous - newStock);
+      if (taken > 0) {
+        await client.query(
+          'UPDATE ingredients SET current_stock = $1, updated_at = NOW() WHERE id = $2',
+          [newStock, row.id],
+        );
+        await client.query(
+          `INSERT INTO ingredient_history (ingredient_id, operation, quantity, previous_stock, new_stock, reason, notes, adjusted_by, waste_log_id)
+           VALUES ($1, 'waste', $2, $3, $4, $5, $6, $7, $8)`,
+          [row.id, taken, previous, newStock, input.reasonType, note, input.userId, wasteLogId],
+        );
+        await consumeIngredientBatches(client, row.id, taken, null);
+      }
+      movements.push({
+        item_type: 'ingredient', item_id: row.id, name: row.name,
+        quantity: taken, previous_stock: previous, new_stock: newStock,
+      });
+    }
+  }
+
+  return { ok: true, wasteLogId, movements };
+}
+
+// ── BuildWasteReport ────────────────────────────────────────────────────────
+// Waste logged over [from, to]: cost per reason and per item, costliest
+// first. Logs of items without a cost are counted but can't be valued.
+
+export async function buildWasteReport(q: Queryable, from: string, to: string, branchId: string | null) {
+  const params: unknown[] = [from, to, RESTAURANT_TIMEZONE];
+  const where = `WHERE DATE(w.created_at AT TIME ZONE $3) BETWEEN $1 AND $2
+                 ${branchCondition('w.branch_id', branchId, params)}`;
+
+  const reasonsRes = await q.query(
+    `SELECT w.reason_type, COUNT(*)::int AS logs,
+            COALESCE(SUM(w.total_cost), 0)::float8 AS total_cost,
+            COUNT(*) FILTER (WHERE w.total_cost IS NULL)::int AS uncosted_logs
+     FROM waste_logs w
+     ${where}
+     GROUP BY w.reason_type
+     ORDER BY total_cost DESC`,
+    params,
+  );
+
+  const itemsRes = await q.query(
+    `SELECT CASE WHEN w.ingredient_id IS NOT NULL THEN 'ingredient' ELSE 'product' END AS item_type,
+            COALESCE(w.ingredient_id, w.product_id) AS item_id, COALESCE(i.name, p.name, 'Deleted item') AS item_name,
+            COALESCE(i.unit, p.sale_unit) AS unit,
+            COUNT(*)::int AS logs, SUM(w.quantity)::float8 AS quantity,
+            SUM(w.total_cost)::float8 AS total_cost,
+            array_agg(DISTINCT w.reason_type) AS reasons
+     FROM waste_logs w
+     LEFT JOIN products p ON p.id = w.product_id
+     LEFT JOIN ingredients i ON i.id = w.ingredient_id
+     ${where}
+     GROUP BY 1, 2, 3, 4
+     ORDER BY total_cost DESC NULLS LAST, item_name ASC`,
+    params,
+  );
+
+  const byReason = reasonsRes.rows.map((row) => ({ ...row, total_cost: round(row.total_cost) }));
+  const items = itemsRes.rows.map((row) => ({
+    ...row,
+    quantity: Math.round(row.quantity * 1000) / 1000,
+    total_cost: row.total_cost !== null ? round(row.total_cost) : null,
+  }));
+
+  return {
+    from,
+    to,
+    branch_id: branchId,
+    summary: {
+      logs: byReason.reduce((sum, r) => sum + r.logs, 0),
+      total_cost: round(byReason.reduce((sum, r) => sum + r.total_cost, 0)),
+      uncosted_logs: byReason.reduce((sum, r) => sum + r.uncosted_logs, 0),
+    },
+    by_reason: byReason,
+    items,
+  };
+}
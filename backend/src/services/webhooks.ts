@@ -0,0 +1,179 @@
+import crypto from 'node:crypto';
+import { pool } from '../db/connection.js';
+import { enqueueJob, type JobAttempt } from '../lib/jobs.js';
+import type { Queryable } from './pricing.js';
+
+// Outbound webhooks. emitWebhookEvent records one delivery per subscribed
+// endpoint and enqueues it, so with a transaction's client an event is only
+// sent if the change that raised it commits, and a receiver outage is
+// retried with the job queue's backoff. Every attempt is logged on the
+// delivery row.
+//
+// Each request is a JSON POST of { id, type, created_at, data } with
+//   X-Webhook-Event:     the event type
+//   X-Webhook-Id:        the event ID (same for every endpoint; for deduping)
+//   X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+// keyed with the endpoint's secret. Receivers should recompute the HMAC and
+// reject old timestamps. Any 2xx response counts as delivered.
+
+export const DELIVER_WEBHOOK_JOB = 'deliver_webhook';
+
+export const WEBHOOK_EVENTS = ['order.created', 'order.completed', 'payment.processed', 'inventory.low_stock'] as const;
+export type WebhookEvent = (typeof WEBHOOK_EVENTS)[number];
+
+// Sent only by the "send test" action, to the one endpoint
+export const WEBHOOK_TEST_EVENT = 'webhook.test';
+
+export const WEBHOOK_DELIVERY_STATUSES = ['queued', 'succeeded', 'failed'];
+
+const DELIVERY_TIMEOUT_MS = 10_000;
+// Enough of a response to see what went wrong
+const RESPONSE_BODY_LIMIT = 1000;
+// Retries span about an hour; a receiver down longer needs a manual retry
+export const WEBHOOK_MAX_ATTEMPTS = 8;
+
+type EventData = Record<string, unknown>;
+
+export function generateWebhookSecret(): string {
+  return `whsec_${crypto.randomBytes(24).toString('hex')}`;
+}
+
+export function signWebhookPayload(secret: string, timestamp: number, body: string): string {
+  const signature = crypto.createHmac('sha256', secret).update(`${timestamp}.${body}`).digest('hex');
+  return `t=${timestamp},v1=${signature}`;
+}
+
+// ── QueueWebhookDelivery ────────────────────────────────────────────────────
+
+export async function queueWebhookDelivery(
+  q: Queryable,
+  endpointId: string,
+  event: string,
+  eventId: string,
+  data: EventData,
+): Promise<string> {
+  const payload = { id: eventId, type: event, created_at: new Date().toISOString(), data };
+  const res = await q.query(
+    `INSERT INTO webhook_deliveries (endpoint_id, event, event_id, payload)
+     VALUES ($1, $2, $3, $4)
+     RETURNING id`,
+    [endpointId, event, eventId, JSON.stringify(payload)],
+  );
+  const id = res.rows[0].id;
+  await enqueueJob(q, DELIVER_WEBHOOK_JOB, { delivery_id: id }, { maxAttempts: WEBHOOK_MAX_ATTEMPTS });
+  return id;
+}
+
+// ── EmitWebhookEvent ────────────────────────────────────────────────────────
+// `data` may be a loader, which only runs when some endpoint subscribes to
+// the event, so events nobody listens to cost one query.
+
+export async function emitWebhookEvent(
+  q: Queryable,
+  event: WebhookEvent,
+  data: EventData | (() => Promise<EventData>),
+): Promise<void> {
+  const endpoints = await q.query(
+    'SELECT id FROM webhook_endpoints WHERE is_active = true AND $1 = ANY(events)',
+    [event],
+  );
+  if (endpoints.rows.length === 0) return;
+
+  const eventData = typeof data === 'function' ? await data() : data;
+  const eventId = crypto.randomUUID();
+  for (const endpoint of endpoints.rows) {
+    await queueWebhookDelivery(q, endpoint.id, event, eventId, eventData);
+  }
+}
+
+// ── Event data ──────────────────────────────────────────────────────────────
+
+export async function orderEventData(q: Queryable, orderId: string): Promise<EventData> {
+  const res = await q.query(
+    `SELECT id, order_number, branch_id, order_type, status, customer_name,
+            subtotal::float8 AS subtotal, tax_amount::float8 AS tax_amount,
+            service_charge_amount::float8 AS service_charge_amount,
+            discount_amount::float8 AS discount_amount, total_amount::float8 AS total_amount,
+            created_at, completed_at
+     FROM orders WHERE id = $1`,
+    [orderId],
+  );
+  return res.rows[0] ?? { id: orderId };
+}
+
+export async function paymentEventData(q: Queryable, paymentId: string, source: string): Promise<EventData> {
+  const res = await q.query(
+    `SELECT p.id, p.order_id, o.order_number, o.branch_id, p.payment_method, p.amount::float8 AS amount,
+            p.reference_number, p.status, p.processed_at, o.total_amount::float8 AS order_total
+     FROM payments p
+     JOIN orders o ON o.id = p.order_id
+     WHERE p.id = $1`,
+    [paymentId],
+  );
+  return { ...(res.rows[0] ?? { id: paymentId }), source };
+}
+
+// ── DeliverWebhook ──────────────────────────────────────────────────────────
+// Job handler. A disabled or deleted endpoint ends the delivery without
+// sending; the last allowed attempt marks it failed for a manual retry.
+
+export async function deliverWebhook(payload: { delivery_id: string }, attempt: JobAttempt): Promise<void> {
+  const res = await pool.query(
+    `SELECT d.id, d.event, d.event_id, d.payload, e.url, e.secret, e.is_active
+     FROM webhook_deliveries d
+     JOIN webhook_endpoints e ON e.id = d.endpoint_id
+     WHERE d.id = $1 AND d.status = 'queued'`,
+    [payload.delivery_id],
+  );
+  const delivery = res.rows[0];
+  if (!delivery) return;
+
+  if (!delivery.is_active) {
+    await pool.query(
+      `UPDATE webhook_deliveries SET status = 'failed', last_error = 'Endpoint is disabled', updated_at = NOW()
+       WHERE id = $1`,
+      [delivery.id],
+    );
+    return;
+  }
+
+  const body = JSON.stringify(delivery.payload);
+  const timestamp = Math.floor(Date.now() / 1000);
+  const started = Date.now();
+  let status: number | null = null;
+  let responseBody: string | null = null;
+  let error: string | null = null;
+
+  try {
+    const response = await fetch(delivery.url, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'User-Agent': 'pos-webhooks/1.0',
+        'X-Webhook-Event': delivery.event,
+        'X-Webhook-Id': delivery.event_id,
+        'X-Webhook-Signature': signWebhookPayload(delivery.secret, timestamp, body),
+      },
+      body,
+      // A redirect would resend the signed payload somewhere unconfigured
+      redirect: 'manual',
+      signal: AbortSignal.timeout(DELIVERY_TIMEOUT_MS),
+    });
+    status = response.status;
+    responseBody = (await response.text().catch(() => '')).slice(0, RESPONSE_BODY_LIMIT);
+    if (!response.ok) error = `Endpoint responded with HTTP ${status}`;
+  } catch (err) {
+    error = (err as Error).message;
+  }
+
+  const final = attempt.attempt >= attempt.maxAttempts;
+  await pool.query(
+    `UPDATE webhook_deliveries
+     SET attempts = attempts + 1, response_status = $2, response_body = $3, duration_ms = $4,
+         last_error = $5, status = $6, delivered_at = CASE WHEN $5::text IS NULL THEN NOW() END,
+         updated_at = NOW()
+     WHERE id = $1`,
+    [delivery.id, status, responseBody, Date.now() - started, error, error ? (final ? 'failed' : 'queued') : 'succeeded'],
+  );
+  if (error) throw new Error(error);
+}
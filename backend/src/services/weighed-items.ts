@@ -0,0 +1,95 @@
+// Products sold by weight. A product's sale_unit is 'each' (sold by the
+// portion, whole quantities only) or a weight unit its price is quoted per:
+// 'kg', '100g' or 'g'. An order item's quantity is always in the product's
+// sale unit, so unit_price * quantity stays the line total and pricing rules,
+// taxes and recipes (quantity_required per sale unit) work unchanged.
+//
+// Quantities can come from a scale instead: an item given weight_grams (and
+// optionally the scale's device name) has its quantity worked out from the
+// weight, and the reading is kept on the item.
+
+export type SaleUnit = 'each' | 'kg' | '100g' | 'g';
+
+export const SALE_UNITS: SaleUnit[] = ['each', 'kg', '100g', 'g'];
+
+const GRAMS_PER_UNIT: Record<Exclude<SaleUnit, 'each'>, number> = { kg: 1000, '100g': 100, g: 1 };
+
+/** Largest quantity of one line, in any unit */
+export const MAX_QUANTITY = 1_000_000;
+
+export interface ItemQuantityInput {
+  quantity?: number;
+  weight_grams?: number;
+  scale_device?: string;
+}
+
+export interface ResolvedQuantity {
+  quantity: number;
+  weight_grams: number | null;
+  scale_device: string | null;
+}
+
+export function isSaleUnit(value: unknown): value is SaleUnit {
+  return typeof value === 'string' && (SALE_UNITS as string[]).includes(value);
+}
+
+export function isWeighed(saleUnit: string | null | undefined): boolean {
+  return !!saleUnit && saleUnit !== 'each';
+}
+
+export function lineTotal(unitPrice: number, quantity: number): number {
+  return Math.round(unitPrice * quantity * 100) / 100;
+}
+
+/** True when the input names a quantity, before the product is known. */
+export function hasQuantityInput(input: ItemQuantityInput): boolean {
+  return input.quantity !== undefined || input.weight_grams !== undefined;
+}
+
+// ── ResolveItemQuantity ─────────────────────────────────────────────────────
+// The quantity of an order line in the product's sale unit. Products sold
+// each take whole quantities; weighed ones take up to three decimals or a
+// scale weight in grams (never both).
+
+export function resolveItemQuantity(
+  product: { name: string; sale_unit: string },
+  input: ItemQuantityInput,
+): { ok: true; value: ResolvedQuantity } | { ok: false; message: string; code: string } {
+  const weighed = isWeighed(product.sale_unit);
+
+  if (input.weight_grams !== undefined) {
+    if (!weighed) {
+      return { ok: false, message: `'${product.name}' is sold each, not by weight`, code: 'not_sold_by_weight' };
+    }
+    if (input.quantity !== undefined) {
+      return { ok: false, message: 'Give either quantity or weight_grams, not both', code: 'invalid_quantity' };
+    }
+    const grams = input.weight_grams;
+    if (typeof grams !== 'number' || !Number.isFinite(grams) || grams <= 0 || grams > MAX_QUANTITY * 1000) {
+      return { ok: false, message: 'weight_grams must be a positive weight', code: 'invalid_weight' };
+    }
+    const device = input.scale_device?.trim() || null;
+    if (device && device.length > 100) {
+      return { ok: false, message: 'scale_device must be at most 100 characters', code: 'invalid_scale_device' };
+    }
+    const roundedGrams = Math.round(grams * 10) / 10;
+    const perUnit = GRAMS_PER_UNIT[product.sale_unit as Exclude<SaleUnit, 'each'>];
+    const quantity = Math.round((roundedGrams / perUnit) * 1000) / 1000;
+    if (quantity <= 0) {
+      return { ok: false, message: 'weight_grams is too small to sell', code: 'invalid_weight' };
+    }
+    return { ok: true, value: { quantity, weight_grams: roundedGrams, scale_device: device } };
+  }
+
+  const qty = input.quantity;
+  if (typeof qty !== 'number' || !Number.isFinite(qty) || qty <= 0 || qty > MAX_QUANTITY) {
+    return { ok: false, message: 'Quantity must be a positive number', code: 'invalid_quantity' };
+  }
+  if (!weighed && !Number.isInteger(qty)) {
+    return { ok: false, message: `Quantity of '${product.name}' must be a positive whole number`, code: 'invalid_quantity' };
+  }
+  if (weighed && Math.abs(Math.round(qty * 1000) - qty * 1000) > 1e-6) {
+    return { ok: false, message: 'Quantity can have at most three decimals', code: 'invalid_quantity' };
+  }
+  return { ok: true, value: { quantity: qty, weight_grams: null, scale_device: null } };
+}
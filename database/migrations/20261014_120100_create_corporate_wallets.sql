@@ -0,0 +1,77 @@
+-- Migration: Corporate meal wallet accounts
+-- Feature: corporate-meal-accounts
+-- Date: 2026-10-14
+-- Description: Prepaid company balances with employee codes redeemable at the counter
+
+CREATE TABLE IF NOT EXISTS corporate_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_name VARCHAR(150) NOT NULL,
+    contact_name VARCHAR(100),
+    contact_email VARCHAR(255),
+    contact_phone VARCHAR(20),
+    billing_address TEXT,
+    balance DECIMAL(12,2) NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_corporate_balance CHECK (balance >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS corporate_employees (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES corporate_accounts(id) ON DELETE CASCADE,
+    employee_code VARCHAR(30) NOT NULL UNIQUE,
+    employee_name VARCHAR(100) NOT NULL,
+    daily_limit DECIMAL(12,2),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS corporate_wallet_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES corporate_accounts(id) ON DELETE CASCADE,
+    employee_id UUID REFERENCES corporate_employees(id) ON DELETE SET NULL,
+    transaction_type VARCHAR(20) NOT NULL CHECK (transaction_type IN ('topup', 'redeem', 'adjustment')),
+    amount DECIMAL(12,2) NOT NULL,
+    balance_after DECIMAL(12,2),
+    status VARCHAR(20) NOT NULL DEFAULT 'completed' CHECK (status IN ('pending', 'completed', 'failed')),
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    gateway_reference VARCHAR(100) UNIQUE,
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_employees_account ON corporate_employees(account_id);
+CREATE INDEX IF NOT EXISTS idx_corporate_wallet_tx_account_created ON corporate_wallet_transactions(account_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_corporate_wallet_tx_employee ON corporate_wallet_transactions(employee_id, created_at);
+
+DROP TRIGGER IF EXISTS set_corporate_accounts_updated_at ON corporate_accounts;
+CREATE TRIGGER set_corporate_accounts_updated_at
+    BEFORE UPDATE ON corporate_accounts
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS set_corporate_employees_updated_at ON corporate_employees;
+CREATE TRIGGER set_corporate_employees_updated_at
+    BEFORE UPDATE ON corporate_employees
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Allow wallet redemptions as a payment method
+ALTER TABLE payments
+DROP CONSTRAINT IF EXISTS payments_payment_method_check;
+
+ALTER TABLE payments
+ADD CONSTRAINT payments_payment_method_check
+CHECK (payment_method IN ('cash', 'credit_card', 'debit_card', 'digital_wallet', 'corporate_wallet'));
+
+COMMENT ON TABLE corporate_accounts IS 'Prepaid meal accounts for nearby companies';
+COMMENT ON COLUMN corporate_employees.employee_code IS 'Code presented at the counter to pay from the company balance';
+COMMENT ON COLUMN corporate_wallet_transactions.amount IS 'Signed amount: positive for top-ups, negative for redemptions';
+COMMENT ON COLUMN corporate_wallet_transactions.gateway_reference IS 'Payment gateway order id for online top-ups';
@@ -0,0 +1,72 @@
+-- Migration: On-account billing for corporate clients
+-- Feature: corporate-invoicing
+-- Date: 2026-10-14
+-- Description: Pay-later charges accumulated into monthly invoices with credit limits
+
+ALTER TABLE corporate_accounts
+ADD COLUMN IF NOT EXISTS on_account_enabled BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(12,2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS payment_terms_days INTEGER NOT NULL DEFAULT 30;
+
+CREATE TABLE IF NOT EXISTS corporate_invoices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES corporate_accounts(id) ON DELETE RESTRICT,
+    invoice_number VARCHAR(30) NOT NULL UNIQUE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    total_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    amount_paid DECIMAL(12,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'partially_paid', 'paid', 'void')),
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    due_date DATE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_corporate_invoice_period UNIQUE (account_id, period_start)
+);
+
+CREATE TABLE IF NOT EXISTS corporate_account_charges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES corporate_accounts(id) ON DELETE RESTRICT,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    invoice_id UUID REFERENCES corporate_invoices(id) ON DELETE SET NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    charged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS corporate_invoice_payments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    invoice_id UUID NOT NULL REFERENCES corporate_invoices(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    payment_method VARCHAR(30) NOT NULL,
+    reference_number VARCHAR(100),
+    notes TEXT,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_charges_account ON corporate_account_charges(account_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_corporate_charges_invoice ON corporate_account_charges(invoice_id);
+CREATE INDEX IF NOT EXISTS idx_corporate_invoices_account ON corporate_invoices(account_id, period_start DESC);
+CREATE INDEX IF NOT EXISTS idx_corporate_invoices_status ON corporate_invoices(status);
+CREATE INDEX IF NOT EXISTS idx_corporate_invoice_payments_invoice ON corporate_invoice_payments(invoice_id);
+
+DROP TRIGGER IF EXISTS set_corporate_invoices_updated_at ON corporate_invoices;
+CREATE TRIGGER set_corporate_invoices_updated_at
+    BEFORE UPDATE ON corporate_invoices
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE payments
+DROP CONSTRAINT IF EXISTS payments_payment_method_check;
+
+ALTER TABLE payments
+ADD CONSTRAINT payments_payment_method_check
+CHECK (payment_method IN ('cash', 'credit_card', 'debit_card', 'digital_wallet', 'corporate_wallet', 'on_account'));
+
+COMMENT ON COLUMN corporate_accounts.credit_limit IS 'Maximum unpaid on-account balance (uninvoiced charges plus open invoices)';
+COMMENT ON TABLE corporate_account_charges IS 'Orders settled on account; invoice_id is set once billed on a monthly invoice';
+COMMENT ON TABLE corporate_invoice_payments IS 'Payments received from the company against an invoice';
@@ -0,0 +1,60 @@
+-- Migration: Dynamic pricing rules
+-- Feature: pricing-rules-engine
+-- Date: 2026-10-14
+-- Description: Automatic price adjustments evaluated at order pricing time, with a per-order audit
+
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    rule_type VARCHAR(20) NOT NULL CHECK (rule_type IN ('item_discount', 'bundle')),
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_value DECIMAL(10,2) NOT NULL CHECK (discount_value > 0),
+    priority INTEGER NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT false,
+    -- item_discount targets (either list may be empty = no restriction on that axis)
+    product_ids UUID[] NOT NULL DEFAULT '{}',
+    category_ids UUID[] NOT NULL DEFAULT '{}',
+    -- bundle: every listed product must be present in the order
+    bundle_product_ids UUID[] NOT NULL DEFAULT '{}',
+    min_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_quantity > 0),
+    -- Effective window
+    days_of_week INTEGER[] NOT NULL DEFAULT '{}',
+    start_time TIME,
+    end_time TIME,
+    valid_from TIMESTAMP WITH TIME ZONE,
+    valid_until TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_pricing_rule_percentage CHECK (discount_type <> 'percentage' OR discount_value <= 100),
+    CONSTRAINT chk_pricing_rule_bundle CHECK (rule_type <> 'bundle' OR cardinality(bundle_product_ids) >= 2)
+);
+
+CREATE TABLE IF NOT EXISTS order_pricing_adjustments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES pricing_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(100) NOT NULL,
+    rule_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_active ON pricing_rules(is_active, priority DESC);
+CREATE INDEX IF NOT EXISTS idx_order_pricing_adjustments_order ON order_pricing_adjustments(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_pricing_adjustments_rule ON order_pricing_adjustments(rule_id, created_at);
+
+DROP TRIGGER IF EXISTS set_pricing_rules_updated_at ON pricing_rules;
+CREATE TRIGGER set_pricing_rules_updated_at
+    BEFORE UPDATE ON pricing_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON COLUMN pricing_rules.priority IS 'Higher priority rules are evaluated first';
+COMMENT ON COLUMN pricing_rules.stackable IS 'Stackable rules may discount items already discounted by another rule';
+COMMENT ON COLUMN pricing_rules.start_time IS 'Daily window start (WIB); a window ending before it wraps past midnight';
+COMMENT ON TABLE order_pricing_adjustments IS 'Audit of pricing rules applied to each order at creation time';
@@ -0,0 +1,35 @@
+-- Migration: Soft delete for products, categories, tables and users
+-- Feature: soft-delete
+-- Date: 2026-10-14
+-- Description: deleted_at/deleted_by columns so deletes keep history and can be restored
+
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE dining_tables
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Products soft-deleted through the older is_deleted flag
+UPDATE products
+SET deleted_at = COALESCE(updated_at, CURRENT_TIMESTAMP)
+WHERE is_deleted = true AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_dining_tables_deleted_at ON dining_tables(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+
+COMMENT ON COLUMN products.deleted_at IS 'Soft delete timestamp; is_deleted is kept in sync for older readers';
+COMMENT ON COLUMN categories.deleted_at IS 'Soft delete timestamp; NULL for live categories';
+COMMENT ON COLUMN dining_tables.deleted_at IS 'Soft delete timestamp; deleted tables no longer resolve by QR code';
+COMMENT ON COLUMN users.deleted_at IS 'Soft delete timestamp; deleted users cannot log in';
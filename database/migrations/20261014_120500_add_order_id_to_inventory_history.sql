@@ -0,0 +1,11 @@
+-- Migration: Link product stock movements to orders
+-- Feature: customer-menu-availability
+-- Date: 2026-10-14
+-- Description: Customer orders deduct finished-goods stock; order_id lets cancellations put it back
+
+ALTER TABLE inventory_history
+ADD COLUMN IF NOT EXISTS order_id UUID REFERENCES orders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_inventory_history_order_id ON inventory_history(order_id);
+
+COMMENT ON COLUMN inventory_history.order_id IS 'Order that triggered this movement (sale deductions and their cancellation returns)';
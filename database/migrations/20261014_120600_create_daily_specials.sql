@@ -0,0 +1,54 @@
+-- Migration: Limited-quantity daily specials
+-- Feature: daily-specials
+-- Date: 2026-10-14
+-- Description: Specials capped at a number of portions per day, reset each morning by the scheduler
+
+CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+    job_name VARCHAR(100) NOT NULL,
+    run_date DATE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+    error TEXT,
+
+    PRIMARY KEY (job_name, run_date)
+);
+
+CREATE TABLE IF NOT EXISTS daily_specials (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL UNIQUE REFERENCES products(id) ON DELETE CASCADE,
+    daily_quantity INTEGER NOT NULL CHECK (daily_quantity > 0),
+    remaining_quantity INTEGER NOT NULL CHECK (remaining_quantity >= 0),
+    -- Business day (restaurant timezone) the remaining count belongs to
+    special_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    sold_out_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS daily_special_sales (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    special_id UUID NOT NULL REFERENCES daily_specials(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    special_date DATE NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_specials_active ON daily_specials(is_active);
+CREATE INDEX IF NOT EXISTS idx_daily_special_sales_order ON daily_special_sales(order_id);
+CREATE INDEX IF NOT EXISTS idx_daily_special_sales_special ON daily_special_sales(special_id, special_date);
+
+DROP TRIGGER IF EXISTS set_daily_specials_updated_at ON daily_specials;
+CREATE TRIGGER set_daily_specials_updated_at
+    BEFORE UPDATE ON daily_specials
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE scheduled_job_runs IS 'One row per scheduled job per day; the primary key stops two instances running the same job';
+COMMENT ON TABLE daily_specials IS 'Products sold in a capped number of portions per day';
+COMMENT ON COLUMN daily_specials.remaining_quantity IS 'Portions left for special_date; reset to daily_quantity each morning';
+COMMENT ON TABLE daily_special_sales IS 'Portions claimed by each order, so cancellations can return them the same day';
@@ -0,0 +1,30 @@
+-- Migration: Payment refunds
+-- Feature: payment-refunds
+-- Date: 2026-10-14
+-- Description: Refunds recorded as negative payments linked to the original, with reason and approver
+
+ALTER TABLE payments
+ADD COLUMN IF NOT EXISTS refund_of UUID REFERENCES payments(id) ON DELETE RESTRICT,
+ADD COLUMN IF NOT EXISTS refund_reason VARCHAR(30),
+ADD COLUMN IF NOT EXISTS refund_notes TEXT,
+ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE payments
+DROP CONSTRAINT IF EXISTS chk_payments_refund;
+
+-- Refund rows are negative and always point at the payment they reverse
+ALTER TABLE payments
+ADD CONSTRAINT chk_payments_refund
+CHECK ((refund_of IS NULL AND refund_reason IS NULL) OR (refund_of IS NOT NULL AND refund_reason IS NOT NULL AND amount < 0));
+
+CREATE INDEX IF NOT EXISTS idx_payments_refund_of ON payments(refund_of);
+
+ALTER TABLE corporate_wallet_transactions
+DROP CONSTRAINT IF EXISTS corporate_wallet_transactions_transaction_type_check;
+
+ALTER TABLE corporate_wallet_transactions
+ADD CONSTRAINT corporate_wallet_transactions_transaction_type_check
+CHECK (transaction_type IN ('topup', 'redeem', 'adjustment', 'refund'));
+
+COMMENT ON COLUMN payments.refund_of IS 'Original payment this row refunds; refund rows carry a negative amount';
+COMMENT ON COLUMN payments.approved_by IS 'Manager or admin who approved the refund';
@@ -0,0 +1,26 @@
+-- Migration: Order item change history
+-- Feature: order-editing
+-- Date: 2026-10-14
+-- Description: Item-level audit trail for items added, re-quantified or voided after an order was placed
+
+CREATE TABLE IF NOT EXISTS order_item_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    -- Voided items are deleted from order_items, so the link is kept loose
+    order_item_id UUID,
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    product_name VARCHAR(100) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('add', 'update_quantity', 'void')),
+    previous_quantity INTEGER NOT NULL DEFAULT 0,
+    new_quantity INTEGER NOT NULL DEFAULT 0,
+    unit_price DECIMAL(10,2) NOT NULL,
+    reason TEXT,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_changes_order ON order_item_changes(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_item_changes_item ON order_item_changes(order_item_id);
+
+COMMENT ON TABLE order_item_changes IS 'Items added, re-quantified or voided on an existing order';
+COMMENT ON COLUMN order_item_changes.order_item_id IS 'order_items row the change applied to; no FK because voided rows are deleted';
@@ -0,0 +1,39 @@
+-- Migration: Manager log book
+-- Feature: logbook
+-- Date: 2026-10-14
+-- Description: Shift notes, incidents and maintenance issues per business day, summarised in a daily digest email
+
+CREATE TABLE IF NOT EXISTS logbook_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- Business day (restaurant timezone) the entry belongs to
+    entry_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    shift VARCHAR(20) CHECK (shift IN ('morning', 'afternoon', 'evening', 'night')),
+    category VARCHAR(20) NOT NULL CHECK (category IN ('shift_note', 'incident', 'maintenance')),
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    priority VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high')),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_logbook_entries_date ON logbook_entries(entry_date DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_logbook_entries_category ON logbook_entries(category, status);
+CREATE INDEX IF NOT EXISTS idx_logbook_entries_tags ON logbook_entries USING GIN (tags);
+
+DROP TRIGGER IF EXISTS set_logbook_entries_updated_at ON logbook_entries;
+CREATE TRIGGER set_logbook_entries_updated_at
+    BEFORE UPDATE ON logbook_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('logbook_digest_recipients', '', 'string', 'Comma-separated emails for the daily log book digest; empty sends to all admins', 'system')
+ON CONFLICT (setting_key) DO NOTHING;
+
+COMMENT ON TABLE logbook_entries IS 'Manager log book: shift handover notes, incidents and maintenance issues';
+COMMENT ON COLUMN logbook_entries.entry_date IS 'Business day the entry is filed under; joins to that day''s sales close in the digest';
@@ -0,0 +1,33 @@
+-- Migration: Staff sales targets
+-- Feature: sales-targets
+-- Date: 2026-10-14
+-- Description: Daily and weekly net sales targets per staff member, versioned by effective date
+
+CREATE TABLE IF NOT EXISTS sales_targets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period_type VARCHAR(10) NOT NULL CHECK (period_type IN ('daily', 'weekly')),
+    target_amount DECIMAL(12,2) NOT NULL CHECK (target_amount > 0),
+    effective_from DATE NOT NULL DEFAULT CURRENT_DATE,
+    -- NULL while the target is current; set when it is replaced or removed
+    effective_until DATE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_sales_targets_dates CHECK (effective_until IS NULL OR effective_until >= effective_from)
+);
+
+-- At most one current target per person and period
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_targets_current
+    ON sales_targets(user_id, period_type) WHERE effective_until IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sales_targets_user ON sales_targets(user_id, period_type, effective_from);
+
+DROP TRIGGER IF EXISTS set_sales_targets_updated_at ON sales_targets;
+CREATE TRIGGER set_sales_targets_updated_at
+    BEFORE UPDATE ON sales_targets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE sales_targets IS 'Per-staff sales targets; old rows are kept so past periods are judged against the target in force at the time';
+COMMENT ON COLUMN sales_targets.target_amount IS 'Net sales (order total excluding tax) of completed orders the staff member took';
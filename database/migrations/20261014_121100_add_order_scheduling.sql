@@ -0,0 +1,24 @@
+-- Migration: Scheduled takeaway and delivery orders
+-- Feature: order-scheduling
+-- Date: 2026-10-14
+-- Description: Future pickup/delivery times; scheduled orders wait out of the kitchen feed until their lead time
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ;
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_status_check
+CHECK (status IN ('scheduled', 'pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'));
+
+-- Only scheduled orders are scanned by the promotion job
+CREATE INDEX IF NOT EXISTS idx_orders_scheduled_at ON orders(scheduled_at) WHERE status = 'scheduled';
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('scheduled_order_lead_minutes', '30', 'number', 'Minutes before pickup/delivery that a scheduled order is released to the kitchen', 'kitchen'),
+('scheduled_order_max_days', '7', 'number', 'How many days ahead customers and counter staff can schedule an order', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
+
+COMMENT ON COLUMN orders.scheduled_at IS 'Requested pickup/delivery time; NULL for orders wanted as soon as possible';
@@ -0,0 +1,43 @@
+-- Migration: Staff commission rules
+-- Feature: commissions
+-- Date: 2026-10-14
+-- Description: Category percentage and per-item bonus commission rules, versioned by effective date
+
+CREATE TABLE IF NOT EXISTS commission_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    rule_type VARCHAR(30) NOT NULL CHECK (rule_type IN ('category_percentage', 'item_bonus')),
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    percentage DECIMAL(5,2),
+    bonus_amount DECIMAL(10,2),
+    -- NULL applies the rule to every role
+    role VARCHAR(20),
+    effective_from DATE NOT NULL DEFAULT CURRENT_DATE,
+    -- NULL while the rule is current; set when it is replaced or removed
+    effective_until DATE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_commission_rules_shape CHECK (
+        (rule_type = 'category_percentage' AND category_id IS NOT NULL AND product_id IS NULL
+            AND percentage > 0 AND percentage <= 100 AND bonus_amount IS NULL)
+        OR (rule_type = 'item_bonus' AND product_id IS NOT NULL AND category_id IS NULL
+            AND bonus_amount > 0 AND percentage IS NULL)
+    ),
+    CONSTRAINT chk_commission_rules_dates CHECK (effective_until IS NULL OR effective_until >= effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_rules_category ON commission_rules(category_id) WHERE category_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_commission_rules_product ON commission_rules(product_id) WHERE product_id IS NOT NULL;
+
+DROP TRIGGER IF EXISTS set_commission_rules_updated_at ON commission_rules;
+CREATE TRIGGER set_commission_rules_updated_at
+    BEFORE UPDATE ON commission_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE commission_rules IS 'Staff commission rules; ended rules are kept so past months pay out at the rates in force at the time';
+COMMENT ON COLUMN commission_rules.percentage IS 'Percent of net item sales (after order discounts, before tax) in the category';
+COMMENT ON COLUMN commission_rules.bonus_amount IS 'Flat bonus per unit of the product sold';
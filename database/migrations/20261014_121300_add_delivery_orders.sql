@@ -0,0 +1,47 @@
+-- Migration: Delivery orders and couriers
+-- Feature: delivery
+-- Date: 2026-10-14
+-- Description: Delivery address and fee on orders, courier role, courier assignment and hand-off tracking
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check
+CHECK (role IN ('admin', 'manager', 'server', 'counter', 'kitchen', 'courier'));
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS delivery_address TEXT,
+ADD COLUMN IF NOT EXISTS delivery_phone VARCHAR(20),
+ADD COLUMN IF NOT EXISTS delivery_notes TEXT,
+ADD COLUMN IF NOT EXISTS delivery_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20),
+ADD COLUMN IF NOT EXISTS courier_id UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS courier_assigned_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS picked_up_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS chk_orders_delivery;
+
+-- Delivery orders always carry an address and a hand-off status; other orders never do.
+-- NOT VALID: delivery orders taken before this change have no address.
+ALTER TABLE orders
+ADD CONSTRAINT chk_orders_delivery
+CHECK (
+    (order_type = 'delivery' AND delivery_address IS NOT NULL
+        AND delivery_status IN ('unassigned', 'assigned', 'picked_up', 'delivered'))
+    OR (order_type <> 'delivery' AND delivery_status IS NULL AND courier_id IS NULL)
+) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_orders_courier_active
+    ON orders(courier_id) WHERE delivery_status IN ('assigned', 'picked_up');
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('delivery_fee', '15000', 'number', 'Flat delivery fee added to delivery orders', 'financial'),
+('delivery_free_threshold', '0', 'number', 'Order value (after discounts, before tax) from which delivery is free; 0 disables', 'financial'),
+('delivery_min_order', '0', 'number', 'Minimum order value (after discounts, before tax) accepted for delivery', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
+
+COMMENT ON COLUMN orders.delivery_fee IS 'Delivery fee included in total_amount; not taxed';
+COMMENT ON COLUMN orders.delivery_status IS 'Courier hand-off: unassigned, assigned, picked_up, delivered';
@@ -0,0 +1,28 @@
+-- Migration: Reservation reminders and no-show handling
+-- Feature: reservation-reminders
+-- Date: 2026-10-14
+-- Description: Table holds, reminder tracking and customer confirm/cancel tokens on reservations
+
+ALTER TABLE reservations
+ADD COLUMN IF NOT EXISTS table_id UUID REFERENCES dining_tables(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS response_token VARCHAR(64),
+ADD COLUMN IF NOT EXISTS reminder_sent_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS reminder_error TEXT,
+ADD COLUMN IF NOT EXISTS customer_responded_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_response_token
+    ON reservations(response_token) WHERE response_token IS NOT NULL;
+
+-- Reminder and no-show sweeps only look at upcoming, unresolved reservations
+CREATE INDEX IF NOT EXISTS idx_reservations_open
+    ON reservations(reservation_date, reservation_time) WHERE status IN ('pending', 'confirmed');
+
+CREATE INDEX IF NOT EXISTS idx_reservations_phone ON reservations(phone);
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('reservation_reminder_hours', '3', 'number', 'Hours before a reservation that the reminder message is sent; 0 disables reminders', 'restaurant'),
+('reservation_no_show_grace_minutes', '20', 'number', 'Minutes after the reservation time before an unseated booking is marked no-show and its table released', 'restaurant')
+ON CONFLICT (setting_key) DO NOTHING;
+
+COMMENT ON COLUMN reservations.table_id IS 'Table held for the booking while it is pending or confirmed';
+COMMENT ON COLUMN reservations.response_token IS 'Secret used in reminder links to confirm or cancel without logging in';
@@ -0,0 +1,36 @@
+-- Migration: Customer flags
+-- Feature: customer-flags
+-- Date: 2026-10-14
+-- Description: Flag problem customers by phone number (no-shows, chargebacks) and keep an audit of who flagged or cleared them and why
+
+CREATE TABLE IF NOT EXISTS customer_flags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- International form, digits only (62812...), so 0812... and +62 812... match
+    phone VARCHAR(20) NOT NULL,
+    customer_name VARCHAR(100),
+    reason_type VARCHAR(20) NOT NULL CHECK (reason_type IN ('no_show', 'chargeback', 'abuse', 'other')),
+    reason TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    flagged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    cleared_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    cleared_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_flags_phone ON customer_flags(phone) WHERE is_active = true;
+
+CREATE TABLE IF NOT EXISTS customer_flag_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flag_id UUID NOT NULL REFERENCES customer_flags(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('flagged', 'updated', 'cleared')),
+    reason_type VARCHAR(20),
+    reason TEXT,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_flag_events_flag ON customer_flag_events(flag_id, created_at);
+
+COMMENT ON TABLE customer_flags IS 'Customers staff should be warned about when they book or order delivery';
+COMMENT ON TABLE customer_flag_events IS 'Audit trail of flags being raised, edited and cleared';
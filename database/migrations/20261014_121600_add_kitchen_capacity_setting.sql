@@ -0,0 +1,8 @@
+-- Migration: Kitchen capacity setting
+-- Feature: wait-time-estimates
+-- Date: 2026-10-14
+-- Description: How many orders the kitchen works on at once, used to turn queue depth into a wait-time estimate
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('kitchen_parallel_orders', '4', 'number', 'Number of orders the kitchen can prepare at the same time; used for wait-time estimates', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
@@ -0,0 +1,11 @@
+-- Migration: Order auto-completion setting
+-- Feature: order-auto-complete
+-- Date: 2026-10-14
+-- Description: Minutes after serving before a fully paid order is completed automatically, releasing its table
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('order_auto_complete_minutes', '30', 'number', 'Minutes after an order is served and fully paid before it is completed automatically (0 disables)', 'restaurant')
+ON CONFLICT (setting_key) DO NOTHING;
+
+-- The auto-complete job looks for served orders by serving time
+CREATE INDEX IF NOT EXISTS idx_orders_served_at ON orders(served_at) WHERE status = 'served';
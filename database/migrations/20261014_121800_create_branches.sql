@@ -0,0 +1,73 @@
+-- Migration: Branches
+-- Feature: multi-branch
+-- Date: 2026-10-14
+-- Description: Branch dimension for users, tables, orders and product inventory, plus per-branch setting overrides
+
+CREATE TABLE IF NOT EXISTS branches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(20) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    address VARCHAR(500),
+    phone VARCHAR(50),
+    -- Orders, tables and stock from before branches existed belong here, as
+    -- do website orders that don't pick a branch
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_branches_default ON branches(is_default) WHERE is_default = true;
+
+INSERT INTO branches (code, name, address, phone, is_default)
+SELECT 'MAIN', COALESCE(ri.name, 'Main Branch'), ri.address, ri.phone, true
+FROM (SELECT 1) seed
+LEFT JOIN LATERAL (SELECT name, address, phone FROM restaurant_info LIMIT 1) ri ON true
+WHERE NOT EXISTS (SELECT 1 FROM branches);
+
+-- Staff without a branch work at head office and see every branch
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id) ON DELETE SET NULL;
+
+ALTER TABLE dining_tables
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id);
+UPDATE dining_tables SET branch_id = (SELECT id FROM branches WHERE is_default) WHERE branch_id IS NULL;
+ALTER TABLE dining_tables ALTER COLUMN branch_id SET NOT NULL;
+
+-- Table numbers repeat across branches ("Table 1" at each)
+ALTER TABLE dining_tables DROP CONSTRAINT IF EXISTS dining_tables_table_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dining_tables_branch_number ON dining_tables(branch_id, table_number);
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id);
+UPDATE orders SET branch_id = (SELECT id FROM branches WHERE is_default) WHERE branch_id IS NULL;
+ALTER TABLE orders ALTER COLUMN branch_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_branch_created ON orders(branch_id, created_at);
+
+ALTER TABLE inventory
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id);
+UPDATE inventory SET branch_id = (SELECT id FROM branches WHERE is_default) WHERE branch_id IS NULL;
+ALTER TABLE inventory ALTER COLUMN branch_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_inventory_branch_product ON inventory(branch_id, product_id);
+
+ALTER TABLE inventory_history
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id);
+UPDATE inventory_history SET branch_id = (SELECT id FROM branches WHERE is_default) WHERE branch_id IS NULL;
+ALTER TABLE inventory_history ALTER COLUMN branch_id SET NOT NULL;
+
+-- Overrides of system_settings for one branch; keys not listed here fall
+-- back to the restaurant-wide value
+CREATE TABLE IF NOT EXISTS branch_settings (
+    branch_id UUID NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    setting_key VARCHAR(100) NOT NULL REFERENCES system_settings(setting_key) ON DELETE CASCADE,
+    setting_value TEXT NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (branch_id, setting_key)
+);
+
+COMMENT ON TABLE branches IS 'Restaurant locations; orders, tables and stock belong to one branch';
+COMMENT ON COLUMN users.branch_id IS 'Branch the staff member works at; NULL for head office (all branches)';
+COMMENT ON TABLE branch_settings IS 'Per-branch overrides of system_settings';
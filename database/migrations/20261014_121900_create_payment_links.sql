@@ -0,0 +1,32 @@
+-- Migration: Payment links
+-- Feature: payment-links
+-- Date: 2026-10-14
+-- Description: Gateway-hosted payment links the counter can send for phone orders, settled by the gateway webhook
+
+CREATE TABLE IF NOT EXISTS payment_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    -- A pending link past expires_at is reported as expired; the gateway
+    -- refuses payment after that time
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'cancelled')),
+    gateway_reference VARCHAR(100) NOT NULL UNIQUE,
+    payment_url TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_to VARCHAR(20),
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    gateway_transaction_id VARCHAR(100),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_links_order ON payment_links(order_id, created_at);
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('payment_link_expiry_minutes', '60', 'number', 'Minutes a payment link sent to a customer stays payable', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
+
+COMMENT ON TABLE payment_links IS 'Payment gateway links sent to customers paying remotely';
@@ -0,0 +1,77 @@
+-- Migration: Roles and permissions
+-- Feature: role-permissions
+-- Date: 2026-10-14
+-- Description: Roles as data with a role-to-permission mapping, so routes check permissions and custom roles need no code change
+
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(20) PRIMARY KEY,
+    display_name VARCHAR(50) NOT NULL,
+    description TEXT,
+    -- Built-in roles can be re-permissioned but not renamed or deleted
+    is_system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, display_name, description, is_system) VALUES
+('admin', 'Administrator', 'Full access, including roles and permissions', true),
+('manager', 'Manager', 'Runs the restaurant: reports, menu, staff and settings', true),
+('server', 'Server', 'Takes dine-in orders at the table', true),
+('counter', 'Counter', 'Takes orders and payments, dispatches deliveries', true),
+('kitchen', 'Kitchen', 'Prepares orders from the kitchen display', true),
+('courier', 'Courier', 'Delivers orders', true)
+ON CONFLICT (name) DO NOTHING;
+
+-- Permission names are defined in the application (services/permissions.ts)
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role, permission)
+);
+
+-- Grants matching the access each role had before permissions existed
+INSERT INTO role_permissions (role, permission)
+SELECT r.role, p.permission
+FROM (VALUES
+    ('admin', ARRAY[
+        'orders.update_status', 'orders.edit_items', 'orders.edit_sent_items', 'orders.create', 'orders.create_dine_in',
+        'payments.process', 'payments.refund', 'payments.links', 'kitchen.view', 'kitchen.update', 'kitchen.load',
+        'deliveries.manage', 'deliveries.courier', 'customer_flags.check', 'customer_flags.manage',
+        'reports.view', 'settings.manage', 'branches.manage', 'contacts.manage', 'reservations.manage',
+        'inventory.manage', 'menu.manage', 'menu.edit_products', 'tables.manage', 'users.manage', 'roles.manage',
+        'pricing.manage', 'sales_targets.manage', 'commissions.manage', 'logbook.manage', 'logbook.delete_any',
+        'corporate.manage', 'records.view_deleted']),
+    ('manager', ARRAY[
+        'orders.update_status', 'orders.edit_items', 'orders.edit_sent_items', 'orders.create', 'orders.create_dine_in',
+        'payments.process', 'payments.refund', 'payments.links', 'kitchen.view', 'kitchen.update', 'kitchen.load',
+        'deliveries.manage', 'deliveries.courier', 'customer_flags.check', 'customer_flags.manage',
+        'reports.view', 'settings.manage', 'branches.manage', 'contacts.manage', 'reservations.manage',
+        'inventory.manage', 'menu.manage', 'menu.edit_products', 'tables.manage', 'users.manage',
+        'pricing.manage', 'sales_targets.manage', 'commissions.manage', 'logbook.manage',
+        'corporate.manage', 'records.view_deleted']),
+    ('server', ARRAY['orders.update_status', 'orders.edit_items', 'orders.create_dine_in', 'menu.edit_products', 'kitchen.load']),
+    ('counter', ARRAY[
+        'orders.update_status', 'orders.edit_items', 'orders.create', 'payments.process', 'payments.links',
+        'deliveries.manage', 'customer_flags.check', 'kitchen.load']),
+    ('kitchen', ARRAY['orders.update_status', 'kitchen.view', 'kitchen.update', 'kitchen.load']),
+    ('courier', ARRAY['orders.update_status', 'deliveries.courier'])
+) AS r(role, permissions)
+CROSS JOIN LATERAL unnest(r.permissions) AS p(permission)
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- users.role now references a role row instead of a fixed list
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_role;
+ALTER TABLE users
+ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+
+DROP TRIGGER IF EXISTS set_roles_updated_at ON roles;
+CREATE TRIGGER set_roles_updated_at
+    BEFORE UPDATE ON roles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE roles IS 'Staff roles; custom roles are created from the admin panel';
+COMMENT ON TABLE role_permissions IS 'Permissions granted to each role';
@@ -0,0 +1,34 @@
+-- Migration: Background jobs
+-- Feature: job-queue
+-- Date: 2026-10-14
+-- Description: Database-backed job queue worked by the backend, with retry and backoff, for mail and alerts that must not block requests
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    -- pending -> running -> succeeded, or back to pending for a retry until
+    -- max_attempts is used up, then failed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    locked_by VARCHAR(100),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- The worker's claim query: next due pending job
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, created_at DESC);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'jobs.manage'),
+('manager', 'jobs.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+COMMENT ON TABLE jobs IS 'Background job queue; failed jobs are kept for inspection and manual retry';
@@ -0,0 +1,30 @@
+-- Migration: Kitchen stations and held customer order items
+-- Feature: station-routing
+-- Date: 2026-10-14
+-- Description: Categories prepare at the kitchen or the bar; customer orders can wait for staff acceptance while auto-released stations (drinks) start straight away
+
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS station VARCHAR(20) NOT NULL DEFAULT 'kitchen'
+    CHECK (station IN ('kitchen', 'bar'));
+
+-- NULL follows the station default (auto_release_stations); true/false
+-- overrides it for the category
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS auto_release BOOLEAN;
+
+-- The seeded drinks category
+UPDATE categories SET station = 'bar' WHERE name = 'Minuman';
+
+-- NULL while a customer order's item waits for acceptance; the kitchen and
+-- bar screens only show released items
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS released_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE order_items SET released_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_held ON order_items(order_id) WHERE released_at IS NULL;
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('customer_order_acceptance_required', 'false', 'boolean', 'Hold QR and online orders until staff accept them; items at auto-released stations are still sent immediately', 'kitchen'),
+('auto_release_stations', 'bar', 'string', 'Comma-separated stations whose items skip acceptance on customer orders (kitchen, bar)', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
@@ -0,0 +1,46 @@
+-- Migration: Email outbox and SMTP settings
+-- Feature: email-service
+-- Date: 2026-10-14
+-- Description: Outbox of every email the system sends (contact replies, sales summaries, stock digests), with delivery status for retries, plus SMTP settings editable from the admin panel
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    template VARCHAR(50) NOT NULL,
+    recipients TEXT[] NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    -- queued -> sent, or failed once the delivery job runs out of attempts
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- What the email is about, e.g. contact_submission / <id>
+    related_type VARCHAR(50),
+    related_id UUID,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_status ON email_outbox(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_related ON email_outbox(related_type, related_id);
+
+-- Blank SMTP settings fall back to the SMTP_* environment variables
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('smtp_host', '', 'string', 'SMTP server host; blank uses SMTP_HOST from the environment', 'email'),
+('smtp_port', '', 'string', 'SMTP server port; blank uses SMTP_PORT', 'email'),
+('smtp_secure', '', 'string', 'true for implicit TLS (port 465), false for STARTTLS; blank uses SMTP_SECURE', 'email'),
+('smtp_user', '', 'string', 'SMTP login; blank uses SMTP_USER', 'email'),
+('smtp_password', '', 'string', 'SMTP password; blank uses SMTP_PASSWORD', 'email'),
+('smtp_from', '', 'string', 'From address, e.g. "Modern Steak <noreply@example.com>"; blank uses SMTP_FROM', 'email'),
+('daily_sales_summary_recipients', '', 'string', 'Comma-separated emails for the daily sales summary; blank sends to staff who can view reports', 'email'),
+('low_stock_digest_recipients', '', 'string', 'Comma-separated emails for the low-stock digest; blank sends to staff who manage inventory', 'email')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'email.manage'),
+('manager', 'email.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+COMMENT ON TABLE email_outbox IS 'Every outgoing email with its delivery status; failed emails can be retried from the admin panel';
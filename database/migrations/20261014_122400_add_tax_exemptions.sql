@@ -0,0 +1,54 @@
+-- Migration: Tax and service charge exemptions
+-- Feature: tax-exemptions
+-- Date: 2026-10-14
+-- Description: Product- and category-level exemptions from tax and service charge, optionally limited to a branch or order type, with the tax and service charge of each order item stored for receipts and tax reports
+
+CREATE TABLE IF NOT EXISTS tax_exemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    -- Exactly one target: a product, or every product in a category
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    -- NULL = every branch / every order type
+    branch_id UUID REFERENCES branches(id) ON DELETE CASCADE,
+    order_type VARCHAR(20) CHECK (order_type IN ('dine_in', 'takeout', 'delivery')),
+    -- Both false is allowed: it overrides a broader exemption for this target
+    exempt_tax BOOLEAN NOT NULL DEFAULT false,
+    exempt_service BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_tax_exemptions_target CHECK ((product_id IS NULL) <> (category_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_exemptions_product ON tax_exemptions(product_id) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_tax_exemptions_category ON tax_exemptions(category_id) WHERE is_active = true;
+-- One rule per target and scope
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_exemptions_scope ON tax_exemptions (
+    COALESCE(product_id, category_id),
+    COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    COALESCE(order_type, '')
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS service_charge_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- Tax and service charge of each item after its share of the discounts.
+-- Existing items keep 0 / not exempt; their order totals are unchanged.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS service_charge_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_exempt BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS service_exempt BOOLEAN NOT NULL DEFAULT false;
+
+-- The service_charge rate was never applied to orders; it now applies to the
+-- order types listed here (e.g. dine_in), which keeps it off until set.
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('service_charge_order_types', '', 'string', 'Comma-separated order types the service charge applies to (dine_in, takeout, delivery); blank disables it', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'tax.manage'),
+('manager', 'tax.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+COMMENT ON TABLE tax_exemptions IS 'Tax / service charge exemptions; the most specific active rule for an item wins (product over category, branch over all branches, order type over all types)';
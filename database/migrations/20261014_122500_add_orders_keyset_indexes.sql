@@ -0,0 +1,12 @@
+-- Migration: Keyset pagination indexes for orders
+-- Feature: orders-keyset-pagination
+-- Date: 2026-10-14
+-- Description: The orders list pages on (created_at, id); these indexes let a cursor seek straight to the next page, with or without a branch filter
+
+CREATE INDEX IF NOT EXISTS idx_orders_created_id ON orders(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_branch_created_id ON orders(branch_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status_created_id ON orders(status, created_at, id);
+
+-- Covered by the wider indexes above
+DROP INDEX IF EXISTS idx_orders_created_at;
+DROP INDEX IF EXISTS idx_orders_branch_created;
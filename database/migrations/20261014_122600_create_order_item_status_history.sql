@@ -0,0 +1,22 @@
+-- Migration: Order item status history
+-- Feature: item-status-history
+-- Date: 2026-10-14
+-- Description: Every kitchen status change of an order item, with who made it, when and at which station, for disputes and preparation-time analysis
+
+CREATE TABLE IF NOT EXISTS order_item_status_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    -- Voided items are deleted from order_items, so the link is kept loose
+    order_item_id UUID NOT NULL,
+    previous_status VARCHAR(20),
+    new_status VARCHAR(20) NOT NULL,
+    -- Station the item was routed to at the time (kitchen, bar)
+    station VARCHAR(20) NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_status_history_item ON order_item_status_history(order_item_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_item_status_history_order ON order_item_status_history(order_id);
+
+COMMENT ON TABLE order_item_status_history IS 'Kitchen status changes of order items';
@@ -0,0 +1,18 @@
+-- Migration: Product full-text and fuzzy search
+-- Feature: product-search
+-- Date: 2026-10-14
+-- Description: Weighted full-text vector on products (name and SKU over description) plus pg_trgm for typo-tolerant name matching
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- 'simple' config: the menu mixes Indonesian and English, so no stemming
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(sku, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+
+COMMENT ON COLUMN products.search_vector IS 'Full-text search document, maintained by Postgres from name, sku and description';
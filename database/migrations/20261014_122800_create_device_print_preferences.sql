@@ -0,0 +1,27 @@
+-- Migration: Per-device print preferences
+-- Feature: device-print-preferences
+-- Date: 2026-10-14
+-- Description: Receipt and kitchen printing preferences for each POS terminal, returned to the device on login
+
+-- device_id is generated by the terminal and kept in its browser storage.
+-- NULL preferences fall back to the receipt and kitchen system settings.
+CREATE TABLE IF NOT EXISTS device_print_preferences (
+    device_id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100),
+    branch_id UUID REFERENCES branches(id) ON DELETE SET NULL,
+    receipt_printer VARCHAR(100),
+    auto_print_receipt BOOLEAN,
+    receipt_copies INTEGER CHECK (receipt_copies BETWEEN 1 AND 5),
+    kitchen_printer VARCHAR(100),
+    auto_print_kitchen BOOLEAN,
+    kitchen_copies INTEGER CHECK (kitchen_copies BETWEEN 1 AND 5),
+    last_seen_at TIMESTAMP WITH TIME ZONE,
+    last_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_print_preferences_branch ON device_print_preferences(branch_id);
+
+COMMENT ON TABLE device_print_preferences IS 'Printing preferences of each POS terminal';
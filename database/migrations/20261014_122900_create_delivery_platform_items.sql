@@ -0,0 +1,26 @@
+-- Migration: Delivery platform menu mapping
+-- Feature: delivery-menu-sync
+-- Date: 2026-10-14
+-- Description: Maps products to their GoFood / GrabFood item IDs and records what was last pushed, for menu sync and reconciliation
+
+CREATE TABLE IF NOT EXISTS delivery_platform_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    platform VARCHAR(20) NOT NULL CHECK (platform IN ('gofood', 'grabfood')),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    platform_item_id VARCHAR(100) NOT NULL,
+    -- What the platform was last sent; compared with the product to find drift
+    synced_price DECIMAL(10,2),
+    synced_available BOOLEAN,
+    sync_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (sync_status IN ('pending', 'synced', 'failed')),
+    last_error TEXT,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One listing per product and platform, and each platform item mapped once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_platform_items_product ON delivery_platform_items(product_id, platform);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_platform_items_platform_item ON delivery_platform_items(platform, platform_item_id);
+
+COMMENT ON TABLE delivery_platform_items IS 'Products listed on delivery platforms and their last synced state';
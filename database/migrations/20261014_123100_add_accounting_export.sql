@@ -0,0 +1,17 @@
+-- Migration: Accounting journal export
+-- Feature: accounting-export
+-- Date: 2026-10-14
+-- Description: Chart-of-accounts mapping for the daily sales journal export (Accurate / Jurnal CSV) and the permission to download it
+
+-- Empty: every account uses its default code. Override per key, e.g.
+-- {"cash": {"code": "1-1101", "name": "Kas Kasir"}}; keys are cash, credit_card,
+-- debit_card, digital_wallet, corporate_wallet, on_account, receivable, sales,
+-- service_charge, delivery_fee and tax_payable.
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('accounting_accounts', '{}', 'json', 'Account codes and names used in the daily accounting journal export, overriding the defaults per account', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'accounting.export'),
+('manager', 'accounting.export')
+ON CONFLICT (role, permission) DO NOTHING;
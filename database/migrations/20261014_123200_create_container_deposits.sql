@@ -0,0 +1,51 @@
+-- Migration: Reusable container deposits
+-- Feature: container-deposits
+-- Date: 2026-10-14
+-- Description: Refundable deposits for reusable takeaway containers: container types, deposit lines on orders, and returns that refund them
+
+CREATE TABLE IF NOT EXISTS container_types (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    deposit_amount DECIMAL(10,2) NOT NULL CHECK (deposit_amount > 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Deposits are part of total_amount (so payments collect them) but are not
+-- sales: revenue figures subtract them like tax and delivery fees
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS deposit_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- unit_deposit is the deposit charged at the time, so returns refund what
+-- was paid even after the container type's deposit changes
+CREATE TABLE IF NOT EXISTS order_container_deposits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    container_type_id UUID NOT NULL REFERENCES container_types(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_deposit DECIMAL(10,2) NOT NULL CHECK (unit_deposit > 0),
+    returned_quantity INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_order_container_deposits_returned CHECK (returned_quantity BETWEEN 0 AND quantity)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_container_deposits_order_type
+    ON order_container_deposits(order_id, container_type_id);
+
+CREATE TABLE IF NOT EXISTS container_returns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_deposit_id UUID NOT NULL REFERENCES order_container_deposits(id) ON DELETE CASCADE,
+    branch_id UUID NOT NULL REFERENCES branches(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    refund_amount DECIMAL(10,2) NOT NULL CHECK (refund_amount > 0),
+    refund_method VARCHAR(20) NOT NULL CHECK (refund_method IN ('cash', 'digital_wallet')),
+    notes TEXT,
+    returned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_container_returns_created ON container_returns(created_at);
+CREATE INDEX IF NOT EXISTS idx_container_returns_deposit ON container_returns(order_deposit_id);
+
+COMMENT ON TABLE container_returns IS 'Containers brought back against an order''s deposit; refunds are paid out from the branch that took them back';
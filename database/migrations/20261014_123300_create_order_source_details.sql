@@ -0,0 +1,32 @@
+-- Migration: Customer order source details
+-- Feature: order-source-risk
+-- Date: 2026-10-14
+-- Description: Device and coarse location of customer (QR and online) orders, with risk flags for orders placed to a table from outside the restaurant
+
+-- Venue location per branch for the distance check; branches without one
+-- use the restaurant's map location
+ALTER TABLE branches
+ADD COLUMN IF NOT EXISTS latitude DECIMAL(10,8),
+ADD COLUMN IF NOT EXISTS longitude DECIMAL(11,8);
+
+-- Location is stored rounded to about 100 m; precise positions aren't needed
+-- for the check and aren't kept
+CREATE TABLE IF NOT EXISTS order_source_details (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    ip_address VARCHAR(64),
+    user_agent VARCHAR(500),
+    device_fingerprint VARCHAR(128),
+    latitude DECIMAL(7,3),
+    longitude DECIMAL(8,3),
+    location_accuracy_m INTEGER,
+    distance_m INTEGER,
+    risk_flags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_source_details_fingerprint
+    ON order_source_details(device_fingerprint, created_at) WHERE device_fingerprint IS NOT NULL;
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('qr_order_max_distance_meters', '300', 'number', 'How far from the restaurant a table QR order may be placed before it is flagged', 'restaurant')
+ON CONFLICT (setting_key) DO NOTHING;
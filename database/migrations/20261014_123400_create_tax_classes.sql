@@ -0,0 +1,36 @@
+-- Migration: Tax classes
+-- Feature: tax-classes
+-- Date: 2026-10-14
+-- Description: Named tax rates assigned per product (e.g. PB1 11% vs 0% items), with the rate and class applied to each order item recorded for receipts and income reports, and the service charge turned on for dine-in orders
+
+CREATE TABLE IF NOT EXISTS tax_classes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    -- Percent, e.g. 11.00
+    rate DECIMAL(5,2) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- NULL = the branch's tax_rate setting
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_class_id UUID REFERENCES tax_classes(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_products_tax_class ON products(tax_class_id) WHERE tax_class_id IS NOT NULL;
+
+-- Snapshot of the tax applied to each item; NULL on items priced before
+-- tax classes existed
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_class_id UUID REFERENCES tax_classes(id) ON DELETE SET NULL;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_label VARCHAR(100);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5,2);
+
+INSERT INTO tax_classes (name, rate, description) VALUES
+('PB1', 11.00, 'Pajak restoran (PB1)'),
+('Non-taxable', 0.00, 'Items not subject to restaurant tax')
+ON CONFLICT (name) DO NOTHING;
+
+-- Dine-in orders carry the service charge unless it was already configured
+UPDATE system_settings SET setting_value = 'dine_in', updated_at = CURRENT_TIMESTAMP
+WHERE setting_key = 'service_charge_order_types' AND setting_value = '';
+
+COMMENT ON TABLE tax_classes IS 'Tax rates assigned to products; products without a class use the tax_rate setting, and tax exemptions still take precedence';
@@ -0,0 +1,13 @@
+-- Migration: Cash rounding
+-- Feature: cash-rounding
+-- Date: 2026-10-14
+-- Description: Rounding of the cash payment that settles an order to the nearest 100 / 500 Rupiah, with the difference to the amount due stored on the payment for the day close
+
+-- Cash handed over minus the amount settled on the order (negative when
+-- rounded down)
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS rounding_adjustment DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('cash_rounding_increment', '0', 'number', 'Round the cash payment settling an order to this many Rupiah (e.g. 100 or 500); 0 disables rounding', 'financial'),
+('cash_rounding_mode', 'nearest', 'string', 'Direction of cash rounding: nearest, down (in the customer''s favour) or up', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
@@ -0,0 +1,32 @@
+-- Migration: Gateway refunds
+-- Feature: gateway-refunds
+-- Date: 2026-10-14
+-- Description: Refunds of payment gateway charges submitted to the gateway's refund API, with the refund payment kept pending until the gateway confirms it
+
+CREATE TABLE IF NOT EXISTS gateway_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- The negative payment row; pending until the gateway confirms
+    refund_payment_id UUID NOT NULL UNIQUE REFERENCES payments(id) ON DELETE CASCADE,
+    payment_link_id UUID NOT NULL REFERENCES payment_links(id) ON DELETE CASCADE,
+    -- The charge's order id at the gateway
+    gateway_order_id VARCHAR(100) NOT NULL,
+    -- Sent as the gateway's idempotency key; renewed when a failed refund is retried
+    refund_key VARCHAR(100) NOT NULL UNIQUE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    reason VARCHAR(255) NOT NULL,
+    -- queued: waiting to be sent; submitted: accepted, awaiting confirmation
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'submitted', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    gateway_status_code VARCHAR(10),
+    last_error TEXT,
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_gateway_refunds_order ON gateway_refunds(gateway_order_id);
+CREATE INDEX IF NOT EXISTS idx_gateway_refunds_open ON gateway_refunds(created_at) WHERE status IN ('queued', 'submitted');
+
+COMMENT ON TABLE gateway_refunds IS 'Refunds sent to the payment gateway; the refund payment completes only once the gateway confirms';
@@ -0,0 +1,27 @@
+-- Migration: Order SLA
+-- Feature: order-sla
+-- Date: 2026-10-14
+-- Description: Configurable targets for how quickly customer orders are accepted, started, ready and served, per daypart, with breaches recorded once per order and stage for staff notifications
+
+CREATE TABLE IF NOT EXISTS order_sla_breaches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    stage VARCHAR(20) NOT NULL
+        CHECK (stage IN ('accepted', 'kitchen_started', 'ready', 'served')),
+    daypart VARCHAR(50),
+    -- The target in force when the breach was found
+    target_minutes INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, stage)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_sla_breaches_created ON order_sla_breaches(created_at);
+
+COMMENT ON TABLE order_sla_breaches IS 'Customer orders that missed an SLA target, one row per stage, so each breach is notified once';
+
+-- Minutes from the order being placed; a daypart can override any stage
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('order_sla_targets', '{"accepted":3,"kitchen_started":8,"ready":25,"served":30}', 'json', 'Minutes from a customer order being placed to staff accepting it, the kitchen starting, ready and served', 'kitchen'),
+('order_sla_dayparts', '[{"name":"Breakfast","start":"06:00","end":"11:00"},{"name":"Lunch","start":"11:00","end":"15:00"},{"name":"Afternoon","start":"15:00","end":"17:00"},{"name":"Dinner","start":"17:00","end":"22:00"},{"name":"Late","start":"22:00","end":"06:00"}]', 'json', 'Dayparts for the SLA report (name, start, end as HH:MM, optional targets overriding order_sla_targets)', 'kitchen'),
+('order_sla_breach_notifications', 'true', 'boolean', 'Notify staff when an open customer order misses an SLA target', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
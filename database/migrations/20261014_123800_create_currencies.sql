@@ -0,0 +1,38 @@
+-- Migration: Display currencies
+-- Feature: currencies
+-- Date: 2026-10-14
+-- Description: Foreign currencies and exchange rates for showing menu prices and order totals to tourists; orders are still priced and settled in IDR
+
+CREATE TABLE IF NOT EXISTS currencies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(3) NOT NULL UNIQUE CHECK (code ~ '^[A-Z]{3}$'),
+    name VARCHAR(100) NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    -- Rupiah per one unit of the currency
+    rate_to_idr DECIMAL(18,6) NOT NULL CHECK (rate_to_idr > 0),
+    decimal_places INTEGER NOT NULL DEFAULT 2 CHECK (decimal_places BETWEEN 0 AND 4),
+    is_active BOOLEAN NOT NULL DEFAULT false,
+    rate_updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE currencies IS 'Display currencies; prices are converted from IDR for display only';
+
+-- Indicative rates; inactive until an admin confirms the day's rate
+INSERT INTO currencies (code, name, symbol, rate_to_idr, decimal_places) VALUES
+('USD', 'US Dollar', 'US$', 16250, 2),
+('EUR', 'Euro', '€', 17600, 2),
+('AUD', 'Australian Dollar', 'A$', 10600, 2),
+('SGD', 'Singapore Dollar', 'S$', 12500, 2)
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'currencies.manage'),
+('manager', 'currencies.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- The currency the guest viewed the order in, with the rate fixed when it
+-- was placed; all amounts on the order stay in IDR
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS display_currency VARCHAR(3);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18,6);
@@ -0,0 +1,29 @@
+-- Migration: Product costing
+-- Feature: product-costing
+-- Date: 2026-10-14
+-- Description: Per-product cost overrides for items without recipes and manual COGS adjustments per period, for the COGS and margin report
+
+-- Cost of one portion; when set it is used instead of the recipe cost
+ALTER TABLE products ADD COLUMN IF NOT EXISTS cost_override DECIMAL(10,2) CHECK (cost_override >= 0);
+
+CREATE TABLE IF NOT EXISTS cogs_adjustments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    -- Optional: the product the adjustment is about (e.g. spoilage of a bought-in item)
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    adjustment_date DATE NOT NULL,
+    -- Positive adds to the cost of goods sold, negative takes it off
+    amount DECIMAL(12,2) NOT NULL CHECK (amount <> 0),
+    reason VARCHAR(255) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cogs_adjustments_date ON cogs_adjustments(adjustment_date);
+
+COMMENT ON TABLE cogs_adjustments IS 'Manual corrections to the theoretical cost of goods sold, e.g. waste, stock counts or supplier credits';
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'costing.manage'),
+('manager', 'costing.manage')
+ON CONFLICT (role, permission) DO NOTHING;
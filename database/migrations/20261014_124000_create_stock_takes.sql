@@ -0,0 +1,61 @@
+-- Migration: Stock takes
+-- Feature: stock-takes
+-- Date: 2026-10-14
+-- Description: Physical inventory counts: a session snapshots expected product and ingredient stock, staff enter counted quantities, and posting applies the variances as stock adjustments
+
+CREATE TABLE IF NOT EXISTS stock_takes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    -- What is counted: the branch's products, the (central) ingredient stock, or both
+    scope VARCHAR(20) NOT NULL DEFAULT 'all' CHECK (scope IN ('products', 'ingredients', 'all')),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'posted', 'cancelled')),
+    notes TEXT,
+    started_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    posted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    posted_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One open count per branch, and one at a time for the shared ingredient stock
+CREATE UNIQUE INDEX IF NOT EXISTS stock_takes_open_branch_key ON stock_takes(branch_id) WHERE status = 'open';
+CREATE UNIQUE INDEX IF NOT EXISTS stock_takes_open_ingredients_key ON stock_takes((true)) WHERE status = 'open' AND scope <> 'products';
+
+CREATE INDEX IF NOT EXISTS idx_stock_takes_started_at ON stock_takes(started_at);
+
+CREATE TABLE IF NOT EXISTS stock_take_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stock_take_id UUID NOT NULL REFERENCES stock_takes(id) ON DELETE CASCADE,
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    ingredient_id UUID REFERENCES ingredients(id) ON DELETE CASCADE,
+    -- Stock on record when the session started
+    expected_quantity DECIMAL(10,2) NOT NULL,
+    counted_quantity DECIMAL(10,2) CHECK (counted_quantity >= 0),
+    -- Unit cost when the session started, to value the variance
+    unit_cost DECIMAL(10,2),
+    -- The stock change actually posted (the variance, unless that would have taken stock below zero)
+    posted_adjustment DECIMAL(10,2),
+    counted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    counted_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((product_id IS NULL) <> (ingredient_id IS NULL)),
+    UNIQUE (stock_take_id, product_id),
+    UNIQUE (stock_take_id, ingredient_id)
+);
+
+-- Posting records ingredient variances as count_gain / count_loss
+ALTER TABLE ingredient_history
+DROP CONSTRAINT IF EXISTS ingredient_history_operation_check;
+
+ALTER TABLE ingredient_history
+ADD CONSTRAINT ingredient_history_operation_check
+CHECK (operation IN ('add', 'remove', 'restock', 'usage', 'spoilage',
+                     'adjustment', 'order_consumption', 'order_cancellation',
+                     'count_gain', 'count_loss'));
+
+COMMENT ON TABLE stock_takes IS 'Physical inventory count sessions';
+COMMENT ON TABLE stock_take_lines IS 'Expected and counted quantity of each product or ingredient in a stock take';
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'stock_takes.post'),
+('manager', 'stock_takes.post')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,18 @@
+-- Migration: Products sold by weight
+-- Feature: weighed-products
+-- Date: 2026-10-14
+-- Description: Lets a product be priced per kilogram, per 100 g or per gram and ordered in decimal quantities of that unit, optionally straight from a scale reading
+
+-- 'each' is sold by the portion; otherwise price is per this weight unit
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_unit VARCHAR(10) NOT NULL DEFAULT 'each'
+    CHECK (sale_unit IN ('each', 'kg', '100g', 'g'));
+
+-- Quantities are in the product's sale unit, e.g. 0.350 for 350 g of a per-kg steak
+ALTER TABLE order_items ALTER COLUMN quantity TYPE DECIMAL(10,3);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS weight_grams DECIMAL(10,1) CHECK (weight_grams > 0);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS scale_device VARCHAR(100);
+
+ALTER TABLE order_item_changes ALTER COLUMN previous_quantity TYPE DECIMAL(10,3);
+ALTER TABLE order_item_changes ALTER COLUMN new_quantity TYPE DECIMAL(10,3);
+
+COMMENT ON COLUMN order_items.weight_grams IS 'Weight the quantity was taken from, when it came from a scale';
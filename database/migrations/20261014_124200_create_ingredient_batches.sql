@@ -0,0 +1,39 @@
+-- Migration: Ingredient batches
+-- Feature: ingredient-batches
+-- Date: 2026-10-14
+-- Description: Lot tracking for ingredient stock: each restock is a batch with an expiry date, consumption draws from the earliest-expiring batch first, and batches about to expire are flagged to managers
+
+CREATE TABLE IF NOT EXISTS ingredient_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ingredient_id UUID NOT NULL REFERENCES ingredients(id) ON DELETE CASCADE,
+    -- Supplier's lot number, if any
+    batch_code VARCHAR(50),
+    quantity_received DECIMAL(10,2) NOT NULL CHECK (quantity_received > 0),
+    quantity_remaining DECIMAL(10,2) NOT NULL CHECK (quantity_remaining >= 0),
+    -- Null for goods that don't expire
+    expiry_date DATE,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- Set once managers have been warned the batch is about to expire
+    expiry_notified_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingredient_batches_open ON ingredient_batches(ingredient_id, expiry_date) WHERE quantity_remaining > 0;
+
+-- What each order (or stock correction) took from which batch, so returns go back where they came from
+CREATE TABLE IF NOT EXISTS ingredient_batch_usage (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    batch_id UUID NOT NULL REFERENCES ingredient_batches(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    -- Negative when stock is put back
+    quantity DECIMAL(10,2) NOT NULL CHECK (quantity <> 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingredient_batch_usage_order ON ingredient_batch_usage(order_id);
+
+COMMENT ON TABLE ingredient_batches IS 'Received lots of an ingredient; the stock on ingredients.current_stock not covered by a batch is untracked';
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('ingredient_expiry_warning_days', '3', 'number', 'Warn managers about ingredient batches expiring within this many days', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
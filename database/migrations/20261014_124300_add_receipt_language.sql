@@ -0,0 +1,21 @@
+-- Migration: Receipt language
+-- Feature: receipt-language
+-- Date: 2026-10-14
+-- Description: Receipts are printed in Bahasa Indonesia, optionally with English alongside; the language can be chosen per order or remembered for a customer
+
+-- 'id' = Bahasa Indonesia only, 'id_en' = Bahasa Indonesia with English.
+-- Null follows the customer's preference, then the receipt_language setting.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS receipt_language VARCHAR(10)
+    CHECK (receipt_language IN ('id', 'id_en'));
+
+-- Customers order without an account, so the preference is kept per phone
+-- number (stored as 62812…)
+CREATE TABLE IF NOT EXISTS customer_receipt_preferences (
+    phone VARCHAR(20) PRIMARY KEY,
+    receipt_language VARCHAR(10) NOT NULL CHECK (receipt_language IN ('id', 'id_en')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('receipt_language', 'id', 'string', 'Default receipt language: id (Bahasa Indonesia) or id_en (Bahasa Indonesia with English)', 'receipt')
+ON CONFLICT (setting_key) DO NOTHING;
@@ -0,0 +1,50 @@
+-- Migration: Waste logs
+-- Feature: waste-logging
+-- Date: 2026-10-14
+-- Description: Kitchen staff log spoilage and prep waste of products or ingredients with a reason and photo; logging deducts the stock and the cost at the time feeds a waste cost report
+
+CREATE TABLE IF NOT EXISTS waste_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    ingredient_id UUID REFERENCES ingredients(id) ON DELETE SET NULL,
+    -- In the product's sale unit or the ingredient's unit
+    quantity DECIMAL(10,3) NOT NULL CHECK (quantity > 0),
+    reason_type VARCHAR(20) NOT NULL CHECK (reason_type IN ('spoilage', 'prep_waste', 'expired', 'damaged', 'other')),
+    reason TEXT,
+    -- Uploaded photo (/uploads/...) or a link to one
+    photo_url VARCHAR(500),
+    -- Cost of one unit when logged; null when the item has no cost
+    unit_cost DECIMAL(10,2),
+    total_cost DECIMAL(12,2),
+    logged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(product_id, ingredient_id) <= 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waste_logs_branch_created ON waste_logs(branch_id, created_at);
+
+ALTER TABLE inventory_history
+DROP CONSTRAINT IF EXISTS inventory_history_reason_check;
+
+ALTER TABLE inventory_history
+ADD CONSTRAINT inventory_history_reason_check
+CHECK (reason IN ('purchase', 'sale', 'spoilage', 'manual_adjustment', 'inventory_count', 'return', 'damage', 'theft', 'expired', 'waste'));
+
+ALTER TABLE inventory_history ADD COLUMN IF NOT EXISTS waste_log_id UUID REFERENCES waste_logs(id) ON DELETE SET NULL;
+ALTER TABLE ingredient_history ADD COLUMN IF NOT EXISTS waste_log_id UUID REFERENCES waste_logs(id) ON DELETE SET NULL;
+
+ALTER TABLE ingredient_history
+DROP CONSTRAINT IF EXISTS ingredient_history_operation_check;
+
+ALTER TABLE ingredient_history
+ADD CONSTRAINT ingredient_history_operation_check
+CHECK (operation IN ('add', 'remove', 'restock', 'usage', 'spoilage',
+                     'adjustment', 'order_consumption', 'order_cancellation',
+                     'count_gain', 'count_loss', 'waste'));
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'kitchen.waste'),
+('manager', 'kitchen.waste'),
+('kitchen', 'kitchen.waste')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,11 @@
+-- Migration: Stock-driven menu availability
+-- Feature: stock-availability
+-- Date: 2026-10-14
+-- Description: Products are taken off the menu automatically when their inventory or a recipe ingredient runs out and put back on restock, unless availability is overridden by hand
+
+-- When true, stock levels never change is_available; staff decide.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS availability_override BOOLEAN NOT NULL DEFAULT false;
+
+-- Set when the stock check took the product off the menu. Only those
+-- products are put back on restock, so a dish hidden by hand stays hidden.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock_unavailable_at TIMESTAMP WITH TIME ZONE;
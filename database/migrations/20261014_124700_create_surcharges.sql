@@ -0,0 +1,48 @@
+-- Migration: Holiday and event surcharges
+-- Feature: surcharges
+-- Date: 2026-10-14
+-- Description: Temporary surcharges (e.g. a New Year's Eve service fee) with a date window, added automatically to orders placed in the window, itemized per order and taxed
+
+CREATE TABLE IF NOT EXISTS surcharges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    -- percentage: value is a percent of the order after discounts;
+    -- fixed: value is an amount per order
+    surcharge_type VARCHAR(20) NOT NULL CHECK (surcharge_type IN ('percentage', 'fixed')),
+    value DECIMAL(10,2) NOT NULL CHECK (value > 0),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- NULL = every branch
+    branch_id UUID REFERENCES branches(id) ON DELETE CASCADE,
+    -- Empty = every order type
+    order_types TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_surcharges_window ON surcharges(starts_at, ends_at) WHERE is_active = true;
+
+-- What each order was charged; name and rate are kept so editing or
+-- deleting a surcharge doesn't change past receipts
+CREATE TABLE IF NOT EXISTS order_surcharges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    surcharge_id UUID REFERENCES surcharges(id) ON DELETE SET NULL,
+    name VARCHAR(100) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    -- Percent the surcharge was taxed at
+    tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_surcharges_order ON order_surcharges(order_id);
+
+-- Sum of order_surcharges.amount; their tax is part of orders.tax_amount
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS surcharge_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+COMMENT ON TABLE surcharges IS 'Temporary order surcharges for holidays and events; taxed at the branch tax_rate, not subject to the service charge';
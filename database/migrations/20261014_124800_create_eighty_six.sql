@@ -0,0 +1,30 @@
+-- Migration: 86 list
+-- Feature: eighty-six
+-- Date: 2026-10-14
+-- Description: Kitchen staff mark a product sold out ("86") for the rest of the business day; it is hidden from the menus until the daily rollover puts it back, and every 86 is kept for reporting
+
+-- Set while the product is 86'd; it is also unavailable until then
+ALTER TABLE products ADD COLUMN IF NOT EXISTS eighty_sixed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS eighty_six_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    business_date DATE NOT NULL,
+    reason TEXT,
+    eighty_sixed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    eighty_sixed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- NULL while still 86'd
+    restored_at TIMESTAMP WITH TIME ZONE,
+    -- NULL when the daily rollover put it back
+    restored_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_eighty_six_events_date ON eighty_six_events(business_date);
+-- At most one open 86 per product
+CREATE UNIQUE INDEX IF NOT EXISTS idx_eighty_six_events_open ON eighty_six_events(product_id) WHERE restored_at IS NULL;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'kitchen.eighty_six'),
+('manager', 'kitchen.eighty_six'),
+('kitchen', 'kitchen.eighty_six')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,37 @@
+-- Migration: Order item remakes
+-- Feature: remakes
+-- Date: 2026-10-14
+-- Description: Staff log a dish sent back by the customer with a reason; the remake goes to the kitchen as a new item at no charge and every remake feeds a kitchen quality report
+
+-- A free replacement for a sent-back item; left out of pricing
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS is_remake BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS order_item_remakes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    branch_id UUID REFERENCES branches(id) ON DELETE SET NULL,
+    -- The item sent back and the one made to replace it
+    order_item_id UUID REFERENCES order_items(id) ON DELETE SET NULL,
+    remake_item_id UUID REFERENCES order_items(id) ON DELETE SET NULL,
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    product_name VARCHAR(255) NOT NULL,
+    quantity DECIMAL(10,3) NOT NULL CHECK (quantity > 0),
+    reason_type VARCHAR(20) NOT NULL CHECK (reason_type IN ('overcooked', 'undercooked', 'wrong_item', 'cold', 'quality', 'foreign_object', 'other')),
+    reason TEXT,
+    station VARCHAR(20) NOT NULL DEFAULT 'kitchen',
+    -- Cost of the remake when logged; null when the product has no cost
+    unit_cost DECIMAL(10,2),
+    total_cost DECIMAL(12,2),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_remakes_created ON order_item_remakes(created_at);
+CREATE INDEX IF NOT EXISTS idx_order_item_remakes_order ON order_item_remakes(order_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'orders.remake'),
+('manager', 'orders.remake'),
+('server', 'orders.remake'),
+('kitchen', 'orders.remake')
+ON CONFLICT (role, permission) DO NOTHING;
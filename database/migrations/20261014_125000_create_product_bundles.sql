@@ -0,0 +1,17 @@
+-- Migration: Product bundles
+-- Feature: product-bundles
+-- Date: 2026-10-14
+-- Description: A product can be sold as a bundle of other products; ordering it takes its components out of stock and it is only in stock while every component is
+
+CREATE TABLE IF NOT EXISTS product_bundle_components (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bundle_product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    -- Portions of the component in one bundle
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (bundle_product_id, component_product_id),
+    CHECK (bundle_product_id <> component_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_bundle_components_component ON product_bundle_components(component_product_id);
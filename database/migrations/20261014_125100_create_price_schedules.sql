@@ -0,0 +1,40 @@
+-- Migration: Price schedules
+-- Feature: price-schedules
+-- Date: 2026-10-14
+-- Description: Happy hour and other time-based prices; a schedule overrides the menu price of its products or categories during a daily window (WIB) on chosen days, for new orders and on the public menu
+
+CREATE TABLE IF NOT EXISTS price_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    -- At least one must be non-empty
+    product_ids UUID[] NOT NULL DEFAULT '{}',
+    category_ids UUID[] NOT NULL DEFAULT '{}',
+    -- fixed_price: the price becomes value; percentage: value percent off
+    price_type VARCHAR(20) NOT NULL CHECK (price_type IN ('fixed_price', 'percentage')),
+    value DECIMAL(10,2) NOT NULL CHECK (value > 0),
+    -- Empty means every day; 0 = Sunday
+    days_of_week INTEGER[] NOT NULL DEFAULT '{}',
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_price_schedule_percentage CHECK (price_type <> 'percentage' OR value <= 100),
+    CONSTRAINT chk_price_schedule_targets CHECK (cardinality(product_ids) > 0 OR cardinality(category_ids) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_active ON price_schedules(is_active);
+
+DROP TRIGGER IF EXISTS set_price_schedules_updated_at ON price_schedules;
+CREATE TRIGGER set_price_schedules_updated_at
+    BEFORE UPDATE ON price_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- The schedule an item was priced by, if any
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price_schedule_id UUID REFERENCES price_schedules(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN price_schedules.start_time IS 'Daily window start (WIB); a window ending before it wraps past midnight';
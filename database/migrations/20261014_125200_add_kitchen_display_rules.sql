@@ -0,0 +1,35 @@
+-- Migration: Kitchen display colors, sort priority and grouping
+-- Feature: kitchen-display-rules
+-- Date: 2026-10-14
+-- Description: Stations and categories carry the color, sort priority and grouping the kitchen display uses, so fast items (drinks) and long items (steaks) stand apart without clients hardcoding it
+
+-- Station defaults. Lower sort_priority is shown first; group_items_by says
+-- how a ticket's items at the station are grouped: by category (or the
+-- category's kds_group), all under the station, or not at all
+CREATE TABLE IF NOT EXISTS kitchen_station_display (
+    station VARCHAR(20) PRIMARY KEY CHECK (station IN ('kitchen', 'bar')),
+    color VARCHAR(7) CHECK (color ~ '^#[0-9A-Fa-f]{6}$'),
+    sort_priority INTEGER NOT NULL DEFAULT 0,
+    group_items_by VARCHAR(20) NOT NULL DEFAULT 'category'
+        CHECK (group_items_by IN ('category', 'station', 'none')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Drinks come up first in blue, grill items after in red
+INSERT INTO kitchen_station_display (station, color, sort_priority, group_items_by) VALUES
+('bar', '#2563EB', 0, 'category'),
+('kitchen', '#DC2626', 10, 'category')
+ON CONFLICT (station) DO NOTHING;
+
+-- Category overrides; NULL falls back to the category's menu color and the
+-- station's priority. kds_group puts several categories under one heading
+-- (e.g. 'Grill' for every steak category)
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS kds_color VARCHAR(7) CHECK (kds_color ~ '^#[0-9A-Fa-f]{6}$');
+
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS kds_priority INTEGER;
+
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS kds_group VARCHAR(50);
@@ -0,0 +1,8 @@
+-- Migration: Deployment self-test permission
+-- Feature: deployment-selftest
+-- Date: 2026-10-14
+-- Description: POST /admin/selftest runs the order path on sandbox data and rolls it back; only admins (and deploy accounts given the permission) may run it
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'system.selftest')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,15 @@
+-- Migration: Product allergens and dietary tags
+-- Feature: dietary-info
+-- Date: 2026-10-14
+-- Description: Products list their allergens, dietary tags (halal, vegetarian...) and spice level, shown as badges on the public menu and usable as menu filters
+
+-- Values come from the fixed lists in services/dietary.ts
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS allergens TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS dietary_tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- 0 = not spicy .. 3 = very spicy; NULL when not stated
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS spicy_level SMALLINT CHECK (spicy_level BETWEEN 0 AND 3);
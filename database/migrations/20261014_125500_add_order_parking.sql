@@ -0,0 +1,36 @@
+-- Migration: Parked orders
+-- Feature: order-parking
+-- Date: 2026-10-14
+-- Description: Counter staff can park an order (customer forgot their wallet) so it stays off the kitchen feed until resumed; parked orders left too long are cancelled automatically
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_status_check
+CHECK (status IN ('scheduled', 'parked', 'pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'));
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS parked_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS parked_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS park_reason TEXT;
+
+-- The expiry job cancels the order after this
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS park_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_park_expires_at ON orders(park_expires_at) WHERE status = 'parked';
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('parked_order_expiry_minutes', '60', 'number', 'Minutes a parked order waits before it is cancelled and its stock returned', 'kitchen')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'orders.park'),
+('manager', 'orders.park'),
+('counter', 'orders.park')
+ON CONFLICT (role, permission) DO NOTHING;
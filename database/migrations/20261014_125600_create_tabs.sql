@@ -0,0 +1,53 @@
+-- Migration: Bar tabs
+-- Feature: tabs
+-- Date: 2026-10-14
+-- Description: Tabs opened against a card pre-authorization taken through the payment gateway; orders accumulate on the tab and the total is captured at close-out, releasing the rest of the hold
+
+CREATE TABLE IF NOT EXISTS tabs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID NOT NULL REFERENCES branches(id),
+    customer_name VARCHAR(100) NOT NULL,
+    table_id UUID REFERENCES dining_tables(id) ON DELETE SET NULL,
+    -- pending: waiting for the guest to authorize the card; open: orders
+    -- can be added; closing: capture sent to the gateway; closed: captured;
+    -- released: the hold was cancelled with nothing to capture; failed: the
+    -- authorization never succeeded
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'open', 'closing', 'closed', 'released', 'failed')),
+    authorized_amount DECIMAL(12,2) NOT NULL CHECK (authorized_amount > 0),
+    captured_amount DECIMAL(12,2),
+    gateway_reference VARCHAR(100) NOT NULL UNIQUE,
+    gateway_transaction_id VARCHAR(100),
+    payment_url TEXT NOT NULL,
+    -- The card hold lapses after this; the expiry job releases or flags the tab
+    auth_expires_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    opened_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    closed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    authorized_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tabs_branch_status ON tabs(branch_id, status);
+CREATE INDEX IF NOT EXISTS idx_tabs_auth_expires_at ON tabs(auth_expires_at) WHERE status = 'open';
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS tab_id UUID REFERENCES tabs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_tab ON orders(tab_id) WHERE tab_id IS NOT NULL;
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('tab_preauth_amount', '500000', 'number', 'Amount held on the guest''s card when a tab is opened, unless staff choose another', 'financial'),
+('tab_auth_hold_hours', '24', 'number', 'Hours a tab''s card authorization is relied on before the hold is released', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'tabs.manage'),
+('manager', 'tabs.manage'),
+('counter', 'tabs.manage'),
+('server', 'tabs.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+COMMENT ON TABLE tabs IS 'Bar tabs secured by a card pre-authorization and captured at close-out';
@@ -0,0 +1,28 @@
+-- Migration: Notification severity
+-- Feature: notifications
+-- Date: 2026-10-14
+-- Description: Notifications record the event that raised them and its severity (info, warning, critical) so POS and kitchen clients can pick a sound and how prominently to show them; admins override the built-in severities per event or per event and role
+
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS event VARCHAR(50);
+
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS severity VARCHAR(10) NOT NULL DEFAULT 'info'
+    CHECK (severity IN ('info', 'warning', 'critical'));
+
+UPDATE notifications SET event = type WHERE event IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread_severity
+    ON notifications(user_id, severity) WHERE is_read = false;
+
+-- role '' applies to every role; a row for a specific role wins over it
+CREATE TABLE IF NOT EXISTS notification_severity_rules (
+    event VARCHAR(50) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT '',
+    severity VARCHAR(10) NOT NULL CHECK (severity IN ('info', 'warning', 'critical')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event, role)
+);
+
+COMMENT ON TABLE notification_severity_rules IS 'Admin overrides of the built-in notification severity per event, optionally per role';
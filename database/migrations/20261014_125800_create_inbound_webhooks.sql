@@ -0,0 +1,27 @@
+-- Migration: Inbound webhooks
+-- Feature: inbound-webhooks
+-- Date: 2026-10-14
+-- Description: Callbacks received from messaging, delivery platform and accounting providers, stored once per provider event and processed through the job queue
+
+CREATE TABLE IF NOT EXISTS inbound_webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(30) NOT NULL,
+    -- The provider's own ID for the event, or a hash of the body when it has none
+    external_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processed', 'ignored', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- Deliveries of the same event after the first
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_webhook_events_status ON inbound_webhook_events(status, received_at);
+CREATE INDEX IF NOT EXISTS idx_inbound_webhook_events_provider ON inbound_webhook_events(provider, received_at);
+
+COMMENT ON TABLE inbound_webhook_events IS 'Signature-verified callbacks from external providers and how they were processed';
@@ -0,0 +1,30 @@
+-- Migration: Settings history
+-- Feature: settings-history
+-- Date: 2026-10-14
+-- Description: Audit trail of system settings changes, one change set per save, so a change can be traced to who made it and rolled back
+
+CREATE TABLE IF NOT EXISTS settings_change_sets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- Set when this change set undid an earlier one
+    rollback_of UUID REFERENCES settings_change_sets(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS settings_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    change_set_id UUID NOT NULL REFERENCES settings_change_sets(id) ON DELETE CASCADE,
+    setting_key VARCHAR(100) NOT NULL,
+    -- NULL when the setting didn't exist before this change
+    old_value TEXT,
+    new_value TEXT,
+    -- Secret values are not kept; both values are NULL for these
+    is_secret BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_settings_change_sets_created ON settings_change_sets(created_at);
+CREATE INDEX IF NOT EXISTS idx_settings_changes_set ON settings_changes(change_set_id);
+CREATE INDEX IF NOT EXISTS idx_settings_changes_key ON settings_changes(setting_key);
+
+COMMENT ON TABLE settings_change_sets IS 'One saved edit of the system settings and who made it';
+COMMENT ON TABLE settings_changes IS 'The settings a change set changed, with their values before and after';
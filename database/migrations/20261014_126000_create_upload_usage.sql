@@ -0,0 +1,18 @@
+-- Migration: Upload usage
+-- Feature: upload-quota
+-- Date: 2026-10-14
+-- Description: Images uploaded per user per day, counted against the daily upload quota
+
+CREATE TABLE IF NOT EXISTS upload_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Calendar date in the restaurant timezone
+    usage_date DATE NOT NULL,
+    uploads INTEGER NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, usage_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_usage_date ON upload_usage(usage_date);
+
+COMMENT ON TABLE upload_usage IS 'Daily image upload count and size per user, checked against the upload quota';
@@ -0,0 +1,34 @@
+-- Migration: Data export permission and audit log
+-- Feature: data-exports
+-- Date: 2026-10-14
+-- Description: Downloading an export (journal, commission report) needs the data.export permission on top of the one for its data, and every download is logged with who made it
+
+CREATE TABLE IF NOT EXISTS export_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    export_type VARCHAR(50) NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- Kept as well, so the log still names the user after the account is gone
+    username VARCHAR(100) NOT NULL,
+    branch_id UUID REFERENCES branches(id) ON DELETE SET NULL,
+    -- The query the export was made with (date, month, format...)
+    params JSONB NOT NULL DEFAULT '{}',
+    row_count INTEGER NOT NULL DEFAULT 0,
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_log_created ON export_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_export_log_user ON export_log(user_id, created_at);
+
+COMMENT ON TABLE export_log IS 'Audit trail of data exports: what was exported, by whom and when';
+
+-- Everyone who can download an export today keeps that ability
+INSERT INTO role_permissions (role, permission)
+SELECT DISTINCT role, 'data.export' FROM role_permissions
+WHERE permission IN ('accounting.export', 'commissions.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'data.export')
+ON CONFLICT (role, permission) DO NOTHING;
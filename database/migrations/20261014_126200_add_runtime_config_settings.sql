@@ -0,0 +1,14 @@
+-- Migration: Runtime configuration settings
+-- Feature: runtime-config
+-- Date: 2026-10-14
+-- Description: Settings the server reloads without a restart (log level, rate limits, maintenance mode, feature flags); the values match the built-in defaults
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('log_level', 'info', 'string', 'Lowest level of request log line written: debug, info, warn or error', 'system'),
+('maintenance_mode', 'false', 'boolean', 'Answer 503 to everything except health checks, login, the admin API and provider callbacks', 'system'),
+('maintenance_message', 'The system is undergoing maintenance. Please try again shortly.', 'string', 'Message shown while maintenance mode is on', 'system'),
+('rate_limit_public', '30', 'number', 'Public API requests per minute per client IP', 'system'),
+('rate_limit_login', '5', 'number', 'Login attempts per minute per client IP', 'system'),
+('rate_limit_contact', '3', 'number', 'Contact form and reservation submissions per 5 minutes per client IP', 'system'),
+('feature_flags', '{}', 'json', 'Feature flags as a JSON object of true/false values', 'system')
+ON CONFLICT (setting_key) DO NOTHING;
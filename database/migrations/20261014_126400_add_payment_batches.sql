@@ -0,0 +1,21 @@
+-- Migration: Batch payments (group billing)
+-- Feature: payment-batches
+-- Date: 2026-10-14
+-- Description: One tender settling several orders at once (a company guest paying for several tables); each order still gets its own payment row, linked to the batch for the combined receipt
+
+CREATE TABLE IF NOT EXISTS payment_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    branch_id UUID REFERENCES branches(id) ON DELETE SET NULL,
+    payment_method VARCHAR(30) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    rounding_adjustment DECIMAL(12,2) NOT NULL DEFAULT 0,
+    reference_number VARCHAR(100),
+    order_count INTEGER NOT NULL,
+    processed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS batch_id UUID REFERENCES payment_batches(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_batch ON payments(batch_id) WHERE batch_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payment_batches_created ON payment_batches(created_at DESC);
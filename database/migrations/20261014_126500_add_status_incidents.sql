@@ -0,0 +1,21 @@
+-- Migration: Status page incidents
+-- Feature: status-page
+-- Date: 2026-10-14
+-- Description: Incidents raised by admins for the public status endpoint, so front-of-house staff can see whether an ordering problem is known and systemic
+
+CREATE TABLE IF NOT EXISTS status_incidents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title VARCHAR(150) NOT NULL,
+    message TEXT,
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('minor', 'major', 'critical')),
+    component VARCHAR(30) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_incidents_open ON status_incidents(started_at DESC) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_status_incidents_resolved ON status_incidents(resolved_at DESC) WHERE resolved_at IS NOT NULL;
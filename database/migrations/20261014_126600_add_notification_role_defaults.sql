@@ -0,0 +1,20 @@
+-- Migration: Notification preference defaults per role
+-- Feature: notifications
+-- Date: 2026-10-14
+-- Description: Default notification preferences per role, given to the role's users when their preferences are first created and bulk-applied to existing users on request; preferences remember which fields the user set themselves so bulk applies leave them alone
+
+CREATE TABLE IF NOT EXISTS notification_role_defaults (
+    role VARCHAR(20) PRIMARY KEY REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    email_enabled BOOLEAN NOT NULL DEFAULT true,
+    types_enabled JSONB NOT NULL DEFAULT '{"order_update": true, "low_stock": true, "payment": true, "system_alert": true, "daily_report": true}'::jsonb,
+    quiet_hours_start TIME,
+    quiet_hours_end TIME,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 'email_enabled', 'quiet_hours' or a notification type
+ALTER TABLE notification_preferences
+ADD COLUMN IF NOT EXISTS overridden_fields TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN notification_preferences.overridden_fields IS 'Preferences the user changed themselves; applying role defaults skips them unless forced';
@@ -0,0 +1,12 @@
+-- Migration: Signed table QR codes
+-- Feature: table-qr
+-- Date: 2026-10-14
+-- Description: Table QR codes become signed tokens carrying the table and a code version; rotating a table's code bumps the version, which invalidates every earlier code. Existing unsigned codes stop working until codes are generated (POST /admin/tables/qr)
+
+ALTER TABLE dining_tables ALTER COLUMN qr_code TYPE VARCHAR(100);
+
+ALTER TABLE dining_tables
+ADD COLUMN IF NOT EXISTS qr_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE dining_tables
+ADD COLUMN IF NOT EXISTS qr_rotated_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,53 @@
+-- Migration: Managed payment methods
+-- Feature: payment-methods
+-- Date: 2026-10-14
+-- Description: Payment methods become a managed list with display names, a surcharge percentage, an active flag and whether guests can choose them, replacing the fixed CHECK on payments; payments record the surcharge charged on top of the amount applied to the order
+
+CREATE TABLE IF NOT EXISTS payment_methods (
+    code VARCHAR(20) PRIMARY KEY CHECK (code ~ '^[a-z][a-z0-9_]{1,19}$'),
+    display_name VARCHAR(50) NOT NULL,
+    surcharge_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (surcharge_percent >= 0 AND surcharge_percent <= 100),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    customer_selectable BOOLEAN NOT NULL DEFAULT false,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    is_system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS set_payment_methods_updated_at ON payment_methods;
+CREATE TRIGGER set_payment_methods_updated_at
+    BEFORE UPDATE ON payment_methods
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO payment_methods (code, display_name, customer_selectable, sort_order, is_system) VALUES
+    ('cash', 'Tunai', true, 10, true),
+    ('credit_card', 'Kartu Kredit', true, 20, true),
+    ('debit_card', 'Kartu Debit', true, 30, true),
+    ('digital_wallet', 'Dompet Digital', true, 40, true),
+    ('corporate_wallet', 'Dompet Korporat', false, 50, true),
+    ('on_account', 'Tagihan Korporat', false, 60, true)
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'payment_methods.manage'),
+('manager', 'payment_methods.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_payment_method_check;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_payment_method_fkey;
+ALTER TABLE payments
+ADD CONSTRAINT payments_payment_method_fkey
+FOREIGN KEY (payment_method) REFERENCES payment_methods(code) ON UPDATE CASCADE;
+
+ALTER TABLE payment_batches DROP CONSTRAINT IF EXISTS payment_batches_payment_method_fkey;
+ALTER TABLE payment_batches
+ADD CONSTRAINT payment_batches_payment_method_fkey
+FOREIGN KEY (payment_method) REFERENCES payment_methods(code) ON UPDATE CASCADE;
+
+ALTER TABLE payments
+ADD COLUMN IF NOT EXISTS surcharge_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN payments.surcharge_amount IS 'Payment method surcharge charged on top of amount; not applied to the order balance';
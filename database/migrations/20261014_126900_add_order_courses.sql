@@ -0,0 +1,27 @@
+-- Migration: Course firing
+-- Feature: order-courses
+-- Date: 2026-10-14
+-- Description: Dine-in order items carry a course number; courses after the first (or every course, for a held order) stay off the kitchen display until a server fires them, and each course records when it was fired for per-course timing
+
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS course SMALLINT NOT NULL DEFAULT 1 CHECK (course BETWEEN 1 AND 9);
+
+-- One row per course of a coursed order; fired_at is NULL while the course is held
+CREATE TABLE IF NOT EXISTS order_courses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    course SMALLINT NOT NULL CHECK (course BETWEEN 1 AND 9),
+    fired_at TIMESTAMP WITH TIME ZONE,
+    fired_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_courses_order_course ON order_courses(order_id, course);
+
+COMMENT ON TABLE order_courses IS 'Courses of a dine-in order; items of a course reach the kitchen when it is fired';
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'orders.fire_courses'),
+('manager', 'orders.fire_courses'),
+('server', 'orders.fire_courses')
+ON CONFLICT (role, permission) DO NOTHING;
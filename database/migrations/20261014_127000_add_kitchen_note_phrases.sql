@@ -0,0 +1,38 @@
+-- Migration: Kitchen note quick phrases
+-- Feature: kitchen-note-phrases
+-- Date: 2026-10-14
+-- Description: Admin-managed quick phrases ("no onion", "extra spicy") that the POS and customer app offer as one-tap item instructions, optionally limited to a category; order items store the chosen phrase codes so the kitchen display can filter on them
+
+CREATE TABLE IF NOT EXISTS kitchen_note_phrases (
+    code VARCHAR(40) PRIMARY KEY CHECK (code ~ '^[a-z][a-z0-9_]{1,39}$'),
+    label VARCHAR(60) NOT NULL,
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    customer_visible BOOLEAN NOT NULL DEFAULT true,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_kitchen_note_phrases_category ON kitchen_note_phrases(category_id);
+
+DROP TRIGGER IF EXISTS set_kitchen_note_phrases_updated_at ON kitchen_note_phrases;
+CREATE TRIGGER set_kitchen_note_phrases_updated_at
+    BEFORE UPDATE ON kitchen_note_phrases
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO kitchen_note_phrases (code, label, sort_order) VALUES
+    ('no_onion', 'Tanpa bawang', 10),
+    ('no_garlic', 'Tanpa bawang putih', 20),
+    ('extra_spicy', 'Ekstra pedas', 30),
+    ('not_spicy', 'Tidak pedas', 40),
+    ('sauce_on_side', 'Saus dipisah', 50)
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS note_phrases TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_order_items_note_phrases ON order_items USING GIN (note_phrases);
+
+COMMENT ON COLUMN order_items.note_phrases IS 'Codes of the kitchen_note_phrases picked for the item, alongside any free-text special_instructions';
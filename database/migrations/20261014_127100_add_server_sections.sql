@@ -0,0 +1,62 @@
+-- Migration: Server sections
+-- Feature: server-sections
+-- Date: 2026-10-14
+-- Description: Named sections of dining tables assigned to servers for a shift; servers without orders.view_all_sections only see their section's orders and their own. Orders record the server they are credited to and the number of covers, for the per-server sales report
+
+CREATE TABLE IF NOT EXISTS server_sections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    branch_id UUID NOT NULL REFERENCES branches(id),
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_server_sections_branch_name ON server_sections(branch_id, name);
+
+DROP TRIGGER IF EXISTS set_server_sections_updated_at ON server_sections;
+CREATE TRIGGER set_server_sections_updated_at
+    BEFORE UPDATE ON server_sections
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE dining_tables
+ADD COLUMN IF NOT EXISTS section_id UUID REFERENCES server_sections(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_dining_tables_section ON dining_tables(section_id);
+
+-- A server's shift in a section; several servers can share one
+CREATE TABLE IF NOT EXISTS section_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    section_id UUID NOT NULL REFERENCES server_sections(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_section_assignments_user ON section_assignments(user_id, starts_at, ends_at);
+CREATE INDEX IF NOT EXISTS idx_section_assignments_section ON section_assignments(section_id, starts_at, ends_at);
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS server_id UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS covers INTEGER CHECK (covers BETWEEN 1 AND 100);
+
+CREATE INDEX IF NOT EXISTS idx_orders_server_created ON orders(server_id, created_at) WHERE server_id IS NOT NULL;
+
+COMMENT ON COLUMN orders.server_id IS 'Staff member credited with the order: the server of the table''s section when it was placed, otherwise whoever rang it in';
+COMMENT ON COLUMN orders.covers IS 'Guests served by the order, when the server entered it';
+
+-- Existing orders are credited to whoever rang them in
+UPDATE orders SET server_id = user_id WHERE server_id IS NULL AND user_id IS NOT NULL;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'sections.manage'),
+('manager', 'sections.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- Every role keeps seeing all orders except servers, who see their section
+INSERT INTO role_permissions (role, permission)
+SELECT name, 'orders.view_all_sections' FROM roles WHERE name <> 'server'
+ON CONFLICT (role, permission) DO NOTHING;
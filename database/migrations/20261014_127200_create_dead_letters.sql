@@ -0,0 +1,46 @@
+-- Migration: Dead letters
+-- Feature: dead-letters
+-- Date: 2026-10-14
+-- Description: Keeps background jobs that ran out of attempts (emails, webhook deliveries, inbound events, alerts) with their failure reasons, so an admin can inspect them and replay them once the downstream problem is fixed
+
+-- Every failed attempt, not just the last one: [{ attempt, error, at }]
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS errors JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- One per job; a replayed job that fails again reopens its row
+    job_id UUID UNIQUE REFERENCES jobs(id) ON DELETE SET NULL,
+    job_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    max_attempts INTEGER NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    errors JSONB NOT NULL DEFAULT '[]',
+    -- open until replayed or discarded
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'replayed', 'discarded')),
+    failure_count INTEGER NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    replay_count INTEGER NOT NULL DEFAULT 0,
+    replayed_at TIMESTAMP WITH TIME ZONE,
+    replayed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    discarded_at TIMESTAMP WITH TIME ZONE,
+    discarded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters(status, last_failed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_type ON dead_letters(job_type, last_failed_at DESC);
+
+-- Jobs that had already failed before this migration
+INSERT INTO dead_letters (job_id, job_type, payload, max_attempts, attempts, last_error, first_failed_at, last_failed_at)
+SELECT id, type, payload, max_attempts, attempts, last_error,
+       COALESCE(completed_at, updated_at, NOW()), COALESCE(completed_at, updated_at, NOW())
+FROM jobs
+WHERE status = 'failed'
+ON CONFLICT (job_id) DO NOTHING;
+
+COMMENT ON TABLE dead_letters IS 'Background jobs that ran out of attempts, for inspection and replay';
@@ -0,0 +1,16 @@
+-- Migration: Payment tips
+-- Feature: tips
+-- Date: 2026-10-14
+-- Description: Tips recorded on payments, on top of what the payment settles on the order, credited to the order's server for the daily tip-out report; tip_suggestions offers percentages at the counter
+
+-- Paid on top of amount, like surcharge_amount; never part of sales
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS tip_amount DECIMAL(10,2) NOT NULL DEFAULT 0
+    CHECK (tip_amount >= 0);
+-- Who the tip is paid out to: the order's server, otherwise the cashier
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS tip_recipient_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_tips ON payments(processed_at, tip_recipient_id) WHERE tip_amount > 0;
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('tip_suggestions', '5,10,15', 'string', 'Tip percentages offered when taking a payment, comma-separated (e.g. 5,10,15); empty offers none', 'financial')
+ON CONFLICT (setting_key) DO NOTHING;
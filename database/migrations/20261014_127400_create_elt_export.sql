@@ -0,0 +1,71 @@
+-- Migration: Analytics export pipeline
+-- Feature: elt-export
+-- Date: 2026-10-14
+-- Description: Incremental exports of changed orders, order items, payments and stock movements as date-partitioned CSV files to object storage for the analytics warehouse; elt_watermarks keeps each stream's position so an export resumes where the last one stopped
+
+-- Payments change after they are taken (gateway confirmation, tips)
+ALTER TABLE payments
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Existing payments last changed when they were taken
+UPDATE payments SET updated_at = COALESCE(processed_at, created_at);
+
+DROP TRIGGER IF EXISTS set_payments_updated_at ON payments;
+CREATE TRIGGER set_payments_updated_at
+    BEFORE UPDATE ON payments
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_orders_updated_id ON orders(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_order_items_updated_id ON order_items(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_payments_updated_id ON payments(updated_at, id);
+
+CREATE TABLE IF NOT EXISTS elt_watermarks (
+    stream VARCHAR(40) PRIMARY KEY,
+    -- Last row exported, as (change time, id); NULL exports from the start
+    watermark_at TIMESTAMP WITH TIME ZONE,
+    watermark_id UUID,
+    rows_exported BIGINT NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_status VARCHAR(20) CHECK (last_status IN ('succeeded', 'failed')),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS set_elt_watermarks_updated_at ON elt_watermarks;
+CREATE TRIGGER set_elt_watermarks_updated_at
+    BEFORE UPDATE ON elt_watermarks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO elt_watermarks (stream) VALUES
+    ('orders'),
+    ('order_items'),
+    ('payments'),
+    ('inventory_movements')
+ON CONFLICT (stream) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS elt_export_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream VARCHAR(40) NOT NULL REFERENCES elt_watermarks(stream) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    from_at TIMESTAMP WITH TIME ZONE,
+    from_id UUID,
+    to_at TIMESTAMP WITH TIME ZONE,
+    to_id UUID,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    -- Object keys written, with their partition and row count
+    files JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_elt_export_runs_stream_started ON elt_export_runs(stream, started_at DESC);
+
+COMMENT ON TABLE elt_watermarks IS 'Position of each analytics export stream; rewinding watermark_at re-exports everything changed since';
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'analytics.export')
+ON CONFLICT (role, permission) DO NOTHING;
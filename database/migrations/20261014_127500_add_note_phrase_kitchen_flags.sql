@@ -0,0 +1,13 @@
+-- Migration: Kitchen flags on note phrases
+-- Feature: kitchen-note-phrases
+-- Date: 2026-10-14
+-- Description: A quick phrase can carry a kitchen flag: allergy items are shown as allergy alerts on the kitchen display and their tickets marked, attention items are highlighted; adds an "allergy alert" phrase
+
+ALTER TABLE kitchen_note_phrases
+ADD COLUMN IF NOT EXISTS kitchen_flag VARCHAR(20) CHECK (kitchen_flag IN ('allergy', 'attention'));
+
+COMMENT ON COLUMN kitchen_note_phrases.kitchen_flag IS 'How the kitchen display marks items given the phrase: allergy (alert) or attention (highlight); NULL for none';
+
+INSERT INTO kitchen_note_phrases (code, label, kitchen_flag, sort_order) VALUES
+    ('allergy_alert', 'Alergi - konfirmasi ke pelayan', 'allergy', 5)
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,30 @@
+-- Migration: Survey follow-up
+-- Feature: satisfaction-surveys
+-- Date: 2026-10-14
+-- Description: Admins work through submitted surveys: acknowledge them with an internal note or email the guest a reply (sent replies stay in email_outbox); adds the surveys.manage permission
+
+ALTER TABLE satisfaction_surveys
+ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'new' CHECK (status IN ('new', 'acknowledged', 'replied')),
+ADD COLUMN IF NOT EXISTS staff_note TEXT,
+ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS replied_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS replied_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE satisfaction_surveys SET updated_at = COALESCE(submitted_at, created_at);
+
+DROP TRIGGER IF EXISTS update_satisfaction_surveys_updated_at ON satisfaction_surveys;
+CREATE TRIGGER update_satisfaction_surveys_updated_at BEFORE UPDATE ON satisfaction_surveys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- The admin list's default: open surveys, newest first
+CREATE INDEX IF NOT EXISTS idx_satisfaction_surveys_status ON satisfaction_surveys(status, submitted_at DESC);
+
+COMMENT ON COLUMN satisfaction_surveys.status IS 'new until an admin acknowledges the survey or replies to the guest';
+COMMENT ON COLUMN satisfaction_surveys.staff_note IS 'Internal note from follow-up; never shown to the guest';
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'surveys.manage'),
+('manager', 'surveys.manage')
+ON CONFLICT (role, permission) DO NOTHING;
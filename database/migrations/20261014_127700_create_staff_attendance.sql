@@ -0,0 +1,83 @@
+-- Migration: Staff attendance
+-- Feature: attendance
+-- Date: 2026-10-14
+-- Description: Staff clock in and out (optionally with a PIN and only on terminals enabled for it); managers schedule shifts, correct entries and export timesheets with overtime against the schedule for payroll
+
+-- bcrypt hash of the staff member's clock-in PIN, set by a manager, and
+-- the wrong-PIN lockout (like failed_login_attempts / locked_until)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS attendance_pin_hash VARCHAR(255),
+ADD COLUMN IF NOT EXISTS attendance_pin_failed_attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS attendance_pin_locked_until TIMESTAMP WITH TIME ZONE;
+
+-- POS terminals a manager allows staff to clock in and out on
+ALTER TABLE device_print_preferences
+ADD COLUMN IF NOT EXISTS attendance_clock BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS staff_shifts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    branch_id UUID NOT NULL REFERENCES branches(id),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    break_minutes INTEGER NOT NULL DEFAULT 0 CHECK (break_minutes >= 0),
+    note VARCHAR(200),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_staff_shifts_user ON staff_shifts(user_id, starts_at, ends_at);
+CREATE INDEX IF NOT EXISTS idx_staff_shifts_branch ON staff_shifts(branch_id, starts_at);
+
+DROP TRIGGER IF EXISTS update_staff_shifts_updated_at ON staff_shifts;
+CREATE TRIGGER update_staff_shifts_updated_at BEFORE UPDATE ON staff_shifts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS attendance (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    branch_id UUID REFERENCES branches(id),
+    shift_id UUID REFERENCES staff_shifts(id) ON DELETE SET NULL,
+    clock_in_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    clock_out_at TIMESTAMP WITH TIME ZONE,
+    clock_in_device_id VARCHAR(64),
+    clock_out_device_id VARCHAR(64),
+    note VARCHAR(500),
+    adjusted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    adjusted_at TIMESTAMP WITH TIME ZONE,
+    adjust_reason VARCHAR(500),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (clock_out_at IS NULL OR clock_out_at > clock_in_at)
+);
+
+-- At most one open entry per staff member
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_open ON attendance(user_id) WHERE clock_out_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_attendance_user_clock_in ON attendance(user_id, clock_in_at);
+CREATE INDEX IF NOT EXISTS idx_attendance_branch_clock_in ON attendance(branch_id, clock_in_at);
+
+DROP TRIGGER IF EXISTS update_attendance_updated_at ON attendance;
+CREATE TRIGGER update_attendance_updated_at BEFORE UPDATE ON attendance
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON COLUMN attendance.shift_id IS 'The scheduled shift the entry was matched to at clock-in; NULL for unscheduled work';
+COMMENT ON COLUMN attendance.adjust_reason IS 'Why a manager last corrected the entry';
+
+INSERT INTO system_settings (setting_key, setting_value, setting_type, description, category) VALUES
+('attendance_require_pin', 'false', 'boolean', 'Staff enter their attendance PIN to clock in and out', 'staff'),
+('attendance_require_terminal', 'false', 'boolean', 'Staff can only clock in and out on a POS terminal of their branch enabled for attendance', 'staff'),
+('attendance_overtime_grace_minutes', '15', 'number', 'Minutes worked past a scheduled shift before they count as overtime', 'staff')
+ON CONFLICT (setting_key) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'attendance.clock'),
+('manager', 'attendance.clock'),
+('server', 'attendance.clock'),
+('counter', 'attendance.clock'),
+('kitchen', 'attendance.clock'),
+('courier', 'attendance.clock'),
+('admin', 'attendance.manage'),
+('manager', 'attendance.manage')
+ON CONFLICT (role, permission) DO NOTHING;
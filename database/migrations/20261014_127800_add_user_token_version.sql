@@ -0,0 +1,6 @@
+-- Migration: Session revocation
+-- Feature: password-reset
+-- Date: 2026-10-14
+-- Description: A per-user token version carried in the JWT; bumping it (password reset or change, role or branch change) ends every session issued before
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Migration: Reusable names after soft delete
+-- Feature: soft-delete
+-- Date: 2026-10-14
+-- Description: Usernames, user emails and branch table numbers are unique among live rows only, so a deleted user's or table's name can be reused
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_live ON users(username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_live ON users(email) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_dining_tables_branch_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dining_tables_branch_number ON dining_tables(branch_id, table_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+-- Revert: 20261014_120100_create_corporate_wallets.sql
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_payment_method_check;
+ALTER TABLE payments ADD CONSTRAINT payments_payment_method_check
+CHECK (payment_method IN ('cash', 'credit_card', 'debit_card', 'digital_wallet'));
+DROP TABLE IF EXISTS corporate_wallet_transactions;
+DROP TABLE IF EXISTS corporate_employees;
+DROP TABLE IF EXISTS corporate_accounts;
@@ -0,0 +1,11 @@
+-- Revert: 20261014_120200_create_corporate_invoices.sql
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_payment_method_check;
+ALTER TABLE payments ADD CONSTRAINT payments_payment_method_check
+CHECK (payment_method IN ('cash', 'credit_card', 'debit_card', 'digital_wallet', 'corporate_wallet'));
+DROP TABLE IF EXISTS corporate_invoice_payments;
+DROP TABLE IF EXISTS corporate_account_charges;
+DROP TABLE IF EXISTS corporate_invoices;
+ALTER TABLE corporate_accounts
+DROP COLUMN IF EXISTS payment_terms_days,
+DROP COLUMN IF EXISTS credit_limit,
+DROP COLUMN IF EXISTS on_account_enabled;
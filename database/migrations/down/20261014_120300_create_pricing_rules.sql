@@ -0,0 +1,3 @@
+-- Revert: 20261014_120300_create_pricing_rules.sql
+DROP TABLE IF EXISTS order_pricing_adjustments;
+DROP TABLE IF EXISTS pricing_rules;
@@ -0,0 +1,10 @@
+-- Revert: 20261014_120400_add_soft_delete_columns.sql
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_dining_tables_deleted_at;
+DROP INDEX IF EXISTS idx_categories_deleted_at;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE dining_tables DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_120500_add_order_id_to_inventory_history.sql
+DROP INDEX IF EXISTS idx_inventory_history_order_id;
+ALTER TABLE inventory_history DROP COLUMN IF EXISTS order_id;
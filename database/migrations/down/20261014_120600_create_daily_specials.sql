@@ -0,0 +1,4 @@
+-- Revert: 20261014_120600_create_daily_specials.sql
+DROP TABLE IF EXISTS daily_special_sales;
+DROP TABLE IF EXISTS daily_specials;
+DROP TABLE IF EXISTS scheduled_job_runs;
@@ -0,0 +1,18 @@
+-- Revert: 20261014_120700_add_payment_refunds.sql
+-- Keep refund ledger entries as adjustments so balances still reconcile
+UPDATE corporate_wallet_transactions SET transaction_type = 'adjustment' WHERE transaction_type = 'refund';
+
+ALTER TABLE corporate_wallet_transactions
+DROP CONSTRAINT IF EXISTS corporate_wallet_transactions_transaction_type_check;
+
+ALTER TABLE corporate_wallet_transactions
+ADD CONSTRAINT corporate_wallet_transactions_transaction_type_check
+CHECK (transaction_type IN ('topup', 'redeem', 'adjustment'));
+
+DROP INDEX IF EXISTS idx_payments_refund_of;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_payments_refund;
+ALTER TABLE payments
+DROP COLUMN IF EXISTS approved_by,
+DROP COLUMN IF EXISTS refund_notes,
+DROP COLUMN IF EXISTS refund_reason,
+DROP COLUMN IF EXISTS refund_of;
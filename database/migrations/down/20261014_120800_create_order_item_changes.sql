@@ -0,0 +1,2 @@
+-- Revert: 20261014_120800_create_order_item_changes.sql
+DROP TABLE IF EXISTS order_item_changes;
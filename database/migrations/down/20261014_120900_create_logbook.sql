@@ -0,0 +1,3 @@
+-- Revert: 20261014_120900_create_logbook.sql
+DELETE FROM system_settings WHERE setting_key = 'logbook_digest_recipients';
+DROP TABLE IF EXISTS logbook_entries;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_121000_create_sales_targets.sql
+DROP TABLE IF EXISTS sales_targets;
@@ -0,0 +1,16 @@
+-- Revert: 20261014_121100_add_order_scheduling.sql
+DELETE FROM system_settings WHERE setting_key IN ('scheduled_order_lead_minutes', 'scheduled_order_max_days');
+
+-- Orders still waiting go straight to the kitchen
+UPDATE orders SET status = 'pending' WHERE status = 'scheduled';
+
+DROP INDEX IF EXISTS idx_orders_scheduled_at;
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_status_check
+CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'));
+
+ALTER TABLE orders DROP COLUMN IF EXISTS scheduled_at;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_121200_create_commission_rules.sql
+DROP TABLE IF EXISTS commission_rules;
@@ -0,0 +1,25 @@
+-- Revert: 20261014_121300_add_delivery_orders.sql
+DELETE FROM system_settings WHERE setting_key IN ('delivery_fee', 'delivery_free_threshold', 'delivery_min_order');
+
+DROP INDEX IF EXISTS idx_orders_courier_active;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_delivery;
+ALTER TABLE orders
+DROP COLUMN IF EXISTS delivered_at,
+DROP COLUMN IF EXISTS picked_up_at,
+DROP COLUMN IF EXISTS courier_assigned_at,
+DROP COLUMN IF EXISTS courier_id,
+DROP COLUMN IF EXISTS delivery_status,
+DROP COLUMN IF EXISTS delivery_fee,
+DROP COLUMN IF EXISTS delivery_notes,
+DROP COLUMN IF EXISTS delivery_phone,
+DROP COLUMN IF EXISTS delivery_address;
+
+-- Courier accounts can't survive the narrower role check
+UPDATE users SET is_active = false, role = 'server' WHERE role = 'courier';
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check
+CHECK (role IN ('admin', 'manager', 'server', 'counter', 'kitchen'));
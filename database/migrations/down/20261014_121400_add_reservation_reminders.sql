@@ -0,0 +1,12 @@
+-- Revert: 20261014_121400_add_reservation_reminders.sql
+DELETE FROM system_settings WHERE setting_key IN ('reservation_reminder_hours', 'reservation_no_show_grace_minutes');
+
+DROP INDEX IF EXISTS idx_reservations_phone;
+DROP INDEX IF EXISTS idx_reservations_open;
+DROP INDEX IF EXISTS idx_reservations_response_token;
+ALTER TABLE reservations
+DROP COLUMN IF EXISTS customer_responded_at,
+DROP COLUMN IF EXISTS reminder_error,
+DROP COLUMN IF EXISTS reminder_sent_at,
+DROP COLUMN IF EXISTS response_token,
+DROP COLUMN IF EXISTS table_id;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_121500_create_customer_flags.sql
+DROP TABLE IF EXISTS customer_flag_events;
+DROP TABLE IF EXISTS customer_flags;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_121600_add_kitchen_capacity_setting.sql
+DELETE FROM system_settings WHERE setting_key = 'kitchen_parallel_orders';
@@ -0,0 +1,3 @@
+-- Revert: 20261014_121700_add_order_auto_complete_setting.sql
+DROP INDEX IF EXISTS idx_orders_served_at;
+DELETE FROM system_settings WHERE setting_key = 'order_auto_complete_minutes';
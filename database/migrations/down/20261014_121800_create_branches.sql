@@ -0,0 +1,25 @@
+-- Revert: 20261014_121800_create_branches.sql
+DROP TABLE IF EXISTS branch_settings;
+
+-- One inventory row per product again: other branches' rows are dropped
+-- where the main branch already tracks the product
+ALTER TABLE inventory_history DROP COLUMN IF EXISTS branch_id;
+
+DROP INDEX IF EXISTS idx_inventory_branch_product;
+DELETE FROM inventory i
+USING branches b
+WHERE b.id = i.branch_id AND NOT b.is_default
+  AND EXISTS (SELECT 1 FROM inventory d JOIN branches db ON db.id = d.branch_id AND db.is_default WHERE d.product_id = i.product_id);
+ALTER TABLE inventory DROP COLUMN IF EXISTS branch_id;
+
+DROP INDEX IF EXISTS idx_orders_branch_created;
+ALTER TABLE orders DROP COLUMN IF EXISTS branch_id;
+
+-- Fails if table numbers are reused across branches; renumber those first
+DROP INDEX IF EXISTS idx_dining_tables_branch_number;
+ALTER TABLE dining_tables ADD CONSTRAINT dining_tables_table_number_key UNIQUE (table_number);
+ALTER TABLE dining_tables DROP COLUMN IF EXISTS branch_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS branch_id;
+
+DROP TABLE IF EXISTS branches;
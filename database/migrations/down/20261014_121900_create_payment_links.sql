@@ -0,0 +1,3 @@
+-- Revert: 20261014_121900_create_payment_links.sql
+DELETE FROM system_settings WHERE setting_key = 'payment_link_expiry_minutes';
+DROP TABLE IF EXISTS payment_links;
@@ -0,0 +1,10 @@
+-- Revert: 20261014_122000_create_roles_permissions.sql
+-- Fails while users hold a custom role; move them to a built-in role first
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_role;
+ALTER TABLE users
+ADD CONSTRAINT users_role_check
+CHECK (role IN ('admin', 'manager', 'server', 'counter', 'kitchen', 'courier'));
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TRIGGER IF EXISTS set_roles_updated_at ON roles;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_122100_create_jobs.sql
+DELETE FROM role_permissions WHERE permission = 'jobs.manage';
+DROP TABLE IF EXISTS jobs;
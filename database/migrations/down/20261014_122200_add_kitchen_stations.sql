@@ -0,0 +1,6 @@
+-- Revert: 20261014_122200_add_kitchen_stations.sql
+DELETE FROM system_settings WHERE setting_key IN ('customer_order_acceptance_required', 'auto_release_stations');
+DROP INDEX IF EXISTS idx_order_items_held;
+ALTER TABLE order_items DROP COLUMN IF EXISTS released_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS auto_release;
+ALTER TABLE categories DROP COLUMN IF EXISTS station;
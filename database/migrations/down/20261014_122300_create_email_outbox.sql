@@ -0,0 +1,7 @@
+-- Revert: 20261014_122300_create_email_outbox.sql
+DELETE FROM role_permissions WHERE permission = 'email.manage';
+DELETE FROM system_settings WHERE setting_key IN (
+    'smtp_host', 'smtp_port', 'smtp_secure', 'smtp_user', 'smtp_password', 'smtp_from',
+    'daily_sales_summary_recipients', 'low_stock_digest_recipients'
+);
+DROP TABLE IF EXISTS email_outbox;
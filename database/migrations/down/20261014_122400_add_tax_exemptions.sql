@@ -0,0 +1,9 @@
+-- Revert: 20261014_122400_add_tax_exemptions.sql
+DELETE FROM role_permissions WHERE permission = 'tax.manage';
+DELETE FROM system_settings WHERE setting_key = 'service_charge_order_types';
+ALTER TABLE order_items DROP COLUMN IF EXISTS service_exempt;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_exempt;
+ALTER TABLE order_items DROP COLUMN IF EXISTS service_charge_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS service_charge_amount;
+DROP TABLE IF EXISTS tax_exemptions;
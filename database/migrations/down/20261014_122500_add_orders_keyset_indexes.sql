@@ -0,0 +1,6 @@
+-- Revert: 20261014_122500_add_orders_keyset_indexes.sql
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_branch_created ON orders(branch_id, created_at);
+DROP INDEX IF EXISTS idx_orders_status_created_id;
+DROP INDEX IF EXISTS idx_orders_branch_created_id;
+DROP INDEX IF EXISTS idx_orders_created_id;
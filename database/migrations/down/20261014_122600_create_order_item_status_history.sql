@@ -0,0 +1,2 @@
+-- Revert: 20261014_122600_create_order_item_status_history.sql
+DROP TABLE IF EXISTS order_item_status_history;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_122700_add_product_search.sql
+DROP INDEX IF EXISTS idx_products_search_vector;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_122800_create_device_print_preferences.sql
+DROP TABLE IF EXISTS device_print_preferences;
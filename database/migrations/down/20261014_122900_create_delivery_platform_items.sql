@@ -0,0 +1,2 @@
+-- Revert: 20261014_122900_create_delivery_platform_items.sql
+DROP TABLE IF EXISTS delivery_platform_items;
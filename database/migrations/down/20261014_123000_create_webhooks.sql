@@ -0,0 +1,3 @@
+-- Revert: 20261014_123000_create_webhooks.sql
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_123100_add_accounting_export.sql
+DELETE FROM role_permissions WHERE permission = 'accounting.export';
+DELETE FROM system_settings WHERE setting_key = 'accounting_accounts';
@@ -0,0 +1,5 @@
+-- Revert: 20261014_123200_create_container_deposits.sql
+DROP TABLE IF EXISTS container_returns;
+DROP TABLE IF EXISTS order_container_deposits;
+ALTER TABLE orders DROP COLUMN IF EXISTS deposit_amount;
+DROP TABLE IF EXISTS container_types;
@@ -0,0 +1,5 @@
+-- Revert: 20261014_123300_create_order_source_details.sql
+DELETE FROM system_settings WHERE setting_key = 'qr_order_max_distance_meters';
+DROP TABLE IF EXISTS order_source_details;
+ALTER TABLE branches DROP COLUMN IF EXISTS longitude;
+ALTER TABLE branches DROP COLUMN IF EXISTS latitude;
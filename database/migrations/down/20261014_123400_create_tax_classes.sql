@@ -0,0 +1,7 @@
+-- Revert: 20261014_123400_create_tax_classes.sql
+UPDATE system_settings SET setting_value = '' WHERE setting_key = 'service_charge_order_types' AND setting_value = 'dine_in';
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_label;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_class_id;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class_id;
+DROP TABLE IF EXISTS tax_classes;
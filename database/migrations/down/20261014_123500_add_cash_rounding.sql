@@ -0,0 +1,3 @@
+-- Revert: 20261014_123500_add_cash_rounding.sql
+DELETE FROM system_settings WHERE setting_key IN ('cash_rounding_increment', 'cash_rounding_mode');
+ALTER TABLE payments DROP COLUMN IF EXISTS rounding_adjustment;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_123600_create_gateway_refunds.sql
+DROP TABLE IF EXISTS gateway_refunds;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_123700_create_order_sla.sql
+DELETE FROM system_settings WHERE setting_key IN ('order_sla_targets', 'order_sla_dayparts', 'order_sla_breach_notifications');
+DROP TABLE IF EXISTS order_sla_breaches;
@@ -0,0 +1,5 @@
+-- Revert: 20261014_123800_create_currencies.sql
+DELETE FROM role_permissions WHERE permission = 'currencies.manage';
+ALTER TABLE orders DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE orders DROP COLUMN IF EXISTS display_currency;
+DROP TABLE IF EXISTS currencies;
@@ -0,0 +1,4 @@
+-- Revert: 20261014_123900_add_product_costing.sql
+DELETE FROM role_permissions WHERE permission = 'costing.manage';
+DROP TABLE IF EXISTS cogs_adjustments;
+ALTER TABLE products DROP COLUMN IF EXISTS cost_override;
@@ -0,0 +1,14 @@
+-- Revert: 20261014_124000_create_stock_takes.sql
+DELETE FROM role_permissions WHERE permission = 'stock_takes.post';
+
+ALTER TABLE ingredient_history
+DROP CONSTRAINT IF EXISTS ingredient_history_operation_check;
+
+-- NOT VALID keeps stock count rows already recorded
+ALTER TABLE ingredient_history
+ADD CONSTRAINT ingredient_history_operation_check
+CHECK (operation IN ('add', 'remove', 'restock', 'usage', 'spoilage',
+                     'adjustment', 'order_consumption', 'order_cancellation')) NOT VALID;
+
+DROP TABLE IF EXISTS stock_take_lines;
+DROP TABLE IF EXISTS stock_takes;
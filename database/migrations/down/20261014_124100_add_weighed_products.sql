@@ -0,0 +1,7 @@
+-- Revert: 20261014_124100_add_weighed_products.sql
+ALTER TABLE order_item_changes ALTER COLUMN new_quantity TYPE INTEGER USING CEIL(new_quantity);
+ALTER TABLE order_item_changes ALTER COLUMN previous_quantity TYPE INTEGER USING CEIL(previous_quantity);
+ALTER TABLE order_items DROP COLUMN IF EXISTS scale_device;
+ALTER TABLE order_items DROP COLUMN IF EXISTS weight_grams;
+ALTER TABLE order_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+ALTER TABLE products DROP COLUMN IF EXISTS sale_unit;
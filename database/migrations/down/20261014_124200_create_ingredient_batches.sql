@@ -0,0 +1,4 @@
+-- Revert: 20261014_124200_create_ingredient_batches.sql
+DELETE FROM system_settings WHERE setting_key = 'ingredient_expiry_warning_days';
+DROP TABLE IF EXISTS ingredient_batch_usage;
+DROP TABLE IF EXISTS ingredient_batches;
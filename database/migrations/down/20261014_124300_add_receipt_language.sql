@@ -0,0 +1,4 @@
+-- Revert: 20261014_124300_add_receipt_language.sql
+DELETE FROM system_settings WHERE setting_key = 'receipt_language';
+DROP TABLE IF EXISTS customer_receipt_preferences;
+ALTER TABLE orders DROP COLUMN IF EXISTS receipt_language;
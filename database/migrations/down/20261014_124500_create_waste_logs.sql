@@ -0,0 +1,23 @@
+-- Revert: 20261014_124500_create_waste_logs.sql
+DELETE FROM role_permissions WHERE permission = 'kitchen.waste';
+
+ALTER TABLE ingredient_history
+DROP CONSTRAINT IF EXISTS ingredient_history_operation_check;
+
+-- NOT VALID keeps waste rows already recorded
+ALTER TABLE ingredient_history
+ADD CONSTRAINT ingredient_history_operation_check
+CHECK (operation IN ('add', 'remove', 'restock', 'usage', 'spoilage',
+                     'adjustment', 'order_consumption', 'order_cancellation',
+                     'count_gain', 'count_loss')) NOT VALID;
+
+ALTER TABLE inventory_history
+DROP CONSTRAINT IF EXISTS inventory_history_reason_check;
+
+ALTER TABLE inventory_history
+ADD CONSTRAINT inventory_history_reason_check
+CHECK (reason IN ('purchase', 'sale', 'spoilage', 'manual_adjustment', 'inventory_count', 'return', 'damage', 'theft', 'expired')) NOT VALID;
+
+ALTER TABLE ingredient_history DROP COLUMN IF EXISTS waste_log_id;
+ALTER TABLE inventory_history DROP COLUMN IF EXISTS waste_log_id;
+DROP TABLE IF EXISTS waste_logs;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_124600_add_stock_availability.sql
+ALTER TABLE products DROP COLUMN IF EXISTS stock_unavailable_at;
+ALTER TABLE products DROP COLUMN IF EXISTS availability_override;
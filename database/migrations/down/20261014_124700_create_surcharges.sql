@@ -0,0 +1,4 @@
+-- Revert: 20261014_124700_create_surcharges.sql
+ALTER TABLE orders DROP COLUMN IF EXISTS surcharge_amount;
+DROP TABLE IF EXISTS order_surcharges;
+DROP TABLE IF EXISTS surcharges;
@@ -0,0 +1,5 @@
+-- Revert: 20261014_124800_create_eighty_six.sql
+DELETE FROM role_permissions WHERE permission = 'kitchen.eighty_six';
+UPDATE products SET is_available = true WHERE eighty_sixed_at IS NOT NULL;
+DROP TABLE IF EXISTS eighty_six_events;
+ALTER TABLE products DROP COLUMN IF EXISTS eighty_sixed_at;
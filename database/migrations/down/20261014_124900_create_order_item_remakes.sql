@@ -0,0 +1,4 @@
+-- Revert: 20261014_124900_create_order_item_remakes.sql
+DELETE FROM role_permissions WHERE permission = 'orders.remake';
+DROP TABLE IF EXISTS order_item_remakes;
+ALTER TABLE order_items DROP COLUMN IF EXISTS is_remake;
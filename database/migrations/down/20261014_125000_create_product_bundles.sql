@@ -0,0 +1,2 @@
+-- Revert: 20261014_125000_create_product_bundles.sql
+DROP TABLE IF EXISTS product_bundle_components;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_125100_create_price_schedules.sql
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_schedule_id;
+DROP TABLE IF EXISTS price_schedules;
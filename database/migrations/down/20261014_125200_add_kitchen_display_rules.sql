@@ -0,0 +1,5 @@
+-- Revert: 20261014_125200_add_kitchen_display_rules.sql
+ALTER TABLE categories DROP COLUMN IF EXISTS kds_group;
+ALTER TABLE categories DROP COLUMN IF EXISTS kds_priority;
+ALTER TABLE categories DROP COLUMN IF EXISTS kds_color;
+DROP TABLE IF EXISTS kitchen_station_display;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_125300_add_selftest_permission.sql
+DELETE FROM role_permissions WHERE permission = 'system.selftest';
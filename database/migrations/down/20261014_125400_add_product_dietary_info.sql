@@ -0,0 +1,4 @@
+-- Revert: 20261014_125400_add_product_dietary_info.sql
+ALTER TABLE products DROP COLUMN IF EXISTS spicy_level;
+ALTER TABLE products DROP COLUMN IF EXISTS dietary_tags;
+ALTER TABLE products DROP COLUMN IF EXISTS allergens;
@@ -0,0 +1,20 @@
+-- Revert: 20261014_125500_add_order_parking.sql
+DELETE FROM role_permissions WHERE permission = 'orders.park';
+DELETE FROM system_settings WHERE setting_key = 'parked_order_expiry_minutes';
+
+-- Orders still parked go to the kitchen
+UPDATE orders SET status = 'pending' WHERE status = 'parked';
+
+DROP INDEX IF EXISTS idx_orders_park_expires_at;
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_status_check
+CHECK (status IN ('scheduled', 'pending', 'confirmed', 'preparing', 'ready', 'served', 'completed', 'cancelled'));
+
+ALTER TABLE orders DROP COLUMN IF EXISTS park_expires_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS park_reason;
+ALTER TABLE orders DROP COLUMN IF EXISTS parked_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS parked_at;
@@ -0,0 +1,6 @@
+-- Revert: 20261014_125600_create_tabs.sql
+DELETE FROM role_permissions WHERE permission = 'tabs.manage';
+DELETE FROM system_settings WHERE setting_key IN ('tab_preauth_amount', 'tab_auth_hold_hours');
+DROP INDEX IF EXISTS idx_orders_tab;
+ALTER TABLE orders DROP COLUMN IF EXISTS tab_id;
+DROP TABLE IF EXISTS tabs;
@@ -0,0 +1,5 @@
+-- Revert: 20261014_125700_add_notification_severity.sql
+DROP TABLE IF EXISTS notification_severity_rules;
+DROP INDEX IF EXISTS idx_notifications_user_unread_severity;
+ALTER TABLE notifications DROP COLUMN IF EXISTS severity;
+ALTER TABLE notifications DROP COLUMN IF EXISTS event;
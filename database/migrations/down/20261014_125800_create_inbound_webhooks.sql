@@ -0,0 +1,2 @@
+-- Revert: 20261014_125800_create_inbound_webhooks.sql
+DROP TABLE IF EXISTS inbound_webhook_events;
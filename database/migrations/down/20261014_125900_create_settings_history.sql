@@ -0,0 +1,3 @@
+-- Revert: 20261014_125900_create_settings_history.sql
+DROP TABLE IF EXISTS settings_changes;
+DROP TABLE IF EXISTS settings_change_sets;
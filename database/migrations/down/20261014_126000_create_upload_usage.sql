@@ -0,0 +1,2 @@
+-- Revert: 20261014_126000_create_upload_usage.sql
+DROP TABLE IF EXISTS upload_usage;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_126100_add_data_export_audit.sql
+DELETE FROM role_permissions WHERE permission = 'data.export';
+DROP TABLE IF EXISTS export_log;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_126200_add_runtime_config_settings.sql
+DELETE FROM system_settings
+WHERE setting_key IN ('log_level', 'maintenance_mode', 'maintenance_message', 'rate_limit_public', 'rate_limit_login', 'rate_limit_contact', 'feature_flags');
@@ -0,0 +1,4 @@
+-- Revert: 20261014_126300_add_password_reset.sql
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_login_attempts;
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,4 @@
+-- Revert: 20261014_126400_add_payment_batches.sql
+DROP INDEX IF EXISTS idx_payments_batch;
+ALTER TABLE payments DROP COLUMN IF EXISTS batch_id;
+DROP TABLE IF EXISTS payment_batches;
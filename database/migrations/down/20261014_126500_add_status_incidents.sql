@@ -0,0 +1,4 @@
+-- Revert: 20261014_126500_add_status_incidents.sql
+DROP INDEX IF EXISTS idx_status_incidents_resolved;
+DROP INDEX IF EXISTS idx_status_incidents_open;
+DROP TABLE IF EXISTS status_incidents;
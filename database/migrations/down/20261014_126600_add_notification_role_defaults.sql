@@ -0,0 +1,3 @@
+-- Revert: 20261014_126600_add_notification_role_defaults.sql
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS overridden_fields;
+DROP TABLE IF EXISTS notification_role_defaults;
@@ -0,0 +1,4 @@
+-- Revert: 20261014_126700_add_table_qr_rotation.sql
+ALTER TABLE dining_tables DROP COLUMN IF EXISTS qr_rotated_at;
+ALTER TABLE dining_tables DROP COLUMN IF EXISTS qr_version;
+ALTER TABLE dining_tables ALTER COLUMN qr_code TYPE VARCHAR(50);
@@ -0,0 +1,8 @@
+-- Revert: 20261014_126800_add_payment_methods.sql
+DELETE FROM role_permissions WHERE permission = 'payment_methods.manage';
+ALTER TABLE payments DROP COLUMN IF EXISTS surcharge_amount;
+ALTER TABLE payment_batches DROP CONSTRAINT IF EXISTS payment_batches_payment_method_fkey;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_payment_method_fkey;
+ALTER TABLE payments ADD CONSTRAINT payments_payment_method_check
+CHECK (payment_method IN ('cash', 'credit_card', 'debit_card', 'digital_wallet', 'corporate_wallet', 'on_account'));
+DROP TABLE IF EXISTS payment_methods;
@@ -0,0 +1,10 @@
+-- Revert: 20261014_126900_add_order_courses.sql
+DELETE FROM role_permissions WHERE permission = 'orders.fire_courses';
+
+-- Held courses go to the kitchen
+UPDATE order_items oi SET released_at = NOW()
+WHERE oi.released_at IS NULL
+  AND EXISTS (SELECT 1 FROM order_courses oc WHERE oc.order_id = oi.order_id AND oc.course = oi.course AND oc.fired_at IS NULL);
+
+DROP TABLE IF EXISTS order_courses;
+ALTER TABLE order_items DROP COLUMN IF EXISTS course;
@@ -0,0 +1,4 @@
+-- Revert: 20261014_127000_add_kitchen_note_phrases.sql
+DROP INDEX IF EXISTS idx_order_items_note_phrases;
+ALTER TABLE order_items DROP COLUMN IF EXISTS note_phrases;
+DROP TABLE IF EXISTS kitchen_note_phrases;
@@ -0,0 +1,8 @@
+-- Revert: 20261014_127100_add_server_sections.sql
+DELETE FROM role_permissions WHERE permission IN ('sections.manage', 'orders.view_all_sections');
+DROP INDEX IF EXISTS idx_orders_server_created;
+ALTER TABLE orders DROP COLUMN IF EXISTS covers;
+ALTER TABLE orders DROP COLUMN IF EXISTS server_id;
+DROP TABLE IF EXISTS section_assignments;
+ALTER TABLE dining_tables DROP COLUMN IF EXISTS section_id;
+DROP TABLE IF EXISTS server_sections;
@@ -0,0 +1,3 @@
+-- Revert: 20261014_127200_create_dead_letters.sql
+DROP TABLE IF EXISTS dead_letters;
+ALTER TABLE jobs DROP COLUMN IF EXISTS errors;
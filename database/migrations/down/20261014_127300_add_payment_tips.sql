@@ -0,0 +1,5 @@
+-- Revert: 20261014_127300_add_payment_tips.sql
+DELETE FROM system_settings WHERE setting_key = 'tip_suggestions';
+DROP INDEX IF EXISTS idx_payments_tips;
+ALTER TABLE payments DROP COLUMN IF EXISTS tip_recipient_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS tip_amount;
@@ -0,0 +1,9 @@
+-- Revert: 20261014_127400_create_elt_export.sql
+DELETE FROM role_permissions WHERE permission = 'analytics.export';
+DROP TABLE IF EXISTS elt_export_runs;
+DROP TABLE IF EXISTS elt_watermarks;
+DROP INDEX IF EXISTS idx_payments_updated_id;
+DROP INDEX IF EXISTS idx_order_items_updated_id;
+DROP INDEX IF EXISTS idx_orders_updated_id;
+DROP TRIGGER IF EXISTS set_payments_updated_at ON payments;
+ALTER TABLE payments DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,5 @@
+-- Revert: 20261014_127500_add_note_phrase_kitchen_flags.sql
+DELETE FROM kitchen_note_phrases np
+WHERE np.code = 'allergy_alert'
+  AND NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.note_phrases @> ARRAY[np.code]::text[]);
+ALTER TABLE kitchen_note_phrases DROP COLUMN IF EXISTS kitchen_flag;
@@ -0,0 +1,12 @@
+-- Revert: 20261014_127600_add_survey_follow_up.sql
+DELETE FROM role_permissions WHERE permission = 'surveys.manage';
+DROP INDEX IF EXISTS idx_satisfaction_surveys_status;
+DROP TRIGGER IF EXISTS update_satisfaction_surveys_updated_at ON satisfaction_surveys;
+ALTER TABLE satisfaction_surveys
+DROP COLUMN IF EXISTS updated_at,
+DROP COLUMN IF EXISTS replied_by,
+DROP COLUMN IF EXISTS replied_at,
+DROP COLUMN IF EXISTS acknowledged_by,
+DROP COLUMN IF EXISTS acknowledged_at,
+DROP COLUMN IF EXISTS staff_note,
+DROP COLUMN IF EXISTS status;
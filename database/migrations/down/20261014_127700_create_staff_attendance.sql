@@ -0,0 +1,14 @@
+-- Revert: 20261014_127700_create_staff_attendance.sql
+DELETE FROM role_permissions WHERE permission IN ('attendance.clock', 'attendance.manage');
+DELETE FROM system_settings WHERE setting_key IN (
+    'attendance_require_pin', 'attendance_require_terminal', 'attendance_overtime_grace_minutes'
+);
+
+DROP TABLE IF EXISTS attendance;
+DROP TABLE IF EXISTS staff_shifts;
+
+ALTER TABLE device_print_preferences DROP COLUMN IF EXISTS attendance_clock;
+ALTER TABLE users
+DROP COLUMN IF EXISTS attendance_pin_locked_until,
+DROP COLUMN IF EXISTS attendance_pin_failed_attempts,
+DROP COLUMN IF EXISTS attendance_pin_hash;
@@ -0,0 +1,2 @@
+-- Revert: 20261014_127800_add_user_token_version.sql
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,9 @@
+-- Revert: 20261014_127900_partial_unique_soft_deleted.sql
+-- Fails if a live row and a deleted row share a username, email or table number
+DROP INDEX IF EXISTS idx_dining_tables_branch_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dining_tables_branch_number ON dining_tables(branch_id, table_number);
+
+DROP INDEX IF EXISTS idx_users_email_live;
+DROP INDEX IF EXISTS idx_users_username_live;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
@@ -9,10 +9,24 @@ import type {
   Category,
   DiningTable,
   Order,
+  OrderReceiptLanguage,
+  ReceiptLanguage,
   Payment,
   CreateOrderRequest,
   UpdateOrderStatusRequest,
   ProcessPaymentRequest,
+  PaymentLink,
+  CreatePaymentLinkRequest,
+  Tab,
+  TabStatus,
+  OpenTabRequest,
+  KitchenStation,
+  KitchenDisplayRules,
+  ProductDietaryInfo,
+  DietaryFilters,
+  DietaryOptions,
+  SelftestReport,
+  StationDisplay,
   PaymentSummary,
   DashboardStats,
   SalesReportItem,
@@ -24,6 +38,7 @@ import type {
   OrderStatus,
   // Public API types (B2C Website)
   PublicMenuItem,
+  PublicMenuSearchResult,
   PublicCategory,
   RestaurantInfo,
   ContactFormData,
@@ -40,10 +55,18 @@ import type {
   CreateSurveyRequest,
   SatisfactionSurvey,
   SurveyStatsResponse,
+  AdminSurvey,
+  AdminSurveyDetail,
+  SurveyFilters,
+  SurveyTrends,
+  SurveysByServer,
+  SurveysByProduct,
   GetNotificationsResponse,
   // Recipe management types (007-fix-order-inventory-system)
   RecipeResponse,
   ProductIngredient,
+  ProductBundle,
+  BundleComponentInput,
   AddRecipeIngredientRequest,
   UpdateRecipeIngredientRequest,
   BulkRecipeRequest,
@@ -52,6 +75,29 @@ import type {
   // Admin management types
   TableByLocation,
   IncomeReportResponse,
+  SlaReportResponse,
+  HeatmapReportResponse,
+  ProductCost,
+  CogsAdjustment,
+  CogsReportResponse,
+  MenuEngineeringReport,
+  StockTake,
+  StockTakeDetail,
+  StockTakeCount,
+  StockTakeStatus,
+  StockVarianceReportResponse,
+  WasteLog,
+  WasteReason,
+  WasteStockMovement,
+  LogWasteRequest,
+  WasteReportResponse,
+  EightySixEvent,
+  EightySixProductResult,
+  EightySixReportResponse,
+  OrderItemRemake,
+  RemakeOrderItemRequest,
+  RemakeReason,
+  KitchenQualityReportResponse,
   CreateUserData,
   UpdateUserData,
   CreateCategoryData,
@@ -60,13 +106,80 @@ import type {
   UpdateTableData,
   Notification,
   NotificationPreferences,
+  NotificationSeverity,
+  NotificationSeverityRule,
+  NotificationRoleDefaults,
+  NotificationRoleDefaultsRequest,
+  ApplyNotificationDefaultsRequest,
+  ApplyNotificationDefaultsResult,
   SystemSettings,
+  SettingsChangeSet,
+  SettingsRollbackResult,
+  ExportLogEntry,
+  RuntimeConfigState,
+  PasswordResetConfirmRequest,
+  PasswordPolicy,
+  BatchPaymentRequest,
+  PaymentBatchReceipt,
+  PublicStatus,
+  TableQrCodeWithImages,
+  TableQrBulkResult,
+  StatusIncident,
+  StatusIncidentRequest,
+  SchemaMeta,
+  PaymentMethodConfig,
+  OrderCourse,
+  FireCourseResult,
+  CustomerPaymentMethod,
+  NotePhrase,
+  NotePhraseKitchenFlag,
+  PublicNotePhrase,
+  ServerSection,
+  MySection,
+  ServerReportResponse,
+  TipReportResponse,
   Ingredient,
   IngredientHistory,
   CreateIngredientData,
   UpdateIngredientData,
   RestockResponse,
+  ExpiringIngredientsResponse,
+  MyTargetProgress,
+  CustomerOrderStatus,
+  CustomerOrder,
+  KitchenLoad,
+  Device,
+  DevicePrintPreferences,
+  DeliveryPlatform,
+  PlatformMapping,
+  MenuReconciliation,
+  WebhookEvent,
+  WebhookEndpoint,
+  WebhookDelivery,
+  InboundWebhookEvent,
+  DeadLetter,
+  DeadLetterStats,
+  DeadLetterReplayResult,
+  AnalyticsStream,
+  AnalyticsStreamState,
+  AnalyticsExportStatus,
+  AnalyticsExportRun,
+  AnalyticsStreamRunResult,
+  ContainerType,
+  ContainerReturnResult,
+  TaxClass,
+  Surcharge,
+  PriceSchedule,
+  Currency,
+  PublicCurrency,
+  OrderCurrency,
+  GatewayRefund,
+  StaffShift,
+  AttendanceEntry,
+  MyAttendance,
+  TimesheetsResponse,
 } from "@/types";
+import type { OrderLocation } from "@/lib/order-source";
 
 class APIClient {
   private client: AxiosInstance;
@@ -92,6 +205,8 @@ class APIClient {
         if (token) {
           config.headers.Authorization = `Bearer ${token}`;
         }
+        // Validation errors come back in the UI language
+        config.headers["Accept-Language"] = localStorage.getItem("i18nextLng") || "id-ID";
         return config;
       },
       (error) => {
@@ -165,7 +280,7 @@ class APIClient {
     return this.request({
       method: "POST",
       url: "/auth/login",
-      data: credentials,
+      data: { ...credentials, device_id: this.getDeviceId() },
     });
   }
 
@@ -176,6 +291,31 @@ class APIClient {
     });
   }
 
+  async requestPasswordReset(email: string): Promise<APIResponse> {
+    return this.request({
+      method: "POST",
+      url: "/auth/password-reset/request",
+      data: { email },
+    });
+  }
+
+  async confirmPasswordReset(
+    data: PasswordResetConfirmRequest,
+  ): Promise<APIResponse> {
+    return this.request({
+      method: "POST",
+      url: "/auth/password-reset/confirm",
+      data,
+    });
+  }
+
+  async getPasswordPolicy(): Promise<APIResponse<PasswordPolicy>> {
+    return this.request({
+      method: "GET",
+      url: "/auth/password-policy",
+    });
+  }
+
   async getCurrentUser(): Promise<APIResponse<User>> {
     return this.request({
       method: "GET",
@@ -263,6 +403,49 @@ class APIClient {
     });
   }
 
+  async getProductBundle(
+    productId: string,
+    params?: { branch_id?: string },
+  ): Promise<APIResponse<ProductBundle>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/products/${productId}/bundle`,
+      params,
+    });
+  }
+
+  /**
+   * Replace a bundle's components; an empty list makes it a plain product
+   */
+  async setProductBundle(
+    productId: string,
+    components: BundleComponentInput[],
+  ): Promise<APIResponse<ProductBundle>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/products/${productId}/bundle`,
+      data: { components },
+    });
+  }
+
+  async getProductDietary(productId: string): Promise<APIResponse<ProductDietaryInfo>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/products/${productId}/dietary`,
+    });
+  }
+
+  async setProductDietary(
+    productId: string,
+    data: Partial<Pick<ProductDietaryInfo, "allergens" | "dietary_tags" | "spicy_level">>,
+  ): Promise<APIResponse<ProductDietaryInfo>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/products/${productId}/dietary`,
+      data,
+    });
+  }
+
   // Table endpoints
   async getTables(filters?: TableFilters): Promise<APIResponse<DiningTable[]>> {
     return this.request({
@@ -330,6 +513,74 @@ class APIClient {
     });
   }
 
+  /**
+   * Park a pending order the kitchen hasn't started; expiresInMinutes
+   * defaults to the parked_order_expiry_minutes setting
+   */
+  async parkOrder(
+    id: string,
+    reason?: string,
+    expiresInMinutes?: number,
+  ): Promise<APIResponse<Order>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/orders/${id}/park`,
+      data: { reason, expires_in_minutes: expiresInMinutes },
+    });
+  }
+
+  /** Send a parked order to the kitchen */
+  async resumeOrder(id: string): Promise<APIResponse<Order>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/orders/${id}/resume`,
+      data: {},
+    });
+  }
+
+  /** Fire a held course to the kitchen; without a course the next held one */
+  async fireOrderCourse(id: string, course?: number): Promise<APIResponse<FireCourseResult>> {
+    return this.request({
+      method: "POST",
+      url: `/server/orders/${id}/fire`,
+      params: course ? { course } : undefined,
+      data: {},
+    });
+  }
+
+  async getOrderCourses(id: string): Promise<APIResponse<OrderCourse[]>> {
+    return this.request({
+      method: "GET",
+      url: `/orders/${id}/courses`,
+    });
+  }
+
+  /**
+   * Set the language of an order's receipt (null follows the customer's
+   * preference or the default); rememberForCustomer also keeps it for the
+   * customer's phone number
+   */
+  async updateOrderReceiptLanguage(
+    id: string,
+    receiptLanguage: ReceiptLanguage | null,
+    rememberForCustomer = false,
+  ): Promise<APIResponse<OrderReceiptLanguage>> {
+    return this.request({
+      method: "PUT",
+      url: `/counter/orders/${id}/receipt-language`,
+      data: { receipt_language: receiptLanguage, remember_for_customer: rememberForCustomer },
+    });
+  }
+
+  // Sales targets
+  async getMyTargetProgress(date?: string): Promise<APIResponse<MyTargetProgress>> {
+    return this.request({
+      method: "GET",
+      url: "/sales-targets/me",
+      params: date ? { date } : undefined,
+    });
+  }
+
   // Payment endpoints
   async processPayment(
     orderId: string,
@@ -342,6 +593,69 @@ class APIClient {
     });
   }
 
+  async processBatchPayment(
+    payment: BatchPaymentRequest,
+  ): Promise<APIResponse<PaymentBatchReceipt>> {
+    return this.request({
+      method: "POST",
+      url: "/counter/payments/batch",
+      data: payment,
+    });
+  }
+
+  async getPaymentBatch(id: string): Promise<APIResponse<PaymentBatchReceipt>> {
+    return this.request({
+      method: "GET",
+      url: `/counter/payments/batch/${id}`,
+    });
+  }
+
+  // Reusable container deposit endpoints
+  async getContainerTypes(): Promise<APIResponse<ContainerType[]>> {
+    return this.request({
+      method: "GET",
+      url: "/container-types",
+    });
+  }
+
+  async createContainerType(data: {
+    name: string;
+    deposit_amount: number;
+    is_active?: boolean;
+  }): Promise<APIResponse<ContainerType>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/container-types",
+      data,
+    });
+  }
+
+  async updateContainerType(
+    id: string,
+    data: Partial<{ name: string; deposit_amount: number; is_active: boolean }>,
+  ): Promise<APIResponse<ContainerType>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/container-types/${id}`,
+      data,
+    });
+  }
+
+  async returnContainers(
+    orderId: string,
+    data: {
+      containers: { container_type_id: string; quantity: number }[];
+      refund_method?: "cash" | "digital_wallet";
+      notes?: string;
+    },
+  ): Promise<APIResponse<ContainerReturnResult>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/orders/${orderId}/container-returns`,
+      data,
+    });
+  }
+
   async getPayments(orderId: string): Promise<APIResponse<Payment[]>> {
     return this.request({
       method: "GET",
@@ -393,360 +707,1733 @@ class APIClient {
     });
   }
 
-  // Kitchen endpoints
-  async getKitchenOrders(status?: string): Promise<APIResponse<Order[]>> {
+  async getSlaReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<SlaReportResponse>> {
     return this.request({
       method: "GET",
-      url: "/kitchen/orders",
-      params: status && status !== "all" ? { status } : {},
+      url: "/admin/reports/sla",
+      params,
     });
   }
 
-  async updateOrderItemStatus(
-    orderId: string,
-    itemId: string,
-    status: string,
-  ): Promise<APIResponse> {
+  async getHeatmapReport(params?: {
+    from?: string;
+    to?: string;
+    order_type?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<HeatmapReportResponse>> {
     return this.request({
-      method: "PATCH",
-      url: `/kitchen/orders/${orderId}/items/${itemId}/status`,
-      data: { status },
+      method: "GET",
+      url: "/admin/reports/heatmap",
+      params,
     });
   }
 
-  // Role-specific order creation
-  async createServerOrder(
-    order: CreateOrderRequest,
-  ): Promise<APIResponse<Order>> {
+  async getCogsReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<CogsReportResponse>> {
     return this.request({
-      method: "POST",
-      url: "/server/orders",
-      data: order,
+      method: "GET",
+      url: "/admin/reports/cogs",
+      params,
     });
   }
 
-  async createCounterOrder(
-    order: CreateOrderRequest,
-  ): Promise<APIResponse<Order>> {
+  async getMenuEngineeringReport(params?: {
+    from?: string;
+    to?: string;
+    category_id?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<MenuEngineeringReport>> {
     return this.request({
-      method: "POST",
-      url: "/counter/orders",
-      data: order,
+      method: "GET",
+      url: "/admin/reports/menu-engineering",
+      params,
     });
   }
 
-  // Counter payment processing
-  async processCounterPayment(
-    orderId: string,
-    payment: ProcessPaymentRequest,
-  ): Promise<APIResponse<Payment>> {
+  async getProductCosts(uncosted = false): Promise<APIResponse<ProductCost[]>> {
     return this.request({
-      method: "POST",
-      url: `/counter/orders/${orderId}/payments`,
-      data: payment,
+      method: "GET",
+      url: "/admin/product-costs",
+      params: uncosted ? { uncosted: true } : undefined,
     });
   }
 
-  // User management endpoints (Admin only)
-  async getUsers(params?: {
+  async updateProductCost(productId: string, costOverride: number | null): Promise<APIResponse<ProductCost>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/product-costs/${productId}`,
+      data: { cost_override: costOverride },
+    });
+  }
+
+  async getCogsAdjustments(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
     page?: number;
-    limit?: number;
-    search?: string;
-  }): Promise<APIResponse<User[]>> {
+    per_page?: number;
+  }): Promise<PaginatedResponse<CogsAdjustment[]>> {
     return this.request({
       method: "GET",
-      url: "/admin/users",
+      url: "/admin/cogs-adjustments",
       params,
     });
   }
 
-  async createUser(userData: CreateUserData): Promise<APIResponse<User>> {
+  async createCogsAdjustment(data: {
+    amount: number;
+    reason: string;
+    adjustment_date?: string;
+    product_id?: string | null;
+    branch_id?: string;
+  }): Promise<APIResponse<CogsAdjustment>> {
     return this.request({
       method: "POST",
-      url: "/admin/users",
-      data: userData,
+      url: "/admin/cogs-adjustments",
+      data,
     });
   }
 
-  async updateUser(id: string, userData: UpdateUserData): Promise<APIResponse<User>> {
+  async deleteCogsAdjustment(id: string): Promise<APIResponse> {
     return this.request({
-      method: "PUT",
-      url: `/admin/users/${id}`,
-      data: userData,
+      method: "DELETE",
+      url: `/admin/cogs-adjustments/${id}`,
     });
   }
 
-  async deleteUser(id: string): Promise<APIResponse> {
+  // Stock takes
+  async getStockTakes(params?: {
+    status?: StockTakeStatus;
+    branch_id?: string;
+    page?: number;
+    per_page?: number;
+  }): Promise<PaginatedResponse<StockTake[]>> {
     return this.request({
-      method: "DELETE",
-      url: `/admin/users/${id}`,
+      method: "GET",
+      url: "/admin/stock-takes",
+      params,
     });
   }
 
-  // Admin-specific product management
-  async createProduct(productData: {
-    category_id: string;
-    name: string;
-    description?: string;
-    price: number;
-    image_url?: string;
-    barcode?: string;
-    sku?: string;
-    is_available?: boolean;
-    preparation_time?: number;
-    sort_order?: number;
-  }): Promise<APIResponse<Product>> {
+  async getStockTake(id: string, itemType?: "product" | "ingredient"): Promise<APIResponse<StockTakeDetail>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/stock-takes/${id}`,
+      params: itemType ? { item_type: itemType } : undefined,
+    });
+  }
+
+  async startStockTake(data: {
+    scope?: "products" | "ingredients" | "all";
+    notes?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<StockTakeDetail>> {
     return this.request({
       method: "POST",
-      url: "/admin/products",
-      data: productData,
+      url: "/admin/stock-takes",
+      data,
     });
   }
 
-  async updateProduct(
-    id: string,
-    productData: {
-      category_id?: string;
-      name?: string;
-      description?: string;
-      price?: number;
-      image_url?: string;
-      barcode?: string;
-      sku?: string;
-      is_available?: boolean;
-      preparation_time?: number;
-      sort_order?: number;
-    },
-  ): Promise<APIResponse<Product>> {
+  async recordStockTakeCounts(id: string, counts: StockTakeCount[]): Promise<APIResponse<StockTakeDetail>> {
     return this.request({
       method: "PUT",
-      url: `/admin/products/${id}`,
-      data: productData,
+      url: `/admin/stock-takes/${id}/counts`,
+      data: { counts },
     });
   }
 
-  async deleteProduct(id: string): Promise<APIResponse> {
-    return this.request({ method: "DELETE", url: `/admin/products/${id}` });
+  async postStockTake(id: string): Promise<APIResponse<StockTakeDetail>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/stock-takes/${id}/post`,
+    });
   }
 
-  // Admin-specific category management
-  async createCategory(categoryData: CreateCategoryData): Promise<APIResponse<Category>> {
+  async cancelStockTake(id: string): Promise<APIResponse<StockTakeDetail>> {
     return this.request({
       method: "POST",
-      url: "/admin/categories",
-      data: categoryData,
+      url: `/admin/stock-takes/${id}/cancel`,
     });
   }
 
-  async updateCategory(
-    id: string,
-    categoryData: UpdateCategoryData,
-  ): Promise<APIResponse<Category>> {
+  async getStockVarianceReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<StockVarianceReportResponse>> {
     return this.request({
-      method: "PUT",
-      url: `/admin/categories/${id}`,
-      data: categoryData,
+      method: "GET",
+      url: "/admin/reports/stock-variance",
+      params,
     });
   }
 
-  async deleteCategory(id: string): Promise<APIResponse> {
-    return this.request({ method: "DELETE", url: `/admin/categories/${id}` });
+  // Kitchen endpoints
+  async getKitchenOrders(status?: string, station?: KitchenStation, notePhrase?: string): Promise<APIResponse<Order[]>> {
+    return this.request({
+      method: "GET",
+      url: "/kitchen/orders",
+      params: {
+        ...(status && status !== "all" ? { status } : {}),
+        ...(station ? { station } : {}),
+        ...(notePhrase ? { note_phrase: notePhrase } : {}),
+      },
+    });
   }
 
-  // Admin products endpoint with pagination
-  async getAdminProducts(params?: {
-    page?: number;
+  async getKitchenLoad(): Promise<APIResponse<KitchenLoad>> {
+    return this.request({
+      method: "GET",
+      url: "/kitchen/load",
+    });
+  }
+
+  // Waste logging
+  async logWaste(data: LogWasteRequest): Promise<APIResponse<WasteLog & { stock_movements: WasteStockMovement[] }>> {
+    return this.request({
+      method: "POST",
+      url: "/kitchen/waste",
+      data,
+    });
+  }
+
+  async getWasteLogs(params?: {
+    from?: string;
+    to?: string;
+    reason_type?: WasteReason;
+    item_type?: "product" | "ingredient";
+    branch_id?: string;
+    page?: number;
+    per_page?: number;
+  }): Promise<PaginatedResponse<WasteLog[]>> {
+    return this.request({
+      method: "GET",
+      url: "/kitchen/waste",
+      params,
+    });
+  }
+
+  /**
+   * Upload a photo of the waste; pass the returned url as photo_url
+   */
+  async uploadWastePhoto(file: File): Promise<APIResponse<UploadResponse>> {
+    const formData = new FormData();
+    formData.append("image", file);
+    return this.request({
+      method: "POST",
+      url: "/kitchen/waste/photo",
+      data: formData,
+      headers: { "Content-Type": "multipart/form-data" },
+    });
+  }
+
+  async getWasteReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<WasteReportResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/reports/waste",
+      params,
+    });
+  }
+
+  /**
+   * 86 a product for the rest of the day, or put it back with eighty_sixed: false
+   */
+  async eightySixProduct(
+    productId: string,
+    data: { eighty_sixed?: boolean; reason?: string } = {},
+  ): Promise<APIResponse<EightySixProductResult>> {
+    return this.request({
+      method: "PATCH",
+      url: `/kitchen/products/${productId}/eighty-six`,
+      data,
+    });
+  }
+
+  async getEightySixList(): Promise<APIResponse<EightySixEvent[]>> {
+    return this.request({
+      method: "GET",
+      url: "/kitchen/eighty-six",
+    });
+  }
+
+  async getEightySixReport(params?: {
+    from?: string;
+    to?: string;
+  }): Promise<APIResponse<EightySixReportResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/reports/eighty-six",
+      params,
+    });
+  }
+
+  /**
+   * Log a dish the customer sent back and send a free remake to the kitchen
+   */
+  async remakeOrderItem(
+    orderId: string,
+    itemId: string,
+    data: RemakeOrderItemRequest,
+  ): Promise<APIResponse<OrderItemRemake>> {
+    return this.request({
+      method: "POST",
+      url: `/orders/${orderId}/items/${itemId}/remake`,
+      data,
+    });
+  }
+
+  async getRemakes(params?: {
+    from?: string;
+    to?: string;
+    reason_type?: RemakeReason;
+    order_id?: string;
+    branch_id?: string;
+    page?: number;
     per_page?: number;
+  }): Promise<PaginatedResponse<OrderItemRemake[]>> {
+    return this.request({
+      method: "GET",
+      url: "/kitchen/remakes",
+      params,
+    });
+  }
+
+  async getKitchenQualityReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<KitchenQualityReportResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/reports/kitchen-quality",
+      params,
+    });
+  }
+
+  async getServerReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<ServerReportResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/reports/servers",
+      params,
+    });
+  }
+
+  async getTipReport(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<TipReportResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/reports/tips",
+      params,
+    });
+  }
+
+  async updateOrderItemStatus(
+    orderId: string,
+    itemId: string,
+    status: string,
+  ): Promise<APIResponse> {
+    return this.request({
+      method: "PATCH",
+      url: `/kitchen/orders/${orderId}/items/${itemId}/status`,
+      data: { status },
+    });
+  }
+
+  // Role-specific order creation
+  async createServerOrder(
+    order: CreateOrderRequest,
+  ): Promise<APIResponse<Order>> {
+    return this.request({
+      method: "POST",
+      url: "/server/orders",
+      data: order,
+    });
+  }
+
+  async createCounterOrder(
+    order: CreateOrderRequest,
+  ): Promise<APIResponse<Order>> {
+    return this.request({
+      method: "POST",
+      url: "/counter/orders",
+      data: order,
+    });
+  }
+
+  // Counter payment processing
+  async processCounterPayment(
+    orderId: string,
+    payment: ProcessPaymentRequest,
+  ): Promise<APIResponse<Payment>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/orders/${orderId}/payments`,
+      data: payment,
+    });
+  }
+
+  // A tip added after the charge (or 0 to give it back), within 24 hours
+  async adjustPaymentTip(
+    orderId: string,
+    paymentId: string,
+    tipAmount: number,
+  ): Promise<APIResponse<Payment>> {
+    return this.request({
+      method: "PUT",
+      url: `/counter/orders/${orderId}/payments/${paymentId}/tip`,
+      data: { tip_amount: tipAmount },
+    });
+  }
+
+  // Payment link for remote payment (phone orders)
+  async createPaymentLink(
+    orderId: string,
+    request: CreatePaymentLinkRequest = {},
+  ): Promise<APIResponse<PaymentLink & { sent: boolean }>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/orders/${orderId}/payment-link`,
+      data: request,
+    });
+  }
+
+  async getOrderPaymentLinks(orderId: string): Promise<APIResponse<PaymentLink[]>> {
+    return this.request({
+      method: "GET",
+      url: `/counter/orders/${orderId}/payment-links`,
+    });
+  }
+
+  // Bar tabs
+  async openTab(request: OpenTabRequest): Promise<APIResponse<Tab>> {
+    return this.request({
+      method: "POST",
+      url: "/counter/tabs",
+      data: request,
+    });
+  }
+
+  async getTabs(params?: { status?: TabStatus; page?: number; per_page?: number }): Promise<PaginatedResponse<Tab[]>> {
+    return this.request({
+      method: "GET",
+      url: "/counter/tabs",
+      params,
+    });
+  }
+
+  async getTab(id: string): Promise<APIResponse<Tab>> {
+    return this.request({
+      method: "GET",
+      url: `/counter/tabs/${id}`,
+    });
+  }
+
+  async closeTab(id: string): Promise<APIResponse<Tab>> {
+    return this.request({
+      method: "POST",
+      url: `/counter/tabs/${id}/close`,
+    });
+  }
+
+  // User management endpoints (Admin only)
+  async getUsers(params?: {
+    page?: number;
     limit?: number;
     search?: string;
-    category_id?: string;
-  }): Promise<PaginatedResponse<Product[]>> {
-    // Normalize params (handle both per_page and limit)
-    const normalizedParams = {
-      page: params?.page,
-      per_page: params?.per_page || params?.limit,
-      search: params?.search,
-      category_id: params?.category_id,
-    };
-
+  }): Promise<APIResponse<User[]>> {
     return this.request({
       method: "GET",
+      url: "/admin/users",
+      params,
+    });
+  }
+
+  async createUser(userData: CreateUserData): Promise<APIResponse<User>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/users",
+      data: userData,
+    });
+  }
+
+  async updateUser(id: string, userData: UpdateUserData): Promise<APIResponse<User>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/users/${id}`,
+      data: userData,
+    });
+  }
+
+  async deleteUser(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/users/${id}`,
+    });
+  }
+
+  // Admin-specific product management
+  async createProduct(productData: {
+    category_id: string;
+    name: string;
+    description?: string;
+    price: number;
+    image_url?: string;
+    barcode?: string;
+    sku?: string;
+    is_available?: boolean;
+    preparation_time?: number;
+    sort_order?: number;
+    tax_class_id?: string | null;
+  }): Promise<APIResponse<Product>> {
+    return this.request({
+      method: "POST",
       url: "/admin/products",
-      params: normalizedParams,
+      data: productData,
+    });
+  }
+
+  async updateProduct(
+    id: string,
+    productData: {
+      category_id?: string;
+      name?: string;
+      description?: string;
+      price?: number;
+      image_url?: string;
+      barcode?: string;
+      sku?: string;
+      is_available?: boolean;
+      preparation_time?: number;
+      sort_order?: number;
+      tax_class_id?: string | null;
+    },
+  ): Promise<APIResponse<Product>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/products/${id}`,
+      data: productData,
+    });
+  }
+
+  // Tax classes (per-product tax rates)
+  async getTaxClasses(activeOnly = false): Promise<APIResponse<TaxClass[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/tax-classes",
+      params: activeOnly ? { active_only: true } : undefined,
+    });
+  }
+
+  async createTaxClass(data: {
+    name: string;
+    rate: number;
+    description?: string | null;
+    is_active?: boolean;
+  }): Promise<APIResponse<TaxClass>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/tax-classes",
+      data,
+    });
+  }
+
+  async updateTaxClass(
+    id: string,
+    data: Partial<{ name: string; rate: number; description: string | null; is_active: boolean }>,
+  ): Promise<APIResponse<TaxClass>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/tax-classes/${id}`,
+      data,
+    });
+  }
+
+  async deleteTaxClass(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/tax-classes/${id}`,
+    });
+  }
+
+  async getSurcharges(activeOnly = false): Promise<APIResponse<Surcharge[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/surcharges",
+      params: activeOnly ? { active_only: true } : undefined,
+    });
+  }
+
+  async createSurcharge(data: {
+    name: string;
+    description?: string | null;
+    surcharge_type: Surcharge["surcharge_type"];
+    value: number;
+    starts_at: string;
+    ends_at: string;
+    branch_id?: string | null;
+    order_types?: Surcharge["order_types"];
+    is_active?: boolean;
+  }): Promise<APIResponse<Surcharge>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/surcharges",
+      data,
+    });
+  }
+
+  async updateSurcharge(
+    id: string,
+    data: Partial<Pick<Surcharge,
+      "name" | "description" | "surcharge_type" | "value" | "starts_at" | "ends_at" | "branch_id" | "order_types" | "is_active">>,
+  ): Promise<APIResponse<Surcharge>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/surcharges/${id}`,
+      data,
+    });
+  }
+
+  async deleteSurcharge(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/surcharges/${id}`,
+    });
+  }
+
+  async getPriceSchedules(activeOnly = false): Promise<APIResponse<PriceSchedule[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/price-schedules",
+      params: activeOnly ? { active_only: true } : undefined,
+    });
+  }
+
+  async createPriceSchedule(data: {
+    name: string;
+    description?: string | null;
+    product_ids?: string[];
+    category_ids?: string[];
+    price_type: PriceSchedule["price_type"];
+    value: number;
+    days_of_week?: number[];
+    start_time: string;
+    end_time: string;
+    is_active?: boolean;
+  }): Promise<APIResponse<PriceSchedule>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/price-schedules",
+      data,
+    });
+  }
+
+  async updatePriceSchedule(
+    id: string,
+    data: Partial<Pick<PriceSchedule,
+      "name" | "description" | "product_ids" | "category_ids" | "price_type" | "value" | "days_of_week" | "start_time" | "end_time" | "is_active">>,
+  ): Promise<APIResponse<PriceSchedule>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/price-schedules/${id}`,
+      data,
+    });
+  }
+
+  async deletePriceSchedule(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/price-schedules/${id}`,
+    });
+  }
+
+  async getCurrencies(activeOnly = false): Promise<APIResponse<Currency[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/currencies",
+      params: activeOnly ? { active_only: true } : undefined,
+    });
+  }
+
+  async createCurrency(data: {
+    code: string;
+    name: string;
+    symbol?: string;
+    rate_to_idr: number;
+    decimal_places?: number;
+    is_active?: boolean;
+  }): Promise<APIResponse<Currency>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/currencies",
+      data,
+    });
+  }
+
+  async updateCurrency(
+    id: string,
+    data: Partial<{ name: string; symbol: string; rate_to_idr: number; decimal_places: number; is_active: boolean }>,
+  ): Promise<APIResponse<Currency>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/currencies/${id}`,
+      data,
+    });
+  }
+
+  async deleteCurrency(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/currencies/${id}`,
+    });
+  }
+
+  /** Active payment methods, for the payment dialog */
+  async getPaymentMethods(): Promise<APIResponse<PaymentMethodConfig[]>> {
+    return this.request({
+      method: "GET",
+      url: "/payment-methods",
+    });
+  }
+
+  async getAllPaymentMethods(): Promise<APIResponse<PaymentMethodConfig[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/payment-methods",
+    });
+  }
+
+  async createPaymentMethod(data: {
+    code: string;
+    display_name: string;
+    surcharge_percent?: number;
+    is_active?: boolean;
+    customer_selectable?: boolean;
+    sort_order?: number;
+  }): Promise<APIResponse<PaymentMethodConfig>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/payment-methods",
+      data,
+    });
+  }
+
+  async updatePaymentMethod(
+    code: string,
+    data: Partial<{
+      display_name: string;
+      surcharge_percent: number;
+      is_active: boolean;
+      customer_selectable: boolean;
+      sort_order: number;
+    }>,
+  ): Promise<APIResponse<PaymentMethodConfig>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/payment-methods/${code}`,
+      data,
+    });
+  }
+
+  async deletePaymentMethod(code: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/payment-methods/${code}`,
+    });
+  }
+
+  /** Active quick phrases for the POS, optionally for one category's products */
+  async getNotePhrases(categoryId?: string): Promise<APIResponse<NotePhrase[]>> {
+    return this.request({
+      method: "GET",
+      url: "/note-phrases",
+      params: categoryId ? { category_id: categoryId } : {},
+    });
+  }
+
+  async getAllNotePhrases(): Promise<APIResponse<NotePhrase[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/note-phrases",
+    });
+  }
+
+  async createNotePhrase(data: {
+    code: string;
+    label: string;
+    category_id?: string | null;
+    customer_visible?: boolean;
+    kitchen_flag?: NotePhraseKitchenFlag | null;
+    is_active?: boolean;
+    sort_order?: number;
+  }): Promise<APIResponse<NotePhrase>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/note-phrases",
+      data,
+    });
+  }
+
+  async updateNotePhrase(
+    code: string,
+    data: Partial<{
+      label: string;
+      category_id: string | null;
+      customer_visible: boolean;
+      kitchen_flag: NotePhraseKitchenFlag | null;
+      is_active: boolean;
+      sort_order: number;
+    }>,
+  ): Promise<APIResponse<NotePhrase>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/note-phrases/${code}`,
+      data,
+    });
+  }
+
+  async deleteNotePhrase(code: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/note-phrases/${code}`,
+    });
+  }
+
+  async getSections(branchId?: string): Promise<APIResponse<ServerSection[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/sections",
+      params: branchId ? { branch_id: branchId } : {},
+    });
+  }
+
+  async createSection(data: {
+    name: string;
+    branch_id?: string;
+    table_ids?: string[];
+  }): Promise<APIResponse<ServerSection>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/sections",
+      data,
+    });
+  }
+
+  async updateSection(
+    id: string,
+    data: Partial<{ name: string; table_ids: string[] }>,
+  ): Promise<APIResponse<ServerSection>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/sections/${id}`,
+      data,
+    });
+  }
+
+  async deleteSection(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/sections/${id}`,
+    });
+  }
+
+  async assignSectionServer(
+    sectionId: string,
+    data: { user_id: string; starts_at: string; ends_at: string },
+  ): Promise<APIResponse<{ assignment_id: string; section: ServerSection }>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/sections/${sectionId}/assignments`,
+      data,
+    });
+  }
+
+  async removeSectionAssignment(assignmentId: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/sections/assignments/${assignmentId}`,
+    });
+  }
+
+  async getMySections(): Promise<APIResponse<MySection[]>> {
+    return this.request({
+      method: "GET",
+      url: "/sections/mine",
+    });
+  }
+
+  async deleteProduct(id: string): Promise<APIResponse> {
+    return this.request({ method: "DELETE", url: `/admin/products/${id}` });
+  }
+
+  // Admin-specific category management
+  async createCategory(categoryData: CreateCategoryData): Promise<APIResponse<Category>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/categories",
+      data: categoryData,
+    });
+  }
+
+  async updateCategory(
+    id: string,
+    categoryData: UpdateCategoryData,
+  ): Promise<APIResponse<Category>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/categories/${id}`,
+      data: categoryData,
+    });
+  }
+
+  async getKitchenDisplayRules(): Promise<APIResponse<KitchenDisplayRules>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/kitchen-display",
+    });
+  }
+
+  async updateStationDisplay(
+    station: KitchenStation,
+    data: Partial<Pick<StationDisplay, "color" | "sort_priority" | "group_items_by">>,
+  ): Promise<APIResponse<StationDisplay>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/kitchen-display/stations/${station}`,
+      data,
+    });
+  }
+
+  async deleteCategory(id: string): Promise<APIResponse> {
+    return this.request({ method: "DELETE", url: `/admin/categories/${id}` });
+  }
+
+  // Admin products endpoint with pagination
+  async getAdminProducts(params?: {
+    page?: number;
+    per_page?: number;
+    limit?: number;
+    search?: string;
+    category_id?: string;
+  }): Promise<PaginatedResponse<Product[]>> {
+    // Normalize params (handle both per_page and limit)
+    const normalizedParams = {
+      page: params?.page,
+      per_page: params?.per_page || params?.limit,
+      search: params?.search,
+      category_id: params?.category_id,
+    };
+
+    return this.request({
+      method: "GET",
+      url: "/admin/products",
+      params: normalizedParams,
+    });
+  }
+
+  // Admin categories endpoint with pagination
+  async getAdminCategories(params?: {
+    page?: number;
+    per_page?: number;
+    limit?: number;
+    search?: string;
+    active_only?: boolean;
+  }): Promise<PaginatedResponse<Category[]>> {
+    // Normalize params (handle both per_page and limit)
+    const normalizedParams = {
+      page: params?.page,
+      per_page: params?.per_page || params?.limit,
+      search: params?.search,
+      active_only: params?.active_only,
+    };
+
+    return this.request({
+      method: "GET",
+      url: "/admin/categories",
+      params: normalizedParams,
+    });
+  }
+
+  // Admin tables endpoint with pagination
+  async getAdminTables(params?: {
+    page?: number;
+    limit?: number;
+    search?: string;
+    status?: string;
+  }): Promise<APIResponse<DiningTable[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/tables",
+      params,
+    });
+  }
+
+  // Admin-specific table management
+  async createTable(tableData: CreateTableData): Promise<APIResponse<DiningTable>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/tables",
+      data: tableData,
+    });
+  }
+
+  async updateTable(
+    id: string,
+    tableData: UpdateTableData,
+  ): Promise<APIResponse<DiningTable>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/tables/${id}`,
+      data: tableData,
+    });
+  }
+
+  async deleteTable(id: string): Promise<APIResponse> {
+    return this.request({ method: "DELETE", url: `/admin/tables/${id}` });
+  }
+
+  /** Keeps a valid existing code unless rotate is true */
+  async generateTableQr(id: string, rotate = false): Promise<APIResponse<TableQrCodeWithImages>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/tables/${id}/qr`,
+      data: { rotate },
+    });
+  }
+
+  /** Issues a new code; the printed one stops working */
+  async rotateTableQr(id: string): Promise<APIResponse<TableQrCodeWithImages>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/tables/${id}/qr/rotate`,
+    });
+  }
+
+  async generateAllTableQr(rotate = false): Promise<APIResponse<TableQrBulkResult>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/tables/qr",
+      data: { rotate },
+    });
+  }
+
+  // ===========================================
+  // Profile endpoints (Protected - Auth Required)
+  // ===========================================
+
+  async getUserProfile(): Promise<APIResponse<User>> {
+    return this.request({
+      method: "GET",
+      url: "/profile",
+    });
+  }
+
+  async updateUserProfile(profileData: {
+    first_name?: string;
+    last_name?: string;
+    email?: string;
+  }): Promise<APIResponse<User>> {
+    return this.request({
+      method: "PUT",
+      url: "/profile",
+      data: profileData,
+    });
+  }
+
+  async changePassword(passwordData: {
+    current_password: string;
+    new_password: string;
+  }): Promise<APIResponse<{ token: string; csrf_token: string }>> {
+    const response = await this.request<APIResponse<{ token: string; csrf_token: string }>>({
+      method: "PUT",
+      url: "/profile/password",
+      data: passwordData,
+    });
+    // Changing the password ends every session, this one's old token included
+    if (response.success && response.data?.token) {
+      this.setAuthToken(response.data.token);
+    }
+    return response;
+  }
+
+  // ===========================================
+  // Notifications endpoints (Protected - Auth Required)
+  // ===========================================
+
+  async getUnreadCounts(): Promise<
+    APIResponse<{
+      notifications: number;
+      by_severity: Record<NotificationSeverity, number>;
+    }>
+  > {
+    return this.request({
+      method: "GET",
+      url: "/notifications/counts/unread",
+    });
+  }
+
+  async getNotifications(filters?: {
+    type?: string;
+    is_read?: boolean;
+    severity?: NotificationSeverity;
+  }): Promise<APIResponse<Notification[]>> {
+    return this.request({
+      method: "GET",
+      url: "/notifications",
+      params: filters,
+    });
+  }
+
+  async markNotificationRead(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "PUT",
+      url: `/notifications/${id}/read`,
+    });
+  }
+
+  async deleteNotification(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/notifications/${id}`,
+    });
+  }
+
+  async getNotificationPreferences(): Promise<APIResponse<NotificationPreferences>> {
+    return this.request({
+      method: "GET",
+      url: "/notifications/preferences",
+    });
+  }
+
+  async updateNotificationPreferences(preferences: {
+    email_enabled?: boolean;
+    notification_types?: string[];
+    quiet_hours_start?: string;
+    quiet_hours_end?: string;
+  }): Promise<APIResponse<NotificationPreferences>> {
+    return this.request({
+      method: "PUT",
+      url: "/notifications/preferences",
+      data: preferences,
+    });
+  }
+
+  // ===========================================
+  // System Settings endpoints (Admin - Auth Required)
+  // ===========================================
+
+  async getNotificationSeverities(): Promise<APIResponse<NotificationSeverityRule[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/notification-severities",
+    });
+  }
+
+  /** Without a role, sets the event's severity for every role */
+  async updateNotificationSeverity(
+    event: string,
+    data: { severity: NotificationSeverity; role?: string },
+  ): Promise<APIResponse<NotificationSeverityRule>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/notification-severities/${event}`,
+      data,
+    });
+  }
+
+  async resetNotificationSeverity(event: string, role?: string): Promise<APIResponse<NotificationSeverityRule>> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/notification-severities/${event}`,
+      params: role !== undefined ? { role } : undefined,
+    });
+  }
+
+  // Notification preferences a role's users start with
+  async getNotificationRoleDefaults(): Promise<APIResponse<NotificationRoleDefaults[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/notification-defaults",
+    });
+  }
+
+  async updateNotificationRoleDefaults(
+    role: string,
+    data: NotificationRoleDefaultsRequest,
+  ): Promise<APIResponse<NotificationRoleDefaults>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/notification-defaults/${role}`,
+      data,
+    });
+  }
+
+  async resetNotificationRoleDefaults(role: string): Promise<APIResponse<NotificationRoleDefaults>> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/notification-defaults/${role}`,
+    });
+  }
+
+  /** Users keep preferences they set themselves unless override_personal is true */
+  async applyNotificationRoleDefaults(
+    role: string,
+    data: ApplyNotificationDefaultsRequest = {},
+  ): Promise<APIResponse<ApplyNotificationDefaultsResult>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/notification-defaults/${role}/apply`,
+      data,
+    });
+  }
+
+  async getSettings(): Promise<APIResponse<SystemSettings>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/settings",
+    });
+  }
+
+  async updateSettings(settings: SystemSettings): Promise<APIResponse<{ change_set_id: string | null }>> {
+    return this.request({
+      method: "PUT",
+      url: "/admin/settings",
+      data: settings,
+    });
+  }
+
+  async getSettingsHistory(params?: {
+    page?: number;
+    per_page?: number;
+    key?: string;
+  }): Promise<PaginatedResponse<SettingsChangeSet[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/settings/history",
+      params,
+    });
+  }
+
+  async rollbackSettingsChange(changeSetId: string): Promise<APIResponse<SettingsRollbackResult>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/settings/history/${changeSetId}/rollback`,
+    });
+  }
+
+  async getRuntimeConfig(): Promise<APIResponse<RuntimeConfigState>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/config",
+    });
+  }
+
+  async reloadRuntimeConfig(): Promise<APIResponse<RuntimeConfigState>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/config/reload",
+    });
+  }
+
+  // Incidents shown on the public status page
+  async getPublicStatus(): Promise<APIResponse<PublicStatus>> {
+    return this.request({
+      method: "GET",
+      url: "/public/status",
+    });
+  }
+
+  async getStatusIncidents(params?: { include_resolved?: boolean }): Promise<APIResponse<StatusIncident[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/status/incidents",
+      params,
+    });
+  }
+
+  async createStatusIncident(data: StatusIncidentRequest): Promise<APIResponse<StatusIncident>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/status/incidents",
+      data,
+    });
+  }
+
+  async updateStatusIncident(id: string, data: Partial<StatusIncidentRequest>): Promise<APIResponse<StatusIncident>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/status/incidents/${id}`,
+      data,
+    });
+  }
+
+  async resolveStatusIncident(id: string): Promise<APIResponse<StatusIncident>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/status/incidents/${id}/resolve`,
+    });
+  }
+
+  /** Entities, enum values and validation limits; `section` returns one part */
+  async getSchemaMeta(section?: "entities" | "enums" | "validation"): Promise<APIResponse<Partial<SchemaMeta>>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/meta/schema",
+      params: section ? { section } : undefined,
+    });
+  }
+
+  async getExportLog(params?: {
+    page?: number;
+    per_page?: number;
+    export_type?: string;
+    user_id?: string;
+  }): Promise<PaginatedResponse<ExportLogEntry[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/exports/log",
+      params,
+    });
+  }
+
+  // Delivery platform menu sync endpoints
+  async getPlatformMappings(platform?: DeliveryPlatform): Promise<APIResponse<PlatformMapping[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/delivery-platforms/mappings",
+      params: { platform },
+    });
+  }
+
+  async createPlatformMapping(data: {
+    platform: DeliveryPlatform;
+    product_id: string;
+    platform_item_id: string;
+  }): Promise<APIResponse<PlatformMapping>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/delivery-platforms/mappings",
+      data,
+    });
+  }
+
+  async deletePlatformMapping(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/delivery-platforms/mappings/${id}`,
+    });
+  }
+
+  async syncPlatformMenu(
+    platform: DeliveryPlatform,
+  ): Promise<APIResponse<{ platform: DeliveryPlatform; queued: number }>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/delivery-platforms/${platform}/sync`,
+    });
+  }
+
+  async getMenuReconciliation(
+    platform?: DeliveryPlatform,
+  ): Promise<APIResponse<MenuReconciliation[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/delivery-platforms/reconciliation",
+      params: { platform },
+    });
+  }
+
+  // Webhook endpoints
+  async getWebhooks(): Promise<APIResponse<{ endpoints: WebhookEndpoint[]; events: WebhookEvent[] }>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/webhooks",
+    });
+  }
+
+  async createWebhook(data: {
+    name: string;
+    url: string;
+    events: WebhookEvent[];
+    is_active?: boolean;
+  }): Promise<APIResponse<WebhookEndpoint>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/webhooks",
+      data,
+    });
+  }
+
+  async updateWebhook(
+    id: string,
+    data: Partial<{ name: string; url: string; events: WebhookEvent[]; is_active: boolean }>,
+  ): Promise<APIResponse<WebhookEndpoint>> {
+    return this.request({
+      method: "PUT",
+      url: `/admin/webhooks/${id}`,
+      data,
+    });
+  }
+
+  async deleteWebhook(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/webhooks/${id}`,
+    });
+  }
+
+  async rotateWebhookSecret(id: string): Promise<APIResponse<{ id: string; secret: string }>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/webhooks/${id}/rotate-secret`,
+    });
+  }
+
+  async sendTestWebhook(id: string): Promise<APIResponse<WebhookDelivery>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/webhooks/${id}/test`,
+    });
+  }
+
+  async getWebhookDeliveries(params?: {
+    page?: number;
+    per_page?: number;
+    endpoint_id?: string;
+    status?: WebhookDelivery["status"];
+    event?: string;
+  }): Promise<PaginatedResponse<WebhookDelivery[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/webhooks/deliveries",
+      params,
+    });
+  }
+
+  async getWebhookDelivery(id: string): Promise<APIResponse<WebhookDelivery>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/webhooks/deliveries/${id}`,
+    });
+  }
+
+  async retryWebhookDelivery(id: string): Promise<APIResponse<WebhookDelivery>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/webhooks/deliveries/${id}/retry`,
+    });
+  }
+
+  async getInboundWebhookEvents(params?: {
+    page?: number;
+    per_page?: number;
+    provider?: InboundWebhookEvent["provider"];
+    status?: InboundWebhookEvent["status"];
+    event_type?: string;
+  }): Promise<PaginatedResponse<InboundWebhookEvent[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/webhooks/inbound-events",
+      params,
+    });
+  }
+
+  async getInboundWebhookEvent(id: string): Promise<APIResponse<InboundWebhookEvent>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/webhooks/inbound-events/${id}`,
+    });
+  }
+
+  async retryInboundWebhookEvent(id: string): Promise<APIResponse<InboundWebhookEvent>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/webhooks/inbound-events/${id}/retry`,
+    });
+  }
+
+  // Dead letter endpoints
+  async getDeadLetters(params?: {
+    page?: number;
+    per_page?: number;
+    status?: DeadLetter["status"];
+    job_type?: string;
+  }): Promise<PaginatedResponse<DeadLetter[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/dead-letters",
+      params,
+    });
+  }
+
+  async getDeadLetterStats(): Promise<APIResponse<DeadLetterStats[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/dead-letters/stats",
+    });
+  }
+
+  async getDeadLetter(id: string): Promise<APIResponse<DeadLetter>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/dead-letters/${id}`,
+    });
+  }
+
+  async replayDeadLetter(id: string, note?: string): Promise<APIResponse<DeadLetter>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/dead-letters/${id}/replay`,
+      data: note ? { note } : {},
+    });
+  }
+
+  async replayDeadLetters(
+    data: { ids: string[]; note?: string } | { job_type: string; note?: string },
+  ): Promise<APIResponse<DeadLetterReplayResult>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/dead-letters/replay",
+      data,
+    });
+  }
+
+  async discardDeadLetter(id: string, note?: string): Promise<APIResponse<DeadLetter>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/dead-letters/${id}/discard`,
+      data: note ? { note } : {},
     });
   }
 
-  // Admin categories endpoint with pagination
-  async getAdminCategories(params?: {
-    page?: number;
-    per_page?: number;
-    limit?: number;
-    search?: string;
-    active_only?: boolean;
-  }): Promise<PaginatedResponse<Category[]>> {
-    // Normalize params (handle both per_page and limit)
-    const normalizedParams = {
-      page: params?.page,
-      per_page: params?.per_page || params?.limit,
-      search: params?.search,
-      active_only: params?.active_only,
-    };
-
+  // Analytics export endpoints
+  async getAnalyticsExport(): Promise<APIResponse<AnalyticsExportStatus>> {
     return this.request({
       method: "GET",
-      url: "/admin/categories",
-      params: normalizedParams,
+      url: "/admin/analytics-export",
     });
   }
 
-  // Admin tables endpoint with pagination
-  async getAdminTables(params?: {
+  async getAnalyticsExportRuns(params?: {
     page?: number;
-    limit?: number;
-    search?: string;
-    status?: string;
-  }): Promise<APIResponse<DiningTable[]>> {
+    per_page?: number;
+    stream?: AnalyticsStream;
+    status?: AnalyticsExportRun["status"];
+  }): Promise<PaginatedResponse<AnalyticsExportRun[]>> {
     return this.request({
       method: "GET",
-      url: "/admin/tables",
+      url: "/admin/analytics-export/runs",
       params,
     });
   }
 
-  // Admin-specific table management
-  async createTable(tableData: CreateTableData): Promise<APIResponse<DiningTable>> {
+  async runAnalyticsExport(stream?: AnalyticsStream): Promise<APIResponse<AnalyticsStreamRunResult[]>> {
     return this.request({
       method: "POST",
-      url: "/admin/tables",
-      data: tableData,
+      url: "/admin/analytics-export/run",
+      params: stream ? { stream } : undefined,
     });
   }
 
-  async updateTable(
-    id: string,
-    tableData: UpdateTableData,
-  ): Promise<APIResponse<DiningTable>> {
+  // since is a YYYY-MM-DD business date; null re-exports everything
+  async rewindAnalyticsStream(stream: AnalyticsStream, since: string | null): Promise<APIResponse<AnalyticsStreamState>> {
     return this.request({
-      method: "PUT",
-      url: `/admin/tables/${id}`,
-      data: tableData,
+      method: "POST",
+      url: `/admin/analytics-export/streams/${stream}/rewind`,
+      data: { since },
     });
   }
 
-  async deleteTable(id: string): Promise<APIResponse> {
-    return this.request({ method: "DELETE", url: `/admin/tables/${id}` });
+  // Attendance endpoints
+  async clockIn(data: { pin?: string; note?: string } = {}): Promise<APIResponse<AttendanceEntry>> {
+    return this.request({
+      method: "POST",
+      url: "/staff/clock-in",
+      data: { ...data, device_id: this.getDeviceId() },
+    });
   }
 
-  // ===========================================
-  // Profile endpoints (Protected - Auth Required)
-  // ===========================================
+  async clockOut(data: { pin?: string; note?: string } = {}): Promise<APIResponse<AttendanceEntry>> {
+    return this.request({
+      method: "POST",
+      url: "/staff/clock-out",
+      data: { ...data, device_id: this.getDeviceId() },
+    });
+  }
 
-  async getUserProfile(): Promise<APIResponse<User>> {
+  async getMyAttendance(): Promise<APIResponse<MyAttendance>> {
     return this.request({
       method: "GET",
-      url: "/profile",
+      url: "/staff/attendance",
     });
   }
 
-  async updateUserProfile(profileData: {
-    first_name?: string;
-    last_name?: string;
-    email?: string;
-  }): Promise<APIResponse<User>> {
+  async getStaffShifts(params?: {
+    from?: string;
+    to?: string;
+    user_id?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<StaffShift[]>> {
     return this.request({
-      method: "PUT",
-      url: "/profile",
-      data: profileData,
+      method: "GET",
+      url: "/admin/staff-shifts",
+      params,
     });
   }
 
-  async changePassword(passwordData: {
-    current_password: string;
-    new_password: string;
-  }): Promise<APIResponse> {
+  async createStaffShift(data: {
+    user_id: string;
+    starts_at: string;
+    ends_at: string;
+    break_minutes?: number;
+    note?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<StaffShift>> {
     return this.request({
-      method: "PUT",
-      url: "/profile/password",
-      data: passwordData,
+      method: "POST",
+      url: "/admin/staff-shifts",
+      data,
     });
   }
 
-  // ===========================================
-  // Notifications endpoints (Protected - Auth Required)
-  // ===========================================
+  async deleteStaffShift(id: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/staff-shifts/${id}`,
+    });
+  }
 
-  async getUnreadCounts(): Promise<
-    APIResponse<{
-      notifications: number;
-    }>
-  > {
+  async getAttendance(params?: {
+    page?: number;
+    per_page?: number;
+    from?: string;
+    to?: string;
+    user_id?: string;
+    open?: boolean;
+    branch_id?: string;
+  }): Promise<PaginatedResponse<AttendanceEntry[]>> {
     return this.request({
       method: "GET",
-      url: "/notifications/counts/unread",
+      url: "/admin/attendance",
+      params,
     });
   }
 
-  async getNotifications(filters?: {
-    type?: string;
-    is_read?: boolean;
-  }): Promise<APIResponse<Notification[]>> {
+  async adjustAttendance(
+    id: string,
+    data: { clock_in_at?: string; clock_out_at?: string | null; reason: string },
+  ): Promise<APIResponse<AttendanceEntry>> {
     return this.request({
-      method: "GET",
-      url: "/notifications",
-      params: filters,
+      method: "PUT",
+      url: `/admin/attendance/${id}`,
+      data,
     });
   }
 
-  async markNotificationRead(id: string): Promise<APIResponse> {
+  // null clears the PIN
+  async setAttendancePin(userId: string, pin: string | null): Promise<APIResponse<{ user_id: string; has_pin: boolean }>> {
     return this.request({
       method: "PUT",
-      url: `/notifications/${id}/read`,
+      url: `/admin/users/${userId}/attendance-pin`,
+      data: { pin },
     });
   }
 
-  async deleteNotification(id: string): Promise<APIResponse> {
+  async setAttendanceTerminal(
+    deviceId: string,
+    attendanceClock: boolean,
+  ): Promise<APIResponse<Pick<Device, "device_id" | "name" | "branch_id" | "attendance_clock">>> {
     return this.request({
-      method: "DELETE",
-      url: `/notifications/${id}`,
+      method: "PUT",
+      url: `/admin/devices/${encodeURIComponent(deviceId)}/attendance`,
+      data: { attendance_clock: attendanceClock },
     });
   }
 
-  async getNotificationPreferences(): Promise<APIResponse<NotificationPreferences>> {
+  async getTimesheets(params?: {
+    from?: string;
+    to?: string;
+    user_id?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<TimesheetsResponse>> {
     return this.request({
       method: "GET",
-      url: "/notifications/preferences",
+      url: "/admin/timesheets",
+      params,
     });
   }
 
-  async updateNotificationPreferences(preferences: {
-    email_enabled?: boolean;
-    notification_types?: string[];
-    quiet_hours_start?: string;
-    quiet_hours_end?: string;
-  }): Promise<APIResponse<NotificationPreferences>> {
+  // Gateway refund endpoints
+  async getGatewayRefunds(params?: {
+    page?: number;
+    per_page?: number;
+    status?: GatewayRefund["status"];
+    order_id?: string;
+  }): Promise<PaginatedResponse<GatewayRefund[]>> {
     return this.request({
-      method: "PUT",
-      url: "/notifications/preferences",
-      data: preferences,
+      method: "GET",
+      url: "/admin/gateway-refunds",
+      params,
     });
   }
 
-  // ===========================================
-  // System Settings endpoints (Admin - Auth Required)
-  // ===========================================
+  async retryGatewayRefund(id: string): Promise<APIResponse<GatewayRefund>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/gateway-refunds/${id}/retry`,
+    });
+  }
 
-  async getSettings(): Promise<APIResponse<SystemSettings>> {
+  // Device (POS terminal) endpoints
+  async getDevices(): Promise<APIResponse<Device[]>> {
     return this.request({
       method: "GET",
-      url: "/admin/settings",
+      url: "/admin/devices",
+    });
+  }
+
+  async getDevicePrintPreferences(
+    deviceId: string = this.getDeviceId(),
+  ): Promise<APIResponse<DevicePrintPreferences>> {
+    return this.request({
+      method: "GET",
+      url: `/devices/${encodeURIComponent(deviceId)}/print-preferences`,
     });
   }
 
-  async updateSettings(settings: SystemSettings): Promise<APIResponse> {
+  async updateDevicePrintPreferences(
+    deviceId: string,
+    preferences: Partial<Omit<DevicePrintPreferences, "device_id">>,
+  ): Promise<APIResponse<Device>> {
     return this.request({
       method: "PUT",
-      url: "/admin/settings",
-      data: settings,
+      url: `/admin/devices/${encodeURIComponent(deviceId)}/print-preferences`,
+      data: preferences,
+    });
+  }
+
+  async deleteDevice(deviceId: string): Promise<APIResponse> {
+    return this.request({
+      method: "DELETE",
+      url: `/admin/devices/${encodeURIComponent(deviceId)}`,
     });
   }
 
@@ -772,6 +2459,14 @@ class APIClient {
     });
   }
 
+  // Rejects with a 503 when a step fails; the report is in the error response
+  async runSelftest(): Promise<APIResponse<SelftestReport>> {
+    return this.request({
+      method: "POST",
+      url: "/admin/selftest",
+    });
+  }
+
   // ===========================================
   // Public API endpoints (B2C Website - No Auth Required)
   // ===========================================
@@ -780,11 +2475,15 @@ class APIClient {
    * Get public menu items with optional filtering
    * @param categoryId - Filter by category ID
    * @param search - Search term for menu items
+   * @param currency - Also show prices converted to this currency
+   * @param dietary - Allergen, dietary tag and spice filters
    * @returns Array of public menu items
    */
   async getPublicMenu(
     categoryId?: string,
     search?: string,
+    currency?: string,
+    dietary?: DietaryFilters,
   ): Promise<PublicMenuItem[]> {
     const response = await this.request<APIResponse<PublicMenuItem[]>>({
       method: "GET",
@@ -792,11 +2491,62 @@ class APIClient {
       params: {
         ...(categoryId && { category_id: categoryId }),
         ...(search && { search }),
+        ...(currency && { currency }),
+        ...(dietary?.exclude_allergens?.length && { exclude_allergens: dietary.exclude_allergens.join(",") }),
+        ...(dietary?.tags?.length && { tags: dietary.tags.join(",") }),
+        ...(dietary?.max_spicy !== undefined && { max_spicy: dietary.max_spicy }),
       },
     });
     return response.data || [];
   }
 
+  /**
+   * Values accepted by the public menu's dietary filters
+   */
+  async getPublicDietaryOptions(): Promise<DietaryOptions> {
+    const response = await this.request<APIResponse<DietaryOptions>>({
+      method: "GET",
+      url: "/public/menu/dietary-options",
+    });
+    return response.data ?? { allergens: [], dietary_tags: [], max_spicy_level: 3 };
+  }
+
+  /**
+   * Search the public menu, tolerating typos; best matches first
+   * @param query - Search term
+   * @param categoryId - Limit results to a category (facets still cover all)
+   * @param currency - Also show prices converted to this currency
+   * @returns Matching items and per-category counts
+   */
+  async searchPublicMenu(
+    query: string,
+    categoryId?: string,
+    currency?: string,
+  ): Promise<PublicMenuSearchResult> {
+    const response = await this.request<APIResponse<PublicMenuSearchResult>>({
+      method: "GET",
+      url: "/public/menu/search",
+      params: {
+        q: query,
+        ...(categoryId && { category_id: categoryId }),
+        ...(currency && { currency }),
+      },
+    });
+    return response.data || { items: [], facets: [] };
+  }
+
+  /**
+   * Get the currencies menu prices can be shown in
+   * @returns Active display currencies; orders are settled in IDR
+   */
+  async getPublicCurrencies(): Promise<{ settlement_currency: string; currencies: PublicCurrency[] }> {
+    const response = await this.request<APIResponse<{ settlement_currency: string; currencies: PublicCurrency[] }>>({
+      method: "GET",
+      url: "/public/currencies",
+    });
+    return response.data || { settlement_currency: "IDR", currencies: [] };
+  }
+
   /**
    * Get public categories
    * @returns Array of public categories
@@ -809,6 +2559,20 @@ class APIClient {
     return response.data || [];
   }
 
+  /**
+   * Get the quick phrases the customer app offers for item instructions
+   * @param categoryId - Only phrases for this category's products
+   * @returns Array of phrases
+   */
+  async getPublicNotePhrases(categoryId?: string): Promise<PublicNotePhrase[]> {
+    const response = await this.request<APIResponse<PublicNotePhrase[]>>({
+      method: "GET",
+      url: "/public/note-phrases",
+      params: categoryId ? { category_id: categoryId } : {},
+    });
+    return response.data || [];
+  }
+
   /**
    * Get restaurant information with operating hours
    * @returns Restaurant info including is_open_now computed field
@@ -936,6 +2700,10 @@ class APIClient {
       special_instructions?: string;
     }>;
     notes?: string;
+    /** Show the order total in this currency; it is still charged in IDR */
+    currency?: string;
+    device_fingerprint?: string;
+    location?: OrderLocation | null;
   }): Promise<{
     order_id: string;
     order_number: string;
@@ -943,15 +2711,21 @@ class APIClient {
     subtotal: number;
     tax_amount: number;
     total_amount: number;
+    currency: OrderCurrency;
   }> {
     const response = await this.request<
       APIResponse<{
         order_id: string;
         order_number: string;
+        /** Identifies the order in customer links; expires at order_token_expires_at */
+        order_token: string;
+        order_token_expires_at: string;
+        status_url: string;
         table_number: string;
         subtotal: number;
         tax_amount: number;
         total_amount: number;
+        currency: OrderCurrency;
       }>
     >({
       method: "POST",
@@ -964,19 +2738,62 @@ class APIClient {
     return response.data;
   }
 
+  /**
+   * Get progress of a QR self-order (no auth required)
+   * @param orderNumber - Order number shown after ordering
+   * @param qrCode - QR code of the table the order was placed from
+   */
+  async getCustomerOrderStatus(orderNumber: string, qrCode: string): Promise<CustomerOrderStatus> {
+    const response = await this.request<APIResponse<CustomerOrderStatus>>({
+      method: "GET",
+      url: `/customer/orders/${encodeURIComponent(orderNumber)}/status`,
+      params: { qr_code: qrCode },
+    });
+    if (!response.data) {
+      throw new Error("Order not found");
+    }
+    return response.data;
+  }
+
+  /**
+   * Payment methods guests can choose on the QR order page (no auth required)
+   */
+  async getCustomerPaymentMethods(): Promise<CustomerPaymentMethod[]> {
+    const response = await this.request<APIResponse<CustomerPaymentMethod[]>>({
+      method: "GET",
+      url: "/customer/payment-methods",
+    });
+    return response.data || [];
+  }
+
+  /**
+   * Order behind a customer link (no auth required)
+   * @param token - order_token from createCustomerOrder, or a survey link's token
+   */
+  async getCustomerOrder(token: string): Promise<CustomerOrder> {
+    const response = await this.request<APIResponse<CustomerOrder>>({
+      method: "GET",
+      url: `/customer/orders/${token}`,
+    });
+    if (!response.data) {
+      throw new Error("Order not found");
+    }
+    return response.data;
+  }
+
   /**
    * T084: Create customer payment for QR-based order (no auth required)
-   * @param orderId - UUID of the order
+   * @param orderToken - order_token from createCustomerOrder
    * @param paymentData - Payment details (payment_method, amount, reference_number)
    * @returns Payment confirmation
    */
   async createCustomerPayment(
-    orderId: string,
+    orderToken: string,
     paymentData: CreatePaymentRequest,
   ): Promise<PaymentConfirmation> {
     const response = await this.request<APIResponse<PaymentConfirmation>>({
       method: "POST",
-      url: `/customer/orders/${orderId}/payment`,
+      url: `/customer/orders/${orderToken}/payment`,
       data: paymentData,
     });
     if (!response.data) {
@@ -987,17 +2804,17 @@ class APIClient {
 
   /**
    * T085: Submit satisfaction survey for completed order (no auth required)
-   * @param orderId - UUID of the completed order
+   * @param orderToken - order_token, or the token from the survey invitation
    * @param surveyData - Survey ratings and comments
    * @returns Survey submission confirmation
    */
   async createSurvey(
-    orderId: string,
+    orderToken: string,
     surveyData: CreateSurveyRequest,
   ): Promise<SatisfactionSurvey> {
     const response = await this.request<APIResponse<SatisfactionSurvey>>({
       method: "POST",
-      url: `/customer/orders/${orderId}/survey`,
+      url: `/customer/orders/${orderToken}/survey`,
       data: surveyData,
     });
     if (!response.data) {
@@ -1021,17 +2838,89 @@ class APIClient {
     return response.data;
   }
 
+  async getSurveys(params?: SurveyFilters): Promise<PaginatedResponse<AdminSurvey[]>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/surveys",
+      params,
+    });
+  }
+
+  async getSurvey(id: string): Promise<APIResponse<AdminSurveyDetail>> {
+    return this.request({
+      method: "GET",
+      url: `/admin/surveys/${id}`,
+    });
+  }
+
+  async acknowledgeSurvey(id: string, data: { note?: string | null } = {}): Promise<APIResponse<AdminSurvey>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/surveys/${id}/acknowledge`,
+      data,
+    });
+  }
+
+  async replyToSurvey(
+    id: string,
+    data: { message: string; note?: string | null },
+  ): Promise<APIResponse<{ email_id: string; status: "replied" }>> {
+    return this.request({
+      method: "POST",
+      url: `/admin/surveys/${id}/reply`,
+      data,
+    });
+  }
+
+  async getSurveyTrends(params?: {
+    from?: string;
+    to?: string;
+    branch_id?: string;
+  }): Promise<APIResponse<SurveyTrends>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/surveys/trends",
+      params,
+    });
+  }
+
+  async getSurveysByServer(params?: {
+    from?: string;
+    to?: string;
+    min_surveys?: number;
+    branch_id?: string;
+  }): Promise<APIResponse<SurveysByServer>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/surveys/by-server",
+      params,
+    });
+  }
+
+  async getSurveysByProduct(params?: {
+    from?: string;
+    to?: string;
+    min_surveys?: number;
+    branch_id?: string;
+  }): Promise<APIResponse<SurveysByProduct>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/surveys/by-product",
+      params,
+    });
+  }
+
   /**
    * T077: Get order notifications for customer (no auth required)
-   * @param orderId - UUID of the order
+   * @param orderToken - order_token from createCustomerOrder
    * @returns List of notifications and unread count
    */
   async getOrderNotifications(
-    orderId: string,
+    orderToken: string,
   ): Promise<GetNotificationsResponse> {
     const response = await this.request<APIResponse<GetNotificationsResponse>>({
       method: "GET",
-      url: `/customer/orders/${orderId}/notifications`,
+      url: `/customer/orders/${orderToken}/notifications`,
     });
     if (!response.data) {
       throw new Error("Failed to fetch notifications");
@@ -1041,12 +2930,13 @@ class APIClient {
 
   /**
    * T077: Mark order notification as read (no auth required)
+   * @param orderToken - order_token of the order the notification belongs to
    * @param notificationId - UUID of the notification
    */
-  async markNotificationAsRead(notificationId: string): Promise<void> {
+  async markNotificationAsRead(orderToken: string, notificationId: string): Promise<void> {
     await this.request({
       method: "PUT",
-      url: `/customer/notifications/${notificationId}/read`,
+      url: `/customer/orders/${orderToken}/notifications/${notificationId}/read`,
     });
   }
 
@@ -1055,19 +2945,15 @@ class APIClient {
   // ============================================
 
   /**
-   * Get all ingredients with pagination
-   * @param page - Page number (default: 1)
-   * @param perPage - Items per page (default: 20)
-   * @param search - Search query
-   * @param lowStockOnly - Filter to show only low stock items
-   * @returns Paginated list of ingredients
+   * Get all active ingredients
+   * @param search - Matches the ingredient name
+   * @param low_stock - Only ingredients below their minimum stock
+   * @returns List of ingredients, low and out of stock first
    */
   async getIngredients(params?: {
-    page?: number;
-    per_page?: number;
     search?: string;
     low_stock?: boolean;
-  }): Promise<PaginatedResponse<Ingredient[]>> {
+  }): Promise<APIResponse<Ingredient[]>> {
     return this.request({
       method: "GET",
       url: "/admin/ingredients",
@@ -1122,17 +3008,19 @@ class APIClient {
    * @param id - Ingredient ID
    * @param quantity - Quantity to add
    * @param notes - Optional notes
+   * @param batch - Optional expiry date (YYYY-MM-DD) and supplier lot code of the delivery
    * @returns Updated stock information
    */
   async restockIngredient(
     id: string,
     quantity: number,
     notes?: string,
+    batch?: { expiry_date?: string; batch_code?: string },
   ): Promise<APIResponse<RestockResponse>> {
     return this.request({
       method: "POST",
-      url: `/admin/ingredients/${id}/restock`,
-      data: { quantity, notes },
+      url: "/admin/ingredients/restock",
+      data: { ingredient_id: id, quantity, notes, ...batch },
     });
   }
 
@@ -1159,6 +3047,19 @@ class APIClient {
     });
   }
 
+  /**
+   * Get ingredient batches about to expire
+   * @param days - Warning window in days (defaults to the ingredient_expiry_warning_days setting)
+   * @returns Open batches expiring within the window, expired ones included
+   */
+  async getExpiringIngredients(days?: number): Promise<APIResponse<ExpiringIngredientsResponse>> {
+    return this.request({
+      method: "GET",
+      url: "/admin/ingredients/expiring",
+      params: days !== undefined ? { days } : undefined,
+    });
+  }
+
   // ============================================
   // RECIPE MANAGEMENT (Feature: 007-fix-order-inventory-system)
   // ============================================
@@ -1340,6 +3241,17 @@ class APIClient {
     localStorage.removeItem("pos_user");
   }
 
+  // Generated once per browser and kept across logins, so the server can
+  // keep print preferences for this terminal
+  getDeviceId(): string {
+    let deviceId = localStorage.getItem("pos_device_id");
+    if (!deviceId) {
+      deviceId = crypto.randomUUID();
+      localStorage.setItem("pos_device_id", deviceId);
+    }
+    return deviceId;
+  }
+
   getAuthToken(): string | null {
     return localStorage.getItem("pos_token");
   }
@@ -39,6 +39,10 @@ export function AdminDashboard() {
     return `Rp.${formatted},-`;
   }
 
+  // Today so far against yesterday up to the same time
+  const formatChange = (change?: number | null) =>
+    change === null || change === undefined ? '—' : `${change > 0 ? '+' : ''}${change}%`
+
   if (statsLoading) {
     return (
       <div className="flex items-center justify-center min-h-screen">
@@ -79,7 +83,7 @@ export function AdminDashboard() {
           <CardContent>
             <div className="text-2xl font-bold">{stats?.today_orders || 0}</div>
             <p className="text-xs text-muted-foreground">
-              +12% {t('admin.fromYesterday')}
+              {formatChange(stats?.vs_yesterday?.orders)} {t('admin.fromYesterday')}
             </p>
           </CardContent>
         </Card>
@@ -92,7 +96,7 @@ export function AdminDashboard() {
           <CardContent>
             <div className="text-2xl font-bold">{formatCurrency(stats?.today_revenue || 0)}</div>
             <p className="text-xs text-muted-foreground">
-              +8% {t('admin.fromYesterday')}
+              {formatChange(stats?.vs_yesterday?.revenue)} {t('admin.fromYesterday')}
             </p>
           </CardContent>
         </Card>
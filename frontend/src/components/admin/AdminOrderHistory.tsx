@@ -520,10 +520,14 @@ export function AdminOrderHistory() {
                       unit_price: item.unit_price,
                       total_price: item.total_price,
                       special_instructions: item.special_instructions,
+                      tax_exempt: item.tax_exempt,
+                      service_exempt: item.service_exempt,
                     })),
                     subtotal: selectedOrder.subtotal,
                     tax_amount: selectedOrder.tax_amount,
-                    service_charge: 0,
+                    tax_lines: selectedOrder.tax_lines,
+                    service_charge: selectedOrder.service_charge_amount || 0,
+                    surcharges: selectedOrder.surcharges,
                     discount_amount: selectedOrder.discount_amount,
                     total_amount: selectedOrder.total_amount,
                     payment_method: selectedOrder.payment_method || 'cash',
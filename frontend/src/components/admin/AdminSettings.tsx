@@ -142,6 +142,7 @@ export function AdminSettings() {
         restaurant_name: settings.restaurant_name,
         receipt_header: settings.receipt_header,
         receipt_footer: settings.receipt_footer,
+        receipt_language: settings.receipt_language === "id_en" ? "id_en" : "id",
         paper_size: settings.paper_size,
         currency: settings.currency,
         tax_rate: parseFloat(settings.tax_rate) || 11,
@@ -527,6 +528,29 @@ export function AdminSettings() {
                   />
                 </div>
 
+                <div className="space-y-2">
+                  <Label htmlFor="receipt_language">
+                    {t("settings.receiptLanguage")}
+                  </Label>
+                  <Select
+                    value={settings.receipt_language || "id"}
+                    onValueChange={(value) =>
+                      updateSetting("receipt_language", value)
+                    }
+                  >
+                    <SelectTrigger id="receipt_language">
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="id">Bahasa Indonesia</SelectItem>
+                      <SelectItem value="id_en">Bahasa Indonesia + English</SelectItem>
+                    </SelectContent>
+                  </Select>
+                  <p className="text-xs text-muted-foreground">
+                    Bisa diganti per pesanan atau diingat per pelanggan
+                  </p>
+                </div>
+
                 <div className="space-y-2">
                   <Label htmlFor="paper_size">{t("settings.paperSize")}</Label>
                   <Select
@@ -68,8 +68,8 @@ export default function ContactSubmissions() {
   const { data: contacts = [], isLoading } = useQuery<ContactSubmission[]>({
     queryKey: ['contactSubmissions', statusFilter, startDate, endDate],
     queryFn: async () => {
-      const response = await apiClient.get<ContactSubmission[]>(`/admin/contacts?${queryParams.toString()}`)
-      return response
+      const response = await apiClient.get<{ success: boolean; data: ContactSubmission[] }>(`/admin/contacts?${queryParams.toString()}`)
+      return response.data
     },
   })
 
@@ -57,6 +57,7 @@ interface ProcessPaymentRequest {
   payment_method: 'cash' | 'credit_card' | 'debit_card' | 'digital_wallet'
   amount: number
   reference_number?: string
+  tip_amount?: number
 }
 
 export function CounterInterface() {
@@ -74,6 +75,7 @@ export function CounterInterface() {
   const [paymentMethod, setPaymentMethod] = useState<'cash' | 'credit_card' | 'debit_card' | 'digital_wallet'>('cash')
   const [paymentAmount, setPaymentAmount] = useState('')
   const [referenceNumber, setReferenceNumber] = useState('')
+  const [tipAmount, setTipAmount] = useState('')
   
   const queryClient = useQueryClient()
 
@@ -126,6 +128,13 @@ export function CounterInterface() {
     }
   })
 
+  // Tip suggestions for the selected order's balance
+  const { data: paymentSummary } = useQuery({
+    queryKey: ['paymentSummary', selectedOrder?.id],
+    queryFn: () => apiClient.getPaymentSummary(selectedOrder!.id).then(res => res.data),
+    enabled: !!selectedOrder
+  })
+
   // Process payment mutation
   const processPaymentMutation = useMutation({
     mutationFn: ({ orderId, paymentData }: { orderId: string, paymentData: ProcessPaymentRequest }) => 
@@ -135,6 +144,7 @@ export function CounterInterface() {
       setSelectedOrder(null)
       setPaymentAmount('')
       setReferenceNumber('')
+      setTipAmount('')
       queryClient.invalidateQueries({ queryKey: ['orders'] })
       queryClient.invalidateQueries({ queryKey: ['pendingOrders'] })
     }
@@ -212,7 +222,8 @@ export function CounterInterface() {
     const paymentData: ProcessPaymentRequest = {
       payment_method: paymentMethod,
       amount: parseFloat(paymentAmount),
-      reference_number: referenceNumber || undefined
+      reference_number: referenceNumber || undefined,
+      tip_amount: tipAmount ? parseFloat(tipAmount) : undefined
     }
 
     processPaymentMutation.mutate({ 
@@ -709,6 +720,31 @@ export function CounterInterface() {
                     />
                   </div>
 
+                  <div>
+                    <label className="text-sm font-medium mb-1 block">Tip</label>
+                    {paymentSummary?.tip_suggestions && paymentSummary.tip_suggestions.length > 0 && (
+                      <div className="flex gap-2 mb-2">
+                        {paymentSummary.tip_suggestions.map((suggestion) => (
+                          <Button
+                            key={suggestion.percent}
+                            variant={tipAmount === suggestion.amount.toString() ? 'default' : 'outline'}
+                            size="sm"
+                            onClick={() => setTipAmount(suggestion.amount.toString())}
+                          >
+                            {suggestion.percent}%
+                          </Button>
+                        ))}
+                      </div>
+                    )}
+                    <Input
+                      type="number"
+                      step="0.01"
+                      placeholder="0.00"
+                      value={tipAmount}
+                      onChange={(e) => setTipAmount(e.target.value)}
+                    />
+                  </div>
+
                   {paymentMethod !== 'cash' && (
                     <div>
                       <label className="text-sm font-medium mb-1 block">Reference Number</label>
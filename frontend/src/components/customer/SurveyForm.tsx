@@ -26,8 +26,8 @@ const surveySchema = z.object({
 type SurveyFormData = z.infer<typeof surveySchema>
 
 interface SurveyFormProps {
-  /** Order ID for the survey */
-  orderId: string
+  /** Order or survey link token of the order being rated */
+  orderToken: string
   /** Callback on successful submission */
   onSuccess?: () => void
   /** Callback on error */
@@ -49,12 +49,12 @@ const ratingCategories = [
  * @example
  * ```tsx
  * <SurveyForm
- *   orderId="order-123"
+ *   orderToken={token}
  *   onSuccess={() => console.log('Survey submitted!')}
  * />
  * ```
  */
-export function SurveyForm({ orderId, onSuccess, onError, className }: SurveyFormProps) {
+export function SurveyForm({ orderToken, onSuccess, onError, className }: SurveyFormProps) {
   const [submitSuccess, setSubmitSuccess] = useState(false)
   const [hoveredRatings, setHoveredRatings] = useState<Record<string, number>>({})
 
@@ -94,7 +94,7 @@ export function SurveyForm({ orderId, onSuccess, onError, className }: SurveyFor
         ambiance: data.ambiance_rating,
         comments: data.comments,
       }
-      return apiClient.createSurvey(orderId, request)
+      return apiClient.createSurvey(orderToken, request)
     },
     onSuccess: () => {
       setSubmitSuccess(true)
@@ -384,6 +384,7 @@ export const roleOptions: SelectOption[] = [
   { value: 'server', label: 'Server' },
   { value: 'counter', label: 'Counter/Checkout' },
   { value: 'kitchen', label: 'Kitchen Staff' },
+  { value: 'courier', label: 'Courier' },
 ]
 
 // POS-specific status options
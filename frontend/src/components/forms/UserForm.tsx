@@ -48,7 +48,7 @@ export function UserForm({ user, onSuccess, onCancel, mode = 'create' }: UserFor
         email: user.email,
         first_name: user.first_name,
         last_name: user.last_name,
-        role: user.role as 'admin' | 'manager' | 'kitchen' | 'server' | 'counter',
+        role: user.role as 'admin' | 'manager' | 'kitchen' | 'server' | 'counter' | 'courier',
         password: '', // Don't pre-fill password for editing
       }
     : {
@@ -335,6 +335,13 @@ export function NewEnhancedKitchenLayout({
             </div>
           )}
 
+          {order.has_allergy_alert && (
+            <div className="flex items-center gap-2 rounded-lg bg-red-600 text-white px-3 py-2 text-sm font-bold">
+              <AlertCircle className="w-5 h-5 flex-shrink-0" />
+              ALLERGY ALERT - check flagged items with the server
+            </div>
+          )}
+
           {/* Progress Bar */}
           <div className="w-full bg-muted dark:bg-muted/50 rounded-full h-3 mt-3">
             <div
@@ -369,7 +376,11 @@ export function NewEnhancedKitchenLayout({
                     "flex items-start space-x-4 p-4 rounded-lg border-2 transition-colors",
                     isServed
                       ? "bg-muted/50 border-border opacity-75"
-                      : "bg-card hover:border-blue-500/50",
+                      : item.allergy_alert
+                        ? "bg-red-500/10 border-red-600"
+                        : item.kitchen_flags?.includes("attention")
+                          ? "bg-card border-amber-500"
+                          : "bg-card hover:border-blue-500/50",
                   )}
                 >
                   <button
@@ -407,6 +418,31 @@ export function NewEnhancedKitchenLayout({
                       )}
                     </div>
 
+                    {item.allergy_alert && (
+                      <div className="flex items-center gap-1 text-sm font-bold text-red-700 dark:text-red-400 mb-2">
+                        <AlertCircle className="w-4 h-4" />
+                        ALLERGY
+                      </div>
+                    )}
+
+                    {item.note_phrase_labels && item.note_phrase_labels.length > 0 && (
+                      <div className="flex flex-wrap gap-1 mb-2">
+                        {item.note_phrase_labels.map((label) => (
+                          <span
+                            key={label}
+                            className={cn(
+                              "text-xs font-medium px-2 py-1 rounded",
+                              item.allergy_alert
+                                ? "bg-red-600 text-white"
+                                : "bg-blue-500/10 text-blue-700 dark:text-blue-400",
+                            )}
+                          >
+                            {label}
+                          </span>
+                        ))}
+                      </div>
+                    )}
+
                     {item.special_instructions && (
                       <div className="text-sm bg-yellow-500/10 border border-yellow-500/20 rounded p-2 text-yellow-700 dark:text-yellow-400">
                         <strong>Special:</strong> {item.special_instructions}
@@ -7,7 +7,7 @@ import {
   Eye,
   Loader2,
 } from 'lucide-react';
-import { receiptPrinter } from '@/services/receiptPrinter';
+import { receiptPrinter, type ReceiptLanguage } from '@/services/receiptPrinter';
 import { toastHelpers } from '@/lib/toast-helpers';
 
 interface ReceiptPrintButtonProps {
@@ -23,9 +23,16 @@ interface ReceiptPrintButtonProps {
       unit_price: number;
       total_price: number;
       special_instructions?: string;
+      tax_exempt?: boolean;
+      service_exempt?: boolean;
     }>;
     subtotal: number;
     tax_amount: number;
+    tax_lines?: Array<{
+      label: string;
+      rate: number | null;
+      tax_amount: number;
+    }>;
     service_charge?: number;
     discount_amount?: number;
     total_amount: number;
@@ -33,6 +40,7 @@ interface ReceiptPrintButtonProps {
     payment_amount?: number;
     change_amount?: number;
     cashier_name?: string;
+    receipt_language?: ReceiptLanguage;
   };
   settings?: {
     restaurant_name?: string;
@@ -42,6 +50,7 @@ interface ReceiptPrintButtonProps {
     show_logo?: boolean;
     tax_rate?: number;
     currency?: string;
+    receipt_language?: ReceiptLanguage;
   };
   variant?: 'default' | 'outline' | 'ghost';
   size?: 'default' | 'sm' | 'lg' | 'icon';
@@ -155,6 +155,7 @@ export function PaymentConfirmationModal({
         restaurant_name: getSettingString("restaurant_name", "Steak Kenangan"),
         receipt_header: settingsData?.receipt_header ? String(settingsData.receipt_header) : undefined,
         receipt_footer: settingsData?.receipt_footer ? String(settingsData.receipt_footer) : undefined,
+        receipt_language: settingsData?.receipt_language === "id_en" ? "id_en" : "id",
         paper_size: paperSize,
         currency: getSettingString("currency", "IDR"),
         tax_rate: getSettingNumber("tax_rate", 11),
@@ -0,0 +1,46 @@
+// Device details sent with customer QR orders so staff can spot orders
+// placed to a table from outside the restaurant.
+
+const FINGERPRINT_KEY = 'order_device_id'
+const LOCATION_TIMEOUT_MS = 5000
+
+export interface OrderLocation {
+  latitude: number
+  longitude: number
+  accuracy: number
+}
+
+/** A random ID kept in this browser, so repeat orders from one phone can be linked. */
+export function getDeviceFingerprint(): string | undefined {
+  try {
+    let id = localStorage.getItem(FINGERPRINT_KEY)
+    if (!id) {
+      id = crypto.randomUUID()
+      localStorage.setItem(FINGERPRINT_KEY, id)
+    }
+    return id
+  } catch {
+    // Private browsing or storage disabled
+    return undefined
+  }
+}
+
+/**
+ * The guest's approximate position, or null when they decline or it takes
+ * too long. Never rejects; the order goes ahead either way.
+ */
+export function getCoarseLocation(): Promise<OrderLocation | null> {
+  if (typeof navigator === 'undefined' || !navigator.geolocation) return Promise.resolve(null)
+  return new Promise((resolve) => {
+    navigator.geolocation.getCurrentPosition(
+      (position) =>
+        resolve({
+          latitude: position.coords.latitude,
+          longitude: position.coords.longitude,
+          accuracy: position.coords.accuracy,
+        }),
+      () => resolve(null),
+      { enableHighAccuracy: false, timeout: LOCATION_TIMEOUT_MS, maximumAge: 300_000 },
+    )
+  })
+}
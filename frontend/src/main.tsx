@@ -7,10 +7,14 @@ import { ErrorBoundary } from "@/components/ErrorBoundary";
 import { OfflineIndicator } from "@/components/OfflineIndicator";
 import { ThemeProvider } from "@/components/theme-provider";
 import { queryClient } from "@/lib/queryClient";
+import { restorePrintPreferences } from "@/services/devicePrintPreferences";
 import * as Sentry from "@sentry/react";
 import "./i18n"; // Import i18n configuration
 import "./index.css";
 
+// This terminal's printers, as set at the last login
+restorePrintPreferences();
+
 // Initialize Sentry error tracking (optional)
 const sentryDsn = import.meta.env.VITE_SENTRY_DSN;
 if (sentryDsn) {
@@ -22,7 +22,7 @@ import { Route as SiteMenuRouteImport } from './routes/site/menu'
 import { Route as SiteContactRouteImport } from './routes/site/contact'
 import { Route as SiteAboutRouteImport } from './routes/site/about'
 import { Route as OrderTableCodeRouteImport } from './routes/order.$tableCode'
-import { Route as OrderStatusOrderIdRouteImport } from './routes/order-status.$orderId'
+import { Route as OrderStatusTokenRouteImport } from './routes/order-status.$token'
 import { Route as CustomerSurveyRouteImport } from './routes/customer/survey'
 import { Route as AdminTablesRouteImport } from './routes/admin/tables'
 import { Route as AdminStaffRouteImport } from './routes/admin/staff'
@@ -104,9 +104,9 @@ const OrderTableCodeRoute = OrderTableCodeRouteImport.update({
   path: '/order/$tableCode',
   getParentRoute: () => rootRouteImport,
 } as any)
-const OrderStatusOrderIdRoute = OrderStatusOrderIdRouteImport.update({
-  id: '/order-status/$orderId',
-  path: '/order-status/$orderId',
+const OrderStatusTokenRoute = OrderStatusTokenRouteImport.update({
+  id: '/order-status/$token',
+  path: '/order-status/$token',
   getParentRoute: () => rootRouteImport,
 } as any)
 const CustomerSurveyRoute = CustomerSurveyRouteImport.update({
@@ -205,7 +205,7 @@ export interface FileRoutesByFullPath {
   '/admin/staff': typeof AdminStaffRoute
   '/admin/tables': typeof AdminTablesRoute
   '/customer/survey': typeof CustomerSurveyRoute
-  '/order-status/$orderId': typeof OrderStatusOrderIdRoute
+  '/order-status/$token': typeof OrderStatusTokenRoute
   '/order/$tableCode': typeof OrderTableCodeRoute
   '/site/about': typeof SiteAboutRoute
   '/site/contact': typeof SiteContactRoute
@@ -235,7 +235,7 @@ export interface FileRoutesByTo {
   '/admin/staff': typeof AdminStaffRoute
   '/admin/tables': typeof AdminTablesRoute
   '/customer/survey': typeof CustomerSurveyRoute
-  '/order-status/$orderId': typeof OrderStatusOrderIdRoute
+  '/order-status/$token': typeof OrderStatusTokenRoute
   '/order/$tableCode': typeof OrderTableCodeRoute
   '/site/about': typeof SiteAboutRoute
   '/site/contact': typeof SiteContactRoute
@@ -267,7 +267,7 @@ export interface FileRoutesById {
   '/admin/staff': typeof AdminStaffRoute
   '/admin/tables': typeof AdminTablesRoute
   '/customer/survey': typeof CustomerSurveyRoute
-  '/order-status/$orderId': typeof OrderStatusOrderIdRoute
+  '/order-status/$token': typeof OrderStatusTokenRoute
   '/order/$tableCode': typeof OrderTableCodeRoute
   '/site/about': typeof SiteAboutRoute
   '/site/contact': typeof SiteContactRoute
@@ -300,7 +300,7 @@ export interface FileRouteTypes {
     | '/admin/staff'
     | '/admin/tables'
     | '/customer/survey'
-    | '/order-status/$orderId'
+    | '/order-status/$token'
     | '/order/$tableCode'
     | '/site/about'
     | '/site/contact'
@@ -330,7 +330,7 @@ export interface FileRouteTypes {
     | '/admin/staff'
     | '/admin/tables'
     | '/customer/survey'
-    | '/order-status/$orderId'
+    | '/order-status/$token'
     | '/order/$tableCode'
     | '/site/about'
     | '/site/contact'
@@ -361,7 +361,7 @@ export interface FileRouteTypes {
     | '/admin/staff'
     | '/admin/tables'
     | '/customer/survey'
-    | '/order-status/$orderId'
+    | '/order-status/$token'
     | '/order/$tableCode'
     | '/site/about'
     | '/site/contact'
@@ -379,7 +379,7 @@ export interface RootRouteChildren {
   KitchenRoute: typeof KitchenRoute
   LoginRoute: typeof LoginRoute
   CustomerSurveyRoute: typeof CustomerSurveyRoute
-  OrderStatusOrderIdRoute: typeof OrderStatusOrderIdRoute
+  OrderStatusTokenRoute: typeof OrderStatusTokenRoute
   OrderTableCodeRoute: typeof OrderTableCodeRoute
   SiteAboutRoute: typeof SiteAboutRoute
   SiteContactRoute: typeof SiteContactRoute
@@ -483,11 +483,11 @@ declare module '@tanstack/react-router' {
       preLoaderRoute: typeof OrderTableCodeRouteImport
       parentRoute: typeof rootRouteImport
     }
-    '/order-status/$orderId': {
-      id: '/order-status/$orderId'
-      path: '/order-status/$orderId'
-      fullPath: '/order-status/$orderId'
-      preLoaderRoute: typeof OrderStatusOrderIdRouteImport
+    '/order-status/$token': {
+      id: '/order-status/$token'
+      path: '/order-status/$token'
+      fullPath: '/order-status/$token'
+      preLoaderRoute: typeof OrderStatusTokenRouteImport
       parentRoute: typeof rootRouteImport
     }
     '/customer/survey': {
@@ -642,7 +642,7 @@ const rootRouteChildren: RootRouteChildren = {
   KitchenRoute: KitchenRoute,
   LoginRoute: LoginRoute,
   CustomerSurveyRoute: CustomerSurveyRoute,
-  OrderStatusOrderIdRoute: OrderStatusOrderIdRoute,
+  OrderStatusTokenRoute: OrderStatusTokenRoute,
   OrderTableCodeRoute: OrderTableCodeRoute,
   SiteAboutRoute: SiteAboutRoute,
   SiteContactRoute: SiteContactRoute,
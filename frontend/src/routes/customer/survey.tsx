@@ -10,20 +10,20 @@ export const Route = createFileRoute('/customer/survey')({
   component: SurveyPage,
   validateSearch: (search: Record<string, unknown>) => {
     return {
-      order_id: (search.order_id as string) || '',
+      token: (search.token as string) || '',
     }
   },
 })
 
 function SurveyPage() {
   const navigate = useNavigate()
-  const { order_id } = Route.useSearch()
+  const { token } = Route.useSearch()
 
   // Fetch order details to show order number
   const { data: orderData, isLoading, isError } = useQuery({
-    queryKey: ['order', order_id],
-    queryFn: () => apiClient.getOrder(order_id).then(res => res.data),
-    enabled: !!order_id,
+    queryKey: ['customer-order', token],
+    queryFn: () => apiClient.getCustomerOrder(token),
+    enabled: !!token,
   })
 
   const handleSurveySuccess = () => {
@@ -33,13 +33,13 @@ function SurveyPage() {
     }, 2000)
   }
 
-  if (!order_id) {
+  if (!token) {
     return (
       <div className="container mx-auto px-4 py-8">
         <Alert variant="destructive">
           <AlertCircle className="h-4 w-4" />
           <AlertDescription>
-            Order ID is required. Please access this page from a valid order link.
+            Please access this page from the link in your survey invitation.
           </AlertDescription>
         </Alert>
       </div>
@@ -64,7 +64,7 @@ function SurveyPage() {
         <Alert variant="destructive">
           <AlertCircle className="h-4 w-4" />
           <AlertDescription>
-            Failed to load order details. Please try again later.
+            This survey link is invalid or has expired.
           </AlertDescription>
         </Alert>
       </div>
@@ -84,7 +84,7 @@ function SurveyPage() {
         </CardHeader>
         <CardContent>
           <SurveyForm 
-            orderId={order_id} 
+            orderToken={token}
             onSuccess={handleSurveySuccess}
           />
         </CardContent>
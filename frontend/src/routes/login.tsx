@@ -8,6 +8,7 @@ import { Label } from '@/components/ui/label'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
 import { Checkbox } from '@/components/ui/checkbox'
 import { apiClient } from '@/api/client'
+import { applyPrintPreferences } from '@/services/devicePrintPreferences'
 import type { LoginRequest, LoginResponse, APIResponse } from '@/types'
 import '@/styles/public-theme.css'
 
@@ -67,6 +68,7 @@ function LoginPage() {
       if (data.success && data.data) {
         apiClient.setAuthToken(data.data.token)
         localStorage.setItem('pos_user', JSON.stringify(data.data.user))
+        applyPrintPreferences(data.data.print_preferences)
 
         // Store remember me preference
         if (rememberMe) {
@@ -2,7 +2,7 @@
  * T087: Order Status Tracking Page
  * T088: Includes survey prompt after order completion
  *
- * Route: /order-status/:orderId
+ * Route: /order-status/:token (the order_token returned when ordering)
  * Customer can track their order status and submit satisfaction survey
  */
 import {
@@ -34,10 +34,10 @@ import {
   DialogTitle,
 } from "@/components/ui/dialog";
 import { SurveyForm } from "@/components/customer/SurveyForm";
-import type { Order, OrderStatus as OrderStatusType } from "@/types";
+import type { OrderStatus as OrderStatusType } from "@/types";
 import "@/styles/public-theme.css";
 
-export const Route = createFileRoute("/order-status/$orderId")({
+export const Route = createFileRoute("/order-status/$token")({
   component: OrderStatusPage,
 });
 
@@ -114,7 +114,7 @@ const statusOrder: OrderStatusType[] = [
 ];
 
 function OrderStatusPage() {
-  const { orderId } = useParams({ from: "/order-status/$orderId" });
+  const { token } = useParams({ from: "/order-status/$token" });
   const navigate = useNavigate();
   const [showSurvey, setShowSurvey] = useState(false);
   const [surveySubmitted, setSurveySubmitted] = useState(false);
@@ -129,15 +129,10 @@ function OrderStatusPage() {
     error,
     refetch,
   } = useQuery({
-    queryKey: ["order-status", orderId],
-    queryFn: async () => {
-      const response = await apiClient.get<{ success: boolean; data: Order }>(
-        `/customer/orders/${orderId}`,
-      );
-      return response.data;
-    },
+    queryKey: ["order-status", token],
+    queryFn: () => apiClient.getCustomerOrder(token),
     refetchInterval: 5000, // Poll every 5 seconds
-    enabled: !!orderId,
+    enabled: !!token,
   });
 
   const order = orderData;
@@ -150,7 +145,8 @@ function OrderStatusPage() {
       // Show survey prompt when order is served or completed (and not already submitted)
       if (
         (order.status === "served" || order.status === "completed") &&
-        !surveySubmitted
+        !surveySubmitted &&
+        !order.survey_submitted
       ) {
         // Delay survey prompt to let customer enjoy the notification
         const timer = setTimeout(() => {
@@ -195,7 +191,7 @@ function OrderStatusPage() {
               Pesanan Tidak Ditemukan
             </h1>
             <p className="text-[var(--public-text-secondary)] mb-6">
-              Tidak dapat menemukan pesanan dengan ID tersebut.
+              Tautan pesanan tidak valid atau sudah kedaluwarsa.
             </p>
             <Button
               onClick={() => navigate({ to: "/site" })}
@@ -396,7 +392,8 @@ function OrderStatusPage() {
 
         {/* Survey prompt for served/completed orders */}
         {(order.status === "served" || order.status === "completed") &&
-          !surveySubmitted && (
+          !surveySubmitted &&
+          !order.survey_submitted && (
             <Card className="mb-6 bg-gradient-to-r from-amber-50 to-orange-50 border-amber-200">
               <CardContent className="py-6 text-center">
                 <Star className="w-10 h-10 mx-auto mb-3 text-amber-500" />
@@ -452,7 +449,7 @@ function OrderStatusPage() {
             </DialogTitle>
           </DialogHeader>
           <SurveyForm
-            orderId={orderId}
+            orderToken={token}
             onSuccess={() => {
               setSurveySubmitted(true);
               setShowSurvey(false);
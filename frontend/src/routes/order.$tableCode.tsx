@@ -11,6 +11,7 @@ import { useState } from "react";
 import { useQuery, useMutation } from "@tanstack/react-query";
 import { ShoppingCart, Plus, Minus, Check, AlertCircle } from "lucide-react";
 import apiClient from "@/api/client";
+import { getCoarseLocation, getDeviceFingerprint } from "@/lib/order-source";
 import { Button } from "@/components/ui/button";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Input } from "@/components/ui/input";
@@ -49,6 +50,7 @@ function CustomerOrderPage() {
   const [currentStep, setCurrentStep] = useState<OrderStep>("menu");
   const [orderInfo, setOrderInfo] = useState<{
     order_id: string;
+    order_token: string;
     order_number: string;
     total_amount: number;
   } | null>(null);
@@ -83,7 +85,7 @@ function CustomerOrderPage() {
 
   // Create order mutation
   const createOrderMutation = useMutation({
-    mutationFn: () =>
+    mutationFn: async () =>
       apiClient.createCustomerOrder({
         table_id: table!.id,
         customer_name: customerName || undefined,
@@ -92,11 +94,14 @@ function CustomerOrderPage() {
           quantity: item.quantity,
           special_instructions: item.special_instructions,
         })),
+        device_fingerprint: getDeviceFingerprint(),
+        location: await getCoarseLocation(),
       }),
     onSuccess: (data) => {
       // T086: Store order info and proceed to payment step
       setOrderInfo({
         order_id: data.order_id,
+        order_token: data.order_token,
         order_number: data.order_number,
         total_amount: data.total_amount,
       });
@@ -108,7 +113,7 @@ function CustomerOrderPage() {
   // T086: Payment mutation
   const paymentMutation = useMutation({
     mutationFn: (paymentData: CreatePaymentRequest) =>
-      apiClient.createCustomerPayment(orderInfo!.order_id, paymentData),
+      apiClient.createCustomerPayment(orderInfo!.order_token, paymentData),
     onSuccess: (data) => {
       // T086: Store payment confirmation and show confirmation step
       setPaymentConfirmation({
@@ -137,12 +142,19 @@ function CustomerOrderPage() {
   // T086: Navigate to order status/tracking page
   const handleProceedToTracking = () => {
     if (orderInfo) {
-      navigate({ to: `/order-status/${orderInfo.order_id}` });
+      navigate({ to: `/order-status/${orderInfo.order_token}` });
     }
   };
 
   const addToCart = (product: PublicMenuItem) => {
     const existing = cart.find((item) => item.product.id === product.id);
+    if (product.in_stock === false) return;
+    if (
+      product.remaining_quantity != null &&
+      (existing?.quantity ?? 0) >= product.remaining_quantity
+    ) {
+      return;
+    }
     if (existing) {
       setCart(
         cart.map((item) =>
@@ -420,6 +432,13 @@ function CustomerOrderPage() {
                       {formatCurrency(item.price)}
                     </p>
                   </div>
+                  {item.in_stock !== false &&
+                    item.remaining_quantity != null &&
+                    item.remaining_quantity <= 10 && (
+                      <p className="text-xs font-medium text-[var(--public-secondary)] mt-1">
+                        Only {item.remaining_quantity} left
+                      </p>
+                    )}
                 </CardHeader>
                 <CardContent>
                   {cartItem ? (
@@ -439,11 +458,19 @@ function CustomerOrderPage() {
                         size="sm"
                         variant="outline"
                         onClick={() => addToCart(item)}
+                        disabled={
+                          item.remaining_quantity != null &&
+                          cartItem.quantity >= item.remaining_quantity
+                        }
                         className="h-10 w-10"
                       >
                         <Plus className="w-4 h-4" />
                       </Button>
                     </div>
+                  ) : item.in_stock === false ? (
+                    <Button disabled variant="outline" className="w-full">
+                      Sold Out
+                    </Button>
                   ) : (
                     <Button
                       onClick={() => addToCart(item)}
@@ -0,0 +1,41 @@
+import type { DevicePrintPreferences } from '@/types';
+import { receiptPrinter } from './receiptPrinter';
+import { kitchenPrinter } from './kitchenPrinter';
+
+const STORAGE_KEY = 'pos_print_preferences';
+
+/**
+ * Apply this terminal's print preferences (from login) to the printers.
+ * Preferences left null keep the system settings.
+ */
+export function applyPrintPreferences(prefs: DevicePrintPreferences | null | undefined) {
+  if (!prefs) {
+    localStorage.removeItem(STORAGE_KEY);
+    return;
+  }
+  localStorage.setItem(STORAGE_KEY, JSON.stringify(prefs));
+
+  receiptPrinter.updateSettings({
+    ...(prefs.receipt_printer != null && { printer_name: prefs.receipt_printer }),
+    ...(prefs.receipt_copies != null && { print_copies: prefs.receipt_copies }),
+    ...(prefs.auto_print_receipt != null && { auto_print: prefs.auto_print_receipt }),
+  });
+  kitchenPrinter.updateSettings({
+    ...(prefs.kitchen_printer != null && { kitchen_printer_name: prefs.kitchen_printer }),
+    ...(prefs.kitchen_copies != null && { print_copies: prefs.kitchen_copies }),
+    ...(prefs.auto_print_kitchen != null && { auto_print_kitchen: prefs.auto_print_kitchen }),
+  });
+}
+
+/**
+ * Re-apply the stored preferences after a page reload
+ */
+export function restorePrintPreferences() {
+  const stored = localStorage.getItem(STORAGE_KEY);
+  if (!stored) return;
+  try {
+    applyPrintPreferences(JSON.parse(stored));
+  } catch {
+    localStorage.removeItem(STORAGE_KEY);
+  }
+}
@@ -8,6 +8,7 @@ interface KitchenTicketSettings {
   show_logo?: boolean;
   auto_print_kitchen?: boolean;
   kitchen_printer_name?: string;
+  print_copies?: number;
 }
 
 interface KitchenOrderItem {
@@ -442,10 +443,14 @@ class KitchenPrinterService {
    * Auto-print kitchen ticket based on settings
    */
   async autoPrintIfEnabled(data: KitchenTicketData): Promise<boolean> {
-    if (this.settings.auto_print_kitchen) {
-      return this.printKitchenTicket(data);
+    if (!this.settings.auto_print_kitchen) {
+      return false;
+    }
+    const copies = this.settings.print_copies ?? 1;
+    for (let i = 0; i < copies; i++) {
+      if (!(await this.printKitchenTicket(data))) return false;
     }
-    return false;
+    return true;
   }
 
   /**
@@ -17,9 +17,61 @@ export class PrintError extends Error {
   }
 }
 
+/**
+ * 'id' prints Bahasa Indonesia only; 'id_en' adds English after each label
+ */
+export type ReceiptLanguage = 'id' | 'id_en';
+
+type ReceiptLabel =
+  | 'order_number' | 'date' | 'order_type' | 'table' | 'name' | 'cashier'
+  | 'tax_exempt' | 'service_exempt' | 'subtotal' | 'service_charge' | 'tax' | 'discount' | 'total'
+  | 'payment_method' | 'paid' | 'change' | 'thank_you';
+
+// Receipt wording in Bahasa Indonesia and English
+const RECEIPT_LABELS: Record<ReceiptLabel, { id: string; en: string }> = {
+  order_number: { id: 'No. Pesanan', en: 'Order No.' },
+  date: { id: 'Tanggal', en: 'Date' },
+  order_type: { id: 'Jenis', en: 'Type' },
+  table: { id: 'Meja', en: 'Table' },
+  name: { id: 'Nama', en: 'Name' },
+  cashier: { id: 'Kasir', en: 'Cashier' },
+  tax_exempt: { id: 'Bebas pajak', en: 'Tax exempt' },
+  service_exempt: { id: 'Tanpa biaya layanan', en: 'No service charge' },
+  subtotal: { id: 'Subtotal', en: 'Subtotal' },
+  service_charge: { id: 'Biaya Layanan', en: 'Service Charge' },
+  tax: { id: 'Pajak', en: 'Tax' },
+  discount: { id: 'Diskon', en: 'Discount' },
+  total: { id: 'TOTAL', en: 'TOTAL' },
+  payment_method: { id: 'Metode Bayar', en: 'Payment' },
+  paid: { id: 'Dibayar', en: 'Paid' },
+  change: { id: 'Kembalian', en: 'Change' },
+  thank_you: { id: 'Terima kasih atas kunjungan Anda!', en: 'Thank you for your visit!' },
+};
+
+const ORDER_TYPE_LABELS: Record<string, { id: string; en: string }> = {
+  dine_in: { id: 'Makan di Tempat', en: 'Dine In' },
+  takeaway: { id: 'Bawa Pulang', en: 'Takeaway' },
+  takeout: { id: 'Bawa Pulang', en: 'Takeaway' },
+  delivery: { id: 'Antar', en: 'Delivery' },
+};
+
+const PAYMENT_METHOD_LABELS: Record<string, { id: string; en: string }> = {
+  cash: { id: 'Tunai', en: 'Cash' },
+  card: { id: 'Kartu', en: 'Card' },
+  digital_wallet: { id: 'Dompet Digital', en: 'E-Wallet' },
+  qris: { id: 'QRIS', en: 'QRIS' },
+};
+
+// Labels the backend gives tax lines of items priced without a tax class
+const TAX_LINE_LABELS: Record<string, { id: string; en: string }> = {
+  Tax: { id: 'Pajak', en: 'Tax' },
+  'Tax exempt': { id: 'Bebas pajak', en: 'Tax exempt' },
+};
+
 interface ReceiptSettings {
   printer_name?: string;
   print_copies?: number;
+  auto_print?: boolean;
   restaurant_name?: string;
   receipt_header?: string;
   receipt_footer?: string;
@@ -28,6 +80,8 @@ interface ReceiptSettings {
   tax_rate?: number;
   service_charge?: number;
   currency?: string;
+  /** Used when the order doesn't say */
+  receipt_language?: ReceiptLanguage;
 }
 
 interface OrderItem {
@@ -36,6 +90,19 @@ interface OrderItem {
   unit_price: number;
   total_price: number;
   special_instructions?: string;
+  tax_exempt?: boolean;
+  service_exempt?: boolean;
+}
+
+interface ReceiptTaxLine {
+  label: string;
+  rate: number | null;
+  tax_amount: number;
+}
+
+interface ReceiptSurcharge {
+  name: string;
+  amount: number;
 }
 
 interface ReceiptData {
@@ -47,13 +114,19 @@ interface ReceiptData {
   items: OrderItem[];
   subtotal: number;
   tax_amount: number;
+  /** Tax per tax class; without it the tax is one line at the settings rate */
+  tax_lines?: ReceiptTaxLine[];
   service_charge?: number;
+  /** Holiday and event surcharges, printed by name */
+  surcharges?: ReceiptSurcharge[];
   discount_amount?: number;
   total_amount: number;
   payment_method?: string;
   payment_amount?: number;
   change_amount?: number;
   cashier_name?: string;
+  /** The order's receipt language (order choice, customer preference or default) */
+  receipt_language?: ReceiptLanguage;
 }
 
 class ReceiptPrinterService {
@@ -62,6 +135,7 @@ class ReceiptPrinterService {
     paper_size: '80mm',
     currency: 'IDR',
     tax_rate: 11,
+    receipt_language: 'id',
   };
 
   /**
@@ -71,6 +145,26 @@ class ReceiptPrinterService {
     this.settings = { ...this.settings, ...settings };
   }
 
+  /**
+   * Wording in the receipt's language: Bahasa Indonesia, followed by English
+   * when the receipt is bilingual and the two differ
+   */
+  private translate(text: { id: string; en: string }, language: ReceiptLanguage): string {
+    return language === 'id_en' && text.en !== text.id ? `${text.id} / ${text.en}` : text.id;
+  }
+
+  private label(key: ReceiptLabel, language: ReceiptLanguage): string {
+    return this.translate(RECEIPT_LABELS[key], language);
+  }
+
+  /**
+   * Markers for items exempt from tax (P) or service charge (L)
+   */
+  private exemptionMarks(item: OrderItem): string {
+    const marks = [item.tax_exempt ? 'P' : '', item.service_exempt ? 'L' : ''].filter(Boolean);
+    return marks.length > 0 ? ` (${marks.join(',')})` : '';
+  }
+
   /**
    * Format currency based on settings
    */
@@ -95,10 +189,12 @@ class ReceiptPrinterService {
     const paperSize = this.settings.paper_size || '80mm';
     const width = paperSize === '58mm' ? '58mm' : '80mm';
     const fontSize = paperSize === '58mm' ? '10px' : '12px';
+    const lang = data.receipt_language ?? this.settings.receipt_language ?? 'id';
+    const t = (key: ReceiptLabel) => this.label(key, lang);
 
     return `
 <!DOCTYPE html>
-<html>
+<html lang="id">
 <head>
   <meta charset="UTF-8">
   <style>
@@ -218,32 +314,32 @@ class ReceiptPrinterService {
   <!-- Order Info -->
   <div class="section">
     <div class="info-row">
-      <span>No. Pesanan:</span>
+      <span>${t('order_number')}:</span>
       <span><strong>#${data.order_number}</strong></span>
     </div>
     <div class="info-row">
-      <span>Tanggal:</span>
+      <span>${t('date')}:</span>
       <span>${format(new Date(data.order_date), 'dd MMM yyyy HH:mm', { locale: localeId })}</span>
     </div>
     <div class="info-row">
-      <span>Jenis:</span>
-      <span>${this.formatOrderType(data.order_type)}</span>
+      <span>${t('order_type')}:</span>
+      <span>${this.formatOrderType(data.order_type, lang)}</span>
     </div>
     ${data.table_number ? `
     <div class="info-row">
-      <span>Meja:</span>
+      <span>${t('table')}:</span>
       <span>${data.table_number}</span>
     </div>
     ` : ''}
     ${data.customer_name ? `
     <div class="info-row">
-      <span>Nama:</span>
+      <span>${t('name')}:</span>
       <span>${data.customer_name}</span>
     </div>
     ` : ''}
     ${data.cashier_name ? `
     <div class="info-row">
-      <span>Kasir:</span>
+      <span>${t('cashier')}:</span>
       <span>${data.cashier_name}</span>
     </div>
     ` : ''}
@@ -253,7 +349,7 @@ class ReceiptPrinterService {
   <div class="items">
     ${data.items.map(item => `
       <div class="item">
-        <div class="item-name">${item.product_name}</div>
+        <div class="item-name">${item.product_name}${this.exemptionMarks(item)}</div>
         <div class="item-details">
           <span>${item.quantity} x ${this.formatCurrency(item.unit_price)}</span>
           <span>${this.formatCurrency(item.total_price)}</span>
@@ -265,30 +361,50 @@ class ReceiptPrinterService {
     `).join('')}
   </div>
 
+  ${data.items.some(item => item.tax_exempt || item.service_exempt) ? `
+  <div class="section">
+    ${data.items.some(item => item.tax_exempt) ? `<div class="item-instructions">(P) ${t('tax_exempt')}</div>` : ''}
+    ${data.items.some(item => item.service_exempt) ? `<div class="item-instructions">(L) ${t('service_exempt')}</div>` : ''}
+  </div>
+  ` : ''}
+
   <!-- Totals -->
   <div class="totals">
     <div class="total-row">
-      <span>Subtotal:</span>
+      <span>${t('subtotal')}:</span>
       <span>${this.formatCurrency(data.subtotal)}</span>
     </div>
     ${data.service_charge && data.service_charge > 0 ? `
     <div class="total-row">
-      <span>Biaya Layanan:</span>
+      <span>${t('service_charge')}:</span>
       <span>${this.formatCurrency(data.service_charge)}</span>
     </div>
     ` : ''}
+    ${(data.surcharges || []).filter(surcharge => surcharge.amount > 0).map(surcharge => `
+    <div class="total-row">
+      <span>${surcharge.name}:</span>
+      <span>${this.formatCurrency(surcharge.amount)}</span>
+    </div>
+    `).join('')}
+    ${data.tax_lines && data.tax_lines.length > 0 ? data.tax_lines.filter(line => line.tax_amount > 0).map(line => `
     <div class="total-row">
-      <span>Pajak (${this.settings.tax_rate || 11}%):</span>
+      <span>${this.formatTaxLabel(line.label, lang)}${line.rate !== null ? ` (${line.rate}%)` : ''}:</span>
+      <span>${this.formatCurrency(line.tax_amount)}</span>
+    </div>
+    `).join('') : `
+    <div class="total-row">
+      <span>${t('tax')} (${this.settings.tax_rate || 11}%):</span>
       <span>${this.formatCurrency(data.tax_amount)}</span>
     </div>
+    `}
     ${data.discount_amount && data.discount_amount > 0 ? `
     <div class="total-row">
-      <span>Diskon:</span>
+      <span>${t('discount')}:</span>
       <span>-${this.formatCurrency(data.discount_amount)}</span>
     </div>
     ` : ''}
     <div class="total-row grand">
-      <span>TOTAL:</span>
+      <span>${t('total')}:</span>
       <span>${this.formatCurrency(data.total_amount)}</span>
     </div>
   </div>
@@ -297,18 +413,18 @@ class ReceiptPrinterService {
   ${data.payment_method ? `
   <div class="payment">
     <div class="total-row">
-      <span>Metode Bayar:</span>
-      <span>${this.formatPaymentMethod(data.payment_method)}</span>
+      <span>${t('payment_method')}:</span>
+      <span>${this.formatPaymentMethod(data.payment_method, lang)}</span>
     </div>
     ${data.payment_amount ? `
     <div class="total-row">
-      <span>Dibayar:</span>
+      <span>${t('paid')}:</span>
       <span>${this.formatCurrency(data.payment_amount)}</span>
     </div>
     ` : ''}
     ${data.change_amount && data.change_amount > 0 ? `
     <div class="total-row">
-      <span>Kembalian:</span>
+      <span>${t('change')}:</span>
       <span>${this.formatCurrency(data.change_amount)}</span>
     </div>
     ` : ''}
@@ -320,7 +436,7 @@ class ReceiptPrinterService {
 
   <!-- Footer -->
   <div class="footer">
-    ${this.settings.receipt_footer || 'Terima kasih atas kunjungan Anda!'}
+    ${this.settings.receipt_footer || t('thank_you')}
     <p style="margin-top: 8px;">================================</p>
     <p style="margin-top: 4px; font-size: ${paperSize === '58mm' ? '8px' : '9px'};">
       Powered by Modern POS System
@@ -334,26 +450,26 @@ class ReceiptPrinterService {
   /**
    * Format order type for display
    */
-  private formatOrderType(type: string): string {
-    const types: Record<string, string> = {
-      dine_in: 'Makan di Tempat',
-      takeaway: 'Bawa Pulang',
-      delivery: 'Antar',
-    };
-    return types[type] || type;
+  private formatOrderType(type: string, language: ReceiptLanguage): string {
+    const label = ORDER_TYPE_LABELS[type];
+    return label ? this.translate(label, language) : type;
+  }
+
+  /**
+   * Tax line label: the default labels are translated, tax class names are
+   * printed as named after the tax wording
+   */
+  private formatTaxLabel(label: string, language: ReceiptLanguage): string {
+    const known = TAX_LINE_LABELS[label];
+    return known ? this.translate(known, language) : `${this.label('tax', language)} ${label}`;
   }
 
   /**
    * Format payment method for display
    */
-  private formatPaymentMethod(method: string): string {
-    const methods: Record<string, string> = {
-      cash: 'Tunai',
-      card: 'Kartu',
-      digital_wallet: 'Dompet Digital',
-      qris: 'QRIS',
-    };
-    return methods[method] || method;
+  private formatPaymentMethod(method: string, language: ReceiptLanguage): string {
+    const label = PAYMENT_METHOD_LABELS[method];
+    return label ? this.translate(label, language) : method;
   }
 
   /**
@@ -456,6 +572,13 @@ class ReceiptPrinterService {
     return this.printReceipt(data);
   }
 
+  /**
+   * Check if this terminal prints receipts automatically
+   */
+  isAutoPrintEnabled(): boolean {
+    return this.settings.auto_print || false;
+  }
+
   /**
    * Print multiple copies of a receipt
    * @param data Receipt data to print
@@ -4,6 +4,17 @@ export interface APIResponse<T = unknown> {
   message: string;
   data?: T;
   error?: string;
+  /** Set on errors; quote it when reporting a problem */
+  request_id?: string;
+  /** Validation errors (400) by field, in the language sent as Accept-Language */
+  errors?: ValidationFieldError[];
+}
+
+export interface ValidationFieldError {
+  /** Dotted path of the field at fault; null when it's the request as a whole */
+  field: string | null;
+  code: string;
+  message: string;
 }
 
 export interface PaginatedResponse<T = unknown> {
@@ -16,8 +27,17 @@ export interface PaginatedResponse<T = unknown> {
 export interface MetaData {
   current_page: number;
   per_page: number;
-  total: number;
-  total_pages: number;
+  /** null when the list was requested with ?include_total=false */
+  total: number | null;
+  total_pages: number | null;
+  has_next?: boolean;
+  has_prev?: boolean;
+  /** e.g. "created_at:desc" */
+  sort?: string;
+  /** The filters the server applied */
+  filters?: Record<string, string | number | boolean>;
+  /** Pass as ?cursor= to fetch the page after this one (orders list) */
+  next_cursor?: string | null;
 }
 
 // User Types
@@ -29,18 +49,255 @@ export interface User {
   last_name: string;
   role: 'admin' | 'manager' | 'cashier' | 'kitchen';
   is_active: boolean;
+  /** Null for head office staff, who see every branch */
+  branch_id?: string | null;
+  /** Permissions granted by the user's role (returned by /auth/me) */
+  permissions?: string[];
+  created_at: string;
+  updated_at: string;
+}
+
+// Role & Permission Types
+export interface PermissionDefinition {
+  name: string;
+  description: string;
+}
+
+export interface Role {
+  name: string;
+  display_name: string;
+  description?: string | null;
+  /** Built-in roles can't be deleted */
+  is_system: boolean;
+  permissions: string[];
+  user_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// Background Job Types
+export interface BackgroundJob {
+  id: string;
+  type: string;
+  payload: Record<string, unknown>;
+  status: 'pending' | 'running' | 'succeeded' | 'failed';
+  attempts: number;
+  max_attempts: number;
+  run_at: string;
+  locked_at?: string | null;
+  locked_by?: string | null;
+  last_error?: string | null;
+  created_at: string;
+  updated_at: string;
+  completed_at?: string | null;
+}
+
+export interface EmailOutboxEntry {
+  id: string;
+  template: string;
+  recipients: string[];
+  subject: string;
+  /** Only returned when fetching a single email; null when hidden */
+  body?: string | null;
+  /** Set on password reset emails, whose body holds a working reset link */
+  body_hidden?: boolean;
+  status: 'queued' | 'sent' | 'failed';
+  attempts: number;
+  last_error?: string | null;
+  related_type?: string | null;
+  related_id?: string | null;
+  created_by?: string | null;
+  created_by_name?: string | null;
+  created_at: string;
+  updated_at: string;
+  sent_at?: string | null;
+}
+
+export interface TaxExemption {
+  id: string;
+  name: string;
+  product_id?: string | null;
+  product_name?: string | null;
+  category_id?: string | null;
+  category_name?: string | null;
+  branch_id?: string | null;
+  branch_name?: string | null;
+  order_type?: 'dine_in' | 'takeout' | 'delivery' | null;
+  exempt_tax: boolean;
+  exempt_service: boolean;
+  is_active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TaxClass {
+  id: string;
+  name: string;
+  /** Percent, e.g. 11 */
+  rate: number;
+  description?: string | null;
+  is_active: boolean;
+  product_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export type SurchargeType = 'percentage' | 'fixed';
+
+/**
+ * Temporary surcharge (holiday, event) added to orders placed in its window
+ */
+export interface Surcharge {
+  id: string;
+  name: string;
+  description: string | null;
+  /** percentage: value is a percent of the order after discounts; fixed: an amount per order */
+  surcharge_type: SurchargeType;
+  value: number;
+  starts_at: string;
+  ends_at: string;
+  /** Null applies to every branch */
+  branch_id: string | null;
+  branch_name: string | null;
+  /** Empty applies to every order type */
+  order_types: Array<'dine_in' | 'takeout' | 'delivery'>;
+  is_active: boolean;
+  created_by: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export type SchedulePriceType = 'fixed_price' | 'percentage';
+
+/**
+ * Happy hour style price override for products or whole categories during a
+ * daily window (Asia/Jakarta time)
+ */
+export interface PriceSchedule {
+  id: string;
+  name: string;
+  description: string | null;
+  product_ids: string[];
+  category_ids: string[];
+  /** fixed_price: value is the new price; percentage: value is a percent off the menu price */
+  price_type: SchedulePriceType;
+  value: number;
+  /** 0 = Sunday; empty means every day */
+  days_of_week: number[];
+  start_time: string;
+  end_time: string;
+  is_active: boolean;
+  /** Whether the window is open right now */
+  in_effect: boolean;
+  created_by: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface OrderSurcharge {
+  surcharge_id: string | null;
+  name: string;
+  amount: number;
+  tax_amount: number;
+  /** Percent the surcharge was taxed at */
+  tax_rate: number;
+}
+
+/**
+ * Display currency; prices are converted from IDR, never charged in it
+ */
+export interface Currency {
+  id: string;
+  code: string;
+  name: string;
+  symbol: string;
+  /** Rupiah per one unit of the currency */
+  rate_to_idr: number;
+  decimal_places: number;
+  is_active: boolean;
+  rate_updated_at: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export type PublicCurrency = Pick<Currency, "code" | "name" | "symbol" | "rate_to_idr" | "decimal_places" | "rate_updated_at">;
+
+/**
+ * The currency an order is shown in; amounts on the order are IDR
+ */
+export interface OrderCurrency {
+  settlement_currency: string;
+  display_currency: string;
+  symbol: string;
+  /** Rate fixed when the order was placed, null for IDR */
+  exchange_rate: number | null;
+  display_total: number | null;
+}
+
+/**
+ * 'id' = Bahasa Indonesia only, 'id_en' = Bahasa Indonesia with English
+ */
+export type ReceiptLanguage = 'id' | 'id_en';
+
+/**
+ * The language an order's receipt prints in and where it comes from: the
+ * order itself, the customer's remembered preference or the setting
+ */
+export interface OrderReceiptLanguage {
+  language: ReceiptLanguage;
+  source: 'order' | 'customer' | 'default';
+}
+
+// Branch Types
+export interface Branch {
+  id: string;
+  code: string;
+  name: string;
+  address?: string | null;
+  phone?: string | null;
+  /** Venue location for the QR order distance check; null uses the restaurant's */
+  latitude?: number | null;
+  longitude?: number | null;
+  is_default: boolean;
+  is_active: boolean;
+  table_count: number;
+  staff_count: number;
   created_at: string;
   updated_at: string;
 }
 
+export interface BranchSetting {
+  setting_key: string;
+  setting_type: string;
+  category: string;
+  description?: string | null;
+  value: string;
+  default_value: string;
+  overridden: boolean;
+  updated_at: string | null;
+}
+
+export interface BranchSales {
+  branch_id: string;
+  branch_code: string;
+  branch_name: string;
+  order_count: number;
+  revenue: number;
+  tax_collected: number;
+}
+
 export interface LoginRequest {
   username: string;
   password: string;
+  device_id?: string;
 }
 
 export interface LoginResponse {
   token: string;
+  /** Send as X-CSRF-Token on writes when authenticating with the session cookie instead of the token */
+  csrf_token: string;
   user: User;
+  print_preferences?: DevicePrintPreferences | null;
 }
 
 // Category Types
@@ -51,11 +308,69 @@ export interface Category {
   color?: string;
   image_url?: string;
   sort_order: number;
+  /** Where the category's items are prepared */
+  station?: KitchenStation;
+  /** Skip acceptance on customer orders; null follows the station default */
+  auto_release?: boolean | null;
+  /** Kitchen display overrides; null falls back to the menu color and the station's priority */
+  kds_color?: string | null;
+  kds_priority?: number | null;
+  /** Heading the category's items go under on tickets; null uses the category name */
+  kds_group?: string | null;
   is_active: boolean;
   created_at: string;
   updated_at: string;
 }
 
+export type KitchenStation = 'kitchen' | 'bar';
+
+/** How a station groups a ticket's items: by category (or kds_group), all under the station, or not at all */
+export type KitchenGrouping = 'category' | 'station' | 'none';
+
+/**
+ * Station defaults for the kitchen display; lower sort_priority is shown first
+ */
+export interface StationDisplay {
+  station: KitchenStation;
+  color: string | null;
+  sort_priority: number;
+  group_items_by: KitchenGrouping;
+  updated_by: string | null;
+  updated_at: string;
+}
+
+export interface CategoryDisplay {
+  id: string;
+  name: string;
+  station: KitchenStation;
+  color: string | null;
+  kds_color: string | null;
+  kds_priority: number | null;
+  kds_group: string | null;
+  /** Resolved from the overrides and the station defaults */
+  display_color: string | null;
+  sort_priority: number;
+  display_group: string | null;
+}
+
+export interface KitchenDisplayRules {
+  stations: StationDisplay[];
+  categories: CategoryDisplay[];
+}
+
+/**
+ * Heading on a kitchen ticket and the items under it, in display order
+ */
+export interface KitchenGroup {
+  name: string;
+  color: string | null;
+  sort_priority: number;
+  item_ids: string[];
+}
+
+/** 'each' is sold by the portion; otherwise the price is per this weight unit */
+export type SaleUnit = 'each' | 'kg' | '100g' | 'g';
+
 // Product Types
 export interface Product {
   id: string;
@@ -69,6 +384,19 @@ export interface Product {
   is_available: boolean;
   preparation_time: number;
   sort_order: number;
+  /** Null taxes the product at the default rate */
+  tax_class_id?: string | null;
+  sale_unit?: SaleUnit;
+  /** When true, stock levels never change is_available */
+  availability_override?: boolean;
+  /** Set while the product is off the menu because it ran out of stock */
+  stock_unavailable_at?: string | null;
+  /** Set while the kitchen has 86'd the product for the day */
+  eighty_sixed_at?: string | null;
+  allergens?: Allergen[];
+  dietary_tags?: DietaryTag[];
+  /** 0 (not spicy) to 3; null when not stated */
+  spicy_level?: number | null;
   created_at: string;
   updated_at: string;
   category?: Category;
@@ -82,24 +410,46 @@ export interface DiningTable {
   location?: string;
   is_occupied: boolean;
   qr_code?: string;
+  branch_id?: string;
   created_at: string;
   updated_at: string;
 }
 
 // Order Types
-export interface Order {
+/**
+ * The display strings the API sends next to amounts, in the configured
+ * locale and currency: total_amount → total_amount_formatted ("Rp 185.000").
+ */
+export type Formatted<K extends string> = { [P in K as `${P}_formatted`]?: string | null };
+
+export interface Order
+  extends Formatted<'subtotal' | 'tax_amount' | 'service_charge_amount' | 'discount_amount' | 'total_amount' | 'deposit_amount' | 'surcharge_amount'> {
   id: string;
   order_number: string;
   table_id?: string;
   user_id?: string;
+  branch_id?: string;
   customer_name?: string;
   order_type: 'dine_in' | 'takeout' | 'delivery';
-  status: 'pending' | 'confirmed' | 'preparing' | 'ready' | 'served' | 'completed' | 'cancelled';
+  status: OrderStatus;
   subtotal: number;
   tax_amount: number;
+  service_charge_amount?: number;
   discount_amount: number;
   total_amount: number;
+  /** Refundable container deposits included in total_amount */
+  deposit_amount?: number;
+  /** Holiday and event surcharges included in total_amount; their tax is in tax_amount */
+  surcharge_amount?: number;
   notes?: string;
+  scheduled_at?: string | null;
+  parked?: OrderParking; // only while parked
+  tab_id?: string | null;
+  /** The server the order is credited to (see /admin/reports/servers) */
+  server_id?: string | null;
+  covers?: number | null;
+  delivery?: OrderDelivery | null;
+  wait_estimate?: WaitEstimate | null; // returned when the order is created
   created_at: string;
   updated_at: string;
   served_at?: string;
@@ -107,18 +457,91 @@ export interface Order {
   table?: DiningTable;
   user?: User;
   items?: OrderItem[];
+  /** On kitchen tickets: an item was given an allergy-flagged phrase */
+  has_allergy_alert?: boolean;
   payments?: Payment[];
+  payment_links?: PaymentLink[];
+  container_deposits?: OrderContainerDeposit[];
+  /** Item (and surcharge) taxes grouped by tax class, for the receipt */
+  tax_lines?: OrderTaxLine[];
+  surcharges?: OrderSurcharge[]; // single order
+  currency?: OrderCurrency;
+  receipt?: OrderReceiptLanguage; // single order
+  /** Customer orders: why the order may need a closer look before accepting */
+  risk_flags?: OrderRiskFlag[]; // order lists
+  source?: OrderSource | null; // single order
+}
+
+export interface OrderParking {
+  parked_at: string;
+  reason: string | null;
+  /** The order is cancelled and its stock returned if not resumed by then */
+  expires_at: string;
+}
+
+export interface OrderTaxLine {
+  tax_class_id: string | null;
+  label: string;
+  /** Percent; null for items taxed before rates were recorded */
+  rate: number | null;
+  items: number;
+  tax_amount: number;
+}
+
+export interface OrderRiskFlag {
+  flag: 'outside_venue' | 'imprecise_location' | 'no_location' | 'multiple_tables' | 'rapid_orders' | 'automated_client';
+  description: string;
+}
+
+export interface OrderSource {
+  has_fingerprint: boolean;
+  has_location: boolean;
+  location_accuracy_m: number | null;
+  distance_m: number | null;
+  risk_flags: OrderRiskFlag[];
 }
 
-export interface OrderItem {
+export interface OrderItem extends Formatted<'unit_price' | 'total_price' | 'tax_amount' | 'service_charge_amount'> {
   id: string;
   order_id: string;
   product_id: string;
+  /** In the product's sale unit, e.g. 0.35 (kg) */
   quantity: number;
+  sale_unit?: SaleUnit;
+  /** Set when the quantity was read off a scale */
+  weight_grams?: number | null;
+  scale_device?: string | null;
   unit_price: number;
   total_price: number;
+  tax_amount?: number;
+  service_charge_amount?: number;
+  tax_exempt?: boolean;
+  service_exempt?: boolean;
+  tax_class_id?: string | null;
+  tax_label?: string | null;
+  tax_rate?: number | null;
   special_instructions?: string;
   status: 'pending' | 'preparing' | 'ready' | 'served';
+  /** A free replacement for a dish the customer sent back */
+  is_remake?: boolean;
+  /** Price schedule the item was priced by, if any */
+  price_schedule_id?: string | null;
+  /** Kitchen display values, on kitchen tickets */
+  display_color?: string | null;
+  sort_priority?: number;
+  display_group?: string | null;
+  /** Dine-in course, 1 unless the order is coursed */
+  course?: number;
+  /** Codes of the quick phrases picked for the item */
+  note_phrases?: string[];
+  /** Their labels in the same order, on kitchen tickets */
+  note_phrase_labels?: string[];
+  /** Kitchen flags of those phrases, on kitchen tickets */
+  kitchen_flags?: NotePhraseKitchenFlag[];
+  /** Given a phrase flagged allergy */
+  allergy_alert?: boolean;
+  /** Null while held for the order to be accepted or for its course to be fired */
+  released_at?: string | null;
   created_at: string;
   updated_at: string;
   product?: Product;
@@ -128,18 +551,59 @@ export interface OrderItem {
   product_description?: string;
 }
 
+export interface OrderDelivery {
+  address: string;
+  phone: string;
+  notes?: string | null;
+  fee: number;
+  status: 'unassigned' | 'assigned' | 'picked_up' | 'delivered';
+  courier?: { id: string; first_name: string; last_name: string } | null;
+  courier_assigned_at?: string | null;
+  picked_up_at?: string | null;
+  delivered_at?: string | null;
+}
+
 export interface CreateOrderRequest {
   table_id?: string;
   customer_name?: string;
   order_type: 'dine_in' | 'takeout' | 'delivery';
   items: CreateOrderItem[];
   notes?: string;
+  /** ISO timestamp for a later pickup/delivery; takeout and delivery only */
+  scheduled_at?: string;
+  delivery_address?: string;
+  delivery_phone?: string;
+  delivery_notes?: string;
+  /** Reusable containers; their deposit is added to the order total */
+  containers?: { container_type_id: string; quantity: number }[];
+  /** Show the bill in this currency; the order is still charged in IDR */
+  currency?: string;
+  receipt_language?: ReceiptLanguage;
+  /** Also keep receipt_language as the customer's preference (needs delivery_phone) */
+  remember_receipt_language?: boolean;
+  /** Create the order parked, off the kitchen board until resumed */
+  park?: boolean;
+  park_reason?: string;
+  /** Add the order to an open tab, settled when the tab is closed */
+  tab_id?: string;
+  /** Dine-in: hold every course, the first included, until it is fired */
+  hold?: boolean;
+  /** Number of guests (1-100), for sales per cover */
+  covers?: number;
 }
 
 export interface CreateOrderItem {
   product_id: string;
-  quantity: number;
+  /** Whole for products sold each; up to three decimals for weighed ones */
+  quantity?: number;
+  /** Scale reading for a weighed product, instead of quantity */
+  weight_grams?: number;
+  scale_device?: string;
   special_instructions?: string;
+  /** Quick phrase codes from GET /note-phrases, at most 10 */
+  note_phrases?: string[];
+  /** Dine-in course (1-9); courses after the first wait to be fired */
+  course?: number;
 }
 
 export interface UpdateOrderStatusRequest {
@@ -148,14 +612,22 @@ export interface UpdateOrderStatusRequest {
 }
 
 // Order status type
-export type OrderStatus = 'pending' | 'confirmed' | 'preparing' | 'ready' | 'served' | 'completed' | 'cancelled';
+export type OrderStatus = 'scheduled' | 'parked' | 'pending' | 'confirmed' | 'preparing' | 'ready' | 'served' | 'completed' | 'cancelled';
 
 // Payment Types
-export interface Payment {
+export interface Payment extends Formatted<'amount' | 'rounding_adjustment'> {
   id: string;
   order_id: string;
   payment_method: 'cash' | 'credit_card' | 'debit_card' | 'digital_wallet' | 'qris';
   amount: number;
+  /** Cash collected minus amount, when the settling cash payment was rounded */
+  rounding_adjustment?: number;
+  /** Payment method surcharge paid on top of amount */
+  surcharge_amount?: number;
+  /** Tip paid on top of amount, credited to tip_recipient_id */
+  tip_amount?: number;
+  tip_recipient_id?: string | null;
+  cash_collected?: number;
   reference_number?: string;
   status: 'pending' | 'completed' | 'failed' | 'refunded';
   processed_by?: string;
@@ -164,10 +636,92 @@ export interface Payment {
   processed_by_user?: User;
 }
 
+// Refund of a gateway payment; its refund payment completes once the gateway confirms
+export interface GatewayRefund {
+  id: string;
+  refund_payment_id: string;
+  payment_id: string;
+  order_id: string;
+  order_number: string;
+  payment_link_id: string;
+  gateway_order_id: string;
+  amount: number;
+  reason: string;
+  status: 'queued' | 'submitted' | 'succeeded' | 'failed';
+  attempts: number;
+  gateway_status_code?: string | null;
+  last_error?: string | null;
+  submitted_at?: string | null;
+  confirmed_at?: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+// Gateway-hosted payment page sent to a customer paying remotely
+export interface PaymentLink {
+  id: string;
+  amount: number;
+  status: 'pending' | 'paid' | 'expired' | 'failed' | 'cancelled';
+  payment_url: string;
+  expires_at: string;
+  sent_to?: string | null;
+  payment_id?: string | null;
+  created_at: string;
+  paid_at?: string | null;
+}
+
+export interface CreatePaymentLinkRequest {
+  send?: boolean;
+  phone?: string;
+  email?: string;
+}
+
+// Bar tab secured by a card pre-authorization, captured at close-out
+export type TabStatus = 'pending' | 'open' | 'closing' | 'closed' | 'released' | 'failed';
+
+export interface Tab {
+  id: string;
+  branch_id: string;
+  customer_name: string;
+  table_id?: string | null;
+  table_number?: string | null;
+  status: TabStatus;
+  /** Amount held on the card */
+  authorized_amount: number;
+  /** What was charged when the tab closed */
+  captured_amount: number | null;
+  /** Headroom left for new orders while open */
+  available_amount: number;
+  /** Part of the hold released back to the card */
+  released_amount: number | null;
+  order_count: number;
+  /** What the tab's orders still owe */
+  balance: number;
+  /** Card page the guest authorizes on (pending tabs) */
+  payment_url: string;
+  auth_expires_at?: string | null;
+  last_error?: string | null;
+  created_at: string;
+  authorized_at?: string | null;
+  closed_at?: string | null;
+  orders?: { id: string; order_number: string; status: OrderStatus; total_amount: number; balance: number; created_at: string }[]; // single tab
+}
+
+export interface OpenTabRequest {
+  customer_name: string;
+  table_id?: string;
+  /** Defaults to the tab_preauth_amount setting */
+  amount?: number;
+  email?: string;
+  phone?: string;
+}
+
 export interface ProcessPaymentRequest {
   payment_method: 'cash' | 'credit_card' | 'debit_card' | 'digital_wallet' | 'qris';
   amount: number;
   reference_number?: string;
+  /** On top of amount, for the order's server */
+  tip_amount?: number;
 }
 
 export interface PaymentSummary {
@@ -176,8 +730,13 @@ export interface PaymentSummary {
   total_paid: number;
   pending_amount: number;
   remaining_amount: number;
+  /** remaining_amount after cash rounding */
+  cash_amount_due: number;
   is_fully_paid: boolean;
   payment_count: number;
+  tip_total: number;
+  /** From the tip_suggestions setting, on remaining_amount */
+  tip_suggestions: { percent: number; amount: number }[];
 }
 
 // Cart Types (Frontend Only)
@@ -185,6 +744,7 @@ export interface CartItem {
   product: Product;
   quantity: number;
   special_instructions?: string;
+  note_phrases?: string[];
 }
 
 export interface Cart {
@@ -195,20 +755,62 @@ export interface Cart {
 }
 
 // Dashboard Types
-export interface DashboardStats {
+export interface DashboardPeriod extends Formatted<'revenue' | 'average_ticket'> {
+  date: string;
+  orders: number;
+  completed_orders: number;
+  cancelled_orders: number;
+  revenue: number;
+  average_ticket: number;
+  covers: number;
+}
+
+/** Percent change of today against the period; null when it had nothing */
+export interface DashboardDelta {
+  orders: number | null;
+  revenue: number | null;
+  average_ticket: number | null;
+  covers: number | null;
+}
+
+export interface DashboardTopProduct extends Formatted<'revenue'> {
+  product_id: string;
+  product_name: string;
+  quantity: number;
+  revenue: number;
+}
+
+export interface DashboardStats extends Formatted<'today_revenue'> {
   today_orders: number;
   today_revenue: number;
   active_orders: number;
   occupied_tables: number;
+  date?: string;
+  as_of?: string;
+  generated_at?: string;
+  branch_id?: string | null;
+  total_tables?: number;
+  today?: DashboardPeriod;
+  yesterday?: DashboardPeriod;
+  last_week?: DashboardPeriod;
+  vs_yesterday?: DashboardDelta;
+  vs_last_week?: DashboardDelta;
+  top_products?: DashboardTopProduct[];
+  kitchen?: {
+    pending_tickets: number;
+    preparing_tickets: number;
+    ready_tickets: number;
+    oldest_ticket_minutes: number | null;
+  };
 }
 
-export interface SalesReportItem {
+export interface SalesReportItem extends Formatted<'revenue'> {
   date: string;
   order_count: number;
   revenue: number;
 }
 
-export interface OrdersReportItem {
+export interface OrdersReportItem extends Formatted<'avg_amount'> {
   status: string;
   count: number;
   avg_amount: number;
@@ -224,7 +826,13 @@ export interface KitchenOrder {
   status: string;
   customer_name?: string;
   created_at: string;
+  /** Items waiting for acceptance or for their course to be fired */
+  held_item_count?: number;
+  held_courses?: number[];
+  /** An item was given an allergy-flagged phrase */
+  has_allergy_alert?: boolean;
   items?: OrderItem[];
+  groups?: KitchenGroup[];
 }
 
 // Table Status Types
@@ -281,9 +889,73 @@ export interface PublicMenuItem {
   name: string;
   description: string | null;
   price: number;
+  /** Menu price while a price schedule applies, null otherwise */
+  regular_price?: number | null;
+  /** Name of the price schedule behind price, if any */
+  price_schedule?: string | null;
+  sale_unit?: SaleUnit;
   image_url: string | null;
   category_id: string;
   category_name: string;
+  /** False once tracked stock runs out */
+  in_stock?: boolean;
+  /** Portions left for stock-tracked items, null when not tracked */
+  remaining_quantity?: number | null;
+  /** Present for limited-quantity daily specials */
+  daily_special?: { daily_quantity: number; remaining_quantity: number } | null;
+  /** With ?currency=: the price converted for display */
+  display_price?: number;
+  display_currency?: string;
+  /** For badges */
+  allergens?: Allergen[];
+  dietary_tags?: DietaryTag[];
+  spicy_level?: number | null;
+}
+
+export type Allergen =
+  | 'peanut' | 'tree_nut' | 'milk' | 'egg' | 'wheat' | 'gluten' | 'soy' | 'fish' | 'shellfish' | 'sesame';
+
+export type DietaryTag = 'halal' | 'vegetarian' | 'vegan' | 'gluten_free' | 'dairy_free' | 'contains_alcohol';
+
+export interface ProductDietaryInfo {
+  product_id: string;
+  allergens: Allergen[];
+  dietary_tags: DietaryTag[];
+  spicy_level: number | null;
+}
+
+/**
+ * Filters for GET /public/menu
+ */
+export interface DietaryFilters {
+  /** Leave out products containing any of these */
+  exclude_allergens?: Allergen[];
+  /** Keep products carrying every one of these */
+  tags?: DietaryTag[];
+  max_spicy?: number;
+}
+
+export interface DietaryOptions {
+  allergens: Allergen[];
+  dietary_tags: DietaryTag[];
+  max_spicy_level: number;
+}
+
+/**
+ * Matches per category for a product or menu search
+ */
+export interface SearchFacet {
+  category_id: string | null;
+  category_name: string | null;
+  count: number;
+}
+
+/**
+ * Public menu search returned by GET /api/v1/public/menu/search
+ */
+export interface PublicMenuSearchResult {
+  items: PublicMenuItem[];
+  facets: SearchFacet[];
 }
 
 /**
@@ -444,10 +1116,70 @@ export interface Reservation {
   notes?: string;
   confirmed_by?: string;
   confirmed_at?: string;
+  table_id?: string; // table held for the booking
+  reminder_sent_at?: string;
+  customer_responded_at?: string;
+  customer_flagged?: boolean; // list view
+  customer_flags?: CustomerFlagMatch[]; // detail view
+  created_at: string;
+  updated_at: string;
+}
+
+export type CustomerFlagReason = 'no_show' | 'chargeback' | 'abuse' | 'other';
+
+/**
+ * Active flag matched against a customer's phone number
+ */
+export interface CustomerFlagMatch {
+  id: string;
+  reason_type: CustomerFlagReason;
+  reason: string;
+  flagged_at: string;
+  flagged_by: string | null;
+}
+
+/**
+ * Customer flag as managed by admins
+ */
+export interface CustomerFlag {
+  id: string;
+  phone: string; // international digits, e.g. 62812...
+  customer_name: string | null;
+  reason_type: CustomerFlagReason;
+  reason: string;
+  is_active: boolean;
+  flagged_by: { id: string; name: string | null } | null;
+  cleared_by: { id: string; name: string | null } | null;
+  cleared_at: string | null;
   created_at: string;
   updated_at: string;
 }
 
+/**
+ * Reservation summary behind a reminder confirm/cancel link
+ */
+export interface ReservationReminderSummary {
+  customer_name: string;
+  party_size: number;
+  reservation_date: string;
+  reservation_time: string;
+  status: ReservationStatus;
+  can_respond: boolean;
+  customer_responded_at?: string;
+}
+
+/**
+ * Per-customer no-show history (keyed by phone digits)
+ */
+export interface CustomerNoShowStat {
+  phone: string;
+  customer_name: string;
+  reservations: number;
+  no_shows: number;
+  no_show_rate: number; // percent
+  last_no_show: string | null;
+}
+
 /**
  * Request payload for creating a reservation (public form)
  */
@@ -610,6 +1342,37 @@ export interface ProductIngredient {
   current_stock?: number;
 }
 
+export interface BundleComponentInput {
+  product_id: string;
+  /** Portions of the component in one bundle */
+  quantity: number;
+}
+
+export interface BundleComponent extends BundleComponentInput {
+  name: string;
+  in_stock: boolean;
+  remaining_quantity: number | null;
+}
+
+/**
+ * A product's bundle components and their stock at one branch
+ */
+export interface ProductBundle {
+  product_id: string;
+  branch_id: string;
+  is_bundle: boolean;
+  components: BundleComponent[];
+  in_stock: boolean;
+  /** Bundles that can still be sold, or null when no component is tracked */
+  remaining_quantity: number | null;
+  /** The tracked component that allows the fewest bundles */
+  limiting_component: {
+    product_id: string;
+    name: string;
+    remaining: number;
+  } | null;
+}
+
 /**
  * IngredientHistory represents an audit record of ingredient stock changes
  */
@@ -617,7 +1380,7 @@ export interface IngredientHistory {
   id: string;
   ingredient_id: string;
   order_id?: string; // Reference to order for consumption/cancellation
-  operation: 'add' | 'remove' | 'restock' | 'usage' | 'spoilage' | 'adjustment' | 'order_consumption' | 'order_cancellation';
+  operation: 'add' | 'remove' | 'restock' | 'usage' | 'spoilage' | 'adjustment' | 'order_consumption' | 'order_cancellation' | 'count_gain' | 'count_loss' | 'waste';
   quantity: number;
   previous_stock: number;
   new_stock: number;
@@ -733,9 +1496,22 @@ export interface IncomeReportSummary {
   total_orders: number;
   gross_income: number;
   tax_collected: number;
+  service_charge_collected?: number;
   net_income: number;
 }
 
+/**
+ * Tax and service charge collected per tax class
+ */
+export interface IncomeTaxClassItem {
+  tax_class_id: string | null;
+  label: string;
+  rate: number | null;
+  items: number;
+  tax: number;
+  service_charge: number;
+}
+
 /**
  * Income breakdown item
  */
@@ -758,24 +1534,529 @@ export interface IncomeReportResponse {
   data: IncomeReportItem[];
   summary: IncomeReportSummary;
   breakdown: IncomeBreakdownItem[];
-}
-
-export interface IncomeReportItem {
-  date: string;
-  revenue: number;
-  order_count: number;
+  tax_by_class?: IncomeTaxClassItem[];
 }
 
 /**
- * User creation payload
+ * A product's unit cost: its override, else its recipe at current ingredient prices
  */
-export interface CreateUserData {
+export interface ProductCost {
+  product_id: string;
+  product_name: string;
+  category_name: string;
+  price: number;
+  cost_override: number | null;
+  recipe_cost: number | null;
+  recipe_ingredients: number;
+  unit_cost: number | null;
+  cost_source: "override" | "recipe" | null;
+  margin_pct: number | null;
+}
+
+/**
+ * Manual correction to a period's cost of goods sold; positive adds cost
+ */
+export interface CogsAdjustment {
+  id: string;
+  branch_id: string;
+  product_id: string | null;
+  product_name?: string | null;
+  adjustment_date: string;
+  amount: number;
+  reason: string;
+  created_by?: string | null;
+  created_by_username?: string | null;
+  created_at: string;
+}
+
+export interface CogsReportProduct {
+  product_id: string;
+  product_name: string;
+  category_name: string;
+  quantity: number;
+  net_sales: number;
+  unit_cost: number | null;
+  cost_source: "override" | "recipe" | null;
+  theoretical_cogs: number | null;
+  adjustments: number;
+  total_cogs: number;
+  gross_margin: number | null;
+  margin_pct: number | null;
+}
+
+/**
+ * COGS and margin report from admin reports
+ */
+export interface CogsReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  summary: {
+    net_sales: number;
+    theoretical_cogs: number;
+    adjustments: number;
+    total_cogs: number;
+    gross_margin: number;
+    margin_pct: number | null;
+    /** Products sold with neither a recipe nor a cost override */
+    uncosted_products: number;
+    uncosted_sales: number;
+  };
+  products: CogsReportProduct[];
+  adjustments: CogsAdjustment[];
+}
+
+export type MenuEngineeringClass = "star" | "plowhorse" | "puzzle" | "dog";
+
+export interface MenuEngineeringProduct {
+  product_id: string;
+  product_name: string;
+  category_id: string | null;
+  category_name: string;
+  quantity: number;
+  net_sales: number;
+  /** Net sales per portion, or the list price when none sold */
+  average_price: number;
+  unit_cost: number | null;
+  cost_source: "override" | "recipe" | null;
+  food_cost: number | null;
+  food_cost_pct: number | null;
+  contribution_margin: number | null;
+  unit_contribution_margin: number | null;
+  menu_mix_pct: number | null;
+  popularity: "high" | "low" | null;
+  profitability: "high" | "low" | null;
+  /** Null for products without a cost */
+  classification: MenuEngineeringClass | null;
+}
+
+/**
+ * Stars, plowhorses, puzzles and dogs from admin reports
+ */
+export interface MenuEngineeringReport {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  category_id: string | null;
+  summary: {
+    products: number;
+    classified_products: number;
+    quantity: number;
+    net_sales: number;
+    food_cost: number;
+    food_cost_pct: number | null;
+    contribution_margin: number;
+    /** Per portion; the profitability line */
+    average_contribution_margin: number | null;
+    /** Menu mix percent a product needs to count as popular */
+    popularity_threshold_pct: number | null;
+    classes: Record<MenuEngineeringClass, number>;
+    uncosted_products: number;
+    uncosted_sales: number;
+  };
+  products: MenuEngineeringProduct[];
+}
+
+export type StockTakeStatus = "open" | "posted" | "cancelled";
+
+/**
+ * A physical inventory count session
+ */
+export interface StockTake {
+  id: string;
+  branch_id: string;
+  branch_name: string;
+  scope: "products" | "ingredients" | "all";
+  status: StockTakeStatus;
+  notes: string | null;
+  started_by: string | null;
+  started_by_username: string | null;
+  started_at: string;
+  posted_by: string | null;
+  posted_by_username: string | null;
+  posted_at: string | null;
+  cancelled_at: string | null;
+  total_lines: number;
+  counted_lines: number;
+}
+
+export interface StockTakeLine {
+  id: string;
+  item_type: "product" | "ingredient";
+  product_id: string | null;
+  ingredient_id: string | null;
+  item_name: string;
+  unit: string;
+  /** Stock on record when the session started */
+  expected_quantity: number;
+  counted_quantity: number | null;
+  unit_cost: number | null;
+  /** Counted minus expected; null until counted */
+  variance: number | null;
+  variance_value: number | null;
+  /** The stock change applied on posting */
+  posted_adjustment: number | null;
+  counted_by: string | null;
+  counted_by_username: string | null;
+  counted_at: string | null;
+}
+
+export interface StockTakeDetail extends StockTake {
+  summary: {
+    total_lines: number;
+    counted_lines: number;
+    variance_lines: number;
+    gain_value: number;
+    loss_value: number;
+    net_variance_value: number;
+  };
+  lines: StockTakeLine[];
+}
+
+export interface StockTakeCount {
+  product_id?: string;
+  ingredient_id?: string;
+  /** null clears the count */
+  counted_quantity: number | null;
+}
+
+/**
+ * Variances of the stock takes posted over a period
+ */
+export interface StockVarianceReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  summary: {
+    stock_takes: number;
+    items_with_variance: number;
+    gain_value: number;
+    loss_value: number;
+    net_variance_value: number;
+    unvalued_items: number;
+  };
+  items: {
+    item_type: "product" | "ingredient";
+    item_id: string;
+    item_name: string;
+    unit: string;
+    stock_takes: number;
+    expected_quantity: number;
+    counted_quantity: number;
+    variance: number;
+    posted_adjustment: number;
+    variance_value: number | null;
+  }[];
+}
+
+export type WasteReason = "spoilage" | "prep_waste" | "expired" | "damaged" | "other";
+
+/**
+ * A product or ingredient thrown away, costed when it was logged
+ */
+export interface WasteLog {
+  id: string;
+  branch_id: string;
+  branch_name: string;
+  item_type: "product" | "ingredient";
+  product_id: string | null;
+  ingredient_id: string | null;
+  item_name: string | null;
+  /** Ingredient unit, or the product's sale unit */
+  unit: string | null;
+  quantity: number;
+  reason_type: WasteReason;
+  reason: string | null;
+  photo_url: string | null;
+  unit_cost: number | null;
+  total_cost: number | null;
+  logged_by: string | null;
+  logged_by_username: string | null;
+  created_at: string;
+}
+
+export interface WasteStockMovement {
+  item_type: "product" | "ingredient";
+  item_id: string;
+  name: string;
+  quantity: number;
+  previous_stock: number;
+  new_stock: number;
+}
+
+export interface LogWasteRequest {
+  product_id?: string;
+  ingredient_id?: string;
+  quantity: number;
+  reason_type: WasteReason;
+  /** Required when reason_type is 'other' */
+  reason?: string;
+  /** From uploadWastePhoto, or a link */
+  photo_url?: string;
+  branch_id?: string;
+}
+
+/**
+ * Waste cost over a period, per reason and per item
+ */
+export interface WasteReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  summary: {
+    logs: number;
+    total_cost: number;
+    uncosted_logs: number;
+  };
+  by_reason: {
+    reason_type: WasteReason;
+    logs: number;
+    total_cost: number;
+    uncosted_logs: number;
+  }[];
+  items: {
+    item_type: "product" | "ingredient";
+    item_id: string | null;
+    item_name: string;
+    unit: string | null;
+    logs: number;
+    quantity: number;
+    total_cost: number | null;
+    reasons: WasteReason[];
+  }[];
+}
+
+/**
+ * A product the kitchen 86'd (sold out for the day)
+ */
+export interface EightySixEvent {
+  id: string;
+  product_id: string;
+  product_name: string;
+  business_date: string;
+  reason: string | null;
+  eighty_sixed_by: string | null;
+  eighty_sixed_by_username: string | null;
+  eighty_sixed_at: string;
+  /** Null while still 86'd */
+  restored_at: string | null;
+  /** Null when the business-day rollover restored it */
+  restored_by: string | null;
+  restored_by_username: string | null;
+}
+
+export interface EightySixProductResult {
+  id: string;
+  name: string;
+  is_available: boolean;
+  eighty_sixed_at: string | null;
+}
+
+/**
+ * How often each product got 86'd over a period
+ */
+export interface EightySixReportResponse {
+  from: string;
+  to: string;
+  total_events: number;
+  products: {
+    product_id: string;
+    product_name: string;
+    category_name: string | null;
+    times_eighty_sixed: number;
+    days_eighty_sixed: number;
+    avg_minutes_off_menu: number | null;
+    last_eighty_sixed_at: string;
+  }[];
+}
+
+export type RemakeReason =
+  | "overcooked"
+  | "undercooked"
+  | "wrong_item"
+  | "cold"
+  | "quality"
+  | "foreign_object"
+  | "other";
+
+/**
+ * A dish sent back by the customer and remade at no charge
+ */
+export interface OrderItemRemake {
+  id: string;
+  order_id: string;
+  order_number: string;
+  branch_id: string | null;
+  /** The item sent back */
+  order_item_id: string | null;
+  /** The free replacement sent to the kitchen */
+  remake_item_id: string | null;
+  product_id: string | null;
+  product_name: string;
+  quantity: number;
+  reason_type: RemakeReason;
+  reason: string | null;
+  station: string;
+  unit_cost: number | null;
+  total_cost: number | null;
+  created_by: string | null;
+  created_by_username: string | null;
+  created_at: string;
+}
+
+export interface RemakeOrderItemRequest {
+  reason_type: RemakeReason;
+  /** Required when reason_type is 'other' */
+  reason?: string;
+  /** Defaults to the whole item */
+  quantity?: number;
+}
+
+/**
+ * Remakes over a period, per reason, station and product
+ */
+export interface KitchenQualityReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  summary: {
+    remakes: number;
+    total_cost: number;
+  };
+  by_reason: {
+    reason_type: RemakeReason;
+    remakes: number;
+    quantity: number;
+    total_cost: number;
+  }[];
+  by_station: {
+    station: string;
+    remakes: number;
+    total_cost: number;
+  }[];
+  products: {
+    product_id: string | null;
+    product_name: string;
+    remakes: number;
+    quantity: number;
+    quantity_sold: number;
+    total_cost: number | null;
+    reasons: RemakeReason[];
+    /** Percent of the quantity sold; null when none was sold in the period */
+    remake_rate: number | null;
+  }[];
+}
+
+export type SlaStage = "accepted" | "kitchen_started" | "ready" | "served";
+
+/**
+ * Customer orders measured against one SLA stage target
+ */
+export interface SlaStageResult {
+  stage: SlaStage;
+  target_minutes: number;
+  measured: number;
+  within_target: number;
+  breached: number;
+  compliance_pct: number | null;
+  avg_minutes: number | null;
+  p90_minutes: number | null;
+}
+
+export interface SlaDaypartResult {
+  daypart: string;
+  start: string | null;
+  end: string | null;
+  orders: number;
+  stages: SlaStageResult[];
+}
+
+/**
+ * Order SLA report from admin reports
+ */
+export interface SlaReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  targets: Record<SlaStage, number>;
+  dayparts: SlaDaypartResult[];
+  overall: { orders: number; stages: SlaStageResult[] };
+}
+
+/**
+ * Sales target progress for one period (daily or weekly)
+ */
+export interface TargetProgress {
+  period_type: "daily" | "weekly";
+  period_start: string;
+  period_end: string;
+  target_amount: number | null;
+  achieved: number;
+  pending: number;
+  remaining: number | null;
+  percent: number | null;
+  met: boolean;
+  orders: number;
+}
+
+export interface MyTargetProgress {
+  user_id: string;
+  date: string;
+  daily: TargetProgress | null;
+  weekly: TargetProgress | null;
+}
+
+// Customer-facing status of a QR self-order
+export interface CustomerOrderStatus {
+  order_number: string;
+  table_number: string;
+  status: OrderStatus;
+  status_message: string;
+  items: Array<{ name: string; quantity: number; status: string; ready: boolean }>;
+  items_ready: number;
+  items_total: number;
+  estimated_wait_minutes: number | null;
+  estimated_ready_at: string | null;
+  total_amount: number;
+  currency: OrderCurrency;
+  created_at: string;
+}
+
+// Wait quoted for an order, from queue depth and recent cook times
+export interface WaitEstimate {
+  estimated_wait_minutes: number;
+  estimated_ready_at: string;
+  orders_ahead: number;
+}
+
+export interface KitchenLoad {
+  parallel_orders: number;
+  active_orders: number;
+  preparing_orders: number;
+  queued_orders: number;
+  outstanding_items: number;
+  calibration: { factor: number; samples: number; avg_cook_minutes: number | null };
+  queue_clear_minutes: number;
+  new_order_wait_minutes: number;
+  load_level: 'quiet' | 'normal' | 'busy' | 'slammed';
+}
+
+export interface IncomeReportItem {
+  date: string;
+  revenue: number;
+  order_count: number;
+}
+
+/**
+ * User creation payload
+ */
+export interface CreateUserData {
   username: string;
   password: string;
   email: string;
   first_name: string;
   last_name: string;
-  role: 'admin' | 'manager' | 'cashier' | 'kitchen' | 'server' | 'counter';
+  role: 'admin' | 'manager' | 'cashier' | 'kitchen' | 'server' | 'counter' | 'courier';
   is_active?: boolean;
 }
 
@@ -788,7 +2069,7 @@ export interface UpdateUserData {
   email?: string;
   first_name?: string;
   last_name?: string;
-  role?: 'admin' | 'manager' | 'cashier' | 'kitchen' | 'server' | 'counter';
+  role?: 'admin' | 'manager' | 'cashier' | 'kitchen' | 'server' | 'counter' | 'courier';
   is_active?: boolean;
 }
 
@@ -801,6 +2082,10 @@ export interface CreateCategoryData {
   color?: string;
   image_url?: string;
   sort_order?: number;
+  station?: KitchenStation;
+  kds_color?: string | null;
+  kds_priority?: number | null;
+  kds_group?: string | null;
   is_active?: boolean;
 }
 
@@ -813,6 +2098,10 @@ export interface UpdateCategoryData {
   color?: string;
   image_url?: string;
   sort_order?: number;
+  station?: KitchenStation;
+  kds_color?: string | null;
+  kds_priority?: number | null;
+  kds_group?: string | null;
   is_active?: boolean;
 }
 
@@ -845,10 +2134,16 @@ export interface UpdateTableData {
 /**
  * User notification
  */
+export type NotificationSeverity = 'info' | 'warning' | 'critical';
+
 export interface Notification {
   id: string;
   user_id: string;
   type: string;
+  /** What raised it, e.g. "order_remake"; picks the severity */
+  event: string;
+  /** Drives the alert sound and how prominently the notification is shown */
+  severity: NotificationSeverity;
   title: string;
   message: string;
   is_read: boolean;
@@ -857,6 +2152,22 @@ export interface Notification {
   updated_at: string;
 }
 
+/**
+ * Severity a notification event is sent with (admin settings)
+ */
+export interface NotificationSeverityRule {
+  event: string;
+  type: string;
+  description: string;
+  /** For every role without its own */
+  severity: NotificationSeverity;
+  /** Roles whose severity differs */
+  roles: Record<string, NotificationSeverity>;
+  default_severity: NotificationSeverity;
+  default_roles: Record<string, NotificationSeverity>;
+  overridden: boolean;
+}
+
 /**
  * Notification preferences for a user
  */
@@ -896,6 +2207,188 @@ export interface SystemHealth {
   };
 }
 
+/**
+ * One step of the deployment self-test (POST /admin/selftest)
+ */
+export interface SelftestStep {
+  name: string;
+  status: 'passed' | 'failed' | 'skipped';
+  duration_ms: number;
+  detail: string | null;
+  error: string | null;
+}
+
+export interface SelftestReport {
+  passed: boolean;
+  duration_ms: number;
+  timestamp: string;
+  steps: SelftestStep[];
+}
+
+/**
+ * Print preferences of one POS terminal; null fields follow the system settings
+ */
+export interface DevicePrintPreferences {
+  device_id: string;
+  name: string | null;
+  receipt_printer: string | null;
+  auto_print_receipt: boolean | null;
+  receipt_copies: number | null;
+  kitchen_printer: string | null;
+  auto_print_kitchen: boolean | null;
+  kitchen_copies: number | null;
+}
+
+export interface Device extends DevicePrintPreferences {
+  branch_id: string | null;
+  branch_name: string | null;
+  /** Staff may clock in and out on this terminal */
+  attendance_clock: boolean;
+  last_seen_at: string | null;
+  last_user_id: string | null;
+  last_username: string | null;
+  updated_by: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+// ===========================================
+// Delivery Platform Menu Sync Types
+// ===========================================
+
+export type DeliveryPlatform = 'gofood' | 'grabfood';
+
+export interface PlatformMapping {
+  id: string;
+  platform: DeliveryPlatform;
+  product_id: string;
+  product_name: string;
+  sku: string | null;
+  price: string;
+  platform_item_id: string;
+  synced_price: string | null;
+  synced_available: boolean | null;
+  sync_status: 'pending' | 'synced' | 'failed';
+  last_error: string | null;
+  last_synced_at: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MenuReconciliation {
+  platform: DeliveryPlatform;
+  configured: boolean;
+  mapped_count: number;
+  unmapped_count: number;
+  out_of_sync_count: number;
+  unmapped: Array<{ id: string; name: string; sku: string | null; price: string; is_available: boolean; category_name: string | null }>;
+  out_of_sync: Array<{
+    id: string;
+    product_id: string;
+    product_name: string;
+    platform_item_id: string;
+    price: string;
+    synced_price: string | null;
+    available: boolean;
+    synced_available: boolean | null;
+    sync_status: PlatformMapping['sync_status'];
+    last_error: string | null;
+    last_synced_at: string | null;
+  }>;
+}
+
+// ===========================================
+// Container Deposit Types
+// ===========================================
+
+export interface ContainerType {
+  id: string;
+  name: string;
+  deposit_amount: number;
+  is_active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface OrderContainerDeposit {
+  id: string;
+  container_type_id: string;
+  name: string;
+  quantity: number;
+  unit_deposit: number;
+  returned_quantity: number;
+  outstanding_quantity: number;
+}
+
+export interface ContainerReturnResult {
+  order_id: string;
+  order_number: string;
+  refund_method: 'cash' | 'digital_wallet';
+  refund_amount: number;
+  containers: OrderContainerDeposit[];
+}
+
+// ===========================================
+// Webhook Types
+// ===========================================
+
+export type WebhookEvent = 'order.created' | 'order.completed' | 'payment.processed' | 'inventory.low_stock';
+
+export interface WebhookEndpoint {
+  id: string;
+  name: string;
+  url: string;
+  events: WebhookEvent[];
+  is_active: boolean;
+  secret_hint: string;
+  /** Only present right after creating the endpoint or rotating its secret */
+  secret?: string;
+  last_delivery_at: string | null;
+  failed_count: number;
+  created_by: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  endpoint_id: string;
+  endpoint_name: string;
+  event: WebhookEvent | 'webhook.test';
+  event_id: string;
+  status: 'queued' | 'succeeded' | 'failed';
+  attempts: number;
+  response_status: number | null;
+  duration_ms: number | null;
+  last_error: string | null;
+  delivered_at: string | null;
+  created_at: string;
+  updated_at: string;
+  /** Only on a single delivery */
+  payload?: Record<string, unknown>;
+  response_body?: string | null;
+  endpoint_url?: string;
+}
+
+export type InboundWebhookProvider = 'whatsapp' | 'gofood' | 'grabfood' | 'accounting';
+
+export interface InboundWebhookEvent {
+  id: string;
+  provider: InboundWebhookProvider;
+  /** The provider's event ID, or a hash of the body when it sends none */
+  external_id: string;
+  event_type: string;
+  status: 'queued' | 'processed' | 'ignored' | 'failed';
+  attempts: number;
+  duplicate_count: number;
+  last_error: string | null;
+  received_at: string;
+  processed_at: string | null;
+  updated_at: string;
+  /** Only on a single event */
+  payload?: Record<string, unknown>;
+}
+
 // ===========================================
 // Ingredient Management Types
 // ===========================================
@@ -938,4 +2431,871 @@ export interface RestockResponse {
   previous_stock: number;
   added_quantity: number;
   new_stock: number;
+  batch?: Pick<IngredientBatch, 'id' | 'batch_code' | 'quantity_received' | 'expiry_date'>;
+}
+
+/**
+ * IngredientBatch is one received lot of an ingredient; consumption draws
+ * from the batch expiring first
+ */
+export interface IngredientBatch {
+  id: string;
+  ingredient_id: string;
+  ingredient_name: string;
+  unit: string;
+  batch_code: string | null;
+  quantity_received: number;
+  quantity_remaining: number;
+  expiry_date: string | null;
+  received_at: string;
+  received_by: string | null;
+  received_by_username: string | null;
+  expiry_notified_at: string | null;
+}
+
+export interface ExpiringIngredientBatch extends IngredientBatch {
+  expiry_date: string;
+  days_until_expiry: number;
+  expired: boolean;
+}
+
+export interface ExpiringIngredientsResponse {
+  days: number;
+  expired: number;
+  batches: ExpiringIngredientBatch[];
+}
+
+export interface SettingsChange {
+  setting_key: string;
+  /** Null when the setting didn't exist before, and for secrets */
+  old_value: string | null;
+  new_value: string | null;
+  is_secret: boolean;
+}
+
+export interface SettingsChangeSet {
+  id: string;
+  changed_by: string | null;
+  changed_by_name: string | null;
+  /** The change set this one rolled back */
+  rollback_of: string | null;
+  created_at: string;
+  changes: SettingsChange[];
+}
+
+export interface SettingsRollbackResult {
+  change_set_id: string;
+  rollback_of: string;
+  restored: string[];
+  /** Secrets and settings the change created, which have no earlier value */
+  skipped: string[];
+}
+
+export interface ExportLogEntry {
+  id: string;
+  export_type: string;
+  format: string;
+  user_id: string | null;
+  username: string;
+  branch_id: string | null;
+  /** The query the export was made with */
+  params: Record<string, string | null>;
+  row_count: number;
+  ip_address: string | null;
+  created_at: string;
+}
+
+export interface RuntimeConfig {
+  log_level: 'debug' | 'info' | 'warn' | 'error';
+  maintenance_mode: boolean;
+  maintenance_message: string;
+  rate_limit_public: number;
+  rate_limit_login: number;
+  rate_limit_contact: number;
+  feature_flags: Record<string, boolean>;
+}
+
+export interface RuntimeConfigState {
+  /** Goes up each time a reload changes something */
+  version: number;
+  loaded_at: string | null;
+  config: RuntimeConfig;
+  /** Only on a reload */
+  changed?: string[];
+}
+
+export interface PasswordResetConfirmRequest {
+  /** The reset_token parameter of the emailed link */
+  token: string;
+  new_password: string;
+}
+
+export interface PasswordPolicy {
+  min_length: number;
+  max_length: number;
+  require_upper: boolean;
+  require_lower: boolean;
+  require_number: boolean;
+  require_special: boolean;
+  deny_common: boolean;
+  /** Checked against known data breaches (Have I Been Pwned) */
+  breach_check: boolean;
+}
+
+/** One broken rule, in the failed_rules of a weak_password error */
+export interface PasswordRuleFailure {
+  rule:
+    | 'min_length'
+    | 'max_length'
+    | 'has_upper'
+    | 'has_lower'
+    | 'has_number'
+    | 'has_special'
+    | 'not_common'
+    | 'not_personal'
+    | 'not_breached';
+  message: string;
+}
+
+export interface BatchPaymentRequest {
+  /** Paid in this order, each balance in full before the next */
+  order_ids: string[];
+  payment_method: 'cash' | 'credit_card' | 'debit_card' | 'digital_wallet' | 'corporate_wallet' | 'on_account';
+  amount: number;
+  reference_number?: string;
+  employee_code?: string;
+  corporate_account_id?: string;
+}
+
+type BatchReceiptAmounts =
+  | 'subtotal'
+  | 'tax_amount'
+  | 'service_charge_amount'
+  | 'discount_amount'
+  | 'total_amount'
+  | 'paid'
+  | 'balance';
+
+export interface BatchReceiptOrder extends Formatted<BatchReceiptAmounts> {
+  order_id: string;
+  order_number: string;
+  customer_name?: string | null;
+  status: OrderStatus;
+  table_number?: string | null;
+  subtotal: number;
+  tax_amount: number;
+  service_charge_amount: number;
+  discount_amount: number;
+  total_amount: number;
+  /** Paid towards this order by the batch */
+  paid: number;
+  /** Still owed after all payments */
+  balance: number;
+  items: Array<{ name: string; quantity: number; unit_price: number; total_price: number } & Formatted<'unit_price' | 'total_price'>>;
+}
+
+/** The combined receipt of a batch payment */
+export interface PaymentBatchReceipt extends Formatted<'amount' | 'rounding_adjustment' | 'surcharge_amount' | 'cash_collected'> {
+  id: string;
+  branch_id: string | null;
+  payment_method: BatchPaymentRequest['payment_method'];
+  amount: number;
+  rounding_adjustment: number;
+  surcharge_amount: number;
+  /** Cash only: amount plus rounding and surcharge */
+  cash_collected?: number;
+  reference_number: string | null;
+  order_count: number;
+  processed_by: string | null;
+  processed_by_name: string | null;
+  created_at: string;
+  orders: BatchReceiptOrder[];
+  totals: Record<BatchReceiptAmounts, number> & Formatted<BatchReceiptAmounts>;
+}
+
+// Public status page
+export type StatusIncidentSeverity = 'minor' | 'major' | 'critical';
+export type StatusIncidentComponent = 'ordering' | 'payments' | 'kitchen' | 'delivery' | 'printing' | 'website' | 'other';
+export type StatusComponentState = 'operational' | 'degraded' | 'outage';
+
+export interface StatusIncidentRequest {
+  title: string;
+  message?: string | null;
+  severity: StatusIncidentSeverity;
+  component: StatusIncidentComponent;
+}
+
+export interface PublicStatusIncident {
+  id: string;
+  title: string;
+  message: string | null;
+  severity: StatusIncidentSeverity;
+  component: StatusIncidentComponent;
+  started_at: string;
+  resolved_at: string | null;
+}
+
+export interface StatusIncident extends PublicStatusIncident {
+  created_by: { id: string; name: string | null } | null;
+  resolved_by: { id: string; name: string | null } | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PublicStatus {
+  status: StatusComponentState | 'maintenance';
+  checked_at: string;
+  started_at: string;
+  uptime_seconds: number;
+  /** Share of the window not covered by a critical incident; null when the database is down */
+  availability: { window_days: number; percent: number | null };
+  components: Record<'api' | 'database' | 'background_jobs' | 'image_storage', StatusComponentState>;
+  incidents: {
+    active: PublicStatusIncident[];
+    /** Resolved in the last 24 hours */
+    recently_resolved: PublicStatusIncident[];
+  };
+}
+
+// Notification preference defaults per role
+export type NotificationPreferenceType = 'order_update' | 'low_stock' | 'payment' | 'system_alert' | 'daily_report';
+export type NotificationPreferenceField = 'email_enabled' | 'quiet_hours' | NotificationPreferenceType;
+
+export interface NotificationRoleDefaults {
+  role: string;
+  display_name: string;
+  /** False while the role uses the built-in defaults */
+  customized: boolean;
+  email_enabled: boolean;
+  types_enabled: Record<NotificationPreferenceType, boolean>;
+  quiet_hours_start: string | null;
+  quiet_hours_end: string | null;
+  user_count: number;
+  /** Users who changed some of their preferences themselves */
+  users_with_overrides: number;
+  updated_at: string | null;
+}
+
+export interface NotificationRoleDefaultsRequest {
+  email_enabled?: boolean;
+  types_enabled?: Partial<Record<NotificationPreferenceType, boolean>>;
+  quiet_hours_start?: string | null;
+  quiet_hours_end?: string | null;
+}
+
+export interface ApplyNotificationDefaultsRequest {
+  /** Defaults to every field */
+  fields?: NotificationPreferenceField[];
+  override_personal?: boolean;
+}
+
+export interface ApplyNotificationDefaultsResult {
+  role: string;
+  fields: NotificationPreferenceField[];
+  users: number;
+  created: number;
+  updated: number;
+  unchanged: number;
+  kept_overrides: Array<{ user_id: string; username: string; fields: NotificationPreferenceField[] }>;
+}
+
+// Signed table QR codes
+export interface TableQrCode {
+  table_id: string;
+  table_number: string;
+  branch_id: string;
+  qr_code: string;
+  qr_version: number;
+  qr_rotated_at: string | null;
+  /** The ordering link the QR code encodes */
+  url: string;
+  /** False when the table kept the code it had */
+  generated: boolean;
+}
+
+export interface TableQrCodeWithImages extends TableQrCode {
+  svg: string;
+  /** PNG data URI */
+  png: string;
+}
+
+export interface TableQrBulkResult {
+  generated: number;
+  unchanged: number;
+  tables: TableQrCode[];
+}
+
+// Order behind a customer link token (order tracking or survey invitation)
+export interface CustomerOrder {
+  order_number: string;
+  order_type: 'dine_in' | 'takeout' | 'delivery';
+  table_number: string | null;
+  status: OrderStatus;
+  status_message: string;
+  items: Array<{
+    product: { name: string };
+    quantity: number;
+    sale_unit: string;
+    total_price: number;
+    special_instructions: string | null;
+    note_phrase_labels: string[];
+    status: string;
+  }>;
+  subtotal: number;
+  tax_amount: number;
+  total_amount: number;
+  currency: OrderCurrency;
+  survey_submitted: boolean;
+  created_at: string;
+}
+
+// Data model published by GET /admin/meta/schema
+export interface SchemaEntityColumn {
+  name: string;
+  type: string;
+  nullable: boolean;
+  has_default: boolean;
+  primary_key: boolean;
+  unique: boolean;
+  /** Key in SchemaMeta.enums the column's values come from */
+  enum?: string;
+}
+
+export interface SchemaEntity {
+  name: string;
+  columns: SchemaEntityColumn[];
+  primary_key: string[];
+  foreign_keys: Array<{ columns: string[]; references: { table: string; columns: string[] } }>;
+}
+
+export interface SchemaMeta {
+  entities: SchemaEntity[];
+  /** e.g. order_status, payment_method, inventory_adjust_reason */
+  enums: Record<string, string[]>;
+  validation: {
+    password: PasswordPolicy;
+    customer_order: {
+      max_length: { customer_name: number; notes: number; special_instructions: number };
+      delivery_address_max_length: number;
+      delivery_notes_max_length: number;
+      quantity: { min_exclusive: number; max: number; weighed_max_decimals: number };
+    };
+    order: { covers: { min: number; max: number } };
+    order_item: { note_phrases_max: number };
+    section_assignment: { shift_max_hours: number };
+    note_phrase: { label_max_length: number };
+    product: { spicy_level: { min: number; max: number } };
+    survey: { rating: { min: number; max: number } };
+  };
+}
+
+// Payment methods managed under /admin/payment-methods
+export interface PaymentMethodConfig {
+  code: string;
+  display_name: string;
+  /** Charged on top of the amount applied to the order, e.g. 2 for 2% */
+  surcharge_percent: number;
+  is_active: boolean;
+  /** Offered to guests on the QR order page */
+  customer_selectable: boolean;
+  sort_order: number;
+  /** Shipped with the POS; can be deactivated but not deleted */
+  is_system: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export type CustomerPaymentMethod = Pick<PaymentMethodConfig, 'code' | 'display_name' | 'surcharge_percent'>;
+
+// A course of a coursed dine-in order, from GET /orders/:id/courses
+export interface OrderCourse {
+  course: number;
+  status: 'held' | 'fired' | 'ready' | 'served';
+  item_count: number;
+  fired_at: string | null;
+  fired_by: string | null;
+  fired_by_username: string | null;
+  ready_at: string | null;
+  served_at: string | null;
+  fire_to_ready_seconds: number | null;
+  fire_to_served_seconds: number | null;
+  /** Between the previous course being served and this one being fired */
+  gap_after_previous_seconds: number | null;
+}
+
+export interface FireCourseResult {
+  order_id: string;
+  course: number;
+  fired_at: string;
+  released_items: number;
+  held_courses: number[];
+  courses: OrderCourse[];
+}
+
+export type NotePhraseKitchenFlag = 'allergy' | 'attention';
+
+// A one-tap item instruction ("no onion"), managed under /admin/note-phrases
+export interface NotePhrase {
+  code: string;
+  label: string;
+  /** Only offered for this category's products; null for every product */
+  category_id: string | null;
+  category_name: string | null;
+  /** Offered in the customer app as well as the POS */
+  customer_visible: boolean;
+  /** How the kitchen display marks items given the phrase */
+  kitchen_flag: NotePhraseKitchenFlag | null;
+  is_active: boolean;
+  sort_order: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export type PublicNotePhrase = Pick<NotePhrase, 'code' | 'label' | 'category_id'>;
+
+// A named group of dining tables, staffed per shift (/admin/sections)
+export interface ServerSection {
+  id: string;
+  branch_id: string;
+  name: string;
+  tables: { id: string; table_number: string }[];
+  /** Current and upcoming shifts */
+  assignments: SectionAssignment[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SectionAssignment {
+  id: string;
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  starts_at: string;
+  ends_at: string;
+  on_shift: boolean;
+}
+
+// One of the caller's shifts, from /sections/mine
+export interface MySection {
+  assignment_id: string;
+  starts_at: string;
+  ends_at: string;
+  on_shift: boolean;
+  section_id: string;
+  section_name: string;
+  tables: { id: string; table_number: string }[];
+}
+
+export interface ServerReportRow
+  extends Formatted<'net_sales' | 'service_charge' | 'average_ticket' | 'sales_per_cover'> {
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  orders: number;
+  dine_in_orders: number;
+  covers: number;
+  orders_without_covers: number;
+  net_sales: number;
+  service_charge: number;
+  average_ticket: number;
+  /** Over the orders that recorded covers; null without any */
+  sales_per_cover: number | null;
+  section_hours: number;
+}
+
+export interface ServerReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  servers: ServerReportRow[];
+  totals: Formatted<'net_sales' | 'service_charge' | 'average_ticket'> & {
+    orders: number;
+    covers: number;
+    net_sales: number;
+    service_charge: number;
+    average_ticket: number;
+  };
+}
+
+// A background job that ran out of attempts (/admin/dead-letters)
+export interface DeadLetter {
+  id: string;
+  job_id: string | null;
+  job_type: string;
+  /** The job's payload, e.g. { outbox_id } or { delivery_id } */
+  payload: Record<string, unknown>;
+  status: 'open' | 'replayed' | 'discarded';
+  attempts: number;
+  max_attempts: number;
+  last_error: string | null;
+  /** Times the job ran out of attempts, replays included */
+  failure_count: number;
+  first_failed_at: string;
+  last_failed_at: string;
+  replay_count: number;
+  replayed_at: string | null;
+  replayed_by: string | null;
+  discarded_at: string | null;
+  discarded_by: string | null;
+  note: string | null;
+  /** False for failed refunds, which are retried from /admin/gateway-refunds */
+  replayable: boolean;
+  created_at: string;
+  updated_at: string;
+  /** Only on a single dead letter */
+  errors?: { attempt: number; error: string; at: string }[];
+  job_status?: string | null;
+  subject?: { type: string; record: Record<string, unknown> | null } | null;
+}
+
+export interface DeadLetterStats {
+  job_type: string;
+  open: number;
+  oldest_failed_at: string;
+  last_failed_at: string;
+  last_error: string | null;
+  replayable: boolean;
+}
+
+export interface DeadLetterReplayResult {
+  replayed: { id: string; job_id: string }[];
+  skipped: { id: string; code: string; message: string }[];
+  /** Open dead letters of the job type left for another call */
+  remaining: number;
+}
+
+// Analytics export (ELT) to object storage
+export type AnalyticsStream = 'orders' | 'order_items' | 'payments' | 'inventory_movements';
+
+export interface AnalyticsStreamState {
+  stream: AnalyticsStream;
+  /** Change time and id of the last row exported; null before the first export */
+  watermark_at: string | null;
+  watermark_id: string | null;
+  rows_exported: number;
+  last_run_at: string | null;
+  last_status: 'succeeded' | 'failed' | null;
+  last_error: string | null;
+}
+
+export interface AnalyticsExportStatus {
+  enabled: boolean;
+  target: 'off' | 'file' | 's3';
+  /** Bucket URL or directory */
+  location: string | null;
+  prefix: string;
+  interval_minutes: number;
+  settle_seconds: number;
+  batch_rows: number;
+  streams: AnalyticsStreamState[];
+}
+
+export interface AnalyticsExportRun {
+  id: string;
+  stream: AnalyticsStream;
+  status: 'succeeded' | 'failed';
+  from_at: string | null;
+  from_id: string | null;
+  to_at: string | null;
+  to_id: string | null;
+  row_count: number;
+  files: { key: string; date: string; rows: number }[];
+  error: string | null;
+  started_at: string;
+  finished_at: string;
+}
+
+export interface AnalyticsStreamRunResult {
+  stream: AnalyticsStream;
+  rows: number;
+  files: number;
+  /** Another instance was exporting the stream */
+  skipped?: boolean;
+  error?: string;
+}
+
+// Tips for payout, per recipient and section shift (null shift: outside any)
+type TipAmounts = Formatted<'tips' | 'cash_tips' | 'non_cash_tips' | 'tipped_sales'> & {
+  tipped_payments: number;
+  /** Payment amounts the tips were given on */
+  tipped_sales: number;
+  tips: number;
+  /** Already in the drawer */
+  cash_tips: number;
+  /** To pay out of the drawer */
+  non_cash_tips: number;
+  tip_percent: number;
+};
+
+export interface TipReportShift extends TipAmounts {
+  assignment_id: string | null;
+  section_name: string | null;
+  starts_at: string | null;
+  ends_at: string | null;
+}
+
+export interface TipReportRecipient extends TipAmounts {
+  user_id: string | null;
+  username: string | null;
+  first_name: string | null;
+  last_name: string | null;
+  role: string | null;
+  shifts: TipReportShift[];
+}
+
+export interface TipReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  recipients: TipReportRecipient[];
+  totals: TipAmounts;
+}
+
+// A submitted survey with its follow-up (/admin/surveys)
+export interface AdminSurvey {
+  id: string;
+  order_id: string;
+  order_number: string;
+  order_type: string;
+  branch_id: string | null;
+  server_id: string | null;
+  server_name: string | null;
+  overall_rating: number;
+  food_quality: number | null;
+  service_quality: number | null;
+  ambiance: number | null;
+  value_for_money: number | null;
+  comments: string | null;
+  would_recommend: boolean | null;
+  customer_name: string | null;
+  customer_email: string | null;
+  status: 'new' | 'acknowledged' | 'replied';
+  /** Internal, never shown to the guest */
+  staff_note: string | null;
+  acknowledged_at: string | null;
+  acknowledged_by: string | null;
+  replied_at: string | null;
+  replied_by: string | null;
+  submitted_at: string;
+  updated_at: string;
+}
+
+export interface AdminSurveyDetail extends AdminSurvey {
+  items: { product_id: string; product_name: string | null; quantity: number; special_instructions: string | null }[];
+  replies: {
+    id: string;
+    subject: string;
+    body: string;
+    status: 'queued' | 'sent' | 'failed';
+    last_error: string | null;
+    created_by: string | null;
+    created_at: string;
+    sent_at: string | null;
+  }[];
+}
+
+export interface SurveyFilters {
+  page?: number;
+  per_page?: number;
+  status?: AdminSurvey['status'];
+  min_rating?: number;
+  max_rating?: number;
+  from?: string;
+  to?: string;
+  has_comments?: boolean;
+  server_id?: string;
+  branch_id?: string;
+}
+
+/** Averages are null when there were no surveys to average */
+export interface SurveyRatings {
+  surveys: number;
+  average_rating: number | null;
+  average_food_quality: number | null;
+  average_service_quality: number | null;
+  average_ambiance: number | null;
+  average_value_for_money: number | null;
+  recommendation_rate: number | null;
+  /** Overall rating of 1 or 2 */
+  low_ratings: number;
+}
+
+export interface SurveyTrends {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  summary: SurveyRatings;
+  days: (SurveyRatings & { date: string })[];
+}
+
+export interface SurveysByServer {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  min_surveys: number;
+  baseline: SurveyRatings;
+  servers: (SurveyRatings & {
+    /** Null for orders without a server */
+    server_id: string | null;
+    username: string | null;
+    first_name: string | null;
+    last_name: string | null;
+    rating_difference: number | null;
+  })[];
+}
+
+export interface SurveysByProduct {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  min_surveys: number;
+  baseline: SurveyRatings;
+  products: (SurveyRatings & {
+    product_id: string;
+    product_name: string | null;
+    category_name: string | null;
+    /** Percent of surveyed orders that included the product */
+    survey_share: number;
+    rating_difference: number | null;
+    food_quality_difference: number | null;
+  })[];
+}
+
+export interface HeatmapTotals {
+  orders: number;
+  revenue: number;
+  revenue_formatted: string;
+  /** Dine-in only */
+  covers: number;
+}
+
+// One weekday × hour cell of GET /admin/reports/heatmap
+export interface HeatmapCell extends HeatmapTotals {
+  /** 1 = Monday … 7 = Sunday */
+  day_of_week: number;
+  hour: number;
+  by_order_type: Record<string, { orders: number; revenue: number; covers: number }>;
+  /** Per occurrence of the weekday in the range */
+  average_orders: number;
+  average_revenue: number;
+  average_revenue_formatted: string;
+  average_covers: number;
+}
+
+export interface HeatmapReportResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  order_type: string | null;
+  timezone: string;
+  /** All 168 cells, Monday 00:00 first */
+  cells: HeatmapCell[];
+  by_day: (HeatmapTotals & {
+    day_of_week: number;
+    name: string;
+    days_in_range: number;
+    average_orders: number;
+    average_revenue: number;
+    average_revenue_formatted: string;
+  })[];
+  by_hour: (HeatmapTotals & { hour: number })[];
+  /** Busiest cells by average orders */
+  peaks: (Omit<HeatmapCell, 'by_order_type'> & { day: string })[];
+  totals: HeatmapTotals;
+}
+
+// ===========================================
+// Staff Attendance Types
+// ===========================================
+
+export interface StaffShift {
+  id: string;
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  branch_id: string;
+  starts_at: string;
+  ends_at: string;
+  /** Unpaid, deducted from the time worked on the shift */
+  break_minutes: number;
+  note: string | null;
+  /** An attendance entry was matched to the shift */
+  attended: boolean;
+  created_by: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AttendanceEntry {
+  id: string;
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  branch_id: string | null;
+  /** Local date of the clock-in */
+  work_date: string;
+  clock_in_at: string;
+  clock_out_at: string | null;
+  open: boolean;
+  /** Until now for an open entry */
+  elapsed_minutes: number;
+  shift: { id: string; starts_at: string; ends_at: string; break_minutes: number } | null;
+  clock_in_device_id: string | null;
+  clock_out_device_id: string | null;
+  note: string | null;
+  adjusted_by: string | null;
+  adjusted_at: string | null;
+  adjust_reason: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MyAttendance {
+  clocked_in: boolean;
+  open_entry: AttendanceEntry | null;
+  requires_pin: boolean;
+  requires_terminal: boolean;
+  recent_entries: AttendanceEntry[];
+  upcoming_shifts: StaffShift[];
+}
+
+export interface TimesheetFigures {
+  worked_minutes: number;
+  scheduled_minutes: number;
+  overtime_minutes: number;
+  /** Worked without a scheduled shift */
+  unscheduled_minutes: number;
+  late_minutes: number;
+  early_leave_minutes: number;
+}
+
+export interface Timesheet extends TimesheetFigures {
+  user_id: string;
+  username: string;
+  first_name: string;
+  last_name: string;
+  role: string;
+  entries: number;
+  open_entries: number;
+  missed_shifts: number;
+  days: (TimesheetFigures & { date: string; entries: number })[];
+}
+
+export interface TimesheetsResponse {
+  from: string;
+  to: string;
+  branch_id: string | null;
+  overtime_grace_minutes: number;
+  totals: Pick<TimesheetFigures, 'worked_minutes' | 'scheduled_minutes' | 'overtime_minutes' | 'unscheduled_minutes'> & {
+    open_entries: number;
+    missed_shifts: number;
+  };
+  staff: Timesheet[];
 }